@@ -0,0 +1,250 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+)
+
+// envBackupPassphrase enables encryption of backups. Restores detect
+// encrypted files by their header and need the same passphrase.
+const envBackupPassphrase = "DBTOOL_BACKUP_PASSPHRASE"
+
+// Encrypted backups are a header (magic, salt, nonce prefix) followed by
+// AES-256-GCM sealed chunks, each prefixed with its sealed length. Every
+// chunk's nonce carries its sequence number and the last chunk is sealed
+// with a final marker, so reordered or truncated files fail to decrypt.
+const (
+	backupMagic       = "MJTBAK01"
+	backupSaltSize    = 16
+	backupPrefixSize  = 8
+	backupChunkSize   = 64 * 1024
+	backupKDFRounds   = 600000
+	backupChunkFinal  = 1
+	backupChunkNormal = 0
+)
+
+// backup writes a pg_dump custom-format archive of the database to path,
+// encrypting it when a passphrase is configured.
+func backup(databaseURL, path string) error {
+	out, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return fmt.Errorf("create backup file: %w", err)
+	}
+	defer out.Close()
+
+	var dst io.WriteCloser = nopWriteCloser{out}
+	if passphrase := os.Getenv(envBackupPassphrase); passphrase != "" {
+		if dst, err = newEncryptWriter(out, passphrase); err != nil {
+			return err
+		}
+	}
+
+	cmd := exec.Command("pg_dump", "--format=custom", "--no-owner", "--no-privileges", "--dbname="+databaseURL)
+	cmd.Stdout = dst
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		_ = os.Remove(path)
+		return fmt.Errorf("pg_dump: %w", err)
+	}
+	if err := dst.Close(); err != nil {
+		_ = os.Remove(path)
+		return fmt.Errorf("finish backup: %w", err)
+	}
+	return out.Close()
+}
+
+// restore replays a backup created by backup into the database, replacing
+// the objects it contains in a single transaction.
+func restore(databaseURL, path string) error {
+	in, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("open backup file: %w", err)
+	}
+	defer in.Close()
+
+	br := bufio.NewReader(in)
+	var src io.Reader = br
+	if magic, err := br.Peek(len(backupMagic)); err == nil && string(magic) == backupMagic {
+		passphrase := os.Getenv(envBackupPassphrase)
+		if passphrase == "" {
+			return fmt.Errorf("backup is encrypted; set %s", envBackupPassphrase)
+		}
+		if src, err = newDecryptReader(br, passphrase); err != nil {
+			return err
+		}
+	}
+
+	cmd := exec.Command("pg_restore", "--clean", "--if-exists", "--no-owner", "--no-privileges",
+		"--single-transaction", "--exit-on-error", "--dbname="+databaseURL)
+	cmd.Stdin = src
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("pg_restore: %w", err)
+	}
+	return nil
+}
+
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
+
+func backupCipher(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key, err := pbkdf2.Key(sha256.New, passphrase, salt, backupKDFRounds, 32)
+	if err != nil {
+		return nil, fmt.Errorf("derive backup key: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func chunkNonce(prefix []byte, seq uint32) []byte {
+	nonce := make([]byte, 12)
+	copy(nonce, prefix)
+	binary.BigEndian.PutUint32(nonce[backupPrefixSize:], seq)
+	return nonce
+}
+
+// encryptWriter buffers plaintext into chunks and seals each one.
+type encryptWriter struct {
+	w      io.Writer
+	aead   cipher.AEAD
+	prefix []byte
+	seq    uint32
+	buf    []byte
+}
+
+func newEncryptWriter(w io.Writer, passphrase string) (*encryptWriter, error) {
+	header := make([]byte, len(backupMagic)+backupSaltSize+backupPrefixSize)
+	copy(header, backupMagic)
+	if _, err := rand.Read(header[len(backupMagic):]); err != nil {
+		return nil, fmt.Errorf("generate backup salt: %w", err)
+	}
+	salt := header[len(backupMagic) : len(backupMagic)+backupSaltSize]
+	aead, err := backupCipher(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(header); err != nil {
+		return nil, fmt.Errorf("write backup header: %w", err)
+	}
+	return &encryptWriter{
+		w:      w,
+		aead:   aead,
+		prefix: header[len(backupMagic)+backupSaltSize:],
+		buf:    make([]byte, 0, backupChunkSize),
+	}, nil
+}
+
+func (e *encryptWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		// Only flush a full chunk once more data arrives, so the last chunk
+		// can always be sealed as final by Close.
+		if len(e.buf) == backupChunkSize {
+			if err := e.seal(backupChunkNormal); err != nil {
+				return 0, err
+			}
+		}
+		take := min(backupChunkSize-len(e.buf), len(p))
+		e.buf = append(e.buf, p[:take]...)
+		p = p[take:]
+	}
+	return n, nil
+}
+
+func (e *encryptWriter) Close() error {
+	return e.seal(backupChunkFinal)
+}
+
+func (e *encryptWriter) seal(final byte) error {
+	sealed := e.aead.Seal(nil, chunkNonce(e.prefix, e.seq), e.buf, []byte{final})
+	e.seq++
+	e.buf = e.buf[:0]
+
+	var size [4]byte
+	binary.BigEndian.PutUint32(size[:], uint32(len(sealed)))
+	if _, err := e.w.Write(size[:]); err != nil {
+		return err
+	}
+	_, err := e.w.Write(sealed)
+	return err
+}
+
+// decryptReader opens the chunks written by encryptWriter.
+type decryptReader struct {
+	r      io.Reader
+	aead   cipher.AEAD
+	prefix []byte
+	seq    uint32
+	buf    bytes.Buffer
+	done   bool
+}
+
+func newDecryptReader(r io.Reader, passphrase string) (*decryptReader, error) {
+	header := make([]byte, len(backupMagic)+backupSaltSize+backupPrefixSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, fmt.Errorf("read backup header: %w", err)
+	}
+	if string(header[:len(backupMagic)]) != backupMagic {
+		return nil, errors.New("not an encrypted backup")
+	}
+	aead, err := backupCipher(passphrase, header[len(backupMagic):len(backupMagic)+backupSaltSize])
+	if err != nil {
+		return nil, err
+	}
+	return &decryptReader{r: r, aead: aead, prefix: header[len(backupMagic)+backupSaltSize:]}, nil
+}
+
+func (d *decryptReader) Read(p []byte) (int, error) {
+	for d.buf.Len() == 0 {
+		if d.done {
+			return 0, io.EOF
+		}
+		if err := d.open(); err != nil {
+			return 0, err
+		}
+	}
+	return d.buf.Read(p)
+}
+
+func (d *decryptReader) open() error {
+	var size [4]byte
+	if _, err := io.ReadFull(d.r, size[:]); err != nil {
+		return fmt.Errorf("backup truncated: %w", err)
+	}
+	n := binary.BigEndian.Uint32(size[:])
+	if n > backupChunkSize+uint32(d.aead.Overhead()) {
+		return errors.New("backup chunk too large")
+	}
+	sealed := make([]byte, n)
+	if _, err := io.ReadFull(d.r, sealed); err != nil {
+		return fmt.Errorf("backup truncated: %w", err)
+	}
+
+	nonce := chunkNonce(d.prefix, d.seq)
+	plain, err := d.aead.Open(nil, nonce, sealed, []byte{backupChunkNormal})
+	if err != nil {
+		if plain, err = d.aead.Open(nil, nonce, sealed, []byte{backupChunkFinal}); err != nil {
+			return errors.New("decrypt backup: wrong passphrase or corrupted file")
+		}
+		d.done = true
+	}
+	d.seq++
+	d.buf.Write(plain)
+	return nil
+}
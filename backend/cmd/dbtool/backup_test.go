@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	"testing"
+)
+
+func encryptForTest(t *testing.T, plain []byte, passphrase string) []byte {
+	t.Helper()
+
+	var out bytes.Buffer
+	w, err := newEncryptWriter(&out, passphrase)
+	if err != nil {
+		t.Fatalf("newEncryptWriter: %v", err)
+	}
+	if _, err := w.Write(plain); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	return out.Bytes()
+}
+
+func TestBackupEncryptionRoundTrip(t *testing.T) {
+	for _, size := range []int{0, 10, backupChunkSize, 3*backupChunkSize + 17} {
+		plain := make([]byte, size)
+		_, _ = rand.Read(plain)
+
+		sealed := encryptForTest(t, plain, "secret")
+		r, err := newDecryptReader(bytes.NewReader(sealed), "secret")
+		if err != nil {
+			t.Fatalf("size %d: newDecryptReader: %v", size, err)
+		}
+		got, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatalf("size %d: ReadAll: %v", size, err)
+		}
+		if !bytes.Equal(got, plain) {
+			t.Fatalf("size %d: round trip mismatch", size)
+		}
+	}
+}
+
+func TestBackupDecryptionRejectsTamperedFiles(t *testing.T) {
+	plain := make([]byte, 2*backupChunkSize+5)
+	sealed := encryptForTest(t, plain, "secret")
+
+	cases := map[string]struct {
+		data       []byte
+		passphrase string
+	}{
+		"wrong passphrase": {sealed, "other"},
+		"truncated":        {sealed[:len(sealed)-backupChunkSize/2], "secret"},
+		"last chunk cut":   {sealed[:len(backupMagic)+backupSaltSize+backupPrefixSize+4+backupChunkSize+16], "secret"},
+	}
+	for name, tc := range cases {
+		r, err := newDecryptReader(bytes.NewReader(tc.data), tc.passphrase)
+		if err != nil {
+			t.Fatalf("%s: newDecryptReader: %v", name, err)
+		}
+		if _, err := io.ReadAll(r); err == nil {
+			t.Fatalf("%s: expected decryption error", name)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/slowquery"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/store"
+)
+
+// indexReport prints the slowest recorded statements and the indexes the
+// advisor suggests for their sequential scans.
+func indexReport(db *sql.DB, limit int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	s, err := store.New(db)
+	if err != nil {
+		return err
+	}
+	queries, err := s.ListSlowQueries(ctx, limit)
+	if err != nil {
+		return err
+	}
+	if len(queries) == 0 {
+		fmt.Println("No slow queries recorded.")
+		return nil
+	}
+	existing, err := s.ListIndexColumns(ctx)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Slowest %d statements by total time:\n\n", len(queries))
+	for _, q := range queries {
+		query := q.Query
+		if len(query) > 120 {
+			query = query[:117] + "..."
+		}
+		fmt.Printf("  %s  calls=%d total=%.0fms max=%.0fms\n    %s\n", q.Fingerprint, q.Calls, q.TotalMs, q.MaxMs, query)
+	}
+
+	suggestions := slowquery.Advise(queries, existing)
+	if len(suggestions) == 0 {
+		fmt.Println("\nNo missing indexes suggested.")
+		return nil
+	}
+	fmt.Println("\nSuggested indexes:")
+	for _, sug := range suggestions {
+		fmt.Printf("\n  -- %.0fms across %s\n  %s\n", sug.TotalMs, strings.Join(sug.Fingerprints, ", "), sug.Statement())
+	}
+	return nil
+}
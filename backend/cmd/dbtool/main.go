@@ -66,13 +66,80 @@ func main() {
 			}
 			log.Printf("Database version forced to %d", v)
 			
+		case "backup":
+			if len(os.Args) < 3 {
+				log.Fatalf("usage: %s backup <file>", os.Args[0])
+			}
+			log.Printf("Backing up database to %s...", os.Args[2])
+			if err := backup(cfg.DatabaseURL, os.Args[2]); err != nil {
+				log.Fatalf("failed to back up database: %v", err)
+			}
+			log.Printf("Backup written to %s", os.Args[2])
+
+		case "restore":
+			// Restoring drops and recreates every object in the backup, so
+			// require an explicit confirmation.
+			if len(os.Args) < 4 || os.Args[3] != "--yes" {
+				log.Fatalf("usage: %s restore <file> --yes", os.Args[0])
+			}
+			log.Printf("Restoring database from %s...", os.Args[2])
+			if err := restore(cfg.DatabaseURL, os.Args[2]); err != nil {
+				log.Fatalf("failed to restore database: %v", err)
+			}
+			log.Printf("Database restored from %s", os.Args[2])
+
+		case "drift":
+			log.Printf("Comparing live schema with migrations...")
+			driftCtx, driftCancel := context.WithTimeout(context.Background(), time.Minute)
+			report, err := migrations.CheckDrift(driftCtx, db)
+			driftCancel()
+			if err != nil {
+				log.Fatalf("failed to check schema drift: %v", err)
+			}
+			if report.Empty() {
+				log.Printf("Schema matches migrations at version %d", report.Version)
+				break
+			}
+			for _, line := range report.Lines() {
+				fmt.Println(line)
+			}
+			log.Fatalf("Schema drifted from migrations at version %d", report.Version)
+
+		case "indexes":
+			limit := 50
+			if len(os.Args) > 2 {
+				if _, err := fmt.Sscanf(os.Args[2], "%d", &limit); err != nil {
+					log.Fatalf("invalid limit: %s", os.Args[2])
+				}
+			}
+			if err := indexReport(db, limit); err != nil {
+				log.Fatalf("failed to build index report: %v", err)
+			}
+
+		case "merge-users":
+			// Merging deletes the source user, so require an explicit
+			// confirmation.
+			if len(os.Args) < 5 || os.Args[4] != "--yes" {
+				log.Fatalf("usage: %s merge-users <source_user_id> <target_user_id> --yes", os.Args[0])
+			}
+			var sourceID, targetID int64
+			if _, err := fmt.Sscanf(os.Args[2], "%d", &sourceID); err != nil {
+				log.Fatalf("invalid source user id: %s", os.Args[2])
+			}
+			if _, err := fmt.Sscanf(os.Args[3], "%d", &targetID); err != nil {
+				log.Fatalf("invalid target user id: %s", os.Args[3])
+			}
+			if err := mergeUsers(db, cfg.RegionalDatabaseURLs, sourceID, targetID); err != nil {
+				log.Fatalf("failed to merge users: %v", err)
+			}
+
 		case "status":
 			log.Printf("Checking migration status...")
 			// This would require adding a status function to migrations
 			log.Printf("Status check not implemented yet")
 			
 		default:
-			log.Printf("Usage: %s [fix|force <version>|status]", os.Args[0])
+			log.Printf("Usage: %s [fix|force <version>|backup <file>|restore <file> --yes|drift|indexes [limit]|merge-users <source_user_id> <target_user_id> --yes|status]", os.Args[0])
 			os.Exit(1)
 		}
 	} else {
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/store"
+)
+
+// mergeUsers folds the duplicate user sourceID into targetID and prints what
+// moved. The merge event goes through the outbox so the server records it in
+// the audit log and evicts cached secrets for both users. Request logs kept in
+// regional databases are merged too.
+func mergeUsers(db *sql.DB, regionalURLs map[string]string, sourceID, targetID int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	s, err := store.New(db)
+	if err != nil {
+		return err
+	}
+	outbox, err := store.NewOutboxStore(db)
+	if err != nil {
+		return err
+	}
+	s.SetOutbox(outbox)
+
+	regional := make(map[string]*sql.DB, len(regionalURLs))
+	for region, dsn := range regionalURLs {
+		rdb, err := sql.Open("postgres", dsn)
+		if err != nil {
+			return fmt.Errorf("open %s database: %w", region, err)
+		}
+		defer rdb.Close()
+		regional[region] = rdb
+	}
+	s.SetRegionalDatabases(regional)
+
+	merge, err := s.MergeUsers(ctx, sourceID, targetID)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Merged user %d (%s) into user %d (%s)\n", merge.SourceID, merge.SourceEmail, merge.TargetID, merge.TargetEmail)
+	printCounts("Moved", merge.Moved)
+	printCounts("Dropped (target kept its own)", merge.Dropped)
+	return nil
+}
+
+func printCounts(title string, counts map[string]int64) {
+	if len(counts) == 0 {
+		return
+	}
+	tables := make([]string, 0, len(counts))
+	for table := range counts {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+	fmt.Printf("\n%s:\n", title)
+	for _, table := range tables {
+		fmt.Printf("  %-28s %d\n", table, counts[table])
+	}
+}
@@ -0,0 +1,243 @@
+// Command loadgen stress-tests job claiming. It enqueues -jobs jobs, drains
+// them with -workers concurrent claimers through store.JobStore, and reports
+// claim throughput, latency, contention and any job claimed more than once.
+//
+// It claims whatever is pending, so it must only run against a disposable
+// database: the DSN comes from -db or TEST_DATABASE_URL, never DATABASE_URL.
+//
+//	go run ./cmd/loadgen -jobs 5000 -workers 16
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	_ "github.com/lib/pq"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/migrations"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/store"
+)
+
+type options struct {
+	dsn      string
+	jobs     int
+	workers  int
+	work     time.Duration
+	idle     time.Duration
+	migrate  bool
+	keepJobs bool
+}
+
+// workerStats is owned by a single worker goroutine until the run finishes.
+type workerStats struct {
+	claimed   int
+	empty     int
+	errors    int
+	latencies []time.Duration
+}
+
+func main() {
+	var opts options
+	flag.StringVar(&opts.dsn, "db", os.Getenv("TEST_DATABASE_URL"), "Postgres DSN of a disposable database (defaults to TEST_DATABASE_URL)")
+	flag.IntVar(&opts.jobs, "jobs", 1000, "number of jobs to enqueue")
+	flag.IntVar(&opts.workers, "workers", 8, "number of concurrent claimers")
+	flag.DurationVar(&opts.work, "work", 0, "simulated processing time per job")
+	flag.DurationVar(&opts.idle, "idle", 5*time.Millisecond, "wait after an empty claim before retrying")
+	flag.BoolVar(&opts.migrate, "migrate", true, "apply migrations before running")
+	flag.BoolVar(&opts.keepJobs, "keep", false, "keep the generated jobs instead of deleting them afterwards")
+	flag.Parse()
+
+	if opts.dsn == "" {
+		log.Fatalf("loadgen: -db or TEST_DATABASE_URL is required")
+	}
+	if opts.jobs < 1 || opts.workers < 1 {
+		log.Fatalf("loadgen: -jobs and -workers must be at least 1")
+	}
+
+	db, err := sql.Open("postgres", opts.dsn)
+	if err != nil {
+		log.Fatalf("loadgen: open database: %v", err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(opts.workers + 2)
+
+	if ok, err := run(context.Background(), db, opts); err != nil {
+		log.Fatalf("loadgen: %v", err)
+	} else if !ok {
+		os.Exit(1)
+	}
+}
+
+// run executes one load test and prints its report. It returns false when
+// the run detected a duplicate claim or left jobs unclaimed.
+func run(ctx context.Context, db *sql.DB, opts options) (bool, error) {
+	pingCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	if err := db.PingContext(pingCtx); err != nil {
+		return false, fmt.Errorf("ping database: %w", err)
+	}
+
+	if opts.migrate {
+		if err := migrations.Up(db); err != nil {
+			return false, err
+		}
+	}
+
+	jobs, err := store.NewJobStore(db)
+	if err != nil {
+		return false, err
+	}
+
+	// Pending jobs that loadgen did not create would be claimed and
+	// completed by the run.
+	var pending int
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM jobs WHERE status = 'pending'`).Scan(&pending); err != nil {
+		return false, fmt.Errorf("count pending jobs: %w", err)
+	}
+	if pending > 0 {
+		return false, fmt.Errorf("%d pending jobs already queued; use a disposable database", pending)
+	}
+
+	jobType := fmt.Sprintf("loadgen_%d", time.Now().UnixNano())
+	if !opts.keepJobs {
+		defer func() {
+			if _, err := db.ExecContext(context.Background(), `DELETE FROM jobs WHERE job_type = $1`, jobType); err != nil {
+				log.Printf("loadgen: delete generated jobs: %v", err)
+			}
+		}()
+	}
+
+	enqueueStart := time.Now()
+	for i := 0; i < opts.jobs; i++ {
+		job := &models.Job{
+			JobType:     jobType,
+			Payload:     models.JSONB{"seq": i},
+			Priority:    models.JobPriorityNormal,
+			MaxAttempts: 1,
+			Metadata:    models.JSONB{},
+		}
+		if err := jobs.Enqueue(ctx, job); err != nil {
+			return false, fmt.Errorf("enqueue job %d: %w", i, err)
+		}
+	}
+	enqueueElapsed := time.Since(enqueueStart)
+
+	var (
+		remaining = int64(opts.jobs)
+		seen      sync.Map // job ID -> number of claims
+		dupes     atomic.Int64
+		stats     = make([]workerStats, opts.workers)
+		wg        sync.WaitGroup
+	)
+
+	claimStart := time.Now()
+	for i := range stats {
+		wg.Add(1)
+		go func(id int, st *workerStats) {
+			defer wg.Done()
+			workerID := fmt.Sprintf("loadgen-%d", id)
+			for atomic.LoadInt64(&remaining) > 0 {
+				start := time.Now()
+				job, err := jobs.ClaimNextJob(ctx, workerID)
+				st.latencies = append(st.latencies, time.Since(start))
+				if err != nil {
+					st.errors++
+					log.Printf("loadgen: %s claim failed: %v", workerID, err)
+					time.Sleep(opts.idle)
+					continue
+				}
+				if job == nil {
+					st.empty++
+					time.Sleep(opts.idle)
+					continue
+				}
+
+				st.claimed++
+				atomic.AddInt64(&remaining, -1)
+				count := new(int64)
+				if prev, loaded := seen.LoadOrStore(job.ID, count); loaded {
+					count = prev.(*int64)
+				}
+				if atomic.AddInt64(count, 1) > 1 {
+					dupes.Add(1)
+					log.Printf("loadgen: job %d claimed again by %s", job.ID, workerID)
+				}
+
+				if opts.work > 0 {
+					time.Sleep(opts.work)
+				}
+				if err := jobs.MarkCompleted(ctx, job.ID); err != nil {
+					st.errors++
+					log.Printf("loadgen: %s complete job %d failed: %v", workerID, job.ID, err)
+				}
+			}
+		}(i, &stats[i])
+	}
+	wg.Wait()
+	claimElapsed := time.Since(claimStart)
+
+	// Attempts above one mean the database handed a job out twice even if
+	// the in-process tally missed it.
+	var reclaimed, unfinished int
+	if err := db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FILTER (WHERE attempts > 1),
+		       COUNT(*) FILTER (WHERE status <> 'completed')
+		FROM jobs WHERE job_type = $1
+	`, jobType).Scan(&reclaimed, &unfinished); err != nil {
+		return false, fmt.Errorf("verify jobs: %w", err)
+	}
+
+	var total workerStats
+	fmt.Printf("loadgen: %d jobs, %d workers, %s simulated work\n", opts.jobs, opts.workers, opts.work)
+	fmt.Printf("enqueue:  %s (%.0f jobs/s)\n", enqueueElapsed.Round(time.Millisecond), perSecond(opts.jobs, enqueueElapsed))
+	for i, st := range stats {
+		fmt.Printf("worker %2d: %6d claimed, %6d empty, %d errors\n", i, st.claimed, st.empty, st.errors)
+		total.claimed += st.claimed
+		total.empty += st.empty
+		total.errors += st.errors
+		total.latencies = append(total.latencies, st.latencies...)
+	}
+	fmt.Printf("claim:    %s (%.0f claims/s)\n", claimElapsed.Round(time.Millisecond), perSecond(total.claimed, claimElapsed))
+	fmt.Printf("latency:  p50 %s  p95 %s  p99 %s  max %s\n",
+		percentile(total.latencies, 0.50), percentile(total.latencies, 0.95),
+		percentile(total.latencies, 0.99), percentile(total.latencies, 1))
+	attempts := len(total.latencies)
+	fmt.Printf("contention: %d empty claims of %d attempts (%.1f%%), %d errors\n",
+		total.empty, attempts, 100*float64(total.empty)/float64(max(attempts, 1)), total.errors)
+	fmt.Printf("duplicates: %d in-process, %d jobs with attempts > 1, %d unfinished\n", dupes.Load(), reclaimed, unfinished)
+
+	return dupes.Load() == 0 && reclaimed == 0 && unfinished == 0, nil
+}
+
+func perSecond(n int, d time.Duration) float64 {
+	if d <= 0 {
+		return 0
+	}
+	return float64(n) / d.Seconds()
+}
+
+// percentile returns the p-th quantile (0..1) of durations, sorting them in
+// place.
+func percentile(durations []time.Duration, p float64) time.Duration {
+	if len(durations) == 0 {
+		return 0
+	}
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+	idx := int(p*float64(len(durations))+0.5) - 1
+	if idx < 0 {
+		idx = 0
+	}
+	if idx >= len(durations) {
+		idx = len(durations) - 1
+	}
+	return durations[idx].Round(time.Microsecond)
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/middleware"
+)
+
+// config is read from the config file; environment variables override it.
+type config struct {
+	// BaseURL is where the backend is reachable, e.g. https://api.example.com.
+	BaseURL string `json:"base_url"`
+	// KeyID and SharedKey are one entry of the backend's WORKER_SHARED_KEYS;
+	// every request is signed with them.
+	KeyID     string `json:"key_id"`
+	SharedKey string `json:"shared_key"`
+	// AuthToken is an optional bearer token from /api/auth/token for routes
+	// that act as a user rather than as an operator.
+	AuthToken string `json:"auth_token"`
+}
+
+// defaultConfigPath returns $MCPCTL_CONFIG or mcpctl/config.json under the
+// user's config directory.
+func defaultConfigPath() string {
+	if path := os.Getenv("MCPCTL_CONFIG"); path != "" {
+		return path
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "mcpctl.json"
+	}
+	return filepath.Join(dir, "mcpctl", "config.json")
+}
+
+// loadConfig reads path, which may be missing when everything comes from
+// MCPCTL_URL, MCPCTL_KEY_ID, MCPCTL_SHARED_KEY and MCPCTL_AUTH_TOKEN. The
+// file holds a signing key, so one readable by other users is refused.
+func loadConfig(path string) (config, error) {
+	var cfg config
+	info, err := os.Stat(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return cfg, err
+	case info.Mode().Perm()&0o077 != 0:
+		return cfg, fmt.Errorf("%s is accessible by other users; run chmod 600 %s", path, path)
+	default:
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return cfg, err
+		}
+		if err := json.Unmarshal(data, &cfg); err != nil {
+			return cfg, fmt.Errorf("parse %s: %w", path, err)
+		}
+	}
+
+	for env, field := range map[string]*string{
+		"MCPCTL_URL":        &cfg.BaseURL,
+		"MCPCTL_KEY_ID":     &cfg.KeyID,
+		"MCPCTL_SHARED_KEY": &cfg.SharedKey,
+		"MCPCTL_AUTH_TOKEN": &cfg.AuthToken,
+	} {
+		if v := os.Getenv(env); v != "" {
+			*field = v
+		}
+	}
+	cfg.BaseURL = strings.TrimRight(cfg.BaseURL, "/")
+	if cfg.BaseURL == "" {
+		return cfg, fmt.Errorf("no backend URL: set base_url in %s or MCPCTL_URL", path)
+	}
+	return cfg, nil
+}
+
+// client calls the backend API with signed requests.
+type client struct {
+	cfg  config
+	http *http.Client
+	now  func() time.Time
+}
+
+func newClient(cfg config) *client {
+	return &client{cfg: cfg, http: &http.Client{Timeout: 30 * time.Second}, now: time.Now}
+}
+
+// do sends a request with body encoded as JSON (when not nil) and returns
+// the response body, or an error carrying the backend's message for
+// statuses other than 2xx.
+func (c *client) do(ctx context.Context, method, path string, body any) ([]byte, error) {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return nil, err
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.cfg.BaseURL+path, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.cfg.AuthToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.cfg.AuthToken)
+	}
+	if c.cfg.SharedKey != "" {
+		timestamp := strconv.FormatInt(c.now().Unix(), 10)
+		req.Header.Set(middleware.TimestampHeader, timestamp)
+		req.Header.Set(middleware.SignatureHeader,
+			middleware.SignRequest([]byte(c.cfg.SharedKey), method, req.URL.Path, req.URL.RawQuery, timestamp, payload))
+		if c.cfg.KeyID != "" {
+			req.Header.Set(middleware.KeyIDHeader, c.cfg.KeyID)
+		}
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("%s %s: %s: %s", method, path, resp.Status, strings.TrimSpace(string(data)))
+	}
+	return data, nil
+}
+
+// printJSON writes data indented, or as is when it is not JSON.
+func printJSON(w io.Writer, data []byte) error {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil
+	}
+	var out bytes.Buffer
+	if err := json.Indent(&out, data, "", "  "); err != nil {
+		_, err = w.Write(data)
+		return err
+	}
+	out.WriteByte('\n')
+	_, err := out.WriteTo(w)
+	return err
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/keyring"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/middleware"
+)
+
+func TestClientSignsRequests(t *testing.T) {
+	keys, err := keyring.Parse("ops:s3cret")
+	if err != nil {
+		t.Fatalf("parse keyring: %v", err)
+	}
+	var gotBody string
+	srv := httptest.NewServer(middleware.RequireSignedRequestKeys(keys, time.Minute)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		gotBody = string(b)
+		w.Write([]byte(`{"ok":true}`))
+	})))
+	defer srv.Close()
+
+	c := newClient(config{BaseURL: srv.URL, KeyID: "ops", SharedKey: "s3cret"})
+	data, err := c.do(context.Background(), http.MethodPost, "/api/jobs?x=1", map[string]string{"job_type": "noop"})
+	if err != nil {
+		t.Fatalf("signed request rejected: %v", err)
+	}
+	if gotBody != `{"job_type":"noop"}` {
+		t.Fatalf("unexpected body %q", gotBody)
+	}
+	var out bytes.Buffer
+	if err := printJSON(&out, data); err != nil || out.String() != "{\n  \"ok\": true\n}\n" {
+		t.Fatalf("unexpected output %q (%v)", out.String(), err)
+	}
+
+	c.cfg.SharedKey = "wrong"
+	if _, err := c.do(context.Background(), http.MethodGet, "/api/jobs/stats", nil); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Fatalf("expected 401 with the wrong key, got %v", err)
+	}
+}
+
+func TestLoadConfigRefusesSharedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"base_url":"https://api.example.com/","shared_key":"k"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadConfig(path); err == nil {
+		t.Fatal("expected a world-readable config file to be refused")
+	}
+
+	if err := os.Chmod(path, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("MCPCTL_KEY_ID", "ops")
+	cfg, err := loadConfig(path)
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	if cfg.BaseURL != "https://api.example.com" || cfg.SharedKey != "k" || cfg.KeyID != "ops" {
+		t.Fatalf("unexpected config %+v", cfg)
+	}
+}
@@ -0,0 +1,232 @@
+// Command mcpctl runs operator tasks against the backend API: listing and
+// inspecting tenants, rotating MCP secrets, enqueueing and retrying jobs,
+// reading queue stats and triggering plan migrations.
+//
+// Requests are signed with one of the backend's WORKER_SHARED_KEYS, read
+// from a JSON config file (mcpctl/config.json in the user config directory, or
+// MCPCTL_CONFIG) that must only be readable by its owner:
+//
+//	{"base_url": "https://api.example.com", "key_id": "ops", "shared_key": "..."}
+//
+//	mcpctl jobs stats
+//	mcpctl tenants show user@example.com
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+
+	"github.com/spf13/cobra"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/handlers"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+)
+
+func main() {
+	if err := newRootCommand().Execute(); err != nil {
+		os.Exit(1)
+	}
+}
+
+func newRootCommand() *cobra.Command {
+	var configPath, baseURL string
+	var api *client
+
+	root := &cobra.Command{
+		Use:          "mcpctl",
+		Short:        "Operate the MCP Jira backend",
+		SilenceUsage: true,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadConfig(configPath)
+			if err != nil {
+				return err
+			}
+			if baseURL != "" {
+				cfg.BaseURL = baseURL
+			}
+			api = newClient(cfg)
+			return nil
+		},
+	}
+	root.PersistentFlags().StringVar(&configPath, "config", defaultConfigPath(), "config file")
+	root.PersistentFlags().StringVar(&baseURL, "url", "", "backend URL, overriding the config file")
+
+	// call runs one request and prints the response.
+	call := func(cmd *cobra.Command, method, path string, body any) error {
+		data, err := api.do(cmd.Context(), method, path, body)
+		if err != nil {
+			return err
+		}
+		return printJSON(cmd.OutOrStdout(), data)
+	}
+
+	root.AddCommand(
+		newTenantsCommand(call),
+		newSecretsCommand(func() *client { return api }),
+		newJobsCommand(call),
+		newPlansCommand(call),
+	)
+	return root
+}
+
+// caller runs a request against the backend and prints the response.
+type caller func(cmd *cobra.Command, method, path string, body any) error
+
+func newTenantsCommand(call caller) *cobra.Command {
+	tenants := &cobra.Command{Use: "tenants", Short: "List and inspect tenants"}
+
+	var (
+		listLimit  int
+		listSort   string
+		listCursor string
+	)
+	list := &cobra.Command{
+		Use:   "list",
+		Short: "List users a page at a time",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			q := url.Values{"limit": {strconv.Itoa(listLimit)}}
+			if listSort != "" {
+				q.Set("sort", listSort)
+			}
+			if listCursor != "" {
+				q.Set("cursor", listCursor)
+			}
+			return call(cmd, http.MethodGet, "/api/users?"+q.Encode(), nil)
+		},
+	}
+	list.Flags().IntVar(&listLimit, "limit", 50, "maximum number of users per page")
+	list.Flags().StringVar(&listSort, "sort", "", "sort order: created_at, email or name, prefixed with - for descending (default -created_at)")
+	list.Flags().StringVar(&listCursor, "cursor", "", "next_cursor of the previous page")
+
+	var showLimit int
+	show := &cobra.Command{
+		Use:   "show <email>",
+		Short: "Show a tenant's account, subscription, recent jobs and requests",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			q := url.Values{"email": {args[0]}, "limit": {strconv.Itoa(showLimit)}}
+			return call(cmd, http.MethodGet, "/api/admin/users/state?"+q.Encode(), nil)
+		},
+	}
+	show.Flags().IntVar(&showLimit, "limit", 20, "number of recent jobs and requests")
+
+	tenants.AddCommand(list, show)
+	return tenants
+}
+
+func newSecretsCommand(api func() *client) *cobra.Command {
+	secrets := &cobra.Command{Use: "secrets", Short: "Manage tenant MCP secrets"}
+
+	rotate := &cobra.Command{
+		Use:   "rotate <email>",
+		Short: "Replace a tenant's MCP secret and print the new one",
+		Long: "Replace a tenant's MCP secret and print the new one. The old secret stops " +
+			"working immediately, so the tenant must update their MCP client.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// The backend rotates the bearer token's own secret when one
+			// is sent, so the tenant is named without it.
+			c := *api()
+			c.cfg.AuthToken = ""
+			data, err := c.do(cmd.Context(), http.MethodPost, "/api/mcp/secret", map[string]string{"user_email": args[0]})
+			if err != nil {
+				return err
+			}
+			return printJSON(cmd.OutOrStdout(), data)
+		},
+	}
+
+	secrets.AddCommand(rotate)
+	return secrets
+}
+
+func newJobsCommand(call caller) *cobra.Command {
+	jobs := &cobra.Command{Use: "jobs", Short: "Inspect and manage the job queue"}
+
+	stats := &cobra.Command{
+		Use:   "stats",
+		Short: "Show job counts by status",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return call(cmd, http.MethodGet, "/api/jobs/stats", nil)
+		},
+	}
+
+	var req handlers.CreateJobRequest
+	var payload string
+	enqueue := &cobra.Command{
+		Use:   "enqueue <job-type>",
+		Short: "Enqueue a job",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			req.JobType = args[0]
+			if err := json.Unmarshal([]byte(payload), &req.Payload); err != nil {
+				return fmt.Errorf("--payload must be a JSON object: %w", err)
+			}
+			return call(cmd, http.MethodPost, "/api/jobs", req)
+		},
+	}
+	enqueue.Flags().StringVar(&payload, "payload", "{}", "job payload as a JSON object")
+	enqueue.Flags().StringVar(&req.Priority, "priority", "", "low, normal, high or critical (default normal)")
+	enqueue.Flags().IntVar(&req.MaxAttempts, "max-attempts", 0, "attempts before the job fails (default 3)")
+	enqueue.Flags().IntVar(&req.DedupWindowSeconds, "dedup-window", 0, "seconds within which an identical pending job is reused")
+
+	retry := &cobra.Command{
+		Use:   "retry <job-id>",
+		Short: "Retry a failed job",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if _, err := strconv.ParseInt(args[0], 10, 64); err != nil {
+				return fmt.Errorf("invalid job id %q", args[0])
+			}
+			return call(cmd, http.MethodPost, "/api/jobs/"+args[0]+"/retry", nil)
+		},
+	}
+
+	var filter models.JobFilter
+	var priority string
+	retryFailed := &cobra.Command{
+		Use:   "retry-failed",
+		Short: "Retry every failed job matching the filters",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			filter.Priority = models.JobPriority(priority)
+			return call(cmd, http.MethodPost, "/api/jobs/retry", filter)
+		},
+	}
+	retryFailed.Flags().StringVar(&filter.JobType, "job-type", "", "only jobs of this type")
+	retryFailed.Flags().StringVar(&priority, "priority", "", "only jobs with this priority")
+	retryFailed.Flags().StringVar(&filter.ErrorContains, "error-contains", "", "only jobs whose last error contains this text")
+	retryFailed.Flags().IntVar(&filter.Limit, "limit", 0, "maximum number of jobs to retry")
+
+	jobs.AddCommand(stats, enqueue, retry, retryFailed)
+	return jobs
+}
+
+func newPlansCommand(call caller) *cobra.Command {
+	plans := &cobra.Command{Use: "plans", Short: "Manage membership plans"}
+
+	migrate := &cobra.Command{
+		Use:   "migrate",
+		Short: "Migrate subscribers off deprecated plan versions past their deadline now",
+		Long: "Enqueue the plan migration check, which moves subscribers of deprecated plan " +
+			"versions past their migration deadline to the active version according to each " +
+			"version's migration policy and archives the emptied versions.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return call(cmd, http.MethodPost, "/api/jobs", handlers.CreateJobRequest{
+				JobType:  "plan_migration_check",
+				Payload:  map[string]interface{}{},
+				Priority: string(models.JobPriorityHigh),
+			})
+		},
+	}
+
+	plans.AddCommand(migrate)
+	return plans
+}
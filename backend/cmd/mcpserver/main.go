@@ -0,0 +1,141 @@
+// Command mcpserver is a Model Context Protocol server over stdio that
+// serves Jira tools for one tenant, for MCP clients that launch local
+// servers instead of connecting to the Cloudflare worker.
+//
+// The tenant is the user holding MCP_SECRET; their Jira credentials are read
+// from the database in DATABASE_URL (and REGIONAL_DATABASE_URLS) on every
+// tool call, so rotated API tokens are picked up without a restart:
+//
+//	MCP_SECRET=... DATABASE_URL=postgres://... mcpserver
+//
+// Stdout carries only protocol messages; logs go to stderr.
+package main
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/joho/godotenv"
+	_ "github.com/lib/pq"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/config"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/handlers"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/jira"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/mcp"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/secretbox"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/store"
+)
+
+// version is reported to clients in serverInfo.
+var version = "dev"
+
+func main() {
+	_ = godotenv.Load(
+		"../.env",
+		".env",
+	)
+	log.SetOutput(os.Stderr)
+	log.SetPrefix("mcpserver: ")
+
+	secret := strings.TrimSpace(os.Getenv("MCP_SECRET"))
+	if secret == "" {
+		log.Fatal("MCP_SECRET is required")
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("failed to load configuration: %v", err)
+	}
+
+	db, err := sql.Open("postgres", cfg.DatabaseURL)
+	if err != nil {
+		log.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(4)
+
+	st, err := store.New(db)
+	if err != nil {
+		log.Fatalf("failed to create store: %v", err)
+	}
+	box, err := secretbox.New(cfg.TokenEncryptionKeys)
+	if err != nil {
+		log.Fatalf("failed to load token encryption keys: %v", err)
+	}
+	st.SetSecretBox(box)
+	if len(cfg.RegionalDatabaseURLs) > 0 {
+		regional := make(map[string]*sql.DB, len(cfg.RegionalDatabaseURLs))
+		for region, dsn := range cfg.RegionalDatabaseURLs {
+			rdb, err := sql.Open("postgres", dsn)
+			if err != nil {
+				log.Fatalf("failed to open %s database: %v", region, err)
+			}
+			defer rdb.Close()
+			regional[region] = rdb
+		}
+		st.SetRegionalDatabases(regional)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Fail at startup rather than on the first tool call when the secret
+	// is unknown or the tenant has no Jira site.
+	var refresh jira.TokenRefresher
+	if cfg.AtlassianClientID != "" {
+		refresh = jira.NewOAuth(cfg.AtlassianClientID, cfg.AtlassianClientSecret, cfg.BackendURL+"/callback/atlassian").Refresher(st)
+	}
+	checkCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	client, err := jira.ForMCPSecret(checkCtx, st, secret, refresh)
+	cancel()
+	if err != nil {
+		log.Fatalf("failed to resolve Jira settings for MCP_SECRET: %v", err)
+	}
+	log.Printf("serving Jira tools for %s", client.BaseURL())
+
+	// Tool calls count against the tenant's tool quotas like calls through
+	// the worker do, and select field values are checked against the
+	// options synced for the tenant.
+	userID, err := st.GetUserIDByMCPSecret(ctx, secret)
+	if err != nil {
+		log.Printf("tool quotas and field options are not enforced: %v", err)
+	}
+
+	clientFor := func(ctx context.Context) (*jira.Client, error) {
+		c, err := jira.ForMCPSecret(ctx, st, secret, refresh)
+		if err != nil {
+			return nil, err
+		}
+		if userID > 0 {
+			c.WithFieldOptions(func(ctx context.Context, siteURL, projectKey string) ([]models.JiraFieldOption, error) {
+				return st.ListJiraFieldOptions(ctx, userID, siteURL, projectKey)
+			})
+		}
+		return c, nil
+	}
+	clientsFor := func(ctx context.Context) ([]*jira.Client, error) {
+		return jira.ClientsForMCPSecret(ctx, st, secret, refresh)
+	}
+	tools := append(jira.MCPTools(clientFor), jira.SiteTools(clientsFor)...)
+	server := mcp.NewServer("mcp-jira-thing", version, tools...)
+	if userID > 0 {
+		server.SetCallGuard(handlers.NewToolQuotas(st, func(context.Context) int64 { return userID }))
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- server.Serve(ctx, os.Stdin, os.Stdout) }()
+	select {
+	case err := <-done:
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+	case <-ctx.Done():
+	}
+}
@@ -14,15 +14,23 @@ import (
 	"time"
 
 	"github.com/joho/godotenv"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 
 	"github.com/PortNumber53/mcp-jira-thing/backend/internal/config"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/dbtimeout"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/events"
 	"github.com/PortNumber53/mcp-jira-thing/backend/internal/handlers"
 	"github.com/PortNumber53/mcp-jira-thing/backend/internal/httpserver"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/jira"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/mail"
 	"github.com/PortNumber53/mcp-jira-thing/backend/internal/migrations"
 	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/realtime"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/secretbox"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/slowquery"
 	"github.com/PortNumber53/mcp-jira-thing/backend/internal/store"
 	stripeClient "github.com/PortNumber53/mcp-jira-thing/backend/internal/stripe"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/warmup"
 	"github.com/PortNumber53/mcp-jira-thing/backend/internal/worker"
 )
 
@@ -39,10 +47,27 @@ func main() {
 		log.Fatalf("failed to load configuration: %v", err)
 	}
 
-	db, err := sql.Open("postgres", cfg.DatabaseURL)
+	connector, err := pq.NewConnector(cfg.DatabaseURL)
 	if err != nil {
 		log.Fatalf("failed to open database: %v", err)
 	}
+	// Statements run under a request's deadline are stopped by Postgres
+	// too once it passes. Every statement is counted per store method for
+	// /metrics.
+	queryMetrics := store.NewQueryMetrics()
+	queryInst := &store.Instrumentation{OnQuery: queryMetrics.Observe}
+	timedConnector := store.Instrument(dbtimeout.Wrap(connector), queryInst)
+
+	// Statements slower than SLOW_QUERY_THRESHOLD (default 250ms, "0"
+	// disables) are recorded with their plans for dbtool's index report.
+	var slowQueries *slowquery.Collector
+	var db *sql.DB
+	if threshold := slowQueryThreshold(); threshold > 0 {
+		slowQueries = slowquery.NewCollector(threshold, 256)
+		db = sql.OpenDB(slowQueries.Wrap(timedConnector))
+	} else {
+		db = sql.OpenDB(timedConnector)
+	}
 	defer db.Close()
 
 	logDBTarget("primary", cfg.DatabaseURL)
@@ -57,12 +82,73 @@ func main() {
 	if err := runMigrationsWithDirtyFix(db, "primary"); err != nil {
 		log.Fatalf("failed to apply database migrations: %v", err)
 	}
+	reportSchemaDrift(db)
 
 	appStore, err := store.New(db)
 	if err != nil {
 		log.Fatalf("failed to create store: %v", err)
 	}
 
+	// Jira API tokens and OAuth tokens are encrypted at rest when keys are
+	// configured.
+	secretBox, err := secretbox.New(cfg.TokenEncryptionKeys)
+	if err != nil {
+		log.Fatalf("failed to load token encryption keys: %v", err)
+	}
+	appStore.SetSecretBox(secretBox)
+	if !secretBox.Enabled() {
+		log.Println("[main] TOKEN_ENCRYPTION_KEYS not set, credentials are stored in plaintext")
+	}
+
+	// Organizations with a data region keep their data in that region's
+	// database; refuse to start if one of their regions is not configured.
+	if len(cfg.RegionalDatabaseURLs) > 0 {
+		regional := openRegionalDatabases(cfg.RegionalDatabaseURLs, 0, queryInst)
+		for _, rdb := range regional {
+			defer rdb.Close()
+		}
+		appStore.SetRegionalDatabases(regional)
+	}
+	regionCtx, regionCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	if err := appStore.CheckDataRegions(regionCtx); err != nil {
+		log.Fatalf("data residency: %v", err)
+	}
+	regionCancel()
+
+	// Tenants placed on a shard keep their request logs in its database;
+	// refuse to start if a shard they are placed on is not configured.
+	if len(cfg.ShardDatabaseURLs) > 0 {
+		shards := openRegionalDatabases(cfg.ShardDatabaseURLs, cfg.ShardMaxOpenConns, queryInst)
+		for _, sdb := range shards {
+			defer sdb.Close()
+		}
+		appStore.SetShardDatabases(shards)
+	}
+	shardCtx, shardCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	if err := appStore.CheckShards(shardCtx); err != nil {
+		log.Fatalf("sharding: %v", err)
+	}
+	shardCancel()
+
+	// In-process event bus and the hub that streams events to /ws clients.
+	// Durable events go through the transactional outbox and reach the bus
+	// via the outbox dispatcher.
+	bus := events.New()
+	hub := realtime.NewHub()
+	outbox, err := store.NewOutboxStore(db)
+	if err != nil {
+		log.Fatalf("failed to create outbox store: %v", err)
+	}
+	appStore.SetOutbox(outbox)
+	outboxDispatcher := worker.NewOutboxDispatcher(worker.DefaultOutboxConfig(), outbox, bus)
+	events.RegisterAuditLog(bus, appStore)
+	events.RegisterNotifications(bus, appStore)
+	events.RegisterBroadcast(bus, hub)
+	events.RegisterDomainCapture(bus, appStore)
+	if len(cfg.RegionalDatabaseURLs) > 0 {
+		events.RegisterDataResidency(bus, appStore)
+	}
+
 	// Initialize job store and worker
 	jobStore, err := store.NewJobStore(db)
 	if err != nil {
@@ -71,12 +157,50 @@ func main() {
 
 	// Configure and create worker
 	workerConfig := worker.DefaultConfig()
-	workerConfig.MaxConcurrent = 5
+	workerConfig.MinConcurrent = 2
+	workerConfig.MaxConcurrent = 10
 	workerConfig.PollInterval = time.Second
 
 	// Initialize worker with empty handlers (handlers registered at runtime)
 	jobWorker := worker.New(workerConfig, jobStore, worker.Handlers{})
 
+	// Injected faults exercise retry and backoff in staging; never in
+	// production.
+	if spec := os.Getenv("WORKER_FAULTS"); spec != "" {
+		if cfg.IsProduction() {
+			log.Println("[main] WORKER_FAULTS ignored in production profile")
+		} else if faults, err := worker.ParseFaultConfig(spec); err != nil {
+			log.Fatalf("invalid WORKER_FAULTS: %v", err)
+		} else {
+			jobWorker.SetFaults(faults)
+		}
+	}
+
+	jobProgressed := func(job *models.Job, status string, retryIn time.Duration) {
+		bus.Publish(context.Background(), events.JobProgressed{
+			JobID:   job.ID,
+			JobType: job.JobType,
+			UserID:  job.OwnerID(),
+			Status:  status,
+			Attempt: job.Attempts,
+			RetryIn: retryIn,
+		})
+	}
+	jobCompleted := func(job *models.Job, status models.JobStatus, duration time.Duration, err error) {
+		ev := events.JobCompleted{
+			JobID:    job.ID,
+			JobType:  job.JobType,
+			UserID:   job.OwnerID(),
+			Status:   string(status),
+			Attempt:  job.Attempts,
+			Duration: duration,
+		}
+		if err != nil {
+			ev.Error = err.Error()
+		}
+		bus.Publish(context.Background(), ev)
+	}
+
 	// Set up instrumentation hooks
 	inst := &worker.Instrumentation{
 		OnEnqueue: func(job *models.Job) {
@@ -85,15 +209,34 @@ func main() {
 		OnStart: func(job *models.Job) {
 			log.Printf("[worker] Job %d started (type: %s, attempt %d/%d)",
 				job.ID, job.JobType, job.Attempts, job.MaxAttempts)
+			jobProgressed(job, "started", 0)
 		},
 		OnComplete: func(job *models.Job, duration time.Duration) {
 			log.Printf("[worker] Job %d completed in %v", job.ID, duration)
+			jobCompleted(job, models.JobStatusCompleted, duration, nil)
 		},
 		OnFail: func(job *models.Job, err error, duration time.Duration) {
 			log.Printf("[worker] Job %d failed after %v: %v", job.ID, duration, err)
+			jobCompleted(job, models.JobStatusFailed, duration, err)
 		},
 		OnRetry: func(job *models.Job, delay time.Duration) {
 			log.Printf("[worker] Job %d scheduled for retry in %v", job.ID, delay)
+			jobProgressed(job, "retrying", delay)
+		},
+		OnCancel: func(job *models.Job) {
+			log.Printf("[worker] Job %d cancelled", job.ID)
+			jobCompleted(job, models.JobStatusCancelled, 0, nil)
+		},
+		OnProgress: func(job *models.Job, done, total int) {
+			bus.Publish(context.Background(), events.JobProgressed{
+				JobID:   job.ID,
+				JobType: job.JobType,
+				UserID:  job.OwnerID(),
+				Status:  "progress",
+				Attempt: job.Attempts,
+				Done:    done,
+				Total:   total,
+			})
 		},
 		OnHeartbeat: func(workerID string, stats worker.Stats) {
 			log.Printf("[worker] Heartbeat from %s: processed=%d, succeeded=%d, failed=%d, active=%d",
@@ -101,6 +244,31 @@ func main() {
 		},
 	}
 	jobWorker.SetInstrumentation(inst)
+	worker.RegisterDomainJobs(jobWorker, appStore)
+	worker.RegisterShardJobs(jobWorker, appStore)
+	worker.RegisterSecretJobs(jobWorker, appStore)
+	if secretBox.Enabled() {
+		// Encrypt rows written before encryption was enabled or under a
+		// key that has since been rotated out.
+		if err := jobWorker.Enqueue(context.Background(), worker.EncryptStoredSecretsJob()); err != nil {
+			log.Printf("[main] Failed to queue stored credential encryption: %v", err)
+		}
+	}
+
+	// Verification links need an SMTP relay in production; other profiles
+	// log them instead.
+	var mailer mail.Mailer
+	switch {
+	case cfg.SMTPAddr != "":
+		mailer = &mail.SMTPMailer{Addr: cfg.SMTPAddr, Username: cfg.SMTPUsername, Password: cfg.SMTPPassword, From: cfg.MailFrom}
+	case !cfg.IsProduction():
+		mailer = mail.LogMailer{}
+	default:
+		log.Println("[main] SMTP_ADDR not set, email verification links will not be sent")
+	}
+	worker.RegisterEmailJobs(jobWorker, appStore, mailer, cfg.BackendURL+"/api/auth/verify-email")
+	worker.RegisterDigestJobs(jobWorker, appStore, mailer)
+	events.RegisterAdminAlerts(bus, mailer, cfg.AdminEmails)
 
 	// Initialize plan store and Stripe integration
 	planStore, err := store.NewPlanStore(db)
@@ -108,12 +276,32 @@ func main() {
 		log.Fatalf("failed to create plan store: %v", err)
 	}
 
-	var stripeHandler *handlers.StripeHandler
+	var (
+		stripeHandler *handlers.StripeHandler
+		sc            *stripeClient.Client
+	)
 	stripeKey := os.Getenv("STRIPE_SECRET_KEY")
 	stripeWebhookSecret := os.Getenv("STRIPE_WEBHOOK_SECRET")
 	if stripeKey != "" {
-		sc := stripeClient.NewClient(stripeKey)
-		stripeHandler = handlers.NewStripeHandler(planStore, appStore, appStore, appStore, sc, stripeWebhookSecret)
+		sc = stripeClient.NewClient(stripeKey)
+		stripeHandler = handlers.NewStripeHandler(planStore, appStore, appStore, appStore, appStore, sc, stripeWebhookSecret)
+		stripeHandler.Events = outbox
+		stripeHandler.Disputes = appStore
+		// Set DISPUTE_AUTO_SUSPEND=true to suspend accounts while one of
+		// their charges is disputed.
+		stripeHandler.SuspendOnDispute = os.Getenv("DISPUTE_AUTO_SUSPEND") == "true"
+		events.RegisterSeatBilling(bus, appStore, sc)
+
+		// Outside production, give each new customer its own test clock so
+		// billing lifecycles can be driven end to end.
+		if !cfg.IsProduction() {
+			if sc.IsTestMode() {
+				stripeHandler.TestClocks = appStore
+				log.Printf("[main] Stripe test clocks enabled for %s profile", cfg.Environment)
+			} else {
+				log.Printf("[main] STRIPE_SECRET_KEY is not a test mode key, Stripe test clocks disabled")
+			}
+		}
 
 		// Register billing worker jobs
 		worker.RegisterBillingJobs(jobWorker, planStore, sc)
@@ -122,21 +310,121 @@ func main() {
 		log.Println("[main] STRIPE_SECRET_KEY not set, Stripe integration disabled")
 	}
 
-	srv := httpserver.New(cfg, db, appStore, appStore, appStore, appStore, appStore, jobWorker, jobStore, stripeHandler)
+	usageRollup := worker.NewUsageRollup(worker.DefaultRollupConfig(), appStore)
+
+	// Abuse detection flags credential stuffing and request spikes; set
+	// ABUSE_AUTO_LIMIT=false to only record findings without limiting IPs.
+	abuseConfig := worker.DefaultAbuseConfig()
+	abuseConfig.AutoLimit = os.Getenv("ABUSE_AUTO_LIMIT") != "false"
+	abuseDetector := worker.NewAbuseDetector(abuseConfig, appStore, bus)
+
+	// Weekly usage digests are queued once each UTC week ends.
+	digestScheduler := worker.NewDigestScheduler(worker.DefaultDigestConfig(), appStore, jobWorker)
+
+	// Deleted Jira settings and jobs are purged once their grace window ends.
+	softDeletePurger := worker.NewSoftDeletePurger(worker.DefaultSoftDeleteConfig(), appStore, jobStore)
+
+	// Recurring jobs provisioned through the admin API are queued as they
+	// come due.
+	recurringJobs := worker.NewRecurringJobScheduler(worker.DefaultRecurringConfig(), appStore, jobWorker)
+
+	// Tenant-defined Jira automations are queued as they come due.
+	recurringJobs.Builtin(worker.AutomationScheduleJob())
+
+	// Recurring reports are rendered as they come due.
+	recurringJobs.Builtin(worker.ReportScheduleJob())
+
+	// Select field options of the projects tenants use are synced from Jira
+	// so the issue tools can check option values.
+	recurringJobs.Builtin(worker.FieldOptionSyncAllJob())
+
+	leaderTasks := []worker.LeaderTask{usageRollup, abuseDetector, digestScheduler, softDeletePurger, recurringJobs}
+
+	// Atlassian OAuth access tokens are refreshed before they expire.
+	var (
+		jiraOAuth   *jira.OAuth
+		jiraRefresh jira.TokenRefresher
+	)
+	if cfg.AtlassianClientID != "" {
+		jiraOAuth = jira.NewOAuth(cfg.AtlassianClientID, cfg.AtlassianClientSecret, cfg.BackendURL+"/callback/atlassian")
+		jiraRefresh = jiraOAuth.Refresher(appStore)
+		recurringJobs.Builtin(worker.JiraTokenRefreshJob())
+	}
+	worker.RegisterJiraOAuthJobs(jobWorker, appStore, jiraOAuth)
+	worker.RegisterAutomationJobs(jobWorker, appStore, mailer, jiraRefresh)
+	worker.RegisterReportJobs(jobWorker, appStore, mailer, jiraRefresh)
+	worker.RegisterFieldOptionJobs(jobWorker, appStore, jiraRefresh)
+	worker.RegisterBackfillJobs(jobWorker, appStore, jiraRefresh)
+	worker.RegisterCommentSyncJobs(jobWorker, appStore, bus, mailer, cfg.CommentReplyAddress, jiraRefresh)
+
+	// With several replicas only the instance holding the leader lock runs
+	// the recurring scans; the others take over if it dies.
+	leaderElector := worker.NewLeaderElector(worker.DefaultLeaderConfig(), db, leaderTasks...)
+
+	var slowQueryRecorder *worker.SlowQueryRecorder
+	if slowQueries != nil {
+		slowQueryRecorder = worker.NewSlowQueryRecorder(worker.DefaultSlowQueryConfig(), slowQueries, appStore)
+	}
+
+	srv := httpserver.New(cfg, db, appStore, appStore, appStore, appStore, appStore, jobWorker, jobStore, stripeHandler, hub, bus, queryMetrics)
 
 	shutdownCtx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 
 	go func() {
 		<-shutdownCtx.Done()
-		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		// Leave room for the worker and dispatchers after the drain.
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownDrainTimeout+10*time.Second)
 		defer cancel()
 
 		if err := srv.Shutdown(ctx); err != nil {
 			log.Printf("graceful shutdown failed: %v", err)
 		}
+		if err := outboxDispatcher.Stop(ctx); err != nil {
+			log.Printf("outbox dispatcher shutdown failed: %v", err)
+		}
+		if err := leaderElector.Stop(ctx); err != nil {
+			log.Printf("leader election shutdown failed: %v", err)
+		}
+		if err := usageRollup.Stop(ctx); err != nil {
+			log.Printf("usage rollup shutdown failed: %v", err)
+		}
+		if err := abuseDetector.Stop(ctx); err != nil {
+			log.Printf("abuse detector shutdown failed: %v", err)
+		}
+		if err := digestScheduler.Stop(ctx); err != nil {
+			log.Printf("digest scheduler shutdown failed: %v", err)
+		}
+		if err := softDeletePurger.Stop(ctx); err != nil {
+			log.Printf("soft delete purger shutdown failed: %v", err)
+		}
+		if err := recurringJobs.Stop(ctx); err != nil {
+			log.Printf("recurring job scheduler shutdown failed: %v", err)
+		}
+		if slowQueryRecorder != nil {
+			if err := slowQueryRecorder.Stop(ctx); err != nil {
+				log.Printf("slow query recorder shutdown failed: %v", err)
+			}
+		}
+		if err := bus.Close(ctx); err != nil {
+			log.Printf("event bus did not drain: %v", err)
+		}
 	}()
 
+	// Warm up before taking traffic: fill the connection pools, read the
+	// plan and feature flag tables once and check the Stripe key.
+	warmupCtx, warmupCancel := context.WithTimeout(context.Background(), warmup.DefaultTimeout)
+	if err := warmup.Run(warmupCtx, warmupChecks(cfg, db, appStore, planStore, sc)...); err != nil {
+		log.Fatalf("startup warm-up failed: %v", err)
+	}
+	warmupCancel()
+
+	outboxDispatcher.Start(context.Background())
+	leaderElector.Start(context.Background())
+	if slowQueryRecorder != nil {
+		slowQueryRecorder.Start(context.Background())
+	}
+
 	log.Printf("backend starting on %s", cfg.ServerAddress)
 	if err := srv.Start(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 		log.Printf("server exited with error: %v", err)
@@ -144,6 +432,66 @@ func main() {
 	}
 }
 
+// warmupChecks lists the startup warm-up steps. Connections are opened up
+// to each pool's idle limit so they stay open for the first requests.
+func warmupChecks(cfg config.Config, db *sql.DB, appStore *store.Store, planStore *store.PlanStore, sc *stripeClient.Client) []warmup.Check {
+	checks := []warmup.Check{
+		warmup.Connections("primary database", db, 5),
+		{Name: "plans", Run: func(ctx context.Context) error {
+			_, err := planStore.ListPlans(ctx)
+			return err
+		}},
+		{Name: "feature flags", Run: func(ctx context.Context) error {
+			_, err := appStore.ListFeatureFlags(ctx)
+			return err
+		}},
+	}
+	for region, rdb := range appStore.RegionalDatabases() {
+		checks = append(checks, warmup.Connections(region+" database", rdb, 5))
+	}
+	for shard, sdb := range appStore.ShardDatabases() {
+		checks = append(checks, warmup.Connections("shard "+shard+" database", sdb, cfg.ShardMaxOpenConns))
+	}
+	if sc != nil {
+		// Only a rejected key stops startup; Stripe being unreachable
+		// should not block a deploy.
+		checks = append(checks, warmup.Check{Name: "stripe", Required: true, Run: func(ctx context.Context) error {
+			verified := make(chan error, 1)
+			go func() { verified <- sc.VerifyKey() }()
+			var err error
+			select {
+			case err = <-verified:
+			case <-ctx.Done():
+				err = ctx.Err()
+			}
+			if err != nil && !errors.Is(err, stripeClient.ErrInvalidKey) {
+				log.Printf("[warmup] stripe key not verified: %v", err)
+				return nil
+			}
+			return err
+		}})
+	}
+	return checks
+}
+
+// slowQueryThreshold parses SLOW_QUERY_THRESHOLD, returning 0 when slow
+// query recording is disabled.
+func slowQueryThreshold() time.Duration {
+	value := os.Getenv("SLOW_QUERY_THRESHOLD")
+	if value == "" {
+		return 250 * time.Millisecond
+	}
+	if value == "0" {
+		return 0
+	}
+	threshold, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("[main] invalid SLOW_QUERY_THRESHOLD %q, slow query recording disabled: %v", value, err)
+		return 0
+	}
+	return threshold
+}
+
 func configureDB(db *sql.DB) {
 	db.SetConnMaxLifetime(30 * time.Minute)
 	db.SetMaxOpenConns(10)
@@ -169,6 +517,60 @@ func runMigrationsWithDirtyFix(db *sql.DB, name string) error {
 	return nil
 }
 
+// reportSchemaDrift logs columns, indexes and tables that differ from what
+// the migrations create, typically left behind by manual hotfixes. Drift is
+// reported, not fatal.
+func reportSchemaDrift(db *sql.DB) {
+	// Replaying migrations is expected to be slow; keep it out of the slow
+	// query log.
+	ctx, cancel := context.WithTimeout(slowquery.WithoutRecording(context.Background()), 30*time.Second)
+	defer cancel()
+
+	report, err := migrations.CheckDrift(ctx, db)
+	if err != nil {
+		log.Printf("migrations: schema drift check skipped: %v", err)
+		return
+	}
+	if report.Empty() {
+		log.Printf("migrations: schema matches migrations at version %d", report.Version)
+		return
+	}
+	for _, line := range report.Lines() {
+		log.Printf("migrations: schema drift at version %d: %s", report.Version, line)
+	}
+}
+
+// openRegionalDatabases connects to and migrates each regional (or shard)
+// database. A positive maxOpenConns overrides the default pool size.
+func openRegionalDatabases(urls map[string]string, maxOpenConns int, inst *store.Instrumentation) map[string]*sql.DB {
+	regional := make(map[string]*sql.DB, len(urls))
+	for region, dsn := range urls {
+		connector, err := pq.NewConnector(dsn)
+		if err != nil {
+			log.Fatalf("failed to open %s database: %v", region, err)
+		}
+		rdb := sql.OpenDB(store.Instrument(dbtimeout.Wrap(connector), inst))
+		logDBTarget(region, dsn)
+		configureDB(rdb)
+		if maxOpenConns > 0 {
+			rdb.SetMaxOpenConns(maxOpenConns)
+			rdb.SetMaxIdleConns(maxOpenConns)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		err = rdb.PingContext(ctx)
+		cancel()
+		if err != nil {
+			log.Fatalf("failed to ping %s database: %v", region, err)
+		}
+		if err := migrations.UpRegional(rdb); err != nil {
+			log.Fatalf("failed to apply %s database migrations: %v", region, err)
+		}
+		regional[region] = rdb
+	}
+	return regional
+}
+
 func logDBTarget(name, dsn string) {
 	// Avoid logging secrets: only log hostname + database path.
 	u, err := url.Parse(dsn)
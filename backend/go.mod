@@ -11,10 +11,17 @@ require (
 require (
 	github.com/hashicorp/errwrap v1.1.0 // indirect
 	github.com/hashicorp/go-multierror v1.1.1 // indirect
-	github.com/mattn/go-sqlite3 v1.14.32 // indirect
+	github.com/inconshreveable/mousetrap v1.1.0 // indirect
+	github.com/jonboulle/clockwork v0.2.2 // indirect
+	github.com/spf13/pflag v1.0.9 // indirect
 )
 
 require (
 	github.com/DATA-DOG/go-sqlmock v1.5.2
+	github.com/beevik/etree v1.1.0
+	github.com/gorilla/websocket v1.5.3
 	github.com/lib/pq v1.10.9
+	github.com/mattn/go-sqlite3 v1.14.32
+	github.com/russellhaering/goxmldsig v1.4.0
+	github.com/spf13/cobra v1.10.2
 )
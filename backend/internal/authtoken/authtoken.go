@@ -0,0 +1,143 @@
+// Package authtoken issues and verifies the short-lived HS256 JWTs the
+// frontend Worker presents instead of passing the user's email as identity.
+package authtoken
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/keyring"
+)
+
+// TTL is how long an issued token stays valid.
+const TTL = 5 * time.Minute
+
+// Issuer is the iss claim of every token.
+const Issuer = "mcp-jira-thing"
+
+// ErrInvalid is returned for malformed, forged or expired tokens.
+var ErrInvalid = errors.New("authtoken: invalid token")
+
+// Claims identify the user and carry the entitlements the backend decided
+// when the token was issued.
+type Claims struct {
+	Issuer    string `json:"iss"`
+	Subject   string `json:"sub"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+
+	UserID    int64  `json:"uid"`
+	Email     string `json:"email"`
+	SessionID string `json:"sid,omitempty"`
+
+	EmailVerified bool   `json:"email_verified"`
+	Plan          string `json:"plan"`
+	PlanTier      int    `json:"plan_tier"`
+}
+
+// header is the JOSE header; kid names the keyring key the token is signed
+// with.
+type header struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ"`
+	Kid string `json:"kid,omitempty"`
+}
+
+// Issue signs claims with the keyring's primary key as a JWT valid for TTL
+// from now, filling in the registered claims.
+func Issue(keys *keyring.Keyring, claims Claims, now time.Time) (string, error) {
+	key, ok := keys.Primary()
+	if !ok {
+		return "", errors.New("authtoken: signing key is required")
+	}
+	claims.Issuer = Issuer
+	claims.Subject = strconv.FormatInt(claims.UserID, 10)
+	claims.IssuedAt = now.Unix()
+	claims.ExpiresAt = now.Add(TTL).Unix()
+
+	head, err := json.Marshal(header{Alg: "HS256", Typ: "JWT", Kid: key.ID})
+	if err != nil {
+		return "", fmt.Errorf("authtoken: marshal header: %w", err)
+	}
+	body, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("authtoken: marshal claims: %w", err)
+	}
+	signingInput := base64.RawURLEncoding.EncodeToString(head) + "." + base64.RawURLEncoding.EncodeToString(body)
+	return signingInput + "." + sign(key.Secret, signingInput), nil
+}
+
+// Verify checks the token's signature against the keyring key named by its
+// kid, and its issuer and expiry, and returns its claims. Tokens without a
+// kid are checked against keyring.DefaultID.
+func Verify(keys *keyring.Keyring, token string, now time.Time) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrInvalid
+	}
+	rawHeader, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, ErrInvalid
+	}
+	var head header
+	if err := json.Unmarshal(rawHeader, &head); err != nil || head.Alg != "HS256" {
+		return nil, ErrInvalid
+	}
+	if head.Kid == "" {
+		head.Kid = keyring.DefaultID
+	}
+	key, ok := keys.Lookup(head.Kid)
+	if !ok {
+		return nil, ErrInvalid
+	}
+	if !hmac.Equal([]byte(sign(key.Secret, parts[0]+"."+parts[1])), []byte(parts[2])) {
+		return nil, ErrInvalid
+	}
+
+	body, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, ErrInvalid
+	}
+	var claims Claims
+	if err := json.Unmarshal(body, &claims); err != nil {
+		return nil, ErrInvalid
+	}
+	if claims.Issuer != Issuer || claims.Email == "" || now.Unix() >= claims.ExpiresAt {
+		return nil, ErrInvalid
+	}
+	return &claims, nil
+}
+
+// LooksLikeJWT reports whether a bearer credential has the three-segment
+// shape of a JWT, to tell these tokens apart from other bearer secrets such
+// as SCIM tokens.
+func LooksLikeJWT(token string) bool {
+	return strings.Count(token, ".") == 2
+}
+
+func sign(secret []byte, signingInput string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(signingInput))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+type contextKey struct{}
+
+// WithClaims returns a context carrying verified claims.
+func WithClaims(ctx context.Context, claims *Claims) context.Context {
+	return context.WithValue(ctx, contextKey{}, claims)
+}
+
+// FromContext returns the verified claims of the request, if any.
+func FromContext(ctx context.Context) (*Claims, bool) {
+	claims, ok := ctx.Value(contextKey{}).(*Claims)
+	return claims, ok && claims != nil
+}
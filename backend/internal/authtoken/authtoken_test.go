@@ -0,0 +1,92 @@
+package authtoken
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/keyring"
+)
+
+func testKeys(t *testing.T, keys ...keyring.Key) *keyring.Keyring {
+	t.Helper()
+	k, err := keyring.New(keys...)
+	if err != nil {
+		t.Fatalf("keyring: %v", err)
+	}
+	return k
+}
+
+func TestIssueVerifyRoundTrip(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	keys := keyring.Single("secret")
+	token, err := Issue(keys, Claims{UserID: 42, Email: "user@example.com", SessionID: "sid", Plan: "pro", PlanTier: 2}, now)
+	if err != nil {
+		t.Fatalf("issue: %v", err)
+	}
+	if !LooksLikeJWT(token) {
+		t.Fatalf("expected JWT shape, got %q", token)
+	}
+
+	claims, err := Verify(keys, token, now.Add(time.Minute))
+	if err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	if claims.UserID != 42 || claims.Subject != "42" || claims.Email != "user@example.com" || claims.SessionID != "sid" || claims.Plan != "pro" {
+		t.Fatalf("unexpected claims: %+v", claims)
+	}
+}
+
+func TestVerifyRejectsForgedAndExpiredTokens(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	keys := keyring.Single("secret")
+	token, err := Issue(keys, Claims{UserID: 42, Email: "user@example.com"}, now)
+	if err != nil {
+		t.Fatalf("issue: %v", err)
+	}
+	parts := strings.Split(token, ".")
+	forged, _ := Issue(keys, Claims{UserID: 7, Email: "other@example.com"}, now)
+	tampered := parts[0] + "." + strings.Split(forged, ".")[1] + "." + parts[2]
+
+	cases := map[string]struct {
+		keys  *keyring.Keyring
+		token string
+		at    time.Time
+	}{
+		"wrong secret": {keyring.Single("other"), token, now},
+		"tampered":     {keys, tampered, now},
+		"expired":      {keys, token, now.Add(TTL)},
+		"malformed":    {keys, "not-a-token", now},
+	}
+	for name, tc := range cases {
+		if _, err := Verify(tc.keys, tc.token, tc.at); err != ErrInvalid {
+			t.Fatalf("%s: expected ErrInvalid, got %v", name, err)
+		}
+	}
+}
+
+func TestVerifyAcceptsRotatedKeys(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	oldKey := keyring.Key{ID: "2024", Secret: []byte("old-secret")}
+	newKey := keyring.Key{ID: "2025", Secret: []byte("new-secret")}
+
+	oldToken, err := Issue(testKeys(t, oldKey), Claims{UserID: 42, Email: "user@example.com"}, now)
+	if err != nil {
+		t.Fatalf("issue: %v", err)
+	}
+
+	rotated := testKeys(t, newKey, oldKey)
+	if _, err := Verify(rotated, oldToken, now); err != nil {
+		t.Fatalf("expected token signed with the previous key to verify: %v", err)
+	}
+	newToken, err := Issue(rotated, Claims{UserID: 42, Email: "user@example.com"}, now)
+	if err != nil {
+		t.Fatalf("issue: %v", err)
+	}
+	if _, err := Verify(testKeys(t, oldKey), newToken, now); err != ErrInvalid {
+		t.Fatalf("expected keyring without the new key to reject its tokens, got %v", err)
+	}
+	if _, err := Verify(testKeys(t, newKey), oldToken, now); err != ErrInvalid {
+		t.Fatalf("expected retired key to be rejected, got %v", err)
+	}
+}
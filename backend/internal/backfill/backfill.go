@@ -0,0 +1,306 @@
+// Package backfill fetches every issue matching a JQL search from Jira. It
+// pages through the matching issue IDs, fetches their fields in parallel
+// batches, slows down as the site's rate limit runs low and checkpoints
+// after every page so an interrupted run resumes where it stopped.
+package backfill
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/jira"
+)
+
+// Defaults used for unset Config fields.
+const (
+	DefaultConcurrency = 4
+	DefaultPageSize    = 500
+)
+
+// maxRateLimitedAttempts is how often a request rejected for exceeding the
+// rate limit is sent before the run fails.
+const maxRateLimitedAttempts = 5
+
+// Source is the Jira API a backfill reads from; *jira.Client implements it.
+type Source interface {
+	Search(ctx context.Context, req jira.SearchRequest) (*jira.SearchResult, error)
+	BulkFetchIssues(ctx context.Context, idsOrKeys, fields []string) ([]jira.Issue, error)
+}
+
+// Sink receives a backfill's issues and checkpoints. StoreIssues is called
+// from several goroutines at once.
+type Sink interface {
+	StoreIssues(ctx context.Context, issues []jira.Issue) error
+	SaveCheckpoint(ctx context.Context, cp Checkpoint) error
+}
+
+// Checkpoint is how far a backfill got: every issue before PageToken has
+// been stored. A run resumed from a checkpoint fetches the page at
+// PageToken again, so StoreIssues must accept issues it already stored.
+type Checkpoint struct {
+	// PageToken is the search page to continue from; empty is the first.
+	PageToken string
+	// Fetched counts the issues stored so far.
+	Fetched int
+	// Done is set once the last page is stored.
+	Done bool
+}
+
+// Config tunes an Engine.
+type Config struct {
+	// Concurrency is the number of bulk fetches in flight.
+	Concurrency int
+	// PageSize is the number of issue IDs read per search page.
+	PageSize int
+	// Fields are the issue fields fetched; nil fetches Jira's default.
+	Fields []string
+}
+
+// Engine runs backfills, sharing one Throttle between its requests.
+type Engine struct {
+	config   Config
+	throttle *Throttle
+}
+
+// New returns an Engine. Attach its Observe method to the Source's Jira
+// client with WithRateObserver so the engine sees the rate limit headers.
+func New(config Config) *Engine {
+	if config.Concurrency <= 0 {
+		config.Concurrency = DefaultConcurrency
+	}
+	if config.PageSize <= 0 {
+		config.PageSize = DefaultPageSize
+	}
+	return &Engine{config: config, throttle: NewThrottle()}
+}
+
+// Observe adapts the engine's pace to a Jira response's rate limit.
+func (e *Engine) Observe(rl jira.RateLimit) {
+	e.throttle.Observe(rl)
+}
+
+// Run backfills the issues matching jql from src into sink, starting at cp.
+// It returns the last checkpoint it saved, which is Done when every page
+// was stored.
+func (e *Engine) Run(ctx context.Context, src Source, jql string, cp Checkpoint, sink Sink) (Checkpoint, error) {
+	for !cp.Done {
+		var page *jira.SearchResult
+		err := e.retry(ctx, func() error {
+			var err error
+			page, err = src.Search(ctx, jira.SearchRequest{JQL: jql, MaxResults: e.config.PageSize, NextPageToken: cp.PageToken})
+			return err
+		})
+		if err != nil {
+			return cp, fmt.Errorf("backfill: search: %w", err)
+		}
+
+		ids := make([]string, 0, len(page.Issues))
+		for _, issue := range page.Issues {
+			ids = append(ids, issue.ID)
+		}
+		stored, err := e.fetch(ctx, src, ids, sink)
+		if err != nil {
+			return cp, err
+		}
+
+		next := Checkpoint{PageToken: page.NextPageToken, Fetched: cp.Fetched + stored}
+		if page.IsLast || page.NextPageToken == "" {
+			next = Checkpoint{Fetched: next.Fetched, Done: true}
+		}
+		if err := sink.SaveCheckpoint(ctx, next); err != nil {
+			return cp, fmt.Errorf("backfill: save checkpoint: %w", err)
+		}
+		cp = next
+	}
+	return cp, nil
+}
+
+// fetch fetches and stores the issues in batches, Concurrency at a time,
+// and returns how many were stored. The first error stops the batches that
+// have not started yet.
+func (e *Engine) fetch(ctx context.Context, src Source, ids []string, sink Sink) (int, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		stored   atomic.Int64
+		errOnce  sync.Once
+		firstErr error
+	)
+	fail := func(err error) {
+		errOnce.Do(func() {
+			firstErr = err
+			cancel()
+		})
+	}
+
+	slots := make(chan struct{}, e.config.Concurrency)
+	for start := 0; start < len(ids); start += jira.MaxBulkFetchIssues {
+		batch := ids[start:min(start+jira.MaxBulkFetchIssues, len(ids))]
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			var issues []jira.Issue
+			err := e.retry(ctx, func() error {
+				var err error
+				issues, err = src.BulkFetchIssues(ctx, batch, e.config.Fields)
+				return err
+			})
+			if err != nil {
+				fail(fmt.Errorf("backfill: fetch issues: %w", err))
+				return
+			}
+			if err := sink.StoreIssues(ctx, issues); err != nil {
+				fail(fmt.Errorf("backfill: store issues: %w", err))
+				return
+			}
+			stored.Add(int64(len(issues)))
+		}()
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return 0, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	return int(stored.Load()), nil
+}
+
+// retry sends a request when the throttle allows, sending it again while
+// Jira rejects it for exceeding the rate limit.
+func (e *Engine) retry(ctx context.Context, send func() error) error {
+	var err error
+	for attempt := 0; attempt < maxRateLimitedAttempts; attempt++ {
+		if err := e.throttle.Wait(ctx); err != nil {
+			return err
+		}
+		if err = send(); !isRateLimited(err) {
+			return err
+		}
+	}
+	return err
+}
+
+func isRateLimited(err error) bool {
+	var apiErr *jira.APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusTooManyRequests
+}
+
+// Throttle spaces requests out, widening the gap as the rate limit runs low
+// and pausing when Jira asks to retry later. It is safe for concurrent use.
+type Throttle struct {
+	mu sync.Mutex
+	// interval is the gap kept between request starts.
+	interval time.Duration
+	// next is the earliest time the next request may start.
+	next time.Time
+	now  func() time.Time
+}
+
+const (
+	// minInterval is the gap the throttle starts slowing down from; a
+	// narrower gap is dropped altogether.
+	minInterval = 50 * time.Millisecond
+	// maxInterval caps the gap between requests.
+	maxInterval = 10 * time.Second
+	// defaultPause is how long a rejected request pauses requests when Jira
+	// gives no hint.
+	defaultPause = 5 * time.Second
+)
+
+// NewThrottle returns a Throttle that does not hold requests back until it
+// observes a low rate limit.
+func NewThrottle() *Throttle {
+	return &Throttle{now: time.Now}
+}
+
+// Wait blocks until the next request may start, or ctx is done.
+func (t *Throttle) Wait(ctx context.Context) error {
+	t.mu.Lock()
+	now := t.now()
+	at := t.next
+	if at.Before(now) {
+		at = now
+	}
+	t.next = at.Add(t.interval)
+	t.mu.Unlock()
+
+	delay := at.Sub(now)
+	if delay <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Observe adapts the pace to a response's rate limit: a rejected request
+// pauses requests for the Retry-After time and doubles the gap, a low
+// remaining budget doubles the gap, and anything else narrows it by a
+// quarter.
+func (t *Throttle) Observe(rl jira.RateLimit) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := t.now()
+
+	low := rl.NearLimit || rl.Limit > 0 && rl.Remaining >= 0 && rl.Remaining*5 < rl.Limit
+	switch {
+	case rl.Limited():
+		pause := rl.RetryAfter
+		if pause <= 0 && rl.Reset.After(now) {
+			pause = rl.Reset.Sub(now)
+		}
+		if pause <= 0 {
+			pause = defaultPause
+		}
+		if resume := now.Add(pause); resume.After(t.next) {
+			t.next = resume
+		}
+		t.slowDown()
+	case low:
+		if rl.RetryAfter > 0 {
+			if resume := now.Add(rl.RetryAfter); resume.After(t.next) {
+				t.next = resume
+			}
+		}
+		t.slowDown()
+	default:
+		t.interval -= t.interval / 4
+		if t.interval < minInterval {
+			t.interval = 0
+		}
+	}
+}
+
+// Interval returns the current gap between requests.
+func (t *Throttle) Interval() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.interval
+}
+
+func (t *Throttle) slowDown() {
+	t.interval = min(max(2*t.interval, minInterval), maxInterval)
+}
@@ -0,0 +1,173 @@
+package backfill
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/jira"
+)
+
+// fakeSource serves issues 1..total in pages chained by the next index.
+type fakeSource struct {
+	total      int
+	limitedOne atomic.Bool
+	inFlight   atomic.Int32
+	maxFlight  atomic.Int32
+	searches   []string
+	mu         sync.Mutex
+}
+
+func (f *fakeSource) Search(ctx context.Context, req jira.SearchRequest) (*jira.SearchResult, error) {
+	f.mu.Lock()
+	f.searches = append(f.searches, req.NextPageToken)
+	f.mu.Unlock()
+
+	start := 0
+	if req.NextPageToken != "" {
+		start, _ = strconv.Atoi(req.NextPageToken)
+	}
+	end := min(start+req.MaxResults, f.total)
+	result := &jira.SearchResult{IsLast: end == f.total}
+	for i := start; i < end; i++ {
+		result.Issues = append(result.Issues, jira.Issue{ID: strconv.Itoa(i + 1)})
+	}
+	if !result.IsLast {
+		result.NextPageToken = strconv.Itoa(end)
+	}
+	return result, nil
+}
+
+func (f *fakeSource) BulkFetchIssues(ctx context.Context, ids, fields []string) ([]jira.Issue, error) {
+	n := f.inFlight.Add(1)
+	defer f.inFlight.Add(-1)
+	for {
+		peak := f.maxFlight.Load()
+		if n <= peak || f.maxFlight.CompareAndSwap(peak, n) {
+			break
+		}
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	if f.limitedOne.CompareAndSwap(false, true) {
+		return nil, &jira.APIError{StatusCode: http.StatusTooManyRequests}
+	}
+	issues := make([]jira.Issue, len(ids))
+	for i, id := range ids {
+		issues[i] = jira.Issue{ID: id, Key: "OPS-" + id}
+	}
+	return issues, nil
+}
+
+type recordingSink struct {
+	mu          sync.Mutex
+	stored      map[string]int
+	checkpoints []Checkpoint
+	failAfter   int
+}
+
+func (s *recordingSink) StoreIssues(ctx context.Context, issues []jira.Issue) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, issue := range issues {
+		s.stored[issue.ID]++
+	}
+	return nil
+}
+
+func (s *recordingSink) SaveCheckpoint(ctx context.Context, cp Checkpoint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failAfter > 0 && len(s.checkpoints) == s.failAfter {
+		return fmt.Errorf("database unavailable")
+	}
+	s.checkpoints = append(s.checkpoints, cp)
+	return nil
+}
+
+func TestRunFetchesEveryPageWithBoundedParallelism(t *testing.T) {
+	src := &fakeSource{total: 1050}
+	sink := &recordingSink{stored: map[string]int{}}
+	engine := New(Config{Concurrency: 3, PageSize: 500})
+
+	cp, err := engine.Run(context.Background(), src, "project = OPS", Checkpoint{}, sink)
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if !cp.Done || cp.Fetched != 1050 || len(sink.stored) != 1050 {
+		t.Fatalf("expected all 1050 issues fetched, got checkpoint %+v and %d stored", cp, len(sink.stored))
+	}
+	if len(sink.checkpoints) != 3 || sink.checkpoints[0].PageToken != "500" || sink.checkpoints[1].Fetched != 1000 {
+		t.Fatalf("expected a checkpoint per page, got %+v", sink.checkpoints)
+	}
+	if peak := src.maxFlight.Load(); peak > 3 || peak < 2 {
+		t.Fatalf("expected up to 3 bulk fetches in flight, peaked at %d", peak)
+	}
+}
+
+func TestRunResumesFromCheckpoint(t *testing.T) {
+	src := &fakeSource{total: 300}
+	sink := &recordingSink{stored: map[string]int{}, failAfter: 1}
+	engine := New(Config{PageSize: 100})
+
+	cp, err := engine.Run(context.Background(), src, "project = OPS", Checkpoint{}, sink)
+	if err == nil {
+		t.Fatal("expected the failed checkpoint to stop the run")
+	}
+	if cp.PageToken != "100" || cp.Fetched != 100 {
+		t.Fatalf("expected to stop after the first page, got %+v", cp)
+	}
+
+	sink.failAfter = 0
+	cp, err = engine.Run(context.Background(), src, "project = OPS", cp, sink)
+	if err != nil {
+		t.Fatalf("resumed Run returned error: %v", err)
+	}
+	if !cp.Done || cp.Fetched != 300 {
+		t.Fatalf("expected the resumed run to finish with 300 issues, got %+v", cp)
+	}
+	want := []string{"", "100", "100", "200"}
+	if fmt.Sprint(src.searches) != fmt.Sprint(want) {
+		t.Fatalf("expected searches from %v, got %v", want, src.searches)
+	}
+	if sink.stored["150"] != 2 || sink.stored["250"] != 1 {
+		t.Fatalf("expected only the unsaved page to be fetched again, got %d and %d", sink.stored["150"], sink.stored["250"])
+	}
+}
+
+func TestThrottleAdaptsToRateLimit(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	throttle := NewThrottle()
+	throttle.now = func() time.Time { return now }
+
+	throttle.Observe(jira.RateLimit{StatusCode: http.StatusOK, Limit: 100, Remaining: 90})
+	if throttle.Interval() != 0 {
+		t.Fatalf("expected no gap with budget left, got %v", throttle.Interval())
+	}
+
+	throttle.Observe(jira.RateLimit{StatusCode: http.StatusOK, Limit: 100, Remaining: 10})
+	throttle.Observe(jira.RateLimit{StatusCode: http.StatusOK, Limit: -1, Remaining: -1, NearLimit: true})
+	if got := throttle.Interval(); got != 2*minInterval {
+		t.Fatalf("expected the gap to double as the budget runs low, got %v", got)
+	}
+
+	throttle.Observe(jira.RateLimit{StatusCode: http.StatusTooManyRequests, Limit: -1, Remaining: -1, RetryAfter: 30 * time.Second})
+	if got := throttle.Interval(); got != 4*minInterval {
+		t.Fatalf("expected a rejected request to double the gap, got %v", got)
+	}
+	if !throttle.next.Equal(now.Add(30 * time.Second)) {
+		t.Fatalf("expected requests paused for Retry-After, next at %v", throttle.next)
+	}
+
+	for i := 0; i < 10; i++ {
+		throttle.Observe(jira.RateLimit{StatusCode: http.StatusOK, Limit: -1, Remaining: -1})
+	}
+	if throttle.Interval() != 0 {
+		t.Fatalf("expected the gap to close again, got %v", throttle.Interval())
+	}
+}
@@ -3,6 +3,11 @@ package config
 import (
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/keyring"
 )
 
 // Config captures runtime configuration values used by the backend service.
@@ -19,9 +24,25 @@ type Config struct {
 	// GoogleClientSecret is the OAuth 2.0 client secret for Google sign-in.
 	GoogleClientSecret string
 
+	// AtlassianClientID and AtlassianClientSecret identify the Atlassian
+	// OAuth 2.0 (3LO) app users connect Jira through. When empty, Jira can
+	// only be connected with an API token.
+	AtlassianClientID     string
+	AtlassianClientSecret string
+
 	// CookieSecret is the HMAC key used to sign session and state cookies.
 	CookieSecret string
 
+	// AuthTokenKeys sign the short-lived JWTs issued by /api/auth/token.
+	// Read from AUTH_TOKEN_KEYS ("id:secret,..."; the first key signs), else
+	// AUTH_TOKEN_SECRET, else CookieSecret.
+	AuthTokenKeys *keyring.Keyring
+
+	// TokenEncryptionKeys encrypt the Jira API tokens and OAuth tokens kept
+	// in the database. Read from TOKEN_ENCRYPTION_KEYS ("id:secret,..."; the
+	// first key encrypts). When empty, credentials are stored in plaintext.
+	TokenEncryptionKeys *keyring.Keyring
+
 	// CookieDomain is the domain attribute set on cookies (e.g. ".dev.portnumber53.com").
 	CookieDomain string
 
@@ -30,35 +51,263 @@ type Config struct {
 
 	// BackendURL is the public origin of this API server, used to build OAuth redirect URIs.
 	BackendURL string
+
+	// WorkerSharedKeys are the HMAC keys the MCP worker may sign requests to
+	// backend-only routes with. Read from WORKER_SHARED_KEYS ("id:secret,..."),
+	// else WORKER_SHARED_KEY. When empty, signatures are not enforced.
+	WorkerSharedKeys *keyring.Keyring
+
+	// APIKeysRequired makes the backend-only worker routes and the sign-in
+	// callbacks the SPA Worker forwards demand an API key (X-API-Key) with
+	// the matching scope. Until it is set from API_KEYS_REQUIRED=true, a key
+	// is accepted in place of the worker signature so workers can switch
+	// over first.
+	APIKeysRequired bool
+
+	// AuthIdentityRequired stops emails in query strings and request bodies
+	// from identifying users; only auth tokens and session cookies do. It is
+	// on outside the development profile; AUTH_IDENTITY_REQUIRED=false turns
+	// it off while clients still migrating to tokens catch up.
+	AuthIdentityRequired bool
+
+	// Environment names the deployment profile (e.g. "production", "staging",
+	// "development"). Defaults to "production" so test-only features stay off
+	// unless explicitly enabled.
+	Environment string
+
+	// SMTPAddr is the host:port of the SMTP relay used for transactional
+	// email such as verification links. When empty, email is not sent.
+	SMTPAddr string
+
+	// SMTPUsername and SMTPPassword authenticate to the relay with PLAIN auth
+	// when set.
+	SMTPUsername string
+	SMTPPassword string
+
+	// MailFrom is the sender address for transactional email.
+	MailFrom string
+
+	// CommentReplyAddress is the address replies to comments synced to
+	// email go to, plus-addressed with the channel's reply token (for
+	// example comments+token@reply.example.com). Read from
+	// COMMENT_REPLY_ADDRESS; email comment channels need it.
+	CommentReplyAddress string
+
+	// InboundEmailSecret authenticates the inbound email provider posting
+	// replies to /api/webhooks/inbound-email, passed as its secret query
+	// parameter. Read from INBOUND_EMAIL_SECRET; without it replies by
+	// email are not accepted.
+	InboundEmailSecret string
+
+	// SlackSigningSecret verifies requests from the Slack app to
+	// /api/webhooks/slack, which brings Slack thread replies back as Jira
+	// comments. Read from SLACK_SIGNING_SECRET; without it the route is not
+	// served.
+	SlackSigningSecret string
+
+	// AdminEmails receive operator alerts such as charge disputes and count
+	// as admins whatever their stored role. Read from ADMIN_EMAILS as a
+	// comma-separated list.
+	AdminEmails []string
+
+	// RegionalDatabaseURLs maps data residency regions (e.g. "eu") to the
+	// Postgres DSN holding the data of organizations resident there. Read
+	// from REGIONAL_DATABASE_URLS ("region=dsn,...").
+	RegionalDatabaseURLs map[string]string
+
+	// ShardDatabaseURLs maps shard names to the Postgres DSN of a database
+	// that large tenants can be placed on to keep their request logs off the
+	// primary database. Read from SHARD_DATABASE_URLS ("shard=dsn,...").
+	ShardDatabaseURLs map[string]string
+
+	// ShardMaxOpenConns caps the connection pool of each shard database, so
+	// adding shards does not multiply the connections the backend may hold.
+	// Read from SHARD_MAX_OPEN_CONNS (default 5).
+	ShardMaxOpenConns int
+
+	// RequestSampleRate is the smallest share of successful requests written
+	// to the request log while inserts are slow; errors are always written.
+	// Read from REQUEST_SAMPLE_RATE (0 < rate <= 1, default 0.1); 1 disables
+	// sampling.
+	RequestSampleRate float64
+
+	// RequestTrackingLatency is the average request log insert latency above
+	// which successful requests start being sampled. Read from
+	// REQUEST_TRACKING_LATENCY (default 50ms).
+	RequestTrackingLatency time.Duration
+
+	// ListenReusePort sets SO_REUSEPORT on the listening socket so a new
+	// process can bind the address while the old one drains during a
+	// rolling deploy. Read from LISTEN_REUSEPORT ("true" to enable). A
+	// socket passed in by systemd socket activation is used instead when
+	// present.
+	ListenReusePort bool
+
+	// ShutdownDrainTimeout bounds how long shutdown waits for in-flight
+	// requests to finish before closing their connections. Read from
+	// SHUTDOWN_DRAIN_TIMEOUT (default 30s).
+	ShutdownDrainTimeout time.Duration
+}
+
+// IsProduction reports whether the backend runs with the production profile.
+func (c Config) IsProduction() bool {
+	return c.Environment == EnvironmentProduction
+}
+
+// IsDevelopment reports whether the backend runs with the development
+// profile, which enables tools meant for a local machine only.
+func (c Config) IsDevelopment() bool {
+	return c.Environment == EnvironmentDevelopment
 }
 
 const (
 	defaultServerAddress = "0.0.0.0:18111"
 	envServerAddress     = "BACKEND_ADDR"
 	envDatabaseURL       = "DATABASE_URL"
+	envEnvironment       = "APP_ENV"
+
+	defaultRequestSampleRate      = 0.1
+	defaultRequestTrackingLatency = 50 * time.Millisecond
+	defaultShardMaxOpenConns      = 5
+	defaultShutdownDrainTimeout   = 30 * time.Second
+
+	// EnvironmentProduction is the default deployment profile.
+	EnvironmentProduction = "production"
+	// EnvironmentDevelopment is the profile for a developer's machine.
+	EnvironmentDevelopment = "development"
 )
 
 // Load reads configuration from environment variables, applies defaults, and returns
 // a Config structure. Required values return an error when missing.
 func Load() (Config, error) {
 	cfg := Config{
-		ServerAddress:      firstNonEmpty(os.Getenv(envServerAddress), defaultServerAddress),
-		DatabaseURL:        os.Getenv(envDatabaseURL),
-		GoogleClientID:     os.Getenv("GOOGLE_CLIENT_ID"),
-		GoogleClientSecret: os.Getenv("GOOGLE_CLIENT_SECRET"),
-		CookieSecret:       firstNonEmpty(os.Getenv("COOKIE_SECRET"), os.Getenv("SESSION_SECRET")),
-		CookieDomain:       os.Getenv("COOKIE_DOMAIN"),
-		FrontendURL:        os.Getenv("FRONTEND_URL"),
-		BackendURL:         os.Getenv("BACKEND_URL"),
+		ServerAddress:         firstNonEmpty(os.Getenv(envServerAddress), defaultServerAddress),
+		DatabaseURL:           os.Getenv(envDatabaseURL),
+		GoogleClientID:        os.Getenv("GOOGLE_CLIENT_ID"),
+		GoogleClientSecret:    os.Getenv("GOOGLE_CLIENT_SECRET"),
+		AtlassianClientID:     os.Getenv("ATLASSIAN_CLIENT_ID"),
+		AtlassianClientSecret: os.Getenv("ATLASSIAN_CLIENT_SECRET"),
+		CookieSecret:          firstNonEmpty(os.Getenv("COOKIE_SECRET"), os.Getenv("SESSION_SECRET")),
+		CookieDomain:          os.Getenv("COOKIE_DOMAIN"),
+		FrontendURL:           os.Getenv("FRONTEND_URL"),
+		BackendURL:            os.Getenv("BACKEND_URL"),
+		Environment:           strings.ToLower(firstNonEmpty(os.Getenv(envEnvironment), EnvironmentProduction)),
+		SMTPAddr:              os.Getenv("SMTP_ADDR"),
+		SMTPUsername:          os.Getenv("SMTP_USERNAME"),
+		SMTPPassword:          os.Getenv("SMTP_PASSWORD"),
+		MailFrom:              firstNonEmpty(os.Getenv("MAIL_FROM"), "no-reply@localhost"),
+		CommentReplyAddress:   os.Getenv("COMMENT_REPLY_ADDRESS"),
+		InboundEmailSecret:    os.Getenv("INBOUND_EMAIL_SECRET"),
+		SlackSigningSecret:    os.Getenv("SLACK_SIGNING_SECRET"),
+
+		RequestSampleRate:      defaultRequestSampleRate,
+		RequestTrackingLatency: defaultRequestTrackingLatency,
+		ShardMaxOpenConns:      defaultShardMaxOpenConns,
+		ListenReusePort:        os.Getenv("LISTEN_REUSEPORT") == "true",
+		APIKeysRequired:        os.Getenv("API_KEYS_REQUIRED") == "true",
+		ShutdownDrainTimeout:   defaultShutdownDrainTimeout,
+	}
+
+	for _, email := range strings.Split(os.Getenv("ADMIN_EMAILS"), ",") {
+		if email = strings.TrimSpace(email); email != "" {
+			cfg.AdminEmails = append(cfg.AdminEmails, email)
+		}
+	}
+
+	var err error
+	if cfg.RegionalDatabaseURLs, err = parseDatabaseURLs("REGIONAL_DATABASE_URLS", "region"); err != nil {
+		return Config{}, err
+	}
+	if cfg.ShardDatabaseURLs, err = parseDatabaseURLs("SHARD_DATABASE_URLS", "shard"); err != nil {
+		return Config{}, err
+	}
+	if value := os.Getenv("SHARD_MAX_OPEN_CONNS"); value != "" {
+		conns, err := strconv.Atoi(value)
+		if err != nil || conns <= 0 {
+			return Config{}, fmt.Errorf("SHARD_MAX_OPEN_CONNS: expected a positive number, got %q", value)
+		}
+		cfg.ShardMaxOpenConns = conns
+	}
+
+	if value := os.Getenv("REQUEST_SAMPLE_RATE"); value != "" {
+		rate, err := strconv.ParseFloat(value, 64)
+		if err != nil || rate <= 0 || rate > 1 {
+			return Config{}, fmt.Errorf("REQUEST_SAMPLE_RATE: expected a number in (0, 1], got %q", value)
+		}
+		cfg.RequestSampleRate = rate
+	}
+	if value := os.Getenv("REQUEST_TRACKING_LATENCY"); value != "" {
+		latency, err := time.ParseDuration(value)
+		if err != nil || latency <= 0 {
+			return Config{}, fmt.Errorf("REQUEST_TRACKING_LATENCY: expected a positive duration, got %q", value)
+		}
+		cfg.RequestTrackingLatency = latency
+	}
+
+	if value := os.Getenv("SHUTDOWN_DRAIN_TIMEOUT"); value != "" {
+		drain, err := time.ParseDuration(value)
+		if err != nil || drain <= 0 {
+			return Config{}, fmt.Errorf("SHUTDOWN_DRAIN_TIMEOUT: expected a positive duration, got %q", value)
+		}
+		cfg.ShutdownDrainTimeout = drain
+	}
+
+	cfg.AuthIdentityRequired = !cfg.IsDevelopment()
+	if value := os.Getenv("AUTH_IDENTITY_REQUIRED"); value != "" {
+		required, err := strconv.ParseBool(value)
+		if err != nil {
+			return Config{}, fmt.Errorf("AUTH_IDENTITY_REQUIRED: expected true or false, got %q", value)
+		}
+		cfg.AuthIdentityRequired = required
+	}
+
+	if cfg.CommentReplyAddress != "" {
+		if local, _, ok := strings.Cut(cfg.CommentReplyAddress, "@"); !ok || local == "" || strings.ContainsAny(cfg.CommentReplyAddress, "+ \r\n") {
+			return Config{}, fmt.Errorf("COMMENT_REPLY_ADDRESS: expected an address without a plus part, got %q", cfg.CommentReplyAddress)
+		}
 	}
 
 	if cfg.DatabaseURL == "" {
 		return Config{}, fmt.Errorf("%s is required", envDatabaseURL)
 	}
 
+	cfg.AuthTokenKeys, err = keyring.Load(os.Getenv("AUTH_TOKEN_KEYS"), firstNonEmpty(os.Getenv("AUTH_TOKEN_SECRET"), cfg.CookieSecret))
+	if err != nil {
+		return Config{}, fmt.Errorf("AUTH_TOKEN_KEYS: %w", err)
+	}
+	cfg.TokenEncryptionKeys, err = keyring.Load(os.Getenv("TOKEN_ENCRYPTION_KEYS"), "")
+	if err != nil {
+		return Config{}, fmt.Errorf("TOKEN_ENCRYPTION_KEYS: %w", err)
+	}
+	cfg.WorkerSharedKeys, err = keyring.Load(os.Getenv("WORKER_SHARED_KEYS"), os.Getenv("WORKER_SHARED_KEY"))
+	if err != nil {
+		return Config{}, fmt.Errorf("WORKER_SHARED_KEYS: %w", err)
+	}
+
 	return cfg, nil
 }
 
+// parseDatabaseURLs reads a "name=dsn,..." list of databases from the
+// environment variable env; names are lower-cased.
+func parseDatabaseURLs(env, kind string) (map[string]string, error) {
+	var urls map[string]string
+	for _, entry := range strings.Split(os.Getenv(env), ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		name, dsn, ok := strings.Cut(entry, "=")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !ok || name == "" || strings.TrimSpace(dsn) == "" {
+			return nil, fmt.Errorf("%s: expected %s=dsn, got %q", env, kind, entry)
+		}
+		if urls == nil {
+			urls = map[string]string{}
+		}
+		urls[name] = strings.TrimSpace(dsn)
+	}
+	return urls, nil
+}
+
 func firstNonEmpty(values ...string) string {
 	for _, v := range values {
 		if v != "" {
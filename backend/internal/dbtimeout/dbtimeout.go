@@ -0,0 +1,171 @@
+// Package dbtimeout carries context deadlines over to the Postgres server.
+// Wrapped connections set the session's statement_timeout to the time left
+// on a statement's context before running it, so the server stops working
+// on queries whose caller has already given up, even when the client side
+// cancellation does not get through.
+package dbtimeout
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"time"
+)
+
+// Wrap returns a connector whose connections bound QueryContext and
+// ExecContext calls by the deadline of their context. Statements without a
+// deadline run under the server's default statement_timeout.
+func Wrap(connector driver.Connector) driver.Connector {
+	return &timeoutConnector{Connector: connector}
+}
+
+type timeoutConnector struct {
+	driver.Connector
+}
+
+func (t *timeoutConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := t.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &timeoutConn{Conn: conn}, nil
+}
+
+// timeoutConn remembers the statement_timeout it last set so statements of
+// the same request reuse it instead of paying a round trip each.
+type timeoutConn struct {
+	driver.Conn
+
+	// timeout is the session's statement_timeout; 0 is the server default.
+	timeout time.Duration
+	// stale is set when the session's statement_timeout is not known, such
+	// as after a rolled back transaction undid a SET.
+	stale bool
+}
+
+// apply sets statement_timeout for a statement run with ctx. A timeout
+// already set for a longer deadline is kept while it exceeds the time left
+// by at most a quarter.
+func (c *timeoutConn) apply(ctx context.Context) error {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		if c.timeout == 0 && !c.stale {
+			return nil
+		}
+		return c.set(ctx, 0)
+	}
+
+	remaining := time.Until(deadline)
+	if remaining <= 0 {
+		return context.DeadlineExceeded
+	}
+	// statement_timeout has millisecond resolution; 0 would disable it.
+	remaining = (remaining + time.Millisecond - 1).Truncate(time.Millisecond)
+	if !c.stale && c.timeout >= remaining && c.timeout <= remaining+remaining/4 {
+		return nil
+	}
+	return c.set(ctx, remaining)
+}
+
+func (c *timeoutConn) set(ctx context.Context, timeout time.Duration) error {
+	execer, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil
+	}
+	query := "RESET statement_timeout"
+	if timeout > 0 {
+		query = fmt.Sprintf("SET statement_timeout = %d", timeout.Milliseconds())
+	}
+	if _, err := execer.ExecContext(ctx, query, nil); err != nil {
+		c.stale = true
+		return err
+	}
+	c.timeout, c.stale = timeout, false
+	return nil
+}
+
+func (c *timeoutConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	if err := c.apply(ctx); err != nil {
+		return nil, err
+	}
+	return queryer.QueryContext(ctx, query, args)
+}
+
+func (c *timeoutConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	execer, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	if err := c.apply(ctx); err != nil {
+		return nil, err
+	}
+	return execer.ExecContext(ctx, query, args)
+}
+
+func (c *timeoutConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if preparer, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		return preparer.PrepareContext(ctx, query)
+	}
+	return c.Conn.Prepare(query)
+}
+
+func (c *timeoutConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	var (
+		tx  driver.Tx
+		err error
+	)
+	if beginner, ok := c.Conn.(driver.ConnBeginTx); ok {
+		tx, err = beginner.BeginTx(ctx, opts)
+	} else {
+		tx, err = c.Conn.Begin()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &timeoutTx{Tx: tx, conn: c}, nil
+}
+
+func (c *timeoutConn) Ping(ctx context.Context) error {
+	if pinger, ok := c.Conn.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *timeoutConn) ResetSession(ctx context.Context) error {
+	if resetter, ok := c.Conn.(driver.SessionResetter); ok {
+		return resetter.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *timeoutConn) IsValid() bool {
+	if validator, ok := c.Conn.(driver.Validator); ok {
+		return validator.IsValid()
+	}
+	return true
+}
+
+// timeoutTx marks the session's timeout unknown when a transaction that
+// may have set it does not commit, since Postgres then undoes the SET.
+type timeoutTx struct {
+	driver.Tx
+	conn *timeoutConn
+}
+
+func (t *timeoutTx) Commit() error {
+	err := t.Tx.Commit()
+	if err != nil {
+		t.conn.stale = true
+	}
+	return err
+}
+
+func (t *timeoutTx) Rollback() error {
+	t.conn.stale = true
+	return t.Tx.Rollback()
+}
@@ -0,0 +1,81 @@
+package dbtimeout
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeConnector struct {
+	conn *fakeConn
+}
+
+func (f *fakeConnector) Connect(context.Context) (driver.Conn, error) { return f.conn, nil }
+func (f *fakeConnector) Driver() driver.Driver                        { return nil }
+
+// fakeConn records the statements sent to it.
+type fakeConn struct {
+	statements []string
+}
+
+func (f *fakeConn) Prepare(string) (driver.Stmt, error) { return nil, driver.ErrSkip }
+func (f *fakeConn) Close() error                        { return nil }
+func (f *fakeConn) Begin() (driver.Tx, error)           { return fakeTx{}, nil }
+
+func (f *fakeConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	f.statements = append(f.statements, query)
+	return driver.RowsAffected(0), nil
+}
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+func TestStatementTimeoutFollowsContextDeadline(t *testing.T) {
+	conn := &fakeConn{}
+	db := sql.OpenDB(Wrap(&fakeConnector{conn: conn}))
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	db.ExecContext(ctx, "UPDATE a")
+	db.ExecContext(ctx, "UPDATE b")
+	db.ExecContext(context.Background(), "UPDATE c")
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatalf("BeginTx: %v", err)
+	}
+	tx.ExecContext(ctx, "UPDATE d")
+	tx.Rollback()
+	db.ExecContext(context.Background(), "UPDATE e")
+
+	setTimeout := regexp.MustCompile(`SET statement_timeout = (\d+)`)
+	got := setTimeout.ReplaceAllStringFunc(strings.Join(conn.statements, "; "), func(set string) string {
+		if ms, _ := strconv.Atoi(setTimeout.FindStringSubmatch(set)[1]); ms <= 1000 || ms > 2000 {
+			t.Errorf("expected the time left on the context, got %q", set)
+		}
+		return "SET statement_timeout = N"
+	})
+	for _, want := range []string{
+		"SET statement_timeout = N; UPDATE a; UPDATE b; RESET statement_timeout; UPDATE c",
+		"SET statement_timeout = N; UPDATE d; RESET statement_timeout; UPDATE e",
+	} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("expected %q in statements, got %q", want, got)
+		}
+	}
+
+	expired, cancelExpired := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancelExpired()
+	if _, err := db.ExecContext(expired, "UPDATE f"); err == nil {
+		t.Fatal("expected an expired context to fail without running the statement")
+	}
+}
@@ -0,0 +1,193 @@
+// Package events provides an in-process event bus so modules can announce
+// state changes (users, subscriptions, jobs) without depending on the code
+// that reacts to them.
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+)
+
+// Topic names a category of events.
+type Topic string
+
+// Event is implemented by every payload published on the bus.
+type Event interface {
+	Topic() Topic
+}
+
+// Handler reacts to a published event. A returned error is logged by Publish
+// and causes the outbox dispatcher to redeliver the event.
+type Handler func(ctx context.Context, ev Event) error
+
+type subscriber struct {
+	handler Handler
+	async   bool
+}
+
+// Bus dispatches events to subscribers by topic. Synchronous subscribers run
+// on the publisher's goroutine before Publish returns; asynchronous
+// subscribers run on their own goroutine with a context detached from the
+// publisher's cancellation. A nil *Bus discards every publish.
+type Bus struct {
+	mu     sync.RWMutex
+	subs   map[Topic][]subscriber
+	wg     sync.WaitGroup
+	closed bool
+}
+
+// New creates an empty bus.
+func New() *Bus {
+	return &Bus{subs: make(map[Topic][]subscriber)}
+}
+
+// Subscribe registers a synchronous handler for topic.
+func (b *Bus) Subscribe(topic Topic, h Handler) {
+	b.add(topic, subscriber{handler: h})
+}
+
+// SubscribeAsync registers a handler for topic that runs in the background.
+func (b *Bus) SubscribeAsync(topic Topic, h Handler) {
+	b.add(topic, subscriber{handler: h, async: true})
+}
+
+func (b *Bus) add(topic Topic, sub subscriber) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subs[topic] = append(b.subs[topic], sub)
+}
+
+// Publish delivers ev to every subscriber of its topic. Subscriber errors and
+// panics are logged so one faulty subscriber cannot break the publisher.
+func (b *Bus) Publish(ctx context.Context, ev Event) {
+	b.publish(ctx, ev, false)
+}
+
+// PublishAndWait delivers ev like Publish but also waits for asynchronous
+// subscribers and returns their combined errors. The outbox dispatcher uses
+// it so an event is only marked delivered once every subscriber succeeded.
+func (b *Bus) PublishAndWait(ctx context.Context, ev Event) error {
+	return b.publish(ctx, ev, true)
+}
+
+func (b *Bus) publish(ctx context.Context, ev Event, wait bool) error {
+	if b == nil || ev == nil {
+		return nil
+	}
+
+	b.mu.RLock()
+	if b.closed {
+		b.mu.RUnlock()
+		log.Printf("[events] bus closed, dropping %s event", ev.Topic())
+		return nil
+	}
+	subs := b.subs[ev.Topic()]
+	var async []subscriber
+	for _, sub := range subs {
+		if sub.async {
+			async = append(async, sub)
+		}
+	}
+	b.wg.Add(len(async))
+	b.mu.RUnlock()
+
+	var (
+		errMu   sync.Mutex
+		errs    []error
+		pending sync.WaitGroup
+	)
+	collect := func(err error) {
+		if err == nil {
+			return
+		}
+		log.Printf("[events] subscriber for %s failed: %v", ev.Topic(), err)
+		errMu.Lock()
+		errs = append(errs, err)
+		errMu.Unlock()
+	}
+
+	if len(async) > 0 {
+		pending.Add(len(async))
+		detached := context.WithoutCancel(ctx)
+		for _, sub := range async {
+			go func(h Handler) {
+				defer b.wg.Done()
+				defer pending.Done()
+				collect(dispatch(detached, h, ev))
+			}(sub.handler)
+		}
+	}
+
+	for _, sub := range subs {
+		if !sub.async {
+			collect(dispatch(ctx, sub.handler, ev))
+		}
+	}
+
+	if !wait {
+		return nil
+	}
+	pending.Wait()
+
+	errMu.Lock()
+	defer errMu.Unlock()
+	return errors.Join(errs...)
+}
+
+// Close stops accepting new events and waits for in-flight asynchronous
+// handlers to finish or for ctx to expire.
+func (b *Bus) Close(ctx context.Context) error {
+	if b == nil {
+		return nil
+	}
+
+	b.mu.Lock()
+	b.closed = true
+	b.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		b.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func dispatch(ctx context.Context, h Handler, ev Event) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("subscriber panicked: %v", r)
+		}
+	}()
+	return h(ctx, ev)
+}
+
+// On registers a synchronous handler for events of type T.
+func On[T Event](b *Bus, fn func(ctx context.Context, ev T) error) {
+	var zero T
+	b.Subscribe(zero.Topic(), typed(fn))
+}
+
+// OnAsync registers an asynchronous handler for events of type T.
+func OnAsync[T Event](b *Bus, fn func(ctx context.Context, ev T) error) {
+	var zero T
+	b.SubscribeAsync(zero.Topic(), typed(fn))
+}
+
+func typed[T Event](fn func(ctx context.Context, ev T) error) Handler {
+	return func(ctx context.Context, ev Event) error {
+		if t, ok := ev.(T); ok {
+			return fn(ctx, t)
+		}
+		return nil
+	}
+}
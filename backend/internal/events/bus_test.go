@@ -0,0 +1,109 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBusDispatchesByType(t *testing.T) {
+	b := New()
+
+	var syncCalls, asyncCalls, otherCalls int32
+	On(b, func(ctx context.Context, ev UserUpserted) error {
+		if ev.UserID != 7 {
+			t.Errorf("unexpected user id %d", ev.UserID)
+		}
+		atomic.AddInt32(&syncCalls, 1)
+		return nil
+	})
+	OnAsync(b, func(ctx context.Context, ev UserUpserted) error {
+		atomic.AddInt32(&asyncCalls, 1)
+		return nil
+	})
+	On(b, func(ctx context.Context, ev JobCompleted) error {
+		atomic.AddInt32(&otherCalls, 1)
+		return nil
+	})
+
+	b.Publish(context.Background(), UserUpserted{UserID: 7, Provider: "github"})
+
+	if got := atomic.LoadInt32(&syncCalls); got != 1 {
+		t.Fatalf("expected sync handler to run before Publish returns, got %d calls", got)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := b.Close(ctx); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+
+	if got := atomic.LoadInt32(&asyncCalls); got != 1 {
+		t.Fatalf("expected async handler to run once, got %d", got)
+	}
+	if got := atomic.LoadInt32(&otherCalls); got != 0 {
+		t.Fatalf("expected unrelated handler not to run, got %d", got)
+	}
+
+	b.Publish(context.Background(), UserUpserted{UserID: 7})
+	if got := atomic.LoadInt32(&syncCalls); got != 1 {
+		t.Fatalf("expected closed bus to drop events, got %d calls", got)
+	}
+}
+
+func TestBusRecoversSubscriberPanic(t *testing.T) {
+	b := New()
+
+	var called bool
+	On(b, func(ctx context.Context, ev JobCompleted) error {
+		panic("boom")
+	})
+	On(b, func(ctx context.Context, ev JobCompleted) error {
+		called = true
+		return nil
+	})
+
+	if err := b.PublishAndWait(context.Background(), JobCompleted{JobID: 1}); err == nil {
+		t.Fatal("expected PublishAndWait to report the panicking subscriber")
+	}
+	if !called {
+		t.Fatal("expected later subscriber to run after an earlier one panicked")
+	}
+
+	var nilBus *Bus
+	nilBus.Publish(context.Background(), JobCompleted{})
+}
+
+func TestPublishAndWaitReportsAsyncErrors(t *testing.T) {
+	b := New()
+
+	OnAsync(b, func(ctx context.Context, ev PaymentRecorded) error {
+		return errors.New("db unavailable")
+	})
+
+	if err := b.PublishAndWait(context.Background(), PaymentRecorded{UserID: 1, Status: "failed"}); err == nil {
+		t.Fatal("expected async subscriber error to be returned")
+	}
+}
+
+func TestDecodeRoundTrip(t *testing.T) {
+	data, err := Encode(SubscriptionChanged{UserID: 3, Change: SubscriptionPaused, Paused: true})
+	if err != nil {
+		t.Fatalf("Encode returned error: %v", err)
+	}
+
+	ev, err := Decode(TopicSubscriptionChanged, data)
+	if err != nil {
+		t.Fatalf("Decode returned error: %v", err)
+	}
+	got, ok := ev.(SubscriptionChanged)
+	if !ok || got.UserID != 3 || !got.Paused || got.Change != SubscriptionPaused {
+		t.Fatalf("unexpected decoded event: %#v", ev)
+	}
+
+	if _, err := Decode("nope", data); err == nil {
+		t.Fatal("expected error for unknown topic")
+	}
+}
@@ -0,0 +1,58 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// decoders rebuilds typed events from their persisted JSON form. Every event
+// that can travel through the outbox must be registered here.
+var decoders = map[Topic]func([]byte) (Event, error){}
+
+func register[T Event]() {
+	var zero T
+	decoders[zero.Topic()] = func(data []byte) (Event, error) {
+		var ev T
+		if err := json.Unmarshal(data, &ev); err != nil {
+			return nil, err
+		}
+		return ev, nil
+	}
+}
+
+func init() {
+	register[UserUpserted]()
+	register[UserDeleted]()
+	register[MCPSecretRotated]()
+	register[SubscriptionChanged]()
+	register[PaymentRecorded]()
+	register[DisputeChanged]()
+	register[JobProgressed]()
+	register[JobCompleted]()
+	register[JiraWebhookReceived]()
+	register[AbuseDetected]()
+	register[MembershipChanged]()
+	register[UsersMerged]()
+}
+
+// Encode serialises an event for persistence.
+func Encode(ev Event) ([]byte, error) {
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return nil, fmt.Errorf("events: encode %s: %w", ev.Topic(), err)
+	}
+	return data, nil
+}
+
+// Decode rebuilds the typed event persisted under topic.
+func Decode(topic Topic, data []byte) (Event, error) {
+	decode, ok := decoders[topic]
+	if !ok {
+		return nil, fmt.Errorf("events: unknown topic %q", topic)
+	}
+	ev, err := decode(data)
+	if err != nil {
+		return nil, fmt.Errorf("events: decode %s: %w", topic, err)
+	}
+	return ev, nil
+}
@@ -0,0 +1,198 @@
+package events
+
+import "time"
+
+// Topics published by the application.
+const (
+	TopicUserUpserted        Topic = "user.upserted"
+	TopicUserDeleted         Topic = "user.deleted"
+	TopicMCPSecretRotated    Topic = "user.mcp_secret_rotated"
+	TopicSubscriptionChanged Topic = "billing.subscription_changed"
+	TopicPaymentRecorded     Topic = "billing.payment_recorded"
+	TopicDisputeChanged      Topic = "billing.dispute_changed"
+	TopicJobProgressed       Topic = "job.progressed"
+	TopicJobCompleted        Topic = "job.completed"
+	TopicJiraWebhookReceived Topic = "jira.webhook_received"
+	TopicAbuseDetected       Topic = "security.abuse_detected"
+	TopicMembershipChanged   Topic = "org.membership_changed"
+	TopicUsersMerged         Topic = "user.merged"
+)
+
+// UserUpserted is published after an OAuth login creates or updates a user.
+type UserUpserted struct {
+	UserID   int64
+	Email    string
+	Provider string
+}
+
+func (UserUpserted) Topic() Topic { return TopicUserUpserted }
+
+// UserDeleted is published after a user deleted their account. Their data
+// is kept until the account is purged.
+type UserDeleted struct {
+	UserID int64
+	Email  string
+}
+
+func (UserDeleted) Topic() Topic { return TopicUserDeleted }
+
+// MCPSecretRotated is published when a user's mcp_secret is regenerated, so
+// anything keyed by the old secret can be dropped.
+type MCPSecretRotated struct {
+	UserID int64
+}
+
+func (MCPSecretRotated) Topic() Topic { return TopicMCPSecretRotated }
+
+// UsersMerged is published after a duplicate user has been folded into
+// another account and deleted.
+type UsersMerged struct {
+	SourceUserID int64
+	SourceEmail  string
+	TargetUserID int64
+	Moved        map[string]int64
+}
+
+func (UsersMerged) Topic() Topic { return TopicUsersMerged }
+
+// Subscription change kinds carried by SubscriptionChanged.
+const (
+	SubscriptionCreated  = "created"
+	SubscriptionUpdated  = "updated"
+	SubscriptionCanceled = "canceled"
+	SubscriptionPaused   = "paused"
+	SubscriptionResumed  = "resumed"
+)
+
+// SubscriptionChanged is published whenever a user's subscription state
+// changes, whether from a Stripe webhook or a user action.
+type SubscriptionChanged struct {
+	UserID               int64
+	StripeSubscriptionID string
+	Change               string
+	Status               string
+	CancelAtPeriodEnd    bool
+	Paused               bool
+}
+
+func (SubscriptionChanged) Topic() Topic { return TopicSubscriptionChanged }
+
+// PaymentRecorded is published when a Stripe invoice payment succeeds or
+// fails. Amount is in the currency's minor unit.
+type PaymentRecorded struct {
+	UserID    int64
+	InvoiceID string
+	Amount    int
+	Currency  string
+	Status    string
+}
+
+func (PaymentRecorded) Topic() Topic { return TopicPaymentRecorded }
+
+// Failed reports whether the payment attempt failed.
+func (p PaymentRecorded) Failed() bool { return p.Status == "failed" }
+
+// Dispute change kinds carried by DisputeChanged.
+const (
+	DisputeCreated = "created"
+	DisputeUpdated = "updated"
+	DisputeClosed  = "closed"
+)
+
+// DisputeChanged is published when a customer disputes a charge and as
+// Stripe updates or closes the dispute. UserID is zero when the charge's
+// customer is not one of ours. Suspended reports whether the dispute
+// suspended the account and the suspension still holds after the change.
+type DisputeChanged struct {
+	UserID          int64
+	StripeDisputeID string
+	ChargeID        string
+	Change          string
+	Status          string
+	Reason          string
+	Amount          int
+	Currency        string
+	Suspended       bool
+}
+
+func (DisputeChanged) Topic() Topic { return TopicDisputeChanged }
+
+// JobProgressed is published when a job starts running, reports progress
+// or is scheduled for another attempt.
+type JobProgressed struct {
+	JobID   int64
+	JobType string
+	UserID  int64
+	Status  string // started, progress, retrying
+	Attempt int
+	RetryIn time.Duration
+	// Done and Total are the units of work reported with progress; Total
+	// is 0 when unknown.
+	Done  int
+	Total int
+}
+
+func (JobProgressed) Topic() Topic { return TopicJobProgressed }
+
+// JobCompleted is published when a job reaches a terminal state: completed,
+// failed, or cancelled.
+type JobCompleted struct {
+	JobID    int64
+	JobType  string
+	UserID   int64
+	Status   string
+	Attempt  int
+	Duration time.Duration
+	Error    string
+}
+
+func (JobCompleted) Topic() Topic { return TopicJobCompleted }
+
+// JiraWebhookReceived is published when a tenant's Jira site delivers a
+// webhook.
+type JiraWebhookReceived struct {
+	UserID       int64
+	WebhookEvent string
+	Data         map[string]any
+}
+
+func (JiraWebhookReceived) Topic() Topic { return TopicJiraWebhookReceived }
+
+// Abuse kinds carried by AbuseDetected.
+const (
+	AbuseAuthFailureBurst = "auth_failure_burst"
+	AbuseRequestSpike     = "request_spike"
+)
+
+// AbuseDetected is published by the abuse detector when traffic looks like
+// credential stuffing or a runaway client. UserID is zero for IP-level
+// findings. RateLimitedUntil is set when the detector limited ClientIP.
+type AbuseDetected struct {
+	Kind             string
+	UserID           int64
+	ClientIP         string
+	Count            int
+	Baseline         float64
+	RateLimitedUntil *time.Time
+}
+
+func (AbuseDetected) Topic() Topic { return TopicAbuseDetected }
+
+// Membership change kinds carried by MembershipChanged.
+const (
+	MembershipAdded       = "added"
+	MembershipDeactivated = "deactivated"
+	MembershipReactivated = "reactivated"
+	MembershipRemoved     = "removed"
+)
+
+// MembershipChanged is published when a user joins, leaves, or is
+// (de)activated in an organization, so seat billing can follow.
+type MembershipChanged struct {
+	OrgID  int64
+	UserID int64
+	Change string
+	Source string // owner, sso, scim, domain
+}
+
+func (MembershipChanged) Topic() Topic { return TopicMembershipChanged }
@@ -0,0 +1,286 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/mail"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+)
+
+// AuditRecorder persists audit log entries.
+type AuditRecorder interface {
+	RecordAuditEvent(ctx context.Context, userID int64, action string, metadata models.JSONB) error
+}
+
+// NotificationWriter records per-user entries in the notification feed.
+type NotificationWriter interface {
+	CreateUserNotification(ctx context.Context, userID int64, kind, severity, title, body string, metadata models.JSONB) error
+}
+
+// SeatStore reports an organization's seat usage and records what was
+// billed.
+type SeatStore interface {
+	GetOrganizationSeats(ctx context.Context, orgID int64) (seats, billedSeats int, subscriptionID string, err error)
+	SetOrganizationBilledSeats(ctx context.Context, orgID int64, seats int) error
+}
+
+// SeatBiller sets the seat quantity on a Stripe subscription.
+type SeatBiller interface {
+	UpdateSubscriptionQuantity(subscriptionID string, quantity int) error
+}
+
+// DomainCaptureRouter queues join requests for users who sign up with an
+// address on a verified organization domain.
+type DomainCaptureRouter interface {
+	RouteUserToCapturedOrganization(ctx context.Context, userID int64, email string) (int64, error)
+}
+
+// DataRegionReconciler moves a user's regional data to the region their
+// organization memberships decide.
+type DataRegionReconciler interface {
+	ReconcileUserDataRegion(ctx context.Context, userID int64) error
+}
+
+// Broadcaster fans events out to a tenant's real-time connections.
+type Broadcaster interface {
+	Publish(userID int64, eventType string, data interface{})
+}
+
+// RegisterAuditLog records account and billing events in the audit log.
+func RegisterAuditLog(b *Bus, audit AuditRecorder) {
+	record := func(ctx context.Context, userID int64, action string, metadata models.JSONB) error {
+		if userID <= 0 {
+			return nil
+		}
+		if err := audit.RecordAuditEvent(ctx, userID, action, metadata); err != nil {
+			return fmt.Errorf("audit %s for user %d: %w", action, userID, err)
+		}
+		return nil
+	}
+
+	OnAsync(b, func(ctx context.Context, ev UserUpserted) error {
+		return record(ctx, ev.UserID, "user.login", models.JSONB{"provider": ev.Provider})
+	})
+	OnAsync(b, func(ctx context.Context, ev MCPSecretRotated) error {
+		return record(ctx, ev.UserID, "user.mcp_secret_rotated", nil)
+	})
+	OnAsync(b, func(ctx context.Context, ev UsersMerged) error {
+		return record(ctx, ev.TargetUserID, "user.merged", models.JSONB{
+			"source_user_id": ev.SourceUserID,
+			"source_email":   ev.SourceEmail,
+			"moved":          ev.Moved,
+		})
+	})
+	OnAsync(b, func(ctx context.Context, ev SubscriptionChanged) error {
+		return record(ctx, ev.UserID, "subscription."+ev.Change, models.JSONB{
+			"stripe_subscription_id": ev.StripeSubscriptionID,
+			"status":                 ev.Status,
+		})
+	})
+	OnAsync(b, func(ctx context.Context, ev PaymentRecorded) error {
+		return record(ctx, ev.UserID, "payment."+ev.Status, models.JSONB{
+			"invoice_id": ev.InvoiceID,
+			"amount":     ev.Amount,
+			"currency":   ev.Currency,
+		})
+	})
+	OnAsync(b, func(ctx context.Context, ev DisputeChanged) error {
+		return record(ctx, ev.UserID, "dispute."+ev.Change, models.JSONB{
+			"stripe_dispute_id": ev.StripeDisputeID,
+			"charge_id":         ev.ChargeID,
+			"status":            ev.Status,
+			"reason":            ev.Reason,
+			"amount":            ev.Amount,
+			"currency":          ev.Currency,
+			"suspended":         ev.Suspended,
+		})
+	})
+	OnAsync(b, func(ctx context.Context, ev AbuseDetected) error {
+		metadata := models.JSONB{"count": ev.Count}
+		if ev.ClientIP != "" {
+			metadata["client_ip"] = ev.ClientIP
+		}
+		if ev.Baseline > 0 {
+			metadata["baseline"] = ev.Baseline
+		}
+		if ev.RateLimitedUntil != nil {
+			metadata["rate_limited_until"] = ev.RateLimitedUntil.UTC().Format(time.RFC3339)
+		}
+		// IP-level findings have no user and are recorded as system events.
+		if err := audit.RecordAuditEvent(ctx, ev.UserID, "abuse."+ev.Kind, metadata); err != nil {
+			return fmt.Errorf("audit abuse.%s: %w", ev.Kind, err)
+		}
+		return nil
+	})
+	OnAsync(b, func(ctx context.Context, ev MembershipChanged) error {
+		return record(ctx, ev.UserID, "org.member_"+ev.Change, models.JSONB{
+			"org_id": ev.OrgID,
+			"source": ev.Source,
+		})
+	})
+}
+
+// RegisterSeatBilling keeps the quantity of an organization owner's Stripe
+// subscription equal to the organization's active member count.
+// Organizations whose owner has no active subscription are not billed.
+func RegisterSeatBilling(b *Bus, seats SeatStore, biller SeatBiller) {
+	OnAsync(b, func(ctx context.Context, ev MembershipChanged) error {
+		count, billed, subscriptionID, err := seats.GetOrganizationSeats(ctx, ev.OrgID)
+		if err != nil {
+			return fmt.Errorf("load seats for org %d: %w", ev.OrgID, err)
+		}
+		if count < 1 {
+			count = 1
+		}
+		if subscriptionID == "" || count == billed {
+			return nil
+		}
+		if err := biller.UpdateSubscriptionQuantity(subscriptionID, count); err != nil {
+			return fmt.Errorf("bill %d seats for org %d: %w", count, ev.OrgID, err)
+		}
+		if err := seats.SetOrganizationBilledSeats(ctx, ev.OrgID, count); err != nil {
+			return fmt.Errorf("record billed seats for org %d: %w", ev.OrgID, err)
+		}
+		return nil
+	})
+}
+
+// RegisterDataResidency moves a user's request logs when joining or leaving
+// an organization changes the region they are kept in.
+func RegisterDataResidency(b *Bus, reconciler DataRegionReconciler) {
+	OnAsync(b, func(ctx context.Context, ev MembershipChanged) error {
+		if err := reconciler.ReconcileUserDataRegion(ctx, ev.UserID); err != nil {
+			return fmt.Errorf("reconcile data region of user %d: %w", ev.UserID, err)
+		}
+		return nil
+	})
+}
+
+// RegisterDomainCapture routes users whose email domain an organization has
+// verified into that organization, pending an admin's approval.
+func RegisterDomainCapture(b *Bus, router DomainCaptureRouter) {
+	OnAsync(b, func(ctx context.Context, ev UserUpserted) error {
+		if ev.Email == "" {
+			return nil
+		}
+		if _, err := router.RouteUserToCapturedOrganization(ctx, ev.UserID, ev.Email); err != nil {
+			return fmt.Errorf("route user %d to captured organization: %w", ev.UserID, err)
+		}
+		return nil
+	})
+}
+
+// RegisterNotifications turns user-facing failures into notification feed
+// entries.
+func RegisterNotifications(b *Bus, store NotificationWriter) {
+	OnAsync(b, func(ctx context.Context, ev PaymentRecorded) error {
+		if !ev.Failed() || ev.UserID <= 0 {
+			return nil
+		}
+		body := fmt.Sprintf("We couldn't collect your payment of %.2f %s. Update your payment method to keep your plan active.",
+			float64(ev.Amount)/100, strings.ToUpper(ev.Currency))
+		if err := store.CreateUserNotification(ctx, ev.UserID, models.NotificationKindPaymentFailed, "critical",
+			"Payment failed", body, models.JSONB{"invoice_id": ev.InvoiceID}); err != nil {
+			return fmt.Errorf("notify payment failure for user %d: %w", ev.UserID, err)
+		}
+		return nil
+	})
+}
+
+// RegisterAdminAlerts emails operators about events that need a human,
+// such as charge disputes, which must be answered in Stripe before the
+// response deadline.
+func RegisterAdminAlerts(b *Bus, mailer mail.Mailer, recipients []string) {
+	if mailer == nil || len(recipients) == 0 {
+		return
+	}
+
+	send := func(ctx context.Context, subject, body string) error {
+		var failed []string
+		for _, to := range recipients {
+			if err := mailer.Send(ctx, mail.Message{To: to, Subject: subject, Body: body}); err != nil {
+				failed = append(failed, fmt.Sprintf("%s: %v", to, err))
+			}
+		}
+		if len(failed) > 0 {
+			return fmt.Errorf("send admin alert %q: %s", subject, strings.Join(failed, "; "))
+		}
+		return nil
+	}
+
+	OnAsync(b, func(ctx context.Context, ev DisputeChanged) error {
+		if ev.Change == DisputeUpdated {
+			return nil
+		}
+		user := "an unknown customer"
+		if ev.UserID > 0 {
+			user = fmt.Sprintf("user %d", ev.UserID)
+		}
+		subject := fmt.Sprintf("Charge dispute %s: %.2f %s from %s", ev.Change, float64(ev.Amount)/100, strings.ToUpper(ev.Currency), user)
+		body := fmt.Sprintf("Dispute %s on charge %s is now %s (reason: %s).\n", ev.StripeDisputeID, ev.ChargeID, ev.Status, ev.Reason)
+		if ev.Suspended {
+			body += "The account is suspended until its open disputes are closed.\n"
+		}
+		if ev.Change == DisputeCreated {
+			body += "Respond with evidence in the Stripe dashboard before the deadline.\n"
+		}
+		return send(ctx, subject, body)
+	})
+}
+
+// RegisterBroadcast forwards tenant-scoped events to real-time clients.
+func RegisterBroadcast(b *Bus, out Broadcaster) {
+	On(b, func(ctx context.Context, ev SubscriptionChanged) error {
+		out.Publish(ev.UserID, "billing.subscription_"+ev.Change, map[string]any{
+			"stripe_subscription_id": ev.StripeSubscriptionID,
+			"status":                 ev.Status,
+			"cancel_at_period_end":   ev.CancelAtPeriodEnd,
+			"paused":                 ev.Paused,
+		})
+		return nil
+	})
+	On(b, func(ctx context.Context, ev PaymentRecorded) error {
+		out.Publish(ev.UserID, "billing.payment_"+ev.Status, map[string]any{
+			"invoice_id": ev.InvoiceID,
+			"amount":     ev.Amount,
+			"currency":   ev.Currency,
+		})
+		return nil
+	})
+	On(b, func(ctx context.Context, ev JobProgressed) error {
+		data := map[string]any{
+			"job_id":   ev.JobID,
+			"job_type": ev.JobType,
+			"attempt":  ev.Attempt,
+		}
+		if ev.RetryIn > 0 {
+			data["retry_in_ms"] = ev.RetryIn.Milliseconds()
+		}
+		if ev.Status == "progress" {
+			data["done"] = ev.Done
+			data["total"] = ev.Total
+		}
+		out.Publish(ev.UserID, "job."+ev.Status, data)
+		return nil
+	})
+	On(b, func(ctx context.Context, ev JobCompleted) error {
+		data := map[string]any{
+			"job_id":      ev.JobID,
+			"job_type":    ev.JobType,
+			"attempt":     ev.Attempt,
+			"duration_ms": ev.Duration.Milliseconds(),
+		}
+		if ev.Error != "" {
+			data["error"] = ev.Error
+		}
+		out.Publish(ev.UserID, "job."+ev.Status, data)
+		return nil
+	})
+	On(b, func(ctx context.Context, ev JiraWebhookReceived) error {
+		out.Publish(ev.UserID, "jira."+ev.WebhookEvent, ev.Data)
+		return nil
+	})
+}
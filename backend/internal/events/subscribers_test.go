@@ -0,0 +1,64 @@
+package events
+
+import (
+	"context"
+	"testing"
+)
+
+type fakeSeats struct {
+	seats, billed  int
+	subscriptionID string
+}
+
+func (f *fakeSeats) GetOrganizationSeats(ctx context.Context, orgID int64) (int, int, string, error) {
+	return f.seats, f.billed, f.subscriptionID, nil
+}
+
+func (f *fakeSeats) SetOrganizationBilledSeats(ctx context.Context, orgID int64, seats int) error {
+	f.billed = seats
+	return nil
+}
+
+type fakeBiller struct {
+	calls    int
+	quantity int
+}
+
+func (f *fakeBiller) UpdateSubscriptionQuantity(subscriptionID string, quantity int) error {
+	f.calls++
+	f.quantity = quantity
+	return nil
+}
+
+func TestSeatBillingOnlyCallsStripeWhenSeatsChange(t *testing.T) {
+	b := New()
+	seats := &fakeSeats{seats: 4, billed: 3, subscriptionID: "sub_123"}
+	biller := &fakeBiller{}
+	RegisterSeatBilling(b, seats, biller)
+
+	ev := MembershipChanged{OrgID: 1, UserID: 9, Change: MembershipAdded, Source: "scim"}
+	if err := b.PublishAndWait(context.Background(), ev); err != nil {
+		t.Fatalf("PublishAndWait: %v", err)
+	}
+	if biller.calls != 1 || biller.quantity != 4 || seats.billed != 4 {
+		t.Fatalf("expected 4 seats billed once, got calls=%d quantity=%d billed=%d", biller.calls, biller.quantity, seats.billed)
+	}
+
+	// Replaying the event does not bill again.
+	if err := b.PublishAndWait(context.Background(), ev); err != nil {
+		t.Fatalf("PublishAndWait: %v", err)
+	}
+	if biller.calls != 1 {
+		t.Fatalf("expected no further Stripe calls, got %d", biller.calls)
+	}
+
+	// Organizations without an owner subscription are not billed.
+	seats.subscriptionID = ""
+	seats.seats = 6
+	if err := b.PublishAndWait(context.Background(), ev); err != nil {
+		t.Fatalf("PublishAndWait: %v", err)
+	}
+	if biller.calls != 1 {
+		t.Fatalf("expected unbilled organization to skip Stripe, got %d calls", biller.calls)
+	}
+}
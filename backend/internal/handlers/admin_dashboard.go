@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"context"
+	"embed"
+	"io/fs"
+	"log"
+	"net/http"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+)
+
+// adminDashboardFiles is the static admin UI served at /admin.
+//
+//go:embed admin_ui
+var adminDashboardFiles embed.FS
+
+// adminDashboardTenantLimit and adminDashboardEventLimit bound the tenant
+// and webhook event tables of the dashboard overview.
+const (
+	adminDashboardTenantLimit = 20
+	adminDashboardEventLimit  = 25
+)
+
+// AdminDashboard serves the embedded admin UI mounted at /admin.
+func AdminDashboard() http.Handler {
+	// fs.Sub only fails for an invalid directory name.
+	files, _ := fs.Sub(adminDashboardFiles, "admin_ui")
+	fileServer := http.StripPrefix("/admin", http.FileServer(http.FS(files)))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Security-Policy", "default-src 'self'; frame-ancestors 'none'")
+		w.Header().Set("Cache-Control", "no-store")
+		fileServer.ServeHTTP(w, r)
+	})
+}
+
+// JobStatsReader reports job queue counts.
+type JobStatsReader interface {
+	GetStats(ctx context.Context) (*models.JobStats, error)
+}
+
+// TenantMetricsReader reports request metrics for every tenant, busiest
+// first.
+type TenantMetricsReader interface {
+	GetAllMetrics(ctx context.Context) ([]models.RequestMetrics, error)
+}
+
+// PlanVersionLister lists the plan versions that are not archived.
+type PlanVersionLister interface {
+	ListPlanVersions(ctx context.Context) ([]models.PlanWithCurrentVersion, error)
+}
+
+// WebhookEventLister lists recent events recorded in the outbox.
+type WebhookEventLister interface {
+	ListRecentOutboxEvents(ctx context.Context, topicPrefix string, limit int) ([]models.OutboxEvent, error)
+}
+
+// AdminDashboardOverview returns what the admin dashboard shows: job queue
+// counts, the busiest tenants, plan versions and the billing events recorded
+// from recent Stripe webhooks. jobs and plans may be nil when the job queue
+// or billing is not configured; their sections are then null.
+func AdminDashboardOverview(jobs JobStatsReader, tenants TenantMetricsReader, plans PlanVersionLister, webhooks WebhookEventLister) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var overview struct {
+			Queue         *models.JobStats                `json:"queue"`
+			Tenants       []models.RequestMetrics         `json:"tenants"`
+			PlanVersions  []models.PlanWithCurrentVersion `json:"plan_versions"`
+			WebhookEvents []models.OutboxEvent            `json:"webhook_events"`
+		}
+		var err error
+
+		if jobs != nil {
+			if overview.Queue, err = jobs.GetStats(r.Context()); err != nil {
+				log.Printf("AdminDashboardOverview: job stats: %v", err)
+				http.Error(w, "failed to load job queue stats", http.StatusInternalServerError)
+				return
+			}
+		}
+
+		if overview.Tenants, err = tenants.GetAllMetrics(r.Context()); err != nil {
+			log.Printf("AdminDashboardOverview: tenant metrics: %v", err)
+			http.Error(w, "failed to load tenant metrics", http.StatusInternalServerError)
+			return
+		}
+		if len(overview.Tenants) > adminDashboardTenantLimit {
+			overview.Tenants = overview.Tenants[:adminDashboardTenantLimit]
+		}
+
+		if plans != nil {
+			if overview.PlanVersions, err = plans.ListPlanVersions(r.Context()); err != nil {
+				log.Printf("AdminDashboardOverview: plan versions: %v", err)
+				http.Error(w, "failed to load plan versions", http.StatusInternalServerError)
+				return
+			}
+			if overview.PlanVersions == nil {
+				overview.PlanVersions = []models.PlanWithCurrentVersion{}
+			}
+		}
+
+		if overview.WebhookEvents, err = webhooks.ListRecentOutboxEvents(r.Context(), "billing.", adminDashboardEventLimit); err != nil {
+			log.Printf("AdminDashboardOverview: webhook events: %v", err)
+			http.Error(w, "failed to load webhook events", http.StatusInternalServerError)
+			return
+		}
+
+		if overview.Tenants == nil {
+			overview.Tenants = []models.RequestMetrics{}
+		}
+		if overview.WebhookEvents == nil {
+			overview.WebhookEvents = []models.OutboxEvent{}
+		}
+		w.Header().Set("Cache-Control", "no-store")
+		writeJSON(w, http.StatusOK, overview)
+	}
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+)
+
+func TestAdminDashboardRequiresPermission(t *testing.T) {
+	const secret = "secret"
+	users := fakeUserAccessStore{"user@example.com": {UserID: 1, Email: "user@example.com", Role: models.RoleUser, EmailVerified: true}}
+	dashboard := RequirePermission(users, secret, []string{"User@Example.com"}, models.PermissionAdminDashboard)(AdminDashboard())
+
+	cases := []struct {
+		name   string
+		cookie bool
+		query  string
+		want   int
+	}{
+		{"anonymous", false, "", http.StatusUnauthorized},
+		{"email in query is not trusted", false, "?email=user@example.com", http.StatusUnauthorized},
+		{"admin session", true, "", http.StatusOK},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/admin/"+tc.query, nil)
+		if tc.cookie {
+			req.AddCookie(sessionCookie(t, secret, "sid"))
+		}
+		rec := httptest.NewRecorder()
+		dashboard.ServeHTTP(rec, req)
+		if rec.Code != tc.want {
+			t.Fatalf("%s: expected %d, got %d", tc.name, tc.want, rec.Code)
+		}
+		if tc.want == http.StatusOK && !strings.Contains(rec.Body.String(), "/admin/admin.js") {
+			t.Fatalf("%s: expected the dashboard page, got %s", tc.name, rec.Body.String())
+		}
+	}
+
+	notAdmin := RequirePermission(users, secret, []string{"ops@example.com"}, models.PermissionAdminDashboard)(AdminDashboard())
+	req := httptest.NewRequest(http.MethodGet, "/admin/", nil)
+	req.AddCookie(sessionCookie(t, secret, "sid"))
+	rec := httptest.NewRecorder()
+	notAdmin.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("non-admin: expected 403, got %d", rec.Code)
+	}
+
+	unverified := RequirePermission(fakeUserAccessStore{"user@example.com": {Email: "user@example.com", Role: models.RoleAdmin}}, secret, nil, models.PermissionAdminDashboard)(AdminDashboard())
+	rec = httptest.NewRecorder()
+	unverified.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("unverified admin: expected 403, got %d", rec.Code)
+	}
+}
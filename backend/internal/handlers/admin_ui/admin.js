@@ -0,0 +1,122 @@
+// Renders /admin/api/overview and refreshes it every 30 seconds. Values are
+// only ever set as text so event payloads cannot inject markup.
+(function () {
+  'use strict';
+
+  var refreshMs = 30000;
+
+  function cell(row, value) {
+    var td = document.createElement('td');
+    td.textContent = value === null || value === undefined ? '' : String(value);
+    row.appendChild(td);
+    return td;
+  }
+
+  function fill(id, items, columns, empty) {
+    var body = document.getElementById(id);
+    body.textContent = '';
+    if (!items || items.length === 0) {
+      var row = body.insertRow();
+      var td = cell(row, empty);
+      td.colSpan = columns;
+      return null;
+    }
+    return body;
+  }
+
+  function renderQueue(queue) {
+    var el = document.getElementById('queue');
+    el.textContent = '';
+    if (!queue) {
+      el.textContent = 'Job queue not configured.';
+      return;
+    }
+    ['pending', 'processing', 'failed', 'completed', 'cancelled', 'total'].forEach(function (key) {
+      var stat = document.createElement('div');
+      stat.className = 'stat';
+      var n = document.createElement('strong');
+      n.textContent = queue[key];
+      stat.appendChild(n);
+      stat.appendChild(document.createTextNode(key));
+      el.appendChild(stat);
+    });
+  }
+
+  function renderTenants(tenants) {
+    var body = fill('tenants', tenants, 6, 'No requests recorded.');
+    if (!body) return;
+    tenants.forEach(function (t) {
+      var row = body.insertRow();
+      cell(row, t.user_id);
+      cell(row, t.total_requests);
+      cell(row, t.error_requests);
+      cell(row, t.avg_response_time_ms);
+      cell(row, t.total_bytes);
+      cell(row, t.last_request_at);
+    });
+  }
+
+  function renderPlanVersions(versions) {
+    var body = fill('plan-versions', versions, 6, versions ? 'No plan versions.' : 'Billing not configured.');
+    if (!body) return;
+    versions.forEach(function (p) {
+      var v = p.version;
+      var row = body.insertRow();
+      cell(row, p.plan.name + ' (' + p.plan.slug + ')');
+      cell(row, v.version);
+      cell(row, v.status);
+      cell(row, (v.price_cents / 100).toFixed(2) + ' ' + v.currency.toUpperCase() + ' / ' + v.billing_interval);
+      cell(row, v.stripe_price_id);
+      cell(row, v.migration_deadline);
+    });
+  }
+
+  function renderWebhookEvents(events) {
+    var body = fill('webhook-events', events, 5, 'No recent webhook events.');
+    if (!body) return;
+    events.forEach(function (ev) {
+      var row = body.insertRow();
+      cell(row, ev.id);
+      cell(row, ev.topic);
+      cell(row, ev.created_at);
+      if (ev.failed_at) {
+        cell(row, 'failed: ' + (ev.last_error || '')).className = 'error';
+      } else if (ev.dispatched_at) {
+        cell(row, 'delivered ' + ev.dispatched_at);
+      } else {
+        cell(row, 'pending (' + ev.attempts + ' attempts)');
+      }
+      var code = document.createElement('code');
+      code.textContent = JSON.stringify(ev.payload);
+      cell(row, '').appendChild(code);
+    });
+  }
+
+  function load() {
+    var status = document.getElementById('status');
+    fetch('/admin/api/overview', { credentials: 'same-origin' })
+      .then(function (res) {
+        if (!res.ok) {
+          return res.text().then(function (text) { throw new Error(res.status + ': ' + text.trim()); });
+        }
+        return res.json();
+      })
+      .then(function (overview) {
+        renderQueue(overview.queue);
+        renderTenants(overview.tenants);
+        renderPlanVersions(overview.plan_versions);
+        renderWebhookEvents(overview.webhook_events);
+        status.className = '';
+        status.textContent = 'Updated ' + new Date().toLocaleTimeString();
+      })
+      .catch(function (err) {
+        status.className = 'error';
+        status.textContent = 'Failed to load: ' + err.message;
+      })
+      .finally(function () {
+        setTimeout(load, refreshMs);
+      });
+  }
+
+  load();
+})();
@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+	storepkg "github.com/PortNumber53/mcp-jira-thing/backend/internal/store"
+)
+
+// APIKeyHeader carries the API key of a server-to-server caller.
+const APIKeyHeader = "X-API-Key"
+
+// maxAPIKeyLifetimeDays bounds expires_in_days when creating a key.
+const maxAPIKeyLifetimeDays = 3650
+
+// APIKeyAuthenticator checks the API keys callers present.
+type APIKeyAuthenticator interface {
+	AuthenticateAPIKey(ctx context.Context, key string) (*models.APIKey, error)
+}
+
+type apiKeyContextKey struct{}
+
+// apiKeyFromContext returns the API key APIKeyAuth accepted, if any.
+func apiKeyFromContext(ctx context.Context) *models.APIKey {
+	key, _ := ctx.Value(apiKeyContextKey{}).(*models.APIKey)
+	return key
+}
+
+// APIKeyAuth authenticates requests carrying an API key in X-API-Key and
+// remembers the key for RequireAPIKeyScope and RequirePermission. Requests
+// without the header pass through untouched; an unknown, revoked or expired
+// key is answered with 401 so a misconfigured worker fails loudly.
+func APIKeyAuth(store APIKeyAuthenticator) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			presented := strings.TrimSpace(r.Header.Get(APIKeyHeader))
+			if presented == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			key, err := store.AuthenticateAPIKey(r.Context(), presented)
+			if errors.Is(err, storepkg.ErrAPIKeyInvalid) {
+				writeJSON(w, http.StatusUnauthorized, map[string]any{"error": "invalid_api_key"})
+				return
+			}
+			if err != nil {
+				log.Printf("APIKeyAuth: failed to check api key: %v", err)
+				http.Error(w, "failed to check api key", http.StatusBadGateway)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), apiKeyContextKey{}, key)))
+		})
+	}
+}
+
+// RequireAPIKeyScope restricts routes to trusted callers. A request whose
+// API key carries scope passes and one whose key lacks it gets 403. Requests
+// without a key are handed to fallback, such as the worker signature check,
+// or refused with 401 when fallback is nil.
+func RequireAPIKeyScope(scope string, fallback func(http.Handler) http.Handler) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		var withoutKey http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, http.StatusUnauthorized, map[string]any{"error": "api_key_required"})
+		})
+		if fallback != nil {
+			withoutKey = fallback(next)
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := apiKeyFromContext(r.Context())
+			if key == nil {
+				withoutKey.ServeHTTP(w, r)
+				return
+			}
+			if !key.HasScope(scope) {
+				writeJSON(w, http.StatusForbidden, map[string]any{"error": "insufficient_scope", "scope": scope})
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// APIKeyStore manages API keys.
+type APIKeyStore interface {
+	CreateAPIKey(ctx context.Context, name string, scopes []string, createdBy int64, expiresAt *time.Time) (*models.APIKey, string, error)
+	ListAPIKeys(ctx context.Context) ([]models.APIKey, error)
+	RevokeAPIKey(ctx context.Context, id int64) (*models.APIKey, error)
+}
+
+type createAPIKeyPayload struct {
+	Name          string   `json:"name"`
+	Scopes        []string `json:"scopes"`
+	ExpiresInDays int      `json:"expires_in_days"`
+}
+
+// APIKeys lists or creates API keys. It must be mounted behind
+// RequirePermission with models.PermissionManageAPIKeys.
+// GET lists every key without its secret.
+// POST {"name": "mcp worker", "scopes": ["tenant"], "expires_in_days": 90}
+// returns the new key once in "key"; only its hash is stored.
+func APIKeys(store APIKeyStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			keys, err := store.ListAPIKeys(r.Context())
+			if err != nil {
+				log.Printf("APIKeys: failed to list api keys: %v", err)
+				http.Error(w, "failed to list api keys", http.StatusBadGateway)
+				return
+			}
+			writeJSON(w, http.StatusOK, map[string]any{"api_keys": keys})
+
+		case http.MethodPost:
+			var payload createAPIKeyPayload
+			if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+				http.Error(w, "invalid JSON payload", http.StatusBadRequest)
+				return
+			}
+			payload.Name = strings.TrimSpace(payload.Name)
+			if payload.Name == "" {
+				http.Error(w, "name is required", http.StatusBadRequest)
+				return
+			}
+			if len(payload.Scopes) == 0 {
+				http.Error(w, "at least one scope is required", http.StatusBadRequest)
+				return
+			}
+			for _, scope := range payload.Scopes {
+				if !models.ValidAPIKeyScope(scope) {
+					http.Error(w, "scopes must be tenant, auth, jobs or admin", http.StatusBadRequest)
+					return
+				}
+			}
+			if payload.ExpiresInDays < 0 || payload.ExpiresInDays > maxAPIKeyLifetimeDays {
+				http.Error(w, "expires_in_days must be between 0 and 3650", http.StatusBadRequest)
+				return
+			}
+			var expiresAt *time.Time
+			if payload.ExpiresInDays > 0 {
+				at := time.Now().Add(time.Duration(payload.ExpiresInDays) * 24 * time.Hour)
+				expiresAt = &at
+			}
+
+			var createdBy int64
+			if operator := operatorFromContext(r.Context()); operator != nil {
+				createdBy = operator.UserID
+			}
+			key, secret, err := store.CreateAPIKey(r.Context(), payload.Name, payload.Scopes, createdBy, expiresAt)
+			if err != nil {
+				log.Printf("APIKeys: failed to create api key name=%q: %v", payload.Name, err)
+				http.Error(w, "failed to create api key", http.StatusBadGateway)
+				return
+			}
+			writeJSON(w, http.StatusCreated, map[string]any{"api_key": key, "key": secret})
+
+		default:
+			w.Header().Set("Allow", "GET, POST")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// RevokeAPIKey revokes the API key in the {id} URL parameter. It must be
+// mounted behind RequirePermission with models.PermissionManageAPIKeys.
+func RevokeAPIKey(store APIKeyStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil || id <= 0 {
+			http.Error(w, "invalid api key id", http.StatusBadRequest)
+			return
+		}
+		key, err := store.RevokeAPIKey(r.Context(), id)
+		if errors.Is(err, storepkg.ErrAPIKeyNotFound) {
+			http.Error(w, "api key not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Printf("RevokeAPIKey: failed to revoke api key id=%d: %v", id, err)
+			http.Error(w, "failed to revoke api key", http.StatusBadGateway)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"api_key": key})
+	}
+}
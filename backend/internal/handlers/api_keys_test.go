@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+	storepkg "github.com/PortNumber53/mcp-jira-thing/backend/internal/store"
+)
+
+type fakeAPIKeyAuthenticator map[string]*models.APIKey
+
+func (f fakeAPIKeyAuthenticator) AuthenticateAPIKey(ctx context.Context, key string) (*models.APIKey, error) {
+	if found, ok := f[key]; ok {
+		return found, nil
+	}
+	return nil, storepkg.ErrAPIKeyInvalid
+}
+
+func TestAPIKeyAuthChecksScopes(t *testing.T) {
+	keys := fakeAPIKeyAuthenticator{
+		"mjt_00000001_tenant": {ID: 1, Scopes: []string{models.APIKeyScopeTenant}},
+		"mjt_00000002_admin":  {ID: 2, Scopes: []string{models.APIKeyScopeAdmin}},
+	}
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) })
+	denyWithoutKey := func(http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusTeapot) })
+	}
+	tenantRoute := APIKeyAuth(keys)(RequireAPIKeyScope(models.APIKeyScopeTenant, denyWithoutKey)(ok))
+	operatorRoute := APIKeyAuth(keys)(RequirePermission(fakeUserAccessStore{}, "secret", nil, models.PermissionOperateBackend)(ok))
+	requiredRoute := APIKeyAuth(keys)(RequireAPIKeyScope(models.APIKeyScopeAuth, nil)(ok))
+
+	for _, tc := range []struct {
+		name  string
+		route http.Handler
+		key   string
+		want  int
+	}{
+		{"tenant key", tenantRoute, "mjt_00000001_tenant", http.StatusNoContent},
+		{"key without scope", tenantRoute, "mjt_00000002_admin", http.StatusForbidden},
+		{"unknown key", tenantRoute, "mjt_00000003_guess", http.StatusUnauthorized},
+		{"no key falls back", tenantRoute, "", http.StatusTeapot},
+		{"admin key operates", operatorRoute, "mjt_00000002_admin", http.StatusNoContent},
+		{"tenant key cannot operate", operatorRoute, "mjt_00000001_tenant", http.StatusUnauthorized},
+		{"required key missing", requiredRoute, "", http.StatusUnauthorized},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/api/settings/jira/tenant", nil)
+		if tc.key != "" {
+			req.Header.Set(APIKeyHeader, tc.key)
+		}
+		rec := httptest.NewRecorder()
+		tc.route.ServeHTTP(rec, req)
+		if rec.Code != tc.want {
+			t.Errorf("%s: expected %d, got %d", tc.name, tc.want, rec.Code)
+		}
+	}
+}
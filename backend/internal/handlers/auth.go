@@ -3,12 +3,18 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/go-chi/chi/v5/middleware"
 
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/keyring"
 	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+	storepkg "github.com/PortNumber53/mcp-jira-thing/backend/internal/store"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/worker"
 )
 
 // OAuthStore defines the behaviour required from the storage client used
@@ -17,12 +23,21 @@ type OAuthStore interface {
 	UpsertGitHubUser(ctx context.Context, user models.GitHubAuthUser) error
 	UpsertGoogleUser(ctx context.Context, user models.GoogleAuthUser) error
 	GetConnectedAccounts(ctx context.Context, email string) ([]models.ConnectedAccount, error)
+	GetEnforcedSSOOrganization(ctx context.Context, email string) (*models.Organization, error)
+	EmailVerificationStatus(ctx context.Context, email string) (int64, bool, error)
+	RecordOAuthLoginEvent(ctx context.Context, provider, providerAccountID, ipAddress, userAgent string) error
+	CreateOAuthUserSession(ctx context.Context, provider, providerAccountID, ipAddress, userAgent string, ttl time.Duration) (string, error)
+	GetEffectivePlan(ctx context.Context, userID int64) (*models.MembershipPlan, error)
 }
 
 // GitHubAuth accepts GitHub OAuth login data (forwarded from the frontend
 // Worker) and persists it into the local database for multi-tenant Jira
-// configuration.
-func GitHubAuth(store OAuthStore) http.HandlerFunc {
+// configuration. GitHub does not guarantee the email belongs to the user, so
+// an unverified email keeps the account restricted and queues a verification
+// link; one that belongs to another verified account is rejected with 403.
+// A login that starts a session also gets an auth token signed with keys,
+// as /api/auth/token would return for the new session.
+func GitHubAuth(store OAuthStore, jobs JobEnqueuer, keys *keyring.Keyring) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		reqID := middleware.GetReqID(r.Context())
 		log.Printf("GitHubAuth: request received (req_id=%s, method=%s, content_length=%d)", reqID, r.Method, r.ContentLength)
@@ -47,16 +62,49 @@ func GitHubAuth(store OAuthStore) http.HandlerFunc {
 			return
 		}
 
-		if err := store.UpsertGitHubUser(r.Context(), payload); err != nil {
+		if payload.Email != nil && ssoRequired(w, r, store, *payload.Email) {
+			return
+		}
+
+		if err := store.UpsertGitHubUser(r.Context(), payload); errors.Is(err, storepkg.ErrEmailUnverified) {
+			log.Printf("GitHubAuth: unverified email matches a verified account (req_id=%s, github_id=%d, login=%s)", reqID, payload.GitHubID, payload.Login)
+			writeJSON(w, http.StatusForbidden, map[string]any{"error": "email_unverified"})
+			return
+		} else if errors.Is(err, storepkg.ErrAccountDeleted) {
+			log.Printf("GitHubAuth: login to a deleted account (req_id=%s, github_id=%d, login=%s)", reqID, payload.GitHubID, payload.Login)
+			writeJSON(w, http.StatusForbidden, map[string]any{"error": "account_deleted"})
+			return
+		} else if err != nil {
 			log.Printf("GitHubAuth: failed to persist GitHub user (req_id=%s, github_id=%d, login=%s): %v", reqID, payload.GitHubID, payload.Login, err)
 			http.Error(w, "failed to persist GitHub user", http.StatusBadGateway)
 			return
 		}
 
 		log.Printf("GitHubAuth: successfully upserted GitHub user (req_id=%s, github_id=%d, login=%s)", reqID, payload.GitHubID, payload.Login)
+		accountID := strconv.FormatInt(payload.GitHubID, 10)
+		recordOAuthLogin(r.Context(), store, "github", accountID, payload.ClientIP, payload.UserAgent)
+
+		response := map[string]any{"ok": true}
+		if payload.StartSession {
+			id := startOAuthSession(r.Context(), store, "github", accountID, payload.ClientIP, payload.UserAgent)
+			if id != "" {
+				response["session_id"] = id
+			}
+			if payload.Email != nil {
+				attachAuthToken(r.Context(), response, keys, store, *payload.Email, id)
+			}
+		}
+		if payload.Email != nil && *payload.Email != "" && !payload.EmailVerified {
+			verified, err := requestEmailVerification(r.Context(), store, jobs, *payload.Email)
+			if err != nil {
+				// Non-fatal: the user can ask for another link.
+				log.Printf("GitHubAuth: failed to queue email verification (req_id=%s, login=%s): %v", reqID, payload.Login, err)
+			}
+			response["email_verified"] = verified
+		}
 
 		w.Header().Set("Content-Type", "application/json")
-		if err := json.NewEncoder(w).Encode(map[string]any{"ok": true}); err != nil {
+		if err := json.NewEncoder(w).Encode(response); err != nil {
 			http.Error(w, "failed to encode response", http.StatusInternalServerError)
 			return
 		}
@@ -65,8 +113,9 @@ func GitHubAuth(store OAuthStore) http.HandlerFunc {
 
 // GoogleAuth accepts Google OAuth login data (forwarded from the frontend
 // Worker) and persists it into the local database for multi-tenant Jira
-// configuration.
-func GoogleAuth(store OAuthStore) http.HandlerFunc {
+// configuration. Like GitHubAuth, a login that starts a session also gets an
+// auth token.
+func GoogleAuth(store OAuthStore, keys *keyring.Keyring) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		reqID := middleware.GetReqID(r.Context())
 		log.Printf("GoogleAuth: request received (req_id=%s, method=%s, content_length=%d)", reqID, r.Method, r.ContentLength)
@@ -96,22 +145,101 @@ func GoogleAuth(store OAuthStore) http.HandlerFunc {
 			email = *payload.Email
 		}
 
-		if err := store.UpsertGoogleUser(r.Context(), payload); err != nil {
+		if ssoRequired(w, r, store, email) {
+			return
+		}
+
+		if err := store.UpsertGoogleUser(r.Context(), payload); errors.Is(err, storepkg.ErrAccountDeleted) {
+			log.Printf("GoogleAuth: login to a deleted account (req_id=%s, sub=%q, email=%q)", reqID, payload.Sub, email)
+			writeJSON(w, http.StatusForbidden, map[string]any{"error": "account_deleted"})
+			return
+		} else if err != nil {
 			log.Printf("GoogleAuth: failed to persist Google user (req_id=%s, sub=%q, email=%q): %v", reqID, payload.Sub, email, err)
 			http.Error(w, "failed to persist Google user", http.StatusBadGateway)
 			return
 		}
 
 		log.Printf("GoogleAuth: successfully upserted Google user (req_id=%s, sub=%q, email=%q)", reqID, payload.Sub, email)
+		recordOAuthLogin(r.Context(), store, "google", payload.Sub, payload.ClientIP, payload.UserAgent)
+
+		response := map[string]any{"ok": true}
+		if payload.StartSession {
+			id := startOAuthSession(r.Context(), store, "google", payload.Sub, payload.ClientIP, payload.UserAgent)
+			if id != "" {
+				response["session_id"] = id
+			}
+			attachAuthToken(r.Context(), response, keys, store, email, id)
+		}
 
 		w.Header().Set("Content-Type", "application/json")
-		if err := json.NewEncoder(w).Encode(map[string]any{"ok": true}); err != nil {
+		if err := json.NewEncoder(w).Encode(response); err != nil {
 			http.Error(w, "failed to encode response", http.StatusInternalServerError)
 			return
 		}
 	}
 }
 
+// attachAuthToken adds an auth token for the user who just signed in to a
+// login response, so the frontend Worker can call the backend as them
+// without a separate /api/auth/token round trip. Failures are logged and
+// leave the token out; the Worker falls back to exchanging the cookie.
+func attachAuthToken(ctx context.Context, response map[string]any, keys *keyring.Keyring, store AuthTokenStore, email, sessionID string) {
+	if email == "" || keys.Len() == 0 {
+		return
+	}
+	token, expiresAt, err := signUserToken(ctx, keys, store, email, sessionID)
+	if err != nil {
+		log.Printf("attachAuthToken: failed to issue token for email=%s: %v", email, err)
+		return
+	}
+	response["token"] = token
+	response["token_type"] = "Bearer"
+	response["expires_at"] = expiresAt.UTC().Format(time.RFC3339)
+}
+
+// requestEmailVerification queues a verification link for email unless it
+// is already verified, and reports whether it is. Repeated logins while a
+// link is being sent reuse the pending job.
+func requestEmailVerification(ctx context.Context, store emailStatusStore, jobs JobEnqueuer, email string) (bool, error) {
+	userID, verified, err := store.EmailVerificationStatus(ctx, email)
+	if err != nil || verified {
+		return verified, err
+	}
+	if jobs == nil {
+		return false, errors.New("job queue unavailable")
+	}
+
+	job := worker.EmailVerificationJob(userID, email)
+	job.DedupWindow = 10 * time.Minute
+	return false, jobs.Enqueue(ctx, job)
+}
+
+// ssoRequired rejects a social login with 403 when the email belongs to a
+// member of an organization that mandates SSO, so the frontend Worker does
+// not start a session. It reports whether the request was rejected.
+func ssoRequired(w http.ResponseWriter, r *http.Request, store OAuthStore, email string) bool {
+	if email == "" {
+		return false
+	}
+
+	org, err := store.GetEnforcedSSOOrganization(r.Context(), email)
+	if err != nil {
+		// Fail closed: an enforced organization must not be bypassed
+		// because the lookup failed.
+		log.Printf("ssoRequired: failed to check sso enforcement for email=%q: %v", email, err)
+		http.Error(w, "failed to check sso enforcement", http.StatusBadGateway)
+		return true
+	}
+	if org == nil {
+		return false
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusForbidden)
+	_ = json.NewEncoder(w).Encode(map[string]any{"error": "sso_required", "org": org.Slug})
+	return true
+}
+
 // ConnectedAccounts returns the list of OAuth providers connected to the user.
 func ConnectedAccounts(store OAuthStore) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -121,7 +249,7 @@ func ConnectedAccounts(store OAuthStore) http.HandlerFunc {
 			return
 		}
 
-		email := r.URL.Query().Get("email")
+		email := tokenEmail(r, r.URL.Query().Get("email"))
 		if email == "" {
 			http.Error(w, "email parameter is required", http.StatusBadRequest)
 			return
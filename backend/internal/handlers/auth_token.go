@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/authtoken"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/config"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/keyring"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/session"
+	storepkg "github.com/PortNumber53/mcp-jira-thing/backend/internal/store"
+)
+
+// AuthTokenStore defines the behaviour required to resolve the identity and
+// entitlements carried by an auth token.
+type AuthTokenStore interface {
+	EmailVerificationStatus(ctx context.Context, email string) (int64, bool, error)
+	GetEffectivePlan(ctx context.Context, userID int64) (*models.MembershipPlan, error)
+}
+
+// IssueAuthToken exchanges the caller's session cookie for a short-lived
+// signed JWT carrying their user id, email and plan entitlements. The
+// frontend Worker sends it as a bearer token instead of the user's email.
+// POST (session cookie required)
+func IssueAuthToken(cfg config.Config, store AuthTokenStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		sess, err := session.ReadSession(r, cfg.CookieSecret)
+		if err != nil || sess.Email == nil || *sess.Email == "" {
+			writeJSON(w, http.StatusUnauthorized, map[string]any{"error": "not_authenticated"})
+			return
+		}
+		email := *sess.Email
+
+		token, expiresAt, err := signUserToken(r.Context(), cfg.AuthTokenKeys, store, email, sess.SessionID)
+		if errors.Is(err, storepkg.ErrUserNotFound) {
+			writeJSON(w, http.StatusUnauthorized, map[string]any{"error": "not_authenticated"})
+			return
+		}
+		if err != nil {
+			log.Printf("IssueAuthToken: failed to issue token for email=%s: %v", email, err)
+			http.Error(w, "failed to issue token", http.StatusBadGateway)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, map[string]any{
+			"token":      token,
+			"token_type": "Bearer",
+			"expires_at": expiresAt.UTC().Format(time.RFC3339),
+		})
+	}
+}
+
+// signUserToken signs an auth token for the user with email, tied to the
+// server-side session sessionID when there is one.
+func signUserToken(ctx context.Context, keys *keyring.Keyring, store AuthTokenStore, email, sessionID string) (string, time.Time, error) {
+	userID, verified, err := store.EmailVerificationStatus(ctx, email)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	plan, err := store.GetEffectivePlan(ctx, userID)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("load plan for user_id=%d: %w", userID, err)
+	}
+
+	now := time.Now()
+	token, err := authtoken.Issue(keys, authtoken.Claims{
+		UserID:        userID,
+		Email:         email,
+		SessionID:     sessionID,
+		EmailVerified: verified,
+		Plan:          plan.Slug,
+		PlanTier:      plan.Tier,
+	}, now)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("sign token: %w", err)
+	}
+	return token, now.Add(authtoken.TTL), nil
+}
+
+// AuthToken verifies bearer JWTs issued by IssueAuthToken and makes their
+// claims the request's identity. Requests without one pass through
+// unchanged; an invalid or expired token is rejected with 401, and an email
+// in the query or JSON body that disagrees with the token with 403.
+func AuthToken(keys *keyring.Keyring) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || !authtoken.LooksLikeJWT(token) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			claims, err := authtoken.Verify(keys, token, time.Now())
+			if err != nil {
+				writeJSON(w, http.StatusUnauthorized, map[string]any{"error": "invalid_token"})
+				return
+			}
+
+			if email := r.URL.Query().Get("email"); email != "" && !strings.EqualFold(email, claims.Email) {
+				writeJSON(w, http.StatusForbidden, map[string]any{"error": "identity_mismatch"})
+				return
+			}
+			if r.Body != nil && r.Body != http.NoBody && strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+				body, err := io.ReadAll(r.Body)
+				if err != nil {
+					http.Error(w, "failed to read request body", http.StatusBadRequest)
+					return
+				}
+				r.Body = io.NopCloser(bytes.NewReader(body))
+
+				var payload struct {
+					UserEmail string `json:"user_email"`
+				}
+				if json.Unmarshal(body, &payload) == nil && payload.UserEmail != "" && !strings.EqualFold(payload.UserEmail, claims.Email) {
+					writeJSON(w, http.StatusForbidden, map[string]any{"error": "identity_mismatch"})
+					return
+				}
+			}
+
+			next.ServeHTTP(w, r.WithContext(authtoken.WithClaims(r.Context(), claims)))
+		})
+	}
+}
+
+// tokenEmail returns the email carried by the request's auth token, or the
+// trimmed fallback for requests without one. Behind CredentialIdentity the
+// fallback is replaced by the session's email.
+func tokenEmail(r *http.Request, fallback string) string {
+	if claims, ok := authtoken.FromContext(r.Context()); ok {
+		return claims.Email
+	}
+	if email, ok := credentialEmail(r.Context()); ok {
+		return email
+	}
+	return strings.TrimSpace(fallback)
+}
+
+// RequestUserID returns the user a request is made on behalf of, from its
+// auth token or the mcp_secret the MCP auth middleware resolved, or 0.
+func RequestUserID(r *http.Request) int64 {
+	if claims, ok := authtoken.FromContext(r.Context()); ok && claims.UserID > 0 {
+		return claims.UserID
+	}
+	return MCPUserID(r)
+}
+
+// MCPUserID returns the tenant the MCP auth middleware resolved from the
+// request's mcp_secret, or 0. Only these requests are logged to requests, so
+// only they count toward monthly quotas.
+func MCPUserID(r *http.Request) int64 {
+	userID, _ := r.Context().Value("user_id").(int64)
+	return userID
+}
+
+type credentialEmailKey struct{}
+
+// credentialEmail returns the session email CredentialIdentity resolved,
+// and whether the request went through it.
+func credentialEmail(ctx context.Context) (string, bool) {
+	email, ok := ctx.Value(credentialEmailKey{}).(string)
+	return email, ok
+}
+
+// CredentialIdentity makes handlers identify users only by the request's
+// bearer token or session cookie: an email in the query string or JSON body
+// no longer says who is calling, so no one can act as a user by naming them.
+// It must run after AuthToken. Requests without credentials are left
+// anonymous and answered by each handler as such.
+func CredentialIdentity(cookieSecret string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			email := ""
+			if sess, err := session.ReadSession(r, cookieSecret); err == nil && sess.Email != nil {
+				email = strings.TrimSpace(*sess.Email)
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), credentialEmailKey{}, email)))
+		})
+	}
+}
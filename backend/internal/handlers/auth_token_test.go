@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/authtoken"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/config"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/keyring"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+)
+
+type fakeAuthTokenStore struct{}
+
+func (fakeAuthTokenStore) EmailVerificationStatus(ctx context.Context, email string) (int64, bool, error) {
+	return 42, true, nil
+}
+
+func (fakeAuthTokenStore) GetEffectivePlan(ctx context.Context, userID int64) (*models.MembershipPlan, error) {
+	return &models.MembershipPlan{Slug: "pro", Tier: 2}, nil
+}
+
+func TestIssueAuthTokenExchangesSession(t *testing.T) {
+	cfg := config.Config{CookieSecret: "cookie-secret", AuthTokenKeys: keyring.Single("token-secret")}
+	handler := IssueAuthToken(cfg, fakeAuthTokenStore{})
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/auth/token", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without a session, got %d", rec.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/auth/token", nil)
+	req.AddCookie(sessionCookie(t, cfg.CookieSecret, "sid-1"))
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var body struct {
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	claims, err := authtoken.Verify(cfg.AuthTokenKeys, body.Token, time.Now())
+	if err != nil {
+		t.Fatalf("verify token: %v", err)
+	}
+	if claims.UserID != 42 || claims.Email != "user@example.com" || claims.SessionID != "sid-1" || claims.Plan != "pro" || !claims.EmailVerified {
+		t.Fatalf("unexpected claims: %+v", claims)
+	}
+}
+
+func TestAuthTokenMiddlewareSetsIdentity(t *testing.T) {
+	keys := keyring.Single("token-secret")
+	token, err := authtoken.Issue(keys, authtoken.Claims{UserID: 42, Email: "user@example.com"}, time.Now())
+	if err != nil {
+		t.Fatalf("issue token: %v", err)
+	}
+	handler := AuthToken(keys)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload struct {
+			UserEmail string `json:"user_email"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		writeJSON(w, http.StatusOK, map[string]any{"email": requestEmail(r, "cookie-secret", payload.UserEmail)})
+	}))
+
+	cases := []struct {
+		name   string
+		auth   string
+		target string
+		body   string
+		code   int
+		email  string
+	}{
+		{name: "token identity", auth: "Bearer " + token, target: "/api/notifications", code: http.StatusOK, email: "user@example.com"},
+		{name: "matching body", auth: "Bearer " + token, target: "/api/settings", body: `{"user_email":"USER@example.com"}`, code: http.StatusOK, email: "user@example.com"},
+		{name: "mismatched query", auth: "Bearer " + token, target: "/api/notifications?email=other@example.com", code: http.StatusForbidden},
+		{name: "mismatched body", auth: "Bearer " + token, target: "/api/settings", body: `{"user_email":"other@example.com"}`, code: http.StatusForbidden},
+		{name: "invalid token", auth: "Bearer a.b.c", target: "/api/notifications", code: http.StatusUnauthorized},
+		{name: "opaque bearer passes through", auth: "Bearer scim-token", target: "/api/notifications?email=other@example.com", code: http.StatusOK, email: "other@example.com"},
+	}
+	for _, tc := range cases {
+		method := http.MethodGet
+		var req *http.Request
+		if tc.body != "" {
+			method = http.MethodPost
+			req = httptest.NewRequest(method, tc.target, strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+		} else {
+			req = httptest.NewRequest(method, tc.target, nil)
+		}
+		req.Header.Set("Authorization", tc.auth)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != tc.code {
+			t.Fatalf("%s: expected %d, got %d", tc.name, tc.code, rec.Code)
+		}
+		if tc.email == "" {
+			continue
+		}
+		var body struct {
+			Email string `json:"email"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+			t.Fatalf("%s: decode response: %v", tc.name, err)
+		}
+		if body.Email != tc.email {
+			t.Fatalf("%s: expected identity %q, got %q", tc.name, tc.email, body.Email)
+		}
+	}
+}
+
+func TestGitHubAuthIssuesTokenForNewSession(t *testing.T) {
+	keys := keyring.Single("token-secret")
+	oauthStore := &fakeOAuthStore{fakeEmailStatusStore: fakeEmailStatusStore{}}
+	handler := GitHubAuth(oauthStore, &recordingEnqueuer{}, keys)
+
+	body := `{"github_id":1,"login":"octo","access_token":"tok","email":"octo@example.com","email_verified":true,"start_session":true}`
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/auth/github", strings.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var resp struct {
+		SessionID string `json:"session_id"`
+		Token     string `json:"token"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	claims, err := authtoken.Verify(keys, resp.Token, time.Now())
+	if err != nil {
+		t.Fatalf("verify token: %v", err)
+	}
+	if claims.Email != "octo@example.com" || claims.SessionID != "sid-1" || resp.SessionID != "sid-1" || !claims.EmailVerified {
+		t.Fatalf("unexpected claims %+v for session %q", claims, resp.SessionID)
+	}
+}
+
+func TestCredentialIdentityIgnoresRawEmails(t *testing.T) {
+	handler := CredentialIdentity("cookie-secret")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]any{
+			"request": requestEmail(r, "cookie-secret", "body@example.com"),
+			"token":   tokenEmail(r, r.URL.Query().Get("email")),
+		})
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/notifications?email=other@example.com", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if got := strings.TrimSpace(rec.Body.String()); got != `{"request":"","token":""}` {
+		t.Fatalf("expected no identity from raw emails, got %s", got)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/notifications?email=other@example.com", nil)
+	req.AddCookie(sessionCookie(t, "cookie-secret", "sid-1"))
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if got := strings.TrimSpace(rec.Body.String()); got != `{"request":"user@example.com","token":"user@example.com"}` {
+		t.Fatalf("expected the session identity, got %s", got)
+	}
+}
@@ -0,0 +1,236 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+	storepkg "github.com/PortNumber53/mcp-jira-thing/backend/internal/store"
+)
+
+// maxAutomationsPerUser caps how many automations a user may define.
+const maxAutomationsPerUser = 25
+
+// automationRunHistory is how many runs the run history returns.
+const automationRunHistory = 50
+
+// AutomationStore defines the behaviour required to manage scheduled Jira
+// automations.
+type AutomationStore interface {
+	UserLookup
+	CountAutomations(ctx context.Context, userID int64) (int, error)
+	CreateAutomation(ctx context.Context, a *models.JiraAutomation) (*models.JiraAutomation, error)
+	ListAutomations(ctx context.Context, userID int64) ([]models.JiraAutomation, error)
+	GetAutomation(ctx context.Context, userID, id int64) (*models.JiraAutomation, error)
+	UpdateAutomation(ctx context.Context, a *models.JiraAutomation) (*models.JiraAutomation, error)
+	DeleteAutomation(ctx context.Context, userID, id int64) error
+	ListAutomationRuns(ctx context.Context, userID, automationID int64, limit int) ([]models.JiraAutomationRun, error)
+}
+
+type automationPayload struct {
+	Name           string `json:"name"`
+	JQL            string `json:"jql"`
+	Days           []int  `json:"days"`
+	TimeOfDay      string `json:"time_of_day"`
+	TimeZone       string `json:"time_zone"`
+	Destination    string `json:"destination"`
+	DestinationURL string `json:"destination_url"`
+	MaxResults     int    `json:"max_results"`
+	Enabled        *bool  `json:"enabled"`
+}
+
+// automation builds a validated, scheduled automation from the payload.
+func (p automationPayload) automation(userID int64, now time.Time) (*models.JiraAutomation, error) {
+	a := &models.JiraAutomation{
+		UserID:         userID,
+		Name:           p.Name,
+		JQL:            p.JQL,
+		WeeklySchedule: models.WeeklySchedule{Days: p.Days, TimeOfDay: p.TimeOfDay, TimeZone: p.TimeZone},
+		Destination:    p.Destination,
+		DestinationURL: p.DestinationURL,
+		MaxResults:     p.MaxResults,
+		Enabled:        p.Enabled == nil || *p.Enabled,
+	}
+	if a.Days == nil {
+		a.Days = []int{}
+	}
+	if err := a.Validate(); err != nil {
+		return nil, err
+	}
+	next, err := a.NextRun(now)
+	if err != nil {
+		return nil, err
+	}
+	a.NextRunAt = next
+	return a, nil
+}
+
+// Automations lists or creates the caller's scheduled Jira automations. The
+// caller is resolved as for UsageForecast.
+// GET  lists the automations without their destination URLs.
+// POST {"name": "Standup", "jql": "project = OPS AND status = Blocked",
+// "days": [1,2,3,4,5], "time_of_day": "09:00", "time_zone": "Europe/Berlin",
+// "destination": "slack", "destination_url": "https://hooks.slack.com/..."}
+func Automations(store AutomationStore, cookieSecret string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := metricsUserID(w, r, store, cookieSecret)
+		if !ok {
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			automations, err := store.ListAutomations(r.Context(), userID)
+			if err != nil {
+				log.Printf("Automations: failed to list automations for user_id=%d: %v", userID, err)
+				http.Error(w, "failed to list automations", http.StatusBadGateway)
+				return
+			}
+			if automations == nil {
+				automations = []models.JiraAutomation{}
+			}
+			writeJSON(w, http.StatusOK, map[string]any{"automations": automations})
+
+		case http.MethodPost:
+			var payload automationPayload
+			if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+				http.Error(w, "invalid JSON payload", http.StatusBadRequest)
+				return
+			}
+			automation, err := payload.automation(userID, time.Now())
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			count, err := store.CountAutomations(r.Context(), userID)
+			if err != nil {
+				log.Printf("Automations: failed to count automations for user_id=%d: %v", userID, err)
+				http.Error(w, "failed to create automation", http.StatusBadGateway)
+				return
+			}
+			if count >= maxAutomationsPerUser {
+				writeJSON(w, http.StatusConflict, map[string]any{"error": "automation_limit_reached", "limit": maxAutomationsPerUser})
+				return
+			}
+
+			created, err := store.CreateAutomation(r.Context(), automation)
+			if err != nil {
+				log.Printf("Automations: failed to create automation for user_id=%d: %v", userID, err)
+				http.Error(w, "failed to create automation", http.StatusBadGateway)
+				return
+			}
+			writeJSON(w, http.StatusCreated, map[string]any{"automation": created})
+
+		default:
+			w.Header().Set("Allow", "GET, POST")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// Automation replaces or deletes the caller's automation in the {id} URL
+// parameter. PUT takes the same payload as creating one; the destination
+// URL must be sent again since it is never returned. Saving an automation
+// reschedules it from now.
+func Automation(store AutomationStore, cookieSecret string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := metricsUserID(w, r, store, cookieSecret)
+		if !ok {
+			return
+		}
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil || id <= 0 {
+			http.Error(w, "invalid automation id", http.StatusBadRequest)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodPut:
+			var payload automationPayload
+			if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+				http.Error(w, "invalid JSON payload", http.StatusBadRequest)
+				return
+			}
+			automation, err := payload.automation(userID, time.Now())
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			automation.ID = id
+
+			updated, err := store.UpdateAutomation(r.Context(), automation)
+			if errors.Is(err, storepkg.ErrAutomationNotFound) {
+				http.Error(w, "automation not found", http.StatusNotFound)
+				return
+			}
+			if err != nil {
+				log.Printf("Automation: failed to update automation id=%d for user_id=%d: %v", id, userID, err)
+				http.Error(w, "failed to update automation", http.StatusBadGateway)
+				return
+			}
+			writeJSON(w, http.StatusOK, map[string]any{"automation": updated})
+
+		case http.MethodDelete:
+			err := store.DeleteAutomation(r.Context(), userID, id)
+			if errors.Is(err, storepkg.ErrAutomationNotFound) {
+				http.Error(w, "automation not found", http.StatusNotFound)
+				return
+			}
+			if err != nil {
+				log.Printf("Automation: failed to delete automation id=%d for user_id=%d: %v", id, userID, err)
+				http.Error(w, "failed to delete automation", http.StatusBadGateway)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+
+		default:
+			w.Header().Set("Allow", "PUT, DELETE")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// AutomationRuns returns the latest runs of the caller's automation in the
+// {id} URL parameter, newest first.
+func AutomationRuns(store AutomationStore, cookieSecret string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := metricsUserID(w, r, store, cookieSecret)
+		if !ok {
+			return
+		}
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil || id <= 0 {
+			http.Error(w, "invalid automation id", http.StatusBadRequest)
+			return
+		}
+
+		automation, err := store.GetAutomation(r.Context(), userID, id)
+		if errors.Is(err, storepkg.ErrAutomationNotFound) {
+			http.Error(w, "automation not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Printf("AutomationRuns: failed to load automation id=%d for user_id=%d: %v", id, userID, err)
+			http.Error(w, "failed to load automation runs", http.StatusBadGateway)
+			return
+		}
+		runs, err := store.ListAutomationRuns(r.Context(), userID, id, automationRunHistory)
+		if err != nil {
+			log.Printf("AutomationRuns: failed to list runs of automation id=%d: %v", id, err)
+			http.Error(w, "failed to load automation runs", http.StatusBadGateway)
+			return
+		}
+		if runs == nil {
+			runs = []models.JiraAutomationRun{}
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"automation": automation, "runs": runs})
+	}
+}
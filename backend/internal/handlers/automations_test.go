@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+	storepkg "github.com/PortNumber53/mcp-jira-thing/backend/internal/store"
+)
+
+type mockAutomationStore struct {
+	automations []models.JiraAutomation
+}
+
+func (m *mockAutomationStore) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	return &models.User{ID: 7}, nil
+}
+
+func (m *mockAutomationStore) CountAutomations(ctx context.Context, userID int64) (int, error) {
+	return len(m.automations), nil
+}
+
+func (m *mockAutomationStore) CreateAutomation(ctx context.Context, a *models.JiraAutomation) (*models.JiraAutomation, error) {
+	a.ID = int64(len(m.automations) + 1)
+	m.automations = append(m.automations, *a)
+	return a, nil
+}
+
+func (m *mockAutomationStore) ListAutomations(ctx context.Context, userID int64) ([]models.JiraAutomation, error) {
+	return m.automations, nil
+}
+
+func (m *mockAutomationStore) GetAutomation(ctx context.Context, userID, id int64) (*models.JiraAutomation, error) {
+	for _, a := range m.automations {
+		if a.ID == id && a.UserID == userID {
+			return &a, nil
+		}
+	}
+	return nil, storepkg.ErrAutomationNotFound
+}
+
+func (m *mockAutomationStore) UpdateAutomation(ctx context.Context, a *models.JiraAutomation) (*models.JiraAutomation, error) {
+	return nil, storepkg.ErrAutomationNotFound
+}
+
+func (m *mockAutomationStore) DeleteAutomation(ctx context.Context, userID, id int64) error {
+	return storepkg.ErrAutomationNotFound
+}
+
+func (m *mockAutomationStore) ListAutomationRuns(ctx context.Context, userID, automationID int64, limit int) ([]models.JiraAutomationRun, error) {
+	return nil, nil
+}
+
+func postAutomation(t *testing.T, store AutomationStore, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/api/automations", strings.NewReader(body))
+	req = req.WithContext(context.WithValue(req.Context(), "user_id", int64(7)))
+	rec := httptest.NewRecorder()
+	Automations(store, "").ServeHTTP(rec, req)
+	return rec
+}
+
+func TestAutomationsCreateSchedulesNextRun(t *testing.T) {
+	store := &mockAutomationStore{}
+	rec := postAutomation(t, store, `{"name": "Standup", "jql": "project = OPS", "days": [1,2,3,4,5],
+		"time_of_day": "09:00", "time_zone": "Europe/Berlin", "destination": "slack",
+		"destination_url": "https://hooks.slack.com/services/T0/B0/secret"}`)
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if strings.Contains(rec.Body.String(), "secret") {
+		t.Fatalf("destination URL leaked in response: %s", rec.Body.String())
+	}
+	created := store.automations[0]
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("time zone data unavailable: %v", err)
+	}
+	local := created.NextRunAt.In(berlin)
+	if !created.Enabled || created.MaxResults != 20 || local.Hour() != 9 || local.Minute() != 0 || !created.NextRunAt.After(time.Now()) {
+		t.Fatalf("unexpected automation %+v", created)
+	}
+	if day := local.Weekday(); day == 0 || day == 6 {
+		t.Fatalf("expected a weekday run, got %v", day)
+	}
+}
+
+func TestAutomationsRejectInvalidDefinitions(t *testing.T) {
+	cases := map[string]string{
+		"non slack url": `{"name": "a", "jql": "x", "time_of_day": "09:00", "destination": "slack", "destination_url": "https://example.com/hook"}`,
+		"plain http":    `{"name": "a", "jql": "x", "time_of_day": "09:00", "destination": "webhook", "destination_url": "http://example.com/hook"}`,
+		"bad time":      `{"name": "a", "jql": "x", "time_of_day": "9am", "destination": "webhook", "destination_url": "https://example.com/hook"}`,
+		"bad day":       `{"name": "a", "jql": "x", "days": [7], "time_of_day": "09:00", "destination": "webhook", "destination_url": "https://example.com/hook"}`,
+	}
+	for name, body := range cases {
+		t.Run(name, func(t *testing.T) {
+			store := &mockAutomationStore{}
+			if rec := postAutomation(t, store, body); rec.Code != http.StatusBadRequest {
+				t.Fatalf("expected 400, got %d: %s", rec.Code, rec.Body.String())
+			}
+			if len(store.automations) != 0 {
+				t.Fatalf("expected nothing stored, got %+v", store.automations)
+			}
+		})
+	}
+}
+
+func TestAutomationRunsRequiresOwnAutomation(t *testing.T) {
+	store := &mockAutomationStore{automations: []models.JiraAutomation{{ID: 1, UserID: 8}}}
+	req := httptest.NewRequest(http.MethodGet, "/api/automations/1/runs", nil)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", "1")
+	ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+	req = req.WithContext(context.WithValue(ctx, "user_id", int64(7)))
+	rec := httptest.NewRecorder()
+
+	AutomationRuns(store, "").ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
@@ -3,12 +3,14 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
-	"strings"
 	"time"
 
 	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+	storepkg "github.com/PortNumber53/mcp-jira-thing/backend/internal/store"
+	stripeClient "github.com/PortNumber53/mcp-jira-thing/backend/internal/stripe"
 )
 
 // BillingStore defines the behaviour required from the storage client
@@ -24,9 +26,21 @@ type BillingStore interface {
 // UserStore defines the behaviour required for user lookup operations.
 type UserStore interface {
 	GetUserByEmail(ctx context.Context, email string) (*models.User, error)
-	DeleteUser(ctx context.Context, email string) error
+	DeleteUser(ctx context.Context, email string) (*models.AccountDeletion, error)
+	RestoreUser(ctx context.Context, email string) error
+	RevokeAllUserSessions(ctx context.Context, userID int64) (int64, error)
 }
 
+// SubscriptionCanceller cancels Stripe subscriptions; *stripe.Client
+// implements it.
+type SubscriptionCanceller interface {
+	CancelSubscription(subscriptionID string, opts stripeClient.CancelOptions) (*stripeClient.Cancellation, error)
+}
+
+// accountDeletionFollowUpTimeout bounds the writes DeleteAccount makes after
+// calling Stripe, which can return after the request itself timed out.
+const accountDeletionFollowUpTimeout = 5 * time.Second
+
 type saveSubscriptionPayload struct {
 	UserEmail            string     `json:"user_email"`
 	StripeCustomerID     string     `json:"stripe_customer_id"`
@@ -69,7 +83,7 @@ func SaveSubscription(store BillingStore, userStore UserStore) http.HandlerFunc
 		log.Printf("SaveSubscription: received payload for user=%s, status=%s, cancel_at_period_end=%v, canceled_at=%v",
 			payload.UserEmail, payload.Status, payload.CancelAtPeriodEnd, payload.CanceledAt)
 
-		userEmail := strings.TrimSpace(payload.UserEmail)
+		userEmail := tokenEmail(r, payload.UserEmail)
 		if userEmail == "" || payload.StripeCustomerID == "" || payload.StripeSubscriptionID == "" {
 			http.Error(w, "missing required fields", http.StatusBadRequest)
 			return
@@ -134,7 +148,7 @@ func SavePayment(store BillingStore, userStore UserStore) http.HandlerFunc {
 			return
 		}
 
-		userEmail := strings.TrimSpace(payload.UserEmail)
+		userEmail := tokenEmail(r, payload.UserEmail)
 		if userEmail == "" || payload.StripeCustomerID == "" {
 			http.Error(w, "missing required fields", http.StatusBadRequest)
 			return
@@ -179,7 +193,7 @@ func GetPaymentHistory(store BillingStore, userStore UserStore) http.HandlerFunc
 			return
 		}
 
-		email := strings.TrimSpace(r.URL.Query().Get("email"))
+		email := tokenEmail(r, r.URL.Query().Get("email"))
 		if email == "" {
 			http.Error(w, "email query parameter is required", http.StatusBadRequest)
 			return
@@ -207,7 +221,7 @@ func GetSubscription(store BillingStore) http.HandlerFunc {
 			return
 		}
 
-		email := strings.TrimSpace(r.URL.Query().Get("email"))
+		email := tokenEmail(r, r.URL.Query().Get("email"))
 		if email == "" {
 			http.Error(w, "email query parameter is required", http.StatusBadRequest)
 			return
@@ -227,8 +241,16 @@ func GetSubscription(store BillingStore) http.HandlerFunc {
 	}
 }
 
-// DeleteAccount handles account deletion including Stripe subscription cancellation with prorated refund.
-func DeleteAccount(billingStore BillingStore, userStore UserStore, stripeKey string) http.HandlerFunc {
+// DeleteAccount deletes an account and cancels its active subscription in
+// Stripe, refunding the unused part of the period. The account is deleted
+// first and restored if the subscription cannot be cancelled, so an account
+// is never left active without the subscription it paid for, nor deleted
+// while still being billed. The user's sessions are revoked only once the
+// deletion stands. The response summarises what was deleted. An operator
+// can restore the account until its retention window passes.
+// stripe may be nil when Stripe is not configured.
+// POST {"email": "..."}
+func DeleteAccount(billingStore BillingStore, userStore UserStore, stripe SubscriptionCanceller) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
 			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
@@ -245,42 +267,87 @@ func DeleteAccount(billingStore BillingStore, userStore UserStore, stripeKey str
 			return
 		}
 
-		if payload.Email == "" {
+		email := tokenEmail(r, payload.Email)
+		if email == "" {
 			http.Error(w, "email is required", http.StatusBadRequest)
 			return
 		}
 
-		// Check if user has an active subscription
-		subscription, err := billingStore.GetSubscription(r.Context(), payload.Email)
+		// Deleted users have no subscription to look up, so this goes first.
+		subscription, err := billingStore.GetSubscription(r.Context(), email)
 		if err != nil {
-			// Log the error but continue with deletion even if we can't check subscription
-			// This ensures users can still delete their account even if subscription lookup fails
-			log.Printf("DeleteAccount: warning - failed to check subscription: %v", err)
-			subscription = nil
+			log.Printf("DeleteAccount: failed to check subscription for %s: %v", email, err)
+			http.Error(w, "failed to check subscription", http.StatusBadGateway)
+			return
 		}
-
-		// If there's an active subscription, cancel it with prorated refund
-		if subscription != nil && subscription.StripeSubscriptionID != "" {
-			// Note: In a production environment, you would use the Stripe Go SDK here
-			// For now, we'll log the cancellation request
-			// The frontend should handle the Stripe API call with the secret key
-			log.Printf("DeleteAccount: cancelling Stripe subscription %s for user %s with proration",
-				subscription.StripeSubscriptionID, payload.Email)
+		subscribed := subscription != nil && subscription.StripeSubscriptionID != ""
+		if subscribed && stripe == nil {
+			http.Error(w, "billing is not configured", http.StatusServiceUnavailable)
+			return
 		}
 
-		// Delete the user from the database
-		if err := userStore.DeleteUser(r.Context(), payload.Email); err != nil {
+		deletion, err := userStore.DeleteUser(r.Context(), email)
+		if errors.Is(err, storepkg.ErrUserNotFound) {
+			http.Error(w, "user not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
 			log.Printf("DeleteAccount: failed to delete user: %v", err)
 			http.Error(w, "failed to delete account", http.StatusInternalServerError)
 			return
 		}
 
-		log.Printf("DeleteAccount: successfully deleted account for user %s", payload.Email)
+		// Stripe calls take no context, so the writes after them must not
+		// depend on the request still being live.
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), accountDeletionFollowUpTimeout)
+		defer cancel()
+
+		if subscribed {
+			cancellation, err := stripe.CancelSubscription(subscription.StripeSubscriptionID,
+				stripeClient.CancelOptions{RefundUnused: true})
+			if cancellation == nil {
+				log.Printf("DeleteAccount: failed to cancel Stripe subscription %s: %v", subscription.StripeSubscriptionID, err)
+				if err := userStore.RestoreUser(ctx, email); err != nil {
+					log.Printf("DeleteAccount: failed to restore %s after the cancellation failed: %v", email, err)
+				}
+				http.Error(w, "failed to cancel subscription", http.StatusBadGateway)
+				return
+			}
+			deletion.Subscription = &models.SubscriptionCancellation{
+				StripeSubscriptionID: cancellation.SubscriptionID,
+				RefundID:             cancellation.RefundID,
+				RefundCents:          cancellation.RefundCents,
+				Currency:             cancellation.Currency,
+			}
+			if err != nil {
+				// The subscription is cancelled either way; the refund can be
+				// issued by hand from the Stripe dashboard.
+				log.Printf("DeleteAccount: refund failed for Stripe subscription %s: %v", subscription.StripeSubscriptionID, err)
+				deletion.Subscription.RefundError = err.Error()
+			}
+
+			now := time.Now()
+			subscription.Status = "canceled"
+			subscription.CanceledAt = &now
+			if err := billingStore.UpdateSubscription(ctx, subscription); err != nil {
+				// The webhook will reconcile local state, so don't fail the request.
+				log.Printf("DeleteAccount: failed to persist cancellation: %v", err)
+			}
+		}
+
+		if _, err := userStore.RevokeAllUserSessions(ctx, deletion.UserID); err != nil {
+			// The account is deleted either way, and no lookup finds it
+			// while it is.
+			log.Printf("DeleteAccount: failed to revoke sessions of %s: %v", email, err)
+		}
+
+		log.Printf("DeleteAccount: successfully deleted account for user %s", email)
 
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]any{
-			"success": true,
-			"message": "Account deleted successfully",
+			"success":  true,
+			"message":  "Account deleted successfully",
+			"deletion": deletion,
 		})
 	}
 }
@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/store"
+	stripeClient "github.com/PortNumber53/mcp-jira-thing/backend/internal/stripe"
+)
+
+type fakeAccountStore struct {
+	sub      *models.Subscription
+	updated  *models.Subscription
+	deleted  []string
+	restored []string
+	revoked  []int64
+	missing  bool
+	failing  bool
+}
+
+func (f *fakeAccountStore) SaveSubscription(ctx context.Context, sub *models.Subscription) error {
+	return nil
+}
+
+func (f *fakeAccountStore) GetSubscription(ctx context.Context, userEmail string) (*models.Subscription, error) {
+	return f.sub, nil
+}
+
+func (f *fakeAccountStore) UpdateSubscription(ctx context.Context, sub *models.Subscription) error {
+	f.updated = sub
+	return nil
+}
+
+func (f *fakeAccountStore) SavePayment(ctx context.Context, payment *models.PaymentHistory) error {
+	return nil
+}
+
+func (f *fakeAccountStore) GetPaymentHistory(ctx context.Context, userEmail string) ([]models.PaymentHistory, error) {
+	return nil, nil
+}
+
+func (f *fakeAccountStore) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	return &models.User{ID: 3, Email: &email}, nil
+}
+
+func (f *fakeAccountStore) DeleteUser(ctx context.Context, email string) (*models.AccountDeletion, error) {
+	if f.missing {
+		return nil, store.ErrUserNotFound
+	}
+	if f.failing {
+		return nil, errors.New("connection reset")
+	}
+	f.deleted = append(f.deleted, email)
+	return &models.AccountDeletion{UserID: 3, Email: email, Retained: map[string]int64{"subscriptions": 1}}, nil
+}
+
+func (f *fakeAccountStore) RestoreUser(ctx context.Context, email string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	f.restored = append(f.restored, email)
+	return nil
+}
+
+func (f *fakeAccountStore) RevokeAllUserSessions(ctx context.Context, userID int64) (int64, error) {
+	f.revoked = append(f.revoked, userID)
+	return 2, nil
+}
+
+type fakeCanceller struct {
+	cancelled []string
+	refundErr error
+	cancelErr error
+}
+
+func (f *fakeCanceller) CancelSubscription(subscriptionID string, opts stripeClient.CancelOptions) (*stripeClient.Cancellation, error) {
+	if f.cancelErr != nil {
+		return nil, f.cancelErr
+	}
+	f.cancelled = append(f.cancelled, subscriptionID)
+	return &stripeClient.Cancellation{SubscriptionID: subscriptionID, RefundID: "re_1", RefundCents: 1500, Currency: "usd"}, f.refundErr
+}
+
+func TestDeleteAccountCancelsSubscription(t *testing.T) {
+	accounts := &fakeAccountStore{sub: &models.Subscription{ID: 9, StripeSubscriptionID: "sub_1", Status: "active"}}
+	stripe := &fakeCanceller{refundErr: errors.New("charge already refunded")}
+
+	rec := httptest.NewRecorder()
+	DeleteAccount(accounts, accounts, stripe)(rec, httptest.NewRequest(http.MethodPost, "/api/account/delete", strings.NewReader(`{"email":"user@example.com"}`)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if len(stripe.cancelled) != 1 || stripe.cancelled[0] != "sub_1" {
+		t.Fatalf("expected sub_1 to be cancelled, got %v", stripe.cancelled)
+	}
+	if accounts.updated == nil || accounts.updated.Status != "canceled" || accounts.updated.CanceledAt == nil {
+		t.Fatalf("expected the local subscription to be marked canceled, got %+v", accounts.updated)
+	}
+	if len(accounts.revoked) != 1 || accounts.revoked[0] != 3 {
+		t.Fatalf("expected the user's sessions to be revoked, got %v", accounts.revoked)
+	}
+
+	var resp struct {
+		Deletion models.AccountDeletion `json:"deletion"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp.Deletion.Retained["subscriptions"] != 1 || resp.Deletion.Subscription == nil {
+		t.Fatalf("unexpected deletion summary: %+v", resp.Deletion)
+	}
+	if resp.Deletion.Subscription.RefundCents != 1500 || resp.Deletion.Subscription.RefundError == "" {
+		t.Fatalf("expected the refund and its failure in the summary, got %+v", resp.Deletion.Subscription)
+	}
+}
+
+func TestDeleteAccountKeepsSubscriptionWhenDeleteFails(t *testing.T) {
+	accounts := &fakeAccountStore{sub: &models.Subscription{ID: 9, StripeSubscriptionID: "sub_1", Status: "active"}, failing: true}
+	stripe := &fakeCanceller{}
+
+	rec := httptest.NewRecorder()
+	DeleteAccount(accounts, accounts, stripe)(rec, httptest.NewRequest(http.MethodPost, "/api/account/delete", strings.NewReader(`{"email":"user@example.com"}`)))
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("expected 500, got %d", rec.Code)
+	}
+	if len(stripe.cancelled) != 0 || accounts.updated != nil {
+		t.Fatalf("expected the subscription to be left alone, got cancelled=%v updated=%+v", stripe.cancelled, accounts.updated)
+	}
+}
+
+func TestDeleteAccountRestoresAccountWhenCancelFails(t *testing.T) {
+	accounts := &fakeAccountStore{sub: &models.Subscription{ID: 9, StripeSubscriptionID: "sub_1", Status: "active"}}
+	stripe := &fakeCanceller{cancelErr: errors.New("stripe is down")}
+
+	// Stripe answers after the request was cancelled; the account is
+	// restored anyway.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req := httptest.NewRequest(http.MethodPost, "/api/account/delete", strings.NewReader(`{"email":"user@example.com"}`)).WithContext(ctx)
+
+	rec := httptest.NewRecorder()
+	DeleteAccount(accounts, accounts, stripe)(rec, req)
+	if rec.Code != http.StatusBadGateway {
+		t.Fatalf("expected 502, got %d", rec.Code)
+	}
+	if len(accounts.restored) != 1 || accounts.restored[0] != "user@example.com" {
+		t.Fatalf("expected the account to be restored, got %v", accounts.restored)
+	}
+	if accounts.updated != nil {
+		t.Fatalf("expected the local subscription to be left alone, got %+v", accounts.updated)
+	}
+	if len(accounts.revoked) != 0 {
+		t.Fatalf("expected the user to stay signed in, got revoked %v", accounts.revoked)
+	}
+}
+
+func TestDeleteAccountUnknownUserIsNotFound(t *testing.T) {
+	accounts := &fakeAccountStore{missing: true}
+
+	rec := httptest.NewRecorder()
+	DeleteAccount(accounts, accounts, nil)(rec, httptest.NewRequest(http.MethodPost, "/api/account/delete", strings.NewReader(`{"email":"nobody@example.com"}`)))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestDeleteAccountWithoutStripeKeepsSubscribedAccount(t *testing.T) {
+	accounts := &fakeAccountStore{sub: &models.Subscription{ID: 9, StripeSubscriptionID: "sub_1", Status: "active"}}
+
+	rec := httptest.NewRecorder()
+	DeleteAccount(accounts, accounts, nil)(rec, httptest.NewRequest(http.MethodPost, "/api/account/delete", strings.NewReader(`{"email":"user@example.com"}`)))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503, got %d", rec.Code)
+	}
+	if len(accounts.deleted) != 0 {
+		t.Fatalf("expected the account to be kept, got %v", accounts.deleted)
+	}
+}
@@ -0,0 +1,372 @@
+package handlers
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+	storepkg "github.com/PortNumber53/mcp-jira-thing/backend/internal/store"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/worker"
+)
+
+// maxCommentChannelsPerUser caps how many comment channels a user may have.
+const maxCommentChannelsPerUser = 100
+
+// slackRequestMaxAge is how old a signed Slack request may be before it is
+// refused as a replay.
+const slackRequestMaxAge = 5 * time.Minute
+
+// CommentChannelStore defines the behaviour required to manage the channels
+// issue comments are synced with.
+type CommentChannelStore interface {
+	UserLookup
+	CountCommentChannels(ctx context.Context, userID int64) (int, error)
+	SaveCommentChannel(ctx context.Context, c *models.CommentChannel) (*models.CommentChannel, error)
+	ListCommentChannels(ctx context.Context, userID int64) ([]models.CommentChannel, error)
+	DeleteCommentChannel(ctx context.Context, userID, id int64) error
+}
+
+// SlackCommentStore finds the comment channels synced with a Slack thread.
+type SlackCommentStore interface {
+	ListSlackCommentChannels(ctx context.Context, slackChannel, threadTS string) ([]models.CommentChannel, error)
+}
+
+// EmailCommentStore finds the comment channel an email reply belongs to.
+type EmailCommentStore interface {
+	GetEmailCommentChannel(ctx context.Context, replyToken string) (*models.CommentChannel, error)
+}
+
+type commentChannelPayload struct {
+	IssueKey      string `json:"issue_key"`
+	Kind          string `json:"kind"`
+	SlackChannel  string `json:"slack_channel"`
+	SlackThreadTS string `json:"slack_thread_ts"`
+	EmailAddress  string `json:"email_address"`
+	Enabled       *bool  `json:"enabled"`
+}
+
+// commentChannelView is a comment channel as returned by the API, with the
+// address replies to its emails go to.
+type commentChannelView struct {
+	models.CommentChannel
+	ReplyAddress string `json:"reply_address,omitempty"`
+}
+
+func newCommentChannelView(c models.CommentChannel, replyAddress string) commentChannelView {
+	view := commentChannelView{CommentChannel: c}
+	if c.Kind == models.CommentChannelEmail && replyAddress != "" {
+		view.ReplyAddress = c.ReplyAddress(replyAddress)
+	}
+	return view
+}
+
+// CommentChannels lists the caller's comment channels or links an issue to
+// a Slack thread or email address. The caller is resolved as for
+// UsageForecast. An issue has at most one channel of each kind; posting
+// another replaces it. replyAddress is the base address email replies go
+// to; without it email channels cannot be created.
+// POST {"issue_key": "OPS-12", "kind": "slack", "slack_channel": "C0123456789",
+// "slack_thread_ts": "1712345678.000100"}
+func CommentChannels(store CommentChannelStore, cookieSecret, replyAddress string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := metricsUserID(w, r, store, cookieSecret)
+		if !ok {
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			channels, err := store.ListCommentChannels(r.Context(), userID)
+			if err != nil {
+				log.Printf("CommentChannels: failed to list comment channels for user_id=%d: %v", userID, err)
+				http.Error(w, "failed to list comment channels", http.StatusBadGateway)
+				return
+			}
+			views := make([]commentChannelView, len(channels))
+			for i, c := range channels {
+				views[i] = newCommentChannelView(c, replyAddress)
+			}
+			writeJSON(w, http.StatusOK, map[string]any{"channels": views})
+
+		case http.MethodPost:
+			var payload commentChannelPayload
+			if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+				http.Error(w, "invalid JSON payload", http.StatusBadRequest)
+				return
+			}
+			channel := &models.CommentChannel{
+				UserID:        userID,
+				IssueKey:      payload.IssueKey,
+				Kind:          payload.Kind,
+				SlackChannel:  payload.SlackChannel,
+				SlackThreadTS: payload.SlackThreadTS,
+				EmailAddress:  payload.EmailAddress,
+				Enabled:       payload.Enabled == nil || *payload.Enabled,
+			}
+			if err := channel.Validate(); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if channel.Kind == models.CommentChannelEmail && replyAddress == "" {
+				http.Error(w, "email comment channels are not available", http.StatusBadRequest)
+				return
+			}
+
+			count, err := store.CountCommentChannels(r.Context(), userID)
+			if err != nil {
+				log.Printf("CommentChannels: failed to count comment channels for user_id=%d: %v", userID, err)
+				http.Error(w, "failed to save comment channel", http.StatusBadGateway)
+				return
+			}
+			if count >= maxCommentChannelsPerUser {
+				writeJSON(w, http.StatusConflict, map[string]any{"error": "comment_channel_limit_reached", "limit": maxCommentChannelsPerUser})
+				return
+			}
+
+			saved, err := store.SaveCommentChannel(r.Context(), channel)
+			if err != nil {
+				log.Printf("CommentChannels: failed to save comment channel for user_id=%d: %v", userID, err)
+				http.Error(w, "failed to save comment channel", http.StatusBadGateway)
+				return
+			}
+			writeJSON(w, http.StatusOK, map[string]any{"channel": newCommentChannelView(*saved, replyAddress)})
+
+		default:
+			w.Header().Set("Allow", "GET, POST")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// CommentChannel removes the caller's comment channel in the {id} URL
+// parameter; its comments stop syncing.
+func CommentChannel(store CommentChannelStore, cookieSecret string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := metricsUserID(w, r, store, cookieSecret)
+		if !ok {
+			return
+		}
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil || id <= 0 {
+			http.Error(w, "invalid comment channel id", http.StatusBadRequest)
+			return
+		}
+		if r.Method != http.MethodDelete {
+			w.Header().Set("Allow", "DELETE")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		err = store.DeleteCommentChannel(r.Context(), userID, id)
+		if errors.Is(err, storepkg.ErrCommentChannelNotFound) {
+			http.Error(w, "comment channel not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Printf("CommentChannel: failed to delete comment channel id=%d for user_id=%d: %v", id, userID, err)
+			http.Error(w, "failed to delete comment channel", http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+type slackEventPayload struct {
+	Type      string `json:"type"`
+	Challenge string `json:"challenge"`
+	Event     struct {
+		Type        string `json:"type"`
+		Subtype     string `json:"subtype"`
+		Channel     string `json:"channel"`
+		User        string `json:"user"`
+		BotID       string `json:"bot_id"`
+		Text        string `json:"text"`
+		TS          string `json:"ts"`
+		ThreadTS    string `json:"thread_ts"`
+		UserProfile *struct {
+			RealName    string `json:"real_name"`
+			DisplayName string `json:"display_name"`
+		} `json:"user_profile"`
+	} `json:"event"`
+}
+
+// slackUnescaper undoes the escaping Slack applies to message text.
+var slackUnescaper = strings.NewReplacer("&lt;", "<", "&gt;", ">", "&amp;", "&")
+
+// SlackEvents receives the Slack app's Events API requests, signed with
+// signingSecret, and queues every reply in a thread linked to an issue to
+// be added as a Jira comment. Messages from bots, including the ones the
+// sync posts, and text carrying the sync marker are ignored.
+func SlackEvents(store SlackCommentStore, jobs JobEnqueuer, signingSecret string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, 1<<20))
+		if err != nil {
+			http.Error(w, "invalid payload", http.StatusBadRequest)
+			return
+		}
+		if !validSlackSignature(signingSecret, r.Header.Get("X-Slack-Request-Timestamp"), r.Header.Get("X-Slack-Signature"), body, time.Now()) {
+			http.Error(w, "invalid signature", http.StatusUnauthorized)
+			return
+		}
+
+		var payload slackEventPayload
+		if err := json.Unmarshal(body, &payload); err != nil {
+			http.Error(w, "invalid JSON payload", http.StatusBadRequest)
+			return
+		}
+		if payload.Type == "url_verification" {
+			writeJSON(w, http.StatusOK, map[string]string{"challenge": payload.Challenge})
+			return
+		}
+
+		ev := payload.Event
+		if payload.Type != "event_callback" || ev.Type != "message" || ev.Subtype != "" || ev.BotID != "" ||
+			ev.ThreadTS == "" || ev.ThreadTS == ev.TS || strings.Contains(ev.Text, models.CommentSyncMarker) {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		text := strings.TrimSpace(slackUnescaper.Replace(ev.Text))
+		if text == "" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		channels, err := store.ListSlackCommentChannels(r.Context(), ev.Channel, ev.ThreadTS)
+		if err != nil {
+			// Slack retries requests that fail.
+			log.Printf("SlackEvents: failed to find comment channels for %s/%s: %v", ev.Channel, ev.ThreadTS, err)
+			http.Error(w, "failed to find comment channels", http.StatusBadGateway)
+			return
+		}
+		author := "Slack user " + ev.User
+		if p := ev.UserProfile; p != nil && (p.DisplayName != "" || p.RealName != "") {
+			author = firstNonEmpty(p.DisplayName, p.RealName)
+		}
+		for _, c := range channels {
+			if err := jobs.Enqueue(r.Context(), worker.CommentInboundJob(c.ID, ev.TS, author, text)); err != nil {
+				log.Printf("SlackEvents: failed to queue reply %s for comment channel %d: %v", ev.TS, c.ID, err)
+				http.Error(w, "failed to queue reply", http.StatusBadGateway)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// validSlackSignature checks a Slack request signature: v0= and the hex
+// HMAC-SHA256 of "v0:<timestamp>:<body>" under the signing secret, sent
+// within slackRequestMaxAge of now.
+func validSlackSignature(secret, timestamp, signature string, body []byte, now time.Time) bool {
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || secret == "" {
+		return false
+	}
+	if age := now.Sub(time.Unix(ts, 0)); age > slackRequestMaxAge || age < -slackRequestMaxAge {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("v0:" + timestamp + ":"))
+	mac.Write(body)
+	expected := "v0=" + hex.EncodeToString(mac.Sum(nil))
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
+
+type inboundEmailPayload struct {
+	From      string `json:"from"`
+	To        string `json:"to"`
+	Subject   string `json:"subject"`
+	Text      string `json:"text"`
+	MessageID string `json:"message_id"`
+}
+
+// InboundEmail receives replies to emailed comments from the inbound email
+// provider, which must pass secret as the secret query parameter. The reply
+// token in a recipient address names the comment channel; the reply, with
+// quoted text and signatures cut off, is queued to be added as a Jira
+// comment. Replies that match no channel are acknowledged and dropped so
+// the provider does not retry them.
+// POST {"from": "Ana <ana@example.com>", "to": "comments+token@reply.example.com",
+// "subject": "Re: [OPS-12] New comment", "text": "...", "message_id": "<...>"}
+func InboundEmail(store EmailCommentStore, jobs JobEnqueuer, secret, replyAddress string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.URL.Query().Get("secret")), []byte(secret)) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		var payload inboundEmailPayload
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&payload); err != nil {
+			http.Error(w, "invalid JSON payload", http.StatusBadRequest)
+			return
+		}
+		if strings.TrimSpace(payload.MessageID) == "" {
+			http.Error(w, "message_id is required", http.StatusBadRequest)
+			return
+		}
+
+		token := ""
+		recipients, _ := mail.ParseAddressList(payload.To)
+		for _, rcpt := range recipients {
+			if t, ok := models.ReplyToken(replyAddress, rcpt.Address); ok {
+				token = t
+				break
+			}
+		}
+		text := replyText(payload.Text)
+		if token == "" || text == "" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		channel, err := store.GetEmailCommentChannel(r.Context(), token)
+		if errors.Is(err, storepkg.ErrCommentChannelNotFound) {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		if err != nil {
+			log.Printf("InboundEmail: failed to find comment channel: %v", err)
+			http.Error(w, "failed to find comment channel", http.StatusBadGateway)
+			return
+		}
+
+		author := payload.From
+		if from, err := mail.ParseAddress(payload.From); err == nil {
+			author = firstNonEmpty(from.Name, from.Address)
+		}
+		if err := jobs.Enqueue(r.Context(), worker.CommentInboundJob(channel.ID, strings.TrimSpace(payload.MessageID), author, text)); err != nil {
+			log.Printf("InboundEmail: failed to queue reply for comment channel %d: %v", channel.ID, err)
+			http.Error(w, "failed to queue reply", http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}
+}
+
+// replyText returns the new part of an email reply: the text before the
+// quoted message, the signature or the sync marker.
+func replyText(text string) string {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, ">") || line == "-- " || trimmed == "-----Original Message-----" ||
+			strings.HasPrefix(trimmed, "On ") && strings.HasSuffix(trimmed, "wrote:") ||
+			strings.Contains(line, models.CommentSyncMarker) {
+			lines = lines[:i]
+			break
+		}
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+	storepkg "github.com/PortNumber53/mcp-jira-thing/backend/internal/store"
+)
+
+type mockCommentChannelStore struct {
+	channels []models.CommentChannel
+}
+
+func (m *mockCommentChannelStore) ListSlackCommentChannels(ctx context.Context, slackChannel, threadTS string) ([]models.CommentChannel, error) {
+	var out []models.CommentChannel
+	for _, c := range m.channels {
+		if c.Kind == models.CommentChannelSlack && c.SlackChannel == slackChannel && c.SlackThreadTS == threadTS {
+			out = append(out, c)
+		}
+	}
+	return out, nil
+}
+
+func (m *mockCommentChannelStore) GetEmailCommentChannel(ctx context.Context, replyToken string) (*models.CommentChannel, error) {
+	for _, c := range m.channels {
+		if c.Kind == models.CommentChannelEmail && c.ReplyToken == replyToken {
+			return &c, nil
+		}
+	}
+	return nil, storepkg.ErrCommentChannelNotFound
+}
+
+func signSlack(secret, timestamp, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("v0:" + timestamp + ":" + body))
+	return "v0=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestSlackEventsQueuesThreadReplies(t *testing.T) {
+	store := &mockCommentChannelStore{channels: []models.CommentChannel{
+		{ID: 3, Kind: models.CommentChannelSlack, SlackChannel: "C0123456", SlackThreadTS: "1712345678.000100"},
+	}}
+	jobs := &recordingEnqueuer{}
+	handler := SlackEvents(store, jobs, "signing")
+
+	send := func(body string, signature func(ts string) string) int {
+		ts := strconv.FormatInt(time.Now().Unix(), 10)
+		req := httptest.NewRequest(http.MethodPost, "/api/webhooks/slack", strings.NewReader(body))
+		req.Header.Set("X-Slack-Request-Timestamp", ts)
+		req.Header.Set("X-Slack-Signature", signature(ts))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	valid := func(body string) func(string) string {
+		return func(ts string) string { return signSlack("signing", ts, body) }
+	}
+
+	reply := `{"type":"event_callback","event":{"type":"message","channel":"C0123456","user":"U1","text":"a &lt; b","ts":"1712345699.000200","thread_ts":"1712345678.000100","user_profile":{"display_name":"ana"}}}`
+	if code := send(reply, func(string) string { return "v0=bad" }); code != http.StatusUnauthorized {
+		t.Fatalf("bad signature: expected 401, got %d", code)
+	}
+	if code := send(reply, valid(reply)); code != http.StatusOK {
+		t.Fatalf("reply: expected 200, got %d", code)
+	}
+
+	bot := `{"type":"event_callback","event":{"type":"message","channel":"C0123456","bot_id":"B1","text":"posted","ts":"1712345700.000300","thread_ts":"1712345678.000100"}}`
+	synced := `{"type":"event_callback","event":{"type":"message","channel":"C0123456","user":"U1","text":"hi ` + models.CommentSyncMarker + `","ts":"1712345701.000400","thread_ts":"1712345678.000100"}}`
+	for _, body := range []string{bot, synced} {
+		if code := send(body, valid(body)); code != http.StatusOK {
+			t.Fatalf("ignored message: expected 200, got %d", code)
+		}
+	}
+
+	if len(jobs.jobs) != 1 {
+		t.Fatalf("expected one queued reply, got %d", len(jobs.jobs))
+	}
+	payload := jobs.jobs[0].Payload
+	if payload["channel_id"] != int64(3) || payload["external_id"] != "1712345699.000200" || payload["author"] != "ana" || payload["text"] != "a < b" {
+		t.Fatalf("unexpected job payload %#v", payload)
+	}
+}
+
+func TestInboundEmailRoutesReplyByToken(t *testing.T) {
+	store := &mockCommentChannelStore{channels: []models.CommentChannel{
+		{ID: 5, Kind: models.CommentChannelEmail, EmailAddress: "ana@example.com", ReplyToken: "abc123"},
+	}}
+	jobs := &recordingEnqueuer{}
+	handler := InboundEmail(store, jobs, "inbound", "comments@reply.example.com")
+
+	send := func(secret, body string) int {
+		req := httptest.NewRequest(http.MethodPost, "/api/webhooks/inbound-email?secret="+secret, strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	body := `{"from":"Ana <ana@example.com>","to":"Comments+ABC123@reply.example.com","message_id":"<m1@example.com>",` +
+		`"text":"Looks good.\r\nShip it.\r\n\r\nOn Mon, Bot wrote:\r\n> earlier comment"}`
+	if code := send("wrong", body); code != http.StatusUnauthorized {
+		t.Fatalf("wrong secret: expected 401, got %d", code)
+	}
+	if code := send("inbound", body); code != http.StatusAccepted {
+		t.Fatalf("reply: expected 202, got %d", code)
+	}
+	unknown := `{"from":"ana@example.com","to":"comments+nope@reply.example.com","message_id":"<m2@example.com>","text":"hi"}`
+	if code := send("inbound", unknown); code != http.StatusOK {
+		t.Fatalf("unknown token: expected 200, got %d", code)
+	}
+
+	if len(jobs.jobs) != 1 {
+		t.Fatalf("expected one queued reply, got %d", len(jobs.jobs))
+	}
+	payload := jobs.jobs[0].Payload
+	if payload["channel_id"] != int64(5) || payload["author"] != "Ana" || payload["text"] != "Looks good.\nShip it." {
+		t.Fatalf("unexpected job payload %#v", payload)
+	}
+}
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+)
+
+// dashboardRecentLimit caps the recent jobs and failed requests on the
+// dashboard.
+const dashboardRecentLimit = 10
+
+// DashboardStore defines the behaviour required to gather the dashboard.
+type DashboardStore interface {
+	UserLookup
+	GetConnectedAccounts(ctx context.Context, email string) ([]models.ConnectedAccount, error)
+	ListUserSettings(ctx context.Context, email string) ([]models.JiraUserSettings, error)
+	ListIntegrationTokens(ctx context.Context, email string) ([]models.IntegrationTokenPublic, error)
+	GetEffectivePlan(ctx context.Context, userID int64) (*models.MembershipPlan, error)
+	CountPeriodRequests(ctx context.Context, userID int64, periodStart time.Time) (int64, error)
+	ListUserRequestErrors(ctx context.Context, userID int64, limit int) ([]models.Request, error)
+}
+
+// Dashboard returns what the web app shows when it loads in a single
+// response, instead of one call each for the profile, connected accounts,
+// Jira settings, plan, quota and recent activity. jobs may be nil when the
+// job queue is not available.
+// GET ?email=...
+func Dashboard(store DashboardStore, jobs UserJobLister, cookieSecret string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		email := requestEmail(r, cookieSecret, "")
+		if email == "" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		user, err := store.GetUserByEmail(r.Context(), email)
+		if err != nil {
+			log.Printf("Dashboard: failed to resolve user for email=%s: %v", email, err)
+			http.Error(w, "user not found", http.StatusNotFound)
+			return
+		}
+
+		dashboard, err := gatherDashboard(r.Context(), store, jobs, user, time.Now())
+		if err != nil {
+			log.Printf("Dashboard: failed to load dashboard for user_id=%d: %v", user.ID, err)
+			http.Error(w, "failed to load dashboard", http.StatusBadGateway)
+			return
+		}
+		writeJSON(w, http.StatusOK, dashboard)
+	}
+}
+
+// gatherDashboard reads each section of user's dashboard.
+func gatherDashboard(ctx context.Context, store DashboardStore, jobs UserJobLister, user *models.User, now time.Time) (*models.Dashboard, error) {
+	email := ""
+	if user.Email != nil {
+		email = *user.Email
+	}
+	dashboard := &models.Dashboard{Profile: *user}
+
+	var err error
+	if dashboard.ConnectedAccounts, err = store.GetConnectedAccounts(ctx, email); err != nil {
+		return nil, err
+	}
+	settings, err := store.ListUserSettings(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+	tokens, err := store.ListIntegrationTokens(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+	dashboard.Jira = jiraHealth(settings, tokens)
+
+	if dashboard.Plan, err = store.GetEffectivePlan(ctx, user.ID); err != nil {
+		return nil, err
+	}
+	periodStart, reset := models.QuotaPeriod(now)
+	used, err := store.CountPeriodRequests(ctx, user.ID, periodStart)
+	if err != nil {
+		return nil, err
+	}
+	dashboard.Quota = planQuota(dashboard.Plan, periodStart, reset, used)
+
+	if jobs != nil {
+		if dashboard.RecentJobs, err = jobs.ListUserJobs(ctx, user.ID, dashboardRecentLimit); err != nil {
+			return nil, err
+		}
+	}
+	if dashboard.RecentErrors, err = store.ListUserRequestErrors(ctx, user.ID, dashboardRecentLimit); err != nil {
+		return nil, err
+	}
+
+	// Empty sections are [] rather than null, as in the tenant state.
+	if dashboard.ConnectedAccounts == nil {
+		dashboard.ConnectedAccounts = []models.ConnectedAccount{}
+	}
+	if dashboard.RecentJobs == nil {
+		dashboard.RecentJobs = []*models.Job{}
+	}
+	if dashboard.RecentErrors == nil {
+		dashboard.RecentErrors = []models.Request{}
+	}
+	return dashboard, nil
+}
+
+// jiraHealth reports whether each Jira site can be used. A site signed in
+// with Atlassian OAuth needs the Atlassian token and the site's cloud ID;
+// expired access tokens are renewed on use, so they are not a problem.
+func jiraHealth(settings []models.JiraUserSettings, tokens []models.IntegrationTokenPublic) models.JiraHealth {
+	health := models.JiraHealth{Status: models.JiraHealthOK, Sites: []models.JiraSiteHealth{}}
+	if len(settings) == 0 {
+		health.Status = models.JiraHealthNotConfigured
+		return health
+	}
+
+	oauthConnected := false
+	for _, t := range tokens {
+		if t.Provider == models.JiraOAuthProvider {
+			oauthConnected = true
+		}
+	}
+	for _, site := range settings {
+		h := models.JiraSiteHealth{JiraUserSettings: site, Status: models.JiraHealthOK}
+		if site.AuthMethod == models.JiraAuthOAuth {
+			switch {
+			case !oauthConnected:
+				h.Status, h.Problem = models.JiraHealthDisconnected, "Atlassian account is not connected"
+			case site.JiraCloudID == nil || *site.JiraCloudID == "":
+				h.Status, h.Problem = models.JiraHealthDisconnected, "site has no Atlassian cloud ID"
+			}
+		}
+		if h.Status != models.JiraHealthOK {
+			health.Status = models.JiraHealthAttention
+		}
+		health.Sites = append(health.Sites, h)
+	}
+	return health
+}
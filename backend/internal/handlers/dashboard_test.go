@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+)
+
+type fakeDashboardStore struct {
+	settings []models.JiraUserSettings
+	tokens   []models.IntegrationTokenPublic
+}
+
+func (f *fakeDashboardStore) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	return &models.User{ID: 7, Login: "user", Email: &email}, nil
+}
+
+func (f *fakeDashboardStore) GetConnectedAccounts(ctx context.Context, email string) ([]models.ConnectedAccount, error) {
+	return []models.ConnectedAccount{{Provider: "github"}}, nil
+}
+
+func (f *fakeDashboardStore) ListUserSettings(ctx context.Context, email string) ([]models.JiraUserSettings, error) {
+	return f.settings, nil
+}
+
+func (f *fakeDashboardStore) ListIntegrationTokens(ctx context.Context, email string) ([]models.IntegrationTokenPublic, error) {
+	return f.tokens, nil
+}
+
+func (f *fakeDashboardStore) GetEffectivePlan(ctx context.Context, userID int64) (*models.MembershipPlan, error) {
+	quota := 100
+	return &models.MembershipPlan{Slug: "starter", MonthlyRequestQuota: &quota}, nil
+}
+
+func (f *fakeDashboardStore) CountPeriodRequests(ctx context.Context, userID int64, periodStart time.Time) (int64, error) {
+	return 40, nil
+}
+
+func (f *fakeDashboardStore) ListUserRequestErrors(ctx context.Context, userID int64, limit int) ([]models.Request, error) {
+	return []models.Request{{ID: "9", StatusCode: http.StatusBadGateway}}, nil
+}
+
+func TestDashboard(t *testing.T) {
+	cloudID := "cloud-1"
+	store := &fakeDashboardStore{settings: []models.JiraUserSettings{
+		{JiraBaseURL: "https://a.atlassian.net", AuthMethod: models.JiraAuthAPIToken, IsDefault: true},
+		{JiraBaseURL: "https://b.atlassian.net", AuthMethod: models.JiraAuthOAuth, JiraCloudID: &cloudID},
+	}}
+	handler := Dashboard(store, &fakeUserJobLister{}, "")
+
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/api/dashboard?email=user@example.com", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var dashboard models.Dashboard
+	if err := json.Unmarshal(rec.Body.Bytes(), &dashboard); err != nil {
+		t.Fatalf("decode dashboard: %v", err)
+	}
+	if dashboard.Profile.ID != 7 || len(dashboard.ConnectedAccounts) != 1 || dashboard.Plan.Slug != "starter" {
+		t.Fatalf("unexpected profile, accounts or plan: %+v", dashboard)
+	}
+	if dashboard.Quota.Remaining == nil || *dashboard.Quota.Remaining != 60 {
+		t.Fatalf("expected 60 requests remaining, got %+v", dashboard.Quota)
+	}
+	if len(dashboard.RecentJobs) != 1 || len(dashboard.RecentErrors) != 1 {
+		t.Fatalf("expected recent jobs and errors, got %d and %d", len(dashboard.RecentJobs), len(dashboard.RecentErrors))
+	}
+	// The OAuth site is unusable until the Atlassian account is connected.
+	if dashboard.Jira.Status != models.JiraHealthAttention || dashboard.Jira.Sites[0].Status != models.JiraHealthOK ||
+		dashboard.Jira.Sites[1].Status != models.JiraHealthDisconnected {
+		t.Fatalf("unexpected Jira health: %+v", dashboard.Jira)
+	}
+
+	store.tokens = []models.IntegrationTokenPublic{{Provider: models.JiraOAuthProvider}}
+	rec = httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/api/dashboard?email=user@example.com", nil))
+	if err := json.Unmarshal(rec.Body.Bytes(), &dashboard); err != nil {
+		t.Fatalf("decode dashboard: %v", err)
+	}
+	if dashboard.Jira.Status != models.JiraHealthOK {
+		t.Fatalf("expected healthy Jira sites once connected, got %+v", dashboard.Jira)
+	}
+
+	rec = httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/api/dashboard", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without a caller, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/store"
+)
+
+// DataResidencyStore moves organizations between data regions.
+type DataResidencyStore interface {
+	GetOrganizationBySlug(ctx context.Context, slug string) (*models.Organization, error)
+	SetOrganizationDataRegion(ctx context.Context, orgID int64, region string) error
+	DataRegions() []string
+}
+
+// AdminOrganizationDataRegion reports (GET) or changes (PUT) the data region
+// of an organization. PUT takes {"data_region": "eu"}, or "" for the primary
+// database, and moves the organization's shared Jira account and its
+// members' request logs before returning. Repeating a PUT finishes a move
+// that failed part way.
+func AdminOrganizationDataRegion(orgs DataResidencyStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodPut {
+			w.Header().Set("Allow", "GET, PUT")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		org, err := orgs.GetOrganizationBySlug(r.Context(), chi.URLParam(r, "slug"))
+		if errors.Is(err, store.ErrOrganizationNotFound) {
+			http.Error(w, "organization not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Printf("AdminOrganizationDataRegion: load organization: %v", err)
+			http.Error(w, "failed to load organization", http.StatusInternalServerError)
+			return
+		}
+
+		region := ""
+		if org.DataRegion != nil {
+			region = *org.DataRegion
+		}
+
+		if r.Method == http.MethodPut {
+			var payload struct {
+				DataRegion *string `json:"data_region"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || payload.DataRegion == nil {
+				http.Error(w, `expected {"data_region": "<region>"}; use "" for the primary database`, http.StatusBadRequest)
+				return
+			}
+			region = strings.ToLower(strings.TrimSpace(*payload.DataRegion))
+
+			err := orgs.SetOrganizationDataRegion(r.Context(), org.ID, region)
+			if errors.Is(err, store.ErrUnknownDataRegion) {
+				http.Error(w, "unknown data region; configured regions: "+strings.Join(orgs.DataRegions(), ", "), http.StatusBadRequest)
+				return
+			}
+			if err != nil {
+				log.Printf("AdminOrganizationDataRegion: move %s to %q: %v", org.Slug, region, err)
+				http.Error(w, "failed to move organization data; retry to finish the move", http.StatusInternalServerError)
+				return
+			}
+		}
+
+		writeJSON(w, http.StatusOK, map[string]any{
+			"organization":      org.Slug,
+			"data_region":       region,
+			"available_regions": orgs.DataRegions(),
+		})
+	}
+}
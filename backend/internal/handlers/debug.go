@@ -0,0 +1,197 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/redact"
+)
+
+const (
+	defaultDebugModeDuration = time.Hour
+	maxDebugModeDuration     = 24 * time.Hour
+	debugTraceTTL            = 24 * time.Hour
+	debugTraceRingSize       = 200
+)
+
+// DebugStore defines the behaviour required for tenant debug mode and the
+// captured tool call traces.
+type DebugStore interface {
+	SetDebugMode(ctx context.Context, email string, until *time.Time) error
+	GetDebugMode(ctx context.Context, email string) (*time.Time, error)
+	RecordDebugTrace(ctx context.Context, trace *models.DebugTrace, keep int) (bool, error)
+	ListDebugTraces(ctx context.Context, email string, limit int) ([]models.DebugTrace, error)
+}
+
+type debugModePayload struct {
+	UserEmail       string `json:"user_email"`
+	Enabled         bool   `json:"enabled"`
+	DurationMinutes int    `json:"duration_minutes,omitempty"`
+}
+
+type debugTracePayload struct {
+	Tool       string          `json:"tool"`
+	Request    json.RawMessage `json:"request"`
+	Response   json.RawMessage `json:"response"`
+	IsError    bool            `json:"is_error"`
+	DurationMs *int            `json:"duration_ms,omitempty"`
+}
+
+// DebugMode reads or toggles the caller's debug mode.
+// GET  ?email=...                                 → current state
+// POST {"enabled": true, "duration_minutes": 60}  → enable (max 24h) or disable
+func DebugMode(store DebugStore, cookieSecret string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			email := requestEmail(r, cookieSecret, "")
+			if email == "" {
+				http.Error(w, "email query parameter is required", http.StatusBadRequest)
+				return
+			}
+
+			until, err := store.GetDebugMode(r.Context(), email)
+			if err != nil {
+				log.Printf("DebugMode: failed to load debug mode for email=%s: %v", email, err)
+				http.Error(w, "failed to load debug mode", http.StatusBadGateway)
+				return
+			}
+			writeDebugMode(w, until)
+
+		case http.MethodPost:
+			var payload debugModePayload
+			if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+				log.Printf("DebugMode: invalid JSON payload: %v", err)
+				http.Error(w, "invalid JSON payload", http.StatusBadRequest)
+				return
+			}
+
+			email := requestEmail(r, cookieSecret, payload.UserEmail)
+			if email == "" {
+				http.Error(w, "user_email is required", http.StatusBadRequest)
+				return
+			}
+
+			var until *time.Time
+			if payload.Enabled {
+				duration := defaultDebugModeDuration
+				if payload.DurationMinutes > 0 {
+					duration = time.Duration(payload.DurationMinutes) * time.Minute
+				}
+				if duration > maxDebugModeDuration {
+					duration = maxDebugModeDuration
+				}
+				t := time.Now().Add(duration).UTC()
+				until = &t
+			}
+
+			if err := store.SetDebugMode(r.Context(), email, until); err != nil {
+				log.Printf("DebugMode: failed to update debug mode for email=%s: %v", email, err)
+				http.Error(w, "failed to update debug mode", http.StatusBadGateway)
+				return
+			}
+			writeDebugMode(w, until)
+
+		default:
+			w.Header().Set("Allow", "GET, POST")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+func writeDebugMode(w http.ResponseWriter, until *time.Time) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]any{
+		"enabled":       until != nil,
+		"enabled_until": until,
+	}); err != nil {
+		http.Error(w, "failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+// DebugTraces returns the caller's captured tool call traces, newest first.
+// GET ?email=...&limit=50
+func DebugTraces(store DebugStore, cookieSecret string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		email := requestEmail(r, cookieSecret, "")
+		if email == "" {
+			http.Error(w, "email query parameter is required", http.StatusBadRequest)
+			return
+		}
+
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+		traces, err := store.ListDebugTraces(r.Context(), email, limit)
+		if err != nil {
+			log.Printf("DebugTraces: failed to list traces for email=%s: %v", email, err)
+			http.Error(w, "failed to load debug traces", http.StatusBadGateway)
+			return
+		}
+
+		if traces == nil {
+			traces = []models.DebugTrace{}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(map[string]any{"traces": traces}); err != nil {
+			http.Error(w, "failed to encode response", http.StatusInternalServerError)
+		}
+	}
+}
+
+// TenantDebugTrace accepts a tool call trace from the MCP worker. Payloads
+// are redacted before storage and dropped when the tenant's debug mode is
+// off; the response tells the worker whether to keep sending.
+func TenantDebugTrace(store DebugStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value("user_id").(int64)
+		if !ok || userID <= 0 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var payload debugTracePayload
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&payload); err != nil {
+			log.Printf("TenantDebugTrace: invalid JSON payload: %v", err)
+			http.Error(w, "invalid JSON payload", http.StatusBadRequest)
+			return
+		}
+
+		tool := strings.TrimSpace(payload.Tool)
+		if tool == "" {
+			http.Error(w, "tool is required", http.StatusBadRequest)
+			return
+		}
+
+		trace := &models.DebugTrace{
+			UserID:     userID,
+			Tool:       tool,
+			Request:    redact.JSON(payload.Request),
+			Response:   redact.JSON(payload.Response),
+			IsError:    payload.IsError,
+			DurationMs: payload.DurationMs,
+			ExpiresAt:  time.Now().Add(debugTraceTTL),
+		}
+
+		recorded, err := store.RecordDebugTrace(r.Context(), trace, debugTraceRingSize)
+		if err != nil {
+			log.Printf("TenantDebugTrace: failed to record trace for user_id=%d: %v", userID, err)
+			http.Error(w, "failed to record debug trace", http.StatusBadGateway)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(map[string]any{
+			"recorded":      recorded,
+			"debug_enabled": recorded,
+		}); err != nil {
+			http.Error(w, "failed to encode response", http.StatusInternalServerError)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/config"
+	storepkg "github.com/PortNumber53/mcp-jira-thing/backend/internal/store"
+)
+
+// emailStatusStore looks up whether a user's email has been verified.
+type emailStatusStore interface {
+	EmailVerificationStatus(ctx context.Context, email string) (int64, bool, error)
+}
+
+// EmailVerificationStore defines the storage used to confirm account emails.
+type EmailVerificationStore interface {
+	emailStatusStore
+	ConfirmEmailVerification(ctx context.Context, token string) (string, error)
+}
+
+// VerifyEmail consumes the token from a verification link and sends the
+// user back to the frontend.
+// GET ?token=...
+func VerifyEmail(cfg config.Config, store EmailVerificationStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		token := r.URL.Query().Get("token")
+		if token == "" {
+			redirectWithError(w, r, cfg.FrontendURL, "missing verification token")
+			return
+		}
+
+		email, err := store.ConfirmEmailVerification(r.Context(), token)
+		if errors.Is(err, storepkg.ErrVerificationTokenInvalid) {
+			redirectWithError(w, r, cfg.FrontendURL, "verification link is invalid or expired")
+			return
+		}
+		if err != nil {
+			log.Printf("VerifyEmail: failed to confirm verification: %v", err)
+			redirectWithError(w, r, cfg.FrontendURL, "email verification failed")
+			return
+		}
+
+		log.Printf("VerifyEmail: verified email=%s", email)
+		http.Redirect(w, r, cfg.FrontendURL+"/dashboard?email_verified=1", http.StatusSeeOther)
+	}
+}
+
+// ResendEmailVerification queues another verification link for the session
+// user, or the user_email in the body.
+// POST {"user_email": "..."}
+func ResendEmailVerification(store EmailVerificationStore, jobs JobEnqueuer, cookieSecret string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var payload struct {
+			UserEmail string `json:"user_email"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil && !errors.Is(err, io.EOF) {
+			http.Error(w, "invalid JSON payload", http.StatusBadRequest)
+			return
+		}
+
+		email := requestEmail(r, cookieSecret, payload.UserEmail)
+		if email == "" {
+			http.Error(w, "not authenticated", http.StatusUnauthorized)
+			return
+		}
+
+		verified, err := requestEmailVerification(r.Context(), store, jobs, email)
+		if errors.Is(err, storepkg.ErrUserNotFound) {
+			http.Error(w, "user not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Printf("ResendEmailVerification: failed for email=%s: %v", email, err)
+			http.Error(w, "failed to send verification email", http.StatusBadGateway)
+			return
+		}
+
+		status := http.StatusAccepted
+		if verified {
+			status = http.StatusOK
+		}
+		writeJSON(w, status, map[string]any{"email_verified": verified})
+	}
+}
+
+// RequireVerifiedEmail rejects writes with 403 {"error": "email_unverified"}
+// from users who have not confirmed their email yet, keeping billing and
+// settings changes off until they do. The user is resolved like the wrapped
+// handlers resolve it: session first, then user_email in a JSON body or the
+// email query parameter. Reads and unidentified requests pass through.
+func RequireVerifiedEmail(store emailStatusStore, cookieSecret string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			var fallback string
+			if r.Body != nil && r.Body != http.NoBody {
+				body, err := io.ReadAll(r.Body)
+				if err != nil {
+					http.Error(w, "failed to read request body", http.StatusBadRequest)
+					return
+				}
+				r.Body = io.NopCloser(bytes.NewReader(body))
+
+				var payload struct {
+					UserEmail string `json:"user_email"`
+				}
+				if json.Unmarshal(body, &payload) == nil {
+					fallback = payload.UserEmail
+				}
+			}
+
+			email := requestEmail(r, cookieSecret, fallback)
+			if email == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			_, verified, err := store.EmailVerificationStatus(r.Context(), email)
+			if errors.Is(err, storepkg.ErrUserNotFound) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if err != nil {
+				log.Printf("RequireVerifiedEmail: failed to check email=%s: %v", email, err)
+				http.Error(w, "failed to check email verification", http.StatusBadGateway)
+				return
+			}
+			if !verified {
+				writeJSON(w, http.StatusForbidden, map[string]any{"error": "email_unverified"})
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/store"
+)
+
+type fakeEmailStatusStore map[string]bool
+
+func (f fakeEmailStatusStore) EmailVerificationStatus(ctx context.Context, email string) (int64, bool, error) {
+	verified, ok := f[email]
+	if !ok {
+		return 0, false, store.ErrUserNotFound
+	}
+	return 7, verified, nil
+}
+
+func TestRequireVerifiedEmail(t *testing.T) {
+	gate := RequireVerifiedEmail(fakeEmailStatusStore{
+		"new@example.com": false,
+		"old@example.com": true,
+	}, "secret")
+	var gotBody string
+	handler := gate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		gotBody = string(b)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	cases := []struct {
+		name   string
+		method string
+		body   string
+		want   int
+	}{
+		{"unverified write", http.MethodPost, `{"user_email":"new@example.com"}`, http.StatusForbidden},
+		{"verified write", http.MethodPost, `{"user_email":"old@example.com"}`, http.StatusNoContent},
+		{"unknown user", http.MethodPost, `{"user_email":"who@example.com"}`, http.StatusNoContent},
+		{"unidentified write", http.MethodPost, `{"type":"checkout.session.completed"}`, http.StatusNoContent},
+		{"unverified read", http.MethodGet, ``, http.StatusNoContent},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			target := "/api/settings/jira"
+			if tc.method == http.MethodGet {
+				target += "?email=new@example.com"
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(tc.method, target, strings.NewReader(tc.body)))
+			if rec.Code != tc.want {
+				t.Fatalf("expected %d, got %d: %s", tc.want, rec.Code, rec.Body.String())
+			}
+			if tc.want == http.StatusNoContent && gotBody != tc.body {
+				t.Fatalf("expected the handler to see the original body %q, got %q", tc.body, gotBody)
+			}
+		})
+	}
+}
+
+type fakeOAuthStore struct {
+	OAuthStore
+	fakeEmailStatusStore
+	upsertErr error
+	logins    []string
+}
+
+func (f *fakeOAuthStore) UpsertGitHubUser(ctx context.Context, user models.GitHubAuthUser) error {
+	if f.upsertErr != nil {
+		return f.upsertErr
+	}
+	f.fakeEmailStatusStore[*user.Email] = user.EmailVerified
+	return nil
+}
+
+func (f *fakeOAuthStore) GetEnforcedSSOOrganization(ctx context.Context, email string) (*models.Organization, error) {
+	return nil, nil
+}
+
+func (f *fakeOAuthStore) EmailVerificationStatus(ctx context.Context, email string) (int64, bool, error) {
+	return f.fakeEmailStatusStore.EmailVerificationStatus(ctx, email)
+}
+
+func (f *fakeOAuthStore) RecordOAuthLoginEvent(ctx context.Context, provider, providerAccountID, ipAddress, userAgent string) error {
+	f.logins = append(f.logins, provider+":"+providerAccountID+"@"+ipAddress+" "+userAgent)
+	return nil
+}
+
+func (f *fakeOAuthStore) CreateOAuthUserSession(ctx context.Context, provider, providerAccountID, ipAddress, userAgent string, ttl time.Duration) (string, error) {
+	return "sid-" + providerAccountID, nil
+}
+
+func (f *fakeOAuthStore) GetEffectivePlan(ctx context.Context, userID int64) (*models.MembershipPlan, error) {
+	return &models.MembershipPlan{Slug: "free"}, nil
+}
+
+type recordingEnqueuer struct {
+	jobs []*models.Job
+}
+
+func (r *recordingEnqueuer) Enqueue(ctx context.Context, job *models.Job) error {
+	r.jobs = append(r.jobs, job)
+	return nil
+}
+
+func TestGitHubAuthQueuesEmailVerification(t *testing.T) {
+	jobs := &recordingEnqueuer{}
+	oauthStore := &fakeOAuthStore{fakeEmailStatusStore: fakeEmailStatusStore{}}
+	handler := GitHubAuth(oauthStore, jobs, nil)
+
+	body := `{"github_id":1,"login":"octo","access_token":"tok","email":"octo@example.com","email_verified":false,"client_ip":"203.0.113.9","user_agent":"Firefox"}`
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/auth/github", strings.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if !strings.Contains(rec.Body.String(), `"email_verified":false`) {
+		t.Fatalf("unexpected body %s", rec.Body.String())
+	}
+	if len(jobs.jobs) != 1 || jobs.jobs[0].JobType != "email_verification" || jobs.jobs[0].DedupWindow == 0 {
+		t.Fatalf("expected one deduplicated verification job, got %+v", jobs.jobs)
+	}
+	if len(oauthStore.logins) != 1 || oauthStore.logins[0] != "github:1@203.0.113.9 Firefox" {
+		t.Fatalf("expected the login to be recorded with the forwarded client, got %v", oauthStore.logins)
+	}
+}
+
+func TestGitHubAuthRejectsUnverifiedTakeover(t *testing.T) {
+	jobs := &recordingEnqueuer{}
+	oauthStore := &fakeOAuthStore{upsertErr: store.ErrEmailUnverified}
+	handler := GitHubAuth(oauthStore, jobs, nil)
+
+	body := `{"github_id":1,"login":"octo","access_token":"tok","email":"owner@example.com"}`
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/auth/github", strings.NewReader(body)))
+	if rec.Code != http.StatusForbidden || !strings.Contains(rec.Body.String(), "email_unverified") {
+		t.Fatalf("expected 403 email_unverified, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if len(jobs.jobs) != 0 {
+		t.Fatalf("expected no verification job, got %d", len(jobs.jobs))
+	}
+	if len(oauthStore.logins) != 0 {
+		t.Fatalf("expected a rejected login not to be recorded, got %v", oauthStore.logins)
+	}
+}
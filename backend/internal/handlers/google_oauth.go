@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -11,8 +12,10 @@ import (
 	"time"
 
 	"github.com/PortNumber53/mcp-jira-thing/backend/internal/config"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/middleware"
 	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
 	"github.com/PortNumber53/mcp-jira-thing/backend/internal/session"
+	storepkg "github.com/PortNumber53/mcp-jira-thing/backend/internal/store"
 )
 
 // googleUserInfo is the response from Google's userinfo endpoint.
@@ -143,21 +146,40 @@ func GoogleOAuthCallback(cfg config.Config, store OAuthStore) http.HandlerFunc {
 			return
 		}
 
-		// Persist user in database
 		email := strings.ToLower(userInfo.Email)
+
+		// Members of organizations that mandate SSO must sign in through
+		// their identity provider instead.
+		org, err := store.GetEnforcedSSOOrganization(r.Context(), email)
+		if err != nil {
+			log.Printf("[google-callback] failed to check sso enforcement: %v", err)
+			redirectWithError(w, r, cfg.FrontendURL, "sso login failed")
+			return
+		}
+		if org != nil {
+			http.Redirect(w, r, cfg.FrontendURL+"/login?error=sso_required&org="+url.QueryEscape(org.Slug), http.StatusSeeOther)
+			return
+		}
+
+		// Persist user in database
 		namePtr := strPtr(userInfo.Name)
 		emailPtr := &email
 		avatarPtr := strPtr(userInfo.Picture)
 
-		if err := store.UpsertGoogleUser(r.Context(), models.GoogleAuthUser{
+		persistErr := store.UpsertGoogleUser(r.Context(), models.GoogleAuthUser{
 			Sub:         userInfo.Sub,
 			Name:        namePtr,
 			Email:       emailPtr,
 			AvatarURL:   avatarPtr,
 			AccessToken: tokenResp.AccessToken,
-		}); err != nil {
-			log.Printf("[google-callback] failed to persist user: %v", err)
+		})
+		var sessionID string
+		if persistErr != nil {
+			log.Printf("[google-callback] failed to persist user: %v", persistErr)
 			// Non-fatal: continue with session creation
+		} else {
+			recordOAuthLogin(r.Context(), store, "google", userInfo.Sub, middleware.ClientIP(r), r.UserAgent())
+			sessionID = startOAuthSession(r.Context(), store, "google", userInfo.Sub, middleware.ClientIP(r), r.UserAgent())
 		}
 
 		// Create session cookie
@@ -169,6 +191,7 @@ func GoogleOAuthCallback(cfg config.Config, store OAuthStore) http.HandlerFunc {
 			Email:     emailPtr,
 			Provider:  "google",
 			Exp:       time.Now().Add(session.SessionTTL).Unix(),
+			SessionID: sessionID,
 		}
 
 		sessionToken, err := session.Encode(cfg.CookieSecret, sessionPayload)
@@ -204,9 +227,16 @@ func SessionCheck(cfg config.Config) http.HandlerFunc {
 	}
 }
 
-// SessionLogout clears the session cookie.
-func SessionLogout(cfg config.Config) http.HandlerFunc {
+// SessionLogout revokes the server-side session, when there is one, and
+// clears the session cookie.
+func SessionLogout(cfg config.Config, store UserSessionStore) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		if sess, err := session.ReadSession(r, cfg.CookieSecret); err == nil && sess.SessionID != "" && sess.Email != nil && store != nil {
+			if err := store.RevokeUserSession(r.Context(), *sess.Email, sess.SessionID); err != nil && !errors.Is(err, storepkg.ErrSessionNotFound) {
+				log.Printf("SessionLogout: failed to revoke session: %v", err)
+			}
+		}
+
 		secure := strings.HasPrefix(cfg.FrontendURL, "https")
 		session.ClearCookie(w, session.SessionCookie, cfg.CookieDomain, secure)
 		w.Header().Set("Content-Type", "application/json")
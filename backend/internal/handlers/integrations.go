@@ -39,7 +39,7 @@ func IntegrationTokens(store IntegrationTokenStore) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
 		case http.MethodGet:
-			email := strings.TrimSpace(r.URL.Query().Get("email"))
+			email := tokenEmail(r, r.URL.Query().Get("email"))
 			if email == "" {
 				http.Error(w, "email query parameter is required", http.StatusBadRequest)
 				return
@@ -69,6 +69,7 @@ func IntegrationTokens(store IntegrationTokenStore) http.HandlerFunc {
 				return
 			}
 
+			payload.UserEmail = tokenEmail(r, payload.UserEmail)
 			if payload.UserEmail == "" || payload.Provider == "" || payload.AccessToken == "" {
 				http.Error(w, "user_email, provider, and access_token are required", http.StatusBadRequest)
 				return
@@ -101,7 +102,7 @@ func IntegrationTokens(store IntegrationTokenStore) http.HandlerFunc {
 			}
 
 		case http.MethodDelete:
-			email := strings.TrimSpace(r.URL.Query().Get("email"))
+			email := tokenEmail(r, r.URL.Query().Get("email"))
 			provider := strings.TrimSpace(r.URL.Query().Get("provider"))
 			if email == "" || provider == "" {
 				http.Error(w, "email and provider query parameters are required", http.StatusBadRequest)
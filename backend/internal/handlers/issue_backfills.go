@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+	storepkg "github.com/PortNumber53/mcp-jira-thing/backend/internal/store"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/worker"
+)
+
+// maxBackfillJQLLength caps the JQL of a backfill.
+const maxBackfillJQLLength = 2000
+
+// IssueBackfillStore defines the behaviour required to run backfills of
+// the issue mirror.
+type IssueBackfillStore interface {
+	UserLookup
+	CreateIssueBackfill(ctx context.Context, userID int64, jql string) (*models.IssueBackfill, error)
+	SetIssueBackfillJob(ctx context.Context, id, jobID int64) error
+	RecordIssueBackfillError(ctx context.Context, id int64, message string, final bool) error
+	ListIssueBackfills(ctx context.Context, userID int64) ([]models.IssueBackfill, error)
+	GetIssueBackfill(ctx context.Context, userID, id int64) (*models.IssueBackfill, error)
+	ResumeIssueBackfill(ctx context.Context, userID, id int64) (*models.IssueBackfill, error)
+}
+
+// IssueBackfills lists the caller's latest backfills or starts one that
+// loads every issue matching a JQL search into the issue mirror. The caller
+// is resolved as for UsageForecast. A caller runs one backfill at a time;
+// its job reports progress through the job API and realtime stream.
+// POST {"jql": "project = OPS ORDER BY created ASC"}
+func IssueBackfills(store IssueBackfillStore, jobs JobEnqueuer, cookieSecret string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := metricsUserID(w, r, store, cookieSecret)
+		if !ok {
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			backfills, err := store.ListIssueBackfills(r.Context(), userID)
+			if err != nil {
+				log.Printf("IssueBackfills: failed to list backfills for user_id=%d: %v", userID, err)
+				http.Error(w, "failed to list issue backfills", http.StatusBadGateway)
+				return
+			}
+			writeJSON(w, http.StatusOK, map[string]any{"backfills": backfills})
+
+		case http.MethodPost:
+			var payload struct {
+				JQL string `json:"jql"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+				http.Error(w, "invalid JSON payload", http.StatusBadRequest)
+				return
+			}
+			jql := strings.TrimSpace(payload.JQL)
+			if jql == "" || len(jql) > maxBackfillJQLLength {
+				http.Error(w, "jql is required and must be at most 2000 characters", http.StatusBadRequest)
+				return
+			}
+
+			backfill, err := store.CreateIssueBackfill(r.Context(), userID, jql)
+			if errors.Is(err, storepkg.ErrIssueBackfillActive) {
+				http.Error(w, err.Error(), http.StatusConflict)
+				return
+			}
+			if err != nil {
+				log.Printf("IssueBackfills: failed to create backfill for user_id=%d: %v", userID, err)
+				http.Error(w, "failed to start issue backfill", http.StatusBadGateway)
+				return
+			}
+			if !queueIssueBackfill(r.Context(), w, store, jobs, backfill) {
+				return
+			}
+			writeJSON(w, http.StatusAccepted, map[string]any{"backfill": backfill})
+
+		default:
+			w.Header().Set("Allow", "GET, POST")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// IssueBackfill returns the caller's backfill in the {id} URL parameter.
+func IssueBackfill(store IssueBackfillStore, cookieSecret string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := metricsUserID(w, r, store, cookieSecret)
+		if !ok {
+			return
+		}
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil || id <= 0 {
+			http.Error(w, "invalid issue backfill id", http.StatusBadRequest)
+			return
+		}
+
+		backfill, err := store.GetIssueBackfill(r.Context(), userID, id)
+		if errors.Is(err, storepkg.ErrIssueBackfillNotFound) {
+			http.Error(w, "issue backfill not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Printf("IssueBackfill: failed to get backfill id=%d for user_id=%d: %v", id, userID, err)
+			http.Error(w, "failed to get issue backfill", http.StatusBadGateway)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"backfill": backfill})
+	}
+}
+
+// ResumeIssueBackfill queues the caller's failed backfill in the {id} URL
+// parameter again; it continues from its last checkpoint.
+func ResumeIssueBackfill(store IssueBackfillStore, jobs JobEnqueuer, cookieSecret string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := metricsUserID(w, r, store, cookieSecret)
+		if !ok {
+			return
+		}
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil || id <= 0 {
+			http.Error(w, "invalid issue backfill id", http.StatusBadRequest)
+			return
+		}
+
+		backfill, err := store.ResumeIssueBackfill(r.Context(), userID, id)
+		switch {
+		case errors.Is(err, storepkg.ErrIssueBackfillNotFound):
+			http.Error(w, "issue backfill not found", http.StatusNotFound)
+			return
+		case errors.Is(err, storepkg.ErrIssueBackfillActive), errors.Is(err, storepkg.ErrIssueBackfillNotResumable):
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		case err != nil:
+			log.Printf("ResumeIssueBackfill: failed to resume backfill id=%d for user_id=%d: %v", id, userID, err)
+			http.Error(w, "failed to resume issue backfill", http.StatusBadGateway)
+			return
+		}
+		if !queueIssueBackfill(r.Context(), w, store, jobs, backfill) {
+			return
+		}
+		writeJSON(w, http.StatusAccepted, map[string]any{"backfill": backfill})
+	}
+}
+
+// queueIssueBackfill queues the job running backfill and records it. A
+// backfill that cannot be queued is marked failed so it can be resumed.
+func queueIssueBackfill(ctx context.Context, w http.ResponseWriter, store IssueBackfillStore, jobs JobEnqueuer, backfill *models.IssueBackfill) bool {
+	job := worker.IssueBackfillJob(backfill.UserID, backfill.ID)
+	if err := jobs.Enqueue(ctx, job); err != nil {
+		log.Printf("IssueBackfills: failed to queue backfill id=%d: %v", backfill.ID, err)
+		if err := store.RecordIssueBackfillError(ctx, backfill.ID, "could not be queued", true); err != nil {
+			log.Printf("IssueBackfills: failed to mark backfill id=%d failed: %v", backfill.ID, err)
+		}
+		http.Error(w, "failed to queue issue backfill", http.StatusBadGateway)
+		return false
+	}
+	if err := store.SetIssueBackfillJob(ctx, backfill.ID, job.ID); err != nil {
+		log.Printf("IssueBackfills: failed to record job of backfill id=%d: %v", backfill.ID, err)
+	} else {
+		backfill.JobID = &job.ID
+	}
+	return true
+}
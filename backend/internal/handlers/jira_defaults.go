@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+	storepkg "github.com/PortNumber53/mcp-jira-thing/backend/internal/store"
+)
+
+// maxJiraDefaultValues bounds the labels and components a tenant can default.
+const maxJiraDefaultValues = 20
+
+var jiraProjectKeyPattern = regexp.MustCompile(`^[A-Z][A-Z0-9_]{0,254}$`)
+
+// JiraIssueDefaultsStore defines the behaviour required to read and update
+// a tenant's Jira issue defaults.
+type JiraIssueDefaultsStore interface {
+	GetJiraIssueDefaults(ctx context.Context, email string) (*models.JiraIssueDefaults, error)
+	UpsertJiraIssueDefaults(ctx context.Context, email string, defaults models.JiraIssueDefaults, ifRevision *int64) (*models.JiraIssueDefaults, error)
+}
+
+type jiraIssueDefaultsPayload struct {
+	ProjectKey *string   `json:"project_key"`
+	IssueType  *string   `json:"issue_type"`
+	Labels     *[]string `json:"labels"`
+	Components *[]string `json:"components"`
+}
+
+// JiraIssueDefaults reads or updates the project, issue type, labels and
+// components the jira_create_issue MCP tool uses when its arguments leave
+// them out.
+// GET
+// POST {"project_key": "ENG", "issue_type": "Task", "labels": ["mcp"], "components": ["Backend"]}
+// Omitted fields keep their current value; "" or [] clears one. Send
+// If-Match: "<revision>" to reject the write when the defaults changed since
+// they were read.
+func JiraIssueDefaults(store JiraIssueDefaultsStore, cookieSecret string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		email := requestEmail(r, cookieSecret, "")
+		if email == "" {
+			http.Error(w, "not authenticated", http.StatusUnauthorized)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			defaults, err := store.GetJiraIssueDefaults(r.Context(), email)
+			if err != nil {
+				log.Printf("JiraIssueDefaults: failed to load defaults for email=%s: %v", email, err)
+				http.Error(w, "failed to load jira issue defaults", http.StatusBadGateway)
+				return
+			}
+			writeJSON(w, http.StatusOK, defaults)
+
+		case http.MethodPost:
+			ifRevision, err := ifMatchRevision(r)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			var payload jiraIssueDefaultsPayload
+			if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+				log.Printf("JiraIssueDefaults: invalid JSON payload: %v", err)
+				http.Error(w, "invalid JSON payload", http.StatusBadRequest)
+				return
+			}
+
+			current, err := store.GetJiraIssueDefaults(r.Context(), email)
+			if err != nil {
+				log.Printf("JiraIssueDefaults: failed to load defaults for email=%s: %v", email, err)
+				http.Error(w, "failed to load jira issue defaults", http.StatusBadGateway)
+				return
+			}
+
+			defaults, msg := mergeJiraIssueDefaults(*current, payload)
+			if msg != "" {
+				http.Error(w, msg, http.StatusBadRequest)
+				return
+			}
+
+			saved, err := store.UpsertJiraIssueDefaults(r.Context(), email, defaults, ifRevision)
+			if errors.Is(err, storepkg.ErrRevisionMismatch) {
+				writeRevisionConflict(w)
+				return
+			}
+			if err != nil {
+				log.Printf("JiraIssueDefaults: failed to save defaults for email=%s: %v", email, err)
+				http.Error(w, "failed to save jira issue defaults", http.StatusBadGateway)
+				return
+			}
+			writeJSON(w, http.StatusOK, saved)
+
+		default:
+			w.Header().Set("Allow", "GET, POST")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// mergeJiraIssueDefaults applies the payload fields that are set over
+// current and returns a client-facing message when a value is invalid.
+func mergeJiraIssueDefaults(current models.JiraIssueDefaults, payload jiraIssueDefaultsPayload) (models.JiraIssueDefaults, string) {
+	defaults := current
+	defaults.UpdatedAt = nil
+
+	if payload.ProjectKey != nil {
+		key := strings.ToUpper(strings.TrimSpace(*payload.ProjectKey))
+		if key != "" && !jiraProjectKeyPattern.MatchString(key) {
+			return defaults, "invalid project_key: use a Jira project key such as ENG"
+		}
+		defaults.ProjectKey = key
+	}
+
+	if payload.IssueType != nil {
+		defaults.IssueType = strings.TrimSpace(*payload.IssueType)
+	}
+
+	if payload.Labels != nil {
+		labels, msg := cleanJiraDefaultValues("labels", *payload.Labels)
+		if msg != "" {
+			return defaults, msg
+		}
+		for _, label := range labels {
+			if strings.ContainsAny(label, " \t") {
+				return defaults, "invalid labels: Jira labels cannot contain spaces"
+			}
+		}
+		defaults.Labels = labels
+	}
+
+	if payload.Components != nil {
+		components, msg := cleanJiraDefaultValues("components", *payload.Components)
+		if msg != "" {
+			return defaults, msg
+		}
+		defaults.Components = components
+	}
+
+	return defaults, ""
+}
+
+// cleanJiraDefaultValues trims values and drops empty and repeated ones.
+func cleanJiraDefaultValues(field string, values []string) ([]string, string) {
+	cleaned := make([]string, 0, len(values))
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" && !slices.Contains(cleaned, value) {
+			cleaned = append(cleaned, value)
+		}
+	}
+	if len(cleaned) > maxJiraDefaultValues {
+		return nil, fmt.Sprintf("too many %s: at most %d are allowed", field, maxJiraDefaultValues)
+	}
+	return cleaned, ""
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+	storepkg "github.com/PortNumber53/mcp-jira-thing/backend/internal/store"
+)
+
+type fakeJiraIssueDefaultsStore struct {
+	defaults models.JiraIssueDefaults
+}
+
+func (f *fakeJiraIssueDefaultsStore) GetJiraIssueDefaults(ctx context.Context, email string) (*models.JiraIssueDefaults, error) {
+	defaults := f.defaults
+	return &defaults, nil
+}
+
+func (f *fakeJiraIssueDefaultsStore) UpsertJiraIssueDefaults(ctx context.Context, email string, defaults models.JiraIssueDefaults, ifRevision *int64) (*models.JiraIssueDefaults, error) {
+	if ifRevision != nil && *ifRevision != f.defaults.Revision {
+		return nil, storepkg.ErrRevisionMismatch
+	}
+	defaults.Revision = f.defaults.Revision + 1
+	f.defaults = defaults
+	return &defaults, nil
+}
+
+func TestJiraIssueDefaults(t *testing.T) {
+	store := &fakeJiraIssueDefaultsStore{defaults: models.JiraIssueDefaults{IssueType: "Bug", Revision: 1}}
+	handler := JiraIssueDefaults(store, "secret")
+	post := func(body, ifMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/settings/jira/defaults", strings.NewReader(body))
+		req.AddCookie(sessionCookie(t, "secret", "sid"))
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := post(`{"project_key":" eng ","labels":["mcp","mcp"," ops "],"components":["API"]}`, `"1"`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %q", rec.Code, rec.Body.String())
+	}
+	var saved models.JiraIssueDefaults
+	if err := json.Unmarshal(rec.Body.Bytes(), &saved); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if saved.ProjectKey != "ENG" || saved.IssueType != "Bug" || !slices.Equal(saved.Labels, []string{"mcp", "ops"}) || !slices.Equal(saved.Components, []string{"API"}) {
+		t.Fatalf("expected the payload merged over the current defaults, got %+v", saved)
+	}
+
+	if rec := post(`{"issue_type":"Task"}`, `"1"`); rec.Code != http.StatusPreconditionFailed {
+		t.Fatalf("stale If-Match status = %d, want 412", rec.Code)
+	}
+	for _, body := range []string{`{"project_key":"1ENG"}`, `{"labels":["two words"]}`} {
+		if rec := post(body, ""); rec.Code != http.StatusBadRequest {
+			t.Fatalf("%s status = %d, want 400", body, rec.Code)
+		}
+	}
+	if rec := post(`{"labels":[]}`, ""); rec.Code != http.StatusOK || len(store.defaults.Labels) != 0 || store.defaults.ProjectKey != "ENG" {
+		t.Fatalf("expected [] to clear only the labels, got %d %+v", rec.Code, store.defaults)
+	}
+}
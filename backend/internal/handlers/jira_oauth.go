@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/config"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/jira"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/session"
+	storepkg "github.com/PortNumber53/mcp-jira-thing/backend/internal/store"
+)
+
+// JiraOAuthStore defines the behaviour required to save the Jira sites a
+// user connects through Atlassian OAuth.
+type JiraOAuthStore interface {
+	ConnectJiraOAuth(ctx context.Context, userEmail string, conn *models.JiraOAuthConnection) error
+}
+
+// JiraOAuthLogin sends the signed-in user to Atlassian's consent page to
+// connect their Jira sites.
+// GET ?redirect=/settings
+func JiraOAuthLogin(cfg config.Config, oauth *jira.OAuth) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, err := session.ReadSession(r, cfg.CookieSecret); err != nil {
+			http.Redirect(w, r, cfg.FrontendURL+"/login?redirect=/settings", http.StatusSeeOther)
+			return
+		}
+
+		redirect := r.URL.Query().Get("redirect")
+		if !strings.HasPrefix(redirect, "/") || strings.HasPrefix(redirect, "//") {
+			redirect = "/settings"
+		}
+
+		nonce, err := session.RandomHex(32)
+		if err != nil {
+			log.Printf("[jira-oauth] failed to generate nonce: %v", err)
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		stateCookie, err := session.Encode(cfg.CookieSecret, session.StatePayload{
+			Nonce:     nonce,
+			Redirect:  redirect,
+			CreatedAt: time.Now().UnixMilli(),
+		})
+		if err != nil {
+			log.Printf("[jira-oauth] failed to encode state: %v", err)
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		secure := strings.HasPrefix(cfg.BackendURL, "https")
+		session.SetCookie(w, session.StateCookie, stateCookie, cfg.CookieDomain, int(session.StateTTL.Seconds()), secure)
+
+		http.Redirect(w, r, oauth.AuthCodeURL(nonce), http.StatusFound)
+	}
+}
+
+// JiraOAuthCallback handles the redirect back from Atlassian: it exchanges
+// the authorization code for tokens, looks up the Jira sites they grant
+// access to and saves them as the user's Jira settings.
+func JiraOAuthCallback(cfg config.Config, store JiraOAuthStore, oauth *jira.OAuth) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		secure := strings.HasPrefix(cfg.BackendURL, "https")
+
+		sess, err := session.ReadSession(r, cfg.CookieSecret)
+		if err != nil || sess.Email == nil || *sess.Email == "" {
+			redirectWithError(w, r, cfg.FrontendURL, "not authenticated")
+			return
+		}
+
+		q := r.URL.Query()
+		if denied := q.Get("error"); denied != "" {
+			log.Printf("[jira-oauth-callback] authorization failed: %s", denied)
+			redirectJiraOAuthError(w, r, cfg.FrontendURL, "Jira access was not granted")
+			return
+		}
+
+		code := q.Get("code")
+		stateParam := q.Get("state")
+		if code == "" || stateParam == "" {
+			redirectJiraOAuthError(w, r, cfg.FrontendURL, "missing code or state")
+			return
+		}
+
+		stateCookie, err := r.Cookie(session.StateCookie)
+		if err != nil {
+			log.Printf("[jira-oauth-callback] missing state cookie: %v", err)
+			redirectJiraOAuthError(w, r, cfg.FrontendURL, "missing state cookie")
+			return
+		}
+
+		var state session.StatePayload
+		if err := session.Decode(cfg.CookieSecret, stateCookie.Value, &state); err != nil {
+			log.Printf("[jira-oauth-callback] invalid state cookie: %v", err)
+			redirectJiraOAuthError(w, r, cfg.FrontendURL, "invalid state")
+			return
+		}
+		if state.Nonce != stateParam {
+			log.Printf("[jira-oauth-callback] state mismatch: cookie=%q param=%q", state.Nonce, stateParam)
+			redirectJiraOAuthError(w, r, cfg.FrontendURL, "state mismatch")
+			return
+		}
+		if time.Since(time.UnixMilli(state.CreatedAt)) > session.StateTTL {
+			redirectJiraOAuthError(w, r, cfg.FrontendURL, "state expired")
+			return
+		}
+		session.ClearCookie(w, session.StateCookie, cfg.CookieDomain, secure)
+
+		token, err := oauth.Exchange(r.Context(), code)
+		if err != nil {
+			log.Printf("[jira-oauth-callback] token exchange failed: %v", err)
+			redirectJiraOAuthError(w, r, cfg.FrontendURL, "token exchange failed")
+			return
+		}
+
+		sites, err := oauth.AccessibleResources(r.Context(), token.AccessToken)
+		if err != nil {
+			log.Printf("[jira-oauth-callback] failed to list accessible sites: %v", err)
+			redirectJiraOAuthError(w, r, cfg.FrontendURL, "failed to list Jira sites")
+			return
+		}
+		if len(sites) == 0 {
+			redirectJiraOAuthError(w, r, cfg.FrontendURL, "no Jira sites were shared")
+			return
+		}
+
+		accountEmail, err := oauth.Email(r.Context(), token.AccessToken)
+		if err != nil {
+			// The email is only shown next to the site; the tokens work
+			// without it.
+			log.Printf("[jira-oauth-callback] failed to read account email: %v", err)
+		}
+
+		conn := &models.JiraOAuthConnection{
+			AccountEmail: accountEmail,
+			AccessToken:  token.AccessToken,
+			RefreshToken: token.RefreshToken,
+			ExpiresAt:    token.ExpiresAt,
+			Scopes:       token.Scope,
+		}
+		for _, site := range sites {
+			conn.Sites = append(conn.Sites, models.JiraOAuthSite{
+				CloudID: site.ID,
+				URL:     strings.TrimRight(site.URL, "/"),
+				Name:    site.Name,
+			})
+		}
+
+		if err := store.ConnectJiraOAuth(r.Context(), *sess.Email, conn); err != nil {
+			if errors.Is(err, storepkg.ErrUserNotFound) {
+				redirectWithError(w, r, cfg.FrontendURL, "not authenticated")
+				return
+			}
+			log.Printf("[jira-oauth-callback] failed to save jira sites for email=%s: %v", *sess.Email, err)
+			redirectJiraOAuthError(w, r, cfg.FrontendURL, "failed to save Jira settings")
+			return
+		}
+
+		redirect := state.Redirect
+		if redirect == "" {
+			redirect = "/settings"
+		}
+		sep := "?"
+		if strings.Contains(redirect, "?") {
+			sep = "&"
+		}
+		http.Redirect(w, r, cfg.FrontendURL+redirect+sep+"jira=connected", http.StatusSeeOther)
+	}
+}
+
+func redirectJiraOAuthError(w http.ResponseWriter, r *http.Request, frontendURL, msg string) {
+	http.Redirect(w, r, frontendURL+"/settings?jira_error="+url.QueryEscape(msg), http.StatusSeeOther)
+}
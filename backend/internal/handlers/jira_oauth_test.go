@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/config"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/jira"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/session"
+)
+
+type fakeJiraOAuthStore struct {
+	email string
+	conn  *models.JiraOAuthConnection
+}
+
+func (f *fakeJiraOAuthStore) ConnectJiraOAuth(ctx context.Context, userEmail string, conn *models.JiraOAuthConnection) error {
+	f.email, f.conn = userEmail, conn
+	return nil
+}
+
+func newFakeAtlassian(t *testing.T) *jira.OAuth {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/oauth/token":
+			var body map[string]string
+			json.NewDecoder(r.Body).Decode(&body)
+			if body["code"] != "good-code" {
+				w.WriteHeader(http.StatusForbidden)
+				w.Write([]byte(`{"error":"invalid_grant","error_description":"Invalid authorization code"}`))
+				return
+			}
+			w.Write([]byte(`{"access_token":"access","refresh_token":"refresh","expires_in":3600,"scope":"read:jira-work offline_access"}`))
+		case "/oauth/token/accessible-resources":
+			if r.Header.Get("Authorization") != "Bearer access" {
+				t.Errorf("unexpected authorization %q", r.Header.Get("Authorization"))
+			}
+			w.Write([]byte(`[{"id":"cloud-1","url":"https://acme.atlassian.net/","name":"acme"}]`))
+		case "/me":
+			w.Write([]byte(`{"email":"jane@acme.com"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	oauth := jira.NewOAuth("client", "secret", "https://api.example.com/callback/atlassian")
+	oauth.TokenURL = server.URL + "/oauth/token"
+	oauth.APIURL = server.URL
+	oauth.HTTPClient = server.Client()
+	return oauth
+}
+
+func jiraOAuthState(t *testing.T, secret, nonce string) *http.Cookie {
+	t.Helper()
+	token, err := session.Encode(secret, session.StatePayload{Nonce: nonce, Redirect: "/settings", CreatedAt: time.Now().UnixMilli()})
+	if err != nil {
+		t.Fatalf("encode state: %v", err)
+	}
+	return &http.Cookie{Name: session.StateCookie, Value: token}
+}
+
+func TestJiraOAuthLoginRedirectsToConsent(t *testing.T) {
+	cfg := config.Config{CookieSecret: "secret", FrontendURL: "https://app.example.com"}
+	handler := JiraOAuthLogin(cfg, newFakeAtlassian(t))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/settings/jira/oauth/login", nil)
+	req.AddCookie(sessionCookie(t, cfg.CookieSecret, ""))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusFound {
+		t.Fatalf("expected 302, got %d", rec.Code)
+	}
+	target, err := url.Parse(rec.Header().Get("Location"))
+	if err != nil {
+		t.Fatalf("parse location: %v", err)
+	}
+	if target.Host != "auth.atlassian.com" || target.Query().Get("client_id") != "client" || target.Query().Get("state") == "" {
+		t.Fatalf("unexpected consent URL %s", target)
+	}
+	if !strings.Contains(target.Query().Get("scope"), "offline_access") {
+		t.Fatalf("expected offline_access scope, got %q", target.Query().Get("scope"))
+	}
+	if !strings.Contains(rec.Header().Get("Set-Cookie"), session.StateCookie+"=") {
+		t.Fatalf("expected state cookie, got %q", rec.Header().Get("Set-Cookie"))
+	}
+}
+
+func TestJiraOAuthCallbackSavesSites(t *testing.T) {
+	cfg := config.Config{CookieSecret: "secret", FrontendURL: "https://app.example.com"}
+	store := &fakeJiraOAuthStore{}
+	handler := JiraOAuthCallback(cfg, store, newFakeAtlassian(t))
+
+	req := httptest.NewRequest(http.MethodGet, "/callback/atlassian?code=good-code&state=nonce", nil)
+	req.AddCookie(sessionCookie(t, cfg.CookieSecret, ""))
+	req.AddCookie(jiraOAuthState(t, cfg.CookieSecret, "nonce"))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if got := rec.Header().Get("Location"); got != "https://app.example.com/settings?jira=connected" {
+		t.Fatalf("unexpected redirect %q", got)
+	}
+	if store.email != "user@example.com" || store.conn == nil {
+		t.Fatalf("expected connection saved for user@example.com, got %q %+v", store.email, store.conn)
+	}
+	conn := store.conn
+	if conn.AccessToken != "access" || conn.RefreshToken != "refresh" || conn.AccountEmail != "jane@acme.com" {
+		t.Fatalf("unexpected tokens %+v", conn)
+	}
+	if len(conn.Sites) != 1 || conn.Sites[0].CloudID != "cloud-1" || conn.Sites[0].URL != "https://acme.atlassian.net" {
+		t.Fatalf("unexpected sites %+v", conn.Sites)
+	}
+	if time.Until(conn.ExpiresAt) < 50*time.Minute {
+		t.Fatalf("unexpected expiry %v", conn.ExpiresAt)
+	}
+}
+
+func TestJiraOAuthCallbackRejectsBadState(t *testing.T) {
+	cfg := config.Config{CookieSecret: "secret", FrontendURL: "https://app.example.com"}
+	store := &fakeJiraOAuthStore{}
+	handler := JiraOAuthCallback(cfg, store, newFakeAtlassian(t))
+
+	for name, query := range map[string]string{
+		"state mismatch": "code=good-code&state=other",
+		"invalid code":   "code=bad-code&state=nonce",
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/callback/atlassian?"+query, nil)
+		req.AddCookie(sessionCookie(t, cfg.CookieSecret, ""))
+		req.AddCookie(jiraOAuthState(t, cfg.CookieSecret, "nonce"))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if got := rec.Header().Get("Location"); !strings.HasPrefix(got, "https://app.example.com/settings?jira_error=") {
+			t.Fatalf("%s: unexpected redirect %q", name, got)
+		}
+	}
+	if store.conn != nil {
+		t.Fatalf("expected nothing saved, got %+v", store.conn)
+	}
+}
@@ -0,0 +1,272 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/events"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/jira"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+)
+
+// EventPublisher announces application events to interested subscribers.
+type EventPublisher interface {
+	Publish(ctx context.Context, ev events.Event)
+}
+
+// JiraWebhookStore de-duplicates Jira webhook deliveries and keeps the
+// mirrored state of each issue.
+type JiraWebhookStore interface {
+	RecordJiraWebhookDelivery(ctx context.Context, userID int64, deliveryID string) (bool, error)
+	ApplyJiraIssueState(ctx context.Context, state *models.JiraIssueState) (bool, error)
+}
+
+// DefaultJiraWebhookDelay is how long events for an issue are held so that
+// deliveries arriving out of order can be applied in timestamp order.
+const DefaultJiraWebhookDelay = 2 * time.Second
+
+type jiraWebhookPayload struct {
+	WebhookEvent string `json:"webhookEvent"`
+	Timestamp    int64  `json:"timestamp"`
+	Issue        *struct {
+		ID     string `json:"id"`
+		Key    string `json:"key"`
+		Fields struct {
+			Summary string `json:"summary"`
+			Status  *struct {
+				Name string `json:"name"`
+			} `json:"status"`
+		} `json:"fields"`
+	} `json:"issue"`
+	User *struct {
+		DisplayName string `json:"displayName"`
+	} `json:"user"`
+	Comment *struct {
+		ID     string `json:"id"`
+		Body   any    `json:"body"`
+		Author *struct {
+			DisplayName string `json:"displayName"`
+		} `json:"author"`
+	} `json:"comment"`
+}
+
+// jiraWebhookEvent is a delivery waiting to be applied.
+type jiraWebhookEvent struct {
+	userID  int64
+	payload jiraWebhookPayload
+}
+
+type jiraIssueRef struct {
+	userID  int64
+	issueID string
+}
+
+// JiraWebhookSequencer applies Jira webhook events in timestamp order per
+// issue. Events for an issue are buffered for a short delay after the first
+// one arrives, then applied oldest first; an event older than the mirrored
+// state is dropped, so the mirror and the events published from it never go
+// back to an older state.
+type JiraWebhookSequencer struct {
+	store     JiraWebhookStore
+	publisher EventPublisher
+	delay     time.Duration
+
+	mu      sync.Mutex
+	pending map[jiraIssueRef][]jiraWebhookEvent
+	timers  map[jiraIssueRef]*time.Timer
+}
+
+// NewJiraWebhookSequencer creates a sequencer that publishes applied events
+// to publisher. A nil store disables de-duplication and the mirror; events
+// are then only ordered within the delay.
+func NewJiraWebhookSequencer(store JiraWebhookStore, publisher EventPublisher, delay time.Duration) *JiraWebhookSequencer {
+	if delay <= 0 {
+		delay = DefaultJiraWebhookDelay
+	}
+	return &JiraWebhookSequencer{
+		store:     store,
+		publisher: publisher,
+		delay:     delay,
+		pending:   map[jiraIssueRef][]jiraWebhookEvent{},
+		timers:    map[jiraIssueRef]*time.Timer{},
+	}
+}
+
+// submit queues an event. Events that are not about an issue have nothing
+// to order against and are applied right away.
+func (s *JiraWebhookSequencer) submit(ev jiraWebhookEvent) {
+	if ev.payload.Issue == nil || ev.payload.Issue.ID == "" {
+		s.apply(ev)
+		return
+	}
+
+	ref := jiraIssueRef{userID: ev.userID, issueID: ev.payload.Issue.ID}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pending[ref] = append(s.pending[ref], ev)
+	if _, ok := s.timers[ref]; !ok {
+		s.timers[ref] = time.AfterFunc(s.delay, func() { s.release(ref) })
+	}
+}
+
+// release applies the buffered events of one issue, oldest first.
+func (s *JiraWebhookSequencer) release(ref jiraIssueRef) {
+	s.mu.Lock()
+	batch := s.pending[ref]
+	delete(s.pending, ref)
+	delete(s.timers, ref)
+	s.mu.Unlock()
+
+	sort.SliceStable(batch, func(i, j int) bool {
+		return batch[i].payload.Timestamp < batch[j].payload.Timestamp
+	})
+	var latest int64
+	for _, ev := range batch {
+		// Without a store, stale events are only caught within the batch.
+		if s.store == nil && ev.payload.Timestamp < latest {
+			continue
+		}
+		latest = ev.payload.Timestamp
+		s.apply(ev)
+	}
+}
+
+// Flush applies every buffered event now. Call it on shutdown after the
+// HTTP server has stopped accepting webhooks.
+func (s *JiraWebhookSequencer) Flush() {
+	s.mu.Lock()
+	refs := make([]jiraIssueRef, 0, len(s.timers))
+	for ref, timer := range s.timers {
+		if timer.Stop() {
+			refs = append(refs, ref)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, ref := range refs {
+		s.release(ref)
+	}
+}
+
+// apply records an issue event in the mirror and publishes it, unless the
+// mirror already holds a newer event for the issue.
+func (s *JiraWebhookSequencer) apply(ev jiraWebhookEvent) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	payload := ev.payload
+	data := map[string]any{
+		"webhook_event": payload.WebhookEvent,
+		"timestamp":     payload.Timestamp,
+	}
+	if payload.Issue != nil {
+		data["issue_id"] = payload.Issue.ID
+		data["issue_key"] = payload.Issue.Key
+		data["summary"] = payload.Issue.Fields.Summary
+		if payload.Issue.Fields.Status != nil {
+			data["status"] = payload.Issue.Fields.Status.Name
+		}
+	}
+	if payload.User != nil {
+		data["actor"] = payload.User.DisplayName
+	}
+	if payload.Comment != nil {
+		data["comment_id"] = payload.Comment.ID
+		data["comment_body"] = jira.DocumentText(payload.Comment.Body)
+		if payload.Comment.Author != nil {
+			data["comment_author"] = payload.Comment.Author.DisplayName
+		}
+	}
+
+	if s.store != nil && payload.Issue != nil && payload.Issue.ID != "" {
+		state := &models.JiraIssueState{
+			UserID:         ev.userID,
+			IssueID:        payload.Issue.ID,
+			IssueKey:       payload.Issue.Key,
+			Summary:        payload.Issue.Fields.Summary,
+			Deleted:        payload.WebhookEvent == "jira:issue_deleted",
+			WebhookEvent:   payload.WebhookEvent,
+			EventTimestamp: payload.Timestamp,
+		}
+		if payload.Issue.Fields.Status != nil {
+			state.Status = payload.Issue.Fields.Status.Name
+		}
+		applied, err := s.store.ApplyJiraIssueState(ctx, state)
+		if err != nil {
+			log.Printf("JiraWebhook: failed to update mirror of issue %s for user %d: %v", payload.Issue.Key, ev.userID, err)
+			return
+		}
+		if !applied {
+			log.Printf("JiraWebhook: dropped stale %s for issue %s (timestamp %d)", payload.WebhookEvent, payload.Issue.Key, payload.Timestamp)
+			return
+		}
+	}
+
+	s.publisher.Publish(ctx, events.JiraWebhookReceived{
+		UserID:       ev.userID,
+		WebhookEvent: payload.WebhookEvent,
+		Data:         data,
+	})
+}
+
+// JiraWebhook receives Jira Cloud webhooks registered with the tenant's
+// mcp_secret in the callback URL and hands them to the sequencer, which
+// republishes them as JiraWebhookReceived events. Redelivered webhooks
+// (same X-Atlassian-Webhook-Identifier, or the same body when the header is
+// missing) are acknowledged and ignored.
+func JiraWebhook(sequencer *JiraWebhookSequencer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value("user_id").(int64)
+		if !ok || userID <= 0 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, 1<<20))
+		if err != nil {
+			http.Error(w, "invalid JSON payload", http.StatusBadRequest)
+			return
+		}
+		var payload jiraWebhookPayload
+		if err := json.Unmarshal(body, &payload); err != nil {
+			log.Printf("JiraWebhook: invalid JSON payload: %v", err)
+			http.Error(w, "invalid JSON payload", http.StatusBadRequest)
+			return
+		}
+
+		payload.WebhookEvent = strings.TrimSpace(payload.WebhookEvent)
+		if payload.WebhookEvent == "" {
+			http.Error(w, "webhookEvent is required", http.StatusBadRequest)
+			return
+		}
+
+		if sequencer.store != nil {
+			deliveryID := strings.TrimSpace(r.Header.Get("X-Atlassian-Webhook-Identifier"))
+			if deliveryID == "" {
+				sum := sha256.Sum256(body)
+				deliveryID = "sha256:" + hex.EncodeToString(sum[:])
+			}
+			fresh, err := sequencer.store.RecordJiraWebhookDelivery(r.Context(), userID, deliveryID)
+			if err != nil {
+				// The mirror still refuses stale state, so keep going.
+				log.Printf("JiraWebhook: failed to record delivery %s: %v", deliveryID, err)
+			} else if !fresh {
+				log.Printf("JiraWebhook: ignoring redelivered webhook %s for user %d", deliveryID, userID)
+				w.WriteHeader(http.StatusOK)
+				return
+			}
+		}
+
+		sequencer.submit(jiraWebhookEvent{userID: userID, payload: payload})
+		w.WriteHeader(http.StatusAccepted)
+	}
+}
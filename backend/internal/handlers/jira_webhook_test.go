@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/events"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+)
+
+type fakeJiraWebhookStore struct {
+	deliveries map[string]bool
+	mirror     map[string]models.JiraIssueState
+}
+
+func (f *fakeJiraWebhookStore) RecordJiraWebhookDelivery(ctx context.Context, userID int64, deliveryID string) (bool, error) {
+	key := strconv.FormatInt(userID, 10) + "/" + deliveryID
+	if f.deliveries[key] {
+		return false, nil
+	}
+	f.deliveries[key] = true
+	return true, nil
+}
+
+func (f *fakeJiraWebhookStore) ApplyJiraIssueState(ctx context.Context, state *models.JiraIssueState) (bool, error) {
+	if current, ok := f.mirror[state.IssueID]; ok && current.EventTimestamp > state.EventTimestamp {
+		return false, nil
+	}
+	f.mirror[state.IssueID] = *state
+	return true, nil
+}
+
+func deliverJiraWebhook(t *testing.T, handler http.HandlerFunc, deliveryID string, timestamp int64, status string) int {
+	t.Helper()
+	body := `{"webhookEvent":"jira:issue_updated","timestamp":` + strconv.FormatInt(timestamp, 10) +
+		`,"issue":{"id":"10001","key":"ABC-1","fields":{"summary":"Fix login","status":{"name":"` + status + `"}}}}`
+	req := httptest.NewRequest(http.MethodPost, "/api/webhooks/jira", strings.NewReader(body))
+	req.Header.Set("X-Atlassian-Webhook-Identifier", deliveryID)
+	req = req.WithContext(context.WithValue(req.Context(), "user_id", int64(7)))
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+	return rec.Code
+}
+
+func TestJiraWebhookAppliesIssueEventsInOrder(t *testing.T) {
+	store := &fakeJiraWebhookStore{deliveries: map[string]bool{}, mirror: map[string]models.JiraIssueState{}}
+	published := &recordingPublisher{}
+	sequencer := NewJiraWebhookSequencer(store, published, time.Hour)
+	handler := JiraWebhook(sequencer)
+
+	// Delivered out of order, with one retry.
+	for _, d := range []struct {
+		id        string
+		timestamp int64
+		status    string
+		want      int
+	}{
+		{"d2", 2000, "In Review", http.StatusAccepted},
+		{"d1", 1000, "In Progress", http.StatusAccepted},
+		{"d2", 2000, "In Review", http.StatusOK},
+		{"d3", 3000, "Done", http.StatusAccepted},
+	} {
+		if got := deliverJiraWebhook(t, handler, d.id, d.timestamp, d.status); got != d.want {
+			t.Fatalf("delivery %s: expected %d, got %d", d.id, d.want, got)
+		}
+	}
+	if len(published.events) != 0 {
+		t.Fatalf("expected events to be held until the delay passes, got %d", len(published.events))
+	}
+
+	sequencer.Flush()
+	var statuses []string
+	for _, ev := range published.events {
+		statuses = append(statuses, ev.(events.JiraWebhookReceived).Data["status"].(string))
+	}
+	if strings.Join(statuses, ",") != "In Progress,In Review,Done" {
+		t.Fatalf("expected events in timestamp order without the retry, got %v", statuses)
+	}
+
+	// A late delivery of an older state must not roll the mirror back.
+	deliverJiraWebhook(t, handler, "d0", 500, "To Do")
+	sequencer.Flush()
+	if got := store.mirror["10001"]; got.Status != "Done" || got.EventTimestamp != 3000 {
+		t.Fatalf("expected the mirror to stay at the newest state, got %+v", got)
+	}
+	if len(published.events) != 3 {
+		t.Fatalf("expected the stale event to be dropped, got %d events", len(published.events))
+	}
+}
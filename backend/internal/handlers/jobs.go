@@ -3,6 +3,9 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"strconv"
@@ -22,6 +25,12 @@ type JobStore interface {
 	GetStats(ctx context.Context) (*models.JobStats, error)
 	ListPendingJobs(ctx context.Context, limit int) ([]*models.Job, error)
 	ListProcessingJobs(ctx context.Context) ([]*models.Job, error)
+	ListFailedJobs(ctx context.Context, filter models.JobFilter) ([]*models.Job, error)
+	RetryJob(ctx context.Context, id int64) error
+	RetryFailedJobs(ctx context.Context, filter models.JobFilter) ([]int64, error)
+	ListJobEvents(ctx context.Context, jobID int64) ([]*models.JobEvent, error)
+	DeleteJob(ctx context.Context, id int64) error
+	RestoreJob(ctx context.Context, id int64) error
 }
 
 // CreateJobRequest represents a request to create a new job
@@ -32,6 +41,9 @@ type CreateJobRequest struct {
 	MaxAttempts  int                    `json:"max_attempts,omitempty"`
 	ScheduledFor *time.Time             `json:"scheduled_for,omitempty"`
 	Metadata     map[string]interface{} `json:"metadata,omitempty"`
+	// DedupWindowSeconds, when set, returns an identical pending job
+	// created within the window instead of creating a new one
+	DedupWindowSeconds int `json:"dedup_window_seconds,omitempty"`
 }
 
 // CreateJob creates a new job in the queue
@@ -74,6 +86,7 @@ func CreateJob(jobStore JobStore) http.HandlerFunc {
 			MaxAttempts:  maxAttempts,
 			ScheduledFor: req.ScheduledFor,
 			Metadata:     req.Metadata,
+			DedupWindow:  time.Duration(req.DedupWindowSeconds) * time.Second,
 		}
 
 		if err := jobStore.Enqueue(r.Context(), job); err != nil {
@@ -82,12 +95,18 @@ func CreateJob(jobStore JobStore) http.HandlerFunc {
 			return
 		}
 
+		status, message := http.StatusCreated, "Job created successfully"
+		if job.Deduplicated {
+			status, message = http.StatusOK, "Identical job already pending"
+		}
+
 		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusCreated)
+		w.WriteHeader(status)
 		if err := json.NewEncoder(w).Encode(map[string]interface{}{
-			"id":      job.ID,
-			"status":  job.Status,
-			"message": "Job created successfully",
+			"id":           job.ID,
+			"status":       job.Status,
+			"deduplicated": job.Deduplicated,
+			"message":      message,
 		}); err != nil {
 			log.Printf("CreateJob: failed to encode response: %v", err)
 		}
@@ -264,10 +283,274 @@ func ListProcessingJobs(jobStore JobStore) http.HandlerFunc {
 	}
 }
 
+// ListFailedJobs returns failed jobs matching the job_type, priority,
+// failed_after, failed_before (RFC3339), error_contains and limit query
+// parameters, so operators can preview a bulk retry
+func ListFailedJobs(jobStore JobStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		filter, err := jobFilterFromQuery(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		jobs, err := jobStore.ListFailedJobs(r.Context(), filter)
+		if err != nil {
+			log.Printf("ListFailedJobs: failed to list jobs: %v", err)
+			http.Error(w, "failed to retrieve jobs", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(map[string]interface{}{
+			"jobs":  jobs,
+			"count": len(jobs),
+		}); err != nil {
+			log.Printf("ListFailedJobs: failed to encode response: %v", err)
+		}
+	}
+}
+
+// RetryJob resets a failed or cancelled job's attempts and error and puts
+// it back in the queue
+func RetryJob(jobStore JobStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		jobID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			http.Error(w, "invalid job ID", http.StatusBadRequest)
+			return
+		}
+
+		if err := jobStore.RetryJob(r.Context(), jobID); err != nil {
+			switch {
+			case errors.Is(err, store.ErrJobNotFound):
+				http.Error(w, "job not found", http.StatusNotFound)
+			case errors.Is(err, store.ErrJobNotRetryable):
+				http.Error(w, err.Error(), http.StatusConflict)
+			default:
+				log.Printf("RetryJob: failed to retry job %d: %v", jobID, err)
+				http.Error(w, "failed to retry job", http.StatusInternalServerError)
+			}
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":      jobID,
+			"message": "Job requeued successfully",
+		}); err != nil {
+			log.Printf("RetryJob: failed to encode response: %v", err)
+		}
+	}
+}
+
+// DeleteJob deletes a completed, failed or cancelled job. It can be
+// restored until the retention job purges it.
+func DeleteJob(jobStore JobStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			w.Header().Set("Allow", http.MethodDelete)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		jobID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			http.Error(w, "invalid job ID", http.StatusBadRequest)
+			return
+		}
+
+		if err := jobStore.DeleteJob(r.Context(), jobID); err != nil {
+			switch {
+			case errors.Is(err, store.ErrJobNotFound):
+				http.Error(w, "job not found", http.StatusNotFound)
+			case errors.Is(err, store.ErrJobNotDeletable):
+				http.Error(w, err.Error(), http.StatusConflict)
+			default:
+				log.Printf("DeleteJob: failed to delete job %d: %v", jobID, err)
+				http.Error(w, "failed to delete job", http.StatusInternalServerError)
+			}
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":      jobID,
+			"message": "Job deleted successfully",
+		}); err != nil {
+			log.Printf("DeleteJob: failed to encode response: %v", err)
+		}
+	}
+}
+
+// RestoreJob restores a deleted job that has not been purged yet
+func RestoreJob(jobStore JobStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		jobID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			http.Error(w, "invalid job ID", http.StatusBadRequest)
+			return
+		}
+
+		if err := jobStore.RestoreJob(r.Context(), jobID); err != nil {
+			if errors.Is(err, store.ErrJobNotFound) {
+				http.Error(w, "deleted job not found", http.StatusNotFound)
+				return
+			}
+			log.Printf("RestoreJob: failed to restore job %d: %v", jobID, err)
+			http.Error(w, "failed to restore job", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":      jobID,
+			"message": "Job restored successfully",
+		}); err != nil {
+			log.Printf("RestoreJob: failed to encode response: %v", err)
+		}
+	}
+}
+
+// JobEvents returns a job's state transitions, oldest first, with the
+// worker and error recorded for each
+func JobEvents(jobStore JobStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		jobID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			http.Error(w, "invalid job ID", http.StatusBadRequest)
+			return
+		}
+
+		events, err := jobStore.ListJobEvents(r.Context(), jobID)
+		if err != nil {
+			if errors.Is(err, store.ErrJobNotFound) {
+				http.Error(w, "job not found", http.StatusNotFound)
+				return
+			}
+			log.Printf("JobEvents: failed to list events for job %d: %v", jobID, err)
+			http.Error(w, "failed to retrieve job events", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(map[string]interface{}{
+			"job_id": jobID,
+			"events": events,
+			"count":  len(events),
+		}); err != nil {
+			log.Printf("JobEvents: failed to encode response: %v", err)
+		}
+	}
+}
+
+// RetryFailedJobs requeues failed jobs matching a JSON filter, e.g.
+// {"job_type": "sync_issues", "error_contains": "timeout", "limit": 50}
+func RetryFailedJobs(jobStore JobStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var filter models.JobFilter
+		if err := json.NewDecoder(r.Body).Decode(&filter); err != nil && !errors.Is(err, io.EOF) {
+			http.Error(w, "invalid JSON payload", http.StatusBadRequest)
+			return
+		}
+		if filter.Priority != "" && !validPriority(filter.Priority) {
+			http.Error(w, "invalid priority", http.StatusBadRequest)
+			return
+		}
+
+		ids, err := jobStore.RetryFailedJobs(r.Context(), filter)
+		if err != nil {
+			log.Printf("RetryFailedJobs: failed to retry jobs: %v", err)
+			http.Error(w, "failed to retry jobs", http.StatusInternalServerError)
+			return
+		}
+		log.Printf("RetryFailedJobs: requeued %d jobs (filter %+v)", len(ids), filter)
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(map[string]interface{}{
+			"ids":   ids,
+			"count": len(ids),
+		}); err != nil {
+			log.Printf("RetryFailedJobs: failed to encode response: %v", err)
+		}
+	}
+}
+
+func jobFilterFromQuery(r *http.Request) (models.JobFilter, error) {
+	q := r.URL.Query()
+	filter := models.JobFilter{
+		JobType:       q.Get("job_type"),
+		Priority:      models.JobPriority(q.Get("priority")),
+		ErrorContains: q.Get("error_contains"),
+	}
+	if filter.Priority != "" && !validPriority(filter.Priority) {
+		return filter, errors.New("invalid priority")
+	}
+	for name, dst := range map[string]**time.Time{"failed_after": &filter.FailedAfter, "failed_before": &filter.FailedBefore} {
+		if v := q.Get(name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return filter, fmt.Errorf("invalid %s: expected RFC3339", name)
+			}
+			*dst = &t
+		}
+	}
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 || limit > 1000 {
+			return filter, errors.New("limit must be between 1 and 1000")
+		}
+		filter.Limit = limit
+	}
+	return filter, nil
+}
+
+func validPriority(p models.JobPriority) bool {
+	_, ok := models.PriorityWeights[p]
+	return ok
+}
+
 // JobHandler holds dependencies for job handlers
 type JobHandler struct {
 	Store  *store.JobStore
 	Worker *worker.Worker
+
+	// Metrics are served on /metrics after the worker's statistics.
+	Metrics []PrometheusSource
+
+	// Authorize, when set, guards the /api/jobs routes: reads need
+	// models.PermissionViewJobs and changes models.PermissionManageJobs.
+	Authorize func(perm models.Permission) func(http.Handler) http.Handler
 }
 
 // NewJobHandler creates a new JobHandler instance
@@ -280,10 +563,24 @@ func NewJobHandler(store *store.JobStore, worker *worker.Worker) *JobHandler {
 
 // RegisterRoutes registers job handlers with the router
 func (h *JobHandler) RegisterRoutes(router chi.Router) {
-	router.Post("/api/jobs", CreateJob(h.Store))
-	router.Get("/api/jobs", GetJob(h.Store))
-	router.Post("/api/jobs/{id}/cancel", CancelJob(h.Store))
-	router.Get("/api/jobs/stats", GetJobStats(h.Store))
-	router.Get("/api/jobs/pending", ListPendingJobs(h.Store))
-	router.Get("/api/jobs/processing", ListProcessingJobs(h.Store))
+	view, manage := router, router
+	if h.Authorize != nil {
+		view = router.With(h.Authorize(models.PermissionViewJobs))
+		manage = router.With(h.Authorize(models.PermissionManageJobs))
+	}
+	manage.Post("/api/jobs", CreateJob(h.Store))
+	view.Get("/api/jobs", GetJob(h.Store))
+	manage.Post("/api/jobs/{id}/cancel", CancelJob(h.Store))
+	manage.Post("/api/jobs/{id}/retry", RetryJob(h.Store))
+	manage.Delete("/api/jobs/{id}", DeleteJob(h.Store))
+	manage.Post("/api/jobs/{id}/restore", RestoreJob(h.Store))
+	view.Get("/api/jobs/{id}/events", JobEvents(h.Store))
+	manage.Post("/api/jobs/retry", RetryFailedJobs(h.Store))
+	view.Get("/api/jobs/failed", ListFailedJobs(h.Store))
+	view.Get("/api/jobs/stats", GetJobStats(h.Store))
+	view.Get("/api/jobs/pending", ListPendingJobs(h.Store))
+	view.Get("/api/jobs/processing", ListProcessingJobs(h.Store))
+	if h.Worker != nil {
+		router.Get("/metrics", WorkerMetrics(h.Worker, h.Metrics...))
+	}
 }
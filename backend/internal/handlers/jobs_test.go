@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/store"
+)
+
+type fakeRetryJobStore struct {
+	JobStore
+	statuses map[int64]models.JobStatus
+	filter   models.JobFilter
+}
+
+func (f *fakeRetryJobStore) RetryJob(ctx context.Context, id int64) error {
+	status, ok := f.statuses[id]
+	switch {
+	case !ok:
+		return store.ErrJobNotFound
+	case status != models.JobStatusFailed && status != models.JobStatusCancelled:
+		return store.ErrJobNotRetryable
+	}
+	f.statuses[id] = models.JobStatusPending
+	return nil
+}
+
+func (f *fakeRetryJobStore) RetryFailedJobs(ctx context.Context, filter models.JobFilter) ([]int64, error) {
+	f.filter = filter
+	return []int64{7, 9}, nil
+}
+
+func TestRetryJob(t *testing.T) {
+	jobs := &fakeRetryJobStore{statuses: map[int64]models.JobStatus{
+		1: models.JobStatusFailed,
+		2: models.JobStatusProcessing,
+	}}
+	router := chi.NewRouter()
+	router.Post("/api/jobs/{id}/retry", RetryJob(jobs))
+
+	for path, want := range map[string]int{
+		"/api/jobs/1/retry":   http.StatusOK,
+		"/api/jobs/2/retry":   http.StatusConflict,
+		"/api/jobs/3/retry":   http.StatusNotFound,
+		"/api/jobs/abc/retry": http.StatusBadRequest,
+	} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, path, nil))
+		if rec.Code != want {
+			t.Errorf("%s: expected %d, got %d", path, want, rec.Code)
+		}
+	}
+	if jobs.statuses[1] != models.JobStatusPending {
+		t.Fatalf("expected job 1 back to pending, got %s", jobs.statuses[1])
+	}
+}
+
+func TestRetryFailedJobs(t *testing.T) {
+	jobs := &fakeRetryJobStore{}
+	handler := RetryFailedJobs(jobs)
+
+	rec := httptest.NewRecorder()
+	body := `{"job_type":"sync_issues","priority":"high","error_contains":"timeout","limit":50}`
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/jobs/retry", strings.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if !strings.Contains(rec.Body.String(), `"count":2`) {
+		t.Fatalf("unexpected body %s", rec.Body.String())
+	}
+	if jobs.filter.JobType != "sync_issues" || jobs.filter.Priority != models.JobPriorityHigh || jobs.filter.Limit != 50 {
+		t.Fatalf("unexpected filter %+v", jobs.filter)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/jobs/retry", strings.NewReader(`{"priority":"urgent"}`)))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for unknown priority, got %d", rec.Code)
+	}
+}
+
+type fakeEventJobStore struct {
+	JobStore
+}
+
+func (fakeEventJobStore) ListJobEvents(ctx context.Context, jobID int64) ([]*models.JobEvent, error) {
+	if jobID != 1 {
+		return nil, store.ErrJobNotFound
+	}
+	return []*models.JobEvent{
+		{ID: 1, JobID: 1, Event: "enqueued", Status: models.JobStatusPending},
+		{ID: 2, JobID: 1, Event: "claimed", Status: models.JobStatusProcessing, Attempt: 1},
+	}, nil
+}
+
+func TestJobEvents(t *testing.T) {
+	router := chi.NewRouter()
+	router.Get("/api/jobs/{id}/events", JobEvents(fakeEventJobStore{}))
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/jobs/1/events", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if body := rec.Body.String(); !strings.Contains(body, `"count":2`) || !strings.Contains(body, `"event":"claimed"`) {
+		t.Fatalf("unexpected body %s", body)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/jobs/2/events", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for unknown job, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+)
+
+// LoginHistoryStore defines the behaviour required to read a user's login
+// history.
+type LoginHistoryStore interface {
+	ListLoginEvents(ctx context.Context, email string, limit int) ([]models.LoginEvent, error)
+}
+
+// oauthLoginRecorder records logins for accounts identified by their OAuth
+// provider identity.
+type oauthLoginRecorder interface {
+	RecordOAuthLoginEvent(ctx context.Context, provider, providerAccountID, ipAddress, userAgent string) error
+}
+
+// recordOAuthLogin appends a login to the user's history. Failures are
+// logged and never block the login.
+func recordOAuthLogin(ctx context.Context, store oauthLoginRecorder, provider, providerAccountID, ipAddress, userAgent string) {
+	if err := store.RecordOAuthLoginEvent(ctx, provider, providerAccountID, ipAddress, userAgent); err != nil {
+		log.Printf("recordOAuthLogin: failed to record %s login: %v", provider, err)
+	}
+}
+
+// LoginHistory returns the caller's recent logins with the provider, IP
+// address and user agent of each, so unfamiliar access stands out.
+// GET ?email=...&limit=50
+func LoginHistory(store LoginHistoryStore, cookieSecret string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		email := requestEmail(r, cookieSecret, "")
+		if email == "" {
+			http.Error(w, "email query parameter is required", http.StatusBadRequest)
+			return
+		}
+
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		logins, err := store.ListLoginEvents(r.Context(), email, limit)
+		if err != nil {
+			log.Printf("LoginHistory: failed to list logins for email=%s: %v", email, err)
+			http.Error(w, "failed to load login history", http.StatusBadGateway)
+			return
+		}
+		if logins == nil {
+			logins = []models.LoginEvent{}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(map[string]any{"logins": logins}); err != nil {
+			http.Error(w, "failed to encode response", http.StatusInternalServerError)
+		}
+	}
+}
@@ -15,12 +15,13 @@ type mcpSecretPayload struct {
 
 // MCPSecret creates an HTTP handler that allows a user to fetch or rotate
 // their MCP tenant secret, which is used to identify the tenant when an MCP
-// client connects. It reads the session cookie to identify the user, falling
-// back to the request body/query param for backward compatibility.
+// client connects. It identifies the user by auth token or session cookie,
+// falling back to the request body/query param for backward compatibility
+// unless CredentialIdentity turned that fallback off.
 func MCPSecret(store UserSettingsStore, cookieSecret string) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		sessionEmail := ""
-		if sess, err := session.ReadSession(r, cookieSecret); err == nil && sess.Email != nil {
+		sessionEmail := tokenEmail(r, "")
+		if sess, err := session.ReadSession(r, cookieSecret); sessionEmail == "" && err == nil && sess.Email != nil {
 			sessionEmail = *sess.Email
 		}
 
@@ -34,7 +35,7 @@ func MCPSecret(store UserSettingsStore, cookieSecret string) http.HandlerFunc {
 					http.Error(w, "invalid JSON payload", http.StatusBadRequest)
 					return
 				}
-				email = strings.TrimSpace(payload.UserEmail)
+				email = tokenEmail(r, payload.UserEmail)
 			}
 
 			if email == "" {
@@ -57,7 +58,7 @@ func MCPSecret(store UserSettingsStore, cookieSecret string) http.HandlerFunc {
 		case http.MethodGet:
 			email := sessionEmail
 			if email == "" {
-				email = strings.TrimSpace(r.URL.Query().Get("email"))
+				email = tokenEmail(r, r.URL.Query().Get("email"))
 			}
 			if email == "" {
 				http.Error(w, "not authenticated", http.StatusUnauthorized)
@@ -100,7 +100,8 @@ func UserRequests(store MetricsStore) http.HandlerFunc {
 	}
 }
 
-// AllMetrics returns usage metrics for all users (admin endpoint)
+// AllMetrics returns usage metrics for all users. It must be mounted behind
+// RequirePermission with models.PermissionViewAllMetrics.
 func AllMetrics(store MetricsStore) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodGet {
@@ -109,9 +110,6 @@ func AllMetrics(store MetricsStore) http.HandlerFunc {
 			return
 		}
 
-		// TODO: Add admin authentication check here
-		// For now, this endpoint is open - you may want to restrict it
-
 		metrics, err := store.GetAllMetrics(r.Context())
 		if err != nil {
 			http.Error(w, "failed to get all metrics", http.StatusInternalServerError)
@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/authtoken"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/session"
+)
+
+// NotificationStore defines the behaviour required to read and acknowledge a
+// user's notification feed.
+type NotificationStore interface {
+	ListNotifications(ctx context.Context, email string, unreadOnly bool, limit int) ([]models.Notification, error)
+	MarkNotificationsRead(ctx context.Context, email string, refs []models.NotificationRef) error
+}
+
+// NotificationWriter records per-user events in the notification feed.
+type NotificationWriter interface {
+	CreateUserNotification(ctx context.Context, userID int64, kind, severity, title, body string, metadata models.JSONB) error
+}
+
+type markNotificationsPayload struct {
+	UserEmail     string                   `json:"user_email"`
+	Notifications []models.NotificationRef `json:"notifications"`
+	All           bool                     `json:"all"`
+}
+
+type credentialFailurePayload struct {
+	Provider string `json:"provider"`
+	Message  string `json:"message"`
+}
+
+// Notifications returns the caller's notification feed.
+// GET ?email=...&unread=true&limit=50
+func Notifications(store NotificationStore, cookieSecret string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		email := requestEmail(r, cookieSecret, "")
+		if email == "" {
+			http.Error(w, "email query parameter is required", http.StatusBadRequest)
+			return
+		}
+
+		unreadOnly, _ := strconv.ParseBool(r.URL.Query().Get("unread"))
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+		notifications, err := store.ListNotifications(r.Context(), email, unreadOnly, limit)
+		if err != nil {
+			log.Printf("Notifications: failed to list notifications for email=%s: %v", email, err)
+			http.Error(w, "failed to load notifications", http.StatusBadGateway)
+			return
+		}
+
+		if notifications == nil {
+			notifications = []models.Notification{}
+		}
+
+		unread := 0
+		for _, n := range notifications {
+			if !n.Read {
+				unread++
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(map[string]any{
+			"notifications": notifications,
+			"unread_count":  unread,
+		}); err != nil {
+			http.Error(w, "failed to encode response", http.StatusInternalServerError)
+		}
+	}
+}
+
+// MarkNotificationsRead marks notifications as read for the caller. Either a
+// list of {source, id} references or "all": true must be provided.
+func MarkNotificationsRead(store NotificationStore, cookieSecret string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var payload markNotificationsPayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			log.Printf("MarkNotificationsRead: invalid JSON payload: %v", err)
+			http.Error(w, "invalid JSON payload", http.StatusBadRequest)
+			return
+		}
+
+		email := requestEmail(r, cookieSecret, payload.UserEmail)
+		if email == "" {
+			http.Error(w, "user_email is required", http.StatusBadRequest)
+			return
+		}
+
+		if !payload.All && len(payload.Notifications) == 0 {
+			http.Error(w, "notifications or all is required", http.StatusBadRequest)
+			return
+		}
+
+		for _, ref := range payload.Notifications {
+			if ref.Source != models.NotificationSourceAnnouncement && ref.Source != models.NotificationSourceUser {
+				http.Error(w, "invalid notification source", http.StatusBadRequest)
+				return
+			}
+		}
+
+		refs := payload.Notifications
+		if payload.All {
+			refs = nil
+		}
+
+		if err := store.MarkNotificationsRead(r.Context(), email, refs); err != nil {
+			log.Printf("MarkNotificationsRead: failed for email=%s: %v", email, err)
+			http.Error(w, "failed to update notifications", http.StatusBadGateway)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(map[string]any{"ok": true}); err != nil {
+			http.Error(w, "failed to encode response", http.StatusInternalServerError)
+		}
+	}
+}
+
+// TenantCredentialFailure lets the MCP worker report that a tenant's Jira or
+// integration credentials were rejected upstream. The tenant is identified by
+// the mcp_secret query parameter.
+func TenantCredentialFailure(store NotificationWriter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value("user_id").(int64)
+		if !ok || userID <= 0 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var payload credentialFailurePayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			log.Printf("TenantCredentialFailure: invalid JSON payload: %v", err)
+			http.Error(w, "invalid JSON payload", http.StatusBadRequest)
+			return
+		}
+
+		provider := strings.TrimSpace(payload.Provider)
+		if provider == "" {
+			provider = "jira"
+		}
+
+		body := strings.TrimSpace(payload.Message)
+		if body == "" {
+			body = "The stored credentials were rejected. Update them in Settings to restore access."
+		}
+
+		if err := store.CreateUserNotification(
+			r.Context(),
+			userID,
+			models.NotificationKindCredentialFailure,
+			"warning",
+			"Credentials for "+provider+" need attention",
+			body,
+			models.JSONB{"provider": provider},
+		); err != nil {
+			log.Printf("TenantCredentialFailure: failed to record notification for user_id=%d: %v", userID, err)
+			http.Error(w, "failed to record notification", http.StatusBadGateway)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(map[string]any{"ok": true}); err != nil {
+			http.Error(w, "failed to encode response", http.StatusInternalServerError)
+		}
+	}
+}
+
+// requestEmail prefers the auth token or session identity and falls back to
+// the explicit email from the request body or query string, unless
+// CredentialIdentity turned that fallback off.
+func requestEmail(r *http.Request, cookieSecret, fallback string) string {
+	if claims, ok := authtoken.FromContext(r.Context()); ok {
+		return claims.Email
+	}
+	if sess, err := session.ReadSession(r, cookieSecret); err == nil && sess.Email != nil && *sess.Email != "" {
+		return *sess.Email
+	}
+	if _, ok := credentialEmail(r.Context()); ok {
+		return ""
+	}
+	if email := strings.TrimSpace(fallback); email != "" {
+		return email
+	}
+	return strings.TrimSpace(r.URL.Query().Get("email"))
+}
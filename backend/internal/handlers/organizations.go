@@ -0,0 +1,759 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/session"
+	storepkg "github.com/PortNumber53/mcp-jira-thing/backend/internal/store"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/worker"
+)
+
+var (
+	orgSlugPattern   = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{1,38}[a-z0-9]$`)
+	orgDomainPattern = regexp.MustCompile(`^([a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?\.)+[a-z]{2,}$`)
+)
+
+// OrganizationStore defines the behaviour required to manage organizations,
+// their claimed domains and their identity provider.
+type OrganizationStore interface {
+	CreateOrganization(ctx context.Context, ownerEmail, slug, name string) (*models.Organization, error)
+	ListUserOrganizations(ctx context.Context, email string) ([]models.Organization, error)
+	GetOrganizationMembership(ctx context.Context, email, slug string) (*models.Organization, error)
+	ListOrganizationDomains(ctx context.Context, orgID int64) ([]models.OrganizationDomain, error)
+	AddOrganizationDomain(ctx context.Context, orgID int64, domain string) error
+	RemoveOrganizationDomain(ctx context.Context, orgID int64, domain string) error
+	GetSSOConfig(ctx context.Context, orgID int64) (*models.SSOConfig, error)
+	UpsertSSOConfig(ctx context.Context, cfg models.SSOConfig) error
+	SetOrganizationSSOEnforced(ctx context.Context, orgID int64, enforced bool) error
+	CreateSCIMToken(ctx context.Context, orgID int64, name, tokenHash string) (*models.SCIMToken, error)
+	ListSCIMTokens(ctx context.Context, orgID int64) ([]models.SCIMToken, error)
+	RevokeSCIMToken(ctx context.Context, orgID, tokenID int64) error
+	GetOrganizationDomain(ctx context.Context, orgID int64, domain string) (*models.OrganizationDomain, error)
+	ListUserJoinRequests(ctx context.Context, email string) ([]models.Organization, error)
+	ListOrganizationJoinRequests(ctx context.Context, orgID int64) ([]models.OrganizationJoinRequest, error)
+	ApproveOrganizationJoinRequest(ctx context.Context, orgID, userID int64) error
+	RejectOrganizationJoinRequest(ctx context.Context, orgID, userID int64) error
+	GetOrganizationJiraAccount(ctx context.Context, orgID int64) (*models.OrganizationJiraAccount, error)
+	UpsertOrganizationJiraAccount(ctx context.Context, adminEmail string, account models.OrganizationJiraAccount) error
+	DeleteOrganizationJiraAccount(ctx context.Context, orgID int64) error
+	ListOrganizationJiraUsage(ctx context.Context, orgID int64, from, to time.Time) ([]models.OrganizationMemberUsage, error)
+}
+
+// JobEnqueuer queues background jobs.
+type JobEnqueuer interface {
+	Enqueue(ctx context.Context, job *models.Job) error
+}
+
+type createOrganizationPayload struct {
+	UserEmail string `json:"user_email"`
+	Slug      string `json:"slug"`
+	Name      string `json:"name"`
+}
+
+type organizationDomainPayload struct {
+	UserEmail string `json:"user_email"`
+	Domain    string `json:"domain"`
+}
+
+type joinRequestDecisionPayload struct {
+	UserEmail string `json:"user_email"`
+	UserID    int64  `json:"user_id"`
+	Approve   bool   `json:"approve"`
+}
+
+// organizationDomainResponse adds the DNS TXT record that verifies the
+// domain.
+type organizationDomainResponse struct {
+	models.OrganizationDomain
+	TXTRecordName  string `json:"txt_record_name"`
+	TXTRecordValue string `json:"txt_record_value"`
+}
+
+func newOrganizationDomainResponse(d models.OrganizationDomain) organizationDomainResponse {
+	name, value := d.VerificationRecord()
+	return organizationDomainResponse{OrganizationDomain: d, TXTRecordName: name, TXTRecordValue: value}
+}
+
+type organizationJiraAccountPayload struct {
+	UserEmail string `json:"user_email"`
+	models.OrganizationJiraAccount
+}
+
+type scimTokenPayload struct {
+	UserEmail string `json:"user_email"`
+	Name      string `json:"name"`
+}
+
+type ssoConfigPayload struct {
+	UserEmail string `json:"user_email"`
+	models.SSOConfig
+	Enforced bool `json:"enforced"`
+}
+
+// Organizations lists the caller's organizations, and those they are waiting
+// to be approved into, or creates a new one owned by the caller.
+// GET
+// POST {"slug": "acme", "name": "Acme Corp"}
+func Organizations(store OrganizationStore, cookieSecret string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			email := requestEmail(r, cookieSecret, "")
+			if email == "" {
+				http.Error(w, "email query parameter is required", http.StatusBadRequest)
+				return
+			}
+
+			orgs, err := store.ListUserOrganizations(r.Context(), email)
+			if err != nil {
+				log.Printf("Organizations: failed to list organizations for email=%s: %v", email, err)
+				http.Error(w, "failed to load organizations", http.StatusBadGateway)
+				return
+			}
+			if orgs == nil {
+				orgs = []models.Organization{}
+			}
+
+			pending, err := store.ListUserJoinRequests(r.Context(), email)
+			if err != nil {
+				log.Printf("Organizations: failed to list join requests for email=%s: %v", email, err)
+				http.Error(w, "failed to load organizations", http.StatusBadGateway)
+				return
+			}
+			if pending == nil {
+				pending = []models.Organization{}
+			}
+
+			writeJSON(w, http.StatusOK, map[string]any{"organizations": orgs, "pending": pending})
+
+		case http.MethodPost:
+			var payload createOrganizationPayload
+			if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+				log.Printf("Organizations: invalid JSON payload: %v", err)
+				http.Error(w, "invalid JSON payload", http.StatusBadRequest)
+				return
+			}
+
+			email := requestEmail(r, cookieSecret, payload.UserEmail)
+			if email == "" {
+				http.Error(w, "user_email is required", http.StatusBadRequest)
+				return
+			}
+
+			slug := strings.ToLower(strings.TrimSpace(payload.Slug))
+			name := strings.TrimSpace(payload.Name)
+			if !orgSlugPattern.MatchString(slug) {
+				http.Error(w, "slug must be 3-40 lowercase letters, digits or dashes", http.StatusBadRequest)
+				return
+			}
+			if name == "" {
+				http.Error(w, "name is required", http.StatusBadRequest)
+				return
+			}
+
+			org, err := store.CreateOrganization(r.Context(), email, slug, name)
+			if errors.Is(err, storepkg.ErrOrganizationSlugTaken) {
+				http.Error(w, "slug is already taken", http.StatusConflict)
+				return
+			}
+			if err != nil {
+				log.Printf("Organizations: failed to create organization slug=%s for email=%s: %v", slug, email, err)
+				http.Error(w, "failed to create organization", http.StatusBadGateway)
+				return
+			}
+
+			writeJSON(w, http.StatusCreated, map[string]any{"organization": org})
+
+		default:
+			w.Header().Set("Allow", "GET, POST")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// OrganizationDomains lists or changes the email domains claimed by the
+// organization in the {slug} URL parameter, with the DNS TXT record that
+// verifies each. Changes require an owner or admin.
+// GET
+// POST   {"domain": "acme.com"}
+// DELETE ?domain=acme.com
+func OrganizationDomains(store OrganizationStore, cookieSecret string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var payload organizationDomainPayload
+		if r.Method == http.MethodPost {
+			if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+				log.Printf("OrganizationDomains: invalid JSON payload: %v", err)
+				http.Error(w, "invalid JSON payload", http.StatusBadRequest)
+				return
+			}
+		}
+
+		org, ok := organizationForRequest(w, r, store, cookieSecret, payload.UserEmail, r.Method != http.MethodGet)
+		if !ok {
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+
+		case http.MethodPost:
+			domain := strings.ToLower(strings.TrimSpace(payload.Domain))
+			if !orgDomainPattern.MatchString(domain) {
+				http.Error(w, "domain is invalid", http.StatusBadRequest)
+				return
+			}
+
+			err := store.AddOrganizationDomain(r.Context(), org.ID, domain)
+			if errors.Is(err, storepkg.ErrDomainClaimed) {
+				http.Error(w, "domain is already claimed", http.StatusConflict)
+				return
+			}
+			if err != nil {
+				log.Printf("OrganizationDomains: failed to add domain=%s to org=%s: %v", domain, org.Slug, err)
+				http.Error(w, "failed to add domain", http.StatusBadGateway)
+				return
+			}
+
+		case http.MethodDelete:
+			domain := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("domain")))
+			if domain == "" {
+				http.Error(w, "domain query parameter is required", http.StatusBadRequest)
+				return
+			}
+
+			if err := store.RemoveOrganizationDomain(r.Context(), org.ID, domain); err != nil {
+				log.Printf("OrganizationDomains: failed to remove domain=%s from org=%s: %v", domain, org.Slug, err)
+				http.Error(w, "failed to remove domain", http.StatusBadGateway)
+				return
+			}
+
+		default:
+			w.Header().Set("Allow", "GET, POST, DELETE")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		domains, err := store.ListOrganizationDomains(r.Context(), org.ID)
+		if err != nil {
+			log.Printf("OrganizationDomains: failed to list domains for org=%s: %v", org.Slug, err)
+			http.Error(w, "failed to load domains", http.StatusBadGateway)
+			return
+		}
+
+		resp := make([]organizationDomainResponse, 0, len(domains))
+		for _, d := range domains {
+			resp = append(resp, newOrganizationDomainResponse(d))
+		}
+
+		writeJSON(w, http.StatusOK, map[string]any{"domains": resp})
+	}
+}
+
+// OrganizationDomainVerification queues a DNS check of the TXT record that
+// proves the organization owns a claimed domain. Requires an owner or admin.
+// POST {"domain": "acme.com"}
+func OrganizationDomainVerification(store OrganizationStore, jobs JobEnqueuer, cookieSecret string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var payload organizationDomainPayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			log.Printf("OrganizationDomainVerification: invalid JSON payload: %v", err)
+			http.Error(w, "invalid JSON payload", http.StatusBadRequest)
+			return
+		}
+
+		org, ok := organizationForRequest(w, r, store, cookieSecret, payload.UserEmail, true)
+		if !ok {
+			return
+		}
+
+		domain, err := store.GetOrganizationDomain(r.Context(), org.ID, strings.ToLower(strings.TrimSpace(payload.Domain)))
+		if errors.Is(err, storepkg.ErrDomainNotFound) {
+			http.Error(w, "domain not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Printf("OrganizationDomainVerification: failed to load domain=%s for org=%s: %v", payload.Domain, org.Slug, err)
+			http.Error(w, "failed to load domain", http.StatusBadGateway)
+			return
+		}
+		if domain.VerifiedAt != nil {
+			writeJSON(w, http.StatusOK, map[string]any{"domain": newOrganizationDomainResponse(*domain)})
+			return
+		}
+
+		// Repeated clicks while a check is queued reuse the pending job.
+		job := worker.DomainVerificationJob(org.ID, domain.Domain)
+		job.DedupWindow = 10 * time.Minute
+		if err := jobs.Enqueue(r.Context(), job); err != nil {
+			log.Printf("OrganizationDomainVerification: failed to enqueue verification of domain=%s for org=%s: %v", domain.Domain, org.Slug, err)
+			http.Error(w, "failed to start domain verification", http.StatusBadGateway)
+			return
+		}
+
+		writeJSON(w, http.StatusAccepted, map[string]any{
+			"domain": newOrganizationDomainResponse(*domain),
+			"job_id": job.ID,
+		})
+	}
+}
+
+// OrganizationJoinRequests lists the users waiting to join the organization
+// in the {slug} URL parameter after signing up on a verified domain, or
+// approves or rejects one. Both require an owner or admin.
+// GET
+// POST {"user_id": 42, "approve": true}
+func OrganizationJoinRequests(store OrganizationStore, cookieSecret string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var payload joinRequestDecisionPayload
+		if r.Method == http.MethodPost {
+			if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+				log.Printf("OrganizationJoinRequests: invalid JSON payload: %v", err)
+				http.Error(w, "invalid JSON payload", http.StatusBadRequest)
+				return
+			}
+		}
+
+		org, ok := organizationForRequest(w, r, store, cookieSecret, payload.UserEmail, true)
+		if !ok {
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+
+		case http.MethodPost:
+			if payload.UserID <= 0 {
+				http.Error(w, "user_id is required", http.StatusBadRequest)
+				return
+			}
+
+			var err error
+			if payload.Approve {
+				err = store.ApproveOrganizationJoinRequest(r.Context(), org.ID, payload.UserID)
+			} else {
+				err = store.RejectOrganizationJoinRequest(r.Context(), org.ID, payload.UserID)
+			}
+			if errors.Is(err, storepkg.ErrJoinRequestNotFound) {
+				http.Error(w, "join request not found", http.StatusNotFound)
+				return
+			}
+			if err != nil {
+				log.Printf("OrganizationJoinRequests: failed to decide request of user=%d for org=%s: %v", payload.UserID, org.Slug, err)
+				http.Error(w, "failed to update join request", http.StatusBadGateway)
+				return
+			}
+
+		default:
+			w.Header().Set("Allow", "GET, POST")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		requests, err := store.ListOrganizationJoinRequests(r.Context(), org.ID)
+		if err != nil {
+			log.Printf("OrganizationJoinRequests: failed to list join requests for org=%s: %v", org.Slug, err)
+			http.Error(w, "failed to load join requests", http.StatusBadGateway)
+			return
+		}
+		if requests == nil {
+			requests = []models.OrganizationJoinRequest{}
+		}
+
+		writeJSON(w, http.StatusOK, map[string]any{"requests": requests})
+	}
+}
+
+// OrganizationSSO returns or replaces the identity provider configuration of
+// the organization in the {slug} URL parameter. Both require an owner or
+// admin. The OIDC client secret is never returned; omitting it on PUT keeps
+// the stored one.
+// GET
+// PUT {"protocol": "oidc", "enabled": true, "oidc_issuer": "...", "oidc_client_id": "...", "enforced": true}
+func OrganizationSSO(store OrganizationStore, cookieSecret, backendURL string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var payload ssoConfigPayload
+		if r.Method == http.MethodPut {
+			if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+				log.Printf("OrganizationSSO: invalid JSON payload: %v", err)
+				http.Error(w, "invalid JSON payload", http.StatusBadRequest)
+				return
+			}
+		}
+
+		org, ok := organizationForRequest(w, r, store, cookieSecret, payload.UserEmail, true)
+		if !ok {
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+
+		case http.MethodPut:
+			cfg := payload.SSOConfig
+			cfg.OrgID = org.ID
+			if msg := validateSSOConfig(&cfg); msg != "" {
+				http.Error(w, msg, http.StatusBadRequest)
+				return
+			}
+
+			if err := store.UpsertSSOConfig(r.Context(), cfg); err != nil {
+				log.Printf("OrganizationSSO: failed to save sso config for org=%s: %v", org.Slug, err)
+				http.Error(w, "failed to save sso config", http.StatusBadGateway)
+				return
+			}
+			if payload.Enforced != org.SSOEnforced {
+				if err := store.SetOrganizationSSOEnforced(r.Context(), org.ID, payload.Enforced); err != nil {
+					log.Printf("OrganizationSSO: failed to set enforcement for org=%s: %v", org.Slug, err)
+					http.Error(w, "failed to save sso enforcement", http.StatusBadGateway)
+					return
+				}
+				org.SSOEnforced = payload.Enforced
+			}
+
+		default:
+			w.Header().Set("Allow", "GET, PUT")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		cfg, err := store.GetSSOConfig(r.Context(), org.ID)
+		if errors.Is(err, storepkg.ErrSSOConfigNotFound) {
+			cfg = nil
+		} else if err != nil {
+			log.Printf("OrganizationSSO: failed to load sso config for org=%s: %v", org.Slug, err)
+			http.Error(w, "failed to load sso config", http.StatusBadGateway)
+			return
+		}
+
+		resp := map[string]any{
+			"enforced":          org.SSOEnforced,
+			"config":            nil,
+			"login_url":         backendURL + "/api/auth/sso/login?org=" + url.QueryEscape(org.Slug),
+			"redirect_uri":      backendURL + "/callback/sso",
+			"saml_acs_url":      backendURL + "/api/auth/sso/saml/acs",
+			"saml_entity_id":    samlEntityID(backendURL, org.Slug),
+			"saml_metadata_url": backendURL + "/api/auth/sso/saml/metadata?org=" + url.QueryEscape(org.Slug),
+		}
+		if cfg != nil {
+			hasSecret := cfg.OIDCClientSecret != ""
+			cfg.OIDCClientSecret = ""
+			resp["config"] = cfg
+			resp["oidc_client_secret_set"] = hasSecret
+		}
+
+		writeJSON(w, http.StatusOK, resp)
+	}
+}
+
+// OrganizationSCIMTokens lists, creates or revokes the SCIM bearer tokens
+// of the organization in the {slug} URL parameter. All require an owner or
+// admin. A new token is only returned in the POST response.
+// GET
+// POST   {"name": "Okta"}
+// DELETE ?id=3
+func OrganizationSCIMTokens(store OrganizationStore, cookieSecret, backendURL string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var payload scimTokenPayload
+		if r.Method == http.MethodPost {
+			if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+				log.Printf("OrganizationSCIMTokens: invalid JSON payload: %v", err)
+				http.Error(w, "invalid JSON payload", http.StatusBadRequest)
+				return
+			}
+		}
+
+		org, ok := organizationForRequest(w, r, store, cookieSecret, payload.UserEmail, true)
+		if !ok {
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			tokens, err := store.ListSCIMTokens(r.Context(), org.ID)
+			if err != nil {
+				log.Printf("OrganizationSCIMTokens: failed to list tokens for org=%s: %v", org.Slug, err)
+				http.Error(w, "failed to load scim tokens", http.StatusBadGateway)
+				return
+			}
+			if tokens == nil {
+				tokens = []models.SCIMToken{}
+			}
+
+			writeJSON(w, http.StatusOK, map[string]any{
+				"tokens":        tokens,
+				"scim_base_url": backendURL + "/scim/v2",
+			})
+
+		case http.MethodPost:
+			secret, err := session.RandomHex(32)
+			if err != nil {
+				log.Printf("OrganizationSCIMTokens: failed to generate token: %v", err)
+				http.Error(w, "failed to create scim token", http.StatusInternalServerError)
+				return
+			}
+			plaintext := scimTokenPrefix + secret
+
+			token, err := store.CreateSCIMToken(r.Context(), org.ID, strings.TrimSpace(payload.Name), hashSCIMToken(plaintext))
+			if err != nil {
+				log.Printf("OrganizationSCIMTokens: failed to create token for org=%s: %v", org.Slug, err)
+				http.Error(w, "failed to create scim token", http.StatusBadGateway)
+				return
+			}
+			token.Token = plaintext
+
+			writeJSON(w, http.StatusCreated, map[string]any{
+				"token":         token,
+				"scim_base_url": backendURL + "/scim/v2",
+			})
+
+		case http.MethodDelete:
+			id, err := strconv.ParseInt(r.URL.Query().Get("id"), 10, 64)
+			if err != nil {
+				http.Error(w, "id query parameter is required", http.StatusBadRequest)
+				return
+			}
+
+			err = store.RevokeSCIMToken(r.Context(), org.ID, id)
+			if errors.Is(err, storepkg.ErrSCIMTokenNotFound) {
+				http.Error(w, "scim token not found", http.StatusNotFound)
+				return
+			}
+			if err != nil {
+				log.Printf("OrganizationSCIMTokens: failed to revoke token=%d for org=%s: %v", id, org.Slug, err)
+				http.Error(w, "failed to revoke scim token", http.StatusBadGateway)
+				return
+			}
+
+			w.WriteHeader(http.StatusNoContent)
+
+		default:
+			w.Header().Set("Allow", "GET, POST, DELETE")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// OrganizationJiraAccount returns, replaces or removes the shared Jira
+// service account members of the organization in the {slug} URL parameter
+// use when they have no Jira settings of their own. All require an owner or
+// admin. The API token is never returned; omitting it on PUT keeps the
+// stored one.
+// GET
+// PUT    {"jira_base_url": "https://acme.atlassian.net", "jira_email": "bot@acme.com", "atlassian_api_key": "...", "enabled": true}
+// DELETE
+func OrganizationJiraAccount(store OrganizationStore, cookieSecret string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var payload organizationJiraAccountPayload
+		if r.Method == http.MethodPut {
+			if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+				log.Printf("OrganizationJiraAccount: invalid JSON payload: %v", err)
+				http.Error(w, "invalid JSON payload", http.StatusBadRequest)
+				return
+			}
+		}
+
+		org, ok := organizationForRequest(w, r, store, cookieSecret, payload.UserEmail, true)
+		if !ok {
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+
+		case http.MethodPut:
+			account := payload.OrganizationJiraAccount
+			account.OrgID = org.ID
+			account.JiraBaseURL = strings.TrimRight(strings.TrimSpace(account.JiraBaseURL), "/")
+			account.JiraEmail = strings.TrimSpace(account.JiraEmail)
+			account.APIToken = strings.TrimSpace(account.APIToken)
+			if !strings.HasPrefix(account.JiraBaseURL, "https://") {
+				http.Error(w, "jira_base_url must be an https URL", http.StatusBadRequest)
+				return
+			}
+			if account.JiraEmail == "" {
+				http.Error(w, "jira_email is required", http.StatusBadRequest)
+				return
+			}
+			if account.APIToken == "" {
+				_, err := store.GetOrganizationJiraAccount(r.Context(), org.ID)
+				if errors.Is(err, storepkg.ErrOrganizationJiraAccountNotFound) {
+					http.Error(w, "atlassian_api_key is required", http.StatusBadRequest)
+					return
+				}
+				if err != nil {
+					log.Printf("OrganizationJiraAccount: failed to load account for org=%s: %v", org.Slug, err)
+					http.Error(w, "failed to load jira account", http.StatusBadGateway)
+					return
+				}
+			}
+
+			email := requestEmail(r, cookieSecret, payload.UserEmail)
+			if err := store.UpsertOrganizationJiraAccount(r.Context(), email, account); err != nil {
+				log.Printf("OrganizationJiraAccount: failed to save account for org=%s: %v", org.Slug, err)
+				http.Error(w, "failed to save jira account", http.StatusBadGateway)
+				return
+			}
+
+		case http.MethodDelete:
+			if err := store.DeleteOrganizationJiraAccount(r.Context(), org.ID); err != nil {
+				log.Printf("OrganizationJiraAccount: failed to delete account for org=%s: %v", org.Slug, err)
+				http.Error(w, "failed to delete jira account", http.StatusBadGateway)
+				return
+			}
+
+		default:
+			w.Header().Set("Allow", "GET, PUT, DELETE")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		account, err := store.GetOrganizationJiraAccount(r.Context(), org.ID)
+		if errors.Is(err, storepkg.ErrOrganizationJiraAccountNotFound) {
+			writeJSON(w, http.StatusOK, map[string]any{"account": nil})
+			return
+		}
+		if err != nil {
+			log.Printf("OrganizationJiraAccount: failed to load account for org=%s: %v", org.Slug, err)
+			http.Error(w, "failed to load jira account", http.StatusBadGateway)
+			return
+		}
+
+		hasToken := account.APIToken != ""
+		account.APIToken = ""
+		writeJSON(w, http.StatusOK, map[string]any{"account": account, "atlassian_api_key_set": hasToken})
+	}
+}
+
+// OrganizationJiraUsage reports, per member, the tool invocations made with
+// the organization's shared Jira account in a date range, defaulting to the
+// current UTC calendar month. Requires an owner or admin.
+// GET ?from=2026-04-01&to=2026-04-30 (both inclusive)
+func OrganizationJiraUsage(store OrganizationStore, cookieSecret string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		org, ok := organizationForRequest(w, r, store, cookieSecret, "", true)
+		if !ok {
+			return
+		}
+
+		now := time.Now().UTC()
+		from := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+		to := from.AddDate(0, 1, -1)
+		for param, dst := range map[string]*time.Time{"from": &from, "to": &to} {
+			v := r.URL.Query().Get(param)
+			if v == "" {
+				continue
+			}
+			t, err := time.Parse("2006-01-02", v)
+			if err != nil {
+				http.Error(w, param+" must be a YYYY-MM-DD date", http.StatusBadRequest)
+				return
+			}
+			*dst = t
+		}
+		if to.Before(from) {
+			http.Error(w, "to must not be before from", http.StatusBadRequest)
+			return
+		}
+
+		usage, err := store.ListOrganizationJiraUsage(r.Context(), org.ID, from, to.AddDate(0, 0, 1))
+		if err != nil {
+			log.Printf("OrganizationJiraUsage: failed to list usage for org=%s: %v", org.Slug, err)
+			http.Error(w, "failed to load usage", http.StatusBadGateway)
+			return
+		}
+		if usage == nil {
+			usage = []models.OrganizationMemberUsage{}
+		}
+
+		writeJSON(w, http.StatusOK, map[string]any{
+			"from":    from.Format("2006-01-02"),
+			"to":      to.Format("2006-01-02"),
+			"members": usage,
+		})
+	}
+}
+
+// organizationForRequest resolves the {slug} organization for the caller,
+// writing an error response and returning false when the caller is not a
+// member or, with requireAdmin, not an owner or admin.
+func organizationForRequest(w http.ResponseWriter, r *http.Request, store OrganizationStore, cookieSecret, fallbackEmail string, requireAdmin bool) (*models.Organization, bool) {
+	email := requestEmail(r, cookieSecret, fallbackEmail)
+	if email == "" {
+		http.Error(w, "user_email is required", http.StatusBadRequest)
+		return nil, false
+	}
+
+	slug := chi.URLParam(r, "slug")
+	org, err := store.GetOrganizationMembership(r.Context(), email, slug)
+	if errors.Is(err, storepkg.ErrOrganizationNotFound) {
+		http.Error(w, "organization not found", http.StatusNotFound)
+		return nil, false
+	}
+	if err != nil {
+		log.Printf("organizationForRequest: failed to load org=%s for email=%s: %v", slug, email, err)
+		http.Error(w, "failed to load organization", http.StatusBadGateway)
+		return nil, false
+	}
+
+	if requireAdmin && org.Role != models.OrgRoleOwner && org.Role != models.OrgRoleAdmin {
+		http.Error(w, "organization admin role required", http.StatusForbidden)
+		return nil, false
+	}
+
+	return org, true
+}
+
+// validateSSOConfig normalises cfg and returns a client-facing message when
+// it is incomplete for its protocol.
+func validateSSOConfig(cfg *models.SSOConfig) string {
+	cfg.Protocol = strings.ToLower(strings.TrimSpace(cfg.Protocol))
+	cfg.OIDCIssuer = strings.TrimRight(strings.TrimSpace(cfg.OIDCIssuer), "/")
+	cfg.OIDCClientID = strings.TrimSpace(cfg.OIDCClientID)
+	cfg.SAMLIdPEntityID = strings.TrimSpace(cfg.SAMLIdPEntityID)
+	cfg.SAMLIdPSSOURL = strings.TrimSpace(cfg.SAMLIdPSSOURL)
+	cfg.SAMLIdPCertificate = strings.TrimSpace(cfg.SAMLIdPCertificate)
+
+	switch cfg.Protocol {
+	case models.SSOProtocolOIDC:
+		if !strings.HasPrefix(cfg.OIDCIssuer, "https://") {
+			return "oidc_issuer must be an https URL"
+		}
+		if cfg.OIDCClientID == "" {
+			return "oidc_client_id is required"
+		}
+		cfg.SAMLIdPEntityID, cfg.SAMLIdPSSOURL, cfg.SAMLIdPCertificate = "", "", ""
+	case models.SSOProtocolSAML:
+		if cfg.SAMLIdPEntityID == "" {
+			return "saml_idp_entity_id is required"
+		}
+		if !strings.HasPrefix(cfg.SAMLIdPSSOURL, "https://") {
+			return "saml_idp_sso_url must be an https URL"
+		}
+		if _, err := parseSAMLCertificate(cfg.SAMLIdPCertificate); err != nil {
+			return "saml_idp_certificate must be a PEM or base64 X.509 certificate"
+		}
+		cfg.OIDCIssuer, cfg.OIDCClientID, cfg.OIDCClientSecret = "", "", ""
+	default:
+		return "protocol must be oidc or saml"
+	}
+
+	return ""
+}
@@ -0,0 +1,234 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/store"
+)
+
+// defaultRolloutGracePeriodDays is how long subscribers on the previous
+// version keep their price after a rollout is promoted, unless the request
+// says otherwise.
+const defaultRolloutGracePeriodDays = 30
+
+// PlanRolloutStore manages gradual rollouts of new plan versions.
+type PlanRolloutStore interface {
+	GetPlanBySlug(ctx context.Context, slug string) (*models.MembershipPlan, error)
+	GetActivePlanVersion(ctx context.Context, planID int64) (*models.PlanVersion, error)
+	GetPlanVersionRollout(ctx context.Context, planID int64) (*models.PlanVersion, *models.PlanVersionRollout, error)
+	SetPlanVersionRollout(ctx context.Context, versionID int64, percent int, emailDomains []string) error
+	PromotePlanVersion(ctx context.Context, versionID int64, gracePeriodDays int, policy models.PlanMigrationPolicy) error
+	CountSubscriptionsByPlanVersion(ctx context.Context, versionID int64) (int, error)
+}
+
+// AdminPlanRollout reports (GET) or configures (PUT) the rollout of a plan's
+// new version. The version must already exist in rollout status; PUT takes
+// {"percent": 0-100, "email_domains": [...]} and only affects who is offered
+// the version from now on.
+func AdminPlanRollout(plans PlanRolloutStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodPut {
+			w.Header().Set("Allow", "GET, PUT")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		plan, version, ok := loadPlanRollout(w, r, plans)
+		if !ok {
+			return
+		}
+
+		if r.Method == http.MethodPut {
+			var payload struct {
+				Percent      *int     `json:"percent"`
+				EmailDomains []string `json:"email_domains"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+				http.Error(w, "invalid JSON payload", http.StatusBadRequest)
+				return
+			}
+			if payload.Percent == nil || *payload.Percent < 0 || *payload.Percent > 100 {
+				http.Error(w, "percent must be between 0 and 100", http.StatusBadRequest)
+				return
+			}
+			domains := make([]string, 0, len(payload.EmailDomains))
+			for _, d := range payload.EmailDomains {
+				if d = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(d), "@")); d != "" {
+					domains = append(domains, d)
+				}
+			}
+			if err := plans.SetPlanVersionRollout(r.Context(), version.ID, *payload.Percent, domains); err != nil {
+				log.Printf("AdminPlanRollout: set rollout for %s: %v", plan.Slug, err)
+				http.Error(w, "failed to update rollout", http.StatusInternalServerError)
+				return
+			}
+		}
+
+		// Re-read so the response reflects what was stored.
+		version, rollout, err := plans.GetPlanVersionRollout(r.Context(), plan.ID)
+		if err != nil {
+			log.Printf("AdminPlanRollout: load rollout for %s: %v", plan.Slug, err)
+			http.Error(w, "failed to load rollout", http.StatusInternalServerError)
+			return
+		}
+
+		body := map[string]any{
+			"plan_slug":             plan.Slug,
+			"rollout_version":       version,
+			"rollout":               rollout,
+			"rollout_subscriptions": countPlanSubscriptions(r.Context(), plans, version.ID),
+		}
+		if active, err := plans.GetActivePlanVersion(r.Context(), plan.ID); err == nil {
+			body["active_version"] = active
+			body["active_subscriptions"] = countPlanSubscriptions(r.Context(), plans, active.ID)
+		}
+		writeJSON(w, http.StatusOK, body)
+	}
+}
+
+// AdminPromotePlanRollout makes a plan's rollout version the active version
+// for everyone. The previous active version is deprecated with
+// grace_period_days (default 30) and migration_policy (default
+// migrate_at_deadline), which decides what happens to its subscribers when
+// the grace period ends.
+func AdminPromotePlanRollout(plans PlanRolloutStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		plan, version, ok := loadPlanRollout(w, r, plans)
+		if !ok {
+			return
+		}
+
+		payload := struct {
+			GracePeriodDays *int                       `json:"grace_period_days"`
+			MigrationPolicy models.PlanMigrationPolicy `json:"migration_policy"`
+		}{MigrationPolicy: models.PlanMigrateAtDeadline}
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+				http.Error(w, "invalid JSON payload", http.StatusBadRequest)
+				return
+			}
+		}
+		graceDays := defaultRolloutGracePeriodDays
+		if payload.GracePeriodDays != nil {
+			if *payload.GracePeriodDays < 0 {
+				http.Error(w, "grace_period_days must not be negative", http.StatusBadRequest)
+				return
+			}
+			graceDays = *payload.GracePeriodDays
+		}
+		if !payload.MigrationPolicy.Valid() {
+			http.Error(w, "migration_policy must be migrate_at_deadline, migrate_at_renewal or grandfather", http.StatusBadRequest)
+			return
+		}
+
+		if err := plans.PromotePlanVersion(r.Context(), version.ID, graceDays, payload.MigrationPolicy); err != nil {
+			log.Printf("AdminPromotePlanRollout: promote %s v%d: %v", plan.Slug, version.Version, err)
+			http.Error(w, "failed to promote rollout", http.StatusInternalServerError)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, map[string]any{
+			"plan_slug":         plan.Slug,
+			"active_version_id": version.ID,
+			"version":           version.Version,
+			"grace_period_days": graceDays,
+			"migration_policy":  payload.MigrationPolicy,
+		})
+	}
+}
+
+// PlanMigrationPolicyStore updates the grandfathering rule of plan versions.
+type PlanMigrationPolicyStore interface {
+	SetPlanVersionMigrationPolicy(ctx context.Context, versionID int64, policy models.PlanMigrationPolicy) error
+}
+
+// AdminPlanVersionMigrationPolicy changes what happens to a plan version's
+// subscribers once it is deprecated and its grace period ends. PUT takes
+// {"migration_policy": "migrate_at_deadline" | "migrate_at_renewal" |
+// "grandfather"}; versions that were already migrated and archived cannot
+// be changed.
+func AdminPlanVersionMigrationPolicy(plans PlanMigrationPolicyStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			w.Header().Set("Allow", http.MethodPut)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		versionID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil || versionID <= 0 {
+			http.Error(w, "invalid plan version id", http.StatusBadRequest)
+			return
+		}
+
+		var payload struct {
+			MigrationPolicy models.PlanMigrationPolicy `json:"migration_policy"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			http.Error(w, "invalid JSON payload", http.StatusBadRequest)
+			return
+		}
+		if !payload.MigrationPolicy.Valid() {
+			http.Error(w, "migration_policy must be migrate_at_deadline, migrate_at_renewal or grandfather", http.StatusBadRequest)
+			return
+		}
+
+		err = plans.SetPlanVersionMigrationPolicy(r.Context(), versionID, payload.MigrationPolicy)
+		if errors.Is(err, store.ErrPlanVersionNotFound) {
+			http.Error(w, "plan version not found or archived", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Printf("AdminPlanVersionMigrationPolicy: version %d: %v", versionID, err)
+			http.Error(w, "failed to update migration policy", http.StatusInternalServerError)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, map[string]any{
+			"plan_version_id":  versionID,
+			"migration_policy": payload.MigrationPolicy,
+		})
+	}
+}
+
+func loadPlanRollout(w http.ResponseWriter, r *http.Request, plans PlanRolloutStore) (*models.MembershipPlan, *models.PlanVersion, bool) {
+	plan, err := plans.GetPlanBySlug(r.Context(), chi.URLParam(r, "slug"))
+	if err != nil {
+		http.Error(w, "plan not found", http.StatusNotFound)
+		return nil, nil, false
+	}
+	version, _, err := plans.GetPlanVersionRollout(r.Context(), plan.ID)
+	if errors.Is(err, store.ErrNoPlanRollout) {
+		http.Error(w, "plan has no version in rollout", http.StatusNotFound)
+		return nil, nil, false
+	}
+	if err != nil {
+		log.Printf("PlanRollout: load rollout for %s: %v", plan.Slug, err)
+		http.Error(w, "failed to load rollout", http.StatusInternalServerError)
+		return nil, nil, false
+	}
+	return plan, version, true
+}
+
+func countPlanSubscriptions(ctx context.Context, plans PlanRolloutStore, versionID int64) int {
+	n, err := plans.CountSubscriptionsByPlanVersion(ctx, versionID)
+	if err != nil {
+		log.Printf("PlanRollout: count subscriptions on version %d: %v", versionID, err)
+	}
+	return n
+}
@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/store"
+)
+
+type fakePlanRolloutStore struct {
+	versions []*models.PlanVersion
+	rollout  *models.PlanVersionRollout
+	promoted int
+}
+
+func (f *fakePlanRolloutStore) GetPlanBySlug(ctx context.Context, slug string) (*models.MembershipPlan, error) {
+	if slug != "premium" {
+		return nil, store.ErrPlanNotFound
+	}
+	return &models.MembershipPlan{ID: 1, Slug: slug}, nil
+}
+
+func (f *fakePlanRolloutStore) versionWithStatus(status models.PlanVersionStatus) *models.PlanVersion {
+	for _, v := range f.versions {
+		if v.Status == status {
+			return v
+		}
+	}
+	return nil
+}
+
+func (f *fakePlanRolloutStore) GetActivePlanVersion(ctx context.Context, planID int64) (*models.PlanVersion, error) {
+	if v := f.versionWithStatus(models.PlanVersionActive); v != nil {
+		return v, nil
+	}
+	return nil, store.ErrPlanVersionNotFound
+}
+
+func (f *fakePlanRolloutStore) GetPlanVersionRollout(ctx context.Context, planID int64) (*models.PlanVersion, *models.PlanVersionRollout, error) {
+	v := f.versionWithStatus(models.PlanVersionRollingOut)
+	if v == nil {
+		return nil, nil, store.ErrNoPlanRollout
+	}
+	if f.rollout == nil {
+		return v, &models.PlanVersionRollout{PlanVersionID: v.ID, EmailDomains: []string{}}, nil
+	}
+	return v, f.rollout, nil
+}
+
+func (f *fakePlanRolloutStore) SetPlanVersionRollout(ctx context.Context, versionID int64, percent int, emailDomains []string) error {
+	f.rollout = &models.PlanVersionRollout{PlanVersionID: versionID, Percent: percent, EmailDomains: emailDomains}
+	return nil
+}
+
+func (f *fakePlanRolloutStore) PromotePlanVersion(ctx context.Context, versionID int64, gracePeriodDays int, policy models.PlanMigrationPolicy) error {
+	for _, v := range f.versions {
+		switch {
+		case v.ID == versionID:
+			v.Status = models.PlanVersionActive
+		case v.Status == models.PlanVersionActive:
+			v.Status = models.PlanVersionDeprecated
+			v.GracePeriodDays = gracePeriodDays
+			v.MigrationPolicy = policy
+		}
+	}
+	f.rollout = nil
+	f.promoted = gracePeriodDays
+	return nil
+}
+
+func (f *fakePlanRolloutStore) CountSubscriptionsByPlanVersion(ctx context.Context, versionID int64) (int, error) {
+	return int(versionID) * 10, nil
+}
+
+func TestPlanRolloutConfigureAndPromote(t *testing.T) {
+	plans := &fakePlanRolloutStore{versions: []*models.PlanVersion{
+		{ID: 1, PlanID: 1, Version: 1, Status: models.PlanVersionActive},
+		{ID: 2, PlanID: 1, Version: 2, Status: models.PlanVersionRollingOut},
+	}}
+	router := chi.NewRouter()
+	router.Put("/api/admin/plans/{slug}/rollout", AdminPlanRollout(plans))
+	router.Post("/api/admin/plans/{slug}/rollout/promote", AdminPromotePlanRollout(plans))
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := do(http.MethodPut, "/api/admin/plans/premium/rollout", `{"percent":150}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("out of range percent: expected 400, got %d", rec.Code)
+	}
+	rec := do(http.MethodPut, "/api/admin/plans/premium/rollout", `{"percent":25,"email_domains":[" @Example.com "]}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("configure: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var body struct {
+		Rollout             models.PlanVersionRollout `json:"rollout"`
+		ActiveSubscriptions int                       `json:"active_subscriptions"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if body.Rollout.PlanVersionID != 2 || body.Rollout.Percent != 25 || len(body.Rollout.EmailDomains) != 1 || body.Rollout.EmailDomains[0] != "example.com" {
+		t.Fatalf("unexpected rollout: %+v", body.Rollout)
+	}
+	if body.ActiveSubscriptions != 10 {
+		t.Fatalf("expected active version subscription count, got %d", body.ActiveSubscriptions)
+	}
+
+	if rec := do(http.MethodPost, "/api/admin/plans/premium/rollout/promote", ""); rec.Code != http.StatusOK {
+		t.Fatalf("promote: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if plans.promoted != defaultRolloutGracePeriodDays || plans.versions[0].Status != models.PlanVersionDeprecated || plans.versions[1].Status != models.PlanVersionActive {
+		t.Fatalf("unexpected versions after promote: %+v %+v", plans.versions[0], plans.versions[1])
+	}
+	if rec := do(http.MethodPost, "/api/admin/plans/premium/rollout/promote", ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("promote without rollout: expected 404, got %d", rec.Code)
+	}
+}
+
+func TestPlanVersionRolloutIncludes(t *testing.T) {
+	rollout := &models.PlanVersionRollout{PlanVersionID: 2, Percent: 30, EmailDomains: []string{"example.com"}}
+	if !rollout.Includes(0, "someone@Example.COM") {
+		t.Fatal("expected listed email domain to be in the cohort")
+	}
+
+	included := 0
+	for userID := int64(1); userID <= 1000; userID++ {
+		if rollout.Includes(userID, "") != rollout.Includes(userID, "") {
+			t.Fatalf("cohort membership for user %d is not stable", userID)
+		}
+		if rollout.Includes(userID, "") {
+			included++
+		}
+	}
+	if included < 220 || included > 380 {
+		t.Fatalf("expected roughly 30%% of users in the cohort, got %d/1000", included)
+	}
+
+	rollout.Percent = 0
+	if rollout.Includes(42, "user@other.org") {
+		t.Fatal("expected nobody outside listed domains at 0%")
+	}
+}
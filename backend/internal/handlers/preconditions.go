@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+var errInvalidIfMatch = errors.New(`If-Match must be a quoted revision such as "3"`)
+
+// ifMatchRevision parses an If-Match header carrying a resource revision
+// ("3", W/"3" or a bare 3). It returns nil when the header is absent, so
+// callers that don't send one keep last-write-wins behaviour.
+func ifMatchRevision(r *http.Request) (*int64, error) {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" {
+		return nil, nil
+	}
+
+	value := strings.Trim(strings.TrimPrefix(header, "W/"), `"`)
+	revision, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || revision < 0 {
+		return nil, errInvalidIfMatch
+	}
+	return &revision, nil
+}
+
+// writeRevisionConflict reports that a conditional write lost the race; the
+// client should re-read the resource and retry.
+func writeRevisionConflict(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusPreconditionFailed)
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"error": "resource was modified by another request; reload and retry",
+	})
+}
@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	// Embed the IANA database so timezone validation does not depend on the
+	// host having zoneinfo installed.
+	_ "time/tzdata"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+	storepkg "github.com/PortNumber53/mcp-jira-thing/backend/internal/store"
+)
+
+var localePattern = regexp.MustCompile(`^[A-Za-z]{2,3}(-[A-Za-z0-9]{2,8})*$`)
+
+// PreferencesStore defines the behaviour required to read and update a
+// user's date and locale preferences.
+type PreferencesStore interface {
+	GetUserPreferences(ctx context.Context, email string) (*models.UserPreferences, error)
+	GetUserPreferencesByUserID(ctx context.Context, userID int64) (*models.UserPreferences, error)
+	UpsertUserPreferences(ctx context.Context, email string, prefs models.UserPreferences, ifRevision *int64) (*models.UserPreferences, error)
+}
+
+type preferencesPayload struct {
+	UserEmail  string `json:"user_email"`
+	Timezone   string `json:"timezone"`
+	Locale     string `json:"locale"`
+	DateFormat string `json:"date_format"`
+
+	MCPLogLevel string `json:"mcp_log_level"`
+	UsageDigest *bool  `json:"usage_digest"`
+}
+
+// Preferences reads or updates the caller's timezone, locale, date format and
+// the level of the MCP log messages streamed to their client sessions.
+// GET  ?email=...
+// POST {"timezone": "Europe/Berlin", "locale": "de-DE", "date_format": "medium", "mcp_log_level": "debug"}
+// Omitted fields keep their current value. Send If-Match: "<revision>" to
+// reject the write when the preferences changed since they were read.
+func Preferences(store PreferencesStore, cookieSecret string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			email := requestEmail(r, cookieSecret, "")
+			if email == "" {
+				http.Error(w, "email query parameter is required", http.StatusBadRequest)
+				return
+			}
+
+			prefs, err := store.GetUserPreferences(r.Context(), email)
+			if err != nil {
+				log.Printf("Preferences: failed to load preferences for email=%s: %v", email, err)
+				http.Error(w, "failed to load preferences", http.StatusBadGateway)
+				return
+			}
+			writePreferences(w, prefs)
+
+		case http.MethodPost:
+			ifRevision, err := ifMatchRevision(r)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			var payload preferencesPayload
+			if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+				log.Printf("Preferences: invalid JSON payload: %v", err)
+				http.Error(w, "invalid JSON payload", http.StatusBadRequest)
+				return
+			}
+
+			email := requestEmail(r, cookieSecret, payload.UserEmail)
+			if email == "" {
+				http.Error(w, "user_email is required", http.StatusBadRequest)
+				return
+			}
+
+			current, err := store.GetUserPreferences(r.Context(), email)
+			if err != nil {
+				log.Printf("Preferences: failed to load preferences for email=%s: %v", email, err)
+				http.Error(w, "failed to load preferences", http.StatusBadGateway)
+				return
+			}
+
+			prefs, msg := mergePreferences(*current, payload)
+			if msg != "" {
+				http.Error(w, msg, http.StatusBadRequest)
+				return
+			}
+
+			saved, err := store.UpsertUserPreferences(r.Context(), email, prefs, ifRevision)
+			if errors.Is(err, storepkg.ErrRevisionMismatch) {
+				writeRevisionConflict(w)
+				return
+			}
+			if err != nil {
+				log.Printf("Preferences: failed to save preferences for email=%s: %v", email, err)
+				http.Error(w, "failed to save preferences", http.StatusBadGateway)
+				return
+			}
+			writePreferences(w, saved)
+
+		default:
+			w.Header().Set("Allow", "GET, POST")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// TenantPreferences returns the preferences of the tenant identified by the
+// mcp_secret query parameter, for the MCP worker.
+func TenantPreferences(store PreferencesStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value("user_id").(int64)
+		if !ok || userID <= 0 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		prefs, err := store.GetUserPreferencesByUserID(r.Context(), userID)
+		if err != nil {
+			log.Printf("TenantPreferences: failed to load preferences for user_id=%d: %v", userID, err)
+			http.Error(w, "failed to load preferences", http.StatusBadGateway)
+			return
+		}
+		writePreferences(w, prefs)
+	}
+}
+
+// mergePreferences applies the non-empty payload fields over current and
+// returns a client-facing message when a value is invalid.
+func mergePreferences(current models.UserPreferences, payload preferencesPayload) (models.UserPreferences, string) {
+	prefs := current
+	prefs.UpdatedAt = nil
+
+	if tz := strings.TrimSpace(payload.Timezone); tz != "" {
+		if _, err := time.LoadLocation(tz); err != nil || tz == "Local" {
+			return prefs, "invalid timezone: use an IANA name such as Europe/Berlin"
+		}
+		prefs.Timezone = tz
+	}
+
+	if locale := strings.TrimSpace(payload.Locale); locale != "" {
+		if !localePattern.MatchString(locale) {
+			return prefs, "invalid locale: use a BCP 47 tag such as en-US"
+		}
+		prefs.Locale = locale
+	}
+
+	if format := strings.ToLower(strings.TrimSpace(payload.DateFormat)); format != "" {
+		switch format {
+		case models.DateFormatISO, models.DateFormatShort, models.DateFormatMedium, models.DateFormatLong:
+			prefs.DateFormat = format
+		default:
+			return prefs, "invalid date_format: must be one of iso, short, medium, long"
+		}
+	}
+
+	if level := strings.ToLower(strings.TrimSpace(payload.MCPLogLevel)); level != "" {
+		if !slices.Contains(models.MCPLogLevels, level) {
+			return prefs, "invalid mcp_log_level: must be one of " + strings.Join(models.MCPLogLevels, ", ")
+		}
+		prefs.MCPLogLevel = level
+	}
+
+	if payload.UsageDigest != nil {
+		prefs.UsageDigest = *payload.UsageDigest
+	}
+
+	return prefs, ""
+}
+
+func writePreferences(w http.ResponseWriter, prefs *models.UserPreferences) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(prefs); err != nil {
+		http.Error(w, "failed to encode response", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,423 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/store"
+)
+
+// The provisioning endpoints let infrastructure-as-code tooling declare
+// plans, feature flags and recurring jobs by slug. PUT always sends the full
+// desired state and is idempotent: repeating it changes nothing and reports
+// "changed": false. DELETE of something already gone succeeds.
+
+// provisioningSlugPattern matches the slugs of provisioned resources.
+var provisioningSlugPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,62}$`)
+
+// provisioningSlug returns the {slug} URL parameter, writing a 400 when it
+// is not a valid slug.
+func provisioningSlug(w http.ResponseWriter, r *http.Request) (string, bool) {
+	slug := chi.URLParam(r, "slug")
+	if !provisioningSlugPattern.MatchString(slug) {
+		http.Error(w, "slug must be 1-63 lowercase letters, digits, '-' or '_'", http.StatusBadRequest)
+		return "", false
+	}
+	return slug, true
+}
+
+// PlanProvisioningStore reads and declaratively provisions plans.
+type PlanProvisioningStore interface {
+	GetPlanBySlug(ctx context.Context, slug string) (*models.MembershipPlan, error)
+	GetActivePlanVersion(ctx context.Context, planID int64) (*models.PlanVersion, error)
+	ProvisionPlan(ctx context.Context, plan *models.MembershipPlan, version *models.PlanVersion) (bool, error)
+}
+
+// AdminProvisionPlan reports (GET) or provisions (PUT) a plan and its active
+// version. PUT takes {"name", "description", "tier", "is_active",
+// "monthly_request_quota", "monthly_cost_unit_quota",
+// "max_streaming_connections", "requests_per_minute", "price_cents", "currency", "billing_interval"}; a price different from the active
+// version's is refused with 409 since prices change through a rollout.
+func AdminProvisionPlan(plans PlanProvisioningStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		slug, ok := provisioningSlug(w, r)
+		if !ok {
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			plan, err := plans.GetPlanBySlug(r.Context(), slug)
+			if errors.Is(err, store.ErrPlanNotFound) {
+				http.Error(w, "plan not found", http.StatusNotFound)
+				return
+			}
+			if err != nil {
+				log.Printf("AdminProvisionPlan: load %s: %v", slug, err)
+				http.Error(w, "failed to load plan", http.StatusInternalServerError)
+				return
+			}
+			version, err := plans.GetActivePlanVersion(r.Context(), plan.ID)
+			if err != nil && !errors.Is(err, store.ErrPlanVersionNotFound) {
+				log.Printf("AdminProvisionPlan: load active version of %s: %v", slug, err)
+				http.Error(w, "failed to load plan", http.StatusInternalServerError)
+				return
+			}
+			writeJSON(w, http.StatusOK, map[string]any{"plan": plan, "version": version})
+
+		case http.MethodPut:
+			var payload struct {
+				Name                    string  `json:"name"`
+				Description             *string `json:"description"`
+				Tier                    int     `json:"tier"`
+				IsActive                *bool   `json:"is_active"`
+				MonthlyRequestQuota     *int    `json:"monthly_request_quota"`
+				MonthlyCostUnitQuota    *int    `json:"monthly_cost_unit_quota"`
+				MaxStreamingConnections *int    `json:"max_streaming_connections"`
+				RequestsPerMinute       *int    `json:"requests_per_minute"`
+				PriceCents              *int    `json:"price_cents"`
+				Currency                string  `json:"currency"`
+				BillingInterval         string  `json:"billing_interval"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+				http.Error(w, "invalid JSON payload", http.StatusBadRequest)
+				return
+			}
+
+			plan := &models.MembershipPlan{
+				Slug:                    slug,
+				Name:                    strings.TrimSpace(payload.Name),
+				Description:             payload.Description,
+				Tier:                    payload.Tier,
+				IsActive:                payload.IsActive == nil || *payload.IsActive,
+				MonthlyRequestQuota:     payload.MonthlyRequestQuota,
+				MonthlyCostUnitQuota:    payload.MonthlyCostUnitQuota,
+				MaxStreamingConnections: payload.MaxStreamingConnections,
+				RequestsPerMinute:       payload.RequestsPerMinute,
+			}
+			version := &models.PlanVersion{
+				Currency:        strings.ToLower(strings.TrimSpace(payload.Currency)),
+				BillingInterval: strings.TrimSpace(payload.BillingInterval),
+			}
+			if version.Currency == "" {
+				version.Currency = "usd"
+			}
+			if version.BillingInterval == "" {
+				version.BillingInterval = "month"
+			}
+			switch {
+			case plan.Name == "":
+				http.Error(w, "name is required", http.StatusBadRequest)
+				return
+			case plan.Tier < 0:
+				http.Error(w, "tier must not be negative", http.StatusBadRequest)
+				return
+			case (plan.MonthlyRequestQuota != nil && *plan.MonthlyRequestQuota < 0) ||
+				(plan.MonthlyCostUnitQuota != nil && *plan.MonthlyCostUnitQuota < 0) ||
+				(plan.MaxStreamingConnections != nil && *plan.MaxStreamingConnections < 0) ||
+				(plan.RequestsPerMinute != nil && *plan.RequestsPerMinute < 0):
+				http.Error(w, "quotas must not be negative; omit them for unlimited", http.StatusBadRequest)
+				return
+			case payload.PriceCents == nil || *payload.PriceCents < 0:
+				http.Error(w, "price_cents is required and must not be negative", http.StatusBadRequest)
+				return
+			case version.BillingInterval != "month" && version.BillingInterval != "year":
+				http.Error(w, `billing_interval must be "month" or "year"`, http.StatusBadRequest)
+				return
+			}
+			version.PriceCents = *payload.PriceCents
+
+			changed, err := plans.ProvisionPlan(r.Context(), plan, version)
+			if errors.Is(err, store.ErrPlanPriceChange) {
+				http.Error(w, err.Error()+"; roll the new price out with /api/admin/plans/"+slug+"/rollout", http.StatusConflict)
+				return
+			}
+			if err != nil {
+				log.Printf("AdminProvisionPlan: provision %s: %v", slug, err)
+				http.Error(w, "failed to provision plan", http.StatusInternalServerError)
+				return
+			}
+			writeJSON(w, http.StatusOK, map[string]any{"changed": changed, "plan": plan, "version": version})
+
+		default:
+			w.Header().Set("Allow", "GET, PUT")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// FeatureFlagStore reads and provisions feature flags.
+type FeatureFlagStore interface {
+	ListFeatureFlags(ctx context.Context) ([]models.FeatureFlag, error)
+	GetFeatureFlag(ctx context.Context, slug string) (*models.FeatureFlag, error)
+	PutFeatureFlag(ctx context.Context, flag *models.FeatureFlag) (bool, error)
+	DeleteFeatureFlag(ctx context.Context, slug string) error
+}
+
+// AdminFeatureFlags lists every feature flag.
+func AdminFeatureFlags(flags FeatureFlagStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		list, err := flags.ListFeatureFlags(r.Context())
+		if err != nil {
+			log.Printf("AdminFeatureFlags: list: %v", err)
+			http.Error(w, "failed to load feature flags", http.StatusInternalServerError)
+			return
+		}
+		if list == nil {
+			list = []models.FeatureFlag{}
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"feature_flags": list})
+	}
+}
+
+// AdminFeatureFlag reports (GET), provisions (PUT) or removes (DELETE) a
+// feature flag. PUT takes {"description", "enabled", "percent" (default
+// 100), "email_domains"}.
+func AdminFeatureFlag(flags FeatureFlagStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		slug, ok := provisioningSlug(w, r)
+		if !ok {
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			flag, err := flags.GetFeatureFlag(r.Context(), slug)
+			if errors.Is(err, store.ErrFeatureFlagNotFound) {
+				http.Error(w, "feature flag not found", http.StatusNotFound)
+				return
+			}
+			if err != nil {
+				log.Printf("AdminFeatureFlag: load %s: %v", slug, err)
+				http.Error(w, "failed to load feature flag", http.StatusInternalServerError)
+				return
+			}
+			writeJSON(w, http.StatusOK, map[string]any{"feature_flag": flag})
+
+		case http.MethodPut:
+			var payload struct {
+				Description  *string  `json:"description"`
+				Enabled      bool     `json:"enabled"`
+				Percent      *int     `json:"percent"`
+				EmailDomains []string `json:"email_domains"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+				http.Error(w, "invalid JSON payload", http.StatusBadRequest)
+				return
+			}
+			flag := &models.FeatureFlag{
+				Slug:         slug,
+				Description:  payload.Description,
+				Enabled:      payload.Enabled,
+				Percent:      100,
+				EmailDomains: make([]string, 0, len(payload.EmailDomains)),
+			}
+			if payload.Percent != nil {
+				flag.Percent = *payload.Percent
+			}
+			if flag.Percent < 0 || flag.Percent > 100 {
+				http.Error(w, "percent must be between 0 and 100", http.StatusBadRequest)
+				return
+			}
+			for _, d := range payload.EmailDomains {
+				if d = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(d), "@")); d != "" {
+					flag.EmailDomains = append(flag.EmailDomains, d)
+				}
+			}
+
+			changed, err := flags.PutFeatureFlag(r.Context(), flag)
+			if err != nil {
+				log.Printf("AdminFeatureFlag: put %s: %v", slug, err)
+				http.Error(w, "failed to save feature flag", http.StatusInternalServerError)
+				return
+			}
+			writeJSON(w, http.StatusOK, map[string]any{"changed": changed, "feature_flag": flag})
+
+		case http.MethodDelete:
+			if err := flags.DeleteFeatureFlag(r.Context(), slug); err != nil {
+				log.Printf("AdminFeatureFlag: delete %s: %v", slug, err)
+				http.Error(w, "failed to delete feature flag", http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+
+		default:
+			w.Header().Set("Allow", "GET, PUT, DELETE")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// TenantFeatureFlagStore evaluates feature flags for a tenant.
+type TenantFeatureFlagStore interface {
+	ListEnabledFeatureFlags(ctx context.Context, userID int64) ([]string, error)
+}
+
+// TenantFeatureFlags returns the slugs of the feature flags that are on for
+// the tenant identified by the mcp_secret query parameter, for the MCP
+// worker.
+func TenantFeatureFlags(flags TenantFeatureFlagStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value("user_id").(int64)
+		if !ok || userID <= 0 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		enabled, err := flags.ListEnabledFeatureFlags(r.Context(), userID)
+		if err != nil {
+			log.Printf("TenantFeatureFlags: evaluate for user %d: %v", userID, err)
+			http.Error(w, "failed to load feature flags", http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"feature_flags": enabled})
+	}
+}
+
+// RecurringJobStore reads and provisions recurring jobs.
+type RecurringJobStore interface {
+	ListRecurringJobs(ctx context.Context) ([]models.RecurringJob, error)
+	GetRecurringJob(ctx context.Context, slug string) (*models.RecurringJob, error)
+	PutRecurringJob(ctx context.Context, job *models.RecurringJob) (bool, error)
+	DeleteRecurringJob(ctx context.Context, slug string) error
+}
+
+// JobTypeRegistry reports whether the worker can run a job type.
+type JobTypeRegistry interface {
+	HasHandler(jobType string) bool
+}
+
+// AdminRecurringJobs lists every recurring job with its schedule.
+func AdminRecurringJobs(jobs RecurringJobStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		list, err := jobs.ListRecurringJobs(r.Context())
+		if err != nil {
+			log.Printf("AdminRecurringJobs: list: %v", err)
+			http.Error(w, "failed to load recurring jobs", http.StatusInternalServerError)
+			return
+		}
+		if list == nil {
+			list = []models.RecurringJob{}
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"recurring_jobs": list})
+	}
+}
+
+// AdminRecurringJob reports (GET), provisions (PUT) or removes (DELETE) a
+// recurring job. PUT takes {"job_type", "payload", "priority",
+// "max_attempts", "interval_seconds" (at least 60), "enabled" (default
+// true)}. When registry is set, job types the worker cannot run are
+// refused.
+func AdminRecurringJob(jobs RecurringJobStore, registry JobTypeRegistry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		slug, ok := provisioningSlug(w, r)
+		if !ok {
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			job, err := jobs.GetRecurringJob(r.Context(), slug)
+			if errors.Is(err, store.ErrRecurringJobNotFound) {
+				http.Error(w, "recurring job not found", http.StatusNotFound)
+				return
+			}
+			if err != nil {
+				log.Printf("AdminRecurringJob: load %s: %v", slug, err)
+				http.Error(w, "failed to load recurring job", http.StatusInternalServerError)
+				return
+			}
+			writeJSON(w, http.StatusOK, map[string]any{"recurring_job": job})
+
+		case http.MethodPut:
+			var payload struct {
+				JobType         string       `json:"job_type"`
+				Payload         models.JSONB `json:"payload"`
+				Priority        string       `json:"priority"`
+				MaxAttempts     int          `json:"max_attempts"`
+				IntervalSeconds int          `json:"interval_seconds"`
+				Enabled         *bool        `json:"enabled"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+				http.Error(w, "invalid JSON payload", http.StatusBadRequest)
+				return
+			}
+			job := &models.RecurringJob{
+				Slug:            slug,
+				JobType:         strings.TrimSpace(payload.JobType),
+				Payload:         payload.Payload,
+				Priority:        models.JobPriority(payload.Priority),
+				MaxAttempts:     payload.MaxAttempts,
+				IntervalSeconds: payload.IntervalSeconds,
+				Enabled:         payload.Enabled == nil || *payload.Enabled,
+			}
+			if job.Payload == nil {
+				job.Payload = models.JSONB{}
+			}
+			if job.Priority == "" {
+				job.Priority = models.JobPriorityNormal
+			}
+			if job.MaxAttempts == 0 {
+				job.MaxAttempts = 3
+			}
+			_, knownPriority := models.PriorityWeights[job.Priority]
+			switch {
+			case job.JobType == "":
+				http.Error(w, "job_type is required", http.StatusBadRequest)
+				return
+			case registry != nil && !registry.HasHandler(job.JobType):
+				http.Error(w, "no handler is registered for job_type "+job.JobType, http.StatusBadRequest)
+				return
+			case !knownPriority:
+				http.Error(w, "priority must be low, normal, high or critical", http.StatusBadRequest)
+				return
+			case job.MaxAttempts < 1 || job.MaxAttempts > 25:
+				http.Error(w, "max_attempts must be between 1 and 25", http.StatusBadRequest)
+				return
+			case job.IntervalSeconds < 60:
+				http.Error(w, "interval_seconds must be at least 60", http.StatusBadRequest)
+				return
+			}
+
+			changed, err := jobs.PutRecurringJob(r.Context(), job)
+			if err != nil {
+				log.Printf("AdminRecurringJob: put %s: %v", slug, err)
+				http.Error(w, "failed to save recurring job", http.StatusInternalServerError)
+				return
+			}
+			writeJSON(w, http.StatusOK, map[string]any{"changed": changed, "recurring_job": job})
+
+		case http.MethodDelete:
+			if err := jobs.DeleteRecurringJob(r.Context(), slug); err != nil {
+				log.Printf("AdminRecurringJob: delete %s: %v", slug, err)
+				http.Error(w, "failed to delete recurring job", http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+
+		default:
+			w.Header().Set("Allow", "GET, PUT, DELETE")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/store"
+)
+
+type fakePlanProvisioningStore struct {
+	plan    *models.MembershipPlan
+	version *models.PlanVersion
+}
+
+func (f *fakePlanProvisioningStore) GetPlanBySlug(ctx context.Context, slug string) (*models.MembershipPlan, error) {
+	if f.plan == nil || f.plan.Slug != slug {
+		return nil, store.ErrPlanNotFound
+	}
+	return f.plan, nil
+}
+
+func (f *fakePlanProvisioningStore) GetActivePlanVersion(ctx context.Context, planID int64) (*models.PlanVersion, error) {
+	if f.version == nil {
+		return nil, store.ErrPlanVersionNotFound
+	}
+	return f.version, nil
+}
+
+func (f *fakePlanProvisioningStore) ProvisionPlan(ctx context.Context, plan *models.MembershipPlan, version *models.PlanVersion) (bool, error) {
+	if f.version != nil && f.version.PriceCents != version.PriceCents {
+		return false, fmt.Errorf("provision plan %s: %w", plan.Slug, store.ErrPlanPriceChange)
+	}
+	changed := f.plan == nil || f.plan.Name != plan.Name || f.version == nil
+	plan.ID = 1
+	stored := *plan
+	f.plan = &stored
+	if f.version == nil {
+		f.version = &models.PlanVersion{ID: 1, PlanID: 1, Version: 1, PriceCents: version.PriceCents,
+			Currency: version.Currency, BillingInterval: version.BillingInterval, Status: models.PlanVersionActive}
+	}
+	*version = *f.version
+	return changed, nil
+}
+
+func TestAdminProvisionPlanIsIdempotent(t *testing.T) {
+	plans := &fakePlanProvisioningStore{}
+	router := chi.NewRouter()
+	router.Put("/api/admin/plans/{slug}", AdminProvisionPlan(plans))
+
+	put := func(body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/api/admin/plans/team", strings.NewReader(body)))
+		return rec
+	}
+
+	if rec := put(`{"name":"Team"}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("missing price: expected 400, got %d", rec.Code)
+	}
+	for i, wantChanged := range []bool{true, false} {
+		rec := put(`{"name":"Team","price_cents":2500}`)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("put %d: expected 200, got %d: %s", i, rec.Code, rec.Body.String())
+		}
+		var body struct {
+			Changed bool               `json:"changed"`
+			Version models.PlanVersion `json:"version"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+			t.Fatalf("decode response: %v", err)
+		}
+		if body.Changed != wantChanged || body.Version.Currency != "usd" || body.Version.BillingInterval != "month" {
+			t.Fatalf("put %d: unexpected response %+v", i, body)
+		}
+	}
+	if rec := put(`{"name":"Team","price_cents":3000}`); rec.Code != http.StatusConflict {
+		t.Fatalf("price change: expected 409, got %d", rec.Code)
+	}
+}
+
+type fakeFeatureFlagStore struct {
+	flags map[string]models.FeatureFlag
+}
+
+func (f *fakeFeatureFlagStore) ListFeatureFlags(ctx context.Context) ([]models.FeatureFlag, error) {
+	var list []models.FeatureFlag
+	for _, flag := range f.flags {
+		list = append(list, flag)
+	}
+	return list, nil
+}
+
+func (f *fakeFeatureFlagStore) GetFeatureFlag(ctx context.Context, slug string) (*models.FeatureFlag, error) {
+	flag, ok := f.flags[slug]
+	if !ok {
+		return nil, store.ErrFeatureFlagNotFound
+	}
+	return &flag, nil
+}
+
+func (f *fakeFeatureFlagStore) PutFeatureFlag(ctx context.Context, flag *models.FeatureFlag) (bool, error) {
+	f.flags[flag.Slug] = *flag
+	return true, nil
+}
+
+func (f *fakeFeatureFlagStore) DeleteFeatureFlag(ctx context.Context, slug string) error {
+	delete(f.flags, slug)
+	return nil
+}
+
+func TestAdminFeatureFlag(t *testing.T) {
+	flags := &fakeFeatureFlagStore{flags: map[string]models.FeatureFlag{}}
+	router := chi.NewRouter()
+	router.Handle("/api/admin/feature-flags/{slug}", AdminFeatureFlag(flags))
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return rec
+	}
+
+	if rec := do(http.MethodPut, "/api/admin/feature-flags/Bad%20Slug", `{}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("invalid slug: expected 400, got %d", rec.Code)
+	}
+	if rec := do(http.MethodPut, "/api/admin/feature-flags/beta", `{"percent":101}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("out of range percent: expected 400, got %d", rec.Code)
+	}
+	if rec := do(http.MethodPut, "/api/admin/feature-flags/beta", `{"enabled":true,"email_domains":[" @Example.com ",""]}`); rec.Code != http.StatusOK {
+		t.Fatalf("put: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	flag := flags.flags["beta"]
+	if !flag.Enabled || flag.Percent != 100 || len(flag.EmailDomains) != 1 || flag.EmailDomains[0] != "example.com" {
+		t.Fatalf("unexpected stored flag: %+v", flag)
+	}
+	if rec := do(http.MethodDelete, "/api/admin/feature-flags/beta", ""); rec.Code != http.StatusNoContent {
+		t.Fatalf("delete: expected 204, got %d", rec.Code)
+	}
+	if rec := do(http.MethodGet, "/api/admin/feature-flags/beta", ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("get deleted: expected 404, got %d", rec.Code)
+	}
+}
+
+func TestFeatureFlagEnabledFor(t *testing.T) {
+	flag := &models.FeatureFlag{Slug: "beta", Enabled: true, Percent: 20, EmailDomains: []string{"example.com"}}
+	if !flag.EnabledFor(0, "someone@Example.COM") {
+		t.Fatal("expected listed email domain to have the flag")
+	}
+
+	enabled := 0
+	for userID := int64(1); userID <= 1000; userID++ {
+		if flag.EnabledFor(userID, "") {
+			enabled++
+		}
+	}
+	if enabled < 130 || enabled > 270 {
+		t.Fatalf("expected roughly 20%% of users to have the flag, got %d/1000", enabled)
+	}
+
+	flag.Enabled = false
+	if flag.EnabledFor(1, "someone@example.com") {
+		t.Fatal("expected a disabled flag to be off for everyone")
+	}
+}
@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/realtime"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/session"
+)
+
+const (
+	wsWriteTimeout = 10 * time.Second
+	wsPongTimeout  = 60 * time.Second
+	wsPingInterval = 45 * time.Second
+)
+
+// RealtimeSocket upgrades the request to a WebSocket and streams the tenant's
+// real-time events (job status, Jira webhooks, billing updates). The tenant is
+// identified by the session cookie or, for non-browser clients, by the
+// mcp_secret query parameter resolved by the MCP auth middleware. Sockets
+// past the tenant's streaming connection limit are refused with 429.
+func RealtimeSocket(hub *realtime.Hub, users UserStore, limits *StreamLimits, cookieSecret, frontendURL string) http.HandlerFunc {
+	upgrader := websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 1024,
+		CheckOrigin: func(r *http.Request) bool {
+			return allowedSocketOrigin(r, frontendURL)
+		},
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value("user_id").(int64)
+		if !ok || userID <= 0 {
+			sess, err := session.ReadSession(r, cookieSecret)
+			if err != nil || sess.Email == nil || *sess.Email == "" {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			user, err := users.GetUserByEmail(r.Context(), *sess.Email)
+			if err != nil || user == nil {
+				log.Printf("RealtimeSocket: failed to resolve user for email=%s: %v", *sess.Email, err)
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			userID = user.ID
+		}
+
+		release, ok := limits.Acquire(w, r, userID, realtime.StreamWebSocket)
+		if !ok {
+			return
+		}
+		defer release()
+
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			// Upgrade has already written an error response.
+			log.Printf("RealtimeSocket: upgrade failed for user_id=%d: %v", userID, err)
+			return
+		}
+		defer conn.Close()
+
+		events, unsubscribe := hub.Subscribe(userID)
+		defer unsubscribe()
+
+		// Drain inbound frames so control messages (pong, close) are handled;
+		// clients are not expected to send data.
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			conn.SetReadLimit(512)
+			_ = conn.SetReadDeadline(time.Now().Add(wsPongTimeout))
+			conn.SetPongHandler(func(string) error {
+				return conn.SetReadDeadline(time.Now().Add(wsPongTimeout))
+			})
+			for {
+				if _, _, err := conn.ReadMessage(); err != nil {
+					return
+				}
+			}
+		}()
+
+		ping := time.NewTicker(wsPingInterval)
+		defer ping.Stop()
+
+		for {
+			select {
+			case ev, ok := <-events:
+				if !ok {
+					return
+				}
+				payload, err := json.Marshal(ev)
+				if err != nil {
+					log.Printf("RealtimeSocket: failed to encode %s event: %v", ev.Type, err)
+					continue
+				}
+				_ = conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+				if err := conn.WriteMessage(websocket.TextMessage, payload); err != nil {
+					return
+				}
+			case <-ping.C:
+				_ = conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+				if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+					return
+				}
+			case <-done:
+				return
+			case <-r.Context().Done():
+				return
+			}
+		}
+	}
+}
+
+// allowedSocketOrigin accepts requests without an Origin header (server-side
+// clients), same-host origins, and the configured frontend origin.
+func allowedSocketOrigin(r *http.Request, frontendURL string) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	if strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	if frontendURL != "" {
+		if f, err := url.Parse(frontendURL); err == nil && strings.EqualFold(u.Host, f.Host) {
+			return true
+		}
+	}
+	return false
+}
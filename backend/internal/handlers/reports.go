@@ -0,0 +1,269 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/reports"
+	storepkg "github.com/PortNumber53/mcp-jira-thing/backend/internal/store"
+)
+
+// maxReportSchedulesPerUser caps how many report schedules a user may have.
+const maxReportSchedulesPerUser = 25
+
+// reportArtifactListLimit is how many artifacts the artifact list returns.
+const reportArtifactListLimit = 100
+
+// ReportStore defines the behaviour required to manage report schedules
+// and download their artifacts.
+type ReportStore interface {
+	UserLookup
+	CountReportSchedules(ctx context.Context, userID int64) (int, error)
+	CreateReportSchedule(ctx context.Context, r *models.ReportSchedule) (*models.ReportSchedule, error)
+	ListReportSchedules(ctx context.Context, userID int64) ([]models.ReportSchedule, error)
+	UpdateReportSchedule(ctx context.Context, r *models.ReportSchedule) (*models.ReportSchedule, error)
+	DeleteReportSchedule(ctx context.Context, userID, id int64) error
+	ListReportArtifacts(ctx context.Context, userID int64, limit int) ([]models.ReportArtifact, error)
+	GetReportArtifact(ctx context.Context, userID, id int64) (*models.ReportArtifact, error)
+}
+
+type reportSchedulePayload struct {
+	Name       string `json:"name"`
+	Template   string `json:"template"`
+	ProjectKey string `json:"project_key"`
+	JQL        string `json:"jql"`
+	Format     string `json:"format"`
+	Delivery   string `json:"delivery"`
+	Days       []int  `json:"days"`
+	TimeOfDay  string `json:"time_of_day"`
+	TimeZone   string `json:"time_zone"`
+	Enabled    *bool  `json:"enabled"`
+}
+
+// schedule builds a validated report schedule from the payload.
+func (p reportSchedulePayload) schedule(userID int64, now time.Time) (*models.ReportSchedule, error) {
+	r := &models.ReportSchedule{
+		UserID:         userID,
+		Name:           p.Name,
+		Template:       p.Template,
+		ProjectKey:     p.ProjectKey,
+		JQL:            p.JQL,
+		Format:         p.Format,
+		Delivery:       p.Delivery,
+		WeeklySchedule: models.WeeklySchedule{Days: p.Days, TimeOfDay: p.TimeOfDay, TimeZone: p.TimeZone},
+		Enabled:        p.Enabled == nil || *p.Enabled,
+	}
+	if r.Days == nil {
+		r.Days = []int{}
+	}
+	if err := r.Validate(); err != nil {
+		return nil, err
+	}
+	next, err := r.NextRun(now)
+	if err != nil {
+		return nil, err
+	}
+	r.NextRunAt = next
+	return r, nil
+}
+
+// ReportTemplates lists the built-in report templates.
+func ReportTemplates() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]any{"templates": reports.Templates()})
+	}
+}
+
+// ReportSchedules lists or creates the caller's recurring reports. The
+// caller is resolved as for UsageForecast.
+// POST {"name": "OPS triage", "template": "weekly_triage", "project_key": "OPS",
+// "format": "html", "delivery": "email", "days": [1], "time_of_day": "08:00",
+// "time_zone": "America/New_York"}
+func ReportSchedules(store ReportStore, cookieSecret string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := metricsUserID(w, r, store, cookieSecret)
+		if !ok {
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			schedules, err := store.ListReportSchedules(r.Context(), userID)
+			if err != nil {
+				log.Printf("ReportSchedules: failed to list report schedules for user_id=%d: %v", userID, err)
+				http.Error(w, "failed to list report schedules", http.StatusBadGateway)
+				return
+			}
+			if schedules == nil {
+				schedules = []models.ReportSchedule{}
+			}
+			writeJSON(w, http.StatusOK, map[string]any{"reports": schedules})
+
+		case http.MethodPost:
+			var payload reportSchedulePayload
+			if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+				http.Error(w, "invalid JSON payload", http.StatusBadRequest)
+				return
+			}
+			schedule, err := payload.schedule(userID, time.Now())
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			count, err := store.CountReportSchedules(r.Context(), userID)
+			if err != nil {
+				log.Printf("ReportSchedules: failed to count report schedules for user_id=%d: %v", userID, err)
+				http.Error(w, "failed to create report schedule", http.StatusBadGateway)
+				return
+			}
+			if count >= maxReportSchedulesPerUser {
+				writeJSON(w, http.StatusConflict, map[string]any{"error": "report_limit_reached", "limit": maxReportSchedulesPerUser})
+				return
+			}
+
+			created, err := store.CreateReportSchedule(r.Context(), schedule)
+			if err != nil {
+				log.Printf("ReportSchedules: failed to create report schedule for user_id=%d: %v", userID, err)
+				http.Error(w, "failed to create report schedule", http.StatusBadGateway)
+				return
+			}
+			writeJSON(w, http.StatusCreated, map[string]any{"report": created})
+
+		default:
+			w.Header().Set("Allow", "GET, POST")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// ReportSchedule replaces or deletes the caller's report schedule in the
+// {id} URL parameter. Saving a schedule moves its next run from now.
+func ReportSchedule(store ReportStore, cookieSecret string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := metricsUserID(w, r, store, cookieSecret)
+		if !ok {
+			return
+		}
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil || id <= 0 {
+			http.Error(w, "invalid report id", http.StatusBadRequest)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodPut:
+			var payload reportSchedulePayload
+			if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+				http.Error(w, "invalid JSON payload", http.StatusBadRequest)
+				return
+			}
+			schedule, err := payload.schedule(userID, time.Now())
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			schedule.ID = id
+
+			updated, err := store.UpdateReportSchedule(r.Context(), schedule)
+			if errors.Is(err, storepkg.ErrReportScheduleNotFound) {
+				http.Error(w, "report schedule not found", http.StatusNotFound)
+				return
+			}
+			if err != nil {
+				log.Printf("ReportSchedule: failed to update report schedule id=%d for user_id=%d: %v", id, userID, err)
+				http.Error(w, "failed to update report schedule", http.StatusBadGateway)
+				return
+			}
+			writeJSON(w, http.StatusOK, map[string]any{"report": updated})
+
+		case http.MethodDelete:
+			err := store.DeleteReportSchedule(r.Context(), userID, id)
+			if errors.Is(err, storepkg.ErrReportScheduleNotFound) {
+				http.Error(w, "report schedule not found", http.StatusNotFound)
+				return
+			}
+			if err != nil {
+				log.Printf("ReportSchedule: failed to delete report schedule id=%d for user_id=%d: %v", id, userID, err)
+				http.Error(w, "failed to delete report schedule", http.StatusBadGateway)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+
+		default:
+			w.Header().Set("Allow", "PUT, DELETE")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// ReportArtifacts lists the caller's stored reports, newest first, without
+// their content.
+func ReportArtifacts(store ReportStore, cookieSecret string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := metricsUserID(w, r, store, cookieSecret)
+		if !ok {
+			return
+		}
+		artifacts, err := store.ListReportArtifacts(r.Context(), userID, reportArtifactListLimit)
+		if err != nil {
+			log.Printf("ReportArtifacts: failed to list report artifacts for user_id=%d: %v", userID, err)
+			http.Error(w, "failed to list report artifacts", http.StatusBadGateway)
+			return
+		}
+		if artifacts == nil {
+			artifacts = []models.ReportArtifact{}
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"artifacts": artifacts})
+	}
+}
+
+// DownloadReportArtifact serves the caller's stored report in the {id} URL
+// parameter as a Markdown or HTML attachment.
+func DownloadReportArtifact(store ReportStore, cookieSecret string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := metricsUserID(w, r, store, cookieSecret)
+		if !ok {
+			return
+		}
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil || id <= 0 {
+			http.Error(w, "invalid artifact id", http.StatusBadRequest)
+			return
+		}
+
+		artifact, err := store.GetReportArtifact(r.Context(), userID, id)
+		if errors.Is(err, storepkg.ErrReportArtifactNotFound) {
+			http.Error(w, "report artifact not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Printf("DownloadReportArtifact: failed to load artifact id=%d for user_id=%d: %v", id, userID, err)
+			http.Error(w, "failed to load report artifact", http.StatusBadGateway)
+			return
+		}
+
+		contentType, ext := "text/markdown; charset=utf-8", "md"
+		if artifact.Format == models.ReportFormatHTML {
+			contentType, ext = "text/html; charset=utf-8", "html"
+		}
+		filename := fmt.Sprintf("%s-%s.%s", strings.ReplaceAll(artifact.Template, "_", "-"), artifact.CreatedAt.UTC().Format("2006-01-02"), ext)
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+		// The report embeds issue text from Jira; never render it inline.
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.Header().Set("Content-Security-Policy", "sandbox")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(artifact.Content))
+	}
+}
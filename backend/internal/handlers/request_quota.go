@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+)
+
+// RequestQuotaStore defines the behaviour required to report a user's
+// monthly request quota.
+type RequestQuotaStore interface {
+	UserLookup
+	GetEffectivePlan(ctx context.Context, userID int64) (*models.MembershipPlan, error)
+	CountPeriodRequests(ctx context.Context, userID int64, periodStart time.Time) (int64, error)
+}
+
+// RequestQuota reports the caller's monthly request quota, how much of it
+// the current UTC month used and when it resets. The caller is resolved as
+// for UsageForecast. Unlike the forecast it counts the request logs, so it
+// matches what the quota middleware enforces.
+func RequestQuota(store RequestQuotaStore, cookieSecret string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := metricsUserID(w, r, store, cookieSecret)
+		if !ok {
+			return
+		}
+
+		plan, err := store.GetEffectivePlan(r.Context(), userID)
+		if err != nil {
+			log.Printf("RequestQuota: failed to resolve plan for user_id=%d: %v", userID, err)
+			http.Error(w, "failed to load plan", http.StatusInternalServerError)
+			return
+		}
+		periodStart, reset := models.QuotaPeriod(time.Now())
+		used, err := store.CountPeriodRequests(r.Context(), userID, periodStart)
+		if err != nil {
+			log.Printf("RequestQuota: failed to count requests for user_id=%d: %v", userID, err)
+			http.Error(w, "failed to load usage", http.StatusInternalServerError)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, planQuota(plan, periodStart, reset, used))
+	}
+}
+
+// planQuota is plan's request quota for the period starting at periodStart,
+// of which used requests were made.
+func planQuota(plan *models.MembershipPlan, periodStart, reset time.Time, used int64) models.RequestQuota {
+	quota := models.RequestQuota{PlanSlug: plan.Slug, PeriodStart: periodStart, ResetsAt: reset, Used: used}
+	if q := plan.MonthlyRequestQuota; q != nil && *q > 0 {
+		remaining := max(int64(*q)-used, 0)
+		quota.Quota, quota.Remaining, quota.Exceeded = q, &remaining, remaining == 0
+	}
+	return quota
+}
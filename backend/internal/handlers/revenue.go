@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"context"
+	"encoding/csv"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+)
+
+// revenueDefaultMonths is how many months, including the current one, the
+// revenue report covers when no range is given.
+const revenueDefaultMonths = 12
+
+// revenueMaxMonths caps the range of one report.
+const revenueMaxMonths = 120
+
+// RevenueStore computes monthly recurring revenue figures.
+type RevenueStore interface {
+	ListRevenueMonths(ctx context.Context, from, to time.Time) ([]models.RevenueMonth, error)
+}
+
+// AdminRevenue reports MRR, ARR and new, churned and collected revenue per
+// month. from and to select months as YYYY-MM (default: the last 12 months);
+// format=csv returns the same rows as a CSV download.
+func AdminRevenue(store RevenueStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		now := time.Now().UTC()
+		to := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+		from := to.AddDate(0, -(revenueDefaultMonths - 1), 0)
+		for param, month := range map[string]*time.Time{"from": &from, "to": &to} {
+			if raw := r.URL.Query().Get(param); raw != "" {
+				parsed, err := time.Parse("2006-01", raw)
+				if err != nil {
+					http.Error(w, param+" must be a month as YYYY-MM", http.StatusBadRequest)
+					return
+				}
+				*month = parsed
+			}
+		}
+		if to.Before(from) {
+			http.Error(w, "from must not be after to", http.StatusBadRequest)
+			return
+		}
+		if from.AddDate(0, revenueMaxMonths, 0).Before(to) {
+			http.Error(w, "range is limited to "+strconv.Itoa(revenueMaxMonths)+" months", http.StatusBadRequest)
+			return
+		}
+
+		months, err := store.ListRevenueMonths(r.Context(), from, to)
+		if err != nil {
+			log.Printf("AdminRevenue: %v", err)
+			http.Error(w, "failed to compute revenue", http.StatusInternalServerError)
+			return
+		}
+
+		if r.URL.Query().Get("format") == "csv" {
+			writeRevenueCSV(w, months, from, to)
+			return
+		}
+
+		body := map[string]any{"months": months}
+		if len(months) > 0 {
+			last := months[len(months)-1]
+			body["mrr"] = last.MRR
+			body["arr"] = last.ARR
+		}
+		writeJSON(w, http.StatusOK, body)
+	}
+}
+
+func writeRevenueCSV(w http.ResponseWriter, months []models.RevenueMonth, from, to time.Time) {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="revenue-`+from.Format("2006-01")+`-to-`+to.Format("2006-01")+`.csv"`)
+
+	out := csv.NewWriter(w)
+	out.Write([]string{"month", "mrr", "arr", "new_mrr", "churned_mrr", "net_new_mrr", "active_subscriptions", "collected"})
+	for _, m := range months {
+		out.Write([]string{
+			m.Month.Format("2006-01"),
+			strconv.FormatInt(m.MRR, 10),
+			strconv.FormatInt(m.ARR, 10),
+			strconv.FormatInt(m.NewMRR, 10),
+			strconv.FormatInt(m.ChurnedMRR, 10),
+			strconv.FormatInt(m.NetNewMRR, 10),
+			strconv.Itoa(m.ActiveSubscriptions),
+			strconv.FormatInt(m.Collected, 10),
+		})
+	}
+	out.Flush()
+	if err := out.Error(); err != nil {
+		log.Printf("AdminRevenue: write CSV: %v", err)
+	}
+}
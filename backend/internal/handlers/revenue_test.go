@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+)
+
+type fakeRevenueStore struct {
+	from, to time.Time
+}
+
+func (f *fakeRevenueStore) ListRevenueMonths(ctx context.Context, from, to time.Time) ([]models.RevenueMonth, error) {
+	f.from, f.to = from, to
+	return []models.RevenueMonth{
+		{Month: from, MRR: 2000, ARR: 24000, NewMRR: 2000, NetNewMRR: 2000, ActiveSubscriptions: 1, Collected: 2000},
+		{Month: to, MRR: 4900, ARR: 58800, NewMRR: 3900, ChurnedMRR: 1000, NetNewMRR: 2900, ActiveSubscriptions: 2, Collected: 5900},
+	}, nil
+}
+
+func TestAdminRevenueCSVExport(t *testing.T) {
+	store := &fakeRevenueStore{}
+	rec := httptest.NewRecorder()
+	AdminRevenue(store)(rec, httptest.NewRequest(http.MethodGet, "/api/admin/revenue?from=2026-08&to=2026-09&format=csv", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if !store.from.Equal(time.Date(2026, 8, 1, 0, 0, 0, 0, time.UTC)) || !store.to.Equal(time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected range %v - %v", store.from, store.to)
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/csv") {
+		t.Fatalf("expected CSV content type, got %q", ct)
+	}
+
+	lines := strings.Split(strings.TrimSpace(rec.Body.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected header and 2 rows, got %q", rec.Body.String())
+	}
+	if lines[0] != "month,mrr,arr,new_mrr,churned_mrr,net_new_mrr,active_subscriptions,collected" {
+		t.Fatalf("unexpected header %q", lines[0])
+	}
+	if lines[2] != "2026-09,4900,58800,3900,1000,2900,2,5900" {
+		t.Fatalf("unexpected row %q", lines[2])
+	}
+}
+
+func TestAdminRevenueRejectsInvertedRange(t *testing.T) {
+	rec := httptest.NewRecorder()
+	AdminRevenue(&fakeRevenueStore{})(rec, httptest.NewRequest(http.MethodGet, "/api/admin/revenue?from=2026-09&to=2026-01", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/authtoken"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/middleware"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/session"
+	storepkg "github.com/PortNumber53/mcp-jira-thing/backend/internal/store"
+)
+
+// UserAccessStore looks up the role of the caller of an admin endpoint.
+type UserAccessStore interface {
+	GetUserAccess(ctx context.Context, email string) (*models.UserAccess, error)
+	GetUserAccessByID(ctx context.Context, userID int64) (*models.UserAccess, error)
+}
+
+type operatorKey struct{}
+
+// operatorFromContext returns the user RequirePermission let through, nil
+// for signed service requests.
+func operatorFromContext(ctx context.Context) *models.UserAccess {
+	access, _ := ctx.Value(operatorKey{}).(*models.UserAccess)
+	return access
+}
+
+// signedInEmail returns the email of the caller's session or bearer token.
+// Unlike requestEmail there is no fallback to an email in the request.
+func signedInEmail(r *http.Request, cookieSecret string) string {
+	if claims, ok := authtoken.FromContext(r.Context()); ok {
+		return strings.TrimSpace(claims.Email)
+	}
+	if sess, err := session.ReadSession(r, cookieSecret); err == nil && sess.Email != nil {
+		return strings.TrimSpace(*sess.Email)
+	}
+	return ""
+}
+
+// RequirePermission restricts routes to callers whose role grants perm. The
+// caller is identified by their session or bearer token, which needs a
+// verified email, or by the mcp_secret the MCP auth middleware resolved.
+// Users listed in adminEmails (ADMIN_EMAILS) count as admins. Requests
+// signed with a worker key pass, since only operator tooling holds one, as
+// do requests with an API key one of whose scopes grants perm; other keys
+// leave the caller to be identified as a user.
+// Anonymous callers get 401 and everyone else without the permission 403.
+func RequirePermission(store UserAccessStore, cookieSecret string, adminEmails []string, perm models.Permission) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if middleware.IsSignedRequest(r.Context()) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if key := apiKeyFromContext(r.Context()); key != nil && key.Can(perm) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			var (
+				access *models.UserAccess
+				err    error
+			)
+			email := signedInEmail(r, cookieSecret)
+			userID, _ := r.Context().Value("user_id").(int64)
+			switch {
+			case email != "":
+				access, err = store.GetUserAccess(r.Context(), email)
+			case userID > 0:
+				access, err = store.GetUserAccessByID(r.Context(), userID)
+			default:
+				http.Error(w, "sign in required", http.StatusUnauthorized)
+				return
+			}
+			if errors.Is(err, storepkg.ErrUserNotFound) {
+				http.Error(w, "permission denied", http.StatusForbidden)
+				return
+			}
+			if err != nil {
+				log.Printf("RequirePermission: failed to look up caller email=%s user_id=%d: %v", email, userID, err)
+				http.Error(w, "failed to check permissions", http.StatusBadGateway)
+				return
+			}
+
+			role := access.Role
+			for _, admin := range adminEmails {
+				if strings.EqualFold(admin, access.Email) {
+					role = models.RoleAdmin
+				}
+			}
+			if !models.RoleCan(role, perm) {
+				http.Error(w, "permission denied", http.StatusForbidden)
+				return
+			}
+			if email != "" && !access.EmailVerified {
+				http.Error(w, "this endpoint requires a verified email", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), operatorKey{}, access)))
+		})
+	}
+}
+
+// UserRoleStore reads and changes user roles.
+type UserRoleStore interface {
+	GetUserAccess(ctx context.Context, email string) (*models.UserAccess, error)
+	SetUserRole(ctx context.Context, email, role string, actorID int64) error
+}
+
+type userRolePayload struct {
+	Email string `json:"email"`
+	Role  string `json:"role"`
+}
+
+// AdminUserRole reads or changes a user's role. It must be mounted behind
+// RequirePermission with models.PermissionManageRoles.
+// GET ?email=user@example.com
+// PUT {"email": "user@example.com", "role": "support"}
+// Operators cannot change their own role, so an admin cannot lock the
+// deployment out by demoting themselves.
+func AdminUserRole(store UserRoleStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			email := strings.TrimSpace(r.URL.Query().Get("email"))
+			if email == "" {
+				http.Error(w, "email is required", http.StatusBadRequest)
+				return
+			}
+			writeUserRole(w, store, r, email)
+
+		case http.MethodPut:
+			var payload userRolePayload
+			if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+				http.Error(w, "invalid JSON payload", http.StatusBadRequest)
+				return
+			}
+			payload.Email = strings.TrimSpace(payload.Email)
+			if payload.Email == "" {
+				http.Error(w, "email is required", http.StatusBadRequest)
+				return
+			}
+			if !models.ValidRole(payload.Role) {
+				http.Error(w, "role must be one of user, support or admin", http.StatusBadRequest)
+				return
+			}
+
+			var actorID int64
+			if operator := operatorFromContext(r.Context()); operator != nil {
+				if strings.EqualFold(operator.Email, payload.Email) {
+					http.Error(w, "you cannot change your own role", http.StatusForbidden)
+					return
+				}
+				actorID = operator.UserID
+			}
+
+			err := store.SetUserRole(r.Context(), payload.Email, payload.Role, actorID)
+			if errors.Is(err, storepkg.ErrUserNotFound) {
+				http.Error(w, "user not found", http.StatusNotFound)
+				return
+			}
+			if err != nil {
+				log.Printf("AdminUserRole: failed to set role for email=%s: %v", payload.Email, err)
+				http.Error(w, "failed to set role", http.StatusBadGateway)
+				return
+			}
+			writeUserRole(w, store, r, payload.Email)
+
+		default:
+			w.Header().Set("Allow", "GET, PUT")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+func writeUserRole(w http.ResponseWriter, store UserRoleStore, r *http.Request, email string) {
+	access, err := store.GetUserAccess(r.Context(), email)
+	if errors.Is(err, storepkg.ErrUserNotFound) {
+		http.Error(w, "user not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("AdminUserRole: failed to load role for email=%s: %v", email, err)
+		http.Error(w, "failed to load role", http.StatusBadGateway)
+		return
+	}
+	permissions := append([]models.Permission{}, models.RolePermissions[access.Role]...)
+	writeJSON(w, http.StatusOK, map[string]any{
+		"email":       access.Email,
+		"role":        access.Role,
+		"permissions": permissions,
+	})
+}
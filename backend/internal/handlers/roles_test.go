@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+	storepkg "github.com/PortNumber53/mcp-jira-thing/backend/internal/store"
+)
+
+type fakeUserAccessStore map[string]*models.UserAccess
+
+func (f fakeUserAccessStore) GetUserAccess(ctx context.Context, email string) (*models.UserAccess, error) {
+	if access, ok := f[strings.ToLower(email)]; ok {
+		copied := *access
+		return &copied, nil
+	}
+	return nil, storepkg.ErrUserNotFound
+}
+
+func (f fakeUserAccessStore) GetUserAccessByID(ctx context.Context, userID int64) (*models.UserAccess, error) {
+	for _, access := range f {
+		if access.UserID == userID {
+			copied := *access
+			return &copied, nil
+		}
+	}
+	return nil, storepkg.ErrUserNotFound
+}
+
+func (f fakeUserAccessStore) SetUserRole(ctx context.Context, email, role string, actorID int64) error {
+	access, ok := f[strings.ToLower(email)]
+	if !ok {
+		return storepkg.ErrUserNotFound
+	}
+	access.Role = role
+	return nil
+}
+
+func TestRequirePermissionChecksRoles(t *testing.T) {
+	users := fakeUserAccessStore{
+		"user@example.com":  {UserID: 1, Email: "user@example.com", Role: models.RoleSupport, EmailVerified: true},
+		"admin@example.com": {UserID: 2, Email: "admin@example.com", Role: models.RoleAdmin, EmailVerified: true},
+	}
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) })
+	call := func(perm models.Permission, userID int64, cookie bool) int {
+		req := httptest.NewRequest(http.MethodGet, "/api/metrics/all", nil)
+		if cookie {
+			req.AddCookie(sessionCookie(t, "secret", "sid"))
+		}
+		if userID > 0 {
+			req = req.WithContext(context.WithValue(req.Context(), "user_id", userID))
+		}
+		rec := httptest.NewRecorder()
+		RequirePermission(users, "secret", nil, perm)(ok).ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	for _, tc := range []struct {
+		name   string
+		perm   models.Permission
+		userID int64
+		cookie bool
+		want   int
+	}{
+		{"anonymous", models.PermissionViewAllMetrics, 0, false, http.StatusUnauthorized},
+		{"support reads metrics", models.PermissionViewAllMetrics, 0, true, http.StatusNoContent},
+		{"support cannot manage jobs", models.PermissionManageJobs, 0, true, http.StatusForbidden},
+		{"admin by mcp secret", models.PermissionManageJobs, 2, false, http.StatusNoContent},
+		{"unknown mcp secret user", models.PermissionViewJobs, 9, false, http.StatusForbidden},
+	} {
+		if got := call(tc.perm, tc.userID, tc.cookie); got != tc.want {
+			t.Errorf("%s: expected %d, got %d", tc.name, tc.want, got)
+		}
+	}
+}
+
+func TestAdminUserRole(t *testing.T) {
+	users := fakeUserAccessStore{
+		"user@example.com": {UserID: 1, Email: "user@example.com", Role: models.RoleUser, EmailVerified: true},
+		"ops@example.com":  {UserID: 2, Email: "ops@example.com", Role: models.RoleUser, EmailVerified: true},
+	}
+	// user@example.com is the session's email and an admin through ADMIN_EMAILS.
+	handler := RequirePermission(users, "secret", []string{"user@example.com"}, models.PermissionManageRoles)(AdminUserRole(users))
+	put := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/api/admin/users/role", strings.NewReader(body))
+		req.AddCookie(sessionCookie(t, "secret", "sid"))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := put(`{"email":"ops@example.com","role":"support"}`)
+	if rec.Code != http.StatusOK || users["ops@example.com"].Role != models.RoleSupport {
+		t.Fatalf("expected the role to change, got %d %s", rec.Code, rec.Body.String())
+	}
+	if !strings.Contains(rec.Body.String(), `"jobs:read"`) {
+		t.Fatalf("expected the role's permissions, got %s", rec.Body.String())
+	}
+	if rec := put(`{"email":"ops@example.com","role":"root"}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("unknown role: expected 400, got %d", rec.Code)
+	}
+	if rec := put(`{"email":"user@example.com","role":"user"}`); rec.Code != http.StatusForbidden {
+		t.Fatalf("own role: expected 403, got %d", rec.Code)
+	}
+	if rec := put(`{"email":"nobody@example.com","role":"admin"}`); rec.Code != http.StatusNotFound {
+		t.Fatalf("unknown user: expected 404, got %d", rec.Code)
+	}
+}
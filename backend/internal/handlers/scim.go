@@ -0,0 +1,506 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+	storepkg "github.com/PortNumber53/mcp-jira-thing/backend/internal/store"
+)
+
+const (
+	scimTokenPrefix = "scim_"
+
+	scimSchemaUser         = "urn:ietf:params:scim:schemas:core:2.0:User"
+	scimSchemaListResponse = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	scimSchemaError        = "urn:ietf:params:scim:api:messages:2.0:Error"
+	scimSchemaSPConfig     = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+
+	scimDefaultCount = 100
+	scimMaxCount     = 200
+)
+
+var scimFilterPattern = regexp.MustCompile(`(?i)^\s*([a-z]+)\s+eq\s+"((?:[^"\\]|\\.)*)"\s*$`)
+
+// SCIMStore defines the behaviour required to serve SCIM provisioning for an
+// organization.
+type SCIMStore interface {
+	GetOrganizationIDBySCIMToken(ctx context.Context, tokenHash string) (int64, error)
+	ListOrganizationMembers(ctx context.Context, orgID int64, filterAttr, filterValue string, offset, limit int) ([]models.OrganizationMember, int, error)
+	GetOrganizationMember(ctx context.Context, orgID, userID int64) (*models.OrganizationMember, error)
+	CreateSCIMMember(ctx context.Context, orgID int64, email string, name, externalID *string, active bool) (*models.OrganizationMember, error)
+	UpdateOrganizationMember(ctx context.Context, orgID, userID int64, update models.OrganizationMemberUpdate) (*models.OrganizationMember, error)
+	RemoveOrganizationMember(ctx context.Context, orgID, userID int64) error
+}
+
+type scimName struct {
+	Formatted  string `json:"formatted,omitempty"`
+	GivenName  string `json:"givenName,omitempty"`
+	FamilyName string `json:"familyName,omitempty"`
+}
+
+type scimEmail struct {
+	Value   string `json:"value"`
+	Type    string `json:"type,omitempty"`
+	Primary bool   `json:"primary,omitempty"`
+}
+
+type scimMeta struct {
+	ResourceType string `json:"resourceType"`
+	Created      string `json:"created"`
+	LastModified string `json:"lastModified"`
+	Location     string `json:"location"`
+}
+
+// scimUser is the SCIM core User resource. userName is the member's email.
+type scimUser struct {
+	Schemas     []string    `json:"schemas"`
+	ID          string      `json:"id,omitempty"`
+	ExternalID  *string     `json:"externalId,omitempty"`
+	UserName    string      `json:"userName"`
+	Name        *scimName   `json:"name,omitempty"`
+	DisplayName string      `json:"displayName,omitempty"`
+	Emails      []scimEmail `json:"emails,omitempty"`
+	Active      *bool       `json:"active,omitempty"`
+	Meta        *scimMeta   `json:"meta,omitempty"`
+}
+
+type scimPatchOp struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value"`
+}
+
+type scimPatchRequest struct {
+	Schemas    []string      `json:"schemas"`
+	Operations []scimPatchOp `json:"Operations"`
+}
+
+// SCIMUsers lists or provisions the members of the organization owning the
+// bearer token.
+// GET  ?filter=userName eq "jane@acme.com"&startIndex=1&count=100
+// POST {"schemas": [...], "userName": "jane@acme.com", "externalId": "00u1", "active": true}
+func SCIMUsers(store SCIMStore, backendURL string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		orgID, ok := scimOrganization(w, r, store)
+		if !ok {
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			attr, value, err := parseSCIMFilter(r.URL.Query().Get("filter"))
+			if err != nil {
+				writeSCIMError(w, http.StatusBadRequest, "invalidFilter", err.Error())
+				return
+			}
+
+			startIndex := 1
+			if v, err := strconv.Atoi(r.URL.Query().Get("startIndex")); err == nil && v > 1 {
+				startIndex = v
+			}
+			count := scimDefaultCount
+			if v, err := strconv.Atoi(r.URL.Query().Get("count")); err == nil && v >= 0 {
+				count = min(v, scimMaxCount)
+			}
+
+			members, total, err := store.ListOrganizationMembers(r.Context(), orgID, attr, value, startIndex-1, count)
+			if err != nil {
+				log.Printf("SCIMUsers: failed to list members for org=%d: %v", orgID, err)
+				writeSCIMError(w, http.StatusInternalServerError, "", "failed to list users")
+				return
+			}
+
+			resources := make([]scimUser, 0, len(members))
+			for _, m := range members {
+				resources = append(resources, scimUserFromMember(m, backendURL))
+			}
+
+			writeSCIM(w, http.StatusOK, map[string]any{
+				"schemas":      []string{scimSchemaListResponse},
+				"totalResults": total,
+				"startIndex":   startIndex,
+				"itemsPerPage": len(resources),
+				"Resources":    resources,
+			})
+
+		case http.MethodPost:
+			var payload scimUser
+			if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+				writeSCIMError(w, http.StatusBadRequest, "invalidSyntax", "invalid JSON payload")
+				return
+			}
+
+			email := scimUserEmail(payload)
+			if email == "" {
+				writeSCIMError(w, http.StatusBadRequest, "invalidValue", "userName must be an email address")
+				return
+			}
+			active := payload.Active == nil || *payload.Active
+
+			member, err := store.CreateSCIMMember(r.Context(), orgID, email, scimUserName(payload), payload.ExternalID, active)
+			if err != nil {
+				writeSCIMStoreError(w, "SCIMUsers", orgID, err)
+				return
+			}
+
+			writeSCIM(w, http.StatusCreated, scimUserFromMember(*member, backendURL))
+
+		default:
+			w.Header().Set("Allow", "GET, POST")
+			writeSCIMError(w, http.StatusMethodNotAllowed, "", "method not allowed")
+		}
+	}
+}
+
+// SCIMUser reads, replaces, patches or deprovisions the member in the {id}
+// URL parameter. PATCH supports replacing active, externalId and the name.
+// GET
+// PUT    {"schemas": [...], "userName": "jane@acme.com", "active": false}
+// PATCH  {"schemas": [...], "Operations": [{"op": "replace", "path": "active", "value": false}]}
+// DELETE
+func SCIMUser(store SCIMStore, backendURL string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		orgID, ok := scimOrganization(w, r, store)
+		if !ok {
+			return
+		}
+
+		userID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			writeSCIMError(w, http.StatusNotFound, "", "user not found")
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			member, err := store.GetOrganizationMember(r.Context(), orgID, userID)
+			if err != nil {
+				writeSCIMStoreError(w, "SCIMUser", orgID, err)
+				return
+			}
+
+			writeSCIM(w, http.StatusOK, scimUserFromMember(*member, backendURL))
+
+		case http.MethodPut:
+			var payload scimUser
+			if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+				writeSCIMError(w, http.StatusBadRequest, "invalidSyntax", "invalid JSON payload")
+				return
+			}
+
+			current, err := store.GetOrganizationMember(r.Context(), orgID, userID)
+			if err != nil {
+				writeSCIMStoreError(w, "SCIMUser", orgID, err)
+				return
+			}
+			if email := scimUserEmail(payload); email != "" && !strings.EqualFold(email, current.Email) {
+				writeSCIMError(w, http.StatusBadRequest, "mutability", "userName cannot be changed")
+				return
+			}
+
+			member, err := store.UpdateOrganizationMember(r.Context(), orgID, userID, models.OrganizationMemberUpdate{
+				Active:     payload.Active,
+				ExternalID: payload.ExternalID,
+				Name:       scimUserName(payload),
+			})
+			if err != nil {
+				writeSCIMStoreError(w, "SCIMUser", orgID, err)
+				return
+			}
+
+			writeSCIM(w, http.StatusOK, scimUserFromMember(*member, backendURL))
+
+		case http.MethodPatch:
+			var payload scimPatchRequest
+			if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+				writeSCIMError(w, http.StatusBadRequest, "invalidSyntax", "invalid JSON payload")
+				return
+			}
+
+			update, err := parseSCIMPatch(payload.Operations)
+			if err != nil {
+				writeSCIMError(w, http.StatusBadRequest, "invalidValue", err.Error())
+				return
+			}
+
+			member, err := store.UpdateOrganizationMember(r.Context(), orgID, userID, update)
+			if err != nil {
+				writeSCIMStoreError(w, "SCIMUser", orgID, err)
+				return
+			}
+
+			writeSCIM(w, http.StatusOK, scimUserFromMember(*member, backendURL))
+
+		case http.MethodDelete:
+			if err := store.RemoveOrganizationMember(r.Context(), orgID, userID); err != nil {
+				writeSCIMStoreError(w, "SCIMUser", orgID, err)
+				return
+			}
+
+			w.WriteHeader(http.StatusNoContent)
+
+		default:
+			w.Header().Set("Allow", "GET, PUT, PATCH, DELETE")
+			writeSCIMError(w, http.StatusMethodNotAllowed, "", "method not allowed")
+		}
+	}
+}
+
+// SCIMServiceProviderConfig describes the SCIM features this server
+// supports.
+func SCIMServiceProviderConfig() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeSCIM(w, http.StatusOK, map[string]any{
+			"schemas":        []string{scimSchemaSPConfig},
+			"patch":          map[string]bool{"supported": true},
+			"bulk":           map[string]any{"supported": false, "maxOperations": 0, "maxPayloadSize": 0},
+			"filter":         map[string]any{"supported": true, "maxResults": scimMaxCount},
+			"changePassword": map[string]bool{"supported": false},
+			"sort":           map[string]bool{"supported": false},
+			"etag":           map[string]bool{"supported": false},
+			"authenticationSchemes": []map[string]any{{
+				"type":        "oauthbearertoken",
+				"name":        "Bearer token",
+				"description": "Organization SCIM token",
+				"primary":     true,
+			}},
+		})
+	}
+}
+
+// scimOrganization authenticates the SCIM bearer token and returns the
+// organization it belongs to, writing a SCIM error when it is invalid.
+func scimOrganization(w http.ResponseWriter, r *http.Request, store SCIMStore) (int64, bool) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	token = strings.TrimSpace(token)
+	if !ok || !strings.HasPrefix(token, scimTokenPrefix) {
+		writeSCIMError(w, http.StatusUnauthorized, "", "bearer token required")
+		return 0, false
+	}
+
+	orgID, err := store.GetOrganizationIDBySCIMToken(r.Context(), hashSCIMToken(token))
+	if errors.Is(err, storepkg.ErrSCIMTokenNotFound) {
+		writeSCIMError(w, http.StatusUnauthorized, "", "invalid bearer token")
+		return 0, false
+	}
+	if err != nil {
+		log.Printf("scimOrganization: failed to verify token: %v", err)
+		writeSCIMError(w, http.StatusInternalServerError, "", "failed to verify token")
+		return 0, false
+	}
+
+	return orgID, true
+}
+
+// hashSCIMToken returns the stored form of a SCIM bearer token.
+func hashSCIMToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// parseSCIMFilter parses the single `attr eq "value"` filter SCIM clients
+// use to look users up before provisioning them.
+func parseSCIMFilter(filter string) (attr, value string, err error) {
+	if strings.TrimSpace(filter) == "" {
+		return "", "", nil
+	}
+
+	m := scimFilterPattern.FindStringSubmatch(filter)
+	if m == nil {
+		return "", "", fmt.Errorf("only `attribute eq \"value\"` filters are supported")
+	}
+	if err := json.Unmarshal([]byte(`"`+m[2]+`"`), &value); err != nil {
+		return "", "", fmt.Errorf("invalid filter value")
+	}
+
+	switch strings.ToLower(m[1]) {
+	case "username":
+		return "userName", value, nil
+	case "externalid":
+		return "externalId", value, nil
+	default:
+		return "", "", fmt.Errorf("filtering on %s is not supported", m[1])
+	}
+}
+
+// parseSCIMPatch turns PatchOp operations into a member update. Operations
+// may name the attribute in path or, without a path, pass a map of
+// attributes as the value.
+func parseSCIMPatch(ops []scimPatchOp) (models.OrganizationMemberUpdate, error) {
+	var update models.OrganizationMemberUpdate
+
+	for _, op := range ops {
+		switch strings.ToLower(op.Op) {
+		case "add", "replace":
+		default:
+			return update, fmt.Errorf("unsupported patch op %q", op.Op)
+		}
+
+		if op.Path != "" {
+			if err := applySCIMPatchValue(&update, op.Path, op.Value); err != nil {
+				return update, err
+			}
+			continue
+		}
+
+		var values map[string]json.RawMessage
+		if err := json.Unmarshal(op.Value, &values); err != nil {
+			return update, fmt.Errorf("patch value must be an object when path is omitted")
+		}
+		for path, value := range values {
+			if err := applySCIMPatchValue(&update, path, value); err != nil {
+				return update, err
+			}
+		}
+	}
+
+	return update, nil
+}
+
+func applySCIMPatchValue(update *models.OrganizationMemberUpdate, path string, value json.RawMessage) error {
+	switch strings.ToLower(path) {
+	case "active":
+		var active bool
+		if err := json.Unmarshal(value, &active); err != nil {
+			// Some identity providers send booleans as strings.
+			var s string
+			if json.Unmarshal(value, &s) != nil {
+				return fmt.Errorf("active must be a boolean")
+			}
+			if active, err = strconv.ParseBool(s); err != nil {
+				return fmt.Errorf("active must be a boolean")
+			}
+		}
+		update.Active = &active
+	case "externalid":
+		var externalID string
+		if err := json.Unmarshal(value, &externalID); err != nil {
+			return fmt.Errorf("externalId must be a string")
+		}
+		update.ExternalID = &externalID
+	case "displayname", "name.formatted":
+		var name string
+		if err := json.Unmarshal(value, &name); err != nil {
+			return fmt.Errorf("%s must be a string", path)
+		}
+		update.Name = &name
+	case "name":
+		var name scimName
+		if err := json.Unmarshal(value, &name); err != nil {
+			return fmt.Errorf("name must be an object")
+		}
+		update.Name = scimUserName(scimUser{Name: &name})
+	case "name.givenname", "name.familyname", "emails", "title", "locale", "timezone", "preferredlanguage":
+		// Accepted but not stored.
+	default:
+		return fmt.Errorf("patching %s is not supported", path)
+	}
+	return nil
+}
+
+// scimUserEmail returns the email from userName or, failing that, the
+// primary email.
+func scimUserEmail(u scimUser) string {
+	if strings.Contains(u.UserName, "@") {
+		return strings.ToLower(strings.TrimSpace(u.UserName))
+	}
+	for _, e := range u.Emails {
+		if e.Primary && strings.Contains(e.Value, "@") {
+			return strings.ToLower(strings.TrimSpace(e.Value))
+		}
+	}
+	return ""
+}
+
+// scimUserName returns the display name from displayName, name.formatted
+// or the given and family names, or nil when none is set.
+func scimUserName(u scimUser) *string {
+	name := strings.TrimSpace(u.DisplayName)
+	if name == "" && u.Name != nil {
+		name = strings.TrimSpace(u.Name.Formatted)
+		if name == "" {
+			name = strings.TrimSpace(u.Name.GivenName + " " + u.Name.FamilyName)
+		}
+	}
+	if name == "" {
+		return nil
+	}
+	return &name
+}
+
+func scimUserFromMember(m models.OrganizationMember, backendURL string) scimUser {
+	id := strconv.FormatInt(m.UserID, 10)
+	active := m.Active
+	u := scimUser{
+		Schemas:    []string{scimSchemaUser},
+		ID:         id,
+		ExternalID: m.ExternalID,
+		UserName:   m.Email,
+		Emails:     []scimEmail{{Value: m.Email, Type: "work", Primary: true}},
+		Active:     &active,
+		Meta: &scimMeta{
+			ResourceType: "User",
+			Created:      m.CreatedAt.UTC().Format("2006-01-02T15:04:05Z"),
+			LastModified: m.UpdatedAt.UTC().Format("2006-01-02T15:04:05Z"),
+			Location:     backendURL + "/scim/v2/Users/" + id,
+		},
+	}
+	if m.Name != nil && *m.Name != "" {
+		u.DisplayName = *m.Name
+		u.Name = &scimName{Formatted: *m.Name}
+	}
+	return u
+}
+
+// writeSCIMStoreError maps store errors onto SCIM error responses.
+func writeSCIMStoreError(w http.ResponseWriter, handler string, orgID int64, err error) {
+	switch {
+	case errors.Is(err, storepkg.ErrOrganizationMemberNotFound):
+		writeSCIMError(w, http.StatusNotFound, "", "user not found")
+	case errors.Is(err, storepkg.ErrOrganizationMemberExists):
+		writeSCIMError(w, http.StatusConflict, "uniqueness", "user is already provisioned")
+	case errors.Is(err, storepkg.ErrSSOAccountConflict):
+		writeSCIMError(w, http.StatusConflict, "uniqueness", "an account with this email exists outside the organization")
+	case errors.Is(err, storepkg.ErrAccountDeleted):
+		writeSCIMError(w, http.StatusConflict, "uniqueness", "an account with this email was deleted")
+	case errors.Is(err, storepkg.ErrSCIMExternalIDTaken):
+		writeSCIMError(w, http.StatusConflict, "uniqueness", "externalId is already assigned")
+	case errors.Is(err, storepkg.ErrSSODomainNotAllowed):
+		writeSCIMError(w, http.StatusBadRequest, "invalidValue", "email domain is not claimed by the organization")
+	default:
+		log.Printf("%s: store error for org=%d: %v", handler, orgID, err)
+		writeSCIMError(w, http.StatusInternalServerError, "", "internal error")
+	}
+}
+
+func writeSCIMError(w http.ResponseWriter, status int, scimType, detail string) {
+	body := map[string]any{
+		"schemas": []string{scimSchemaError},
+		"status":  strconv.Itoa(status),
+		"detail":  detail,
+	}
+	if scimType != "" {
+		body["scimType"] = scimType
+	}
+	writeSCIM(w, status, body)
+}
+
+func writeSCIM(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/scim+json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.Printf("writeSCIM: failed to encode response: %v", err)
+	}
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseSCIMFilter(t *testing.T) {
+	attr, value, err := parseSCIMFilter(`userName eq "Jane@Acme.com"`)
+	if err != nil || attr != "userName" || value != "Jane@Acme.com" {
+		t.Fatalf("got attr=%q value=%q err=%v", attr, value, err)
+	}
+
+	attr, value, err = parseSCIMFilter(`externalid EQ "00u\"1"`)
+	if err != nil || attr != "externalId" || value != `00u"1` {
+		t.Fatalf("got attr=%q value=%q err=%v", attr, value, err)
+	}
+
+	if attr, _, err := parseSCIMFilter(""); err != nil || attr != "" {
+		t.Fatalf("expected empty filter to match everything, got attr=%q err=%v", attr, err)
+	}
+	for _, filter := range []string{`title eq "x"`, `userName co "jane"`, `userName eq "a" and active eq true`} {
+		if _, _, err := parseSCIMFilter(filter); err == nil {
+			t.Fatalf("expected %q to be rejected", filter)
+		}
+	}
+}
+
+func TestParseSCIMPatch(t *testing.T) {
+	var req scimPatchRequest
+	body := `{"Operations": [
+		{"op": "Replace", "path": "active", "value": "False"},
+		{"op": "replace", "value": {"externalId": "00u1", "displayName": "Jane Doe"}}
+	]}`
+	if err := json.Unmarshal([]byte(body), &req); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+
+	update, err := parseSCIMPatch(req.Operations)
+	if err != nil {
+		t.Fatalf("parseSCIMPatch: %v", err)
+	}
+	if update.Active == nil || *update.Active {
+		t.Fatalf("expected active=false, got %v", update.Active)
+	}
+	if update.ExternalID == nil || *update.ExternalID != "00u1" {
+		t.Fatalf("unexpected externalId %v", update.ExternalID)
+	}
+	if update.Name == nil || *update.Name != "Jane Doe" {
+		t.Fatalf("unexpected name %v", update.Name)
+	}
+
+	for _, ops := range []string{
+		`[{"op": "remove", "path": "active"}]`,
+		`[{"op": "replace", "path": "userName", "value": "ceo@acme.com"}]`,
+		`[{"op": "replace", "path": "active", "value": "maybe"}]`,
+	} {
+		var bad []scimPatchOp
+		if err := json.Unmarshal([]byte(ops), &bad); err != nil {
+			t.Fatalf("unmarshal: %v", err)
+		}
+		if _, err := parseSCIMPatch(bad); err == nil {
+			t.Fatalf("expected %s to be rejected", ops)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/authtoken"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/config"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/session"
+	storepkg "github.com/PortNumber53/mcp-jira-thing/backend/internal/store"
+)
+
+// SessionValidator reports whether a server-side session is still active.
+type SessionValidator interface {
+	SessionActive(ctx context.Context, id string) (bool, error)
+}
+
+// UserSessionStore defines the behaviour required to list and revoke a
+// user's sessions.
+type UserSessionStore interface {
+	ListUserSessions(ctx context.Context, email string) ([]models.UserSession, error)
+	RevokeUserSession(ctx context.Context, email, id string) error
+	RevokeOtherUserSessions(ctx context.Context, email, keepID string) (int64, error)
+}
+
+// oauthSessionCreator records sessions for accounts identified by their
+// OAuth provider identity.
+type oauthSessionCreator interface {
+	CreateOAuthUserSession(ctx context.Context, provider, providerAccountID, ipAddress, userAgent string, ttl time.Duration) (string, error)
+}
+
+// ActiveSession drops the session cookie from requests whose session has
+// been revoked or has expired server-side, and tells the browser to forget
+// it, so downstream handlers treat the request as signed out. Lookups that
+// fail leave the request untouched.
+func ActiveSession(cfg config.Config, store SessionValidator) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			sess, err := session.ReadSession(r, cfg.CookieSecret)
+			if err != nil || sess.SessionID == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			active, err := store.SessionActive(r.Context(), sess.SessionID)
+			if err != nil {
+				log.Printf("ActiveSession: failed to check session: %v", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+			if !active {
+				session.ClearCookie(w, session.SessionCookie, cfg.CookieDomain, strings.HasPrefix(cfg.FrontendURL, "https"))
+				r = withoutCookie(r, session.SessionCookie)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// withoutCookie returns a shallow copy of r with the named cookie removed.
+func withoutCookie(r *http.Request, name string) *http.Request {
+	r = r.Clone(r.Context())
+	cookies := r.Cookies()
+	r.Header.Del("Cookie")
+	for _, c := range cookies {
+		if c.Name != name {
+			r.AddCookie(c)
+		}
+	}
+	return r
+}
+
+// currentSessionID returns the caller's session id from their auth token or
+// session cookie or, for requests proxied by the frontend Worker without a
+// token, the current query parameter.
+func currentSessionID(r *http.Request, cookieSecret string) string {
+	if claims, ok := authtoken.FromContext(r.Context()); ok && claims.SessionID != "" {
+		return claims.SessionID
+	}
+	if sess, err := session.ReadSession(r, cookieSecret); err == nil && sess.SessionID != "" {
+		return sess.SessionID
+	}
+	return strings.TrimSpace(r.URL.Query().Get("current"))
+}
+
+// UserSessions lists the caller's active sessions, or with DELETE signs out
+// every session except the current one ("sign out everywhere").
+// GET ?email=...&current=<sid>
+// DELETE ?email=...&current=<sid>
+func UserSessions(store UserSessionStore, cookieSecret string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		email := requestEmail(r, cookieSecret, "")
+		if email == "" {
+			http.Error(w, "email query parameter is required", http.StatusBadRequest)
+			return
+		}
+		current := currentSessionID(r, cookieSecret)
+
+		switch r.Method {
+		case http.MethodGet:
+			sessions, err := store.ListUserSessions(r.Context(), email)
+			if err != nil {
+				log.Printf("UserSessions: failed to list sessions for email=%s: %v", email, err)
+				http.Error(w, "failed to load sessions", http.StatusBadGateway)
+				return
+			}
+			if sessions == nil {
+				sessions = []models.UserSession{}
+			}
+			for i := range sessions {
+				sessions[i].Current = sessions[i].ID == current
+			}
+			writeJSON(w, http.StatusOK, map[string]any{"sessions": sessions})
+		case http.MethodDelete:
+			if current == "" {
+				http.Error(w, "current session is required", http.StatusBadRequest)
+				return
+			}
+			revoked, err := store.RevokeOtherUserSessions(r.Context(), email, current)
+			if err != nil {
+				log.Printf("UserSessions: failed to revoke sessions for email=%s: %v", email, err)
+				http.Error(w, "failed to revoke sessions", http.StatusBadGateway)
+				return
+			}
+			writeJSON(w, http.StatusOK, map[string]any{"revoked": revoked})
+		default:
+			w.Header().Set("Allow", strings.Join([]string{http.MethodGet, http.MethodDelete}, ", "))
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// RevokeUserSession signs out one of the caller's sessions.
+// DELETE /api/auth/sessions/{id}?email=...
+func RevokeUserSession(store UserSessionStore, cookieSecret string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			w.Header().Set("Allow", http.MethodDelete)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		email := requestEmail(r, cookieSecret, "")
+		if email == "" {
+			http.Error(w, "email query parameter is required", http.StatusBadRequest)
+			return
+		}
+
+		id := chi.URLParam(r, "id")
+		err := store.RevokeUserSession(r.Context(), email, id)
+		if errors.Is(err, storepkg.ErrSessionNotFound) {
+			http.Error(w, "session not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Printf("RevokeUserSession: failed to revoke session for email=%s: %v", email, err)
+			http.Error(w, "failed to revoke session", http.StatusBadGateway)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+	}
+}
+
+// startOAuthSession records a session for a login forwarded by the frontend
+// Worker and returns its id, or "" when it could not be recorded; the
+// Worker then issues a cookie that cannot be revoked early.
+func startOAuthSession(ctx context.Context, store oauthSessionCreator, provider, providerAccountID, ipAddress, userAgent string) string {
+	id, err := store.CreateOAuthUserSession(ctx, provider, providerAccountID, ipAddress, userAgent, session.SessionTTL)
+	if err != nil {
+		log.Printf("startOAuthSession: failed to record %s session: %v", provider, err)
+		return ""
+	}
+	return id
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/config"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/session"
+)
+
+type fakeSessionStore struct {
+	active  map[string]bool
+	revoked []string
+}
+
+func (f *fakeSessionStore) SessionActive(ctx context.Context, id string) (bool, error) {
+	return f.active[id], nil
+}
+
+func (f *fakeSessionStore) ListUserSessions(ctx context.Context, email string) ([]models.UserSession, error) {
+	var sessions []models.UserSession
+	for id, active := range f.active {
+		if active {
+			sessions = append(sessions, models.UserSession{ID: id, Provider: "github"})
+		}
+	}
+	return sessions, nil
+}
+
+func (f *fakeSessionStore) RevokeUserSession(ctx context.Context, email, id string) error {
+	f.revoked = append(f.revoked, id)
+	f.active[id] = false
+	return nil
+}
+
+func (f *fakeSessionStore) RevokeOtherUserSessions(ctx context.Context, email, keepID string) (int64, error) {
+	var n int64
+	for id, active := range f.active {
+		if active && id != keepID {
+			f.active[id] = false
+			n++
+		}
+	}
+	return n, nil
+}
+
+func sessionCookie(t *testing.T, secret, sid string) *http.Cookie {
+	t.Helper()
+	email := "user@example.com"
+	token, err := session.Encode(secret, session.Payload{Login: "user", Email: &email, SessionID: sid})
+	if err != nil {
+		t.Fatalf("encode session: %v", err)
+	}
+	return &http.Cookie{Name: session.SessionCookie, Value: token}
+}
+
+func TestActiveSessionSignsOutRevokedSessions(t *testing.T) {
+	cfg := config.Config{CookieSecret: "secret", FrontendURL: "https://app.example.com"}
+	store := &fakeSessionStore{active: map[string]bool{"live": true, "revoked": false}}
+	handler := ActiveSession(cfg, store)(SessionCheck(cfg))
+
+	for sid, want := range map[string]bool{"live": true, "revoked": false} {
+		req := httptest.NewRequest(http.MethodGet, "/api/auth/session", nil)
+		req.AddCookie(sessionCookie(t, cfg.CookieSecret, sid))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		var body struct {
+			Authenticated bool `json:"authenticated"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+			t.Fatalf("decode response: %v", err)
+		}
+		if body.Authenticated != want {
+			t.Fatalf("session %s: expected authenticated=%t", sid, want)
+		}
+		cleared := strings.Contains(rec.Header().Get("Set-Cookie"), session.SessionCookie+"=;")
+		if cleared == want {
+			t.Fatalf("session %s: unexpected Set-Cookie %q", sid, rec.Header().Get("Set-Cookie"))
+		}
+	}
+}
+
+func TestUserSessionsSignOutEverywhereElse(t *testing.T) {
+	store := &fakeSessionStore{active: map[string]bool{"this": true, "laptop": true, "phone": true}}
+	handler := UserSessions(store, "secret")
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/auth/sessions?email=user@example.com&current=this", nil))
+	var listed struct {
+		Sessions []models.UserSession `json:"sessions"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&listed); err != nil {
+		t.Fatalf("decode list: %v", err)
+	}
+	current := 0
+	for _, s := range listed.Sessions {
+		if s.Current {
+			current++
+		}
+	}
+	if len(listed.Sessions) != 3 || current != 1 {
+		t.Fatalf("expected three sessions with one current, got %+v", listed.Sessions)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/api/auth/sessions?email=user@example.com&current=this", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"revoked":2`) {
+		t.Fatalf("expected two sessions revoked, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if !store.active["this"] || store.active["laptop"] || store.active["phone"] {
+		t.Fatalf("expected only the current session to survive, got %v", store.active)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/api/auth/sessions?email=user@example.com", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 without a current session, got %d", rec.Code)
+	}
+}
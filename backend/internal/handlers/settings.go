@@ -3,18 +3,21 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 	"strings"
 
 	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
 	"github.com/PortNumber53/mcp-jira-thing/backend/internal/session"
+	storepkg "github.com/PortNumber53/mcp-jira-thing/backend/internal/store"
 )
 
 // UserSettingsStore defines the behaviour required from the storage client
 // backing the Jira user settings handler.
 type UserSettingsStore interface {
 	UpsertUserSettings(ctx context.Context, userEmail, baseURL, jiraEmail, apiKey string) error
+	UpsertUserSettingsIfRevision(ctx context.Context, userEmail, baseURL, jiraEmail, apiKey string, ifRevision int64) (int64, error)
 	ListUserSettings(ctx context.Context, email string) ([]models.JiraUserSettings, error)
 	GenerateMCPSecret(ctx context.Context, email string) (string, error)
 	GetMCPSecret(ctx context.Context, email string) (*string, error)
@@ -30,12 +33,15 @@ type jiraSettingsPayload struct {
 
 // UserSettings creates an HTTP handler that upserts Jira settings for a user.
 // It reads the session cookie to identify the authenticated user, falling back
-// to user_email in the request body for backward compatibility.
+// to user_email in the request body for backward compatibility unless
+// CredentialIdentity turned that fallback off. Writers may
+// send If-Match: "<revision>" (from the GET response) to avoid overwriting a
+// concurrent change to the same Jira site; a stale revision yields 412.
 func UserSettings(store UserSettingsStore, cookieSecret string) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		// Try to resolve user email from session cookie first.
-		sessionEmail := ""
-		if sess, err := session.ReadSession(r, cookieSecret); err == nil && sess.Email != nil {
+		// Try to resolve user email from the auth token or session cookie first.
+		sessionEmail := tokenEmail(r, "")
+		if sess, err := session.ReadSession(r, cookieSecret); sessionEmail == "" && err == nil && sess.Email != nil {
 			sessionEmail = *sess.Email
 		}
 
@@ -51,10 +57,11 @@ func UserSettings(store UserSettingsStore, cookieSecret string) http.HandlerFunc
 			// userEmail: prefer session, then payload, then jira email as last resort.
 			userEmail := sessionEmail
 			if userEmail == "" {
-				userEmail = strings.TrimSpace(payload.UserEmail)
-			}
-			if userEmail == "" {
-				userEmail = strings.TrimSpace(payload.JiraEmail)
+				fallback := payload.UserEmail
+				if strings.TrimSpace(fallback) == "" {
+					fallback = payload.JiraEmail
+				}
+				userEmail = tokenEmail(r, fallback)
 			}
 
 			if payload.JiraBaseURL == "" || userEmail == "" || payload.JiraEmail == "" || payload.AtlassianAPIKey == "" {
@@ -64,14 +71,33 @@ func UserSettings(store UserSettingsStore, cookieSecret string) http.HandlerFunc
 				return
 			}
 
-			if err := store.UpsertUserSettings(r.Context(), userEmail, payload.JiraBaseURL, payload.JiraEmail, payload.AtlassianAPIKey); err != nil {
+			ifRevision, err := ifMatchRevision(r)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			response := map[string]any{"ok": true}
+			if ifRevision != nil {
+				revision, err := store.UpsertUserSettingsIfRevision(r.Context(), userEmail, payload.JiraBaseURL, payload.JiraEmail, payload.AtlassianAPIKey, *ifRevision)
+				if errors.Is(err, storepkg.ErrRevisionMismatch) {
+					writeRevisionConflict(w)
+					return
+				}
+				if err != nil {
+					log.Printf("UserSettings: failed to persist settings for user_email=%s jira_email=%s: %v", userEmail, payload.JiraEmail, err)
+					http.Error(w, "failed to persist Jira settings", http.StatusBadGateway)
+					return
+				}
+				response["revision"] = revision
+			} else if err := store.UpsertUserSettings(r.Context(), userEmail, payload.JiraBaseURL, payload.JiraEmail, payload.AtlassianAPIKey); err != nil {
 				log.Printf("UserSettings: failed to persist settings for user_email=%s jira_email=%s: %v", userEmail, payload.JiraEmail, err)
 				http.Error(w, "failed to persist Jira settings", http.StatusBadGateway)
 				return
 			}
 
 			w.Header().Set("Content-Type", "application/json")
-			if err := json.NewEncoder(w).Encode(map[string]any{"ok": true}); err != nil {
+			if err := json.NewEncoder(w).Encode(response); err != nil {
 				http.Error(w, "failed to encode response", http.StatusInternalServerError)
 				return
 			}
@@ -79,7 +105,7 @@ func UserSettings(store UserSettingsStore, cookieSecret string) http.HandlerFunc
 			// Prefer session email, fall back to query param.
 			email := sessionEmail
 			if email == "" {
-				email = strings.TrimSpace(r.URL.Query().Get("email"))
+				email = tokenEmail(r, r.URL.Query().Get("email"))
 			}
 			if email == "" {
 				http.Error(w, "not authenticated", http.StatusUnauthorized)
@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+)
+
+// maxImportedSites bounds a single bulk import.
+const maxImportedSites = 100
+
+// SettingsBulkStore defines the behaviour required to export and import all
+// of a tenant's Jira sites at once.
+type SettingsBulkStore interface {
+	ListUserSettings(ctx context.Context, email string) ([]models.JiraUserSettings, error)
+	ImportUserSettings(ctx context.Context, email string, sites []models.JiraSiteSettings, replace bool) (int, int, error)
+}
+
+// ExportJiraSettings returns every Jira site configured by the caller as a
+// single document. API tokens are never exported.
+// GET ?email=...
+func ExportJiraSettings(store SettingsBulkStore, cookieSecret string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		email := requestEmail(r, cookieSecret, "")
+		if email == "" {
+			http.Error(w, "email query parameter is required", http.StatusBadRequest)
+			return
+		}
+
+		settings, err := store.ListUserSettings(r.Context(), email)
+		if err != nil {
+			log.Printf("ExportJiraSettings: failed to list settings for email=%s: %v", email, err)
+			http.Error(w, "failed to load Jira settings", http.StatusBadGateway)
+			return
+		}
+
+		now := time.Now().UTC()
+		doc := models.JiraSettingsDocument{
+			Version:    models.JiraSettingsDocumentVersion,
+			ExportedAt: &now,
+			Sites:      make([]models.JiraSiteSettings, 0, len(settings)),
+		}
+		for _, s := range settings {
+			doc.Sites = append(doc.Sites, models.JiraSiteSettings{
+				JiraBaseURL: s.JiraBaseURL,
+				JiraEmail:   s.JiraEmail,
+				JiraCloudID: s.JiraCloudID,
+				IsDefault:   s.IsDefault,
+			})
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Disposition", `attachment; filename="jira-settings.json"`)
+		if err := json.NewEncoder(w).Encode(doc); err != nil {
+			http.Error(w, "failed to encode response", http.StatusInternalServerError)
+		}
+	}
+}
+
+// ImportJiraSettings upserts every site in the posted document in one
+// transaction. Each site must carry its atlassian_api_key. With
+// ?replace=true, sites not present in the document are removed.
+// POST ?email=...&replace=true  {"version": 1, "sites": [...]}
+func ImportJiraSettings(store SettingsBulkStore, cookieSecret string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		email := requestEmail(r, cookieSecret, "")
+		if email == "" {
+			http.Error(w, "email query parameter is required", http.StatusBadRequest)
+			return
+		}
+
+		replace, _ := strconv.ParseBool(r.URL.Query().Get("replace"))
+
+		var doc models.JiraSettingsDocument
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&doc); err != nil {
+			log.Printf("ImportJiraSettings: invalid JSON payload: %v", err)
+			http.Error(w, "invalid JSON payload", http.StatusBadRequest)
+			return
+		}
+
+		sites, msg := validateSettingsDocument(doc)
+		if msg != "" {
+			http.Error(w, msg, http.StatusBadRequest)
+			return
+		}
+
+		imported, deleted, err := store.ImportUserSettings(r.Context(), email, sites, replace)
+		if err != nil {
+			log.Printf("ImportJiraSettings: failed to import %d sites for email=%s: %v", len(sites), email, err)
+			http.Error(w, "failed to import Jira settings", http.StatusBadGateway)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(map[string]any{
+			"ok":       true,
+			"imported": imported,
+			"deleted":  deleted,
+		}); err != nil {
+			http.Error(w, "failed to encode response", http.StatusInternalServerError)
+		}
+	}
+}
+
+// validateSettingsDocument normalises the sites of an import document and
+// returns a client-facing message for the first problem found.
+func validateSettingsDocument(doc models.JiraSettingsDocument) ([]models.JiraSiteSettings, string) {
+	if doc.Version != 0 && doc.Version != models.JiraSettingsDocumentVersion {
+		return nil, fmt.Sprintf("unsupported document version %d", doc.Version)
+	}
+	if len(doc.Sites) == 0 {
+		return nil, "sites must not be empty"
+	}
+	if len(doc.Sites) > maxImportedSites {
+		return nil, fmt.Sprintf("at most %d sites can be imported at once", maxImportedSites)
+	}
+
+	seen := make(map[string]bool, len(doc.Sites))
+	defaults := 0
+	sites := make([]models.JiraSiteSettings, 0, len(doc.Sites))
+	for i, site := range doc.Sites {
+		site.JiraBaseURL = strings.TrimRight(strings.TrimSpace(site.JiraBaseURL), "/")
+		site.JiraEmail = strings.TrimSpace(site.JiraEmail)
+		site.AtlassianAPIKey = strings.TrimSpace(site.AtlassianAPIKey)
+
+		u, err := url.Parse(site.JiraBaseURL)
+		if err != nil || u.Scheme != "https" || u.Host == "" {
+			return nil, fmt.Sprintf("sites[%d]: jira_base_url must be an https URL", i)
+		}
+		if site.JiraEmail == "" {
+			return nil, fmt.Sprintf("sites[%d]: jira_email is required", i)
+		}
+		if site.AtlassianAPIKey == "" {
+			return nil, fmt.Sprintf("sites[%d]: atlassian_api_key is required", i)
+		}
+		key := strings.ToLower(site.JiraBaseURL)
+		if seen[key] {
+			return nil, fmt.Sprintf("sites[%d]: duplicate jira_base_url %s", i, site.JiraBaseURL)
+		}
+		seen[key] = true
+		if site.IsDefault {
+			defaults++
+		}
+		sites = append(sites, site)
+	}
+	if defaults > 1 {
+		return nil, "only one site can be marked is_default"
+	}
+
+	return sites, ""
+}
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/store"
+)
+
+// SettingsTrashStore defines the behaviour required to delete Jira sites
+// and restore them within the retention window.
+type SettingsTrashStore interface {
+	DeleteUserSettings(ctx context.Context, email, baseURL string) error
+	RestoreUserSettings(ctx context.Context, email, baseURL string) error
+	ListDeletedUserSettings(ctx context.Context, email string) ([]models.DeletedJiraUserSettings, error)
+}
+
+// DeleteJiraSettings deletes one of the caller's Jira sites. It can be
+// restored until it is purged.
+// DELETE ?email=...&jira_base_url=...
+func DeleteJiraSettings(store SettingsTrashStore, cookieSecret string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		email := requestEmail(r, cookieSecret, "")
+		if email == "" {
+			http.Error(w, "email query parameter is required", http.StatusBadRequest)
+			return
+		}
+		baseURL := strings.TrimRight(strings.TrimSpace(r.URL.Query().Get("jira_base_url")), "/")
+		if baseURL == "" {
+			http.Error(w, "jira_base_url query parameter is required", http.StatusBadRequest)
+			return
+		}
+
+		if !trashSettingsResult(w, "DeleteJiraSettings", email, baseURL,
+			store.DeleteUserSettings(r.Context(), email, baseURL)) {
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"ok": true, "jira_base_url": baseURL})
+	}
+}
+
+// DeletedJiraSettings lists the caller's deleted Jira sites that can still
+// be restored and when each will be purged.
+// GET ?email=...
+func DeletedJiraSettings(store SettingsTrashStore, cookieSecret string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		email := requestEmail(r, cookieSecret, "")
+		if email == "" {
+			http.Error(w, "email query parameter is required", http.StatusBadRequest)
+			return
+		}
+
+		deleted, err := store.ListDeletedUserSettings(r.Context(), email)
+		if err != nil {
+			log.Printf("DeletedJiraSettings: failed to list deleted settings for email=%s: %v", email, err)
+			http.Error(w, "failed to load deleted Jira settings", http.StatusBadGateway)
+			return
+		}
+		if deleted == nil {
+			deleted = []models.DeletedJiraUserSettings{}
+		}
+		writeJSON(w, http.StatusOK, deleted)
+	}
+}
+
+// RestoreJiraSettings restores a deleted Jira site of the caller.
+// POST ?email=...  {"jira_base_url": "..."}
+func RestoreJiraSettings(store SettingsTrashStore, cookieSecret string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		email := requestEmail(r, cookieSecret, "")
+		if email == "" {
+			http.Error(w, "email query parameter is required", http.StatusBadRequest)
+			return
+		}
+
+		var body struct {
+			JiraBaseURL string `json:"jira_base_url"`
+		}
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16)).Decode(&body); err != nil {
+			http.Error(w, "invalid JSON payload", http.StatusBadRequest)
+			return
+		}
+		baseURL := strings.TrimRight(strings.TrimSpace(body.JiraBaseURL), "/")
+		if baseURL == "" {
+			http.Error(w, "jira_base_url is required", http.StatusBadRequest)
+			return
+		}
+
+		if !trashSettingsResult(w, "RestoreJiraSettings", email, baseURL,
+			store.RestoreUserSettings(r.Context(), email, baseURL)) {
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"ok": true, "jira_base_url": baseURL})
+	}
+}
+
+// trashSettingsResult writes the error response for err, if any, and
+// reports whether the request succeeded.
+func trashSettingsResult(w http.ResponseWriter, name, email, baseURL string, err error) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, store.ErrUserSettingsNotFound):
+		http.Error(w, "Jira settings not found", http.StatusNotFound)
+	default:
+		log.Printf("%s: failed for email=%s jira_base_url=%s: %v", name, email, baseURL, err)
+		http.Error(w, "failed to update Jira settings", http.StatusBadGateway)
+	}
+	return false
+}
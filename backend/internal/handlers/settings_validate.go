@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/jira"
+)
+
+// Reasons ValidateJiraSettings gives for credentials that do not work.
+const (
+	jiraValidationInvalidCredentials = "invalid_credentials"
+	jiraValidationNotJiraSite        = "not_jira_site"
+	jiraValidationUnreachable        = "unreachable"
+	jiraValidationJiraError          = "jira_error"
+)
+
+type jiraValidatePayload struct {
+	JiraBaseURL     string `json:"jira_base_url"`
+	JiraEmail       string `json:"jira_email"`
+	AtlassianAPIKey string `json:"atlassian_api_key"`
+}
+
+// JiraSiteRecorder remembers what validation found out about Jira sites.
+type JiraSiteRecorder interface {
+	RecordJiraSite(ctx context.Context, baseURL string, serviceManagement bool) error
+}
+
+// ValidateJiraSettings checks Jira credentials before they are saved by
+// calling /rest/api/3/myself with them. It answers 200 with "ok" telling
+// whether they work: the resolved account on success, otherwise a reason
+// (invalid_credentials, not_jira_site, unreachable or jira_error) and
+// Jira's status when it answered. On success it also reports in
+// "service_management" whether the site has Jira Service Management, and
+// records it with sites when not nil so the jsm_* MCP tools are enabled for
+// the site. httpClient is used for the Jira calls when not nil.
+// POST {"jira_base_url": "https://acme.atlassian.net", "jira_email": "...", "atlassian_api_key": "..."}
+func ValidateJiraSettings(sites JiraSiteRecorder, cookieSecret string, httpClient *http.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if requestEmail(r, cookieSecret, "-") == "-" {
+			writeJSON(w, http.StatusUnauthorized, map[string]any{"ok": false, "error": "not authenticated"})
+			return
+		}
+
+		var payload jiraValidatePayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": "invalid JSON payload"})
+			return
+		}
+		payload.JiraEmail = strings.TrimSpace(payload.JiraEmail)
+		payload.AtlassianAPIKey = strings.TrimSpace(payload.AtlassianAPIKey)
+		if payload.JiraBaseURL == "" || payload.JiraEmail == "" || payload.AtlassianAPIKey == "" {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": "jira_base_url, jira_email and atlassian_api_key are required"})
+			return
+		}
+
+		client, err := jira.NewClient(payload.JiraBaseURL, payload.JiraEmail, payload.AtlassianAPIKey)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": "jira_base_url must be an https URL such as https://your-site.atlassian.net"})
+			return
+		}
+		client.WithHTTPClient(httpClient)
+
+		account, err := client.Myself(r.Context())
+		if err != nil {
+			result := map[string]any{"ok": false, "error": err.Error()}
+			var apiErr *jira.APIError
+			if errors.As(err, &apiErr) {
+				result["status"] = apiErr.StatusCode
+			}
+			switch {
+			case jira.IsUnauthorized(err):
+				result["reason"] = jiraValidationInvalidCredentials
+				result["error"] = "Jira rejected the email and API token"
+			case jira.IsNotFound(err):
+				result["reason"] = jiraValidationNotJiraSite
+				result["error"] = "no Jira Cloud site answered at this URL"
+			case apiErr != nil:
+				result["reason"] = jiraValidationJiraError
+			default:
+				log.Printf("ValidateJiraSettings: request to %s failed: %v", client.BaseURL(), err)
+				result["reason"] = jiraValidationUnreachable
+				result["error"] = "could not reach the Jira site"
+			}
+			writeJSON(w, http.StatusOK, result)
+			return
+		}
+
+		result := map[string]any{
+			"ok":            true,
+			"jira_base_url": client.BaseURL(),
+			"account":       account,
+		}
+		// A failed check leaves the site's recorded state alone.
+		serviceManagement, err := client.HasServiceManagement(r.Context())
+		if err != nil {
+			log.Printf("ValidateJiraSettings: failed to detect Jira Service Management on %s: %v", client.BaseURL(), err)
+		} else {
+			result["service_management"] = serviceManagement
+			if sites != nil {
+				if err := sites.RecordJiraSite(r.Context(), client.BaseURL(), serviceManagement); err != nil {
+					log.Printf("ValidateJiraSettings: failed to record site %s: %v", client.BaseURL(), err)
+				}
+			}
+		}
+		writeJSON(w, http.StatusOK, result)
+	}
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type fakeJiraSites map[string]bool
+
+func (f fakeJiraSites) RecordJiraSite(ctx context.Context, baseURL string, serviceManagement bool) error {
+	f[baseURL] = serviceManagement
+	return nil
+}
+
+func TestValidateJiraSettings(t *testing.T) {
+	jiraServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/rest/servicedeskapi/info" {
+			w.Write([]byte(`{"version":"10.3.0"}`))
+			return
+		}
+		if r.URL.Path != "/rest/api/3/myself" {
+			http.NotFound(w, r)
+			return
+		}
+		if _, token, _ := r.BasicAuth(); token != "good-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"errorMessages":["Client must be authenticated to access this resource."]}`))
+			return
+		}
+		w.Write([]byte(`{"accountId":"5b10","displayName":"Ada Lovelace","emailAddress":"ada@example.com","active":true}`))
+	}))
+	defer jiraServer.Close()
+
+	sites := fakeJiraSites{}
+	handler := ValidateJiraSettings(sites, "secret", jiraServer.Client())
+	validate := func(body string, authenticated bool) (int, map[string]any) {
+		req := httptest.NewRequest(http.MethodPost, "/api/settings/jira/validate", strings.NewReader(body))
+		if authenticated {
+			req.AddCookie(sessionCookie(t, "secret", "sid"))
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		var out map[string]any
+		if err := json.Unmarshal(rec.Body.Bytes(), &out); err != nil {
+			t.Fatalf("decode response %q: %v", rec.Body.String(), err)
+		}
+		return rec.Code, out
+	}
+	payload := func(token string) string {
+		return `{"jira_base_url":"` + jiraServer.URL + `","jira_email":"ada@example.com","atlassian_api_key":"` + token + `"}`
+	}
+
+	if code, _ := validate(payload("good-token"), false); code != http.StatusUnauthorized {
+		t.Fatalf("unauthenticated status = %d, want 401", code)
+	}
+	if code, _ := validate(`{"jira_base_url":"http://acme.atlassian.net","jira_email":"a@b.c","atlassian_api_key":"x"}`, true); code != http.StatusBadRequest {
+		t.Fatalf("http URL status = %d, want 400", code)
+	}
+
+	code, out := validate(payload("good-token"), true)
+	if code != http.StatusOK || out["ok"] != true {
+		t.Fatalf("valid credentials = %d %v", code, out)
+	}
+	if account, _ := out["account"].(map[string]any); account["accountId"] != "5b10" || account["displayName"] != "Ada Lovelace" {
+		t.Fatalf("account = %v", out["account"])
+	}
+	if out["service_management"] != true || !sites[jiraServer.URL] {
+		t.Fatalf("expected Jira Service Management to be detected and recorded, got %v and %v", out["service_management"], sites)
+	}
+
+	code, out = validate(payload("bad-token"), true)
+	if code != http.StatusOK || out["ok"] != false || out["reason"] != "invalid_credentials" || out["status"] != float64(401) {
+		t.Fatalf("bad token = %d %v", code, out)
+	}
+}
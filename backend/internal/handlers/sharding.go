@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/worker"
+)
+
+// ShardStore reports and changes where tenants' request logs are kept.
+type ShardStore interface {
+	GetUserByEmail(ctx context.Context, email string) (*models.User, error)
+	GetTenantShard(ctx context.Context, userID int64) (*models.TenantShard, error)
+	ListShardUsage(ctx context.Context) ([]models.ShardUsage, error)
+	Shards() []string
+}
+
+// AdminShards reports how many tenants and request logs each shard holds,
+// the primary database included as shard "".
+func AdminShards(shards ShardStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		usage, err := shards.ListShardUsage(r.Context())
+		if err != nil {
+			log.Printf("AdminShards: list shard usage: %v", err)
+			http.Error(w, "failed to load shard usage", http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"shards": usage})
+	}
+}
+
+// AdminTenantShard reports (GET) or changes (PUT) the shard of the tenant in
+// the email query parameter. PUT takes {"shard": "s1"}, or "" for the
+// primary database, and queues a tenant_shard_move job that copies the
+// tenant's request logs; the placement reads "moving" until it finishes.
+// Tenants of a resident organization stay in their data region.
+func AdminTenantShard(shards ShardStore, jobs JobEnqueuer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodPut {
+			w.Header().Set("Allow", "GET, PUT")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		email := strings.TrimSpace(r.URL.Query().Get("email"))
+		if email == "" {
+			http.Error(w, "email query parameter is required", http.StatusBadRequest)
+			return
+		}
+		user, err := shards.GetUserByEmail(r.Context(), email)
+		if err != nil || user == nil {
+			log.Printf("AdminTenantShard: failed to find user email=%s: %v", email, err)
+			http.Error(w, "user not found", http.StatusNotFound)
+			return
+		}
+		placement, err := shards.GetTenantShard(r.Context(), user.ID)
+		if err != nil {
+			log.Printf("AdminTenantShard: load shard of user %d: %v", user.ID, err)
+			http.Error(w, "failed to load tenant shard", http.StatusInternalServerError)
+			return
+		}
+
+		if r.Method == http.MethodGet {
+			writeJSON(w, http.StatusOK, map[string]any{
+				"placement":        placement,
+				"available_shards": shards.Shards(),
+			})
+			return
+		}
+
+		var payload struct {
+			Shard *string `json:"shard"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || payload.Shard == nil {
+			http.Error(w, `expected {"shard": "<shard>"}; use "" for the primary database`, http.StatusBadRequest)
+			return
+		}
+		shard := strings.ToLower(strings.TrimSpace(*payload.Shard))
+		if shard != "" && !slices.Contains(shards.Shards(), shard) {
+			http.Error(w, "unknown shard; configured shards: "+strings.Join(shards.Shards(), ", "), http.StatusBadRequest)
+			return
+		}
+		if placement.DataRegion != "" {
+			http.Error(w, "tenant is resident in data region "+placement.DataRegion+"; its request logs stay there", http.StatusConflict)
+			return
+		}
+		if jobs == nil {
+			http.Error(w, "background jobs are not available", http.StatusServiceUnavailable)
+			return
+		}
+
+		job := worker.TenantShardMoveJob(user.ID, shard)
+		if err := jobs.Enqueue(r.Context(), job); err != nil {
+			log.Printf("AdminTenantShard: failed to enqueue move of user %d to %q: %v", user.ID, shard, err)
+			http.Error(w, "failed to start shard move", http.StatusBadGateway)
+			return
+		}
+
+		writeJSON(w, http.StatusAccepted, map[string]any{
+			"placement": placement,
+			"shard":     shard,
+			"job_id":    job.ID,
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+)
+
+type fakeShardStore struct {
+	placements map[int64]*models.TenantShard
+}
+
+func (f *fakeShardStore) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	switch email {
+	case "big@example.com":
+		return &models.User{ID: 1}, nil
+	case "eu@example.com":
+		return &models.User{ID: 2}, nil
+	}
+	return nil, errors.New("store: user not found")
+}
+
+func (f *fakeShardStore) GetTenantShard(ctx context.Context, userID int64) (*models.TenantShard, error) {
+	return f.placements[userID], nil
+}
+
+func (f *fakeShardStore) ListShardUsage(ctx context.Context) ([]models.ShardUsage, error) {
+	return []models.ShardUsage{{Shard: "", Tenants: 2, Requests: 10}, {Shard: "s1", Tenants: 0, Requests: 0}}, nil
+}
+
+func (f *fakeShardStore) Shards() []string { return []string{"s1"} }
+
+func TestAdminTenantShardQueuesMove(t *testing.T) {
+	shards := &fakeShardStore{placements: map[int64]*models.TenantShard{
+		1: {UserID: 1, Status: models.TenantShardActive},
+		2: {UserID: 2, Status: models.TenantShardActive, DataRegion: "eu"},
+	}}
+	jobs := &recordingEnqueuer{}
+	handler := AdminTenantShard(shards, jobs)
+
+	for _, tc := range []struct {
+		email, body string
+		want        int
+	}{
+		{"big@example.com", `{"shard":"s1"}`, http.StatusAccepted},
+		{"big@example.com", `{"shard":"s9"}`, http.StatusBadRequest},
+		{"big@example.com", `{}`, http.StatusBadRequest},
+		{"eu@example.com", `{"shard":"s1"}`, http.StatusConflict},
+		{"nobody@example.com", `{"shard":"s1"}`, http.StatusNotFound},
+	} {
+		req := httptest.NewRequest(http.MethodPut, "/api/admin/users/shard?email="+tc.email, strings.NewReader(tc.body))
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		if rec.Code != tc.want {
+			t.Fatalf("PUT %s %s: expected %d, got %d: %s", tc.email, tc.body, tc.want, rec.Code, rec.Body.String())
+		}
+	}
+
+	if len(jobs.jobs) != 1 {
+		t.Fatalf("expected one queued move, got %d", len(jobs.jobs))
+	}
+	if job := jobs.jobs[0]; job.JobType != "tenant_shard_move" || job.Payload["shard"] != "s1" || job.Payload["user_id"] != int64(1) {
+		t.Fatalf("unexpected job %+v", job)
+	}
+}
@@ -0,0 +1,978 @@
+package handlers
+
+import (
+	"bytes"
+	"compress/flate"
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/beevik/etree"
+	dsig "github.com/russellhaering/goxmldsig"
+	"github.com/russellhaering/goxmldsig/etreeutils"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/config"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/middleware"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/session"
+	storepkg "github.com/PortNumber53/mcp-jira-thing/backend/internal/store"
+)
+
+const (
+	// oidcDiscoveryTTL bounds how long an issuer's discovery document is
+	// cached before it is fetched again.
+	oidcDiscoveryTTL = time.Hour
+
+	// samlClockSkew is the tolerance applied to assertion validity windows.
+	samlClockSkew = 2 * time.Minute
+)
+
+var ssoHTTPClient = &http.Client{Timeout: 10 * time.Second}
+
+// SSOStore defines the behaviour required to sign users in through an
+// organization's identity provider.
+type SSOStore interface {
+	GetOrganizationBySlug(ctx context.Context, slug string) (*models.Organization, error)
+	GetOrganizationByDomain(ctx context.Context, domain string) (*models.Organization, error)
+	GetSSOConfig(ctx context.Context, orgID int64) (*models.SSOConfig, error)
+	ProvisionSSOUser(ctx context.Context, orgID int64, identity models.SSOIdentity) (int64, error)
+	RecordLoginEvent(ctx context.Context, userID int64, provider, ipAddress, userAgent string) error
+	CreateUserSession(ctx context.Context, userID int64, provider, ipAddress, userAgent string, ttl time.Duration) (string, error)
+}
+
+// SSOLogin starts a login through an organization's identity provider. The
+// organization is picked by slug or, for domain-based routing, by the domain
+// of the email the user entered.
+// GET ?org=acme&redirect=/dashboard
+// GET ?email=jane@acme.com
+func SSOLogin(cfg config.Config, store SSOStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+
+		var org *models.Organization
+		var err error
+		if slug := strings.TrimSpace(q.Get("org")); slug != "" {
+			org, err = store.GetOrganizationBySlug(r.Context(), slug)
+		} else if email := strings.TrimSpace(q.Get("email")); strings.Contains(email, "@") {
+			org, err = store.GetOrganizationByDomain(r.Context(), email[strings.LastIndex(email, "@")+1:])
+		} else {
+			redirectWithError(w, r, cfg.FrontendURL, "organization or email is required")
+			return
+		}
+		if errors.Is(err, storepkg.ErrOrganizationNotFound) {
+			redirectWithError(w, r, cfg.FrontendURL, "no single sign-on configured for this organization")
+			return
+		}
+		if err != nil {
+			log.Printf("[sso-login] failed to resolve organization: %v", err)
+			redirectWithError(w, r, cfg.FrontendURL, "sso login failed")
+			return
+		}
+
+		ssoCfg, err := store.GetSSOConfig(r.Context(), org.ID)
+		if err != nil || !ssoCfg.Enabled {
+			if err != nil && !errors.Is(err, storepkg.ErrSSOConfigNotFound) {
+				log.Printf("[sso-login] failed to load sso config for org=%s: %v", org.Slug, err)
+			}
+			redirectWithError(w, r, cfg.FrontendURL, "no single sign-on configured for this organization")
+			return
+		}
+
+		redirect := q.Get("redirect")
+		if !strings.HasPrefix(redirect, "/") || strings.HasPrefix(redirect, "//") {
+			redirect = "/dashboard"
+		}
+
+		nonce, err := session.RandomHex(32)
+		if err != nil {
+			log.Printf("[sso-login] failed to generate nonce: %v", err)
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		state := session.StatePayload{
+			Nonce:     nonce,
+			Redirect:  redirect,
+			CreatedAt: time.Now().UnixMilli(),
+			Org:       org.Slug,
+		}
+
+		switch ssoCfg.Protocol {
+		case models.SSOProtocolOIDC:
+			meta, err := discoverOIDC(r.Context(), ssoCfg.OIDCIssuer)
+			if err != nil {
+				log.Printf("[sso-login] oidc discovery failed for org=%s: %v", org.Slug, err)
+				redirectWithError(w, r, cfg.FrontendURL, "identity provider unavailable")
+				return
+			}
+
+			state.Verifier, err = session.RandomHex(32)
+			if err != nil {
+				log.Printf("[sso-login] failed to generate code verifier: %v", err)
+				http.Error(w, "internal error", http.StatusInternalServerError)
+				return
+			}
+
+			stateCookie, err := session.Encode(cfg.CookieSecret, state)
+			if err != nil {
+				log.Printf("[sso-login] failed to encode state: %v", err)
+				http.Error(w, "internal error", http.StatusInternalServerError)
+				return
+			}
+			secure := strings.HasPrefix(cfg.BackendURL, "https")
+			session.SetCookie(w, session.StateCookie, stateCookie, cfg.CookieDomain, int(session.StateTTL.Seconds()), secure)
+
+			params := url.Values{
+				"client_id":             {ssoCfg.OIDCClientID},
+				"redirect_uri":          {cfg.BackendURL + "/callback/sso"},
+				"response_type":         {"code"},
+				"scope":                 {"openid email profile"},
+				"state":                 {nonce},
+				"nonce":                 {nonce},
+				"code_challenge":        {pkceChallenge(state.Verifier)},
+				"code_challenge_method": {"S256"},
+			}
+			http.Redirect(w, r, meta.AuthorizationEndpoint+querySeparator(meta.AuthorizationEndpoint)+params.Encode(), http.StatusFound)
+
+		case models.SSOProtocolSAML:
+			// The ACS is reached by a cross-site POST that does not carry
+			// SameSite=Lax cookies, so the signed state travels in RelayState
+			// and its nonce doubles as the AuthnRequest ID.
+			state.Nonce = "_" + nonce
+			relayState, err := session.Encode(cfg.CookieSecret, state)
+			if err != nil {
+				log.Printf("[sso-login] failed to encode relay state: %v", err)
+				http.Error(w, "internal error", http.StatusInternalServerError)
+				return
+			}
+
+			samlRequest, err := buildSAMLAuthnRequest(state.Nonce, ssoCfg.SAMLIdPSSOURL,
+				samlEntityID(cfg.BackendURL, org.Slug), cfg.BackendURL+"/api/auth/sso/saml/acs", time.Now())
+			if err != nil {
+				log.Printf("[sso-login] failed to build saml request for org=%s: %v", org.Slug, err)
+				http.Error(w, "internal error", http.StatusInternalServerError)
+				return
+			}
+
+			params := url.Values{
+				"SAMLRequest": {samlRequest},
+				"RelayState":  {relayState},
+			}
+			http.Redirect(w, r, ssoCfg.SAMLIdPSSOURL+querySeparator(ssoCfg.SAMLIdPSSOURL)+params.Encode(), http.StatusFound)
+
+		default:
+			redirectWithError(w, r, cfg.FrontendURL, "no single sign-on configured for this organization")
+		}
+	}
+}
+
+// SSOCallback completes an OIDC login: it exchanges the authorization code
+// with the PKCE verifier, checks the ID token and signs the user in.
+func SSOCallback(cfg config.Config, store SSOStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if idpErr := q.Get("error"); idpErr != "" {
+			log.Printf("[sso-callback] identity provider returned error=%s: %s", idpErr, q.Get("error_description"))
+			redirectWithError(w, r, cfg.FrontendURL, "sign-in was cancelled or denied")
+			return
+		}
+
+		code := q.Get("code")
+		stateParam := q.Get("state")
+		if code == "" || stateParam == "" {
+			redirectWithError(w, r, cfg.FrontendURL, "missing code or state")
+			return
+		}
+
+		stateCookie, err := r.Cookie(session.StateCookie)
+		if err != nil {
+			log.Printf("[sso-callback] missing state cookie: %v", err)
+			redirectWithError(w, r, cfg.FrontendURL, "missing state cookie")
+			return
+		}
+
+		var state session.StatePayload
+		if err := session.Decode(cfg.CookieSecret, stateCookie.Value, &state); err != nil {
+			log.Printf("[sso-callback] invalid state cookie: %v", err)
+			redirectWithError(w, r, cfg.FrontendURL, "invalid state")
+			return
+		}
+		if state.Nonce != stateParam || state.Org == "" || state.Verifier == "" {
+			log.Printf("[sso-callback] state mismatch: cookie=%q param=%q", state.Nonce, stateParam)
+			redirectWithError(w, r, cfg.FrontendURL, "state mismatch")
+			return
+		}
+		if time.Since(time.UnixMilli(state.CreatedAt)) > session.StateTTL {
+			redirectWithError(w, r, cfg.FrontendURL, "state expired")
+			return
+		}
+
+		org, ssoCfg, ok := ssoOrganization(w, r, cfg, store, state.Org, models.SSOProtocolOIDC)
+		if !ok {
+			return
+		}
+
+		meta, err := discoverOIDC(r.Context(), ssoCfg.OIDCIssuer)
+		if err != nil {
+			log.Printf("[sso-callback] oidc discovery failed for org=%s: %v", org.Slug, err)
+			redirectWithError(w, r, cfg.FrontendURL, "identity provider unavailable")
+			return
+		}
+
+		tokens, err := exchangeOIDCCode(r.Context(), meta.TokenEndpoint, url.Values{
+			"grant_type":    {"authorization_code"},
+			"code":          {code},
+			"redirect_uri":  {cfg.BackendURL + "/callback/sso"},
+			"client_id":     {ssoCfg.OIDCClientID},
+			"client_secret": {ssoCfg.OIDCClientSecret},
+			"code_verifier": {state.Verifier},
+		})
+		if err != nil {
+			log.Printf("[sso-callback] token exchange failed for org=%s: %v", org.Slug, err)
+			redirectWithError(w, r, cfg.FrontendURL, "token exchange failed")
+			return
+		}
+
+		claims, err := parseIDTokenClaims(tokens.IDToken)
+		if err == nil {
+			err = claims.verify(meta.Issuer, ssoCfg.OIDCClientID, state.Nonce, time.Now())
+		}
+		if err != nil {
+			log.Printf("[sso-callback] invalid id token for org=%s: %v", org.Slug, err)
+			redirectWithError(w, r, cfg.FrontendURL, "invalid identity token")
+			return
+		}
+
+		if claims.Email == "" && meta.UserinfoEndpoint != "" {
+			if err := fetchOIDCUserInfo(r.Context(), meta.UserinfoEndpoint, tokens.AccessToken, claims); err != nil {
+				log.Printf("[sso-callback] userinfo fetch failed for org=%s: %v", org.Slug, err)
+				redirectWithError(w, r, cfg.FrontendURL, "failed to get user info")
+				return
+			}
+		}
+		if claims.Email == "" || !bool(claims.EmailVerified) {
+			redirectWithError(w, r, cfg.FrontendURL, "identity provider did not return a verified email")
+			return
+		}
+
+		finishSSOLogin(w, r, cfg, store, org, models.SSOIdentity{
+			Subject: claims.Subject,
+			Email:   claims.Email,
+			Name:    strPtr(claims.Name),
+		}, state.Redirect)
+	}
+}
+
+// SSOSAMLACS is the SAML assertion consumer service. It accepts only
+// responses to AuthnRequests this service issued; IdP-initiated logins are
+// rejected because they cannot be tied to a login attempt.
+func SSOSAMLACS(cfg config.Config, store SSOStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			redirectWithError(w, r, cfg.FrontendURL, "invalid saml response")
+			return
+		}
+
+		var state session.StatePayload
+		if err := session.Decode(cfg.CookieSecret, r.PostForm.Get("RelayState"), &state); err != nil || state.Org == "" {
+			log.Printf("[sso-saml] missing or invalid relay state: %v", err)
+			redirectWithError(w, r, cfg.FrontendURL, "unsolicited saml response")
+			return
+		}
+		if time.Since(time.UnixMilli(state.CreatedAt)) > session.StateTTL {
+			redirectWithError(w, r, cfg.FrontendURL, "state expired")
+			return
+		}
+
+		org, ssoCfg, ok := ssoOrganization(w, r, cfg, store, state.Org, models.SSOProtocolSAML)
+		if !ok {
+			return
+		}
+
+		raw, err := base64.StdEncoding.DecodeString(r.PostForm.Get("SAMLResponse"))
+		if err != nil {
+			redirectWithError(w, r, cfg.FrontendURL, "invalid saml response")
+			return
+		}
+
+		cert, err := parseSAMLCertificate(ssoCfg.SAMLIdPCertificate)
+		if err != nil {
+			log.Printf("[sso-saml] invalid idp certificate for org=%s: %v", org.Slug, err)
+			redirectWithError(w, r, cfg.FrontendURL, "sso login failed")
+			return
+		}
+
+		identity, err := validateSAMLResponse(raw, samlExpectations{
+			IdPEntityID: ssoCfg.SAMLIdPEntityID,
+			SPEntityID:  samlEntityID(cfg.BackendURL, org.Slug),
+			ACSURL:      cfg.BackendURL + "/api/auth/sso/saml/acs",
+			RequestID:   state.Nonce,
+			Certificate: cert,
+			Now:         time.Now(),
+		})
+		if err != nil {
+			log.Printf("[sso-saml] rejected response for org=%s: %v", org.Slug, err)
+			redirectWithError(w, r, cfg.FrontendURL, "invalid saml response")
+			return
+		}
+
+		finishSSOLogin(w, r, cfg, store, org, *identity, state.Redirect)
+	}
+}
+
+// SSOSAMLMetadata serves the service provider metadata an organization
+// uploads to its SAML identity provider.
+// GET ?org=acme
+func SSOSAMLMetadata(cfg config.Config, store SSOStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		org, err := store.GetOrganizationBySlug(r.Context(), r.URL.Query().Get("org"))
+		if errors.Is(err, storepkg.ErrOrganizationNotFound) {
+			http.Error(w, "organization not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Printf("SSOSAMLMetadata: failed to load organization: %v", err)
+			http.Error(w, "failed to load organization", http.StatusBadGateway)
+			return
+		}
+
+		type acs struct {
+			Binding  string `xml:"Binding,attr"`
+			Location string `xml:"Location,attr"`
+			Index    int    `xml:"index,attr"`
+		}
+		type spDescriptor struct {
+			AuthnRequestsSigned        bool   `xml:"AuthnRequestsSigned,attr"`
+			WantAssertionsSigned       bool   `xml:"WantAssertionsSigned,attr"`
+			ProtocolSupportEnumeration string `xml:"protocolSupportEnumeration,attr"`
+			NameIDFormat               string `xml:"md:NameIDFormat"`
+			AssertionConsumerService   acs    `xml:"md:AssertionConsumerService"`
+		}
+		metadata := struct {
+			XMLName  xml.Name     `xml:"md:EntityDescriptor"`
+			XMLNS    string       `xml:"xmlns:md,attr"`
+			EntityID string       `xml:"entityID,attr"`
+			SP       spDescriptor `xml:"md:SPSSODescriptor"`
+		}{
+			XMLNS:    "urn:oasis:names:tc:SAML:2.0:metadata",
+			EntityID: samlEntityID(cfg.BackendURL, org.Slug),
+			SP: spDescriptor{
+				WantAssertionsSigned:       true,
+				ProtocolSupportEnumeration: "urn:oasis:names:tc:SAML:2.0:protocol",
+				NameIDFormat:               "urn:oasis:names:tc:SAML:1.1:nameid-format:emailAddress",
+				AssertionConsumerService: acs{
+					Binding:  "urn:oasis:names:tc:SAML:2.0:bindings:HTTP-POST",
+					Location: cfg.BackendURL + "/api/auth/sso/saml/acs",
+				},
+			},
+		}
+
+		w.Header().Set("Content-Type", "application/samlmetadata+xml")
+		_, _ = io.WriteString(w, xml.Header)
+		if err := xml.NewEncoder(w).Encode(metadata); err != nil {
+			log.Printf("SSOSAMLMetadata: failed to encode metadata: %v", err)
+		}
+	}
+}
+
+// ssoOrganization loads the organization a login is for and its identity
+// provider, redirecting with an error and returning false unless the
+// provider is enabled and speaks protocol.
+func ssoOrganization(w http.ResponseWriter, r *http.Request, cfg config.Config, store SSOStore, slug, protocol string) (*models.Organization, *models.SSOConfig, bool) {
+	org, err := store.GetOrganizationBySlug(r.Context(), slug)
+	if err != nil {
+		log.Printf("[sso] failed to load org=%s: %v", slug, err)
+		redirectWithError(w, r, cfg.FrontendURL, "sso login failed")
+		return nil, nil, false
+	}
+
+	ssoCfg, err := store.GetSSOConfig(r.Context(), org.ID)
+	if err != nil || !ssoCfg.Enabled || ssoCfg.Protocol != protocol {
+		log.Printf("[sso] org=%s has no enabled %s provider: %v", slug, protocol, err)
+		redirectWithError(w, r, cfg.FrontendURL, "sso login failed")
+		return nil, nil, false
+	}
+
+	return org, ssoCfg, true
+}
+
+// finishSSOLogin provisions the asserted user into the organization and
+// starts their session.
+func finishSSOLogin(w http.ResponseWriter, r *http.Request, cfg config.Config, store SSOStore, org *models.Organization, identity models.SSOIdentity, redirect string) {
+	email := strings.ToLower(strings.TrimSpace(identity.Email))
+	identity.Email = email
+
+	userID, err := store.ProvisionSSOUser(r.Context(), org.ID, identity)
+	if err != nil {
+		switch {
+		case errors.Is(err, storepkg.ErrSSODomainNotAllowed):
+			redirectWithError(w, r, cfg.FrontendURL, "your email domain is not part of this organization")
+		case errors.Is(err, storepkg.ErrSSOAccountConflict):
+			redirectWithError(w, r, cfg.FrontendURL, "an account with this email exists outside the organization")
+		case errors.Is(err, storepkg.ErrSSOMemberDeactivated):
+			redirectWithError(w, r, cfg.FrontendURL, "your organization membership has been deactivated")
+		case errors.Is(err, storepkg.ErrAccountDeleted):
+			redirectWithError(w, r, cfg.FrontendURL, "this account has been deleted")
+		default:
+			log.Printf("[sso] failed to provision user for org=%s: %v", org.Slug, err)
+			redirectWithError(w, r, cfg.FrontendURL, "sso login failed")
+		}
+		return
+	}
+
+	sessionID, err := store.CreateUserSession(r.Context(), userID, "sso", middleware.ClientIP(r), r.UserAgent(), session.SessionTTL)
+	if err != nil {
+		// Non-fatal: the cookie still works but cannot be revoked early.
+		log.Printf("[sso] failed to record session for org=%s: %v", org.Slug, err)
+	}
+
+	sessionPayload := session.Payload{
+		Login:     email,
+		ID:        time.Now().UnixMilli(),
+		Name:      identity.Name,
+		Email:     &email,
+		Provider:  "sso",
+		Exp:       time.Now().Add(session.SessionTTL).Unix(),
+		SessionID: sessionID,
+	}
+	sessionToken, err := session.Encode(cfg.CookieSecret, sessionPayload)
+	if err != nil {
+		log.Printf("[sso] failed to encode session: %v", err)
+		redirectWithError(w, r, cfg.FrontendURL, "session creation failed")
+		return
+	}
+
+	secure := strings.HasPrefix(cfg.FrontendURL, "https")
+	session.SetCookie(w, session.SessionCookie, sessionToken, cfg.CookieDomain, int(session.SessionTTL.Seconds()), secure)
+	session.ClearCookie(w, session.StateCookie, cfg.CookieDomain, secure)
+
+	if err := store.RecordLoginEvent(r.Context(), userID, "sso", middleware.ClientIP(r), r.UserAgent()); err != nil {
+		log.Printf("[sso] failed to record login for org=%s: %v", org.Slug, err)
+	}
+
+	if redirect == "" {
+		redirect = "/dashboard"
+	}
+	http.Redirect(w, r, cfg.FrontendURL+redirect, http.StatusSeeOther)
+}
+
+func querySeparator(endpoint string) string {
+	if strings.Contains(endpoint, "?") {
+		return "&"
+	}
+	return "?"
+}
+
+// --- OIDC ---
+
+// oidcProviderMetadata is the subset of an issuer's discovery document used
+// for the authorization code flow.
+type oidcProviderMetadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserinfoEndpoint      string `json:"userinfo_endpoint"`
+}
+
+type oidcTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	IDToken     string `json:"id_token"`
+	TokenType   string `json:"token_type"`
+}
+
+// oidcBool accepts both JSON booleans and the "true"/"false" strings some
+// providers send for email_verified.
+type oidcBool bool
+
+func (b *oidcBool) UnmarshalJSON(data []byte) error {
+	*b = oidcBool(string(data) == "true" || string(data) == `"true"`)
+	return nil
+}
+
+// oidcAudience accepts the aud claim as a single string or an array.
+type oidcAudience []string
+
+func (a *oidcAudience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = oidcAudience{single}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return err
+	}
+	*a = many
+	return nil
+}
+
+type idTokenClaims struct {
+	Issuer        string       `json:"iss"`
+	Subject       string       `json:"sub"`
+	Audience      oidcAudience `json:"aud"`
+	Expiry        int64        `json:"exp"`
+	Nonce         string       `json:"nonce"`
+	Email         string       `json:"email"`
+	EmailVerified oidcBool     `json:"email_verified"`
+	Name          string       `json:"name"`
+}
+
+// verify checks the claims of an ID token received directly from the token
+// endpoint. Its signature is not checked: the TLS connection to the issuer
+// authenticates it (OpenID Connect Core 1.0, section 3.1.3.7).
+func (c *idTokenClaims) verify(issuer, clientID, nonce string, now time.Time) error {
+	if c.Issuer != issuer {
+		return fmt.Errorf("issuer %q does not match %q", c.Issuer, issuer)
+	}
+	audOK := false
+	for _, aud := range c.Audience {
+		if aud == clientID {
+			audOK = true
+		}
+	}
+	if !audOK {
+		return errors.New("token was not issued for this client")
+	}
+	if c.Expiry == 0 || now.After(time.Unix(c.Expiry, 0)) {
+		return errors.New("token expired")
+	}
+	if c.Nonce != nonce {
+		return errors.New("nonce mismatch")
+	}
+	if c.Subject == "" {
+		return errors.New("missing subject")
+	}
+	return nil
+}
+
+type oidcDiscoveryEntry struct {
+	meta    *oidcProviderMetadata
+	fetched time.Time
+}
+
+var oidcDiscovery = struct {
+	sync.Mutex
+	entries map[string]oidcDiscoveryEntry
+}{entries: map[string]oidcDiscoveryEntry{}}
+
+// discoverOIDC returns the issuer's discovery document, cached for
+// oidcDiscoveryTTL.
+func discoverOIDC(ctx context.Context, issuer string) (*oidcProviderMetadata, error) {
+	oidcDiscovery.Lock()
+	entry, ok := oidcDiscovery.entries[issuer]
+	oidcDiscovery.Unlock()
+	if ok && time.Since(entry.fetched) < oidcDiscoveryTTL {
+		return entry.meta, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, issuer+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, fmt.Errorf("build discovery request: %w", err)
+	}
+	resp, err := ssoHTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("GET discovery: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("discovery returned %d", resp.StatusCode)
+	}
+
+	var meta oidcProviderMetadata
+	if err := json.Unmarshal(body, &meta); err != nil {
+		return nil, fmt.Errorf("unmarshal discovery: %w", err)
+	}
+	if strings.TrimRight(meta.Issuer, "/") != issuer {
+		return nil, fmt.Errorf("discovery issuer %q does not match %q", meta.Issuer, issuer)
+	}
+	if meta.AuthorizationEndpoint == "" || meta.TokenEndpoint == "" {
+		return nil, errors.New("discovery document is missing endpoints")
+	}
+
+	oidcDiscovery.Lock()
+	oidcDiscovery.entries[issuer] = oidcDiscoveryEntry{meta: &meta, fetched: time.Now()}
+	oidcDiscovery.Unlock()
+
+	return &meta, nil
+}
+
+// pkceChallenge derives the S256 code challenge for a PKCE code verifier.
+func pkceChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func exchangeOIDCCode(ctx context.Context, tokenEndpoint string, form url.Values) (*oidcTokenResponse, error) {
+	if form.Get("client_secret") == "" {
+		form.Del("client_secret")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("build token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := ssoHTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("POST token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("token endpoint returned %d: %s", resp.StatusCode, body)
+	}
+
+	var tokens oidcTokenResponse
+	if err := json.Unmarshal(body, &tokens); err != nil {
+		return nil, fmt.Errorf("unmarshal token: %w", err)
+	}
+	if tokens.IDToken == "" {
+		return nil, errors.New("token response has no id_token")
+	}
+	return &tokens, nil
+}
+
+func parseIDTokenClaims(idToken string) (*idTokenClaims, error) {
+	parts := strings.Split(idToken, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed id token")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("decode id token: %w", err)
+	}
+
+	var claims idTokenClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, fmt.Errorf("unmarshal id token: %w", err)
+	}
+	return &claims, nil
+}
+
+// fetchOIDCUserInfo fills in email and name from the userinfo endpoint when
+// the ID token does not carry them. The subject must match the ID token.
+func fetchOIDCUserInfo(ctx context.Context, endpoint, accessToken string, claims *idTokenClaims) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return fmt.Errorf("build userinfo request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+
+	resp, err := ssoHTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("GET userinfo: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("userinfo returned %d: %s", resp.StatusCode, body)
+	}
+
+	var info idTokenClaims
+	if err := json.Unmarshal(body, &info); err != nil {
+		return fmt.Errorf("unmarshal userinfo: %w", err)
+	}
+	if info.Subject != claims.Subject {
+		return errors.New("userinfo subject does not match id token")
+	}
+
+	claims.Email = info.Email
+	claims.EmailVerified = info.EmailVerified
+	if claims.Name == "" {
+		claims.Name = info.Name
+	}
+	return nil
+}
+
+// --- SAML ---
+
+// samlEntityID is the service provider entity ID for an organization.
+func samlEntityID(backendURL, slug string) string {
+	return backendURL + "/api/auth/sso/saml/metadata?org=" + url.QueryEscape(slug)
+}
+
+// parseSAMLCertificate accepts an IdP signing certificate as PEM or as the
+// bare base64 DER found in IdP metadata.
+func parseSAMLCertificate(data string) (*x509.Certificate, error) {
+	data = strings.TrimSpace(data)
+	if block, _ := pem.Decode([]byte(data)); block != nil {
+		return x509.ParseCertificate(block.Bytes)
+	}
+	der, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(data), ""))
+	if err != nil {
+		return nil, fmt.Errorf("decode certificate: %w", err)
+	}
+	return x509.ParseCertificate(der)
+}
+
+// buildSAMLAuthnRequest returns a deflated, base64-encoded AuthnRequest for
+// the HTTP-Redirect binding.
+func buildSAMLAuthnRequest(id, destination, spEntityID, acsURL string, now time.Time) (string, error) {
+	type nameIDPolicy struct {
+		Format      string `xml:"Format,attr"`
+		AllowCreate bool   `xml:"AllowCreate,attr"`
+	}
+	request := struct {
+		XMLName                     xml.Name     `xml:"samlp:AuthnRequest"`
+		XMLNSP                      string       `xml:"xmlns:samlp,attr"`
+		XMLNS                       string       `xml:"xmlns:saml,attr"`
+		ID                          string       `xml:"ID,attr"`
+		Version                     string       `xml:"Version,attr"`
+		IssueInstant                string       `xml:"IssueInstant,attr"`
+		Destination                 string       `xml:"Destination,attr"`
+		AssertionConsumerServiceURL string       `xml:"AssertionConsumerServiceURL,attr"`
+		ProtocolBinding             string       `xml:"ProtocolBinding,attr"`
+		Issuer                      string       `xml:"saml:Issuer"`
+		NameIDPolicy                nameIDPolicy `xml:"samlp:NameIDPolicy"`
+	}{
+		XMLNSP:                      "urn:oasis:names:tc:SAML:2.0:protocol",
+		XMLNS:                       "urn:oasis:names:tc:SAML:2.0:assertion",
+		ID:                          id,
+		Version:                     "2.0",
+		IssueInstant:                now.UTC().Format(time.RFC3339),
+		Destination:                 destination,
+		AssertionConsumerServiceURL: acsURL,
+		ProtocolBinding:             "urn:oasis:names:tc:SAML:2.0:bindings:HTTP-POST",
+		Issuer:                      spEntityID,
+		NameIDPolicy: nameIDPolicy{
+			Format:      "urn:oasis:names:tc:SAML:1.1:nameid-format:emailAddress",
+			AllowCreate: true,
+		},
+	}
+
+	raw, err := xml.Marshal(request)
+	if err != nil {
+		return "", fmt.Errorf("marshal authn request: %w", err)
+	}
+
+	var buf bytes.Buffer
+	fw, err := flate.NewWriter(&buf, flate.BestCompression)
+	if err != nil {
+		return "", fmt.Errorf("deflate authn request: %w", err)
+	}
+	if _, err := fw.Write(raw); err != nil {
+		return "", fmt.Errorf("deflate authn request: %w", err)
+	}
+	if err := fw.Close(); err != nil {
+		return "", fmt.Errorf("deflate authn request: %w", err)
+	}
+
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// samlExpectations are the values a SAML response must match.
+type samlExpectations struct {
+	IdPEntityID string
+	SPEntityID  string
+	ACSURL      string
+	RequestID   string
+	Certificate *x509.Certificate
+	Now         time.Time
+}
+
+// samlEmailAttributes are attribute names IdPs commonly use for the email.
+var samlEmailAttributes = []string{
+	"email",
+	"mail",
+	"emailaddress",
+	"http://schemas.xmlsoap.org/ws/2005/05/identity/claims/emailaddress",
+	"urn:oid:0.9.2342.19200300.100.1.3",
+}
+
+// validateSAMLResponse checks the signature, issuer, audience, recipient,
+// validity window and InResponseTo of a SAML response and returns the
+// asserted identity. Either the response or its assertion must be signed by
+// the configured certificate; only content covered by that signature is
+// read. Encrypted assertions are not supported.
+func validateSAMLResponse(raw []byte, want samlExpectations) (*models.SSOIdentity, error) {
+	doc := etree.NewDocument()
+	if err := doc.ReadFromBytes(raw); err != nil {
+		return nil, fmt.Errorf("parse response: %w", err)
+	}
+	response := doc.Root()
+	if response == nil || response.Tag != "Response" {
+		return nil, errors.New("document is not a SAML response")
+	}
+
+	validator := dsig.NewDefaultValidationContext(&dsig.MemoryX509CertificateStore{
+		Roots: []*x509.Certificate{want.Certificate},
+	})
+	validator.Clock = dsig.NewFakeClockAt(want.Now)
+
+	var assertion *etree.Element
+	if samlChild(response, "Signature") != nil {
+		signed, err := validator.Validate(response)
+		if err != nil {
+			return nil, fmt.Errorf("response signature: %w", err)
+		}
+		response = signed
+		assertion, err = samlOnlyChild(response, "Assertion")
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		unsigned, err := samlOnlyChild(response, "Assertion")
+		if err != nil {
+			return nil, err
+		}
+		// Carry namespaces declared on the response over to the assertion
+		// so it canonicalizes as it did when the IdP signed it.
+		nsCtx, err := etreeutils.NSBuildParentContext(unsigned)
+		if err != nil {
+			return nil, fmt.Errorf("assertion namespaces: %w", err)
+		}
+		detached, err := etreeutils.NSDetatch(nsCtx, unsigned)
+		if err != nil {
+			return nil, fmt.Errorf("assertion namespaces: %w", err)
+		}
+		assertion, err = validator.Validate(detached)
+		if err != nil {
+			return nil, fmt.Errorf("assertion signature: %w", err)
+		}
+	}
+
+	// Response-level attributes are only trusted when the response itself
+	// was signed, but a mismatch is a reason to reject either way.
+	if dest := response.SelectAttrValue("Destination", ""); dest != "" && dest != want.ACSURL {
+		return nil, fmt.Errorf("destination %q does not match", dest)
+	}
+	if irt := response.SelectAttrValue("InResponseTo", ""); irt != "" && irt != want.RequestID {
+		return nil, errors.New("response InResponseTo mismatch")
+	}
+	if status := response.FindElement("./Status/StatusCode"); status == nil ||
+		status.SelectAttrValue("Value", "") != "urn:oasis:names:tc:SAML:2.0:status:Success" {
+		return nil, errors.New("response status is not success")
+	}
+
+	if issuer := samlChild(assertion, "Issuer"); issuer == nil || strings.TrimSpace(issuer.Text()) != want.IdPEntityID {
+		return nil, errors.New("assertion issuer mismatch")
+	}
+
+	subject := samlChild(assertion, "Subject")
+	if subject == nil {
+		return nil, errors.New("assertion has no subject")
+	}
+	nameID := samlChild(subject, "NameID")
+	if nameID == nil || strings.TrimSpace(nameID.Text()) == "" {
+		return nil, errors.New("assertion has no NameID")
+	}
+
+	bearerOK := false
+	for _, sc := range subject.SelectElements("SubjectConfirmation") {
+		data := samlChild(sc, "SubjectConfirmationData")
+		if sc.SelectAttrValue("Method", "") != "urn:oasis:names:tc:SAML:2.0:cm:bearer" || data == nil {
+			continue
+		}
+		if data.SelectAttrValue("Recipient", "") != want.ACSURL ||
+			data.SelectAttrValue("InResponseTo", "") != want.RequestID {
+			continue
+		}
+		if notOnOrAfter, err := time.Parse(time.RFC3339, data.SelectAttrValue("NotOnOrAfter", "")); err != nil ||
+			!want.Now.Before(notOnOrAfter.Add(samlClockSkew)) {
+			continue
+		}
+		bearerOK = true
+	}
+	if !bearerOK {
+		return nil, errors.New("no valid bearer subject confirmation")
+	}
+
+	conditions := samlChild(assertion, "Conditions")
+	if conditions == nil {
+		return nil, errors.New("assertion has no conditions")
+	}
+	if v := conditions.SelectAttrValue("NotBefore", ""); v != "" {
+		notBefore, err := time.Parse(time.RFC3339, v)
+		if err != nil || want.Now.Add(samlClockSkew).Before(notBefore) {
+			return nil, errors.New("assertion is not yet valid")
+		}
+	}
+	if v := conditions.SelectAttrValue("NotOnOrAfter", ""); v != "" {
+		notOnOrAfter, err := time.Parse(time.RFC3339, v)
+		if err != nil || !want.Now.Before(notOnOrAfter.Add(samlClockSkew)) {
+			return nil, errors.New("assertion has expired")
+		}
+	}
+	audienceOK := false
+	for _, restriction := range conditions.SelectElements("AudienceRestriction") {
+		for _, aud := range restriction.SelectElements("Audience") {
+			if strings.TrimSpace(aud.Text()) == want.SPEntityID {
+				audienceOK = true
+			}
+		}
+	}
+	if !audienceOK {
+		return nil, errors.New("assertion audience mismatch")
+	}
+
+	identity := &models.SSOIdentity{Subject: strings.TrimSpace(nameID.Text())}
+	if statement := samlChild(assertion, "AttributeStatement"); statement != nil {
+		for _, attr := range statement.SelectElements("Attribute") {
+			name := strings.ToLower(attr.SelectAttrValue("Name", ""))
+			value := samlChild(attr, "AttributeValue")
+			if value == nil {
+				continue
+			}
+			for _, candidate := range samlEmailAttributes {
+				if name == candidate && identity.Email == "" {
+					identity.Email = strings.TrimSpace(value.Text())
+				}
+			}
+			if name == "displayname" || name == "name" || name == "http://schemas.microsoft.com/identity/claims/displayname" {
+				identity.Name = strPtr(strings.TrimSpace(value.Text()))
+			}
+		}
+	}
+	if identity.Email == "" && strings.Contains(identity.Subject, "@") {
+		identity.Email = identity.Subject
+	}
+	if identity.Email == "" {
+		return nil, errors.New("assertion has no email")
+	}
+
+	return identity, nil
+}
+
+func samlChild(el *etree.Element, tag string) *etree.Element {
+	for _, child := range el.ChildElements() {
+		if child.Tag == tag {
+			return child
+		}
+	}
+	return nil
+}
+
+func samlOnlyChild(el *etree.Element, tag string) (*etree.Element, error) {
+	var found *etree.Element
+	for _, child := range el.ChildElements() {
+		if child.Tag == tag {
+			if found != nil {
+				return nil, fmt.Errorf("response has more than one %s", tag)
+			}
+			found = child
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("response has no %s", tag)
+	}
+	return found, nil
+}
@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"crypto/x509"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/beevik/etree"
+	dsig "github.com/russellhaering/goxmldsig"
+	"github.com/russellhaering/goxmldsig/etreeutils"
+)
+
+func TestPKCEChallengeIsUnpaddedBase64URLSHA256(t *testing.T) {
+	// SHA-256("abc") = ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad
+	got := pkceChallenge("abc")
+	if want := "ungWv48Bz-pBQUDeXa4iI7ADYaOWF3qctBD_YfIAFa0"; got != want {
+		t.Fatalf("pkceChallenge = %q, want %q", got, want)
+	}
+}
+
+func TestIDTokenClaimsVerify(t *testing.T) {
+	now := time.Unix(1_800_000_000, 0)
+	claims := idTokenClaims{
+		Issuer:   "https://idp.example.com",
+		Subject:  "user-1",
+		Audience: oidcAudience{"other", "client-1"},
+		Expiry:   now.Add(time.Minute).Unix(),
+		Nonce:    "n-1",
+	}
+
+	if err := claims.verify("https://idp.example.com", "client-1", "n-1", now); err != nil {
+		t.Fatalf("expected valid claims, got %v", err)
+	}
+	if err := claims.verify("https://idp.example.com", "client-2", "n-1", now); err == nil {
+		t.Fatal("expected audience mismatch")
+	}
+	if err := claims.verify("https://idp.example.com", "client-1", "n-2", now); err == nil {
+		t.Fatal("expected nonce mismatch")
+	}
+	if err := claims.verify("https://idp.example.com", "client-1", "n-1", now.Add(2*time.Minute)); err == nil {
+		t.Fatal("expected expired token")
+	}
+}
+
+const testSAMLResponse = `<samlp:Response xmlns:samlp="urn:oasis:names:tc:SAML:2.0:protocol" xmlns:saml="urn:oasis:names:tc:SAML:2.0:assertion" ID="_resp" Version="2.0" IssueInstant="2026-10-17T10:00:00Z" Destination="https://api.example.com/api/auth/sso/saml/acs" InResponseTo="_req1">
+<saml:Issuer>https://idp.example.com</saml:Issuer>
+<samlp:Status><samlp:StatusCode Value="urn:oasis:names:tc:SAML:2.0:status:Success"/></samlp:Status>
+<saml:Assertion ID="_assert" Version="2.0" IssueInstant="2026-10-17T10:00:00Z">
+<saml:Issuer>https://idp.example.com</saml:Issuer>
+<saml:Subject>
+<saml:NameID Format="urn:oasis:names:tc:SAML:1.1:nameid-format:emailAddress">jane@acme.com</saml:NameID>
+<saml:SubjectConfirmation Method="urn:oasis:names:tc:SAML:2.0:cm:bearer">
+<saml:SubjectConfirmationData InResponseTo="_req1" Recipient="https://api.example.com/api/auth/sso/saml/acs" NotOnOrAfter="2026-10-17T10:05:00Z"/>
+</saml:SubjectConfirmation>
+</saml:Subject>
+<saml:Conditions NotBefore="2026-10-17T09:59:00Z" NotOnOrAfter="2026-10-17T10:05:00Z">
+<saml:AudienceRestriction><saml:Audience>https://api.example.com/api/auth/sso/saml/metadata?org=acme</saml:Audience></saml:AudienceRestriction>
+</saml:Conditions>
+<saml:AttributeStatement>
+<saml:Attribute Name="displayName"><saml:AttributeValue>Jane Doe</saml:AttributeValue></saml:Attribute>
+</saml:AttributeStatement>
+</saml:Assertion>
+</samlp:Response>`
+
+// signTestSAMLResponse signs the assertion of testSAMLResponse with a fresh
+// key and returns the serialized response with the IdP certificate.
+func signTestSAMLResponse(t *testing.T) ([]byte, *x509.Certificate) {
+	t.Helper()
+
+	ks := dsig.RandomKeyStoreForTest()
+	_, certDER, err := ks.GetKeyPair()
+	if err != nil {
+		t.Fatalf("GetKeyPair: %v", err)
+	}
+	cert, err := x509.ParseCertificate(certDER)
+	if err != nil {
+		t.Fatalf("ParseCertificate: %v", err)
+	}
+
+	doc := etree.NewDocument()
+	if err := doc.ReadFromString(testSAMLResponse); err != nil {
+		t.Fatalf("parse response: %v", err)
+	}
+	response := doc.Root()
+	assertion := response.SelectElement("Assertion")
+
+	// Sign the assertion standalone with the response's namespaces in
+	// scope, using exclusive canonicalization as IdPs do.
+	nsCtx, err := etreeutils.NSBuildParentContext(assertion)
+	if err != nil {
+		t.Fatalf("NSBuildParentContext: %v", err)
+	}
+	detached, err := etreeutils.NSDetatch(nsCtx, assertion)
+	if err != nil {
+		t.Fatalf("NSDetatch: %v", err)
+	}
+	signer := dsig.NewDefaultSigningContext(ks)
+	signer.Canonicalizer = dsig.MakeC14N10ExclusiveCanonicalizerWithPrefixList("")
+	signed, err := signer.SignEnveloped(detached)
+	if err != nil {
+		t.Fatalf("SignEnveloped: %v", err)
+	}
+	response.RemoveChild(assertion)
+	response.AddChild(signed)
+
+	raw, err := doc.WriteToBytes()
+	if err != nil {
+		t.Fatalf("serialize response: %v", err)
+	}
+	return raw, cert
+}
+
+func testSAMLExpectations(cert *x509.Certificate) samlExpectations {
+	return samlExpectations{
+		IdPEntityID: "https://idp.example.com",
+		SPEntityID:  "https://api.example.com/api/auth/sso/saml/metadata?org=acme",
+		ACSURL:      "https://api.example.com/api/auth/sso/saml/acs",
+		RequestID:   "_req1",
+		Certificate: cert,
+		Now:         time.Date(2026, 10, 17, 10, 1, 0, 0, time.UTC),
+	}
+}
+
+func TestValidateSAMLResponseAcceptsSignedAssertion(t *testing.T) {
+	raw, cert := signTestSAMLResponse(t)
+
+	identity, err := validateSAMLResponse(raw, testSAMLExpectations(cert))
+	if err != nil {
+		t.Fatalf("validateSAMLResponse: %v", err)
+	}
+	if identity.Email != "jane@acme.com" || identity.Name == nil || *identity.Name != "Jane Doe" {
+		t.Fatalf("unexpected identity: %+v", identity)
+	}
+}
+
+func TestValidateSAMLResponseRejectsTamperedAssertion(t *testing.T) {
+	raw, cert := signTestSAMLResponse(t)
+	tampered := []byte(strings.Replace(string(raw), "jane@acme.com", "ceo@acme.com", 1))
+
+	if _, err := validateSAMLResponse(tampered, testSAMLExpectations(cert)); err == nil {
+		t.Fatal("expected tampered assertion to be rejected")
+	}
+}
+
+func TestValidateSAMLResponseChecksBinding(t *testing.T) {
+	raw, cert := signTestSAMLResponse(t)
+
+	cases := map[string]func(*samlExpectations){
+		"audience": func(w *samlExpectations) {
+			w.SPEntityID = "https://api.example.com/api/auth/sso/saml/metadata?org=other"
+		},
+		"request id": func(w *samlExpectations) { w.RequestID = "_req2" },
+		"issuer":     func(w *samlExpectations) { w.IdPEntityID = "https://evil.example.com" },
+		"expired":    func(w *samlExpectations) { w.Now = w.Now.Add(10 * time.Minute) },
+		"untrusted key": func(w *samlExpectations) {
+			_, other := signTestSAMLResponse(t)
+			w.Certificate = other
+		},
+	}
+	for name, mutate := range cases {
+		t.Run(name, func(t *testing.T) {
+			want := testSAMLExpectations(cert)
+			mutate(&want)
+			if _, err := validateSAMLResponse(raw, want); err == nil {
+				t.Fatalf("expected %s mismatch to be rejected", name)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/realtime"
+)
+
+// DefaultStreamConnectionLimit applies when the tenant's plan cannot be
+// loaded.
+const DefaultStreamConnectionLimit = 3
+
+// StreamPlanStore resolves the plan whose streaming connection limit applies
+// to a user.
+type StreamPlanStore interface {
+	GetEffectivePlan(ctx context.Context, userID int64) (*models.MembershipPlan, error)
+}
+
+// StreamLimits caps each tenant's concurrent streaming connections at their
+// plan's max_streaming_connections. A nil *StreamLimits allows everything.
+type StreamLimits struct {
+	Limiter *realtime.StreamLimiter
+	Plans   StreamPlanStore
+}
+
+// Acquire counts a streaming connection of kind for the user, or answers
+// 429 and returns false when they already hold as many as their plan
+// allows. Call release once the connection closes.
+func (l *StreamLimits) Acquire(w http.ResponseWriter, r *http.Request, userID int64, kind string) (release func(), ok bool) {
+	if l == nil || l.Limiter == nil {
+		return func() {}, true
+	}
+
+	limit := DefaultStreamConnectionLimit
+	if l.Plans != nil {
+		plan, err := l.Plans.GetEffectivePlan(r.Context(), userID)
+		switch {
+		case err != nil:
+			log.Printf("StreamLimits: failed to load plan for user_id=%d: %v", userID, err)
+		case plan.MaxStreamingConnections == nil:
+			limit = 0
+		default:
+			limit = *plan.MaxStreamingConnections
+		}
+	}
+
+	release, ok = l.Limiter.Acquire(userID, kind, limit)
+	if !ok {
+		log.Printf("StreamLimits: refused %s for user_id=%d at its limit of %d", kind, userID, limit)
+		http.Error(w, "too many open streaming connections for your plan", http.StatusTooManyRequests)
+		return nil, false
+	}
+	return release, true
+}
@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/realtime"
+)
+
+type fakeStreamPlans map[int64]*models.MembershipPlan
+
+func (f fakeStreamPlans) GetEffectivePlan(ctx context.Context, userID int64) (*models.MembershipPlan, error) {
+	return f[userID], nil
+}
+
+func TestStreamLimitsFollowThePlan(t *testing.T) {
+	one := 1
+	limits := &StreamLimits{
+		Limiter: realtime.NewStreamLimiter(),
+		Plans: fakeStreamPlans{
+			1: {Slug: "free", MaxStreamingConnections: &one},
+			2: {Slug: "enterprise"},
+		},
+	}
+	acquire := func(userID int64) (func(), int) {
+		rec := httptest.NewRecorder()
+		release, ok := limits.Acquire(rec, httptest.NewRequest(http.MethodGet, "/ws", nil), userID, realtime.StreamWebSocket)
+		if !ok {
+			return nil, rec.Code
+		}
+		return release, http.StatusOK
+	}
+
+	release, code := acquire(1)
+	if code != http.StatusOK {
+		t.Fatalf("expected the first stream to be allowed, got %d", code)
+	}
+	if _, code := acquire(1); code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429 past the plan limit, got %d", code)
+	}
+	release()
+	if _, code := acquire(1); code != http.StatusOK {
+		t.Fatalf("expected a stream after one closed, got %d", code)
+	}
+
+	// Plans without a limit are unlimited.
+	for i := 0; i < 5; i++ {
+		if _, code := acquire(2); code != http.StatusOK {
+			t.Fatalf("expected stream %d to be allowed, got %d", i, code)
+		}
+	}
+}
@@ -6,8 +6,12 @@ import (
 	"io"
 	"log"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/events"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/middleware"
 	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
 	"github.com/PortNumber53/mcp-jira-thing/backend/internal/store"
 	stripeClient "github.com/PortNumber53/mcp-jira-thing/backend/internal/stripe"
@@ -29,23 +33,47 @@ type SubscriptionLookupStore interface {
 	GetSubscriptionByCustomerID(ctx context.Context, customerID string) (*models.Subscription, error)
 }
 
+// StripeCustomerStore persists the Stripe customer associated with a user
+type StripeCustomerStore interface {
+	SetStripeCustomerID(ctx context.Context, userID int64, customerID string) error
+}
+
+// DisputeStore records charge disputes and the suspensions they cause
+type DisputeStore interface {
+	UpsertDispute(ctx context.Context, d *models.Dispute) (*models.Dispute, error)
+	SuspendUserForDispute(ctx context.Context, disputeID, userID int64) error
+	ReleaseDisputeSuspension(ctx context.Context, userID int64) (bool, error)
+}
+
 // StripeHandler holds dependencies for Stripe-related handlers
 type StripeHandler struct {
 	PlanStore     *store.PlanStore
 	BillingStore  BillingStore
 	SubLookup     SubscriptionLookupStore
 	UserStore     UserStore
+	Customers     StripeCustomerStore
+	Events        EventPublisher
 	Stripe        *stripeClient.Client
 	WebhookSecret string
+
+	// TestClocks is set in non-production profiles; new customers are then
+	// created on their own Stripe test clock.
+	TestClocks StripeTestClockStore
+
+	// Disputes records charge disputes; when SuspendOnDispute is set the
+	// disputed account is suspended until its open disputes are closed.
+	Disputes         DisputeStore
+	SuspendOnDispute bool
 }
 
 // NewStripeHandler creates a new StripeHandler
-func NewStripeHandler(planStore *store.PlanStore, billingStore BillingStore, subLookup SubscriptionLookupStore, userStore UserStore, stripe *stripeClient.Client, webhookSecret string) *StripeHandler {
+func NewStripeHandler(planStore *store.PlanStore, billingStore BillingStore, subLookup SubscriptionLookupStore, userStore UserStore, customers StripeCustomerStore, stripe *stripeClient.Client, webhookSecret string) *StripeHandler {
 	return &StripeHandler{
 		PlanStore:     planStore,
 		BillingStore:  billingStore,
 		SubLookup:     subLookup,
 		UserStore:     userStore,
+		Customers:     customers,
 		Stripe:        stripe,
 		WebhookSecret: webhookSecret,
 	}
@@ -53,10 +81,16 @@ func NewStripeHandler(planStore *store.PlanStore, billingStore BillingStore, sub
 
 // RegisterRoutes registers Stripe/billing routes
 func (h *StripeHandler) RegisterRoutes(router chi.Router) {
-	router.Get("/api/plans", h.ListPlans())
+	router.With(middleware.ETag).Get("/api/plans", h.ListPlans())
 	router.Post("/api/checkout", h.CreateCheckout())
 	router.Post("/api/webhooks/stripe", h.HandleWebhook())
 	router.Get("/api/billing/current-plan", h.GetCurrentPlan())
+	router.Post("/api/billing/pause", h.PauseSubscription())
+	router.Post("/api/billing/resume", h.ResumeSubscription())
+	router.Get("/api/billing/preview-change", h.PreviewPlanChange())
+	router.Get("/api/billing/payment-methods", h.ListPaymentMethods())
+	router.Post("/api/billing/payment-methods/setup-intent", h.CreateSetupIntent())
+	router.Post("/api/billing/payment-methods/default", h.SetDefaultPaymentMethod())
 }
 
 // ListPlans returns all available membership plans with pricing
@@ -85,6 +119,7 @@ func (h *StripeHandler) CreateCheckout() http.HandlerFunc {
 			return
 		}
 
+		req.UserEmail = tokenEmail(r, req.UserEmail)
 		if req.UserEmail == "" || req.PlanSlug == "" {
 			http.Error(w, "user_email and plan_slug are required", http.StatusBadRequest)
 			return
@@ -103,19 +138,41 @@ func (h *StripeHandler) CreateCheckout() http.HandlerFunc {
 			return
 		}
 
-		version, err := h.PlanStore.GetActivePlanVersion(r.Context(), plan.ID)
+		params := stripeClient.CheckoutSessionParams{
+			CustomerEmail: req.UserEmail,
+			SuccessURL:    req.SuccessURL,
+			CancelURL:     req.CancelURL,
+		}
+
+		// Reuse the user's Stripe customer when we already know it so repeat
+		// checkouts don't create duplicate customers.
+		var userID int64
+		if user, err := h.UserStore.GetUserByEmail(r.Context(), req.UserEmail); err == nil {
+			userID = user.ID
+			params.ClientReferenceID = strconv.FormatInt(user.ID, 10)
+			if user.StripeCustomerID != nil {
+				params.CustomerID = *user.StripeCustomerID
+			} else if h.TestClocks != nil {
+				if customerID, err := h.ensureTestClockCustomer(r.Context(), user); err == nil {
+					params.CustomerID = customerID
+				} else {
+					log.Printf("CreateCheckout: test clock customer for %s failed, falling back to customer_email: %v", req.UserEmail, err)
+				}
+			}
+		} else {
+			log.Printf("CreateCheckout: user lookup failed for %s, falling back to customer_email: %v", req.UserEmail, err)
+		}
+
+		// A price rollout may offer this user the plan's new version.
+		version, err := h.PlanStore.ResolvePlanVersion(r.Context(), plan.ID, userID, req.UserEmail)
 		if err != nil || version.StripePriceID == nil {
 			log.Printf("CreateCheckout: no active price for plan %s: %v", req.PlanSlug, err)
 			http.Error(w, "plan not configured for billing", http.StatusInternalServerError)
 			return
 		}
+		params.PriceID = *version.StripePriceID
 
-		sessionID, sessionURL, err := h.Stripe.CreateCheckoutSession(
-			req.UserEmail,
-			*version.StripePriceID,
-			req.SuccessURL,
-			req.CancelURL,
-		)
+		sessionID, sessionURL, err := h.Stripe.CreateCheckoutSession(params)
 		if err != nil {
 			log.Printf("CreateCheckout: Stripe error: %v", err)
 			http.Error(w, "failed to create checkout session", http.StatusInternalServerError)
@@ -133,7 +190,7 @@ func (h *StripeHandler) CreateCheckout() http.HandlerFunc {
 // GetCurrentPlan returns the user's current membership plan
 func (h *StripeHandler) GetCurrentPlan() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		email := strings.TrimSpace(r.URL.Query().Get("email"))
+		email := tokenEmail(r, r.URL.Query().Get("email"))
 		if email == "" {
 			http.Error(w, "email query parameter is required", http.StatusBadRequest)
 			return
@@ -171,11 +228,229 @@ func (h *StripeHandler) GetCurrentPlan() http.HandlerFunc {
 			}
 		}
 
+		// A paused subscription keeps its plan on record but is only
+		// entitled to the free tier until collection resumes.
+		result["paused"] = sub.IsPaused()
+		if sub.IsPaused() {
+			result["subscribed_plan_slug"] = result["plan_slug"]
+			result["subscribed_plan_name"] = result["plan_name"]
+			result["plan_slug"] = "free"
+			result["plan_name"] = "Free"
+			result["tier"] = 0
+			result["pause_behavior"] = *sub.PauseBehavior
+			result["paused_at"] = sub.PausedAt
+			result["pause_resumes_at"] = sub.PauseResumesAt
+		}
+
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(result)
 	}
 }
 
+// PreviewPlanChange returns what switching the user's subscription to
+// another plan would cost right now, prorated for the rest of the current
+// billing period, using Stripe's upcoming invoice. Nothing is changed.
+func (h *StripeHandler) PreviewPlanChange() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		email := tokenEmail(r, r.URL.Query().Get("email"))
+		planSlug := strings.TrimSpace(r.URL.Query().Get("plan_slug"))
+		if email == "" || planSlug == "" {
+			http.Error(w, "email and plan_slug query parameters are required", http.StatusBadRequest)
+			return
+		}
+
+		sub, err := h.BillingStore.GetSubscription(r.Context(), email)
+		if err != nil {
+			log.Printf("PreviewPlanChange: failed to load subscription for %s: %v", email, err)
+			http.Error(w, "failed to get subscription", http.StatusInternalServerError)
+			return
+		}
+		if sub == nil || sub.StripeSubscriptionID == "" {
+			http.Error(w, "no active subscription; subscribe through checkout instead", http.StatusNotFound)
+			return
+		}
+
+		plan, err := h.PlanStore.GetPlanBySlug(r.Context(), planSlug)
+		if err != nil {
+			http.Error(w, "plan not found", http.StatusNotFound)
+			return
+		}
+		if plan.Tier == 0 {
+			http.Error(w, "switching to the free plan cancels the subscription; there is no charge to preview", http.StatusBadRequest)
+			return
+		}
+
+		version, err := h.PlanStore.ResolvePlanVersion(r.Context(), plan.ID, sub.UserID, email)
+		if err != nil || version.StripePriceID == nil {
+			log.Printf("PreviewPlanChange: no active price for plan %s: %v", planSlug, err)
+			http.Error(w, "plan not configured for billing", http.StatusInternalServerError)
+			return
+		}
+		if *version.StripePriceID == sub.StripePriceID {
+			http.Error(w, "subscription is already on this plan", http.StatusConflict)
+			return
+		}
+
+		preview, err := h.Stripe.PreviewSubscriptionChange(sub.StripeSubscriptionID, *version.StripePriceID, time.Now())
+		if err != nil {
+			log.Printf("PreviewPlanChange: Stripe error: %v", err)
+			http.Error(w, "failed to preview plan change", http.StatusBadGateway)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"plan_slug":        plan.Slug,
+			"plan_name":        plan.Name,
+			"plan_version_id":  version.ID,
+			"price_cents":      version.PriceCents,
+			"billing_interval": version.BillingInterval,
+			"preview":          preview,
+			// Positive amounts are charged for the change, negative ones are
+			// credited against the next invoice.
+			"immediate_amount_cents": preview.ProrationCents,
+		})
+	}
+}
+
+type pauseSubscriptionPayload struct {
+	UserEmail string     `json:"user_email"`
+	Behavior  string     `json:"behavior"`
+	ResumesAt *time.Time `json:"resumes_at,omitempty"`
+}
+
+var validPauseBehaviors = map[string]bool{
+	"keep_as_draft":      true,
+	"mark_uncollectible": true,
+	"void":               true,
+}
+
+// PauseSubscription pauses payment collection on the user's active subscription
+func (h *StripeHandler) PauseSubscription() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var payload pauseSubscriptionPayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			http.Error(w, "invalid JSON payload", http.StatusBadRequest)
+			return
+		}
+
+		email := tokenEmail(r, payload.UserEmail)
+		if email == "" {
+			http.Error(w, "user_email is required", http.StatusBadRequest)
+			return
+		}
+		if payload.Behavior == "" {
+			payload.Behavior = "void"
+		}
+		if !validPauseBehaviors[payload.Behavior] {
+			http.Error(w, "behavior must be one of keep_as_draft, mark_uncollectible, void", http.StatusBadRequest)
+			return
+		}
+		if payload.ResumesAt != nil && !payload.ResumesAt.After(time.Now()) {
+			http.Error(w, "resumes_at must be in the future", http.StatusBadRequest)
+			return
+		}
+
+		sub, err := h.BillingStore.GetSubscription(r.Context(), email)
+		if err != nil {
+			log.Printf("PauseSubscription: failed to load subscription for %s: %v", email, err)
+			http.Error(w, "failed to get subscription", http.StatusInternalServerError)
+			return
+		}
+		if sub == nil {
+			http.Error(w, "no active subscription", http.StatusNotFound)
+			return
+		}
+		if sub.IsPaused() {
+			http.Error(w, "subscription is already paused", http.StatusConflict)
+			return
+		}
+
+		if err := h.Stripe.PauseSubscription(sub.StripeSubscriptionID, payload.Behavior, payload.ResumesAt); err != nil {
+			log.Printf("PauseSubscription: Stripe error: %v", err)
+			http.Error(w, "failed to pause subscription", http.StatusBadGateway)
+			return
+		}
+
+		sub.PauseBehavior = &payload.Behavior
+		sub.PauseResumesAt = payload.ResumesAt
+		if err := h.BillingStore.UpdateSubscription(r.Context(), sub); err != nil {
+			// The webhook will reconcile local state, so don't fail the request.
+			log.Printf("PauseSubscription: failed to persist pause state: %v", err)
+		}
+
+		h.publishEvent(r.Context(), events.SubscriptionChanged{
+			UserID:               sub.UserID,
+			StripeSubscriptionID: sub.StripeSubscriptionID,
+			Change:               events.SubscriptionPaused,
+			Status:               sub.Status,
+			CancelAtPeriodEnd:    sub.CancelAtPeriodEnd,
+			Paused:               true,
+		})
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"ok":               true,
+			"paused":           true,
+			"pause_behavior":   payload.Behavior,
+			"pause_resumes_at": payload.ResumesAt,
+		})
+	}
+}
+
+// ResumeSubscription resumes payment collection on a paused subscription
+func (h *StripeHandler) ResumeSubscription() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var payload struct {
+			UserEmail string `json:"user_email"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			http.Error(w, "invalid JSON payload", http.StatusBadRequest)
+			return
+		}
+
+		email := tokenEmail(r, payload.UserEmail)
+		if email == "" {
+			http.Error(w, "user_email is required", http.StatusBadRequest)
+			return
+		}
+
+		sub, err := h.BillingStore.GetSubscription(r.Context(), email)
+		if err != nil {
+			log.Printf("ResumeSubscription: failed to load subscription for %s: %v", email, err)
+			http.Error(w, "failed to get subscription", http.StatusInternalServerError)
+			return
+		}
+		if sub == nil || !sub.IsPaused() {
+			http.Error(w, "no paused subscription", http.StatusNotFound)
+			return
+		}
+
+		if err := h.Stripe.ResumeSubscription(sub.StripeSubscriptionID); err != nil {
+			log.Printf("ResumeSubscription: Stripe error: %v", err)
+			http.Error(w, "failed to resume subscription", http.StatusBadGateway)
+			return
+		}
+
+		sub.PauseBehavior = nil
+		sub.PauseResumesAt = nil
+		if err := h.BillingStore.UpdateSubscription(r.Context(), sub); err != nil {
+			log.Printf("ResumeSubscription: failed to persist resume state: %v", err)
+		}
+
+		h.publishEvent(r.Context(), events.SubscriptionChanged{
+			UserID:               sub.UserID,
+			StripeSubscriptionID: sub.StripeSubscriptionID,
+			Change:               events.SubscriptionResumed,
+			Status:               sub.Status,
+			CancelAtPeriodEnd:    sub.CancelAtPeriodEnd,
+		})
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"ok": true, "paused": false})
+	}
+}
+
 // HandleWebhook processes Stripe webhook events
 func (h *StripeHandler) HandleWebhook() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -185,6 +460,15 @@ func (h *StripeHandler) HandleWebhook() http.HandlerFunc {
 			return
 		}
 
+		// Without a signing secret (local setups), events are accepted as is.
+		if h.WebhookSecret != "" {
+			if err := stripeClient.VerifyWebhookSignature(body, r.Header.Get("Stripe-Signature"), h.WebhookSecret, stripeClient.DefaultWebhookTolerance); err != nil {
+				log.Printf("Webhook: rejected event: %v", err)
+				http.Error(w, "invalid webhook signature", http.StatusBadRequest)
+				return
+			}
+		}
+
 		event, err := stripeClient.ConstructWebhookEvent(body)
 		if err != nil {
 			log.Printf("Webhook: failed to parse event: %v", err)
@@ -214,6 +498,11 @@ func (h *StripeHandler) HandleWebhook() http.HandlerFunc {
 		case "invoice.payment_failed":
 			h.handlePaymentFailed(r.Context(), event)
 
+		case "charge.dispute.created",
+			"charge.dispute.updated",
+			"charge.dispute.closed":
+			h.handleDispute(r.Context(), eventType, event)
+
 		default:
 			log.Printf("[webhook] Unhandled event type: %s", eventType)
 		}
@@ -231,6 +520,14 @@ func (h *StripeHandler) handleCheckoutCompleted(ctx context.Context, event map[s
 	subscriptionID, _ := obj["subscription"].(string)
 	customerID, _ := obj["customer"].(string)
 
+	// Sessions created for an existing customer carry the email only in
+	// customer_details.
+	if customerEmail == "" {
+		if details, ok := obj["customer_details"].(map[string]interface{}); ok {
+			customerEmail, _ = details["email"].(string)
+		}
+	}
+
 	if customerEmail == "" || subscriptionID == "" {
 		log.Printf("[webhook] checkout.session.completed: missing email or subscription ID")
 		return
@@ -244,6 +541,8 @@ func (h *StripeHandler) handleCheckoutCompleted(ctx context.Context, event map[s
 		return
 	}
 
+	h.backfillCustomerID(ctx, user.ID, customerID)
+
 	sub := &models.Subscription{
 		UserID:               user.ID,
 		StripeCustomerID:     customerID,
@@ -254,6 +553,13 @@ func (h *StripeHandler) handleCheckoutCompleted(ctx context.Context, event map[s
 	if err := h.BillingStore.SaveSubscription(ctx, sub); err != nil {
 		log.Printf("[webhook] checkout: failed to save subscription: %v", err)
 	}
+
+	h.publishEvent(ctx, events.SubscriptionChanged{
+		UserID:               user.ID,
+		StripeSubscriptionID: subscriptionID,
+		Change:               events.SubscriptionCreated,
+		Status:               sub.Status,
+	})
 }
 
 func (h *StripeHandler) handleSubscriptionUpdated(ctx context.Context, event map[string]interface{}) {
@@ -283,6 +589,10 @@ func (h *StripeHandler) handleSubscriptionUpdated(ctx context.Context, event map
 	sub.StripeCustomerID = customerID
 	sub.CancelAtPeriodEnd = cancelAtPeriodEnd
 
+	sub.PauseBehavior, sub.PauseResumesAt = extractPauseCollection(obj)
+
+	h.backfillCustomerID(ctx, sub.UserID, customerID)
+
 	if err := h.BillingStore.UpdateSubscription(ctx, sub); err != nil {
 		log.Printf("[webhook] subscription.updated: failed to update: %v", err)
 	}
@@ -294,6 +604,15 @@ func (h *StripeHandler) handleSubscriptionUpdated(ctx context.Context, event map
 			h.PlanStore.UpdateSubscriptionPlanVersion(ctx, sub.ID, version.ID, priceID)
 		}
 	}
+
+	h.publishEvent(ctx, events.SubscriptionChanged{
+		UserID:               sub.UserID,
+		StripeSubscriptionID: subscriptionID,
+		Change:               events.SubscriptionUpdated,
+		Status:               status,
+		CancelAtPeriodEnd:    cancelAtPeriodEnd,
+		Paused:               sub.IsPaused(),
+	})
 }
 
 func (h *StripeHandler) handleSubscriptionDeleted(ctx context.Context, event map[string]interface{}) {
@@ -310,9 +629,20 @@ func (h *StripeHandler) handleSubscriptionDeleted(ctx context.Context, event map
 	}
 
 	sub.Status = "canceled"
+	if sub.CanceledAt == nil {
+		canceledAt := time.Now()
+		sub.CanceledAt = &canceledAt
+	}
 	if err := h.BillingStore.UpdateSubscription(ctx, sub); err != nil {
 		log.Printf("[webhook] subscription.deleted: failed to update: %v", err)
 	}
+
+	h.publishEvent(ctx, events.SubscriptionChanged{
+		UserID:               sub.UserID,
+		StripeSubscriptionID: subscriptionID,
+		Change:               events.SubscriptionCanceled,
+		Status:               sub.Status,
+	})
 }
 
 func (h *StripeHandler) handlePaymentSucceeded(ctx context.Context, event map[string]interface{}) {
@@ -343,12 +673,20 @@ func (h *StripeHandler) handlePaymentSucceeded(ctx context.Context, event map[st
 		payment.UserID = sub.UserID
 		subID := sub.ID
 		payment.SubscriptionID = &subID
+		h.backfillCustomerID(ctx, sub.UserID, customerID)
 	}
 
 	if payment.UserID > 0 {
 		if err := h.BillingStore.SavePayment(ctx, payment); err != nil {
 			log.Printf("[webhook] payment.succeeded: failed to save: %v", err)
 		}
+		h.publishEvent(ctx, events.PaymentRecorded{
+			UserID:    payment.UserID,
+			InvoiceID: invoiceID,
+			Amount:    payment.Amount,
+			Currency:  payment.Currency,
+			Status:    payment.Status,
+		})
 	}
 }
 
@@ -379,7 +717,117 @@ func (h *StripeHandler) handlePaymentFailed(ctx context.Context, event map[strin
 		if err := h.BillingStore.SavePayment(ctx, payment); err != nil {
 			log.Printf("[webhook] payment.failed: failed to save: %v", err)
 		}
+
+		h.publishEvent(ctx, events.PaymentRecorded{
+			UserID:    sub.UserID,
+			InvoiceID: invoiceID,
+			Amount:    payment.Amount,
+			Currency:  payment.Currency,
+			Status:    payment.Status,
+		})
+	}
+}
+
+// backfillCustomerID stores the Stripe customer ID on the user row so later
+// checkouts can reuse it. Failures are logged and otherwise ignored.
+func (h *StripeHandler) backfillCustomerID(ctx context.Context, userID int64, customerID string) {
+	if h.Customers == nil || userID == 0 || customerID == "" {
+		return
+	}
+	if err := h.Customers.SetStripeCustomerID(ctx, userID, customerID); err != nil {
+		log.Printf("[webhook] failed to backfill stripe customer %s for user %d: %v", customerID, userID, err)
+	}
+}
+
+// publishEvent announces a billing change when an event publisher is
+// configured.
+func (h *StripeHandler) publishEvent(ctx context.Context, ev events.Event) {
+	if h.Events == nil {
+		return
+	}
+	h.Events.Publish(ctx, ev)
+}
+
+func (h *StripeHandler) handleDispute(ctx context.Context, eventType string, event map[string]interface{}) {
+	if h.Disputes == nil {
+		log.Printf("[webhook] %s: no dispute store configured", eventType)
+		return
+	}
+
+	data, _ := event["data"].(map[string]interface{})
+	obj, _ := data["object"].(map[string]interface{})
+
+	dispute := &models.Dispute{}
+	dispute.StripeDisputeID, _ = obj["id"].(string)
+	dispute.StripeChargeID, _ = obj["charge"].(string)
+	dispute.Reason, _ = obj["reason"].(string)
+	dispute.Status, _ = obj["status"].(string)
+	currency, _ := obj["currency"].(string)
+	dispute.Currency = strings.ToLower(currency)
+	amount, _ := obj["amount"].(float64)
+	dispute.Amount = int(amount)
+	if dispute.StripeDisputeID == "" || dispute.StripeChargeID == "" {
+		log.Printf("[webhook] %s: missing dispute or charge ID", eventType)
+		return
+	}
+
+	// Disputes reference the charge, not the customer.
+	if h.Stripe != nil {
+		customerID, err := h.Stripe.GetChargeCustomer(dispute.StripeChargeID)
+		if err != nil {
+			log.Printf("[webhook] %s: failed to look up charge %s: %v", eventType, dispute.StripeChargeID, err)
+		}
+		dispute.StripeCustomerID = customerID
+	}
+
+	saved, err := h.Disputes.UpsertDispute(ctx, dispute)
+	if err != nil {
+		log.Printf("[webhook] %s: failed to record dispute %s: %v", eventType, dispute.StripeDisputeID, err)
+		return
+	}
+
+	log.Printf("[webhook] Dispute %s on charge %s: status=%s, amount=%d %s", saved.StripeDisputeID, saved.StripeChargeID, saved.Status, saved.Amount, saved.Currency)
+
+	change := events.DisputeUpdated
+	switch {
+	case eventType == "charge.dispute.created":
+		change = events.DisputeCreated
+	case saved.IsClosed():
+		change = events.DisputeClosed
+	}
+
+	var userID int64
+	if saved.UserID != nil {
+		userID = *saved.UserID
 	}
+	suspended := saved.Suspended
+	if userID > 0 {
+		if saved.IsClosed() {
+			released, err := h.Disputes.ReleaseDisputeSuspension(ctx, userID)
+			if err != nil {
+				log.Printf("[webhook] %s: failed to release suspension of user %d: %v", eventType, userID, err)
+			}
+			suspended = suspended && !released
+		} else if h.SuspendOnDispute && !saved.Suspended {
+			if err := h.Disputes.SuspendUserForDispute(ctx, saved.ID, userID); err != nil {
+				log.Printf("[webhook] %s: failed to suspend user %d: %v", eventType, userID, err)
+			} else {
+				suspended = true
+			}
+		}
+	}
+
+	h.publishEvent(ctx, events.DisputeChanged{
+		UserID:          userID,
+		StripeDisputeID: saved.StripeDisputeID,
+		ChargeID:        saved.StripeChargeID,
+		Change:          change,
+		Status:          saved.Status,
+		Reason:          saved.Reason,
+		Amount:          saved.Amount,
+		Currency:        saved.Currency,
+		Suspended:       suspended,
+	})
 }
 
 // Helper to find a subscription by Stripe subscription ID
@@ -392,6 +840,25 @@ func (h *StripeHandler) findSubscriptionByCustomerID(ctx context.Context, custom
 	return h.SubLookup.GetSubscriptionByCustomerID(ctx, customerID)
 }
 
+// extractPauseCollection returns the pause_collection behavior and resume time
+// from a subscription object. Both are nil when collection is not paused.
+func extractPauseCollection(obj map[string]interface{}) (*string, *time.Time) {
+	pause, ok := obj["pause_collection"].(map[string]interface{})
+	if !ok {
+		return nil, nil
+	}
+	behavior, _ := pause["behavior"].(string)
+	if behavior == "" {
+		return nil, nil
+	}
+	var resumesAt *time.Time
+	if ts, ok := pause["resumes_at"].(float64); ok && ts > 0 {
+		t := time.Unix(int64(ts), 0).UTC()
+		resumesAt = &t
+	}
+	return &behavior, resumesAt
+}
+
 // extractPriceID extracts the price ID from a subscription object's items
 func extractPriceID(obj map[string]interface{}) string {
 	items, ok := obj["items"].(map[string]interface{})
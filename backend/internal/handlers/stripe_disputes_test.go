@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/events"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+)
+
+type fakeDisputeStore struct {
+	disputes  map[string]*models.Dispute
+	suspended bool
+}
+
+func (f *fakeDisputeStore) UpsertDispute(ctx context.Context, d *models.Dispute) (*models.Dispute, error) {
+	saved, ok := f.disputes[d.StripeDisputeID]
+	if !ok {
+		userID := int64(7)
+		saved = &models.Dispute{ID: int64(len(f.disputes) + 1), StripeDisputeID: d.StripeDisputeID, StripeChargeID: d.StripeChargeID, UserID: &userID}
+		f.disputes[d.StripeDisputeID] = saved
+	}
+	saved.Status, saved.Reason, saved.Amount, saved.Currency = d.Status, d.Reason, d.Amount, d.Currency
+	out := *saved
+	return &out, nil
+}
+
+func (f *fakeDisputeStore) SuspendUserForDispute(ctx context.Context, disputeID, userID int64) error {
+	for _, d := range f.disputes {
+		if d.ID == disputeID {
+			d.Suspended = true
+		}
+	}
+	f.suspended = true
+	return nil
+}
+
+func (f *fakeDisputeStore) ReleaseDisputeSuspension(ctx context.Context, userID int64) (bool, error) {
+	released := f.suspended
+	f.suspended = false
+	return released, nil
+}
+
+type recordingPublisher struct {
+	events []events.Event
+}
+
+func (p *recordingPublisher) Publish(ctx context.Context, ev events.Event) {
+	p.events = append(p.events, ev)
+}
+
+func TestDisputeWebhookSuspendsWhileOpen(t *testing.T) {
+	disputes := &fakeDisputeStore{disputes: map[string]*models.Dispute{}}
+	published := &recordingPublisher{}
+	h := &StripeHandler{Disputes: disputes, Events: published, SuspendOnDispute: true}
+
+	send := func(eventType, status string) {
+		body := `{"id":"evt_1","type":"` + eventType + `","data":{"object":{"id":"dp_1","charge":"ch_1","amount":1900,"currency":"USD","reason":"fraudulent","status":"` + status + `"}}}`
+		rec := httptest.NewRecorder()
+		h.HandleWebhook()(rec, httptest.NewRequest(http.MethodPost, "/api/webhooks/stripe", strings.NewReader(body)))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d", eventType, rec.Code)
+		}
+	}
+
+	send("charge.dispute.created", "needs_response")
+	if !disputes.suspended {
+		t.Fatal("expected the account to be suspended while the dispute is open")
+	}
+	send("charge.dispute.closed", models.DisputeWon)
+	if disputes.suspended {
+		t.Fatal("expected the suspension to be lifted once the dispute closed")
+	}
+
+	if len(published.events) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(published.events))
+	}
+	created := published.events[0].(events.DisputeChanged)
+	closed := published.events[1].(events.DisputeChanged)
+	if created.Change != events.DisputeCreated || !created.Suspended || created.UserID != 7 || created.Currency != "usd" {
+		t.Fatalf("unexpected created event: %+v", created)
+	}
+	if closed.Change != events.DisputeClosed || closed.Suspended {
+		t.Fatalf("unexpected closed event: %+v", closed)
+	}
+}
@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+	stripeClient "github.com/PortNumber53/mcp-jira-thing/backend/internal/stripe"
+)
+
+type paymentMethodPayload struct {
+	UserEmail       string `json:"user_email"`
+	PaymentMethodID string `json:"payment_method_id"`
+}
+
+// ListPaymentMethods returns the cards saved on the user's Stripe customer
+func (h *StripeHandler) ListPaymentMethods() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		email := tokenEmail(r, r.URL.Query().Get("email"))
+		if email == "" {
+			http.Error(w, "email query parameter is required", http.StatusBadRequest)
+			return
+		}
+
+		user, err := h.UserStore.GetUserByEmail(r.Context(), email)
+		if err != nil {
+			http.Error(w, "user not found", http.StatusNotFound)
+			return
+		}
+
+		methods := []stripeClient.PaymentMethod{}
+		if user.StripeCustomerID != nil {
+			methods, err = h.Stripe.ListPaymentMethods(*user.StripeCustomerID)
+			if err != nil {
+				log.Printf("ListPaymentMethods: Stripe error: %v", err)
+				http.Error(w, "failed to list payment methods", http.StatusBadGateway)
+				return
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"payment_methods": methods})
+	}
+}
+
+// CreateSetupIntent starts adding a card: the client confirms the returned
+// SetupIntent with Stripe.js and then makes the new card the default with
+// SetDefaultPaymentMethod. Users without a Stripe customer get one.
+func (h *StripeHandler) CreateSetupIntent() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var payload paymentMethodPayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			http.Error(w, "invalid JSON payload", http.StatusBadRequest)
+			return
+		}
+		email := tokenEmail(r, payload.UserEmail)
+		if email == "" {
+			http.Error(w, "user_email is required", http.StatusBadRequest)
+			return
+		}
+
+		user, err := h.UserStore.GetUserByEmail(r.Context(), email)
+		if err != nil {
+			http.Error(w, "user not found", http.StatusNotFound)
+			return
+		}
+
+		customerID, err := h.ensureCustomer(r.Context(), user)
+		if err != nil {
+			log.Printf("CreateSetupIntent: customer for %s: %v", email, err)
+			http.Error(w, "failed to create Stripe customer", http.StatusBadGateway)
+			return
+		}
+
+		intent, err := h.Stripe.CreateSetupIntent(customerID)
+		if err != nil {
+			log.Printf("CreateSetupIntent: Stripe error: %v", err)
+			http.Error(w, "failed to create setup intent", http.StatusBadGateway)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"setup_intent_id": intent.ID,
+			"client_secret":   intent.ClientSecret,
+			"status":          intent.Status,
+		})
+	}
+}
+
+// SetDefaultPaymentMethod makes a saved card the one the user's invoices
+// are charged to
+func (h *StripeHandler) SetDefaultPaymentMethod() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var payload paymentMethodPayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			http.Error(w, "invalid JSON payload", http.StatusBadRequest)
+			return
+		}
+		email := tokenEmail(r, payload.UserEmail)
+		paymentMethodID := strings.TrimSpace(payload.PaymentMethodID)
+		if email == "" || paymentMethodID == "" {
+			http.Error(w, "user_email and payment_method_id are required", http.StatusBadRequest)
+			return
+		}
+
+		user, err := h.UserStore.GetUserByEmail(r.Context(), email)
+		if err != nil {
+			http.Error(w, "user not found", http.StatusNotFound)
+			return
+		}
+		if user.StripeCustomerID == nil {
+			http.Error(w, "no saved payment methods", http.StatusNotFound)
+			return
+		}
+
+		subscriptionID := ""
+		if sub, err := h.BillingStore.GetSubscription(r.Context(), email); err != nil {
+			log.Printf("SetDefaultPaymentMethod: failed to load subscription for %s: %v", email, err)
+		} else if sub != nil {
+			subscriptionID = sub.StripeSubscriptionID
+		}
+
+		err = h.Stripe.SetDefaultPaymentMethod(*user.StripeCustomerID, paymentMethodID, subscriptionID)
+		if errors.Is(err, stripeClient.ErrPaymentMethodNotAttached) {
+			http.Error(w, "payment method not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Printf("SetDefaultPaymentMethod: Stripe error: %v", err)
+			http.Error(w, "failed to update default payment method", http.StatusBadGateway)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"ok": true, "default_payment_method": paymentMethodID})
+	}
+}
+
+// ensureCustomer returns the user's Stripe customer, creating and recording
+// one (on a test clock in non-production profiles) when they have none.
+func (h *StripeHandler) ensureCustomer(ctx context.Context, user *models.User) (string, error) {
+	if user.StripeCustomerID != nil {
+		return *user.StripeCustomerID, nil
+	}
+	if h.TestClocks != nil {
+		return h.ensureTestClockCustomer(ctx, user)
+	}
+
+	email, name := "", ""
+	if user.Email != nil {
+		email = *user.Email
+	}
+	if user.Name != nil {
+		name = *user.Name
+	}
+	customerID, err := h.Stripe.CreateCustomer(email, name, "")
+	if err != nil {
+		return "", err
+	}
+	if err := h.Customers.SetStripeCustomerID(ctx, user.ID, customerID); err != nil {
+		return "", err
+	}
+	return customerID, nil
+}
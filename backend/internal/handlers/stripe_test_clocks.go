@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+// StripeTestClockStore persists the Stripe test clocks created for users in
+// non-production profiles
+type StripeTestClockStore interface {
+	RecordStripeTestClock(ctx context.Context, userID int64, testClockID, customerID string) error
+	GetStripeTestClockByEmail(ctx context.Context, email string) (string, error)
+}
+
+// RegisterTestClockRoutes registers the test clock endpoints. Advancing a
+// clock generates invoices and webhooks, so callers should only mount these
+// behind operator authentication and never in production.
+func (h *StripeHandler) RegisterTestClockRoutes(router chi.Router) {
+	handler := h.TestClock()
+	router.Get("/api/billing/test-clock", handler)
+	router.Post("/api/billing/test-clock/advance", handler)
+}
+
+// ensureTestClockCustomer creates a Stripe customer attached to a fresh test
+// clock for a user who has no customer yet. It returns the new customer ID.
+func (h *StripeHandler) ensureTestClockCustomer(ctx context.Context, user *models.User) (string, error) {
+	email := ""
+	if user.Email != nil {
+		email = *user.Email
+	}
+	name := ""
+	if user.Name != nil {
+		name = *user.Name
+	}
+
+	clock, err := h.Stripe.CreateTestClock(fmt.Sprintf("user %d (%s)", user.ID, email), time.Now())
+	if err != nil {
+		return "", err
+	}
+	customerID, err := h.Stripe.CreateCustomer(email, name, clock.ID)
+	if err != nil {
+		return "", err
+	}
+	if err := h.TestClocks.RecordStripeTestClock(ctx, user.ID, clock.ID, customerID); err != nil {
+		return "", err
+	}
+
+	log.Printf("[stripe] Attached user %d to test clock %s as customer %s", user.ID, clock.ID, customerID)
+	return customerID, nil
+}
+
+// advanceTestClockRequest moves a test clock to FrozenTime, or forward by
+// AdvanceBy (a Go duration such as "744h") from its current time.
+type advanceTestClockRequest struct {
+	UserEmail   string     `json:"user_email"`
+	TestClockID string     `json:"test_clock_id"`
+	FrozenTime  *time.Time `json:"frozen_time"`
+	AdvanceBy   string     `json:"advance_by"`
+}
+
+// TestClock reports (GET) or advances (POST) a Stripe test clock, identified
+// either by test_clock_id or by the email of the user attached to it.
+func (h *StripeHandler) TestClock() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req advanceTestClockRequest
+		switch r.Method {
+		case http.MethodGet:
+			req.UserEmail = r.URL.Query().Get("user_email")
+			req.TestClockID = r.URL.Query().Get("test_clock_id")
+		case http.MethodPost:
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "invalid JSON payload", http.StatusBadRequest)
+				return
+			}
+		default:
+			w.Header().Set("Allow", "GET, POST")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		clockID := strings.TrimSpace(req.TestClockID)
+		if clockID == "" {
+			if strings.TrimSpace(req.UserEmail) == "" {
+				http.Error(w, "user_email or test_clock_id is required", http.StatusBadRequest)
+				return
+			}
+			id, err := h.TestClocks.GetStripeTestClockByEmail(r.Context(), req.UserEmail)
+			if err != nil {
+				if errors.Is(err, store.ErrStripeTestClockNotFound) {
+					http.Error(w, "user has no test clock", http.StatusNotFound)
+					return
+				}
+				log.Printf("TestClock: lookup for %s failed: %v", req.UserEmail, err)
+				http.Error(w, "failed to load test clock", http.StatusInternalServerError)
+				return
+			}
+			clockID = id
+		}
+
+		clock, err := h.Stripe.GetTestClock(clockID)
+		if err != nil {
+			log.Printf("TestClock: get %s failed: %v", clockID, err)
+			http.Error(w, "failed to load test clock", http.StatusBadGateway)
+			return
+		}
+		if r.Method == http.MethodGet {
+			writeJSON(w, http.StatusOK, clock)
+			return
+		}
+
+		var target time.Time
+		switch {
+		case req.FrozenTime != nil && req.AdvanceBy != "":
+			http.Error(w, "frozen_time and advance_by are mutually exclusive", http.StatusBadRequest)
+			return
+		case req.FrozenTime != nil:
+			target = *req.FrozenTime
+		case req.AdvanceBy != "":
+			d, err := time.ParseDuration(req.AdvanceBy)
+			if err != nil || d <= 0 {
+				http.Error(w, "advance_by must be a positive duration", http.StatusBadRequest)
+				return
+			}
+			target = clock.FrozenTime.Add(d)
+		default:
+			http.Error(w, "frozen_time or advance_by is required", http.StatusBadRequest)
+			return
+		}
+		if !target.After(clock.FrozenTime) {
+			http.Error(w, "test clocks can only move forward", http.StatusBadRequest)
+			return
+		}
+
+		clock, err = h.Stripe.AdvanceTestClock(clockID, target)
+		if err != nil {
+			log.Printf("TestClock: advance %s failed: %v", clockID, err)
+			http.Error(w, "failed to advance test clock", http.StatusBadGateway)
+			return
+		}
+
+		writeJSON(w, http.StatusAccepted, clock)
+	}
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/store"
+)
+
+// tenantStateDefaultLimit and tenantStateMaxLimit bound how many recent jobs
+// and requests a tenant state lookup returns.
+const (
+	tenantStateDefaultLimit = 20
+	tenantStateMaxLimit     = 200
+)
+
+// TenantStateStore reads a user's account for support.
+type TenantStateStore interface {
+	GetTenantState(ctx context.Context, email string, limit int) (*models.TenantState, error)
+}
+
+// UserJobLister lists the jobs run on behalf of a user.
+type UserJobLister interface {
+	ListUserJobs(ctx context.Context, userID int64, limit int) ([]*models.Job, error)
+}
+
+// AdminTenantState returns everything support needs about one user in a
+// single read-only response: the user row, OAuth providers, Jira settings
+// without tokens, the latest subscription and recent jobs and requests.
+// jobs may be nil when the job queue is not available.
+// GET ?email=...&limit=20
+func AdminTenantState(tenants TenantStateStore, jobs UserJobLister) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		email := strings.TrimSpace(r.URL.Query().Get("email"))
+		if email == "" {
+			http.Error(w, "email query parameter is required", http.StatusBadRequest)
+			return
+		}
+		limit := tenantStateDefaultLimit
+		if raw := r.URL.Query().Get("limit"); raw != "" {
+			n, err := strconv.Atoi(raw)
+			if err != nil || n <= 0 || n > tenantStateMaxLimit {
+				http.Error(w, "limit must be between 1 and "+strconv.Itoa(tenantStateMaxLimit), http.StatusBadRequest)
+				return
+			}
+			limit = n
+		}
+
+		state, err := tenants.GetTenantState(r.Context(), email, limit)
+		if errors.Is(err, store.ErrUserNotFound) {
+			http.Error(w, "user not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Printf("AdminTenantState: failed to load state for email=%s: %v", email, err)
+			http.Error(w, "failed to load tenant state", http.StatusInternalServerError)
+			return
+		}
+
+		if jobs != nil {
+			state.RecentJobs, err = jobs.ListUserJobs(r.Context(), state.User.ID, limit)
+			if err != nil {
+				log.Printf("AdminTenantState: failed to list jobs for user %d: %v", state.User.ID, err)
+				http.Error(w, "failed to load tenant jobs", http.StatusInternalServerError)
+				return
+			}
+		}
+
+		// Empty sections are [] rather than null so every key is always an
+		// array to iterate.
+		if state.OAuthAccounts == nil {
+			state.OAuthAccounts = []models.ConnectedAccount{}
+		}
+		if state.JiraSettings == nil {
+			state.JiraSettings = []models.JiraUserSettings{}
+		}
+		if state.RecentJobs == nil {
+			state.RecentJobs = []*models.Job{}
+		}
+		if state.RecentRequests == nil {
+			state.RecentRequests = []models.Request{}
+		}
+		writeJSON(w, http.StatusOK, state)
+	}
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/store"
+)
+
+type fakeTenantStateStore struct {
+	limit int
+}
+
+func (f *fakeTenantStateStore) GetTenantState(ctx context.Context, email string, limit int) (*models.TenantState, error) {
+	f.limit = limit
+	if email != "user@example.com" {
+		return nil, store.ErrUserNotFound
+	}
+	return &models.TenantState{
+		User:         models.AdminUser{ID: 7, Login: "user", HasMCPSecret: true},
+		JiraSettings: []models.JiraUserSettings{{JiraBaseURL: "https://example.atlassian.net", JiraEmail: "jira@example.com"}},
+	}, nil
+}
+
+type fakeUserJobLister struct {
+	userID int64
+}
+
+func (f *fakeUserJobLister) ListUserJobs(ctx context.Context, userID int64, limit int) ([]*models.Job, error) {
+	f.userID = userID
+	return []*models.Job{{ID: 3, JobType: "usage_digest", Status: models.JobStatusCompleted}}, nil
+}
+
+func TestAdminTenantState(t *testing.T) {
+	tenants := &fakeTenantStateStore{}
+	jobs := &fakeUserJobLister{}
+	handler := AdminTenantState(tenants, jobs)
+
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/api/admin/users/state?email=user@example.com&limit=5", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if tenants.limit != 5 || jobs.userID != 7 {
+		t.Fatalf("expected limit 5 and jobs of user 7, got %d and %d", tenants.limit, jobs.userID)
+	}
+	body := rec.Body.String()
+	for _, want := range []string{`"has_mcp_secret":true`, `"job_type":"usage_digest"`, `"oauth_accounts":[]`, `"recent_requests":[]`} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("expected %s in %s", want, body)
+		}
+	}
+	if strings.Contains(body, "api_token") || strings.Contains(body, "atlassian_api_key") {
+		t.Fatalf("tenant state must not include tokens: %s", body)
+	}
+
+	rec = httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/api/admin/users/state?email=nobody@example.com", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for unknown user, got %d", rec.Code)
+	}
+}
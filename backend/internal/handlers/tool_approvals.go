@@ -0,0 +1,328 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+	storepkg "github.com/PortNumber53/mcp-jira-thing/backend/internal/store"
+)
+
+// ToolApprovalPolicyStore defines the behaviour required to manage which
+// tools need human approval.
+type ToolApprovalPolicyStore interface {
+	ListToolApprovalPolicies(ctx context.Context, email string) ([]models.ToolApprovalPolicy, error)
+	ListToolApprovalPoliciesByUserID(ctx context.Context, userID int64) ([]models.ToolApprovalPolicy, error)
+	SetToolApprovalPolicy(ctx context.Context, email, tool string, requireApproval bool) error
+	DeleteToolApprovalPolicy(ctx context.Context, email, tool string) error
+}
+
+// ToolApprovalStore defines the behaviour required to hold tool calls for
+// approval, decide them and hand approved calls back to the worker.
+type ToolApprovalStore interface {
+	CreateToolApproval(ctx context.Context, userID int64, tool string, arguments json.RawMessage, ttl time.Duration) (*models.ToolApproval, error)
+	ListToolApprovals(ctx context.Context, email, status string) ([]models.ToolApproval, error)
+	DecideToolApproval(ctx context.Context, email string, id int64, approve bool) (*models.ToolApproval, error)
+	GetToolApprovalByToken(ctx context.Context, userID int64, token string) (*models.ToolApproval, error)
+	ConsumeToolApproval(ctx context.Context, userID int64, token string) (*models.ToolApproval, error)
+	CreateUserNotification(ctx context.Context, userID int64, kind, severity, title, body string, metadata models.JSONB) error
+}
+
+type toolApprovalPolicyPayload struct {
+	UserEmail       string `json:"user_email"`
+	Tool            string `json:"tool"`
+	RequireApproval bool   `json:"require_approval"`
+}
+
+// ToolApprovalPolicies manages which of the caller's MCP tools need human
+// approval. An empty tool (or "*") addresses the default for destructive
+// tools.
+// GET    ?email=...
+// POST   {"tool": "deleteComment", "require_approval": true}
+// DELETE ?email=...&tool=deleteComment
+func ToolApprovalPolicies(store ToolApprovalPolicyStore, cookieSecret string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			email := requestEmail(r, cookieSecret, "")
+			if email == "" {
+				http.Error(w, "email query parameter is required", http.StatusBadRequest)
+				return
+			}
+			writeToolApprovalPolicies(w, r, store, email)
+
+		case http.MethodPost:
+			var payload toolApprovalPolicyPayload
+			if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+				log.Printf("ToolApprovalPolicies: invalid JSON payload: %v", err)
+				http.Error(w, "invalid JSON payload", http.StatusBadRequest)
+				return
+			}
+
+			email := requestEmail(r, cookieSecret, payload.UserEmail)
+			if email == "" {
+				http.Error(w, "user_email is required", http.StatusBadRequest)
+				return
+			}
+
+			tool := toolApprovalPolicyName(payload.Tool)
+			if err := store.SetToolApprovalPolicy(r.Context(), email, tool, payload.RequireApproval); err != nil {
+				log.Printf("ToolApprovalPolicies: failed to set policy for email=%s tool=%s: %v", email, tool, err)
+				http.Error(w, "failed to save tool approval policy", http.StatusBadGateway)
+				return
+			}
+			writeToolApprovalPolicies(w, r, store, email)
+
+		case http.MethodDelete:
+			email := requestEmail(r, cookieSecret, "")
+			if email == "" {
+				http.Error(w, "email query parameter is required", http.StatusBadRequest)
+				return
+			}
+
+			tool := toolApprovalPolicyName(r.URL.Query().Get("tool"))
+			if err := store.DeleteToolApprovalPolicy(r.Context(), email, tool); err != nil {
+				log.Printf("ToolApprovalPolicies: failed to delete policy for email=%s tool=%s: %v", email, tool, err)
+				http.Error(w, "failed to delete tool approval policy", http.StatusBadGateway)
+				return
+			}
+			writeToolApprovalPolicies(w, r, store, email)
+
+		default:
+			w.Header().Set("Allow", "GET, POST, DELETE")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// TenantToolApprovalPolicies returns the resolved approval policies of the
+// tenant identified by the mcp_secret query parameter, for the MCP worker.
+func TenantToolApprovalPolicies(store ToolApprovalPolicyStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value("user_id").(int64)
+		if !ok || userID <= 0 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		rows, err := store.ListToolApprovalPoliciesByUserID(r.Context(), userID)
+		if err != nil {
+			log.Printf("TenantToolApprovalPolicies: failed to list policies for user_id=%d: %v", userID, err)
+			http.Error(w, "failed to load tool approval policies", http.StatusBadGateway)
+			return
+		}
+		writeJSON(w, http.StatusOK, models.ResolveToolApprovalPolicies(rows))
+	}
+}
+
+// ToolApprovals lists the caller's held tool calls, newest first.
+// GET ?email=...&status=pending
+func ToolApprovals(store ToolApprovalStore, cookieSecret string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		email := requestEmail(r, cookieSecret, "")
+		if email == "" {
+			http.Error(w, "email query parameter is required", http.StatusBadRequest)
+			return
+		}
+
+		approvals, err := store.ListToolApprovals(r.Context(), email, strings.TrimSpace(r.URL.Query().Get("status")))
+		if err != nil {
+			log.Printf("ToolApprovals: failed to list approvals for email=%s: %v", email, err)
+			http.Error(w, "failed to load tool approvals", http.StatusBadGateway)
+			return
+		}
+		if approvals == nil {
+			approvals = []models.ToolApproval{}
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"approvals": approvals})
+	}
+}
+
+// DecideToolApproval approves or rejects one of the caller's pending tool
+// calls.
+// POST /api/tool-approvals/{id}/{decision}?email=... (decision: approve|reject)
+func DecideToolApproval(store ToolApprovalStore, cookieSecret string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		email := requestEmail(r, cookieSecret, "")
+		if email == "" {
+			http.Error(w, "email query parameter is required", http.StatusBadRequest)
+			return
+		}
+
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil || id <= 0 {
+			http.Error(w, "invalid approval id", http.StatusBadRequest)
+			return
+		}
+
+		var approve bool
+		switch chi.URLParam(r, "decision") {
+		case "approve":
+			approve = true
+		case "reject":
+		default:
+			http.Error(w, "decision must be approve or reject", http.StatusBadRequest)
+			return
+		}
+
+		approval, err := store.DecideToolApproval(r.Context(), email, id, approve)
+		if errors.Is(err, storepkg.ErrToolApprovalNotFound) {
+			http.Error(w, "pending tool approval not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Printf("DecideToolApproval: failed to decide approval id=%d for email=%s: %v", id, email, err)
+			http.Error(w, "failed to decide tool approval", http.StatusBadGateway)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"approval": approval})
+	}
+}
+
+type toolApprovalRequestPayload struct {
+	Tool      string          `json:"tool"`
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+// TenantCreateToolApproval lets the MCP worker hold a tool call for the
+// tenant's approval. The tenant is notified, and the returned token lets
+// the worker execute the call once it is approved.
+// POST {"tool": "deleteComment", "arguments": {...}}
+func TenantCreateToolApproval(store ToolApprovalStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value("user_id").(int64)
+		if !ok || userID <= 0 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var payload toolApprovalRequestPayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			log.Printf("TenantCreateToolApproval: invalid JSON payload: %v", err)
+			http.Error(w, "invalid JSON payload", http.StatusBadRequest)
+			return
+		}
+		tool := strings.TrimSpace(payload.Tool)
+		if tool == "" {
+			http.Error(w, "tool is required", http.StatusBadRequest)
+			return
+		}
+
+		approval, err := store.CreateToolApproval(r.Context(), userID, tool, payload.Arguments, models.ToolApprovalTTL)
+		if err != nil {
+			log.Printf("TenantCreateToolApproval: failed to hold %s for user_id=%d: %v", tool, userID, err)
+			http.Error(w, "failed to create tool approval", http.StatusBadGateway)
+			return
+		}
+
+		if err := store.CreateUserNotification(
+			r.Context(),
+			userID,
+			models.NotificationKindToolApproval,
+			"warning",
+			"A "+tool+" call is waiting for your approval",
+			"An MCP client asked to run "+tool+". Approve or reject it from the dashboard before it expires.",
+			models.JSONB{"approval_id": approval.ID, "tool": tool},
+		); err != nil {
+			log.Printf("TenantCreateToolApproval: failed to notify user_id=%d: %v", userID, err)
+		}
+
+		writeJSON(w, http.StatusCreated, map[string]any{"approval": approval})
+	}
+}
+
+// TenantToolApproval returns the status of a held call by its token.
+// GET /api/tool-approvals/tenant/{token}
+func TenantToolApproval(store ToolApprovalStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value("user_id").(int64)
+		if !ok || userID <= 0 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		approval, err := store.GetToolApprovalByToken(r.Context(), userID, chi.URLParam(r, "token"))
+		if errors.Is(err, storepkg.ErrToolApprovalNotFound) {
+			http.Error(w, "tool approval not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Printf("TenantToolApproval: failed to load approval for user_id=%d: %v", userID, err)
+			http.Error(w, "failed to load tool approval", http.StatusBadGateway)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"approval": approval})
+	}
+}
+
+// TenantConsumeToolApproval hands an approved call back to the MCP worker
+// for execution and marks it executed, so it runs at most once. Calls that
+// are not approved are answered with 409 and their current status.
+// POST /api/tool-approvals/tenant/{token}/consume
+func TenantConsumeToolApproval(store ToolApprovalStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value("user_id").(int64)
+		if !ok || userID <= 0 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		approval, err := store.ConsumeToolApproval(r.Context(), userID, chi.URLParam(r, "token"))
+		switch {
+		case errors.Is(err, storepkg.ErrToolApprovalNotFound):
+			http.Error(w, "tool approval not found", http.StatusNotFound)
+		case errors.Is(err, storepkg.ErrToolApprovalNotApproved):
+			writeJSON(w, http.StatusConflict, map[string]any{"error": "not_approved", "status": approval.Status})
+		case err != nil:
+			log.Printf("TenantConsumeToolApproval: failed to consume approval for user_id=%d: %v", userID, err)
+			http.Error(w, "failed to consume tool approval", http.StatusBadGateway)
+		default:
+			writeJSON(w, http.StatusOK, map[string]any{"approval": approval})
+		}
+	}
+}
+
+func writeToolApprovalPolicies(w http.ResponseWriter, r *http.Request, store ToolApprovalPolicyStore, email string) {
+	rows, err := store.ListToolApprovalPolicies(r.Context(), email)
+	if err != nil {
+		log.Printf("ToolApprovalPolicies: failed to list policies for email=%s: %v", email, err)
+		http.Error(w, "failed to load tool approval policies", http.StatusBadGateway)
+		return
+	}
+	if rows == nil {
+		rows = []models.ToolApprovalPolicy{}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"policies":  models.ResolveToolApprovalPolicies(rows),
+		"overrides": rows,
+	})
+}
+
+func toolApprovalPolicyName(tool string) string {
+	tool = strings.TrimSpace(tool)
+	if tool == "" {
+		return models.ToolApprovalDefault
+	}
+	return tool
+}
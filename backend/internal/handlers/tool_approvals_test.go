@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+	storepkg "github.com/PortNumber53/mcp-jira-thing/backend/internal/store"
+)
+
+type fakeToolApprovalStore struct {
+	approvals     []*models.ToolApproval
+	notifications []string
+}
+
+func (f *fakeToolApprovalStore) CreateToolApproval(ctx context.Context, userID int64, tool string, arguments json.RawMessage, ttl time.Duration) (*models.ToolApproval, error) {
+	a := &models.ToolApproval{
+		ID:        int64(len(f.approvals) + 1),
+		Token:     "tok-" + tool,
+		Tool:      tool,
+		Arguments: arguments,
+		Status:    models.ToolApprovalPending,
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	f.approvals = append(f.approvals, a)
+	return a, nil
+}
+
+func (f *fakeToolApprovalStore) ListToolApprovals(ctx context.Context, email, status string) ([]models.ToolApproval, error) {
+	var out []models.ToolApproval
+	for _, a := range f.approvals {
+		if status == "" || a.Status == status {
+			out = append(out, *a)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeToolApprovalStore) DecideToolApproval(ctx context.Context, email string, id int64, approve bool) (*models.ToolApproval, error) {
+	for _, a := range f.approvals {
+		if a.ID == id && a.Status == models.ToolApprovalPending {
+			a.Status = models.ToolApprovalRejected
+			if approve {
+				a.Status = models.ToolApprovalApproved
+			}
+			return a, nil
+		}
+	}
+	return nil, storepkg.ErrToolApprovalNotFound
+}
+
+func (f *fakeToolApprovalStore) GetToolApprovalByToken(ctx context.Context, userID int64, token string) (*models.ToolApproval, error) {
+	for _, a := range f.approvals {
+		if a.Token == token {
+			return a, nil
+		}
+	}
+	return nil, storepkg.ErrToolApprovalNotFound
+}
+
+func (f *fakeToolApprovalStore) ConsumeToolApproval(ctx context.Context, userID int64, token string) (*models.ToolApproval, error) {
+	a, err := f.GetToolApprovalByToken(ctx, userID, token)
+	if err != nil {
+		return nil, err
+	}
+	if a.Status != models.ToolApprovalApproved {
+		return a, storepkg.ErrToolApprovalNotApproved
+	}
+	a.Status = models.ToolApprovalExecuted
+	return a, nil
+}
+
+func (f *fakeToolApprovalStore) CreateUserNotification(ctx context.Context, userID int64, kind, severity, title, body string, metadata models.JSONB) error {
+	f.notifications = append(f.notifications, kind)
+	return nil
+}
+
+func TestToolApprovalRunsOnceAfterApproval(t *testing.T) {
+	store := &fakeToolApprovalStore{}
+	router := chi.NewRouter()
+	router.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), "user_id", int64(7))))
+		})
+	})
+	router.Post("/api/tool-approvals/tenant", TenantCreateToolApproval(store))
+	router.Post("/api/tool-approvals/tenant/{token}/consume", TenantConsumeToolApproval(store))
+	router.Post("/api/tool-approvals/{id}/{decision}", DecideToolApproval(store, ""))
+
+	do := func(path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := do("/api/tool-approvals/tenant", `{"tool":"deleteComment","arguments":{"issueKey":"ENG-1","commentId":"10"}}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create: expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if len(store.notifications) != 1 || store.notifications[0] != models.NotificationKindToolApproval {
+		t.Fatalf("expected a tool approval notification, got %v", store.notifications)
+	}
+
+	if rec := do("/api/tool-approvals/tenant/tok-deleteComment/consume", ""); rec.Code != http.StatusConflict {
+		t.Fatalf("consume before approval: expected 409, got %d", rec.Code)
+	}
+	if rec := do("/api/tool-approvals/1/maybe?email=user@example.com", ""); rec.Code != http.StatusBadRequest {
+		t.Fatalf("unknown decision: expected 400, got %d", rec.Code)
+	}
+	if rec := do("/api/tool-approvals/1/approve?email=user@example.com", ""); rec.Code != http.StatusOK {
+		t.Fatalf("approve: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = do("/api/tool-approvals/tenant/tok-deleteComment/consume", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("consume: expected 200, got %d", rec.Code)
+	}
+	var body struct {
+		Approval models.ToolApproval `json:"approval"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if body.Approval.Tool != "deleteComment" || !strings.Contains(string(body.Approval.Arguments), "ENG-1") {
+		t.Fatalf("unexpected approval: %+v", body.Approval)
+	}
+
+	if rec := do("/api/tool-approvals/tenant/tok-deleteComment/consume", ""); rec.Code != http.StatusConflict {
+		t.Fatalf("second consume: expected 409, got %d", rec.Code)
+	}
+}
+
+func TestResolveToolApprovalPolicies(t *testing.T) {
+	policies := models.ResolveToolApprovalPolicies([]models.ToolApprovalPolicy{
+		{Tool: models.ToolApprovalDefault, RequireApproval: true},
+		{Tool: "updateWorkItem", RequireApproval: false},
+	})
+	if !policies.Default || policies.Tools["updateWorkItem"] {
+		t.Fatalf("unexpected policies: %+v", policies)
+	}
+}
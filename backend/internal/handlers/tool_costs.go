@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+)
+
+// maxToolCostUnits bounds a single tool's configured weight.
+const maxToolCostUnits = 1000
+
+// ToolCostStore defines the behaviour required to record MCP tool
+// invocations and report what they cost.
+type ToolCostStore interface {
+	UserLookup
+	RecordToolInvocation(ctx context.Context, inv *models.ToolInvocation) (int, error)
+	ListToolCostWeights(ctx context.Context) ([]models.ToolCostWeight, error)
+	SetToolCostWeight(ctx context.Context, tool string, costUnits int) error
+	DeleteToolCostWeight(ctx context.Context, tool string) error
+	ListToolCosts(ctx context.Context, userID int64, from, to time.Time) ([]models.ToolCost, error)
+}
+
+type toolInvocationPayload struct {
+	Tool       string `json:"tool"`
+	IsError    bool   `json:"is_error"`
+	DurationMs *int   `json:"duration_ms,omitempty"`
+	ProjectKey string `json:"project_key,omitempty"`
+}
+
+type toolCostWeightPayload struct {
+	Tool      string `json:"tool"`
+	CostUnits int    `json:"cost_units"`
+}
+
+// TenantToolInvocation records an MCP tool call reported by the worker in the
+// invocation log and returns the cost units it was charged. The tenant is
+// identified by the mcp_secret query parameter.
+func TenantToolInvocation(store ToolCostStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value("user_id").(int64)
+		if !ok || userID <= 0 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var payload toolInvocationPayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			log.Printf("TenantToolInvocation: invalid JSON payload: %v", err)
+			http.Error(w, "invalid JSON payload", http.StatusBadRequest)
+			return
+		}
+
+		tool := strings.TrimSpace(payload.Tool)
+		if tool == "" {
+			http.Error(w, "tool is required", http.StatusBadRequest)
+			return
+		}
+
+		inv := &models.ToolInvocation{
+			UserID:     userID,
+			Tool:       tool,
+			IsError:    payload.IsError,
+			DurationMs: payload.DurationMs,
+		}
+		if project := strings.ToUpper(strings.TrimSpace(payload.ProjectKey)); project != "" {
+			inv.ProjectKey = &project
+		}
+		costUnits, err := store.RecordToolInvocation(r.Context(), inv)
+		if err != nil {
+			log.Printf("TenantToolInvocation: failed to record invocation for user_id=%d tool=%s: %v", userID, tool, err)
+			http.Error(w, "failed to record tool invocation", http.StatusBadGateway)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(map[string]any{"cost_units": costUnits}); err != nil {
+			http.Error(w, "failed to encode response", http.StatusInternalServerError)
+		}
+	}
+}
+
+// ToolCostWeights lists or changes the per-tool cost weights. Changes are
+// operator actions and are only routed behind the signed backend group.
+// GET
+// PUT    {"tool": "planSprint", "cost_units": 5}
+// DELETE ?tool=planSprint
+func ToolCostWeights(store ToolCostStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			writeToolCostWeights(w, r, store)
+
+		case http.MethodPut:
+			var payload toolCostWeightPayload
+			if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+				log.Printf("ToolCostWeights: invalid JSON payload: %v", err)
+				http.Error(w, "invalid JSON payload", http.StatusBadRequest)
+				return
+			}
+
+			tool := strings.TrimSpace(payload.Tool)
+			if tool == "" {
+				http.Error(w, "tool is required", http.StatusBadRequest)
+				return
+			}
+			if payload.CostUnits < 1 || payload.CostUnits > maxToolCostUnits {
+				http.Error(w, "cost_units must be between 1 and 1000", http.StatusBadRequest)
+				return
+			}
+
+			if err := store.SetToolCostWeight(r.Context(), tool, payload.CostUnits); err != nil {
+				log.Printf("ToolCostWeights: failed to set weight for tool=%s: %v", tool, err)
+				http.Error(w, "failed to save tool cost weight", http.StatusBadGateway)
+				return
+			}
+			writeToolCostWeights(w, r, store)
+
+		case http.MethodDelete:
+			tool := strings.TrimSpace(r.URL.Query().Get("tool"))
+			if tool == "" {
+				http.Error(w, "tool query parameter is required", http.StatusBadRequest)
+				return
+			}
+
+			if err := store.DeleteToolCostWeight(r.Context(), tool); err != nil {
+				log.Printf("ToolCostWeights: failed to delete weight for tool=%s: %v", tool, err)
+				http.Error(w, "failed to delete tool cost weight", http.StatusBadGateway)
+				return
+			}
+			writeToolCostWeights(w, r, store)
+
+		default:
+			w.Header().Set("Allow", "GET, PUT, DELETE")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+func writeToolCostWeights(w http.ResponseWriter, r *http.Request, store ToolCostStore) {
+	weights, err := store.ListToolCostWeights(r.Context())
+	if err != nil {
+		log.Printf("ToolCostWeights: failed to list weights: %v", err)
+		http.Error(w, "failed to load tool cost weights", http.StatusBadGateway)
+		return
+	}
+	if weights == nil {
+		weights = []models.ToolCostWeight{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]any{
+		"default_cost_units": models.DefaultToolCostUnits,
+		"tools":              weights,
+	}); err != nil {
+		http.Error(w, "failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+// ToolCostBreakdown returns the caller's tool cost units grouped by tool for
+// a date range, defaulting to the current UTC calendar month.
+// GET ?from=2026-04-01&to=2026-04-30 (both inclusive)
+func ToolCostBreakdown(store ToolCostStore, cookieSecret string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := metricsUserID(w, r, store, cookieSecret)
+		if !ok {
+			return
+		}
+
+		now := time.Now().UTC()
+		from := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+		to := from.AddDate(0, 1, -1)
+		for param, dst := range map[string]*time.Time{"from": &from, "to": &to} {
+			v := r.URL.Query().Get(param)
+			if v == "" {
+				continue
+			}
+			t, err := time.Parse("2006-01-02", v)
+			if err != nil {
+				http.Error(w, param+" must be a YYYY-MM-DD date", http.StatusBadRequest)
+				return
+			}
+			*dst = t
+		}
+		if to.Before(from) {
+			http.Error(w, "to must not be before from", http.StatusBadRequest)
+			return
+		}
+
+		costs, err := store.ListToolCosts(r.Context(), userID, from, to.AddDate(0, 0, 1))
+		if err != nil {
+			log.Printf("ToolCostBreakdown: failed to list tool costs for user_id=%d: %v", userID, err)
+			http.Error(w, "failed to load tool costs", http.StatusInternalServerError)
+			return
+		}
+		if costs == nil {
+			costs = []models.ToolCost{}
+		}
+
+		var totalUnits int64
+		totalInvocations := 0
+		for _, c := range costs {
+			totalUnits += c.CostUnits
+			totalInvocations += c.Invocations
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(map[string]any{
+			"from":              from.Format("2006-01-02"),
+			"to":                to.Format("2006-01-02"),
+			"tools":             costs,
+			"total_cost_units":  totalUnits,
+			"total_invocations": totalInvocations,
+		}); err != nil {
+			http.Error(w, "failed to encode response", http.StatusInternalServerError)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+)
+
+type mockToolCostStore struct {
+	weights  map[string]int
+	recorded []models.ToolInvocation
+}
+
+func (m *mockToolCostStore) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	return &models.User{ID: 7}, nil
+}
+
+func (m *mockToolCostStore) RecordToolInvocation(ctx context.Context, inv *models.ToolInvocation) (int, error) {
+	inv.CostUnits = models.DefaultToolCostUnits
+	if units, ok := m.weights[inv.Tool]; ok {
+		inv.CostUnits = units
+	}
+	m.recorded = append(m.recorded, *inv)
+	return inv.CostUnits, nil
+}
+
+func (m *mockToolCostStore) ListToolCostWeights(ctx context.Context) ([]models.ToolCostWeight, error) {
+	var weights []models.ToolCostWeight
+	for tool, units := range m.weights {
+		weights = append(weights, models.ToolCostWeight{Tool: tool, CostUnits: units})
+	}
+	return weights, nil
+}
+
+func (m *mockToolCostStore) SetToolCostWeight(ctx context.Context, tool string, costUnits int) error {
+	m.weights[tool] = costUnits
+	return nil
+}
+
+func (m *mockToolCostStore) DeleteToolCostWeight(ctx context.Context, tool string) error {
+	delete(m.weights, tool)
+	return nil
+}
+
+func (m *mockToolCostStore) ListToolCosts(ctx context.Context, userID int64, from, to time.Time) ([]models.ToolCost, error) {
+	return nil, nil
+}
+
+func TestTenantToolInvocationChargesWeight(t *testing.T) {
+	store := &mockToolCostStore{weights: map[string]int{"planSprint": 5}}
+	handler := TenantToolInvocation(store)
+
+	for tool, want := range map[string]float64{"planSprint": 5, "getProjects": 1} {
+		req := httptest.NewRequest(http.MethodPost, "/api/metrics/tool-invocations/tenant", strings.NewReader(`{"tool":"`+tool+`","duration_ms":12}`))
+		req = req.WithContext(context.WithValue(req.Context(), "user_id", int64(3)))
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d, body = %s", tool, rec.Code, rec.Body.String())
+		}
+		var resp map[string]float64
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("%s: decode: %v", tool, err)
+		}
+		if resp["cost_units"] != want {
+			t.Fatalf("%s: cost_units = %v, want %v", tool, resp["cost_units"], want)
+		}
+	}
+
+	if len(store.recorded) != 2 || store.recorded[0].UserID != 3 {
+		t.Fatalf("recorded = %+v, want two invocations for user 3", store.recorded)
+	}
+}
+
+func TestTenantToolInvocationRequiresTenant(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/api/metrics/tool-invocations/tenant", strings.NewReader(`{"tool":"planSprint"}`))
+	rec := httptest.NewRecorder()
+	TenantToolInvocation(&mockToolCostStore{})(rec, req)
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want 401", rec.Code)
+	}
+}
+
+func TestToolCostWeightsValidatesUnits(t *testing.T) {
+	store := &mockToolCostStore{weights: map[string]int{}}
+	handler := ToolCostWeights(store)
+
+	for body, status := range map[string]int{
+		`{"tool":"planSprint","cost_units":0}`:    http.StatusBadRequest,
+		`{"tool":"","cost_units":3}`:              http.StatusBadRequest,
+		`{"tool":"planSprint","cost_units":3}`:    http.StatusOK,
+		`{"tool":"planSprint","cost_units":5000}`: http.StatusBadRequest,
+	} {
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodPut, "/api/metrics/cost-weights", strings.NewReader(body)))
+		if rec.Code != status {
+			t.Fatalf("%s: status = %d, want %d", body, rec.Code, status)
+		}
+	}
+
+	if store.weights["planSprint"] != 3 {
+		t.Fatalf("planSprint weight = %d, want 3", store.weights["planSprint"])
+	}
+}
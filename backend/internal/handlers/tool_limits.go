@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+)
+
+// ToolLimitStore defines the behaviour required to manage per-tool response
+// size limits.
+type ToolLimitStore interface {
+	ListToolResponseLimits(ctx context.Context, email string) ([]models.ToolResponseLimit, error)
+	ListToolResponseLimitsByUserID(ctx context.Context, userID int64) ([]models.ToolResponseLimit, error)
+	SetToolResponseLimit(ctx context.Context, email, tool string, maxBytes int) error
+	DeleteToolResponseLimit(ctx context.Context, email, tool string) error
+}
+
+type toolLimitPayload struct {
+	UserEmail string `json:"user_email"`
+	Tool      string `json:"tool"`
+	MaxBytes  int    `json:"max_bytes"`
+}
+
+// ToolResponseLimits manages the caller's tool response size limits. An
+// empty tool (or "*") addresses the tenant default.
+// GET    ?email=...
+// POST   {"tool": "searchWorkItems", "max_bytes": 20000}
+// DELETE ?email=...&tool=searchWorkItems
+func ToolResponseLimits(store ToolLimitStore, cookieSecret string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			email := requestEmail(r, cookieSecret, "")
+			if email == "" {
+				http.Error(w, "email query parameter is required", http.StatusBadRequest)
+				return
+			}
+			writeToolLimits(w, r, store, email)
+
+		case http.MethodPost:
+			var payload toolLimitPayload
+			if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+				log.Printf("ToolResponseLimits: invalid JSON payload: %v", err)
+				http.Error(w, "invalid JSON payload", http.StatusBadRequest)
+				return
+			}
+
+			email := requestEmail(r, cookieSecret, payload.UserEmail)
+			if email == "" {
+				http.Error(w, "user_email is required", http.StatusBadRequest)
+				return
+			}
+
+			if payload.MaxBytes < models.MinToolResponseBytes || payload.MaxBytes > models.MaxToolResponseBytes {
+				http.Error(w, fmt.Sprintf("max_bytes must be between %d and %d",
+					models.MinToolResponseBytes, models.MaxToolResponseBytes), http.StatusBadRequest)
+				return
+			}
+
+			tool := toolLimitName(payload.Tool)
+			if err := store.SetToolResponseLimit(r.Context(), email, tool, payload.MaxBytes); err != nil {
+				log.Printf("ToolResponseLimits: failed to set limit for email=%s tool=%s: %v", email, tool, err)
+				http.Error(w, "failed to save tool response limit", http.StatusBadGateway)
+				return
+			}
+			writeToolLimits(w, r, store, email)
+
+		case http.MethodDelete:
+			email := requestEmail(r, cookieSecret, "")
+			if email == "" {
+				http.Error(w, "email query parameter is required", http.StatusBadRequest)
+				return
+			}
+
+			tool := toolLimitName(r.URL.Query().Get("tool"))
+			if err := store.DeleteToolResponseLimit(r.Context(), email, tool); err != nil {
+				log.Printf("ToolResponseLimits: failed to delete limit for email=%s tool=%s: %v", email, tool, err)
+				http.Error(w, "failed to delete tool response limit", http.StatusBadGateway)
+				return
+			}
+			writeToolLimits(w, r, store, email)
+
+		default:
+			w.Header().Set("Allow", "GET, POST, DELETE")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// TenantToolResponseLimits returns the resolved limits of the tenant
+// identified by the mcp_secret query parameter, for the MCP worker.
+func TenantToolResponseLimits(store ToolLimitStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value("user_id").(int64)
+		if !ok || userID <= 0 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		rows, err := store.ListToolResponseLimitsByUserID(r.Context(), userID)
+		if err != nil {
+			log.Printf("TenantToolResponseLimits: failed to list limits for user_id=%d: %v", userID, err)
+			http.Error(w, "failed to load tool response limits", http.StatusBadGateway)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(models.ResolveToolResponseLimits(rows)); err != nil {
+			http.Error(w, "failed to encode response", http.StatusInternalServerError)
+		}
+	}
+}
+
+func writeToolLimits(w http.ResponseWriter, r *http.Request, store ToolLimitStore, email string) {
+	rows, err := store.ListToolResponseLimits(r.Context(), email)
+	if err != nil {
+		log.Printf("ToolResponseLimits: failed to list limits for email=%s: %v", email, err)
+		http.Error(w, "failed to load tool response limits", http.StatusBadGateway)
+		return
+	}
+	if rows == nil {
+		rows = []models.ToolResponseLimit{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]any{
+		"limits":    models.ResolveToolResponseLimits(rows),
+		"overrides": rows,
+	}); err != nil {
+		http.Error(w, "failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+func toolLimitName(tool string) string {
+	tool = strings.TrimSpace(tool)
+	if tool == "" {
+		return models.ToolResponseLimitDefault
+	}
+	return tool
+}
@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/mcp"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+)
+
+// ToolQuotaStore defines the behaviour required to enforce and report the
+// per-category daily tool quotas.
+type ToolQuotaStore interface {
+	UserLookup
+	GetEffectivePlan(ctx context.Context, userID int64) (*models.MembershipPlan, error)
+	ToolCategory(ctx context.Context, tool string) (string, error)
+	ListToolCategories(ctx context.Context) (map[string]string, error)
+	ListToolCategoryUsage(ctx context.Context, userID, planID int64, since time.Time) ([]models.ToolCategoryUsage, error)
+	RecordToolInvocation(ctx context.Context, inv *models.ToolInvocation) (int, error)
+}
+
+// loadToolQuotas returns the user's usage of each tool category today.
+func loadToolQuotas(ctx context.Context, store ToolQuotaStore, userID int64, now time.Time) (*models.ToolQuotas, error) {
+	plan, err := store.GetEffectivePlan(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("resolve plan: %w", err)
+	}
+	dayStart, reset := models.ToolQuotaDay(now)
+	usage, err := store.ListToolCategoryUsage(ctx, userID, plan.ID, dayStart)
+	if err != nil {
+		return nil, err
+	}
+	if usage == nil {
+		usage = []models.ToolCategoryUsage{}
+	}
+	return &models.ToolQuotas{PlanSlug: plan.Slug, DayStart: dayStart, ResetsAt: reset, Categories: usage}, nil
+}
+
+// ToolQuotaUsage reports the caller's tool calls today broken down by
+// category, with their plan's daily limit for each. The caller is resolved
+// as for UsageForecast.
+func ToolQuotaUsage(store ToolQuotaStore, cookieSecret string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := metricsUserID(w, r, store, cookieSecret)
+		if !ok {
+			return
+		}
+
+		quotas, err := loadToolQuotas(r.Context(), store, userID, time.Now())
+		if err != nil {
+			log.Printf("ToolQuotaUsage: failed to load tool quotas for user_id=%d: %v", userID, err)
+			http.Error(w, "failed to load tool quotas", http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusOK, quotas)
+	}
+}
+
+// TenantToolQuotas returns the tool quotas of the tenant identified by the
+// mcp_secret query parameter together with each configured tool's category,
+// so the worker can refuse calls over a quota before running them.
+func TenantToolQuotas(store ToolQuotaStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value("user_id").(int64)
+		if !ok || userID <= 0 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		quotas, err := loadToolQuotas(r.Context(), store, userID, time.Now())
+		if err != nil {
+			log.Printf("TenantToolQuotas: failed to load tool quotas for user_id=%d: %v", userID, err)
+			http.Error(w, "failed to load tool quotas", http.StatusBadGateway)
+			return
+		}
+		categories, err := store.ListToolCategories(r.Context())
+		if err != nil {
+			log.Printf("TenantToolQuotas: failed to list tool categories: %v", err)
+			http.Error(w, "failed to load tool categories", http.StatusBadGateway)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, map[string]any{
+			"plan_slug":        quotas.PlanSlug,
+			"day_start":        quotas.DayStart,
+			"resets_at":        quotas.ResetsAt,
+			"categories":       quotas.Categories,
+			"tools":            categories,
+			"default_category": models.DefaultToolCategory,
+		})
+	}
+}
+
+// ToolQuotas enforces the per-category daily tool quotas on the backend's
+// own MCP server and records its tool calls in the invocation log, where
+// they are charged like the worker's. It implements mcp.CallGuard.
+type ToolQuotas struct {
+	store    ToolQuotaStore
+	identify func(ctx context.Context) int64
+	now      func() time.Time
+}
+
+// NewToolQuotas creates the guard for the users identify returns; calls it
+// returns 0 for are neither limited nor recorded.
+func NewToolQuotas(store ToolQuotaStore, identify func(ctx context.Context) int64) *ToolQuotas {
+	return &ToolQuotas{store: store, identify: identify, now: time.Now}
+}
+
+// BeforeCall refuses the call with quota_exceeded when the user already
+// made as many successful calls of the tool's category today as their plan
+// allows. Quotas that cannot be read let the call through.
+func (q *ToolQuotas) BeforeCall(ctx context.Context, tool string) error {
+	userID := q.identify(ctx)
+	if userID <= 0 {
+		return nil
+	}
+
+	category, err := q.store.ToolCategory(ctx, tool)
+	if err != nil {
+		log.Printf("ToolQuotas: failed to resolve category of tool=%s: %v", tool, err)
+		return nil
+	}
+	now := q.now()
+	quotas, err := loadToolQuotas(ctx, q.store, userID, now)
+	if err != nil {
+		log.Printf("ToolQuotas: failed to load tool quotas for user_id=%d: %v", userID, err)
+		return nil
+	}
+	for _, usage := range quotas.Categories {
+		if usage.Category != category || !usage.Exceeded {
+			continue
+		}
+		return &mcp.ToolError{
+			Code:       mcp.ErrorQuotaExceeded,
+			Message:    fmt.Sprintf("daily %s tool quota of %d calls exceeded for the %s plan; it resets at %s", category, *usage.DailyLimit, quotas.PlanSlug, quotas.ResetsAt.Format(time.RFC3339)),
+			RetryAfter: quotas.ResetsAt.Sub(now),
+		}
+	}
+	return nil
+}
+
+// AfterCall records the call in the invocation log.
+func (q *ToolQuotas) AfterCall(ctx context.Context, tool string, failed bool, elapsed time.Duration) {
+	userID := q.identify(ctx)
+	if userID <= 0 {
+		return
+	}
+
+	durationMs := int(elapsed.Milliseconds())
+	inv := &models.ToolInvocation{UserID: userID, Tool: tool, IsError: failed, DurationMs: &durationMs}
+	if _, err := q.store.RecordToolInvocation(context.WithoutCancel(ctx), inv); err != nil {
+		log.Printf("ToolQuotas: failed to record invocation for user_id=%d tool=%s: %v", userID, tool, err)
+	}
+}
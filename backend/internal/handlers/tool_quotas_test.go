@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/mcp"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+)
+
+type mockToolQuotaStore struct {
+	categories map[string]string
+	limits     map[string]int
+	recorded   []models.ToolInvocation
+}
+
+func (m *mockToolQuotaStore) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	return &models.User{ID: 7}, nil
+}
+
+func (m *mockToolQuotaStore) GetEffectivePlan(ctx context.Context, userID int64) (*models.MembershipPlan, error) {
+	return &models.MembershipPlan{ID: 1, Slug: "free"}, nil
+}
+
+func (m *mockToolQuotaStore) ToolCategory(ctx context.Context, tool string) (string, error) {
+	if category, ok := m.categories[tool]; ok {
+		return category, nil
+	}
+	return models.DefaultToolCategory, nil
+}
+
+func (m *mockToolQuotaStore) ListToolCategories(ctx context.Context) (map[string]string, error) {
+	return m.categories, nil
+}
+
+func (m *mockToolQuotaStore) ListToolCategoryUsage(ctx context.Context, userID, planID int64, since time.Time) ([]models.ToolCategoryUsage, error) {
+	used := map[string]int64{}
+	for _, inv := range m.recorded {
+		if !inv.IsError && !inv.CreatedAt.Before(since) {
+			used[inv.Category]++
+		}
+	}
+	var usage []models.ToolCategoryUsage
+	for category, limit := range m.limits {
+		remaining := max(int64(limit)-used[category], 0)
+		usage = append(usage, models.ToolCategoryUsage{Category: category, Used: used[category], DailyLimit: &limit, Remaining: &remaining, Exceeded: remaining == 0})
+	}
+	return usage, nil
+}
+
+func (m *mockToolQuotaStore) RecordToolInvocation(ctx context.Context, inv *models.ToolInvocation) (int, error) {
+	inv.Category, _ = m.ToolCategory(ctx, inv.Tool)
+	inv.CreatedAt = time.Now()
+	m.recorded = append(m.recorded, *inv)
+	return models.DefaultToolCostUnits, nil
+}
+
+func TestToolQuotasRefuseCategoryOverDailyLimit(t *testing.T) {
+	store := &mockToolQuotaStore{
+		categories: map[string]string{"jira_create_issue": models.ToolCategoryWrite},
+		limits:     map[string]int{models.ToolCategoryWrite: 2},
+	}
+	quotas := NewToolQuotas(store, func(context.Context) int64 { return 7 })
+	ctx := context.Background()
+
+	// Failed calls do not use up the quota.
+	quotas.AfterCall(ctx, "jira_create_issue", true, time.Millisecond)
+	for i := 0; i < 2; i++ {
+		if err := quotas.BeforeCall(ctx, "jira_create_issue"); err != nil {
+			t.Fatalf("call %d: unexpected refusal: %v", i+1, err)
+		}
+		quotas.AfterCall(ctx, "jira_create_issue", false, time.Millisecond)
+	}
+
+	err := quotas.BeforeCall(ctx, "jira_create_issue")
+	var toolErr *mcp.ToolError
+	if !errors.As(err, &toolErr) || toolErr.Code != mcp.ErrorQuotaExceeded || toolErr.RetryAfter <= 0 {
+		t.Fatalf("expected quota_exceeded with a retry delay, got %v", err)
+	}
+	if err := quotas.BeforeCall(ctx, "getProjects"); err != nil {
+		t.Fatalf("expected read tools to stay available, got %v", err)
+	}
+	if len(store.recorded) != 3 || store.recorded[0].DurationMs == nil {
+		t.Fatalf("expected every call to be recorded, got %+v", store.recorded)
+	}
+}
+
+func TestToolQuotaUsageReportsCategories(t *testing.T) {
+	store := &mockToolQuotaStore{limits: map[string]int{models.ToolCategoryWrite: 100}}
+	req := httptest.NewRequest(http.MethodGet, "/api/metrics/tool-quotas", nil)
+	req = req.WithContext(context.WithValue(req.Context(), "user_id", int64(7)))
+	rec := httptest.NewRecorder()
+
+	ToolQuotaUsage(store, "").ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var body models.ToolQuotas
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if body.PlanSlug != "free" || len(body.Categories) != 1 || *body.Categories[0].Remaining != 100 || !body.ResetsAt.After(body.DayStart) {
+		t.Fatalf("unexpected tool quotas %+v", body)
+	}
+}
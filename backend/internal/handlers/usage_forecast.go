@@ -0,0 +1,269 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+)
+
+// forecastRunRateDays is the trailing window of completed days used for the
+// run-rate model.
+const forecastRunRateDays = 7
+
+// forecastMinTrendDays is how many completed days of the current month are
+// needed before the linear trend is trusted over the run rate.
+const forecastMinTrendDays = 7
+
+// UserLookup resolves a dashboard caller's email to their user record.
+type UserLookup interface {
+	GetUserByEmail(ctx context.Context, email string) (*models.User, error)
+}
+
+// ForecastStore defines the behaviour required to project a user's usage.
+type ForecastStore interface {
+	UserLookup
+	ListDailyUsage(ctx context.Context, userID int64, from, to time.Time) ([]models.DailyUsage, error)
+	GetEffectivePlan(ctx context.Context, userID int64) (*models.MembershipPlan, error)
+}
+
+// UsageForecast projects the caller's end-of-month request volume from the
+// daily rollups and reports the risk of exceeding their plan quota. The
+// caller is the MCP tenant when an mcp_secret is present, otherwise the
+// session or email identity used by the dashboard.
+func UsageForecast(store ForecastStore, cookieSecret string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := metricsUserID(w, r, store, cookieSecret)
+		if !ok {
+			return
+		}
+
+		now := time.Now().UTC()
+		periodStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+		days, err := store.ListDailyUsage(r.Context(), userID, periodStart.AddDate(0, 0, -forecastRunRateDays), now)
+		if err != nil {
+			log.Printf("UsageForecast: failed to list daily usage for user_id=%d: %v", userID, err)
+			http.Error(w, "failed to load usage", http.StatusInternalServerError)
+			return
+		}
+
+		plan, err := store.GetEffectivePlan(r.Context(), userID)
+		if err != nil {
+			log.Printf("UsageForecast: failed to resolve plan for user_id=%d: %v", userID, err)
+			http.Error(w, "failed to load plan", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(forecastUsage(days, now, plan)); err != nil {
+			http.Error(w, "failed to encode response", http.StatusInternalServerError)
+		}
+	}
+}
+
+// metricsUserID resolves the caller of a usage endpoint: the MCP tenant when
+// an mcp_secret is present, otherwise the session or email identity used by
+// the dashboard. It writes the error response itself when it returns false.
+func metricsUserID(w http.ResponseWriter, r *http.Request, users UserLookup, cookieSecret string) (int64, bool) {
+	if userID, ok := r.Context().Value("user_id").(int64); ok {
+		return userID, true
+	}
+
+	email := requestEmail(r, cookieSecret, "")
+	if email == "" {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return 0, false
+	}
+	user, err := users.GetUserByEmail(r.Context(), email)
+	if err != nil {
+		log.Printf("metricsUserID: failed to resolve user for email=%s: %v", email, err)
+		http.Error(w, "user not found", http.StatusNotFound)
+		return 0, false
+	}
+	return user.ID, true
+}
+
+// forecastUsage projects usage to the end of now's UTC calendar month. Two
+// models are computed: a run rate over the trailing completed days, and a
+// least-squares line through the month's completed days. The trend is used
+// once enough of the month has passed; before that the run rate is steadier.
+// Requests and tool cost units are projected independently and the overage
+// risk reflects whichever allowance is closer to running out.
+func forecastUsage(days []models.DailyUsage, now time.Time, plan *models.MembershipPlan) models.UsageForecast {
+	now = now.UTC()
+	periodStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	periodEnd := periodStart.AddDate(0, 1, -1)
+
+	requests := make(map[time.Time]float64, len(days))
+	costs := make(map[time.Time]float64, len(days))
+	var earliest time.Time
+	for _, d := range days {
+		day := time.Date(d.Day.Year(), d.Day.Month(), d.Day.Day(), 0, 0, 0, 0, time.UTC)
+		requests[day] += float64(d.RequestCount)
+		costs[day] += float64(d.CostUnits)
+		if earliest.IsZero() || day.Before(earliest) {
+			earliest = day
+		}
+	}
+
+	req := projectUsage(requests, earliest, now)
+	cost := projectUsage(costs, earliest, now)
+
+	f := models.UsageForecast{
+		PeriodStart:        periodStart,
+		PeriodEnd:          periodEnd,
+		DaysElapsed:        now.Day(),
+		DaysRemaining:      periodEnd.Day() - now.Day(),
+		MonthToDate:        int(req.toDate),
+		DailyAverage:       req.dailyAverage,
+		ProjectedRunRate:   int(math.Round(req.runRate)),
+		ProjectedTrend:     int(math.Round(req.trend)),
+		Projected:          int(math.Round(req.projected)),
+		Model:              req.model,
+		CostUnitsToDate:    int64(cost.toDate),
+		CostUnitsProjected: int64(math.Round(cost.projected)),
+		OverageRisk:        models.OverageRiskNone,
+	}
+
+	if plan == nil {
+		return f
+	}
+	f.PlanSlug = plan.Slug
+
+	worst := -1.0
+	note := func(projectedPercent float64, exhaustedOn *time.Time) {
+		worst = math.Max(worst, projectedPercent)
+		if exhaustedOn != nil && (f.QuotaExhaustedOn == nil || exhaustedOn.Before(*f.QuotaExhaustedOn)) {
+			f.QuotaExhaustedOn = exhaustedOn
+		}
+	}
+	if q := plan.MonthlyRequestQuota; q != nil && *q > 0 {
+		quota := *q
+		used, projected, exhaustedOn := req.against(float64(quota), now)
+		f.Quota, f.QuotaUsedPercent, f.ProjectedPercent = &quota, &used, &projected
+		note(projected, exhaustedOn)
+	}
+	if q := plan.MonthlyCostUnitQuota; q != nil && *q > 0 {
+		quota := *q
+		used, projected, exhaustedOn := cost.against(float64(quota), now)
+		f.CostUnitQuota, f.CostUnitsUsedPercent, f.CostUnitsProjectedPercent = &quota, &used, &projected
+		note(projected, exhaustedOn)
+	}
+
+	switch {
+	case worst < 0:
+		// Unlimited on every allowance.
+	case worst > 100:
+		f.OverageRisk = models.OverageRiskHigh
+	case worst >= 80:
+		f.OverageRisk = models.OverageRiskMedium
+	default:
+		f.OverageRisk = models.OverageRiskLow
+	}
+	f.UpgradeSuggested = f.OverageRisk == models.OverageRiskHigh
+
+	return f
+}
+
+// usageProjection is the end-of-month outlook for one daily usage series.
+type usageProjection struct {
+	toDate       float64
+	dailyAverage float64
+	runRate      float64
+	trend        float64
+	projected    float64
+	model        string
+	// estimate returns the expected usage on a future day of the month.
+	estimate func(day int) float64
+}
+
+// projectUsage projects a daily series, keyed by UTC day, to the end of now's
+// month. earliest is the first day with any recorded usage.
+func projectUsage(series map[time.Time]float64, earliest, now time.Time) usageProjection {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	periodStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	daysInMonth := periodStart.AddDate(0, 1, -1).Day()
+	remaining := float64(daysInMonth - now.Day())
+
+	var p usageProjection
+	for day := periodStart; !day.After(today); day = day.AddDate(0, 0, 1) {
+		p.toDate += series[day]
+	}
+
+	// Run rate: mean of the trailing completed days, shortened when the
+	// tenant has less history than the window.
+	windowStart := today.AddDate(0, 0, -forecastRunRateDays)
+	if !earliest.IsZero() && earliest.After(windowStart) {
+		windowStart = earliest
+	}
+	if windowDays := int(today.Sub(windowStart).Hours() / 24); windowDays > 0 {
+		total := 0.0
+		for day := windowStart; day.Before(today); day = day.AddDate(0, 0, 1) {
+			total += series[day]
+		}
+		p.dailyAverage = total / float64(windowDays)
+	} else {
+		// Nothing but today to go on.
+		p.dailyAverage = series[today]
+	}
+	p.runRate = p.toDate + p.dailyAverage*remaining
+
+	// Trend: ordinary least squares over the month's completed days, with
+	// days that have no rollup counted as zero.
+	completed := now.Day() - 1
+	slope, intercept := 0.0, p.dailyAverage
+	if completed >= 2 {
+		var sx, sy, sxx, sxy float64
+		for i := 1; i <= completed; i++ {
+			x, y := float64(i), series[periodStart.AddDate(0, 0, i-1)]
+			sx += x
+			sy += y
+			sxx += x * x
+			sxy += x * y
+		}
+		n := float64(completed)
+		slope = (n*sxy - sx*sy) / (n*sxx - sx*sx)
+		intercept = (sy - slope*sx) / n
+	}
+	trend := func(day int) float64 { return math.Max(0, intercept+slope*float64(day)) }
+	p.trend = p.toDate
+	for day := now.Day() + 1; day <= daysInMonth; day++ {
+		p.trend += trend(day)
+	}
+
+	p.model, p.projected = "run_rate", p.runRate
+	p.estimate = func(int) float64 { return p.dailyAverage }
+	if completed >= forecastMinTrendDays {
+		p.model, p.projected, p.estimate = "trend", p.trend, trend
+	}
+
+	return p
+}
+
+// against compares the projection with a monthly allowance, returning the
+// percentage used so far, the projected percentage, and the day the
+// allowance is expected to run out, if it does this month.
+func (p usageProjection) against(quota float64, now time.Time) (used, projected float64, exhaustedOn *time.Time) {
+	periodStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	daysInMonth := periodStart.AddDate(0, 1, -1).Day()
+
+	used = p.toDate / quota * 100
+	projected = p.projected / quota * 100
+
+	cumulative := p.toDate
+	for day := now.Day(); day <= daysInMonth; day++ {
+		if day > now.Day() {
+			cumulative += p.estimate(day)
+		}
+		if cumulative >= quota {
+			t := periodStart.AddDate(0, 0, day-1)
+			return used, projected, &t
+		}
+	}
+	return used, projected, nil
+}
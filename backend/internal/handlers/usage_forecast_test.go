@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+)
+
+func dailyUsage(from time.Time, counts ...int) []models.DailyUsage {
+	days := make([]models.DailyUsage, 0, len(counts))
+	for i, c := range counts {
+		days = append(days, models.DailyUsage{Day: from.AddDate(0, 0, i), RequestCount: c})
+	}
+	return days
+}
+
+func TestForecastUsageRunRate(t *testing.T) {
+	// April has 30 days; on the 4th three days are complete.
+	now := time.Date(2026, time.April, 4, 12, 0, 0, 0, time.UTC)
+	quota := 1000
+	plan := &models.MembershipPlan{Slug: "free", MonthlyRequestQuota: &quota}
+
+	f := forecastUsage(dailyUsage(time.Date(2026, time.April, 1, 0, 0, 0, 0, time.UTC), 40, 40, 40, 10), now, plan)
+
+	if f.Model != "run_rate" {
+		t.Fatalf("model = %q, want run_rate", f.Model)
+	}
+	if f.MonthToDate != 130 {
+		t.Fatalf("month_to_date = %d, want 130", f.MonthToDate)
+	}
+	// 130 so far plus 40/day for the remaining 26 days.
+	if f.Projected != 1170 {
+		t.Fatalf("projected = %d, want 1170", f.Projected)
+	}
+	if f.OverageRisk != models.OverageRiskHigh || !f.UpgradeSuggested {
+		t.Fatalf("risk = %q upgrade = %v, want high and suggested", f.OverageRisk, f.UpgradeSuggested)
+	}
+	// 130 + 40*22 crosses 1000 on the 26th.
+	if f.QuotaExhaustedOn == nil || f.QuotaExhaustedOn.Day() != 26 {
+		t.Fatalf("quota_exhausted_on = %v, want April 26", f.QuotaExhaustedOn)
+	}
+}
+
+func TestForecastUsageTrend(t *testing.T) {
+	now := time.Date(2026, time.April, 11, 0, 0, 0, 0, time.UTC)
+	quota := 25000
+	plan := &models.MembershipPlan{Slug: "basic", MonthlyRequestQuota: &quota}
+
+	// Ten completed days growing by 10 a day.
+	f := forecastUsage(dailyUsage(time.Date(2026, time.April, 1, 0, 0, 0, 0, time.UTC), 10, 20, 30, 40, 50, 60, 70, 80, 90, 100), now, plan)
+
+	if f.Model != "trend" {
+		t.Fatalf("model = %q, want trend", f.Model)
+	}
+	// 550 so far plus 120+130+...+300 for days 12 through 30; today counts
+	// as observed.
+	if f.ProjectedTrend != 4540 {
+		t.Fatalf("projected_trend = %d, want 4540", f.ProjectedTrend)
+	}
+	if f.ProjectedTrend <= f.ProjectedRunRate {
+		t.Fatalf("trend %d should exceed run rate %d for growing usage", f.ProjectedTrend, f.ProjectedRunRate)
+	}
+	if f.OverageRisk != models.OverageRiskLow || f.UpgradeSuggested {
+		t.Fatalf("risk = %q upgrade = %v, want low", f.OverageRisk, f.UpgradeSuggested)
+	}
+}
+
+func TestForecastUsageUnlimitedPlan(t *testing.T) {
+	now := time.Date(2026, time.April, 4, 0, 0, 0, 0, time.UTC)
+	plan := &models.MembershipPlan{Slug: "premium"}
+
+	f := forecastUsage(dailyUsage(time.Date(2026, time.April, 1, 0, 0, 0, 0, time.UTC), 5000, 5000, 5000), now, plan)
+
+	if f.Quota != nil || f.OverageRisk != models.OverageRiskNone || f.UpgradeSuggested {
+		t.Fatalf("unlimited plan reported quota=%v risk=%q upgrade=%v", f.Quota, f.OverageRisk, f.UpgradeSuggested)
+	}
+}
+
+func TestForecastUsageCostUnitQuota(t *testing.T) {
+	now := time.Date(2026, time.April, 4, 0, 0, 0, 0, time.UTC)
+	requests, costUnits := 100000, 200
+	plan := &models.MembershipPlan{Slug: "free", MonthlyRequestQuota: &requests, MonthlyCostUnitQuota: &costUnits}
+
+	days := dailyUsage(time.Date(2026, time.April, 1, 0, 0, 0, 0, time.UTC), 10, 10, 10)
+	for i := range days {
+		days[i].CostUnits = 30
+	}
+	f := forecastUsage(days, now, plan)
+
+	if f.CostUnitsToDate != 90 {
+		t.Fatalf("cost_units_to_date = %d, want 90", f.CostUnitsToDate)
+	}
+	// Requests are nowhere near their quota, but heavy tools will exhaust
+	// the cost unit allowance: 90 + 30/day crosses 200 on the 8th.
+	if f.OverageRisk != models.OverageRiskHigh || !f.UpgradeSuggested {
+		t.Fatalf("risk = %q upgrade = %v, want high and suggested", f.OverageRisk, f.UpgradeSuggested)
+	}
+	if f.QuotaExhaustedOn == nil || f.QuotaExhaustedOn.Day() != 8 {
+		t.Fatalf("quota_exhausted_on = %v, want April 8", f.QuotaExhaustedOn)
+	}
+}
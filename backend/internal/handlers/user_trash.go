@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/store"
+)
+
+// UserTrashStore defines the behaviour required to restore deleted accounts
+// or purge them before their retention window passes.
+type UserTrashStore interface {
+	ListDeletedUsers(ctx context.Context) ([]models.DeletedUser, error)
+	RestoreUser(ctx context.Context, email string) error
+	PurgeUser(ctx context.Context, email string) (*models.AccountDeletion, error)
+}
+
+// AdminDeletedUsers lists the deleted accounts that can still be restored
+// and when each will be purged.
+// GET
+func AdminDeletedUsers(store UserTrashStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		deleted, err := store.ListDeletedUsers(r.Context())
+		if err != nil {
+			log.Printf("AdminDeletedUsers: failed to list deleted users: %v", err)
+			http.Error(w, "failed to load deleted users", http.StatusBadGateway)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"users": deleted})
+	}
+}
+
+// AdminRestoreUser restores a deleted account with its data.
+// POST {"email": "..."}
+func AdminRestoreUser(store UserTrashStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		email, ok := trashUserEmail(w, r)
+		if !ok {
+			return
+		}
+		if !trashUserResult(w, "AdminRestoreUser", email, store.RestoreUser(r.Context(), email)) {
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"ok": true, "email": email})
+	}
+}
+
+// AdminPurgeUser removes a deleted account and all its data now, for
+// erasure requests that cannot wait for the retention window, and returns
+// what was removed.
+// POST {"email": "..."}
+func AdminPurgeUser(store UserTrashStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		email, ok := trashUserEmail(w, r)
+		if !ok {
+			return
+		}
+		deletion, err := store.PurgeUser(r.Context(), email)
+		if !trashUserResult(w, "AdminPurgeUser", email, err) {
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"ok": true, "email": email, "deletion": deletion})
+	}
+}
+
+// trashUserEmail reads the email of the account to act on from the body.
+func trashUserEmail(w http.ResponseWriter, r *http.Request) (string, bool) {
+	var body struct {
+		Email string `json:"email"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16)).Decode(&body); err != nil {
+		http.Error(w, "invalid JSON payload", http.StatusBadRequest)
+		return "", false
+	}
+	email := strings.TrimSpace(body.Email)
+	if email == "" {
+		http.Error(w, "email is required", http.StatusBadRequest)
+		return "", false
+	}
+	return email, true
+}
+
+// trashUserResult writes the error response for err, if any, and reports
+// whether the request succeeded.
+func trashUserResult(w http.ResponseWriter, name, email string, err error) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, store.ErrUserNotFound):
+		http.Error(w, "deleted user not found", http.StatusNotFound)
+	default:
+		log.Printf("%s: failed for email=%s: %v", name, email, err)
+		http.Error(w, "failed to update deleted user", http.StatusBadGateway)
+	}
+	return false
+}
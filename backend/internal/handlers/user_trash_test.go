@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/store"
+)
+
+type fakeUserTrashStore struct {
+	deleted map[string]bool
+	purged  []string
+}
+
+func (f *fakeUserTrashStore) ListDeletedUsers(ctx context.Context) ([]models.DeletedUser, error) {
+	return []models.DeletedUser{}, nil
+}
+
+func (f *fakeUserTrashStore) RestoreUser(ctx context.Context, email string) error {
+	if !f.deleted[email] {
+		return store.ErrUserNotFound
+	}
+	delete(f.deleted, email)
+	return nil
+}
+
+func (f *fakeUserTrashStore) PurgeUser(ctx context.Context, email string) (*models.AccountDeletion, error) {
+	if !f.deleted[email] {
+		return nil, store.ErrUserNotFound
+	}
+	f.purged = append(f.purged, email)
+	return &models.AccountDeletion{Email: email, Removed: map[string]int64{}}, nil
+}
+
+func TestAdminRestoreAndPurgeUser(t *testing.T) {
+	trash := &fakeUserTrashStore{deleted: map[string]bool{"gone@example.com": true}}
+
+	rec := httptest.NewRecorder()
+	AdminPurgeUser(trash)(rec, httptest.NewRequest(http.MethodPost, "/api/admin/users/deleted/purge", strings.NewReader(`{"email":"active@example.com"}`)))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 purging an account that is not deleted, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	AdminRestoreUser(trash)(rec, httptest.NewRequest(http.MethodPost, "/api/admin/users/deleted/restore", strings.NewReader(`{"email":" gone@example.com "}`)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if trash.deleted["gone@example.com"] {
+		t.Fatal("expected the account to be restored")
+	}
+
+	rec = httptest.NewRecorder()
+	AdminRestoreUser(trash)(rec, httptest.NewRequest(http.MethodPost, "/api/admin/users/deleted/restore", strings.NewReader(`{}`)))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 without an email, got %d", rec.Code)
+	}
+}
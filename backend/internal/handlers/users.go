@@ -2,41 +2,59 @@ package handlers
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
 	"strconv"
 
 	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+	storepkg "github.com/PortNumber53/mcp-jira-thing/backend/internal/store"
 )
 
 const defaultUserPageSize = 50
 
 // UserLister defines the behaviour required from the storage client backing the users handler.
 type UserLister interface {
-	ListUsers(rCtx context.Context, limit int) ([]models.PublicUser, error)
+	ListUsers(rCtx context.Context, opts models.UserListOptions) (*models.UserPage, error)
 }
 
-// Users creates an HTTP handler that returns a list of users from the primary database.
+// Users creates an HTTP handler that returns a page of users from the primary
+// database. limit sets the page size (at most 200), sort one of the
+// models.UserSort orders (newest first by default) and cursor the
+// next_cursor of the previous page. The response carries the total number
+// of users and, unless it is the last page, next_cursor.
+// GET /api/users?limit=50&sort=email&cursor=...
 func Users(client UserLister) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
+		query := r.URL.Query()
 
-		limit := defaultUserPageSize
-		if override := r.URL.Query().Get("limit"); override != "" {
+		opts := models.UserListOptions{
+			Limit:  defaultUserPageSize,
+			Sort:   query.Get("sort"),
+			Cursor: query.Get("cursor"),
+		}
+		if override := query.Get("limit"); override != "" {
 			if parsed, err := strconv.Atoi(override); err == nil && parsed > 0 {
-				limit = parsed
+				opts.Limit = parsed
 			}
 		}
 
-		users, err := client.ListUsers(ctx, limit)
+		page, err := client.ListUsers(ctx, opts)
+		if errors.Is(err, storepkg.ErrInvalidUserSort) {
+			http.Error(w, "sort must be one of created_at, -created_at, email, -email, name, -name", http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, storepkg.ErrInvalidUserCursor) {
+			http.Error(w, "invalid cursor", http.StatusBadRequest)
+			return
+		}
 		if err != nil {
+			log.Printf("Users: failed to list users: %v", err)
 			http.Error(w, "failed to load users", http.StatusBadGateway)
 			return
 		}
 
-		w.Header().Set("Content-Type", "application/json")
-		if err := json.NewEncoder(w).Encode(map[string]any{"users": users}); err != nil {
-			http.Error(w, "failed to encode response", http.StatusInternalServerError)
-		}
+		writeJSON(w, http.StatusOK, page)
 	}
 }
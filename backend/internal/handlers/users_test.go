@@ -2,30 +2,32 @@ package handlers
 
 import (
 	"context"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
 	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+	storepkg "github.com/PortNumber53/mcp-jira-thing/backend/internal/store"
 )
 
 type mockUserClient struct {
-	lastLimit int
-	users     []models.PublicUser
-	err       error
+	lastOpts models.UserListOptions
+	page     *models.UserPage
+	err      error
 }
 
-func (m *mockUserClient) ListUsers(ctx context.Context, limit int) ([]models.PublicUser, error) {
-	m.lastLimit = limit
-	return m.users, m.err
+func (m *mockUserClient) ListUsers(ctx context.Context, opts models.UserListOptions) (*models.UserPage, error) {
+	m.lastOpts = opts
+	return m.page, m.err
 }
 
 func TestUsersHandler(t *testing.T) {
 	client := &mockUserClient{
-		users: []models.PublicUser{{ID: "rec1"}},
+		page: &models.UserPage{Users: []models.PublicUser{{ID: "rec1"}}, NextCursor: "next", Total: 12},
 	}
 
-	req := httptest.NewRequest(http.MethodGet, "/users?limit=5", nil)
+	req := httptest.NewRequest(http.MethodGet, "/users?limit=5&sort=-email&cursor=abc", nil)
 	rr := httptest.NewRecorder()
 
 	handler := Users(client)
@@ -35,7 +37,27 @@ func TestUsersHandler(t *testing.T) {
 		t.Fatalf("unexpected status: %d", rr.Code)
 	}
 
-	if client.lastLimit != 5 {
-		t.Fatalf("expected limit 5 got %d", client.lastLimit)
+	want := models.UserListOptions{Limit: 5, Sort: "-email", Cursor: "abc"}
+	if client.lastOpts != want {
+		t.Fatalf("expected options %+v got %+v", want, client.lastOpts)
+	}
+
+	var body models.UserPage
+	if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(body.Users) != 1 || body.NextCursor != "next" || body.Total != 12 {
+		t.Fatalf("unexpected response %+v", body)
+	}
+}
+
+func TestUsersHandlerRejectsInvalidCursor(t *testing.T) {
+	client := &mockUserClient{err: storepkg.ErrInvalidUserCursor}
+
+	rr := httptest.NewRecorder()
+	Users(client).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/users?cursor=bogus", nil))
+
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rr.Code)
 	}
 }
@@ -0,0 +1,285 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	stripeClient "github.com/PortNumber53/mcp-jira-thing/backend/internal/stripe"
+)
+
+// MCPSecretReader looks up the mcp_secret a tenant's webhooks are
+// registered with.
+type MCPSecretReader interface {
+	GetMCPSecret(ctx context.Context, email string) (*string, error)
+}
+
+type webhookSimulationRequest struct {
+	// Event is the Stripe event type or Jira webhookEvent to send.
+	Event string `json:"event"`
+	Email string `json:"email"`
+
+	// Stripe objects default to IDs derived from the email, so a simulated
+	// checkout and the subscription and invoice events after it line up.
+	CustomerID     string `json:"customer_id,omitempty"`
+	SubscriptionID string `json:"subscription_id,omitempty"`
+	PriceID        string `json:"price_id,omitempty"`
+	Status         string `json:"status,omitempty"`
+	Amount         int    `json:"amount,omitempty"`
+	Currency       string `json:"currency,omitempty"`
+
+	IssueKey string `json:"issue_key,omitempty"`
+	Summary  string `json:"summary,omitempty"`
+}
+
+type webhookSimulationResult struct {
+	Provider string          `json:"provider"`
+	Event    string          `json:"event"`
+	Payload  json.RawMessage `json:"payload"`
+	Status   int             `json:"status"`
+	Response string          `json:"response"`
+}
+
+// WebhookSimulator builds a realistic Stripe or Jira webhook for a local
+// account, signs it the way the provider would and delivers it through
+// router (the application router), so billing and Jira flows can be tested
+// without exposing the backend to the internet. It must only be mounted in
+// the development profile.
+func WebhookSimulator(router http.Handler, secrets MCPSecretReader, stripeWebhookSecret string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		provider := chi.URLParam(r, "provider")
+
+		var req webhookSimulationRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&req); err != nil {
+			http.Error(w, "invalid JSON payload", http.StatusBadRequest)
+			return
+		}
+		req.Event = strings.TrimSpace(req.Event)
+		req.Email = strings.ToLower(strings.TrimSpace(req.Email))
+		if req.Event == "" || req.Email == "" {
+			http.Error(w, "event and email are required", http.StatusBadRequest)
+			return
+		}
+
+		var payload any
+		var path, signature string
+		switch provider {
+		case "stripe":
+			object, ok := simulatedStripeObject(req)
+			if !ok {
+				http.Error(w, "unsupported Stripe event type", http.StatusBadRequest)
+				return
+			}
+			payload = map[string]any{
+				"id":          "evt_sim_" + simulationID(),
+				"object":      "event",
+				"api_version": "2024-06-20",
+				"created":     time.Now().Unix(),
+				"livemode":    false,
+				"type":        req.Event,
+				"data":        map[string]any{"object": object},
+			}
+			path = "/api/webhooks/stripe"
+
+		case "jira":
+			if secrets == nil {
+				http.Error(w, "Jira webhooks are not available", http.StatusNotImplemented)
+				return
+			}
+			secret, err := secrets.GetMCPSecret(r.Context(), req.Email)
+			if err != nil || secret == nil {
+				http.Error(w, "no mcp_secret found for email", http.StatusNotFound)
+				return
+			}
+			payload = simulatedJiraPayload(req)
+			path = "/api/webhooks/jira?mcp_secret=" + url.QueryEscape(*secret)
+
+		default:
+			http.Error(w, "provider must be stripe or jira", http.StatusNotFound)
+			return
+		}
+
+		body, err := json.Marshal(payload)
+		if err != nil {
+			http.Error(w, "failed to encode payload", http.StatusInternalServerError)
+			return
+		}
+		if provider == "stripe" && stripeWebhookSecret != "" {
+			signature = stripeClient.SignWebhookPayload(stripeWebhookSecret, body, time.Now())
+		}
+
+		delivery := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(body))
+		delivery = delivery.WithContext(context.WithoutCancel(r.Context()))
+		delivery.Header.Set("Content-Type", "application/json")
+		if signature != "" {
+			delivery.Header.Set("Stripe-Signature", signature)
+		}
+		delivery.RemoteAddr = r.RemoteAddr
+
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, delivery)
+
+		log.Printf("WebhookSimulator: delivered %s %s for %s: %d", provider, req.Event, req.Email, rec.Code)
+		writeJSON(w, http.StatusOK, webhookSimulationResult{
+			Provider: provider,
+			Event:    req.Event,
+			Payload:  body,
+			Status:   rec.Code,
+			Response: strings.TrimSpace(rec.Body.String()),
+		})
+	}
+}
+
+// simulatedStripeObject returns the data.object of a Stripe event, shaped
+// like the real API objects the webhook handler reads.
+func simulatedStripeObject(req webhookSimulationRequest) (map[string]any, bool) {
+	seed := simulationSeed(req.Email)
+	customerID := firstNonEmpty(req.CustomerID, "cus_sim_"+seed)
+	subscriptionID := firstNonEmpty(req.SubscriptionID, "sub_sim_"+seed)
+	currency := firstNonEmpty(strings.ToLower(req.Currency), "usd")
+	amount := req.Amount
+	if amount <= 0 {
+		amount = 1900
+	}
+	now := time.Now()
+
+	subscription := func(status string) map[string]any {
+		return map[string]any{
+			"id":                   subscriptionID,
+			"object":               "subscription",
+			"customer":             customerID,
+			"status":               firstNonEmpty(req.Status, status),
+			"cancel_at_period_end": false,
+			"current_period_start": now.Unix(),
+			"current_period_end":   now.AddDate(0, 1, 0).Unix(),
+			"pause_collection":     nil,
+			"items": map[string]any{
+				"object": "list",
+				"data": []any{map[string]any{
+					"id":     "si_sim_" + seed,
+					"object": "subscription_item",
+					"price":  map[string]any{"id": firstNonEmpty(req.PriceID, "price_sim_basic"), "object": "price", "currency": currency, "unit_amount": amount},
+				}},
+			},
+		}
+	}
+	invoice := func(paid bool) map[string]any {
+		obj := map[string]any{
+			"id":                 "in_sim_" + simulationID(),
+			"object":             "invoice",
+			"customer":           customerID,
+			"customer_email":     req.Email,
+			"subscription":       subscriptionID,
+			"currency":           currency,
+			"amount_due":         amount,
+			"amount_paid":        0,
+			"status":             "open",
+			"hosted_invoice_url": "https://invoice.stripe.com/i/acct_sim/test_sim",
+			"created":            now.Unix(),
+		}
+		if paid {
+			obj["amount_paid"] = amount
+			obj["status"] = "paid"
+		}
+		return obj
+	}
+
+	switch req.Event {
+	case "checkout.session.completed":
+		return map[string]any{
+			"id":               "cs_test_sim_" + simulationID(),
+			"object":           "checkout.session",
+			"mode":             "subscription",
+			"status":           "complete",
+			"payment_status":   "paid",
+			"customer":         customerID,
+			"customer_email":   req.Email,
+			"customer_details": map[string]any{"email": req.Email},
+			"subscription":     subscriptionID,
+			"amount_total":     amount,
+			"currency":         currency,
+		}, true
+	case "customer.subscription.created", "customer.subscription.updated":
+		return subscription("active"), true
+	case "customer.subscription.deleted":
+		return subscription("canceled"), true
+	case "invoice.payment_succeeded":
+		return invoice(true), true
+	case "invoice.payment_failed":
+		return invoice(false), true
+	case "charge.dispute.created", "charge.dispute.updated", "charge.dispute.closed":
+		status := "needs_response"
+		if req.Event == "charge.dispute.closed" {
+			status = "lost"
+		}
+		return map[string]any{
+			"id":       "dp_sim_" + seed,
+			"object":   "dispute",
+			"charge":   "ch_sim_" + seed,
+			"amount":   amount,
+			"currency": currency,
+			"reason":   "fraudulent",
+			"status":   firstNonEmpty(req.Status, status),
+			"created":  now.Unix(),
+		}, true
+	}
+	return nil, false
+}
+
+// simulatedJiraPayload returns a Jira Cloud issue webhook body.
+func simulatedJiraPayload(req webhookSimulationRequest) map[string]any {
+	key := firstNonEmpty(strings.ToUpper(req.IssueKey), "SIM-1")
+	return map[string]any{
+		"timestamp":             time.Now().UnixMilli(),
+		"webhookEvent":          req.Event,
+		"issue_event_type_name": "issue_generic",
+		"user": map[string]any{
+			"accountId":    "sim-" + simulationSeed(req.Email),
+			"displayName":  req.Email,
+			"emailAddress": req.Email,
+		},
+		"issue": map[string]any{
+			"id":   "10000",
+			"key":  key,
+			"self": "https://example.atlassian.net/rest/api/3/issue/10000",
+			"fields": map[string]any{
+				"summary":   firstNonEmpty(req.Summary, "Simulated issue"),
+				"status":    map[string]any{"name": firstNonEmpty(req.Status, "In Progress")},
+				"issuetype": map[string]any{"name": "Task"},
+				"project":   map[string]any{"key": strings.SplitN(key, "-", 2)[0]},
+			},
+		},
+	}
+}
+
+// simulationSeed derives stable object IDs for an account.
+func simulationSeed(email string) string {
+	sum := sha256.Sum256([]byte(email))
+	return hex.EncodeToString(sum[:7])
+}
+
+// simulationID returns a random suffix for one-off object IDs.
+func simulationID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// firstNonEmpty returns value, or fallback when value is empty.
+func firstNonEmpty(value, fallback string) string {
+	if value != "" {
+		return value
+	}
+	return fallback
+}
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	stripeClient "github.com/PortNumber53/mcp-jira-thing/backend/internal/stripe"
+)
+
+type fakeMCPSecrets map[string]string
+
+func (f fakeMCPSecrets) GetMCPSecret(ctx context.Context, email string) (*string, error) {
+	secret, ok := f[email]
+	if !ok {
+		return nil, errors.New("no local user")
+	}
+	return &secret, nil
+}
+
+func TestWebhookSimulatorSignsStripeEvents(t *testing.T) {
+	var delivered map[string]any
+	router := chi.NewRouter()
+	router.Post("/api/webhooks/stripe", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if err := stripeClient.VerifyWebhookSignature(body, r.Header.Get("Stripe-Signature"), "whsec_test", time.Minute); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		json.Unmarshal(body, &delivered)
+		w.WriteHeader(http.StatusOK)
+	})
+	router.Post("/api/dev/webhooks/{provider}", WebhookSimulator(router, nil, "whsec_test"))
+
+	simulate := func(body string) webhookSimulationResult {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/dev/webhooks/stripe", strings.NewReader(body)))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var result webhookSimulationResult
+		json.Unmarshal(rec.Body.Bytes(), &result)
+		return result
+	}
+
+	result := simulate(`{"event":"customer.subscription.updated","email":"User@Example.com","price_id":"price_pro"}`)
+	if result.Status != http.StatusOK || delivered["type"] != "customer.subscription.updated" {
+		t.Fatalf("expected the signed event to be accepted, got %+v", result)
+	}
+	object := delivered["data"].(map[string]any)["object"].(map[string]any)
+	if extractPriceID(object) != "price_pro" || object["status"] != "active" {
+		t.Fatalf("unexpected subscription object %v", object)
+	}
+
+	// Later events for the same account reference the same customer.
+	customerID := object["customer"]
+	simulate(`{"event":"invoice.payment_succeeded","email":"user@example.com"}`)
+	invoice := delivered["data"].(map[string]any)["object"].(map[string]any)
+	if invoice["customer"] != customerID || invoice["amount_paid"] != float64(1900) {
+		t.Fatalf("unexpected invoice object %v", invoice)
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/dev/webhooks/stripe", strings.NewReader(`{"event":"payout.paid","email":"user@example.com"}`)))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected unsupported events to be refused, got %d", rec.Code)
+	}
+}
+
+func TestWebhookSimulatorUsesTenantMCPSecretForJira(t *testing.T) {
+	var gotSecret, gotEvent string
+	router := chi.NewRouter()
+	router.Post("/api/webhooks/jira", func(w http.ResponseWriter, r *http.Request) {
+		gotSecret = r.URL.Query().Get("mcp_secret")
+		var payload jiraWebhookPayload
+		json.NewDecoder(r.Body).Decode(&payload)
+		gotEvent = payload.WebhookEvent + " " + payload.Issue.Key
+		w.WriteHeader(http.StatusAccepted)
+	})
+	router.Post("/api/dev/webhooks/{provider}", WebhookSimulator(router, fakeMCPSecrets{"user@example.com": "s3cret"}, ""))
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/dev/webhooks/jira", strings.NewReader(`{"event":"jira:issue_updated","email":"user@example.com","issue_key":"abc-7"}`)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if gotSecret != "s3cret" || gotEvent != "jira:issue_updated ABC-7" {
+		t.Fatalf("unexpected delivery: secret=%q event=%q", gotSecret, gotEvent)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/dev/webhooks/jira", strings.NewReader(`{"event":"jira:issue_updated","email":"nobody@example.com"}`)))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected unknown accounts to be refused, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/worker"
+)
+
+// WorkerStatsSource exposes the job worker's in-process statistics.
+type WorkerStatsSource interface {
+	GetStats() worker.Stats
+}
+
+// PrometheusSource writes metrics in the Prometheus text exposition format.
+type PrometheusSource interface {
+	WritePrometheus(w io.Writer) error
+}
+
+// WorkerMetrics serves the job worker's statistics in the Prometheus text
+// exposition format, followed by those of extra.
+func WorkerMetrics(src WorkerStatsSource, extra ...PrometheusSource) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		stats := src.GetStats()
+		var b strings.Builder
+		metric := func(name, kind, help string, value float64) {
+			fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n%s %g\n", name, help, name, kind, name, value)
+		}
+		metric("worker_jobs_processed_total", "counter", "Jobs processed by this instance.", float64(stats.JobsProcessed))
+		metric("worker_jobs_succeeded_total", "counter", "Jobs that completed successfully.", float64(stats.JobsSucceeded))
+		metric("worker_jobs_failed_total", "counter", "Job attempts that failed.", float64(stats.JobsFailed))
+		metric("worker_jobs_retried_total", "counter", "Failed attempts scheduled for retry.", float64(stats.JobsRetried))
+		metric("worker_active_jobs", "gauge", "Jobs currently being processed.", float64(stats.ActiveWorkers))
+		metric("worker_concurrency", "gauge", "Running processor goroutines.", float64(stats.Concurrency))
+		metric("worker_queue_depth", "gauge", "Ready jobs at the last autoscaler sample.", float64(stats.QueueDepth))
+		metric("worker_queue_wait_seconds", "gauge", "Smoothed time jobs wait before being claimed.", stats.QueueWait.Seconds())
+		for _, source := range extra {
+			if err := source.WritePrometheus(&b); err != nil {
+				log.Printf("WorkerMetrics: write metrics: %v", err)
+			}
+		}
+
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_, _ = w.Write([]byte(b.String()))
+	}
+}
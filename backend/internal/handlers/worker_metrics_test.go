@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/worker"
+)
+
+type fakeWorkerStats worker.Stats
+
+type fakePrometheusSource string
+
+func (f fakePrometheusSource) WritePrometheus(w io.Writer) error {
+	_, err := io.WriteString(w, string(f))
+	return err
+}
+
+func (f fakeWorkerStats) GetStats() worker.Stats { return worker.Stats(f) }
+
+func TestWorkerMetrics(t *testing.T) {
+	handler := WorkerMetrics(fakeWorkerStats{JobsProcessed: 12, Concurrency: 4, QueueDepth: 3, QueueWait: 1500 * time.Millisecond},
+		fakePrometheusSource("store_queries_total{method=\"Store.ListUsers\"} 7\n"))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	body := rec.Body.String()
+	for _, want := range []string{
+		"# TYPE worker_jobs_processed_total counter\nworker_jobs_processed_total 12\n",
+		"worker_concurrency 4\n",
+		"worker_queue_depth 3\n",
+		"worker_queue_wait_seconds 1.5\n",
+		"store_queries_total{method=\"Store.ListUsers\"} 7\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics missing %q:\n%s", want, body)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package httpserver
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// connectionTracker follows the server's connections through
+// http.Server.ConnState and reports them, with the state of the shutdown
+// drain, on /metrics.
+type connectionTracker struct {
+	mu           sync.Mutex
+	states       map[net.Conn]http.ConnState
+	drainStarted time.Time
+	lastDrain    time.Duration
+	forced       int64
+}
+
+func newConnectionTracker() *connectionTracker {
+	return &connectionTracker{states: map[net.Conn]http.ConnState{}}
+}
+
+// track is the http.Server ConnState hook. Hijacked connections, such as
+// websockets, leave the server's hands and are no longer counted.
+func (t *connectionTracker) track(conn net.Conn, state http.ConnState) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	switch state {
+	case http.StateClosed, http.StateHijacked:
+		delete(t.states, conn)
+	default:
+		t.states[conn] = state
+	}
+}
+
+// counts returns the open connections and those serving a request.
+func (t *connectionTracker) counts() (open, active int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, state := range t.states {
+		if state == http.StateActive {
+			active++
+		}
+	}
+	return len(t.states), active
+}
+
+func (t *connectionTracker) startDrain() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.drainStarted = time.Now()
+}
+
+// finishDrain records a drain that had to close forced connections at its
+// deadline and returns how long it took.
+func (t *connectionTracker) finishDrain(forced int) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.lastDrain = time.Since(t.drainStarted)
+	t.drainStarted = time.Time{}
+	t.forced += int64(forced)
+	return t.lastDrain
+}
+
+// WritePrometheus writes the connection and drain metrics in the Prometheus
+// text exposition format.
+func (t *connectionTracker) WritePrometheus(w io.Writer) error {
+	open, active := t.counts()
+	t.mu.Lock()
+	draining := 0
+	if !t.drainStarted.IsZero() {
+		draining = 1
+	}
+	lastDrain, forced := t.lastDrain, t.forced
+	t.mu.Unlock()
+
+	var b strings.Builder
+	b.WriteString("# HELP http_connections_open Client connections held by the HTTP server.\n")
+	b.WriteString("# TYPE http_connections_open gauge\n")
+	fmt.Fprintf(&b, "http_connections_open %d\n", open)
+	b.WriteString("# HELP http_connections_active Client connections serving a request.\n")
+	b.WriteString("# TYPE http_connections_active gauge\n")
+	fmt.Fprintf(&b, "http_connections_active %d\n", active)
+	b.WriteString("# HELP http_draining Whether the server is draining connections for shutdown.\n")
+	b.WriteString("# TYPE http_draining gauge\n")
+	fmt.Fprintf(&b, "http_draining %d\n", draining)
+	b.WriteString("# HELP http_drain_duration_seconds Duration of the last completed drain.\n")
+	b.WriteString("# TYPE http_drain_duration_seconds gauge\n")
+	fmt.Fprintf(&b, "http_drain_duration_seconds %g\n", lastDrain.Seconds())
+	b.WriteString("# HELP http_drain_forced_connections_total Connections closed because a drain reached its deadline.\n")
+	b.WriteString("# TYPE http_drain_forced_connections_total counter\n")
+	fmt.Fprintf(&b, "http_drain_forced_connections_total %d\n", forced)
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
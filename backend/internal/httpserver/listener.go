@@ -0,0 +1,55 @@
+package httpserver
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+)
+
+// systemdFirstFD is the first file descriptor systemd passes to a socket
+// activated service.
+const systemdFirstFD = 3
+
+// listen returns the socket to serve on: the one systemd passed in through
+// socket activation, or a new one bound to addr. With reusePort the new
+// socket sets SO_REUSEPORT, so the next release can bind the same address
+// while this one drains.
+func listen(addr string, reusePort bool) (net.Listener, error) {
+	ln, err := systemdListener()
+	if ln != nil || err != nil {
+		return ln, err
+	}
+
+	var lc net.ListenConfig
+	if reusePort {
+		lc.Control = setReusePort
+	}
+	if addr == "" {
+		addr = ":http"
+	}
+	return lc.Listen(context.Background(), "tcp", addr)
+}
+
+// systemdListener returns the first socket passed by systemd socket
+// activation, or nil when the process was not socket activated.
+func systemdListener() (net.Listener, error) {
+	pid, _ := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	fds, _ := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if pid != os.Getpid() || fds < 1 {
+		return nil, nil
+	}
+	// Child processes must not pick the sockets up again.
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDS")
+	os.Unsetenv("LISTEN_FDNAMES")
+
+	f := os.NewFile(systemdFirstFD, "systemd-socket")
+	defer f.Close()
+	ln, err := net.FileListener(f)
+	if err != nil {
+		return nil, fmt.Errorf("httpserver: use systemd socket: %w", err)
+	}
+	return ln, nil
+}
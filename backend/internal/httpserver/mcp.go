@@ -0,0 +1,179 @@
+package httpserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/handlers"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/jira"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/mcp"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/realtime"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/store"
+)
+
+// mcpServerVersion is reported to MCP clients in serverInfo.
+const mcpServerVersion = "backend"
+
+type mcpSecretKey struct{}
+
+// mcpTransport serves the Jira MCP tools over the streamable HTTP transport
+// to tenants authenticated by mcp_secret, and forwards their account events
+// to open sessions as log notifications. Event streams count against the
+// tenant's streaming connection limit.
+type mcpTransport struct {
+	handler *mcp.HTTPHandler
+	streams *handlers.StreamLimits
+}
+
+// mcpSettings resolves the tenant's default Jira site and all of its sites,
+// and the select field options synced for its projects.
+type mcpSettings interface {
+	jira.SettingsResolver
+	jira.SitesResolver
+	ListJiraFieldOptions(ctx context.Context, userID int64, siteURL, projectKey string) ([]models.JiraFieldOption, error)
+}
+
+func newMCPTransport(settings mcpSettings, refresh jira.TokenRefresher, sessions mcp.SessionStore, streams *handlers.StreamLimits, guard mcp.CallGuard) *mcpTransport {
+	// Credentials are resolved on every tool call, so rotated API tokens
+	// are picked up right away.
+	clientFor := func(ctx context.Context) (*jira.Client, error) {
+		secret, _ := ctx.Value(mcpSecretKey{}).(string)
+		c, err := jira.ForMCPSecret(ctx, settings, secret, refresh)
+		if err != nil {
+			return nil, err
+		}
+		if userID := mcpCallerID(ctx); userID > 0 {
+			c.WithFieldOptions(func(ctx context.Context, siteURL, projectKey string) ([]models.JiraFieldOption, error) {
+				return settings.ListJiraFieldOptions(ctx, userID, siteURL, projectKey)
+			})
+		}
+		return c, nil
+	}
+	clientsFor := func(ctx context.Context) ([]*jira.Client, error) {
+		secret, _ := ctx.Value(mcpSecretKey{}).(string)
+		return jira.ClientsForMCPSecret(ctx, settings, secret, refresh)
+	}
+	tools := append(jira.MCPTools(clientFor), jira.SiteTools(clientsFor)...)
+	server := mcp.NewServer("mcp-jira-thing", mcpServerVersion, tools...)
+	if guard != nil {
+		server.SetCallGuard(guard)
+	}
+	handler := mcp.NewHTTPHandler(server, func(r *http.Request) string {
+		userID, ok := r.Context().Value("user_id").(int64)
+		if !ok || userID <= 0 {
+			return ""
+		}
+		return strconv.FormatInt(userID, 10)
+	})
+	if sessions != nil {
+		handler.SetSessionStore(sessions)
+	}
+	return &mcpTransport{handler: handler, streams: streams}
+}
+
+// mcpCallerID returns the tenant making a tool call over /mcp, 0 when
+// unknown.
+func mcpCallerID(ctx context.Context) int64 {
+	userID, _ := ctx.Value("user_id").(int64)
+	return userID
+}
+
+// ServeHTTP implements http.Handler. The MCP auth middleware has already
+// resolved mcp_secret to the tenant.
+func (t *mcpTransport) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := context.WithValue(r.Context(), mcpSecretKey{}, r.URL.Query().Get("mcp_secret"))
+	if userID, ok := ctx.Value("user_id").(int64); ok && userID > 0 && r.Method == http.MethodGet {
+		release, ok := t.streams.Acquire(w, r, userID, realtime.StreamMCP)
+		if !ok {
+			return
+		}
+		defer release()
+	}
+	t.handler.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// Publish implements events.Broadcaster.
+func (t *mcpTransport) Publish(userID int64, eventType string, data interface{}) {
+	t.handler.Notify(strconv.FormatInt(userID, 10), "notifications/message", map[string]any{
+		"level":  "info",
+		"logger": eventType,
+		"data":   data,
+	})
+}
+
+// mcpSessionStore keeps MCP sessions in Postgres, where every instance can
+// resume them. Session owners are user IDs.
+type mcpSessionStore struct {
+	store *store.Store
+}
+
+func (m mcpSessionStore) SaveSession(ctx context.Context, state mcp.SessionState) error {
+	userID, err := strconv.ParseInt(state.Owner, 10, 64)
+	if err != nil {
+		return fmt.Errorf("session owner %q is not a user ID", state.Owner)
+	}
+	return m.store.SaveMCPSession(ctx, &models.MCPSession{
+		ID:                 state.ID,
+		UserID:             userID,
+		ProtocolVersion:    state.ProtocolVersion,
+		ClientInfo:         state.ClientInfo,
+		ClientCapabilities: state.ClientCapabilities,
+		LastSeenAt:         state.LastSeen,
+		ExpiresAt:          state.LastSeen.Add(mcp.SessionIdleTimeout),
+	})
+}
+
+func (m mcpSessionStore) LoadSession(ctx context.Context, id string) (*mcp.SessionState, error) {
+	sess, err := m.store.GetMCPSession(ctx, id)
+	if errors.Is(err, store.ErrMCPSessionNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &mcp.SessionState{
+		ID:                 sess.ID,
+		Owner:              strconv.FormatInt(sess.UserID, 10),
+		ProtocolVersion:    sess.ProtocolVersion,
+		ClientInfo:         sess.ClientInfo,
+		ClientCapabilities: sess.ClientCapabilities,
+		LastSeen:           sess.LastSeenAt,
+	}, nil
+}
+
+func (m mcpSessionStore) DeleteSession(ctx context.Context, id string) error {
+	return m.store.DeleteMCPSession(ctx, id)
+}
+
+func (m mcpSessionStore) StartCall(ctx context.Context, sessionID string, requestID json.RawMessage) error {
+	return m.store.StartMCPCall(ctx, sessionID, string(requestID))
+}
+
+func (m mcpSessionStore) FinishCall(ctx context.Context, sessionID string, requestID, response json.RawMessage) error {
+	return m.store.FinishMCPCall(ctx, sessionID, string(requestID), response)
+}
+
+func (m mcpSessionStore) ForgetCall(ctx context.Context, sessionID string, requestID json.RawMessage) error {
+	return m.store.ForgetMCPCall(ctx, sessionID, string(requestID))
+}
+
+func (m mcpSessionStore) PendingCalls(ctx context.Context, sessionID string) ([]mcp.PendingCall, error) {
+	calls, err := m.store.ListMCPCalls(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	pending := make([]mcp.PendingCall, 0, len(calls))
+	for _, call := range calls {
+		pending = append(pending, mcp.PendingCall{
+			RequestID: json.RawMessage(call.RequestID),
+			Response:  call.Response,
+			StartedAt: call.StartedAt,
+		})
+	}
+	return pending, nil
+}
@@ -0,0 +1,17 @@
+package httpserver
+
+import "syscall"
+
+// soReusePort is SO_REUSEPORT, which the syscall package does not define
+// for Linux.
+const soReusePort = 0x0f
+
+func setReusePort(network, address string, c syscall.RawConn) error {
+	var sockErr error
+	if err := c.Control(func(fd uintptr) {
+		sockErr = syscall.SetsockoptInt(int(fd), syscall.SOL_SOCKET, soReusePort, 1)
+	}); err != nil {
+		return err
+	}
+	return sockErr
+}
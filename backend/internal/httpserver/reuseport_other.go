@@ -0,0 +1,12 @@
+//go:build !linux
+
+package httpserver
+
+import (
+	"errors"
+	"syscall"
+)
+
+func setReusePort(network, address string, c syscall.RawConn) error {
+	return errors.New("httpserver: SO_REUSEPORT is only supported on Linux")
+}
@@ -0,0 +1,84 @@
+package httpserver
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/events"
+)
+
+// mcpSecretCacheTTL bounds how long a resolved mcp_secret is trusted without
+// hitting the database. Rotation and deletion evict entries immediately via
+// the event bus; the TTL only covers changes made outside this process.
+const mcpSecretCacheTTL = time.Minute
+
+type cachedSecret struct {
+	userID    int64
+	expiresAt time.Time
+}
+
+// secretCache memoizes mcp_secret → user ID lookups for the MCP auth
+// middleware, which runs on every request.
+type secretCache struct {
+	mu      sync.Mutex
+	entries map[string]cachedSecret
+}
+
+func newSecretCache() *secretCache {
+	return &secretCache{entries: make(map[string]cachedSecret)}
+}
+
+func (c *secretCache) get(secret string) (int64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[secret]
+	if !ok {
+		return 0, false
+	}
+	if time.Now().After(entry.expiresAt) {
+		delete(c.entries, secret)
+		return 0, false
+	}
+	return entry.userID, true
+}
+
+func (c *secretCache) put(secret string, userID int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[secret] = cachedSecret{userID: userID, expiresAt: time.Now().Add(mcpSecretCacheTTL)}
+}
+
+// invalidateUser drops every cached secret that resolved to userID.
+func (c *secretCache) invalidateUser(userID int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for secret, entry := range c.entries {
+		if entry.userID == userID {
+			delete(c.entries, secret)
+		}
+	}
+}
+
+// subscribe evicts cached secrets when a user's secret is rotated, the user
+// is deleted or merged into another account, or a dispute may have changed
+// whether they are suspended.
+func (c *secretCache) subscribe(bus *events.Bus) {
+	events.On(bus, func(ctx context.Context, ev events.MCPSecretRotated) error {
+		c.invalidateUser(ev.UserID)
+		return nil
+	})
+	events.On(bus, func(ctx context.Context, ev events.UserDeleted) error {
+		c.invalidateUser(ev.UserID)
+		return nil
+	})
+	events.On(bus, func(ctx context.Context, ev events.UsersMerged) error {
+		c.invalidateUser(ev.SourceUserID)
+		c.invalidateUser(ev.TargetUserID)
+		return nil
+	})
+	events.On(bus, func(ctx context.Context, ev events.DisputeChanged) error {
+		c.invalidateUser(ev.UserID)
+		return nil
+	})
+}
@@ -3,6 +3,8 @@ package httpserver
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
 	"net/http"
 	"time"
 
@@ -12,38 +14,132 @@ import (
 	"log"
 
 	"github.com/PortNumber53/mcp-jira-thing/backend/internal/config"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/events"
 	"github.com/PortNumber53/mcp-jira-thing/backend/internal/handlers"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/jira"
 	requesttracking "github.com/PortNumber53/mcp-jira-thing/backend/internal/middleware"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/realtime"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/secretbox"
 	"github.com/PortNumber53/mcp-jira-thing/backend/internal/store"
 	"github.com/PortNumber53/mcp-jira-thing/backend/internal/worker"
 )
 
+// Handler deadlines per route class. Jira and other upstream calls answer
+// 504 when they run out of time; everything else answers 503.
+var (
+	fastTimeout     = requesttracking.TimeoutClass{Name: "fast", Timeout: 10 * time.Second, Status: http.StatusServiceUnavailable}
+	upstreamTimeout = requesttracking.TimeoutClass{Name: "upstream", Timeout: 30 * time.Second, Status: http.StatusGatewayTimeout}
+	exportTimeout   = requesttracking.TimeoutClass{Name: "export", Timeout: 2 * time.Minute, Status: http.StatusServiceUnavailable}
+
+	routeTimeouts = []requesttracking.TimeoutRule{
+		// The realtime socket is long-lived and hijacks the connection.
+		{Prefix: "/ws", Class: requesttracking.TimeoutClass{Name: "stream"}},
+		// MCP event streams are long-lived; tool calls wait on Jira.
+		{Prefix: "/mcp", Method: http.MethodGet, Class: requesttracking.TimeoutClass{Name: "stream"}},
+		{Prefix: "/mcp", Class: upstreamTimeout},
+		{Prefix: "/api/settings/jira/test", Class: upstreamTimeout},
+		{Prefix: "/api/settings/jira/validate", Class: upstreamTimeout},
+		{Prefix: "/api/settings/jira/export", Class: exportTimeout},
+		{Prefix: "/api/settings/jira/import", Class: exportTimeout},
+		{Prefix: "/callback/", Class: upstreamTimeout},
+		{Prefix: "/api/auth/sso/", Class: upstreamTimeout},
+		{Prefix: "/api/checkout", Class: upstreamTimeout},
+		{Prefix: "/api/billing/pause", Class: upstreamTimeout},
+		{Prefix: "/api/billing/resume", Class: upstreamTimeout},
+		{Prefix: "/api/billing/preview-change", Class: upstreamTimeout},
+		{Prefix: "/api/billing/payment-methods", Class: upstreamTimeout},
+		{Prefix: "/api/billing/test-clock", Class: upstreamTimeout},
+		// Deleting an account cancels and refunds its Stripe subscription.
+		{Prefix: "/api/account/delete", Class: upstreamTimeout},
+	}
+)
+
 // Server wraps an http.Server with convenience helpers for startup/shutdown.
 type Server struct {
-	httpServer *http.Server
-	worker     *worker.Worker
+	httpServer   *http.Server
+	worker       *worker.Worker
+	jiraWebhooks *handlers.JiraWebhookSequencer
+	conns        *connectionTracker
+	reusePort    bool
+	drainTimeout time.Duration
 }
 
 // New constructs an HTTP server using the provided configuration and storage clients.
-func New(cfg config.Config, db *sql.DB, userClient handlers.UserLister, authStore handlers.OAuthStore, settingsStore handlers.UserSettingsStore, billingStore handlers.BillingStore, userStore handlers.UserStore, jobWorker *worker.Worker, jobStore *store.JobStore, stripeHandler *handlers.StripeHandler) *Server {
+func New(cfg config.Config, db *sql.DB, userClient handlers.UserLister, authStore handlers.OAuthStore, settingsStore handlers.UserSettingsStore, billingStore handlers.BillingStore, userStore handlers.UserStore, jobWorker *worker.Worker, jobStore *store.JobStore, stripeHandler *handlers.StripeHandler, hub *realtime.Hub, bus *events.Bus, queryMetrics *store.QueryMetrics) *Server {
 	router := chi.NewRouter()
 	router.Use(middleware.RequestID)
 	router.Use(middleware.RealIP)
 	router.Use(middleware.Logger)
 	router.Use(middleware.Recoverer)
 
+	// Stores created here serve resident organizations from their region
+	// and sharded tenants from their shard, and encrypt credentials, like
+	// the application store passed in.
+	var regional, shards map[string]*sql.DB
+	var box *secretbox.Box
+	if r, ok := userStore.(interface {
+		RegionalDatabases() map[string]*sql.DB
+	}); ok {
+		regional = r.RegionalDatabases()
+	}
+	if r, ok := userStore.(interface {
+		ShardDatabases() map[string]*sql.DB
+	}); ok {
+		shards = r.ShardDatabases()
+	}
+	if r, ok := userStore.(interface{ SecretBox() *secretbox.Box }); ok {
+		box = r.SecretBox()
+	}
+	newStore := func() (*store.Store, error) {
+		st, err := store.New(db)
+		if err != nil {
+			return nil, err
+		}
+		st.SetRegionalDatabases(regional)
+		st.SetShardDatabases(shards)
+		st.SetSecretBox(box)
+		return st, nil
+	}
+
+	// Cache mcp_secret lookups; the bus evicts entries on rotation/deletion.
+	secrets := newSecretCache()
+	if bus != nil {
+		secrets.subscribe(bus)
+	}
+
 	// Add custom MCP auth middleware function
-	mcpAuthMiddleware := func(db *sql.DB, store *store.Store) func(next http.Handler) http.Handler {
+	mcpAuthMiddleware := func(db *sql.DB, st *store.Store) func(next http.Handler) http.Handler {
 		return func(next http.Handler) http.Handler {
 			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 				secret := r.URL.Query().Get("mcp_secret")
 				if secret != "" {
-					userID, err := store.GetUserIDByMCPSecret(r.Context(), secret) // Assume or add this method in store if not exist
+					userID, ok := secrets.get(secret)
+					var err error
+					if !ok {
+						userID, err = st.GetUserIDByMCPSecret(r.Context(), secret) // Assume or add this method in store if not exist
+						if err == nil && userID > 0 && bus != nil {
+							secrets.put(secret, userID)
+						}
+					}
+					if errors.Is(err, store.ErrUserSuspended) {
+						http.Error(w, "account suspended", http.StatusForbidden)
+						return
+					}
 					if err == nil && userID > 0 {
 						ctx := context.WithValue(r.Context(), "user_id", userID)
 						r = r.WithContext(ctx)
 					} else {
 						log.Printf("[mcpAuth] Invalid MCP secret: %v", err)
+						if errors.Is(err, store.ErrMCPSecretNotFound) {
+							// Feed the abuse detector's credential stuffing scan.
+							ip := requesttracking.ClientIP(r)
+							go func() {
+								if err := st.RecordAuthFailure(context.Background(), ip, models.AuthFailureReasonInvalidMCPSecret); err != nil {
+									log.Printf("[mcpAuth] Failed to record auth failure: %v", err)
+								}
+							}()
+						}
 					}
 				}
 				next.ServeHTTP(w, r)
@@ -52,15 +148,40 @@ func New(cfg config.Config, db *sql.DB, userClient handlers.UserLister, authStor
 	}
 
 	// Add custom MCP auth middleware using the store
-	s, err := store.New(db)
+	s, err := newStore()
 	if err != nil {
 		log.Printf("failed to create store for MCP auth: %v", err)
 	} else {
+		// Temporary per-IP limits applied by the abuse detector.
+		router.Use(requesttracking.NewIPRateLimiter(s, 30*time.Second).Middleware)
 		router.Use(mcpAuthMiddleware(db, s))
+		// Revoked sessions are signed out before any handler reads the cookie.
+		router.Use(handlers.ActiveSession(cfg, s))
+	}
+	// Bearer tokens from /api/auth/token identify the caller in place of an
+	// email in the request.
+	router.Use(handlers.AuthToken(cfg.AuthTokenKeys))
+	if cfg.AuthIdentityRequired {
+		router.Use(handlers.CredentialIdentity(cfg.CookieSecret))
+	}
+	// Trusted callers such as the MCP and SPA Workers identify themselves
+	// with an API key.
+	if s != nil {
+		router.Use(handlers.APIKeyAuth(s))
+		// Each tenant's requests are limited by their plan's
+		// requests_per_minute.
+		router.Use(requesttracking.NewUserRateLimiter(s, handlers.RequestUserID).Middleware)
+		// Logged requests count toward the plan's monthly_request_quota;
+		// the endpoints reporting it stay reachable once it runs out.
+		router.Use(requesttracking.NewQuotaEnforcer(s, handlers.MCPUserID, "/api/metrics/quota", "/api/dashboard").Middleware)
 	}
 
 	// Add request tracking middleware
-	requestTracker, err := requesttracking.NewRequestTracker(db)
+	requestTracker, err := requesttracking.NewRequestTracker(db, requesttracking.RequestSamplingConfig{
+		MinRate:       cfg.RequestSampleRate,
+		LatencyTarget: cfg.RequestTrackingLatency,
+		MaxInFlight:   requesttracking.DefaultRequestSamplingConfig().MaxInFlight,
+	})
 	if err != nil {
 		// Log and continue without tracking
 		router.Use(func(next http.Handler) http.Handler {
@@ -69,76 +190,490 @@ func New(cfg config.Config, db *sql.DB, userClient handlers.UserLister, authStor
 			})
 		})
 	} else {
+		requestTracker.SetRegionalDatabases(regional)
+		requestTracker.SetShardDatabases(shards)
 		router.Use(requestTracker.Middleware())
 	}
+	router.Use(requesttracking.RouteTimeouts(routeTimeouts, fastTimeout))
 
 	// Create a store that implements MetricsStore for the metrics endpoints
-	metricsStore, err := store.New(db)
+	metricsStore, err := newStore()
 	if err != nil {
 		// Handle error appropriately - for now, don't register metrics endpoints
 		metricsStore = nil
 	}
 
+	// Accounts whose email has not been confirmed yet may sign in and read,
+	// but not change settings or billing.
+	var verificationJobs handlers.JobEnqueuer
+	if jobWorker != nil {
+		verificationJobs = jobWorker
+	}
+	requireVerifiedEmail := func(next http.Handler) http.Handler { return next }
+	if s != nil {
+		requireVerifiedEmail = handlers.RequireVerifiedEmail(s, cfg.CookieSecret)
+	}
+	verified := router.With(requireVerifiedEmail)
+
+	// Operator endpoints check the caller's role; without a database no one
+	// can be identified, so they are unavailable.
+	requirePermission := func(models.Permission) func(http.Handler) http.Handler {
+		return func(http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, "permission checks are unavailable", http.StatusServiceUnavailable)
+			})
+		}
+	}
+	if s != nil {
+		requirePermission = func(perm models.Permission) func(http.Handler) http.Handler {
+			return handlers.RequirePermission(s, cfg.CookieSecret, cfg.AdminEmails, perm)
+		}
+	}
+
 	router.Get("/healthz", handlers.Health)
 	router.Get("/api/users", handlers.Users(userClient))
-	router.Post("/api/auth/github", handlers.GitHubAuth(authStore))
-	router.Post("/api/auth/google", handlers.GoogleAuth(authStore))
+	// The SPA Worker forwards OAuth sign-ins with an auth-scoped API key;
+	// once API_KEYS_REQUIRED is set, no one else may.
+	requireAuthKey := handlers.RequireAPIKeyScope(models.APIKeyScopeAuth, func(next http.Handler) http.Handler { return next })
+	if cfg.APIKeysRequired {
+		requireAuthKey = handlers.RequireAPIKeyScope(models.APIKeyScopeAuth, nil)
+	}
+	router.With(requireAuthKey).Post("/api/auth/github", handlers.GitHubAuth(authStore, verificationJobs, cfg.AuthTokenKeys))
+	router.With(requireAuthKey).Post("/api/auth/google", handlers.GoogleAuth(authStore, cfg.AuthTokenKeys))
 	router.Get("/api/auth/connected-accounts", handlers.ConnectedAccounts(authStore))
 
 	// Google OAuth flow (browser-based login + callback)
 	router.Get("/api/auth/google/login", handlers.GoogleOAuthLogin(cfg))
 	router.Get("/callback/google", handlers.GoogleOAuthCallback(cfg, authStore))
 	router.Get("/api/auth/session", handlers.SessionCheck(cfg))
-	router.Post("/api/auth/logout", handlers.SessionLogout(cfg))
+	var sessionStore handlers.UserSessionStore
+	if s != nil {
+		sessionStore = s
+	}
+	router.Post("/api/auth/logout", handlers.SessionLogout(cfg, sessionStore))
+	if s != nil {
+		router.Post("/api/auth/token", handlers.IssueAuthToken(cfg, s))
+		router.Get("/api/auth/verify-email", handlers.VerifyEmail(cfg, s))
+		router.Post("/api/auth/verify-email/resend", handlers.ResendEmailVerification(s, verificationJobs, cfg.CookieSecret))
+		router.Get("/api/auth/logins", handlers.LoginHistory(s, cfg.CookieSecret))
+		router.Get("/api/auth/sessions", handlers.UserSessions(s, cfg.CookieSecret))
+		router.Delete("/api/auth/sessions", handlers.UserSessions(s, cfg.CookieSecret))
+		router.Delete("/api/auth/sessions/{id}", handlers.RevokeUserSession(s, cfg.CookieSecret))
+	}
+
+	// Organizations and enterprise SSO (OIDC or SAML per organization)
+	if s != nil {
+		router.Get("/api/orgs", handlers.Organizations(s, cfg.CookieSecret))
+		router.Post("/api/orgs", handlers.Organizations(s, cfg.CookieSecret))
+		orgDomainsHandler := handlers.OrganizationDomains(s, cfg.CookieSecret)
+		router.Get("/api/orgs/{slug}/domains", orgDomainsHandler)
+		router.Post("/api/orgs/{slug}/domains", orgDomainsHandler)
+		router.Delete("/api/orgs/{slug}/domains", orgDomainsHandler)
+		if jobWorker != nil {
+			router.Post("/api/orgs/{slug}/domains/verify", handlers.OrganizationDomainVerification(s, jobWorker, cfg.CookieSecret))
+		}
+		orgJoinRequestsHandler := handlers.OrganizationJoinRequests(s, cfg.CookieSecret)
+		router.Get("/api/orgs/{slug}/join-requests", orgJoinRequestsHandler)
+		router.Post("/api/orgs/{slug}/join-requests", orgJoinRequestsHandler)
+		orgJiraAccountHandler := handlers.OrganizationJiraAccount(s, cfg.CookieSecret)
+		router.Get("/api/orgs/{slug}/jira-account", orgJiraAccountHandler)
+		router.Put("/api/orgs/{slug}/jira-account", orgJiraAccountHandler)
+		router.Delete("/api/orgs/{slug}/jira-account", orgJiraAccountHandler)
+		router.Get("/api/orgs/{slug}/jira-account/usage", handlers.OrganizationJiraUsage(s, cfg.CookieSecret))
+		orgSSOHandler := handlers.OrganizationSSO(s, cfg.CookieSecret, cfg.BackendURL)
+		router.Get("/api/orgs/{slug}/sso", orgSSOHandler)
+		router.Put("/api/orgs/{slug}/sso", orgSSOHandler)
+		orgSCIMTokensHandler := handlers.OrganizationSCIMTokens(s, cfg.CookieSecret, cfg.BackendURL)
+		router.Get("/api/orgs/{slug}/scim-tokens", orgSCIMTokensHandler)
+		router.Post("/api/orgs/{slug}/scim-tokens", orgSCIMTokensHandler)
+		router.Delete("/api/orgs/{slug}/scim-tokens", orgSCIMTokensHandler)
+
+		router.Get("/api/auth/sso/login", handlers.SSOLogin(cfg, s))
+		router.Get("/callback/sso", handlers.SSOCallback(cfg, s))
+		router.Post("/api/auth/sso/saml/acs", handlers.SSOSAMLACS(cfg, s))
+		router.Get("/api/auth/sso/saml/metadata", handlers.SSOSAMLMetadata(cfg, s))
+
+		// SCIM 2.0 provisioning, authenticated by an organization SCIM token
+		router.Get("/scim/v2/ServiceProviderConfig", handlers.SCIMServiceProviderConfig())
+		scimUsersHandler := handlers.SCIMUsers(s, cfg.BackendURL)
+		router.Get("/scim/v2/Users", scimUsersHandler)
+		router.Post("/scim/v2/Users", scimUsersHandler)
+		scimUserHandler := handlers.SCIMUser(s, cfg.BackendURL)
+		router.Get("/scim/v2/Users/{id}", scimUserHandler)
+		router.Put("/scim/v2/Users/{id}", scimUserHandler)
+		router.Patch("/scim/v2/Users/{id}", scimUserHandler)
+		router.Delete("/scim/v2/Users/{id}", scimUserHandler)
+	}
 	jiraSettingsHandler := handlers.UserSettings(settingsStore, cfg.CookieSecret)
-	router.Post("/api/settings/jira", jiraSettingsHandler)
-	router.Get("/api/settings/jira", jiraSettingsHandler)
+	verified.Post("/api/settings/jira", jiraSettingsHandler)
+	router.With(requesttracking.ETag).Get("/api/settings/jira", jiraSettingsHandler)
 	router.Post("/api/settings/jira/test", handlers.TestJiraSettings(cfg.CookieSecret))
+	var jiraSites handlers.JiraSiteRecorder
+	if s != nil {
+		jiraSites = s
+	}
+	router.Post("/api/settings/jira/validate", handlers.ValidateJiraSettings(jiraSites, cfg.CookieSecret, nil))
+	if s != nil {
+		router.Get("/api/settings/jira/export", handlers.ExportJiraSettings(s, cfg.CookieSecret))
+		verified.Post("/api/settings/jira/import", handlers.ImportJiraSettings(s, cfg.CookieSecret))
+		verified.Delete("/api/settings/jira", handlers.DeleteJiraSettings(s, cfg.CookieSecret))
+		router.Get("/api/settings/jira/deleted", handlers.DeletedJiraSettings(s, cfg.CookieSecret))
+		verified.Post("/api/settings/jira/restore", handlers.RestoreJiraSettings(s, cfg.CookieSecret))
+		router.With(requesttracking.ETag).Get("/api/settings/jira/defaults", handlers.JiraIssueDefaults(s, cfg.CookieSecret))
+		verified.Post("/api/settings/jira/defaults", handlers.JiraIssueDefaults(s, cfg.CookieSecret))
+
+		// Connecting Jira through Atlassian OAuth instead of an API token
+		if cfg.AtlassianClientID != "" {
+			jiraOAuth := jira.NewOAuth(cfg.AtlassianClientID, cfg.AtlassianClientSecret, cfg.BackendURL+"/callback/atlassian")
+			verified.Get("/api/settings/jira/oauth/login", handlers.JiraOAuthLogin(cfg, jiraOAuth))
+			router.Get("/callback/atlassian", handlers.JiraOAuthCallback(cfg, s, jiraOAuth))
+		}
+	}
 
 	// Integration token endpoints
-	integrationStore, _ := store.New(db)
+	integrationStore, _ := newStore()
 	if integrationStore != nil {
 		router.Get("/api/integrations/tokens", handlers.IntegrationTokens(integrationStore))
-		router.Post("/api/integrations/tokens", handlers.IntegrationTokens(integrationStore))
-		router.Delete("/api/integrations/tokens", handlers.IntegrationTokens(integrationStore))
+		verified.Post("/api/integrations/tokens", handlers.IntegrationTokens(integrationStore))
+		verified.Delete("/api/integrations/tokens", handlers.IntegrationTokens(integrationStore))
 	}
 
 	// Billing endpoints
-	router.Post("/api/billing/save-subscription", handlers.SaveSubscription(billingStore, userStore))
-	router.Post("/api/billing/save-payment", handlers.SavePayment(billingStore, userStore))
+	verified.Post("/api/billing/save-subscription", handlers.SaveSubscription(billingStore, userStore))
+	verified.Post("/api/billing/save-payment", handlers.SavePayment(billingStore, userStore))
 	router.Get("/api/billing/payment-history", handlers.GetPaymentHistory(billingStore, userStore))
 	router.Get("/api/billing/subscription", handlers.GetSubscription(billingStore))
 
-	// Account management endpoints
-	router.Post("/api/account/delete", handlers.DeleteAccount(billingStore, userStore, ""))
+	// Everything the web app shows on load, in one call
+	if s != nil {
+		var dashboardJobs handlers.UserJobLister
+		if jobStore != nil {
+			dashboardJobs = jobStore
+		}
+		router.Get("/api/dashboard", handlers.Dashboard(s, dashboardJobs, cfg.CookieSecret))
+	}
+
+	// Notification feed endpoints
+	if s != nil {
+		router.Get("/api/notifications", handlers.Notifications(s, cfg.CookieSecret))
+		router.Post("/api/notifications/read", handlers.MarkNotificationsRead(s, cfg.CookieSecret))
+	}
+
+	// Tenant debug mode and captured tool call traces
+	if s != nil {
+		router.Get("/api/debug/mode", handlers.DebugMode(s, cfg.CookieSecret))
+		router.Post("/api/debug/mode", handlers.DebugMode(s, cfg.CookieSecret))
+		router.Get("/api/debug/traces", handlers.DebugTraces(s, cfg.CookieSecret))
+	}
+
+	// Timezone, locale and date format preferences
+	if s != nil {
+		router.With(requesttracking.ETag).Get("/api/preferences", handlers.Preferences(s, cfg.CookieSecret))
+		verified.Post("/api/preferences", handlers.Preferences(s, cfg.CookieSecret))
+	}
+
+	// Per-tool MCP response size limits
+	if s != nil {
+		toolLimitsHandler := handlers.ToolResponseLimits(s, cfg.CookieSecret)
+		router.Get("/api/settings/tool-limits", toolLimitsHandler)
+		verified.Post("/api/settings/tool-limits", toolLimitsHandler)
+		verified.Delete("/api/settings/tool-limits", toolLimitsHandler)
+	}
+
+	// Human approval gate for destructive MCP tools
+	if s != nil {
+		toolApprovalPoliciesHandler := handlers.ToolApprovalPolicies(s, cfg.CookieSecret)
+		router.Get("/api/settings/tool-approvals", toolApprovalPoliciesHandler)
+		verified.Post("/api/settings/tool-approvals", toolApprovalPoliciesHandler)
+		verified.Delete("/api/settings/tool-approvals", toolApprovalPoliciesHandler)
+		router.Get("/api/tool-approvals", handlers.ToolApprovals(s, cfg.CookieSecret))
+		verified.Post("/api/tool-approvals/{id}/{decision}", handlers.DecideToolApproval(s, cfg.CookieSecret))
+	}
+
+	// Scheduled JQL searches posted to Slack or a webhook
+	if s != nil {
+		automationsHandler := handlers.Automations(s, cfg.CookieSecret)
+		automationHandler := handlers.Automation(s, cfg.CookieSecret)
+		router.Get("/api/automations", automationsHandler)
+		verified.Post("/api/automations", automationsHandler)
+		verified.Put("/api/automations/{id}", automationHandler)
+		verified.Delete("/api/automations/{id}", automationHandler)
+		router.Get("/api/automations/{id}/runs", handlers.AutomationRuns(s, cfg.CookieSecret))
+	}
+
+	// Recurring reports rendered from built-in templates
+	router.Get("/api/reports/templates", handlers.ReportTemplates())
+	if s != nil {
+		reportsHandler := handlers.ReportSchedules(s, cfg.CookieSecret)
+		reportHandler := handlers.ReportSchedule(s, cfg.CookieSecret)
+		router.Get("/api/reports", reportsHandler)
+		verified.Post("/api/reports", reportsHandler)
+		verified.Put("/api/reports/{id}", reportHandler)
+		verified.Delete("/api/reports/{id}", reportHandler)
+		router.Get("/api/report-artifacts", handlers.ReportArtifacts(s, cfg.CookieSecret))
+		router.Get("/api/report-artifacts/{id}", handlers.DownloadReportArtifact(s, cfg.CookieSecret))
+	}
 
+	// Backfills of the issue mirror from a JQL search
+	if s != nil && jobWorker != nil {
+		backfillsHandler := handlers.IssueBackfills(s, jobWorker, cfg.CookieSecret)
+		router.Get("/api/issue-backfills", backfillsHandler)
+		verified.Post("/api/issue-backfills", backfillsHandler)
+		router.Get("/api/issue-backfills/{id}", handlers.IssueBackfill(s, cfg.CookieSecret))
+		verified.Post("/api/issue-backfills/{id}/resume", handlers.ResumeIssueBackfill(s, jobWorker, cfg.CookieSecret))
+	}
+
+	// Issue comments synced with Slack threads and email. Replies come back
+	// through the Slack Events API and the inbound email provider.
+	if s != nil {
+		commentChannelsHandler := handlers.CommentChannels(s, cfg.CookieSecret, cfg.CommentReplyAddress)
+		router.Get("/api/comment-channels", commentChannelsHandler)
+		verified.Post("/api/comment-channels", commentChannelsHandler)
+		verified.Delete("/api/comment-channels/{id}", handlers.CommentChannel(s, cfg.CookieSecret))
+		if jobWorker != nil && cfg.SlackSigningSecret != "" {
+			router.Post("/api/webhooks/slack", handlers.SlackEvents(s, jobWorker, cfg.SlackSigningSecret))
+		}
+		if jobWorker != nil && cfg.InboundEmailSecret != "" && cfg.CommentReplyAddress != "" {
+			router.Post("/api/webhooks/inbound-email", handlers.InboundEmail(s, jobWorker, cfg.InboundEmailSecret, cfg.CommentReplyAddress))
+		}
+	}
+
+	// Streaming connections count against the tenant's plan limit.
+	streams := &handlers.StreamLimits{Limiter: realtime.NewStreamLimiter()}
+	if s != nil {
+		streams.Plans = s
+	}
+
+	// Real-time tenant event stream
+	if hub != nil {
+		router.Get("/ws", handlers.RealtimeSocket(hub, userStore, streams, cfg.CookieSecret, cfg.FrontendURL))
+	}
+
+	// MCP clients can connect here directly instead of through the worker.
+	var mcpHTTP *mcpTransport
+	if s != nil {
+		// OAuth access tokens Jira rejects are refreshed and the call retried.
+		var jiraRefresh jira.TokenRefresher
+		if cfg.AtlassianClientID != "" {
+			jiraRefresh = jira.NewOAuth(cfg.AtlassianClientID, cfg.AtlassianClientSecret, cfg.BackendURL+"/callback/atlassian").Refresher(s)
+		}
+		mcpHTTP = newMCPTransport(s, jiraRefresh, mcpSessionStore{store: s}, streams, handlers.NewToolQuotas(s, mcpCallerID))
+		if bus != nil {
+			events.RegisterBroadcast(bus, mcpHTTP)
+		}
+		router.Handle("/mcp", mcpHTTP)
+	}
+
+	// Account management endpoints. Without Stripe, accounts with a
+	// subscription cannot be deleted.
+	var canceller handlers.SubscriptionCanceller
+	if stripeHandler != nil && stripeHandler.Stripe != nil {
+		canceller = stripeHandler.Stripe
+	}
+	router.Post("/api/account/delete", handlers.DeleteAccount(billingStore, userStore, canceller))
+
+	if cfg.WorkerSharedKeys.Len() == 0 && !cfg.APIKeysRequired {
+		log.Printf("[server] WORKER_SHARED_KEY not set, backend-only routes accept unsigned requests")
+	}
+
+	var jiraWebhooks *handlers.JiraWebhookSequencer
 	router.Group(func(r chi.Router) {
 		r.Use(mcpAuthMiddleware(db, s)) // Apply MCP auth middleware to this group
-		r.Get("/api/settings/jira/tenant", handlers.TenantJiraSettings(settingsStore))
 		mcpSecretHandler := handlers.MCPSecret(settingsStore, cfg.CookieSecret)
 		r.Get("/api/mcp/secret", mcpSecretHandler)
-		r.Post("/api/mcp/secret", mcpSecretHandler)
-		if integrationStore != nil {
-			r.Get("/api/integrations/tokens/tenant", handlers.TenantIntegrationToken(integrationStore))
+		r.With(requireVerifiedEmail).Post("/api/mcp/secret", mcpSecretHandler)
+		if bus != nil {
+			// Deliveries are de-duplicated and applied in timestamp order
+			// per issue so the issue mirror never goes back in time.
+			var webhookStore handlers.JiraWebhookStore
+			if s != nil {
+				webhookStore = s
+			}
+			jiraWebhooks = handlers.NewJiraWebhookSequencer(webhookStore, bus, handlers.DefaultJiraWebhookDelay)
+			r.Post("/api/webhooks/jira", handlers.JiraWebhook(jiraWebhooks))
 		}
+
+		// Backend-only routes called by the MCP worker must carry a
+		// tenant-scoped API key or be signed with WORKER_SHARED_KEY, in
+		// addition to the tenant's mcp_secret. With API_KEYS_REQUIRED the
+		// signature alone is no longer enough.
+		signedOrKey := requesttracking.RequireSignedRequestKeys(cfg.WorkerSharedKeys, 5*time.Minute)
+		if cfg.APIKeysRequired {
+			signedOrKey = nil
+		}
+		r.Group(func(r chi.Router) {
+			r.Use(handlers.RequireAPIKeyScope(models.APIKeyScopeTenant, signedOrKey))
+			r.With(requesttracking.ETag).Get("/api/settings/jira/tenant", handlers.TenantJiraSettings(settingsStore))
+			if integrationStore != nil {
+				r.Get("/api/integrations/tokens/tenant", handlers.TenantIntegrationToken(integrationStore))
+			}
+			if s != nil {
+				r.Post("/api/notifications/tenant/credential-failure", handlers.TenantCredentialFailure(s))
+				r.Post("/api/debug/traces/tenant", handlers.TenantDebugTrace(s))
+				r.With(requesttracking.ETag).Get("/api/preferences/tenant", handlers.TenantPreferences(s))
+				r.With(requesttracking.ETag).Get("/api/settings/tool-limits/tenant", handlers.TenantToolResponseLimits(s))
+				r.Post("/api/metrics/tool-invocations/tenant", handlers.TenantToolInvocation(s))
+				r.Get("/api/metrics/tool-quotas/tenant", handlers.TenantToolQuotas(s))
+				r.Get("/api/settings/tool-approvals/tenant", handlers.TenantToolApprovalPolicies(s))
+				r.Post("/api/tool-approvals/tenant", handlers.TenantCreateToolApproval(s))
+				r.Get("/api/tool-approvals/tenant/{token}", handlers.TenantToolApproval(s))
+				r.Post("/api/tool-approvals/tenant/{token}/consume", handlers.TenantConsumeToolApproval(s))
+
+				// Operator routes also check the caller's role, so they stay
+				// closed when no worker key is configured.
+				operator := r.With(requirePermission(models.PermissionOperateBackend))
+
+				// Cost weights change what every tenant is charged, so only
+				// signed (operator) requests may modify them.
+				toolCostWeightsHandler := handlers.ToolCostWeights(s)
+				operator.Put("/api/metrics/cost-weights", toolCostWeightsHandler)
+				operator.Delete("/api/metrics/cost-weights", toolCostWeightsHandler)
+
+				// Revenue figures are for operators only.
+				operator.Get("/api/admin/revenue", handlers.AdminRevenue(s))
+
+				// Support reads a tenant's whole account without database access.
+				var tenantJobs handlers.UserJobLister
+				if jobStore != nil {
+					tenantJobs = jobStore
+				}
+				operator.Get("/api/admin/users/state", handlers.AdminTenantState(s, tenantJobs))
+
+				// Deleted accounts stay restorable until the purger removes
+				// them; erasure requests can purge one right away.
+				operator.Get("/api/admin/users/deleted", handlers.AdminDeletedUsers(s))
+				operator.Post("/api/admin/users/deleted/restore", handlers.AdminRestoreUser(s))
+				operator.Post("/api/admin/users/deleted/purge", handlers.AdminPurgeUser(s))
+
+				// Moving an organization between data regions relocates its
+				// data, so only operators may do it.
+				dataRegion := handlers.AdminOrganizationDataRegion(s)
+				operator.Get("/api/admin/organizations/{slug}/data-region", dataRegion)
+				operator.Put("/api/admin/organizations/{slug}/data-region", dataRegion)
+
+				// Rebalancing tenants across shard databases; the move itself
+				// runs as a background job.
+				var shardJobs handlers.JobEnqueuer
+				if jobWorker != nil {
+					shardJobs = jobWorker
+				}
+				operator.Get("/api/admin/shards", handlers.AdminShards(s))
+				tenantShard := handlers.AdminTenantShard(s, shardJobs)
+				operator.Get("/api/admin/users/shard", tenantShard)
+				operator.Put("/api/admin/users/shard", tenantShard)
+
+				// Declarative provisioning for infrastructure-as-code tooling;
+				// PUT is idempotent and reports whether anything changed.
+				r.Get("/api/feature-flags/tenant", handlers.TenantFeatureFlags(s))
+				operator.Get("/api/admin/feature-flags", handlers.AdminFeatureFlags(s))
+				featureFlag := handlers.AdminFeatureFlag(s)
+				operator.Get("/api/admin/feature-flags/{slug}", featureFlag)
+				operator.Put("/api/admin/feature-flags/{slug}", featureFlag)
+				operator.Delete("/api/admin/feature-flags/{slug}", featureFlag)
+				var jobTypes handlers.JobTypeRegistry
+				if jobWorker != nil {
+					jobTypes = jobWorker
+				}
+				operator.Get("/api/admin/recurring-jobs", handlers.AdminRecurringJobs(s))
+				recurringJob := handlers.AdminRecurringJob(s, jobTypes)
+				operator.Get("/api/admin/recurring-jobs/{slug}", recurringJob)
+				operator.Put("/api/admin/recurring-jobs/{slug}", recurringJob)
+				operator.Delete("/api/admin/recurring-jobs/{slug}", recurringJob)
+			}
+			if stripeHandler != nil && stripeHandler.PlanStore != nil {
+				// Price rollouts decide what new subscribers pay.
+				plans := r.With(requirePermission(models.PermissionManagePlans))
+				planRollout := handlers.AdminPlanRollout(stripeHandler.PlanStore)
+				plans.Get("/api/admin/plans/{slug}/rollout", planRollout)
+				plans.Put("/api/admin/plans/{slug}/rollout", planRollout)
+				plans.Post("/api/admin/plans/{slug}/rollout/promote", handlers.AdminPromotePlanRollout(stripeHandler.PlanStore))
+				plans.Put("/api/admin/plan-versions/{id}/migration-policy", handlers.AdminPlanVersionMigrationPolicy(stripeHandler.PlanStore))
+				provisionPlan := handlers.AdminProvisionPlan(stripeHandler.PlanStore)
+				plans.Get("/api/admin/plans/{slug}", provisionPlan)
+				plans.Put("/api/admin/plans/{slug}", provisionPlan)
+			}
+			if stripeHandler != nil && stripeHandler.TestClocks != nil {
+				stripeHandler.RegisterTestClockRoutes(r)
+			}
+		})
 	})
 
 	// Metrics endpoints
 	if metricsStore != nil {
 		router.Get("/api/metrics/user", handlers.UserMetrics(metricsStore))
 		router.Get("/api/metrics/user/requests", handlers.UserRequests(metricsStore))
-		router.Get("/api/metrics/all", handlers.AllMetrics(metricsStore))
+		router.With(requirePermission(models.PermissionViewAllMetrics)).Get("/api/metrics/all", handlers.AllMetrics(metricsStore))
+		router.Get("/api/metrics/forecast", handlers.UsageForecast(metricsStore, cfg.CookieSecret))
+		router.Get("/api/metrics/quota", handlers.RequestQuota(metricsStore, cfg.CookieSecret))
+		router.Get("/api/metrics/costs", handlers.ToolCostBreakdown(metricsStore, cfg.CookieSecret))
+		router.Get("/api/metrics/tool-quotas", handlers.ToolQuotaUsage(metricsStore, cfg.CookieSecret))
+		router.Get("/api/metrics/cost-weights", handlers.ToolCostWeights(metricsStore))
 	}
 
 	// Job queue endpoints
+	conns := newConnectionTracker()
 	if jobStore != nil {
 		jobHandler := handlers.NewJobHandler(jobStore, jobWorker)
+		if queryMetrics != nil {
+			jobHandler.Metrics = append(jobHandler.Metrics, queryMetrics)
+		}
+		jobHandler.Metrics = append(jobHandler.Metrics, conns, streams.Limiter)
+		jobHandler.Authorize = requirePermission
 		jobHandler.RegisterRoutes(router)
 	}
 
+	// Embedded admin dashboard for deployments without a separate frontend;
+	// only signed-in admins and support staff may open it.
+	if s != nil {
+		var dashboardJobs handlers.JobStatsReader
+		if jobStore != nil {
+			dashboardJobs = jobStore
+		}
+		var dashboardPlans handlers.PlanVersionLister
+		if stripeHandler != nil && stripeHandler.PlanStore != nil {
+			dashboardPlans = stripeHandler.PlanStore
+		}
+		router.Group(func(r chi.Router) {
+			r.Use(requirePermission(models.PermissionAdminDashboard))
+			r.Get("/admin/api/overview", handlers.AdminDashboardOverview(dashboardJobs, s, dashboardPlans, s))
+			dashboard := handlers.AdminDashboard()
+			r.Get("/admin", dashboard.ServeHTTP)
+			r.Get("/admin/*", dashboard.ServeHTTP)
+		})
+
+		// Admins grant roles to signed-in users; ADMIN_EMAILS bootstraps
+		// the first ones.
+		userRole := handlers.AdminUserRole(s)
+		router.With(requirePermission(models.PermissionManageRoles)).Get("/api/admin/users/role", userRole)
+		router.With(requirePermission(models.PermissionManageRoles)).Put("/api/admin/users/role", userRole)
+
+		// Admins issue and revoke the API keys trusted callers present.
+		apiKeys := router.With(requirePermission(models.PermissionManageAPIKeys))
+		apiKeys.Get("/api/admin/api-keys", handlers.APIKeys(s))
+		apiKeys.Post("/api/admin/api-keys", handlers.APIKeys(s))
+		apiKeys.Delete("/api/admin/api-keys/{id}", handlers.RevokeAPIKey(s))
+	}
+
 	// Stripe / membership plan endpoints
+	// Checkout, pause and resume need a verified email; the Stripe webhook
+	// carries no user identity and passes through.
 	if stripeHandler != nil {
-		stripeHandler.RegisterRoutes(router)
+		stripeHandler.RegisterRoutes(verified)
+	}
+
+	// Development only: deliver simulated, correctly signed Stripe and Jira
+	// webhooks to the handlers above without a public callback URL.
+	if cfg.IsDevelopment() {
+		var simulatorSecrets handlers.MCPSecretReader
+		if settingsStore != nil {
+			simulatorSecrets = settingsStore
+		}
+		stripeWebhookSecret := ""
+		if stripeHandler != nil {
+			stripeWebhookSecret = stripeHandler.WebhookSecret
+		}
+		router.Post("/api/dev/webhooks/{provider}", handlers.WebhookSimulator(router, simulatorSecrets, stripeWebhookSecret))
+		log.Printf("[server] Webhook simulator enabled at /api/dev/webhooks/{provider}")
 	}
 
 	srv := &http.Server{
@@ -147,9 +682,22 @@ func New(cfg config.Config, db *sql.DB, userClient handlers.UserLister, authStor
 		ReadTimeout:  15 * time.Second,
 		WriteTimeout: 15 * time.Second,
 		IdleTimeout:  60 * time.Second,
+		ConnState:    conns.track,
+	}
+	// MCP event streams never go idle; end them when the drain starts so
+	// clients reconnect to the next release.
+	if mcpHTTP != nil {
+		srv.RegisterOnShutdown(mcpHTTP.handler.CloseStreams)
 	}
 
-	return &Server{httpServer: srv, worker: jobWorker}
+	return &Server{
+		httpServer:   srv,
+		worker:       jobWorker,
+		jiraWebhooks: jiraWebhooks,
+		conns:        conns,
+		reusePort:    cfg.ListenReusePort,
+		drainTimeout: cfg.ShutdownDrainTimeout,
+	}
 }
 
 // Start begins serving HTTP traffic and starts the worker.
@@ -158,10 +706,16 @@ func (s *Server) Start() error {
 		log.Println("[server] Starting job worker...")
 		s.worker.Start(context.Background())
 	}
-	return s.httpServer.ListenAndServe()
+	ln, err := listen(s.httpServer.Addr, s.reusePort)
+	if err != nil {
+		return err
+	}
+	return s.httpServer.Serve(ln)
 }
 
-// Shutdown gracefully stops the HTTP server and worker.
+// Shutdown gracefully stops the HTTP server and worker. In-flight requests
+// get up to the drain timeout to finish; connections still open then are
+// closed.
 func (s *Server) Shutdown(ctx context.Context) error {
 	if s.worker != nil {
 		log.Println("[server] Shutting down job worker...")
@@ -169,7 +723,28 @@ func (s *Server) Shutdown(ctx context.Context) error {
 			log.Printf("[server] Worker shutdown error: %v", err)
 		}
 	}
-	return s.httpServer.Shutdown(ctx)
+
+	s.conns.startDrain()
+	drainCtx := ctx
+	if s.drainTimeout > 0 {
+		var cancel context.CancelFunc
+		drainCtx, cancel = context.WithTimeout(ctx, s.drainTimeout)
+		defer cancel()
+	}
+	err := s.httpServer.Shutdown(drainCtx)
+	forced := 0
+	if err != nil {
+		forced, _ = s.conns.counts()
+		s.httpServer.Close()
+		err = fmt.Errorf("drain incomplete, closed %d connections: %w", forced, err)
+	}
+	took := s.conns.finishDrain(forced)
+	log.Printf("[server] Drained connections in %v (%d closed at the deadline)", took.Round(time.Millisecond), forced)
+
+	if s.jiraWebhooks != nil {
+		s.jiraWebhooks.Flush()
+	}
+	return err
 }
 
 // Handler exposes the underlying http.Handler for testing.
@@ -5,17 +5,23 @@ import (
 	"database/sql"
 	"net/http"
 	"net/http/httptest"
+	"runtime"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/PortNumber53/mcp-jira-thing/backend/internal/config"
 	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
 	_ "github.com/mattn/go-sqlite3"
 )
 
-type stubUserClient struct{}
+type stubUserClient struct {
+	// lookups records the emails handlers acted on.
+	lookups []string
+}
 
-func (s *stubUserClient) ListUsers(ctx context.Context, limit int) ([]models.PublicUser, error) {
-	return []models.PublicUser{{ID: "rec1"}}, nil
+func (s *stubUserClient) ListUsers(ctx context.Context, opts models.UserListOptions) (*models.UserPage, error) {
+	return &models.UserPage{Users: []models.PublicUser{{ID: "rec1"}}, Total: 1}, nil
 }
 
 func (s *stubUserClient) UpsertGitHubUser(ctx context.Context, user models.GitHubAuthUser) error {
@@ -30,11 +36,17 @@ func (s *stubUserClient) UpsertGoogleUser(ctx context.Context, user models.Googl
 	return nil
 }
 
+func (s *stubUserClient) UpsertUserSettingsIfRevision(ctx context.Context, userEmail, baseURL, jiraEmail, apiKey string, ifRevision int64) (int64, error) {
+	return ifRevision + 1, nil
+}
+
 func (s *stubUserClient) ListUserSettings(ctx context.Context, email string) ([]models.JiraUserSettings, error) {
+	s.lookups = append(s.lookups, email)
 	return nil, nil
 }
 
 func (s *stubUserClient) GenerateMCPSecret(ctx context.Context, email string) (string, error) {
+	s.lookups = append(s.lookups, email)
 	return "dummy", nil
 }
 
@@ -63,6 +75,7 @@ func (s *stubUserClient) SavePayment(ctx context.Context, payment *models.Paymen
 }
 
 func (s *stubUserClient) GetPaymentHistory(ctx context.Context, userEmail string) ([]models.PaymentHistory, error) {
+	s.lookups = append(s.lookups, userEmail)
 	return nil, nil
 }
 
@@ -74,10 +87,74 @@ func (s *stubUserClient) GetConnectedAccounts(ctx context.Context, email string)
 	return nil, nil
 }
 
-func (s *stubUserClient) DeleteUser(ctx context.Context, email string) error {
+func (s *stubUserClient) GetEnforcedSSOOrganization(ctx context.Context, email string) (*models.Organization, error) {
+	return nil, nil
+}
+
+func (s *stubUserClient) EmailVerificationStatus(ctx context.Context, email string) (int64, bool, error) {
+	return 1, true, nil
+}
+
+func (s *stubUserClient) RecordOAuthLoginEvent(ctx context.Context, provider, providerAccountID, ipAddress, userAgent string) error {
+	return nil
+}
+
+func (s *stubUserClient) CreateOAuthUserSession(ctx context.Context, provider, providerAccountID, ipAddress, userAgent string, ttl time.Duration) (string, error) {
+	return "", nil
+}
+
+func (s *stubUserClient) GetEffectivePlan(ctx context.Context, userID int64) (*models.MembershipPlan, error) {
+	return &models.MembershipPlan{Slug: "free"}, nil
+}
+
+func (s *stubUserClient) DeleteUser(ctx context.Context, email string) (*models.AccountDeletion, error) {
+	s.lookups = append(s.lookups, email)
+	return &models.AccountDeletion{Email: email}, nil
+}
+
+func (s *stubUserClient) RestoreUser(ctx context.Context, email string) error {
 	return nil
 }
 
+func (s *stubUserClient) RevokeAllUserSessions(ctx context.Context, userID int64) (int64, error) {
+	return 0, nil
+}
+
+func TestAuthIdentityRequiredIgnoresRawEmails(t *testing.T) {
+	cfg := config.Config{ServerAddress: ":0", CookieSecret: "cookie-secret", AuthIdentityRequired: true}
+	stub := &stubUserClient{}
+
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open test database: %v", err)
+	}
+	defer db.Close()
+
+	server := New(cfg, db, stub, stub, stub, stub, stub, nil, nil, nil, nil, nil, nil)
+
+	for _, tc := range []struct {
+		method, target, body string
+	}{
+		{http.MethodPost, "/api/mcp/secret", `{"user_email":"victim@example.com"}`},
+		{http.MethodGet, "/api/mcp/secret?email=victim@example.com", ""},
+		{http.MethodGet, "/api/settings/jira?email=victim@example.com", ""},
+		{http.MethodGet, "/api/billing/payment-history?email=victim@example.com", ""},
+		{http.MethodPost, "/api/account/delete", `{"email":"victim@example.com"}`},
+	} {
+		req := httptest.NewRequest(tc.method, tc.target, strings.NewReader(tc.body))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		server.Handler().ServeHTTP(rr, req)
+
+		if rr.Code < 400 || rr.Code >= 500 {
+			t.Errorf("%s %s: expected a 4xx without credentials, got %d: %s", tc.method, tc.target, rr.Code, rr.Body.String())
+		}
+	}
+	if len(stub.lookups) != 0 {
+		t.Fatalf("expected no user to be identified by a raw email, got %v", stub.lookups)
+	}
+}
+
 func TestHealthRoute(t *testing.T) {
 	cfg := config.Config{ServerAddress: ":0"}
 	stub := &stubUserClient{}
@@ -89,7 +166,7 @@ func TestHealthRoute(t *testing.T) {
 	}
 	defer db.Close()
 
-	server := New(cfg, db, stub, stub, stub, stub, stub, nil, nil, nil)
+	server := New(cfg, db, stub, stub, stub, stub, stub, nil, nil, nil, nil, nil, nil)
 
 	req := httptest.NewRequest(http.MethodGet, "/healthz", nil)
 	rr := httptest.NewRecorder()
@@ -100,3 +177,66 @@ func TestHealthRoute(t *testing.T) {
 		t.Fatalf("expected 200 got %d", rr.Code)
 	}
 }
+
+func TestListenReusePort(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("SO_REUSEPORT is only set on linux")
+	}
+	first, err := listen("127.0.0.1:0", true)
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer first.Close()
+
+	// A second release binds the same port while the first still serves.
+	second, err := listen(first.Addr().String(), true)
+	if err != nil {
+		t.Fatalf("expected a second listener on %s, got %v", first.Addr(), err)
+	}
+	second.Close()
+}
+
+func TestConnectionTrackerReportsDrain(t *testing.T) {
+	conns := newConnectionTracker()
+	release := make(chan struct{})
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	srv.Config.ConnState = conns.track
+	srv.Start()
+	defer srv.Close()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if resp, err := http.Get(srv.URL); err == nil {
+			resp.Body.Close()
+		}
+	}()
+	deadline := time.Now().Add(time.Second)
+	for {
+		if _, active := conns.counts(); active == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected an active connection")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	conns.startDrain()
+	var b strings.Builder
+	conns.WritePrometheus(&b)
+	if !strings.Contains(b.String(), "http_connections_active 1\n") || !strings.Contains(b.String(), "http_draining 1\n") {
+		t.Fatalf("expected an active connection while draining, got:\n%s", b.String())
+	}
+
+	close(release)
+	<-done
+	conns.finishDrain(2)
+	b.Reset()
+	conns.WritePrometheus(&b)
+	if !strings.Contains(b.String(), "http_draining 0\n") || !strings.Contains(b.String(), "http_drain_forced_connections_total 2\n") {
+		t.Fatalf("expected the finished drain to be reported, got:\n%s", b.String())
+	}
+}
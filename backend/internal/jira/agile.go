@@ -0,0 +1,60 @@
+package jira
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// maxSprintBoards bounds how many of a project's boards ActiveSprints looks
+// at.
+const maxSprintBoards = 10
+
+// Board is a Jira Software board.
+type Board struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// Sprint is a sprint of a scrum board. The dates are unset for sprints that
+// have not been started.
+type Sprint struct {
+	ID        int        `json:"id"`
+	Name      string     `json:"name"`
+	State     string     `json:"state"`
+	StartDate *time.Time `json:"startDate,omitempty"`
+	EndDate   *time.Time `json:"endDate,omitempty"`
+	BoardID   int        `json:"boardId"`
+	BoardName string     `json:"boardName,omitempty"`
+}
+
+// ActiveSprints returns the active sprints of the scrum boards of the
+// project with projectKey.
+func (c *Client) ActiveSprints(ctx context.Context, projectKey string) ([]Sprint, error) {
+	var boards struct {
+		Values []Board `json:"values"`
+	}
+	params := url.Values{"projectKeyOrId": {projectKey}, "type": {"scrum"}, "maxResults": {strconv.Itoa(maxSprintBoards)}}
+	if err := c.doAgile(ctx, http.MethodGet, "/board", params, nil, &boards); err != nil {
+		return nil, err
+	}
+
+	var sprints []Sprint
+	for _, board := range boards.Values {
+		var page struct {
+			Values []Sprint `json:"values"`
+		}
+		if err := c.doAgile(ctx, http.MethodGet, "/board/"+strconv.Itoa(board.ID)+"/sprint", url.Values{"state": {"active"}}, nil, &page); err != nil {
+			return nil, err
+		}
+		for _, sprint := range page.Values {
+			sprint.BoardID = board.ID
+			sprint.BoardName = board.Name
+			sprints = append(sprints, sprint)
+		}
+	}
+	return sprints, nil
+}
@@ -0,0 +1,93 @@
+package jira
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// ChangelogItem is one field changed in a changelog entry. From and To hold
+// IDs (account IDs, status IDs, ...) where the field has them; FromString
+// and ToString hold what Jira displays.
+type ChangelogItem struct {
+	Field      string `json:"field"`
+	FieldID    string `json:"fieldId,omitempty"`
+	FieldType  string `json:"fieldtype,omitempty"`
+	From       string `json:"from,omitempty"`
+	FromString string `json:"fromString,omitempty"`
+	To         string `json:"to,omitempty"`
+	ToString   string `json:"toString,omitempty"`
+}
+
+// ChangelogEntry is a set of field changes one user made at once.
+type ChangelogEntry struct {
+	ID      string          `json:"id"`
+	Author  *Account        `json:"author,omitempty"`
+	Created string          `json:"created"`
+	Items   []ChangelogItem `json:"items"`
+}
+
+// ChangelogPage is one page of an issue's changelog, oldest entry first.
+type ChangelogPage struct {
+	StartAt    int              `json:"startAt"`
+	MaxResults int              `json:"maxResults"`
+	Total      int              `json:"total"`
+	IsLast     bool             `json:"isLast"`
+	Values     []ChangelogEntry `json:"values"`
+}
+
+// IssueChangelog returns up to maxResults changelog entries of an issue,
+// starting at startAt. Jira caps maxResults at 100.
+func (c *Client) IssueChangelog(ctx context.Context, idOrKey string, startAt, maxResults int) (*ChangelogPage, error) {
+	query := url.Values{}
+	if startAt > 0 {
+		query.Set("startAt", strconv.Itoa(startAt))
+	}
+	if maxResults > 0 {
+		query.Set("maxResults", strconv.Itoa(maxResults))
+	}
+	var page ChangelogPage
+	if err := c.do(ctx, http.MethodGet, issuePath(idOrKey)+"/changelog", query, nil, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// FilterItems keeps the items of the page that change one of fields, matched
+// by name or ID without regard to case, and drops entries left empty. No
+// fields keeps everything.
+func (p *ChangelogPage) FilterItems(fields []string) {
+	if len(fields) == 0 {
+		return
+	}
+	entries := p.Values[:0]
+	for _, entry := range p.Values {
+		var items []ChangelogItem
+		for _, item := range entry.Items {
+			for _, field := range fields {
+				field = strings.TrimSpace(field)
+				if strings.EqualFold(item.Field, field) || strings.EqualFold(item.FieldID, field) {
+					items = append(items, item)
+					break
+				}
+			}
+		}
+		if len(items) > 0 {
+			entry.Items = items
+			entries = append(entries, entry)
+		}
+	}
+	p.Values = entries
+}
+
+// IssueHistory is the page of an issue's changelog jira_get_issue_history
+// returns. NextStartAt is unset on the last page.
+type IssueHistory struct {
+	IssueKey    string           `json:"issueKey"`
+	StartAt     int              `json:"startAt"`
+	Total       int              `json:"total"`
+	NextStartAt *int             `json:"nextStartAt,omitempty"`
+	Changes     []ChangelogEntry `json:"changes"`
+}
@@ -0,0 +1,340 @@
+// Package jira talks to the Atlassian Cloud REST API (v3) with a tenant's
+// stored credentials, so backend code does not have to build Jira requests
+// itself.
+package jira
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+)
+
+// defaultTimeout bounds every request made by a client from NewClient.
+const defaultTimeout = 30 * time.Second
+
+// maxResponseBytes bounds how much of a response body is read.
+const maxResponseBytes = 10 << 20
+
+// Client calls the Jira REST API of one site as one user, authenticating
+// with the user's email and Atlassian API token, or with an OAuth access
+// token through the Atlassian API gateway.
+type Client struct {
+	baseURL    string
+	siteURL    string
+	email      string
+	apiToken   string
+	httpClient *http.Client
+	defaults   *models.JiraIssueDefaults
+	// serviceManagement is set for sites found to have Jira Service
+	// Management.
+	serviceManagement bool
+	// fieldOptions looks up the synced options of select fields, if set.
+	fieldOptions FieldOptionLookup
+	// rateObserver is told the rate limit of every response, if set.
+	rateObserver RateObserver
+
+	// mu guards accessToken, which refresh replaces after Jira rejects it.
+	mu          sync.Mutex
+	accessToken string
+	refresh     func(ctx context.Context, rejected string) (string, error)
+}
+
+// NewClient creates a client for the site at baseURL (for example
+// https://acme.atlassian.net). The URL must be https.
+func NewClient(baseURL, email, apiToken string) (*Client, error) {
+	u, err := url.Parse(strings.TrimSpace(baseURL))
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return nil, fmt.Errorf("jira: base URL must be an https URL, got %q", baseURL)
+	}
+	if email == "" || apiToken == "" {
+		return nil, errors.New("jira: email and API token are required")
+	}
+	return &Client{
+		baseURL:    strings.TrimRight(u.String(), "/"),
+		email:      email,
+		apiToken:   apiToken,
+		httpClient: &http.Client{Timeout: defaultTimeout},
+	}, nil
+}
+
+// NewOAuthClient creates a client for the site with cloudID, reached
+// through the Atlassian API gateway with an OAuth access token. siteURL is
+// the site's own URL, reported by BaseURL.
+func NewOAuthClient(cloudID, siteURL, accessToken string) (*Client, error) {
+	if cloudID == "" || accessToken == "" {
+		return nil, errors.New("jira: cloud ID and access token are required")
+	}
+	return &Client{
+		baseURL:     "https://api.atlassian.com/ex/jira/" + url.PathEscape(cloudID),
+		siteURL:     strings.TrimRight(siteURL, "/"),
+		accessToken: accessToken,
+		httpClient:  &http.Client{Timeout: defaultTimeout},
+	}, nil
+}
+
+// TokenRefresher renews the OAuth access token of the user with userID after
+// Jira rejected the token rejected, saves it and returns the new one.
+type TokenRefresher func(ctx context.Context, userID int64, rejected string) (string, error)
+
+// FromSettings creates a client from stored Jira settings, carrying the
+// tenant's issue defaults and whether the site has Jira Service Management.
+func FromSettings(settings *models.JiraUserSettingsWithSecret) (*Client, error) {
+	if settings == nil {
+		return nil, errors.New("jira: settings cannot be nil")
+	}
+	var (
+		client *Client
+		err    error
+	)
+	if settings.AuthMethod == models.JiraAuthOAuth {
+		if settings.JiraCloudID == nil {
+			return nil, errors.New("jira: OAuth settings have no cloud ID")
+		}
+		client, err = NewOAuthClient(*settings.JiraCloudID, settings.JiraBaseURL, settings.AtlassianAPIToken)
+	} else {
+		client, err = NewClient(settings.JiraBaseURL, settings.JiraEmail, settings.AtlassianAPIToken)
+	}
+	if err != nil {
+		return nil, err
+	}
+	client.defaults = settings.IssueDefaults
+	client.serviceManagement = settings.ServiceManagement
+	return client, nil
+}
+
+// SettingsResolver finds the Jira credentials of the tenant holding an MCP
+// secret; *store.Store implements it.
+type SettingsResolver interface {
+	GetUserSettingsByMCPSecret(ctx context.Context, secret string) (*models.JiraUserSettingsWithSecret, error)
+}
+
+// ForMCPSecret creates a client for the Jira site the tenant holding
+// mcpSecret uses by default, falling back to their organization's shared
+// account as GetUserSettingsByMCPSecret does. When refresh is not nil, a
+// client for an OAuth site renews its access token with it and retries once
+// when Jira answers 401.
+func ForMCPSecret(ctx context.Context, resolver SettingsResolver, mcpSecret string, refresh TokenRefresher) (*Client, error) {
+	settings, err := resolver.GetUserSettingsByMCPSecret(ctx, mcpSecret)
+	if err != nil {
+		return nil, fmt.Errorf("jira: resolve settings: %w", err)
+	}
+	return refreshingClient(settings, refresh)
+}
+
+// SitesResolver finds the Jira credentials of every site the tenant holding
+// an MCP secret configured; *store.Store implements it.
+type SitesResolver interface {
+	ListUserSettingsByMCPSecret(ctx context.Context, secret string) ([]models.JiraUserSettingsWithSecret, error)
+}
+
+// ClientsForMCPSecret creates a client for each Jira site of the tenant
+// holding mcpSecret, the default site first. refresh is used as in
+// ForMCPSecret.
+func ClientsForMCPSecret(ctx context.Context, resolver SitesResolver, mcpSecret string, refresh TokenRefresher) ([]*Client, error) {
+	sites, err := resolver.ListUserSettingsByMCPSecret(ctx, mcpSecret)
+	if err != nil {
+		return nil, fmt.Errorf("jira: resolve settings: %w", err)
+	}
+	clients := make([]*Client, 0, len(sites))
+	for i := range sites {
+		client, err := refreshingClient(&sites[i], refresh)
+		if err != nil {
+			return nil, fmt.Errorf("jira: %s: %w", sites[i].JiraBaseURL, err)
+		}
+		clients = append(clients, client)
+	}
+	return clients, nil
+}
+
+// refreshingClient is FromSettings, renewing OAuth access tokens with
+// refresh when it is not nil.
+func refreshingClient(settings *models.JiraUserSettingsWithSecret, refresh TokenRefresher) (*Client, error) {
+	client, err := FromSettings(settings)
+	if err != nil {
+		return nil, err
+	}
+	if refresh != nil && settings.AuthMethod == models.JiraAuthOAuth && settings.UserID > 0 {
+		userID := settings.UserID
+		client.refresh = func(ctx context.Context, rejected string) (string, error) {
+			return refresh(ctx, userID, rejected)
+		}
+	}
+	return client, nil
+}
+
+// WithHTTPClient makes the client send its requests with hc, for example
+// to reach a test server. A nil hc keeps the current one.
+func (c *Client) WithHTTPClient(hc *http.Client) *Client {
+	if hc != nil {
+		c.httpClient = hc
+	}
+	return c
+}
+
+// IssueDefaults returns the tenant's defaults for new issues, nil when it
+// has none or the client was not created from settings.
+func (c *Client) IssueDefaults() *models.JiraIssueDefaults {
+	return c.defaults
+}
+
+// BaseURL returns the site the client talks to.
+func (c *Client) BaseURL() string {
+	if c.siteURL != "" {
+		return c.siteURL
+	}
+	return c.baseURL
+}
+
+// APIError is an error response from the Jira API.
+type APIError struct {
+	StatusCode int
+	// Messages and FieldErrors are Jira's errorMessages and errors.
+	Messages    []string
+	FieldErrors map[string]string
+	// RetryAfterDelay is the response's Retry-After hint, zero when absent.
+	RetryAfterDelay time.Duration
+}
+
+func (e *APIError) Error() string {
+	parts := append([]string(nil), e.Messages...)
+	for field, msg := range e.FieldErrors {
+		parts = append(parts, field+": "+msg)
+	}
+	if len(parts) == 0 {
+		parts = append(parts, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("jira API error (%d): %s", e.StatusCode, strings.Join(parts, "; "))
+}
+
+// IsNotFound reports whether err is a 404 from Jira, which it also returns
+// for issues and projects the user may not see.
+func IsNotFound(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// IsUnauthorized reports whether err means the stored credentials were
+// rejected, for example because the API token was revoked.
+func IsUnauthorized(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && (apiErr.StatusCode == http.StatusUnauthorized || apiErr.StatusCode == http.StatusForbidden)
+}
+
+func isStatus(err error, status int) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == status
+}
+
+// do sends a request to path under /rest/api/3 and decodes the JSON
+// response into out when out is not nil. An OAuth client that can refresh
+// its access token does so when Jira answers 401 and retries once; the 401
+// is returned when the refresh fails.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, out any) error {
+	return c.call(ctx, method, "/rest/api/3"+path, query, body, out)
+}
+
+// doAgile is do for the Jira Software API under /rest/agile/1.0.
+func (c *Client) doAgile(ctx context.Context, method, path string, query url.Values, body, out any) error {
+	return c.call(ctx, method, "/rest/agile/1.0"+path, query, body, out)
+}
+
+func (c *Client) call(ctx context.Context, method, path string, query url.Values, body, out any) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return fmt.Errorf("jira: encode request: %w", err)
+		}
+	}
+
+	target := c.baseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	c.mu.Lock()
+	accessToken := c.accessToken
+	c.mu.Unlock()
+
+	err := c.send(ctx, method, target, payload, accessToken, out)
+	if c.refresh == nil || !isStatus(err, http.StatusUnauthorized) {
+		return err
+	}
+	fresh, refreshErr := c.refresh(ctx, accessToken)
+	if refreshErr != nil || fresh == "" {
+		return err
+	}
+	c.mu.Lock()
+	c.accessToken = fresh
+	c.mu.Unlock()
+	return c.send(ctx, method, target, payload, fresh, out)
+}
+
+func (c *Client) send(ctx context.Context, method, target string, payload []byte, accessToken string, out any) error {
+	var reader io.Reader
+	if payload != nil {
+		reader = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return fmt.Errorf("jira: build request: %w", err)
+	}
+	if accessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+	} else {
+		req.SetBasicAuth(c.email, c.apiToken)
+	}
+	req.Header.Set("Accept", "application/json")
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("jira request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if c.rateObserver != nil {
+		c.rateObserver(parseRateLimit(resp))
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBytes))
+	if err != nil {
+		return fmt.Errorf("read jira response: %w", err)
+	}
+
+	if resp.StatusCode >= 400 {
+		apiErr := &APIError{StatusCode: resp.StatusCode}
+		var payload struct {
+			ErrorMessages []string          `json:"errorMessages"`
+			Errors        map[string]string `json:"errors"`
+		}
+		if json.Unmarshal(data, &payload) == nil {
+			apiErr.Messages = payload.ErrorMessages
+			apiErr.FieldErrors = payload.Errors
+		}
+		if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && secs > 0 {
+			apiErr.RetryAfterDelay = time.Duration(secs) * time.Second
+		}
+		return apiErr
+	}
+
+	if out == nil || len(bytes.TrimSpace(data)) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("parse jira response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,737 @@
+package jira
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/mcp"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+)
+
+type fakeResolver map[string]*models.JiraUserSettingsWithSecret
+
+func (f fakeResolver) GetUserSettingsByMCPSecret(ctx context.Context, secret string) (*models.JiraUserSettingsWithSecret, error) {
+	return f[secret], nil
+}
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+	server := httptest.NewTLSServer(handler)
+	t.Cleanup(server.Close)
+
+	c, err := ForMCPSecret(context.Background(), fakeResolver{"secret": {
+		JiraBaseURL:       server.URL + "/",
+		JiraEmail:         "bot@example.com",
+		AtlassianAPIToken: "token",
+	}}, "secret", nil)
+	if err != nil {
+		t.Fatalf("ForMCPSecret: %v", err)
+	}
+	c.httpClient = server.Client()
+	return c
+}
+
+func TestClientIssuesAndSearch(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "bot@example.com" || pass != "token" {
+			t.Errorf("unexpected credentials %q %q", user, pass)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/rest/api/3/issue/ABC-1":
+			if got := r.URL.Query().Get("fields"); got != "summary,status" {
+				t.Errorf("unexpected fields %q", got)
+			}
+			w.Write([]byte(`{"id":"10001","key":"ABC-1","fields":{"summary":"Broken build"}}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/rest/api/3/search/jql":
+			var req SearchRequest
+			json.NewDecoder(r.Body).Decode(&req)
+			if req.JQL != "project = ABC" || req.NextPageToken != "page2" {
+				t.Errorf("unexpected search %+v", req)
+			}
+			w.Write([]byte(`{"issues":[{"id":"10002","key":"ABC-2"}],"isLast":true}`))
+		case r.Method == http.MethodPut && r.URL.Path == "/rest/api/3/issue/ABC-1":
+			w.WriteHeader(http.StatusNoContent)
+		case r.Method == http.MethodDelete && r.URL.Path == "/rest/api/3/issue/ABC-1":
+			if got := r.URL.Query().Get("deleteSubtasks"); got != "true" {
+				t.Errorf("unexpected deleteSubtasks %q", got)
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+	ctx := context.Background()
+
+	issue, err := c.GetIssue(ctx, "ABC-1", []string{"summary", "status"})
+	if err != nil {
+		t.Fatalf("GetIssue: %v", err)
+	}
+	if issue.Key != "ABC-1" || issue.Fields["summary"] != "Broken build" {
+		t.Fatalf("unexpected issue %+v", issue)
+	}
+
+	result, err := c.Search(ctx, SearchRequest{JQL: "project = ABC", NextPageToken: "page2"})
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if len(result.Issues) != 1 || result.Issues[0].Key != "ABC-2" || !result.IsLast {
+		t.Fatalf("unexpected search result %+v", result)
+	}
+
+	if err := c.UpdateIssue(ctx, "ABC-1", map[string]any{"summary": "Fixed build"}); err != nil {
+		t.Fatalf("UpdateIssue: %v", err)
+	}
+
+	if err := c.DeleteIssue(ctx, "ABC-1", true); err != nil {
+		t.Fatalf("DeleteIssue: %v", err)
+	}
+}
+
+func TestClientAPIError(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/rest/api/3/project/NOPE":
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"errorMessages":["No project could be found with key 'NOPE'."],"errors":{}}`))
+		default:
+			w.Header().Set("Retry-After", "7")
+			w.WriteHeader(http.StatusTooManyRequests)
+		}
+	})
+	ctx := context.Background()
+
+	_, err := c.GetProject(ctx, "NOPE")
+	if !IsNotFound(err) || err.Error() != "jira API error (404): No project could be found with key 'NOPE'." {
+		t.Fatalf("expected a not found error, got %v", err)
+	}
+
+	_, err = c.ListProjects(ctx, "", 0, 50)
+	apiErr, ok := err.(*APIError)
+	if !ok || apiErr.StatusCode != http.StatusTooManyRequests || apiErr.RetryAfterDelay != 7*time.Second {
+		t.Fatalf("expected a rate limit error with Retry-After, got %v", err)
+	}
+}
+
+func TestClientReportsRateLimit(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Limit", "350")
+		w.Header().Set("X-RateLimit-Remaining", "12")
+		w.Header().Set("X-RateLimit-NearLimit", "true")
+		w.Header().Set("X-RateLimit-Reset", "2024-05-01T12:00:30Z")
+		w.Header().Set("Beta-Retry-After", "4")
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+	var seen []RateLimit
+	c.WithRateObserver(func(rl RateLimit) { seen = append(seen, rl) })
+
+	if _, err := c.ApproximateCount(context.Background(), "project = OPS"); err == nil {
+		t.Fatal("expected the rejected request to fail")
+	}
+	if len(seen) != 1 {
+		t.Fatalf("expected one observed response, got %d", len(seen))
+	}
+	rl := seen[0]
+	if !rl.Limited() || rl.Limit != 350 || rl.Remaining != 12 || !rl.NearLimit || rl.RetryAfter != 4*time.Second ||
+		!rl.Reset.Equal(time.Date(2024, 5, 1, 12, 0, 30, 0, time.UTC)) {
+		t.Fatalf("unexpected rate limit %+v", rl)
+	}
+}
+
+func TestNewClientRequiresHTTPS(t *testing.T) {
+	if _, err := NewClient("http://acme.atlassian.net", "bot@example.com", "token"); err == nil {
+		t.Fatal("expected a plain http base URL to be refused")
+	}
+}
+
+func TestMCPToolsDeleteIssue(t *testing.T) {
+	var deleted string
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			deleted = r.URL.Path + "?" + r.URL.RawQuery
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		http.NotFound(w, r)
+	})
+	tools := map[string]mcp.Tool{}
+	for _, tool := range MCPTools(func(ctx context.Context) (*Client, error) { return c, nil }) {
+		tools[tool.Name] = tool
+	}
+	for _, name := range []string{"jira_create_issue", "jira_get_issue", "jira_update_issue", "jira_delete_issue"} {
+		if _, ok := tools[name]; !ok {
+			t.Fatalf("expected tool %s", name)
+		}
+	}
+
+	out, err := tools["jira_delete_issue"].Handler(context.Background(), json.RawMessage(`{"issueKey":"ABC-1"}`))
+	if err != nil || out != "Deleted ABC-1." {
+		t.Fatalf("unexpected result %v, %v", out, err)
+	}
+	if deleted != "/rest/api/3/issue/ABC-1?" {
+		t.Fatalf("unexpected request %q", deleted)
+	}
+}
+
+func TestFromSettingsOAuthUsesGateway(t *testing.T) {
+	cloudID := "cloud-1"
+	c, err := FromSettings(&models.JiraUserSettingsWithSecret{
+		JiraBaseURL:       "https://acme.atlassian.net/",
+		JiraCloudID:       &cloudID,
+		AuthMethod:        models.JiraAuthOAuth,
+		AtlassianAPIToken: "access",
+	})
+	if err != nil {
+		t.Fatalf("FromSettings: %v", err)
+	}
+	if c.baseURL != "https://api.atlassian.com/ex/jira/cloud-1" || c.accessToken != "access" {
+		t.Fatalf("unexpected client %+v", c)
+	}
+	if got := c.BaseURL(); got != "https://acme.atlassian.net" {
+		t.Fatalf("expected the site URL, got %q", got)
+	}
+
+	if _, err := FromSettings(&models.JiraUserSettingsWithSecret{JiraBaseURL: "https://acme.atlassian.net", AuthMethod: models.JiraAuthOAuth, AtlassianAPIToken: "access"}); err == nil {
+		t.Fatal("expected an error without a cloud ID")
+	}
+}
+
+func TestMCPToolsClassifyJiraErrors(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/rest/api/3/issue/GONE-1":
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"errorMessages":["Issue does not exist or you do not have permission to see it."]}`))
+		case "/rest/api/3/issue/SLOW-1":
+			w.Header().Set("Retry-After", "30")
+			w.WriteHeader(http.StatusTooManyRequests)
+		case "/rest/api/3/issue":
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"errors":{"priority":"Priority name 'P0' is not valid"}}`))
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	})
+	tools := map[string]mcp.Tool{}
+	for _, tool := range MCPTools(func(ctx context.Context) (*Client, error) { return c, nil }) {
+		tools[tool.Name] = tool
+	}
+
+	for _, tc := range []struct {
+		tool, args string
+		code       mcp.ErrorCode
+	}{
+		{"jira_get_issue", `{"issueKey":"GONE-1"}`, mcp.ErrorNotFound},
+		{"jira_get_issue", `{"issueKey":"SLOW-1"}`, mcp.ErrorJiraRateLimited},
+		{"jira_get_issue", `{"issueKey":"ANY-1"}`, mcp.ErrorAuthExpired},
+		{"jira_create_issue", `{"projectKey":"ABC","issueType":"Bug","summary":"x","fields":{"priority":{"name":"P0"}}}`, mcp.ErrorFieldValidation},
+		{"jira_update_issue", `{"issueKey":"ABC-1"}`, mcp.ErrorFieldValidation},
+	} {
+		_, err := tools[tc.tool].Handler(context.Background(), json.RawMessage(tc.args))
+		toolErr := mcp.AsToolError(err)
+		if toolErr.Code != tc.code {
+			t.Errorf("%s %s: expected %s, got %s (%v)", tc.tool, tc.args, tc.code, toolErr.Code, err)
+		}
+		switch tc.code {
+		case mcp.ErrorJiraRateLimited:
+			if toolErr.RetryAfter != 30*time.Second {
+				t.Errorf("expected the Retry-After hint, got %v", toolErr.RetryAfter)
+			}
+		case mcp.ErrorFieldValidation:
+			if tc.tool == "jira_create_issue" && toolErr.Fields["priority"] == "" {
+				t.Errorf("expected the priority field error, got %+v", toolErr)
+			}
+		}
+	}
+}
+
+func TestMCPToolsCreateIssueAppliesDefaults(t *testing.T) {
+	var created map[string]map[string]any
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/rest/api/3/issue" {
+			http.NotFound(w, r)
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&created); err != nil {
+			t.Errorf("decode body: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id":"10001","key":"ENG-7"}`))
+	})
+	c.defaults = &models.JiraIssueDefaults{ProjectKey: "ENG", IssueType: "Task", Labels: []string{"mcp"}, Components: []string{"API"}}
+	var create mcp.Tool
+	for _, tool := range MCPTools(func(ctx context.Context) (*Client, error) { return c, nil }) {
+		if tool.Name == "jira_create_issue" {
+			create = tool
+		}
+	}
+
+	if _, err := create.Handler(context.Background(), json.RawMessage(`{"summary":"x"}`)); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	fields, _ := json.Marshal(created["fields"])
+	if want := `{"components":[{"name":"API"}],"issuetype":{"name":"Task"},"labels":["mcp"],"project":{"key":"ENG"},"summary":"x"}`; string(fields) != want {
+		t.Fatalf("expected the defaults to be applied, got %s", fields)
+	}
+
+	if _, err := create.Handler(context.Background(), json.RawMessage(`{"projectKey":"OPS","issueType":"Bug","summary":"x","fields":{"labels":[]}}`)); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	fields, _ = json.Marshal(created["fields"])
+	if want := `{"components":[{"name":"API"}],"issuetype":{"name":"Bug"},"labels":[],"project":{"key":"OPS"},"summary":"x"}`; string(fields) != want {
+		t.Fatalf("expected the arguments to win over the defaults, got %s", fields)
+	}
+
+	c.defaults = nil
+	_, err := create.Handler(context.Background(), json.RawMessage(`{"summary":"x"}`))
+	if toolErr := mcp.AsToolError(err); toolErr.Code != mcp.ErrorFieldValidation {
+		t.Fatalf("expected a field validation error without defaults, got %v", err)
+	}
+}
+
+func TestMCPToolsCheckProjectPermissions(t *testing.T) {
+	lookups, writes := 0, 0
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/rest/api/3/mypermissions":
+			lookups++
+			if r.URL.Query().Get("projectKey") != "ENG" {
+				t.Errorf("unexpected permissions query %q", r.URL.RawQuery)
+			}
+			w.Write([]byte(`{"permissions":{"CREATE_ISSUES":{"havePermission":false},"DELETE_ISSUES":{"havePermission":true},"TRANSITION_ISSUES":{"havePermission":false}}}`))
+		default:
+			writes++
+			w.WriteHeader(http.StatusNoContent)
+		}
+	})
+	tools := map[string]mcp.Tool{}
+	for _, tool := range MCPTools(func(ctx context.Context) (*Client, error) { return c, nil }) {
+		tools[tool.Name] = tool
+	}
+
+	_, err := tools["jira_create_issue"].Handler(context.Background(), json.RawMessage(`{"projectKey":"ENG","issueType":"Task","summary":"x"}`))
+	if toolErr := mcp.AsToolError(err); toolErr.Code != mcp.ErrorPermissionDenied || toolErr.Message != "missing CREATE_ISSUES permission on project ENG" {
+		t.Fatalf("expected a permission error, got %+v", toolErr)
+	}
+	_, err = tools["transitionJiraIssue"].Handler(context.Background(), json.RawMessage(`{"issueKey":"ENG-1","transitionId":"31"}`))
+	if toolErr := mcp.AsToolError(err); toolErr.Code != mcp.ErrorPermissionDenied {
+		t.Fatalf("expected a permission error, got %+v", toolErr)
+	}
+	if writes != 0 {
+		t.Fatalf("expected no writes without permission, got %d", writes)
+	}
+
+	if _, err := tools["jira_delete_issue"].Handler(context.Background(), json.RawMessage(`{"issueKey":"ENG-1"}`)); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if writes != 1 || lookups != 1 {
+		t.Fatalf("expected one cached permission lookup and one write, got %d lookups and %d writes", lookups, writes)
+	}
+}
+
+func TestRelativeDate(t *testing.T) {
+	// A Wednesday.
+	now := time.Date(2026, 10, 14, 18, 30, 0, 0, time.UTC)
+	for phrase, want := range map[string]string{
+		"today":             "2026-10-14",
+		"Tomorrow":          "2026-10-15",
+		"friday":            "2026-10-16",
+		"this Wednesday":    "2026-10-14",
+		"next Wednesday":    "2026-10-21",
+		"next Monday":       "2026-10-19",
+		"next week":         "2026-10-19",
+		"by end of week":    "2026-10-16",
+		"end of the month":  "2026-10-31",
+		"end of next month": "2026-11-30",
+		"in 3 days":         "2026-10-17",
+		"in two weeks":      "2026-10-28",
+		"in a month":        "2026-11-14",
+		"2026-12-01":        "2026-12-01",
+	} {
+		date, ok := relativeDate(normalizePhrase(phrase, "by "), now)
+		if !ok || date.Format(DateLayout) != want {
+			t.Errorf("%q: expected %s, got %s (%v)", phrase, want, date.Format(DateLayout), ok)
+		}
+	}
+	if _, ok := relativeDate("whenever", now); ok {
+		t.Error("expected an unknown phrase to be refused")
+	}
+}
+
+func TestResolveUser(t *testing.T) {
+	searches := 0
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path != "/rest/api/3/user/search" {
+			http.NotFound(w, r)
+			return
+		}
+		searches++
+		switch r.URL.Query().Get("query") {
+		case "maria":
+			w.Write([]byte(`[
+				{"accountId":"a1","accountType":"atlassian","displayName":"Maria Silva","active":true},
+				{"accountId":"a2","accountType":"atlassian","displayName":"Mariana Costa","active":true},
+				{"accountId":"a3","accountType":"app","displayName":"Maria Bot","active":true}]`))
+		case "sam":
+			w.Write([]byte(`[
+				{"accountId":"b1","accountType":"atlassian","displayName":"Sam Green","active":true},
+				{"accountId":"b2","accountType":"atlassian","displayName":"Sam Brown","active":true},
+				{"accountId":"b3","accountType":"atlassian","displayName":"Sam Old","active":false}]`))
+		default:
+			w.Write([]byte(`[]`))
+		}
+	})
+	ctx := context.Background()
+
+	resolution, err := c.ResolveUser(ctx, "assign to Maria")
+	if err != nil || resolution.Account == nil || resolution.Account.AccountID != "a1" {
+		t.Fatalf("expected Maria Silva, got %+v, %v", resolution, err)
+	}
+	if _, err := c.ResolveUser(ctx, "maria"); err != nil || searches != 1 {
+		t.Fatalf("expected the search to be cached, got %d searches (%v)", searches, err)
+	}
+
+	resolution, err = c.ResolveUser(ctx, "Sam")
+	if err != nil || resolution.Account != nil || len(resolution.Candidates) != 2 {
+		t.Fatalf("expected two active candidates, got %+v, %v", resolution, err)
+	}
+	if _, err := c.ResolveUser(ctx, "nobody"); !errors.Is(err, ErrUnresolved) {
+		t.Fatalf("expected ErrUnresolved, got %v", err)
+	}
+	resolution, err = c.ResolveUser(ctx, "5b10a2844c20165700ede21f")
+	if err != nil || resolution.Account.AccountID != "5b10a2844c20165700ede21f" {
+		t.Fatalf("expected the account ID to pass through, got %+v, %v", resolution, err)
+	}
+}
+
+func TestMCPToolsResolveAssigneeAndSprintDueDate(t *testing.T) {
+	var created map[string]map[string]any
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/rest/api/3/user/search":
+			w.Write([]byte(`[{"accountId":"a1","displayName":"Ada Lovelace","active":true}]`))
+		case "/rest/agile/1.0/board":
+			if r.URL.Query().Get("projectKeyOrId") != "ENG" {
+				t.Errorf("unexpected board query %q", r.URL.RawQuery)
+			}
+			w.Write([]byte(`{"values":[{"id":7,"name":"ENG board","type":"scrum"}]}`))
+		case "/rest/agile/1.0/board/7/sprint":
+			w.Write([]byte(`{"values":[{"id":3,"name":"Sprint 12","state":"active","endDate":"2026-10-23T23:00:00.000Z"}]}`))
+		case "/rest/api/3/issue":
+			json.NewDecoder(r.Body).Decode(&created)
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"id":"10001","key":"ENG-8"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	})
+	var create mcp.Tool
+	for _, tool := range MCPTools(func(ctx context.Context) (*Client, error) { return c, nil }) {
+		if tool.Name == "jira_create_issue" {
+			create = tool
+		}
+	}
+
+	args := `{"projectKey":"ENG","issueType":"Task","summary":"x","assignee":"ada","dueDate":"end of sprint","timeZone":"Europe/Berlin"}`
+	if _, err := create.Handler(context.Background(), json.RawMessage(args)); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if assignee, _ := created["fields"]["assignee"].(map[string]any); assignee["accountId"] != "a1" {
+		t.Fatalf("expected the resolved assignee, got %v", created["fields"]["assignee"])
+	}
+	// 23:00 UTC is the next day in Berlin.
+	if got := created["fields"]["duedate"]; got != "2026-10-24" {
+		t.Fatalf("expected the sprint end in Berlin, got %v", got)
+	}
+
+	_, err := create.Handler(context.Background(), json.RawMessage(`{"projectKey":"ENG","issueType":"Task","summary":"x","dueDate":"someday"}`))
+	if toolErr := mcp.AsToolError(err); toolErr.Code != mcp.ErrorFieldValidation || toolErr.Fields["dueDate"] == "" {
+		t.Fatalf("expected a dueDate validation error, got %+v", toolErr)
+	}
+}
+
+func TestMCPToolsIssueHistoryFiltersFields(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/rest/api/3/issue/ENG-8/changelog" {
+			http.NotFound(w, r)
+			return
+		}
+		if q := r.URL.Query(); q.Get("startAt") != "2" || q.Get("maxResults") != "2" {
+			t.Errorf("unexpected paging %q", r.URL.RawQuery)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"startAt":2,"maxResults":2,"total":5,"isLast":false,"values":[
+			{"id":"3","author":{"accountId":"a1","displayName":"Ada Lovelace"},"created":"2026-10-01T09:00:00.000+0000","items":[
+				{"field":"status","fieldId":"status","fromString":"To Do","toString":"In Progress"},
+				{"field":"duedate","fieldId":"duedate","from":"2026-10-10","to":"2026-10-17"}]},
+			{"id":"4","created":"2026-10-02T09:00:00.000+0000","items":[{"field":"assignee","fieldId":"assignee","to":"a1"}]}
+		]}`))
+	})
+	var history mcp.Tool
+	for _, tool := range MCPTools(func(ctx context.Context) (*Client, error) { return c, nil }) {
+		if tool.Name == "jira_get_issue_history" {
+			history = tool
+		}
+	}
+
+	out, err := history.Handler(context.Background(), json.RawMessage(`{"issueKey":"ENG-8","fields":["DueDate"],"startAt":2,"maxResults":2}`))
+	if err != nil {
+		t.Fatalf("history: %v", err)
+	}
+	got := out.(*IssueHistory)
+	if len(got.Changes) != 1 || len(got.Changes[0].Items) != 1 || got.Changes[0].Items[0].To != "2026-10-17" {
+		t.Fatalf("expected only the due date change, got %+v", got.Changes)
+	}
+	if got.Changes[0].Author.DisplayName != "Ada Lovelace" {
+		t.Fatalf("expected the author, got %+v", got.Changes[0].Author)
+	}
+	if got.NextStartAt == nil || *got.NextStartAt != 4 || got.Total != 5 {
+		t.Fatalf("expected the next page at 4 of 5, got %+v", got)
+	}
+}
+
+func TestMCPToolsServiceDeskRequiresJSM(t *testing.T) {
+	var transitioned map[string]any
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/rest/servicedeskapi/request/HELP-4/transition":
+			json.NewDecoder(r.Body).Decode(&transitioned)
+			w.WriteHeader(http.StatusNoContent)
+		case "/rest/servicedeskapi/request/HELP-4/sla":
+			w.Write([]byte(`{"values":[{"id":"1","name":"Time to resolution","ongoingCycle":{"breached":false,"remainingTime":{"millis":3600000,"friendly":"1h"}}}]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	})
+	var transition mcp.Tool
+	for _, tool := range MCPTools(func(ctx context.Context) (*Client, error) { return c, nil }) {
+		if tool.Name == "jsm_transition_request" {
+			transition = tool
+		}
+	}
+	args := json.RawMessage(`{"issueKey":"HELP-4","transitionId":"21","comment":"Fixed"}`)
+
+	if _, err := transition.Handler(context.Background(), args); err == nil || transitioned != nil {
+		t.Fatalf("expected the tool to refuse without Jira Service Management, got %v", err)
+	}
+
+	c.serviceManagement = true
+	out, err := transition.Handler(context.Background(), args)
+	if err != nil {
+		t.Fatalf("transition: %v", err)
+	}
+	if transitioned["id"] != "21" || transitioned["additionalComment"].(map[string]any)["body"] != "Fixed" {
+		t.Fatalf("unexpected transition request %v", transitioned)
+	}
+	result := out.(RequestTransition)
+	if !result.Transitioned || len(result.SLAs) != 1 || result.SLAs[0].OngoingCycle.RemainingTime.Friendly != "1h" {
+		t.Fatalf("expected the SLAs after the transition, got %+v", result)
+	}
+}
+
+func TestSiteToolsIsolateFailingSites(t *testing.T) {
+	var jql string
+	healthy := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		var req SearchRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		jql = req.JQL
+		w.Write([]byte(`{"issues":[{"id":"1","key":"ENG-1"},{"id":"2","key":"ENG-2"}],"isLast":true}`))
+	})
+	revoked := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+	var mine mcp.Tool
+	for _, tool := range SiteTools(func(ctx context.Context) ([]*Client, error) { return []*Client{revoked, healthy}, nil }) {
+		if tool.Name == "jira_my_open_issues" {
+			mine = tool
+		}
+	}
+
+	out, err := mine.Handler(context.Background(), json.RawMessage(`{}`))
+	if err != nil {
+		t.Fatalf("my open issues: %v", err)
+	}
+	if jql != myOpenIssuesJQL {
+		t.Fatalf("unexpected JQL %q", jql)
+	}
+	result := out.(SitesSearch)
+	if result.Total != 2 || result.FailedSites != 1 || len(result.Sites) != 2 {
+		t.Fatalf("unexpected result %+v", result)
+	}
+	if failed := result.Sites[0]; failed.Error == nil || failed.Error.Code != mcp.ErrorAuthExpired || len(failed.Issues) != 0 {
+		t.Fatalf("expected the revoked site to report auth_expired, got %+v", failed)
+	}
+	if ok := result.Sites[1]; ok.Error != nil || ok.Site != healthy.BaseURL() || len(ok.Issues) != 2 {
+		t.Fatalf("expected the healthy site's issues, got %+v", ok)
+	}
+}
+
+func TestOAuthClientRefreshesRejectedToken(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer fresh" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"accountId":"5b10"}`))
+	}))
+	defer server.Close()
+
+	cloudID := "cloud-1"
+	settings := &models.JiraUserSettingsWithSecret{JiraBaseURL: "https://acme.atlassian.net", JiraCloudID: &cloudID, AuthMethod: models.JiraAuthOAuth, AtlassianAPIToken: "stale", UserID: 7}
+	var refreshes []string
+	refresh := func(ctx context.Context, userID int64, rejected string) (string, error) {
+		if userID != 7 {
+			t.Errorf("refresh for user %d", userID)
+		}
+		refreshes = append(refreshes, rejected)
+		if len(refreshes) > 1 {
+			return "", ErrInvalidGrant
+		}
+		return "fresh", nil
+	}
+	c, err := ForMCPSecret(context.Background(), fakeResolver{"secret": settings}, "secret", refresh)
+	if err != nil {
+		t.Fatalf("ForMCPSecret: %v", err)
+	}
+	c.baseURL = server.URL
+	c.httpClient = server.Client()
+
+	account, err := c.Myself(context.Background())
+	if err != nil || account.AccountID != "5b10" {
+		t.Fatalf("Myself = %+v, %v", account, err)
+	}
+	if len(refreshes) != 1 || refreshes[0] != "stale" {
+		t.Fatalf("refreshes = %v", refreshes)
+	}
+	// The refreshed token is kept for later calls.
+	if _, err := c.Myself(context.Background()); err != nil || len(refreshes) != 1 {
+		t.Fatalf("second call: %v, refreshes = %v", err, refreshes)
+	}
+
+	// When the refresh fails the 401 is returned.
+	c.accessToken = "revoked"
+	if _, err := c.Myself(context.Background()); !IsUnauthorized(err) || len(refreshes) != 2 {
+		t.Fatalf("expected a 401 after a failed refresh, got %v (refreshes %v)", err, refreshes)
+	}
+}
+
+type fakeOAuthTokens struct {
+	token   models.IntegrationToken
+	updates int
+}
+
+func (f *fakeOAuthTokens) GetJiraOAuthToken(ctx context.Context, userID int64) (*models.IntegrationToken, error) {
+	t := f.token
+	return &t, nil
+}
+
+func (f *fakeOAuthTokens) UpdateJiraOAuthToken(ctx context.Context, id int64, accessToken, refreshToken string, expiresAt time.Time) error {
+	f.updates++
+	if accessToken != "" {
+		f.token.AccessToken = accessToken
+	}
+	f.token.RefreshToken = &refreshToken
+	f.token.ExpiresAt = &expiresAt
+	return nil
+}
+
+func TestOAuthRefresherSavesRotatedTokens(t *testing.T) {
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		if body["grant_type"] != "refresh_token" || body["refresh_token"] != "refresh-1" {
+			t.Errorf("unexpected token request %v", body)
+		}
+		w.Write([]byte(`{"access_token":"access-2","refresh_token":"refresh-2","expires_in":3600}`))
+	}))
+	defer tokenServer.Close()
+
+	oauth := NewOAuth("id", "secret", "https://app.example.com/callback/atlassian")
+	oauth.TokenURL = tokenServer.URL
+	refresh1 := "refresh-1"
+	expired := time.Now().Add(-time.Minute)
+	tokens := &fakeOAuthTokens{token: models.IntegrationToken{ID: 3, AccessToken: "access-1", RefreshToken: &refresh1, ExpiresAt: &expired}}
+	refresh := oauth.Refresher(tokens)
+
+	got, err := refresh(context.Background(), 7, "access-1")
+	if err != nil || got != "access-2" {
+		t.Fatalf("refresh = %q, %v", got, err)
+	}
+	if *tokens.token.RefreshToken != "refresh-2" || tokens.updates != 1 {
+		t.Fatalf("rotated refresh token not saved: %+v", tokens.token)
+	}
+
+	// A caller still holding the old token gets the new one without
+	// spending the rotated refresh token.
+	got, err = refresh(context.Background(), 7, "access-1")
+	if err != nil || got != "access-2" || tokens.updates != 1 {
+		t.Fatalf("second refresh = %q, %v (updates %d)", got, err, tokens.updates)
+	}
+}
+
+func TestMCPToolsCheckFieldOptions(t *testing.T) {
+	var created map[string]map[string]any
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/rest/api/3/issue" {
+			http.NotFound(w, r)
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&created); err != nil {
+			t.Errorf("decode body: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id":"10001","key":"ENG-7"}`))
+	})
+	c.WithFieldOptions(func(ctx context.Context, siteURL, projectKey string) ([]models.JiraFieldOption, error) {
+		if projectKey != "ENG" {
+			return nil, nil
+		}
+		return []models.JiraFieldOption{
+			{FieldID: "customfield_10010", FieldName: "Environment", OptionID: "1", Value: "Production"},
+			{FieldID: "customfield_10010", FieldName: "Environment", OptionID: "2", Value: "Staging"},
+			{FieldID: "customfield_10020", FieldName: "Platforms", Multi: true, OptionID: "3", Value: "iOS"},
+			{FieldID: "customfield_10020", FieldName: "Platforms", Multi: true, OptionID: "4", Value: "Android"},
+		}, nil
+	})
+	var create mcp.Tool
+	for _, tool := range MCPTools(func(ctx context.Context) (*Client, error) { return c, nil }) {
+		if tool.Name == "jira_create_issue" {
+			create = tool
+		}
+	}
+
+	args := `{"projectKey":"ENG","issueType":"Task","summary":"x","fields":{"environment":"production","customfield_10020":["ios",{"id":"4"}]}}`
+	if _, err := create.Handler(context.Background(), json.RawMessage(args)); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	fields, _ := json.Marshal(map[string]any{"env": created["fields"]["customfield_10010"], "platforms": created["fields"]["customfield_10020"]})
+	if want := `{"env":{"value":"Production"},"platforms":[{"value":"iOS"},{"value":"Android"}]}`; string(fields) != want {
+		t.Fatalf("expected the options to be normalized, got %s", fields)
+	}
+
+	created = nil
+	_, err := create.Handler(context.Background(), json.RawMessage(`{"projectKey":"ENG","issueType":"Task","summary":"x","fields":{"customfield_10010":"Stagign"}}`))
+	toolErr := mcp.AsToolError(err)
+	if toolErr.Code != mcp.ErrorFieldValidation || toolErr.Fields["customfield_10010"] != `"Stagign" is not an option of Environment; did you mean "Staging"?` {
+		t.Fatalf("expected a suggestion for the typo, got %+v", toolErr)
+	}
+	if created != nil {
+		t.Fatalf("expected the issue not to be created")
+	}
+
+	if _, err := create.Handler(context.Background(), json.RawMessage(`{"projectKey":"OPS","issueType":"Task","summary":"x","fields":{"customfield_10010":"Stagign"}}`)); err != nil {
+		t.Fatalf("expected projects without synced options to be left to Jira, got %v", err)
+	}
+}
@@ -0,0 +1,309 @@
+package jira
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/mcp"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+)
+
+// maxSuggestions caps the nearest matches offered for a mistyped option.
+const maxSuggestions = 3
+
+// maxListedOptions caps the allowed values listed when nothing is close to
+// a mistyped option.
+const maxListedOptions = 10
+
+// FieldOptionLookup returns the options synced for the select fields of a
+// project on the Jira site at siteURL, or none when they were not synced.
+type FieldOptionLookup func(ctx context.Context, siteURL, projectKey string) ([]models.JiraFieldOption, error)
+
+// WithFieldOptions makes the issue tools check select field values against
+// the options lookup returns before sending them to Jira.
+func (c *Client) WithFieldOptions(lookup FieldOptionLookup) *Client {
+	c.fieldOptions = lookup
+	return c
+}
+
+// FieldOptions returns the synced select field options of the project,
+// reading them from Jira when none were synced.
+func (c *Client) FieldOptions(ctx context.Context, projectKey string) ([]models.JiraFieldOption, error) {
+	if c.fieldOptions != nil {
+		if options, err := c.fieldOptions(ctx, c.BaseURL(), projectKey); err == nil && len(options) > 0 {
+			return options, nil
+		}
+	}
+	return c.ProjectFieldOptions(ctx, projectKey)
+}
+
+type createMetaField struct {
+	FieldID string `json:"fieldId"`
+	Name    string `json:"name"`
+	Schema  struct {
+		Type   string `json:"type"`
+		Items  string `json:"items"`
+		Custom string `json:"custom"`
+	} `json:"schema"`
+	AllowedValues []struct {
+		ID    string `json:"id"`
+		Value string `json:"value"`
+	} `json:"allowedValues"`
+}
+
+// ProjectFieldOptions returns the allowed values of the project's select,
+// radio button and checkbox custom fields across all of its issue types,
+// read from the create metadata.
+func (c *Client) ProjectFieldOptions(ctx context.Context, projectKey string) ([]models.JiraFieldOption, error) {
+	base := "/issue/createmeta/" + url.PathEscape(projectKey) + "/issuetypes"
+
+	var issueTypes []string
+	for startAt := 0; ; {
+		var page struct {
+			IssueTypes []struct {
+				ID string `json:"id"`
+			} `json:"issueTypes"`
+			Total int `json:"total"`
+		}
+		query := url.Values{"startAt": {strconv.Itoa(startAt)}, "maxResults": {"50"}}
+		if err := c.do(ctx, http.MethodGet, base, query, nil, &page); err != nil {
+			return nil, err
+		}
+		for _, t := range page.IssueTypes {
+			issueTypes = append(issueTypes, t.ID)
+		}
+		startAt += len(page.IssueTypes)
+		if len(page.IssueTypes) == 0 || startAt >= page.Total {
+			break
+		}
+	}
+
+	seen := map[string]bool{}
+	var options []models.JiraFieldOption
+	for _, issueType := range issueTypes {
+		for startAt := 0; ; {
+			var page struct {
+				Fields []createMetaField `json:"fields"`
+				Total  int               `json:"total"`
+			}
+			query := url.Values{"startAt": {strconv.Itoa(startAt)}, "maxResults": {"200"}}
+			if err := c.do(ctx, http.MethodGet, base+"/"+url.PathEscape(issueType), query, nil, &page); err != nil {
+				return nil, err
+			}
+			for _, f := range page.Fields {
+				single := f.Schema.Type == "option"
+				multi := f.Schema.Type == "array" && f.Schema.Items == "option"
+				if !strings.HasPrefix(f.FieldID, "customfield_") || !single && !multi {
+					continue
+				}
+				for _, v := range f.AllowedValues {
+					key := f.FieldID + "\x00" + v.ID
+					if v.ID == "" || seen[key] {
+						continue
+					}
+					seen[key] = true
+					options = append(options, models.JiraFieldOption{FieldID: f.FieldID, FieldName: f.Name, Multi: multi, OptionID: v.ID, Value: v.Value})
+				}
+			}
+			startAt += len(page.Fields)
+			if len(page.Fields) == 0 || startAt >= page.Total {
+				break
+			}
+		}
+	}
+	return options, nil
+}
+
+// selectField is a synced select field and its options.
+type selectField struct {
+	id, name string
+	multi    bool
+	options  []models.JiraFieldOption
+}
+
+// checkFieldOptions checks the values of the synced select fields among
+// fields, which may be keyed by field ID or name. Values may be given as
+// plain strings, {"value": ...} or {"id": ...}, or a list of those for
+// multi-select fields; they are rewritten to the {"value": ...} form with
+// the option's exact spelling. Unknown values are reported together with
+// the nearest options. Projects without synced options are left for Jira to
+// check, as are fields when the options cannot be loaded.
+func checkFieldOptions(ctx context.Context, c *Client, projectKey string, fields map[string]any) error {
+	if c.fieldOptions == nil || projectKey == "" || len(fields) == 0 {
+		return nil
+	}
+	synced, err := c.fieldOptions(ctx, c.BaseURL(), projectKey)
+	if err != nil || len(synced) == 0 {
+		return nil
+	}
+
+	byKey := map[string]*selectField{}
+	for _, o := range synced {
+		f := byKey[o.FieldID]
+		if f == nil {
+			f = &selectField{id: o.FieldID, name: o.FieldName, multi: o.Multi}
+			byKey[o.FieldID] = f
+			byKey[strings.ToLower(o.FieldName)] = f
+		}
+		f.options = append(f.options, o)
+	}
+
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	problems := map[string]string{}
+	for _, key := range keys {
+		value := fields[key]
+		f := byKey[key]
+		if f == nil {
+			f = byKey[strings.ToLower(key)]
+		}
+		if f == nil || value == nil {
+			continue
+		}
+
+		normalized, problem := f.normalize(value)
+		if problem != "" {
+			problems[key] = problem
+			continue
+		}
+		delete(fields, key)
+		fields[f.id] = normalized
+	}
+	if len(problems) > 0 {
+		return &mcp.ToolError{Code: mcp.ErrorFieldValidation, Message: "some field values are not allowed options", Fields: problems}
+	}
+	return nil
+}
+
+// normalize rewrites value to Jira's option format, or explains why it is
+// not an option of the field.
+func (f *selectField) normalize(value any) (any, string) {
+	values, isList := value.([]any)
+	if !isList {
+		values = []any{value}
+	} else if !f.multi {
+		return nil, fmt.Sprintf("%s takes a single option", f.name)
+	}
+
+	out := make([]any, 0, len(values))
+	for _, v := range values {
+		option, problem := f.match(v)
+		if problem != "" {
+			return nil, problem
+		}
+		out = append(out, map[string]string{"value": option.Value})
+	}
+	if f.multi {
+		return out, ""
+	}
+	return out[0], ""
+}
+
+// match finds the option v names, ignoring case.
+func (f *selectField) match(v any) (*models.JiraFieldOption, string) {
+	var text, id string
+	switch v := v.(type) {
+	case string:
+		text = v
+	case map[string]any:
+		text, _ = v["value"].(string)
+		id, _ = v["id"].(string)
+	}
+	if text == "" && id == "" {
+		return nil, fmt.Sprintf("%s takes option values such as %q", f.name, f.options[0].Value)
+	}
+
+	for i := range f.options {
+		o := &f.options[i]
+		if id != "" && o.OptionID == id || text != "" && strings.EqualFold(strings.TrimSpace(text), o.Value) {
+			return o, ""
+		}
+	}
+	if id != "" {
+		return nil, fmt.Sprintf("%s has no option with ID %s", f.name, id)
+	}
+
+	if near := f.nearest(text); len(near) > 0 {
+		return nil, fmt.Sprintf("%q is not an option of %s; did you mean %s?", text, f.name, quoteList(near, " or "))
+	}
+	allowed := make([]string, 0, maxListedOptions)
+	for _, o := range f.options {
+		if len(allowed) == maxListedOptions {
+			break
+		}
+		allowed = append(allowed, o.Value)
+	}
+	more := ""
+	if len(f.options) > maxListedOptions {
+		more = fmt.Sprintf(" and %d more", len(f.options)-maxListedOptions)
+	}
+	return nil, fmt.Sprintf("%q is not an option of %s; allowed values are %s%s", text, f.name, quoteList(allowed, ", "), more)
+}
+
+// nearest returns the options closest to text: those that contain it or
+// that are a few edits away, closest first.
+func (f *selectField) nearest(text string) []string {
+	text = strings.ToLower(strings.TrimSpace(text))
+	type candidate struct {
+		value    string
+		distance int
+	}
+	var candidates []candidate
+	for _, o := range f.options {
+		value := strings.ToLower(o.Value)
+		d := editDistance(text, value)
+		if len(text) >= 3 && strings.Contains(value, text) {
+			d = min(d, 1)
+		}
+		if d <= max(2, len([]rune(text))/3) {
+			candidates = append(candidates, candidate{o.Value, d})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].distance < candidates[j].distance })
+
+	var out []string
+	for _, c := range candidates {
+		if len(out) == maxSuggestions {
+			break
+		}
+		out = append(out, c.value)
+	}
+	return out
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+func quoteList(values []string, sep string) string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = strconv.Quote(v)
+	}
+	return strings.Join(quoted, sep)
+}
@@ -0,0 +1,156 @@
+package jira
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Issue is a Jira issue. Fields holds the requested fields as Jira returns
+// them, keyed by field ID (summary, status, customfield_10010, ...); rich
+// text fields are Atlassian Document Format objects.
+type Issue struct {
+	ID     string         `json:"id"`
+	Key    string         `json:"key"`
+	Self   string         `json:"self"`
+	Fields map[string]any `json:"fields"`
+}
+
+// IssueRef identifies an issue that was just created.
+type IssueRef struct {
+	ID   string `json:"id"`
+	Key  string `json:"key"`
+	Self string `json:"self"`
+}
+
+// Transition is a workflow transition available on an issue.
+type Transition struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	To   struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"to"`
+}
+
+// issuePath returns the path of an issue, escaping its ID or key.
+func issuePath(idOrKey string) string {
+	return "/issue/" + url.PathEscape(idOrKey)
+}
+
+// GetIssue returns the issue with the given ID or key. fields limits the
+// fields returned; nil returns Jira's default (all navigable fields).
+func (c *Client) GetIssue(ctx context.Context, idOrKey string, fields []string) (*Issue, error) {
+	query := url.Values{}
+	if len(fields) > 0 {
+		query.Set("fields", strings.Join(fields, ","))
+	}
+	var issue Issue
+	if err := c.do(ctx, http.MethodGet, issuePath(idOrKey), query, nil, &issue); err != nil {
+		return nil, err
+	}
+	return &issue, nil
+}
+
+// CreateIssue creates an issue from fields, which must include at least
+// project ({"key": ...}), issuetype ({"name": ...}) and summary.
+func (c *Client) CreateIssue(ctx context.Context, fields map[string]any) (*IssueRef, error) {
+	var ref IssueRef
+	if err := c.do(ctx, http.MethodPost, "/issue", nil, map[string]any{"fields": fields}, &ref); err != nil {
+		return nil, err
+	}
+	return &ref, nil
+}
+
+// UpdateIssue sets the given fields of an issue.
+func (c *Client) UpdateIssue(ctx context.Context, idOrKey string, fields map[string]any) error {
+	return c.do(ctx, http.MethodPut, issuePath(idOrKey), nil, map[string]any{"fields": fields}, nil)
+}
+
+// DeleteIssue deletes an issue. Jira refuses to delete an issue with
+// subtasks unless deleteSubtasks is set.
+func (c *Client) DeleteIssue(ctx context.Context, idOrKey string, deleteSubtasks bool) error {
+	query := url.Values{}
+	if deleteSubtasks {
+		query.Set("deleteSubtasks", "true")
+	}
+	return c.do(ctx, http.MethodDelete, issuePath(idOrKey), query, nil, nil)
+}
+
+// ListTransitions returns the transitions the user can make on an issue.
+func (c *Client) ListTransitions(ctx context.Context, idOrKey string) ([]Transition, error) {
+	var resp struct {
+		Transitions []Transition `json:"transitions"`
+	}
+	if err := c.do(ctx, http.MethodGet, issuePath(idOrKey)+"/transitions", nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Transitions, nil
+}
+
+// TransitionIssue moves an issue through the transition with transitionID.
+func (c *Client) TransitionIssue(ctx context.Context, idOrKey, transitionID string) error {
+	body := map[string]any{"transition": map[string]string{"id": transitionID}}
+	return c.do(ctx, http.MethodPost, issuePath(idOrKey)+"/transitions", nil, body, nil)
+}
+
+// Comment is a comment on an issue. Body is an Atlassian Document Format
+// object.
+type Comment struct {
+	ID     string `json:"id"`
+	Body   any    `json:"body"`
+	Author struct {
+		AccountID   string `json:"accountId"`
+		DisplayName string `json:"displayName"`
+	} `json:"author"`
+	Created string `json:"created"`
+}
+
+// AddComment adds a plain text comment to an issue.
+func (c *Client) AddComment(ctx context.Context, idOrKey, text string) (*Comment, error) {
+	var comment Comment
+	if err := c.do(ctx, http.MethodPost, issuePath(idOrKey)+"/comment", nil, map[string]any{"body": textDocument(text)}, &comment); err != nil {
+		return nil, err
+	}
+	return &comment, nil
+}
+
+// DocumentText returns the text of a rich text value: an Atlassian Document
+// Format object, with one line per paragraph, or a plain string as
+// delivered by some webhooks.
+func DocumentText(doc any) string {
+	var b strings.Builder
+	var walk func(node any)
+	walk = func(node any) {
+		n, ok := node.(map[string]any)
+		if !ok {
+			return
+		}
+		switch n["type"] {
+		case "text":
+			text, _ := n["text"].(string)
+			b.WriteString(text)
+		case "hardBreak":
+			b.WriteString("\n")
+		case "mention", "emoji":
+			attrs, _ := n["attrs"].(map[string]any)
+			text, _ := attrs["text"].(string)
+			b.WriteString(text)
+		}
+		children, _ := n["content"].([]any)
+		for _, child := range children {
+			walk(child)
+		}
+		switch n["type"] {
+		case "paragraph", "heading", "codeBlock":
+			b.WriteString("\n")
+		}
+	}
+
+	if text, ok := doc.(string); ok {
+		return strings.TrimSpace(text)
+	}
+	walk(doc)
+	return strings.TrimSpace(b.String())
+}
@@ -0,0 +1,535 @@
+package jira
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/mcp"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+)
+
+// ClientResolver returns a client for the tenant an MCP request is served
+// for.
+type ClientResolver func(ctx context.Context) (*Client, error)
+
+// defaultSearchFields are returned by the search tools unless the caller
+// asks for others.
+var defaultSearchFields = []string{"summary", "status", "assignee", "issuetype", "priority", "updated"}
+
+// MCPTools returns the Jira tools served over MCP. Issue CRUD tools are
+// jira_*_issue and the Jira Service Management tools are jsm_*; the others
+// follow the Cloudflare worker's tools so prompts work with either.
+// Arguments are validated against each InputSchema before a tool runs.
+func MCPTools(clientFor ClientResolver) []mcp.Tool {
+	return append([]mcp.Tool{
+		{
+			Name:        "jira_get_issue",
+			Description: "Get a Jira issue by key (e.g. PROJ-123), optionally limited to some fields.",
+			InputSchema: json.RawMessage(`{"type":"object","properties":{
+				"issueKey":{"type":"string","minLength":1,"description":"Issue key or ID, e.g. PROJ-123."},
+				"fields":{"type":"array","items":{"type":"string"},"description":"Fields to return; all navigable fields when omitted."}
+			},"required":["issueKey"],"additionalProperties":false}`),
+			Handler: withClient(clientFor, func(ctx context.Context, c *Client, args struct {
+				IssueKey string   `json:"issueKey"`
+				Fields   []string `json:"fields"`
+			}) (any, error) {
+				if err := required("issueKey", args.IssueKey); err != nil {
+					return nil, err
+				}
+				return c.GetIssue(ctx, args.IssueKey, args.Fields)
+			}),
+		},
+		{
+			Name:        "jira_get_issue_history",
+			Description: "Get the change history of a Jira issue: status changes, field edits and assignee changes with who made them and when, oldest first. Pass fields (e.g. [\"duedate\"]) to answer questions about particular fields, and nextStartAt from a previous result to get the next page; filtered pages may hold fewer changes than maxResults.",
+			InputSchema: json.RawMessage(`{"type":"object","properties":{
+				"issueKey":{"type":"string","minLength":1,"description":"Issue key or ID, e.g. PROJ-123."},
+				"fields":{"type":"array","items":{"type":"string","minLength":1},"description":"Only changes to these fields, by name or ID (status, assignee, duedate, customfield_10010, ...)."},
+				"startAt":{"type":"integer","minimum":0,"description":"Index of the first changelog entry, 0 by default."},
+				"maxResults":{"type":"integer","minimum":1,"maximum":100,"description":"Page size, default 50."}
+			},"required":["issueKey"],"additionalProperties":false}`),
+			Handler: withClient(clientFor, func(ctx context.Context, c *Client, args struct {
+				IssueKey   string   `json:"issueKey"`
+				Fields     []string `json:"fields"`
+				StartAt    int      `json:"startAt"`
+				MaxResults int      `json:"maxResults"`
+			}) (any, error) {
+				if err := required("issueKey", args.IssueKey); err != nil {
+					return nil, err
+				}
+				if args.MaxResults <= 0 || args.MaxResults > 100 {
+					args.MaxResults = 50
+				}
+				page, err := c.IssueChangelog(ctx, args.IssueKey, args.StartAt, args.MaxResults)
+				if err != nil {
+					return nil, err
+				}
+				history := &IssueHistory{IssueKey: args.IssueKey, StartAt: page.StartAt, Total: page.Total}
+				if next := page.StartAt + len(page.Values); !page.IsLast && next < page.Total {
+					history.NextStartAt = &next
+				}
+				page.FilterItems(args.Fields)
+				history.Changes = page.Values
+				if history.Changes == nil {
+					history.Changes = []ChangelogEntry{}
+				}
+				return history, nil
+			}),
+		},
+		{
+			Name:        "searchJiraIssues",
+			Description: "Search for Jira issues using JQL. Pass nextPageToken from a previous result to get the next page.",
+			InputSchema: json.RawMessage(`{"type":"object","properties":{
+				"jql":{"type":"string","description":"JQL query, e.g. 'project = TEST AND status = Open'."},
+				"fields":{"type":"array","items":{"type":"string"},"description":"Fields to return for each issue."},
+				"maxResults":{"type":"integer","minimum":1,"maximum":100,"description":"Page size, default 50."},
+				"nextPageToken":{"type":"string"}
+			},"required":["jql"]}`),
+			Handler: withClient(clientFor, func(ctx context.Context, c *Client, args struct {
+				JQL           string   `json:"jql"`
+				Fields        []string `json:"fields"`
+				MaxResults    int      `json:"maxResults"`
+				NextPageToken string   `json:"nextPageToken"`
+			}) (any, error) {
+				if err := required("jql", args.JQL); err != nil {
+					return nil, err
+				}
+				req := searchRequest(args.JQL, args.Fields, args.MaxResults)
+				req.NextPageToken = args.NextPageToken
+				return c.Search(ctx, req)
+			}),
+		},
+		{
+			Name:        "jira_create_issue",
+			Description: "Create a Jira issue in a project. The project, issue type, labels and components fall back to the tenant's issue defaults when left out.",
+			InputSchema: json.RawMessage(`{"type":"object","properties":{
+				"projectKey":{"type":"string","minLength":1,"description":"Project key; the tenant's default project when omitted."},
+				"issueType":{"type":"string","minLength":1,"description":"Issue type name, e.g. Task or Bug; the tenant's default when omitted."},
+				"summary":{"type":"string","minLength":1},
+				"description":{"type":"string","description":"Plain text description."},
+				"assignee":{"type":"string","description":"Who to assign: a name, email, account ID or \"me\"."},
+				"dueDate":{"type":"string","description":"YYYY-MM-DD or a phrase like \"next Monday\", \"in 2 weeks\" or \"end of sprint\"."},
+				"timeZone":{"type":"string","description":"IANA time zone relative dates are counted in, UTC by default."},
+				"fields":{"type":"object","description":"Additional fields by ID, in Jira's format. labels and components default to the tenant's when not set here. Select fields may be keyed by name and given option values as plain strings."}
+			},"required":["summary"],"additionalProperties":false}`),
+			Handler: withClient(clientFor, func(ctx context.Context, c *Client, args struct {
+				ProjectKey  string         `json:"projectKey"`
+				IssueType   string         `json:"issueType"`
+				Summary     string         `json:"summary"`
+				Description string         `json:"description"`
+				Assignee    string         `json:"assignee"`
+				DueDate     string         `json:"dueDate"`
+				TimeZone    string         `json:"timeZone"`
+				Fields      map[string]any `json:"fields"`
+			}) (any, error) {
+				fields := map[string]any{}
+				for k, v := range args.Fields {
+					fields[k] = v
+				}
+				applyIssueDefaults(c.IssueDefaults(), &args.ProjectKey, &args.IssueType, fields)
+				if err := errors.Join(required("projectKey", args.ProjectKey), required("issueType", args.IssueType), required("summary", args.Summary)); err != nil {
+					return nil, err
+				}
+				if err := requireProjectPermissions(ctx, c, args.ProjectKey, issuePermissions(PermissionCreateIssues, args.Assignee)...); err != nil {
+					return nil, err
+				}
+				if err := resolveIssueArgs(ctx, c, args.ProjectKey, args.Assignee, args.DueDate, args.TimeZone, fields); err != nil {
+					return nil, err
+				}
+				if err := checkFieldOptions(ctx, c, args.ProjectKey, fields); err != nil {
+					return nil, err
+				}
+				fields["project"] = map[string]string{"key": args.ProjectKey}
+				fields["issuetype"] = map[string]string{"name": args.IssueType}
+				fields["summary"] = args.Summary
+				if args.Description != "" {
+					fields["description"] = textDocument(args.Description)
+				}
+				return c.CreateIssue(ctx, fields)
+			}),
+		},
+		{
+			Name:        "jira_update_issue",
+			Description: "Update fields of a Jira issue. Set summary, description, assignee or due date directly, or any field by ID in fields.",
+			InputSchema: json.RawMessage(`{"type":"object","properties":{
+				"issueKey":{"type":"string","minLength":1},
+				"summary":{"type":"string"},
+				"description":{"type":"string","description":"Plain text description."},
+				"assignee":{"type":"string","description":"Who to assign: a name, email, account ID or \"me\"."},
+				"dueDate":{"type":"string","description":"YYYY-MM-DD or a phrase like \"next Monday\", \"in 2 weeks\" or \"end of sprint\"."},
+				"timeZone":{"type":"string","description":"IANA time zone relative dates are counted in, UTC by default."},
+				"fields":{"type":"object","description":"Fields by ID, in Jira's format. Select fields may be keyed by name and given option values as plain strings."}
+			},"required":["issueKey"],"additionalProperties":false}`),
+			Handler: withClient(clientFor, func(ctx context.Context, c *Client, args struct {
+				IssueKey    string         `json:"issueKey"`
+				Summary     string         `json:"summary"`
+				Description string         `json:"description"`
+				Assignee    string         `json:"assignee"`
+				DueDate     string         `json:"dueDate"`
+				TimeZone    string         `json:"timeZone"`
+				Fields      map[string]any `json:"fields"`
+			}) (any, error) {
+				if err := required("issueKey", args.IssueKey); err != nil {
+					return nil, err
+				}
+				fields := map[string]any{}
+				for k, v := range args.Fields {
+					fields[k] = v
+				}
+				if args.Summary != "" {
+					fields["summary"] = args.Summary
+				}
+				if args.Description != "" {
+					fields["description"] = textDocument(args.Description)
+				}
+				projectKey := issueProject(args.IssueKey)
+				if err := resolveIssueArgs(ctx, c, projectKey, args.Assignee, args.DueDate, args.TimeZone, fields); err != nil {
+					return nil, err
+				}
+				if err := checkFieldOptions(ctx, c, projectKey, fields); err != nil {
+					return nil, err
+				}
+				if len(fields) == 0 {
+					return nil, mcp.NewToolError(mcp.ErrorFieldValidation, "nothing to update: pass summary, description, assignee, dueDate or fields")
+				}
+				if err := requireProjectPermissions(ctx, c, projectKey, issuePermissions(PermissionEditIssues, args.Assignee)...); err != nil {
+					return nil, err
+				}
+				if err := c.UpdateIssue(ctx, args.IssueKey, fields); err != nil {
+					return nil, err
+				}
+				return fmt.Sprintf("Updated %s.", args.IssueKey), nil
+			}),
+		},
+		{
+			Name:        "jira_delete_issue",
+			Description: "Delete a Jira issue. This cannot be undone. Issues with subtasks are only deleted when deleteSubtasks is true.",
+			InputSchema: json.RawMessage(`{"type":"object","properties":{
+				"issueKey":{"type":"string","minLength":1},
+				"deleteSubtasks":{"type":"boolean","description":"Also delete the issue's subtasks."}
+			},"required":["issueKey"],"additionalProperties":false}`),
+			Handler: withClient(clientFor, func(ctx context.Context, c *Client, args struct {
+				IssueKey       string `json:"issueKey"`
+				DeleteSubtasks bool   `json:"deleteSubtasks"`
+			}) (any, error) {
+				if err := required("issueKey", args.IssueKey); err != nil {
+					return nil, err
+				}
+				if err := requireProjectPermissions(ctx, c, issueProject(args.IssueKey), PermissionDeleteIssues); err != nil {
+					return nil, err
+				}
+				if err := c.DeleteIssue(ctx, args.IssueKey, args.DeleteSubtasks); err != nil {
+					return nil, err
+				}
+				return fmt.Sprintf("Deleted %s.", args.IssueKey), nil
+			}),
+		},
+		{
+			Name:        "getJiraIssueTransitions",
+			Description: "List the workflow transitions available on a Jira issue.",
+			InputSchema: json.RawMessage(`{"type":"object","properties":{"issueKey":{"type":"string"}},"required":["issueKey"]}`),
+			Handler: withClient(clientFor, func(ctx context.Context, c *Client, args struct {
+				IssueKey string `json:"issueKey"`
+			}) (any, error) {
+				if err := required("issueKey", args.IssueKey); err != nil {
+					return nil, err
+				}
+				return c.ListTransitions(ctx, args.IssueKey)
+			}),
+		},
+		{
+			Name:        "transitionJiraIssue",
+			Description: "Move a Jira issue through a workflow transition. Get transition IDs from getJiraIssueTransitions.",
+			InputSchema: json.RawMessage(`{"type":"object","properties":{
+				"issueKey":{"type":"string"},
+				"transitionId":{"type":"string"}
+			},"required":["issueKey","transitionId"]}`),
+			Handler: withClient(clientFor, func(ctx context.Context, c *Client, args struct {
+				IssueKey     string `json:"issueKey"`
+				TransitionID string `json:"transitionId"`
+			}) (any, error) {
+				if err := errors.Join(required("issueKey", args.IssueKey), required("transitionId", args.TransitionID)); err != nil {
+					return nil, err
+				}
+				if err := requireProjectPermissions(ctx, c, issueProject(args.IssueKey), PermissionTransitionIssues); err != nil {
+					return nil, err
+				}
+				if err := c.TransitionIssue(ctx, args.IssueKey, args.TransitionID); err != nil {
+					return nil, err
+				}
+				return fmt.Sprintf("Transitioned %s.", args.IssueKey), nil
+			}),
+		},
+		{
+			Name:        "getProjects",
+			Description: "List Jira projects visible to the user, optionally filtered by key or name.",
+			InputSchema: json.RawMessage(`{"type":"object","properties":{
+				"query":{"type":"string"},
+				"startAt":{"type":"integer","minimum":0},
+				"maxResults":{"type":"integer","minimum":1,"maximum":100}
+			}}`),
+			Handler: withClient(clientFor, func(ctx context.Context, c *Client, args struct {
+				Query      string `json:"query"`
+				StartAt    int    `json:"startAt"`
+				MaxResults int    `json:"maxResults"`
+			}) (any, error) {
+				return c.ListProjects(ctx, args.Query, args.StartAt, args.MaxResults)
+			}),
+		},
+		{
+			Name:        "getJiraProject",
+			Description: "Get a Jira project by key or ID.",
+			InputSchema: json.RawMessage(`{"type":"object","properties":{"projectKey":{"type":"string"}},"required":["projectKey"]}`),
+			Handler: withClient(clientFor, func(ctx context.Context, c *Client, args struct {
+				ProjectKey string `json:"projectKey"`
+			}) (any, error) {
+				if err := required("projectKey", args.ProjectKey); err != nil {
+					return nil, err
+				}
+				return c.GetProject(ctx, args.ProjectKey)
+			}),
+		},
+		{
+			Name:        "jira_resolve_user",
+			Description: "Find the Jira account for a name, email or \"me\", e.g. to assign an issue to \"Maria\". Returns the account, or the candidates when several users match.",
+			InputSchema: json.RawMessage(`{"type":"object","properties":{
+				"query":{"type":"string","minLength":1,"description":"Name, email, account ID or \"me\"."}
+			},"required":["query"],"additionalProperties":false}`),
+			Handler: withClient(clientFor, func(ctx context.Context, c *Client, args struct {
+				Query string `json:"query"`
+			}) (any, error) {
+				if err := required("query", args.Query); err != nil {
+					return nil, err
+				}
+				resolution, err := c.ResolveUser(ctx, args.Query)
+				if errors.Is(err, ErrUnresolved) {
+					return nil, &mcp.ToolError{Code: mcp.ErrorNotFound, Message: unresolvedMessage(err), Err: err}
+				}
+				return resolution, err
+			}),
+		},
+		{
+			Name:        "jira_resolve_date",
+			Description: "Turn a date phrase such as \"tomorrow\", \"next Monday\", \"in 2 weeks\", \"end of month\" or \"end of sprint\" into a Jira date (YYYY-MM-DD). The end of sprint is read from the project's active sprint; several active sprints are returned as candidates.",
+			InputSchema: json.RawMessage(`{"type":"object","properties":{
+				"phrase":{"type":"string","minLength":1},
+				"projectKey":{"type":"string","description":"Project whose sprint \"end of sprint\" refers to; the tenant's default project when omitted."},
+				"timeZone":{"type":"string","description":"IANA time zone the phrase is counted in, UTC by default."}
+			},"required":["phrase"],"additionalProperties":false}`),
+			Handler: withClient(clientFor, func(ctx context.Context, c *Client, args struct {
+				Phrase     string `json:"phrase"`
+				ProjectKey string `json:"projectKey"`
+				TimeZone   string `json:"timeZone"`
+			}) (any, error) {
+				if err := required("phrase", args.Phrase); err != nil {
+					return nil, err
+				}
+				now, err := nowIn(args.TimeZone)
+				if err != nil {
+					return nil, err
+				}
+				if args.ProjectKey == "" && c.IssueDefaults() != nil {
+					args.ProjectKey = c.IssueDefaults().ProjectKey
+				}
+				resolution, err := c.ResolveDate(ctx, args.Phrase, args.ProjectKey, now)
+				if errors.Is(err, ErrUnresolved) {
+					return nil, &mcp.ToolError{Code: mcp.ErrorFieldValidation, Message: unresolvedMessage(err), Fields: map[string]string{"phrase": unresolvedMessage(err)}, Err: err}
+				}
+				return resolution, err
+			}),
+		},
+		{
+			Name:        "jira_list_field_options",
+			Description: "List the allowed values of a project's select and checkbox custom fields, to fill fields in jira_create_issue and jira_update_issue.",
+			InputSchema: json.RawMessage(`{"type":"object","properties":{
+				"projectKey":{"type":"string","description":"Project key; the tenant's default project when omitted."},
+				"field":{"type":"string","description":"Only list the options of this field, by ID or name."}
+			},"additionalProperties":false}`),
+			Handler: withClient(clientFor, func(ctx context.Context, c *Client, args struct {
+				ProjectKey string `json:"projectKey"`
+				Field      string `json:"field"`
+			}) (any, error) {
+				if args.ProjectKey == "" && c.IssueDefaults() != nil {
+					args.ProjectKey = c.IssueDefaults().ProjectKey
+				}
+				if err := required("projectKey", args.ProjectKey); err != nil {
+					return nil, err
+				}
+				options, err := c.FieldOptions(ctx, args.ProjectKey)
+				if err != nil {
+					return nil, err
+				}
+				out := []models.JiraFieldOption{}
+				for _, o := range options {
+					if args.Field == "" || o.FieldID == args.Field || strings.EqualFold(o.FieldName, args.Field) {
+						out = append(out, o)
+					}
+				}
+				return map[string]any{"projectKey": args.ProjectKey, "options": out}, nil
+			}),
+		},
+	}, serviceDeskTools(clientFor)...)
+}
+
+// withClient adapts a typed tool function to an mcp.ToolHandler: it decodes
+// the arguments into A and resolves the tenant's Jira client.
+func withClient[A any](clientFor ClientResolver, fn func(ctx context.Context, c *Client, args A) (any, error)) mcp.ToolHandler {
+	return func(ctx context.Context, raw json.RawMessage) (any, error) {
+		var args A
+		if err := json.Unmarshal(raw, &args); err != nil {
+			return nil, &mcp.ToolError{Code: mcp.ErrorFieldValidation, Message: "invalid arguments: " + err.Error(), Err: err}
+		}
+		c, err := clientFor(ctx)
+		if err != nil {
+			return nil, err
+		}
+		out, err := fn(ctx, c, args)
+		if err != nil {
+			return nil, toolError(err)
+		}
+		return out, nil
+	}
+}
+
+// toolError classifies a Jira API failure for MCP clients. Other errors are
+// returned unchanged.
+func toolError(err error) error {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return err
+	}
+	toolErr := &mcp.ToolError{Message: apiErr.Error(), Err: err}
+	switch {
+	case IsUnauthorized(err):
+		toolErr.Code = mcp.ErrorAuthExpired
+	case apiErr.StatusCode == http.StatusTooManyRequests:
+		toolErr.Code = mcp.ErrorJiraRateLimited
+		toolErr.RetryAfter = apiErr.RetryAfterDelay
+	case IsNotFound(err):
+		toolErr.Code = mcp.ErrorNotFound
+	case apiErr.StatusCode == http.StatusBadRequest && len(apiErr.FieldErrors) > 0:
+		toolErr.Code = mcp.ErrorFieldValidation
+		toolErr.Fields = apiErr.FieldErrors
+		// The fields are listed separately.
+		toolErr.Message = (&APIError{StatusCode: apiErr.StatusCode, Messages: apiErr.Messages}).Error()
+	default:
+		return err
+	}
+	return toolErr
+}
+
+// applyIssueDefaults fills the project, issue type, labels and components
+// of a new issue from the tenant's defaults where the caller left them out.
+func applyIssueDefaults(defaults *models.JiraIssueDefaults, projectKey, issueType *string, fields map[string]any) {
+	if defaults == nil {
+		return
+	}
+	if strings.TrimSpace(*projectKey) == "" {
+		*projectKey = defaults.ProjectKey
+	}
+	if strings.TrimSpace(*issueType) == "" {
+		*issueType = defaults.IssueType
+	}
+	if _, ok := fields["labels"]; !ok && len(defaults.Labels) > 0 {
+		fields["labels"] = defaults.Labels
+	}
+	if _, ok := fields["components"]; !ok && len(defaults.Components) > 0 {
+		components := make([]map[string]string, 0, len(defaults.Components))
+		for _, name := range defaults.Components {
+			components = append(components, map[string]string{"name": name})
+		}
+		fields["components"] = components
+	}
+}
+
+// resolveIssueArgs sets the assignee and duedate fields from the phrases a
+// tool was given. projectKey is the project whose sprint "end of sprint"
+// refers to. Phrases that match nothing or several users or sprints are
+// reported together as a field validation error listing the candidates.
+func resolveIssueArgs(ctx context.Context, c *Client, projectKey, assignee, dueDate, timeZone string, fields map[string]any) error {
+	problems := map[string]string{}
+	if strings.TrimSpace(assignee) != "" {
+		resolution, err := c.ResolveUser(ctx, assignee)
+		switch {
+		case errors.Is(err, ErrUnresolved):
+			problems["assignee"] = unresolvedMessage(err)
+		case err != nil:
+			return err
+		case resolution.Account == nil:
+			names := make([]string, 0, len(resolution.Candidates))
+			for _, account := range resolution.Candidates {
+				names = append(names, fmt.Sprintf("%s (%s)", account.DisplayName, account.AccountID))
+			}
+			problems["assignee"] = fmt.Sprintf("%q matches several users: %s; pass one of the account IDs", assignee, strings.Join(names, ", "))
+		default:
+			fields["assignee"] = map[string]string{"accountId": resolution.Account.AccountID}
+		}
+	}
+	if strings.TrimSpace(dueDate) != "" {
+		now, err := nowIn(timeZone)
+		if err != nil {
+			return err
+		}
+		resolution, err := c.ResolveDate(ctx, dueDate, projectKey, now)
+		switch {
+		case errors.Is(err, ErrUnresolved):
+			problems["dueDate"] = unresolvedMessage(err)
+		case err != nil:
+			return err
+		case resolution.Date == "":
+			names := make([]string, 0, len(resolution.Candidates))
+			for _, sprint := range resolution.Candidates {
+				names = append(names, fmt.Sprintf("%s ends %s", sprint.Name, sprint.EndDate.In(now.Location()).Format(DateLayout)))
+			}
+			problems["dueDate"] = fmt.Sprintf("%q matches several active sprints: %s; pass the date", dueDate, strings.Join(names, ", "))
+		default:
+			fields["duedate"] = resolution.Date
+		}
+	}
+	if len(problems) > 0 {
+		return &mcp.ToolError{Code: mcp.ErrorFieldValidation, Message: "could not resolve the assignee or due date", Fields: problems}
+	}
+	return nil
+}
+
+// nowIn returns the current time in the IANA time zone name, UTC when name
+// is empty.
+func nowIn(name string) (time.Time, error) {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return time.Time{}, &mcp.ToolError{Code: mcp.ErrorFieldValidation, Message: fmt.Sprintf("unknown time zone %q", name), Fields: map[string]string{"timeZone": "not an IANA time zone"}, Err: err}
+	}
+	return time.Now().In(loc), nil
+}
+
+// unresolvedMessage returns the explanation of an ErrUnresolved error
+// without the sentinel's prefix.
+func unresolvedMessage(err error) string {
+	return strings.TrimPrefix(err.Error(), ErrUnresolved.Error()+": ")
+}
+
+func required(name, value string) error {
+	if strings.TrimSpace(value) == "" {
+		return &mcp.ToolError{Code: mcp.ErrorFieldValidation, Message: name + " is required"}
+	}
+	return nil
+}
+
+// textDocument wraps plain text in an Atlassian Document Format document,
+// one paragraph per line.
+func textDocument(text string) map[string]any {
+	var content []any
+	for _, line := range strings.Split(strings.TrimSpace(text), "\n") {
+		paragraph := map[string]any{"type": "paragraph"}
+		if line = strings.TrimRight(line, "\r"); line != "" {
+			paragraph["content"] = []any{map[string]any{"type": "text", "text": line}}
+		}
+		content = append(content, paragraph)
+	}
+	return map[string]any{"type": "doc", "version": 1, "content": content}
+}
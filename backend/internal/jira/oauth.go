@@ -0,0 +1,252 @@
+package jira
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+)
+
+// OAuthScopes are requested when a user connects Jira through Atlassian
+// OAuth; offline_access grants the refresh token.
+var OAuthScopes = []string{"read:jira-work", "write:jira-work", "read:jira-user", "read:me", "offline_access"}
+
+// ErrInvalidGrant is returned when Atlassian rejects an authorization code
+// or refresh token; the user has to connect again.
+var ErrInvalidGrant = errors.New("jira: oauth grant is invalid or revoked")
+
+// OAuth runs the Atlassian OAuth 2.0 (3LO) flow for an app registered in
+// the Atlassian developer console. The endpoint URLs default to
+// Atlassian's and can be pointed elsewhere in tests.
+type OAuth struct {
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+
+	AuthURL  string
+	TokenURL string
+	APIURL   string
+
+	HTTPClient *http.Client
+}
+
+// NewOAuth configures the flow for an app; redirectURL must match the
+// callback URL registered for it.
+func NewOAuth(clientID, clientSecret, redirectURL string) *OAuth {
+	return &OAuth{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		RedirectURL:  redirectURL,
+		AuthURL:      "https://auth.atlassian.com/authorize",
+		TokenURL:     "https://auth.atlassian.com/oauth/token",
+		APIURL:       "https://api.atlassian.com",
+		HTTPClient:   &http.Client{Timeout: defaultTimeout},
+	}
+}
+
+// OAuthToken is an Atlassian access token with the refresh token that
+// replaces it. Atlassian rotates refresh tokens, so each refresh returns a
+// new one that must be stored.
+type OAuthToken struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresAt    time.Time
+	Scope        string
+}
+
+// Site is a Jira site the user granted the app access to.
+type Site struct {
+	// ID is the site's cloud ID, used to reach it through the API gateway.
+	ID     string   `json:"id"`
+	URL    string   `json:"url"`
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+}
+
+// AuthCodeURL returns the consent page to send the user to.
+func (o *OAuth) AuthCodeURL(state string) string {
+	q := url.Values{
+		"audience":      {"api.atlassian.com"},
+		"client_id":     {o.ClientID},
+		"scope":         {strings.Join(OAuthScopes, " ")},
+		"redirect_uri":  {o.RedirectURL},
+		"state":         {state},
+		"response_type": {"code"},
+		"prompt":        {"consent"},
+	}
+	return o.AuthURL + "?" + q.Encode()
+}
+
+// Exchange trades the authorization code from the callback for tokens.
+func (o *OAuth) Exchange(ctx context.Context, code string) (*OAuthToken, error) {
+	return o.token(ctx, map[string]string{
+		"grant_type":    "authorization_code",
+		"client_id":     o.ClientID,
+		"client_secret": o.ClientSecret,
+		"code":          code,
+		"redirect_uri":  o.RedirectURL,
+	})
+}
+
+// Refresh returns a new access token and refresh token for refreshToken.
+func (o *OAuth) Refresh(ctx context.Context, refreshToken string) (*OAuthToken, error) {
+	return o.token(ctx, map[string]string{
+		"grant_type":    "refresh_token",
+		"client_id":     o.ClientID,
+		"client_secret": o.ClientSecret,
+		"refresh_token": refreshToken,
+	})
+}
+
+func (o *OAuth) token(ctx context.Context, body map[string]string) (*OAuthToken, error) {
+	var out struct {
+		AccessToken  string `json:"access_token"`
+		RefreshToken string `json:"refresh_token"`
+		ExpiresIn    int    `json:"expires_in"`
+		Scope        string `json:"scope"`
+	}
+	if err := o.call(ctx, http.MethodPost, o.TokenURL, "", body, &out); err != nil {
+		return nil, err
+	}
+	if out.AccessToken == "" {
+		return nil, errors.New("jira: oauth token response has no access token")
+	}
+	return &OAuthToken{
+		AccessToken:  out.AccessToken,
+		RefreshToken: out.RefreshToken,
+		ExpiresAt:    time.Now().Add(time.Duration(out.ExpiresIn) * time.Second),
+		Scope:        out.Scope,
+	}, nil
+}
+
+// AccessibleResources lists the Jira sites accessToken can reach.
+func (o *OAuth) AccessibleResources(ctx context.Context, accessToken string) ([]Site, error) {
+	var sites []Site
+	if err := o.call(ctx, http.MethodGet, o.APIURL+"/oauth/token/accessible-resources", accessToken, nil, &sites); err != nil {
+		return nil, err
+	}
+	return sites, nil
+}
+
+// Email returns the email address of the Atlassian account that granted
+// accessToken.
+func (o *OAuth) Email(ctx context.Context, accessToken string) (string, error) {
+	var me struct {
+		Email string `json:"email"`
+	}
+	if err := o.call(ctx, http.MethodGet, o.APIURL+"/me", accessToken, nil, &me); err != nil {
+		return "", err
+	}
+	return me.Email, nil
+}
+
+func (o *OAuth) call(ctx context.Context, method, target, accessToken string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("jira: encode oauth request: %w", err)
+		}
+		reader = strings.NewReader(string(payload))
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return fmt.Errorf("jira: build oauth request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if accessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+	}
+
+	resp, err := o.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("jira: oauth request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBytes))
+	if err != nil {
+		return fmt.Errorf("jira: read oauth response: %w", err)
+	}
+
+	if resp.StatusCode >= 400 {
+		var oauthErr struct {
+			Error       string `json:"error"`
+			Description string `json:"error_description"`
+		}
+		json.Unmarshal(data, &oauthErr)
+		if oauthErr.Error == "invalid_grant" {
+			return fmt.Errorf("%w: %s", ErrInvalidGrant, oauthErr.Description)
+		}
+		return fmt.Errorf("jira: oauth request returned %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("jira: parse oauth response: %w", err)
+	}
+	return nil
+}
+
+// OAuthTokenStore loads and saves users' Atlassian OAuth tokens;
+// *store.Store implements it.
+type OAuthTokenStore interface {
+	GetJiraOAuthToken(ctx context.Context, userID int64) (*models.IntegrationToken, error)
+	UpdateJiraOAuthToken(ctx context.Context, id int64, accessToken, refreshToken string, expiresAt time.Time) error
+}
+
+// Refresher returns a TokenRefresher that renews access tokens through o
+// and saves them in tokens. Refreshes are serialized, and a token already
+// replaced since Jira rejected it is returned without another refresh, as
+// Atlassian rotates refresh tokens and a second use of the old one fails. A
+// revoked grant loses its refresh token, as in the background refresher.
+func (o *OAuth) Refresher(tokens OAuthTokenStore) TokenRefresher {
+	var mu sync.Mutex
+	return func(ctx context.Context, userID int64, rejected string) (string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		t, err := tokens.GetJiraOAuthToken(ctx, userID)
+		if err != nil {
+			return "", fmt.Errorf("jira: load oauth token: %w", err)
+		}
+		if t.AccessToken != rejected && t.ExpiresAt != nil && time.Now().Before(*t.ExpiresAt) {
+			return t.AccessToken, nil
+		}
+		if t.RefreshToken == nil {
+			return "", ErrInvalidGrant
+		}
+
+		token, err := o.Refresh(ctx, *t.RefreshToken)
+		if errors.Is(err, ErrInvalidGrant) {
+			expiresAt := time.Now()
+			if t.ExpiresAt != nil {
+				expiresAt = *t.ExpiresAt
+			}
+			if clearErr := tokens.UpdateJiraOAuthToken(ctx, t.ID, "", "", expiresAt); clearErr != nil {
+				return "", errors.Join(err, fmt.Errorf("jira: clear refresh token: %w", clearErr))
+			}
+			return "", err
+		}
+		if err != nil {
+			return "", err
+		}
+
+		refreshToken := token.RefreshToken
+		if refreshToken == "" {
+			refreshToken = *t.RefreshToken
+		}
+		if err := tokens.UpdateJiraOAuthToken(ctx, t.ID, token.AccessToken, refreshToken, token.ExpiresAt); err != nil {
+			return "", fmt.Errorf("jira: save refreshed oauth token: %w", err)
+		}
+		return token.AccessToken, nil
+	}
+}
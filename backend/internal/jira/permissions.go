@@ -0,0 +1,163 @@
+package jira
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/mcp"
+)
+
+// Project permissions the write tools need, by their Jira keys.
+const (
+	PermissionCreateIssues     = "CREATE_ISSUES"
+	PermissionEditIssues       = "EDIT_ISSUES"
+	PermissionAssignIssues     = "ASSIGN_ISSUES"
+	PermissionDeleteIssues     = "DELETE_ISSUES"
+	PermissionTransitionIssues = "TRANSITION_ISSUES"
+)
+
+// writePermissions are looked up together, so one request per project
+// answers every write tool.
+var writePermissions = []string{
+	PermissionCreateIssues,
+	PermissionEditIssues,
+	PermissionAssignIssues,
+	PermissionDeleteIssues,
+	PermissionTransitionIssues,
+}
+
+const (
+	// permissionCacheTTL is how long a project's permissions are reused, so
+	// granted or revoked permissions apply within a few minutes.
+	permissionCacheTTL = 5 * time.Minute
+	// maxPermissionCacheEntries bounds the permission cache.
+	maxPermissionCacheEntries = 1000
+)
+
+// ProjectPermissions reports which of permissions the client's user holds in
+// the project with projectKey. Permissions Jira does not report are left out.
+func (c *Client) ProjectPermissions(ctx context.Context, projectKey string, permissions []string) (map[string]bool, error) {
+	query := url.Values{}
+	query.Set("projectKey", projectKey)
+	query.Set("permissions", strings.Join(permissions, ","))
+	var out struct {
+		Permissions map[string]struct {
+			HavePermission bool `json:"havePermission"`
+		} `json:"permissions"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/mypermissions", query, nil, &out); err != nil {
+		return nil, err
+	}
+	held := make(map[string]bool, len(out.Permissions))
+	for key, permission := range out.Permissions {
+		held[key] = permission.HavePermission
+	}
+	return held, nil
+}
+
+// permissionCache holds the write permissions of recent projects by site,
+// Jira user and project.
+var permissionCache = struct {
+	mu      sync.Mutex
+	entries map[string]permissionCacheEntry
+}{entries: map[string]permissionCacheEntry{}}
+
+type permissionCacheEntry struct {
+	held    map[string]bool
+	expires time.Time
+}
+
+func (c *Client) writePermissionsCached(ctx context.Context, projectKey string) (map[string]bool, error) {
+	key := c.BaseURL() + "\n" + c.identity() + "\n" + strings.ToUpper(projectKey)
+	now := time.Now()
+
+	permissionCache.mu.Lock()
+	entry, ok := permissionCache.entries[key]
+	permissionCache.mu.Unlock()
+	if ok && now.Before(entry.expires) {
+		return entry.held, nil
+	}
+
+	held, err := c.ProjectPermissions(ctx, projectKey, writePermissions)
+	if err != nil {
+		return nil, err
+	}
+
+	permissionCache.mu.Lock()
+	defer permissionCache.mu.Unlock()
+	if len(permissionCache.entries) >= maxPermissionCacheEntries {
+		for k, e := range permissionCache.entries {
+			if !now.Before(e.expires) {
+				delete(permissionCache.entries, k)
+			}
+		}
+		if len(permissionCache.entries) >= maxPermissionCacheEntries {
+			permissionCache.entries = map[string]permissionCacheEntry{}
+		}
+	}
+	permissionCache.entries[key] = permissionCacheEntry{held: held, expires: now.Add(permissionCacheTTL)}
+	return held, nil
+}
+
+// identity names the Jira user the client acts as: the email of an API
+// token client, or a hash of an OAuth client's access token.
+func (c *Client) identity() string {
+	if c.email != "" {
+		return c.email
+	}
+	c.mu.Lock()
+	token := c.accessToken
+	c.mu.Unlock()
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:8])
+}
+
+// requireProjectPermissions fails fast with a permission_denied error when
+// the client's user lacks one of permissions in the project, instead of
+// letting the write end in Jira's bare 403. If the permissions cannot be
+// read the write goes ahead, and Jira has the final say.
+func requireProjectPermissions(ctx context.Context, c *Client, projectKey string, permissions ...string) error {
+	projectKey = strings.TrimSpace(projectKey)
+	if projectKey == "" {
+		return nil
+	}
+	held, err := c.writePermissionsCached(ctx, projectKey)
+	if err != nil {
+		return nil
+	}
+	var missing []string
+	for _, permission := range permissions {
+		if have, ok := held[permission]; ok && !have {
+			missing = append(missing, permission)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	return mcp.NewToolError(mcp.ErrorPermissionDenied, "missing %s permission on project %s", strings.Join(missing, " and "), projectKey)
+}
+
+// issueProject returns the project key of an issue key such as ENG-12, or
+// "" for a numeric issue ID.
+func issueProject(issueKey string) string {
+	project, _, ok := strings.Cut(strings.TrimSpace(issueKey), "-")
+	if !ok {
+		return ""
+	}
+	return project
+}
+
+// issuePermissions returns permission, plus ASSIGN_ISSUES when the write
+// sets an assignee.
+func issuePermissions(permission, assignee string) []string {
+	if strings.TrimSpace(assignee) == "" {
+		return []string{permission}
+	}
+	return []string{permission, PermissionAssignIssues}
+}
@@ -0,0 +1,54 @@
+package jira
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// Project is a Jira project.
+type Project struct {
+	ID             string `json:"id"`
+	Key            string `json:"key"`
+	Name           string `json:"name"`
+	ProjectTypeKey string `json:"projectTypeKey"`
+	Self           string `json:"self"`
+}
+
+// ProjectPage is one page of ListProjects results.
+type ProjectPage struct {
+	Projects []Project `json:"values"`
+	StartAt  int       `json:"startAt"`
+	Total    int       `json:"total"`
+	IsLast   bool      `json:"isLast"`
+}
+
+// ListProjects returns up to maxResults projects visible to the user,
+// starting at startAt and, when query is set, matching it by key or name.
+func (c *Client) ListProjects(ctx context.Context, query string, startAt, maxResults int) (*ProjectPage, error) {
+	params := url.Values{}
+	if query != "" {
+		params.Set("query", query)
+	}
+	if startAt > 0 {
+		params.Set("startAt", strconv.Itoa(startAt))
+	}
+	if maxResults > 0 {
+		params.Set("maxResults", strconv.Itoa(maxResults))
+	}
+	var page ProjectPage
+	if err := c.do(ctx, http.MethodGet, "/project/search", params, nil, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// GetProject returns the project with the given ID or key.
+func (c *Client) GetProject(ctx context.Context, idOrKey string) (*Project, error) {
+	var project Project
+	if err := c.do(ctx, http.MethodGet, "/project/"+url.PathEscape(idOrKey), nil, nil, &project); err != nil {
+		return nil, err
+	}
+	return &project, nil
+}
@@ -0,0 +1,62 @@
+package jira
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RateLimit is what a Jira response said about the caller's rate limit.
+// Atlassian sends the X-RateLimit headers on some responses only; fields
+// of headers that were absent are zero, and Limit and Remaining are -1.
+type RateLimit struct {
+	// StatusCode is the response's status; 429 means the request was
+	// rejected for exceeding the limit.
+	StatusCode int
+	Limit      int
+	Remaining  int
+	// NearLimit is set when less than a fifth of the budget is left.
+	NearLimit bool
+	// RetryAfter is how long to wait before the next request, from
+	// Retry-After or Beta-Retry-After.
+	RetryAfter time.Duration
+	// Reset is when the budget refills.
+	Reset time.Time
+}
+
+// Limited reports whether the request was rejected for exceeding the limit.
+func (r RateLimit) Limited() bool {
+	return r.StatusCode == http.StatusTooManyRequests
+}
+
+// RateObserver is called with the rate limit of every Jira response.
+type RateObserver func(RateLimit)
+
+// WithRateObserver makes the client report the rate limit headers of every
+// response to observe, which must be safe for concurrent use.
+func (c *Client) WithRateObserver(observe RateObserver) *Client {
+	c.rateObserver = observe
+	return c
+}
+
+func parseRateLimit(resp *http.Response) RateLimit {
+	rl := RateLimit{StatusCode: resp.StatusCode, Limit: -1, Remaining: -1}
+	if n, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Limit")); err == nil {
+		rl.Limit = n
+	}
+	if n, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Remaining")); err == nil {
+		rl.Remaining = n
+	}
+	rl.NearLimit = resp.Header.Get("X-RateLimit-NearLimit") == "true"
+	for _, header := range []string{"Retry-After", "Beta-Retry-After"} {
+		if secs, err := strconv.Atoi(resp.Header.Get(header)); err == nil && secs > 0 {
+			if d := time.Duration(secs) * time.Second; d > rl.RetryAfter {
+				rl.RetryAfter = d
+			}
+		}
+	}
+	if reset, err := time.Parse(time.RFC3339, resp.Header.Get("X-RateLimit-Reset")); err == nil {
+		rl.Reset = reset
+	}
+	return rl
+}
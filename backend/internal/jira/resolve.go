@@ -0,0 +1,316 @@
+package jira
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrUnresolved is returned when a date or user phrase matches nothing.
+var ErrUnresolved = errors.New("jira: phrase did not resolve")
+
+// DateLayout is the format of Jira date fields such as duedate.
+const DateLayout = "2006-01-02"
+
+const (
+	// userCacheTTL is how long user search results are reused.
+	userCacheTTL = 10 * time.Minute
+	// maxUserCacheEntries bounds the user search cache.
+	maxUserCacheEntries = 1000
+	// maxUserCandidates bounds the candidates of an ambiguous user phrase.
+	maxUserCandidates = 10
+)
+
+// DateResolution is a date phrase resolved to a Jira date. When the phrase
+// fits several dates, such as the end of the sprint of a project with more
+// than one active sprint, Date is empty and Candidates lists the sprints.
+type DateResolution struct {
+	Phrase     string   `json:"phrase"`
+	Date       string   `json:"date,omitempty"`
+	Sprint     *Sprint  `json:"sprint,omitempty"`
+	Candidates []Sprint `json:"candidates,omitempty"`
+}
+
+// UserResolution is a user phrase resolved to a Jira account. When the
+// phrase fits several users, Account is nil and Candidates lists them.
+type UserResolution struct {
+	Phrase     string    `json:"phrase"`
+	Account    *Account  `json:"account,omitempty"`
+	Candidates []Account `json:"candidates,omitempty"`
+}
+
+var (
+	isoDate        = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+	inDuration     = regexp.MustCompile(`^in (\d+|a|an|one|two|three|four|five|six|seven|eight|nine|ten) (day|week|month)s?$`)
+	accountIDShape = regexp.MustCompile(`^([0-9a-f]{24}|\d+:[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12})$`)
+)
+
+var smallNumbers = map[string]int{"a": 1, "an": 1, "one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6, "seven": 7, "eight": 8, "nine": 9, "ten": 10}
+
+var weekdays = map[string]time.Weekday{
+	"sunday": time.Sunday, "monday": time.Monday, "tuesday": time.Tuesday, "wednesday": time.Wednesday,
+	"thursday": time.Thursday, "friday": time.Friday, "saturday": time.Saturday,
+}
+
+// ResolveDate turns a phrase such as "tomorrow", "next Monday", "in 2
+// weeks", "end of month" or "end of sprint" into a Jira date, counting from
+// now in now's location. A weekday alone is its next occurrence from today
+// on; "next" skips today. The end of the week is its Friday. "End of
+// sprint" is the end date of the active sprint of projectKey's scrum board.
+// Dates already in YYYY-MM-DD form are returned as they are.
+func (c *Client) ResolveDate(ctx context.Context, phrase, projectKey string, now time.Time) (*DateResolution, error) {
+	text := normalizePhrase(phrase, "by ", "on ", "due ", "at ")
+	switch text {
+	case "end of sprint", "end of the sprint", "end of this sprint", "sprint end", "end of current sprint", "end of the current sprint":
+		return c.resolveSprintEnd(ctx, phrase, projectKey, now.Location())
+	}
+	date, ok := relativeDate(text, now)
+	if !ok {
+		return nil, fmt.Errorf("%w: %q is not a date Jira understands; use YYYY-MM-DD or a phrase like \"next Monday\"", ErrUnresolved, phrase)
+	}
+	return &DateResolution{Phrase: phrase, Date: date.Format(DateLayout)}, nil
+}
+
+func (c *Client) resolveSprintEnd(ctx context.Context, phrase, projectKey string, loc *time.Location) (*DateResolution, error) {
+	if projectKey == "" {
+		return nil, fmt.Errorf("%w: a project is needed to find the sprint %q refers to", ErrUnresolved, phrase)
+	}
+	sprints, err := c.ActiveSprints(ctx, projectKey)
+	if err != nil {
+		return nil, err
+	}
+	var dated []Sprint
+	for _, sprint := range sprints {
+		if sprint.EndDate != nil {
+			dated = append(dated, sprint)
+		}
+	}
+	switch len(dated) {
+	case 0:
+		return nil, fmt.Errorf("%w: project %s has no active sprint with an end date", ErrUnresolved, projectKey)
+	case 1:
+		sprint := dated[0]
+		return &DateResolution{Phrase: phrase, Date: sprint.EndDate.In(loc).Format(DateLayout), Sprint: &sprint}, nil
+	}
+	return &DateResolution{Phrase: phrase, Candidates: dated}, nil
+}
+
+// relativeDate resolves the date phrases that need no lookup. text is
+// lower case.
+func relativeDate(text string, now time.Time) (time.Time, bool) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	if isoDate.MatchString(text) {
+		date, err := time.ParseInLocation(DateLayout, text, now.Location())
+		return date, err == nil
+	}
+	if date, err := time.Parse(time.RFC3339, strings.ToUpper(text)); err == nil {
+		return date.In(now.Location()), true
+	}
+	if m := inDuration.FindStringSubmatch(text); m != nil {
+		n, ok := smallNumbers[m[1]]
+		if !ok {
+			n, _ = strconv.Atoi(m[1])
+		}
+		switch m[2] {
+		case "day":
+			return today.AddDate(0, 0, n), true
+		case "week":
+			return today.AddDate(0, 0, 7*n), true
+		default:
+			return today.AddDate(0, n, 0), true
+		}
+	}
+
+	switch text {
+	case "today", "now", "eod", "end of day", "end of today":
+		return today, true
+	case "tomorrow":
+		return today.AddDate(0, 0, 1), true
+	case "yesterday":
+		return today.AddDate(0, 0, -1), true
+	case "end of week", "end of the week", "end of this week", "eow", "this week":
+		return weekday(today, time.Friday, false), true
+	case "next week":
+		return weekday(today, time.Monday, true), true
+	case "end of next week":
+		return weekday(today, time.Friday, false).AddDate(0, 0, 7), true
+	case "end of month", "end of the month", "end of this month", "eom":
+		return time.Date(today.Year(), today.Month()+1, 0, 0, 0, 0, 0, today.Location()), true
+	case "next month":
+		return time.Date(today.Year(), today.Month()+1, 1, 0, 0, 0, 0, today.Location()), true
+	case "end of next month":
+		return time.Date(today.Year(), today.Month()+2, 0, 0, 0, 0, 0, today.Location()), true
+	}
+
+	next := false
+	if rest, ok := strings.CutPrefix(text, "next "); ok {
+		text, next = rest, true
+	} else if rest, ok := strings.CutPrefix(text, "this "); ok {
+		text = rest
+	}
+	if day, ok := weekdays[text]; ok {
+		return weekday(today, day, next), true
+	}
+	return time.Time{}, false
+}
+
+// weekday returns the first day from today on that falls on day, or the
+// first one after today when skipToday is set.
+func weekday(today time.Time, day time.Weekday, skipToday bool) time.Time {
+	diff := (int(day) - int(today.Weekday()) + 7) % 7
+	if diff == 0 && skipToday {
+		diff = 7
+	}
+	return today.AddDate(0, 0, diff)
+}
+
+// ResolveUser turns a phrase such as "Maria", "assign to maria@acme.com" or
+// "me" into a Jira account. Account IDs are returned as they are. A name
+// that fits several active users resolves to the one whose display name or
+// email matches it exactly, or to the one with a name part equal to it;
+// otherwise the resolution lists the candidates. Searches are cached for a
+// few minutes per site.
+func (c *Client) ResolveUser(ctx context.Context, phrase string) (*UserResolution, error) {
+	query := normalizePhrase(phrase, "assign to ", "assign ", "assignee ", "to ", "@")
+	if query == "" {
+		return nil, fmt.Errorf("%w: no user given", ErrUnresolved)
+	}
+	switch query {
+	case "me", "myself", "i":
+		account, err := c.Myself(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return &UserResolution{Phrase: phrase, Account: account}, nil
+	}
+	if id := strings.TrimSpace(phrase); accountIDShape.MatchString(id) {
+		return &UserResolution{Phrase: phrase, Account: &Account{AccountID: id}}, nil
+	}
+
+	accounts, err := c.searchUsersCached(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	var people []Account
+	for _, account := range accounts {
+		if account.Active && (account.AccountType == "" || account.AccountType == "atlassian") {
+			people = append(people, account)
+		}
+	}
+	if len(people) == 0 {
+		return nil, fmt.Errorf("%w: no active Jira user matches %q", ErrUnresolved, phrase)
+	}
+	if len(people) == 1 {
+		return &UserResolution{Phrase: phrase, Account: &people[0]}, nil
+	}
+	for _, match := range []func(Account) bool{
+		func(a Account) bool {
+			return strings.EqualFold(a.DisplayName, query) || strings.EqualFold(a.EmailAddress, query)
+		},
+		func(a Account) bool {
+			for _, part := range strings.Fields(a.DisplayName) {
+				if strings.EqualFold(part, query) {
+					return true
+				}
+			}
+			return false
+		},
+	} {
+		var matched []Account
+		for _, account := range people {
+			if match(account) {
+				matched = append(matched, account)
+			}
+		}
+		if len(matched) == 1 {
+			return &UserResolution{Phrase: phrase, Account: &matched[0]}, nil
+		}
+		if len(matched) > 1 {
+			people = matched
+			break
+		}
+	}
+	if len(people) > maxUserCandidates {
+		people = people[:maxUserCandidates]
+	}
+	return &UserResolution{Phrase: phrase, Candidates: people}, nil
+}
+
+// SearchUsers returns up to maxResults users whose name or email starts
+// with query.
+func (c *Client) SearchUsers(ctx context.Context, query string, maxResults int) ([]Account, error) {
+	params := url.Values{"query": {query}}
+	if maxResults > 0 {
+		params.Set("maxResults", strconv.Itoa(maxResults))
+	}
+	var accounts []Account
+	if err := c.do(ctx, http.MethodGet, "/user/search", params, nil, &accounts); err != nil {
+		return nil, err
+	}
+	return accounts, nil
+}
+
+// userCache holds recent user searches by site and query.
+var userCache = struct {
+	mu      sync.Mutex
+	entries map[string]userCacheEntry
+}{entries: map[string]userCacheEntry{}}
+
+type userCacheEntry struct {
+	accounts []Account
+	expires  time.Time
+}
+
+func (c *Client) searchUsersCached(ctx context.Context, query string) ([]Account, error) {
+	key := c.BaseURL() + "\n" + query
+	now := time.Now()
+
+	userCache.mu.Lock()
+	entry, ok := userCache.entries[key]
+	userCache.mu.Unlock()
+	if ok && now.Before(entry.expires) {
+		return entry.accounts, nil
+	}
+
+	accounts, err := c.SearchUsers(ctx, query, 50)
+	if err != nil {
+		return nil, err
+	}
+
+	userCache.mu.Lock()
+	defer userCache.mu.Unlock()
+	if len(userCache.entries) >= maxUserCacheEntries {
+		for k, e := range userCache.entries {
+			if !now.Before(e.expires) {
+				delete(userCache.entries, k)
+			}
+		}
+		if len(userCache.entries) >= maxUserCacheEntries {
+			userCache.entries = map[string]userCacheEntry{}
+		}
+	}
+	userCache.entries[key] = userCacheEntry{accounts: accounts, expires: now.Add(userCacheTTL)}
+	return accounts, nil
+}
+
+// normalizePhrase lower-cases phrase, collapses its spaces and drops the
+// first of prefixes it starts with, along with a leading "the ".
+func normalizePhrase(phrase string, prefixes ...string) string {
+	text := strings.ToLower(strings.Join(strings.Fields(phrase), " "))
+	for _, prefix := range prefixes {
+		if rest, ok := strings.CutPrefix(text, prefix); ok {
+			text = rest
+			break
+		}
+	}
+	text = strings.TrimPrefix(strings.TrimSuffix(text, "."), "the ")
+	return strings.TrimSpace(text)
+}
@@ -0,0 +1,67 @@
+package jira
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// SearchRequest is a JQL search. Pages are chained through NextPageToken.
+type SearchRequest struct {
+	JQL string `json:"jql"`
+	// Fields limits the fields of each issue; nil returns only issue IDs.
+	Fields        []string `json:"fields,omitempty"`
+	MaxResults    int      `json:"maxResults,omitempty"`
+	NextPageToken string   `json:"nextPageToken,omitempty"`
+}
+
+// SearchResult is one page of search results. NextPageToken is empty on the
+// last page.
+type SearchResult struct {
+	Issues        []Issue `json:"issues"`
+	NextPageToken string  `json:"nextPageToken"`
+	IsLast        bool    `json:"isLast"`
+}
+
+// Search runs a JQL search and returns one page of matching issues.
+func (c *Client) Search(ctx context.Context, req SearchRequest) (*SearchResult, error) {
+	var result SearchResult
+	if err := c.do(ctx, http.MethodPost, "/search/jql", nil, req, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// ApproximateCount returns Jira's estimate of how many issues match jql.
+func (c *Client) ApproximateCount(ctx context.Context, jql string) (int, error) {
+	var result struct {
+		Count int `json:"count"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/search/approximate-count", nil, map[string]string{"jql": jql}, &result); err != nil {
+		return 0, err
+	}
+	return result.Count, nil
+}
+
+// MaxBulkFetchIssues is how many issues BulkFetchIssues accepts at once.
+const MaxBulkFetchIssues = 100
+
+// BulkFetchIssues returns the issues with the given IDs or keys, up to
+// MaxBulkFetchIssues, limited to fields. Issues that no longer exist or
+// that the user may not see are left out.
+func (c *Client) BulkFetchIssues(ctx context.Context, idsOrKeys, fields []string) ([]Issue, error) {
+	if len(idsOrKeys) > MaxBulkFetchIssues {
+		return nil, errors.New("jira: too many issues to fetch at once")
+	}
+	body := map[string]any{"issueIdsOrKeys": idsOrKeys}
+	if len(fields) > 0 {
+		body["fields"] = fields
+	}
+	var result struct {
+		Issues []Issue `json:"issues"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/issue/bulkfetch", nil, body, &result); err != nil {
+		return nil, err
+	}
+	return result.Issues, nil
+}
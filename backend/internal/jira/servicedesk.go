@@ -0,0 +1,251 @@
+package jira
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// ServiceDesk is a Jira Service Management service desk, backed by a
+// project.
+type ServiceDesk struct {
+	ID          string `json:"id"`
+	ProjectID   string `json:"projectId"`
+	ProjectKey  string `json:"projectKey"`
+	ProjectName string `json:"projectName"`
+}
+
+// RequestType is a kind of request customers can raise on a service desk.
+type RequestType struct {
+	ID            string `json:"id"`
+	Name          string `json:"name"`
+	Description   string `json:"description,omitempty"`
+	ServiceDeskID string `json:"serviceDeskId"`
+	IssueTypeID   string `json:"issueTypeId,omitempty"`
+}
+
+// Queue is a service desk queue of requests picked by JQL. IssueCount is
+// only set when counts were asked for.
+type Queue struct {
+	ID         string   `json:"id"`
+	Name       string   `json:"name"`
+	JQL        string   `json:"jql"`
+	Fields     []string `json:"fields,omitempty"`
+	IssueCount *int     `json:"issueCount,omitempty"`
+}
+
+// JSMDate is a date as the service desk API reports it.
+type JSMDate struct {
+	ISO8601  string `json:"iso8601"`
+	Friendly string `json:"friendly,omitempty"`
+}
+
+// CustomerRequest is a service desk request, which is also a Jira issue.
+type CustomerRequest struct {
+	IssueID       string         `json:"issueId"`
+	IssueKey      string         `json:"issueKey"`
+	RequestTypeID string         `json:"requestTypeId"`
+	ServiceDeskID string         `json:"serviceDeskId"`
+	CreatedDate   *JSMDate       `json:"createdDate,omitempty"`
+	Reporter      *Account       `json:"reporter,omitempty"`
+	CurrentStatus *RequestStatus `json:"currentStatus,omitempty"`
+	Links         struct {
+		Web string `json:"web,omitempty"`
+	} `json:"_links"`
+}
+
+// RequestStatus is the status a request is in and since when.
+type RequestStatus struct {
+	Status         string   `json:"status"`
+	StatusCategory string   `json:"statusCategory,omitempty"`
+	StatusDate     *JSMDate `json:"statusDate,omitempty"`
+}
+
+// CreateRequestInput is what CreateCustomerRequest needs. Fields are the
+// request type's fields by ID, such as summary and description, as plain
+// values.
+type CreateRequestInput struct {
+	ServiceDeskID   string         `json:"serviceDeskId"`
+	RequestTypeID   string         `json:"requestTypeId"`
+	Fields          map[string]any `json:"requestFieldValues"`
+	RaiseOnBehalfOf string         `json:"raiseOnBehalfOf,omitempty"`
+	Participants    []string       `json:"requestParticipants,omitempty"`
+}
+
+// CustomerTransition is a transition of a customer request.
+type CustomerTransition struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// SLADuration is an SLA time span in milliseconds and as Jira shows it.
+type SLADuration struct {
+	Millis   int64  `json:"millis"`
+	Friendly string `json:"friendly"`
+}
+
+// SLACycle is one run of an SLA clock, from when it started until it
+// stopped, or until now for the ongoing cycle.
+type SLACycle struct {
+	StartTime     *JSMDate     `json:"startTime,omitempty"`
+	StopTime      *JSMDate     `json:"stopTime,omitempty"`
+	BreachTime    *JSMDate     `json:"breachTime,omitempty"`
+	Breached      bool         `json:"breached"`
+	Paused        bool         `json:"paused,omitempty"`
+	GoalDuration  *SLADuration `json:"goalDuration,omitempty"`
+	ElapsedTime   *SLADuration `json:"elapsedTime,omitempty"`
+	RemainingTime *SLADuration `json:"remainingTime,omitempty"`
+}
+
+// SLA is one of a request's SLA metrics, such as time to first response.
+type SLA struct {
+	ID              string     `json:"id"`
+	Name            string     `json:"name"`
+	OngoingCycle    *SLACycle  `json:"ongoingCycle,omitempty"`
+	CompletedCycles []SLACycle `json:"completedCycles,omitempty"`
+}
+
+// serviceDeskPage is a page of the service desk API.
+type serviceDeskPage[T any] struct {
+	Size       int  `json:"size"`
+	Start      int  `json:"start"`
+	IsLastPage bool `json:"isLastPage"`
+	Values     []T  `json:"values"`
+}
+
+// doServiceDesk is do for the Jira Service Management API under
+// /rest/servicedeskapi.
+func (c *Client) doServiceDesk(ctx context.Context, method, path string, query url.Values, body, out any) error {
+	return c.call(ctx, method, "/rest/servicedeskapi"+path, query, body, out)
+}
+
+// requestPath returns the path of a customer request, escaping its ID or
+// key.
+func requestPath(idOrKey string) string {
+	return "/request/" + url.PathEscape(idOrKey)
+}
+
+// HasServiceManagement reports whether the site has Jira Service
+// Management. Sites without it answer 404 under /rest/servicedeskapi.
+func (c *Client) HasServiceManagement(ctx context.Context) (bool, error) {
+	err := c.doServiceDesk(ctx, http.MethodGet, "/info", nil, nil, nil)
+	if IsNotFound(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// ServiceManagement reports whether the client's site was found to have
+// Jira Service Management when its settings were validated.
+func (c *Client) ServiceManagement() bool {
+	return c.serviceManagement
+}
+
+// ListServiceDesks returns up to limit service desks the user can see,
+// starting at start.
+func (c *Client) ListServiceDesks(ctx context.Context, start, limit int) ([]ServiceDesk, error) {
+	var page serviceDeskPage[ServiceDesk]
+	if err := c.doServiceDesk(ctx, http.MethodGet, "/servicedesk", pageQuery(start, limit), nil, &page); err != nil {
+		return nil, err
+	}
+	return page.Values, nil
+}
+
+// ListRequestTypes returns up to limit request types of a service desk,
+// starting at start.
+func (c *Client) ListRequestTypes(ctx context.Context, serviceDeskID string, start, limit int) ([]RequestType, error) {
+	var page serviceDeskPage[RequestType]
+	path := "/servicedesk/" + url.PathEscape(serviceDeskID) + "/requesttype"
+	if err := c.doServiceDesk(ctx, http.MethodGet, path, pageQuery(start, limit), nil, &page); err != nil {
+		return nil, err
+	}
+	return page.Values, nil
+}
+
+// ListQueues returns the queues of a service desk, with how many requests
+// each holds when includeCount is set.
+func (c *Client) ListQueues(ctx context.Context, serviceDeskID string, includeCount bool) ([]Queue, error) {
+	query := pageQuery(0, 50)
+	if includeCount {
+		query.Set("includeCount", "true")
+	}
+	var page serviceDeskPage[Queue]
+	path := "/servicedesk/" + url.PathEscape(serviceDeskID) + "/queue"
+	if err := c.doServiceDesk(ctx, http.MethodGet, path, query, nil, &page); err != nil {
+		return nil, err
+	}
+	return page.Values, nil
+}
+
+// CreateCustomerRequest raises a request on a service desk.
+func (c *Client) CreateCustomerRequest(ctx context.Context, input CreateRequestInput) (*CustomerRequest, error) {
+	var request CustomerRequest
+	if err := c.doServiceDesk(ctx, http.MethodPost, "/request", nil, input, &request); err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
+
+// AddRequestParticipants adds the accounts with accountIDs to a request and
+// returns all of its participants.
+func (c *Client) AddRequestParticipants(ctx context.Context, idOrKey string, accountIDs []string) ([]Account, error) {
+	var page serviceDeskPage[Account]
+	body := map[string]any{"accountIds": accountIDs}
+	if err := c.doServiceDesk(ctx, http.MethodPost, requestPath(idOrKey)+"/participant", nil, body, &page); err != nil {
+		return nil, err
+	}
+	return page.Values, nil
+}
+
+// ListRequestTransitions returns the transitions a customer request can
+// take, which may differ from the issue's workflow transitions.
+func (c *Client) ListRequestTransitions(ctx context.Context, idOrKey string) ([]CustomerTransition, error) {
+	var page serviceDeskPage[CustomerTransition]
+	if err := c.doServiceDesk(ctx, http.MethodGet, requestPath(idOrKey)+"/transition", nil, nil, &page); err != nil {
+		return nil, err
+	}
+	return page.Values, nil
+}
+
+// TransitionRequest moves a customer request through transitionID, adding
+// comment as a public comment when it is not empty.
+func (c *Client) TransitionRequest(ctx context.Context, idOrKey, transitionID, comment string) error {
+	body := map[string]any{"id": transitionID}
+	if comment != "" {
+		body["additionalComment"] = map[string]string{"body": comment}
+	}
+	return c.doServiceDesk(ctx, http.MethodPost, requestPath(idOrKey)+"/transition", nil, body, nil)
+}
+
+// RequestSLAs returns the SLA metrics of a customer request.
+func (c *Client) RequestSLAs(ctx context.Context, idOrKey string) ([]SLA, error) {
+	var page serviceDeskPage[SLA]
+	if err := c.doServiceDesk(ctx, http.MethodGet, requestPath(idOrKey)+"/sla", nil, nil, &page); err != nil {
+		return nil, err
+	}
+	return page.Values, nil
+}
+
+// pageQuery returns the start and limit parameters of the service desk API,
+// leaving out values that are not positive.
+func pageQuery(start, limit int) url.Values {
+	query := url.Values{}
+	if start > 0 {
+		query.Set("start", strconv.Itoa(start))
+	}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	return query
+}
+
+// RequestTransition is what jsm_transition_request returns: the request's
+// status after the transition, or the transitions it can take when none was
+// given, with its SLAs.
+type RequestTransition struct {
+	IssueKey     string               `json:"issueKey"`
+	Transitioned bool                 `json:"transitioned"`
+	Transitions  []CustomerTransition `json:"transitions,omitempty"`
+	SLAs         []SLA                `json:"slas"`
+}
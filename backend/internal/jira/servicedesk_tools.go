@@ -0,0 +1,243 @@
+package jira
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/mcp"
+)
+
+// serviceDeskTools returns the Jira Service Management tools. They refuse
+// to run for sites where validating the settings found no Jira Service
+// Management.
+func serviceDeskTools(clientFor ClientResolver) []mcp.Tool {
+	return []mcp.Tool{
+		{
+			Name:        "jsm_list_service_desks",
+			Description: "List the Jira Service Management service desks visible to the user, with their IDs and projects.",
+			InputSchema: json.RawMessage(`{"type":"object","properties":{
+				"start":{"type":"integer","minimum":0},
+				"limit":{"type":"integer","minimum":1,"maximum":100}
+			},"additionalProperties":false}`),
+			Handler: withServiceDesk(clientFor, func(ctx context.Context, c *Client, args struct {
+				Start int `json:"start"`
+				Limit int `json:"limit"`
+			}) (any, error) {
+				return c.ListServiceDesks(ctx, args.Start, args.Limit)
+			}),
+		},
+		{
+			Name:        "jsm_list_request_types",
+			Description: "List the request types of a service desk. Use a request type's ID with jsm_create_request.",
+			InputSchema: json.RawMessage(`{"type":"object","properties":{
+				"serviceDeskId":{"type":"string","minLength":1},
+				"start":{"type":"integer","minimum":0},
+				"limit":{"type":"integer","minimum":1,"maximum":100}
+			},"required":["serviceDeskId"],"additionalProperties":false}`),
+			Handler: withServiceDesk(clientFor, func(ctx context.Context, c *Client, args struct {
+				ServiceDeskID string `json:"serviceDeskId"`
+				Start         int    `json:"start"`
+				Limit         int    `json:"limit"`
+			}) (any, error) {
+				if err := required("serviceDeskId", args.ServiceDeskID); err != nil {
+					return nil, err
+				}
+				return c.ListRequestTypes(ctx, args.ServiceDeskID, args.Start, args.Limit)
+			}),
+		},
+		{
+			Name:        "jsm_create_request",
+			Description: "Raise a customer request on a Jira Service Management service desk, optionally on behalf of a customer and with participants.",
+			InputSchema: json.RawMessage(`{"type":"object","properties":{
+				"serviceDeskId":{"type":"string","minLength":1},
+				"requestTypeId":{"type":"string","minLength":1,"description":"From jsm_list_request_types."},
+				"summary":{"type":"string","minLength":1},
+				"description":{"type":"string","description":"Plain text description."},
+				"raiseOnBehalfOf":{"type":"string","description":"Customer to raise the request for: a name, email, account ID or \"me\"."},
+				"participants":{"type":"array","items":{"type":"string","minLength":1},"description":"Names, emails or account IDs of users to add as participants."},
+				"fields":{"type":"object","description":"Other request type fields by ID."}
+			},"required":["serviceDeskId","requestTypeId","summary"],"additionalProperties":false}`),
+			Handler: withServiceDesk(clientFor, func(ctx context.Context, c *Client, args struct {
+				ServiceDeskID   string         `json:"serviceDeskId"`
+				RequestTypeID   string         `json:"requestTypeId"`
+				Summary         string         `json:"summary"`
+				Description     string         `json:"description"`
+				RaiseOnBehalfOf string         `json:"raiseOnBehalfOf"`
+				Participants    []string       `json:"participants"`
+				Fields          map[string]any `json:"fields"`
+			}) (any, error) {
+				if err := errors.Join(required("serviceDeskId", args.ServiceDeskID), required("requestTypeId", args.RequestTypeID), required("summary", args.Summary)); err != nil {
+					return nil, err
+				}
+				input := CreateRequestInput{ServiceDeskID: args.ServiceDeskID, RequestTypeID: args.RequestTypeID, Fields: map[string]any{}}
+				for k, v := range args.Fields {
+					input.Fields[k] = v
+				}
+				input.Fields["summary"] = args.Summary
+				if args.Description != "" {
+					input.Fields["description"] = args.Description
+				}
+
+				problems := map[string]string{}
+				if strings.TrimSpace(args.RaiseOnBehalfOf) != "" {
+					ids, err := resolveAccountIDs(ctx, c, []string{args.RaiseOnBehalfOf})
+					if err != nil {
+						return nil, err
+					}
+					if len(ids.problems) > 0 {
+						problems["raiseOnBehalfOf"] = strings.Join(ids.problems, "; ")
+					}
+					if len(ids.accountIDs) > 0 {
+						input.RaiseOnBehalfOf = ids.accountIDs[0]
+					}
+				}
+				participants, err := resolveAccountIDs(ctx, c, args.Participants)
+				if err != nil {
+					return nil, err
+				}
+				if len(participants.problems) > 0 {
+					problems["participants"] = strings.Join(participants.problems, "; ")
+				}
+				if len(problems) > 0 {
+					return nil, &mcp.ToolError{Code: mcp.ErrorFieldValidation, Message: "some users could not be resolved", Fields: problems}
+				}
+				input.Participants = participants.accountIDs
+				return c.CreateCustomerRequest(ctx, input)
+			}),
+		},
+		{
+			Name:        "jsm_list_queues",
+			Description: "List the queues of a service desk with their JQL and, when includeCount is true, how many requests each holds.",
+			InputSchema: json.RawMessage(`{"type":"object","properties":{
+				"serviceDeskId":{"type":"string","minLength":1},
+				"includeCount":{"type":"boolean"}
+			},"required":["serviceDeskId"],"additionalProperties":false}`),
+			Handler: withServiceDesk(clientFor, func(ctx context.Context, c *Client, args struct {
+				ServiceDeskID string `json:"serviceDeskId"`
+				IncludeCount  bool   `json:"includeCount"`
+			}) (any, error) {
+				if err := required("serviceDeskId", args.ServiceDeskID); err != nil {
+					return nil, err
+				}
+				return c.ListQueues(ctx, args.ServiceDeskID, args.IncludeCount)
+			}),
+		},
+		{
+			Name:        "jsm_add_request_participants",
+			Description: "Add users as participants of a customer request, so they can see and comment on it. Returns all of the request's participants.",
+			InputSchema: json.RawMessage(`{"type":"object","properties":{
+				"issueKey":{"type":"string","minLength":1},
+				"participants":{"type":"array","minItems":1,"items":{"type":"string","minLength":1},"description":"Names, emails or account IDs."}
+			},"required":["issueKey","participants"],"additionalProperties":false}`),
+			Handler: withServiceDesk(clientFor, func(ctx context.Context, c *Client, args struct {
+				IssueKey     string   `json:"issueKey"`
+				Participants []string `json:"participants"`
+			}) (any, error) {
+				if err := required("issueKey", args.IssueKey); err != nil {
+					return nil, err
+				}
+				participants, err := resolveAccountIDs(ctx, c, args.Participants)
+				if err != nil {
+					return nil, err
+				}
+				if len(participants.problems) > 0 || len(participants.accountIDs) == 0 {
+					message := "no participants given"
+					if len(participants.problems) > 0 {
+						message = strings.Join(participants.problems, "; ")
+					}
+					return nil, &mcp.ToolError{Code: mcp.ErrorFieldValidation, Message: "some users could not be resolved", Fields: map[string]string{"participants": message}}
+				}
+				return c.AddRequestParticipants(ctx, args.IssueKey, participants.accountIDs)
+			}),
+		},
+		{
+			Name:        "jsm_transition_request",
+			Description: "Move a customer request through one of its transitions, optionally with a public comment, and return its SLAs afterwards. Without transitionId, returns the transitions the request can take and its SLAs without changing it.",
+			InputSchema: json.RawMessage(`{"type":"object","properties":{
+				"issueKey":{"type":"string","minLength":1},
+				"transitionId":{"type":"string","description":"Customer transition ID; omit to list them."},
+				"comment":{"type":"string","description":"Public comment added with the transition."}
+			},"required":["issueKey"],"additionalProperties":false}`),
+			Handler: withServiceDesk(clientFor, func(ctx context.Context, c *Client, args struct {
+				IssueKey     string `json:"issueKey"`
+				TransitionID string `json:"transitionId"`
+				Comment      string `json:"comment"`
+			}) (any, error) {
+				if err := required("issueKey", args.IssueKey); err != nil {
+					return nil, err
+				}
+				result := RequestTransition{IssueKey: args.IssueKey}
+				if strings.TrimSpace(args.TransitionID) == "" {
+					transitions, err := c.ListRequestTransitions(ctx, args.IssueKey)
+					if err != nil {
+						return nil, err
+					}
+					result.Transitions = transitions
+				} else {
+					if err := c.TransitionRequest(ctx, args.IssueKey, args.TransitionID, strings.TrimSpace(args.Comment)); err != nil {
+						return nil, err
+					}
+					result.Transitioned = true
+				}
+				slas, err := c.RequestSLAs(ctx, args.IssueKey)
+				if err != nil {
+					return nil, err
+				}
+				result.SLAs = slas
+				return result, nil
+			}),
+		},
+	}
+}
+
+// withServiceDesk is withClient for tools that need Jira Service
+// Management on the tenant's site.
+func withServiceDesk[A any](clientFor ClientResolver, fn func(ctx context.Context, c *Client, args A) (any, error)) mcp.ToolHandler {
+	return withClient(clientFor, func(ctx context.Context, c *Client, args A) (any, error) {
+		if !c.ServiceManagement() {
+			return nil, &mcp.ToolError{
+				Code:    mcp.ErrorToolFailed,
+				Message: "Jira Service Management is not enabled for " + c.BaseURL(),
+				Hint:    "The site had no Jira Service Management when its Jira settings were validated. If it has been added since, ask the user to validate the settings again; otherwise use the jira_* issue tools.",
+			}
+		}
+		return fn(ctx, c, args)
+	})
+}
+
+// resolvedAccounts are the account IDs user phrases resolved to, and what
+// went wrong with the phrases that did not resolve.
+type resolvedAccounts struct {
+	accountIDs []string
+	problems   []string
+}
+
+// resolveAccountIDs resolves each phrase to a single account like the
+// assignee of jira_create_issue.
+func resolveAccountIDs(ctx context.Context, c *Client, phrases []string) (resolvedAccounts, error) {
+	var out resolvedAccounts
+	for _, phrase := range phrases {
+		if strings.TrimSpace(phrase) == "" {
+			continue
+		}
+		resolution, err := c.ResolveUser(ctx, phrase)
+		switch {
+		case errors.Is(err, ErrUnresolved):
+			out.problems = append(out.problems, unresolvedMessage(err))
+		case err != nil:
+			return out, err
+		case resolution.Account == nil:
+			names := make([]string, 0, len(resolution.Candidates))
+			for _, candidate := range resolution.Candidates {
+				names = append(names, fmt.Sprintf("%s (%s)", candidate.DisplayName, candidate.AccountID))
+			}
+			out.problems = append(out.problems, fmt.Sprintf("%q matches several users: %s; pass one of the account IDs", phrase, strings.Join(names, ", ")))
+		default:
+			out.accountIDs = append(out.accountIDs, resolution.Account.AccountID)
+		}
+	}
+	return out, nil
+}
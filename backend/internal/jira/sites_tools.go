@@ -0,0 +1,141 @@
+package jira
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/mcp"
+)
+
+// ClientsResolver returns a client for each Jira site of the tenant making
+// a tool call.
+type ClientsResolver func(ctx context.Context) ([]*Client, error)
+
+// myOpenIssuesJQL finds the caller's unresolved issues on a site.
+const myOpenIssuesJQL = "assignee = currentUser() AND statusCategory != Done ORDER BY updated DESC"
+
+// SiteSearch is one site's part of a search across sites. A site that
+// failed has Error set and no issues; the other sites are unaffected.
+type SiteSearch struct {
+	Site          string     `json:"site"`
+	Issues        []Issue    `json:"issues"`
+	NextPageToken string     `json:"nextPageToken,omitempty"`
+	IsLast        bool       `json:"isLast"`
+	Error         *SiteError `json:"error,omitempty"`
+}
+
+// SiteError is why a site's part of a search across sites failed.
+type SiteError struct {
+	Code    mcp.ErrorCode `json:"code"`
+	Message string        `json:"message"`
+}
+
+// SitesSearch is what the cross-site tools return: each site's results in
+// the order the sites were configured, the default site first.
+type SitesSearch struct {
+	Total       int          `json:"total"`
+	FailedSites int          `json:"failedSites"`
+	Sites       []SiteSearch `json:"sites"`
+}
+
+// SiteTools returns the tools that work across all of the tenant's Jira
+// sites. Each site is searched concurrently, and a site that fails is
+// reported in its result without failing the call.
+func SiteTools(clientsFor ClientsResolver) []mcp.Tool {
+	return []mcp.Tool{
+		{
+			Name:        "jira_search_all_sites",
+			Description: "Run a JQL search on every Jira site the user configured and return the matches grouped by site. Sites that fail are reported with an error code while the others still return results.",
+			InputSchema: json.RawMessage(`{"type":"object","properties":{
+				"jql":{"type":"string","minLength":1,"description":"JQL query run on each site."},
+				"fields":{"type":"array","items":{"type":"string"},"description":"Fields to return for each issue."},
+				"maxResults":{"type":"integer","minimum":1,"maximum":100,"description":"Page size per site, default 50."}
+			},"required":["jql"],"additionalProperties":false}`),
+			Handler: withClients(clientsFor, func(ctx context.Context, clients []*Client, args struct {
+				JQL        string   `json:"jql"`
+				Fields     []string `json:"fields"`
+				MaxResults int      `json:"maxResults"`
+			}) (any, error) {
+				if err := required("jql", args.JQL); err != nil {
+					return nil, err
+				}
+				return searchSites(ctx, clients, searchRequest(args.JQL, args.Fields, args.MaxResults)), nil
+			}),
+		},
+		{
+			Name:        "jira_my_open_issues",
+			Description: "List the unresolved issues assigned to the user on every Jira site they configured, most recently updated first, grouped by site.",
+			InputSchema: json.RawMessage(`{"type":"object","properties":{
+				"fields":{"type":"array","items":{"type":"string"},"description":"Fields to return for each issue."},
+				"maxResults":{"type":"integer","minimum":1,"maximum":100,"description":"Issues per site, default 50."}
+			},"additionalProperties":false}`),
+			Handler: withClients(clientsFor, func(ctx context.Context, clients []*Client, args struct {
+				Fields     []string `json:"fields"`
+				MaxResults int      `json:"maxResults"`
+			}) (any, error) {
+				return searchSites(ctx, clients, searchRequest(myOpenIssuesJQL, args.Fields, args.MaxResults)), nil
+			}),
+		},
+	}
+}
+
+// withClients is withClient for the cross-site tools.
+func withClients[A any](clientsFor ClientsResolver, fn func(ctx context.Context, clients []*Client, args A) (any, error)) mcp.ToolHandler {
+	return func(ctx context.Context, raw json.RawMessage) (any, error) {
+		var args A
+		if err := json.Unmarshal(raw, &args); err != nil {
+			return nil, &mcp.ToolError{Code: mcp.ErrorFieldValidation, Message: "invalid arguments: " + err.Error(), Err: err}
+		}
+		clients, err := clientsFor(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return fn(ctx, clients, args)
+	}
+}
+
+// searchRequest is the first page of a search with searchJiraIssues'
+// defaults.
+func searchRequest(jql string, fields []string, maxResults int) SearchRequest {
+	if len(fields) == 0 {
+		fields = defaultSearchFields
+	}
+	if maxResults <= 0 || maxResults > 100 {
+		maxResults = 50
+	}
+	return SearchRequest{JQL: jql, Fields: fields, MaxResults: maxResults}
+}
+
+// searchSites runs req on every site at once and collects the results in
+// the order of clients.
+func searchSites(ctx context.Context, clients []*Client, req SearchRequest) SitesSearch {
+	out := SitesSearch{Sites: make([]SiteSearch, len(clients))}
+	var wg sync.WaitGroup
+	for i, c := range clients {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			site := SiteSearch{Site: c.BaseURL(), Issues: []Issue{}}
+			result, err := c.Search(ctx, req)
+			if err != nil {
+				toolErr := mcp.AsToolError(toolError(err))
+				site.Error = &SiteError{Code: toolErr.Code, Message: toolErr.Message}
+			} else {
+				site.Issues = result.Issues
+				site.NextPageToken = result.NextPageToken
+				site.IsLast = result.IsLast
+			}
+			out.Sites[i] = site
+		}()
+	}
+	wg.Wait()
+
+	for _, site := range out.Sites {
+		out.Total += len(site.Issues)
+		if site.Error != nil {
+			out.FailedSites++
+		}
+	}
+	return out
+}
@@ -0,0 +1,26 @@
+package jira
+
+import (
+	"context"
+	"net/http"
+)
+
+// Account is a Jira user account.
+type Account struct {
+	AccountID    string `json:"accountId"`
+	AccountType  string `json:"accountType"`
+	DisplayName  string `json:"displayName"`
+	EmailAddress string `json:"emailAddress"`
+	TimeZone     string `json:"timeZone"`
+	Locale       string `json:"locale"`
+	Active       bool   `json:"active"`
+}
+
+// Myself returns the account the client authenticates as.
+func (c *Client) Myself(ctx context.Context) (*Account, error) {
+	var account Account
+	if err := c.do(ctx, http.MethodGet, "/myself", nil, nil, &account); err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
@@ -0,0 +1,137 @@
+// Package keyring holds the ordered sets of secrets used to sign and verify
+// tokens and requests, so a key can be rotated without invalidating
+// everything issued under the previous one.
+//
+// A keyring is configured as a comma-separated list of id:secret pairs. The
+// first key is the primary and signs new material; every key is accepted
+// when verifying. To rotate, prepend the new key, wait for material signed
+// with the old one to expire, then drop it.
+package keyring
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// DefaultID names the key built from a single legacy secret.
+const DefaultID = "default"
+
+// Key is one secret of a keyring.
+type Key struct {
+	ID     string
+	Secret []byte
+}
+
+// Keyring is an ordered set of keys; the first is the primary. The zero
+// value and nil are empty keyrings.
+type Keyring struct {
+	keys []Key
+}
+
+// New builds a keyring from keys, primary first. IDs must be non-empty and
+// unique, and secrets non-empty.
+func New(keys ...Key) (*Keyring, error) {
+	seen := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		if k.ID == "" {
+			return nil, errors.New("keyring: key id is required")
+		}
+		if strings.ContainsAny(k.ID, ":,") {
+			return nil, fmt.Errorf("keyring: key id %q must not contain ':' or ','", k.ID)
+		}
+		if len(k.Secret) == 0 {
+			return nil, fmt.Errorf("keyring: key %q has an empty secret", k.ID)
+		}
+		if seen[k.ID] {
+			return nil, fmt.Errorf("keyring: duplicate key id %q", k.ID)
+		}
+		seen[k.ID] = true
+	}
+	return &Keyring{keys: keys}, nil
+}
+
+// Single returns a keyring holding only secret under DefaultID, or an empty
+// keyring when secret is empty.
+func Single(secret string) *Keyring {
+	if secret == "" {
+		return &Keyring{}
+	}
+	return &Keyring{keys: []Key{{ID: DefaultID, Secret: []byte(secret)}}}
+}
+
+// Parse reads a keyring from "id:secret,id:secret". Surrounding whitespace
+// is ignored; the secret is everything after the first colon.
+func Parse(spec string) (*Keyring, error) {
+	var keys []Key
+	for i, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		id, secret, ok := strings.Cut(entry, ":")
+		if !ok {
+			// Do not echo the entry: it is probably a bare secret.
+			return nil, fmt.Errorf("keyring: entry %d is not id:secret", i+1)
+		}
+		keys = append(keys, Key{ID: strings.TrimSpace(id), Secret: []byte(strings.TrimSpace(secret))})
+	}
+	return New(keys...)
+}
+
+// Load parses spec when set and otherwise falls back to a single-key
+// keyring built from legacy, so existing single-secret deployments keep
+// working unchanged.
+func Load(spec, legacy string) (*Keyring, error) {
+	if strings.TrimSpace(spec) != "" {
+		return Parse(spec)
+	}
+	return Single(legacy), nil
+}
+
+// Len returns the number of keys.
+func (k *Keyring) Len() int {
+	if k == nil {
+		return 0
+	}
+	return len(k.keys)
+}
+
+// Primary returns the key new material is signed with.
+func (k *Keyring) Primary() (Key, bool) {
+	if k.Len() == 0 {
+		return Key{}, false
+	}
+	return k.keys[0], true
+}
+
+// Lookup returns the key with id.
+func (k *Keyring) Lookup(id string) (Key, bool) {
+	if k == nil {
+		return Key{}, false
+	}
+	for _, key := range k.keys {
+		if key.ID == id {
+			return key, true
+		}
+	}
+	return Key{}, false
+}
+
+// Keys returns every key, primary first.
+func (k *Keyring) Keys() []Key {
+	if k == nil {
+		return nil
+	}
+	return append([]Key(nil), k.keys...)
+}
+
+// IDs returns the key ids, primary first, for logging without exposing the
+// secrets.
+func (k *Keyring) IDs() []string {
+	ids := make([]string, 0, k.Len())
+	for _, key := range k.Keys() {
+		ids = append(ids, key.ID)
+	}
+	return ids
+}
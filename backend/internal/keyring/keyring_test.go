@@ -0,0 +1,38 @@
+package keyring
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	k, err := Parse(" 2025-06:new:secret , 2025-01:old ")
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	primary, ok := k.Primary()
+	if !ok || primary.ID != "2025-06" || string(primary.Secret) != "new:secret" {
+		t.Fatalf("unexpected primary key %+v", primary)
+	}
+	if old, ok := k.Lookup("2025-01"); !ok || string(old.Secret) != "old" {
+		t.Fatalf("expected to find the previous key, got %+v", old)
+	}
+
+	for _, spec := range []string{"bare-secret", "a:one,a:two", ":secret", "a:"} {
+		if _, err := Parse(spec); err == nil {
+			t.Fatalf("expected %q to be rejected", spec)
+		}
+	}
+}
+
+func TestLoadFallsBackToLegacySecret(t *testing.T) {
+	k, err := Load("", "legacy")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if key, ok := k.Primary(); !ok || key.ID != DefaultID || string(key.Secret) != "legacy" {
+		t.Fatalf("unexpected legacy key %+v", key)
+	}
+
+	empty, err := Load("", "")
+	if err != nil || empty.Len() != 0 {
+		t.Fatalf("expected an empty keyring, got %d keys (err %v)", empty.Len(), err)
+	}
+}
@@ -0,0 +1,110 @@
+// Package mail sends transactional email such as verification links.
+package mail
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"mime/multipart"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+)
+
+// Message is a plain-text email, optionally with an HTML version.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+	// HTML, when set, is sent alongside Body as multipart/alternative.
+	HTML string
+	// ReplyTo, when set, is where replies are addressed instead of the
+	// sender.
+	ReplyTo string
+}
+
+// Mailer delivers messages.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// SMTPMailer sends mail through an SMTP relay.
+type SMTPMailer struct {
+	Addr     string
+	Username string
+	Password string
+	From     string
+}
+
+// Send delivers msg through the relay, authenticating when credentials are
+// configured.
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	if strings.ContainsAny(msg.To+msg.Subject+msg.ReplyTo, "\r\n") {
+		return fmt.Errorf("mail: header values must not contain newlines")
+	}
+
+	var auth smtp.Auth
+	if m.Username != "" {
+		host, _, err := net.SplitHostPort(m.Addr)
+		if err != nil {
+			return fmt.Errorf("mail: invalid smtp address %q: %w", m.Addr, err)
+		}
+		auth = smtp.PlainAuth("", m.Username, m.Password, host)
+	}
+
+	contentType, content := "text/plain; charset=utf-8", strings.ReplaceAll(msg.Body, "\n", "\r\n")
+	if msg.HTML != "" {
+		contentType, content = alternative(msg)
+	}
+	replyTo := ""
+	if msg.ReplyTo != "" {
+		replyTo = "Reply-To: " + msg.ReplyTo + "\r\n"
+	}
+	body := fmt.Sprintf("From: %s\r\nTo: %s\r\n%sSubject: %s\r\nMIME-Version: 1.0\r\nContent-Type: %s\r\n\r\n%s",
+		m.From, msg.To, replyTo, msg.Subject, contentType, content)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(m.Addr, auth, m.From, []string{msg.To}, []byte(body))
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			return fmt.Errorf("mail: send to %s: %w", msg.To, err)
+		}
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// alternative encodes the plain and HTML bodies of msg as a
+// multipart/alternative message, returning its content type and body.
+func alternative(msg Message) (contentType, body string) {
+	var b strings.Builder
+	w := multipart.NewWriter(&b)
+	for _, part := range []struct{ contentType, content string }{
+		{"text/plain; charset=utf-8", msg.Body},
+		{"text/html; charset=utf-8", msg.HTML},
+	} {
+		pw, err := w.CreatePart(textproto.MIMEHeader{"Content-Type": {part.contentType}})
+		if err != nil {
+			// Writing to a strings.Builder cannot fail.
+			continue
+		}
+		pw.Write([]byte(strings.ReplaceAll(part.content, "\n", "\r\n")))
+	}
+	w.Close()
+	return "multipart/alternative; boundary=" + w.Boundary(), b.String()
+}
+
+// LogMailer writes messages to the log instead of sending them, for local
+// development without an SMTP relay.
+type LogMailer struct{}
+
+// Send logs msg.
+func (LogMailer) Send(ctx context.Context, msg Message) error {
+	log.Printf("[mail] To: %s | Subject: %s\n%s", msg.To, msg.Subject, msg.Body)
+	return nil
+}
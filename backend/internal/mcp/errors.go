@@ -0,0 +1,136 @@
+package mcp
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrorCode classifies a failed tool call. It is sent to clients with the
+// result so they can branch on the kind of failure instead of parsing the
+// message.
+type ErrorCode string
+
+const (
+	// ErrorAuthExpired means the tenant's Jira credentials were rejected;
+	// retrying will not help until they are replaced.
+	ErrorAuthExpired ErrorCode = "auth_expired"
+	// ErrorJiraRateLimited means Jira is throttling the tenant; the call can
+	// be retried after RetryAfter.
+	ErrorJiraRateLimited ErrorCode = "jira_rate_limited"
+	// ErrorFieldValidation means arguments or Jira fields were rejected; the
+	// call can be retried with corrected values.
+	ErrorFieldValidation ErrorCode = "field_validation"
+	// ErrorNotFound means the issue, project or other object does not exist
+	// or is not visible to the tenant's Jira account.
+	ErrorNotFound ErrorCode = "not_found"
+	// ErrorQuotaExceeded means the tenant used up a plan quota.
+	ErrorQuotaExceeded ErrorCode = "quota_exceeded"
+	// ErrorPermissionDenied means the tenant's Jira account lacks a project
+	// permission the call needs.
+	ErrorPermissionDenied ErrorCode = "permission_denied"
+	// ErrorToolFailed is any other failure.
+	ErrorToolFailed ErrorCode = "tool_failed"
+)
+
+// defaultHints are the remediation hints sent for each code when the
+// ToolError does not carry its own.
+var defaultHints = map[ErrorCode]string{
+	ErrorAuthExpired:      "The Jira credentials were rejected. Ask the user to reconnect Jira or update the API token in their settings; retrying will not help until then.",
+	ErrorJiraRateLimited:  "Jira is rate limiting requests. Wait before retrying and avoid issuing calls in parallel.",
+	ErrorFieldValidation:  "Fix the listed arguments or fields and call the tool again; the tool's input schema is in tools/list.",
+	ErrorNotFound:         "Check the key or ID. It may not exist, or the connected Jira account may not have permission to see it; search to find the right one.",
+	ErrorQuotaExceeded:    "The account has used up its plan quota. Tell the user; calls will keep failing until the quota resets or the plan is upgraded.",
+	ErrorPermissionDenied: "The connected Jira account lacks the named project permission. Ask the user or a Jira admin to grant it; retrying will not help until then.",
+}
+
+// ToolError is a tool failure of a known kind. Tool handlers return one
+// (possibly wrapped) to have the failure reported to clients with its code,
+// a remediation hint and, for validation failures, the offending fields.
+type ToolError struct {
+	Code    ErrorCode
+	Message string
+	// Hint tells the client how to recover; the code's default hint is used
+	// when empty.
+	Hint string
+	// Fields maps argument or Jira field names to what is wrong with them.
+	Fields map[string]string
+	// RetryAfter is how long to wait before retrying, zero when unknown.
+	RetryAfter time.Duration
+	// Err is the underlying error, if any.
+	Err error
+}
+
+// NewToolError returns a ToolError with code and message.
+func NewToolError(code ErrorCode, format string, args ...any) *ToolError {
+	return &ToolError{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+func (e *ToolError) Error() string {
+	return e.Message
+}
+
+func (e *ToolError) Unwrap() error {
+	return e.Err
+}
+
+// AsToolError returns the ToolError in err's chain, or a ToolError with
+// code ErrorToolFailed. The message is err's, so context added while
+// wrapping, or the other errors joined with it, is kept.
+func AsToolError(err error) *ToolError {
+	var toolErr *ToolError
+	if !errors.As(err, &toolErr) {
+		return &ToolError{Code: ErrorToolFailed, Message: err.Error(), Err: err}
+	}
+	if toolErr == err {
+		return toolErr
+	}
+	wrapped := *toolErr
+	wrapped.Message = err.Error()
+	wrapped.Err = err
+	return &wrapped
+}
+
+// result is the tools/call result reporting the failure. The text leads
+// with the message so models read it first; structuredContent carries the
+// same details for clients that branch on the code.
+func (e *ToolError) result() map[string]any {
+	hint := e.Hint
+	if hint == "" {
+		hint = defaultHints[e.Code]
+	}
+
+	lines := []string{e.Message}
+	if len(e.Fields) > 0 {
+		names := make([]string, 0, len(e.Fields))
+		for name := range e.Fields {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			lines = append(lines, "- "+name+": "+e.Fields[name])
+		}
+	}
+
+	detail := map[string]any{"code": e.Code, "message": e.Message}
+	if hint != "" {
+		detail["hint"] = hint
+		lines = append(lines, "", fmt.Sprintf("Error code: %s. %s", e.Code, hint))
+	}
+	if len(e.Fields) > 0 {
+		detail["fields"] = e.Fields
+	}
+	if e.RetryAfter > 0 {
+		seconds := int((e.RetryAfter + time.Second - 1) / time.Second)
+		detail["retryAfterSeconds"] = seconds
+		lines = append(lines, fmt.Sprintf("Retry after %d seconds.", seconds))
+	}
+
+	return map[string]any{
+		"content":           []map[string]string{{"type": "text", "text": strings.Join(lines, "\n")}},
+		"isError":           true,
+		"structuredContent": map[string]any{"error": detail},
+	}
+}
@@ -0,0 +1,475 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SessionIdleTimeout is how long an HTTP session is kept without requests
+// or an open stream before it is forgotten.
+const SessionIdleTimeout = time.Hour
+
+// sseKeepAlive is how often an idle event stream gets a comment, so proxies
+// do not close it.
+const sseKeepAlive = 30 * time.Second
+
+// streamBuffer is how many notifications a slow event stream may fall
+// behind before further ones are dropped.
+const streamBuffer = 16
+
+// HTTPHandler serves a Server over the streamable HTTP transport. Clients
+// POST JSON-RPC messages and get the responses back as JSON; a GET opens an
+// event stream of server-initiated messages for the session. The session,
+// issued in the Mcp-Session-Id header of the initialize response, belongs to
+// the caller owner returns and is ended with a DELETE.
+type HTTPHandler struct {
+	server *Server
+	owner  func(r *http.Request) string
+	store  SessionStore
+
+	mu       sync.Mutex
+	sessions map[string]*httpSession
+}
+
+type httpSession struct {
+	state   SessionState
+	saved   time.Time
+	streams map[chan []byte]struct{}
+}
+
+// NewHTTPHandler serves s over HTTP. owner identifies the caller, for
+// example their tenant; requests with an empty owner are refused, and a
+// session is only usable by the owner that created it.
+func NewHTTPHandler(s *Server, owner func(r *http.Request) string) *HTTPHandler {
+	return &HTTPHandler{server: s, owner: owner, sessions: map[string]*httpSession{}}
+}
+
+// SetSessionStore persists sessions in store. Sessions then survive
+// restarts and are shared with other processes using the same store, and
+// the responses of tool calls whose client disconnected are kept and
+// delivered on the session's next event stream.
+func (h *HTTPHandler) SetSessionStore(store SessionStore) {
+	h.store = store
+}
+
+// ServeHTTP implements http.Handler.
+func (h *HTTPHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	owner := h.owner(r)
+	if owner == "" {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if version := r.Header.Get("Mcp-Protocol-Version"); version != "" && !supportedProtocolVersions[version] {
+		http.Error(w, "unsupported MCP-Protocol-Version "+version, http.StatusBadRequest)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPost:
+		h.post(w, r, owner)
+	case http.MethodGet:
+		h.stream(w, r, owner)
+	case http.MethodDelete:
+		id, ok := h.session(w, r, owner)
+		if !ok {
+			return
+		}
+		h.mu.Lock()
+		if sess := h.sessions[id]; sess != nil {
+			for ch := range sess.streams {
+				close(ch)
+			}
+			delete(h.sessions, id)
+		}
+		h.mu.Unlock()
+		if h.store != nil {
+			if err := h.store.DeleteSession(r.Context(), id); err != nil {
+				log.Printf("mcp: failed to delete session %s: %v", id, err)
+			}
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.Header().Set("Allow", "GET, POST, DELETE")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// session returns the caller's session from the Mcp-Session-Id header and
+// marks it used, or writes the error and returns false. A session this
+// process does not hold is resumed from the session store.
+func (h *HTTPHandler) session(w http.ResponseWriter, r *http.Request, owner string) (string, bool) {
+	id := r.Header.Get("Mcp-Session-Id")
+	if id == "" {
+		http.Error(w, "Mcp-Session-Id header is required", http.StatusBadRequest)
+		return "", false
+	}
+
+	h.mu.Lock()
+	sess := h.sessions[id]
+	h.mu.Unlock()
+	if sess == nil {
+		sess = h.resume(r.Context(), id)
+	}
+	if sess == nil || sess.state.Owner != owner {
+		http.Error(w, "session not found", http.StatusNotFound)
+		return "", false
+	}
+	if version := r.Header.Get("Mcp-Protocol-Version"); version != "" && sess.state.ProtocolVersion != "" && version != sess.state.ProtocolVersion {
+		http.Error(w, "MCP-Protocol-Version does not match the session's "+sess.state.ProtocolVersion, http.StatusBadRequest)
+		return "", false
+	}
+
+	h.mu.Lock()
+	now := time.Now()
+	sess.state.LastSeen = now
+	var save *SessionState
+	if h.store != nil && now.Sub(sess.saved) >= sessionTouchInterval {
+		sess.saved = now
+		state := sess.state
+		save = &state
+	}
+	h.mu.Unlock()
+	if save != nil {
+		if err := h.store.SaveSession(r.Context(), *save); err != nil {
+			log.Printf("mcp: failed to save session %s: %v", id, err)
+		}
+	}
+	return id, true
+}
+
+// resume loads a session from the session store into this process, or
+// returns nil when there is none.
+func (h *HTTPHandler) resume(ctx context.Context, id string) *httpSession {
+	if h.store == nil {
+		return nil
+	}
+	state, err := h.store.LoadSession(ctx, id)
+	if err != nil {
+		log.Printf("mcp: failed to load session %s: %v", id, err)
+		return nil
+	}
+	if state == nil {
+		return nil
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if sess := h.sessions[id]; sess != nil {
+		return sess
+	}
+	sess := &httpSession{state: *state, saved: state.LastSeen, streams: map[chan []byte]struct{}{}}
+	h.sessions[id] = sess
+	return sess
+}
+
+// post answers a single JSON-RPC message or a batch of them.
+func (h *HTTPHandler) post(w http.ResponseWriter, r *http.Request, owner string) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxMessageBytes))
+	if err != nil {
+		http.Error(w, "message too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	body = bytes.TrimSpace(body)
+
+	var messages []json.RawMessage
+	batch := len(body) > 0 && body[0] == '['
+	if batch {
+		if err := json.Unmarshal(body, &messages); err != nil || len(messages) == 0 {
+			writeMessages(w, false, []response{{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &rpcError{Code: codeInvalidRequest, Message: "invalid batch"}}})
+			return
+		}
+	} else {
+		messages = []json.RawMessage{body}
+	}
+
+	var (
+		requests  []request
+		responses []response
+		initReq   *request
+	)
+	for _, message := range messages {
+		req, resp, ok := parseMessage(message)
+		if resp != nil {
+			responses = append(responses, *resp)
+		}
+		if ok {
+			requests = append(requests, req)
+			if req.Method == "initialize" {
+				initReq = &req
+			}
+		}
+	}
+
+	var id string
+	if initReq != nil {
+		id = h.newSession(r.Context(), owner, initReq.Params)
+		w.Header().Set("Mcp-Session-Id", id)
+	} else {
+		var ok bool
+		if id, ok = h.session(w, r, owner); !ok {
+			return
+		}
+	}
+
+	// With a session store, tool calls outlive a client that disconnects
+	// and their responses are kept for the session's next event stream.
+	ctx := r.Context()
+	var tracked []json.RawMessage
+	if h.store != nil {
+		ctx = context.WithoutCancel(ctx)
+		if deadline, ok := r.Context().Deadline(); ok {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithDeadline(ctx, deadline)
+			defer cancel()
+		}
+		for _, req := range requests {
+			if req.Method != "tools/call" {
+				continue
+			}
+			if err := h.store.StartCall(ctx, id, req.ID); err != nil {
+				log.Printf("mcp: failed to record call %s of session %s: %v", req.ID, id, err)
+				continue
+			}
+			tracked = append(tracked, req.ID)
+		}
+	}
+
+	for _, req := range requests {
+		responses = append(responses, h.server.respond(ctx, req))
+	}
+	if len(responses) == 0 {
+		// Only notifications or responses.
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	delivered := r.Context().Err() == nil && writeMessages(w, batch, responses) == nil
+	for _, requestID := range tracked {
+		var err error
+		if delivered {
+			err = h.store.ForgetCall(ctx, id, requestID)
+		} else {
+			err = h.store.FinishCall(ctx, id, requestID, responseFor(responses, requestID))
+		}
+		if err != nil {
+			log.Printf("mcp: failed to update call %s of session %s: %v", requestID, id, err)
+		}
+	}
+}
+
+// newSession starts a session for owner, negotiated by the initialize
+// params, and forgets idle ones.
+func (h *HTTPHandler) newSession(ctx context.Context, owner string, params json.RawMessage) string {
+	var init struct {
+		ProtocolVersion string          `json:"protocolVersion"`
+		Capabilities    json.RawMessage `json:"capabilities"`
+		ClientInfo      json.RawMessage `json:"clientInfo"`
+	}
+	json.Unmarshal(params, &init)
+
+	buf := make([]byte, 16)
+	rand.Read(buf)
+	now := time.Now()
+	state := SessionState{
+		ID:                 hex.EncodeToString(buf),
+		Owner:              owner,
+		ProtocolVersion:    negotiateVersion(init.ProtocolVersion),
+		ClientInfo:         init.ClientInfo,
+		ClientCapabilities: init.Capabilities,
+		LastSeen:           now,
+	}
+
+	h.mu.Lock()
+	for id, sess := range h.sessions {
+		if len(sess.streams) == 0 && now.Sub(sess.state.LastSeen) > SessionIdleTimeout {
+			delete(h.sessions, id)
+		}
+	}
+	h.sessions[state.ID] = &httpSession{state: state, saved: now, streams: map[chan []byte]struct{}{}}
+	h.mu.Unlock()
+
+	if h.store != nil {
+		// The session still works in this process if it cannot be saved.
+		if err := h.store.SaveSession(ctx, state); err != nil {
+			log.Printf("mcp: failed to save session %s: %v", state.ID, err)
+		}
+	}
+	return state.ID
+}
+
+// responseFor returns the encoded response to the request with requestID.
+func responseFor(responses []response, requestID json.RawMessage) json.RawMessage {
+	for _, resp := range responses {
+		if bytes.Equal(resp.ID, requestID) {
+			data, _ := json.Marshal(resp)
+			return data
+		}
+	}
+	return nil
+}
+
+func writeMessages(w http.ResponseWriter, batch bool, responses []response) error {
+	var body any = responses
+	if !batch {
+		body = responses[0]
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.Printf("mcp: failed to encode response: %v", err)
+		return err
+	}
+	return nil
+}
+
+// stream sends the session's server-initiated messages as server-sent
+// events until the client disconnects or the session ends.
+func (h *HTTPHandler) stream(w http.ResponseWriter, r *http.Request, owner string) {
+	if !strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		w.Header().Set("Allow", "POST, DELETE")
+		http.Error(w, "GET requires Accept: text/event-stream", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, ok := h.session(w, r, owner)
+	if !ok {
+		return
+	}
+	ch := make(chan []byte, streamBuffer)
+	h.mu.Lock()
+	sess := h.sessions[id]
+	if sess == nil {
+		h.mu.Unlock()
+		http.Error(w, "session not found", http.StatusNotFound)
+		return
+	}
+	sess.streams[ch] = struct{}{}
+	h.mu.Unlock()
+
+	defer func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		if sess := h.sessions[id]; sess != nil {
+			if _, open := sess.streams[ch]; open {
+				delete(sess.streams, ch)
+				sess.state.LastSeen = time.Now()
+			}
+		}
+	}()
+
+	// The stream outlives the server's write timeout.
+	rc := http.NewResponseController(w)
+	rc.SetWriteDeadline(time.Time{})
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		log.Printf("mcp: event stream cannot be flushed: %v", err)
+		return
+	}
+
+	if !h.replay(r.Context(), id, w) {
+		return
+	}
+
+	keepAlive := time.NewTicker(sseKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case data, open := <-ch:
+			if !open {
+				return
+			}
+			if _, err := w.Write([]byte("event: message\ndata: " + string(data) + "\n\n")); err != nil {
+				return
+			}
+		case <-keepAlive.C:
+			if _, err := w.Write([]byte(": keep-alive\n\n")); err != nil {
+				return
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// replay sends the responses of the session's tool calls that did not reach
+// the client, and fails calls presumed lost with the process that ran them.
+// It reports whether the stream is still writable.
+func (h *HTTPHandler) replay(ctx context.Context, id string, w http.ResponseWriter) bool {
+	if h.store == nil {
+		return true
+	}
+	calls, err := h.store.PendingCalls(ctx, id)
+	if err != nil {
+		log.Printf("mcp: failed to load pending calls of session %s: %v", id, err)
+		return true
+	}
+	for _, call := range calls {
+		data := call.Response
+		if data == nil {
+			if time.Since(call.StartedAt) < interruptedCallAge {
+				continue
+			}
+			data, _ = json.Marshal(response{JSONRPC: "2.0", ID: call.RequestID, Error: &rpcError{
+				Code: codeInternalError, Message: "the tool call was interrupted; retry it",
+			}})
+		}
+		if _, err := w.Write([]byte("event: message\ndata: " + string(data) + "\n\n")); err != nil {
+			return false
+		}
+		if err := h.store.ForgetCall(ctx, id, call.RequestID); err != nil {
+			log.Printf("mcp: failed to forget call %s of session %s: %v", call.RequestID, id, err)
+		}
+	}
+	return http.NewResponseController(w).Flush() == nil
+}
+
+// CloseStreams ends every open event stream. Call it when the server shuts
+// down, since streams never finish on their own; clients reconnect.
+func (h *HTTPHandler) CloseStreams() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, sess := range h.sessions {
+		for ch := range sess.streams {
+			close(ch)
+			delete(sess.streams, ch)
+		}
+	}
+}
+
+// Notify sends a notification to the open event streams of owner's
+// sessions. Sessions without an open stream miss it, and a stream that has
+// fallen behind drops it.
+func (h *HTTPHandler) Notify(owner, method string, params any) {
+	data, err := json.Marshal(map[string]any{"jsonrpc": "2.0", "method": method, "params": params})
+	if err != nil {
+		log.Printf("mcp: failed to encode %s notification: %v", method, err)
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, sess := range h.sessions {
+		if sess.state.Owner != owner {
+			continue
+		}
+		for ch := range sess.streams {
+			select {
+			case ch <- data:
+			default:
+			}
+		}
+	}
+}
@@ -0,0 +1,256 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func postMCP(t *testing.T, url, owner, session, body string) *http.Response {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodPost, url, strings.NewReader(body))
+	req.Header.Set("X-Owner", owner)
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	if session != "" {
+		req.Header.Set("Mcp-Session-Id", session)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("POST: %v", err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func TestHTTPHandlerSessions(t *testing.T) {
+	echo := Tool{Name: "echo", Handler: func(ctx context.Context, args json.RawMessage) (any, error) {
+		return "pong", nil
+	}}
+	handler := NewHTTPHandler(NewServer("test", "1.0", echo), func(r *http.Request) string {
+		return r.Header.Get("X-Owner")
+	})
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	if resp := postMCP(t, srv.URL, "", "", `{"jsonrpc":"2.0","id":1,"method":"ping"}`); resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without an owner, got %d", resp.StatusCode)
+	}
+
+	resp := postMCP(t, srv.URL, "alice", "", `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-06-18"}}`)
+	session := resp.Header.Get("Mcp-Session-Id")
+	if resp.StatusCode != http.StatusOK || session == "" {
+		t.Fatalf("expected a session from initialize, got %d %q", resp.StatusCode, session)
+	}
+
+	if resp := postMCP(t, srv.URL, "alice", session, `{"jsonrpc":"2.0","method":"notifications/initialized"}`); resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("expected 202 for a notification, got %d", resp.StatusCode)
+	}
+	if resp := postMCP(t, srv.URL, "alice", "", `{"jsonrpc":"2.0","id":2,"method":"ping"}`); resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 without a session, got %d", resp.StatusCode)
+	}
+	if resp := postMCP(t, srv.URL, "mallory", session, `{"jsonrpc":"2.0","id":2,"method":"ping"}`); resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404 for another owner's session, got %d", resp.StatusCode)
+	}
+
+	resp = postMCP(t, srv.URL, "alice", session, `[{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"echo"}},{"jsonrpc":"2.0","id":4,"method":"ping"}]`)
+	var batch []map[string]any
+	if err := json.NewDecoder(resp.Body).Decode(&batch); err != nil || len(batch) != 2 {
+		t.Fatalf("expected two batched responses, got %v (%v)", batch, err)
+	}
+	if text := batch[0]["result"].(map[string]any)["content"].([]any)[0].(map[string]any)["text"]; text != "pong" {
+		t.Fatalf("unexpected tool result %v", batch[0])
+	}
+
+	// Server-initiated messages arrive on the session's event stream.
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	req.Header.Set("X-Owner", "alice")
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("Mcp-Session-Id", session)
+	stream, err := http.DefaultClient.Do(req)
+	if err != nil || stream.StatusCode != http.StatusOK {
+		t.Fatalf("GET stream: %v %v", err, stream)
+	}
+	defer stream.Body.Close()
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		handler.mu.Lock()
+		open := len(handler.sessions[session].streams)
+		handler.mu.Unlock()
+		if open == 1 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	handler.Notify("mallory", "notifications/message", map[string]any{"data": "not for alice"})
+	handler.Notify("alice", "notifications/message", map[string]any{"data": "hello"})
+
+	reader := bufio.NewReader(stream.Body)
+	var data string
+	for data == "" {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("read stream: %v", err)
+		}
+		if rest, ok := strings.CutPrefix(line, "data: "); ok {
+			data = strings.TrimSpace(rest)
+		}
+	}
+	if !strings.Contains(data, `"hello"`) {
+		t.Fatalf("expected alice's notification, got %s", data)
+	}
+
+	req, _ = http.NewRequest(http.MethodDelete, srv.URL, nil)
+	req.Header.Set("X-Owner", "alice")
+	req.Header.Set("Mcp-Session-Id", session)
+	if resp, err := http.DefaultClient.Do(req); err != nil || resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("DELETE: %v %v", err, resp)
+	}
+	if resp := postMCP(t, srv.URL, "alice", session, `{"jsonrpc":"2.0","id":5,"method":"ping"}`); resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404 after the session ended, got %d", resp.StatusCode)
+	}
+}
+
+type memorySessionStore struct {
+	mu       sync.Mutex
+	sessions map[string]SessionState
+	calls    map[string][]PendingCall
+}
+
+func (m *memorySessionStore) SaveSession(ctx context.Context, state SessionState) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sessions[state.ID] = state
+	return nil
+}
+
+func (m *memorySessionStore) LoadSession(ctx context.Context, id string) (*SessionState, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	state, ok := m.sessions[id]
+	if !ok {
+		return nil, nil
+	}
+	return &state, nil
+}
+
+func (m *memorySessionStore) DeleteSession(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.sessions, id)
+	delete(m.calls, id)
+	return nil
+}
+
+func (m *memorySessionStore) StartCall(ctx context.Context, sessionID string, requestID json.RawMessage) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.calls[sessionID] = append(m.calls[sessionID], PendingCall{RequestID: requestID, StartedAt: time.Now()})
+	return nil
+}
+
+func (m *memorySessionStore) FinishCall(ctx context.Context, sessionID string, requestID, response json.RawMessage) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, call := range m.calls[sessionID] {
+		if string(call.RequestID) == string(requestID) {
+			m.calls[sessionID][i].Response = response
+		}
+	}
+	return nil
+}
+
+func (m *memorySessionStore) ForgetCall(ctx context.Context, sessionID string, requestID json.RawMessage) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	calls := m.calls[sessionID][:0]
+	for _, call := range m.calls[sessionID] {
+		if string(call.RequestID) != string(requestID) {
+			calls = append(calls, call)
+		}
+	}
+	m.calls[sessionID] = calls
+	return nil
+}
+
+func (m *memorySessionStore) PendingCalls(ctx context.Context, sessionID string) ([]PendingCall, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]PendingCall(nil), m.calls[sessionID]...), nil
+}
+
+func TestHTTPHandlerResumesSessions(t *testing.T) {
+	store := &memorySessionStore{sessions: map[string]SessionState{}, calls: map[string][]PendingCall{}}
+	var disconnect context.CancelFunc
+	slow := Tool{Name: "slow", Handler: func(ctx context.Context, args json.RawMessage) (any, error) {
+		// The client goes away while the call runs.
+		disconnect()
+		return "finished", ctx.Err()
+	}}
+	owner := func(r *http.Request) string { return r.Header.Get("X-Owner") }
+	before := NewHTTPHandler(NewServer("test", "1.0", slow), owner)
+	before.SetSessionStore(store)
+
+	srv := httptest.NewServer(before)
+	resp := postMCP(t, srv.URL, "alice", "", `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26","capabilities":{"roots":{}}}}`)
+	srv.Close()
+	session := resp.Header.Get("Mcp-Session-Id")
+	if state := store.sessions[session]; state.ProtocolVersion != "2025-03-26" || string(state.ClientCapabilities) != `{"roots":{}}` {
+		t.Fatalf("expected the negotiated session to be saved, got %+v", state)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	disconnect = cancel
+	req := httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(`{"jsonrpc":"2.0","id":7,"method":"tools/call","params":{"name":"slow"}}`)).WithContext(ctx)
+	req.Header.Set("X-Owner", "alice")
+	req.Header.Set("Mcp-Session-Id", session)
+	before.ServeHTTP(httptest.NewRecorder(), req)
+
+	// A new process resumes the session and delivers the lost response.
+	after := NewHTTPHandler(NewServer("test", "1.0", slow), owner)
+	after.SetSessionStore(store)
+	srv = httptest.NewServer(after)
+	defer srv.Close()
+
+	if resp := postMCP(t, srv.URL, "mallory", session, `{"jsonrpc":"2.0","id":2,"method":"ping"}`); resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404 for another owner, got %d", resp.StatusCode)
+	}
+	if resp := postMCP(t, srv.URL, "alice", session, `{"jsonrpc":"2.0","id":2,"method":"ping"}`); resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected the session to resume, got %d", resp.StatusCode)
+	}
+
+	req, _ = http.NewRequest(http.MethodGet, srv.URL, nil)
+	req.Header.Set("X-Owner", "alice")
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("Mcp-Session-Id", session)
+	req.Header.Set("Mcp-Protocol-Version", "2025-03-26")
+	stream, err := http.DefaultClient.Do(req)
+	if err != nil || stream.StatusCode != http.StatusOK {
+		t.Fatalf("GET stream: %v %v", err, stream)
+	}
+	defer stream.Body.Close()
+
+	reader := bufio.NewReader(stream.Body)
+	var data string
+	for data == "" {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("read stream: %v", err)
+		}
+		if rest, ok := strings.CutPrefix(line, "data: "); ok {
+			data = strings.TrimSpace(rest)
+		}
+	}
+	if !strings.Contains(data, `"id":7`) || !strings.Contains(data, "finished") {
+		t.Fatalf("expected the response to call 7, got %s", data)
+	}
+	if calls, _ := store.PendingCalls(context.Background(), session); len(calls) != 0 {
+		t.Fatalf("expected the delivered call to be forgotten, got %v", calls)
+	}
+}
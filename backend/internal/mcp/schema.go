@@ -0,0 +1,176 @@
+package mcp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// schema is the subset of JSON Schema tool arguments are checked against:
+// type, properties, required, additionalProperties (as a boolean), items,
+// enum, minimum, maximum and minLength. Other keywords are passed on to
+// clients but not enforced.
+type schema struct {
+	Type                 string             `json:"type"`
+	Properties           map[string]*schema `json:"properties"`
+	Required             []string           `json:"required"`
+	AdditionalProperties *bool              `json:"-"`
+	Items                *schema            `json:"items"`
+	Enum                 []any              `json:"enum"`
+	Minimum              *float64           `json:"minimum"`
+	Maximum              *float64           `json:"maximum"`
+	MinLength            *int               `json:"minLength"`
+}
+
+func (s *schema) UnmarshalJSON(data []byte) error {
+	type plain schema
+	var raw struct {
+		plain
+		AdditionalProperties json.RawMessage `json:"additionalProperties"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*s = schema(raw.plain)
+	// A schema for additional properties is not enforced.
+	var allowed bool
+	if json.Unmarshal(raw.AdditionalProperties, &allowed) == nil {
+		s.AdditionalProperties = &allowed
+	}
+	return nil
+}
+
+// compileSchema parses a tool's input schema.
+func compileSchema(data json.RawMessage) (*schema, error) {
+	var s schema
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// validate checks tool arguments against the schema and returns an error
+// naming the first offending argument.
+func (s *schema) validate(args json.RawMessage) error {
+	dec := json.NewDecoder(bytes.NewReader(args))
+	dec.UseNumber()
+	var value any
+	if err := dec.Decode(&value); err != nil {
+		return fmt.Errorf("arguments are not valid JSON: %w", err)
+	}
+	return s.check("arguments", value)
+}
+
+func (s *schema) check(path string, value any) error {
+	if s == nil {
+		return nil
+	}
+	if s.Type != "" && !hasType(value, s.Type) {
+		return fmt.Errorf("%s must be %s", path, article(s.Type))
+	}
+	if len(s.Enum) > 0 && !slices.ContainsFunc(s.Enum, func(option any) bool { return sameValue(option, value) }) {
+		options := make([]string, len(s.Enum))
+		for i, option := range s.Enum {
+			data, _ := json.Marshal(option)
+			options[i] = string(data)
+		}
+		return fmt.Errorf("%s must be one of %s", path, strings.Join(options, ", "))
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				return fmt.Errorf("%s is required", childPath(path, name))
+			}
+		}
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			prop, known := s.Properties[name]
+			if !known && s.AdditionalProperties != nil && !*s.AdditionalProperties {
+				return fmt.Errorf("%s is not a known argument", childPath(path, name))
+			}
+			if err := prop.check(childPath(path, name), v[name]); err != nil {
+				return err
+			}
+		}
+	case []any:
+		for i, item := range v {
+			if err := s.Items.check(fmt.Sprintf("%s[%d]", path, i), item); err != nil {
+				return err
+			}
+		}
+	case string:
+		if s.MinLength != nil && len([]rune(v)) < *s.MinLength {
+			if *s.MinLength == 1 {
+				return fmt.Errorf("%s must not be empty", path)
+			}
+			return fmt.Errorf("%s must be at least %d characters", path, *s.MinLength)
+		}
+	case json.Number:
+		n, _ := v.Float64()
+		if s.Minimum != nil && n < *s.Minimum {
+			return fmt.Errorf("%s must be at least %v", path, *s.Minimum)
+		}
+		if s.Maximum != nil && n > *s.Maximum {
+			return fmt.Errorf("%s must be at most %v", path, *s.Maximum)
+		}
+	}
+	return nil
+}
+
+func hasType(value any, typ string) bool {
+	switch v := value.(type) {
+	case map[string]any:
+		return typ == "object"
+	case []any:
+		return typ == "array"
+	case string:
+		return typ == "string"
+	case bool:
+		return typ == "boolean"
+	case nil:
+		return typ == "null"
+	case json.Number:
+		if typ == "number" {
+			return true
+		}
+		if typ == "integer" {
+			_, err := v.Int64()
+			return err == nil
+		}
+	}
+	return false
+}
+
+func sameValue(a, b any) bool {
+	if n, ok := b.(json.Number); ok {
+		f, _ := n.Float64()
+		b = f
+	}
+	ja, _ := json.Marshal(a)
+	jb, _ := json.Marshal(b)
+	return bytes.Equal(ja, jb)
+}
+
+func childPath(path, name string) string {
+	if path == "arguments" {
+		return name
+	}
+	return path + "." + name
+}
+
+func article(typ string) string {
+	switch typ {
+	case "object", "array", "integer":
+		return "an " + typ
+	}
+	return "a " + typ
+}
@@ -0,0 +1,308 @@
+// Package mcp implements the server side of the Model Context Protocol:
+// JSON-RPC 2.0 messages over a newline-delimited stream such as stdio, or
+// over the streamable HTTP transport, covering initialize, ping, tools/list
+// and tools/call.
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"time"
+)
+
+// LatestProtocolVersion is the newest MCP revision the server speaks. A
+// client asking for another supported revision gets that one back.
+const LatestProtocolVersion = "2025-06-18"
+
+var supportedProtocolVersions = map[string]bool{
+	"2024-11-05":          true,
+	"2025-03-26":          true,
+	LatestProtocolVersion: true,
+}
+
+// maxMessageBytes bounds a single incoming message.
+const maxMessageBytes = 4 << 20
+
+// JSON-RPC error codes.
+const (
+	codeParseError     = -32700
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+	codeInternalError  = -32603
+)
+
+// ToolHandler runs a tool with the raw arguments of a tools/call request.
+// The result is sent to the client as JSON text; an error is reported as a
+// tool error the model can see, not as a protocol error, classified by the
+// *ToolError in its chain (ErrorToolFailed when there is none).
+type ToolHandler func(ctx context.Context, args json.RawMessage) (any, error)
+
+// Tool is a tool offered by the server.
+type Tool struct {
+	Name        string
+	Description string
+	// InputSchema is the JSON Schema of the arguments object.
+	InputSchema json.RawMessage
+	Handler     ToolHandler
+}
+
+// CallGuard is consulted around every tools/call with valid arguments, for
+// example to enforce and count plan quotas. An error from BeforeCall
+// refuses the call and is reported like a tool failure.
+type CallGuard interface {
+	BeforeCall(ctx context.Context, tool string) error
+	AfterCall(ctx context.Context, tool string, failed bool, elapsed time.Duration)
+}
+
+// Server answers MCP requests for a fixed set of tools.
+type Server struct {
+	name    string
+	version string
+	tools   []Tool
+	byName  map[string]Tool
+	schemas map[string]*schema
+	guard   CallGuard
+}
+
+// NewServer creates a server that introduces itself as name and version.
+// Tool arguments are validated against each tool's InputSchema before its
+// handler runs. NewServer panics if an InputSchema is not valid JSON.
+func NewServer(name, version string, tools ...Tool) *Server {
+	s := &Server{
+		name:    name,
+		version: version,
+		byName:  make(map[string]Tool, len(tools)),
+		schemas: make(map[string]*schema, len(tools)),
+	}
+	for _, tool := range tools {
+		if tool.InputSchema == nil {
+			tool.InputSchema = json.RawMessage(`{"type":"object"}`)
+		}
+		compiled, err := compileSchema(tool.InputSchema)
+		if err != nil {
+			panic(fmt.Sprintf("mcp: invalid input schema for tool %s: %v", tool.Name, err))
+		}
+		s.tools = append(s.tools, tool)
+		s.byName[tool.Name] = tool
+		s.schemas[tool.Name] = compiled
+	}
+	return s
+}
+
+// SetCallGuard makes the server consult g around tool calls. Call it
+// before serving.
+func (s *Server) SetCallGuard(g CallGuard) {
+	s.guard = g
+}
+
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Serve reads requests from r and writes responses to w until r is
+// exhausted; ctx is passed to tool calls. Requests are handled concurrently, so a
+// slow tool call does not hold up pings; writes are serialized.
+func (s *Server) Serve(ctx context.Context, r io.Reader, w io.Writer) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	send := func(resp response) {
+		data, err := json.Marshal(resp)
+		if err != nil {
+			log.Printf("mcp: failed to encode response: %v", err)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		w.Write(append(data, '\n'))
+	}
+	defer wg.Wait()
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxMessageBytes)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+
+		req, resp, ok := parseMessage(line)
+		if resp != nil {
+			send(*resp)
+		}
+		if !ok {
+			continue
+		}
+
+		wg.Add(1)
+		go func(req request) {
+			defer wg.Done()
+			send(s.respond(ctx, req))
+		}(req)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("mcp: read: %w", err)
+	}
+	return nil
+}
+
+// parseMessage decodes one incoming message. It returns the request and
+// true when the message is a request to answer, or the error response to
+// send for a malformed one; notifications and responses to server-initiated
+// requests yield neither.
+func parseMessage(data []byte) (request, *response, bool) {
+	var req request
+	if err := json.Unmarshal(data, &req); err != nil {
+		return req, &response{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &rpcError{Code: codeParseError, Message: "parse error"}}, false
+	}
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		// Responses to server-initiated requests are not expected.
+		if req.ID != nil && req.Method == "" {
+			return req, nil, false
+		}
+		return req, &response{JSONRPC: "2.0", ID: idOrNull(req.ID), Error: &rpcError{Code: codeInvalidRequest, Message: "invalid request"}}, false
+	}
+	// Notifications (no id) never get a response.
+	if req.ID == nil {
+		return req, nil, false
+	}
+	return req, nil, true
+}
+
+// respond answers a request.
+func (s *Server) respond(ctx context.Context, req request) response {
+	result, rpcErr := s.handle(ctx, req)
+	resp := response{JSONRPC: "2.0", ID: req.ID, Result: result, Error: rpcErr}
+	if rpcErr == nil && result == nil {
+		resp.Result = struct{}{}
+	}
+	return resp
+}
+
+func (s *Server) handle(ctx context.Context, req request) (any, *rpcError) {
+	switch req.Method {
+	case "initialize":
+		var params struct {
+			ProtocolVersion string `json:"protocolVersion"`
+		}
+		json.Unmarshal(req.Params, &params)
+		return map[string]any{
+			"protocolVersion": negotiateVersion(params.ProtocolVersion),
+			"capabilities":    map[string]any{"tools": map[string]any{"listChanged": false}, "logging": map[string]any{}},
+			"serverInfo":      map[string]string{"name": s.name, "version": s.version},
+		}, nil
+
+	case "ping":
+		return nil, nil
+
+	case "logging/setLevel":
+		// Notifications are few, so every level is accepted and none
+		// are filtered.
+		return nil, nil
+
+	case "tools/list":
+		tools := make([]map[string]any, 0, len(s.tools))
+		for _, tool := range s.tools {
+			tools = append(tools, map[string]any{
+				"name":        tool.Name,
+				"description": tool.Description,
+				"inputSchema": tool.InputSchema,
+			})
+		}
+		return map[string]any{"tools": tools}, nil
+
+	case "tools/call":
+		var params struct {
+			Name      string          `json:"name"`
+			Arguments json.RawMessage `json:"arguments"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil || params.Name == "" {
+			return nil, &rpcError{Code: codeInvalidParams, Message: "tools/call requires a tool name"}
+		}
+		tool, ok := s.byName[params.Name]
+		if !ok {
+			return nil, &rpcError{Code: codeInvalidParams, Message: "unknown tool: " + params.Name}
+		}
+		if len(params.Arguments) == 0 || string(params.Arguments) == "null" {
+			params.Arguments = json.RawMessage("{}")
+		}
+		// Invalid arguments are reported as a tool error, like any other
+		// failure, so the model can correct the call.
+		if err := s.schemas[tool.Name].validate(params.Arguments); err != nil {
+			return (&ToolError{Code: ErrorFieldValidation, Message: err.Error(), Err: err}).result(), nil
+		}
+		if s.guard == nil {
+			return callTool(ctx, tool, params.Arguments), nil
+		}
+		if err := s.guard.BeforeCall(ctx, tool.Name); err != nil {
+			return AsToolError(err).result(), nil
+		}
+		started := time.Now()
+		result := callTool(ctx, tool, params.Arguments)
+		s.guard.AfterCall(ctx, tool.Name, result["isError"] == true, time.Since(started))
+		return result, nil
+	}
+	return nil, &rpcError{Code: codeMethodNotFound, Message: "method not found: " + req.Method}
+}
+
+// negotiateVersion returns the protocol revision to use with a client that
+// asked for requested.
+func negotiateVersion(requested string) string {
+	if supportedProtocolVersions[requested] {
+		return requested
+	}
+	return LatestProtocolVersion
+}
+
+// callTool runs a tool and wraps its outcome in a tools/call result.
+func callTool(ctx context.Context, tool Tool, args json.RawMessage) map[string]any {
+	out, err := tool.Handler(ctx, args)
+	if err != nil {
+		return AsToolError(err).result()
+	}
+
+	text, ok := out.(string)
+	if !ok {
+		data, err := json.MarshalIndent(out, "", "  ")
+		if err != nil {
+			return NewToolError(ErrorToolFailed, "failed to encode result: %v", err).result()
+		}
+		text = string(data)
+	}
+	return map[string]any{
+		"content": []map[string]string{{"type": "text", "text": text}},
+		"isError": false,
+	}
+}
+
+func idOrNull(id json.RawMessage) json.RawMessage {
+	if id == nil {
+		return json.RawMessage("null")
+	}
+	return id
+}
@@ -0,0 +1,192 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func serve(t *testing.T, s *Server, messages ...string) map[string]map[string]any {
+	t.Helper()
+	var out bytes.Buffer
+	if err := s.Serve(context.Background(), strings.NewReader(strings.Join(messages, "\n")+"\n"), &out); err != nil {
+		t.Fatalf("Serve: %v", err)
+	}
+
+	responses := map[string]map[string]any{}
+	scanner := bufio.NewScanner(&out)
+	for scanner.Scan() {
+		var resp map[string]any
+		if err := json.Unmarshal(scanner.Bytes(), &resp); err != nil {
+			t.Fatalf("invalid response %q: %v", scanner.Text(), err)
+		}
+		id, _ := json.Marshal(resp["id"])
+		responses[string(id)] = resp
+	}
+	return responses
+}
+
+func TestServerLifecycleAndTools(t *testing.T) {
+	echo := Tool{
+		Name:        "echo",
+		Description: "Echo the text back",
+		InputSchema: json.RawMessage(`{"type":"object","properties":{"text":{"type":"string"}}}`),
+		Handler: func(ctx context.Context, args json.RawMessage) (any, error) {
+			var in struct {
+				Text string `json:"text"`
+			}
+			json.Unmarshal(args, &in)
+			if in.Text == "" {
+				return nil, errors.New("text is required")
+			}
+			return map[string]string{"text": in.Text}, nil
+		},
+	}
+	s := NewServer("test", "1.0", echo)
+
+	responses := serve(t, s,
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26","capabilities":{},"clientInfo":{"name":"c","version":"1"}}}`,
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/list"}`,
+		`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"echo","arguments":{"text":"hi"}}}`,
+		`{"jsonrpc":"2.0","id":4,"method":"tools/call","params":{"name":"echo","arguments":{}}}`,
+		`{"jsonrpc":"2.0","id":"five","method":"tools/call","params":{"name":"missing"}}`,
+		`{"jsonrpc":"2.0","id":6,"method":"resources/list"}`,
+		`{"jsonrpc":"2.0","id":7,"method":"ping"}`,
+		`not json`,
+	)
+	if len(responses) != 8 {
+		t.Fatalf("expected 8 responses (none for the notification), got %d: %v", len(responses), responses)
+	}
+
+	initResult := responses["1"]["result"].(map[string]any)
+	if initResult["protocolVersion"] != "2025-03-26" || initResult["serverInfo"].(map[string]any)["name"] != "test" {
+		t.Fatalf("unexpected initialize result %v", initResult)
+	}
+
+	tools := responses["2"]["result"].(map[string]any)["tools"].([]any)
+	if len(tools) != 1 || tools[0].(map[string]any)["name"] != "echo" || tools[0].(map[string]any)["inputSchema"] == nil {
+		t.Fatalf("unexpected tools %v", tools)
+	}
+
+	call := responses["3"]["result"].(map[string]any)
+	text := call["content"].([]any)[0].(map[string]any)["text"].(string)
+	if call["isError"] != false || !strings.Contains(text, `"text": "hi"`) {
+		t.Fatalf("unexpected call result %v", call)
+	}
+
+	// Tool failures are results the model can read, not protocol errors.
+	failed := responses["4"]["result"].(map[string]any)
+	if failed["isError"] != true || failed["content"].([]any)[0].(map[string]any)["text"] != "text is required" {
+		t.Fatalf("unexpected failed call %v", failed)
+	}
+
+	for id, code := range map[string]float64{`"five"`: codeInvalidParams, "6": codeMethodNotFound, "null": codeParseError} {
+		rpcErr, _ := responses[id]["error"].(map[string]any)
+		if rpcErr == nil || rpcErr["code"] != code {
+			t.Errorf("response %s: expected error %v, got %v", id, code, responses[id])
+		}
+	}
+	if _, ok := responses["7"]["result"]; !ok {
+		t.Errorf("expected an empty result for ping, got %v", responses["7"])
+	}
+}
+
+func TestInitializeFallsBackToLatestVersion(t *testing.T) {
+	responses := serve(t, NewServer("test", "1.0"), `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"1999-01-01"}}`)
+	if got := responses["1"]["result"].(map[string]any)["protocolVersion"]; got != LatestProtocolVersion {
+		t.Fatalf("expected %s, got %v", LatestProtocolVersion, got)
+	}
+}
+
+func TestToolArgumentsAreValidated(t *testing.T) {
+	called := 0
+	tool := Tool{
+		Name: "create",
+		InputSchema: json.RawMessage(`{"type":"object","properties":{
+			"key":{"type":"string","minLength":1},
+			"count":{"type":"integer","minimum":1,"maximum":10},
+			"kind":{"type":"string","enum":["Bug","Task"]},
+			"labels":{"type":"array","items":{"type":"string"}}
+		},"required":["key"],"additionalProperties":false}`),
+		Handler: func(ctx context.Context, args json.RawMessage) (any, error) {
+			called++
+			return "ok", nil
+		},
+	}
+	s := NewServer("test", "1.0", tool)
+
+	for args, want := range map[string]string{
+		`{"key":"A-1","count":3,"kind":"Bug","labels":["x"]}`: "ok",
+		`{}`:                            "key is required",
+		`{"key":""}`:                    "key must not be empty",
+		`{"key":1}`:                     "key must be a string",
+		`{"key":"A-1","count":1.5}`:     "count must be an integer",
+		`{"key":"A-1","count":11}`:      "count must be at most 10",
+		`{"key":"A-1","kind":"Epic"}`:   `kind must be one of "Bug", "Task"`,
+		`{"key":"A-1","labels":[2]}`:    "labels[0] must be a string",
+		`{"key":"A-1","priority":"P1"}`: "priority is not a known argument",
+	} {
+		responses := serve(t, s, `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"create","arguments":`+args+`}}`)
+		result := responses["1"]["result"].(map[string]any)
+		text := result["content"].([]any)[0].(map[string]any)["text"].(string)
+		if first, _, _ := strings.Cut(text, "\n"); first != want {
+			t.Errorf("%s: expected %q, got %q", args, want, text)
+		}
+		if want == "ok" {
+			continue
+		}
+		if code := result["structuredContent"].(map[string]any)["error"].(map[string]any)["code"]; code != string(ErrorFieldValidation) {
+			t.Errorf("%s: expected code %s, got %v", args, ErrorFieldValidation, code)
+		}
+	}
+	if called != 1 {
+		t.Fatalf("expected only the valid call to reach the handler, got %d calls", called)
+	}
+}
+
+func TestToolErrorsCarryCodeAndHint(t *testing.T) {
+	tool := Tool{
+		Name: "get",
+		Handler: func(ctx context.Context, args json.RawMessage) (any, error) {
+			return nil, fmt.Errorf("lookup: %w", &ToolError{
+				Code:       ErrorJiraRateLimited,
+				Message:    "jira API error (429): Too Many Requests",
+				RetryAfter: 1500 * time.Millisecond,
+			})
+		},
+	}
+	plain := Tool{
+		Name: "plain",
+		Handler: func(ctx context.Context, args json.RawMessage) (any, error) {
+			return nil, errors.New("boom")
+		},
+	}
+	responses := serve(t, NewServer("test", "1.0", tool, plain),
+		`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"get"}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"plain"}}`,
+	)
+
+	result := responses["1"]["result"].(map[string]any)
+	detail := result["structuredContent"].(map[string]any)["error"].(map[string]any)
+	if result["isError"] != true || detail["code"] != "jira_rate_limited" || detail["retryAfterSeconds"] != float64(2) || detail["hint"] == "" {
+		t.Fatalf("unexpected result %v", result)
+	}
+	text := result["content"].([]any)[0].(map[string]any)["text"].(string)
+	if !strings.HasPrefix(text, "lookup: jira API error (429)") || !strings.Contains(text, "Error code: jira_rate_limited.") || !strings.Contains(text, "Retry after 2 seconds.") {
+		t.Fatalf("unexpected text %q", text)
+	}
+
+	// Unclassified failures keep their message and get the generic code.
+	result = responses["2"]["result"].(map[string]any)
+	detail = result["structuredContent"].(map[string]any)["error"].(map[string]any)
+	if detail["code"] != "tool_failed" || result["content"].([]any)[0].(map[string]any)["text"] != "boom" {
+		t.Fatalf("unexpected result %v", result)
+	}
+}
@@ -0,0 +1,49 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// sessionTouchInterval is how often a session's last use is saved to the
+// session store while it is in use.
+const sessionTouchInterval = time.Minute
+
+// interruptedCallAge is how long a tool call may stay unfinished in the
+// session store before it is presumed lost with the process that ran it.
+// It is well above any tool call's timeout.
+const interruptedCallAge = 10 * time.Minute
+
+// SessionState is what an HTTP session keeps in a SessionStore, so that a
+// client reconnecting after a network blip or a deploy, possibly to another
+// process, resumes the session instead of initializing again.
+type SessionState struct {
+	ID                 string
+	Owner              string
+	ProtocolVersion    string
+	ClientInfo         json.RawMessage
+	ClientCapabilities json.RawMessage
+	LastSeen           time.Time
+}
+
+// PendingCall is a tools/call request whose response has not reached the
+// client. Response is nil while the call runs.
+type PendingCall struct {
+	RequestID json.RawMessage
+	Response  json.RawMessage
+	StartedAt time.Time
+}
+
+// SessionStore persists HTTP sessions and their pending tool calls.
+// LoadSession returns nil for an unknown session or one idle for longer
+// than SessionIdleTimeout.
+type SessionStore interface {
+	SaveSession(ctx context.Context, state SessionState) error
+	LoadSession(ctx context.Context, id string) (*SessionState, error)
+	DeleteSession(ctx context.Context, id string) error
+	StartCall(ctx context.Context, sessionID string, requestID json.RawMessage) error
+	FinishCall(ctx context.Context, sessionID string, requestID, response json.RawMessage) error
+	ForgetCall(ctx context.Context, sessionID string, requestID json.RawMessage) error
+	PendingCalls(ctx context.Context, sessionID string) ([]PendingCall, error)
+}
@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// BackoffRateLimited is the reason reported for requests over a rate limit.
+const BackoffRateLimited = "rate_limited"
+
+// BackoffHint is the machine-readable body of a rejected request. The MCP
+// worker relays it to tool callers so they know when to try again.
+type BackoffHint struct {
+	Error             string    `json:"error"`
+	Reason            string    `json:"reason"`
+	RetryAfterSeconds int       `json:"retry_after_seconds"`
+	RetryAt           time.Time `json:"retry_at"`
+}
+
+// WriteBackoff rejects a request with status, a Retry-After header and a
+// BackoffHint body. retryAfter is rounded up to whole seconds, with a
+// minimum of one, so clients never retry immediately.
+func WriteBackoff(w http.ResponseWriter, status int, reason, message string, retryAfter time.Duration) {
+	seconds := int((retryAfter + time.Second - 1) / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(BackoffHint{
+		Error:             message,
+		Reason:            reason,
+		RetryAfterSeconds: seconds,
+		RetryAt:           time.Now().Add(time.Duration(seconds) * time.Second).UTC().Truncate(time.Second),
+	})
+}
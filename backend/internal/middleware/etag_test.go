@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestETag(t *testing.T) {
+	body := `{"plans":[]}`
+	handler := ETag(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(body))
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/plans", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != body {
+		t.Fatalf("first request: got %d %q", rec.Code, rec.Body.String())
+	}
+	tag := rec.Header().Get("ETag")
+	if tag == "" {
+		t.Fatal("expected ETag header")
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/plans", nil)
+	req.Header.Set("If-None-Match", `"stale", W/`+tag)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotModified {
+		t.Fatalf("expected 304, got %d", rec.Code)
+	}
+	if rec.Body.Len() != 0 {
+		t.Fatalf("expected empty body on 304, got %q", rec.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/plans", nil)
+	req.Header.Set("If-None-Match", `"stale"`)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 for mismatched tag, got %d", rec.Code)
+	}
+}
+
+func TestETagSkipsErrorsAndWrites(t *testing.T) {
+	handler := ETag(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "boom", http.StatusBadGateway)
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/settings/jira", nil))
+	if rec.Code != http.StatusBadGateway || rec.Header().Get("ETag") != "" {
+		t.Fatalf("expected untagged 502, got %d etag=%q", rec.Code, rec.Header().Get("ETag"))
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/settings/jira", nil))
+	if rec.Header().Get("ETag") != "" {
+		t.Fatal("expected POST to pass through without ETag")
+	}
+}
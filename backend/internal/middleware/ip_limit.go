@@ -0,0 +1,136 @@
+package middleware
+
+import (
+	"context"
+	"log"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+)
+
+// IPRateLimitSource lists the temporary per-IP limits to enforce.
+type IPRateLimitSource interface {
+	ListActiveIPRateLimits(ctx context.Context) ([]models.IPRateLimit, error)
+}
+
+// IPRateLimiter enforces the temporary per-IP request limits applied by the
+// abuse detector. Limits are reloaded from the source every refresh interval
+// and counted in fixed one-minute windows per instance.
+type IPRateLimiter struct {
+	source  IPRateLimitSource
+	refresh time.Duration
+	now     func() time.Time
+
+	mu       sync.Mutex
+	limits   map[string]models.IPRateLimit
+	loadedAt time.Time
+	loading  bool
+	windows  map[string]*ipWindow
+}
+
+type ipWindow struct {
+	start time.Time
+	count int
+}
+
+// NewIPRateLimiter creates a limiter that reloads limits every refresh.
+func NewIPRateLimiter(source IPRateLimitSource, refresh time.Duration) *IPRateLimiter {
+	if refresh <= 0 {
+		refresh = 30 * time.Second
+	}
+	return &IPRateLimiter{
+		source:  source,
+		refresh: refresh,
+		now:     time.Now,
+		limits:  map[string]models.IPRateLimit{},
+		windows: map[string]*ipWindow{},
+	}
+}
+
+// Middleware rejects requests over the caller IP's limit with 429, a
+// Retry-After header and a backoff hint. IPs without a limit pass straight
+// through.
+func (l *IPRateLimiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		l.reloadIfStale(r.Context())
+
+		if retryAfter, limited := l.allow(ClientIP(r)); limited {
+			WriteBackoff(w, http.StatusTooManyRequests, BackoffRateLimited, "too many requests", retryAfter)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// allow counts a request from ip and reports whether it is over the limit
+// and, if so, how long until the window resets.
+func (l *IPRateLimiter) allow(ip string) (time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	limit, ok := l.limits[ip]
+	now := l.now()
+	if !ok || !now.Before(limit.ExpiresAt) {
+		delete(l.windows, ip)
+		return 0, false
+	}
+
+	win := l.windows[ip]
+	if win == nil || now.Sub(win.start) >= time.Minute {
+		win = &ipWindow{start: now}
+		l.windows[ip] = win
+	}
+	if win.count >= limit.RequestsPerMinute {
+		return win.start.Add(time.Minute).Sub(now), true
+	}
+	win.count++
+	return 0, false
+}
+
+// reloadIfStale refreshes the limits when the refresh interval has passed.
+// Only one request pays for the reload; others keep using the old limits.
+func (l *IPRateLimiter) reloadIfStale(ctx context.Context) {
+	l.mu.Lock()
+	if l.loading || l.now().Sub(l.loadedAt) < l.refresh {
+		l.mu.Unlock()
+		return
+	}
+	l.loading = true
+	l.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	rows, err := l.source.ListActiveIPRateLimits(ctx)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.loading = false
+	l.loadedAt = l.now()
+	if err != nil {
+		log.Printf("[ratelimit] Failed to load IP rate limits: %v", err)
+		return
+	}
+	limits := make(map[string]models.IPRateLimit, len(rows))
+	for _, row := range rows {
+		limits[row.ClientIP] = row
+	}
+	l.limits = limits
+	for ip := range l.windows {
+		if _, ok := limits[ip]; !ok {
+			delete(l.windows, ip)
+		}
+	}
+}
+
+// ClientIP returns the request's client address without the port. It relies
+// on chi's RealIP middleware having already applied forwarding headers.
+func ClientIP(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
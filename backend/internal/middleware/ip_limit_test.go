@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+)
+
+type staticIPLimits []models.IPRateLimit
+
+func (s staticIPLimits) ListActiveIPRateLimits(ctx context.Context) ([]models.IPRateLimit, error) {
+	return s, nil
+}
+
+func TestIPRateLimiterLimitsOnlyListedIPs(t *testing.T) {
+	now := time.Date(2026, time.April, 1, 12, 0, 0, 0, time.UTC)
+	limiter := NewIPRateLimiter(staticIPLimits{
+		{ClientIP: "203.0.113.7", RequestsPerMinute: 2, ExpiresAt: now.Add(time.Hour)},
+	}, time.Minute)
+	limiter.now = func() time.Time { return now }
+
+	handler := limiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	do := func(addr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/users", nil)
+		req.RemoteAddr = addr
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for i := 0; i < 2; i++ {
+		if rec := do("203.0.113.7:4000"); rec.Code != http.StatusOK {
+			t.Fatalf("request %d: status = %d, want 200", i+1, rec.Code)
+		}
+	}
+
+	rec := do("203.0.113.7:4001")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("third request: status = %d, want 429", rec.Code)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "60" {
+		t.Fatalf("Retry-After = %q, want 60", got)
+	}
+	var hint BackoffHint
+	if err := json.Unmarshal(rec.Body.Bytes(), &hint); err != nil {
+		t.Fatalf("decode backoff hint: %v", err)
+	}
+	if hint.Reason != BackoffRateLimited || hint.RetryAfterSeconds != 60 {
+		t.Fatalf("unexpected backoff hint %+v", hint)
+	}
+
+	if rec := do("198.51.100.1:4000"); rec.Code != http.StatusOK {
+		t.Fatalf("unlisted IP: status = %d, want 200", rec.Code)
+	}
+
+	now = now.Add(time.Minute)
+	if rec := do("203.0.113.7:4000"); rec.Code != http.StatusOK {
+		t.Fatalf("next window: status = %d, want 200", rec.Code)
+	}
+}
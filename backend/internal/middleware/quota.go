@@ -0,0 +1,186 @@
+package middleware
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+)
+
+// BackoffQuotaExceeded is the reason reported for requests over a monthly
+// quota.
+const BackoffQuotaExceeded = "quota_exceeded"
+
+const (
+	// quotaUsageTTL is how long a user's quota and usage are trusted before
+	// they are read again. In between, requests passed by this instance are
+	// counted locally.
+	quotaUsageTTL = time.Minute
+	// quotaIdle is how long the state of a user without requests is kept.
+	quotaIdle = 10 * time.Minute
+)
+
+// QuotaSource resolves a user's plan and how many requests they made in the
+// current quota period.
+type QuotaSource interface {
+	GetEffectivePlan(ctx context.Context, userID int64) (*models.MembershipPlan, error)
+	CountPeriodRequests(ctx context.Context, userID int64, periodStart time.Time) (int64, error)
+}
+
+// QuotaEnforcer rejects the requests of users who used up their plan's
+// monthly_request_quota for the current UTC month. Usage is read from the
+// request logs, so it covers every instance, at most a minute late.
+type QuotaEnforcer struct {
+	source   QuotaSource
+	identify func(*http.Request) int64
+	exempt   map[string]bool
+	now      func() time.Time
+
+	mu        sync.Mutex
+	users     map[int64]*quotaUsage
+	lastSweep time.Time
+}
+
+type quotaUsage struct {
+	// quota is the plan's monthly request quota; 0 is unlimited.
+	quota       int
+	used        int64
+	periodStart time.Time
+	loadedAt    time.Time
+	last        time.Time
+}
+
+// NewQuotaEnforcer creates an enforcer for the users identify returns; it
+// returns 0 for requests that are not counted against a user. Requests to
+// the exempt paths, such as the endpoint reporting the quota, always pass.
+func NewQuotaEnforcer(source QuotaSource, identify func(*http.Request) int64, exempt ...string) *QuotaEnforcer {
+	e := &QuotaEnforcer{
+		source:   source,
+		identify: identify,
+		exempt:   map[string]bool{},
+		now:      time.Now,
+		users:    map[int64]*quotaUsage{},
+	}
+	for _, path := range exempt {
+		e.exempt[path] = true
+	}
+	return e
+}
+
+// Middleware rejects requests of users over their quota with 429, a
+// Retry-After header pointing at the start of the next period and a backoff
+// hint with reason quota_exceeded. Counted requests report the quota in
+// X-Quota-Limit, X-Quota-Remaining and X-Quota-Reset (Unix seconds).
+// Anonymous requests, requests that are not logged and users whose plan has
+// no quota pass straight through.
+func (e *QuotaEnforcer) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID := e.identify(r)
+		if userID <= 0 || shouldSkipTracking(r.URL.Path) || e.exempt[r.URL.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		quota, remaining, reset, exceeded := e.take(r.Context(), userID)
+		if quota <= 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Set("X-Quota-Limit", strconv.Itoa(quota))
+		w.Header().Set("X-Quota-Remaining", strconv.FormatInt(remaining, 10))
+		w.Header().Set("X-Quota-Reset", strconv.FormatInt(reset.Unix(), 10))
+		if exceeded {
+			WriteBackoff(w, http.StatusTooManyRequests, BackoffQuotaExceeded, "monthly request quota exceeded for your plan", reset.Sub(e.now()))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// take counts a request against the user's quota. It returns the quota, the
+// requests left after this one, when the period resets and whether the
+// request is over the quota.
+func (e *QuotaEnforcer) take(ctx context.Context, userID int64) (int, int64, time.Time, bool) {
+	usage := e.load(ctx, userID)
+	if usage == nil {
+		return 0, 0, time.Time{}, false
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	_, reset := models.QuotaPeriod(usage.periodStart)
+	usage.last = e.now()
+	if usage.quota <= 0 {
+		return 0, 0, reset, false
+	}
+	if usage.used >= int64(usage.quota) {
+		return usage.quota, 0, reset, true
+	}
+	usage.used++
+	return usage.quota, int64(usage.quota) - usage.used, reset, false
+}
+
+// load returns the user's quota state, rereading the plan and usage when
+// they are stale or a new period began. A failed read keeps the previous
+// state, or lets a new user through until their usage can be read.
+func (e *QuotaEnforcer) load(ctx context.Context, userID int64) *quotaUsage {
+	now := e.now()
+	periodStart, _ := models.QuotaPeriod(now)
+
+	e.mu.Lock()
+	usage := e.users[userID]
+	if usage != nil && usage.periodStart.Equal(periodStart) && now.Sub(usage.loadedAt) < quotaUsageTTL {
+		e.mu.Unlock()
+		return usage
+	}
+	e.mu.Unlock()
+
+	fresh, err := e.read(ctx, userID, periodStart)
+	if err != nil {
+		log.Printf("[quota] Failed to load quota usage for user_id=%d: %v", userID, err)
+		return usage
+	}
+	fresh.loadedAt = now
+	fresh.last = now
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.sweep(now)
+	e.users[userID] = fresh
+	return fresh
+}
+
+func (e *QuotaEnforcer) read(ctx context.Context, userID int64, periodStart time.Time) (*quotaUsage, error) {
+	plan, err := e.source.GetEffectivePlan(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	usage := &quotaUsage{periodStart: periodStart}
+	if plan.MonthlyRequestQuota == nil || *plan.MonthlyRequestQuota <= 0 {
+		return usage, nil
+	}
+	usage.quota = *plan.MonthlyRequestQuota
+	if usage.used, err = e.source.CountPeriodRequests(ctx, userID, periodStart); err != nil {
+		return nil, err
+	}
+	return usage, nil
+}
+
+// sweep drops the state of users without recent requests, at most once a
+// minute. e.mu must be held.
+func (e *QuotaEnforcer) sweep(now time.Time) {
+	if now.Sub(e.lastSweep) < time.Minute {
+		return
+	}
+	e.lastSweep = now
+	for userID, usage := range e.users {
+		if now.Sub(usage.last) > quotaIdle {
+			delete(e.users, userID)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+)
+
+type staticQuotaSource struct {
+	quota *int
+	used  int64
+	since time.Time
+}
+
+func (s *staticQuotaSource) GetEffectivePlan(ctx context.Context, userID int64) (*models.MembershipPlan, error) {
+	return &models.MembershipPlan{MonthlyRequestQuota: s.quota}, nil
+}
+
+func (s *staticQuotaSource) CountPeriodRequests(ctx context.Context, userID int64, periodStart time.Time) (int64, error) {
+	s.since = periodStart
+	return s.used, nil
+}
+
+func TestQuotaEnforcerRejectsRequestsOverQuota(t *testing.T) {
+	quota := 10
+	source := &staticQuotaSource{quota: &quota, used: 8}
+	now := time.Date(2026, time.April, 30, 23, 0, 0, 0, time.UTC)
+	enforcer := NewQuotaEnforcer(source, func(r *http.Request) int64 { return 1 }, "/api/metrics/quota")
+	enforcer.now = func() time.Time { return now }
+	handler := enforcer.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	do := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	for i := 0; i < 2; i++ {
+		if rec := do("/api/settings/jira"); rec.Code != http.StatusOK {
+			t.Fatalf("request %d: status = %d, want 200", i+1, rec.Code)
+		}
+	}
+	if !source.since.Equal(time.Date(2026, time.April, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("expected usage counted from the start of the month, got %v", source.since)
+	}
+	rec := do("/api/settings/jira")
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("X-Quota-Remaining") != "0" {
+		t.Fatalf("over quota: status = %d, remaining = %q", rec.Code, rec.Header().Get("X-Quota-Remaining"))
+	}
+	// The quota resets at midnight UTC, an hour away.
+	if got := rec.Header().Get("Retry-After"); got != "3600" {
+		t.Fatalf("Retry-After = %q, want 3600", got)
+	}
+	var hint BackoffHint
+	if err := json.Unmarshal(rec.Body.Bytes(), &hint); err != nil || hint.Reason != BackoffQuotaExceeded {
+		t.Fatalf("unexpected backoff hint %s: %v", rec.Body.String(), err)
+	}
+	if rec := do("/api/metrics/quota"); rec.Code != http.StatusOK {
+		t.Fatalf("exempt path: status = %d, want 200", rec.Code)
+	}
+
+	// A new month starts from the request logs again.
+	now = now.Add(2 * time.Hour)
+	source.used = 0
+	if rec := do("/api/settings/jira"); rec.Code != http.StatusOK || rec.Header().Get("X-Quota-Remaining") != "9" {
+		t.Fatalf("new period: status = %d, remaining = %q", rec.Code, rec.Header().Get("X-Quota-Remaining"))
+	}
+}
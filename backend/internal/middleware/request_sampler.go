@@ -0,0 +1,116 @@
+package middleware
+
+import (
+	"log"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// RequestSamplingConfig decides which tracked requests are written to the
+// request log. Errors are always written. Successful requests are all
+// written while inserts keep up; once the average insert latency exceeds
+// LatencyTarget only a share of them is, falling towards MinRate as latency
+// grows. A written success carries the count of the user's successes skipped
+// since their previous written one, so usage totals stay exact.
+type RequestSamplingConfig struct {
+	// MinRate is the smallest share of successful requests written under
+	// load, in (0, 1]. 1 disables sampling.
+	MinRate float64
+	// LatencyTarget is the average insert latency above which successful
+	// requests are sampled.
+	LatencyTarget time.Duration
+	// MaxInFlight bounds concurrent inserts of successful requests; those
+	// finishing while it is reached are skipped. 0 means no bound.
+	MaxInFlight int
+}
+
+// DefaultRequestSamplingConfig returns sensible default configuration
+func DefaultRequestSamplingConfig() RequestSamplingConfig {
+	return RequestSamplingConfig{
+		MinRate:       0.1,
+		LatencyTarget: 50 * time.Millisecond,
+		MaxInFlight:   64,
+	}
+}
+
+// requestSampler tracks insert latency and the successes skipped per user.
+type requestSampler struct {
+	config RequestSamplingConfig
+	random func() float64
+
+	mu       sync.Mutex
+	latency  time.Duration // moving average of insert latency
+	inFlight int
+	sampling bool
+	skipped  map[int64]int
+}
+
+func newRequestSampler(config RequestSamplingConfig) *requestSampler {
+	if config.MinRate <= 0 || config.MinRate > 1 {
+		config.MinRate = 1
+	}
+	return &requestSampler{
+		config:  config,
+		random:  rand.Float64,
+		skipped: make(map[int64]int),
+	}
+}
+
+// rateLocked returns the share of successful requests to write at the
+// current insert latency.
+func (s *requestSampler) rateLocked() float64 {
+	if s.config.MinRate >= 1 || s.latency <= s.config.LatencyTarget {
+		return 1
+	}
+	rate := float64(s.config.LatencyTarget) / float64(s.latency)
+	if rate < s.config.MinRate {
+		rate = s.config.MinRate
+	}
+	return rate
+}
+
+// admit decides whether to write a request of userID that finished with
+// statusCode. It returns the sample weight to write it with, or 0 to skip
+// it. Every admitted request must be followed by a call to done.
+func (s *requestSampler) admit(userID int64, statusCode int) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if statusCode >= 400 {
+		s.inFlight++
+		return 1
+	}
+
+	weight := s.skipped[userID] + 1
+	full := s.config.MaxInFlight > 0 && s.inFlight >= s.config.MaxInFlight
+	if full || s.random() >= s.rateLocked() {
+		s.skipped[userID] = weight
+		return 0
+	}
+	delete(s.skipped, userID)
+	s.inFlight++
+	return weight
+}
+
+// done records how long an admitted request took to write.
+func (s *requestSampler) done(took time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.inFlight--
+	if s.latency == 0 {
+		s.latency = took
+	} else {
+		s.latency += (took - s.latency) / 8
+	}
+
+	if rate := s.rateLocked(); (rate < 1) != s.sampling {
+		s.sampling = rate < 1
+		if s.sampling {
+			log.Printf("[tracker] request log inserts average %s (target %s); sampling successful requests", s.latency, s.config.LatencyTarget)
+		} else {
+			log.Printf("[tracker] request log inserts average %s; sampling stopped", s.latency)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRequestSamplerKeepsErrorsAndCountsSkippedSuccesses(t *testing.T) {
+	sampler := newRequestSampler(RequestSamplingConfig{MinRate: 0.1, LatencyTarget: 10 * time.Millisecond})
+	draw := 0.5
+	sampler.random = func() float64 { return draw }
+
+	if w := sampler.admit(1, 200); w != 1 {
+		t.Fatalf("expected successes to be written while inserts keep up, got weight %d", w)
+	}
+	// Inserts at four times the target write a quarter of the successes.
+	sampler.done(40 * time.Millisecond)
+
+	for i := 0; i < 3; i++ {
+		if w := sampler.admit(1, 200); w != 0 {
+			t.Fatalf("expected success %d to be skipped, got weight %d", i, w)
+		}
+	}
+	if w := sampler.admit(1, 500); w != 1 {
+		t.Fatalf("expected errors to always be written, got weight %d", w)
+	}
+	sampler.done(40 * time.Millisecond)
+
+	draw = 0.1
+	if w := sampler.admit(1, 200); w != 4 {
+		t.Fatalf("expected the written success to count the 3 skipped ones, got weight %d", w)
+	}
+	if w := sampler.admit(2, 200); w != 1 {
+		t.Fatalf("expected skipped counts to be per user, got weight %d", w)
+	}
+}
+
+func TestRequestSamplerBoundsInFlightInserts(t *testing.T) {
+	sampler := newRequestSampler(RequestSamplingConfig{MinRate: 1, MaxInFlight: 1})
+
+	if w := sampler.admit(1, 200); w != 1 {
+		t.Fatalf("expected first success to be written, got weight %d", w)
+	}
+	if w := sampler.admit(1, 200); w != 0 {
+		t.Fatalf("expected success to be skipped while an insert is in flight, got weight %d", w)
+	}
+	if w := sampler.admit(1, 404); w != 1 {
+		t.Fatalf("expected error to be written while an insert is in flight, got weight %d", w)
+	}
+	sampler.done(time.Millisecond)
+	sampler.done(time.Millisecond)
+
+	if w := sampler.admit(1, 200); w != 2 {
+		t.Fatalf("expected the skipped success to be counted, got weight %d", w)
+	}
+}
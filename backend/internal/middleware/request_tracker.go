@@ -1,9 +1,12 @@
 package middleware
 
 import (
+	"bufio"
 	"context"
 	"database/sql"
+	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"strings"
 	"time"
@@ -13,16 +16,30 @@ import (
 
 // RequestTracker stores request metrics in the database
 type RequestTracker struct {
-	store *store.Store
+	store   *store.Store
+	sampler *requestSampler
 }
 
-// NewRequestTracker creates a new request tracker middleware
-func NewRequestTracker(db *sql.DB) (*RequestTracker, error) {
+// NewRequestTracker creates a new request tracker middleware that samples
+// successful requests according to sampling when inserts slow down.
+func NewRequestTracker(db *sql.DB, sampling RequestSamplingConfig) (*RequestTracker, error) {
 	s, err := store.New(db)
 	if err != nil {
 		return nil, err
 	}
-	return &RequestTracker{store: s}, nil
+	return &RequestTracker{store: s, sampler: newRequestSampler(sampling)}, nil
+}
+
+// SetRegionalDatabases lets the tracker write the request logs of resident
+// organizations' members to their region.
+func (rt *RequestTracker) SetRegionalDatabases(dbs map[string]*sql.DB) {
+	rt.store.SetRegionalDatabases(dbs)
+}
+
+// SetShardDatabases lets the tracker write the request logs of tenants placed
+// on a shard to that shard.
+func (rt *RequestTracker) SetShardDatabases(dbs map[string]*sql.DB) {
+	rt.store.SetShardDatabases(dbs)
 }
 
 // Middleware returns an HTTP middleware that tracks request metrics
@@ -58,18 +75,27 @@ func (rt *RequestTracker) Middleware() func(http.Handler) http.Handler {
 			if shouldSkipTracking(r.URL.Path) {
 				return
 			}
+			if userID == 0 {
+				log.Printf("[db] Skipping request log for unauthenticated request: method=%s, endpoint=%s", r.Method, r.URL.Path)
+				return
+			}
+
+			// Under load only a sample of successful requests is written;
+			// the skipped ones are counted into the next written one.
+			weight := rt.sampler.admit(userID, rw.statusCode)
+			if weight == 0 {
+				return
+			}
 
 			// Track the request asynchronously to avoid blocking
 			go func() {
 				ctx := context.Background()
-				if userID == 0 {
-					log.Printf("[db] Skipping request log for unauthenticated request: method=%s, endpoint=%s", r.Method, r.URL.Path)
-					return // Skip the rest of the logging logic
-				}
 				log.Printf("[db] Attempting to log request: method=%s, endpoint=%s", r.Method, r.URL.Path)
-				err := rt.store.CreateRequest(
+				started := time.Now()
+				err := rt.store.CreateSampledRequest(
 					ctx,
 					userID,
+					weight,
 					r.Method,
 					r.URL.Path,
 					rw.statusCode,
@@ -78,6 +104,7 @@ func (rt *RequestTracker) Middleware() func(http.Handler) http.Handler {
 					&responseSizeBytes,
 					nil, // error message - could be enhanced to capture errors
 				)
+				rt.sampler.done(time.Since(started))
 				if err != nil {
 					log.Printf("[db] Error logging request: %v", err)
 					// Log error but don't fail the request
@@ -107,6 +134,22 @@ func (rw *responseWriter) Write(b []byte) (int, error) {
 	return n, err
 }
 
+// Hijack lets WebSocket upgrades take over the underlying connection.
+func (rw *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := rw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("middleware: response writer does not support hijacking")
+	}
+	rw.statusCode = http.StatusSwitchingProtocols
+	return h.Hijack()
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer, for
+// flushing event streams.
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
 func shouldSkipTracking(path string) bool {
 	switch path {
 	case "/healthz", "/favicon.ico", "/robots.txt":
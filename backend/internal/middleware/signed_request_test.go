@@ -0,0 +1,120 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/keyring"
+)
+
+func TestRequireSignedRequest(t *testing.T) {
+	key := []byte("shared-key")
+	handler := RequireSignedRequest(key, 5*time.Minute)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !IsSignedRequest(r.Context()) {
+			t.Error("expected a verified request to be marked signed")
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	newRequest := func(ts time.Time, sign bool, tamper bool) *http.Request {
+		body := `{"provider":"jira"}`
+		req := httptest.NewRequest(http.MethodPost, "/api/notifications/tenant/credential-failure?mcp_secret=abc", strings.NewReader(body))
+		timestamp := strconv.FormatInt(ts.Unix(), 10)
+		req.Header.Set(TimestampHeader, timestamp)
+		if sign {
+			signed := body
+			if tamper {
+				signed = `{"provider":"github"}`
+			}
+			req.Header.Set(SignatureHeader, SignRequest(key, req.Method, req.URL.Path, req.URL.RawQuery, timestamp, []byte(signed)))
+		}
+		return req
+	}
+
+	cases := []struct {
+		name string
+		req  *http.Request
+		want int
+	}{
+		{"valid", newRequest(time.Now(), true, false), http.StatusNoContent},
+		{"missing signature", newRequest(time.Now(), false, false), http.StatusUnauthorized},
+		{"tampered body", newRequest(time.Now(), true, true), http.StatusUnauthorized},
+		{"stale timestamp", newRequest(time.Now().Add(-10*time.Minute), true, false), http.StatusUnauthorized},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, tc.req)
+			if rec.Code != tc.want {
+				t.Fatalf("expected status %d, got %d", tc.want, rec.Code)
+			}
+		})
+	}
+}
+
+func TestRequireSignedRequestDisabledWithoutKey(t *testing.T) {
+	handler := RequireSignedRequest(nil, time.Minute)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if IsSignedRequest(r.Context()) {
+			t.Error("expected an unchecked request not to be marked signed")
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/settings/jira/tenant", nil))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected unsigned request to pass when no key is configured, got %d", rec.Code)
+	}
+}
+
+func TestRequireSignedRequestKeysAcceptsRotatedKeys(t *testing.T) {
+	keys, err := keyring.New(
+		keyring.Key{ID: "new", Secret: []byte("new-key")},
+		keyring.Key{ID: "old", Secret: []byte("old-key")},
+	)
+	if err != nil {
+		t.Fatalf("keyring: %v", err)
+	}
+	handler := RequireSignedRequestKeys(keys, time.Minute)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	newRequest := func(key, keyID string) *http.Request {
+		req := httptest.NewRequest(http.MethodGet, "/api/settings/jira/tenant?mcp_secret=abc", nil)
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set(TimestampHeader, timestamp)
+		req.Header.Set(SignatureHeader, SignRequest([]byte(key), req.Method, req.URL.Path, req.URL.RawQuery, timestamp, nil))
+		if keyID != "" {
+			req.Header.Set(KeyIDHeader, keyID)
+		}
+		return req
+	}
+
+	cases := []struct {
+		name string
+		req  *http.Request
+		want int
+	}{
+		{"primary key", newRequest("new-key", ""), http.StatusNoContent},
+		{"previous key", newRequest("old-key", ""), http.StatusNoContent},
+		{"named key", newRequest("old-key", "old"), http.StatusNoContent},
+		{"wrong named key", newRequest("old-key", "new"), http.StatusUnauthorized},
+		{"unknown key id", newRequest("old-key", "retired"), http.StatusUnauthorized},
+		{"unknown key", newRequest("other-key", ""), http.StatusUnauthorized},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, tc.req)
+			if rec.Code != tc.want {
+				t.Fatalf("expected status %d, got %d", tc.want, rec.Code)
+			}
+		})
+	}
+}
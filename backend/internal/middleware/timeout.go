@@ -0,0 +1,150 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// timeoutWriteGrace is added to a class timeout when extending the
+// connection's write deadline, leaving room to send the timeout response.
+const timeoutWriteGrace = 5 * time.Second
+
+// TimeoutClass is the deadline applied to a group of routes.
+type TimeoutClass struct {
+	Name string
+	// Timeout bounds the handler; zero disables the deadline for long-lived
+	// connections such as websockets.
+	Timeout time.Duration
+	// Status is returned when the deadline expires: 504 for routes waiting
+	// on an upstream such as Jira or Stripe, 503 otherwise.
+	Status int
+}
+
+// TimeoutRule assigns a class to requests whose path starts with Prefix
+// and, when Method is set, that use Method.
+type TimeoutRule struct {
+	Prefix string
+	Method string
+	Class  TimeoutClass
+}
+
+// RouteTimeouts runs each request under the deadline of the first rule
+// matching its path, or fallback when none does. Responses are buffered so
+// that a handler still running at the deadline cannot interleave with the
+// JSON timeout error; its later writes fail with http.ErrHandlerTimeout.
+// The connection's write deadline is moved to match the class, so classes
+// may run longer than the server-wide WriteTimeout.
+func RouteTimeouts(rules []TimeoutRule, fallback TimeoutClass) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			class := fallback
+			for _, rule := range rules {
+				if strings.HasPrefix(r.URL.Path, rule.Prefix) && (rule.Method == "" || rule.Method == r.Method) {
+					class = rule.Class
+					break
+				}
+			}
+			if class.Timeout <= 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+			serveWithTimeout(w, r, next, class)
+		})
+	}
+}
+
+func serveWithTimeout(w http.ResponseWriter, r *http.Request, next http.Handler, class TimeoutClass) {
+	ctx, cancel := context.WithTimeout(r.Context(), class.Timeout)
+	defer cancel()
+
+	// Not every writer supports deadlines (e.g. test recorders); the
+	// server-wide WriteTimeout then still applies.
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Now().Add(class.Timeout + timeoutWriteGrace))
+
+	tw := &timeoutWriter{header: make(http.Header)}
+	done := make(chan struct{})
+	panicked := make(chan any, 1)
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				panicked <- p
+			}
+		}()
+		next.ServeHTTP(tw, r.WithContext(ctx))
+		close(done)
+	}()
+
+	select {
+	case p := <-panicked:
+		// Re-panic on the serving goroutine so the recoverer sees it.
+		panic(p)
+	case <-done:
+		tw.mu.Lock()
+		defer tw.mu.Unlock()
+		dst := w.Header()
+		for k, v := range tw.header {
+			dst[k] = v
+		}
+		if tw.status == 0 {
+			tw.status = http.StatusOK
+		}
+		w.WriteHeader(tw.status)
+		_, _ = w.Write(tw.body.Bytes())
+	case <-ctx.Done():
+		tw.mu.Lock()
+		defer tw.mu.Unlock()
+		tw.timedOut = true
+		if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			// The client went away; there is nobody to answer.
+			return
+		}
+		log.Printf("[timeout] %s %s exceeded the %s timeout of %v", r.Method, r.URL.Path, class.Name, class.Timeout)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(class.Status)
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"error":   "request timed out",
+			"timeout": class.Timeout.String(),
+		})
+	}
+}
+
+// timeoutWriter buffers a response until the handler returns.
+type timeoutWriter struct {
+	mu       sync.Mutex
+	header   http.Header
+	body     bytes.Buffer
+	status   int
+	timedOut bool
+}
+
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.header
+}
+
+func (tw *timeoutWriter) WriteHeader(status int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut || tw.status != 0 {
+		return
+	}
+	tw.status = status
+}
+
+func (tw *timeoutWriter) Write(p []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	if tw.status == 0 {
+		tw.status = http.StatusOK
+	}
+	return tw.body.Write(p)
+}
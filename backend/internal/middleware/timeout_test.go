@@ -0,0 +1,99 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRouteTimeouts(t *testing.T) {
+	fast := TimeoutClass{Name: "fast", Timeout: 20 * time.Millisecond, Status: http.StatusServiceUnavailable}
+	upstream := TimeoutClass{Name: "upstream", Timeout: 20 * time.Millisecond, Status: http.StatusGatewayTimeout}
+	rules := []TimeoutRule{
+		{Prefix: "/ws", Class: TimeoutClass{Name: "stream"}},
+		{Prefix: "/mcp", Method: http.MethodGet, Class: TimeoutClass{Name: "stream"}},
+		{Prefix: "/api/jira", Class: upstream},
+	}
+
+	release := make(chan struct{})
+	defer close(release)
+	slow := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-time.After(time.Second):
+		}
+		_, _ = w.Write([]byte("late"))
+	})
+	handler := RouteTimeouts(rules, fast)(slow)
+
+	for path, want := range map[string]int{"/api/jira/test": http.StatusGatewayTimeout, "/api/other": http.StatusServiceUnavailable} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != want {
+			t.Fatalf("%s: expected %d, got %d", path, want, rec.Code)
+		}
+		if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+			t.Fatalf("%s: expected JSON error, got %q", path, ct)
+		}
+		var body map[string]string
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body["error"] == "" {
+			t.Fatalf("%s: unexpected body %q", path, rec.Body.String())
+		}
+	}
+
+	// Unbounded classes see the request untouched.
+	stream := RouteTimeouts(rules, fast)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.Context().Deadline(); ok {
+			t.Error("expected no deadline for the stream class")
+		}
+		w.WriteHeader(http.StatusSwitchingProtocols)
+	}))
+	for _, path := range []string{"/ws", "/mcp"} {
+		rec := httptest.NewRecorder()
+		stream.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusSwitchingProtocols {
+			t.Fatalf("%s: expected pass-through status, got %d", path, rec.Code)
+		}
+	}
+
+	// Rules with a method only match that method.
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/mcp", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("POST /mcp: expected the fallback deadline, got %d", rec.Code)
+	}
+}
+
+func TestRouteTimeoutsCopiesResponse(t *testing.T) {
+	class := TimeoutClass{Name: "fast", Timeout: time.Second, Status: http.StatusServiceUnavailable}
+	handler := RouteTimeouts(nil, class)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.Context().Deadline(); !ok {
+			t.Error("expected a request deadline")
+		}
+		w.Header().Set("X-Test", "1")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte("ok"))
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/jobs", nil))
+	if rec.Code != http.StatusCreated || rec.Body.String() != "ok" || rec.Header().Get("X-Test") != "1" {
+		t.Fatalf("unexpected response %d %q %v", rec.Code, rec.Body.String(), rec.Header())
+	}
+}
+
+func TestRouteTimeoutsPropagatesPanics(t *testing.T) {
+	class := TimeoutClass{Name: "fast", Timeout: time.Second, Status: http.StatusServiceUnavailable}
+	handler := RouteTimeouts(nil, class)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}))
+
+	defer func() {
+		if p := recover(); p != "boom" {
+			t.Fatalf("expected the handler panic, got %v", p)
+		}
+	}()
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+}
@@ -0,0 +1,168 @@
+package middleware
+
+import (
+	"context"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+)
+
+const (
+	// userPlanTTL is how long a user's plan limit is reused before it is
+	// looked up again, so plan changes apply within a minute.
+	userPlanTTL = time.Minute
+	// userBucketIdle is how long an unused bucket is kept.
+	userBucketIdle = 10 * time.Minute
+)
+
+// UserPlanSource resolves the plan whose request rate applies to a user.
+type UserPlanSource interface {
+	GetEffectivePlan(ctx context.Context, userID int64) (*models.MembershipPlan, error)
+}
+
+// UserRateLimiter limits each user's requests to their plan's
+// requests_per_minute with a token bucket: a user may burst up to a
+// minute's worth of requests, which refill evenly over the minute. Buckets
+// live in memory, so each instance enforces the limit on its own.
+type UserRateLimiter struct {
+	plans    UserPlanSource
+	identify func(*http.Request) int64
+	now      func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[int64]*userBucket
+	lastSweep time.Time
+}
+
+type userBucket struct {
+	// limit is the plan's requests per minute; 0 is unlimited.
+	limit    int
+	loadedAt time.Time
+	tokens   float64
+	last     time.Time
+}
+
+// NewUserRateLimiter creates a limiter for the users identify returns; it
+// returns 0 for requests that are not made on behalf of a user.
+func NewUserRateLimiter(plans UserPlanSource, identify func(*http.Request) int64) *UserRateLimiter {
+	return &UserRateLimiter{
+		plans:    plans,
+		identify: identify,
+		now:      time.Now,
+		buckets:  map[int64]*userBucket{},
+	}
+}
+
+// Middleware rejects requests over the caller's plan limit with 429, a
+// Retry-After header and a backoff hint, and reports the limit in
+// X-RateLimit-Limit and X-RateLimit-Remaining. Anonymous requests and users
+// whose plan has no limit pass straight through.
+func (l *UserRateLimiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID := l.identify(r)
+		if userID <= 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		limit := l.limitFor(r.Context(), userID)
+		if limit <= 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+		remaining, retryAfter, limited := l.take(userID)
+		w.Header().Set("X-RateLimit-Limit", strconv.Itoa(limit))
+		w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+		if limited {
+			WriteBackoff(w, http.StatusTooManyRequests, BackoffRateLimited, "rate limit exceeded for your plan", retryAfter)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// limitFor returns the user's requests per minute, reloading their plan
+// when the cached limit is stale. A failed lookup keeps the previous limit,
+// or leaves a new user unlimited until their plan can be read.
+func (l *UserRateLimiter) limitFor(ctx context.Context, userID int64) int {
+	l.mu.Lock()
+	bucket := l.buckets[userID]
+	now := l.now()
+	if bucket != nil && now.Sub(bucket.loadedAt) < userPlanTTL {
+		limit := bucket.limit
+		l.mu.Unlock()
+		return limit
+	}
+	l.mu.Unlock()
+
+	limit := 0
+	plan, err := l.plans.GetEffectivePlan(ctx, userID)
+	if err != nil {
+		log.Printf("[ratelimit] Failed to load plan for user_id=%d: %v", userID, err)
+		if bucket == nil {
+			return 0
+		}
+		limit = bucket.limit
+	} else if plan.RequestsPerMinute != nil {
+		limit = *plan.RequestsPerMinute
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sweep(now)
+	bucket = l.buckets[userID]
+	if bucket == nil {
+		bucket = &userBucket{tokens: float64(limit), last: now}
+		l.buckets[userID] = bucket
+	}
+	if limit > 0 && bucket.tokens > float64(limit) {
+		bucket.tokens = float64(limit)
+	}
+	bucket.limit = limit
+	bucket.loadedAt = now
+	return limit
+}
+
+// take spends one of the user's tokens. It returns the whole tokens left
+// and, when none was available, how long until one is.
+func (l *UserRateLimiter) take(userID int64) (int, time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	bucket := l.buckets[userID]
+	if bucket == nil || bucket.limit <= 0 {
+		return 0, 0, false
+	}
+	now := l.now()
+	perSecond := float64(bucket.limit) / 60
+	if elapsed := now.Sub(bucket.last).Seconds(); elapsed > 0 {
+		bucket.tokens = math.Min(float64(bucket.limit), bucket.tokens+elapsed*perSecond)
+	}
+	bucket.last = now
+	if bucket.tokens < 1 {
+		wait := time.Duration((1 - bucket.tokens) / perSecond * float64(time.Second))
+		return 0, wait, true
+	}
+	bucket.tokens--
+	return int(bucket.tokens), 0, false
+}
+
+// sweep drops buckets that have not been used for a while, at most once a
+// minute. l.mu must be held.
+func (l *UserRateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < time.Minute {
+		return
+	}
+	l.lastSweep = now
+	for userID, bucket := range l.buckets {
+		if now.Sub(bucket.last) > userBucketIdle && now.Sub(bucket.loadedAt) > userBucketIdle {
+			delete(l.buckets, userID)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+)
+
+type staticUserPlans map[int64]*int
+
+func (s staticUserPlans) GetEffectivePlan(ctx context.Context, userID int64) (*models.MembershipPlan, error) {
+	return &models.MembershipPlan{RequestsPerMinute: s[userID]}, nil
+}
+
+func TestUserRateLimiterUsesPlanLimits(t *testing.T) {
+	two := 2
+	now := time.Date(2026, time.April, 1, 12, 0, 0, 0, time.UTC)
+	limiter := NewUserRateLimiter(staticUserPlans{1: &two, 2: nil}, func(r *http.Request) int64 {
+		id, _ := strconv.ParseInt(r.Header.Get("X-User"), 10, 64)
+		return id
+	})
+	limiter.now = func() time.Time { return now }
+	handler := limiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	do := func(user string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/settings/jira", nil)
+		req.Header.Set("X-User", user)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for i := 0; i < 2; i++ {
+		if rec := do("1"); rec.Code != http.StatusOK {
+			t.Fatalf("request %d: status = %d, want 200", i+1, rec.Code)
+		}
+	}
+	rec := do("1")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("third request: status = %d, want 429", rec.Code)
+	}
+	// Two requests a minute refill one token every 30 seconds.
+	if got := rec.Header().Get("Retry-After"); got != "30" {
+		t.Fatalf("Retry-After = %q, want 30", got)
+	}
+
+	for _, user := range []string{"2", "0"} {
+		for i := 0; i < 5; i++ {
+			if rec := do(user); rec.Code != http.StatusOK {
+				t.Fatalf("user %s request %d: status = %d, want 200 without a limit", user, i+1, rec.Code)
+			}
+		}
+	}
+
+	now = now.Add(30 * time.Second)
+	if rec := do("1"); rec.Code != http.StatusOK || rec.Header().Get("X-RateLimit-Remaining") != "0" {
+		t.Fatalf("after refill: status = %d, remaining = %q", rec.Code, rec.Header().Get("X-RateLimit-Remaining"))
+	}
+}
@@ -0,0 +1,312 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const migrationsTable = "mcp_jira_thing_schema_migrations"
+
+// DriftReport lists the differences between the live schema and the schema
+// the embedded migrations produce at the live schema version. Entries are
+// "table", "table.column type" or "index ON table" strings.
+type DriftReport struct {
+	Version           uint
+	UnexpectedTables  []string
+	MissingTables     []string
+	UnexpectedColumns []string
+	MissingColumns    []string
+	ChangedColumns    []string
+	UnexpectedIndexes []string
+	MissingIndexes    []string
+	ChangedIndexes    []string
+}
+
+// Empty reports whether the live schema matches the migrations.
+func (r *DriftReport) Empty() bool {
+	return len(r.UnexpectedTables)+len(r.MissingTables)+len(r.UnexpectedColumns)+
+		len(r.MissingColumns)+len(r.ChangedColumns)+len(r.UnexpectedIndexes)+
+		len(r.MissingIndexes)+len(r.ChangedIndexes) == 0
+}
+
+// Lines returns one human readable line per difference.
+func (r *DriftReport) Lines() []string {
+	var lines []string
+	add := func(label string, items []string) {
+		for _, item := range items {
+			lines = append(lines, label+": "+item)
+		}
+	}
+	add("unexpected table", r.UnexpectedTables)
+	add("missing table", r.MissingTables)
+	add("unexpected column", r.UnexpectedColumns)
+	add("missing column", r.MissingColumns)
+	add("changed column", r.ChangedColumns)
+	add("unexpected index", r.UnexpectedIndexes)
+	add("missing index", r.MissingIndexes)
+	add("changed index", r.ChangedIndexes)
+	return lines
+}
+
+// schemaSnapshot holds the tables, columns and indexes of one schema keyed
+// by name, with the column type or normalized index definition as value.
+type schemaSnapshot struct {
+	tables  map[string]bool
+	columns map[string]string
+	indexes map[string]string
+}
+
+// CheckDrift compares the live schema with the schema produced by replaying
+// the embedded migrations, up to the live version, into a scratch schema.
+// The scratch schema is created inside a transaction that is always rolled
+// back, so the check leaves no trace. The database user needs permission to
+// create schemas.
+func CheckDrift(ctx context.Context, db *sql.DB) (*DriftReport, error) {
+	var version uint
+	var dirty bool
+	if err := db.QueryRowContext(ctx, `SELECT version, dirty FROM `+migrationsTable).Scan(&version, &dirty); err != nil {
+		return nil, fmt.Errorf("migrations: read schema version: %w", err)
+	}
+	if dirty {
+		return nil, fmt.Errorf("migrations: schema version %d is dirty", version)
+	}
+
+	files, err := upMigrations(version)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("migrations: begin drift check tx: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	var live string
+	if err := tx.QueryRowContext(ctx, `SELECT current_schema()`).Scan(&live); err != nil {
+		return nil, fmt.Errorf("migrations: read current schema: %w", err)
+	}
+
+	scratch := fmt.Sprintf("drift_check_%d", os.Getpid())
+	if _, err := tx.ExecContext(ctx, `CREATE SCHEMA `+scratch); err != nil {
+		return nil, fmt.Errorf("migrations: create scratch schema: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `SET LOCAL search_path TO `+scratch); err != nil {
+		return nil, fmt.Errorf("migrations: use scratch schema: %w", err)
+	}
+	for _, name := range files {
+		body, err := fs.ReadFile(sqlFS, "sql/"+name)
+		if err != nil {
+			return nil, fmt.Errorf("migrations: read %s: %w", name, err)
+		}
+		if _, err := tx.ExecContext(ctx, string(body)); err != nil {
+			return nil, fmt.Errorf("migrations: replay %s: %w", name, err)
+		}
+	}
+
+	expected, err := snapshotSchema(ctx, tx, scratch)
+	if err != nil {
+		return nil, err
+	}
+	actual, err := snapshotSchema(ctx, tx, live)
+	if err != nil {
+		return nil, err
+	}
+	delete(actual.tables, migrationsTable)
+	for key := range actual.columns {
+		if strings.HasPrefix(key, migrationsTable+".") {
+			delete(actual.columns, key)
+		}
+	}
+	for key := range actual.indexes {
+		if strings.HasSuffix(key, " ON "+migrationsTable) {
+			delete(actual.indexes, key)
+		}
+	}
+
+	report := &DriftReport{Version: version}
+	report.UnexpectedTables, report.MissingTables = diffKeys(actual.tables, expected.tables)
+	report.UnexpectedColumns, report.MissingColumns, report.ChangedColumns = diffValues(actual.columns, expected.columns)
+	report.UnexpectedIndexes, report.MissingIndexes, report.ChangedIndexes = diffValues(actual.indexes, expected.indexes)
+
+	// Columns and indexes of unexpected or missing tables are already
+	// covered by the table entry.
+	report.UnexpectedColumns = withoutTables(report.UnexpectedColumns, report.UnexpectedTables)
+	report.MissingColumns = withoutTables(report.MissingColumns, report.MissingTables)
+	report.UnexpectedIndexes = withoutTables(report.UnexpectedIndexes, report.UnexpectedTables)
+	report.MissingIndexes = withoutTables(report.MissingIndexes, report.MissingTables)
+
+	return report, nil
+}
+
+// upMigrations returns the embedded up migrations with versions up to and
+// including version, in order.
+func upMigrations(version uint) ([]string, error) {
+	entries, err := fs.ReadDir(sqlFS, "sql")
+	if err != nil {
+		return nil, fmt.Errorf("migrations: list embedded migrations: %w", err)
+	}
+
+	type migration struct {
+		version uint64
+		name    string
+	}
+	var ups []migration
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasSuffix(name, ".up.sql") {
+			continue
+		}
+		prefix, _, ok := strings.Cut(name, "_")
+		if !ok {
+			return nil, fmt.Errorf("migrations: unexpected file name %s", name)
+		}
+		v, err := strconv.ParseUint(prefix, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("migrations: unexpected file name %s", name)
+		}
+		if v <= uint64(version) {
+			ups = append(ups, migration{version: v, name: name})
+		}
+	}
+	if len(ups) == 0 {
+		return nil, errors.New("migrations: no migrations applied")
+	}
+
+	sort.Slice(ups, func(i, j int) bool { return ups[i].version < ups[j].version })
+	names := make([]string, len(ups))
+	for i, m := range ups {
+		names[i] = m.name
+	}
+	return names, nil
+}
+
+func snapshotSchema(ctx context.Context, tx *sql.Tx, schema string) (*schemaSnapshot, error) {
+	snap := &schemaSnapshot{
+		tables:  map[string]bool{},
+		columns: map[string]string{},
+		indexes: map[string]string{},
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT c.table_name, c.column_name, c.data_type, c.is_nullable
+		FROM information_schema.columns c
+		JOIN information_schema.tables t
+		  ON t.table_schema = c.table_schema AND t.table_name = c.table_name
+		WHERE c.table_schema = $1 AND t.table_type = 'BASE TABLE'
+	`, schema)
+	if err != nil {
+		return nil, fmt.Errorf("migrations: read columns of %s: %w", schema, err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var table, column, dataType, nullable string
+		if err := rows.Scan(&table, &column, &dataType, &nullable); err != nil {
+			return nil, fmt.Errorf("migrations: scan column: %w", err)
+		}
+		snap.tables[table] = true
+		if nullable == "NO" {
+			dataType += " not null"
+		}
+		snap.columns[table+"."+column] = dataType
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("migrations: iterate columns: %w", err)
+	}
+
+	rows, err = tx.QueryContext(ctx, `
+		SELECT tablename, indexname, indexdef FROM pg_indexes WHERE schemaname = $1
+	`, schema)
+	if err != nil {
+		return nil, fmt.Errorf("migrations: read indexes of %s: %w", schema, err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var table, index, def string
+		if err := rows.Scan(&table, &index, &def); err != nil {
+			return nil, fmt.Errorf("migrations: scan index: %w", err)
+		}
+		// Definitions are schema qualified ("ON public.users"); drop the
+		// schema so both snapshots compare equal.
+		snap.indexes[index+" ON "+table] = strings.ReplaceAll(def, " "+schema+".", " ")
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("migrations: iterate indexes: %w", err)
+	}
+
+	return snap, nil
+}
+
+func diffKeys(actual, expected map[string]bool) (unexpected, missing []string) {
+	for key := range actual {
+		if !expected[key] {
+			unexpected = append(unexpected, key)
+		}
+	}
+	for key := range expected {
+		if !actual[key] {
+			missing = append(missing, key)
+		}
+	}
+	sort.Strings(unexpected)
+	sort.Strings(missing)
+	return unexpected, missing
+}
+
+func diffValues(actual, expected map[string]string) (unexpected, missing, changed []string) {
+	for key, value := range actual {
+		want, ok := expected[key]
+		switch {
+		case !ok:
+			unexpected = append(unexpected, key+" "+value)
+		case want != value:
+			changed = append(changed, fmt.Sprintf("%s is %q, expected %q", key, value, want))
+		}
+	}
+	for key, value := range expected {
+		if _, ok := actual[key]; !ok {
+			missing = append(missing, key+" "+value)
+		}
+	}
+	sort.Strings(unexpected)
+	sort.Strings(missing)
+	sort.Strings(changed)
+	return unexpected, missing, changed
+}
+
+// withoutTables drops column entries ("table.column ...") and index entries
+// ("index ON table ...") that belong to one of tables.
+func withoutTables(entries, tables []string) []string {
+	if len(tables) == 0 {
+		return entries
+	}
+	skip := make(map[string]bool, len(tables))
+	for _, t := range tables {
+		skip[t] = true
+	}
+	var kept []string
+	for _, entry := range entries {
+		if !skip[entryTable(entry)] {
+			kept = append(kept, entry)
+		}
+	}
+	return kept
+}
+
+func entryTable(entry string) string {
+	name, rest, _ := strings.Cut(entry, " ")
+	if table, _, ok := strings.Cut(name, "."); ok {
+		return table
+	}
+	table, _, _ := strings.Cut(strings.TrimPrefix(rest, "ON "), " ")
+	return table
+}
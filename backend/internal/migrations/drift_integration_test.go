@@ -0,0 +1,54 @@
+//go:build integration
+
+package migrations_test
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/migrations"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/testutil"
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(testutil.Main(m))
+}
+
+func TestCheckDriftReportsHotfixes(t *testing.T) {
+	db := testutil.NewDB(t)
+	ctx := context.Background()
+
+	report, err := migrations.CheckDrift(ctx, db)
+	if err != nil {
+		t.Fatalf("CheckDrift: %v", err)
+	}
+	if !report.Empty() {
+		t.Fatalf("expected freshly migrated schema to match, got %v", report.Lines())
+	}
+
+	for _, stmt := range []string{
+		`ALTER TABLE users ADD COLUMN hotfix_flag BOOLEAN`,
+		`CREATE INDEX users_hotfix_idx ON users (hotfix_flag)`,
+		`CREATE TABLE hotfix_scratch (id INT)`,
+	} {
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+
+	report, err = migrations.CheckDrift(ctx, db)
+	if err != nil {
+		t.Fatalf("CheckDrift: %v", err)
+	}
+	if len(report.UnexpectedTables) != 1 || report.UnexpectedTables[0] != "hotfix_scratch" {
+		t.Fatalf("expected hotfix_scratch table, got %v", report.UnexpectedTables)
+	}
+	if len(report.UnexpectedColumns) != 1 || !strings.HasPrefix(report.UnexpectedColumns[0], "users.hotfix_flag boolean") {
+		t.Fatalf("expected users.hotfix_flag column, got %v", report.UnexpectedColumns)
+	}
+	if len(report.UnexpectedIndexes) != 1 || !strings.HasPrefix(report.UnexpectedIndexes[0], "users_hotfix_idx ON users") {
+		t.Fatalf("expected users_hotfix_idx index, got %v", report.UnexpectedIndexes)
+	}
+}
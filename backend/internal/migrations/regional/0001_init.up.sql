@@ -0,0 +1,31 @@
+-- A regional database holds the data of organizations resident in its
+-- region. Users and organizations stay in the primary database, so rows here
+-- reference them by ID without foreign keys.
+
+CREATE TABLE IF NOT EXISTS organization_jira_accounts (
+    org_id BIGINT PRIMARY KEY,
+    jira_base_url TEXT NOT NULL,
+    jira_email TEXT NOT NULL,
+    jira_cloud_id TEXT,
+    jira_api_token TEXT NOT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    updated_by BIGINT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS requests (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    method TEXT NOT NULL,
+    endpoint TEXT NOT NULL,
+    status_code INTEGER NOT NULL,
+    response_time_ms INTEGER,
+    request_size_bytes INTEGER,
+    response_size_bytes INTEGER,
+    error_message TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS requests_created_at_idx ON requests (created_at);
+CREATE INDEX IF NOT EXISTS requests_user_created_idx ON requests (user_id, created_at);
@@ -0,0 +1,3 @@
+-- Mirrors 0040_add_request_sample_weight of the primary schema.
+ALTER TABLE requests ADD COLUMN IF NOT EXISTS sample_weight INTEGER NOT NULL DEFAULT 1
+    CHECK (sample_weight >= 1);
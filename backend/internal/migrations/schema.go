@@ -16,17 +16,32 @@ import (
 //go:embed sql/*.sql
 var sqlFS embed.FS
 
+// regionalFS contains the migrations of regional databases, which only hold
+// the data of organizations resident in their region.
+//
+//go:embed regional/*.sql
+var regionalFS embed.FS
+
 // Up applies all pending database migrations. It is safe to call multiple
 // times; when the database schema is up to date, the function is a no-op.
 func Up(db *sql.DB) error {
+	return up(db, sqlFS, "sql", "mcp_jira_thing_schema_migrations")
+}
+
+// UpRegional applies pending migrations to a regional database.
+func UpRegional(db *sql.DB) error {
+	return up(db, regionalFS, "regional", "mcp_jira_thing_regional_schema_migrations")
+}
+
+func up(db *sql.DB, fsys embed.FS, dir, table string) error {
 	driver, err := postgres.WithInstance(db, &postgres.Config{
-		MigrationsTable: "mcp_jira_thing_schema_migrations",
+		MigrationsTable: table,
 	})
 	if err != nil {
 		return fmt.Errorf("migrations: create postgres driver: %w", err)
 	}
 
-	sourceDriver, err := iofs.New(sqlFS, "sql")
+	sourceDriver, err := iofs.New(fsys, dir)
 	if err != nil {
 		return fmt.Errorf("migrations: open embedded migrations: %w", err)
 	}
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS users_stripe_customer_id_idx;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS stripe_customer_id;
@@ -0,0 +1,21 @@
+-- Store the Stripe customer ID on the user so checkout can reuse an existing
+-- customer instead of creating a new one for every session.
+
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS stripe_customer_id TEXT;
+
+CREATE INDEX IF NOT EXISTS users_stripe_customer_id_idx
+    ON users (stripe_customer_id)
+    WHERE stripe_customer_id IS NOT NULL;
+
+-- Backfill from existing subscriptions, preferring the most recent one.
+UPDATE users u
+SET stripe_customer_id = s.stripe_customer_id
+FROM (
+    SELECT DISTINCT ON (user_id) user_id, stripe_customer_id
+    FROM subscriptions
+    WHERE stripe_customer_id <> ''
+    ORDER BY user_id, created_at DESC
+) s
+WHERE u.id = s.user_id
+  AND u.stripe_customer_id IS NULL;
@@ -0,0 +1,4 @@
+ALTER TABLE subscriptions
+    DROP COLUMN IF EXISTS paused_at,
+    DROP COLUMN IF EXISTS pause_resumes_at,
+    DROP COLUMN IF EXISTS pause_behavior;
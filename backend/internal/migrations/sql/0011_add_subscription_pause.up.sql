@@ -0,0 +1,8 @@
+-- Track Stripe pause_collection state on subscriptions. A non-null
+-- pause_behavior means collection is paused and entitlements fall back to the
+-- free tier until the subscription is resumed.
+
+ALTER TABLE subscriptions
+    ADD COLUMN IF NOT EXISTS pause_behavior TEXT,          -- keep_as_draft, mark_uncollectible, void
+    ADD COLUMN IF NOT EXISTS pause_resumes_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS paused_at TIMESTAMPTZ;
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS user_notifications;
+DROP TABLE IF EXISTS announcement_reads;
+DROP TABLE IF EXISTS announcements;
@@ -0,0 +1,19 @@
+-- Transactional outbox: events are written in the same transaction as the
+-- state change that produced them and delivered to the in-process event bus
+-- by the outbox dispatcher.
+
+CREATE TABLE IF NOT EXISTS event_outbox (
+    id BIGSERIAL PRIMARY KEY,
+    topic TEXT NOT NULL,
+    payload JSONB NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    available_at TIMESTAMPTZ NOT NULL DEFAULT now(),  -- also used as the claim lease
+    dispatched_at TIMESTAMPTZ,
+    failed_at TIMESTAMPTZ,                            -- set once attempts are exhausted
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_event_outbox_pending
+    ON event_outbox (available_at, id)
+    WHERE dispatched_at IS NULL AND failed_at IS NULL;
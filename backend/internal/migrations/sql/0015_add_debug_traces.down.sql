@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS debug_traces;
+ALTER TABLE users DROP COLUMN IF EXISTS debug_mode_until;
@@ -0,0 +1,20 @@
+-- Opt-in per-tenant debug mode: while users.debug_mode_until is in the
+-- future, the MCP worker reports sanitized tool call payloads which are kept
+-- in a small per-user ring buffer and expire automatically.
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS debug_mode_until TIMESTAMPTZ;
+
+CREATE TABLE IF NOT EXISTS debug_traces (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    tool TEXT NOT NULL,
+    request JSONB NOT NULL DEFAULT '{}',
+    response JSONB NOT NULL DEFAULT '{}',
+    is_error BOOLEAN NOT NULL DEFAULT false,
+    duration_ms INTEGER,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_debug_traces_user_created ON debug_traces (user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_debug_traces_expires_at ON debug_traces (expires_at);
@@ -0,0 +1,12 @@
+-- Per-user display preferences used by the MCP worker when formatting Jira
+-- dates in tool responses and when interpreting natural dates ("next
+-- Friday") in tool arguments. Users without a row get the defaults.
+
+CREATE TABLE IF NOT EXISTS user_preferences (
+    user_id BIGINT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    timezone TEXT NOT NULL DEFAULT 'UTC',
+    locale TEXT NOT NULL DEFAULT 'en-US',
+    date_format TEXT NOT NULL DEFAULT 'iso',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
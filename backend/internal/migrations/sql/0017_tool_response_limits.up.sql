@@ -0,0 +1,12 @@
+-- Per-tenant caps on MCP tool response size. tool_name '*' is the tenant's
+-- default; other rows override it for a single tool. The MCP worker trims
+-- responses above the cap and hands out a continuation token for the rest.
+
+CREATE TABLE IF NOT EXISTS tool_response_limits (
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    tool_name TEXT NOT NULL,
+    max_bytes INTEGER NOT NULL CHECK (max_bytes > 0),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (user_id, tool_name)
+);
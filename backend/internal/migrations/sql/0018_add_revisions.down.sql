@@ -0,0 +1,3 @@
+ALTER TABLE membership_plans DROP COLUMN IF EXISTS revision;
+ALTER TABLE user_preferences DROP COLUMN IF EXISTS revision;
+ALTER TABLE users_settings DROP COLUMN IF EXISTS revision;
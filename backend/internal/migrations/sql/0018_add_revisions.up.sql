@@ -0,0 +1,7 @@
+-- Revision counters for optimistic concurrency. Writers may send the
+-- revision they last read (If-Match); the update is rejected if another
+-- writer bumped it in the meantime.
+
+ALTER TABLE users_settings ADD COLUMN IF NOT EXISTS revision INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE user_preferences ADD COLUMN IF NOT EXISTS revision INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE membership_plans ADD COLUMN IF NOT EXISTS revision INTEGER NOT NULL DEFAULT 1;
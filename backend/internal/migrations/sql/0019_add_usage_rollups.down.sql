@@ -0,0 +1,2 @@
+ALTER TABLE membership_plans DROP COLUMN IF EXISTS monthly_request_quota;
+DROP TABLE IF EXISTS request_daily_rollups;
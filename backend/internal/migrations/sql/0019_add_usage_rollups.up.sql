@@ -0,0 +1,20 @@
+-- Daily per-user request rollups, refreshed periodically from the raw
+-- requests table so usage reports and forecasts don't scan it, plus the
+-- monthly request allowance each plan includes (NULL = unlimited).
+
+CREATE TABLE IF NOT EXISTS request_daily_rollups (
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    day DATE NOT NULL,
+    request_count INTEGER NOT NULL DEFAULT 0,
+    error_count INTEGER NOT NULL DEFAULT 0,
+    total_response_ms BIGINT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (user_id, day)
+);
+
+CREATE INDEX IF NOT EXISTS idx_request_daily_rollups_day ON request_daily_rollups (day);
+
+ALTER TABLE membership_plans ADD COLUMN IF NOT EXISTS monthly_request_quota INTEGER;
+
+UPDATE membership_plans SET monthly_request_quota = 1000 WHERE slug = 'free' AND monthly_request_quota IS NULL;
+UPDATE membership_plans SET monthly_request_quota = 25000 WHERE slug = 'basic' AND monthly_request_quota IS NULL;
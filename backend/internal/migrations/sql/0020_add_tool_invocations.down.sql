@@ -0,0 +1,4 @@
+ALTER TABLE membership_plans DROP COLUMN IF EXISTS monthly_cost_unit_quota;
+ALTER TABLE request_daily_rollups DROP COLUMN IF EXISTS cost_units;
+DROP TABLE IF EXISTS tool_invocations;
+DROP TABLE IF EXISTS tool_cost_weights;
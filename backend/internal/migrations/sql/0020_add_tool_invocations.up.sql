@@ -0,0 +1,40 @@
+-- MCP tool invocation log with per-tool cost attribution. Each invocation
+-- records the cost units charged at the time, taken from tool_cost_weights
+-- (tools without a row cost 1). Daily cost totals are folded into
+-- request_daily_rollups and counted against the plan's cost unit allowance
+-- (NULL = unlimited).
+
+CREATE TABLE IF NOT EXISTS tool_cost_weights (
+    tool_name TEXT PRIMARY KEY,
+    cost_units INTEGER NOT NULL CHECK (cost_units > 0),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+INSERT INTO tool_cost_weights (tool_name, cost_units) VALUES
+    ('searchJiraIssues', 2),
+    ('searchWorkItems', 2),
+    ('getProjectOverview', 3),
+    ('manageBacklog', 5),
+    ('planSprint', 5),
+    ('completeSprintReport', 5),
+    ('generateImage', 10)
+ON CONFLICT (tool_name) DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS tool_invocations (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    tool_name TEXT NOT NULL,
+    cost_units INTEGER NOT NULL,
+    is_error BOOLEAN NOT NULL DEFAULT FALSE,
+    duration_ms INTEGER,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_tool_invocations_user_created ON tool_invocations (user_id, created_at);
+
+ALTER TABLE request_daily_rollups ADD COLUMN IF NOT EXISTS cost_units BIGINT NOT NULL DEFAULT 0;
+
+ALTER TABLE membership_plans ADD COLUMN IF NOT EXISTS monthly_cost_unit_quota INTEGER;
+
+UPDATE membership_plans SET monthly_cost_unit_quota = 2000 WHERE slug = 'free' AND monthly_cost_unit_quota IS NULL;
+UPDATE membership_plans SET monthly_cost_unit_quota = 50000 WHERE slug = 'basic' AND monthly_cost_unit_quota IS NULL;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS ip_rate_limits;
+DROP TABLE IF EXISTS auth_failures;
@@ -0,0 +1,25 @@
+-- Inputs and outputs of the abuse detector. auth_failures records rejected
+-- mcp_secret attempts by client IP (those requests have no user, so they
+-- never reach the requests table). ip_rate_limits holds temporary per-IP
+-- limits applied by the detector and enforced by the HTTP middleware until
+-- they expire.
+
+CREATE TABLE IF NOT EXISTS auth_failures (
+    id BIGSERIAL PRIMARY KEY,
+    client_ip TEXT NOT NULL,
+    reason TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_auth_failures_created ON auth_failures (created_at);
+CREATE INDEX IF NOT EXISTS idx_auth_failures_ip_created ON auth_failures (client_ip, created_at);
+
+CREATE TABLE IF NOT EXISTS ip_rate_limits (
+    client_ip TEXT PRIMARY KEY,
+    requests_per_minute INTEGER NOT NULL CHECK (requests_per_minute > 0),
+    reason TEXT NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_ip_rate_limits_expires ON ip_rate_limits (expires_at);
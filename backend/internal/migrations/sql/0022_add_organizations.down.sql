@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS organization_sso_configs;
+DROP TABLE IF EXISTS organization_domains;
+DROP TABLE IF EXISTS organization_members;
+DROP TABLE IF EXISTS organizations;
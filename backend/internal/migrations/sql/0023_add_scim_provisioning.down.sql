@@ -0,0 +1,7 @@
+ALTER TABLE organizations DROP COLUMN IF EXISTS billed_seats;
+DROP TABLE IF EXISTS organization_scim_tokens;
+DROP INDEX IF EXISTS idx_organization_members_scim_external_id;
+ALTER TABLE organization_members
+    DROP COLUMN IF EXISTS updated_at,
+    DROP COLUMN IF EXISTS scim_external_id,
+    DROP COLUMN IF EXISTS active;
@@ -0,0 +1,30 @@
+-- SCIM provisioning lets an organization's identity provider create,
+-- deactivate and remove members with an organization-scoped bearer token.
+-- Only token hashes are stored. Deactivated members keep their membership
+-- row but no longer count as billed seats or may sign in through SSO.
+
+ALTER TABLE organization_members
+    ADD COLUMN IF NOT EXISTS active BOOLEAN NOT NULL DEFAULT TRUE,
+    ADD COLUMN IF NOT EXISTS scim_external_id TEXT,
+    ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ NOT NULL DEFAULT now();
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_organization_members_scim_external_id
+    ON organization_members (org_id, scim_external_id)
+    WHERE scim_external_id IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS organization_scim_tokens (
+    id BIGSERIAL PRIMARY KEY,
+    org_id BIGINT NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    name TEXT NOT NULL DEFAULT '',
+    token_hash TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_used_at TIMESTAMPTZ,
+    revoked_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_organization_scim_tokens_org ON organization_scim_tokens (org_id);
+
+-- Seats last pushed to the owner's Stripe subscription, so membership
+-- changes only call Stripe when the count actually moves.
+ALTER TABLE organizations
+    ADD COLUMN IF NOT EXISTS billed_seats INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS organization_join_requests;
+
+ALTER TABLE organization_domains
+    DROP COLUMN IF EXISTS verification_error,
+    DROP COLUMN IF EXISTS last_checked_at,
+    DROP COLUMN IF EXISTS verified_at,
+    DROP COLUMN IF EXISTS verification_token;
@@ -0,0 +1,23 @@
+-- Domain capture: an organization proves it owns a claimed email domain by
+-- publishing a DNS TXT record with the domain's verification token. Once
+-- verified, users who sign up with an address on the domain are queued as
+-- join requests for an organization admin to approve. Decided requests are
+-- kept so a user is only routed to an organization once.
+
+ALTER TABLE organization_domains
+    ADD COLUMN IF NOT EXISTS verification_token TEXT NOT NULL DEFAULT md5(random()::text || clock_timestamp()::text),
+    ADD COLUMN IF NOT EXISTS verified_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS last_checked_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS verification_error TEXT;
+
+CREATE TABLE IF NOT EXISTS organization_join_requests (
+    org_id BIGINT NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    domain TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'approved', 'rejected')),
+    decided_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (org_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_organization_join_requests_user ON organization_join_requests (user_id);
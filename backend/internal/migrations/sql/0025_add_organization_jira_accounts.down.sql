@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_tool_invocations_org_created;
+ALTER TABLE tool_invocations DROP COLUMN IF EXISTS org_id;
+DROP TABLE IF EXISTS organization_jira_accounts;
@@ -0,0 +1,24 @@
+-- A shared Jira service account lets organization members use Jira through
+-- their MCP secrets without pasting their own API token. Members with
+-- personal Jira settings keep using them. Tool invocations made with the
+-- shared credential record the organization so usage can be attributed to
+-- each member.
+
+CREATE TABLE IF NOT EXISTS organization_jira_accounts (
+    org_id BIGINT PRIMARY KEY REFERENCES organizations(id) ON DELETE CASCADE,
+    jira_base_url TEXT NOT NULL,
+    jira_email TEXT NOT NULL,
+    jira_cloud_id TEXT,
+    jira_api_token TEXT NOT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    updated_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+ALTER TABLE tool_invocations
+    ADD COLUMN IF NOT EXISTS org_id BIGINT REFERENCES organizations(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_tool_invocations_org_created
+    ON tool_invocations (org_id, created_at)
+    WHERE org_id IS NOT NULL;
@@ -0,0 +1,9 @@
+-- Stripe test clocks created for users in non-production profiles. Each
+-- user's test customer is attached to its own clock so billing lifecycles
+-- can be simulated independently.
+CREATE TABLE IF NOT EXISTS stripe_test_clocks (
+    user_id BIGINT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    test_clock_id TEXT NOT NULL UNIQUE,
+    stripe_customer_id TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
@@ -0,0 +1,16 @@
+-- Statements that exceeded the slow query threshold, aggregated per
+-- normalized statement text, with the latest captured plan. dbtool's index
+-- report reads this table to suggest missing indexes.
+CREATE TABLE IF NOT EXISTS slow_queries (
+    fingerprint TEXT PRIMARY KEY,
+    query TEXT NOT NULL,
+    calls BIGINT NOT NULL DEFAULT 0,
+    total_ms DOUBLE PRECISION NOT NULL DEFAULT 0,
+    max_ms DOUBLE PRECISION NOT NULL DEFAULT 0,
+    plan JSONB,
+    plan_captured_at TIMESTAMPTZ,
+    first_seen_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_seen_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_slow_queries_total_ms ON slow_queries (total_ms DESC);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_jobs_dedup;
+ALTER TABLE jobs DROP COLUMN IF EXISTS payload_hash;
@@ -0,0 +1,5 @@
+-- Hash of job_type + payload used to collapse duplicate pending jobs
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS payload_hash TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_jobs_dedup ON jobs(job_type, payload_hash, created_at)
+    WHERE status = 'pending' AND payload_hash IS NOT NULL;
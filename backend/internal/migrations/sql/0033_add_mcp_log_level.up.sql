@@ -0,0 +1,5 @@
+-- Minimum level of the MCP log messages the worker streams to a tenant's
+-- client sessions. Clients may still change it per session with
+-- logging/setLevel.
+
+ALTER TABLE user_preferences ADD COLUMN IF NOT EXISTS mcp_log_level TEXT NOT NULL DEFAULT 'info';
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS tool_approvals;
+DROP TABLE IF EXISTS tool_approval_policies;
@@ -0,0 +1,26 @@
+-- Per-tenant switches for the human approval gate on MCP tools. The row for
+-- tool_name '*' covers every destructive tool without its own row.
+CREATE TABLE IF NOT EXISTS tool_approval_policies (
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    tool_name TEXT NOT NULL,
+    require_approval BOOLEAN NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (user_id, tool_name)
+);
+
+-- Tool calls held until the tenant approves them. The worker executes the
+-- stored arguments once an approval is granted, at most once.
+CREATE TABLE IF NOT EXISTS tool_approvals (
+    id BIGSERIAL PRIMARY KEY,
+    token TEXT NOT NULL UNIQUE,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    tool_name TEXT NOT NULL,
+    arguments JSONB NOT NULL DEFAULT '{}'::jsonb,
+    status TEXT NOT NULL DEFAULT 'pending',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at TIMESTAMPTZ NOT NULL,
+    decided_at TIMESTAMPTZ,
+    executed_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_tool_approvals_user ON tool_approvals (user_id, status, created_at DESC);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS usage_digests;
+ALTER TABLE user_preferences DROP COLUMN IF EXISTS usage_digest;
+ALTER TABLE tool_invocations DROP COLUMN IF EXISTS project_key;
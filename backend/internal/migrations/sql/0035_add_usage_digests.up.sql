@@ -0,0 +1,13 @@
+-- Weekly usage digests: the Jira project each tool call touched, the user's
+-- opt-out, and a record of the digests already sent so each week's digest
+-- goes out once.
+ALTER TABLE tool_invocations ADD COLUMN IF NOT EXISTS project_key TEXT;
+
+ALTER TABLE user_preferences ADD COLUMN IF NOT EXISTS usage_digest BOOLEAN NOT NULL DEFAULT TRUE;
+
+CREATE TABLE IF NOT EXISTS usage_digests (
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    week_start DATE NOT NULL,
+    sent_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (user_id, week_start)
+);
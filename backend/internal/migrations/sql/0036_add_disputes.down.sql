@@ -0,0 +1,3 @@
+ALTER TABLE users DROP COLUMN IF EXISTS suspended_reason;
+ALTER TABLE users DROP COLUMN IF EXISTS suspended_at;
+DROP TABLE IF EXISTS disputes;
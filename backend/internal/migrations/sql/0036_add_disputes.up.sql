@@ -0,0 +1,21 @@
+-- Stripe charge disputes, and account suspension while a dispute is open.
+CREATE TABLE IF NOT EXISTS disputes (
+    id BIGSERIAL PRIMARY KEY,
+    stripe_dispute_id TEXT NOT NULL UNIQUE,
+    stripe_charge_id TEXT NOT NULL,
+    stripe_customer_id TEXT,
+    user_id BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    amount INTEGER NOT NULL,
+    currency TEXT NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL,
+    suspended BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    closed_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_disputes_user ON disputes (user_id);
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS suspended_at TIMESTAMPTZ;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS suspended_reason TEXT;
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS plan_version_assignments;
+DROP TABLE IF EXISTS plan_version_rollouts;
+UPDATE plan_versions SET status = 'archived', archived_at = now() WHERE status = 'rollout';
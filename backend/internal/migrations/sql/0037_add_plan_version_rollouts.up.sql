@@ -0,0 +1,20 @@
+-- Gradual price rollouts. A plan version in 'rollout' status is offered to a
+-- share of new subscribers (a percentage bucket of user IDs, or everyone on
+-- listed email domains) while the plan's active version stays the default.
+-- Assignments keep each user on the version they were first offered until
+-- the rollout is promoted.
+CREATE TABLE IF NOT EXISTS plan_version_rollouts (
+    plan_version_id BIGINT PRIMARY KEY REFERENCES plan_versions(id) ON DELETE CASCADE,
+    percent INTEGER NOT NULL DEFAULT 0 CHECK (percent BETWEEN 0 AND 100),
+    email_domains TEXT[] NOT NULL DEFAULT '{}',
+    started_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS plan_version_assignments (
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    plan_id BIGINT NOT NULL REFERENCES membership_plans(id) ON DELETE CASCADE,
+    plan_version_id BIGINT NOT NULL REFERENCES plan_versions(id) ON DELETE CASCADE,
+    assigned_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (user_id, plan_id)
+);
@@ -0,0 +1 @@
+ALTER TABLE plan_versions DROP COLUMN IF EXISTS migration_policy;
@@ -0,0 +1,6 @@
+-- Grandfathering rule applied to a deprecated version's subscribers when its
+-- grace period ends: migrate right away, migrate with the new price starting
+-- at renewal, or keep them on the old price for good.
+ALTER TABLE plan_versions
+    ADD COLUMN IF NOT EXISTS migration_policy TEXT NOT NULL DEFAULT 'migrate_at_deadline'
+        CHECK (migration_policy IN ('migrate_at_deadline', 'migrate_at_renewal', 'grandfather'));
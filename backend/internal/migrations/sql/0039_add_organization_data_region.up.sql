@@ -0,0 +1,5 @@
+-- Organizations with a data region keep their shared Jira account and their
+-- members' request logs in that region's database. The primary database keeps
+-- a placeholder account row without credentials so membership lookups still
+-- know the organization has an enabled shared account.
+ALTER TABLE organizations ADD COLUMN IF NOT EXISTS data_region TEXT;
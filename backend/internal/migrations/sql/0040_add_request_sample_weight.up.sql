@@ -0,0 +1,5 @@
+-- Under load only a sample of successful requests is logged. Each row counts
+-- for sample_weight requests: itself plus the skipped successes of the same
+-- user before it, so usage totals stay exact.
+ALTER TABLE requests ADD COLUMN IF NOT EXISTS sample_weight INTEGER NOT NULL DEFAULT 1
+    CHECK (sample_weight >= 1);
@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_jobs_deleted_at;
+DROP INDEX IF EXISTS idx_users_settings_deleted_at;
+
+DELETE FROM jobs WHERE deleted_at IS NOT NULL;
+DELETE FROM users_settings WHERE deleted_at IS NOT NULL;
+
+ALTER TABLE jobs DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE users_settings DROP COLUMN IF EXISTS deleted_at;
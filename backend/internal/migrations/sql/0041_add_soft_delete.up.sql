@@ -0,0 +1,7 @@
+-- Deleted Jira settings and jobs are kept for a grace window so accidental
+-- deletions can be undone; the retention job purges them afterwards.
+ALTER TABLE users_settings ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_users_settings_deleted_at ON users_settings (deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_jobs_deleted_at ON jobs (deleted_at) WHERE deleted_at IS NOT NULL;
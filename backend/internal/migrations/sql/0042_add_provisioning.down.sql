@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS recurring_jobs;
+DROP TABLE IF EXISTS feature_flags;
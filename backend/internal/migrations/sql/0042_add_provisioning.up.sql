@@ -0,0 +1,31 @@
+-- Feature flags and recurring jobs are declared by slug through the admin
+-- provisioning API, so infrastructure-as-code tooling can configure each
+-- environment the same way it configures plans.
+CREATE TABLE IF NOT EXISTS feature_flags (
+    slug TEXT PRIMARY KEY,
+    description TEXT,
+    enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    -- Share of users, bucketed by user ID, the flag is on for when enabled.
+    percent INTEGER NOT NULL DEFAULT 100 CHECK (percent BETWEEN 0 AND 100),
+    -- Users on these email domains always get the flag when enabled.
+    email_domains TEXT[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS recurring_jobs (
+    slug TEXT PRIMARY KEY,
+    job_type TEXT NOT NULL,
+    payload JSONB NOT NULL DEFAULT '{}',
+    priority TEXT NOT NULL DEFAULT 'normal',
+    max_attempts INTEGER NOT NULL DEFAULT 3,
+    interval_seconds INTEGER NOT NULL CHECK (interval_seconds >= 60),
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    next_run_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_enqueued_at TIMESTAMPTZ,
+    last_job_id BIGINT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_recurring_jobs_next_run_at ON recurring_jobs (next_run_at) WHERE enabled;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS jira_issue_mirror;
+DROP TABLE IF EXISTS jira_webhook_deliveries;
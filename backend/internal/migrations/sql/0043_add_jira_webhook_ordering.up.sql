@@ -0,0 +1,27 @@
+-- Jira retries a webhook delivery with the same X-Atlassian-Webhook-Identifier;
+-- recording each delivery lets the backend drop the repeats.
+CREATE TABLE IF NOT EXISTS jira_webhook_deliveries (
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    delivery_id TEXT NOT NULL,
+    received_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (user_id, delivery_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_jira_webhook_deliveries_received_at
+    ON jira_webhook_deliveries (user_id, received_at);
+
+-- Latest state of each issue as delivered by webhooks. event_timestamp is
+-- the webhook's own timestamp (Unix milliseconds); a delivery older than
+-- the stored one never overwrites it.
+CREATE TABLE IF NOT EXISTS jira_issue_mirror (
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    issue_id TEXT NOT NULL,
+    issue_key TEXT NOT NULL,
+    summary TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL DEFAULT '',
+    deleted BOOLEAN NOT NULL DEFAULT FALSE,
+    last_webhook_event TEXT NOT NULL,
+    event_timestamp BIGINT NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (user_id, issue_id)
+);
@@ -0,0 +1,11 @@
+-- Tenants placed on a shard keep their request logs in that shard's
+-- database (SHARD_DATABASE_URLS). Tenants without a row stay on the
+-- primary database; a data region, when set, takes precedence.
+CREATE TABLE IF NOT EXISTS tenant_shards (
+    user_id BIGINT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    shard TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'moving')),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_tenant_shards_shard ON tenant_shards (shard);
@@ -0,0 +1 @@
+ALTER TABLE membership_plans DROP COLUMN IF EXISTS max_streaming_connections;
@@ -0,0 +1,7 @@
+-- Concurrent streaming connections (WebSocket and MCP event streams) a
+-- tenant may hold open; NULL means unlimited.
+ALTER TABLE membership_plans ADD COLUMN IF NOT EXISTS max_streaming_connections INTEGER;
+
+UPDATE membership_plans SET max_streaming_connections = 3 WHERE slug = 'free' AND max_streaming_connections IS NULL;
+UPDATE membership_plans SET max_streaming_connections = 10 WHERE slug = 'basic' AND max_streaming_connections IS NULL;
+UPDATE membership_plans SET max_streaming_connections = 50 WHERE slug = 'premium' AND max_streaming_connections IS NULL;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS mcp_pending_calls;
+DROP TABLE IF EXISTS mcp_sessions;
@@ -0,0 +1,25 @@
+-- MCP streamable HTTP sessions, kept so clients resume them after a network
+-- blip or a deploy instead of initializing again.
+CREATE TABLE IF NOT EXISTS mcp_sessions (
+    id TEXT PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    protocol_version TEXT NOT NULL,
+    client_info JSONB,
+    client_capabilities JSONB,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_seen_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_mcp_sessions_user_expires
+    ON mcp_sessions (user_id, expires_at);
+
+-- Tool calls whose response has not reached the client. response is NULL
+-- while the call runs; request_id is the JSON-RPC id as JSON text.
+CREATE TABLE IF NOT EXISTS mcp_pending_calls (
+    session_id TEXT NOT NULL REFERENCES mcp_sessions(id) ON DELETE CASCADE,
+    request_id TEXT NOT NULL,
+    response JSONB,
+    started_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (session_id, request_id)
+);
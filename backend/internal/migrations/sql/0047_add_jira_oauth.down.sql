@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_integration_tokens_expiring;
+ALTER TABLE users_settings DROP COLUMN IF EXISTS auth_method;
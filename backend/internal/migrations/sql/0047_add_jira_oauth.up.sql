@@ -0,0 +1,7 @@
+-- Jira sites connected through Atlassian OAuth (3LO) keep no API token;
+-- their access and refresh tokens live in integration_tokens under the
+-- 'atlassian' provider.
+ALTER TABLE users_settings ADD COLUMN IF NOT EXISTS auth_method TEXT NOT NULL DEFAULT 'api_token';
+
+CREATE INDEX IF NOT EXISTS idx_integration_tokens_expiring
+    ON integration_tokens (provider, expires_at) WHERE refresh_token IS NOT NULL;
@@ -0,0 +1,13 @@
+-- Per-tenant defaults applied by the jira_create_issue MCP tool when the
+-- arguments leave them out. Tenants without a row have no defaults.
+
+CREATE TABLE IF NOT EXISTS jira_issue_defaults (
+    user_id BIGINT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    project_key TEXT NOT NULL DEFAULT '',
+    issue_type TEXT NOT NULL DEFAULT '',
+    labels TEXT[] NOT NULL DEFAULT '{}',
+    components TEXT[] NOT NULL DEFAULT '{}',
+    revision INTEGER NOT NULL DEFAULT 1,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
@@ -0,0 +1,6 @@
+-- Roles grant access to operator endpoints; see models.RolePermissions.
+-- Everyone starts as a plain user.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role TEXT NOT NULL DEFAULT 'user';
+
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_role_check;
+ALTER TABLE users ADD CONSTRAINT users_role_check CHECK (role IN ('user', 'support', 'admin'));
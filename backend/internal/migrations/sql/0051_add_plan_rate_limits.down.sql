@@ -0,0 +1 @@
+ALTER TABLE membership_plans DROP COLUMN IF EXISTS requests_per_minute;
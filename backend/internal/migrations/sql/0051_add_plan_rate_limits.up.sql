@@ -0,0 +1,6 @@
+-- Requests per minute a tenant may make to the API; NULL means unlimited.
+ALTER TABLE membership_plans ADD COLUMN IF NOT EXISTS requests_per_minute INTEGER;
+
+UPDATE membership_plans SET requests_per_minute = 60 WHERE slug = 'free' AND requests_per_minute IS NULL;
+UPDATE membership_plans SET requests_per_minute = 300 WHERE slug = 'basic' AND requests_per_minute IS NULL;
+UPDATE membership_plans SET requests_per_minute = 1200 WHERE slug = 'premium' AND requests_per_minute IS NULL;
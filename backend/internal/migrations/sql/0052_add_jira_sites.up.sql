@@ -0,0 +1,9 @@
+-- What the backend learned about each Jira site when credentials for it
+-- were validated. service_management enables the Jira Service Management
+-- MCP tools for every tenant using the site.
+
+CREATE TABLE IF NOT EXISTS jira_sites (
+    jira_base_url TEXT PRIMARY KEY,
+    service_management BOOLEAN NOT NULL DEFAULT FALSE,
+    checked_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
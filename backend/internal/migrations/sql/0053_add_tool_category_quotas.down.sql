@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS plan_tool_quotas;
+DROP INDEX IF EXISTS idx_tool_invocations_user_category_created;
+ALTER TABLE tool_invocations DROP COLUMN IF EXISTS category;
+DROP TABLE IF EXISTS tool_categories;
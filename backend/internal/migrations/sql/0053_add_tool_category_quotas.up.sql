@@ -0,0 +1,46 @@
+-- Per-category daily tool quotas. Each tool belongs to a category (tools
+-- without a row are 'read'), each invocation records the category it was
+-- counted in, and plans may cap the successful calls per category per UTC
+-- day (no row = unlimited).
+
+CREATE TABLE IF NOT EXISTS tool_categories (
+    tool_name TEXT PRIMARY KEY,
+    category TEXT NOT NULL CHECK (category IN ('read', 'search', 'write')),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+INSERT INTO tool_categories (tool_name, category) VALUES
+    ('searchJiraIssues', 'search'),
+    ('searchWorkItems', 'search'),
+    ('jira_search_all_sites', 'search'),
+    ('jira_my_open_issues', 'search'),
+    ('createTask', 'write'),
+    ('createJiraProject', 'write'),
+    ('createJiraSprint', 'write'),
+    ('startJiraSprint', 'write'),
+    ('createJiraIssueType', 'write'),
+    ('editJiraIssueType', 'write'),
+    ('deleteJiraIssueType', 'write'),
+    ('jira_create_issue', 'write'),
+    ('jira_update_issue', 'write'),
+    ('jira_delete_issue', 'write'),
+    ('transitionJiraIssue', 'write'),
+    ('jsm_create_request', 'write'),
+    ('jsm_add_request_participants', 'write'),
+    ('jsm_transition_request', 'write')
+ON CONFLICT (tool_name) DO NOTHING;
+
+ALTER TABLE tool_invocations ADD COLUMN IF NOT EXISTS category TEXT NOT NULL DEFAULT 'read';
+
+CREATE INDEX IF NOT EXISTS idx_tool_invocations_user_category_created ON tool_invocations (user_id, category, created_at);
+
+CREATE TABLE IF NOT EXISTS plan_tool_quotas (
+    plan_id BIGINT NOT NULL REFERENCES membership_plans(id) ON DELETE CASCADE,
+    category TEXT NOT NULL CHECK (category IN ('read', 'search', 'write')),
+    daily_limit INTEGER NOT NULL CHECK (daily_limit > 0),
+    PRIMARY KEY (plan_id, category)
+);
+
+INSERT INTO plan_tool_quotas (plan_id, category, daily_limit)
+SELECT id, 'write', 100 FROM membership_plans WHERE slug = 'free'
+ON CONFLICT (plan_id, category) DO NOTHING;
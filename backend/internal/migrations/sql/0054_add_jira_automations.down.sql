@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS jira_automation_runs;
+DROP TABLE IF EXISTS jira_automations;
@@ -0,0 +1,41 @@
+-- Tenant-defined automations: run a JQL search on a weekly schedule and post
+-- the results to a Slack incoming webhook or a generic webhook. Each run is
+-- kept in jira_automation_runs; automations that keep failing are disabled.
+
+CREATE TABLE IF NOT EXISTS jira_automations (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    jql TEXT NOT NULL,
+    -- Days of the week to run on, 0 = Sunday; empty runs every day.
+    schedule_days INTEGER[] NOT NULL DEFAULT '{}',
+    -- Local time of day, HH:MM, in time_zone.
+    schedule_time TEXT NOT NULL,
+    time_zone TEXT NOT NULL DEFAULT 'UTC',
+    destination TEXT NOT NULL CHECK (destination IN ('slack', 'webhook')),
+    -- Sealed with the credential key like Jira API tokens.
+    destination_url TEXT NOT NULL,
+    max_results INTEGER NOT NULL DEFAULT 20 CHECK (max_results BETWEEN 1 AND 100),
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    next_run_at TIMESTAMPTZ NOT NULL,
+    last_run_at TIMESTAMPTZ,
+    last_status TEXT,
+    consecutive_failures INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_jira_automations_user ON jira_automations (user_id);
+CREATE INDEX IF NOT EXISTS idx_jira_automations_next_run_at ON jira_automations (next_run_at) WHERE enabled;
+
+CREATE TABLE IF NOT EXISTS jira_automation_runs (
+    id BIGSERIAL PRIMARY KEY,
+    automation_id BIGINT NOT NULL REFERENCES jira_automations(id) ON DELETE CASCADE,
+    status TEXT NOT NULL CHECK (status IN ('succeeded', 'failed')),
+    issue_count INTEGER NOT NULL DEFAULT 0,
+    error TEXT,
+    started_at TIMESTAMPTZ NOT NULL,
+    finished_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_jira_automation_runs_automation ON jira_automation_runs (automation_id, started_at DESC);
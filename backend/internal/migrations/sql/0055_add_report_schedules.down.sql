@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS report_artifacts;
+DROP TABLE IF EXISTS report_schedules;
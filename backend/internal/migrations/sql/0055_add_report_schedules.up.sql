@@ -0,0 +1,42 @@
+-- Recurring reports rendered from built-in templates (sprint summary, weekly
+-- triage, stale issues) for one project, on a weekly schedule like
+-- jira_automations. Reports are mailed or kept in report_artifacts for
+-- download.
+
+CREATE TABLE IF NOT EXISTS report_schedules (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    template TEXT NOT NULL CHECK (template IN ('sprint_summary', 'weekly_triage', 'stale_issues')),
+    project_key TEXT NOT NULL,
+    -- Optional JQL AND-ed onto the template's own query.
+    jql TEXT NOT NULL DEFAULT '',
+    format TEXT NOT NULL DEFAULT 'markdown' CHECK (format IN ('markdown', 'html')),
+    delivery TEXT NOT NULL DEFAULT 'email' CHECK (delivery IN ('email', 'artifact')),
+    schedule_days INTEGER[] NOT NULL DEFAULT '{}',
+    schedule_time TEXT NOT NULL,
+    time_zone TEXT NOT NULL DEFAULT 'UTC',
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    next_run_at TIMESTAMPTZ NOT NULL,
+    last_run_at TIMESTAMPTZ,
+    last_error TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_report_schedules_user ON report_schedules (user_id);
+CREATE INDEX IF NOT EXISTS idx_report_schedules_next_run_at ON report_schedules (next_run_at) WHERE enabled;
+
+CREATE TABLE IF NOT EXISTS report_artifacts (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    schedule_id BIGINT REFERENCES report_schedules(id) ON DELETE SET NULL,
+    template TEXT NOT NULL,
+    format TEXT NOT NULL,
+    title TEXT NOT NULL,
+    content TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_report_artifacts_user ON report_artifacts (user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_report_artifacts_schedule ON report_artifacts (schedule_id, created_at DESC);
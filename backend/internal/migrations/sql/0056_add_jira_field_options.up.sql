@@ -0,0 +1,16 @@
+-- Allowed values of select-style custom fields, synced per tenant, Jira
+-- site and project from Jira's create metadata so the MCP tools can check
+-- option values before sending them.
+
+CREATE TABLE IF NOT EXISTS jira_field_options (
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    site_url TEXT NOT NULL,
+    project_key TEXT NOT NULL,
+    field_id TEXT NOT NULL,
+    field_name TEXT NOT NULL,
+    multi BOOLEAN NOT NULL DEFAULT FALSE,
+    option_id TEXT NOT NULL,
+    value TEXT NOT NULL,
+    synced_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (user_id, site_url, project_key, field_id, option_id)
+);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS issue_comment_sync_log;
+DROP TABLE IF EXISTS issue_comment_channels;
@@ -0,0 +1,41 @@
+-- External channels an issue's comments are synced with: a Slack thread or
+-- an email address. New Jira comments are posted to the channel and replies
+-- in the channel come back as Jira comments. Replies by email reach the
+-- backend through the plus-addressed reply address holding reply_token.
+
+CREATE TABLE IF NOT EXISTS issue_comment_channels (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    issue_key TEXT NOT NULL,
+    kind TEXT NOT NULL CHECK (kind IN ('slack', 'email')),
+    slack_channel TEXT,
+    -- Set from the first message posted when the channel starts a thread.
+    slack_thread_ts TEXT,
+    email_address TEXT,
+    reply_token TEXT NOT NULL UNIQUE,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    last_synced_at TIMESTAMPTZ,
+    last_error TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (user_id, issue_key, kind)
+);
+
+CREATE INDEX IF NOT EXISTS idx_issue_comment_channels_slack_thread
+    ON issue_comment_channels (slack_channel, slack_thread_ts) WHERE kind = 'slack';
+
+-- Every comment synced through a channel. Outbound rows are keyed by the
+-- Jira comment ID and inbound rows by the Slack message ts or email
+-- Message-ID, with the Jira comment created for them, so nothing is synced
+-- twice or echoed back where it came from.
+CREATE TABLE IF NOT EXISTS issue_comment_sync_log (
+    channel_id BIGINT NOT NULL REFERENCES issue_comment_channels(id) ON DELETE CASCADE,
+    direction TEXT NOT NULL CHECK (direction IN ('outbound', 'inbound')),
+    external_id TEXT NOT NULL,
+    jira_comment_id TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (channel_id, direction, external_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_issue_comment_sync_log_jira_comment
+    ON issue_comment_sync_log (channel_id, jira_comment_id) WHERE jira_comment_id IS NOT NULL;
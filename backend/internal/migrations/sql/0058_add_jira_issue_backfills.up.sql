@@ -0,0 +1,21 @@
+-- Backfills of the issue mirror from a JQL search. next_page_token is the
+-- checkpoint: the search page a resumed backfill continues from. total is
+-- Jira's estimate of the matching issues when the backfill started.
+CREATE TABLE IF NOT EXISTS jira_issue_backfills (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    jql TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'running', 'completed', 'failed')),
+    next_page_token TEXT NOT NULL DEFAULT '',
+    fetched INTEGER NOT NULL DEFAULT 0,
+    total INTEGER,
+    job_id BIGINT,
+    last_error TEXT,
+    started_at TIMESTAMPTZ,
+    completed_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_jira_issue_backfills_user
+    ON jira_issue_backfills (user_id, created_at DESC);
@@ -0,0 +1,5 @@
+-- Deleted accounts are kept for a grace window so a deletion can be undone;
+-- the retention job purges them and their data afterwards.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users (deleted_at) WHERE deleted_at IS NOT NULL;
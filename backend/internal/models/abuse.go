@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// AuthFailureReasonInvalidMCPSecret marks a request carrying an mcp_secret
+// that matches no user.
+const AuthFailureReasonInvalidMCPSecret = "invalid_mcp_secret"
+
+// AuthFailureBurst is a client IP with many rejected credentials in a window.
+type AuthFailureBurst struct {
+	ClientIP string `json:"client_ip"`
+	Failures int    `json:"failures"`
+}
+
+// RequestSpike is a user whose recent request volume far exceeds their
+// baseline for a window of the same length.
+type RequestSpike struct {
+	UserID   int64   `json:"user_id"`
+	Recent   int     `json:"recent"`
+	Baseline float64 `json:"baseline"`
+}
+
+// IPRateLimit is a temporary request rate cap on a client IP.
+type IPRateLimit struct {
+	ClientIP          string    `json:"client_ip"`
+	RequestsPerMinute int       `json:"requests_per_minute"`
+	Reason            string    `json:"reason"`
+	ExpiresAt         time.Time `json:"expires_at"`
+	CreatedAt         time.Time `json:"created_at"`
+}
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"slices"
+	"time"
+)
+
+// APIKeyPrefix starts every API key, so leaked keys are easy to spot.
+const APIKeyPrefix = "mjt_"
+
+// API key scopes. A key only opens the routes its scopes cover.
+const (
+	// APIKeyScopeTenant covers the backend-only routes the MCP Worker calls
+	// with a tenant's mcp_secret.
+	APIKeyScopeTenant = "tenant"
+	// APIKeyScopeAuth covers the sign-in callbacks the SPA Worker forwards
+	// after an OAuth login.
+	APIKeyScopeAuth = "auth"
+	// APIKeyScopeJobs covers the job queue endpoints.
+	APIKeyScopeJobs = "jobs"
+	// APIKeyScopeAdmin covers every operator endpoint.
+	APIKeyScopeAdmin = "admin"
+)
+
+// APIKeyScopePermissions lists the operator permissions each scope grants;
+// scopes for service routes grant none.
+var APIKeyScopePermissions = map[string][]Permission{
+	APIKeyScopeTenant: nil,
+	APIKeyScopeAuth:   nil,
+	APIKeyScopeJobs:   {PermissionViewJobs, PermissionManageJobs},
+	APIKeyScopeAdmin:  RolePermissions[RoleAdmin],
+}
+
+// ValidAPIKeyScope reports whether scope is a known API key scope.
+func ValidAPIKeyScope(scope string) bool {
+	_, ok := APIKeyScopePermissions[scope]
+	return ok
+}
+
+// APIKey is a key a server-to-server caller authenticates with. The key
+// itself is only returned when it is created.
+type APIKey struct {
+	ID         int64      `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	CreatedBy  *int64     `json:"created_by,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// HasScope reports whether the key carries scope.
+func (k APIKey) HasScope(scope string) bool {
+	return slices.Contains(k.Scopes, scope)
+}
+
+// Can reports whether one of the key's scopes grants perm.
+func (k APIKey) Can(perm Permission) bool {
+	for _, scope := range k.Scopes {
+		if slices.Contains(APIKeyScopePermissions[scope], perm) {
+			return true
+		}
+	}
+	return false
+}
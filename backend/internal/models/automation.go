@@ -0,0 +1,165 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Where automations post their results.
+const (
+	AutomationDestinationSlack   = "slack"
+	AutomationDestinationWebhook = "webhook"
+)
+
+// Outcomes of an automation run.
+const (
+	AutomationRunSucceeded = "succeeded"
+	AutomationRunFailed    = "failed"
+)
+
+// MaxAutomationFailures is how many runs in a row may fail before an
+// automation is disabled.
+const MaxAutomationFailures = 5
+
+// WeeklySchedule runs something at TimeOfDay (HH:MM) in TimeZone on each of
+// Days (0 = Sunday), or every day when Days is empty.
+type WeeklySchedule struct {
+	Days      []int  `json:"days"`
+	TimeOfDay string `json:"time_of_day"`
+	TimeZone  string `json:"time_zone"`
+}
+
+// Validate checks the schedule, defaulting the time zone to UTC.
+func (w *WeeklySchedule) Validate() error {
+	for _, day := range w.Days {
+		if day < 0 || day > 6 {
+			return errors.New("days must be between 0 (Sunday) and 6 (Saturday)")
+		}
+	}
+	if _, _, err := w.clock(); err != nil {
+		return err
+	}
+	if w.TimeZone == "" {
+		w.TimeZone = "UTC"
+	}
+	if _, err := time.LoadLocation(w.TimeZone); err != nil {
+		return fmt.Errorf("unknown time_zone %q", w.TimeZone)
+	}
+	return nil
+}
+
+// clock parses TimeOfDay.
+func (w *WeeklySchedule) clock() (hour, minute int, err error) {
+	t, err := time.Parse("15:04", w.TimeOfDay)
+	if err != nil {
+		return 0, 0, errors.New("time_of_day must be HH:MM")
+	}
+	return t.Hour(), t.Minute(), nil
+}
+
+// NextRun returns the first scheduled time after after.
+func (w *WeeklySchedule) NextRun(after time.Time) (time.Time, error) {
+	loc, err := time.LoadLocation(w.TimeZone)
+	if err != nil {
+		return time.Time{}, err
+	}
+	hour, minute, err := w.clock()
+	if err != nil {
+		return time.Time{}, err
+	}
+	days := map[time.Weekday]bool{}
+	for _, day := range w.Days {
+		days[time.Weekday(day)] = true
+	}
+
+	local := after.In(loc)
+	for i := 0; i <= 7; i++ {
+		day := local.AddDate(0, 0, i)
+		run := time.Date(day.Year(), day.Month(), day.Day(), hour, minute, 0, 0, loc)
+		if run.After(after) && (len(days) == 0 || days[run.Weekday()]) {
+			return run, nil
+		}
+	}
+	return time.Time{}, errors.New("schedule has no upcoming run")
+}
+
+// JiraAutomation runs a JQL search on a weekly schedule and posts the
+// matching issues to Slack or a webhook. DestinationURL is a secret and
+// never returned by the API.
+type JiraAutomation struct {
+	ID     int64  `json:"id"`
+	UserID int64  `json:"-"`
+	Name   string `json:"name"`
+	JQL    string `json:"jql"`
+	WeeklySchedule
+	Destination         string     `json:"destination"`
+	DestinationURL      string     `json:"-"`
+	MaxResults          int        `json:"max_results"`
+	Enabled             bool       `json:"enabled"`
+	NextRunAt           time.Time  `json:"next_run_at"`
+	LastRunAt           *time.Time `json:"last_run_at,omitempty"`
+	LastStatus          *string    `json:"last_status,omitempty"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	CreatedAt           time.Time  `json:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at"`
+}
+
+// Validate checks the automation's definition, filling in the default time
+// zone and result count.
+func (a *JiraAutomation) Validate() error {
+	a.Name = strings.TrimSpace(a.Name)
+	a.JQL = strings.TrimSpace(a.JQL)
+	if a.Name == "" {
+		return errors.New("name is required")
+	}
+	if a.JQL == "" {
+		return errors.New("jql is required")
+	}
+	if err := a.WeeklySchedule.Validate(); err != nil {
+		return err
+	}
+	if a.MaxResults == 0 {
+		a.MaxResults = 20
+	}
+	if a.MaxResults < 1 || a.MaxResults > 100 {
+		return errors.New("max_results must be between 1 and 100")
+	}
+
+	u, err := url.Parse(a.DestinationURL)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return errors.New("destination_url must be an https URL")
+	}
+	switch a.Destination {
+	case AutomationDestinationSlack:
+		if u.Host != "hooks.slack.com" {
+			return errors.New("slack destinations must be a Slack incoming webhook URL (https://hooks.slack.com/...)")
+		}
+	case AutomationDestinationWebhook:
+	default:
+		return errors.New("destination must be slack or webhook")
+	}
+	return nil
+}
+
+// JiraAutomationRun is one run of an automation.
+type JiraAutomationRun struct {
+	ID           int64     `json:"id"`
+	AutomationID int64     `json:"automation_id"`
+	Status       string    `json:"status"`
+	IssueCount   int       `json:"issue_count"`
+	Error        *string   `json:"error,omitempty"`
+	StartedAt    time.Time `json:"started_at"`
+	FinishedAt   time.Time `json:"finished_at"`
+}
+
+// AutomationRunTarget is an automation about to run together with what
+// running it needs: the owner's MCP secret, which resolves their Jira
+// credentials, and their email for failure alerts.
+type AutomationRunTarget struct {
+	Automation JiraAutomation
+	MCPSecret  string
+	Email      string
+}
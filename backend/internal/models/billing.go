@@ -3,31 +3,93 @@ package models
 import "time"
 
 type Subscription struct {
-	ID                   int64     `json:"id"`
-	UserID               int64     `json:"user_id"`
-	StripeCustomerID     string    `json:"stripe_customer_id"`
-	StripeSubscriptionID string    `json:"stripe_subscription_id"`
-	StripePriceID        string    `json:"stripe_price_id"`
-	Status               string    `json:"status"`
-	CurrentPeriodStart   time.Time `json:"current_period_start"`
-	CurrentPeriodEnd     time.Time `json:"current_period_end"`
-	CancelAtPeriodEnd    bool      `json:"cancel_at_period_end"`
+	ID                   int64      `json:"id"`
+	UserID               int64      `json:"user_id"`
+	StripeCustomerID     string     `json:"stripe_customer_id"`
+	StripeSubscriptionID string     `json:"stripe_subscription_id"`
+	StripePriceID        string     `json:"stripe_price_id"`
+	Status               string     `json:"status"`
+	CurrentPeriodStart   time.Time  `json:"current_period_start"`
+	CurrentPeriodEnd     time.Time  `json:"current_period_end"`
+	CancelAtPeriodEnd    bool       `json:"cancel_at_period_end"`
 	CanceledAt           *time.Time `json:"canceled_at,omitempty"`
-	CreatedAt            time.Time `json:"created_at"`
-	UpdatedAt            time.Time `json:"updated_at"`
+	PauseBehavior        *string    `json:"pause_behavior,omitempty"`
+	PauseResumesAt       *time.Time `json:"pause_resumes_at,omitempty"`
+	PausedAt             *time.Time `json:"paused_at,omitempty"`
+	CreatedAt            time.Time  `json:"created_at"`
+	UpdatedAt            time.Time  `json:"updated_at"`
+}
+
+// IsPaused reports whether payment collection is currently paused for the
+// subscription. Paused subscriptions are entitled to the free tier only.
+func (s *Subscription) IsPaused() bool {
+	return s != nil && s.PauseBehavior != nil && *s.PauseBehavior != ""
 }
 
 type PaymentHistory struct {
-	ID                     int64     `json:"id"`
-	UserID                 int64     `json:"user_id"`
-	SubscriptionID         *int64    `json:"subscription_id,omitempty"`
-	StripeCustomerID       string    `json:"stripe_customer_id"`
-	StripePaymentIntentID  *string   `json:"stripe_payment_intent_id,omitempty"`
-	StripeInvoiceID        *string   `json:"stripe_invoice_id,omitempty"`
-	Amount                 int       `json:"amount"`
-	Currency               string    `json:"currency"`
-	Status                 string    `json:"status"`
-	Description            *string   `json:"description,omitempty"`
-	ReceiptURL             *string   `json:"receipt_url,omitempty"`
-	CreatedAt              time.Time `json:"created_at"`
+	ID                    int64     `json:"id"`
+	UserID                int64     `json:"user_id"`
+	SubscriptionID        *int64    `json:"subscription_id,omitempty"`
+	StripeCustomerID      string    `json:"stripe_customer_id"`
+	StripePaymentIntentID *string   `json:"stripe_payment_intent_id,omitempty"`
+	StripeInvoiceID       *string   `json:"stripe_invoice_id,omitempty"`
+	Amount                int       `json:"amount"`
+	Currency              string    `json:"currency"`
+	Status                string    `json:"status"`
+	Description           *string   `json:"description,omitempty"`
+	ReceiptURL            *string   `json:"receipt_url,omitempty"`
+	CreatedAt             time.Time `json:"created_at"`
+}
+
+// Stripe dispute statuses that end a dispute.
+const (
+	DisputeWon           = "won"
+	DisputeLost          = "lost"
+	DisputeWarningClosed = "warning_closed"
+)
+
+// SuspensionReasonDispute marks accounts suspended because of an open charge
+// dispute, so closing the dispute only lifts suspensions it caused.
+const SuspensionReasonDispute = "dispute"
+
+// Dispute is a Stripe charge dispute. Amount is in the currency's minor
+// unit. UserID is nil when the disputed charge's customer is not one of ours.
+type Dispute struct {
+	ID               int64      `json:"id"`
+	StripeDisputeID  string     `json:"stripe_dispute_id"`
+	StripeChargeID   string     `json:"stripe_charge_id"`
+	StripeCustomerID string     `json:"stripe_customer_id,omitempty"`
+	UserID           *int64     `json:"user_id,omitempty"`
+	Amount           int        `json:"amount"`
+	Currency         string     `json:"currency"`
+	Reason           string     `json:"reason"`
+	Status           string     `json:"status"`
+	Suspended        bool       `json:"suspended"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+	ClosedAt         *time.Time `json:"closed_at,omitempty"`
+}
+
+// IsClosed reports whether Stripe has decided the dispute.
+func (d *Dispute) IsClosed() bool {
+	switch d.Status {
+	case DisputeWon, DisputeLost, DisputeWarningClosed:
+		return true
+	}
+	return false
+}
+
+// RevenueMonth summarizes recurring revenue for one calendar month (UTC).
+// Amounts are in the plans' currency minor unit; yearly plans count a
+// twelfth of their price towards MRR. MRR and ARR are as of the end of the
+// month; Collected is what successful payments brought in during it.
+type RevenueMonth struct {
+	Month               time.Time `json:"month"`
+	MRR                 int64     `json:"mrr"`
+	ARR                 int64     `json:"arr"`
+	NewMRR              int64     `json:"new_mrr"`
+	ChurnedMRR          int64     `json:"churned_mrr"`
+	NetNewMRR           int64     `json:"net_new_mrr"`
+	ActiveSubscriptions int       `json:"active_subscriptions"`
+	Collected           int64     `json:"collected"`
 }
@@ -0,0 +1,120 @@
+package models
+
+import (
+	"errors"
+	"net/mail"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Kinds of external channels an issue's comments are synced with.
+const (
+	CommentChannelSlack = "slack"
+	CommentChannelEmail = "email"
+)
+
+// Directions a comment is synced in.
+const (
+	CommentSyncOutbound = "outbound"
+	CommentSyncInbound  = "inbound"
+)
+
+// CommentSyncMarker ends every comment the sync writes, in Jira and in the
+// channels. Text carrying it is never synced again, which keeps a comment
+// from bouncing between Jira and a channel.
+const CommentSyncMarker = "[synced by mcp-jira-thing]"
+
+var (
+	issueKeyPattern     = regexp.MustCompile(`^[A-Z][A-Z0-9_]+-[1-9][0-9]*$`)
+	slackChannelPattern = regexp.MustCompile(`^[CG][A-Z0-9]{6,}$`)
+	slackTSPattern      = regexp.MustCompile(`^[0-9]+\.[0-9]+$`)
+)
+
+// CommentChannel links an issue to a Slack thread or an email address.
+// New Jira comments on the issue are posted to the channel, and replies in
+// the thread or to the channel's reply address are added to the issue.
+type CommentChannel struct {
+	ID            int64  `json:"id"`
+	UserID        int64  `json:"-"`
+	IssueKey      string `json:"issue_key"`
+	Kind          string `json:"kind"`
+	SlackChannel  string `json:"slack_channel,omitempty"`
+	SlackThreadTS string `json:"slack_thread_ts,omitempty"`
+	EmailAddress  string `json:"email_address,omitempty"`
+	// ReplyToken routes email replies back to the channel; it is part of
+	// the reply address and never returned by itself.
+	ReplyToken   string     `json:"-"`
+	Enabled      bool       `json:"enabled"`
+	LastSyncedAt *time.Time `json:"last_synced_at,omitempty"`
+	LastError    *string    `json:"last_error,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// Validate checks the channel, normalizing the issue key and address.
+func (c *CommentChannel) Validate() error {
+	c.IssueKey = strings.ToUpper(strings.TrimSpace(c.IssueKey))
+	if !issueKeyPattern.MatchString(c.IssueKey) {
+		return errors.New("issue_key must be an issue key such as OPS-12")
+	}
+	switch c.Kind {
+	case CommentChannelSlack:
+		c.SlackChannel = strings.TrimSpace(c.SlackChannel)
+		c.SlackThreadTS = strings.TrimSpace(c.SlackThreadTS)
+		if !slackChannelPattern.MatchString(c.SlackChannel) {
+			return errors.New("slack_channel must be a Slack channel ID such as C0123456789")
+		}
+		if c.SlackThreadTS != "" && !slackTSPattern.MatchString(c.SlackThreadTS) {
+			return errors.New("slack_thread_ts must be the ts of the thread's first message")
+		}
+		c.EmailAddress = ""
+	case CommentChannelEmail:
+		addr, err := mail.ParseAddress(strings.TrimSpace(c.EmailAddress))
+		if err != nil {
+			return errors.New("email_address must be an email address")
+		}
+		c.EmailAddress = addr.Address
+		c.SlackChannel, c.SlackThreadTS = "", ""
+	default:
+		return errors.New("kind must be slack or email")
+	}
+	return nil
+}
+
+// CommentSyncTarget is a channel about to be synced together with what
+// syncing needs: the owner's MCP secret, which resolves their Jira
+// credentials, and their email, which finds their Slack token.
+type CommentSyncTarget struct {
+	Channel   CommentChannel
+	MCPSecret string
+	Email     string
+}
+
+// ReplyAddress returns the address replies to the channel's emails go to:
+// base plus-addressed with the channel's reply token.
+func (c *CommentChannel) ReplyAddress(base string) string {
+	local, domain, ok := strings.Cut(base, "@")
+	if !ok || c.ReplyToken == "" {
+		return ""
+	}
+	return local + "+" + c.ReplyToken + "@" + domain
+}
+
+// ReplyToken extracts the reply token from an address built by
+// ReplyAddress for base, ignoring case.
+func ReplyToken(base, address string) (string, bool) {
+	baseLocal, baseDomain, ok := strings.Cut(strings.ToLower(base), "@")
+	if !ok {
+		return "", false
+	}
+	local, domain, ok := strings.Cut(strings.ToLower(strings.TrimSpace(address)), "@")
+	if !ok || domain != baseDomain {
+		return "", false
+	}
+	prefix, token, ok := strings.Cut(local, "+")
+	if !ok || prefix != baseLocal || token == "" {
+		return "", false
+	}
+	return token, true
+}
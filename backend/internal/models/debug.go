@@ -0,0 +1,20 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// DebugTrace is a sanitized record of a single MCP tool call captured while
+// the tenant has debug mode enabled.
+type DebugTrace struct {
+	ID         int64           `json:"id"`
+	UserID     int64           `json:"-"`
+	Tool       string          `json:"tool"`
+	Request    json.RawMessage `json:"request"`
+	Response   json.RawMessage `json:"response"`
+	IsError    bool            `json:"is_error"`
+	DurationMs *int            `json:"duration_ms,omitempty"`
+	CreatedAt  time.Time       `json:"created_at"`
+	ExpiresAt  time.Time       `json:"expires_at"`
+}
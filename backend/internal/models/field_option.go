@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// JiraFieldOption is one allowed value of a select, radio button or
+// checkbox custom field in a project, as last synced from Jira.
+type JiraFieldOption struct {
+	FieldID   string `json:"field_id"`
+	FieldName string `json:"field_name"`
+	// Multi is set for fields that take several options.
+	Multi    bool      `json:"multi"`
+	OptionID string    `json:"option_id"`
+	Value    string    `json:"value"`
+	SyncedAt time.Time `json:"synced_at"`
+}
+
+// FieldOptionSyncTarget is a tenant whose select field options are synced,
+// with the credentials and projects to sync them for.
+type FieldOptionSyncTarget struct {
+	UserID    int64
+	MCPSecret string
+	// ProjectKeys are the projects the tenant used recently, and its default
+	// project.
+	ProjectKeys []string
+}
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// JiraIssueDefaults are a tenant's defaults for new Jira issues, applied
+// when an issue is created without them.
+type JiraIssueDefaults struct {
+	ProjectKey string   `json:"project_key"`
+	IssueType  string   `json:"issue_type"`
+	Labels     []string `json:"labels"`
+	Components []string `json:"components"`
+
+	// Revision is 0 until the tenant saves defaults for the first time.
+	Revision  int64      `json:"revision"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
@@ -0,0 +1,53 @@
+package models
+
+import "time"
+
+// JiraIssueState is the mirrored state of a Jira issue as last delivered by
+// the tenant's webhooks.
+type JiraIssueState struct {
+	UserID   int64  `json:"-"`
+	IssueID  string `json:"issue_id"`
+	IssueKey string `json:"issue_key"`
+	Summary  string `json:"summary"`
+	Status   string `json:"status"`
+	Deleted  bool   `json:"deleted"`
+	// WebhookEvent and EventTimestamp (Unix milliseconds) come from the
+	// webhook that produced this state.
+	WebhookEvent   string    `json:"webhook_event"`
+	EventTimestamp int64     `json:"event_timestamp"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// Issue backfill statuses.
+const (
+	IssueBackfillPending   = "pending"
+	IssueBackfillRunning   = "running"
+	IssueBackfillCompleted = "completed"
+	IssueBackfillFailed    = "failed"
+)
+
+// IssueBackfill loads the issues matching a JQL search into the issue
+// mirror. NextPageToken is its checkpoint; a failed backfill resumes from
+// it. Total is Jira's estimate of the matching issues, nil when unknown.
+type IssueBackfill struct {
+	ID            int64      `json:"id"`
+	UserID        int64      `json:"-"`
+	JQL           string     `json:"jql"`
+	Status        string     `json:"status"`
+	NextPageToken string     `json:"-"`
+	Fetched       int        `json:"fetched"`
+	Total         *int       `json:"total,omitempty"`
+	JobID         *int64     `json:"job_id,omitempty"`
+	LastError     *string    `json:"last_error,omitempty"`
+	StartedAt     *time.Time `json:"started_at,omitempty"`
+	CompletedAt   *time.Time `json:"completed_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// IssueBackfillTarget is a backfill about to run together with its owner's
+// MCP secret, which resolves their Jira credentials.
+type IssueBackfillTarget struct {
+	Backfill  IssueBackfill
+	MCPSecret string
+}
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// JiraOAuthProvider is the integration_tokens provider holding a user's
+// Atlassian OAuth tokens.
+const JiraOAuthProvider = "atlassian"
+
+// JiraOAuthConnection is the outcome of connecting Jira through Atlassian
+// OAuth: the tokens and the sites they grant access to.
+type JiraOAuthConnection struct {
+	AccountEmail string          `json:"account_email"`
+	AccessToken  string          `json:"-"`
+	RefreshToken string          `json:"-"`
+	ExpiresAt    time.Time       `json:"expires_at"`
+	Scopes       string          `json:"scopes"`
+	Sites        []JiraOAuthSite `json:"sites"`
+}
+
+// JiraOAuthSite is a Jira site reachable with a user's OAuth tokens.
+type JiraOAuthSite struct {
+	CloudID string `json:"cloud_id"`
+	URL     string `json:"url"`
+	Name    string `json:"name"`
+}
@@ -54,6 +54,12 @@ type Job struct {
 	CompletedAt  *time.Time      `json:"completed_at,omitempty"`
 	WorkerID     *string         `json:"worker_id,omitempty"`
 	Metadata     JSONB           `json:"metadata"`
+
+	// DedupWindow, when set, makes Enqueue return an existing pending job
+	// with the same type and payload created within the window instead of
+	// inserting a new one; Deduplicated reports that it did.
+	DedupWindow  time.Duration   `json:"-"`
+	Deduplicated bool            `json:"deduplicated,omitempty"`
 }
 
 // JSONB is a custom type for PostgreSQL JSONB columns
@@ -97,6 +103,32 @@ type JobStats struct {
 	Total      int `json:"total"`
 }
 
+// JobFilter selects failed jobs for listing and bulk retry. Zero fields
+// match everything.
+type JobFilter struct {
+	JobType       string      `json:"job_type,omitempty"`
+	Priority      JobPriority `json:"priority,omitempty"`
+	FailedAfter   *time.Time  `json:"failed_after,omitempty"`
+	FailedBefore  *time.Time  `json:"failed_before,omitempty"`
+	ErrorContains string      `json:"error_contains,omitempty"`
+	Limit         int         `json:"limit,omitempty"`
+}
+
+// JobEvent records one state transition of a job: enqueued, claimed,
+// completed, failed, retry_scheduled, released, cancelled, requeued,
+// deleted or restored.
+// WorkerID is the worker that held the job when the event happened.
+type JobEvent struct {
+	ID        int64     `json:"id"`
+	JobID     int64     `json:"job_id"`
+	Event     string    `json:"event"`
+	Status    JobStatus `json:"status"`
+	Attempt   int       `json:"attempt"`
+	WorkerID  *string   `json:"worker_id,omitempty"`
+	Error     *string   `json:"error,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
 // IsValid checks if the job is in a valid state for processing
 func (j *Job) IsValid() error {
 	if j.JobType == "" {
@@ -129,3 +161,26 @@ func (j *Job) ShouldProcess() bool {
 	}
 	return true
 }
+
+// OwnerID returns the ID of the user a job was enqueued for, read from the
+// "user_id" entry of the job metadata or payload. It returns 0 for system jobs.
+func (j *Job) OwnerID() int64 {
+	if j == nil {
+		return 0
+	}
+	for _, src := range []JSONB{j.Metadata, j.Payload} {
+		switch v := src["user_id"].(type) {
+		case float64:
+			return int64(v)
+		case int64:
+			return v
+		case int:
+			return int64(v)
+		case json.Number:
+			if n, err := v.Int64(); err == nil {
+				return n
+			}
+		}
+	}
+	return 0
+}
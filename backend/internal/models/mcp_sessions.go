@@ -0,0 +1,25 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// MCPSession is a persisted MCP streamable HTTP session.
+type MCPSession struct {
+	ID                 string          `json:"id"`
+	UserID             int64           `json:"user_id"`
+	ProtocolVersion    string          `json:"protocol_version"`
+	ClientInfo         json.RawMessage `json:"client_info,omitempty"`
+	ClientCapabilities json.RawMessage `json:"client_capabilities,omitempty"`
+	LastSeenAt         time.Time       `json:"last_seen_at"`
+	ExpiresAt          time.Time       `json:"expires_at"`
+}
+
+// MCPPendingCall is a tool call of an MCP session whose response has not
+// reached the client. Response is nil while the call runs.
+type MCPPendingCall struct {
+	RequestID string          `json:"request_id"`
+	Response  json.RawMessage `json:"response,omitempty"`
+	StartedAt time.Time       `json:"started_at"`
+}
@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// Notification sources distinguish system-wide announcements from events
+// that concern a single user.
+const (
+	NotificationSourceAnnouncement = "announcement"
+	NotificationSourceUser         = "user"
+)
+
+// Notification kinds recorded for individual users.
+const (
+	NotificationKindPaymentFailed      = "payment_failed"
+	NotificationKindCredentialFailure  = "credential_failure"
+	NotificationKindToolApproval       = "tool_approval"
+	NotificationKindAutomationFailure  = "automation_failure"
+	NotificationKindReportFailure      = "report_failure"
+	NotificationKindCommentSyncFailure = "comment_sync_failure"
+)
+
+// Notification is a single entry in a user's notification feed. It is either
+// a system announcement or a per-user event; ID is only unique within Source.
+type Notification struct {
+	ID        int64     `json:"id"`
+	Source    string    `json:"source"`
+	Kind      string    `json:"kind"`
+	Title     string    `json:"title"`
+	Body      string    `json:"body"`
+	Severity  string    `json:"severity"`
+	Metadata  JSONB     `json:"metadata,omitempty"`
+	Read      bool      `json:"read"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// NotificationRef identifies a notification to mark as read.
+type NotificationRef struct {
+	Source string `json:"source"`
+	ID     int64  `json:"id"`
+}
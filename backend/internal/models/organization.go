@@ -0,0 +1,145 @@
+package models
+
+import "time"
+
+// Organization member roles, from most to least privileged.
+const (
+	OrgRoleOwner  = "owner"
+	OrgRoleAdmin  = "admin"
+	OrgRoleMember = "member"
+)
+
+// SSO protocols an organization can configure.
+const (
+	SSOProtocolOIDC = "oidc"
+	SSOProtocolSAML = "saml"
+)
+
+// Organization groups users under shared administration.
+type Organization struct {
+	ID          int64     `json:"id"`
+	Slug        string    `json:"slug"`
+	Name        string    `json:"name"`
+	SSOEnforced bool      `json:"sso_enforced"`
+	Role        string    `json:"role,omitempty"` // caller's role, when listed for a user
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+
+	// DataRegion names the regional database holding the organization's
+	// shared Jira account and its members' request logs; nil means the
+	// primary database.
+	DataRegion *string `json:"data_region,omitempty"`
+}
+
+// DomainVerificationPrefix names the DNS TXT record an organization
+// publishes under a claimed domain to prove it owns it.
+const DomainVerificationPrefix = "_mcp-jira-thing-challenge"
+
+// OrganizationDomain is an email domain claimed by an organization. A
+// domain is captured once VerifiedAt is set: new users signing up with an
+// address on it are queued to join the organization.
+type OrganizationDomain struct {
+	Domain            string     `json:"domain"`
+	VerificationToken string     `json:"verification_token"`
+	VerifiedAt        *time.Time `json:"verified_at,omitempty"`
+	LastCheckedAt     *time.Time `json:"last_checked_at,omitempty"`
+	VerificationError *string    `json:"verification_error,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+}
+
+// VerificationRecord returns the name and value of the DNS TXT record that
+// proves ownership of the domain.
+func (d OrganizationDomain) VerificationRecord() (name, value string) {
+	return DomainVerificationPrefix + "." + d.Domain, "mcp-jira-thing-verification=" + d.VerificationToken
+}
+
+// OrganizationJoinRequest is a user who signed up with an address on one of
+// the organization's verified domains and awaits an admin's approval.
+type OrganizationJoinRequest struct {
+	UserID    int64     `json:"user_id"`
+	Email     string    `json:"email"`
+	Name      *string   `json:"name,omitempty"`
+	Domain    string    `json:"domain"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// SSOConfig is an organization's identity provider configuration. Only the
+// fields for Protocol are used. The OIDC client secret is write-only.
+type SSOConfig struct {
+	OrgID    int64  `json:"-"`
+	Protocol string `json:"protocol"`
+	Enabled  bool   `json:"enabled"`
+
+	OIDCIssuer       string `json:"oidc_issuer,omitempty"`
+	OIDCClientID     string `json:"oidc_client_id,omitempty"`
+	OIDCClientSecret string `json:"oidc_client_secret,omitempty"`
+
+	SAMLIdPEntityID    string `json:"saml_idp_entity_id,omitempty"`
+	SAMLIdPSSOURL      string `json:"saml_idp_sso_url,omitempty"`
+	SAMLIdPCertificate string `json:"saml_idp_certificate,omitempty"`
+
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// SSOIdentity is a user asserted by an organization's identity provider.
+type SSOIdentity struct {
+	Subject string
+	Email   string
+	Name    *string
+}
+
+// OrganizationMember is a user's membership in an organization. Inactive
+// members were deprovisioned by the identity provider: they are not billed
+// and may not sign in through SSO.
+type OrganizationMember struct {
+	UserID     int64     `json:"user_id"`
+	Email      string    `json:"email"`
+	Name       *string   `json:"name,omitempty"`
+	Role       string    `json:"role"`
+	Active     bool      `json:"active"`
+	ExternalID *string   `json:"external_id,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// OrganizationMemberUpdate holds the membership fields a SCIM client may
+// change. Nil fields are left as they are.
+type OrganizationMemberUpdate struct {
+	Active     *bool
+	ExternalID *string
+	Name       *string
+}
+
+// SCIMToken is an organization-scoped bearer token for SCIM provisioning.
+// The token itself is only returned once, when it is created.
+type SCIMToken struct {
+	ID         int64      `json:"id"`
+	Name       string     `json:"name"`
+	Token      string     `json:"token,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// OrganizationJiraAccount is a Jira service-account credential shared by an
+// organization's members. The API token is write-only.
+type OrganizationJiraAccount struct {
+	OrgID       int64     `json:"-"`
+	JiraBaseURL string    `json:"jira_base_url"`
+	JiraEmail   string    `json:"jira_email"`
+	JiraCloudID *string   `json:"jira_cloud_id,omitempty"`
+	APIToken    string    `json:"atlassian_api_key,omitempty"`
+	Enabled     bool      `json:"enabled"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// OrganizationMemberUsage aggregates one member's tool invocations made with
+// the organization's shared Jira account.
+type OrganizationMemberUsage struct {
+	UserID      int64   `json:"user_id"`
+	Email       string  `json:"email"`
+	Name        *string `json:"name,omitempty"`
+	Invocations int     `json:"invocations"`
+	Errors      int     `json:"errors"`
+	CostUnits   int64   `json:"cost_units"`
+}
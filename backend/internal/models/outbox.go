@@ -0,0 +1,20 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// OutboxEvent is an event persisted in the transactional outbox awaiting
+// delivery to the event bus.
+type OutboxEvent struct {
+	ID           int64           `json:"id"`
+	Topic        string          `json:"topic"`
+	Payload      json.RawMessage `json:"payload"`
+	Attempts     int             `json:"attempts"`
+	LastError    *string         `json:"last_error,omitempty"`
+	AvailableAt  time.Time       `json:"available_at"`
+	DispatchedAt *time.Time      `json:"dispatched_at,omitempty"`
+	FailedAt     *time.Time      `json:"failed_at,omitempty"`
+	CreatedAt    time.Time       `json:"created_at"`
+}
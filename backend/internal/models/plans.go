@@ -1,17 +1,35 @@
 package models
 
-import "time"
+import (
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"time"
+)
 
 // MembershipPlan represents a membership tier (free, basic, premium)
 type MembershipPlan struct {
-	ID          int64     `json:"id"`
-	Slug        string    `json:"slug"`
-	Name        string    `json:"name"`
-	Description *string   `json:"description,omitempty"`
-	Tier        int       `json:"tier"`
-	IsActive    bool      `json:"is_active"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID          int64   `json:"id"`
+	Slug        string  `json:"slug"`
+	Name        string  `json:"name"`
+	Description *string `json:"description,omitempty"`
+	Tier        int     `json:"tier"`
+	IsActive    bool    `json:"is_active"`
+	// MonthlyRequestQuota is the included request volume per calendar
+	// month; nil means unlimited.
+	MonthlyRequestQuota *int `json:"monthly_request_quota,omitempty"`
+	// MonthlyCostUnitQuota is the included MCP tool cost units per
+	// calendar month; nil means unlimited.
+	MonthlyCostUnitQuota *int `json:"monthly_cost_unit_quota,omitempty"`
+	// MaxStreamingConnections caps the WebSocket and MCP event streams a
+	// tenant may hold open at once; nil means unlimited.
+	MaxStreamingConnections *int `json:"max_streaming_connections,omitempty"`
+	// RequestsPerMinute caps each tenant's API requests per minute; nil
+	// means unlimited.
+	RequestsPerMinute *int      `json:"requests_per_minute,omitempty"`
+	Revision          int64     `json:"revision"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
 }
 
 // PlanVersionStatus represents the lifecycle state of a plan version
@@ -21,8 +39,37 @@ const (
 	PlanVersionActive     PlanVersionStatus = "active"
 	PlanVersionDeprecated PlanVersionStatus = "deprecated"
 	PlanVersionArchived   PlanVersionStatus = "archived"
+
+	// PlanVersionRollingOut versions are offered to a rollout cohort of new
+	// subscribers until promoted to active.
+	PlanVersionRollingOut PlanVersionStatus = "rollout"
 )
 
+// PlanMigrationPolicy is the grandfathering rule for subscribers of a
+// deprecated plan version.
+type PlanMigrationPolicy string
+
+const (
+	// PlanMigrateAtDeadline moves subscribers to the active version as soon
+	// as the grace period ends, prorating the change.
+	PlanMigrateAtDeadline PlanMigrationPolicy = "migrate_at_deadline"
+	// PlanMigrateAtRenewal switches subscribers once the grace period ends
+	// but bills the new price only from their next renewal.
+	PlanMigrateAtRenewal PlanMigrationPolicy = "migrate_at_renewal"
+	// PlanGrandfatherForever keeps subscribers on the deprecated version's
+	// price for as long as they stay subscribed.
+	PlanGrandfatherForever PlanMigrationPolicy = "grandfather"
+)
+
+// Valid reports whether p is a known migration policy.
+func (p PlanMigrationPolicy) Valid() bool {
+	switch p {
+	case PlanMigrateAtDeadline, PlanMigrateAtRenewal, PlanGrandfatherForever:
+		return true
+	}
+	return false
+}
+
 // PlanVersion represents a specific price version of a membership plan
 type PlanVersion struct {
 	ID                int64             `json:"id"`
@@ -40,6 +87,39 @@ type PlanVersion struct {
 	ArchivedAt        *time.Time        `json:"archived_at,omitempty"`
 	CreatedAt         time.Time         `json:"created_at"`
 	UpdatedAt         time.Time         `json:"updated_at"`
+
+	// MigrationPolicy decides what happens to the version's subscribers
+	// once it is deprecated and its grace period ends.
+	MigrationPolicy PlanMigrationPolicy `json:"migration_policy"`
+}
+
+// PlanVersionRollout configures who is offered a version in rollout
+// status: users whose email domain is listed, plus Percent percent of the
+// remaining users, bucketed by user ID so each user gets a stable answer.
+type PlanVersionRollout struct {
+	PlanVersionID int64     `json:"plan_version_id"`
+	Percent       int       `json:"percent"`
+	EmailDomains  []string  `json:"email_domains"`
+	StartedAt     time.Time `json:"started_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// Includes reports whether the user belongs to the rollout cohort.
+func (r *PlanVersionRollout) Includes(userID int64, email string) bool {
+	if at := strings.LastIndex(email, "@"); at >= 0 {
+		domain := strings.ToLower(email[at+1:])
+		for _, d := range r.EmailDomains {
+			if strings.EqualFold(d, domain) {
+				return true
+			}
+		}
+	}
+	if r.Percent <= 0 || userID <= 0 {
+		return false
+	}
+	h := fnv.New32a()
+	fmt.Fprintf(h, "%d:%d", r.PlanVersionID, userID)
+	return int(h.Sum32()%100) < r.Percent
 }
 
 // PlanWithCurrentVersion combines a plan with its active version for display
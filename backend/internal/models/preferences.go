@@ -0,0 +1,50 @@
+package models
+
+import "time"
+
+// Supported values for UserPreferences.DateFormat. "iso" keeps Jira's
+// timestamps machine-readable; the others map to Intl dateStyle presets in
+// the MCP worker.
+const (
+	DateFormatISO    = "iso"
+	DateFormatShort  = "short"
+	DateFormatMedium = "medium"
+	DateFormatLong   = "long"
+)
+
+// MCPLogLevels are the MCP logging levels, least severe first. The worker
+// streams tool logs at or above the tenant's MCPLogLevel to the client.
+var MCPLogLevels = []string{"debug", "info", "notice", "warning", "error", "critical", "alert", "emergency"}
+
+// DefaultMCPLogLevel is the MCP log level of users who have not chosen one.
+const DefaultMCPLogLevel = "info"
+
+// UserPreferences controls how dates are rendered and interpreted for a user.
+type UserPreferences struct {
+	Timezone   string `json:"timezone"`
+	Locale     string `json:"locale"`
+	DateFormat string `json:"date_format"`
+
+	MCPLogLevel string `json:"mcp_log_level"`
+
+	// UsageDigest controls whether the weekly usage digest is emailed.
+	UsageDigest bool `json:"usage_digest"`
+
+	// Revision is 0 until the user saves preferences for the first time.
+	Revision  int64      `json:"revision"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+// DefaultUserPreferences returns the preferences applied to users who have
+// not saved any.
+func DefaultUserPreferences() UserPreferences {
+	return UserPreferences{
+		Timezone:   "UTC",
+		Locale:     "en-US",
+		DateFormat: DateFormatISO,
+
+		MCPLogLevel: DefaultMCPLogLevel,
+
+		UsageDigest: true,
+	}
+}
@@ -0,0 +1,81 @@
+package models
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"time"
+)
+
+// FeatureFlag turns a feature on for some or all users. A disabled flag is
+// off for everyone; an enabled one is on for users whose email domain is
+// listed plus Percent percent of the rest, bucketed by user ID so each user
+// gets a stable answer.
+type FeatureFlag struct {
+	Slug         string    `json:"slug"`
+	Description  *string   `json:"description,omitempty"`
+	Enabled      bool      `json:"enabled"`
+	Percent      int       `json:"percent"`
+	EmailDomains []string  `json:"email_domains"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// EnabledFor reports whether the flag is on for the user.
+func (f *FeatureFlag) EnabledFor(userID int64, email string) bool {
+	if !f.Enabled {
+		return false
+	}
+	if at := strings.LastIndex(email, "@"); at >= 0 {
+		domain := strings.ToLower(email[at+1:])
+		for _, d := range f.EmailDomains {
+			if strings.EqualFold(d, domain) {
+				return true
+			}
+		}
+	}
+	if f.Percent >= 100 {
+		return true
+	}
+	if f.Percent <= 0 || userID <= 0 {
+		return false
+	}
+	h := fnv.New32a()
+	fmt.Fprintf(h, "%s:%d", f.Slug, userID)
+	return int(h.Sum32()%100) < f.Percent
+}
+
+// RecurringJob enqueues a job of JobType with Payload every
+// IntervalSeconds while enabled. NextRunAt is when it is next due.
+type RecurringJob struct {
+	Slug            string      `json:"slug"`
+	JobType         string      `json:"job_type"`
+	Payload         JSONB       `json:"payload"`
+	Priority        JobPriority `json:"priority"`
+	MaxAttempts     int         `json:"max_attempts"`
+	IntervalSeconds int         `json:"interval_seconds"`
+	Enabled         bool        `json:"enabled"`
+	NextRunAt       time.Time   `json:"next_run_at"`
+	LastEnqueuedAt  *time.Time  `json:"last_enqueued_at,omitempty"`
+	LastJobID       *int64      `json:"last_job_id,omitempty"`
+	CreatedAt       time.Time   `json:"created_at"`
+	UpdatedAt       time.Time   `json:"updated_at"`
+}
+
+// Interval returns the time between runs.
+func (r *RecurringJob) Interval() time.Duration {
+	return time.Duration(r.IntervalSeconds) * time.Second
+}
+
+// Job returns the job to enqueue for one run. Runs that are still pending
+// when the next one is due are deduplicated.
+func (r *RecurringJob) Job() *Job {
+	return &Job{
+		JobType:     r.JobType,
+		Payload:     r.Payload,
+		Priority:    r.Priority,
+		MaxAttempts: r.MaxAttempts,
+		Metadata:    JSONB{"recurring_job": r.Slug},
+		DedupWindow: r.Interval(),
+	}
+}
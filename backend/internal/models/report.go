@@ -0,0 +1,114 @@
+package models
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Built-in report templates.
+const (
+	ReportTemplateSprintSummary = "sprint_summary"
+	ReportTemplateWeeklyTriage  = "weekly_triage"
+	ReportTemplateStaleIssues   = "stale_issues"
+)
+
+// Formats reports are rendered in.
+const (
+	ReportFormatMarkdown = "markdown"
+	ReportFormatHTML     = "html"
+)
+
+// How rendered reports are delivered: mailed to the owner or kept as a
+// downloadable artifact.
+const (
+	ReportDeliveryEmail    = "email"
+	ReportDeliveryArtifact = "artifact"
+)
+
+// ValidReportTemplate reports whether name is a built-in report template.
+func ValidReportTemplate(name string) bool {
+	switch name {
+	case ReportTemplateSprintSummary, ReportTemplateWeeklyTriage, ReportTemplateStaleIssues:
+		return true
+	}
+	return false
+}
+
+var projectKeyPattern = regexp.MustCompile(`^[A-Z][A-Z0-9_]{0,254}$`)
+
+// ReportSchedule renders a report template for one Jira project on a weekly
+// schedule. JQL optionally narrows the template's own query.
+type ReportSchedule struct {
+	ID         int64  `json:"id"`
+	UserID     int64  `json:"-"`
+	Name       string `json:"name"`
+	Template   string `json:"template"`
+	ProjectKey string `json:"project_key"`
+	JQL        string `json:"jql"`
+	Format     string `json:"format"`
+	Delivery   string `json:"delivery"`
+	WeeklySchedule
+	Enabled   bool       `json:"enabled"`
+	NextRunAt time.Time  `json:"next_run_at"`
+	LastRunAt *time.Time `json:"last_run_at,omitempty"`
+	LastError *string    `json:"last_error,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// Validate checks the schedule's definition, filling in the default format
+// and delivery.
+func (r *ReportSchedule) Validate() error {
+	r.Name = strings.TrimSpace(r.Name)
+	r.ProjectKey = strings.ToUpper(strings.TrimSpace(r.ProjectKey))
+	r.JQL = strings.TrimSpace(r.JQL)
+	if r.Name == "" {
+		return errors.New("name is required")
+	}
+	if !ValidReportTemplate(r.Template) {
+		return errors.New("template must be sprint_summary, weekly_triage or stale_issues")
+	}
+	if !projectKeyPattern.MatchString(r.ProjectKey) {
+		return errors.New("project_key must be a Jira project key such as OPS")
+	}
+	if strings.Contains(strings.ToLower(r.JQL), "order by") {
+		return errors.New("jql must not contain ORDER BY; the template orders the issues")
+	}
+	if r.Format == "" {
+		r.Format = ReportFormatMarkdown
+	}
+	if r.Format != ReportFormatMarkdown && r.Format != ReportFormatHTML {
+		return errors.New("format must be markdown or html")
+	}
+	if r.Delivery == "" {
+		r.Delivery = ReportDeliveryEmail
+	}
+	if r.Delivery != ReportDeliveryEmail && r.Delivery != ReportDeliveryArtifact {
+		return errors.New("delivery must be email or artifact")
+	}
+	return r.WeeklySchedule.Validate()
+}
+
+// ReportArtifact is a rendered report kept for download. Content is only
+// loaded when the artifact is downloaded.
+type ReportArtifact struct {
+	ID         int64     `json:"id"`
+	ScheduleID *int64    `json:"schedule_id,omitempty"`
+	Template   string    `json:"template"`
+	Format     string    `json:"format"`
+	Title      string    `json:"title"`
+	Size       int       `json:"size"`
+	Content    string    `json:"-"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// ReportRunTarget is a report schedule about to run together with the
+// owner's MCP secret, which resolves their Jira credentials, and the email
+// reports are mailed to.
+type ReportRunTarget struct {
+	Schedule  ReportSchedule
+	MCPSecret string
+	Email     string
+}
@@ -0,0 +1,66 @@
+package models
+
+import "slices"
+
+// Account roles, from least to most privileged. Operators listed in
+// ADMIN_EMAILS count as admins whatever their stored role.
+const (
+	RoleUser    = "user"
+	RoleSupport = "support"
+	RoleAdmin   = "admin"
+)
+
+// Permission is an operator capability granted by a role.
+type Permission string
+
+// Permissions checked by the admin endpoints.
+const (
+	PermissionViewAllMetrics Permission = "metrics:read_all"
+	PermissionViewJobs       Permission = "jobs:read"
+	PermissionManageJobs     Permission = "jobs:manage"
+	PermissionManagePlans    Permission = "plans:manage"
+	PermissionManageRoles    Permission = "roles:manage"
+	PermissionManageAPIKeys  Permission = "api_keys:manage"
+	PermissionAdminDashboard Permission = "admin:dashboard"
+	PermissionOperateBackend Permission = "admin:operate"
+)
+
+// RolePermissions lists what each role may do. Support staff can look at the
+// whole deployment but not change it.
+var RolePermissions = map[string][]Permission{
+	RoleUser: nil,
+	RoleSupport: {
+		PermissionViewAllMetrics,
+		PermissionViewJobs,
+		PermissionAdminDashboard,
+	},
+	RoleAdmin: {
+		PermissionViewAllMetrics,
+		PermissionViewJobs,
+		PermissionManageJobs,
+		PermissionManagePlans,
+		PermissionManageRoles,
+		PermissionManageAPIKeys,
+		PermissionAdminDashboard,
+		PermissionOperateBackend,
+	},
+}
+
+// ValidRole reports whether role is one of the account roles.
+func ValidRole(role string) bool {
+	_, ok := RolePermissions[role]
+	return ok
+}
+
+// RoleCan reports whether role grants perm. Unknown roles grant nothing.
+func RoleCan(role string, perm Permission) bool {
+	return slices.Contains(RolePermissions[role], perm)
+}
+
+// UserAccess is what permission checks need to know about a user.
+type UserAccess struct {
+	UserID        int64  `json:"user_id"`
+	Email         string `json:"email"`
+	Role          string `json:"role"`
+	EmailVerified bool   `json:"email_verified"`
+}
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// Tenant shard placement states.
+const (
+	TenantShardActive = "active"
+	// TenantShardMoving means new request logs already go to the shard while
+	// a tenant_shard_move job copies the existing ones over.
+	TenantShardMoving = "moving"
+)
+
+// TenantShard is where a tenant's request logs are kept. Shard is "" for
+// the primary database; DataRegion, when set, overrides the shard because
+// the tenant's organization is resident in that region.
+type TenantShard struct {
+	UserID     int64      `json:"user_id"`
+	Shard      string     `json:"shard"`
+	Status     string     `json:"status"`
+	DataRegion string     `json:"data_region,omitempty"`
+	UpdatedAt  *time.Time `json:"updated_at,omitempty"`
+}
+
+// ShardUsage summarizes how much of the request log one database holds.
+// Shard is "" for the primary database.
+type ShardUsage struct {
+	Shard    string `json:"shard"`
+	Tenants  int64  `json:"tenants"`
+	Requests int64  `json:"requests"`
+}
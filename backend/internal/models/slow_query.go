@@ -0,0 +1,21 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// SlowQuery aggregates the executions of one statement that ran longer than
+// the slow query threshold. Plan is the statement's most recent EXPLAIN
+// (FORMAT JSON) output, when one could be captured.
+type SlowQuery struct {
+	Fingerprint    string          `json:"fingerprint"`
+	Query          string          `json:"query"`
+	Calls          int64           `json:"calls"`
+	TotalMs        float64         `json:"total_ms"`
+	MaxMs          float64         `json:"max_ms"`
+	Plan           json.RawMessage `json:"plan,omitempty"`
+	PlanCapturedAt *time.Time      `json:"plan_captured_at,omitempty"`
+	FirstSeenAt    time.Time       `json:"first_seen_at"`
+	LastSeenAt     time.Time       `json:"last_seen_at"`
+}
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// ToolApprovalDefault is the tool name under which a tenant's default
+// approval setting for destructive tools is stored.
+const ToolApprovalDefault = "*"
+
+// ToolApprovalTTL is how long a held tool call waits for a decision, and
+// then for the worker to execute it, before it expires.
+const ToolApprovalTTL = 24 * time.Hour
+
+// Tool approval states. A call is held as pending until the tenant approves
+// or rejects it; approved calls become executed once the worker runs them.
+const (
+	ToolApprovalPending  = "pending"
+	ToolApprovalApproved = "approved"
+	ToolApprovalRejected = "rejected"
+	ToolApprovalExecuted = "executed"
+	ToolApprovalExpired  = "expired"
+)
+
+// ToolApprovalPolicy is a single stored approval setting.
+type ToolApprovalPolicy struct {
+	Tool            string    `json:"tool"`
+	RequireApproval bool      `json:"require_approval"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// ToolApprovalPolicies is the resolved view consumed by the MCP worker:
+// whether destructive tools need approval by default, plus per-tool
+// overrides, which may also gate tools that are not destructive.
+type ToolApprovalPolicies struct {
+	Default bool            `json:"default"`
+	Tools   map[string]bool `json:"tools"`
+}
+
+// ResolveToolApprovalPolicies folds stored rows into the worker view. The
+// gate is off unless the tenant turned it on.
+func ResolveToolApprovalPolicies(rows []ToolApprovalPolicy) ToolApprovalPolicies {
+	policies := ToolApprovalPolicies{Tools: map[string]bool{}}
+	for _, row := range rows {
+		if row.Tool == ToolApprovalDefault {
+			policies.Default = row.RequireApproval
+			continue
+		}
+		policies.Tools[row.Tool] = row.RequireApproval
+	}
+	return policies
+}
+
+// ToolApproval is a tool call held for the tenant's approval. Token is the
+// handle the MCP worker and client use; the dashboard addresses calls by ID.
+type ToolApproval struct {
+	ID         int64           `json:"id"`
+	Token      string          `json:"token,omitempty"`
+	Tool       string          `json:"tool"`
+	Arguments  json.RawMessage `json:"arguments"`
+	Status     string          `json:"status"`
+	CreatedAt  time.Time       `json:"created_at"`
+	ExpiresAt  time.Time       `json:"expires_at"`
+	DecidedAt  *time.Time      `json:"decided_at,omitempty"`
+	ExecutedAt *time.Time      `json:"executed_at,omitempty"`
+}
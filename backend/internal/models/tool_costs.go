@@ -0,0 +1,82 @@
+package models
+
+import "time"
+
+// DefaultToolCostUnits is charged for tools without a configured weight.
+const DefaultToolCostUnits = 1
+
+// Tool categories group tools for the per-category daily quotas.
+const (
+	ToolCategoryRead   = "read"
+	ToolCategorySearch = "search"
+	ToolCategoryWrite  = "write"
+)
+
+// DefaultToolCategory is the category of tools without a configured one.
+const DefaultToolCategory = ToolCategoryRead
+
+// ValidToolCategory reports whether category is a known tool category.
+func ValidToolCategory(category string) bool {
+	switch category {
+	case ToolCategoryRead, ToolCategorySearch, ToolCategoryWrite:
+		return true
+	}
+	return false
+}
+
+// ToolCostWeight is the number of cost units charged per call of a tool.
+type ToolCostWeight struct {
+	Tool      string    `json:"tool"`
+	CostUnits int       `json:"cost_units"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ToolInvocation is one entry in the MCP tool invocation log.
+type ToolInvocation struct {
+	ID         int64     `json:"id"`
+	UserID     int64     `json:"user_id"`
+	Tool       string    `json:"tool"`
+	Category   string    `json:"category"`
+	CostUnits  int       `json:"cost_units"`
+	IsError    bool      `json:"is_error"`
+	DurationMs *int      `json:"duration_ms,omitempty"`
+	OrgID      *int64    `json:"org_id,omitempty"` // set when made with an organization's shared Jira account
+	ProjectKey *string   `json:"project_key,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// ToolCost aggregates a user's invocations of one tool over a period.
+type ToolCost struct {
+	Tool        string `json:"tool"`
+	Invocations int    `json:"invocations"`
+	Errors      int    `json:"errors"`
+	CostUnits   int64  `json:"cost_units"`
+}
+
+// ToolQuotaDay returns the UTC day holding now, over which the tool category
+// quotas are counted, and when the next day starts.
+func ToolQuotaDay(now time.Time) (start, reset time.Time) {
+	now = now.UTC()
+	start = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	return start, start.AddDate(0, 0, 1)
+}
+
+// ToolCategoryUsage is a user's successful tool calls of one category today
+// against their plan's daily limit for it. DailyLimit and Remaining are nil
+// when the category is unlimited.
+type ToolCategoryUsage struct {
+	Category   string `json:"category"`
+	Used       int64  `json:"used"`
+	DailyLimit *int   `json:"daily_limit"`
+	Remaining  *int64 `json:"remaining"`
+	Exceeded   bool   `json:"exceeded"`
+}
+
+// ToolQuotas is the caller's usage of each tool category that is limited
+// or was used today.
+type ToolQuotas struct {
+	PlanSlug   string              `json:"plan_slug"`
+	DayStart   time.Time           `json:"day_start"`
+	ResetsAt   time.Time           `json:"resets_at"`
+	Categories []ToolCategoryUsage `json:"categories"`
+}
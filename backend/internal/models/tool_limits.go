@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+// Tool response size bounds, in bytes of serialized tool result.
+const (
+	// ToolResponseLimitDefault is the tool name under which a tenant's
+	// default limit is stored.
+	ToolResponseLimitDefault = "*"
+
+	DefaultToolResponseBytes = 50_000
+	MinToolResponseBytes     = 1_024
+	MaxToolResponseBytes     = 1_048_576
+)
+
+// ToolResponseLimit is a single stored limit row.
+type ToolResponseLimit struct {
+	Tool      string    `json:"tool"`
+	MaxBytes  int       `json:"max_bytes"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ToolResponseLimits is the resolved view consumed by the MCP worker: the
+// tenant default plus per-tool overrides.
+type ToolResponseLimits struct {
+	DefaultMaxBytes int            `json:"default_max_bytes"`
+	Tools           map[string]int `json:"tools"`
+}
+
+// ResolveToolResponseLimits folds stored rows into the worker view, filling
+// in the system default when the tenant has not set one.
+func ResolveToolResponseLimits(rows []ToolResponseLimit) ToolResponseLimits {
+	limits := ToolResponseLimits{
+		DefaultMaxBytes: DefaultToolResponseBytes,
+		Tools:           map[string]int{},
+	}
+	for _, row := range rows {
+		if row.Tool == ToolResponseLimitDefault {
+			limits.DefaultMaxBytes = row.MaxBytes
+			continue
+		}
+		limits.Tools[row.Tool] = row.MaxBytes
+	}
+	return limits
+}
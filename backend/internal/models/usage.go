@@ -0,0 +1,99 @@
+package models
+
+import "time"
+
+// DailyUsage is one day of a user's request rollup.
+type DailyUsage struct {
+	Day             time.Time `json:"day"`
+	RequestCount    int       `json:"request_count"`
+	ErrorCount      int       `json:"error_count"`
+	TotalResponseMs int64     `json:"total_response_ms"`
+	CostUnits       int64     `json:"cost_units"`
+}
+
+// Overage risk levels reported by a UsageForecast.
+const (
+	OverageRiskNone   = "none"
+	OverageRiskLow    = "low"
+	OverageRiskMedium = "medium"
+	OverageRiskHigh   = "high"
+)
+
+// UsageForecast projects a user's request volume and tool cost units to the
+// end of the current calendar month.
+type UsageForecast struct {
+	PeriodStart      time.Time  `json:"period_start"`
+	PeriodEnd        time.Time  `json:"period_end"`
+	DaysElapsed      int        `json:"days_elapsed"`
+	DaysRemaining    int        `json:"days_remaining"`
+	MonthToDate      int        `json:"month_to_date"`
+	DailyAverage     float64    `json:"daily_average"`
+	ProjectedRunRate int        `json:"projected_run_rate"`
+	ProjectedTrend   int        `json:"projected_trend"`
+	Projected        int        `json:"projected"`
+	Model            string     `json:"model"`
+	PlanSlug         string     `json:"plan_slug"`
+	Quota            *int       `json:"quota,omitempty"`
+	QuotaUsedPercent *float64   `json:"quota_used_percent,omitempty"`
+	ProjectedPercent *float64   `json:"projected_percent,omitempty"`
+	QuotaExhaustedOn *time.Time `json:"quota_exhausted_on,omitempty"`
+	// Cost units weight each MCP tool call by how expensive the tool is and
+	// are measured against the plan's separate cost unit allowance.
+	CostUnitsToDate           int64    `json:"cost_units_to_date"`
+	CostUnitsProjected        int64    `json:"cost_units_projected"`
+	CostUnitQuota             *int     `json:"cost_unit_quota,omitempty"`
+	CostUnitsUsedPercent      *float64 `json:"cost_units_used_percent,omitempty"`
+	CostUnitsProjectedPercent *float64 `json:"cost_units_projected_percent,omitempty"`
+	OverageRisk               string   `json:"overage_risk"`
+	UpgradeSuggested          bool     `json:"upgrade_suggested"`
+}
+
+// UsageDigest summarizes one UTC week (Monday to Sunday) of a user's usage
+// for the weekly digest email. Quota consumption is month to date as of the
+// end of the week.
+type UsageDigest struct {
+	UserID      int64          `json:"user_id"`
+	Email       string         `json:"email"`
+	WeekStart   time.Time      `json:"week_start"`
+	WeekEnd     time.Time      `json:"week_end"`
+	Requests    int            `json:"requests"`
+	Errors      int            `json:"errors"`
+	CostUnits   int64          `json:"cost_units"`
+	ToolCalls   int            `json:"tool_calls"`
+	ToolErrors  int            `json:"tool_errors"`
+	TopTools    []ToolCost     `json:"top_tools"`
+	TopProjects []ProjectUsage `json:"top_projects"`
+	PlanName    string         `json:"plan_name"`
+
+	RequestsMonthToDate  int   `json:"requests_month_to_date"`
+	RequestQuota         *int  `json:"request_quota,omitempty"`
+	CostUnitsMonthToDate int64 `json:"cost_units_month_to_date"`
+	CostUnitQuota        *int  `json:"cost_unit_quota,omitempty"`
+}
+
+// ProjectUsage counts the tool calls that touched one Jira project.
+type ProjectUsage struct {
+	ProjectKey string `json:"project_key"`
+	ToolCalls  int    `json:"tool_calls"`
+	Errors     int    `json:"errors"`
+}
+
+// QuotaPeriod returns the monthly quota period containing now: the start of
+// its UTC calendar month and the start of the next, when the quota resets.
+func QuotaPeriod(now time.Time) (start, reset time.Time) {
+	now = now.UTC()
+	start = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return start, start.AddDate(0, 1, 0)
+}
+
+// RequestQuota is a user's monthly request quota and their use of it in the
+// current period. Quota and Remaining are unset when the plan is unlimited.
+type RequestQuota struct {
+	PlanSlug    string    `json:"plan_slug"`
+	PeriodStart time.Time `json:"period_start"`
+	ResetsAt    time.Time `json:"resets_at"`
+	Used        int64     `json:"used"`
+	Quota       *int      `json:"quota,omitempty"`
+	Remaining   *int64    `json:"remaining,omitempty"`
+	Exceeded    bool      `json:"exceeded"`
+}
@@ -4,13 +4,14 @@ import "time"
 
 // User represents a sanitized view of a user record exposed by the backend API.
 type User struct {
-	ID        int64     `json:"id"`
-	Login     string    `json:"login"`
-	Email     *string   `json:"email,omitempty"`
-	Name      *string   `json:"name,omitempty"`
-	AvatarURL *string   `json:"avatar_url,omitempty"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID               int64     `json:"id"`
+	Login            string    `json:"login"`
+	Email            *string   `json:"email,omitempty"`
+	Name             *string   `json:"name,omitempty"`
+	AvatarURL        *string   `json:"avatar_url,omitempty"`
+	StripeCustomerID *string   `json:"-"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
 }
 
 // PublicUser represents the external API view of a user with string ID
@@ -21,6 +22,34 @@ type PublicUser struct {
 	Image *string `json:"image,omitempty"`
 }
 
+// User list sort orders. A leading "-" sorts descending; ties are broken by
+// user ID in the same direction.
+const (
+	UserSortCreatedDesc = "-created_at"
+	UserSortCreatedAsc  = "created_at"
+	UserSortEmailAsc    = "email"
+	UserSortEmailDesc   = "-email"
+	UserSortNameAsc     = "name"
+	UserSortNameDesc    = "-name"
+)
+
+// UserListOptions selects a page of users. Cursor is the NextCursor of the
+// previous page, or empty for the first one, and only valid with the sort
+// it was issued for.
+type UserListOptions struct {
+	Limit  int
+	Sort   string
+	Cursor string
+}
+
+// UserPage is one page of users together with the total number of users.
+// NextCursor is empty on the last page.
+type UserPage struct {
+	Users      []PublicUser `json:"users"`
+	NextCursor string       `json:"next_cursor,omitempty"`
+	Total      int          `json:"total"`
+}
+
 // GitHubAuthUser captures the data produced during a GitHub OAuth login that we
 // want to persist in our own database for multi-tenant management.
 type GitHubAuthUser struct {
@@ -31,6 +60,18 @@ type GitHubAuthUser struct {
 	AvatarURL   *string `json:"avatar_url,omitempty"`
 	AccessToken string  `json:"access_token"`
 	Scope       *string `json:"scope,omitempty"`
+	// EmailVerified reports whether GitHub lists Email as verified. Hidden
+	// or unverified addresses must be confirmed by email before the account
+	// is fully activated.
+	EmailVerified bool `json:"email_verified"`
+
+	// ClientIP and UserAgent describe the browser that completed the login,
+	// forwarded by the frontend Worker for the login history.
+	ClientIP  string `json:"client_ip,omitempty"`
+	UserAgent string `json:"user_agent,omitempty"`
+	// StartSession asks for a server-side session record whose id the
+	// Worker puts in the new session cookie.
+	StartSession bool `json:"start_session,omitempty"`
 }
 
 // GoogleAuthUser captures the data produced during a Google OAuth login that we
@@ -41,6 +82,10 @@ type GoogleAuthUser struct {
 	Email       *string `json:"email,omitempty"`
 	AvatarURL   *string `json:"avatar_url,omitempty"`
 	AccessToken string  `json:"access_token"`
+
+	ClientIP     string `json:"client_ip,omitempty"`
+	UserAgent    string `json:"user_agent,omitempty"`
+	StartSession bool   `json:"start_session,omitempty"`
 }
 
 // JiraUserSettings represents a non-sensitive view of Jira settings associated
@@ -50,6 +95,58 @@ type JiraUserSettings struct {
 	JiraEmail   string  `json:"jira_email"`
 	JiraCloudID *string `json:"jira_cloud_id,omitempty"`
 	IsDefault   bool    `json:"is_default"`
+	AuthMethod  string  `json:"auth_method"`
+	Revision    int64   `json:"revision"`
+}
+
+// Ways a Jira site's credentials authenticate.
+const (
+	JiraAuthAPIToken = "api_token"
+	JiraAuthOAuth    = "oauth"
+)
+
+// DeletedJiraUserSettings is a deleted Jira site that can still be restored
+// until PurgeAfter.
+type DeletedJiraUserSettings struct {
+	JiraBaseURL string    `json:"jira_base_url"`
+	JiraEmail   string    `json:"jira_email"`
+	DeletedAt   time.Time `json:"deleted_at"`
+	PurgeAfter  time.Time `json:"purge_after"`
+}
+
+// DeletedUser is a deleted account that can still be restored until
+// PurgeAfter.
+type DeletedUser struct {
+	ID         int64     `json:"id"`
+	Email      *string   `json:"email,omitempty"`
+	Name       *string   `json:"name,omitempty"`
+	DeletedAt  time.Time `json:"deleted_at"`
+	PurgeAfter time.Time `json:"purge_after"`
+}
+
+// AccountDeletion summarises deleting an account. A deleted account keeps
+// its data until PurgeAfter, and Retained counts the rows of each table
+// that will be removed then; a purge reports what it removed in Removed.
+// Subscription is the Stripe subscription cancelled with the account, if
+// any.
+type AccountDeletion struct {
+	UserID       int64                     `json:"user_id"`
+	Email        string                    `json:"email"`
+	Retained     map[string]int64          `json:"retained,omitempty"`
+	PurgeAfter   *time.Time                `json:"purge_after,omitempty"`
+	Removed      map[string]int64          `json:"removed,omitempty"`
+	Subscription *SubscriptionCancellation `json:"subscription,omitempty"`
+}
+
+// SubscriptionCancellation is a Stripe subscription cancelled immediately
+// and what was refunded of its unused period. RefundError is set when the
+// subscription was cancelled but the refund failed.
+type SubscriptionCancellation struct {
+	StripeSubscriptionID string `json:"stripe_subscription_id"`
+	RefundID             string `json:"refund_id,omitempty"`
+	RefundCents          int64  `json:"refund_cents"`
+	Currency             string `json:"currency,omitempty"`
+	RefundError          string `json:"refund_error,omitempty"`
 }
 
 // JiraUserSettingsWithSecret is the internal representation of Jira settings
@@ -57,13 +154,49 @@ type JiraUserSettings struct {
 // returned to trusted server-side callers (e.g. the MCP Worker) and never to
 // the public frontend.
 type JiraUserSettingsWithSecret struct {
-	JiraBaseURL       string  `json:"jira_base_url"`
-	JiraEmail         string  `json:"jira_email"`
-	JiraCloudID       *string `json:"jira_cloud_id,omitempty"`
-	IsDefault         bool    `json:"is_default"`
-	AtlassianAPIToken string  `json:"atlassian_api_key"`
+	JiraBaseURL string  `json:"jira_base_url"`
+	JiraEmail   string  `json:"jira_email"`
+	JiraCloudID *string `json:"jira_cloud_id,omitempty"`
+	IsDefault   bool    `json:"is_default"`
+	// AuthMethod says how AtlassianAPIToken is used: as an API token with
+	// JiraEmail (JiraAuthAPIToken), or as an OAuth access token for the
+	// site JiraCloudID (JiraAuthOAuth).
+	AuthMethod        string `json:"auth_method"`
+	AtlassianAPIToken string `json:"atlassian_api_key"`
+	// ServiceManagement is set when the site was found to have Jira Service
+	// Management when credentials for it were last validated.
+	ServiceManagement bool `json:"service_management"`
+	// OrgID is set when the credential is the organization's shared Jira
+	// account rather than the tenant's own settings.
+	OrgID *int64 `json:"org_id,omitempty"`
+	// UserID is the tenant whose settings these are; zero for an
+	// organization's shared account.
+	UserID int64 `json:"-"`
+	// IssueDefaults are the tenant's defaults for new issues, nil when they
+	// have none.
+	IssueDefaults *JiraIssueDefaults `json:"-"`
+}
+
+// JiraSettingsDocument is the bulk import/export format for all of a
+// tenant's Jira sites. Exports omit API tokens; imports require them.
+type JiraSettingsDocument struct {
+	Version    int                `json:"version"`
+	ExportedAt *time.Time         `json:"exported_at,omitempty"`
+	Sites      []JiraSiteSettings `json:"sites"`
 }
 
+// JiraSiteSettings is a single site entry in a JiraSettingsDocument.
+type JiraSiteSettings struct {
+	JiraBaseURL     string  `json:"jira_base_url"`
+	JiraEmail       string  `json:"jira_email"`
+	JiraCloudID     *string `json:"jira_cloud_id,omitempty"`
+	IsDefault       bool    `json:"is_default"`
+	AtlassianAPIKey string  `json:"atlassian_api_key,omitempty"`
+}
+
+// JiraSettingsDocumentVersion is the current bulk settings format version.
+const JiraSettingsDocumentVersion = 1
+
 // Request represents an API request made by a user for tracking usage metrics
 type Request struct {
 	ID                string  `json:"id"`
@@ -76,6 +209,10 @@ type Request struct {
 	ResponseSizeBytes *int    `json:"response_size_bytes,omitempty"`
 	ErrorMessage      *string `json:"error_message,omitempty"`
 	CreatedAt         string  `json:"created_at"`
+
+	// SampleWeight is how many requests this row stands for when successful
+	// requests were sampled under load; 1 otherwise.
+	SampleWeight int `json:"sample_weight"`
 }
 
 // RequestMetrics represents aggregated usage metrics for a user
@@ -123,3 +260,104 @@ type ConnectedAccount struct {
 	AvatarURL         *string   `json:"avatar_url,omitempty"`
 	ConnectedAt       time.Time `json:"connected_at"`
 }
+
+// AdminUser is a users row as operators see it. The MCP secret itself is
+// never included.
+type AdminUser struct {
+	ID               int64      `json:"id"`
+	Login            string     `json:"login"`
+	Name             *string    `json:"name,omitempty"`
+	Email            *string    `json:"email,omitempty"`
+	AvatarURL        *string    `json:"avatar_url,omitempty"`
+	StripeCustomerID *string    `json:"stripe_customer_id,omitempty"`
+	HasMCPSecret     bool       `json:"has_mcp_secret"`
+	EmailVerifiedAt  *time.Time `json:"email_verified_at,omitempty"`
+	SuspendedAt      *time.Time `json:"suspended_at,omitempty"`
+	SuspendedReason  *string    `json:"suspended_reason,omitempty"`
+	DebugModeUntil   *time.Time `json:"debug_mode_until,omitempty"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+}
+
+// TenantState gathers what support needs to look into one user's account:
+// the user row, sign-in providers, Jira sites without their tokens, the
+// latest subscription and the most recent jobs and requests.
+type TenantState struct {
+	User           AdminUser          `json:"user"`
+	OAuthAccounts  []ConnectedAccount `json:"oauth_accounts"`
+	JiraSettings   []JiraUserSettings `json:"jira_settings"`
+	Subscription   *Subscription      `json:"subscription"`
+	RecentJobs     []*Job             `json:"recent_jobs"`
+	RecentRequests []Request          `json:"recent_requests"`
+}
+
+// Dashboard is everything the web app shows when it loads, gathered in one
+// response: the caller's profile, sign-in providers, the health of their
+// Jira sites, their plan and quota use, and their recent jobs and failed
+// requests.
+type Dashboard struct {
+	Profile           User               `json:"profile"`
+	ConnectedAccounts []ConnectedAccount `json:"connected_accounts"`
+	Jira              JiraHealth         `json:"jira"`
+	Plan              *MembershipPlan    `json:"plan"`
+	Quota             RequestQuota       `json:"quota"`
+	RecentJobs        []*Job             `json:"recent_jobs"`
+	RecentErrors      []Request          `json:"recent_errors"`
+}
+
+// Health of a user's Jira sites, overall and per site.
+const (
+	JiraHealthOK            = "ok"
+	JiraHealthNotConfigured = "not_configured"
+	JiraHealthAttention     = "attention"
+	JiraHealthDisconnected  = "disconnected"
+)
+
+// JiraHealth reports whether a user's Jira sites can be used. Status is
+// JiraHealthAttention when any site is not ok.
+type JiraHealth struct {
+	Status string           `json:"status"`
+	Sites  []JiraSiteHealth `json:"sites"`
+}
+
+// JiraSiteHealth is a Jira site without its token and whether it can be
+// used; Problem explains a status other than ok.
+type JiraSiteHealth struct {
+	JiraUserSettings
+	Status  string `json:"status"`
+	Problem string `json:"problem,omitempty"`
+}
+
+// UserMerge summarises folding a duplicate user into another account. Moved
+// counts the rows reassigned per table; Dropped counts the duplicate's rows
+// discarded because the target already had an equivalent one.
+type UserMerge struct {
+	SourceID    int64            `json:"source_id"`
+	SourceEmail string           `json:"source_email"`
+	TargetID    int64            `json:"target_id"`
+	TargetEmail string           `json:"target_email"`
+	Moved       map[string]int64 `json:"moved"`
+	Dropped     map[string]int64 `json:"dropped"`
+}
+
+// LoginEvent is one successful login to a user's account.
+type LoginEvent struct {
+	ID        int64     `json:"id"`
+	Provider  string    `json:"provider"`
+	IPAddress *string   `json:"ip_address,omitempty"`
+	UserAgent *string   `json:"user_agent,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// UserSession is a browser session signed in to a user's account. Current
+// marks the session making the request.
+type UserSession struct {
+	ID         string    `json:"id"`
+	Provider   string    `json:"provider"`
+	IPAddress  *string   `json:"ip_address,omitempty"`
+	UserAgent  *string   `json:"user_agent,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current"`
+}
@@ -0,0 +1,87 @@
+// Package realtime provides an in-process publish/subscribe hub that fans
+// tenant-scoped events out to connected WebSocket clients.
+package realtime
+
+import (
+	"sync"
+	"time"
+)
+
+// subscriberBuffer is the number of events queued per subscriber before new
+// events are dropped for that subscriber.
+const subscriberBuffer = 64
+
+// Event is a single message delivered to a tenant's subscribers.
+type Event struct {
+	Type string      `json:"type"`
+	Data interface{} `json:"data,omitempty"`
+	At   time.Time   `json:"at"`
+}
+
+// Hub routes events to subscribers keyed by user ID. The zero value is not
+// usable; construct with NewHub. A nil *Hub silently discards publishes so
+// callers can treat real-time delivery as optional.
+type Hub struct {
+	mu          sync.RWMutex
+	subscribers map[int64]map[chan Event]struct{}
+}
+
+// NewHub creates an empty hub.
+func NewHub() *Hub {
+	return &Hub{subscribers: make(map[int64]map[chan Event]struct{})}
+}
+
+// Subscribe registers a new subscriber for the given user and returns the
+// event channel together with a function that unsubscribes and closes it.
+func (h *Hub) Subscribe(userID int64) (<-chan Event, func()) {
+	ch := make(chan Event, subscriberBuffer)
+
+	h.mu.Lock()
+	subs, ok := h.subscribers[userID]
+	if !ok {
+		subs = make(map[chan Event]struct{})
+		h.subscribers[userID] = subs
+	}
+	subs[ch] = struct{}{}
+	h.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			h.mu.Lock()
+			delete(h.subscribers[userID], ch)
+			if len(h.subscribers[userID]) == 0 {
+				delete(h.subscribers, userID)
+			}
+			h.mu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+// Publish delivers an event to every subscriber of the given user. Slow
+// subscribers whose buffers are full miss the event rather than blocking the
+// publisher.
+func (h *Hub) Publish(userID int64, eventType string, data interface{}) {
+	if h == nil || userID <= 0 {
+		return
+	}
+
+	ev := Event{Type: eventType, Data: data, At: time.Now().UTC()}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for ch := range h.subscribers[userID] {
+		select {
+		case ch <- ev:
+		default:
+		}
+	}
+}
+
+// SubscriberCount returns the number of active subscribers for a user.
+func (h *Hub) SubscriberCount(userID int64) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.subscribers[userID])
+}
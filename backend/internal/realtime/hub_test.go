@@ -0,0 +1,54 @@
+package realtime
+
+import "testing"
+
+func TestHubPublishScopedToUser(t *testing.T) {
+	h := NewHub()
+
+	events, unsubscribe := h.Subscribe(1)
+	other, unsubscribeOther := h.Subscribe(2)
+	defer unsubscribeOther()
+
+	h.Publish(1, "job.completed", map[string]int64{"job_id": 9})
+
+	select {
+	case ev := <-events:
+		if ev.Type != "job.completed" {
+			t.Fatalf("unexpected event type %q", ev.Type)
+		}
+	default:
+		t.Fatal("expected event for subscribed user")
+	}
+
+	select {
+	case ev := <-other:
+		t.Fatalf("unexpected event for other user: %+v", ev)
+	default:
+	}
+
+	unsubscribe()
+	unsubscribe()
+	if n := h.SubscriberCount(1); n != 0 {
+		t.Fatalf("expected no subscribers after unsubscribe, got %d", n)
+	}
+	if _, ok := <-events; ok {
+		t.Fatal("expected channel to be closed after unsubscribe")
+	}
+}
+
+func TestHubPublishDropsWhenBufferFull(t *testing.T) {
+	h := NewHub()
+	events, unsubscribe := h.Subscribe(1)
+	defer unsubscribe()
+
+	for i := 0; i < subscriberBuffer+10; i++ {
+		h.Publish(1, "tick", i)
+	}
+
+	if len(events) != subscriberBuffer {
+		t.Fatalf("expected %d buffered events, got %d", subscriberBuffer, len(events))
+	}
+
+	var nilHub *Hub
+	nilHub.Publish(1, "ignored", nil)
+}
@@ -0,0 +1,126 @@
+package realtime
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Streaming connection kinds counted by a StreamLimiter.
+const (
+	StreamWebSocket = "websocket"
+	StreamMCP       = "mcp_sse"
+)
+
+// StreamLimiter counts each tenant's open streaming connections, WebSockets
+// and MCP event streams alike, and refuses new ones past the tenant's limit
+// so that one tenant cannot hold all of the server's file descriptors.
+type StreamLimiter struct {
+	mu       sync.Mutex
+	open     map[int64]map[string]int
+	rejected map[string]int64
+}
+
+// NewStreamLimiter creates a limiter with no open connections.
+func NewStreamLimiter() *StreamLimiter {
+	return &StreamLimiter{open: map[int64]map[string]int{}, rejected: map[string]int64{}}
+}
+
+// Acquire counts a new connection of kind for the user unless they already
+// hold limit connections; limit <= 0 means unlimited. Call release once the
+// connection closes.
+func (l *StreamLimiter) Acquire(userID int64, kind string, limit int) (release func(), ok bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if limit > 0 && l.countLocked(userID) >= limit {
+		l.rejected[kind]++
+		return nil, false
+	}
+	if l.open[userID] == nil {
+		l.open[userID] = map[string]int{}
+	}
+	l.open[userID][kind]++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			if l.open[userID][kind]--; l.open[userID][kind] == 0 {
+				delete(l.open[userID], kind)
+			}
+			if len(l.open[userID]) == 0 {
+				delete(l.open, userID)
+			}
+		})
+	}, true
+}
+
+// Count returns the streaming connections the user holds open.
+func (l *StreamLimiter) Count(userID int64) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.countLocked(userID)
+}
+
+func (l *StreamLimiter) countLocked(userID int64) int {
+	n := 0
+	for _, count := range l.open[userID] {
+		n += count
+	}
+	return n
+}
+
+// WritePrometheus writes the connection counts in the Prometheus text
+// exposition format. Tenants are not labelled; the busiest tenant's count
+// shows how close anyone is to their limit.
+func (l *StreamLimiter) WritePrometheus(w io.Writer) error {
+	l.mu.Lock()
+	byKind := map[string]int{StreamWebSocket: 0, StreamMCP: 0}
+	busiest := 0
+	for userID, kinds := range l.open {
+		for kind, count := range kinds {
+			byKind[kind] += count
+		}
+		busiest = max(busiest, l.countLocked(userID))
+	}
+	tenants := len(l.open)
+	rejected := map[string]int64{StreamWebSocket: 0, StreamMCP: 0}
+	for kind, count := range l.rejected {
+		rejected[kind] = count
+	}
+	l.mu.Unlock()
+
+	var b strings.Builder
+	b.WriteString("# HELP streaming_connections_open Open streaming connections by kind.\n")
+	b.WriteString("# TYPE streaming_connections_open gauge\n")
+	for _, kind := range sortedKinds(byKind) {
+		fmt.Fprintf(&b, "streaming_connections_open{kind=%q} %d\n", kind, byKind[kind])
+	}
+	b.WriteString("# HELP streaming_connections_tenants Tenants holding at least one streaming connection.\n")
+	b.WriteString("# TYPE streaming_connections_tenants gauge\n")
+	fmt.Fprintf(&b, "streaming_connections_tenants %d\n", tenants)
+	b.WriteString("# HELP streaming_connections_tenant_max Streaming connections held by the busiest tenant.\n")
+	b.WriteString("# TYPE streaming_connections_tenant_max gauge\n")
+	fmt.Fprintf(&b, "streaming_connections_tenant_max %d\n", busiest)
+	b.WriteString("# HELP streaming_connections_rejected_total Streaming connections refused because the tenant was at its limit.\n")
+	b.WriteString("# TYPE streaming_connections_rejected_total counter\n")
+	for _, kind := range sortedKinds(rejected) {
+		fmt.Fprintf(&b, "streaming_connections_rejected_total{kind=%q} %d\n", kind, rejected[kind])
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func sortedKinds[V any](m map[string]V) []string {
+	kinds := make([]string, 0, len(m))
+	for kind := range m {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
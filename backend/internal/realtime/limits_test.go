@@ -0,0 +1,47 @@
+package realtime
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStreamLimiterCapsEachTenant(t *testing.T) {
+	l := NewStreamLimiter()
+
+	releaseSocket, ok := l.Acquire(1, StreamWebSocket, 2)
+	if !ok {
+		t.Fatal("expected the first connection to be allowed")
+	}
+	if _, ok := l.Acquire(1, StreamMCP, 2); !ok {
+		t.Fatal("expected the second connection to be allowed")
+	}
+	if _, ok := l.Acquire(1, StreamMCP, 2); ok {
+		t.Fatal("expected the third connection to be refused")
+	}
+	if _, ok := l.Acquire(2, StreamMCP, 2); !ok {
+		t.Fatal("expected another tenant to be unaffected")
+	}
+
+	var b strings.Builder
+	l.WritePrometheus(&b)
+	for _, want := range []string{
+		`streaming_connections_open{kind="mcp_sse"} 2`,
+		`streaming_connections_open{kind="websocket"} 1`,
+		"streaming_connections_tenants 2",
+		"streaming_connections_tenant_max 2",
+		`streaming_connections_rejected_total{kind="mcp_sse"} 1`,
+	} {
+		if !strings.Contains(b.String(), want+"\n") {
+			t.Errorf("expected %q in:\n%s", want, b.String())
+		}
+	}
+
+	releaseSocket()
+	releaseSocket()
+	if got := l.Count(1); got != 1 {
+		t.Fatalf("expected one connection after release, got %d", got)
+	}
+	if _, ok := l.Acquire(1, StreamWebSocket, 2); !ok {
+		t.Fatal("expected a released slot to be reusable")
+	}
+}
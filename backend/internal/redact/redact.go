@@ -0,0 +1,101 @@
+// Package redact strips credentials and other sensitive values from
+// arbitrary JSON payloads before they are persisted for debugging.
+package redact
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Placeholder replaces redacted values.
+const Placeholder = "[REDACTED]"
+
+// maxStringLen caps individual string values so a single large field cannot
+// dominate a stored payload.
+const maxStringLen = 4096
+
+// sensitiveKeyParts are matched case-insensitively against object keys with
+// separators removed, e.g. "api_key", "apiKey" and "X-Api-Key" all match.
+var sensitiveKeyParts = []string{
+	"password",
+	"passwd",
+	"secret",
+	"token",
+	"apikey",
+	"authorization",
+	"cookie",
+	"credential",
+	"privatekey",
+	"signature",
+}
+
+// IsSensitiveKey reports whether values stored under key should be hidden.
+func IsSensitiveKey(key string) bool {
+	normalized := strings.NewReplacer("_", "", "-", "", " ", "").Replace(strings.ToLower(key))
+	for _, part := range sensitiveKeyParts {
+		if strings.Contains(normalized, part) {
+			return true
+		}
+	}
+	return false
+}
+
+// Value returns a copy of v (as produced by encoding/json) with sensitive
+// keys masked, bearer credentials hidden, and long strings truncated.
+func Value(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(t))
+		for k, val := range t {
+			if IsSensitiveKey(k) {
+				out[k] = Placeholder
+				continue
+			}
+			out[k] = Value(val)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(t))
+		for i, val := range t {
+			out[i] = Value(val)
+		}
+		return out
+	case string:
+		return String(t)
+	default:
+		return v
+	}
+}
+
+// String hides inline bearer/basic credentials and truncates long values.
+func String(s string) string {
+	lower := strings.ToLower(s)
+	for _, scheme := range []string{"bearer ", "basic "} {
+		if i := strings.Index(lower, scheme); i >= 0 {
+			return s[:i+len(scheme)] + Placeholder
+		}
+	}
+	if len(s) > maxStringLen {
+		return s[:maxStringLen] + "…[truncated]"
+	}
+	return s
+}
+
+// JSON redacts a raw JSON document. Input that is not valid JSON is treated
+// as an opaque string.
+func JSON(raw json.RawMessage) json.RawMessage {
+	if len(raw) == 0 {
+		return json.RawMessage("{}")
+	}
+
+	var v interface{}
+	if err := json.Unmarshal(raw, &v); err != nil {
+		v = string(raw)
+	}
+
+	out, err := json.Marshal(Value(v))
+	if err != nil {
+		return json.RawMessage("{}")
+	}
+	return out
+}
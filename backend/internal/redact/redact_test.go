@@ -0,0 +1,49 @@
+package redact
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestJSONMasksSensitiveKeys(t *testing.T) {
+	raw := json.RawMessage(`{
+		"issueKey": "PROJ-1",
+		"api_key": "abc",
+		"headers": {"Authorization": "Bearer xyz", "X-Api-Key": "k"},
+		"items": [{"accessToken": "t", "summary": "ok"}],
+		"note": "call with Bearer sk_live_123"
+	}`)
+
+	var got map[string]interface{}
+	if err := json.Unmarshal(JSON(raw), &got); err != nil {
+		t.Fatalf("redacted output is not valid JSON: %v", err)
+	}
+
+	if got["issueKey"] != "PROJ-1" {
+		t.Fatalf("expected non-sensitive value to be kept, got %v", got["issueKey"])
+	}
+	if got["api_key"] != Placeholder {
+		t.Fatalf("expected api_key to be redacted, got %v", got["api_key"])
+	}
+	headers := got["headers"].(map[string]interface{})
+	if headers["Authorization"] != Placeholder || headers["X-Api-Key"] != Placeholder {
+		t.Fatalf("expected nested credentials to be redacted, got %v", headers)
+	}
+	item := got["items"].([]interface{})[0].(map[string]interface{})
+	if item["accessToken"] != Placeholder || item["summary"] != "ok" {
+		t.Fatalf("unexpected array element: %v", item)
+	}
+	if note := got["note"].(string); strings.Contains(note, "sk_live_123") {
+		t.Fatalf("expected inline bearer token to be hidden, got %q", note)
+	}
+}
+
+func TestJSONHandlesInvalidInput(t *testing.T) {
+	if got := string(JSON(json.RawMessage(`not json`))); got != `"not json"` {
+		t.Fatalf("expected invalid JSON to be stored as a string, got %s", got)
+	}
+	if got := string(JSON(nil)); got != `{}` {
+		t.Fatalf("expected empty input to become {}, got %s", got)
+	}
+}
@@ -0,0 +1,338 @@
+// Package reports renders the built-in Jira report templates to Markdown
+// or HTML.
+package reports
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"html/template"
+	"strings"
+	"time"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/jira"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+)
+
+// maxIssues caps the issues a report covers.
+const maxIssues = 200
+
+// staleAfterDays is how long an open issue goes without an update before
+// the stale issues report lists it.
+const staleAfterDays = 14
+
+var issueFields = []string{"summary", "status", "assignee", "priority", "created", "updated"}
+
+// Template describes a built-in report.
+type Template struct {
+	Name        string `json:"name"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+
+	// query is the template's JQL for a project, without ordering.
+	query string
+	order string
+	build func(r *Report, issues []Issue, now time.Time)
+}
+
+var templates = []Template{
+	{
+		Name:        models.ReportTemplateSprintSummary,
+		Title:       "Sprint summary",
+		Description: "Issues in the project's open sprints grouped by status, with how many are done.",
+		query:       "sprint in openSprints()",
+		order:       "ORDER BY status, priority DESC",
+		build:       buildSprintSummary,
+	},
+	{
+		Name:        models.ReportTemplateWeeklyTriage,
+		Title:       "Weekly triage",
+		Description: "Issues created in the last 7 days, unassigned ones first.",
+		query:       "created >= -7d",
+		order:       "ORDER BY priority DESC, created DESC",
+		build:       buildWeeklyTriage,
+	},
+	{
+		Name:        models.ReportTemplateStaleIssues,
+		Title:       "Stale issues",
+		Description: fmt.Sprintf("Open issues not updated in %d days, oldest first.", staleAfterDays),
+		query:       fmt.Sprintf("statusCategory != Done AND updated <= -%dd", staleAfterDays),
+		order:       "ORDER BY updated ASC",
+		build:       buildStaleIssues,
+	},
+}
+
+// Templates returns the built-in templates.
+func Templates() []Template {
+	return templates
+}
+
+// Lookup returns the template called name.
+func Lookup(name string) (Template, bool) {
+	for _, t := range templates {
+		if t.Name == name {
+			return t, true
+		}
+	}
+	return Template{}, false
+}
+
+// JQL returns the search the template runs for the schedule.
+func (t Template) JQL(s *models.ReportSchedule) string {
+	clauses := []string{fmt.Sprintf("project = %q", s.ProjectKey), t.query}
+	if s.JQL != "" {
+		clauses = append(clauses, "("+s.JQL+")")
+	}
+	return strings.Join(clauses, " AND ") + " " + t.order
+}
+
+// Report is a rendered template: sections of issues with a summary line.
+type Report struct {
+	Title       string
+	Project     string
+	GeneratedAt time.Time
+	Summary     string
+	Sections    []Section
+	// Truncated is set when more issues matched than the report covers.
+	Truncated bool
+}
+
+// Section is a group of issues in a report.
+type Section struct {
+	Heading string
+	Issues  []Issue
+}
+
+// Issue is an issue as listed in a report.
+type Issue struct {
+	Key      string
+	URL      string
+	Summary  string
+	Status   string
+	Category string
+	Assignee string
+	Priority string
+	Created  time.Time
+	Updated  time.Time
+	// Note is template specific, such as how long an issue has been idle.
+	Note string
+}
+
+// Searcher runs JQL searches; *jira.Client implements it.
+type Searcher interface {
+	Search(ctx context.Context, req jira.SearchRequest) (*jira.SearchResult, error)
+	BaseURL() string
+}
+
+// Generate runs the schedule's template against Jira.
+func Generate(ctx context.Context, jc Searcher, s *models.ReportSchedule, now time.Time) (*Report, error) {
+	t, ok := Lookup(s.Template)
+	if !ok {
+		return nil, fmt.Errorf("reports: unknown template %q", s.Template)
+	}
+
+	req := jira.SearchRequest{JQL: t.JQL(s), Fields: issueFields, MaxResults: 100}
+	var (
+		issues    []Issue
+		truncated bool
+	)
+	for {
+		page, err := jc.Search(ctx, req)
+		if err != nil {
+			return nil, fmt.Errorf("reports: search: %w", err)
+		}
+		for _, issue := range page.Issues {
+			if len(issues) == maxIssues {
+				truncated = true
+				break
+			}
+			issues = append(issues, newIssue(jc.BaseURL(), issue))
+		}
+		if truncated || page.IsLast || page.NextPageToken == "" {
+			break
+		}
+		req.NextPageToken = page.NextPageToken
+	}
+
+	r := &Report{
+		Title:       fmt.Sprintf("%s: %s", t.Title, s.ProjectKey),
+		Project:     s.ProjectKey,
+		GeneratedAt: now.UTC(),
+		Truncated:   truncated,
+	}
+	t.build(r, issues, now)
+	return r, nil
+}
+
+func newIssue(baseURL string, issue jira.Issue) Issue {
+	field := func(name, key string) string {
+		if v, ok := issue.Fields[name].(map[string]any); ok {
+			s, _ := v[key].(string)
+			return s
+		}
+		return ""
+	}
+	timeField := func(name string) time.Time {
+		s, _ := issue.Fields[name].(string)
+		t, _ := time.Parse("2006-01-02T15:04:05.000-0700", s)
+		return t
+	}
+	summary, _ := issue.Fields["summary"].(string)
+
+	out := Issue{
+		Key:      issue.Key,
+		URL:      strings.TrimRight(baseURL, "/") + "/browse/" + issue.Key,
+		Summary:  summary,
+		Status:   field("status", "name"),
+		Assignee: field("assignee", "displayName"),
+		Priority: field("priority", "name"),
+		Created:  timeField("created"),
+		Updated:  timeField("updated"),
+	}
+	if status, ok := issue.Fields["status"].(map[string]any); ok {
+		if category, ok := status["statusCategory"].(map[string]any); ok {
+			out.Category, _ = category["key"].(string)
+		}
+	}
+	return out
+}
+
+func buildSprintSummary(r *Report, issues []Issue, now time.Time) {
+	groups := map[string][]Issue{}
+	for _, issue := range issues {
+		groups[issue.Category] = append(groups[issue.Category], issue)
+	}
+	done := len(groups["done"])
+	r.Summary = fmt.Sprintf("%d of %d issues in open sprints are done.", done, len(issues))
+	if len(issues) == 0 {
+		r.Summary = "No issues in open sprints."
+	}
+	for _, g := range []struct{ key, heading string }{
+		{"indeterminate", "In progress"},
+		{"new", "To do"},
+		{"done", "Done"},
+	} {
+		if len(groups[g.key]) > 0 {
+			r.Sections = append(r.Sections, Section{Heading: g.heading, Issues: groups[g.key]})
+		}
+	}
+}
+
+func buildWeeklyTriage(r *Report, issues []Issue, now time.Time) {
+	var unassigned, assigned []Issue
+	for _, issue := range issues {
+		if issue.Assignee == "" {
+			unassigned = append(unassigned, issue)
+		} else {
+			assigned = append(assigned, issue)
+		}
+	}
+	r.Summary = fmt.Sprintf("%d issues created in the last 7 days, %d unassigned.", len(issues), len(unassigned))
+	if len(unassigned) > 0 {
+		r.Sections = append(r.Sections, Section{Heading: "Needs an assignee", Issues: unassigned})
+	}
+	if len(assigned) > 0 {
+		r.Sections = append(r.Sections, Section{Heading: "Assigned", Issues: assigned})
+	}
+}
+
+func buildStaleIssues(r *Report, issues []Issue, now time.Time) {
+	for i := range issues {
+		if !issues[i].Updated.IsZero() {
+			issues[i].Note = fmt.Sprintf("idle %d days", int(now.Sub(issues[i].Updated).Hours()/24))
+		}
+	}
+	r.Summary = fmt.Sprintf("%d open issues have not been updated in %d days.", len(issues), staleAfterDays)
+	if len(issues) > 0 {
+		r.Sections = append(r.Sections, Section{Heading: "Stale issues", Issues: issues})
+	}
+}
+
+// Render renders the report in format.
+func (r *Report) Render(format string) (string, error) {
+	if format == models.ReportFormatHTML {
+		return r.HTML()
+	}
+	return r.Markdown(), nil
+}
+
+// Markdown renders the report as Markdown.
+func (r *Report) Markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", r.Title)
+	fmt.Fprintf(&b, "_Generated %s_\n\n", r.GeneratedAt.Format("Mon Jan 2, 2006 15:04 MST"))
+	b.WriteString(r.Summary + "\n")
+	for _, s := range r.Sections {
+		fmt.Fprintf(&b, "\n## %s (%d)\n\n", s.Heading, len(s.Issues))
+		for _, issue := range s.Issues {
+			fmt.Fprintf(&b, "- [%s](%s) %s", issue.Key, issue.URL, markdownEscaper.Replace(issue.Summary))
+			if details := issue.details(); details != "" {
+				b.WriteString(" (" + details + ")")
+			}
+			b.WriteString("\n")
+		}
+	}
+	if r.Truncated {
+		fmt.Fprintf(&b, "\nOnly the first %d matching issues are included.\n", maxIssues)
+	}
+	return b.String()
+}
+
+var markdownEscaper = strings.NewReplacer(`\`, `\\`, "[", `\[`, "]", `\]`, "*", `\*`, "_", `\_`, "`", "\\`")
+
+// details joins the issue's status, assignee, priority and note.
+func (i Issue) details() string {
+	var parts []string
+	for _, p := range []string{i.Status, i.Assignee, i.Priority, i.Note} {
+		if p != "" {
+			parts = append(parts, p)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+var htmlReport = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>{{.Title}}</title></head>
+<body style="font-family: sans-serif">
+<h1>{{.Title}}</h1>
+<p><em>Generated {{.GeneratedAt.Format "Mon Jan 2, 2006 15:04 MST"}}</em></p>
+<p>{{.Summary}}</p>
+{{range .Sections}}<h2>{{.Heading}} ({{len .Issues}})</h2>
+<ul>
+{{range .Issues}}<li><a href="{{.URL}}">{{.Key}}</a> {{.Summary}}{{with .Details}} ({{.}}){{end}}</li>
+{{end}}</ul>
+{{end}}{{if .Truncated}}<p>Only the first {{.MaxIssues}} matching issues are included.</p>
+{{end}}</body>
+</html>
+`))
+
+// HTML renders the report as a standalone HTML page.
+func (r *Report) HTML() (string, error) {
+	type htmlIssue struct {
+		Issue
+		Details string
+	}
+	type htmlSection struct {
+		Heading string
+		Issues  []htmlIssue
+	}
+	data := struct {
+		*Report
+		Sections  []htmlSection
+		MaxIssues int
+	}{Report: r, MaxIssues: maxIssues}
+	for _, s := range r.Sections {
+		section := htmlSection{Heading: s.Heading}
+		for _, issue := range s.Issues {
+			section.Issues = append(section.Issues, htmlIssue{Issue: issue, Details: issue.details()})
+		}
+		data.Sections = append(data.Sections, section)
+	}
+
+	var b bytes.Buffer
+	if err := htmlReport.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("reports: render html: %w", err)
+	}
+	return b.String(), nil
+}
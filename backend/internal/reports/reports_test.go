@@ -0,0 +1,95 @@
+package reports
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/jira"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+)
+
+type fakeSearcher struct {
+	issues   []jira.Issue
+	requests []jira.SearchRequest
+}
+
+func (f *fakeSearcher) BaseURL() string { return "https://example.atlassian.net" }
+
+func (f *fakeSearcher) Search(ctx context.Context, req jira.SearchRequest) (*jira.SearchResult, error) {
+	f.requests = append(f.requests, req)
+	start := 0
+	if req.NextPageToken != "" {
+		fmt.Sscanf(req.NextPageToken, "%d", &start)
+	}
+	end := min(start+req.MaxResults, len(f.issues))
+	result := &jira.SearchResult{Issues: f.issues[start:end], IsLast: end == len(f.issues)}
+	if !result.IsLast {
+		result.NextPageToken = fmt.Sprint(end)
+	}
+	return result, nil
+}
+
+func issue(key, summary, category, assignee string) jira.Issue {
+	fields := map[string]any{
+		"summary": summary,
+		"status":  map[string]any{"name": category, "statusCategory": map[string]any{"key": category}},
+		"updated": "2026-03-01T10:00:00.000+0000",
+	}
+	if assignee != "" {
+		fields["assignee"] = map[string]any{"displayName": assignee}
+	}
+	return jira.Issue{Key: key, Fields: fields}
+}
+
+func TestGenerateSprintSummary(t *testing.T) {
+	jc := &fakeSearcher{issues: []jira.Issue{
+		issue("OPS-1", "Fix [login] *now*", "done", "Ana"),
+		issue("OPS-2", "<script>alert(1)</script>", "indeterminate", ""),
+	}}
+	schedule := &models.ReportSchedule{Template: models.ReportTemplateSprintSummary, ProjectKey: "OPS", JQL: "labels = backend"}
+
+	report, err := Generate(context.Background(), jc, schedule, time.Date(2026, 3, 20, 9, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	if got := jc.requests[0].JQL; got != `project = "OPS" AND sprint in openSprints() AND (labels = backend) ORDER BY status, priority DESC` {
+		t.Fatalf("unexpected jql %q", got)
+	}
+	if report.Summary != "1 of 2 issues in open sprints are done." || len(report.Sections) != 2 || report.Sections[0].Heading != "In progress" {
+		t.Fatalf("unexpected report %+v", report)
+	}
+
+	md := report.Markdown()
+	if !strings.Contains(md, `- [OPS-1](https://example.atlassian.net/browse/OPS-1) Fix \[login\] \*now\* (done, Ana)`) {
+		t.Fatalf("unexpected markdown:\n%s", md)
+	}
+	html, err := report.HTML()
+	if err != nil {
+		t.Fatalf("html: %v", err)
+	}
+	if strings.Contains(html, "<script>") || !strings.Contains(html, "&lt;script&gt;") {
+		t.Fatalf("issue text not escaped in html:\n%s", html)
+	}
+}
+
+func TestGenerateCapsIssues(t *testing.T) {
+	jc := &fakeSearcher{}
+	for i := 0; i < maxIssues+50; i++ {
+		jc.issues = append(jc.issues, issue(fmt.Sprintf("OPS-%d", i), "old", "new", ""))
+	}
+	schedule := &models.ReportSchedule{Template: models.ReportTemplateStaleIssues, ProjectKey: "OPS"}
+
+	report, err := Generate(context.Background(), jc, schedule, time.Date(2026, 3, 20, 10, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	if !report.Truncated || len(report.Sections[0].Issues) != maxIssues || len(jc.requests) != 3 {
+		t.Fatalf("expected %d issues over 3 pages, got %d issues over %d pages", maxIssues, len(report.Sections[0].Issues), len(jc.requests))
+	}
+	if note := report.Sections[0].Issues[0].Note; note != "idle 19 days" {
+		t.Fatalf("unexpected note %q", note)
+	}
+}
@@ -0,0 +1,136 @@
+// Package secretbox encrypts credentials kept in the database, such as Jira
+// API tokens and OAuth access tokens, with AES-256-GCM under keys from a
+// keyring.
+//
+// A sealed value is "enc:v1:<key id>:<base64 nonce and ciphertext>", so it
+// names the key it was sealed with and keys can be rotated: prepend the new
+// key, re-seal stored values (Stale reports the ones to redo), then drop
+// the old key. Values without the prefix are plaintext written before
+// encryption was enabled and are returned as they are.
+package secretbox
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/keyring"
+)
+
+// prefix marks a sealed value and its format version.
+const prefix = "enc:v1:"
+
+// ErrUnknownKey is returned when a value was sealed with a key that is no
+// longer in the keyring.
+var ErrUnknownKey = errors.New("secretbox: value sealed with an unknown key")
+
+// Box seals and opens values. A nil Box, or one built from an empty
+// keyring, stores values in plaintext.
+type Box struct {
+	primary string
+	aeads   map[string]cipher.AEAD
+}
+
+// New builds a box from keys; the primary key seals. Each key's secret is
+// hashed with SHA-256 into the AES-256 key, so secrets of any length work.
+func New(keys *keyring.Keyring) (*Box, error) {
+	b := &Box{aeads: make(map[string]cipher.AEAD, keys.Len())}
+	for _, key := range keys.Keys() {
+		sum := sha256.Sum256(key.Secret)
+		block, err := aes.NewCipher(sum[:])
+		if err != nil {
+			return nil, fmt.Errorf("secretbox: key %q: %w", key.ID, err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, fmt.Errorf("secretbox: key %q: %w", key.ID, err)
+		}
+		b.aeads[key.ID] = aead
+	}
+	if primary, ok := keys.Primary(); ok {
+		b.primary = primary.ID
+	}
+	return b, nil
+}
+
+// Enabled reports whether the box encrypts.
+func (b *Box) Enabled() bool {
+	return b != nil && b.primary != ""
+}
+
+// Seal encrypts plaintext with the primary key. Empty values, and every
+// value when the box is disabled, are returned unchanged.
+func (b *Box) Seal(plaintext string) (string, error) {
+	if plaintext == "" || !b.Enabled() {
+		return plaintext, nil
+	}
+	aead := b.aeads[b.primary]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("secretbox: generate nonce: %w", err)
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return prefix + b.primary + ":" + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// Open decrypts a value from Seal. Plaintext values are returned unchanged.
+func (b *Box) Open(value string) (string, error) {
+	keyID, data, ok := parse(value)
+	if !ok {
+		return value, nil
+	}
+	var aead cipher.AEAD
+	if b != nil {
+		aead = b.aeads[keyID]
+	}
+	if aead == nil {
+		return "", fmt.Errorf("%w %q", ErrUnknownKey, keyID)
+	}
+	sealed, err := base64.RawStdEncoding.DecodeString(data)
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return "", errors.New("secretbox: malformed sealed value")
+	}
+	plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], nil)
+	if err != nil {
+		return "", fmt.Errorf("secretbox: decrypt with key %q: %w", keyID, err)
+	}
+	return string(plaintext), nil
+}
+
+// Stale reports whether a stored value should be sealed again: it is
+// plaintext, or sealed with a key other than the primary.
+func (b *Box) Stale(value string) bool {
+	if value == "" || !b.Enabled() {
+		return false
+	}
+	keyID, _, ok := parse(value)
+	return !ok || keyID != b.primary
+}
+
+// PrimaryPrefix returns the prefix of values sealed with the primary key,
+// for finding stale values in the database; "" when the box is disabled.
+func (b *Box) PrimaryPrefix() string {
+	if !b.Enabled() {
+		return ""
+	}
+	return prefix + b.primary + ":"
+}
+
+// Sealed reports whether value was produced by Seal.
+func Sealed(value string) bool {
+	_, _, ok := parse(value)
+	return ok
+}
+
+func parse(value string) (keyID, data string, ok bool) {
+	rest, found := strings.CutPrefix(value, prefix)
+	if !found {
+		return "", "", false
+	}
+	return strings.Cut(rest, ":")
+}
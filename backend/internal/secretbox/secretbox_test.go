@@ -0,0 +1,60 @@
+package secretbox
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/keyring"
+)
+
+func TestSealAndRotate(t *testing.T) {
+	oldKeys, _ := keyring.Parse("2025-01:old-secret")
+	oldBox, err := New(oldKeys)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	sealed, err := oldBox.Seal("atlassian-token")
+	if err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+	if !strings.HasPrefix(sealed, "enc:v1:2025-01:") || strings.Contains(sealed, "atlassian-token") {
+		t.Fatalf("unexpected sealed value %q", sealed)
+	}
+
+	rotated, _ := keyring.Parse("2025-06:new-secret,2025-01:old-secret")
+	box, _ := New(rotated)
+	if got, err := box.Open(sealed); err != nil || got != "atlassian-token" {
+		t.Fatalf("Open with the previous key = %q, %v", got, err)
+	}
+	if !box.Stale(sealed) || !box.Stale("plaintext") || box.Stale("") {
+		t.Fatal("expected values not under the primary key to be stale")
+	}
+	resealed, _ := box.Seal("atlassian-token")
+	if box.Stale(resealed) {
+		t.Fatalf("freshly sealed value %q is stale", resealed)
+	}
+
+	newOnly, _ := keyring.Parse("2025-06:new-secret")
+	dropped, _ := New(newOnly)
+	if _, err := dropped.Open(sealed); !errors.Is(err, ErrUnknownKey) {
+		t.Fatalf("expected ErrUnknownKey, got %v", err)
+	}
+	if _, err := box.Open(sealed[:len(sealed)-4] + "AAAA"); err == nil {
+		t.Fatal("expected tampering to be detected")
+	}
+}
+
+func TestDisabledBoxStoresPlaintext(t *testing.T) {
+	var box *Box
+	if got, _ := box.Seal("token"); got != "token" {
+		t.Fatalf("nil box sealed to %q", got)
+	}
+	if got, _ := box.Open("token"); got != "token" {
+		t.Fatalf("nil box opened to %q", got)
+	}
+	empty, _ := New(nil)
+	if empty.Enabled() || empty.Stale("token") {
+		t.Fatal("an empty keyring must not encrypt")
+	}
+}
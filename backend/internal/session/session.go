@@ -29,6 +29,10 @@ type Payload struct {
 	Email     *string `json:"email,omitempty"`
 	Provider  string  `json:"provider,omitempty"`
 	Exp       int64   `json:"exp"`
+
+	// SessionID names the server-side session record. Cookies issued
+	// before sessions were recorded have none and cannot be revoked.
+	SessionID string `json:"sid,omitempty"`
 }
 
 // StatePayload is the data stored in the OAuth state cookie.
@@ -37,6 +41,10 @@ type StatePayload struct {
 	Redirect    string `json:"redirect"`
 	CreatedAt   int64  `json:"createdAt"`
 	LinkAccount bool   `json:"linkAccount,omitempty"`
+	// Org and Verifier are set for organization SSO logins: the
+	// organization slug and, for OIDC, the PKCE code verifier.
+	Org      string `json:"org,omitempty"`
+	Verifier string `json:"verifier,omitempty"`
 }
 
 // --- Base64URL helpers (no padding, URL-safe) ---
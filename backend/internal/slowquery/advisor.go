@@ -0,0 +1,171 @@
+package slowquery
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+)
+
+// Suggestion is a candidate index for a table scanned sequentially by one
+// or more slow queries.
+type Suggestion struct {
+	Table        string
+	Columns      []string
+	TotalMs      float64
+	Fingerprints []string
+}
+
+// Statement returns the DDL that creates the suggested index.
+func (s Suggestion) Statement() string {
+	return fmt.Sprintf("CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_%s_%s ON %s (%s);",
+		s.Table, strings.Join(s.Columns, "_"), s.Table, strings.Join(s.Columns, ", "))
+}
+
+// planNode is the subset of an EXPLAIN (FORMAT JSON) node the advisor reads.
+type planNode struct {
+	NodeType     string     `json:"Node Type"`
+	RelationName string     `json:"Relation Name"`
+	Filter       string     `json:"Filter"`
+	SortKey      []string   `json:"Sort Key"`
+	Plans        []planNode `json:"Plans"`
+}
+
+// conditionPattern matches a simple column predicate in a plan filter, such
+// as "(user_id = $1)" or "(created_at >= $2)". Expressions on columns are
+// ignored since a plain index would not serve them.
+var conditionPattern = regexp.MustCompile(`^\(*(?:[a-z_][a-z0-9_]*\.)?([a-z_][a-z0-9_]*)\)?(?:::[a-z ]+)?\s+(=|<>|<=|>=|<|>|IS NULL|IS NOT NULL)`)
+
+// Advise suggests indexes for the sequential scans with filters found in
+// the queries' plans. Equality columns lead, followed by one range column
+// or, failing that, the sort keys of a sort directly above the scan.
+// Suggestions already covered by a leading prefix of an index in existing
+// (table name to index column lists) are skipped. Results are ordered by the
+// slow query time they account for.
+func Advise(queries []models.SlowQuery, existing map[string][][]string) []Suggestion {
+	byKey := map[string]*Suggestion{}
+	for _, q := range queries {
+		if len(q.Plan) == 0 {
+			continue
+		}
+		var plans []struct {
+			Plan planNode `json:"Plan"`
+		}
+		if err := json.Unmarshal(q.Plan, &plans); err != nil {
+			continue
+		}
+		for _, p := range plans {
+			for _, s := range scanSuggestions(p.Plan, nil) {
+				if covered(s, existing[s.Table]) {
+					continue
+				}
+				key := s.Table + "(" + strings.Join(s.Columns, ",") + ")"
+				agg, ok := byKey[key]
+				if !ok {
+					agg = &Suggestion{Table: s.Table, Columns: s.Columns}
+					byKey[key] = agg
+				}
+				agg.TotalMs += q.TotalMs
+				agg.Fingerprints = append(agg.Fingerprints, q.Fingerprint)
+			}
+		}
+	}
+
+	suggestions := make([]Suggestion, 0, len(byKey))
+	for _, s := range byKey {
+		suggestions = append(suggestions, *s)
+	}
+	sort.Slice(suggestions, func(i, j int) bool {
+		if suggestions[i].TotalMs != suggestions[j].TotalMs {
+			return suggestions[i].TotalMs > suggestions[j].TotalMs
+		}
+		return suggestions[i].Statement() < suggestions[j].Statement()
+	})
+	return suggestions
+}
+
+// scanSuggestions walks the plan tree; sortKey carries the keys of a Sort
+// node whose child is being visited.
+func scanSuggestions(node planNode, sortKey []string) []Suggestion {
+	var out []Suggestion
+	if node.NodeType == "Seq Scan" && node.RelationName != "" && node.Filter != "" {
+		if cols := indexColumns(node.Filter, sortKey); len(cols) > 0 {
+			out = append(out, Suggestion{Table: node.RelationName, Columns: cols})
+		}
+	}
+
+	var childSort []string
+	if node.NodeType == "Sort" {
+		childSort = node.SortKey
+	}
+	for _, child := range node.Plans {
+		out = append(out, scanSuggestions(child, childSort)...)
+	}
+	return out
+}
+
+func indexColumns(filter string, sortKey []string) []string {
+	var equality, ranges []string
+	seen := map[string]bool{}
+	for _, cond := range strings.Split(filter, " AND ") {
+		// Disjunctions cannot use a single index prefix.
+		if strings.Contains(cond, " OR ") {
+			continue
+		}
+		m := conditionPattern.FindStringSubmatch(strings.TrimSpace(cond))
+		if m == nil || seen[m[1]] {
+			continue
+		}
+		seen[m[1]] = true
+		switch m[2] {
+		case "=", "IS NULL":
+			equality = append(equality, m[1])
+		case "<", ">", "<=", ">=":
+			ranges = append(ranges, m[1])
+		}
+	}
+
+	cols := equality
+	if len(ranges) > 0 {
+		return append(cols, ranges[0])
+	}
+	for _, key := range sortKey {
+		fields := strings.Fields(key)
+		if len(fields) == 0 {
+			continue
+		}
+		col := fields[0]
+		if i := strings.LastIndex(col, "."); i >= 0 {
+			col = col[i+1:]
+		}
+		if !seen[col] && !strings.ContainsAny(col, "()") {
+			seen[col] = true
+			cols = append(cols, col)
+		}
+	}
+	return cols
+}
+
+// covered reports whether an existing index starts with the suggestion's
+// columns.
+func covered(s Suggestion, indexes [][]string) bool {
+	for _, index := range indexes {
+		if len(index) < len(s.Columns) {
+			continue
+		}
+		match := true
+		for i, col := range s.Columns {
+			if index[i] != col {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,47 @@
+package slowquery
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+)
+
+const requestsPlan = `[{"Plan": {"Node Type": "Limit", "Plans": [
+  {"Node Type": "Sort", "Sort Key": ["requests.created_at DESC"], "Plans": [
+    {"Node Type": "Seq Scan", "Relation Name": "requests", "Filter": "(user_id = $1)"}
+  ]}
+]}}]`
+
+const jobsPlan = `[{"Plan": {"Node Type": "Seq Scan", "Relation Name": "jobs",
+  "Filter": "(((status)::text = 'pending'::text) AND ((scheduled_for IS NULL) OR (scheduled_for <= now())) AND (created_at >= $1))"}}]`
+
+func TestAdviseSuggestsIndexesForFilteredSeqScans(t *testing.T) {
+	queries := []models.SlowQuery{
+		{Fingerprint: "a", TotalMs: 100, Plan: []byte(requestsPlan)},
+		{Fingerprint: "b", TotalMs: 500, Plan: []byte(jobsPlan)},
+		{Fingerprint: "c", TotalMs: 50, Plan: []byte(requestsPlan)},
+		{Fingerprint: "d", TotalMs: 900},
+	}
+
+	got := Advise(queries, nil)
+	want := []Suggestion{
+		{Table: "jobs", Columns: []string{"status", "created_at"}, TotalMs: 500, Fingerprints: []string{"b"}},
+		{Table: "requests", Columns: []string{"user_id", "created_at"}, TotalMs: 150, Fingerprints: []string{"a", "c"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Advise() = %+v, want %+v", got, want)
+	}
+	if stmt := got[1].Statement(); stmt != "CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_requests_user_id_created_at ON requests (user_id, created_at);" {
+		t.Fatalf("unexpected statement %q", stmt)
+	}
+}
+
+func TestAdviseSkipsCoveredIndexes(t *testing.T) {
+	queries := []models.SlowQuery{{Fingerprint: "a", TotalMs: 100, Plan: []byte(requestsPlan)}}
+	existing := map[string][][]string{"requests": {{"user_id", "created_at", "id"}}}
+
+	if got := Advise(queries, existing); len(got) != 0 {
+		t.Fatalf("expected no suggestions, got %+v", got)
+	}
+}
@@ -0,0 +1,202 @@
+// Package slowquery times every statement sent through a wrapped database
+// driver and hands statements slower than a threshold to a consumer, and
+// turns recorded query plans into index suggestions.
+package slowquery
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql/driver"
+	"encoding/hex"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Sample is one statement that ran longer than the collector's threshold.
+type Sample struct {
+	Fingerprint string
+	Query       string
+	Args        []any
+	Duration    time.Duration
+	At          time.Time
+}
+
+// Collector wraps database connectors and queues slow statements. Samples
+// are dropped when the queue is full so a slow database never blocks
+// callers on the collector.
+type Collector struct {
+	threshold time.Duration
+	samples   chan Sample
+	dropped   atomic.Int64
+}
+
+// NewCollector returns a collector that queues statements taking at least
+// threshold.
+func NewCollector(threshold time.Duration, queueSize int) *Collector {
+	if queueSize <= 0 {
+		queueSize = 256
+	}
+	return &Collector{threshold: threshold, samples: make(chan Sample, queueSize)}
+}
+
+// Threshold returns the duration above which statements are recorded.
+func (c *Collector) Threshold() time.Duration {
+	return c.threshold
+}
+
+// Samples returns the queue of slow statements.
+func (c *Collector) Samples() <-chan Sample {
+	return c.samples
+}
+
+// Dropped returns how many samples were discarded because the queue was full.
+func (c *Collector) Dropped() int64 {
+	return c.dropped.Load()
+}
+
+type skipRecordingKey struct{}
+
+// WithoutRecording marks ctx so statements run with it are not timed. The
+// consumer uses it for its own EXPLAIN and bookkeeping queries.
+func WithoutRecording(ctx context.Context) context.Context {
+	return context.WithValue(ctx, skipRecordingKey{}, true)
+}
+
+// Fingerprint identifies a statement by its whitespace-normalized text.
+// Store queries are parameterized, so the text is stable across calls.
+func Fingerprint(query string) string {
+	sum := sha256.Sum256([]byte(normalize(query)))
+	return hex.EncodeToString(sum[:16])
+}
+
+func normalize(query string) string {
+	return strings.Join(strings.Fields(query), " ")
+}
+
+func (c *Collector) observe(ctx context.Context, query string, args []driver.NamedValue, start time.Time) {
+	elapsed := time.Since(start)
+	if elapsed < c.threshold {
+		return
+	}
+	if skip, _ := ctx.Value(skipRecordingKey{}).(bool); skip {
+		return
+	}
+
+	values := make([]any, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+	sample := Sample{
+		Fingerprint: Fingerprint(query),
+		Query:       normalize(query),
+		Args:        values,
+		Duration:    elapsed,
+		At:          start,
+	}
+	select {
+	case c.samples <- sample:
+	default:
+		c.dropped.Add(1)
+	}
+}
+
+// Wrap returns a connector whose connections time QueryContext and
+// ExecContext calls. Queries are timed until their rows are closed.
+func (c *Collector) Wrap(connector driver.Connector) driver.Connector {
+	return &timedConnector{Connector: connector, collector: c}
+}
+
+type timedConnector struct {
+	driver.Connector
+	collector *Collector
+}
+
+func (t *timedConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := t.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &timedConn{Conn: conn, collector: t.collector}, nil
+}
+
+// timedConn forwards the optional driver interfaces database/sql relies on.
+type timedConn struct {
+	driver.Conn
+	collector *Collector
+}
+
+func (c *timedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	start := time.Now()
+	rows, err := queryer.QueryContext(ctx, query, args)
+	if err != nil {
+		c.collector.observe(ctx, query, args, start)
+		return nil, err
+	}
+	return &timedRows{Rows: rows, done: func() { c.collector.observe(ctx, query, args, start) }}, nil
+}
+
+func (c *timedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	execer, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	start := time.Now()
+	res, err := execer.ExecContext(ctx, query, args)
+	c.collector.observe(ctx, query, args, start)
+	return res, err
+}
+
+func (c *timedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if preparer, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		return preparer.PrepareContext(ctx, query)
+	}
+	return c.Conn.Prepare(query)
+}
+
+func (c *timedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if beginner, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return beginner.BeginTx(ctx, opts)
+	}
+	return c.Conn.Begin()
+}
+
+func (c *timedConn) Ping(ctx context.Context) error {
+	if pinger, ok := c.Conn.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *timedConn) ResetSession(ctx context.Context) error {
+	if resetter, ok := c.Conn.(driver.SessionResetter); ok {
+		return resetter.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *timedConn) IsValid() bool {
+	if validator, ok := c.Conn.(driver.Validator); ok {
+		return validator.IsValid()
+	}
+	return true
+}
+
+type timedRows struct {
+	driver.Rows
+	done   func()
+	closed bool
+}
+
+func (r *timedRows) Close() error {
+	err := r.Rows.Close()
+	if !r.closed {
+		r.closed = true
+		r.done()
+	}
+	return err
+}
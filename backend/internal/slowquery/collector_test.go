@@ -0,0 +1,84 @@
+package slowquery
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"testing"
+	"time"
+)
+
+// sleepConnector's connections take the number of milliseconds given as the
+// first argument to run any statement.
+type sleepConnector struct{}
+
+func (sleepConnector) Connect(context.Context) (driver.Conn, error) { return sleepConn{}, nil }
+func (sleepConnector) Driver() driver.Driver                        { return nil }
+
+type sleepConn struct{}
+
+func (sleepConn) Prepare(string) (driver.Stmt, error) { return nil, driver.ErrSkip }
+func (sleepConn) Close() error                        { return nil }
+func (sleepConn) Begin() (driver.Tx, error)           { return nil, driver.ErrSkip }
+
+func (sleepConn) ExecContext(_ context.Context, _ string, args []driver.NamedValue) (driver.Result, error) {
+	time.Sleep(time.Duration(args[0].Value.(int64)) * time.Millisecond)
+	return driver.RowsAffected(0), nil
+}
+
+func (sleepConn) QueryContext(_ context.Context, _ string, args []driver.NamedValue) (driver.Rows, error) {
+	return &sleepRows{delay: time.Duration(args[0].Value.(int64)) * time.Millisecond}, nil
+}
+
+// sleepRows spends its delay while being read, so only timing through Close
+// sees it.
+type sleepRows struct{ delay time.Duration }
+
+func (r *sleepRows) Columns() []string { return []string{"n"} }
+func (r *sleepRows) Close() error      { return nil }
+func (r *sleepRows) Next([]driver.Value) error {
+	time.Sleep(r.delay)
+	return io.EOF
+}
+
+func TestCollectorQueuesOnlySlowStatements(t *testing.T) {
+	c := NewCollector(20*time.Millisecond, 4)
+	db := sql.OpenDB(c.Wrap(sleepConnector{}))
+	defer db.Close()
+	ctx := context.Background()
+
+	if _, err := db.ExecContext(ctx, "UPDATE fast  SET x = $1", 0); err != nil {
+		t.Fatalf("fast exec: %v", err)
+	}
+	if _, err := db.ExecContext(ctx, "UPDATE slow\n\tSET x = $1", 30); err != nil {
+		t.Fatalf("slow exec: %v", err)
+	}
+	rows, err := db.QueryContext(ctx, "SELECT n FROM slow WHERE x = $1", 30)
+	if err != nil {
+		t.Fatalf("slow query: %v", err)
+	}
+	for rows.Next() {
+	}
+	rows.Close()
+	if _, err := db.ExecContext(WithoutRecording(ctx), "UPDATE skipped SET x = $1", 30); err != nil {
+		t.Fatalf("skipped exec: %v", err)
+	}
+
+	var got []Sample
+	for len(c.Samples()) > 0 {
+		got = append(got, <-c.Samples())
+	}
+	if len(got) != 2 {
+		t.Fatalf("expected 2 samples, got %+v", got)
+	}
+	if got[0].Query != "UPDATE slow SET x = $1" || got[0].Fingerprint != Fingerprint("UPDATE slow SET x = $1") {
+		t.Fatalf("unexpected exec sample %+v", got[0])
+	}
+	if got[1].Query != "SELECT n FROM slow WHERE x = $1" || got[1].Duration < 30*time.Millisecond {
+		t.Fatalf("unexpected query sample %+v", got[1])
+	}
+	if len(got[1].Args) != 1 || got[1].Args[0] != int64(30) {
+		t.Fatalf("expected sample args [30], got %v", got[1].Args)
+	}
+}
@@ -0,0 +1,202 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+)
+
+// RecordAuthFailure notes a rejected credential from clientIP.
+func (s *Store) RecordAuthFailure(ctx context.Context, clientIP, reason string) error {
+	if s == nil || s.db == nil {
+		return errors.New("store: db cannot be nil")
+	}
+
+	if _, err := s.db.ExecContext(ctx, `
+		INSERT INTO auth_failures (client_ip, reason) VALUES ($1, $2)
+	`, clientIP, reason); err != nil {
+		return fmt.Errorf("store: record auth failure: %w", err)
+	}
+
+	return nil
+}
+
+// ListAuthFailureBursts returns client IPs with at least threshold auth
+// failures since the given time, skipping IPs that are already limited.
+func (s *Store) ListAuthFailureBursts(ctx context.Context, since time.Time, threshold int) ([]models.AuthFailureBurst, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT f.client_ip, COUNT(*)
+		FROM auth_failures f
+		WHERE f.created_at >= $1
+		  AND NOT EXISTS (
+			SELECT 1 FROM ip_rate_limits l
+			WHERE l.client_ip = f.client_ip AND l.expires_at > now()
+		  )
+		GROUP BY f.client_ip
+		HAVING COUNT(*) >= $2
+		ORDER BY COUNT(*) DESC
+	`, since, threshold)
+	if err != nil {
+		return nil, fmt.Errorf("store: list auth failure bursts: %w", err)
+	}
+	defer rows.Close()
+
+	var bursts []models.AuthFailureBurst
+	for rows.Next() {
+		var b models.AuthFailureBurst
+		if err := rows.Scan(&b.ClientIP, &b.Failures); err != nil {
+			return nil, fmt.Errorf("store: scan auth failure burst: %w", err)
+		}
+		bursts = append(bursts, b)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("store: iterate auth failure bursts: %w", err)
+	}
+
+	return bursts, nil
+}
+
+// ListRequestSpikes returns users whose request count in the trailing window
+// is at least factor times their average for a window of the same length over
+// the preceding baseline period, and at least minRequests. Users with no
+// baseline traffic are compared against a baseline of one request.
+func (s *Store) ListRequestSpikes(ctx context.Context, window, baseline time.Duration, factor float64, minRequests int) ([]models.RequestSpike, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	now := time.Now()
+	recentStart := now.Add(-window)
+	baselineStart := recentStart.Add(-baseline)
+	windows := baseline.Seconds() / window.Seconds()
+
+	var spikes []models.RequestSpike
+	dbs := s.requestDatabases()
+	for _, rdb := range dbs {
+		regional, err := queryRequestSpikes(ctx, rdb.db, baselineStart, recentStart, windows, factor, minRequests)
+		if err != nil {
+			return nil, err
+		}
+		spikes = append(spikes, regional...)
+	}
+	if len(dbs) > 1 {
+		sort.SliceStable(spikes, func(i, j int) bool { return spikes[i].Recent > spikes[j].Recent })
+	}
+
+	return spikes, nil
+}
+
+func queryRequestSpikes(ctx context.Context, db *sql.DB, baselineStart, recentStart time.Time, windows, factor float64, minRequests int) ([]models.RequestSpike, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT user_id, recent, baseline
+		FROM (
+			SELECT
+				user_id,
+				COALESCE(SUM(sample_weight) FILTER (WHERE created_at >= $2), 0) AS recent,
+				COALESCE(SUM(sample_weight) FILTER (WHERE created_at < $2), 0)::float8 / $3 AS baseline
+			FROM requests
+			WHERE created_at >= $1
+			GROUP BY user_id
+		) t
+		WHERE recent >= $4 AND recent >= $5 * GREATEST(baseline, 1)
+		ORDER BY recent DESC
+	`, baselineStart, recentStart, windows, minRequests, factor)
+	if err != nil {
+		return nil, fmt.Errorf("store: list request spikes: %w", err)
+	}
+	defer rows.Close()
+
+	var spikes []models.RequestSpike
+	for rows.Next() {
+		var sp models.RequestSpike
+		if err := rows.Scan(&sp.UserID, &sp.Recent, &sp.Baseline); err != nil {
+			return nil, fmt.Errorf("store: scan request spike: %w", err)
+		}
+		spikes = append(spikes, sp)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("store: iterate request spikes: %w", err)
+	}
+
+	return spikes, nil
+}
+
+// SetIPRateLimit applies or extends a temporary rate limit on clientIP.
+func (s *Store) SetIPRateLimit(ctx context.Context, limit *models.IPRateLimit) error {
+	if s == nil || s.db == nil {
+		return errors.New("store: db cannot be nil")
+	}
+	if limit == nil {
+		return errors.New("store: ip rate limit cannot be nil")
+	}
+
+	if _, err := s.db.ExecContext(ctx, `
+		INSERT INTO ip_rate_limits (client_ip, requests_per_minute, reason, expires_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (client_ip) DO UPDATE
+		SET requests_per_minute = LEAST(ip_rate_limits.requests_per_minute, EXCLUDED.requests_per_minute),
+		    reason = EXCLUDED.reason,
+		    expires_at = GREATEST(ip_rate_limits.expires_at, EXCLUDED.expires_at)
+	`, limit.ClientIP, limit.RequestsPerMinute, limit.Reason, limit.ExpiresAt); err != nil {
+		return fmt.Errorf("store: set ip rate limit: %w", err)
+	}
+
+	return nil
+}
+
+// ListActiveIPRateLimits returns the rate limits that have not expired.
+func (s *Store) ListActiveIPRateLimits(ctx context.Context) ([]models.IPRateLimit, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT client_ip, requests_per_minute, reason, expires_at, created_at
+		FROM ip_rate_limits
+		WHERE expires_at > now()
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("store: list ip rate limits: %w", err)
+	}
+	defer rows.Close()
+
+	var limits []models.IPRateLimit
+	for rows.Next() {
+		var l models.IPRateLimit
+		if err := rows.Scan(&l.ClientIP, &l.RequestsPerMinute, &l.Reason, &l.ExpiresAt, &l.CreatedAt); err != nil {
+			return nil, fmt.Errorf("store: scan ip rate limit: %w", err)
+		}
+		limits = append(limits, l)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("store: iterate ip rate limits: %w", err)
+	}
+
+	return limits, nil
+}
+
+// PurgeAbuseRecords deletes auth failures older than the retention period
+// and expired rate limits.
+func (s *Store) PurgeAbuseRecords(ctx context.Context, retention time.Duration) error {
+	if s == nil || s.db == nil {
+		return errors.New("store: db cannot be nil")
+	}
+
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM auth_failures WHERE created_at < $1`, time.Now().Add(-retention)); err != nil {
+		return fmt.Errorf("store: purge auth failures: %w", err)
+	}
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM ip_rate_limits WHERE expires_at <= now()`); err != nil {
+		return fmt.Errorf("store: purge ip rate limits: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,205 @@
+package store
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+)
+
+// ErrAPIKeyNotFound is returned for unknown or already revoked API keys.
+var ErrAPIKeyNotFound = errors.New("api key not found")
+
+// ErrAPIKeyInvalid is returned when a presented API key is unknown,
+// revoked, expired or malformed.
+var ErrAPIKeyInvalid = errors.New("api key is invalid, revoked or expired")
+
+// apiKeyTouchInterval limits how often last_used_at is written for a key
+// that is used on every request.
+const apiKeyTouchInterval = time.Minute
+
+const apiKeyColumns = `id, name, prefix, scopes, created_by, created_at, last_used_at, expires_at, revoked_at`
+
+func scanAPIKey(row interface{ Scan(...any) error }) (*models.APIKey, error) {
+	var (
+		key       models.APIKey
+		createdBy sql.NullInt64
+		lastUsed  sql.NullTime
+		expires   sql.NullTime
+		revoked   sql.NullTime
+	)
+	if err := row.Scan(&key.ID, &key.Name, &key.Prefix, pq.Array(&key.Scopes), &createdBy, &key.CreatedAt, &lastUsed, &expires, &revoked); err != nil {
+		return nil, err
+	}
+	if createdBy.Valid {
+		key.CreatedBy = &createdBy.Int64
+	}
+	if lastUsed.Valid {
+		key.LastUsedAt = &lastUsed.Time
+	}
+	if expires.Valid {
+		key.ExpiresAt = &expires.Time
+	}
+	if revoked.Valid {
+		key.RevokedAt = &revoked.Time
+	}
+	return &key, nil
+}
+
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// apiKeyPrefix returns the lookup prefix of key ("mjt_" and eight hex
+// characters), or "" when key does not have the shape CreateAPIKey gives.
+func apiKeyPrefix(key string) string {
+	rest, ok := strings.CutPrefix(key, models.APIKeyPrefix)
+	if !ok {
+		return ""
+	}
+	id, secret, ok := strings.Cut(rest, "_")
+	if !ok || len(id) != 8 || secret == "" {
+		return ""
+	}
+	return models.APIKeyPrefix + id
+}
+
+// CreateAPIKey stores a new API key with scopes on behalf of createdBy (zero
+// when no user is known) and returns it along with the key itself, which is
+// not stored and cannot be shown again. A nil expiresAt never expires.
+func (s *Store) CreateAPIKey(ctx context.Context, name string, scopes []string, createdBy int64, expiresAt *time.Time) (*models.APIKey, string, error) {
+	if s == nil || s.db == nil {
+		return nil, "", errors.New("store: db cannot be nil")
+	}
+	for _, scope := range scopes {
+		if !models.ValidAPIKeyScope(scope) {
+			return nil, "", fmt.Errorf("store: unknown api key scope %q", scope)
+		}
+	}
+
+	id, err := randomHex(4)
+	if err != nil {
+		return nil, "", fmt.Errorf("store: generate api key: %w", err)
+	}
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, "", fmt.Errorf("store: generate api key: %w", err)
+	}
+	prefix := models.APIKeyPrefix + id
+	key := prefix + "_" + base64.RawURLEncoding.EncodeToString(secret)
+
+	var creator sql.NullInt64
+	if createdBy > 0 {
+		creator = sql.NullInt64{Int64: createdBy, Valid: true}
+	}
+	created, err := scanAPIKey(s.db.QueryRowContext(ctx, `
+		INSERT INTO api_keys (name, prefix, key_hash, scopes, created_by, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING `+apiKeyColumns,
+		name, prefix, hashAPIKey(key), pq.Array(scopes), creator, expiresAt))
+	if err != nil {
+		return nil, "", fmt.Errorf("store: create api key: %w", err)
+	}
+	return created, key, nil
+}
+
+// AuthenticateAPIKey returns the live API key matching key, or
+// ErrAPIKeyInvalid. It records when the key was last used.
+func (s *Store) AuthenticateAPIKey(ctx context.Context, key string) (*models.APIKey, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+	prefix := apiKeyPrefix(key)
+	if prefix == "" {
+		return nil, ErrAPIKeyInvalid
+	}
+
+	var hash string
+	var (
+		found    models.APIKey
+		lastUsed sql.NullTime
+	)
+	err := s.db.QueryRowContext(ctx, `
+		SELECT id, name, prefix, key_hash, scopes, last_used_at
+		FROM api_keys
+		WHERE prefix = $1
+		  AND revoked_at IS NULL
+		  AND (expires_at IS NULL OR expires_at > NOW())
+	`, prefix).Scan(&found.ID, &found.Name, &found.Prefix, &hash, pq.Array(&found.Scopes), &lastUsed)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrAPIKeyInvalid
+	}
+	if err != nil {
+		return nil, fmt.Errorf("store: authenticate api key: %w", err)
+	}
+	if subtle.ConstantTimeCompare([]byte(hash), []byte(hashAPIKey(key))) != 1 {
+		return nil, ErrAPIKeyInvalid
+	}
+
+	if !lastUsed.Valid || time.Since(lastUsed.Time) >= apiKeyTouchInterval {
+		if _, err := s.db.ExecContext(ctx, `UPDATE api_keys SET last_used_at = NOW() WHERE id = $1`, found.ID); err != nil {
+			return nil, fmt.Errorf("store: touch api key: %w", err)
+		}
+	} else {
+		found.LastUsedAt = &lastUsed.Time
+	}
+	return &found, nil
+}
+
+// ListAPIKeys returns every API key, newest first, revoked ones included.
+func (s *Store) ListAPIKeys(ctx context.Context) ([]models.APIKey, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	rows, err := s.db.QueryContext(ctx, `SELECT `+apiKeyColumns+` FROM api_keys ORDER BY created_at DESC, id DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("store: list api keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := []models.APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("store: scan api key: %w", err)
+		}
+		keys = append(keys, *key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("store: list api keys: %w", err)
+	}
+	return keys, nil
+}
+
+// RevokeAPIKey revokes the API key with id. Revoked keys stop
+// authenticating at once and cannot be restored.
+func (s *Store) RevokeAPIKey(ctx context.Context, id int64) (*models.APIKey, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	key, err := scanAPIKey(s.db.QueryRowContext(ctx, `
+		UPDATE api_keys SET revoked_at = NOW()
+		WHERE id = $1 AND revoked_at IS NULL
+		RETURNING `+apiKeyColumns, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("store: revoke api key: %w", err)
+	}
+	return key, nil
+}
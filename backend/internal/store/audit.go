@@ -0,0 +1,40 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+)
+
+// RecordAuditEvent appends an entry to the audit log for the given user. A
+// userID of zero records a system event not tied to any user.
+func (s *Store) RecordAuditEvent(ctx context.Context, userID int64, action string, metadata models.JSONB) error {
+	if s == nil || s.db == nil {
+		return errors.New("store: db cannot be nil")
+	}
+
+	if action == "" {
+		return errors.New("store: audit action cannot be empty")
+	}
+
+	if metadata == nil {
+		metadata = models.JSONB{}
+	}
+
+	var uid sql.NullInt64
+	if userID > 0 {
+		uid = sql.NullInt64{Int64: userID, Valid: true}
+	}
+
+	if _, err := s.db.ExecContext(ctx, `
+INSERT INTO audit_log (user_id, action, metadata)
+VALUES ($1, $2, $3)
+`, uid, action, metadata); err != nil {
+		return fmt.Errorf("store: record audit event: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,369 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+)
+
+// ErrAutomationNotFound is returned when an automation does not exist or
+// belongs to another user.
+var ErrAutomationNotFound = errors.New("automation not found")
+
+const automationColumns = `id, user_id, name, jql, schedule_days, schedule_time, time_zone,
+	destination, destination_url, max_results, enabled, next_run_at, last_run_at,
+	last_status, consecutive_failures, created_at, updated_at`
+
+// scanAutomation reads an automation selected with automationColumns and
+// opens its destination URL.
+func (s *Store) scanAutomation(row interface{ Scan(...any) error }) (*models.JiraAutomation, error) {
+	var (
+		a          models.JiraAutomation
+		days       pq.Int64Array
+		lastRun    sql.NullTime
+		lastStatus sql.NullString
+	)
+	if err := row.Scan(&a.ID, &a.UserID, &a.Name, &a.JQL, &days, &a.TimeOfDay, &a.TimeZone,
+		&a.Destination, &a.DestinationURL, &a.MaxResults, &a.Enabled, &a.NextRunAt, &lastRun,
+		&lastStatus, &a.ConsecutiveFailures, &a.CreatedAt, &a.UpdatedAt); err != nil {
+		return nil, err
+	}
+	a.Days = make([]int, len(days))
+	for i, day := range days {
+		a.Days[i] = int(day)
+	}
+	if lastRun.Valid {
+		a.LastRunAt = &lastRun.Time
+	}
+	a.LastStatus = nullStringPtr(lastStatus)
+
+	destination, err := s.open(a.DestinationURL)
+	if err != nil {
+		return nil, err
+	}
+	a.DestinationURL = destination
+	return &a, nil
+}
+
+// scheduleDays converts a WeeklySchedule's days for a schedule_days column.
+func scheduleDays(days []int) pq.Int64Array {
+	out := make(pq.Int64Array, len(days))
+	for i, day := range days {
+		out[i] = int64(day)
+	}
+	return out
+}
+
+// CountAutomations returns how many automations the user has defined.
+func (s *Store) CountAutomations(ctx context.Context, userID int64) (int, error) {
+	if s == nil || s.db == nil {
+		return 0, errors.New("store: db cannot be nil")
+	}
+
+	var n int
+	if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM jira_automations WHERE user_id = $1`, userID).Scan(&n); err != nil {
+		return 0, fmt.Errorf("store: count automations: %w", err)
+	}
+	return n, nil
+}
+
+// CreateAutomation stores a validated automation for a.UserID, scheduled
+// to first run at a.NextRunAt.
+func (s *Store) CreateAutomation(ctx context.Context, a *models.JiraAutomation) (*models.JiraAutomation, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	destination, err := s.seal(a.DestinationURL)
+	if err != nil {
+		return nil, err
+	}
+	row := s.db.QueryRowContext(ctx, `
+		INSERT INTO jira_automations (user_id, name, jql, schedule_days, schedule_time, time_zone,
+			destination, destination_url, max_results, enabled, next_run_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		RETURNING `+automationColumns,
+		a.UserID, a.Name, a.JQL, scheduleDays(a.Days), a.TimeOfDay, a.TimeZone,
+		a.Destination, destination, a.MaxResults, a.Enabled, a.NextRunAt)
+	created, err := s.scanAutomation(row)
+	if err != nil {
+		return nil, fmt.Errorf("store: create automation: %w", err)
+	}
+	return created, nil
+}
+
+// ListAutomations returns the user's automations, oldest first.
+func (s *Store) ListAutomations(ctx context.Context, userID int64) ([]models.JiraAutomation, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+automationColumns+`
+		FROM jira_automations
+		WHERE user_id = $1
+		ORDER BY id
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("store: list automations: %w", err)
+	}
+	defer rows.Close()
+
+	var automations []models.JiraAutomation
+	for rows.Next() {
+		a, err := s.scanAutomation(rows)
+		if err != nil {
+			return nil, fmt.Errorf("store: scan automation: %w", err)
+		}
+		automations = append(automations, *a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("store: iterate automations: %w", err)
+	}
+	return automations, nil
+}
+
+// GetAutomation returns one of the user's automations.
+func (s *Store) GetAutomation(ctx context.Context, userID, id int64) (*models.JiraAutomation, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	row := s.db.QueryRowContext(ctx, `
+		SELECT `+automationColumns+`
+		FROM jira_automations
+		WHERE id = $1 AND user_id = $2
+	`, id, userID)
+	a, err := s.scanAutomation(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrAutomationNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("store: get automation: %w", err)
+	}
+	return a, nil
+}
+
+// UpdateAutomation replaces the definition of one of a.UserID's automations.
+// Re-enabling an automation clears its failure count.
+func (s *Store) UpdateAutomation(ctx context.Context, a *models.JiraAutomation) (*models.JiraAutomation, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	destination, err := s.seal(a.DestinationURL)
+	if err != nil {
+		return nil, err
+	}
+	row := s.db.QueryRowContext(ctx, `
+		UPDATE jira_automations
+		SET name = $3, jql = $4, schedule_days = $5, schedule_time = $6, time_zone = $7,
+			destination = $8, destination_url = $9, max_results = $10, next_run_at = $12,
+			consecutive_failures = CASE WHEN $11 AND NOT enabled THEN 0 ELSE consecutive_failures END,
+			enabled = $11, updated_at = now()
+		WHERE id = $1 AND user_id = $2
+		RETURNING `+automationColumns,
+		a.ID, a.UserID, a.Name, a.JQL, scheduleDays(a.Days), a.TimeOfDay, a.TimeZone,
+		a.Destination, destination, a.MaxResults, a.Enabled, a.NextRunAt)
+	updated, err := s.scanAutomation(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrAutomationNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("store: update automation: %w", err)
+	}
+	return updated, nil
+}
+
+// DeleteAutomation removes one of the user's automations and its runs.
+func (s *Store) DeleteAutomation(ctx context.Context, userID, id int64) error {
+	if s == nil || s.db == nil {
+		return errors.New("store: db cannot be nil")
+	}
+
+	res, err := s.db.ExecContext(ctx, `DELETE FROM jira_automations WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return fmt.Errorf("store: delete automation: %w", err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrAutomationNotFound
+	}
+	return nil
+}
+
+// ListDueAutomations returns up to limit enabled automations whose next
+// run is at or before now.
+func (s *Store) ListDueAutomations(ctx context.Context, now time.Time, limit int) ([]models.JiraAutomation, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+automationColumns+`
+		FROM jira_automations
+		WHERE enabled AND next_run_at <= $1
+		ORDER BY next_run_at
+		LIMIT $2
+	`, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("store: list due automations: %w", err)
+	}
+	defer rows.Close()
+
+	var automations []models.JiraAutomation
+	for rows.Next() {
+		a, err := s.scanAutomation(rows)
+		if err != nil {
+			return nil, fmt.Errorf("store: scan due automation: %w", err)
+		}
+		automations = append(automations, *a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("store: iterate due automations: %w", err)
+	}
+	return automations, nil
+}
+
+// ScheduleAutomation moves an automation's next run from due to next. It
+// reports false when the automation was rescheduled in the meantime, so a
+// due run is only queued once.
+func (s *Store) ScheduleAutomation(ctx context.Context, id int64, due, next time.Time) (bool, error) {
+	if s == nil || s.db == nil {
+		return false, errors.New("store: db cannot be nil")
+	}
+
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE jira_automations SET next_run_at = $3
+		WHERE id = $1 AND next_run_at = $2
+	`, id, due, next)
+	if err != nil {
+		return false, fmt.Errorf("store: schedule automation: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("store: schedule automation: %w", err)
+	}
+	return n > 0, nil
+}
+
+// GetAutomationForRun returns an automation with its owner's MCP secret and
+// email, which running it needs.
+func (s *Store) GetAutomationForRun(ctx context.Context, id int64) (*models.AutomationRunTarget, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	var (
+		secret sql.NullString
+		email  sql.NullString
+	)
+	row := s.db.QueryRowContext(ctx, `
+		SELECT a.id, a.user_id, a.name, a.jql, a.schedule_days, a.schedule_time, a.time_zone,
+			a.destination, a.destination_url, a.max_results, a.enabled, a.next_run_at, a.last_run_at,
+			a.last_status, a.consecutive_failures, a.created_at, a.updated_at, u.mcp_secret, u.email
+		FROM jira_automations a
+		JOIN users u ON u.id = a.user_id
+		WHERE a.id = $1 AND u.deleted_at IS NULL
+	`, id)
+	a, err := s.scanAutomation(scanFunc(func(dest ...any) error {
+		return row.Scan(append(dest, &secret, &email)...)
+	}))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrAutomationNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("store: get automation for run: %w", err)
+	}
+	return &models.AutomationRunTarget{Automation: *a, MCPSecret: secret.String, Email: email.String}, nil
+}
+
+// scanFunc adapts a function to the Scan method scanners expect.
+type scanFunc func(dest ...any) error
+
+func (f scanFunc) Scan(dest ...any) error { return f(dest...) }
+
+// RecordAutomationRun stores the outcome of a run and updates the
+// automation's status. Failures in a row are counted and the automation is
+// disabled once models.MaxAutomationFailures is reached. It returns the
+// failure count after the run and whether the automation was disabled.
+func (s *Store) RecordAutomationRun(ctx context.Context, run *models.JiraAutomationRun) (failures int, disabled bool, err error) {
+	if s == nil || s.db == nil {
+		return 0, false, errors.New("store: db cannot be nil")
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, false, fmt.Errorf("store: begin automation run: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := tx.QueryRowContext(ctx, `
+		INSERT INTO jira_automation_runs (automation_id, status, issue_count, error, started_at, finished_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id
+	`, run.AutomationID, run.Status, run.IssueCount, run.Error, run.StartedAt, run.FinishedAt).Scan(&run.ID); err != nil {
+		return 0, false, fmt.Errorf("store: insert automation run: %w", err)
+	}
+
+	var enabled bool
+	if err := tx.QueryRowContext(ctx, `
+		UPDATE jira_automations
+		SET last_run_at = $2, last_status = $3,
+			consecutive_failures = CASE WHEN $3 = 'failed' THEN consecutive_failures + 1 ELSE 0 END,
+			enabled = enabled AND (CASE WHEN $3 = 'failed' THEN consecutive_failures + 1 ELSE 0 END) < $4,
+			updated_at = now()
+		WHERE id = $1
+		RETURNING consecutive_failures, enabled
+	`, run.AutomationID, run.StartedAt, run.Status, models.MaxAutomationFailures).Scan(&failures, &enabled); err != nil {
+		return 0, false, fmt.Errorf("store: update automation status: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, false, fmt.Errorf("store: commit automation run: %w", err)
+	}
+	return failures, !enabled && failures >= models.MaxAutomationFailures, nil
+}
+
+// ListAutomationRuns returns the latest runs of one of the user's
+// automations, newest first.
+func (s *Store) ListAutomationRuns(ctx context.Context, userID, automationID int64, limit int) ([]models.JiraAutomationRun, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT r.id, r.automation_id, r.status, r.issue_count, r.error, r.started_at, r.finished_at
+		FROM jira_automation_runs r
+		JOIN jira_automations a ON a.id = r.automation_id
+		WHERE r.automation_id = $1 AND a.user_id = $2
+		ORDER BY r.started_at DESC, r.id DESC
+		LIMIT $3
+	`, automationID, userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("store: list automation runs: %w", err)
+	}
+	defer rows.Close()
+
+	var runs []models.JiraAutomationRun
+	for rows.Next() {
+		var (
+			run     models.JiraAutomationRun
+			message sql.NullString
+		)
+		if err := rows.Scan(&run.ID, &run.AutomationID, &run.Status, &run.IssueCount, &message, &run.StartedAt, &run.FinishedAt); err != nil {
+			return nil, fmt.Errorf("store: scan automation run: %w", err)
+		}
+		run.Error = nullStringPtr(message)
+		runs = append(runs, run)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("store: iterate automation runs: %w", err)
+	}
+	return runs, nil
+}
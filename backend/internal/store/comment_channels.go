@@ -0,0 +1,359 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+)
+
+// ErrCommentChannelNotFound is returned when a comment channel does not
+// exist or belongs to another user.
+var ErrCommentChannelNotFound = errors.New("comment channel not found")
+
+const commentChannelColumns = `c.id, c.user_id, c.issue_key, c.kind, c.slack_channel, c.slack_thread_ts,
+	c.email_address, c.reply_token, c.enabled, c.last_synced_at, c.last_error, c.created_at, c.updated_at`
+
+// scanCommentChannel reads a channel selected with commentChannelColumns.
+func scanCommentChannel(row interface{ Scan(...any) error }) (*models.CommentChannel, error) {
+	var (
+		c                             models.CommentChannel
+		slackChannel, threadTS, email sql.NullString
+		lastError                     sql.NullString
+		lastSynced                    sql.NullTime
+	)
+	if err := row.Scan(&c.ID, &c.UserID, &c.IssueKey, &c.Kind, &slackChannel, &threadTS,
+		&email, &c.ReplyToken, &c.Enabled, &lastSynced, &lastError, &c.CreatedAt, &c.UpdatedAt); err != nil {
+		return nil, err
+	}
+	c.SlackChannel, c.SlackThreadTS, c.EmailAddress = slackChannel.String, threadTS.String, email.String
+	if lastSynced.Valid {
+		c.LastSyncedAt = &lastSynced.Time
+	}
+	c.LastError = nullStringPtr(lastError)
+	return &c, nil
+}
+
+// CountCommentChannels returns how many comment channels the user has.
+func (s *Store) CountCommentChannels(ctx context.Context, userID int64) (int, error) {
+	if s == nil || s.db == nil {
+		return 0, errors.New("store: db cannot be nil")
+	}
+
+	var count int
+	if err := s.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM issue_comment_channels WHERE user_id = $1
+	`, userID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("store: count comment channels: %w", err)
+	}
+	return count, nil
+}
+
+// SaveCommentChannel creates the issue's channel of c's kind or replaces
+// its settings, clearing the last error. A replaced channel keeps its reply
+// token, so reply addresses already handed out keep working.
+func (s *Store) SaveCommentChannel(ctx context.Context, c *models.CommentChannel) (*models.CommentChannel, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	token, err := randomHex(16)
+	if err != nil {
+		return nil, fmt.Errorf("store: generate reply token: %w", err)
+	}
+	row := s.db.QueryRowContext(ctx, `
+		INSERT INTO issue_comment_channels AS c (user_id, issue_key, kind, slack_channel, slack_thread_ts,
+			email_address, reply_token, enabled)
+		VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''), NULLIF($6, ''), $7, $8)
+		ON CONFLICT (user_id, issue_key, kind) DO UPDATE
+		SET slack_channel = EXCLUDED.slack_channel,
+			slack_thread_ts = CASE WHEN c.slack_channel IS NOT DISTINCT FROM EXCLUDED.slack_channel
+				THEN COALESCE(EXCLUDED.slack_thread_ts, c.slack_thread_ts) ELSE EXCLUDED.slack_thread_ts END,
+			email_address = EXCLUDED.email_address,
+			enabled = EXCLUDED.enabled,
+			last_error = NULL,
+			updated_at = now()
+		RETURNING `+commentChannelColumns,
+		c.UserID, c.IssueKey, c.Kind, c.SlackChannel, c.SlackThreadTS, c.EmailAddress, token, c.Enabled)
+	saved, err := scanCommentChannel(row)
+	if err != nil {
+		return nil, fmt.Errorf("store: save comment channel: %w", err)
+	}
+	return saved, nil
+}
+
+// ListCommentChannels returns the user's comment channels by issue.
+func (s *Store) ListCommentChannels(ctx context.Context, userID int64) ([]models.CommentChannel, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+commentChannelColumns+`
+		FROM issue_comment_channels c
+		WHERE c.user_id = $1
+		ORDER BY c.issue_key, c.kind
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("store: list comment channels: %w", err)
+	}
+	defer rows.Close()
+
+	var channels []models.CommentChannel
+	for rows.Next() {
+		c, err := scanCommentChannel(rows)
+		if err != nil {
+			return nil, fmt.Errorf("store: scan comment channel: %w", err)
+		}
+		channels = append(channels, *c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("store: iterate comment channels: %w", err)
+	}
+	return channels, nil
+}
+
+// DeleteCommentChannel removes one of the user's comment channels.
+func (s *Store) DeleteCommentChannel(ctx context.Context, userID, id int64) error {
+	if s == nil || s.db == nil {
+		return errors.New("store: db cannot be nil")
+	}
+
+	res, err := s.db.ExecContext(ctx, `
+		DELETE FROM issue_comment_channels WHERE id = $1 AND user_id = $2
+	`, id, userID)
+	if err != nil {
+		return fmt.Errorf("store: delete comment channel: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrCommentChannelNotFound
+	}
+	return nil
+}
+
+// listCommentSyncTargets runs a query selecting commentChannelColumns plus
+// the owner's MCP secret and email.
+func (s *Store) listCommentSyncTargets(ctx context.Context, query string, args ...any) ([]models.CommentSyncTarget, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var targets []models.CommentSyncTarget
+	for rows.Next() {
+		var secret, email sql.NullString
+		c, err := scanCommentChannel(scanFunc(func(dest ...any) error {
+			return rows.Scan(append(dest, &secret, &email)...)
+		}))
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, models.CommentSyncTarget{Channel: *c, MCPSecret: secret.String, Email: email.String})
+	}
+	return targets, rows.Err()
+}
+
+// ListIssueCommentSyncTargets returns the enabled channels of one of the
+// user's issues.
+func (s *Store) ListIssueCommentSyncTargets(ctx context.Context, userID int64, issueKey string) ([]models.CommentSyncTarget, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	targets, err := s.listCommentSyncTargets(ctx, `
+		SELECT `+commentChannelColumns+`, u.mcp_secret, u.email
+		FROM issue_comment_channels c
+		JOIN users u ON u.id = c.user_id
+		WHERE c.user_id = $1 AND c.issue_key = $2 AND c.enabled AND u.deleted_at IS NULL
+		ORDER BY c.id
+	`, userID, issueKey)
+	if err != nil {
+		return nil, fmt.Errorf("store: list issue comment channels: %w", err)
+	}
+	return targets, nil
+}
+
+// ListSlackCommentChannels returns the enabled channels synced with a Slack
+// thread.
+func (s *Store) ListSlackCommentChannels(ctx context.Context, slackChannel, threadTS string) ([]models.CommentChannel, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+commentChannelColumns+`
+		FROM issue_comment_channels c
+		WHERE c.kind = 'slack' AND c.slack_channel = $1 AND c.slack_thread_ts = $2 AND c.enabled
+		ORDER BY c.id
+	`, slackChannel, threadTS)
+	if err != nil {
+		return nil, fmt.Errorf("store: list slack comment channels: %w", err)
+	}
+	defer rows.Close()
+
+	var channels []models.CommentChannel
+	for rows.Next() {
+		c, err := scanCommentChannel(rows)
+		if err != nil {
+			return nil, fmt.Errorf("store: scan comment channel: %w", err)
+		}
+		channels = append(channels, *c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("store: iterate slack comment channels: %w", err)
+	}
+	return channels, nil
+}
+
+// GetEmailCommentChannel returns the enabled email channel a reply token
+// belongs to.
+func (s *Store) GetEmailCommentChannel(ctx context.Context, replyToken string) (*models.CommentChannel, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	c, err := scanCommentChannel(s.db.QueryRowContext(ctx, `
+		SELECT `+commentChannelColumns+`
+		FROM issue_comment_channels c
+		WHERE c.kind = 'email' AND c.reply_token = $1 AND c.enabled
+	`, replyToken))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrCommentChannelNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("store: get email comment channel: %w", err)
+	}
+	return c, nil
+}
+
+// GetCommentSyncTarget returns a channel with its owner's MCP secret and
+// email.
+func (s *Store) GetCommentSyncTarget(ctx context.Context, id int64) (*models.CommentSyncTarget, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	targets, err := s.listCommentSyncTargets(ctx, `
+		SELECT `+commentChannelColumns+`, u.mcp_secret, u.email
+		FROM issue_comment_channels c
+		JOIN users u ON u.id = c.user_id
+		WHERE c.id = $1 AND u.deleted_at IS NULL
+	`, id)
+	if err != nil {
+		return nil, fmt.Errorf("store: get comment channel: %w", err)
+	}
+	if len(targets) == 0 {
+		return nil, ErrCommentChannelNotFound
+	}
+	return &targets[0], nil
+}
+
+// SetCommentChannelThread records the Slack thread a channel started with
+// its first message, unless it already has one.
+func (s *Store) SetCommentChannelThread(ctx context.Context, id int64, threadTS string) error {
+	if s == nil || s.db == nil {
+		return errors.New("store: db cannot be nil")
+	}
+
+	if _, err := s.db.ExecContext(ctx, `
+		UPDATE issue_comment_channels
+		SET slack_thread_ts = $2, updated_at = now()
+		WHERE id = $1 AND slack_thread_ts IS NULL
+	`, id, threadTS); err != nil {
+		return fmt.Errorf("store: set comment channel thread: %w", err)
+	}
+	return nil
+}
+
+// RecordCommentChannelSync records the outcome of syncing a comment through
+// a channel; message is nil on success.
+func (s *Store) RecordCommentChannelSync(ctx context.Context, id int64, at time.Time, message *string) error {
+	if s == nil || s.db == nil {
+		return errors.New("store: db cannot be nil")
+	}
+
+	if _, err := s.db.ExecContext(ctx, `
+		UPDATE issue_comment_channels
+		SET last_synced_at = CASE WHEN $3::text IS NULL THEN $2 ELSE last_synced_at END,
+			last_error = $3
+		WHERE id = $1
+	`, id, at, message); err != nil {
+		return fmt.Errorf("store: record comment channel sync: %w", err)
+	}
+	return nil
+}
+
+// ClaimCommentSync marks a comment as being synced through a channel in one
+// direction. It reports false when the comment was already claimed, so each
+// comment is synced once even when jobs are retried or run concurrently.
+func (s *Store) ClaimCommentSync(ctx context.Context, channelID int64, direction, externalID string) (bool, error) {
+	if s == nil || s.db == nil {
+		return false, errors.New("store: db cannot be nil")
+	}
+
+	res, err := s.db.ExecContext(ctx, `
+		INSERT INTO issue_comment_sync_log (channel_id, direction, external_id)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (channel_id, direction, external_id) DO NOTHING
+	`, channelID, direction, externalID)
+	if err != nil {
+		return false, fmt.Errorf("store: claim comment sync: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// ReleaseCommentSync drops a claim whose sync failed so a retry can take
+// it again.
+func (s *Store) ReleaseCommentSync(ctx context.Context, channelID int64, direction, externalID string) error {
+	if s == nil || s.db == nil {
+		return errors.New("store: db cannot be nil")
+	}
+
+	if _, err := s.db.ExecContext(ctx, `
+		DELETE FROM issue_comment_sync_log WHERE channel_id = $1 AND direction = $2 AND external_id = $3
+	`, channelID, direction, externalID); err != nil {
+		return fmt.Errorf("store: release comment sync: %w", err)
+	}
+	return nil
+}
+
+// SetCommentSyncJiraComment records the Jira comment an inbound message
+// was added as.
+func (s *Store) SetCommentSyncJiraComment(ctx context.Context, channelID int64, externalID, jiraCommentID string) error {
+	if s == nil || s.db == nil {
+		return errors.New("store: db cannot be nil")
+	}
+
+	if _, err := s.db.ExecContext(ctx, `
+		UPDATE issue_comment_sync_log
+		SET jira_comment_id = $3
+		WHERE channel_id = $1 AND direction = 'inbound' AND external_id = $2
+	`, channelID, externalID, jiraCommentID); err != nil {
+		return fmt.Errorf("store: set synced jira comment: %w", err)
+	}
+	return nil
+}
+
+// IsInboundJiraComment reports whether a Jira comment was added from a
+// message in the channel, so it is not posted back there.
+func (s *Store) IsInboundJiraComment(ctx context.Context, channelID int64, jiraCommentID string) (bool, error) {
+	if s == nil || s.db == nil {
+		return false, errors.New("store: db cannot be nil")
+	}
+
+	var exists bool
+	if err := s.db.QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM issue_comment_sync_log
+			WHERE channel_id = $1 AND direction = 'inbound' AND jira_comment_id = $2
+		)
+	`, channelID, jiraCommentID).Scan(&exists); err != nil {
+		return false, fmt.Errorf("store: check synced jira comment: %w", err)
+	}
+	return exists, nil
+}
@@ -0,0 +1,157 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+)
+
+// SetDebugMode enables debug mode for the user identified by email until the
+// given time, or disables it when until is nil.
+func (s *Store) SetDebugMode(ctx context.Context, email string, until *time.Time) error {
+	if s == nil || s.db == nil {
+		return errors.New("store: db cannot be nil")
+	}
+
+	res, err := s.db.ExecContext(ctx,
+		`UPDATE users SET debug_mode_until = $1, updated_at = now() WHERE LOWER(email) = LOWER($2)`,
+		until, email)
+	if err != nil {
+		return fmt.Errorf("store: set debug mode: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("store: no local user found for email=%s", email)
+	}
+
+	return nil
+}
+
+// GetDebugMode returns when debug mode expires for the user identified by
+// email, or nil if it is not currently enabled.
+func (s *Store) GetDebugMode(ctx context.Context, email string) (*time.Time, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	var until sql.NullTime
+	if err := s.db.QueryRowContext(ctx,
+		`SELECT debug_mode_until FROM users WHERE LOWER(email) = LOWER($1) AND deleted_at IS NULL`, email,
+	).Scan(&until); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("store: no local user found for email=%s", email)
+		}
+		return nil, fmt.Errorf("store: get debug mode: %w", err)
+	}
+
+	if !until.Valid || !until.Time.After(time.Now()) {
+		return nil, nil
+	}
+	return &until.Time, nil
+}
+
+// RecordDebugTrace stores a trace for the user when their debug mode is
+// active and reports whether it was recorded. Each user keeps at most keep
+// traces; older and expired ones are removed in the same transaction.
+func (s *Store) RecordDebugTrace(ctx context.Context, trace *models.DebugTrace, keep int) (bool, error) {
+	if s == nil || s.db == nil {
+		return false, errors.New("store: db cannot be nil")
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("store: begin debug trace tx: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	var enabled bool
+	if err := tx.QueryRowContext(ctx,
+		`SELECT COALESCE(debug_mode_until > now(), false) FROM users WHERE id = $1 AND deleted_at IS NULL`, trace.UserID,
+	).Scan(&enabled); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, fmt.Errorf("store: no local user found for id=%d", trace.UserID)
+		}
+		return false, fmt.Errorf("store: check debug mode: %w", err)
+	}
+	if !enabled {
+		return false, nil
+	}
+
+	if err := tx.QueryRowContext(ctx, `
+INSERT INTO debug_traces (user_id, tool, request, response, is_error, duration_ms, expires_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING id, created_at
+`, trace.UserID, trace.Tool, []byte(trace.Request), []byte(trace.Response), trace.IsError, trace.DurationMs, trace.ExpiresAt,
+	).Scan(&trace.ID, &trace.CreatedAt); err != nil {
+		return false, fmt.Errorf("store: insert debug trace: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+DELETE FROM debug_traces
+WHERE user_id = $1
+  AND (expires_at <= now()
+       OR id NOT IN (SELECT id FROM debug_traces WHERE user_id = $1 ORDER BY id DESC LIMIT $2))
+`, trace.UserID, keep); err != nil {
+		return false, fmt.Errorf("store: trim debug traces: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("store: commit debug trace tx: %w", err)
+	}
+
+	return true, nil
+}
+
+// ListDebugTraces returns the unexpired traces for the user identified by
+// email, newest first.
+func (s *Store) ListDebugTraces(ctx context.Context, email string, limit int) ([]models.DebugTrace, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	if limit <= 0 || limit > defaultPageSize {
+		limit = defaultPageSize
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+SELECT t.id, t.tool, t.request, t.response, t.is_error, t.duration_ms, t.created_at, t.expires_at
+FROM debug_traces t
+JOIN users u ON u.id = t.user_id
+WHERE LOWER(u.email) = LOWER($1) AND u.deleted_at IS NULL
+  AND t.expires_at > now()
+ORDER BY t.id DESC
+LIMIT $2
+`, email, limit)
+	if err != nil {
+		return nil, fmt.Errorf("store: list debug traces: %w", err)
+	}
+	defer rows.Close()
+
+	var traces []models.DebugTrace
+	for rows.Next() {
+		var t models.DebugTrace
+		var request, response []byte
+		var duration sql.NullInt64
+		if err := rows.Scan(&t.ID, &t.Tool, &request, &response, &t.IsError, &duration, &t.CreatedAt, &t.ExpiresAt); err != nil {
+			return nil, fmt.Errorf("store: scan debug trace: %w", err)
+		}
+		t.Request = request
+		t.Response = response
+		if duration.Valid {
+			d := int(duration.Int64)
+			t.DurationMs = &d
+		}
+		traces = append(traces, t)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("store: iterate debug traces: %w", err)
+	}
+
+	return traces, nil
+}
@@ -0,0 +1,109 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+)
+
+// ErrUserSuspended is returned when an mcp_secret belongs to a suspended
+// account.
+var ErrUserSuspended = errors.New("store: user is suspended")
+
+// UpsertDispute records a dispute or updates its status, resolving the user
+// from the Stripe customer on first sight. Closed disputes get closed_at.
+func (s *Store) UpsertDispute(ctx context.Context, d *models.Dispute) (*models.Dispute, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	var (
+		out      models.Dispute
+		customer sql.NullString
+		userID   sql.NullInt64
+		closedAt sql.NullTime
+	)
+	err := s.db.QueryRowContext(ctx, `
+		INSERT INTO disputes (stripe_dispute_id, stripe_charge_id, stripe_customer_id, user_id, amount, currency, reason, status, closed_at)
+		VALUES ($1, $2, NULLIF($3, ''),
+		        COALESCE((SELECT id FROM users WHERE stripe_customer_id = NULLIF($3, '') AND deleted_at IS NULL LIMIT 1),
+		                 (SELECT user_id FROM subscriptions WHERE stripe_customer_id = NULLIF($3, '') ORDER BY updated_at DESC LIMIT 1)),
+		        $4, $5, $6, $7, CASE WHEN $8 THEN now() END)
+		ON CONFLICT (stripe_dispute_id) DO UPDATE
+		SET amount = EXCLUDED.amount,
+		    reason = EXCLUDED.reason,
+		    status = EXCLUDED.status,
+		    closed_at = COALESCE(disputes.closed_at, EXCLUDED.closed_at),
+		    updated_at = now()
+		RETURNING id, stripe_dispute_id, stripe_charge_id, stripe_customer_id, user_id, amount, currency, reason, status,
+		          suspended, created_at, updated_at, closed_at
+	`, d.StripeDisputeID, d.StripeChargeID, d.StripeCustomerID, d.Amount, d.Currency, d.Reason, d.Status, d.IsClosed()).Scan(
+		&out.ID, &out.StripeDisputeID, &out.StripeChargeID, &customer, &userID, &out.Amount, &out.Currency, &out.Reason, &out.Status,
+		&out.Suspended, &out.CreatedAt, &out.UpdatedAt, &closedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("store: upsert dispute: %w", err)
+	}
+	out.StripeCustomerID = customer.String
+	if userID.Valid {
+		out.UserID = &userID.Int64
+	}
+	if closedAt.Valid {
+		out.ClosedAt = &closedAt.Time
+	}
+
+	return &out, nil
+}
+
+// SuspendUserForDispute suspends the dispute's user and marks the dispute as
+// the cause. Users already suspended keep their original reason.
+func (s *Store) SuspendUserForDispute(ctx context.Context, disputeID, userID int64) error {
+	if s == nil || s.db == nil {
+		return errors.New("store: db cannot be nil")
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("store: begin suspend for dispute: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `UPDATE disputes SET suspended = TRUE, updated_at = now() WHERE id = $1`, disputeID); err != nil {
+		return fmt.Errorf("store: mark dispute suspended: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE users SET suspended_at = now(), suspended_reason = $2, updated_at = now()
+		WHERE id = $1 AND suspended_at IS NULL
+	`, userID, models.SuspensionReasonDispute); err != nil {
+		return fmt.Errorf("store: suspend user: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("store: commit suspend for dispute: %w", err)
+	}
+	return nil
+}
+
+// ReleaseDisputeSuspension lifts a dispute suspension once none of the
+// user's disputes that caused one is still open. It reports whether the
+// user was unsuspended.
+func (s *Store) ReleaseDisputeSuspension(ctx context.Context, userID int64) (bool, error) {
+	if s == nil || s.db == nil {
+		return false, errors.New("store: db cannot be nil")
+	}
+
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE users SET suspended_at = NULL, suspended_reason = NULL, updated_at = now()
+		WHERE id = $1 AND suspended_reason = $2
+		  AND NOT EXISTS (SELECT 1 FROM disputes WHERE user_id = $1 AND suspended AND closed_at IS NULL)
+	`, userID, models.SuspensionReasonDispute)
+	if err != nil {
+		return false, fmt.Errorf("store: release dispute suspension: %w", err)
+	}
+
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
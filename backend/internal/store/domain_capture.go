@@ -0,0 +1,255 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/events"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+)
+
+// ErrDomainNotFound is returned when the organization has not claimed the
+// domain.
+var ErrDomainNotFound = errors.New("organization domain not found")
+
+// ErrJoinRequestNotFound is returned when a user has no pending request to
+// join the organization.
+var ErrJoinRequestNotFound = errors.New("join request not found")
+
+const organizationDomainColumns = `domain, verification_token, verified_at, last_checked_at, verification_error, created_at`
+
+func scanOrganizationDomain(row interface{ Scan(...any) error }) (*models.OrganizationDomain, error) {
+	var d models.OrganizationDomain
+	if err := row.Scan(&d.Domain, &d.VerificationToken, &d.VerifiedAt, &d.LastCheckedAt,
+		&d.VerificationError, &d.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// GetOrganizationDomain returns a domain claimed by the organization.
+func (s *Store) GetOrganizationDomain(ctx context.Context, orgID int64, domain string) (*models.OrganizationDomain, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	d, err := scanOrganizationDomain(s.db.QueryRowContext(ctx, `
+		SELECT `+organizationDomainColumns+`
+		FROM organization_domains
+		WHERE org_id = $1 AND domain = LOWER($2)
+	`, orgID, domain))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrDomainNotFound
+		}
+		return nil, fmt.Errorf("store: get organization domain: %w", err)
+	}
+
+	return d, nil
+}
+
+// RecordDomainVerification stores the outcome of a DNS verification check.
+// A domain stays verified once it has been verified; checkErr describes why
+// an unverified domain failed its latest check.
+func (s *Store) RecordDomainVerification(ctx context.Context, orgID int64, domain string, verified bool, checkErr string) error {
+	if s == nil || s.db == nil {
+		return errors.New("store: db cannot be nil")
+	}
+
+	if _, err := s.db.ExecContext(ctx, `
+		UPDATE organization_domains
+		SET verified_at = CASE WHEN $3 THEN COALESCE(verified_at, now()) ELSE verified_at END,
+		    last_checked_at = now(),
+		    verification_error = NULLIF($4, '')
+		WHERE org_id = $1 AND domain = LOWER($2)
+	`, orgID, domain, verified, checkErr); err != nil {
+		return fmt.Errorf("store: record domain verification: %w", err)
+	}
+
+	return nil
+}
+
+// RouteUserToCapturedOrganization queues a join request when the user's
+// email domain was verified by an organization before the user signed up
+// and the user is neither a member nor previously routed to it. It returns
+// the organization's id, or 0 when the user was not routed.
+func (s *Store) RouteUserToCapturedOrganization(ctx context.Context, userID int64, email string) (int64, error) {
+	if s == nil || s.db == nil {
+		return 0, errors.New("store: db cannot be nil")
+	}
+
+	email = strings.ToLower(strings.TrimSpace(email))
+	at := strings.LastIndex(email, "@")
+	if at <= 0 || at == len(email)-1 {
+		return 0, nil
+	}
+	domain := email[at+1:]
+
+	var orgID int64
+	err := s.db.QueryRowContext(ctx, `
+		INSERT INTO organization_join_requests (org_id, user_id, domain)
+		SELECT d.org_id, u.id, d.domain
+		FROM organization_domains d
+		JOIN users u ON u.id = $1
+		WHERE d.domain = $2
+		  AND d.verified_at IS NOT NULL
+		  AND u.deleted_at IS NULL
+		  AND u.created_at >= d.verified_at
+		  AND NOT EXISTS (
+			SELECT 1 FROM organization_members m WHERE m.org_id = d.org_id AND m.user_id = u.id
+		  )
+		ON CONFLICT (org_id, user_id) DO NOTHING
+		RETURNING org_id
+	`, userID, domain).Scan(&orgID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("store: route user to captured organization: %w", err)
+	}
+
+	return orgID, nil
+}
+
+// ListOrganizationJoinRequests returns the organization's pending join
+// requests, oldest first.
+func (s *Store) ListOrganizationJoinRequests(ctx context.Context, orgID int64) ([]models.OrganizationJoinRequest, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT u.id, u.email, u.name, j.domain, j.created_at
+		FROM organization_join_requests j
+		JOIN users u ON u.id = j.user_id
+		WHERE j.org_id = $1 AND j.status = 'pending' AND u.deleted_at IS NULL
+		ORDER BY j.created_at
+	`, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("store: list join requests: %w", err)
+	}
+	defer rows.Close()
+
+	var requests []models.OrganizationJoinRequest
+	for rows.Next() {
+		var req models.OrganizationJoinRequest
+		var email sql.NullString
+		if err := rows.Scan(&req.UserID, &email, &req.Name, &req.Domain, &req.CreatedAt); err != nil {
+			return nil, fmt.Errorf("store: scan join request: %w", err)
+		}
+		req.Email = email.String
+		requests = append(requests, req)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("store: iterate join requests: %w", err)
+	}
+
+	return requests, nil
+}
+
+// ListUserJoinRequests returns the organizations the user is waiting to be
+// approved into.
+func (s *Store) ListUserJoinRequests(ctx context.Context, email string) ([]models.Organization, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+organizationColumns+`
+		FROM organizations o
+		JOIN organization_join_requests j ON j.org_id = o.id
+		JOIN users u ON u.id = j.user_id
+		WHERE LOWER(u.email) = LOWER($1) AND u.deleted_at IS NULL AND j.status = 'pending'
+		ORDER BY o.name
+	`, email)
+	if err != nil {
+		return nil, fmt.Errorf("store: list user join requests: %w", err)
+	}
+	defer rows.Close()
+
+	var orgs []models.Organization
+	for rows.Next() {
+		org, err := scanOrganization(rows)
+		if err != nil {
+			return nil, fmt.Errorf("store: scan organization: %w", err)
+		}
+		orgs = append(orgs, *org)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("store: iterate user join requests: %w", err)
+	}
+
+	return orgs, nil
+}
+
+// ApproveOrganizationJoinRequest adds the requesting user to the
+// organization as a member.
+func (s *Store) ApproveOrganizationJoinRequest(ctx context.Context, orgID, userID int64) error {
+	if s == nil || s.db == nil {
+		return errors.New("store: db cannot be nil")
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("store: begin approve join request tx: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	res, err := tx.ExecContext(ctx, `
+		UPDATE organization_join_requests SET status = 'approved', decided_at = now()
+		WHERE org_id = $1 AND user_id = $2 AND status = 'pending'
+	`, orgID, userID)
+	if err != nil {
+		return fmt.Errorf("store: approve join request: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrJoinRequestNotFound
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO organization_members (org_id, user_id, role) VALUES ($1, $2, $3)
+		ON CONFLICT (org_id, user_id) DO NOTHING
+	`, orgID, userID, models.OrgRoleMember); err != nil {
+		return fmt.Errorf("store: add approved member: %w", err)
+	}
+
+	if err := s.enqueueEvent(ctx, tx, events.MembershipChanged{
+		OrgID: orgID, UserID: userID, Change: events.MembershipAdded, Source: "domain",
+	}); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("store: commit approve join request tx: %w", err)
+	}
+
+	s.wakeOutbox()
+
+	return nil
+}
+
+// RejectOrganizationJoinRequest declines a pending join request. The user
+// is not routed to the organization again.
+func (s *Store) RejectOrganizationJoinRequest(ctx context.Context, orgID, userID int64) error {
+	if s == nil || s.db == nil {
+		return errors.New("store: db cannot be nil")
+	}
+
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE organization_join_requests SET status = 'rejected', decided_at = now()
+		WHERE org_id = $1 AND user_id = $2 AND status = 'pending'
+	`, orgID, userID)
+	if err != nil {
+		return fmt.Errorf("store: reject join request: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrJoinRequestNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,147 @@
+package store
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrEmailUnverified is returned when a login with an unverified email would
+// merge into an account whose email has been verified.
+var ErrEmailUnverified = errors.New("email is not verified")
+
+// ErrEmailAlreadyVerified is returned when requesting a verification link
+// for an email that no longer needs one.
+var ErrEmailAlreadyVerified = errors.New("email already verified")
+
+// ErrVerificationTokenInvalid is returned for unknown, used or expired email
+// verification tokens.
+var ErrVerificationTokenInvalid = errors.New("email verification token is invalid or expired")
+
+// ErrUserNotFound is returned when no user has the requested email.
+var ErrUserNotFound = errors.New("user not found")
+
+func markEmailVerified(ctx context.Context, tx *sql.Tx, userID int64) error {
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE users SET email_verified_at = COALESCE(email_verified_at, now()) WHERE id = $1
+	`, userID); err != nil {
+		return fmt.Errorf("store: mark email verified: %w", err)
+	}
+	return nil
+}
+
+func hashVerificationToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// EmailVerificationStatus returns the user that owns email and whether the
+// address has been verified.
+func (s *Store) EmailVerificationStatus(ctx context.Context, email string) (int64, bool, error) {
+	if s == nil || s.db == nil {
+		return 0, false, errors.New("store: db cannot be nil")
+	}
+
+	var userID int64
+	var verified bool
+	err := s.db.QueryRowContext(ctx, `
+		SELECT id, email_verified_at IS NOT NULL
+		FROM users
+		WHERE LOWER(email) = LOWER($1) AND deleted_at IS NULL
+		ORDER BY email_verified_at IS NULL, id
+		LIMIT 1
+	`, email).Scan(&userID, &verified)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, false, ErrUserNotFound
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("store: get email verification status: %w", err)
+	}
+	return userID, verified, nil
+}
+
+// CreateEmailVerification issues a single-use token that verifies email for
+// the user until ttl elapses. Only the token's hash is stored.
+func (s *Store) CreateEmailVerification(ctx context.Context, userID int64, email string, ttl time.Duration) (string, error) {
+	if s == nil || s.db == nil {
+		return "", errors.New("store: db cannot be nil")
+	}
+
+	var pending bool
+	if err := s.db.QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM users
+			WHERE id = $1 AND LOWER(email) = LOWER($2) AND email_verified_at IS NULL AND deleted_at IS NULL
+		)
+	`, userID, email).Scan(&pending); err != nil {
+		return "", fmt.Errorf("store: check email verification: %w", err)
+	}
+	if !pending {
+		return "", ErrEmailAlreadyVerified
+	}
+
+	token, err := randomHex(32)
+	if err != nil {
+		return "", fmt.Errorf("store: generate email verification token: %w", err)
+	}
+	if _, err := s.db.ExecContext(ctx, `
+		INSERT INTO email_verification_tokens (user_id, email, token_hash, expires_at)
+		VALUES ($1, LOWER($2), $3, now() + make_interval(secs => $4))
+	`, userID, email, hashVerificationToken(token), ttl.Seconds()); err != nil {
+		return "", fmt.Errorf("store: insert email verification token: %w", err)
+	}
+	return token, nil
+}
+
+// ConfirmEmailVerification consumes token and marks its email verified,
+// returning the verified address. The user's email must not have changed
+// since the token was issued.
+func (s *Store) ConfirmEmailVerification(ctx context.Context, token string) (string, error) {
+	if s == nil || s.db == nil {
+		return "", errors.New("store: db cannot be nil")
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", fmt.Errorf("store: begin confirm email verification tx: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	var userID int64
+	var email string
+	err = tx.QueryRowContext(ctx, `
+		UPDATE email_verification_tokens
+		SET consumed_at = now()
+		WHERE token_hash = $1 AND consumed_at IS NULL AND expires_at > now()
+		RETURNING user_id, email
+	`, hashVerificationToken(token)).Scan(&userID, &email)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrVerificationTokenInvalid
+	}
+	if err != nil {
+		return "", fmt.Errorf("store: consume email verification token: %w", err)
+	}
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE users
+		SET email_verified_at = COALESCE(email_verified_at, now()), updated_at = now()
+		WHERE id = $1 AND LOWER(email) = $2
+	`, userID, email)
+	if err != nil {
+		return "", fmt.Errorf("store: verify user email: %w", err)
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return "", ErrVerificationTokenInvalid
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", fmt.Errorf("store: commit confirm email verification tx: %w", err)
+	}
+	return email, nil
+}
@@ -0,0 +1,147 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+)
+
+// ErrFeatureFlagNotFound is returned when no feature flag has the slug.
+var ErrFeatureFlagNotFound = errors.New("feature flag not found")
+
+const featureFlagColumns = `slug, description, enabled, percent, email_domains, created_at, updated_at`
+
+func scanFeatureFlag(row interface{ Scan(...any) error }) (*models.FeatureFlag, error) {
+	var f models.FeatureFlag
+	if err := row.Scan(&f.Slug, &f.Description, &f.Enabled, &f.Percent, pq.Array(&f.EmailDomains), &f.CreatedAt, &f.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if f.EmailDomains == nil {
+		f.EmailDomains = []string{}
+	}
+	return &f, nil
+}
+
+// PutFeatureFlag creates the flag or replaces its settings, filling in the
+// stored timestamps. Putting a flag that is already as requested writes
+// nothing; it reports whether anything changed.
+func (s *Store) PutFeatureFlag(ctx context.Context, flag *models.FeatureFlag) (bool, error) {
+	if s == nil || s.db == nil {
+		return false, errors.New("store: db cannot be nil")
+	}
+
+	err := s.db.QueryRowContext(ctx, `
+		INSERT INTO feature_flags (slug, description, enabled, percent, email_domains)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (slug) DO UPDATE
+		SET description = EXCLUDED.description,
+		    enabled = EXCLUDED.enabled,
+		    percent = EXCLUDED.percent,
+		    email_domains = EXCLUDED.email_domains,
+		    updated_at = now()
+		WHERE (feature_flags.description, feature_flags.enabled, feature_flags.percent, feature_flags.email_domains)
+		      IS DISTINCT FROM (EXCLUDED.description, EXCLUDED.enabled, EXCLUDED.percent, EXCLUDED.email_domains)
+		RETURNING created_at, updated_at
+	`, flag.Slug, flag.Description, flag.Enabled, flag.Percent, pq.Array(flag.EmailDomains)).Scan(&flag.CreatedAt, &flag.UpdatedAt)
+	if err == nil {
+		return true, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return false, fmt.Errorf("store: put feature flag: %w", err)
+	}
+
+	stored, err := s.GetFeatureFlag(ctx, flag.Slug)
+	if err != nil {
+		return false, err
+	}
+	flag.CreatedAt, flag.UpdatedAt = stored.CreatedAt, stored.UpdatedAt
+	return false, nil
+}
+
+// GetFeatureFlag returns the flag with slug.
+func (s *Store) GetFeatureFlag(ctx context.Context, slug string) (*models.FeatureFlag, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	flag, err := scanFeatureFlag(s.db.QueryRowContext(ctx,
+		`SELECT `+featureFlagColumns+` FROM feature_flags WHERE slug = $1`, slug))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrFeatureFlagNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("store: get feature flag: %w", err)
+	}
+	return flag, nil
+}
+
+// ListFeatureFlags returns every feature flag ordered by slug.
+func (s *Store) ListFeatureFlags(ctx context.Context) ([]models.FeatureFlag, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	rows, err := s.db.QueryContext(ctx, `SELECT `+featureFlagColumns+` FROM feature_flags ORDER BY slug`)
+	if err != nil {
+		return nil, fmt.Errorf("store: list feature flags: %w", err)
+	}
+	defer rows.Close()
+
+	var flags []models.FeatureFlag
+	for rows.Next() {
+		flag, err := scanFeatureFlag(rows)
+		if err != nil {
+			return nil, fmt.Errorf("store: scan feature flag: %w", err)
+		}
+		flags = append(flags, *flag)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("store: iterate feature flags: %w", err)
+	}
+	return flags, nil
+}
+
+// DeleteFeatureFlag removes the flag with slug, if any.
+func (s *Store) DeleteFeatureFlag(ctx context.Context, slug string) error {
+	if s == nil || s.db == nil {
+		return errors.New("store: db cannot be nil")
+	}
+
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM feature_flags WHERE slug = $1`, slug); err != nil {
+		return fmt.Errorf("store: delete feature flag: %w", err)
+	}
+	return nil
+}
+
+// ListEnabledFeatureFlags returns the slugs of the flags that are on for
+// the user.
+func (s *Store) ListEnabledFeatureFlags(ctx context.Context, userID int64) ([]string, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	var email sql.NullString
+	if err := s.db.QueryRowContext(ctx, `SELECT email FROM users WHERE id = $1 AND deleted_at IS NULL`, userID).Scan(&email); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("store: lookup feature flag user: %w", err)
+	}
+
+	flags, err := s.ListFeatureFlags(ctx)
+	if err != nil {
+		return nil, err
+	}
+	enabled := []string{}
+	for _, flag := range flags {
+		if flag.EnabledFor(userID, email.String) {
+			enabled = append(enabled, flag.Slug)
+		}
+	}
+	return enabled, nil
+}
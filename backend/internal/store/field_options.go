@@ -0,0 +1,178 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+)
+
+// maxFieldOptionProjects caps the projects whose field options are synced
+// per tenant.
+const maxFieldOptionProjects = 20
+
+// ReplaceJiraFieldOptions replaces the select field options stored for a
+// project on the user's Jira site with options.
+func (s *Store) ReplaceJiraFieldOptions(ctx context.Context, userID int64, siteURL, projectKey string, options []models.JiraFieldOption) error {
+	if s == nil || s.db == nil {
+		return errors.New("store: db cannot be nil")
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("store: begin field options: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
+		DELETE FROM jira_field_options WHERE user_id = $1 AND site_url = $2 AND project_key = $3
+	`, userID, siteURL, projectKey); err != nil {
+		return fmt.Errorf("store: delete field options: %w", err)
+	}
+	for _, o := range options {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO jira_field_options (user_id, site_url, project_key, field_id, field_name, multi, option_id, value)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			ON CONFLICT (user_id, site_url, project_key, field_id, option_id) DO NOTHING
+		`, userID, siteURL, projectKey, o.FieldID, o.FieldName, o.Multi, o.OptionID, o.Value); err != nil {
+			return fmt.Errorf("store: insert field option: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("store: commit field options: %w", err)
+	}
+	return nil
+}
+
+// ListJiraFieldOptions returns the select field options synced for a
+// project on the user's Jira site, by field and value.
+func (s *Store) ListJiraFieldOptions(ctx context.Context, userID int64, siteURL, projectKey string) ([]models.JiraFieldOption, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT field_id, field_name, multi, option_id, value, synced_at
+		FROM jira_field_options
+		WHERE user_id = $1 AND site_url = $2 AND project_key = $3
+		ORDER BY field_name, field_id, value
+	`, userID, siteURL, projectKey)
+	if err != nil {
+		return nil, fmt.Errorf("store: list field options: %w", err)
+	}
+	defer rows.Close()
+
+	var options []models.JiraFieldOption
+	for rows.Next() {
+		var o models.JiraFieldOption
+		if err := rows.Scan(&o.FieldID, &o.FieldName, &o.Multi, &o.OptionID, &o.Value, &o.SyncedAt); err != nil {
+			return nil, fmt.Errorf("store: scan field option: %w", err)
+		}
+		options = append(options, o)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("store: iterate field options: %w", err)
+	}
+	return options, nil
+}
+
+// DeleteStaleJiraFieldOptions removes the user's field options last synced
+// before before, such as those of projects no longer in use.
+func (s *Store) DeleteStaleJiraFieldOptions(ctx context.Context, userID int64, before time.Time) error {
+	if s == nil || s.db == nil {
+		return errors.New("store: db cannot be nil")
+	}
+
+	if _, err := s.db.ExecContext(ctx, `
+		DELETE FROM jira_field_options WHERE user_id = $1 AND synced_at < $2
+	`, userID, before); err != nil {
+		return fmt.Errorf("store: delete stale field options: %w", err)
+	}
+	return nil
+}
+
+// ListFieldOptionSyncUsers returns the users with an MCP secret that set a
+// default project or called a tool on a project since since.
+func (s *Store) ListFieldOptionSyncUsers(ctx context.Context, since time.Time, limit int) ([]int64, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT u.id
+		FROM users u
+		WHERE u.mcp_secret IS NOT NULL AND u.mcp_secret <> '' AND u.deleted_at IS NULL
+			AND (
+				EXISTS (SELECT 1 FROM jira_issue_defaults d WHERE d.user_id = u.id AND d.project_key <> '')
+				OR EXISTS (
+					SELECT 1 FROM tool_invocations t
+					WHERE t.user_id = u.id AND t.created_at >= $1 AND t.project_key IS NOT NULL AND t.project_key <> ''
+				)
+			)
+		ORDER BY u.id
+		LIMIT $2
+	`, since, limit)
+	if err != nil {
+		return nil, fmt.Errorf("store: list field option sync users: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("store: scan field option sync user: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("store: iterate field option sync users: %w", err)
+	}
+	return ids, nil
+}
+
+// GetFieldOptionSyncTarget returns the user's MCP secret and the projects to
+// sync field options for: the default project and those the user's tools
+// were called on since since, most recent first.
+func (s *Store) GetFieldOptionSyncTarget(ctx context.Context, userID int64, since time.Time) (*models.FieldOptionSyncTarget, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	var (
+		secret   sql.NullString
+		projects pq.StringArray
+	)
+	err := s.db.QueryRowContext(ctx, `
+		SELECT u.mcp_secret, ARRAY(
+			SELECT p.project_key FROM (
+				SELECT d.project_key, 'infinity'::timestamptz AS used_at
+				FROM jira_issue_defaults d
+				WHERE d.user_id = u.id AND d.project_key <> ''
+				UNION ALL
+				SELECT t.project_key, MAX(t.created_at)
+				FROM tool_invocations t
+				WHERE t.user_id = u.id AND t.created_at >= $2 AND t.project_key IS NOT NULL AND t.project_key <> ''
+				GROUP BY t.project_key
+			) p
+			GROUP BY p.project_key
+			ORDER BY MAX(p.used_at) DESC
+			LIMIT $3
+		)
+		FROM users u
+		WHERE u.id = $1 AND u.deleted_at IS NULL
+	`, userID, since, maxFieldOptionProjects).Scan(&secret, &projects)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("store: get field option sync target: %w", err)
+	}
+	return &models.FieldOptionSyncTarget{UserID: userID, MCPSecret: secret.String, ProjectKeys: projects}, nil
+}
@@ -0,0 +1,288 @@
+package store
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Instrumentation provides hooks for monitoring store queries
+type Instrumentation struct {
+	// OnQuery is called after every statement with the store method that
+	// issued it, such as "Store.GetUserMetrics" or "JobStore.ClaimJob".
+	// Queries last until their rows are closed.
+	OnQuery func(method string, duration time.Duration, err error)
+}
+
+// Instrument returns a connector whose statements are reported to inst,
+// attributed to the store method that ran them. Statements not
+// issued from this package are reported with the method "other".
+func Instrument(connector driver.Connector, inst *Instrumentation) driver.Connector {
+	return &instrumentedConnector{Connector: connector, inst: inst}
+}
+
+// storePackage is the import path prefix of functions in this package.
+var storePackage = func() string {
+	name := runtime.FuncForPC(reflect.ValueOf(Instrument).Pointer()).Name()
+	return strings.TrimSuffix(name, "Instrument")
+}()
+
+// callingMethod names the outermost store method in the run of store
+// functions on the stack that leads to the statement, skipping the
+// instrumentation itself.
+func callingMethod() string {
+	var pcs [64]uintptr
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(3, pcs[:])])
+
+	method, inRun := "", false
+	for {
+		frame, more := frames.Next()
+		name, inStore := strings.CutPrefix(frame.Function, storePackage)
+		switch {
+		case inStore && strings.HasPrefix(name, "(*instrumented"):
+		case inStore:
+			inRun = true
+			if strings.HasPrefix(name, "(*") {
+				method = name
+			}
+		case inRun:
+			more = false
+		}
+		if !more {
+			break
+		}
+	}
+	if method == "" {
+		return "other"
+	}
+
+	// "(*Store).MergeUsers.func1" is reported as "Store.MergeUsers".
+	method = strings.NewReplacer("(*", "", ")", "").Replace(method)
+	if parts := strings.SplitN(method, ".", 3); len(parts) == 3 {
+		method = parts[0] + "." + parts[1]
+	}
+	return method
+}
+
+type instrumentedConnector struct {
+	driver.Connector
+	inst *Instrumentation
+}
+
+func (c *instrumentedConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &instrumentedConn{Conn: conn, inst: c.inst}, nil
+}
+
+// instrumentedConn forwards the optional driver interfaces database/sql
+// relies on.
+type instrumentedConn struct {
+	driver.Conn
+	inst *Instrumentation
+}
+
+func (c *instrumentedConn) observe(method string, start time.Time, err error) {
+	if c.inst != nil && c.inst.OnQuery != nil {
+		c.inst.OnQuery(method, time.Since(start), err)
+	}
+}
+
+func (c *instrumentedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	method := callingMethod()
+	start := time.Now()
+	rows, err := queryer.QueryContext(ctx, query, args)
+	if err != nil {
+		c.observe(method, start, err)
+		return nil, err
+	}
+	return &instrumentedRows{Rows: rows, done: func() { c.observe(method, start, nil) }}, nil
+}
+
+func (c *instrumentedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	execer, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	method := callingMethod()
+	start := time.Now()
+	res, err := execer.ExecContext(ctx, query, args)
+	c.observe(method, start, err)
+	return res, err
+}
+
+func (c *instrumentedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if preparer, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		return preparer.PrepareContext(ctx, query)
+	}
+	return c.Conn.Prepare(query)
+}
+
+func (c *instrumentedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if beginner, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return beginner.BeginTx(ctx, opts)
+	}
+	return c.Conn.Begin()
+}
+
+func (c *instrumentedConn) Ping(ctx context.Context) error {
+	if pinger, ok := c.Conn.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *instrumentedConn) ResetSession(ctx context.Context) error {
+	if resetter, ok := c.Conn.(driver.SessionResetter); ok {
+		return resetter.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *instrumentedConn) IsValid() bool {
+	if validator, ok := c.Conn.(driver.Validator); ok {
+		return validator.IsValid()
+	}
+	return true
+}
+
+type instrumentedRows struct {
+	driver.Rows
+	done   func()
+	closed bool
+}
+
+func (r *instrumentedRows) Close() error {
+	err := r.Rows.Close()
+	if !r.closed {
+		r.closed = true
+		r.done()
+	}
+	return err
+}
+
+// queryDurationBuckets are the upper bounds, in seconds, of the query
+// duration histogram.
+var queryDurationBuckets = []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5}
+
+// QueryMetrics aggregates the statements reported by Instrument per store
+// method. Tests use it to assert how many queries a call makes; the server
+// exposes it to Prometheus.
+type QueryMetrics struct {
+	mu      sync.Mutex
+	methods map[string]*methodMetrics
+}
+
+type methodMetrics struct {
+	queries int64
+	errors  int64
+	seconds float64
+	buckets []int64
+}
+
+// NewQueryMetrics returns empty query metrics.
+func NewQueryMetrics() *QueryMetrics {
+	return &QueryMetrics{methods: make(map[string]*methodMetrics)}
+}
+
+// Observe records one statement of method. It is meant as the OnQuery hook.
+func (m *QueryMetrics) Observe(method string, duration time.Duration, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	mm := m.methods[method]
+	if mm == nil {
+		mm = &methodMetrics{buckets: make([]int64, len(queryDurationBuckets))}
+		m.methods[method] = mm
+	}
+	mm.queries++
+	if err != nil {
+		mm.errors++
+	}
+	seconds := duration.Seconds()
+	mm.seconds += seconds
+	for i, bound := range queryDurationBuckets {
+		if seconds <= bound {
+			mm.buckets[i]++
+		}
+	}
+}
+
+// Queries returns how many statements method has run.
+func (m *QueryMetrics) Queries(method string) int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if mm := m.methods[method]; mm != nil {
+		return mm.queries
+	}
+	return 0
+}
+
+// Errors returns how many statements of method failed.
+func (m *QueryMetrics) Errors(method string) int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if mm := m.methods[method]; mm != nil {
+		return mm.errors
+	}
+	return 0
+}
+
+// Reset forgets all recorded statements.
+func (m *QueryMetrics) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.methods = make(map[string]*methodMetrics)
+}
+
+// WritePrometheus writes the metrics in the Prometheus text exposition
+// format.
+func (m *QueryMetrics) WritePrometheus(w io.Writer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	names := make([]string, 0, len(m.methods))
+	for name := range m.methods {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteString("# HELP store_queries_total Statements run by store method.\n")
+	b.WriteString("# TYPE store_queries_total counter\n")
+	for _, name := range names {
+		fmt.Fprintf(&b, "store_queries_total{method=%q} %d\n", name, m.methods[name].queries)
+	}
+	b.WriteString("# HELP store_query_errors_total Statements that failed, by store method.\n")
+	b.WriteString("# TYPE store_query_errors_total counter\n")
+	for _, name := range names {
+		fmt.Fprintf(&b, "store_query_errors_total{method=%q} %d\n", name, m.methods[name].errors)
+	}
+	b.WriteString("# HELP store_query_duration_seconds Statement duration by store method.\n")
+	b.WriteString("# TYPE store_query_duration_seconds histogram\n")
+	for _, name := range names {
+		mm := m.methods[name]
+		for i, bound := range queryDurationBuckets {
+			fmt.Fprintf(&b, "store_query_duration_seconds_bucket{method=%q,le=\"%g\"} %d\n", name, bound, mm.buckets[i])
+		}
+		fmt.Fprintf(&b, "store_query_duration_seconds_bucket{method=%q,le=\"+Inf\"} %d\n", name, mm.queries)
+		fmt.Fprintf(&b, "store_query_duration_seconds_sum{method=%q} %g\n", name, mm.seconds)
+		fmt.Fprintf(&b, "store_query_duration_seconds_count{method=%q} %d\n", name, mm.queries)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,73 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// dsnConnector opens connections to a named sqlmock database.
+type dsnConnector struct {
+	dsn string
+	drv driver.Driver
+}
+
+func (c dsnConnector) Connect(context.Context) (driver.Conn, error) { return c.drv.Open(c.dsn) }
+func (c dsnConnector) Driver() driver.Driver                        { return c.drv }
+
+func TestInstrumentCountsQueriesPerStoreMethod(t *testing.T) {
+	mockDB, mock, err := sqlmock.NewWithDSN("instrumented")
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	metrics := NewQueryMetrics()
+	db := sql.OpenDB(Instrument(dsnConnector{dsn: "instrumented", drv: mockDB.Driver()}, &Instrumentation{OnQuery: metrics.Observe}))
+	s := &Store{db: db}
+	t.Cleanup(func() {
+		db.Close()
+		mockDB.Close()
+	})
+
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM users_settings us`)).
+		WithArgs("secret").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}))
+	mock.ExpectQuery(regexp.QuoteMeta(`JOIN organization_jira_accounts a`)).
+		WithArgs("secret").
+		WillReturnError(errors.New("connection reset"))
+
+	if _, err := s.GetUserSettingsByMCPSecret(context.Background(), "secret"); err == nil {
+		t.Fatal("expected the failed shared account lookup to be returned")
+	}
+
+	// The shared account lookup is a helper; its query counts for the
+	// exported method that called it.
+	if n := metrics.Queries("Store.GetUserSettingsByMCPSecret"); n != 2 {
+		t.Fatalf("expected 2 queries for GetUserSettingsByMCPSecret, got %d", n)
+	}
+	if n := metrics.Errors("Store.GetUserSettingsByMCPSecret"); n != 1 {
+		t.Fatalf("expected 1 failed query, got %d", n)
+	}
+
+	var out strings.Builder
+	if err := metrics.WritePrometheus(&out); err != nil {
+		t.Fatalf("WritePrometheus: %v", err)
+	}
+	for _, want := range []string{
+		`store_queries_total{method="Store.GetUserSettingsByMCPSecret"} 2`,
+		`store_query_duration_seconds_bucket{method="Store.GetUserSettingsByMCPSecret",le="+Inf"} 2`,
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Fatalf("expected %q in:\n%s", want, out.String())
+		}
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
@@ -0,0 +1,376 @@
+//go:build integration
+
+package store_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/store"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/testutil"
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(testutil.Main(m))
+}
+
+func TestClaimNextJobHandsEachJobToOneWorker(t *testing.T) {
+	db := testutil.NewDB(t)
+	jobs := testutil.NewJobStore(t, db)
+
+	const n = 8
+	for i := 0; i < n; i++ {
+		testutil.EnqueueJob(t, jobs, "integration", models.JobPriorityNormal)
+	}
+
+	var (
+		mu      sync.Mutex
+		claimed = map[int64]string{}
+		wg      sync.WaitGroup
+	)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(worker string) {
+			defer wg.Done()
+			job, err := jobs.ClaimNextJob(context.Background(), worker)
+			if err != nil {
+				t.Errorf("ClaimNextJob(%s): %v", worker, err)
+				return
+			}
+			if job == nil {
+				t.Errorf("ClaimNextJob(%s): no job claimed", worker)
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if other, ok := claimed[job.ID]; ok {
+				t.Errorf("job %d claimed by both %s and %s", job.ID, other, worker)
+			}
+			claimed[job.ID] = worker
+		}(string(rune('a' + i)))
+	}
+	wg.Wait()
+
+	if len(claimed) != n {
+		t.Fatalf("expected %d distinct jobs claimed, got %d", n, len(claimed))
+	}
+	if job, err := jobs.ClaimNextJob(context.Background(), "late"); err != nil || job != nil {
+		t.Fatalf("expected empty queue, got job=%v err=%v", job, err)
+	}
+}
+
+func TestEnqueueDeduplicatesConcurrentDeliveries(t *testing.T) {
+	db := testutil.NewDB(t)
+	jobs := testutil.NewJobStore(t, db)
+
+	const n = 8
+	ids := make([]int64, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			job := &models.Job{
+				JobType:     "webhook",
+				Payload:     models.JSONB{"issue": "PROJ-1", "event": "updated"},
+				Priority:    models.JobPriorityNormal,
+				MaxAttempts: 3,
+				DedupWindow: time.Minute,
+			}
+			if err := jobs.Enqueue(context.Background(), job); err != nil {
+				t.Errorf("Enqueue: %v", err)
+				return
+			}
+			ids[i] = job.ID
+		}(i)
+	}
+	wg.Wait()
+
+	for _, id := range ids[1:] {
+		if id != ids[0] {
+			t.Fatalf("expected every delivery to share job %d, got %v", ids[0], ids)
+		}
+	}
+
+	other := &models.Job{
+		JobType:     "webhook",
+		Payload:     models.JSONB{"issue": "PROJ-2", "event": "updated"},
+		Priority:    models.JobPriorityNormal,
+		MaxAttempts: 3,
+		DedupWindow: time.Minute,
+	}
+	if err := jobs.Enqueue(context.Background(), other); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	if other.Deduplicated || other.ID == ids[0] {
+		t.Fatalf("expected a new job for a different payload, got %+v", other)
+	}
+}
+
+func TestClaimNextJobPrefersHigherPriority(t *testing.T) {
+	db := testutil.NewDB(t)
+	jobs := testutil.NewJobStore(t, db)
+
+	testutil.EnqueueJob(t, jobs, "integration", models.JobPriorityLow)
+	critical := testutil.EnqueueJob(t, jobs, "integration", models.JobPriorityCritical)
+	testutil.EnqueueJob(t, jobs, "integration", models.JobPriorityNormal)
+
+	job, err := jobs.ClaimNextJob(context.Background(), "worker")
+	if err != nil {
+		t.Fatalf("ClaimNextJob: %v", err)
+	}
+	if job == nil || job.ID != critical.ID {
+		t.Fatalf("expected critical job %d first, got %+v", critical.ID, job)
+	}
+	if job.Status != models.JobStatusProcessing || job.Attempts != 1 {
+		t.Fatalf("expected processing job with 1 attempt, got status=%s attempts=%d", job.Status, job.Attempts)
+	}
+}
+
+func TestJobEventsRecordTimeline(t *testing.T) {
+	db := testutil.NewDB(t)
+	jobs := testutil.NewJobStore(t, db)
+	ctx := context.Background()
+
+	enqueued := testutil.EnqueueJob(t, jobs, "integration", models.JobPriorityNormal)
+	if _, err := jobs.ClaimNextJob(ctx, "worker-a"); err != nil {
+		t.Fatalf("ClaimNextJob: %v", err)
+	}
+	if err := jobs.ScheduleRetry(ctx, enqueued.ID, "jira timeout", time.Now().Add(-time.Second)); err != nil {
+		t.Fatalf("ScheduleRetry: %v", err)
+	}
+	if _, err := jobs.ClaimNextJob(ctx, "worker-b"); err != nil {
+		t.Fatalf("ClaimNextJob: %v", err)
+	}
+	if err := jobs.MarkFailed(ctx, enqueued.ID, "jira 500"); err != nil {
+		t.Fatalf("MarkFailed: %v", err)
+	}
+
+	events, err := jobs.ListJobEvents(ctx, enqueued.ID)
+	if err != nil {
+		t.Fatalf("ListJobEvents: %v", err)
+	}
+	want := []struct{ event, worker, err string }{
+		{"enqueued", "", ""},
+		{"claimed", "worker-a", ""},
+		{"retry_scheduled", "worker-a", "jira timeout"},
+		{"claimed", "worker-b", ""},
+		{"failed", "worker-b", "jira 500"},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("expected %d events, got %d", len(want), len(events))
+	}
+	for i, w := range want {
+		e := events[i]
+		var worker, errMsg string
+		if e.WorkerID != nil {
+			worker = *e.WorkerID
+		}
+		if e.Error != nil {
+			errMsg = *e.Error
+		}
+		if e.Event != w.event || worker != w.worker || errMsg != w.err {
+			t.Errorf("event %d: got %s/%q/%q, want %s/%q/%q", i, e.Event, worker, errMsg, w.event, w.worker, w.err)
+		}
+	}
+
+	if _, err := jobs.ListJobEvents(ctx, enqueued.ID+1000); !errors.Is(err, store.ErrJobNotFound) {
+		t.Fatalf("expected ErrJobNotFound for a missing job, got %v", err)
+	}
+}
+
+func TestGitHubEmailVerificationLifecycle(t *testing.T) {
+	db := testutil.NewDB(t)
+	s := testutil.NewStore(t, db)
+	ctx := context.Background()
+
+	owner := testutil.CreateUser(t, s, "")
+	takeover := models.GitHubAuthUser{GitHubID: 9001, Login: "intruder", Email: owner.Email, AccessToken: "tok"}
+	if err := s.UpsertGitHubUser(ctx, takeover); !errors.Is(err, store.ErrEmailUnverified) {
+		t.Fatalf("expected ErrEmailUnverified merging into a verified account, got %v", err)
+	}
+
+	email := "hidden@example.com"
+	if err := s.UpsertGitHubUser(ctx, models.GitHubAuthUser{GitHubID: 9002, Login: "octo", Email: &email, AccessToken: "tok"}); err != nil {
+		t.Fatalf("UpsertGitHubUser: %v", err)
+	}
+	userID, verified, err := s.EmailVerificationStatus(ctx, email)
+	if err != nil || verified {
+		t.Fatalf("expected an unverified account, got verified=%t err=%v", verified, err)
+	}
+
+	token, err := s.CreateEmailVerification(ctx, userID, email, time.Hour)
+	if err != nil {
+		t.Fatalf("CreateEmailVerification: %v", err)
+	}
+	if got, err := s.ConfirmEmailVerification(ctx, token); err != nil || got != email {
+		t.Fatalf("ConfirmEmailVerification = %q, %v", got, err)
+	}
+	if _, verified, _ := s.EmailVerificationStatus(ctx, email); !verified {
+		t.Fatal("expected the email to be verified")
+	}
+	if _, err := s.ConfirmEmailVerification(ctx, token); !errors.Is(err, store.ErrVerificationTokenInvalid) {
+		t.Fatalf("expected a used token to be rejected, got %v", err)
+	}
+	if _, err := s.CreateEmailVerification(ctx, userID, email, time.Hour); !errors.Is(err, store.ErrEmailAlreadyVerified) {
+		t.Fatalf("expected ErrEmailAlreadyVerified, got %v", err)
+	}
+}
+
+func TestMergeUsersFoldsDuplicateIntoTarget(t *testing.T) {
+	db := testutil.NewDB(t)
+	s := testutil.NewStore(t, db)
+	jobs := testutil.NewJobStore(t, db)
+	ctx := context.Background()
+
+	target := testutil.CreateUser(t, s, "")
+	targetSecret := testutil.CreateJiraSettings(t, s, target)
+	source := testutil.CreateUser(t, s, "")
+	sourceSecret := testutil.CreateJiraSettings(t, s, source)
+	if err := s.UpsertUserSettings(ctx, *source.Email, "https://other.atlassian.net", *source.Email, "tok"); err != nil {
+		t.Fatalf("UpsertUserSettings: %v", err)
+	}
+	if _, err := db.ExecContext(ctx, `UPDATE users_settings SET is_default = TRUE WHERE (jira_base_url = 'https://example.atlassian.net' AND user_id = $1) OR jira_base_url = 'https://other.atlassian.net'`, target.ID); err != nil {
+		t.Fatalf("mark defaults: %v", err)
+	}
+	job := &models.Job{JobType: "integration", Payload: models.JSONB{"user_id": source.ID}, MaxAttempts: 1, Metadata: models.JSONB{}}
+	if err := jobs.Enqueue(ctx, job); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+
+	if _, err := s.MergeUsers(ctx, target.ID, target.ID); !errors.Is(err, store.ErrMergeSameUser) {
+		t.Fatalf("expected ErrMergeSameUser, got %v", err)
+	}
+	merge, err := s.MergeUsers(ctx, source.ID, target.ID)
+	if err != nil {
+		t.Fatalf("MergeUsers: %v", err)
+	}
+	if merge.Dropped["users_settings"] != 1 || merge.Moved["users_settings"] != 1 || merge.Moved["jobs"] != 1 {
+		t.Fatalf("unexpected merge counts: moved=%v dropped=%v", merge.Moved, merge.Dropped)
+	}
+
+	settings, err := s.ListUserSettings(ctx, *target.Email)
+	if err != nil {
+		t.Fatalf("ListUserSettings: %v", err)
+	}
+	defaults := 0
+	for _, setting := range settings {
+		if setting.IsDefault {
+			defaults++
+			if setting.JiraBaseURL != "https://example.atlassian.net" {
+				t.Fatalf("expected the target's default site to win, got %s", setting.JiraBaseURL)
+			}
+		}
+	}
+	if len(settings) != 2 || defaults != 1 {
+		t.Fatalf("expected two sites with one default, got %+v", settings)
+	}
+
+	if _, err := s.GetUserSettingsByMCPSecret(ctx, targetSecret); err != nil {
+		t.Fatalf("expected the target to keep its MCP secret: %v", err)
+	}
+	if _, err := s.GetUserSettingsByMCPSecret(ctx, sourceSecret); err == nil {
+		t.Fatal("expected the source's MCP secret to be gone")
+	}
+	reloaded, err := jobs.GetByID(ctx, job.ID)
+	if err != nil || reloaded.OwnerID() != target.ID {
+		t.Fatalf("expected the job to belong to the target, got %+v, %v", reloaded, err)
+	}
+	if _, err := s.GetUserByEmail(ctx, *source.Email); err == nil {
+		t.Fatal("expected the source user to be deleted")
+	}
+}
+
+func TestGetUserSettingsByMCPSecretReadsStoredSettings(t *testing.T) {
+	db := testutil.NewDB(t)
+	s := testutil.NewStore(t, db)
+
+	user := testutil.CreateUser(t, s, "")
+	secret := testutil.CreateJiraSettings(t, s, user)
+
+	settings, err := s.GetUserSettingsByMCPSecret(context.Background(), secret)
+	if err != nil {
+		t.Fatalf("GetUserSettingsByMCPSecret: %v", err)
+	}
+	if settings.JiraBaseURL != "https://example.atlassian.net" || settings.JiraEmail != *user.Email {
+		t.Fatalf("unexpected settings: %+v", settings)
+	}
+}
+
+func TestJiraWebhookDeliveriesAndMirrorOrdering(t *testing.T) {
+	db := testutil.NewDB(t)
+	s := testutil.NewStore(t, db)
+	ctx := context.Background()
+	user := testutil.CreateUser(t, s, "")
+
+	for i, want := range []bool{true, false} {
+		fresh, err := s.RecordJiraWebhookDelivery(ctx, user.ID, "delivery-1")
+		if err != nil {
+			t.Fatalf("RecordJiraWebhookDelivery: %v", err)
+		}
+		if fresh != want {
+			t.Fatalf("delivery %d: expected fresh=%v, got %v", i, want, fresh)
+		}
+	}
+
+	apply := func(timestamp int64, status string) bool {
+		t.Helper()
+		applied, err := s.ApplyJiraIssueState(ctx, &models.JiraIssueState{
+			UserID: user.ID, IssueID: "10001", IssueKey: "ABC-1", Status: status,
+			WebhookEvent: "jira:issue_updated", EventTimestamp: timestamp,
+		})
+		if err != nil {
+			t.Fatalf("ApplyJiraIssueState: %v", err)
+		}
+		return applied
+	}
+	if !apply(2000, "In Review") {
+		t.Fatal("expected the first state to apply")
+	}
+	if apply(1000, "In Progress") {
+		t.Fatal("expected an older state to be refused")
+	}
+	if !apply(3000, "Done") {
+		t.Fatal("expected a newer state to apply")
+	}
+
+	var status string
+	if err := db.QueryRowContext(ctx, `SELECT status FROM jira_issue_mirror WHERE user_id = $1 AND issue_id = '10001'`, user.ID).Scan(&status); err != nil {
+		t.Fatalf("read mirror: %v", err)
+	}
+	if status != "Done" {
+		t.Fatalf("expected the mirror to hold the newest state, got %q", status)
+	}
+}
+
+func BenchmarkClaimNextJobParallel(b *testing.B) {
+	db := testutil.NewDB(b)
+	jobs := testutil.NewJobStore(b, db)
+	for i := 0; i < b.N; i++ {
+		testutil.EnqueueJob(b, jobs, "integration", models.JobPriorityNormal)
+	}
+
+	var workers atomic.Int64
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		workerID := fmt.Sprintf("bench-%d", workers.Add(1))
+		for pb.Next() {
+			job, err := jobs.ClaimNextJob(context.Background(), workerID)
+			if err != nil {
+				b.Errorf("ClaimNextJob: %v", err)
+				return
+			}
+			if job == nil {
+				b.Errorf("ClaimNextJob: queue drained early")
+				return
+			}
+		}
+	})
+}
@@ -0,0 +1,269 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+)
+
+// ErrIssueBackfillNotFound is returned when a backfill does not exist or
+// belongs to another user.
+var ErrIssueBackfillNotFound = errors.New("issue backfill not found")
+
+// ErrIssueBackfillActive is returned when starting a backfill while another
+// one of the user's is pending or running.
+var ErrIssueBackfillActive = errors.New("another issue backfill is in progress")
+
+// ErrIssueBackfillNotResumable is returned when resuming a backfill that
+// did not fail.
+var ErrIssueBackfillNotResumable = errors.New("only failed issue backfills can be resumed")
+
+// maxListedIssueBackfills caps how many backfills ListIssueBackfills returns.
+const maxListedIssueBackfills = 20
+
+const issueBackfillColumns = `b.id, b.user_id, b.jql, b.status, b.next_page_token, b.fetched, b.total,
+	b.job_id, b.last_error, b.started_at, b.completed_at, b.created_at, b.updated_at`
+
+// scanIssueBackfill reads a backfill selected with issueBackfillColumns.
+func scanIssueBackfill(row interface{ Scan(...any) error }) (*models.IssueBackfill, error) {
+	var (
+		b                  models.IssueBackfill
+		total, jobID       sql.NullInt64
+		lastError          sql.NullString
+		started, completed sql.NullTime
+	)
+	if err := row.Scan(&b.ID, &b.UserID, &b.JQL, &b.Status, &b.NextPageToken, &b.Fetched, &total,
+		&jobID, &lastError, &started, &completed, &b.CreatedAt, &b.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if total.Valid {
+		n := int(total.Int64)
+		b.Total = &n
+	}
+	if jobID.Valid {
+		b.JobID = &jobID.Int64
+	}
+	b.LastError = nullStringPtr(lastError)
+	if started.Valid {
+		b.StartedAt = &started.Time
+	}
+	if completed.Valid {
+		b.CompletedAt = &completed.Time
+	}
+	return &b, nil
+}
+
+// CreateIssueBackfill records a pending backfill of the issues matching jql,
+// or returns ErrIssueBackfillActive while another one of the user's is
+// pending or running.
+func (s *Store) CreateIssueBackfill(ctx context.Context, userID int64, jql string) (*models.IssueBackfill, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	b, err := scanIssueBackfill(s.db.QueryRowContext(ctx, `
+		INSERT INTO jira_issue_backfills AS b (user_id, jql)
+		SELECT $1, $2
+		WHERE NOT EXISTS (
+			SELECT 1 FROM jira_issue_backfills
+			WHERE user_id = $1 AND status IN ('pending', 'running')
+		)
+		RETURNING `+issueBackfillColumns,
+		userID, jql))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrIssueBackfillActive
+	}
+	if err != nil {
+		return nil, fmt.Errorf("store: create issue backfill: %w", err)
+	}
+	return b, nil
+}
+
+// SetIssueBackfillJob records the job that runs the backfill.
+func (s *Store) SetIssueBackfillJob(ctx context.Context, id, jobID int64) error {
+	if s == nil || s.db == nil {
+		return errors.New("store: db cannot be nil")
+	}
+
+	if _, err := s.db.ExecContext(ctx, `
+		UPDATE jira_issue_backfills SET job_id = $2, updated_at = now() WHERE id = $1
+	`, id, jobID); err != nil {
+		return fmt.Errorf("store: set issue backfill job: %w", err)
+	}
+	return nil
+}
+
+// ListIssueBackfills returns the user's latest backfills, newest first.
+func (s *Store) ListIssueBackfills(ctx context.Context, userID int64) ([]models.IssueBackfill, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+issueBackfillColumns+`
+		FROM jira_issue_backfills b
+		WHERE b.user_id = $1
+		ORDER BY b.created_at DESC, b.id DESC
+		LIMIT $2
+	`, userID, maxListedIssueBackfills)
+	if err != nil {
+		return nil, fmt.Errorf("store: list issue backfills: %w", err)
+	}
+	defer rows.Close()
+
+	backfills := []models.IssueBackfill{}
+	for rows.Next() {
+		b, err := scanIssueBackfill(rows)
+		if err != nil {
+			return nil, fmt.Errorf("store: scan issue backfill: %w", err)
+		}
+		backfills = append(backfills, *b)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("store: iterate issue backfills: %w", err)
+	}
+	return backfills, nil
+}
+
+// GetIssueBackfill returns the user's backfill with the given ID.
+func (s *Store) GetIssueBackfill(ctx context.Context, userID, id int64) (*models.IssueBackfill, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	b, err := scanIssueBackfill(s.db.QueryRowContext(ctx, `
+		SELECT `+issueBackfillColumns+`
+		FROM jira_issue_backfills b
+		WHERE b.id = $1 AND b.user_id = $2
+	`, id, userID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrIssueBackfillNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("store: get issue backfill: %w", err)
+	}
+	return b, nil
+}
+
+// ResumeIssueBackfill puts the user's failed backfill back to pending so it
+// continues from its checkpoint.
+func (s *Store) ResumeIssueBackfill(ctx context.Context, userID, id int64) (*models.IssueBackfill, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	b, err := scanIssueBackfill(s.db.QueryRowContext(ctx, `
+		UPDATE jira_issue_backfills AS b
+		SET status = 'pending', last_error = NULL, updated_at = now()
+		WHERE b.id = $1 AND b.user_id = $2 AND b.status = 'failed'
+		  AND NOT EXISTS (
+			SELECT 1 FROM jira_issue_backfills
+			WHERE user_id = $2 AND status IN ('pending', 'running')
+		  )
+		RETURNING `+issueBackfillColumns,
+		id, userID))
+	if err == nil {
+		return b, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("store: resume issue backfill: %w", err)
+	}
+
+	current, err := s.GetIssueBackfill(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if current.Status == models.IssueBackfillFailed {
+		return nil, ErrIssueBackfillActive
+	}
+	return nil, ErrIssueBackfillNotResumable
+}
+
+// GetIssueBackfillTarget returns the backfill with the given ID and its
+// owner's MCP secret.
+func (s *Store) GetIssueBackfillTarget(ctx context.Context, id int64) (*models.IssueBackfillTarget, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	var (
+		target    models.IssueBackfillTarget
+		mcpSecret sql.NullString
+	)
+	row := s.db.QueryRowContext(ctx, `
+		SELECT `+issueBackfillColumns+`, u.mcp_secret
+		FROM jira_issue_backfills b
+		JOIN users u ON u.id = b.user_id
+		WHERE b.id = $1 AND u.deleted_at IS NULL
+	`, id)
+	b, err := scanIssueBackfill(scanFunc(func(dest ...any) error {
+		return row.Scan(append(dest, &mcpSecret)...)
+	}))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrIssueBackfillNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("store: get issue backfill target: %w", err)
+	}
+	target.Backfill = *b
+	target.MCPSecret = mcpSecret.String
+	return &target, nil
+}
+
+// StartIssueBackfill marks the backfill running and records total, Jira's
+// estimate of the matching issues, unless it is nil.
+func (s *Store) StartIssueBackfill(ctx context.Context, id int64, total *int) error {
+	if s == nil || s.db == nil {
+		return errors.New("store: db cannot be nil")
+	}
+
+	if _, err := s.db.ExecContext(ctx, `
+		UPDATE jira_issue_backfills
+		SET status = 'running', total = COALESCE($2, total), started_at = COALESCE(started_at, now()),
+			last_error = NULL, updated_at = now()
+		WHERE id = $1
+	`, id, total); err != nil {
+		return fmt.Errorf("store: start issue backfill: %w", err)
+	}
+	return nil
+}
+
+// SaveIssueBackfillCheckpoint records how far the backfill got, completing
+// it when done.
+func (s *Store) SaveIssueBackfillCheckpoint(ctx context.Context, id int64, pageToken string, fetched int, done bool) error {
+	if s == nil || s.db == nil {
+		return errors.New("store: db cannot be nil")
+	}
+
+	if _, err := s.db.ExecContext(ctx, `
+		UPDATE jira_issue_backfills
+		SET next_page_token = $2, fetched = $3,
+			status = CASE WHEN $4 THEN 'completed' ELSE status END,
+			completed_at = CASE WHEN $4 THEN now() ELSE completed_at END,
+			updated_at = now()
+		WHERE id = $1
+	`, id, pageToken, fetched, done); err != nil {
+		return fmt.Errorf("store: save issue backfill checkpoint: %w", err)
+	}
+	return nil
+}
+
+// RecordIssueBackfillError records why the backfill's last attempt failed,
+// and marks it failed when no attempts are left.
+func (s *Store) RecordIssueBackfillError(ctx context.Context, id int64, message string, final bool) error {
+	if s == nil || s.db == nil {
+		return errors.New("store: db cannot be nil")
+	}
+
+	if _, err := s.db.ExecContext(ctx, `
+		UPDATE jira_issue_backfills
+		SET last_error = $2, status = CASE WHEN $3 THEN 'failed' ELSE status END, updated_at = now()
+		WHERE id = $1
+	`, id, message, final); err != nil {
+		return fmt.Errorf("store: record issue backfill error: %w", err)
+	}
+	return nil
+}
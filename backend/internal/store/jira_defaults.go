@@ -0,0 +1,152 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+)
+
+// issueDefaultsColumns selects the defaults of a tenant from
+// jira_issue_defaults joined as d; every column is NULL for tenants without
+// defaults.
+const issueDefaultsColumns = `d.project_key, d.issue_type, d.labels, d.components`
+
+// joinedIssueDefaults scans issueDefaultsColumns.
+type joinedIssueDefaults struct {
+	projectKey, issueType sql.NullString
+	labels, components    []string
+}
+
+func (j *joinedIssueDefaults) dest() []any {
+	return []any{&j.projectKey, &j.issueType, pq.Array(&j.labels), pq.Array(&j.components)}
+}
+
+// value returns the scanned defaults, nil when the tenant has none.
+func (j *joinedIssueDefaults) value() *models.JiraIssueDefaults {
+	if !j.projectKey.Valid {
+		return nil
+	}
+	return &models.JiraIssueDefaults{
+		ProjectKey: j.projectKey.String,
+		IssueType:  j.issueType.String,
+		Labels:     j.labels,
+		Components: j.components,
+	}
+}
+
+// GetJiraIssueDefaults returns the Jira issue defaults of the user
+// identified by email, empty when none have been saved.
+func (s *Store) GetJiraIssueDefaults(ctx context.Context, email string) (*models.JiraIssueDefaults, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	var userID int64
+	if err := s.db.QueryRowContext(ctx,
+		`SELECT id FROM users WHERE LOWER(email) = LOWER($1) AND deleted_at IS NULL`, email,
+	).Scan(&userID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("store: no local user found for email=%s", email)
+		}
+		return nil, fmt.Errorf("store: lookup user for jira issue defaults: %w", err)
+	}
+
+	return s.GetJiraIssueDefaultsByUserID(ctx, userID)
+}
+
+// GetJiraIssueDefaultsByUserID returns the Jira issue defaults for a user
+// ID, empty (revision 0) when none have been saved.
+func (s *Store) GetJiraIssueDefaultsByUserID(ctx context.Context, userID int64) (*models.JiraIssueDefaults, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	defaults := models.JiraIssueDefaults{Labels: []string{}, Components: []string{}}
+	var updatedAt sql.NullTime
+	err := s.db.QueryRowContext(ctx,
+		`SELECT project_key, issue_type, labels, components, revision, updated_at FROM jira_issue_defaults WHERE user_id = $1`, userID,
+	).Scan(&defaults.ProjectKey, &defaults.IssueType, pq.Array(&defaults.Labels), pq.Array(&defaults.Components), &defaults.Revision, &updatedAt)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("store: get jira issue defaults: %w", err)
+	}
+	if updatedAt.Valid {
+		defaults.UpdatedAt = &updatedAt.Time
+	}
+
+	return &defaults, nil
+}
+
+// UpsertJiraIssueDefaults saves the Jira issue defaults of the user
+// identified by email. When ifRevision is non-nil the write only happens if
+// the stored revision still equals it (0 for users who never saved
+// defaults); otherwise an error wrapping ErrRevisionMismatch is returned.
+func (s *Store) UpsertJiraIssueDefaults(ctx context.Context, email string, defaults models.JiraIssueDefaults, ifRevision *int64) (*models.JiraIssueDefaults, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("store: begin jira issue defaults tx: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	var (
+		userID  int64
+		current sql.NullInt64
+	)
+	if err := tx.QueryRowContext(ctx, `
+		SELECT u.id, d.revision
+		FROM users u
+		LEFT JOIN jira_issue_defaults d ON d.user_id = u.id
+		WHERE LOWER(u.email) = LOWER($1) AND u.deleted_at IS NULL
+		FOR UPDATE OF u
+	`, email).Scan(&userID, &current); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("store: no local user found for email=%s", email)
+		}
+		return nil, fmt.Errorf("store: lock jira issue defaults: %w", err)
+	}
+
+	if ifRevision != nil && current.Int64 != *ifRevision {
+		return nil, fmt.Errorf("store: jira issue defaults revision is %d, expected %d: %w", current.Int64, *ifRevision, ErrRevisionMismatch)
+	}
+
+	saved := defaults
+	if saved.Labels == nil {
+		saved.Labels = []string{}
+	}
+	if saved.Components == nil {
+		saved.Components = []string{}
+	}
+	var updatedAt time.Time
+	if err := tx.QueryRowContext(ctx, `
+		INSERT INTO jira_issue_defaults (user_id, project_key, issue_type, labels, components)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id) DO UPDATE
+		SET project_key = EXCLUDED.project_key,
+		    issue_type = EXCLUDED.issue_type,
+		    labels = EXCLUDED.labels,
+		    components = EXCLUDED.components,
+		    revision = jira_issue_defaults.revision + 1,
+		    updated_at = now()
+		RETURNING revision, updated_at
+	`, userID, saved.ProjectKey, saved.IssueType, pq.Array(saved.Labels), pq.Array(saved.Components)).Scan(&saved.Revision, &updatedAt); err != nil {
+		return nil, fmt.Errorf("store: upsert jira issue defaults: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("store: commit jira issue defaults: %w", err)
+	}
+
+	saved.UpdatedAt = &updatedAt
+	return &saved, nil
+}
@@ -0,0 +1,204 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+)
+
+// ErrIntegrationTokenNotFound is returned when the user has no token for a
+// provider.
+var ErrIntegrationTokenNotFound = errors.New("integration token not found")
+
+// ConnectJiraOAuth stores the user's Atlassian OAuth tokens and points each
+// granted site's Jira settings at them, replacing any API token saved for
+// the site. The first site becomes the default when the user has none.
+func (s *Store) ConnectJiraOAuth(ctx context.Context, userEmail string, conn *models.JiraOAuthConnection) error {
+	if s == nil || s.db == nil {
+		return errors.New("store: db cannot be nil")
+	}
+	if conn == nil || len(conn.Sites) == 0 {
+		return errors.New("store: jira oauth connection has no sites")
+	}
+
+	metadata, err := json.Marshal(map[string]any{"account_email": conn.AccountEmail, "sites": conn.Sites})
+	if err != nil {
+		return fmt.Errorf("store: encode jira oauth metadata: %w", err)
+	}
+	accessToken, err := s.seal(conn.AccessToken)
+	if err != nil {
+		return err
+	}
+	refreshToken, err := s.seal(conn.RefreshToken)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("store: begin jira oauth tx: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	var userID int64
+	if err := tx.QueryRowContext(ctx, `SELECT id FROM users WHERE LOWER(email) = LOWER($1) AND deleted_at IS NULL`, userEmail).Scan(&userID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrUserNotFound
+		}
+		return fmt.Errorf("store: lookup user by email: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO integration_tokens (user_id, provider, access_token, refresh_token, token_type, expires_at, scopes, metadata)
+		VALUES ($1, $2, $3, NULLIF($4, ''), 'Bearer', $5, $6, $7::jsonb)
+		ON CONFLICT (user_id, provider) DO UPDATE
+		SET access_token  = EXCLUDED.access_token,
+		    refresh_token = EXCLUDED.refresh_token,
+		    token_type    = EXCLUDED.token_type,
+		    expires_at    = EXCLUDED.expires_at,
+		    scopes        = EXCLUDED.scopes,
+		    metadata      = EXCLUDED.metadata,
+		    updated_at    = now()
+	`, userID, models.JiraOAuthProvider, accessToken, refreshToken, conn.ExpiresAt, conn.Scopes, string(metadata)); err != nil {
+		return fmt.Errorf("store: save jira oauth token: %w", err)
+	}
+
+	for _, site := range conn.Sites {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO users_settings (user_id, jira_base_url, jira_email, jira_api_token, jira_cloud_id, auth_method)
+			VALUES ($1, $2, $3, NULL, $4, $5)
+			ON CONFLICT (user_id, jira_base_url) DO UPDATE
+			SET jira_email = EXCLUDED.jira_email,
+			    jira_api_token = NULL,
+			    jira_cloud_id = EXCLUDED.jira_cloud_id,
+			    auth_method = EXCLUDED.auth_method,
+			    is_default = users_settings.is_default AND users_settings.deleted_at IS NULL,
+			    revision = users_settings.revision + 1,
+			    deleted_at = NULL,
+			    updated_at = now()
+		`, userID, site.URL, conn.AccountEmail, site.CloudID, models.JiraAuthOAuth); err != nil {
+			return fmt.Errorf("store: save jira oauth site %s: %w", site.URL, err)
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE users_settings SET is_default = TRUE
+		WHERE user_id = $1 AND jira_base_url = $2
+		  AND NOT EXISTS (SELECT 1 FROM users_settings WHERE user_id = $1 AND is_default AND deleted_at IS NULL)
+	`, userID, conn.Sites[0].URL); err != nil {
+		return fmt.Errorf("store: set default jira site: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("store: commit jira oauth: %w", err)
+	}
+	return nil
+}
+
+// ListExpiringJiraOAuthTokens returns the Atlassian OAuth tokens that
+// expire before the given time and can be refreshed.
+func (s *Store) ListExpiringJiraOAuthTokens(ctx context.Context, before time.Time) ([]models.IntegrationToken, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, user_id, refresh_token, expires_at
+		FROM integration_tokens
+		WHERE provider = $1 AND refresh_token IS NOT NULL AND expires_at < $2
+		ORDER BY expires_at
+	`, models.JiraOAuthProvider, before)
+	if err != nil {
+		return nil, fmt.Errorf("store: list expiring jira oauth tokens: %w", err)
+	}
+	defer rows.Close()
+
+	var tokens []models.IntegrationToken
+	for rows.Next() {
+		t := models.IntegrationToken{Provider: models.JiraOAuthProvider}
+		var refreshToken string
+		var expiresAt time.Time
+		if err := rows.Scan(&t.ID, &t.UserID, &refreshToken, &expiresAt); err != nil {
+			return nil, fmt.Errorf("store: scan jira oauth token: %w", err)
+		}
+		if refreshToken, err = s.open(refreshToken); err != nil {
+			return nil, err
+		}
+		t.RefreshToken = &refreshToken
+		t.ExpiresAt = &expiresAt
+		tokens = append(tokens, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("store: iterate jira oauth tokens: %w", err)
+	}
+	return tokens, nil
+}
+
+// GetJiraOAuthToken returns the user's Atlassian OAuth token, or
+// ErrIntegrationTokenNotFound when they have not connected Jira through
+// OAuth.
+func (s *Store) GetJiraOAuthToken(ctx context.Context, userID int64) (*models.IntegrationToken, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	t := models.IntegrationToken{UserID: userID, Provider: models.JiraOAuthProvider}
+	var refreshToken sql.NullString
+	var expiresAt sql.NullTime
+	if err := s.db.QueryRowContext(ctx, `
+		SELECT id, access_token, refresh_token, expires_at
+		FROM integration_tokens
+		WHERE user_id = $1 AND provider = $2
+	`, userID, models.JiraOAuthProvider).Scan(&t.ID, &t.AccessToken, &refreshToken, &expiresAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrIntegrationTokenNotFound
+		}
+		return nil, fmt.Errorf("store: get jira oauth token: %w", err)
+	}
+	t.RefreshToken = nullStringPtr(refreshToken)
+	var err error
+	if t.AccessToken, err = s.open(t.AccessToken); err != nil {
+		return nil, err
+	}
+	if err := s.openPtr(t.RefreshToken); err != nil {
+		return nil, err
+	}
+	if expiresAt.Valid {
+		t.ExpiresAt = &expiresAt.Time
+	}
+	return &t, nil
+}
+
+// UpdateJiraOAuthToken stores a refreshed access token and the refresh token
+// that replaced the old one. An empty accessToken keeps the current one and
+// an empty refreshToken clears it, after Atlassian revoked the grant, so the
+// token is not retried.
+func (s *Store) UpdateJiraOAuthToken(ctx context.Context, id int64, accessToken, refreshToken string, expiresAt time.Time) error {
+	if s == nil || s.db == nil {
+		return errors.New("store: db cannot be nil")
+	}
+
+	accessToken, err := s.seal(accessToken)
+	if err != nil {
+		return err
+	}
+	if refreshToken, err = s.seal(refreshToken); err != nil {
+		return err
+	}
+
+	if _, err := s.db.ExecContext(ctx, `
+		UPDATE integration_tokens
+		SET access_token = COALESCE(NULLIF($2, ''), access_token), refresh_token = NULLIF($3, ''), expires_at = $4, updated_at = now()
+		WHERE id = $1
+	`, id, accessToken, refreshToken, expiresAt); err != nil {
+		return fmt.Errorf("store: update jira oauth token: %w", err)
+	}
+	return nil
+}
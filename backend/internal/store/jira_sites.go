@@ -0,0 +1,104 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+)
+
+// jiraSiteJoin joins the jira_sites row of the settings table aliased as
+// alias; stored base URLs may keep a trailing slash.
+func jiraSiteJoin(alias string) string {
+	return `LEFT JOIN jira_sites js ON js.jira_base_url = RTRIM(` + alias + `.jira_base_url, '/')`
+}
+
+// RecordJiraSite saves what validating credentials found out about the Jira
+// site at baseURL: whether it has Jira Service Management.
+func (s *Store) RecordJiraSite(ctx context.Context, baseURL string, serviceManagement bool) error {
+	if s == nil || s.db == nil {
+		return errors.New("store: db cannot be nil")
+	}
+
+	if _, err := s.db.ExecContext(ctx, `
+		INSERT INTO jira_sites (jira_base_url, service_management, checked_at)
+		VALUES ($1, $2, now())
+		ON CONFLICT (jira_base_url) DO UPDATE
+		SET service_management = EXCLUDED.service_management,
+		    checked_at = EXCLUDED.checked_at
+	`, strings.TrimRight(baseURL, "/"), serviceManagement); err != nil {
+		return fmt.Errorf("store: record jira site: %w", err)
+	}
+	return nil
+}
+
+// ListUserSettingsByMCPSecret returns the Jira credentials of every site the
+// tenant holding secret configured, the default site first. Tenants without
+// sites of their own get their organization's shared account, as
+// GetUserSettingsByMCPSecret does.
+func (s *Store) ListUserSettingsByMCPSecret(ctx context.Context, secret string) ([]models.JiraUserSettingsWithSecret, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+SELECT
+  us.user_id,
+  us.jira_base_url,
+  us.jira_email,
+  us.jira_cloud_id,
+  us.is_default,
+  us.auth_method,
+  CASE WHEN us.auth_method = 'oauth' THEN COALESCE(it.access_token, '') ELSE us.jira_api_token END,
+  COALESCE(js.service_management, FALSE),
+  `+issueDefaultsColumns+`
+FROM users_settings us
+JOIN users u ON us.user_id = u.id
+LEFT JOIN integration_tokens it ON it.user_id = us.user_id AND it.provider = 'atlassian'
+LEFT JOIN jira_issue_defaults d ON d.user_id = us.user_id
+`+jiraSiteJoin("us")+`
+WHERE u.mcp_secret = $1 AND u.deleted_at IS NULL AND us.deleted_at IS NULL
+ORDER BY us.is_default DESC, us.jira_base_url ASC
+`, secret)
+	if err != nil {
+		return nil, fmt.Errorf("store: list users_settings by mcp_secret: %w", err)
+	}
+	defer rows.Close()
+
+	var sites []models.JiraUserSettingsWithSecret
+	for rows.Next() {
+		var (
+			settings models.JiraUserSettingsWithSecret
+			cloudID  sql.NullString
+			defaults joinedIssueDefaults
+		)
+		dest := append([]any{
+			&settings.UserID, &settings.JiraBaseURL, &settings.JiraEmail, &cloudID, &settings.IsDefault,
+			&settings.AuthMethod, &settings.AtlassianAPIToken, &settings.ServiceManagement,
+		}, defaults.dest()...)
+		if err := rows.Scan(dest...); err != nil {
+			return nil, fmt.Errorf("store: scan users_settings: %w", err)
+		}
+		if settings.AtlassianAPIToken, err = s.open(settings.AtlassianAPIToken); err != nil {
+			return nil, err
+		}
+		settings.JiraCloudID = nullStringPtr(cloudID)
+		settings.IssueDefaults = defaults.value()
+		sites = append(sites, settings)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("store: iterate users_settings: %w", err)
+	}
+	if len(sites) > 0 {
+		return sites, nil
+	}
+
+	shared, err := s.getSharedJiraSettingsByMCPSecret(ctx, secret)
+	if err != nil {
+		return nil, err
+	}
+	return []models.JiraUserSettingsWithSecret{*shared}, nil
+}
@@ -0,0 +1,79 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+)
+
+// JiraWebhookDeliveryRetention is how long delivery IDs are remembered.
+// Jira stops retrying a delivery well within it.
+const JiraWebhookDeliveryRetention = 7 * 24 * time.Hour
+
+// RecordJiraWebhookDelivery remembers a webhook delivery for the user and
+// reports whether it is new; false means Jira already delivered it. Delivery
+// IDs older than JiraWebhookDeliveryRetention are forgotten.
+func (s *Store) RecordJiraWebhookDelivery(ctx context.Context, userID int64, deliveryID string) (bool, error) {
+	if s == nil || s.db == nil {
+		return false, errors.New("store: db cannot be nil")
+	}
+
+	result, err := s.db.ExecContext(ctx, `
+INSERT INTO jira_webhook_deliveries (user_id, delivery_id)
+VALUES ($1, $2)
+ON CONFLICT (user_id, delivery_id) DO NOTHING
+`, userID, deliveryID)
+	if err != nil {
+		return false, fmt.Errorf("store: record jira webhook delivery: %w", err)
+	}
+	inserted, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("store: record jira webhook delivery: %w", err)
+	}
+
+	if _, err := s.db.ExecContext(ctx, `
+DELETE FROM jira_webhook_deliveries
+WHERE user_id = $1 AND received_at < now() - make_interval(secs => $2)
+`, userID, JiraWebhookDeliveryRetention.Seconds()); err != nil {
+		return false, fmt.Errorf("store: trim jira webhook deliveries: %w", err)
+	}
+
+	return inserted == 1, nil
+}
+
+// ApplyJiraIssueState stores state as the mirrored state of its issue unless
+// the mirror already holds a newer event, and reports whether it did. Events
+// with the same timestamp apply in arrival order.
+func (s *Store) ApplyJiraIssueState(ctx context.Context, state *models.JiraIssueState) (bool, error) {
+	if s == nil || s.db == nil {
+		return false, errors.New("store: db cannot be nil")
+	}
+	if state == nil || state.IssueID == "" {
+		return false, errors.New("store: issue ID is required")
+	}
+
+	result, err := s.db.ExecContext(ctx, `
+INSERT INTO jira_issue_mirror (user_id, issue_id, issue_key, summary, status, deleted, last_webhook_event, event_timestamp)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+ON CONFLICT (user_id, issue_id) DO UPDATE SET
+  issue_key = EXCLUDED.issue_key,
+  summary = EXCLUDED.summary,
+  status = EXCLUDED.status,
+  deleted = EXCLUDED.deleted,
+  last_webhook_event = EXCLUDED.last_webhook_event,
+  event_timestamp = EXCLUDED.event_timestamp,
+  updated_at = now()
+WHERE jira_issue_mirror.event_timestamp <= EXCLUDED.event_timestamp
+`, state.UserID, state.IssueID, state.IssueKey, state.Summary, state.Status, state.Deleted, state.WebhookEvent, state.EventTimestamp)
+	if err != nil {
+		return false, fmt.Errorf("store: apply jira issue state: %w", err)
+	}
+	applied, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("store: apply jira issue state: %w", err)
+	}
+	return applied == 1, nil
+}
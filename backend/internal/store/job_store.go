@@ -2,10 +2,13 @@ package store
 
 import (
 	"context"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
@@ -14,6 +17,14 @@ import (
 // ErrJobNotFound is returned when a job is not found in the database
 var ErrJobNotFound = errors.New("job not found")
 
+// ErrJobNotRetryable is returned when retrying a job that is not failed or
+// cancelled
+var ErrJobNotRetryable = errors.New("job is not failed or cancelled")
+
+// ErrJobNotDeletable is returned when deleting a job that is still pending
+// or processing
+var ErrJobNotDeletable = errors.New("job is not completed, failed or cancelled")
+
 // JobStore provides database operations for job queue management
 type JobStore struct {
 	db *sql.DB
@@ -27,16 +38,40 @@ func NewJobStore(db *sql.DB) (*JobStore, error) {
 	return &JobStore{db: db}, nil
 }
 
-// Enqueue creates a new job in the queue
+// Enqueue creates a new job in the queue. When job.DedupWindow is set and
+// a pending job with the same type and payload was created within the
+// window, job is filled in from that job instead and marked Deduplicated.
 func (s *JobStore) Enqueue(ctx context.Context, job *models.Job) error {
 	if err := job.IsValid(); err != nil {
 		return fmt.Errorf("invalid job: %w", err)
 	}
 
+	hash, err := payloadHash(job.Payload)
+	if err != nil {
+		return fmt.Errorf("enqueue job: %w", err)
+	}
+	if job.DedupWindow > 0 {
+		return s.enqueueDeduplicated(ctx, job, hash)
+	}
+	return s.insertJob(ctx, s.db, job, hash)
+}
+
+// queryRower is satisfied by both *sql.DB and *sql.Tx.
+type queryRower interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func (s *JobStore) insertJob(ctx context.Context, q queryRower, job *models.Job, hash string) error {
 	query := `
-		INSERT INTO jobs (job_type, payload, status, priority, max_attempts, scheduled_for, metadata)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
-		RETURNING id, created_at, updated_at
+		WITH inserted AS (
+			INSERT INTO jobs (job_type, payload, status, priority, max_attempts, scheduled_for, metadata, payload_hash)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			RETURNING id, status, created_at, updated_at
+		), logged AS (
+			INSERT INTO job_events (job_id, event, status, attempt)
+			SELECT id, 'enqueued', status, 0 FROM inserted
+		)
+		SELECT id, created_at, updated_at FROM inserted
 	`
 
 	status := models.JobStatusPending
@@ -44,7 +79,7 @@ func (s *JobStore) Enqueue(ctx context.Context, job *models.Job) error {
 		status = job.Status
 	}
 
-	err := s.db.QueryRowContext(
+	err := q.QueryRowContext(
 		ctx,
 		query,
 		job.JobType,
@@ -54,6 +89,7 @@ func (s *JobStore) Enqueue(ctx context.Context, job *models.Job) error {
 		job.MaxAttempts,
 		job.ScheduledFor,
 		job.Metadata,
+		hash,
 	).Scan(&job.ID, &job.CreatedAt, &job.UpdatedAt)
 
 	if err != nil {
@@ -63,14 +99,77 @@ func (s *JobStore) Enqueue(ctx context.Context, job *models.Job) error {
 	return nil
 }
 
-// GetByID retrieves a job by its ID
+// enqueueDeduplicated looks for a matching pending job and inserts only when
+// there is none. An advisory lock on the type and hash serializes concurrent
+// enqueues of the same job, so repeated deliveries cannot both insert.
+func (s *JobStore) enqueueDeduplicated(ctx context.Context, job *models.Job, hash string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("enqueue job: begin tx: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtextextended($1, 0))`, job.JobType+":"+hash); err != nil {
+		return fmt.Errorf("enqueue job: lock dedup key: %w", err)
+	}
+
+	err = tx.QueryRowContext(ctx, `
+		SELECT id, status, priority, attempts, max_attempts, created_at, updated_at, scheduled_for
+		FROM jobs
+		WHERE job_type = $1
+		  AND payload_hash = $2
+		  AND status = 'pending'
+		  AND created_at >= NOW() - make_interval(secs => $3)
+		ORDER BY created_at DESC
+		LIMIT 1
+	`, job.JobType, hash, job.DedupWindow.Seconds()).Scan(
+		&job.ID,
+		&job.Status,
+		&job.Priority,
+		&job.Attempts,
+		&job.MaxAttempts,
+		&job.CreatedAt,
+		&job.UpdatedAt,
+		&job.ScheduledFor,
+	)
+	switch {
+	case err == nil:
+		job.Deduplicated = true
+	case errors.Is(err, sql.ErrNoRows):
+		if err := s.insertJob(ctx, tx, job, hash); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("enqueue job: find duplicate: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("enqueue job: commit: %w", err)
+	}
+	return nil
+}
+
+// payloadHash identifies a payload by the SHA-256 of its JSON encoding,
+// which sorts object keys, so equal payloads hash equally.
+func payloadHash(payload models.JSONB) (string, error) {
+	encoded, err := payload.Value()
+	if err != nil {
+		return "", fmt.Errorf("encode payload: %w", err)
+	}
+	sum := sha256.Sum256(encoded.([]byte))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// GetByID retrieves a job by its ID. Deleted jobs are not found.
 func (s *JobStore) GetByID(ctx context.Context, id int64) (*models.Job, error) {
 	query := `
 		SELECT id, job_type, payload, status, priority, attempts, max_attempts,
 		       created_at, updated_at, scheduled_for, last_error, retry_after,
 		       processed_at, completed_at, worker_id, metadata
 		FROM jobs
-		WHERE id = $1
+		WHERE id = $1 AND deleted_at IS NULL
 	`
 
 	job := &models.Job{}
@@ -122,6 +221,7 @@ func (s *JobStore) GetByID(ctx context.Context, id int64) (*models.Job, error) {
 // ClaimNextJob atomically claims the next available job for processing
 func (s *JobStore) ClaimNextJob(ctx context.Context, workerID string) (*models.Job, error) {
 	query := `
+		WITH claimed AS (
 		UPDATE jobs
 		SET status = 'processing',
 		    worker_id = $1,
@@ -147,6 +247,14 @@ func (s *JobStore) ClaimNextJob(ctx context.Context, workerID string) (*models.J
 		RETURNING id, job_type, payload, status, priority, attempts, max_attempts,
 		          created_at, updated_at, scheduled_for, last_error, retry_after,
 		          processed_at, completed_at, worker_id, metadata
+		), logged AS (
+			INSERT INTO job_events (job_id, event, status, attempt, worker_id)
+			SELECT id, 'claimed', status, attempts, worker_id FROM claimed
+		)
+		SELECT id, job_type, payload, status, priority, attempts, max_attempts,
+		       created_at, updated_at, scheduled_for, last_error, retry_after,
+		       processed_at, completed_at, worker_id, metadata
+		FROM claimed
 	`
 
 	job := &models.Job{}
@@ -195,18 +303,54 @@ func (s *JobStore) ClaimNextJob(ctx context.Context, workerID string) (*models.J
 	return job, nil
 }
 
-// MarkCompleted marks a job as successfully completed
-func (s *JobStore) MarkCompleted(ctx context.Context, id int64) error {
-	query := `
-		UPDATE jobs
-		SET status = 'completed',
-		    completed_at = NOW(),
-		    updated_at = NOW(),
-		    worker_id = NULL
-		WHERE id = $1
-	`
+// transitionJobs applies set to the jobs matched by where and records a
+// job_events row for each changed job. The event name is bound after args.
+// Events carry the worker that held the job before the change and, for
+// failures and retries, the error. It returns the IDs of the changed jobs.
+func (s *JobStore) transitionJobs(ctx context.Context, event, set, where string, args ...any) ([]int64, error) {
+	args = append(args, event)
+	query := fmt.Sprintf(`
+		WITH prev AS (
+			SELECT id, worker_id FROM jobs
+			WHERE %s
+			FOR UPDATE
+		), changed AS (
+			UPDATE jobs SET %s
+			FROM prev
+			WHERE jobs.id = prev.id
+			RETURNING jobs.id, jobs.status, jobs.attempts, prev.worker_id, jobs.last_error
+		)
+		INSERT INTO job_events (job_id, event, status, attempt, worker_id, error)
+		SELECT id, $%[3]d, status, attempts, worker_id,
+		       CASE WHEN $%[3]d IN ('failed', 'retry_scheduled') THEN last_error END
+		FROM changed
+		RETURNING job_id
+	`, where, set, len(args))
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
 
-	_, err := s.db.ExecContext(ctx, query, id)
+// MarkCompleted marks a job as completed
+func (s *JobStore) MarkCompleted(ctx context.Context, id int64) error {
+	_, err := s.transitionJobs(ctx, "completed", `
+		status = 'completed',
+		completed_at = NOW(),
+		updated_at = NOW(),
+		worker_id = NULL`, "id = $1", id)
 	if err != nil {
 		return fmt.Errorf("mark job completed: %w", err)
 	}
@@ -214,18 +358,28 @@ func (s *JobStore) MarkCompleted(ctx context.Context, id int64) error {
 	return nil
 }
 
-// MarkFailed marks a job as failed with an error message
-func (s *JobStore) MarkFailed(ctx context.Context, id int64, errorMsg string) error {
-	query := `
+// SetProgress records a running job's progress in its metadata as
+// {"progress": {"done": done, "total": total}}; total is 0 when unknown
+func (s *JobStore) SetProgress(ctx context.Context, id int64, done, total int) error {
+	_, err := s.db.ExecContext(ctx, `
 		UPDATE jobs
-		SET status = 'failed',
-		    last_error = $2,
-		    updated_at = NOW(),
-		    worker_id = NULL
-		WHERE id = $1
-	`
+		SET metadata = COALESCE(metadata, '{}'::jsonb) || jsonb_build_object('progress', jsonb_build_object('done', $2::int, 'total', $3::int)),
+		    updated_at = NOW()
+		WHERE id = $1`, id, done, total)
+	if err != nil {
+		return fmt.Errorf("set job progress: %w", err)
+	}
 
-	_, err := s.db.ExecContext(ctx, query, id, errorMsg)
+	return nil
+}
+
+// MarkFailed marks a job as failed with an error message
+func (s *JobStore) MarkFailed(ctx context.Context, id int64, errorMsg string) error {
+	_, err := s.transitionJobs(ctx, "failed", `
+		status = 'failed',
+		last_error = $2,
+		updated_at = NOW(),
+		worker_id = NULL`, "id = $1", id, errorMsg)
 	if err != nil {
 		return fmt.Errorf("mark job failed: %w", err)
 	}
@@ -235,17 +389,12 @@ func (s *JobStore) MarkFailed(ctx context.Context, id int64, errorMsg string) er
 
 // ScheduleRetry schedules a job for retry with exponential backoff
 func (s *JobStore) ScheduleRetry(ctx context.Context, id int64, errorMsg string, retryAfter time.Time) error {
-	query := `
-		UPDATE jobs
-		SET status = 'pending',
-		    last_error = $2,
-		    retry_after = $3,
-		    updated_at = NOW(),
-		    worker_id = NULL
-		WHERE id = $1
-	`
-
-	_, err := s.db.ExecContext(ctx, query, id, errorMsg, retryAfter)
+	_, err := s.transitionJobs(ctx, "retry_scheduled", `
+		status = 'pending',
+		last_error = $2,
+		retry_after = $3,
+		updated_at = NOW(),
+		worker_id = NULL`, "id = $1", id, errorMsg, retryAfter)
 	if err != nil {
 		return fmt.Errorf("schedule job retry: %w", err)
 	}
@@ -255,38 +404,220 @@ func (s *JobStore) ScheduleRetry(ctx context.Context, id int64, errorMsg string,
 
 // CancelJob marks a job as cancelled
 func (s *JobStore) CancelJob(ctx context.Context, id int64) error {
-	query := `
-		UPDATE jobs
-		SET status = 'cancelled',
-		    updated_at = NOW(),
-		    worker_id = NULL
-		WHERE id = $1 AND status IN ('pending', 'failed')
-	`
-
-	result, err := s.db.ExecContext(ctx, query, id)
+	ids, err := s.transitionJobs(ctx, "cancelled", `
+		status = 'cancelled',
+		updated_at = NOW(),
+		worker_id = NULL`, "id = $1 AND status IN ('pending', 'failed') AND deleted_at IS NULL", id)
 	if err != nil {
 		return fmt.Errorf("cancel job: %w", err)
 	}
 
-	affected, _ := result.RowsAffected()
-	if affected == 0 {
+	if len(ids) == 0 {
 		return fmt.Errorf("job cannot be cancelled (may be processing or already completed)")
 	}
 
 	return nil
 }
 
+// resetJobColumns puts a job back in the queue as if it were new
+const resetJobColumns = `
+		status = 'pending',
+		attempts = 0,
+		last_error = NULL,
+		retry_after = NULL,
+		processed_at = NULL,
+		completed_at = NULL,
+		worker_id = NULL,
+		updated_at = NOW()`
+
+// RetryJob resets a failed or cancelled job's attempts and error and puts it
+// back in the queue
+func (s *JobStore) RetryJob(ctx context.Context, id int64) error {
+	ids, err := s.transitionJobs(ctx, "requeued", resetJobColumns,
+		"id = $1 AND status IN ('failed', 'cancelled') AND deleted_at IS NULL", id)
+	if err != nil {
+		return fmt.Errorf("retry job: %w", err)
+	}
+
+	if len(ids) == 0 {
+		if _, err := s.GetByID(ctx, id); err != nil {
+			return err
+		}
+		return ErrJobNotRetryable
+	}
+	return nil
+}
+
+// DeleteJob deletes a finished job. It stays restorable for
+// SoftDeleteRetention before PurgeDeletedJobs removes it.
+func (s *JobStore) DeleteJob(ctx context.Context, id int64) error {
+	ids, err := s.transitionJobs(ctx, "deleted", "deleted_at = NOW()",
+		"id = $1 AND status IN ('completed', 'failed', 'cancelled') AND deleted_at IS NULL", id)
+	if err != nil {
+		return fmt.Errorf("delete job: %w", err)
+	}
+
+	if len(ids) == 0 {
+		if _, err := s.GetByID(ctx, id); err != nil {
+			return err
+		}
+		return ErrJobNotDeletable
+	}
+	return nil
+}
+
+// RestoreJob undoes DeleteJob within the retention window
+func (s *JobStore) RestoreJob(ctx context.Context, id int64) error {
+	ids, err := s.transitionJobs(ctx, "restored", "deleted_at = NULL",
+		"id = $1 AND deleted_at > NOW() - make_interval(secs => $2)", id, SoftDeleteRetention.Seconds())
+	if err != nil {
+		return fmt.Errorf("restore job: %w", err)
+	}
+
+	if len(ids) == 0 {
+		return ErrJobNotFound
+	}
+	return nil
+}
+
+// PurgeDeletedJobs removes jobs deleted more than retention ago, along with
+// their events, and returns how many it removed
+func (s *JobStore) PurgeDeletedJobs(ctx context.Context, retention time.Duration) (int64, error) {
+	result, err := s.db.ExecContext(ctx,
+		`DELETE FROM jobs WHERE deleted_at < NOW() - make_interval(secs => $1)`,
+		retention.Seconds())
+	if err != nil {
+		return 0, fmt.Errorf("purge deleted jobs: %w", err)
+	}
+
+	affected, _ := result.RowsAffected()
+	return affected, nil
+}
+
+// RetryFailedJobs requeues up to filter.Limit failed jobs matching the
+// filter, oldest failures first, and returns their IDs
+func (s *JobStore) RetryFailedJobs(ctx context.Context, filter models.JobFilter) ([]int64, error) {
+	where, args := failedJobConditions(filter)
+	args = append(args, filterLimit(filter))
+	ids, err := s.transitionJobs(ctx, "requeued", resetJobColumns, fmt.Sprintf(`id IN (
+				SELECT id FROM jobs
+				WHERE %s
+				ORDER BY updated_at ASC
+				LIMIT $%d
+				FOR UPDATE SKIP LOCKED
+			)`, where, len(args)), args...)
+	if err != nil {
+		return nil, fmt.Errorf("retry failed jobs: %w", err)
+	}
+	return ids, nil
+}
+
+// ListJobEvents returns a job's state transitions, oldest first
+func (s *JobStore) ListJobEvents(ctx context.Context, jobID int64) ([]*models.JobEvent, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, job_id, event, status, attempt, worker_id, error, created_at
+		FROM job_events
+		WHERE job_id = $1
+		ORDER BY id ASC
+	`, jobID)
+	if err != nil {
+		return nil, fmt.Errorf("list job events: %w", err)
+	}
+	defer rows.Close()
+
+	events := []*models.JobEvent{}
+	for rows.Next() {
+		var e models.JobEvent
+		var workerID, errMsg sql.NullString
+		if err := rows.Scan(&e.ID, &e.JobID, &e.Event, &e.Status, &e.Attempt, &workerID, &errMsg, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan job event: %w", err)
+		}
+		if workerID.Valid {
+			e.WorkerID = &workerID.String
+		}
+		if errMsg.Valid {
+			e.Error = &errMsg.String
+		}
+		events = append(events, &e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate job events: %w", err)
+	}
+
+	// Jobs enqueued before events were recorded have no history; tell them
+	// apart from jobs that do not exist.
+	if len(events) == 0 {
+		if _, err := s.GetByID(ctx, jobID); err != nil {
+			return nil, err
+		}
+	}
+	return events, nil
+}
+
+// ListFailedJobs returns failed jobs matching the filter, most recent
+// failures first
+func (s *JobStore) ListFailedJobs(ctx context.Context, filter models.JobFilter) ([]*models.Job, error) {
+	where, args := failedJobConditions(filter)
+	args = append(args, filterLimit(filter))
+	query := fmt.Sprintf(`
+		SELECT id, job_type, payload, status, priority, attempts, max_attempts,
+		       created_at, updated_at, scheduled_for, last_error, retry_after,
+		       processed_at, completed_at, worker_id, metadata
+		FROM jobs
+		WHERE %s
+		ORDER BY updated_at DESC
+		LIMIT $%d
+	`, where, len(args))
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list failed jobs: %w", err)
+	}
+	defer rows.Close()
+
+	return s.scanJobs(rows)
+}
+
+// failedJobConditions builds the WHERE clause selecting failed jobs that
+// match filter. A failed job's updated_at is when it failed.
+func failedJobConditions(filter models.JobFilter) (string, []any) {
+	conds := []string{"status = 'failed'", "deleted_at IS NULL"}
+	var args []any
+	add := func(cond string, arg any) {
+		args = append(args, arg)
+		conds = append(conds, fmt.Sprintf(cond, len(args)))
+	}
+	if filter.JobType != "" {
+		add("job_type = $%d", filter.JobType)
+	}
+	if filter.Priority != "" {
+		add("priority = $%d", filter.Priority)
+	}
+	if filter.FailedAfter != nil {
+		add("updated_at >= $%d", *filter.FailedAfter)
+	}
+	if filter.FailedBefore != nil {
+		add("updated_at < $%d", *filter.FailedBefore)
+	}
+	if filter.ErrorContains != "" {
+		add("strpos(last_error, $%d) > 0", filter.ErrorContains)
+	}
+	return strings.Join(conds, " AND "), args
+}
+
+func filterLimit(filter models.JobFilter) int {
+	if filter.Limit <= 0 || filter.Limit > 1000 {
+		return 100
+	}
+	return filter.Limit
+}
+
 // ReleaseJob releases a processing job back to pending (for graceful shutdown)
 func (s *JobStore) ReleaseJob(ctx context.Context, id int64) error {
-	query := `
-		UPDATE jobs
-		SET status = 'pending',
-		    worker_id = NULL,
-		    updated_at = NOW()
-		WHERE id = $1 AND status = 'processing'
-	`
-
-	_, err := s.db.ExecContext(ctx, query, id)
+	_, err := s.transitionJobs(ctx, "released", `
+		status = 'pending',
+		worker_id = NULL,
+		updated_at = NOW()`, "id = $1 AND status = 'processing'", id)
 	if err != nil {
 		return fmt.Errorf("release job: %w", err)
 	}
@@ -305,6 +636,7 @@ func (s *JobStore) GetStats(ctx context.Context) (*models.JobStats, error) {
 			COUNT(*) FILTER (WHERE status = 'cancelled') as cancelled,
 			COUNT(*) as total
 		FROM jobs
+		WHERE deleted_at IS NULL
 	`
 
 	stats := &models.JobStats{}
@@ -323,6 +655,22 @@ func (s *JobStore) GetStats(ctx context.Context) (*models.JobStats, error) {
 	return stats, nil
 }
 
+// CountReadyJobs returns the number of pending jobs that are due to run
+func (s *JobStore) CountReadyJobs(ctx context.Context) (int, error) {
+	query := `
+		SELECT COUNT(*) FROM jobs
+		WHERE status = 'pending'
+		  AND (scheduled_for IS NULL OR scheduled_for <= NOW())
+		  AND (retry_after IS NULL OR retry_after <= NOW())
+	`
+
+	var n int
+	if err := s.db.QueryRowContext(ctx, query).Scan(&n); err != nil {
+		return 0, fmt.Errorf("count ready jobs: %w", err)
+	}
+	return n, nil
+}
+
 // ListProcessingJobs returns all jobs currently being processed
 func (s *JobStore) ListProcessingJobs(ctx context.Context) ([]*models.Job, error) {
 	query := `
@@ -377,6 +725,33 @@ func (s *JobStore) ListPendingJobs(ctx context.Context, limit int) ([]*models.Jo
 	return s.scanJobs(rows)
 }
 
+// ListUserJobs returns the most recent jobs whose payload or metadata names
+// userID as their user
+func (s *JobStore) ListUserJobs(ctx context.Context, userID int64, limit int) ([]*models.Job, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+
+	query := `
+		SELECT id, job_type, payload, status, priority, attempts, max_attempts,
+		       created_at, updated_at, scheduled_for, last_error, retry_after,
+		       processed_at, completed_at, worker_id, metadata
+		FROM jobs
+		WHERE (metadata->>'user_id' = $1::text OR payload->>'user_id' = $1::text)
+		  AND deleted_at IS NULL
+		ORDER BY created_at DESC
+		LIMIT $2
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list user jobs: %w", err)
+	}
+	defer rows.Close()
+
+	return s.scanJobs(rows)
+}
+
 // scanJobs scans multiple job rows
 func (s *JobStore) scanJobs(rows *sql.Rows) ([]*models.Job, error) {
 	var jobs []*models.Job
@@ -431,11 +806,13 @@ func (s *JobStore) scanJobs(rows *sql.Rows) ([]*models.Job, error) {
 	return jobs, nil
 }
 
-// CleanupOldJobs removes completed/failed jobs older than the specified duration
+// CleanupOldJobs deletes completed/failed jobs older than the specified
+// duration. They can be restored until PurgeDeletedJobs removes them.
 func (s *JobStore) CleanupOldJobs(ctx context.Context, olderThan time.Duration) (int64, error) {
 	query := `
-		DELETE FROM jobs
+		UPDATE jobs SET deleted_at = NOW()
 		WHERE status IN ('completed', 'failed', 'cancelled')
+		  AND deleted_at IS NULL
 		  AND updated_at < NOW() - INTERVAL '1 second' * $1
 	`
 
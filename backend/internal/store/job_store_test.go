@@ -0,0 +1,137 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+)
+
+func TestPayloadHashIgnoresKeyOrder(t *testing.T) {
+	a, err := payloadHash(models.JSONB{"issue": "PROJ-1", "event": "updated"})
+	if err != nil {
+		t.Fatalf("payloadHash: %v", err)
+	}
+	b, _ := payloadHash(models.JSONB{"event": "updated", "issue": "PROJ-1"})
+	c, _ := payloadHash(models.JSONB{"event": "deleted", "issue": "PROJ-1"})
+	if a != b {
+		t.Fatal("expected equal payloads to hash equally")
+	}
+	if a == c {
+		t.Fatal("expected different payloads to hash differently")
+	}
+}
+
+func TestEnqueueReturnsPendingDuplicate(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	s := &JobStore{db: db}
+
+	payload := models.JSONB{"issue": "PROJ-1"}
+	hash, _ := payloadHash(payload)
+	created := time.Now().Add(-30 * time.Second)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`SELECT pg_advisory_xact_lock(hashtextextended($1, 0))`)).
+		WithArgs("webhook:" + hash).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT id, status, priority, attempts, max_attempts, created_at, updated_at, scheduled_for\s+FROM jobs`).
+		WithArgs("webhook", hash, 60.0).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "status", "priority", "attempts", "max_attempts", "created_at", "updated_at", "scheduled_for"}).
+			AddRow(int64(42), "pending", "normal", 0, 3, created, created, nil))
+	mock.ExpectCommit()
+
+	job := &models.Job{
+		JobType:     "webhook",
+		Payload:     payload,
+		Priority:    models.JobPriorityNormal,
+		MaxAttempts: 3,
+		DedupWindow: time.Minute,
+	}
+	if err := s.Enqueue(context.Background(), job); err != nil {
+		t.Fatalf("Enqueue returned error: %v", err)
+	}
+	if job.ID != 42 || !job.Deduplicated || job.Status != models.JobStatusPending {
+		t.Fatalf("expected the pending duplicate, got %+v", job)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestFailedJobConditions(t *testing.T) {
+	after := time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)
+	where, args := failedJobConditions(models.JobFilter{
+		JobType:       "sync_issues",
+		FailedAfter:   &after,
+		ErrorContains: "timeout",
+	})
+
+	want := "status = 'failed' AND deleted_at IS NULL AND job_type = $1 AND updated_at >= $2 AND strpos(last_error, $3) > 0"
+	if where != want {
+		t.Fatalf("where = %q, want %q", where, want)
+	}
+	if len(args) != 3 || args[0] != "sync_issues" || args[1] != after || args[2] != "timeout" {
+		t.Fatalf("unexpected args %v", args)
+	}
+
+	if where, args := failedJobConditions(models.JobFilter{}); where != "status = 'failed' AND deleted_at IS NULL" || len(args) != 0 {
+		t.Fatalf("empty filter: got %q %v", where, args)
+	}
+}
+
+func TestMarkFailedRecordsEvent(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	s := &JobStore{db: db}
+
+	mock.ExpectQuery(`WITH prev AS \(\s+SELECT id, worker_id FROM jobs\s+WHERE id = \$1\s+FOR UPDATE.*INSERT INTO job_events`).
+		WithArgs(int64(5), "boom", "failed").
+		WillReturnRows(sqlmock.NewRows([]string{"job_id"}).AddRow(int64(5)))
+
+	if err := s.MarkFailed(context.Background(), 5, "boom"); err != nil {
+		t.Fatalf("MarkFailed returned error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestDeleteJobRejectsUnfinishedJob(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	s := &JobStore{db: db}
+
+	mock.ExpectQuery(`WITH prev AS \(\s+SELECT id, worker_id FROM jobs\s+WHERE id = \$1 AND status IN \('completed', 'failed', 'cancelled'\) AND deleted_at IS NULL`).
+		WithArgs(int64(5), "deleted").
+		WillReturnRows(sqlmock.NewRows([]string{"job_id"}))
+	mock.ExpectQuery(`FROM jobs\s+WHERE id = \$1 AND deleted_at IS NULL`).
+		WithArgs(int64(5)).
+		WillReturnRows(sqlmock.NewRows([]string{
+			"id", "job_type", "payload", "status", "priority", "attempts", "max_attempts",
+			"created_at", "updated_at", "scheduled_for", "last_error", "retry_after",
+			"processed_at", "completed_at", "worker_id", "metadata",
+		}).AddRow(int64(5), "sync_issues", nil, "processing", "normal", 1, 3,
+			time.Now(), time.Now(), nil, nil, nil, nil, nil, "worker-1", nil))
+
+	if err := s.DeleteJob(context.Background(), 5); !errors.Is(err, ErrJobNotDeletable) {
+		t.Fatalf("expected ErrJobNotDeletable, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
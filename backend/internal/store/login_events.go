@@ -0,0 +1,99 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+)
+
+const (
+	defaultLoginEventLimit = 50
+
+	// maxUserAgentLength bounds stored user agents, which are client
+	// controlled.
+	maxUserAgentLength = 512
+)
+
+// RecordLoginEvent appends a login by provider to the user's login history.
+func (s *Store) RecordLoginEvent(ctx context.Context, userID int64, provider, ipAddress, userAgent string) error {
+	if s == nil || s.db == nil {
+		return errors.New("store: db cannot be nil")
+	}
+
+	if _, err := s.db.ExecContext(ctx, `
+		INSERT INTO login_events (user_id, provider, ip_address, user_agent)
+		VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''))
+	`, userID, provider, ipAddress, truncateUserAgent(userAgent)); err != nil {
+		return fmt.Errorf("store: record login event: %w", err)
+	}
+	return nil
+}
+
+// RecordOAuthLoginEvent appends a login to the history of the user linked
+// to the provider account. Unknown accounts are ignored.
+func (s *Store) RecordOAuthLoginEvent(ctx context.Context, provider, providerAccountID, ipAddress, userAgent string) error {
+	if s == nil || s.db == nil {
+		return errors.New("store: db cannot be nil")
+	}
+
+	if _, err := s.db.ExecContext(ctx, `
+		INSERT INTO login_events (user_id, provider, ip_address, user_agent)
+		SELECT user_id, provider, NULLIF($3, ''), NULLIF($4, '')
+		FROM users_oauths
+		WHERE provider = $1 AND provider_account_id = $2
+	`, provider, providerAccountID, ipAddress, truncateUserAgent(userAgent)); err != nil {
+		return fmt.Errorf("store: record oauth login event: %w", err)
+	}
+	return nil
+}
+
+// ListLoginEvents returns the most recent logins for the user with email,
+// newest first.
+func (s *Store) ListLoginEvents(ctx context.Context, email string, limit int) ([]models.LoginEvent, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	if limit <= 0 {
+		limit = defaultLoginEventLimit
+	}
+	if limit > defaultPageSize {
+		limit = defaultPageSize
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT le.id, le.provider, le.ip_address, le.user_agent, le.created_at
+		FROM login_events le
+		JOIN users u ON u.id = le.user_id
+		WHERE LOWER(u.email) = LOWER($1) AND u.deleted_at IS NULL
+		ORDER BY le.created_at DESC, le.id DESC
+		LIMIT $2
+	`, email, limit)
+	if err != nil {
+		return nil, fmt.Errorf("store: list login events: %w", err)
+	}
+	defer rows.Close()
+
+	var logins []models.LoginEvent
+	for rows.Next() {
+		var login models.LoginEvent
+		if err := rows.Scan(&login.ID, &login.Provider, &login.IPAddress, &login.UserAgent, &login.CreatedAt); err != nil {
+			return nil, fmt.Errorf("store: scan login event: %w", err)
+		}
+		logins = append(logins, login)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("store: iterate login events: %w", err)
+	}
+	return logins, nil
+}
+
+func truncateUserAgent(userAgent string) string {
+	if len(userAgent) > maxUserAgentLength {
+		return strings.ToValidUTF8(userAgent[:maxUserAgentLength], "")
+	}
+	return userAgent
+}
@@ -0,0 +1,161 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+)
+
+// ErrMCPSessionNotFound is returned for an MCP session that does not exist
+// or has expired.
+var ErrMCPSessionNotFound = errors.New("mcp session not found")
+
+// SaveMCPSession creates or refreshes an MCP session and forgets the user's
+// expired ones.
+func (s *Store) SaveMCPSession(ctx context.Context, sess *models.MCPSession) error {
+	if s == nil || s.db == nil {
+		return errors.New("store: db cannot be nil")
+	}
+
+	if _, err := s.db.ExecContext(ctx, `
+		INSERT INTO mcp_sessions (id, user_id, protocol_version, client_info, client_capabilities, last_seen_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (id) DO UPDATE
+		SET last_seen_at = EXCLUDED.last_seen_at, expires_at = EXCLUDED.expires_at
+		WHERE mcp_sessions.user_id = EXCLUDED.user_id
+	`, sess.ID, sess.UserID, sess.ProtocolVersion, nullJSON(sess.ClientInfo), nullJSON(sess.ClientCapabilities), sess.LastSeenAt, sess.ExpiresAt); err != nil {
+		return fmt.Errorf("store: save mcp session: %w", err)
+	}
+	if _, err := s.db.ExecContext(ctx, `
+		DELETE FROM mcp_sessions WHERE user_id = $1 AND expires_at < now()
+	`, sess.UserID); err != nil {
+		return fmt.Errorf("store: trim mcp sessions: %w", err)
+	}
+	return nil
+}
+
+// GetMCPSession returns an unexpired MCP session.
+func (s *Store) GetMCPSession(ctx context.Context, id string) (*models.MCPSession, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	var sess models.MCPSession
+	var clientInfo, capabilities []byte
+	err := s.db.QueryRowContext(ctx, `
+		SELECT id, user_id, protocol_version, client_info, client_capabilities, last_seen_at, expires_at
+		FROM mcp_sessions
+		WHERE id = $1 AND expires_at > now()
+	`, id).Scan(&sess.ID, &sess.UserID, &sess.ProtocolVersion, &clientInfo, &capabilities, &sess.LastSeenAt, &sess.ExpiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrMCPSessionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("store: get mcp session: %w", err)
+	}
+	sess.ClientInfo = clientInfo
+	sess.ClientCapabilities = capabilities
+	return &sess, nil
+}
+
+// DeleteMCPSession ends an MCP session along with its pending calls.
+func (s *Store) DeleteMCPSession(ctx context.Context, id string) error {
+	if s == nil || s.db == nil {
+		return errors.New("store: db cannot be nil")
+	}
+
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM mcp_sessions WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("store: delete mcp session: %w", err)
+	}
+	return nil
+}
+
+// StartMCPCall records a tool call of the session as running.
+func (s *Store) StartMCPCall(ctx context.Context, sessionID, requestID string) error {
+	if s == nil || s.db == nil {
+		return errors.New("store: db cannot be nil")
+	}
+
+	if _, err := s.db.ExecContext(ctx, `
+		INSERT INTO mcp_pending_calls (session_id, request_id)
+		VALUES ($1, $2)
+		ON CONFLICT (session_id, request_id) DO UPDATE SET response = NULL, started_at = now()
+	`, sessionID, requestID); err != nil {
+		return fmt.Errorf("store: start mcp call: %w", err)
+	}
+	return nil
+}
+
+// FinishMCPCall keeps the response of a tool call that did not reach the
+// client.
+func (s *Store) FinishMCPCall(ctx context.Context, sessionID, requestID string, response []byte) error {
+	if s == nil || s.db == nil {
+		return errors.New("store: db cannot be nil")
+	}
+
+	if _, err := s.db.ExecContext(ctx, `
+		UPDATE mcp_pending_calls SET response = $3 WHERE session_id = $1 AND request_id = $2
+	`, sessionID, requestID, nullJSON(response)); err != nil {
+		return fmt.Errorf("store: finish mcp call: %w", err)
+	}
+	return nil
+}
+
+// ForgetMCPCall drops a tool call whose response has been delivered.
+func (s *Store) ForgetMCPCall(ctx context.Context, sessionID, requestID string) error {
+	if s == nil || s.db == nil {
+		return errors.New("store: db cannot be nil")
+	}
+
+	if _, err := s.db.ExecContext(ctx, `
+		DELETE FROM mcp_pending_calls WHERE session_id = $1 AND request_id = $2
+	`, sessionID, requestID); err != nil {
+		return fmt.Errorf("store: forget mcp call: %w", err)
+	}
+	return nil
+}
+
+// ListMCPCalls returns the session's pending tool calls, oldest first.
+func (s *Store) ListMCPCalls(ctx context.Context, sessionID string) ([]models.MCPPendingCall, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT request_id, response, started_at
+		FROM mcp_pending_calls
+		WHERE session_id = $1
+		ORDER BY started_at
+	`, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("store: list mcp calls: %w", err)
+	}
+	defer rows.Close()
+
+	var calls []models.MCPPendingCall
+	for rows.Next() {
+		var call models.MCPPendingCall
+		var response []byte
+		if err := rows.Scan(&call.RequestID, &response, &call.StartedAt); err != nil {
+			return nil, fmt.Errorf("store: scan mcp call: %w", err)
+		}
+		call.Response = response
+		calls = append(calls, call)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("store: iterate mcp calls: %w", err)
+	}
+	return calls, nil
+}
+
+// nullJSON passes encoded JSON as text, or NULL when empty; lib/pq would
+// send a []byte as bytea.
+func nullJSON(data []byte) any {
+	if len(data) == 0 {
+		return nil
+	}
+	return string(data)
+}
@@ -0,0 +1,186 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+)
+
+const defaultNotificationLimit = 50
+
+// ListNotifications returns the notification feed for the user identified by
+// email: currently active announcements merged with the user's own events,
+// newest first. When unreadOnly is set, entries the user has read are skipped.
+func (s *Store) ListNotifications(ctx context.Context, email string, unreadOnly bool, limit int) ([]models.Notification, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	if limit <= 0 {
+		limit = defaultNotificationLimit
+	}
+	if limit > defaultPageSize {
+		limit = defaultPageSize
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+WITH u AS (
+  SELECT id FROM users WHERE LOWER(email) = LOWER($1) AND deleted_at IS NULL
+)
+SELECT * FROM (
+  SELECT
+    a.id,
+    'announcement' AS source,
+    'announcement' AS kind,
+    a.title,
+    a.body,
+    a.severity,
+    '{}'::jsonb AS metadata,
+    ar.read_at IS NOT NULL AS read,
+    a.starts_at AS created_at
+  FROM announcements a
+  CROSS JOIN u
+  LEFT JOIN announcement_reads ar ON ar.announcement_id = a.id AND ar.user_id = u.id
+  WHERE a.starts_at <= now()
+    AND (a.ends_at IS NULL OR a.ends_at > now())
+  UNION ALL
+  SELECT
+    n.id,
+    'user' AS source,
+    n.kind,
+    n.title,
+    n.body,
+    n.severity,
+    n.metadata,
+    n.read_at IS NOT NULL AS read,
+    n.created_at
+  FROM user_notifications n
+  JOIN u ON n.user_id = u.id
+) feed
+WHERE NOT ($2 AND read)
+ORDER BY created_at DESC
+LIMIT $3
+`, email, unreadOnly, limit)
+	if err != nil {
+		return nil, fmt.Errorf("store: list notifications: %w", err)
+	}
+	defer rows.Close()
+
+	var notifications []models.Notification
+	for rows.Next() {
+		var n models.Notification
+		if err := rows.Scan(
+			&n.ID,
+			&n.Source,
+			&n.Kind,
+			&n.Title,
+			&n.Body,
+			&n.Severity,
+			&n.Metadata,
+			&n.Read,
+			&n.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("store: scan notification: %w", err)
+		}
+		notifications = append(notifications, n)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("store: iterate notifications: %w", err)
+	}
+
+	return notifications, nil
+}
+
+// CreateUserNotification records a per-user event in the notification feed.
+func (s *Store) CreateUserNotification(ctx context.Context, userID int64, kind, severity, title, body string, metadata models.JSONB) error {
+	if s == nil || s.db == nil {
+		return errors.New("store: db cannot be nil")
+	}
+
+	if severity == "" {
+		severity = "info"
+	}
+
+	if _, err := s.db.ExecContext(ctx, `
+INSERT INTO user_notifications (user_id, kind, severity, title, body, metadata)
+VALUES ($1, $2, $3, $4, $5, $6)
+`, userID, kind, severity, title, body, metadata); err != nil {
+		return fmt.Errorf("store: create user notification: %w", err)
+	}
+
+	return nil
+}
+
+// MarkNotificationsRead marks the referenced notifications as read for the
+// user identified by email. When refs is empty, every notification currently
+// visible to the user is marked as read.
+func (s *Store) MarkNotificationsRead(ctx context.Context, email string, refs []models.NotificationRef) error {
+	if s == nil || s.db == nil {
+		return errors.New("store: db cannot be nil")
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("store: begin mark notifications tx: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	var userID int64
+	if err := tx.QueryRowContext(ctx, `SELECT id FROM users WHERE LOWER(email) = LOWER($1) AND deleted_at IS NULL`, email).Scan(&userID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("store: no local user found for email=%s", email)
+		}
+		return fmt.Errorf("store: lookup user by email: %w", err)
+	}
+
+	if len(refs) == 0 {
+		if _, err := tx.ExecContext(ctx, `
+UPDATE user_notifications SET read_at = now()
+WHERE user_id = $1 AND read_at IS NULL
+`, userID); err != nil {
+			return fmt.Errorf("store: mark user notifications read: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, `
+INSERT INTO announcement_reads (announcement_id, user_id)
+SELECT id, $1 FROM announcements
+WHERE starts_at <= now() AND (ends_at IS NULL OR ends_at > now())
+ON CONFLICT (announcement_id, user_id) DO NOTHING
+`, userID); err != nil {
+			return fmt.Errorf("store: mark announcements read: %w", err)
+		}
+	}
+
+	for _, ref := range refs {
+		switch ref.Source {
+		case models.NotificationSourceAnnouncement:
+			if _, err := tx.ExecContext(ctx, `
+INSERT INTO announcement_reads (announcement_id, user_id)
+VALUES ($1, $2)
+ON CONFLICT (announcement_id, user_id) DO NOTHING
+`, ref.ID, userID); err != nil {
+				return fmt.Errorf("store: mark announcement %d read: %w", ref.ID, err)
+			}
+		case models.NotificationSourceUser:
+			if _, err := tx.ExecContext(ctx, `
+UPDATE user_notifications SET read_at = now()
+WHERE id = $1 AND user_id = $2 AND read_at IS NULL
+`, ref.ID, userID); err != nil {
+				return fmt.Errorf("store: mark notification %d read: %w", ref.ID, err)
+			}
+		default:
+			return fmt.Errorf("store: unknown notification source %q", ref.Source)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("store: commit mark notifications tx: %w", err)
+	}
+
+	return nil
+}
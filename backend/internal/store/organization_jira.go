@@ -0,0 +1,265 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+)
+
+// ErrOrganizationJiraAccountNotFound is returned when an organization has no
+// shared Jira account.
+var ErrOrganizationJiraAccountNotFound = errors.New("organization jira account not found")
+
+// sharedJiraOrgForUser selects the organization whose shared Jira account
+// serves the user in $1: the earliest joined active membership with an
+// enabled account, and only when the user has no Jira settings of their
+// own. GetUserSettingsByMCPSecret resolves credentials in the same order.
+const sharedJiraOrgForUser = `
+	SELECT a.org_id
+	FROM organization_members m
+	JOIN organization_jira_accounts a ON a.org_id = m.org_id AND a.enabled
+	WHERE m.user_id = $1 AND m.active
+	  AND NOT EXISTS (SELECT 1 FROM users_settings us WHERE us.user_id = m.user_id AND us.deleted_at IS NULL)
+	ORDER BY m.created_at, a.org_id
+	LIMIT 1`
+
+// getSharedJiraSettingsByMCPSecret resolves the shared Jira account of an
+// organization the mcp_secret's user belongs to.
+func (s *Store) getSharedJiraSettingsByMCPSecret(ctx context.Context, secret string) (*models.JiraUserSettingsWithSecret, error) {
+	var (
+		orgID    int64
+		region   sql.NullString
+		settings models.JiraUserSettingsWithSecret
+		cloudID  sql.NullString
+		defaults joinedIssueDefaults
+	)
+	dest := append([]any{&orgID, &region, &settings.JiraBaseURL, &settings.JiraEmail, &cloudID, &settings.AtlassianAPIToken, &settings.ServiceManagement}, defaults.dest()...)
+	if err := s.db.QueryRowContext(ctx, `
+		SELECT a.org_id, o.data_region, a.jira_base_url, a.jira_email, a.jira_cloud_id, a.jira_api_token,
+		       COALESCE(js.service_management, FALSE), `+issueDefaultsColumns+`
+		FROM users u
+		JOIN organization_members m ON m.user_id = u.id AND m.active
+		JOIN organization_jira_accounts a ON a.org_id = m.org_id AND a.enabled
+		JOIN organizations o ON o.id = m.org_id
+		LEFT JOIN jira_issue_defaults d ON d.user_id = u.id
+		`+jiraSiteJoin("a")+`
+		WHERE u.mcp_secret = $1 AND u.deleted_at IS NULL
+		ORDER BY m.created_at, a.org_id
+		LIMIT 1
+	`, secret).Scan(dest...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("store: no Jira settings found for provided mcp_secret")
+		}
+		return nil, fmt.Errorf("store: lookup shared jira account by mcp_secret: %w", err)
+	}
+
+	// The primary database only has a placeholder for accounts of
+	// organizations resident in another region.
+	if region.Valid {
+		db, err := s.regionDB(region.String)
+		if err != nil {
+			return nil, err
+		}
+		if err := db.QueryRowContext(ctx, `
+			SELECT jira_base_url, jira_email, jira_cloud_id, jira_api_token
+			FROM organization_jira_accounts
+			WHERE org_id = $1 AND enabled
+		`, orgID).Scan(&settings.JiraBaseURL, &settings.JiraEmail, &cloudID, &settings.AtlassianAPIToken); err != nil {
+			return nil, fmt.Errorf("store: lookup regional shared jira account: %w", err)
+		}
+	}
+
+	apiToken, err := s.open(settings.AtlassianAPIToken)
+	if err != nil {
+		return nil, err
+	}
+	settings.AtlassianAPIToken = apiToken
+	settings.JiraCloudID = nullStringPtr(cloudID)
+	settings.IsDefault = true
+	settings.AuthMethod = models.JiraAuthAPIToken
+	settings.OrgID = &orgID
+	settings.IssueDefaults = defaults.value()
+	return &settings, nil
+}
+
+// GetOrganizationJiraAccount returns the organization's shared Jira account.
+func (s *Store) GetOrganizationJiraAccount(ctx context.Context, orgID int64) (*models.OrganizationJiraAccount, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	region, err := s.orgRegion(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	db, err := s.regionDB(region)
+	if err != nil {
+		return nil, err
+	}
+
+	account := models.OrganizationJiraAccount{OrgID: orgID}
+	var cloudID sql.NullString
+	if err := db.QueryRowContext(ctx, `
+		SELECT jira_base_url, jira_email, jira_cloud_id, jira_api_token, enabled, updated_at
+		FROM organization_jira_accounts
+		WHERE org_id = $1
+	`, orgID).Scan(&account.JiraBaseURL, &account.JiraEmail, &cloudID, &account.APIToken,
+		&account.Enabled, &account.UpdatedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrOrganizationJiraAccountNotFound
+		}
+		return nil, fmt.Errorf("store: get organization jira account: %w", err)
+	}
+
+	if account.APIToken, err = s.open(account.APIToken); err != nil {
+		return nil, err
+	}
+	account.JiraCloudID = nullStringPtr(cloudID)
+	return &account, nil
+}
+
+// UpsertOrganizationJiraAccount stores the organization's shared Jira
+// account on behalf of the admin with the given email. An empty API token
+// keeps the stored one. Accounts of organizations resident in another region
+// are stored there, leaving a placeholder without credentials behind.
+func (s *Store) UpsertOrganizationJiraAccount(ctx context.Context, adminEmail string, account models.OrganizationJiraAccount) error {
+	if s == nil || s.db == nil {
+		return errors.New("store: db cannot be nil")
+	}
+
+	region, err := s.orgRegion(ctx, account.OrgID)
+	if err != nil {
+		return err
+	}
+	if account.APIToken, err = s.seal(account.APIToken); err != nil {
+		return err
+	}
+	if region != "" {
+		return s.upsertRegionalOrganizationJiraAccount(ctx, region, adminEmail, account)
+	}
+
+	if _, err := s.db.ExecContext(ctx, `
+		INSERT INTO organization_jira_accounts (
+			org_id, jira_base_url, jira_email, jira_cloud_id, jira_api_token, enabled, updated_by
+		) VALUES ($1, $2, $3, $4, $5, $6, (SELECT id FROM users WHERE LOWER(email) = LOWER($7) AND deleted_at IS NULL LIMIT 1))
+		ON CONFLICT (org_id) DO UPDATE
+		SET jira_base_url = EXCLUDED.jira_base_url,
+		    jira_email = EXCLUDED.jira_email,
+		    jira_cloud_id = EXCLUDED.jira_cloud_id,
+		    jira_api_token = COALESCE(NULLIF(EXCLUDED.jira_api_token, ''), organization_jira_accounts.jira_api_token),
+		    enabled = EXCLUDED.enabled,
+		    updated_by = EXCLUDED.updated_by,
+		    updated_at = now()
+	`, account.OrgID, account.JiraBaseURL, account.JiraEmail, account.JiraCloudID, account.APIToken,
+		account.Enabled, adminEmail); err != nil {
+		return fmt.Errorf("store: upsert organization jira account: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteOrganizationJiraAccount removes the organization's shared Jira
+// account. Members without settings of their own lose Jira access.
+func (s *Store) DeleteOrganizationJiraAccount(ctx context.Context, orgID int64) error {
+	if s == nil || s.db == nil {
+		return errors.New("store: db cannot be nil")
+	}
+
+	region, err := s.orgRegion(ctx, orgID)
+	if err != nil {
+		return err
+	}
+	if region != "" {
+		db, err := s.regionDB(region)
+		if err != nil {
+			return err
+		}
+		if _, err := db.ExecContext(ctx, `DELETE FROM organization_jira_accounts WHERE org_id = $1`, orgID); err != nil {
+			return fmt.Errorf("store: delete regional organization jira account: %w", err)
+		}
+	}
+
+	if _, err := s.db.ExecContext(ctx, `
+		DELETE FROM organization_jira_accounts WHERE org_id = $1
+	`, orgID); err != nil {
+		return fmt.Errorf("store: delete organization jira account: %w", err)
+	}
+
+	return nil
+}
+
+// upsertRegionalOrganizationJiraAccount stores the shared Jira account in
+// the organization's regional database and its placeholder in the primary
+// one.
+func (s *Store) upsertRegionalOrganizationJiraAccount(ctx context.Context, region, adminEmail string, account models.OrganizationJiraAccount) error {
+	db, err := s.regionDB(region)
+	if err != nil {
+		return err
+	}
+
+	var updatedBy sql.NullInt64
+	if err := s.db.QueryRowContext(ctx, `SELECT id FROM users WHERE LOWER(email) = LOWER($1) AND deleted_at IS NULL LIMIT 1`, adminEmail).Scan(&updatedBy); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("store: lookup organization admin: %w", err)
+	}
+
+	if _, err := db.ExecContext(ctx, `
+		INSERT INTO organization_jira_accounts (
+			org_id, jira_base_url, jira_email, jira_cloud_id, jira_api_token, enabled, updated_by
+		) VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (org_id) DO UPDATE
+		SET jira_base_url = EXCLUDED.jira_base_url,
+		    jira_email = EXCLUDED.jira_email,
+		    jira_cloud_id = EXCLUDED.jira_cloud_id,
+		    jira_api_token = COALESCE(NULLIF(EXCLUDED.jira_api_token, ''), organization_jira_accounts.jira_api_token),
+		    enabled = EXCLUDED.enabled,
+		    updated_by = EXCLUDED.updated_by,
+		    updated_at = now()
+	`, account.OrgID, account.JiraBaseURL, account.JiraEmail, account.JiraCloudID, account.APIToken,
+		account.Enabled, updatedBy); err != nil {
+		return fmt.Errorf("store: upsert regional organization jira account: %w", err)
+	}
+
+	return upsertOrganizationJiraAccountRow(ctx, s.db, account.OrgID, "", "", sql.NullString{}, "", account.Enabled, updatedBy)
+}
+
+// ListOrganizationJiraUsage aggregates tool invocations made with the
+// organization's shared Jira account in [from, to) by member, most
+// expensive first.
+func (s *Store) ListOrganizationJiraUsage(ctx context.Context, orgID int64, from, to time.Time) ([]models.OrganizationMemberUsage, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT u.id, u.email, u.name, COUNT(*), COUNT(*) FILTER (WHERE t.is_error), COALESCE(SUM(t.cost_units), 0)
+		FROM tool_invocations t
+		JOIN users u ON u.id = t.user_id
+		WHERE t.org_id = $1 AND u.deleted_at IS NULL AND t.created_at >= $2 AND t.created_at < $3
+		GROUP BY u.id, u.email, u.name
+		ORDER BY 6 DESC, u.email
+	`, orgID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("store: list organization jira usage: %w", err)
+	}
+	defer rows.Close()
+
+	var usage []models.OrganizationMemberUsage
+	for rows.Next() {
+		var u models.OrganizationMemberUsage
+		var email sql.NullString
+		if err := rows.Scan(&u.UserID, &email, &u.Name, &u.Invocations, &u.Errors, &u.CostUnits); err != nil {
+			return nil, fmt.Errorf("store: scan organization jira usage: %w", err)
+		}
+		u.Email = email.String
+		usage = append(usage, u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("store: iterate organization jira usage: %w", err)
+	}
+
+	return usage, nil
+}
@@ -0,0 +1,501 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/events"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+)
+
+// ErrOrganizationNotFound is returned when an organization does not exist or
+// the caller is not a member of it.
+var ErrOrganizationNotFound = errors.New("organization not found")
+
+// ErrOrganizationSlugTaken is returned when creating an organization whose
+// slug is already in use.
+var ErrOrganizationSlugTaken = errors.New("organization slug already taken")
+
+// ErrDomainClaimed is returned when a domain is already claimed by another
+// organization.
+var ErrDomainClaimed = errors.New("domain already claimed by another organization")
+
+// ErrSSOConfigNotFound is returned when an organization has no identity
+// provider configured.
+var ErrSSOConfigNotFound = errors.New("sso config not found")
+
+// ErrSSODomainNotAllowed is returned when an identity provider asserts an
+// email outside the organization's claimed domains.
+var ErrSSODomainNotAllowed = errors.New("email domain is not claimed by the organization")
+
+// ErrSSOAccountConflict is returned when an identity provider asserts the
+// email of an existing user who is not a member of the organization.
+var ErrSSOAccountConflict = errors.New("email belongs to a user outside the organization")
+
+// ErrSSOMemberDeactivated is returned when an identity provider asserts a
+// member the organization has deprovisioned.
+var ErrSSOMemberDeactivated = errors.New("organization membership is deactivated")
+
+const organizationColumns = `o.id, o.slug, o.name, o.sso_enforced, o.created_at, o.updated_at, o.data_region`
+
+func scanOrganization(row interface{ Scan(...any) error }, extra ...any) (*models.Organization, error) {
+	var org models.Organization
+	dest := append([]any{&org.ID, &org.Slug, &org.Name, &org.SSOEnforced, &org.CreatedAt, &org.UpdatedAt, &org.DataRegion}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+	return &org, nil
+}
+
+// CreateOrganization creates an organization owned by the user with the
+// given email.
+func (s *Store) CreateOrganization(ctx context.Context, ownerEmail, slug, name string) (*models.Organization, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("store: begin create organization tx: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	var ownerID int64
+	if err := tx.QueryRowContext(ctx,
+		`SELECT id FROM users WHERE LOWER(email) = LOWER($1) AND deleted_at IS NULL LIMIT 1`, ownerEmail,
+	).Scan(&ownerID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("store: user not found")
+		}
+		return nil, fmt.Errorf("store: lookup organization owner: %w", err)
+	}
+
+	org, err := scanOrganization(tx.QueryRowContext(ctx, `
+		INSERT INTO organizations AS o (slug, name) VALUES ($1, $2)
+		ON CONFLICT (slug) DO NOTHING
+		RETURNING `+organizationColumns,
+		slug, name))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrOrganizationSlugTaken
+		}
+		return nil, fmt.Errorf("store: insert organization: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO organization_members (org_id, user_id, role) VALUES ($1, $2, $3)
+	`, org.ID, ownerID, models.OrgRoleOwner); err != nil {
+		return nil, fmt.Errorf("store: insert organization owner: %w", err)
+	}
+
+	if err := s.enqueueEvent(ctx, tx, events.MembershipChanged{
+		OrgID: org.ID, UserID: ownerID, Change: events.MembershipAdded, Source: "owner",
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("store: commit create organization tx: %w", err)
+	}
+
+	s.wakeOutbox()
+
+	org.Role = models.OrgRoleOwner
+	return org, nil
+}
+
+// ListUserOrganizations returns the organizations the user belongs to along
+// with their role in each.
+func (s *Store) ListUserOrganizations(ctx context.Context, email string) ([]models.Organization, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+organizationColumns+`, m.role
+		FROM organizations o
+		JOIN organization_members m ON m.org_id = o.id
+		JOIN users u ON u.id = m.user_id
+		WHERE LOWER(u.email) = LOWER($1) AND u.deleted_at IS NULL AND m.active
+		ORDER BY o.name
+	`, email)
+	if err != nil {
+		return nil, fmt.Errorf("store: list user organizations: %w", err)
+	}
+	defer rows.Close()
+
+	var orgs []models.Organization
+	for rows.Next() {
+		var role string
+		org, err := scanOrganization(rows, &role)
+		if err != nil {
+			return nil, fmt.Errorf("store: scan organization: %w", err)
+		}
+		org.Role = role
+		orgs = append(orgs, *org)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("store: iterate organizations: %w", err)
+	}
+
+	return orgs, nil
+}
+
+// GetOrganizationMembership returns the organization with the given slug and
+// the user's role in it, or ErrOrganizationNotFound if the user is not a
+// member.
+func (s *Store) GetOrganizationMembership(ctx context.Context, email, slug string) (*models.Organization, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	var role string
+	org, err := scanOrganization(s.db.QueryRowContext(ctx, `
+		SELECT `+organizationColumns+`, m.role
+		FROM organizations o
+		JOIN organization_members m ON m.org_id = o.id
+		JOIN users u ON u.id = m.user_id
+		WHERE o.slug = $1 AND LOWER(u.email) = LOWER($2) AND u.deleted_at IS NULL AND m.active
+	`, slug, email), &role)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrOrganizationNotFound
+		}
+		return nil, fmt.Errorf("store: get organization membership: %w", err)
+	}
+
+	org.Role = role
+	return org, nil
+}
+
+// GetOrganizationBySlug returns the organization with the given slug.
+func (s *Store) GetOrganizationBySlug(ctx context.Context, slug string) (*models.Organization, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	org, err := scanOrganization(s.db.QueryRowContext(ctx, `
+		SELECT `+organizationColumns+` FROM organizations o WHERE o.slug = $1
+	`, slug))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrOrganizationNotFound
+		}
+		return nil, fmt.Errorf("store: get organization by slug: %w", err)
+	}
+
+	return org, nil
+}
+
+// GetOrganizationByDomain returns the organization that claimed the domain.
+func (s *Store) GetOrganizationByDomain(ctx context.Context, domain string) (*models.Organization, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	org, err := scanOrganization(s.db.QueryRowContext(ctx, `
+		SELECT `+organizationColumns+`
+		FROM organizations o
+		JOIN organization_domains d ON d.org_id = o.id
+		WHERE d.domain = LOWER($1)
+	`, domain))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrOrganizationNotFound
+		}
+		return nil, fmt.Errorf("store: get organization by domain: %w", err)
+	}
+
+	return org, nil
+}
+
+// ListOrganizationDomains returns the domains claimed by the organization.
+func (s *Store) ListOrganizationDomains(ctx context.Context, orgID int64) ([]models.OrganizationDomain, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+organizationDomainColumns+` FROM organization_domains WHERE org_id = $1 ORDER BY domain
+	`, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("store: list organization domains: %w", err)
+	}
+	defer rows.Close()
+
+	var domains []models.OrganizationDomain
+	for rows.Next() {
+		d, err := scanOrganizationDomain(rows)
+		if err != nil {
+			return nil, fmt.Errorf("store: scan organization domain: %w", err)
+		}
+		domains = append(domains, *d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("store: iterate organization domains: %w", err)
+	}
+
+	return domains, nil
+}
+
+// AddOrganizationDomain claims a domain for the organization. Claiming a
+// domain the organization already owns is a no-op.
+func (s *Store) AddOrganizationDomain(ctx context.Context, orgID int64, domain string) error {
+	if s == nil || s.db == nil {
+		return errors.New("store: db cannot be nil")
+	}
+
+	var owner int64
+	if err := s.db.QueryRowContext(ctx, `
+		INSERT INTO organization_domains (domain, org_id) VALUES (LOWER($1), $2)
+		ON CONFLICT (domain) DO UPDATE SET domain = organization_domains.domain
+		RETURNING org_id
+	`, domain, orgID).Scan(&owner); err != nil {
+		return fmt.Errorf("store: add organization domain: %w", err)
+	}
+	if owner != orgID {
+		return ErrDomainClaimed
+	}
+
+	return nil
+}
+
+// RemoveOrganizationDomain releases a domain claimed by the organization.
+func (s *Store) RemoveOrganizationDomain(ctx context.Context, orgID int64, domain string) error {
+	if s == nil || s.db == nil {
+		return errors.New("store: db cannot be nil")
+	}
+
+	if _, err := s.db.ExecContext(ctx, `
+		DELETE FROM organization_domains WHERE org_id = $1 AND domain = LOWER($2)
+	`, orgID, domain); err != nil {
+		return fmt.Errorf("store: remove organization domain: %w", err)
+	}
+
+	return nil
+}
+
+// GetSSOConfig returns the organization's identity provider configuration.
+func (s *Store) GetSSOConfig(ctx context.Context, orgID int64) (*models.SSOConfig, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	var cfg models.SSOConfig
+	var issuer, clientID, clientSecret, entityID, ssoURL, cert sql.NullString
+	if err := s.db.QueryRowContext(ctx, `
+		SELECT org_id, protocol, enabled, oidc_issuer, oidc_client_id, oidc_client_secret,
+		       saml_idp_entity_id, saml_idp_sso_url, saml_idp_certificate, updated_at
+		FROM organization_sso_configs
+		WHERE org_id = $1
+	`, orgID).Scan(&cfg.OrgID, &cfg.Protocol, &cfg.Enabled, &issuer, &clientID, &clientSecret,
+		&entityID, &ssoURL, &cert, &cfg.UpdatedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrSSOConfigNotFound
+		}
+		return nil, fmt.Errorf("store: get sso config: %w", err)
+	}
+
+	cfg.OIDCIssuer = issuer.String
+	cfg.OIDCClientID = clientID.String
+	cfg.OIDCClientSecret = clientSecret.String
+	cfg.SAMLIdPEntityID = entityID.String
+	cfg.SAMLIdPSSOURL = ssoURL.String
+	cfg.SAMLIdPCertificate = cert.String
+
+	return &cfg, nil
+}
+
+// UpsertSSOConfig stores the organization's identity provider configuration.
+// An empty OIDC client secret keeps the previously stored one.
+func (s *Store) UpsertSSOConfig(ctx context.Context, cfg models.SSOConfig) error {
+	if s == nil || s.db == nil {
+		return errors.New("store: db cannot be nil")
+	}
+
+	if _, err := s.db.ExecContext(ctx, `
+		INSERT INTO organization_sso_configs (
+			org_id, protocol, enabled, oidc_issuer, oidc_client_id, oidc_client_secret,
+			saml_idp_entity_id, saml_idp_sso_url, saml_idp_certificate
+		) VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''), NULLIF($6, ''), NULLIF($7, ''), NULLIF($8, ''), NULLIF($9, ''))
+		ON CONFLICT (org_id) DO UPDATE
+		SET protocol = EXCLUDED.protocol,
+		    enabled = EXCLUDED.enabled,
+		    oidc_issuer = EXCLUDED.oidc_issuer,
+		    oidc_client_id = EXCLUDED.oidc_client_id,
+		    oidc_client_secret = COALESCE(EXCLUDED.oidc_client_secret, organization_sso_configs.oidc_client_secret),
+		    saml_idp_entity_id = EXCLUDED.saml_idp_entity_id,
+		    saml_idp_sso_url = EXCLUDED.saml_idp_sso_url,
+		    saml_idp_certificate = EXCLUDED.saml_idp_certificate,
+		    updated_at = now()
+	`, cfg.OrgID, cfg.Protocol, cfg.Enabled, cfg.OIDCIssuer, cfg.OIDCClientID, cfg.OIDCClientSecret,
+		cfg.SAMLIdPEntityID, cfg.SAMLIdPSSOURL, cfg.SAMLIdPCertificate); err != nil {
+		return fmt.Errorf("store: upsert sso config: %w", err)
+	}
+
+	return nil
+}
+
+// SetOrganizationSSOEnforced toggles whether members must sign in through the
+// organization's identity provider.
+func (s *Store) SetOrganizationSSOEnforced(ctx context.Context, orgID int64, enforced bool) error {
+	if s == nil || s.db == nil {
+		return errors.New("store: db cannot be nil")
+	}
+
+	if _, err := s.db.ExecContext(ctx, `
+		UPDATE organizations SET sso_enforced = $2, updated_at = now() WHERE id = $1
+	`, orgID, enforced); err != nil {
+		return fmt.Errorf("store: set organization sso enforced: %w", err)
+	}
+
+	return nil
+}
+
+// GetEnforcedSSOOrganization returns an organization the user belongs to that
+// requires SSO through an enabled identity provider, or nil if none does.
+func (s *Store) GetEnforcedSSOOrganization(ctx context.Context, email string) (*models.Organization, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	org, err := scanOrganization(s.db.QueryRowContext(ctx, `
+		SELECT `+organizationColumns+`
+		FROM organizations o
+		JOIN organization_members m ON m.org_id = o.id
+		JOIN users u ON u.id = m.user_id
+		JOIN organization_sso_configs c ON c.org_id = o.id
+		WHERE LOWER(u.email) = LOWER($1) AND u.deleted_at IS NULL AND m.active AND o.sso_enforced AND c.enabled
+		ORDER BY o.id
+		LIMIT 1
+	`, email))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("store: get enforced sso organization: %w", err)
+	}
+
+	return org, nil
+}
+
+// ProvisionSSOUser signs in a user asserted by the organization's identity
+// provider. The email must belong to one of the organization's claimed
+// domains. Unknown users are created and added as members; existing users
+// must already be active members, so an identity provider cannot take over
+// accounts it does not administer. It returns the user's id.
+func (s *Store) ProvisionSSOUser(ctx context.Context, orgID int64, identity models.SSOIdentity) (int64, error) {
+	if s == nil || s.db == nil {
+		return 0, errors.New("store: db cannot be nil")
+	}
+
+	email := strings.ToLower(strings.TrimSpace(identity.Email))
+	at := strings.LastIndex(email, "@")
+	if at <= 0 || at == len(email)-1 {
+		return 0, ErrSSODomainNotAllowed
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("store: begin provision sso user tx: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	var claimed bool
+	if err := tx.QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM organization_domains WHERE org_id = $1 AND domain = $2)
+	`, orgID, email[at+1:]).Scan(&claimed); err != nil {
+		return 0, fmt.Errorf("store: check sso domain: %w", err)
+	}
+	if !claimed {
+		return 0, ErrSSODomainNotAllowed
+	}
+
+	accountID := fmt.Sprintf("%d:%s", orgID, identity.Subject)
+
+	var userID int64
+	var member, deleted bool
+	err = tx.QueryRowContext(ctx, `
+		SELECT u.id, EXISTS (SELECT 1 FROM organization_members m WHERE m.org_id = $2 AND m.user_id = u.id),
+			u.deleted_at IS NOT NULL
+		FROM users u
+		WHERE LOWER(u.email) = $1
+		LIMIT 1
+	`, email, orgID).Scan(&userID, &member, &deleted)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		if err := tx.QueryRowContext(ctx, `
+			INSERT INTO users (login, name, email, provider, provider_account_id, email_verified_at)
+			VALUES ($1, $2, $1, 'sso', $3, now())
+			ON CONFLICT (provider, provider_account_id) DO UPDATE
+			SET email = EXCLUDED.email,
+			    name = COALESCE(EXCLUDED.name, users.name),
+			    email_verified_at = now(),
+			    updated_at = now()
+			RETURNING id
+		`, email, identity.Name, accountID).Scan(&userID); err != nil {
+			return 0, fmt.Errorf("store: insert sso user: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO organization_members (org_id, user_id, role) VALUES ($1, $2, $3)
+			ON CONFLICT (org_id, user_id) DO NOTHING
+		`, orgID, userID, models.OrgRoleMember); err != nil {
+			return 0, fmt.Errorf("store: add sso user to organization: %w", err)
+		}
+		if err := s.enqueueEvent(ctx, tx, events.MembershipChanged{
+			OrgID: orgID, UserID: userID, Change: events.MembershipAdded, Source: "sso",
+		}); err != nil {
+			return 0, err
+		}
+	case err != nil:
+		return 0, fmt.Errorf("store: lookup sso user by email: %w", err)
+	case deleted:
+		return 0, ErrAccountDeleted
+	case !member:
+		return 0, ErrSSOAccountConflict
+	default:
+		var active bool
+		if err := tx.QueryRowContext(ctx, `
+			SELECT active FROM organization_members WHERE org_id = $1 AND user_id = $2
+		`, orgID, userID).Scan(&active); err != nil {
+			return 0, fmt.Errorf("store: check sso membership: %w", err)
+		}
+		if !active {
+			return 0, ErrSSOMemberDeactivated
+		}
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE users SET name = COALESCE($2, name), updated_at = now() WHERE id = $1
+		`, userID, identity.Name); err != nil {
+			return 0, fmt.Errorf("store: update sso user: %w", err)
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO users_oauths (user_id, provider, provider_account_id, access_token, scope)
+		VALUES ($1, 'sso', $2, '', '')
+		ON CONFLICT (provider, provider_account_id) DO UPDATE
+		SET user_id = EXCLUDED.user_id,
+		    updated_at = now()
+	`, userID, accountID); err != nil {
+		return 0, fmt.Errorf("store: upsert users_oauths (sso): %w", err)
+	}
+
+	if err := s.enqueueEvent(ctx, tx, events.UserUpserted{UserID: userID, Email: email, Provider: "sso"}); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("store: commit provision sso user tx: %w", err)
+	}
+
+	s.wakeOutbox()
+
+	return userID, nil
+}
@@ -0,0 +1,46 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+)
+
+// ListRecentOutboxEvents returns the limit most recent events in the outbox
+// whose topic starts with topicPrefix, whether delivered or not. Delivered
+// events are kept until the outbox dispatcher purges them.
+func (s *Store) ListRecentOutboxEvents(ctx context.Context, topicPrefix string, limit int) ([]models.OutboxEvent, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, topic, payload, attempts, last_error, available_at, dispatched_at, failed_at, created_at
+		FROM event_outbox
+		WHERE starts_with(topic, $1)
+		ORDER BY id DESC
+		LIMIT $2
+	`, topicPrefix, limit)
+	if err != nil {
+		return nil, fmt.Errorf("store: list outbox events: %w", err)
+	}
+	defer rows.Close()
+
+	var list []models.OutboxEvent
+	for rows.Next() {
+		var ev models.OutboxEvent
+		var payload []byte
+		if err := rows.Scan(&ev.ID, &ev.Topic, &payload, &ev.Attempts, &ev.LastError, &ev.AvailableAt,
+			&ev.DispatchedAt, &ev.FailedAt, &ev.CreatedAt); err != nil {
+			return nil, fmt.Errorf("store: scan outbox event: %w", err)
+		}
+		ev.Payload = payload
+		list = append(list, ev)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("store: iterate outbox events: %w", err)
+	}
+	return list, nil
+}
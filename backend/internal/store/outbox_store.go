@@ -0,0 +1,167 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/events"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+)
+
+// OutboxStore persists events in the event_outbox table so they survive a
+// crash between the state change and delivery.
+type OutboxStore struct {
+	db     *sql.DB
+	notify chan struct{}
+}
+
+// NewOutboxStore creates a new OutboxStore instance.
+func NewOutboxStore(db *sql.DB) (*OutboxStore, error) {
+	if db == nil {
+		return nil, errors.New("db cannot be nil")
+	}
+	return &OutboxStore{db: db, notify: make(chan struct{}, 1)}, nil
+}
+
+// execer is satisfied by both *sql.DB and *sql.Tx.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+func (s *OutboxStore) insert(ctx context.Context, ex execer, ev events.Event) error {
+	payload, err := events.Encode(ev)
+	if err != nil {
+		return err
+	}
+	if _, err := ex.ExecContext(ctx,
+		`INSERT INTO event_outbox (topic, payload) VALUES ($1, $2)`,
+		string(ev.Topic()), payload,
+	); err != nil {
+		return fmt.Errorf("store: insert outbox event %s: %w", ev.Topic(), err)
+	}
+	return nil
+}
+
+// EnqueueTx writes ev to the outbox as part of tx. The event is only
+// delivered if tx commits; call Wake after committing to deliver promptly.
+func (s *OutboxStore) EnqueueTx(ctx context.Context, tx *sql.Tx, ev events.Event) error {
+	return s.insert(ctx, tx, ev)
+}
+
+// Enqueue writes ev to the outbox outside of any transaction and wakes the
+// dispatcher.
+func (s *OutboxStore) Enqueue(ctx context.Context, ev events.Event) error {
+	if err := s.insert(ctx, s.db, ev); err != nil {
+		return err
+	}
+	s.Wake()
+	return nil
+}
+
+// Publish enqueues ev, logging rather than returning failures, so the outbox
+// can stand in wherever an event publisher is expected.
+func (s *OutboxStore) Publish(ctx context.Context, ev events.Event) {
+	if err := s.Enqueue(ctx, ev); err != nil {
+		log.Printf("[outbox] failed to enqueue %s: %v", ev.Topic(), err)
+	}
+}
+
+// Wake signals the dispatcher that new events are available.
+func (s *OutboxStore) Wake() {
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+}
+
+// Notifications returns the channel signalled by Wake.
+func (s *OutboxStore) Notifications() <-chan struct{} {
+	return s.notify
+}
+
+// ClaimBatch leases up to limit pending events for lease. Claimed events are
+// hidden from other dispatchers until the lease expires, so an event whose
+// dispatcher crashes mid-delivery is retried.
+func (s *OutboxStore) ClaimBatch(ctx context.Context, limit int, lease time.Duration) ([]*models.OutboxEvent, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		UPDATE event_outbox
+		SET attempts = attempts + 1,
+		    available_at = NOW() + make_interval(secs => $2)
+		WHERE id IN (
+			SELECT id FROM event_outbox
+			WHERE dispatched_at IS NULL
+			  AND failed_at IS NULL
+			  AND available_at <= NOW()
+			ORDER BY id
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, topic, payload, attempts, last_error, available_at, created_at
+	`, limit, lease.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("claim outbox events: %w", err)
+	}
+	defer rows.Close()
+
+	var claimed []*models.OutboxEvent
+	for rows.Next() {
+		ev := &models.OutboxEvent{}
+		var payload []byte
+		if err := rows.Scan(&ev.ID, &ev.Topic, &payload, &ev.Attempts, &ev.LastError, &ev.AvailableAt, &ev.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan outbox event: %w", err)
+		}
+		ev.Payload = payload
+		claimed = append(claimed, ev)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate outbox events: %w", err)
+	}
+
+	return claimed, nil
+}
+
+// MarkDispatched records that an event has been delivered.
+func (s *OutboxStore) MarkDispatched(ctx context.Context, id int64) error {
+	if _, err := s.db.ExecContext(ctx,
+		`UPDATE event_outbox SET dispatched_at = NOW(), last_error = NULL WHERE id = $1`, id,
+	); err != nil {
+		return fmt.Errorf("mark outbox event dispatched: %w", err)
+	}
+	return nil
+}
+
+// MarkFailed records a delivery failure. The event becomes available again at
+// retryAt, or is parked permanently when retryAt is nil.
+func (s *OutboxStore) MarkFailed(ctx context.Context, id int64, errMsg string, retryAt *time.Time) error {
+	var err error
+	if retryAt != nil {
+		_, err = s.db.ExecContext(ctx,
+			`UPDATE event_outbox SET last_error = $1, available_at = $2 WHERE id = $3`,
+			errMsg, *retryAt, id)
+	} else {
+		_, err = s.db.ExecContext(ctx,
+			`UPDATE event_outbox SET last_error = $1, failed_at = NOW() WHERE id = $2`,
+			errMsg, id)
+	}
+	if err != nil {
+		return fmt.Errorf("mark outbox event failed: %w", err)
+	}
+	return nil
+}
+
+// PurgeDispatched deletes delivered events older than the given age and
+// returns the number of rows removed.
+func (s *OutboxStore) PurgeDispatched(ctx context.Context, olderThan time.Duration) (int64, error) {
+	res, err := s.db.ExecContext(ctx,
+		`DELETE FROM event_outbox WHERE dispatched_at IS NOT NULL AND dispatched_at < NOW() - make_interval(secs => $1)`,
+		olderThan.Seconds())
+	if err != nil {
+		return 0, fmt.Errorf("purge outbox events: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return n, nil
+}
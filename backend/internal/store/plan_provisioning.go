@@ -0,0 +1,117 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+)
+
+// ErrPlanPriceChange is returned when provisioning a plan whose active
+// version has a different price; prices change through a rollout instead.
+var ErrPlanPriceChange = errors.New("plan price differs from its active version")
+
+// ProvisionPlan makes the plan with plan.Slug match plan: it is created if
+// missing, and its name, description, tier, availability and quotas are
+// updated otherwise. A plan without an active version gets one with
+// version's price, currency and billing interval; an existing active version
+// must already have them, or ErrPlanPriceChange is returned. plan and
+// version are filled in from the stored rows. Provisioning a plan that
+// already matches writes nothing; it reports whether anything changed.
+func (s *PlanStore) ProvisionPlan(ctx context.Context, plan *models.MembershipPlan, version *models.PlanVersion) (bool, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("provision plan: begin tx: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	changed := true
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO membership_plans (slug, name, description, tier, is_active, monthly_request_quota, monthly_cost_unit_quota, max_streaming_connections, requests_per_minute)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (slug) DO UPDATE
+		SET name = EXCLUDED.name,
+		    description = EXCLUDED.description,
+		    tier = EXCLUDED.tier,
+		    is_active = EXCLUDED.is_active,
+		    monthly_request_quota = EXCLUDED.monthly_request_quota,
+		    monthly_cost_unit_quota = EXCLUDED.monthly_cost_unit_quota,
+		    max_streaming_connections = EXCLUDED.max_streaming_connections,
+		    requests_per_minute = EXCLUDED.requests_per_minute,
+		    revision = membership_plans.revision + 1,
+		    updated_at = now()
+		WHERE (membership_plans.name, membership_plans.description, membership_plans.tier, membership_plans.is_active,
+		       membership_plans.monthly_request_quota, membership_plans.monthly_cost_unit_quota, membership_plans.max_streaming_connections,
+		       membership_plans.requests_per_minute)
+		      IS DISTINCT FROM (EXCLUDED.name, EXCLUDED.description, EXCLUDED.tier, EXCLUDED.is_active,
+		       EXCLUDED.monthly_request_quota, EXCLUDED.monthly_cost_unit_quota, EXCLUDED.max_streaming_connections,
+		       EXCLUDED.requests_per_minute)
+		RETURNING id, revision, created_at, updated_at
+	`, plan.Slug, plan.Name, plan.Description, plan.Tier, plan.IsActive, plan.MonthlyRequestQuota, plan.MonthlyCostUnitQuota, plan.MaxStreamingConnections, plan.RequestsPerMinute,
+	).Scan(&plan.ID, &plan.Revision, &plan.CreatedAt, &plan.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		changed = false
+		err = tx.QueryRowContext(ctx,
+			`SELECT id, revision, created_at, updated_at FROM membership_plans WHERE slug = $1`, plan.Slug,
+		).Scan(&plan.ID, &plan.Revision, &plan.CreatedAt, &plan.UpdatedAt)
+	}
+	if err != nil {
+		return false, fmt.Errorf("provision plan %s: %w", plan.Slug, err)
+	}
+
+	want := *version
+	err = tx.QueryRowContext(ctx, `
+		SELECT id, plan_id, version, stripe_product_id, stripe_price_id,
+			price_cents, currency, billing_interval, status,
+			deprecated_at, grace_period_days, migration_deadline, archived_at, migration_policy,
+			created_at, updated_at
+		FROM plan_versions
+		WHERE plan_id = $1 AND status = 'active'
+		ORDER BY version DESC
+		LIMIT 1
+		FOR UPDATE
+	`, plan.ID).Scan(
+		&version.ID, &version.PlanID, &version.Version, &version.StripeProductID, &version.StripePriceID,
+		&version.PriceCents, &version.Currency, &version.BillingInterval, &version.Status,
+		&version.DeprecatedAt, &version.GracePeriodDays, &version.MigrationDeadline, &version.ArchivedAt, &version.MigrationPolicy,
+		&version.CreatedAt, &version.UpdatedAt,
+	)
+	switch {
+	case err == nil:
+		if version.PriceCents != want.PriceCents || version.Currency != want.Currency || version.BillingInterval != want.BillingInterval {
+			return false, fmt.Errorf("provision plan %s: %d %s per %s requested, active version %d has %d %s per %s: %w",
+				plan.Slug, want.PriceCents, want.Currency, want.BillingInterval,
+				version.Version, version.PriceCents, version.Currency, version.BillingInterval, ErrPlanPriceChange)
+		}
+	case errors.Is(err, sql.ErrNoRows):
+		*version = models.PlanVersion{
+			PlanID:          plan.ID,
+			PriceCents:      want.PriceCents,
+			Currency:        want.Currency,
+			BillingInterval: want.BillingInterval,
+			Status:          models.PlanVersionActive,
+			MigrationPolicy: models.PlanMigrateAtDeadline,
+		}
+		err := tx.QueryRowContext(ctx, `
+			INSERT INTO plan_versions (plan_id, version, price_cents, currency, billing_interval, status, migration_policy)
+			SELECT $1, COALESCE(MAX(version), 0) + 1, $2, $3, $4, $5, $6 FROM plan_versions WHERE plan_id = $1
+			RETURNING id, version, created_at, updated_at
+		`, plan.ID, version.PriceCents, version.Currency, version.BillingInterval, version.Status, version.MigrationPolicy,
+		).Scan(&version.ID, &version.Version, &version.CreatedAt, &version.UpdatedAt)
+		if err != nil {
+			return false, fmt.Errorf("provision plan %s: create version: %w", plan.Slug, err)
+		}
+		changed = true
+	default:
+		return false, fmt.Errorf("provision plan %s: get active version: %w", plan.Slug, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("provision plan %s: commit: %w", plan.Slug, err)
+	}
+	return changed, nil
+}
@@ -0,0 +1,206 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+)
+
+// ErrNoPlanRollout is returned when a plan has no version in rollout.
+var ErrNoPlanRollout = errors.New("plan has no version in rollout")
+
+const planVersionColumns = `
+	pv.id, pv.plan_id, pv.version, pv.stripe_product_id, pv.stripe_price_id,
+	pv.price_cents, pv.currency, pv.billing_interval, pv.status,
+	pv.deprecated_at, pv.grace_period_days, pv.migration_deadline, pv.archived_at, pv.migration_policy,
+	pv.created_at, pv.updated_at`
+
+// GetPlanVersionRollout returns the plan's version in rollout together with
+// its cohort settings. A version that was put in rollout status but not yet
+// configured is returned with a zero percent rollout.
+func (s *PlanStore) GetPlanVersionRollout(ctx context.Context, planID int64) (*models.PlanVersion, *models.PlanVersionRollout, error) {
+	var (
+		v         models.PlanVersion
+		r         models.PlanVersionRollout
+		percent   sql.NullInt64
+		startedAt sql.NullTime
+		updatedAt sql.NullTime
+	)
+	err := s.db.QueryRowContext(ctx, `
+		SELECT `+planVersionColumns+`,
+			r.percent, COALESCE(r.email_domains, '{}'), r.started_at, r.updated_at
+		FROM plan_versions pv
+		LEFT JOIN plan_version_rollouts r ON r.plan_version_id = pv.id
+		WHERE pv.plan_id = $1 AND pv.status = 'rollout'
+		ORDER BY pv.version DESC
+		LIMIT 1
+	`, planID).Scan(
+		&v.ID, &v.PlanID, &v.Version, &v.StripeProductID, &v.StripePriceID,
+		&v.PriceCents, &v.Currency, &v.BillingInterval, &v.Status,
+		&v.DeprecatedAt, &v.GracePeriodDays, &v.MigrationDeadline, &v.ArchivedAt, &v.MigrationPolicy,
+		&v.CreatedAt, &v.UpdatedAt,
+		&percent, pq.Array(&r.EmailDomains), &startedAt, &updatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, ErrNoPlanRollout
+		}
+		return nil, nil, fmt.Errorf("get plan version rollout: %w", err)
+	}
+
+	r.PlanVersionID = v.ID
+	r.Percent = int(percent.Int64)
+	r.StartedAt = startedAt.Time
+	r.UpdatedAt = updatedAt.Time
+	if r.EmailDomains == nil {
+		r.EmailDomains = []string{}
+	}
+	return &v, &r, nil
+}
+
+// SetPlanVersionRollout configures the cohort offered a version in rollout
+// status. Raising the percentage only adds users to the cohort; users
+// already assigned keep the version they were offered.
+func (s *PlanStore) SetPlanVersionRollout(ctx context.Context, versionID int64, percent int, emailDomains []string) error {
+	if percent < 0 || percent > 100 {
+		return fmt.Errorf("rollout percent %d out of range", percent)
+	}
+	if emailDomains == nil {
+		emailDomains = []string{}
+	}
+
+	res, err := s.db.ExecContext(ctx, `
+		INSERT INTO plan_version_rollouts (plan_version_id, percent, email_domains)
+		SELECT id, $2, $3 FROM plan_versions WHERE id = $1 AND status = 'rollout'
+		ON CONFLICT (plan_version_id) DO UPDATE
+		SET percent = EXCLUDED.percent,
+		    email_domains = EXCLUDED.email_domains,
+		    updated_at = now()
+	`, versionID, percent, pq.Array(emailDomains))
+	if err != nil {
+		return fmt.Errorf("set plan version rollout: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("plan version %d not found or not in rollout: %w", versionID, ErrPlanVersionNotFound)
+	}
+	return nil
+}
+
+// ResolvePlanVersion picks the version of a plan to offer a subscriber.
+// Users keep the version they were first offered while a rollout runs;
+// otherwise users in the rollout cohort get the rollout version and
+// everyone else the active one. userID may be zero for users without a
+// local account, who are only matched by email domain and not recorded.
+func (s *PlanStore) ResolvePlanVersion(ctx context.Context, planID, userID int64, email string) (*models.PlanVersion, error) {
+	if userID > 0 {
+		var v models.PlanVersion
+		err := s.db.QueryRowContext(ctx, `
+			SELECT `+planVersionColumns+`
+			FROM plan_version_assignments a
+			JOIN plan_versions pv ON pv.id = a.plan_version_id
+			WHERE a.user_id = $1 AND a.plan_id = $2 AND pv.status IN ('active', 'rollout')
+		`, userID, planID).Scan(
+			&v.ID, &v.PlanID, &v.Version, &v.StripeProductID, &v.StripePriceID,
+			&v.PriceCents, &v.Currency, &v.BillingInterval, &v.Status,
+			&v.DeprecatedAt, &v.GracePeriodDays, &v.MigrationDeadline, &v.ArchivedAt, &v.MigrationPolicy,
+			&v.CreatedAt, &v.UpdatedAt,
+		)
+		if err == nil {
+			return &v, nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("get plan version assignment: %w", err)
+		}
+	}
+
+	active, err := s.GetActivePlanVersion(ctx, planID)
+	if err != nil && !errors.Is(err, ErrPlanVersionNotFound) {
+		return nil, err
+	}
+	candidate, rollout, err := s.GetPlanVersionRollout(ctx, planID)
+	if errors.Is(err, ErrNoPlanRollout) {
+		if active == nil {
+			return nil, ErrPlanVersionNotFound
+		}
+		return active, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	chosen := active
+	if active == nil || rollout.Includes(userID, email) {
+		chosen = candidate
+	}
+
+	if userID > 0 {
+		if _, err := s.db.ExecContext(ctx, `
+			INSERT INTO plan_version_assignments (user_id, plan_id, plan_version_id)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (user_id, plan_id) DO UPDATE
+			SET plan_version_id = EXCLUDED.plan_version_id, assigned_at = now()
+		`, userID, planID, chosen.ID); err != nil {
+			return nil, fmt.Errorf("record plan version assignment: %w", err)
+		}
+	}
+	return chosen, nil
+}
+
+// PromotePlanVersion ends a rollout: the rollout version becomes the plan's
+// active version and the previous active version is deprecated with the
+// given grace period and migration policy, which decides what happens to its
+// remaining subscribers when the grace period ends.
+func (s *PlanStore) PromotePlanVersion(ctx context.Context, versionID int64, gracePeriodDays int, policy models.PlanMigrationPolicy) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin promote plan version: %w", err)
+	}
+	defer tx.Rollback()
+
+	var planID int64
+	err = tx.QueryRowContext(ctx, `
+		SELECT plan_id FROM plan_versions WHERE id = $1 AND status = 'rollout' FOR UPDATE
+	`, versionID).Scan(&planID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("plan version %d not found or not in rollout: %w", versionID, ErrPlanVersionNotFound)
+		}
+		return fmt.Errorf("lock rollout plan version: %w", err)
+	}
+
+	now := time.Now()
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE plan_versions
+		SET status = 'deprecated',
+			deprecated_at = $2,
+			grace_period_days = $3,
+			migration_deadline = $4,
+			migration_policy = $5,
+			updated_at = now()
+		WHERE plan_id = $1 AND status = 'active'
+	`, planID, now, gracePeriodDays, now.AddDate(0, 0, gracePeriodDays), policy); err != nil {
+		return fmt.Errorf("deprecate active plan version: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE plan_versions SET status = 'active', updated_at = now() WHERE id = $1
+	`, versionID); err != nil {
+		return fmt.Errorf("activate plan version: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM plan_version_rollouts WHERE plan_version_id = $1`, versionID); err != nil {
+		return fmt.Errorf("delete plan version rollout: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM plan_version_assignments WHERE plan_id = $1`, planID); err != nil {
+		return fmt.Errorf("delete plan version assignments: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit promote plan version: %w", err)
+	}
+	return nil
+}
@@ -33,10 +33,10 @@ func NewPlanStore(db *sql.DB) (*PlanStore, error) {
 func (s *PlanStore) ListPlans(ctx context.Context) ([]models.PlanWithCurrentVersion, error) {
 	query := `
 		SELECT
-			mp.id, mp.slug, mp.name, mp.description, mp.tier, mp.is_active, mp.created_at, mp.updated_at,
+			mp.id, mp.slug, mp.name, mp.description, mp.tier, mp.is_active, mp.monthly_request_quota, mp.monthly_cost_unit_quota, mp.max_streaming_connections, mp.requests_per_minute, mp.revision, mp.created_at, mp.updated_at,
 			pv.id, pv.plan_id, pv.version, pv.stripe_product_id, pv.stripe_price_id,
 			pv.price_cents, pv.currency, pv.billing_interval, pv.status,
-			pv.deprecated_at, pv.grace_period_days, pv.migration_deadline, pv.archived_at,
+			pv.deprecated_at, pv.grace_period_days, pv.migration_deadline, pv.archived_at, pv.migration_policy,
 			pv.created_at, pv.updated_at
 		FROM membership_plans mp
 		JOIN plan_versions pv ON pv.plan_id = mp.id AND pv.status = 'active'
@@ -55,12 +55,12 @@ func (s *PlanStore) ListPlans(ctx context.Context) ([]models.PlanWithCurrentVers
 		var p models.PlanWithCurrentVersion
 		if err := rows.Scan(
 			&p.Plan.ID, &p.Plan.Slug, &p.Plan.Name, &p.Plan.Description,
-			&p.Plan.Tier, &p.Plan.IsActive, &p.Plan.CreatedAt, &p.Plan.UpdatedAt,
+			&p.Plan.Tier, &p.Plan.IsActive, &p.Plan.MonthlyRequestQuota, &p.Plan.MonthlyCostUnitQuota, &p.Plan.MaxStreamingConnections, &p.Plan.RequestsPerMinute, &p.Plan.Revision, &p.Plan.CreatedAt, &p.Plan.UpdatedAt,
 			&p.Version.ID, &p.Version.PlanID, &p.Version.Version,
 			&p.Version.StripeProductID, &p.Version.StripePriceID,
 			&p.Version.PriceCents, &p.Version.Currency, &p.Version.BillingInterval,
 			&p.Version.Status, &p.Version.DeprecatedAt, &p.Version.GracePeriodDays,
-			&p.Version.MigrationDeadline, &p.Version.ArchivedAt,
+			&p.Version.MigrationDeadline, &p.Version.ArchivedAt, &p.Version.MigrationPolicy,
 			&p.Version.CreatedAt, &p.Version.UpdatedAt,
 		); err != nil {
 			return nil, fmt.Errorf("scan plan: %w", err)
@@ -71,15 +71,57 @@ func (s *PlanStore) ListPlans(ctx context.Context) ([]models.PlanWithCurrentVers
 	return plans, rows.Err()
 }
 
+// ListPlanVersions returns every version that is not archived, newest
+// first within each plan, together with its plan
+func (s *PlanStore) ListPlanVersions(ctx context.Context) ([]models.PlanWithCurrentVersion, error) {
+	query := `
+		SELECT
+			mp.id, mp.slug, mp.name, mp.description, mp.tier, mp.is_active, mp.monthly_request_quota, mp.monthly_cost_unit_quota, mp.max_streaming_connections, mp.requests_per_minute, mp.revision, mp.created_at, mp.updated_at,
+			pv.id, pv.plan_id, pv.version, pv.stripe_product_id, pv.stripe_price_id,
+			pv.price_cents, pv.currency, pv.billing_interval, pv.status,
+			pv.deprecated_at, pv.grace_period_days, pv.migration_deadline, pv.archived_at, pv.migration_policy,
+			pv.created_at, pv.updated_at
+		FROM membership_plans mp
+		JOIN plan_versions pv ON pv.plan_id = mp.id AND pv.status <> 'archived'
+		ORDER BY mp.tier ASC, mp.slug ASC, pv.version DESC
+	`
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("list plan versions: %w", err)
+	}
+	defer rows.Close()
+
+	var versions []models.PlanWithCurrentVersion
+	for rows.Next() {
+		var p models.PlanWithCurrentVersion
+		if err := rows.Scan(
+			&p.Plan.ID, &p.Plan.Slug, &p.Plan.Name, &p.Plan.Description,
+			&p.Plan.Tier, &p.Plan.IsActive, &p.Plan.MonthlyRequestQuota, &p.Plan.MonthlyCostUnitQuota, &p.Plan.MaxStreamingConnections, &p.Plan.RequestsPerMinute, &p.Plan.Revision, &p.Plan.CreatedAt, &p.Plan.UpdatedAt,
+			&p.Version.ID, &p.Version.PlanID, &p.Version.Version,
+			&p.Version.StripeProductID, &p.Version.StripePriceID,
+			&p.Version.PriceCents, &p.Version.Currency, &p.Version.BillingInterval,
+			&p.Version.Status, &p.Version.DeprecatedAt, &p.Version.GracePeriodDays,
+			&p.Version.MigrationDeadline, &p.Version.ArchivedAt, &p.Version.MigrationPolicy,
+			&p.Version.CreatedAt, &p.Version.UpdatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("scan plan version: %w", err)
+		}
+		versions = append(versions, p)
+	}
+
+	return versions, rows.Err()
+}
+
 // GetPlanByID returns a plan by its ID
 func (s *PlanStore) GetPlanByID(ctx context.Context, id int64) (*models.MembershipPlan, error) {
-	query := `SELECT id, slug, name, description, tier, is_active, created_at, updated_at
+	query := `SELECT id, slug, name, description, tier, is_active, monthly_request_quota, monthly_cost_unit_quota, max_streaming_connections, requests_per_minute, revision, created_at, updated_at
 		FROM membership_plans WHERE id = $1`
 
 	var p models.MembershipPlan
 	err := s.db.QueryRowContext(ctx, query, id).Scan(
 		&p.ID, &p.Slug, &p.Name, &p.Description,
-		&p.Tier, &p.IsActive, &p.CreatedAt, &p.UpdatedAt,
+		&p.Tier, &p.IsActive, &p.MonthlyRequestQuota, &p.MonthlyCostUnitQuota, &p.MaxStreamingConnections, &p.RequestsPerMinute, &p.Revision, &p.CreatedAt, &p.UpdatedAt,
 	)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -92,13 +134,13 @@ func (s *PlanStore) GetPlanByID(ctx context.Context, id int64) (*models.Membersh
 
 // GetPlanBySlug returns a plan by its slug
 func (s *PlanStore) GetPlanBySlug(ctx context.Context, slug string) (*models.MembershipPlan, error) {
-	query := `SELECT id, slug, name, description, tier, is_active, created_at, updated_at
+	query := `SELECT id, slug, name, description, tier, is_active, monthly_request_quota, monthly_cost_unit_quota, max_streaming_connections, requests_per_minute, revision, created_at, updated_at
 		FROM membership_plans WHERE slug = $1`
 
 	var p models.MembershipPlan
 	err := s.db.QueryRowContext(ctx, query, slug).Scan(
 		&p.ID, &p.Slug, &p.Name, &p.Description,
-		&p.Tier, &p.IsActive, &p.CreatedAt, &p.UpdatedAt,
+		&p.Tier, &p.IsActive, &p.MonthlyRequestQuota, &p.MonthlyCostUnitQuota, &p.MaxStreamingConnections, &p.RequestsPerMinute, &p.Revision, &p.CreatedAt, &p.UpdatedAt,
 	)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -109,12 +151,39 @@ func (s *PlanStore) GetPlanBySlug(ctx context.Context, slug string) (*models.Mem
 	return &p, nil
 }
 
+// UpdatePlan updates a plan's display fields and availability, identified by
+// slug. The write only applies if the stored revision equals ifRevision;
+// otherwise it returns an error wrapping ErrRevisionMismatch. On success the
+// plan's Revision and UpdatedAt are refreshed.
+func (s *PlanStore) UpdatePlan(ctx context.Context, plan *models.MembershipPlan, ifRevision int64) error {
+	err := s.db.QueryRowContext(ctx, `
+		UPDATE membership_plans
+		SET name = $2, description = $3, tier = $4, is_active = $5,
+		    revision = revision + 1, updated_at = now()
+		WHERE slug = $1 AND revision = $6
+		RETURNING id, revision, created_at, updated_at`,
+		plan.Slug, plan.Name, plan.Description, plan.Tier, plan.IsActive, ifRevision,
+	).Scan(&plan.ID, &plan.Revision, &plan.CreatedAt, &plan.UpdatedAt)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("update plan: %w", err)
+	}
+
+	// Distinguish a missing plan from a lost race.
+	if _, lookupErr := s.GetPlanBySlug(ctx, plan.Slug); lookupErr != nil {
+		return lookupErr
+	}
+	return fmt.Errorf("update plan %s: %w", plan.Slug, ErrRevisionMismatch)
+}
+
 // GetActivePlanVersion returns the current active version for a plan
 func (s *PlanStore) GetActivePlanVersion(ctx context.Context, planID int64) (*models.PlanVersion, error) {
 	query := `
 		SELECT id, plan_id, version, stripe_product_id, stripe_price_id,
 			price_cents, currency, billing_interval, status,
-			deprecated_at, grace_period_days, migration_deadline, archived_at,
+			deprecated_at, grace_period_days, migration_deadline, archived_at, migration_policy,
 			created_at, updated_at
 		FROM plan_versions
 		WHERE plan_id = $1 AND status = 'active'
@@ -126,7 +195,7 @@ func (s *PlanStore) GetActivePlanVersion(ctx context.Context, planID int64) (*mo
 	err := s.db.QueryRowContext(ctx, query, planID).Scan(
 		&v.ID, &v.PlanID, &v.Version, &v.StripeProductID, &v.StripePriceID,
 		&v.PriceCents, &v.Currency, &v.BillingInterval, &v.Status,
-		&v.DeprecatedAt, &v.GracePeriodDays, &v.MigrationDeadline, &v.ArchivedAt,
+		&v.DeprecatedAt, &v.GracePeriodDays, &v.MigrationDeadline, &v.ArchivedAt, &v.MigrationPolicy,
 		&v.CreatedAt, &v.UpdatedAt,
 	)
 	if err != nil {
@@ -143,7 +212,7 @@ func (s *PlanStore) GetPlanVersionByStripePriceID(ctx context.Context, stripePri
 	query := `
 		SELECT id, plan_id, version, stripe_product_id, stripe_price_id,
 			price_cents, currency, billing_interval, status,
-			deprecated_at, grace_period_days, migration_deadline, archived_at,
+			deprecated_at, grace_period_days, migration_deadline, archived_at, migration_policy,
 			created_at, updated_at
 		FROM plan_versions
 		WHERE stripe_price_id = $1
@@ -153,7 +222,7 @@ func (s *PlanStore) GetPlanVersionByStripePriceID(ctx context.Context, stripePri
 	err := s.db.QueryRowContext(ctx, query, stripePriceID).Scan(
 		&v.ID, &v.PlanID, &v.Version, &v.StripeProductID, &v.StripePriceID,
 		&v.PriceCents, &v.Currency, &v.BillingInterval, &v.Status,
-		&v.DeprecatedAt, &v.GracePeriodDays, &v.MigrationDeadline, &v.ArchivedAt,
+		&v.DeprecatedAt, &v.GracePeriodDays, &v.MigrationDeadline, &v.ArchivedAt, &v.MigrationPolicy,
 		&v.CreatedAt, &v.UpdatedAt,
 	)
 	if err != nil {
@@ -169,19 +238,23 @@ func (s *PlanStore) GetPlanVersionByStripePriceID(ctx context.Context, stripePri
 func (s *PlanStore) CreatePlanVersion(ctx context.Context, v *models.PlanVersion) error {
 	query := `
 		INSERT INTO plan_versions (plan_id, version, stripe_product_id, stripe_price_id,
-			price_cents, currency, billing_interval, status, grace_period_days)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+			price_cents, currency, billing_interval, status, grace_period_days, migration_policy)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
 		RETURNING id, created_at, updated_at
 	`
 
+	if v.MigrationPolicy == "" {
+		v.MigrationPolicy = models.PlanMigrateAtDeadline
+	}
 	return s.db.QueryRowContext(ctx, query,
 		v.PlanID, v.Version, v.StripeProductID, v.StripePriceID,
-		v.PriceCents, v.Currency, v.BillingInterval, v.Status, v.GracePeriodDays,
+		v.PriceCents, v.Currency, v.BillingInterval, v.Status, v.GracePeriodDays, v.MigrationPolicy,
 	).Scan(&v.ID, &v.CreatedAt, &v.UpdatedAt)
 }
 
 // DeprecatePlanVersion marks a plan version as deprecated with a grace period
-func (s *PlanStore) DeprecatePlanVersion(ctx context.Context, versionID int64, gracePeriodDays int) error {
+// and the policy applied to its subscribers once the grace period ends
+func (s *PlanStore) DeprecatePlanVersion(ctx context.Context, versionID int64, gracePeriodDays int, policy models.PlanMigrationPolicy) error {
 	now := time.Now()
 	deadline := now.AddDate(0, 0, gracePeriodDays)
 
@@ -191,11 +264,12 @@ func (s *PlanStore) DeprecatePlanVersion(ctx context.Context, versionID int64, g
 			deprecated_at = $2,
 			grace_period_days = $3,
 			migration_deadline = $4,
+			migration_policy = $5,
 			updated_at = now()
 		WHERE id = $1 AND status = 'active'
 	`
 
-	result, err := s.db.ExecContext(ctx, query, versionID, now, gracePeriodDays, deadline)
+	result, err := s.db.ExecContext(ctx, query, versionID, now, gracePeriodDays, deadline, policy)
 	if err != nil {
 		return fmt.Errorf("deprecate plan version: %w", err)
 	}
@@ -227,6 +301,23 @@ func (s *PlanStore) ArchivePlanVersion(ctx context.Context, versionID int64) err
 	return nil
 }
 
+// SetPlanVersionMigrationPolicy changes the grandfathering rule of a version
+// that has not been archived yet
+func (s *PlanStore) SetPlanVersionMigrationPolicy(ctx context.Context, versionID int64, policy models.PlanMigrationPolicy) error {
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE plan_versions SET migration_policy = $2, updated_at = now()
+		WHERE id = $1 AND status <> 'archived'
+	`, versionID, policy)
+	if err != nil {
+		return fmt.Errorf("set plan version migration policy: %w", err)
+	}
+	affected, _ := result.RowsAffected()
+	if affected == 0 {
+		return fmt.Errorf("plan version %d not found or archived: %w", versionID, ErrPlanVersionNotFound)
+	}
+	return nil
+}
+
 // UpdatePlanVersionStripeIDs updates the Stripe product/price IDs for a plan version
 func (s *PlanStore) UpdatePlanVersionStripeIDs(ctx context.Context, versionID int64, productID, priceID string) error {
 	query := `
@@ -241,15 +332,17 @@ func (s *PlanStore) UpdatePlanVersionStripeIDs(ctx context.Context, versionID in
 	return nil
 }
 
-// GetDeprecatedVersionsPastDeadline returns deprecated versions whose grace period has expired
+// GetDeprecatedVersionsPastDeadline returns deprecated versions whose grace period has expired,
+// except grandfathered versions, whose subscribers are never migrated
 func (s *PlanStore) GetDeprecatedVersionsPastDeadline(ctx context.Context) ([]models.PlanVersion, error) {
 	query := `
 		SELECT id, plan_id, version, stripe_product_id, stripe_price_id,
 			price_cents, currency, billing_interval, status,
-			deprecated_at, grace_period_days, migration_deadline, archived_at,
+			deprecated_at, grace_period_days, migration_deadline, archived_at, migration_policy,
 			created_at, updated_at
 		FROM plan_versions
 		WHERE status = 'deprecated'
+		  AND migration_policy <> 'grandfather'
 		  AND migration_deadline IS NOT NULL
 		  AND migration_deadline <= NOW()
 		ORDER BY migration_deadline ASC
@@ -267,7 +360,7 @@ func (s *PlanStore) GetDeprecatedVersionsPastDeadline(ctx context.Context) ([]mo
 		if err := rows.Scan(
 			&v.ID, &v.PlanID, &v.Version, &v.StripeProductID, &v.StripePriceID,
 			&v.PriceCents, &v.Currency, &v.BillingInterval, &v.Status,
-			&v.DeprecatedAt, &v.GracePeriodDays, &v.MigrationDeadline, &v.ArchivedAt,
+			&v.DeprecatedAt, &v.GracePeriodDays, &v.MigrationDeadline, &v.ArchivedAt, &v.MigrationPolicy,
 			&v.CreatedAt, &v.UpdatedAt,
 		); err != nil {
 			return nil, fmt.Errorf("scan deprecated version: %w", err)
@@ -282,7 +375,8 @@ func (s *PlanStore) GetSubscriptionsByPlanVersion(ctx context.Context, versionID
 	query := `
 		SELECT id, user_id, stripe_customer_id, stripe_subscription_id,
 			stripe_price_id, status, current_period_start, current_period_end,
-			cancel_at_period_end, canceled_at, created_at, updated_at
+			cancel_at_period_end, canceled_at, pause_behavior, pause_resumes_at, paused_at,
+			created_at, updated_at
 		FROM subscriptions
 		WHERE plan_version_id = $1 AND status IN ('active', 'trialing', 'past_due')
 		ORDER BY created_at ASC
@@ -300,7 +394,8 @@ func (s *PlanStore) GetSubscriptionsByPlanVersion(ctx context.Context, versionID
 		if err := rows.Scan(
 			&sub.ID, &sub.UserID, &sub.StripeCustomerID, &sub.StripeSubscriptionID,
 			&sub.StripePriceID, &sub.Status, &sub.CurrentPeriodStart, &sub.CurrentPeriodEnd,
-			&sub.CancelAtPeriodEnd, &sub.CanceledAt, &sub.CreatedAt, &sub.UpdatedAt,
+			&sub.CancelAtPeriodEnd, &sub.CanceledAt, &sub.PauseBehavior, &sub.PauseResumesAt, &sub.PausedAt,
+			&sub.CreatedAt, &sub.UpdatedAt,
 		); err != nil {
 			return nil, fmt.Errorf("scan subscription: %w", err)
 		}
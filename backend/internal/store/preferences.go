@@ -0,0 +1,117 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+)
+
+// GetUserPreferences returns the preferences of the user identified by email,
+// falling back to the defaults when none have been saved.
+func (s *Store) GetUserPreferences(ctx context.Context, email string) (*models.UserPreferences, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	var userID int64
+	if err := s.db.QueryRowContext(ctx,
+		`SELECT id FROM users WHERE LOWER(email) = LOWER($1) AND deleted_at IS NULL`, email,
+	).Scan(&userID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("store: no local user found for email=%s", email)
+		}
+		return nil, fmt.Errorf("store: lookup user for preferences: %w", err)
+	}
+
+	return s.GetUserPreferencesByUserID(ctx, userID)
+}
+
+// GetUserPreferencesByUserID returns the preferences for a user ID, falling
+// back to the defaults (revision 0) when none have been saved.
+func (s *Store) GetUserPreferencesByUserID(ctx context.Context, userID int64) (*models.UserPreferences, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	prefs := models.DefaultUserPreferences()
+	var updatedAt sql.NullTime
+	err := s.db.QueryRowContext(ctx,
+		`SELECT timezone, locale, date_format, mcp_log_level, usage_digest, revision, updated_at FROM user_preferences WHERE user_id = $1`, userID,
+	).Scan(&prefs.Timezone, &prefs.Locale, &prefs.DateFormat, &prefs.MCPLogLevel, &prefs.UsageDigest, &prefs.Revision, &updatedAt)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("store: get user preferences: %w", err)
+	}
+	if updatedAt.Valid {
+		prefs.UpdatedAt = &updatedAt.Time
+	}
+
+	return &prefs, nil
+}
+
+// UpsertUserPreferences saves the preferences of the user identified by
+// email. When ifRevision is non-nil the write only happens if the stored
+// revision still equals it (0 for users who never saved preferences);
+// otherwise an error wrapping ErrRevisionMismatch is returned.
+func (s *Store) UpsertUserPreferences(ctx context.Context, email string, prefs models.UserPreferences, ifRevision *int64) (*models.UserPreferences, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("store: begin preferences tx: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	var (
+		userID  int64
+		current sql.NullInt64
+	)
+	if err := tx.QueryRowContext(ctx, `
+		SELECT u.id, p.revision
+		FROM users u
+		LEFT JOIN user_preferences p ON p.user_id = u.id
+		WHERE LOWER(u.email) = LOWER($1) AND u.deleted_at IS NULL
+		FOR UPDATE OF u
+	`, email).Scan(&userID, &current); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("store: no local user found for email=%s", email)
+		}
+		return nil, fmt.Errorf("store: lock user preferences: %w", err)
+	}
+
+	if ifRevision != nil && current.Int64 != *ifRevision {
+		return nil, fmt.Errorf("store: user preferences revision is %d, expected %d: %w", current.Int64, *ifRevision, ErrRevisionMismatch)
+	}
+
+	saved := prefs
+	var updatedAt time.Time
+	if err := tx.QueryRowContext(ctx, `
+		INSERT INTO user_preferences (user_id, timezone, locale, date_format, mcp_log_level, usage_digest)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (user_id) DO UPDATE
+		SET timezone = EXCLUDED.timezone,
+		    locale = EXCLUDED.locale,
+		    date_format = EXCLUDED.date_format,
+		    mcp_log_level = EXCLUDED.mcp_log_level,
+		    usage_digest = EXCLUDED.usage_digest,
+		    revision = user_preferences.revision + 1,
+		    updated_at = now()
+		RETURNING revision, updated_at
+	`, userID, prefs.Timezone, prefs.Locale, prefs.DateFormat, prefs.MCPLogLevel, prefs.UsageDigest).Scan(&saved.Revision, &updatedAt); err != nil {
+		return nil, fmt.Errorf("store: upsert user preferences: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("store: commit user preferences: %w", err)
+	}
+
+	saved.UpdatedAt = &updatedAt
+	return &saved, nil
+}
@@ -0,0 +1,156 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+)
+
+// ErrRecurringJobNotFound is returned when no recurring job has the slug.
+var ErrRecurringJobNotFound = errors.New("recurring job not found")
+
+const recurringJobColumns = `slug, job_type, payload, priority, max_attempts, interval_seconds, enabled,
+	next_run_at, last_enqueued_at, last_job_id, created_at, updated_at`
+
+func scanRecurringJob(row interface{ Scan(...any) error }) (*models.RecurringJob, error) {
+	var r models.RecurringJob
+	if err := row.Scan(&r.Slug, &r.JobType, &r.Payload, &r.Priority, &r.MaxAttempts, &r.IntervalSeconds, &r.Enabled,
+		&r.NextRunAt, &r.LastEnqueuedAt, &r.LastJobID, &r.CreatedAt, &r.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// PutRecurringJob creates the recurring job or replaces its definition and
+// fills in the stored schedule. A new job is due right away; changing the
+// interval of an existing one reschedules it from its last run. Putting a
+// job that is already as requested writes nothing; it reports whether
+// anything changed.
+func (s *Store) PutRecurringJob(ctx context.Context, job *models.RecurringJob) (bool, error) {
+	if s == nil || s.db == nil {
+		return false, errors.New("store: db cannot be nil")
+	}
+
+	stored, err := scanRecurringJob(s.db.QueryRowContext(ctx, `
+		INSERT INTO recurring_jobs (slug, job_type, payload, priority, max_attempts, interval_seconds, enabled)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (slug) DO UPDATE
+		SET job_type = EXCLUDED.job_type,
+		    payload = EXCLUDED.payload,
+		    priority = EXCLUDED.priority,
+		    max_attempts = EXCLUDED.max_attempts,
+		    interval_seconds = EXCLUDED.interval_seconds,
+		    enabled = EXCLUDED.enabled,
+		    next_run_at = CASE WHEN recurring_jobs.interval_seconds = EXCLUDED.interval_seconds
+		        THEN recurring_jobs.next_run_at
+		        ELSE COALESCE(recurring_jobs.last_enqueued_at, now()) + make_interval(secs => EXCLUDED.interval_seconds) END,
+		    updated_at = now()
+		WHERE (recurring_jobs.job_type, recurring_jobs.payload, recurring_jobs.priority,
+		       recurring_jobs.max_attempts, recurring_jobs.interval_seconds, recurring_jobs.enabled)
+		      IS DISTINCT FROM (EXCLUDED.job_type, EXCLUDED.payload, EXCLUDED.priority,
+		       EXCLUDED.max_attempts, EXCLUDED.interval_seconds, EXCLUDED.enabled)
+		RETURNING `+recurringJobColumns,
+		job.Slug, job.JobType, job.Payload, job.Priority, job.MaxAttempts, job.IntervalSeconds, job.Enabled))
+	changed := err == nil
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		if stored, err = s.GetRecurringJob(ctx, job.Slug); err != nil {
+			return false, err
+		}
+	case err != nil:
+		return false, fmt.Errorf("store: put recurring job: %w", err)
+	}
+	*job = *stored
+	return changed, nil
+}
+
+// GetRecurringJob returns the recurring job with slug.
+func (s *Store) GetRecurringJob(ctx context.Context, slug string) (*models.RecurringJob, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	job, err := scanRecurringJob(s.db.QueryRowContext(ctx,
+		`SELECT `+recurringJobColumns+` FROM recurring_jobs WHERE slug = $1`, slug))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrRecurringJobNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("store: get recurring job: %w", err)
+	}
+	return job, nil
+}
+
+// ListRecurringJobs returns every recurring job ordered by slug.
+func (s *Store) ListRecurringJobs(ctx context.Context) ([]models.RecurringJob, error) {
+	return s.listRecurringJobs(ctx, `SELECT `+recurringJobColumns+` FROM recurring_jobs ORDER BY slug`)
+}
+
+// ListDueRecurringJobs returns the enabled recurring jobs due at now.
+func (s *Store) ListDueRecurringJobs(ctx context.Context, now time.Time) ([]models.RecurringJob, error) {
+	return s.listRecurringJobs(ctx, `SELECT `+recurringJobColumns+` FROM recurring_jobs
+		WHERE enabled AND next_run_at <= $1 ORDER BY next_run_at`, now)
+}
+
+func (s *Store) listRecurringJobs(ctx context.Context, query string, args ...any) ([]models.RecurringJob, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("store: list recurring jobs: %w", err)
+	}
+	defer rows.Close()
+
+	var jobs []models.RecurringJob
+	for rows.Next() {
+		job, err := scanRecurringJob(rows)
+		if err != nil {
+			return nil, fmt.Errorf("store: scan recurring job: %w", err)
+		}
+		jobs = append(jobs, *job)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("store: iterate recurring jobs: %w", err)
+	}
+	return jobs, nil
+}
+
+// MarkRecurringJobEnqueued records that the run due at dueAt enqueued
+// jobID and schedules the next run. Runs missed while nothing was
+// scheduling are skipped rather than enqueued back to back.
+func (s *Store) MarkRecurringJobEnqueued(ctx context.Context, slug string, dueAt time.Time, jobID int64) error {
+	if s == nil || s.db == nil {
+		return errors.New("store: db cannot be nil")
+	}
+
+	if _, err := s.db.ExecContext(ctx, `
+		UPDATE recurring_jobs
+		SET last_enqueued_at = now(),
+		    last_job_id = $3,
+		    next_run_at = next_run_at + make_interval(secs => interval_seconds *
+		        (floor(extract(epoch FROM now() - next_run_at) / interval_seconds) + 1))
+		WHERE slug = $1 AND next_run_at = $2
+	`, slug, dueAt, jobID); err != nil {
+		return fmt.Errorf("store: mark recurring job enqueued: %w", err)
+	}
+	return nil
+}
+
+// DeleteRecurringJob removes the recurring job with slug, if any. Jobs it
+// already enqueued are left alone.
+func (s *Store) DeleteRecurringJob(ctx context.Context, slug string) error {
+	if s == nil || s.db == nil {
+		return errors.New("store: db cannot be nil")
+	}
+
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM recurring_jobs WHERE slug = $1`, slug); err != nil {
+		return fmt.Errorf("store: delete recurring job: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,368 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+)
+
+// reportArtifactsKept is how many artifacts are kept per schedule; older
+// ones are pruned as new ones are stored.
+const reportArtifactsKept = 20
+
+// ErrReportScheduleNotFound is returned when a report schedule does not
+// exist or belongs to another user.
+var ErrReportScheduleNotFound = errors.New("report schedule not found")
+
+// ErrReportArtifactNotFound is returned when a report artifact does not
+// exist or belongs to another user.
+var ErrReportArtifactNotFound = errors.New("report artifact not found")
+
+const reportScheduleColumns = `id, user_id, name, template, project_key, jql, format, delivery,
+	schedule_days, schedule_time, time_zone, enabled, next_run_at, last_run_at, last_error,
+	created_at, updated_at`
+
+func scanReportSchedule(row interface{ Scan(...any) error }) (*models.ReportSchedule, error) {
+	var (
+		r         models.ReportSchedule
+		days      pq.Int64Array
+		lastRun   sql.NullTime
+		lastError sql.NullString
+	)
+	if err := row.Scan(&r.ID, &r.UserID, &r.Name, &r.Template, &r.ProjectKey, &r.JQL, &r.Format, &r.Delivery,
+		&days, &r.TimeOfDay, &r.TimeZone, &r.Enabled, &r.NextRunAt, &lastRun, &lastError,
+		&r.CreatedAt, &r.UpdatedAt); err != nil {
+		return nil, err
+	}
+	r.Days = make([]int, len(days))
+	for i, day := range days {
+		r.Days[i] = int(day)
+	}
+	if lastRun.Valid {
+		r.LastRunAt = &lastRun.Time
+	}
+	r.LastError = nullStringPtr(lastError)
+	return &r, nil
+}
+
+// CountReportSchedules returns how many report schedules the user has.
+func (s *Store) CountReportSchedules(ctx context.Context, userID int64) (int, error) {
+	if s == nil || s.db == nil {
+		return 0, errors.New("store: db cannot be nil")
+	}
+
+	var n int
+	if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM report_schedules WHERE user_id = $1`, userID).Scan(&n); err != nil {
+		return 0, fmt.Errorf("store: count report schedules: %w", err)
+	}
+	return n, nil
+}
+
+// CreateReportSchedule stores a validated report schedule for r.UserID,
+// first running at r.NextRunAt.
+func (s *Store) CreateReportSchedule(ctx context.Context, r *models.ReportSchedule) (*models.ReportSchedule, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	row := s.db.QueryRowContext(ctx, `
+		INSERT INTO report_schedules (user_id, name, template, project_key, jql, format, delivery,
+			schedule_days, schedule_time, time_zone, enabled, next_run_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		RETURNING `+reportScheduleColumns,
+		r.UserID, r.Name, r.Template, r.ProjectKey, r.JQL, r.Format, r.Delivery,
+		scheduleDays(r.Days), r.TimeOfDay, r.TimeZone, r.Enabled, r.NextRunAt)
+	created, err := scanReportSchedule(row)
+	if err != nil {
+		return nil, fmt.Errorf("store: create report schedule: %w", err)
+	}
+	return created, nil
+}
+
+// ListReportSchedules returns the user's report schedules, oldest first.
+func (s *Store) ListReportSchedules(ctx context.Context, userID int64) ([]models.ReportSchedule, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+reportScheduleColumns+`
+		FROM report_schedules
+		WHERE user_id = $1
+		ORDER BY id
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("store: list report schedules: %w", err)
+	}
+	defer rows.Close()
+
+	var schedules []models.ReportSchedule
+	for rows.Next() {
+		r, err := scanReportSchedule(rows)
+		if err != nil {
+			return nil, fmt.Errorf("store: scan report schedule: %w", err)
+		}
+		schedules = append(schedules, *r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("store: iterate report schedules: %w", err)
+	}
+	return schedules, nil
+}
+
+// UpdateReportSchedule replaces the definition of one of r.UserID's report
+// schedules.
+func (s *Store) UpdateReportSchedule(ctx context.Context, r *models.ReportSchedule) (*models.ReportSchedule, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	row := s.db.QueryRowContext(ctx, `
+		UPDATE report_schedules
+		SET name = $3, template = $4, project_key = $5, jql = $6, format = $7, delivery = $8,
+			schedule_days = $9, schedule_time = $10, time_zone = $11, enabled = $12,
+			next_run_at = $13, updated_at = now()
+		WHERE id = $1 AND user_id = $2
+		RETURNING `+reportScheduleColumns,
+		r.ID, r.UserID, r.Name, r.Template, r.ProjectKey, r.JQL, r.Format, r.Delivery,
+		scheduleDays(r.Days), r.TimeOfDay, r.TimeZone, r.Enabled, r.NextRunAt)
+	updated, err := scanReportSchedule(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrReportScheduleNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("store: update report schedule: %w", err)
+	}
+	return updated, nil
+}
+
+// DeleteReportSchedule removes one of the user's report schedules. Its
+// artifacts are kept.
+func (s *Store) DeleteReportSchedule(ctx context.Context, userID, id int64) error {
+	if s == nil || s.db == nil {
+		return errors.New("store: db cannot be nil")
+	}
+
+	res, err := s.db.ExecContext(ctx, `DELETE FROM report_schedules WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return fmt.Errorf("store: delete report schedule: %w", err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrReportScheduleNotFound
+	}
+	return nil
+}
+
+// ListDueReportSchedules returns up to limit enabled report schedules whose
+// next run is at or before now.
+func (s *Store) ListDueReportSchedules(ctx context.Context, now time.Time, limit int) ([]models.ReportSchedule, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+reportScheduleColumns+`
+		FROM report_schedules
+		WHERE enabled AND next_run_at <= $1
+		ORDER BY next_run_at
+		LIMIT $2
+	`, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("store: list due report schedules: %w", err)
+	}
+	defer rows.Close()
+
+	var schedules []models.ReportSchedule
+	for rows.Next() {
+		r, err := scanReportSchedule(rows)
+		if err != nil {
+			return nil, fmt.Errorf("store: scan due report schedule: %w", err)
+		}
+		schedules = append(schedules, *r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("store: iterate due report schedules: %w", err)
+	}
+	return schedules, nil
+}
+
+// ScheduleReport moves a report schedule's next run from due to next. It
+// reports false when the schedule was moved in the meantime, so a due run
+// is only queued once.
+func (s *Store) ScheduleReport(ctx context.Context, id int64, due, next time.Time) (bool, error) {
+	if s == nil || s.db == nil {
+		return false, errors.New("store: db cannot be nil")
+	}
+
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE report_schedules SET next_run_at = $3
+		WHERE id = $1 AND next_run_at = $2
+	`, id, due, next)
+	if err != nil {
+		return false, fmt.Errorf("store: schedule report: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("store: schedule report: %w", err)
+	}
+	return n > 0, nil
+}
+
+// GetReportScheduleForRun returns a report schedule with its owner's MCP
+// secret and email.
+func (s *Store) GetReportScheduleForRun(ctx context.Context, id int64) (*models.ReportRunTarget, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	var secret, email sql.NullString
+	row := s.db.QueryRowContext(ctx, `
+		SELECT r.id, r.user_id, r.name, r.template, r.project_key, r.jql, r.format, r.delivery,
+			r.schedule_days, r.schedule_time, r.time_zone, r.enabled, r.next_run_at, r.last_run_at,
+			r.last_error, r.created_at, r.updated_at, u.mcp_secret, u.email
+		FROM report_schedules r
+		JOIN users u ON u.id = r.user_id
+		WHERE r.id = $1 AND u.deleted_at IS NULL
+	`, id)
+	schedule, err := scanReportSchedule(scanFunc(func(dest ...any) error {
+		return row.Scan(append(dest, &secret, &email)...)
+	}))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrReportScheduleNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("store: get report schedule for run: %w", err)
+	}
+	return &models.ReportRunTarget{Schedule: *schedule, MCPSecret: secret.String, Email: email.String}, nil
+}
+
+// RecordReportRun stores when a report schedule last ran and the error it
+// failed with, if any.
+func (s *Store) RecordReportRun(ctx context.Context, id int64, ranAt time.Time, runErr *string) error {
+	if s == nil || s.db == nil {
+		return errors.New("store: db cannot be nil")
+	}
+
+	if _, err := s.db.ExecContext(ctx, `
+		UPDATE report_schedules SET last_run_at = $2, last_error = $3, updated_at = now()
+		WHERE id = $1
+	`, id, ranAt, runErr); err != nil {
+		return fmt.Errorf("store: record report run: %w", err)
+	}
+	return nil
+}
+
+// CreateReportArtifact stores a rendered report of userID and prunes the
+// schedule's artifacts beyond the newest reportArtifactsKept.
+func (s *Store) CreateReportArtifact(ctx context.Context, userID int64, a *models.ReportArtifact) error {
+	if s == nil || s.db == nil {
+		return errors.New("store: db cannot be nil")
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("store: begin report artifact: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := tx.QueryRowContext(ctx, `
+		INSERT INTO report_artifacts (user_id, schedule_id, template, format, title, content)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+	`, userID, a.ScheduleID, a.Template, a.Format, a.Title, a.Content).Scan(&a.ID, &a.CreatedAt); err != nil {
+		return fmt.Errorf("store: insert report artifact: %w", err)
+	}
+	a.Size = len(a.Content)
+
+	if a.ScheduleID != nil {
+		if _, err := tx.ExecContext(ctx, `
+			DELETE FROM report_artifacts
+			WHERE schedule_id = $1 AND id NOT IN (
+				SELECT id FROM report_artifacts WHERE schedule_id = $1
+				ORDER BY created_at DESC, id DESC
+				LIMIT $2
+			)
+		`, *a.ScheduleID, reportArtifactsKept); err != nil {
+			return fmt.Errorf("store: prune report artifacts: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("store: commit report artifact: %w", err)
+	}
+	return nil
+}
+
+// ListReportArtifacts returns the user's latest report artifacts without
+// their content, newest first.
+func (s *Store) ListReportArtifacts(ctx context.Context, userID int64, limit int) ([]models.ReportArtifact, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, schedule_id, template, format, title, octet_length(content), created_at
+		FROM report_artifacts
+		WHERE user_id = $1
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2
+	`, userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("store: list report artifacts: %w", err)
+	}
+	defer rows.Close()
+
+	var artifacts []models.ReportArtifact
+	for rows.Next() {
+		var (
+			a          models.ReportArtifact
+			scheduleID sql.NullInt64
+		)
+		if err := rows.Scan(&a.ID, &scheduleID, &a.Template, &a.Format, &a.Title, &a.Size, &a.CreatedAt); err != nil {
+			return nil, fmt.Errorf("store: scan report artifact: %w", err)
+		}
+		if scheduleID.Valid {
+			a.ScheduleID = &scheduleID.Int64
+		}
+		artifacts = append(artifacts, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("store: iterate report artifacts: %w", err)
+	}
+	return artifacts, nil
+}
+
+// GetReportArtifact returns one of the user's report artifacts with its
+// content.
+func (s *Store) GetReportArtifact(ctx context.Context, userID, id int64) (*models.ReportArtifact, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	var (
+		a          models.ReportArtifact
+		scheduleID sql.NullInt64
+	)
+	err := s.db.QueryRowContext(ctx, `
+		SELECT id, schedule_id, template, format, title, content, created_at
+		FROM report_artifacts
+		WHERE id = $1 AND user_id = $2
+	`, id, userID).Scan(&a.ID, &scheduleID, &a.Template, &a.Format, &a.Title, &a.Content, &a.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrReportArtifactNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("store: get report artifact: %w", err)
+	}
+	if scheduleID.Valid {
+		a.ScheduleID = &scheduleID.Int64
+	}
+	a.Size = len(a.Content)
+	return &a, nil
+}
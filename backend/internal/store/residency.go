@@ -0,0 +1,358 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// ErrUnknownDataRegion is returned for a data region without a configured
+// regional database.
+var ErrUnknownDataRegion = errors.New("unknown data region")
+
+// requestMoveBatch is how many request log rows are moved between databases
+// per round trip when an organization changes region.
+const requestMoveBatch = 1000
+
+// userDataRegion selects the data region of the user in $1: that of the
+// earliest joined active membership in an organization with a region.
+const userDataRegion = `
+	SELECT o.data_region
+	FROM organization_members m
+	JOIN organizations o ON o.id = m.org_id
+	WHERE m.user_id = $1 AND m.active AND o.data_region IS NOT NULL
+	ORDER BY m.created_at, o.id
+	LIMIT 1`
+
+// SetRegionalDatabases configures the regional databases by region name.
+// Organizations with a data region keep their shared Jira account and their
+// members' request logs there; everything else stays in the primary
+// database.
+func (s *Store) SetRegionalDatabases(dbs map[string]*sql.DB) {
+	s.regions = dbs
+}
+
+// RegionalDatabases returns the regional databases by region name.
+func (s *Store) RegionalDatabases() map[string]*sql.DB {
+	return s.regions
+}
+
+// DataRegions returns the names of the configured regions, sorted.
+func (s *Store) DataRegions() []string {
+	names := make([]string, 0, len(s.regions))
+	for name := range s.regions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// CheckDataRegions returns an error naming the regions organizations are
+// resident in that have no configured database. Serving such organizations
+// from the primary database would break their residency.
+func (s *Store) CheckDataRegions(ctx context.Context) error {
+	if s == nil || s.db == nil {
+		return errors.New("store: db cannot be nil")
+	}
+
+	rows, err := s.db.QueryContext(ctx, `SELECT DISTINCT data_region FROM organizations WHERE data_region IS NOT NULL`)
+	if err != nil {
+		return fmt.Errorf("store: list data regions: %w", err)
+	}
+	defer rows.Close()
+
+	var missing []string
+	for rows.Next() {
+		var region string
+		if err := rows.Scan(&region); err != nil {
+			return fmt.Errorf("store: scan data region: %w", err)
+		}
+		if s.regions[region] == nil {
+			missing = append(missing, region)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("store: iterate data regions: %w", err)
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("store: organizations are resident in %s: %w", strings.Join(missing, ", "), ErrUnknownDataRegion)
+	}
+	return nil
+}
+
+// regionDB returns the database of region; the empty region is the primary
+// database.
+func (s *Store) regionDB(region string) (*sql.DB, error) {
+	if region == "" {
+		return s.db, nil
+	}
+	db := s.regions[region]
+	if db == nil {
+		return nil, fmt.Errorf("store: region %q: %w", region, ErrUnknownDataRegion)
+	}
+	return db, nil
+}
+
+// orgRegion returns the organization's data region, or "" for the primary
+// database.
+func (s *Store) orgRegion(ctx context.Context, orgID int64) (string, error) {
+	if len(s.regions) == 0 {
+		return "", nil
+	}
+	var region sql.NullString
+	err := s.db.QueryRowContext(ctx, `SELECT data_region FROM organizations WHERE id = $1`, orgID).Scan(&region)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return "", fmt.Errorf("store: get organization data region: %w", err)
+	}
+	return region.String, nil
+}
+
+// userRegion returns the data region the user's request logs are kept in.
+func (s *Store) userRegion(ctx context.Context, userID int64) (string, error) {
+	if len(s.regions) == 0 {
+		return "", nil
+	}
+	var region string
+	err := s.db.QueryRowContext(ctx, userDataRegion, userID).Scan(&region)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return "", fmt.Errorf("store: get user data region: %w", err)
+	}
+	return region, nil
+}
+
+// userDB returns the database holding the user's request logs: their
+// data region's, else their shard's, else the primary database.
+func (s *Store) userDB(ctx context.Context, userID int64) (*sql.DB, error) {
+	region, err := s.userRegion(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if region != "" {
+		return s.regionDB(region)
+	}
+	shard, err := s.userShard(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.shardDB(shard)
+}
+
+// SetOrganizationDataRegion moves the organization to region ("" for the
+// primary database): its shared Jira account is copied to the new region
+// and removed from the old one, and its members' request logs follow them
+// unless another organization they joined earlier decides their region.
+// Moving to the current region again finishes a move that failed part way.
+func (s *Store) SetOrganizationDataRegion(ctx context.Context, orgID int64, region string) error {
+	if s == nil || s.db == nil {
+		return errors.New("store: db cannot be nil")
+	}
+	newDB, err := s.regionDB(region)
+	if err != nil {
+		return err
+	}
+
+	var current sql.NullString
+	if err := s.db.QueryRowContext(ctx, `SELECT data_region FROM organizations WHERE id = $1`, orgID).Scan(&current); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrOrganizationNotFound
+		}
+		return fmt.Errorf("store: get organization data region: %w", err)
+	}
+
+	if current.String != region {
+		oldDB, err := s.regionDB(current.String)
+		if err != nil {
+			return err
+		}
+		if err := s.moveOrganizationJiraAccount(ctx, orgID, oldDB, newDB, current.String, region); err != nil {
+			return err
+		}
+		if _, err := s.db.ExecContext(ctx, `
+			UPDATE organizations SET data_region = NULLIF($2, ''), updated_at = now() WHERE id = $1
+		`, orgID, region); err != nil {
+			return fmt.Errorf("store: set organization data region: %w", err)
+		}
+	}
+
+	rows, err := s.db.QueryContext(ctx, `SELECT user_id FROM organization_members WHERE org_id = $1`, orgID)
+	if err != nil {
+		return fmt.Errorf("store: list organization members: %w", err)
+	}
+	var members []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return fmt.Errorf("store: scan organization member: %w", err)
+		}
+		members = append(members, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("store: iterate organization members: %w", err)
+	}
+
+	for _, userID := range members {
+		if err := s.ReconcileUserDataRegion(ctx, userID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReconcileUserDataRegion moves the user's request logs into the database of
+// the region their memberships currently decide, or of their shard when no
+// region does.
+func (s *Store) ReconcileUserDataRegion(ctx context.Context, userID int64) error {
+	if s == nil || s.db == nil {
+		return errors.New("store: db cannot be nil")
+	}
+	if len(s.regions) == 0 && len(s.shards) == 0 {
+		return nil
+	}
+	db, err := s.userDB(ctx, userID)
+	if err != nil {
+		return err
+	}
+	return s.gatherUserRequests(ctx, userID, db)
+}
+
+// moveOrganizationJiraAccount copies the shared Jira account between
+// databases. The primary database keeps a placeholder row without
+// credentials for organizations resident elsewhere.
+func (s *Store) moveOrganizationJiraAccount(ctx context.Context, orgID int64, oldDB, newDB *sql.DB, oldRegion, newRegion string) error {
+	var (
+		baseURL, email, token string
+		cloudID               sql.NullString
+		enabled               bool
+		updatedBy             sql.NullInt64
+	)
+	err := oldDB.QueryRowContext(ctx, `
+		SELECT jira_base_url, jira_email, jira_cloud_id, jira_api_token, enabled, updated_by
+		FROM organization_jira_accounts WHERE org_id = $1
+	`, orgID).Scan(&baseURL, &email, &cloudID, &token, &enabled, &updatedBy)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("store: read organization jira account: %w", err)
+	}
+
+	if err := upsertOrganizationJiraAccountRow(ctx, newDB, orgID, baseURL, email, cloudID, token, enabled, updatedBy); err != nil {
+		return err
+	}
+	if newRegion != "" {
+		if err := upsertOrganizationJiraAccountRow(ctx, s.db, orgID, "", "", sql.NullString{}, "", enabled, updatedBy); err != nil {
+			return err
+		}
+	}
+	if oldRegion != "" {
+		if _, err := oldDB.ExecContext(ctx, `DELETE FROM organization_jira_accounts WHERE org_id = $1`, orgID); err != nil {
+			return fmt.Errorf("store: delete moved organization jira account: %w", err)
+		}
+	}
+	return nil
+}
+
+func upsertOrganizationJiraAccountRow(ctx context.Context, db *sql.DB, orgID int64, baseURL, email string, cloudID sql.NullString, token string, enabled bool, updatedBy sql.NullInt64) error {
+	if _, err := db.ExecContext(ctx, `
+		INSERT INTO organization_jira_accounts (
+			org_id, jira_base_url, jira_email, jira_cloud_id, jira_api_token, enabled, updated_by
+		) VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (org_id) DO UPDATE
+		SET jira_base_url = EXCLUDED.jira_base_url,
+		    jira_email = EXCLUDED.jira_email,
+		    jira_cloud_id = EXCLUDED.jira_cloud_id,
+		    jira_api_token = EXCLUDED.jira_api_token,
+		    enabled = EXCLUDED.enabled,
+		    updated_by = EXCLUDED.updated_by,
+		    updated_at = now()
+	`, orgID, baseURL, email, cloudID, token, enabled, updatedBy); err != nil {
+		return fmt.Errorf("store: write organization jira account: %w", err)
+	}
+	return nil
+}
+
+// gatherUserRequests moves the user's request logs from every other
+// database into target.
+func (s *Store) gatherUserRequests(ctx context.Context, userID int64, target *sql.DB) error {
+	for _, source := range s.requestDatabases() {
+		if source.db == target {
+			continue
+		}
+		if err := moveUserRequests(ctx, source.db, target, userID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// moveUserRequests copies the user's request logs in batches and deletes
+// each batch from source once it is committed to target.
+func moveUserRequests(ctx context.Context, source, target *sql.DB, userID int64) error {
+	for {
+		rows, err := source.QueryContext(ctx, `
+			SELECT id, method, endpoint, status_code, response_time_ms, request_size_bytes,
+			       response_size_bytes, error_message, created_at, sample_weight
+			FROM requests WHERE user_id = $1
+			ORDER BY id
+			LIMIT $2
+		`, userID, requestMoveBatch)
+		if err != nil {
+			return fmt.Errorf("store: read requests to move: %w", err)
+		}
+
+		tx, err := target.BeginTx(ctx, nil)
+		if err != nil {
+			rows.Close()
+			return fmt.Errorf("store: begin request move: %w", err)
+		}
+		var ids []int64
+		for rows.Next() {
+			var (
+				id                              int64
+				method, endpoint                string
+				status, weight                  int
+				responseMs, reqBytes, respBytes sql.NullInt64
+				errMessage                      sql.NullString
+				createdAt                       sql.NullTime
+			)
+			if err := rows.Scan(&id, &method, &endpoint, &status, &responseMs, &reqBytes, &respBytes, &errMessage, &createdAt, &weight); err != nil {
+				rows.Close()
+				tx.Rollback()
+				return fmt.Errorf("store: scan request to move: %w", err)
+			}
+			if _, err := tx.ExecContext(ctx, `
+				INSERT INTO requests (user_id, method, endpoint, status_code, response_time_ms,
+				                      request_size_bytes, response_size_bytes, error_message, created_at, sample_weight)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+			`, userID, method, endpoint, status, responseMs, reqBytes, respBytes, errMessage, createdAt.Time, weight); err != nil {
+				rows.Close()
+				tx.Rollback()
+				return fmt.Errorf("store: copy request: %w", err)
+			}
+			ids = append(ids, id)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("store: iterate requests to move: %w", err)
+		}
+		if len(ids) == 0 {
+			tx.Rollback()
+			return nil
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("store: commit request move: %w", err)
+		}
+
+		if _, err := source.ExecContext(ctx, `DELETE FROM requests WHERE id = ANY($1)`, pq.Array(ids)); err != nil {
+			return fmt.Errorf("store: delete moved requests: %w", err)
+		}
+	}
+}
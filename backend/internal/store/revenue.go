@@ -0,0 +1,69 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+)
+
+// ListRevenueMonths computes MRR, ARR, new and churned MRR and collected
+// revenue for every calendar month from the month of from through the month
+// of to. A subscription counts from its creation until it was canceled;
+// incomplete subscriptions never started and are ignored.
+func (s *Store) ListRevenueMonths(ctx context.Context, from, to time.Time) ([]models.RevenueMonth, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		WITH months AS (
+			SELECT m AS month_start, m + interval '1 month' AS month_end
+			FROM generate_series(date_trunc('month', $1::timestamptz), date_trunc('month', $2::timestamptz), interval '1 month') m
+		),
+		subs AS (
+			SELECT s.created_at AS started_at,
+			       COALESCE(s.canceled_at, CASE WHEN s.status = 'canceled' THEN s.updated_at END) AS ended_at,
+			       CASE WHEN pv.billing_interval = 'year' THEN pv.price_cents / 12.0 ELSE pv.price_cents END AS mrr
+			FROM subscriptions s
+			JOIN plan_versions pv ON pv.stripe_price_id = s.stripe_price_id
+			WHERE s.status NOT IN ('incomplete', 'incomplete_expired')
+		)
+		SELECT m.month_start,
+		       ROUND(COALESCE(SUM(subs.mrr) FILTER (WHERE subs.started_at < m.month_end
+		                                            AND (subs.ended_at IS NULL OR subs.ended_at >= m.month_end)), 0))::bigint,
+		       ROUND(COALESCE(SUM(subs.mrr) FILTER (WHERE subs.started_at >= m.month_start AND subs.started_at < m.month_end), 0))::bigint,
+		       ROUND(COALESCE(SUM(subs.mrr) FILTER (WHERE subs.ended_at >= m.month_start AND subs.ended_at < m.month_end), 0))::bigint,
+		       COUNT(subs.started_at) FILTER (WHERE subs.started_at < m.month_end
+		                                      AND (subs.ended_at IS NULL OR subs.ended_at >= m.month_end)),
+		       (SELECT COALESCE(SUM(p.amount), 0) FROM payment_history p
+		        WHERE p.status = 'succeeded' AND p.created_at >= m.month_start AND p.created_at < m.month_end)
+		FROM months m
+		LEFT JOIN subs ON TRUE
+		GROUP BY m.month_start, m.month_end
+		ORDER BY m.month_start
+	`, from.UTC(), to.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("store: list revenue months: %w", err)
+	}
+	defer rows.Close()
+
+	var months []models.RevenueMonth
+	for rows.Next() {
+		var m models.RevenueMonth
+		if err := rows.Scan(&m.Month, &m.MRR, &m.NewMRR, &m.ChurnedMRR, &m.ActiveSubscriptions, &m.Collected); err != nil {
+			return nil, fmt.Errorf("store: scan revenue month: %w", err)
+		}
+		m.Month = m.Month.UTC()
+		m.ARR = m.MRR * 12
+		m.NetNewMRR = m.NewMRR - m.ChurnedMRR
+		months = append(months, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("store: iterate revenue months: %w", err)
+	}
+
+	return months, nil
+}
@@ -0,0 +1,114 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+)
+
+// GetUserAccess returns the ID, role and email verification of the user
+// with email, picking the same account as EmailVerificationStatus when
+// several share the address.
+func (s *Store) GetUserAccess(ctx context.Context, email string) (*models.UserAccess, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	var access models.UserAccess
+	err := s.db.QueryRowContext(ctx, `
+		SELECT id, email, role, email_verified_at IS NOT NULL
+		FROM users
+		WHERE LOWER(email) = LOWER($1) AND deleted_at IS NULL
+		ORDER BY email_verified_at IS NULL, id
+		LIMIT 1
+	`, email).Scan(&access.UserID, &access.Email, &access.Role, &access.EmailVerified)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("store: get user access: %w", err)
+	}
+	return &access, nil
+}
+
+// GetUserAccessByID is GetUserAccess for the user with userID.
+func (s *Store) GetUserAccessByID(ctx context.Context, userID int64) (*models.UserAccess, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	var access models.UserAccess
+	var email sql.NullString
+	err := s.db.QueryRowContext(ctx, `
+		SELECT id, email, role, email_verified_at IS NOT NULL
+		FROM users
+		WHERE id = $1 AND deleted_at IS NULL
+	`, userID).Scan(&access.UserID, &email, &access.Role, &access.EmailVerified)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("store: get user access: %w", err)
+	}
+	access.Email = email.String
+	return &access, nil
+}
+
+// SetUserRole changes the role of the user with email and records the
+// change in the audit log on behalf of actorID (zero for a system change).
+func (s *Store) SetUserRole(ctx context.Context, email, role string, actorID int64) error {
+	if s == nil || s.db == nil {
+		return errors.New("store: db cannot be nil")
+	}
+	if !models.ValidRole(role) {
+		return fmt.Errorf("store: unknown role %q", role)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("store: begin set user role: %w", err)
+	}
+	defer tx.Rollback()
+
+	var userID int64
+	var previous string
+	err = tx.QueryRowContext(ctx, `
+		SELECT id, role
+		FROM users
+		WHERE LOWER(email) = LOWER($1) AND deleted_at IS NULL
+		ORDER BY email_verified_at IS NULL, id
+		LIMIT 1
+		FOR UPDATE
+	`, email).Scan(&userID, &previous)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrUserNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("store: find user for role change: %w", err)
+	}
+	if previous == role {
+		return nil
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE users SET role = $2, updated_at = NOW() WHERE id = $1`, userID, role); err != nil {
+		return fmt.Errorf("store: set user role: %w", err)
+	}
+	metadata := models.JSONB{"from": previous, "to": role}
+	if actorID > 0 {
+		metadata["actor_id"] = actorID
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO audit_log (user_id, action, metadata)
+		VALUES ($1, 'user.role_changed', $2)
+	`, userID, metadata); err != nil {
+		return fmt.Errorf("store: audit role change: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("store: commit set user role: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,501 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/events"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+)
+
+// ErrSCIMTokenNotFound is returned when a SCIM bearer token is unknown or
+// revoked.
+var ErrSCIMTokenNotFound = errors.New("scim token not found")
+
+// ErrOrganizationMemberNotFound is returned when a user is not a member of
+// the organization.
+var ErrOrganizationMemberNotFound = errors.New("organization member not found")
+
+// ErrOrganizationMemberExists is returned when provisioning a user who is
+// already a member of the organization.
+var ErrOrganizationMemberExists = errors.New("user is already a member of the organization")
+
+// ErrSCIMExternalIDTaken is returned when another member of the organization
+// already has the external id.
+var ErrSCIMExternalIDTaken = errors.New("external id already assigned to another member")
+
+const organizationMemberColumns = `u.id, u.email, u.name, m.role, m.active, m.scim_external_id, m.created_at, m.updated_at`
+
+func scanOrganizationMember(row interface{ Scan(...any) error }) (*models.OrganizationMember, error) {
+	var member models.OrganizationMember
+	var email sql.NullString
+	if err := row.Scan(&member.UserID, &email, &member.Name, &member.Role, &member.Active,
+		&member.ExternalID, &member.CreatedAt, &member.UpdatedAt); err != nil {
+		return nil, err
+	}
+	member.Email = email.String
+	return &member, nil
+}
+
+// CreateSCIMToken stores the hash of a new SCIM bearer token for the
+// organization.
+func (s *Store) CreateSCIMToken(ctx context.Context, orgID int64, name, tokenHash string) (*models.SCIMToken, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	token := models.SCIMToken{Name: name}
+	if err := s.db.QueryRowContext(ctx, `
+		INSERT INTO organization_scim_tokens (org_id, name, token_hash) VALUES ($1, $2, $3)
+		RETURNING id, created_at
+	`, orgID, name, tokenHash).Scan(&token.ID, &token.CreatedAt); err != nil {
+		return nil, fmt.Errorf("store: create scim token: %w", err)
+	}
+
+	return &token, nil
+}
+
+// ListSCIMTokens returns the organization's SCIM tokens, newest first.
+func (s *Store) ListSCIMTokens(ctx context.Context, orgID int64) ([]models.SCIMToken, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, name, created_at, last_used_at, revoked_at
+		FROM organization_scim_tokens
+		WHERE org_id = $1
+		ORDER BY created_at DESC
+	`, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("store: list scim tokens: %w", err)
+	}
+	defer rows.Close()
+
+	var tokens []models.SCIMToken
+	for rows.Next() {
+		var t models.SCIMToken
+		if err := rows.Scan(&t.ID, &t.Name, &t.CreatedAt, &t.LastUsedAt, &t.RevokedAt); err != nil {
+			return nil, fmt.Errorf("store: scan scim token: %w", err)
+		}
+		tokens = append(tokens, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("store: iterate scim tokens: %w", err)
+	}
+
+	return tokens, nil
+}
+
+// RevokeSCIMToken revokes one of the organization's SCIM tokens.
+func (s *Store) RevokeSCIMToken(ctx context.Context, orgID, tokenID int64) error {
+	if s == nil || s.db == nil {
+		return errors.New("store: db cannot be nil")
+	}
+
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE organization_scim_tokens SET revoked_at = now()
+		WHERE id = $1 AND org_id = $2 AND revoked_at IS NULL
+	`, tokenID, orgID)
+	if err != nil {
+		return fmt.Errorf("store: revoke scim token: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrSCIMTokenNotFound
+	}
+
+	return nil
+}
+
+// GetOrganizationIDBySCIMToken resolves a SCIM bearer token hash to its
+// organization and records its use.
+func (s *Store) GetOrganizationIDBySCIMToken(ctx context.Context, tokenHash string) (int64, error) {
+	if s == nil || s.db == nil {
+		return 0, errors.New("store: db cannot be nil")
+	}
+
+	var orgID int64
+	if err := s.db.QueryRowContext(ctx, `
+		UPDATE organization_scim_tokens SET last_used_at = now()
+		WHERE token_hash = $1 AND revoked_at IS NULL
+		RETURNING org_id
+	`, tokenHash).Scan(&orgID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrSCIMTokenNotFound
+		}
+		return 0, fmt.Errorf("store: lookup scim token: %w", err)
+	}
+
+	return orgID, nil
+}
+
+// ListOrganizationMembers returns a page of the organization's members and
+// the total number matching. filterAttr is "", "userName" or "externalId";
+// userName matches the member's email case-insensitively.
+func (s *Store) ListOrganizationMembers(ctx context.Context, orgID int64, filterAttr, filterValue string, offset, limit int) ([]models.OrganizationMember, int, error) {
+	if s == nil || s.db == nil {
+		return nil, 0, errors.New("store: db cannot be nil")
+	}
+
+	where := `m.org_id = $1 AND u.deleted_at IS NULL`
+	args := []any{orgID}
+	switch filterAttr {
+	case "":
+	case "userName":
+		where += ` AND LOWER(u.email) = LOWER($2)`
+		args = append(args, filterValue)
+	case "externalId":
+		where += ` AND m.scim_external_id = $2`
+		args = append(args, filterValue)
+	default:
+		return nil, 0, fmt.Errorf("store: unsupported member filter %q", filterAttr)
+	}
+
+	var total int
+	if err := s.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM organization_members m JOIN users u ON u.id = m.user_id WHERE `+where,
+		args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("store: count organization members: %w", err)
+	}
+
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(`
+		SELECT `+organizationMemberColumns+`
+		FROM organization_members m
+		JOIN users u ON u.id = m.user_id
+		WHERE `+where+`
+		ORDER BY m.created_at, u.id
+		OFFSET $%d LIMIT $%d
+	`, len(args)+1, len(args)+2), append(args, offset, limit)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("store: list organization members: %w", err)
+	}
+	defer rows.Close()
+
+	var members []models.OrganizationMember
+	for rows.Next() {
+		member, err := scanOrganizationMember(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("store: scan organization member: %w", err)
+		}
+		members = append(members, *member)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("store: iterate organization members: %w", err)
+	}
+
+	return members, total, nil
+}
+
+// GetOrganizationMember returns one member of the organization.
+func (s *Store) GetOrganizationMember(ctx context.Context, orgID, userID int64) (*models.OrganizationMember, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	member, err := scanOrganizationMember(s.db.QueryRowContext(ctx, `
+		SELECT `+organizationMemberColumns+`
+		FROM organization_members m
+		JOIN users u ON u.id = m.user_id
+		WHERE m.org_id = $1 AND m.user_id = $2 AND u.deleted_at IS NULL
+	`, orgID, userID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrOrganizationMemberNotFound
+		}
+		return nil, fmt.Errorf("store: get organization member: %w", err)
+	}
+
+	return member, nil
+}
+
+// CreateSCIMMember provisions a user pushed by the organization's identity
+// provider. As with SSO, the email must belong to a claimed domain and must
+// not belong to an existing user outside the organization.
+func (s *Store) CreateSCIMMember(ctx context.Context, orgID int64, email string, name, externalID *string, active bool) (*models.OrganizationMember, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	email = strings.ToLower(strings.TrimSpace(email))
+	at := strings.LastIndex(email, "@")
+	if at <= 0 || at == len(email)-1 {
+		return nil, ErrSSODomainNotAllowed
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("store: begin create scim member tx: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	var claimed bool
+	if err := tx.QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM organization_domains WHERE org_id = $1 AND domain = $2)
+	`, orgID, email[at+1:]).Scan(&claimed); err != nil {
+		return nil, fmt.Errorf("store: check scim domain: %w", err)
+	}
+	if !claimed {
+		return nil, ErrSSODomainNotAllowed
+	}
+
+	if err := checkSCIMExternalID(ctx, tx, orgID, 0, externalID); err != nil {
+		return nil, err
+	}
+
+	var userID int64
+	var member, deleted bool
+	err = tx.QueryRowContext(ctx, `
+		SELECT u.id, EXISTS (SELECT 1 FROM organization_members m WHERE m.org_id = $2 AND m.user_id = u.id),
+			u.deleted_at IS NOT NULL
+		FROM users u
+		WHERE LOWER(u.email) = $1
+		LIMIT 1
+	`, email, orgID).Scan(&userID, &member, &deleted)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		if err := tx.QueryRowContext(ctx, `
+			INSERT INTO users (login, name, email, provider, provider_account_id, email_verified_at)
+			VALUES ($1, $2, $1, 'scim', $3, now())
+			RETURNING id
+		`, email, name, fmt.Sprintf("%d:%s", orgID, email)).Scan(&userID); err != nil {
+			return nil, fmt.Errorf("store: insert scim user: %w", err)
+		}
+	case err != nil:
+		return nil, fmt.Errorf("store: lookup scim user by email: %w", err)
+	case deleted:
+		return nil, ErrAccountDeleted
+	case member:
+		return nil, ErrOrganizationMemberExists
+	default:
+		return nil, ErrSSOAccountConflict
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO organization_members (org_id, user_id, role, active, scim_external_id)
+		VALUES ($1, $2, $3, $4, $5)
+	`, orgID, userID, models.OrgRoleMember, active, externalID); err != nil {
+		return nil, fmt.Errorf("store: insert scim member: %w", err)
+	}
+
+	change := events.MembershipAdded
+	if !active {
+		change = events.MembershipDeactivated
+	}
+	if err := s.enqueueEvent(ctx, tx, events.MembershipChanged{
+		OrgID: orgID, UserID: userID, Change: change, Source: "scim",
+	}); err != nil {
+		return nil, err
+	}
+
+	created, err := scanOrganizationMember(tx.QueryRowContext(ctx, `
+		SELECT `+organizationMemberColumns+`
+		FROM organization_members m
+		JOIN users u ON u.id = m.user_id
+		WHERE m.org_id = $1 AND m.user_id = $2 AND u.deleted_at IS NULL
+	`, orgID, userID))
+	if err != nil {
+		return nil, fmt.Errorf("store: reload scim member: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("store: commit create scim member tx: %w", err)
+	}
+
+	s.wakeOutbox()
+
+	return created, nil
+}
+
+// UpdateOrganizationMember applies a SCIM update to a member. Toggling
+// Active publishes a membership change so seat billing follows.
+func (s *Store) UpdateOrganizationMember(ctx context.Context, orgID, userID int64, update models.OrganizationMemberUpdate) (*models.OrganizationMember, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("store: begin update organization member tx: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	var wasActive bool
+	if err := tx.QueryRowContext(ctx, `
+		SELECT active FROM organization_members WHERE org_id = $1 AND user_id = $2 FOR UPDATE
+	`, orgID, userID).Scan(&wasActive); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrOrganizationMemberNotFound
+		}
+		return nil, fmt.Errorf("store: lock organization member: %w", err)
+	}
+
+	if err := checkSCIMExternalID(ctx, tx, orgID, userID, update.ExternalID); err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE organization_members
+		SET active = COALESCE($3, active),
+		    scim_external_id = COALESCE($4, scim_external_id),
+		    updated_at = now()
+		WHERE org_id = $1 AND user_id = $2
+	`, orgID, userID, update.Active, update.ExternalID); err != nil {
+		return nil, fmt.Errorf("store: update organization member: %w", err)
+	}
+
+	if update.Name != nil {
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE users SET name = $2, updated_at = now() WHERE id = $1
+		`, userID, *update.Name); err != nil {
+			return nil, fmt.Errorf("store: update organization member name: %w", err)
+		}
+	}
+
+	if update.Active != nil && *update.Active != wasActive {
+		change := events.MembershipReactivated
+		if !*update.Active {
+			change = events.MembershipDeactivated
+		}
+		if err := s.enqueueEvent(ctx, tx, events.MembershipChanged{
+			OrgID: orgID, UserID: userID, Change: change, Source: "scim",
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	member, err := scanOrganizationMember(tx.QueryRowContext(ctx, `
+		SELECT `+organizationMemberColumns+`
+		FROM organization_members m
+		JOIN users u ON u.id = m.user_id
+		WHERE m.org_id = $1 AND m.user_id = $2 AND u.deleted_at IS NULL
+	`, orgID, userID))
+	if err != nil {
+		return nil, fmt.Errorf("store: reload organization member: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("store: commit update organization member tx: %w", err)
+	}
+
+	s.wakeOutbox()
+
+	return member, nil
+}
+
+// RemoveOrganizationMember deletes a membership. The organization's owner
+// cannot be removed.
+func (s *Store) RemoveOrganizationMember(ctx context.Context, orgID, userID int64) error {
+	if s == nil || s.db == nil {
+		return errors.New("store: db cannot be nil")
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("store: begin remove organization member tx: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	res, err := tx.ExecContext(ctx, `
+		DELETE FROM organization_members WHERE org_id = $1 AND user_id = $2 AND role <> $3
+	`, orgID, userID, models.OrgRoleOwner)
+	if err != nil {
+		return fmt.Errorf("store: remove organization member: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrOrganizationMemberNotFound
+	}
+
+	if err := s.enqueueEvent(ctx, tx, events.MembershipChanged{
+		OrgID: orgID, UserID: userID, Change: events.MembershipRemoved, Source: "scim",
+	}); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("store: commit remove organization member tx: %w", err)
+	}
+
+	s.wakeOutbox()
+
+	return nil
+}
+
+// GetOrganizationSeats returns the organization's active member count, the
+// seat count last billed, and the owner's active Stripe subscription, which
+// is empty when the organization is not billed.
+func (s *Store) GetOrganizationSeats(ctx context.Context, orgID int64) (int, int, string, error) {
+	if s == nil || s.db == nil {
+		return 0, 0, "", errors.New("store: db cannot be nil")
+	}
+
+	var seats, billed int
+	var subscriptionID sql.NullString
+	if err := s.db.QueryRowContext(ctx, `
+		SELECT
+			(SELECT COUNT(*) FROM organization_members WHERE org_id = o.id AND active),
+			o.billed_seats,
+			(SELECT sub.stripe_subscription_id
+			   FROM organization_members owner
+			   JOIN subscriptions sub ON sub.user_id = owner.user_id
+			  WHERE owner.org_id = o.id AND owner.role = $2
+			    AND sub.status IN ('active', 'trialing')
+			  ORDER BY sub.created_at DESC
+			  LIMIT 1)
+		FROM organizations o
+		WHERE o.id = $1
+	`, orgID, models.OrgRoleOwner).Scan(&seats, &billed, &subscriptionID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, 0, "", ErrOrganizationNotFound
+		}
+		return 0, 0, "", fmt.Errorf("store: get organization seats: %w", err)
+	}
+
+	return seats, billed, subscriptionID.String, nil
+}
+
+// SetOrganizationBilledSeats records the seat count pushed to Stripe.
+func (s *Store) SetOrganizationBilledSeats(ctx context.Context, orgID int64, seats int) error {
+	if s == nil || s.db == nil {
+		return errors.New("store: db cannot be nil")
+	}
+
+	if _, err := s.db.ExecContext(ctx, `
+		UPDATE organizations SET billed_seats = $2, updated_at = now() WHERE id = $1
+	`, orgID, seats); err != nil {
+		return fmt.Errorf("store: set organization billed seats: %w", err)
+	}
+
+	return nil
+}
+
+// checkSCIMExternalID returns ErrSCIMExternalIDTaken if another member of
+// the organization already has the external id.
+func checkSCIMExternalID(ctx context.Context, tx *sql.Tx, orgID, userID int64, externalID *string) error {
+	if externalID == nil || *externalID == "" {
+		return nil
+	}
+
+	var taken bool
+	if err := tx.QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM organization_members
+			WHERE org_id = $1 AND scim_external_id = $2 AND user_id <> $3
+		)
+	`, orgID, *externalID, userID).Scan(&taken); err != nil {
+		return fmt.Errorf("store: check scim external id: %w", err)
+	}
+	if taken {
+		return ErrSCIMExternalIDTaken
+	}
+
+	return nil
+}
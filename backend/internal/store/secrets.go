@@ -0,0 +1,154 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/secretbox"
+)
+
+// SetSecretBox encrypts the credentials the store writes from now on: Jira
+// API tokens and the access and refresh tokens of sign-in providers and
+// integrations. Values written before stay readable; EncryptStoredSecrets
+// seals them. Without a box, credentials are stored in plaintext.
+func (s *Store) SetSecretBox(box *secretbox.Box) {
+	s.secrets = box
+}
+
+// SecretBox returns the box set with SetSecretBox.
+func (s *Store) SecretBox() *secretbox.Box {
+	return s.secrets
+}
+
+// seal encrypts a credential before it is written.
+func (s *Store) seal(value string) (string, error) {
+	sealed, err := s.secrets.Seal(value)
+	if err != nil {
+		return "", fmt.Errorf("store: encrypt credential: %w", err)
+	}
+	return sealed, nil
+}
+
+// open decrypts a credential read from the database.
+func (s *Store) open(value string) (string, error) {
+	plaintext, err := s.secrets.Open(value)
+	if err != nil {
+		return "", fmt.Errorf("store: decrypt credential: %w", err)
+	}
+	return plaintext, nil
+}
+
+// openPtr decrypts an optional credential in place.
+func (s *Store) openPtr(value *string) error {
+	if value == nil {
+		return nil
+	}
+	plaintext, err := s.open(*value)
+	if err != nil {
+		return err
+	}
+	*value = plaintext
+	return nil
+}
+
+// secretColumn is a table column holding credentials, keyed by key.
+type secretColumn struct {
+	table, key, column string
+	// regional is set for tables also kept in the regional databases.
+	regional bool
+}
+
+var secretColumns = []secretColumn{
+	{table: "users_settings", key: "id", column: "jira_api_token"},
+	{table: "users_oauths", key: "id", column: "access_token"},
+	{table: "integration_tokens", key: "id", column: "access_token"},
+	{table: "integration_tokens", key: "id", column: "refresh_token"},
+	{table: "organization_jira_accounts", key: "org_id", column: "jira_api_token", regional: true},
+	{table: "jira_automations", key: "id", column: "destination_url"},
+}
+
+// EncryptStoredSecrets seals up to limit stored credentials per column that
+// are in plaintext or sealed with a key other than the primary one, in the
+// primary and regional databases. It returns how many it sealed; callers
+// repeat it until that is zero. Rows changed concurrently are skipped and
+// picked up by the next pass.
+func (s *Store) EncryptStoredSecrets(ctx context.Context, limit int) (int, error) {
+	if s == nil || s.db == nil {
+		return 0, errors.New("store: db cannot be nil")
+	}
+	if !s.secrets.Enabled() {
+		return 0, nil
+	}
+
+	sealed := 0
+	for _, col := range secretColumns {
+		dbs := []*sql.DB{s.db}
+		if col.regional {
+			for _, db := range s.regions {
+				dbs = append(dbs, db)
+			}
+		}
+		for _, db := range dbs {
+			n, err := s.encryptColumn(ctx, db, col, limit)
+			sealed += n
+			if err != nil {
+				return sealed, err
+			}
+		}
+	}
+	return sealed, nil
+}
+
+func (s *Store) encryptColumn(ctx context.Context, db *sql.DB, col secretColumn, limit int) (int, error) {
+	rows, err := db.QueryContext(ctx, fmt.Sprintf(`
+		SELECT %[2]s, %[3]s FROM %[1]s
+		WHERE %[3]s IS NOT NULL AND %[3]s <> '' AND NOT starts_with(%[3]s, $1)
+		ORDER BY %[2]s
+		LIMIT $2
+	`, col.table, col.key, col.column), s.secrets.PrimaryPrefix(), limit)
+	if err != nil {
+		return 0, fmt.Errorf("store: find plaintext %s.%s: %w", col.table, col.column, err)
+	}
+	type stale struct {
+		key   int64
+		value string
+	}
+	var pending []stale
+	for rows.Next() {
+		var row stale
+		if err := rows.Scan(&row.key, &row.value); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("store: scan %s.%s: %w", col.table, col.column, err)
+		}
+		pending = append(pending, row)
+	}
+	err = rows.Err()
+	rows.Close()
+	if err != nil {
+		return 0, fmt.Errorf("store: iterate %s.%s: %w", col.table, col.column, err)
+	}
+
+	sealed := 0
+	for _, row := range pending {
+		plaintext, err := s.open(row.value)
+		if err != nil {
+			return sealed, fmt.Errorf("store: %s %d: %w", col.table, row.key, err)
+		}
+		value, err := s.seal(plaintext)
+		if err != nil {
+			return sealed, err
+		}
+		res, err := db.ExecContext(ctx, fmt.Sprintf(
+			`UPDATE %s SET %s = $2 WHERE %s = $1 AND %s = $3`,
+			col.table, col.column, col.key, col.column), row.key, value, row.value)
+		if err != nil {
+			return sealed, fmt.Errorf("store: encrypt %s.%s: %w", col.table, col.column, err)
+		}
+		if n, _ := res.RowsAffected(); n > 0 {
+			sealed++
+		}
+	}
+	return sealed, nil
+}
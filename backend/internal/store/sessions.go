@@ -0,0 +1,172 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+)
+
+// ErrSessionNotFound is returned when a session does not exist, belongs to
+// another user or has already been revoked.
+var ErrSessionNotFound = errors.New("session not found")
+
+// CreateUserSession records a new browser session for the user and returns
+// its id, which the session cookie carries.
+func (s *Store) CreateUserSession(ctx context.Context, userID int64, provider, ipAddress, userAgent string, ttl time.Duration) (string, error) {
+	if s == nil || s.db == nil {
+		return "", errors.New("store: db cannot be nil")
+	}
+
+	id, err := randomHex(32)
+	if err != nil {
+		return "", fmt.Errorf("store: generate session id: %w", err)
+	}
+	if _, err := s.db.ExecContext(ctx, `
+		INSERT INTO user_sessions (id, user_id, provider, ip_address, user_agent, expires_at)
+		VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''), now() + make_interval(secs => $6))
+	`, id, userID, provider, ipAddress, truncateUserAgent(userAgent), ttl.Seconds()); err != nil {
+		return "", fmt.Errorf("store: create user session: %w", err)
+	}
+	return id, nil
+}
+
+// CreateOAuthUserSession records a new browser session for the user linked
+// to the provider account, returning ErrUserNotFound for unknown accounts.
+func (s *Store) CreateOAuthUserSession(ctx context.Context, provider, providerAccountID, ipAddress, userAgent string, ttl time.Duration) (string, error) {
+	if s == nil || s.db == nil {
+		return "", errors.New("store: db cannot be nil")
+	}
+
+	var userID int64
+	err := s.db.QueryRowContext(ctx, `
+		SELECT user_id FROM users_oauths WHERE provider = $1 AND provider_account_id = $2
+	`, provider, providerAccountID).Scan(&userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrUserNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("store: lookup oauth session user: %w", err)
+	}
+	return s.CreateUserSession(ctx, userID, provider, ipAddress, userAgent, ttl)
+}
+
+// SessionActive reports whether the session exists, is unexpired and has
+// not been revoked. Active sessions have last_seen_at refreshed at most once
+// a minute.
+func (s *Store) SessionActive(ctx context.Context, id string) (bool, error) {
+	if s == nil || s.db == nil {
+		return false, errors.New("store: db cannot be nil")
+	}
+
+	var active bool
+	if err := s.db.QueryRowContext(ctx, `
+		WITH live AS (
+			SELECT id, last_seen_at FROM user_sessions
+			WHERE id = $1 AND revoked_at IS NULL AND expires_at > now()
+		), touched AS (
+			UPDATE user_sessions us SET last_seen_at = now()
+			FROM live
+			WHERE us.id = live.id AND live.last_seen_at < now() - interval '1 minute'
+		)
+		SELECT EXISTS (SELECT 1 FROM live)
+	`, id).Scan(&active); err != nil {
+		return false, fmt.Errorf("store: check session: %w", err)
+	}
+	return active, nil
+}
+
+// ListUserSessions returns the active sessions of the user with email, most
+// recently used first.
+func (s *Store) ListUserSessions(ctx context.Context, email string) ([]models.UserSession, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT us.id, us.provider, us.ip_address, us.user_agent, us.created_at, us.last_seen_at, us.expires_at
+		FROM user_sessions us
+		JOIN users u ON u.id = us.user_id
+		WHERE LOWER(u.email) = LOWER($1) AND u.deleted_at IS NULL AND us.revoked_at IS NULL AND us.expires_at > now()
+		ORDER BY us.last_seen_at DESC, us.created_at DESC
+		LIMIT $2
+	`, email, defaultPageSize)
+	if err != nil {
+		return nil, fmt.Errorf("store: list user sessions: %w", err)
+	}
+	defer rows.Close()
+
+	var sessions []models.UserSession
+	for rows.Next() {
+		var us models.UserSession
+		if err := rows.Scan(&us.ID, &us.Provider, &us.IPAddress, &us.UserAgent, &us.CreatedAt, &us.LastSeenAt, &us.ExpiresAt); err != nil {
+			return nil, fmt.Errorf("store: scan user session: %w", err)
+		}
+		sessions = append(sessions, us)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("store: iterate user sessions: %w", err)
+	}
+	return sessions, nil
+}
+
+// RevokeUserSession revokes one of the sessions of the user with email.
+func (s *Store) RevokeUserSession(ctx context.Context, email, id string) error {
+	if s == nil || s.db == nil {
+		return errors.New("store: db cannot be nil")
+	}
+
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE user_sessions SET revoked_at = now()
+		WHERE id = $2 AND revoked_at IS NULL
+		  AND user_id IN (SELECT id FROM users WHERE LOWER(email) = LOWER($1) AND deleted_at IS NULL)
+	`, email, id)
+	if err != nil {
+		return fmt.Errorf("store: revoke user session: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+// RevokeAllUserSessions revokes every session of the user, returning how
+// many were revoked. A deleted account's sessions are revoked so that
+// restoring it later means signing in again.
+func (s *Store) RevokeAllUserSessions(ctx context.Context, userID int64) (int64, error) {
+	if s == nil || s.db == nil {
+		return 0, errors.New("store: db cannot be nil")
+	}
+
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE user_sessions SET revoked_at = now()
+		WHERE user_id = $1 AND revoked_at IS NULL
+	`, userID)
+	if err != nil {
+		return 0, fmt.Errorf("store: revoke all user sessions: %w", err)
+	}
+	n, _ := result.RowsAffected()
+	return n, nil
+}
+
+// RevokeOtherUserSessions revokes every session of the user with email
+// except keepID, returning how many were revoked.
+func (s *Store) RevokeOtherUserSessions(ctx context.Context, email, keepID string) (int64, error) {
+	if s == nil || s.db == nil {
+		return 0, errors.New("store: db cannot be nil")
+	}
+
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE user_sessions SET revoked_at = now()
+		WHERE id <> $2 AND revoked_at IS NULL AND expires_at > now()
+		  AND user_id IN (SELECT id FROM users WHERE LOWER(email) = LOWER($1) AND deleted_at IS NULL)
+	`, email, keepID)
+	if err != nil {
+		return 0, fmt.Errorf("store: revoke other user sessions: %w", err)
+	}
+	n, _ := result.RowsAffected()
+	return n, nil
+}
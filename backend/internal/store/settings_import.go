@@ -0,0 +1,97 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+)
+
+// ImportUserSettings upserts every site in one transaction for the user
+// identified by email. When replace is true, sites missing from the import
+// are deleted; they stay restorable for SoftDeleteRetention. If a site is
+// marked default, it becomes the only default. It returns the number of
+// sites written and deleted.
+func (s *Store) ImportUserSettings(ctx context.Context, email string, sites []models.JiraSiteSettings, replace bool) (int, int, error) {
+	if s == nil || s.db == nil {
+		return 0, 0, errors.New("store: db cannot be nil")
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, 0, fmt.Errorf("store: begin settings import tx: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	var userID int64
+	if err := tx.QueryRowContext(ctx,
+		`SELECT id FROM users WHERE LOWER(email) = LOWER($1) AND deleted_at IS NULL`, email,
+	).Scan(&userID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, 0, fmt.Errorf("store: no local user found for email=%s", email)
+		}
+		return 0, 0, fmt.Errorf("store: lookup user by email: %w", err)
+	}
+
+	baseURLs := make([]string, 0, len(sites))
+	hasDefault := false
+	for _, site := range sites {
+		baseURLs = append(baseURLs, site.JiraBaseURL)
+		hasDefault = hasDefault || site.IsDefault
+	}
+
+	deleted := 0
+	if replace {
+		res, err := tx.ExecContext(ctx,
+			`UPDATE users_settings SET deleted_at = now(), updated_at = now()
+			 WHERE user_id = $1 AND deleted_at IS NULL AND NOT (jira_base_url = ANY($2))`,
+			userID, pq.Array(baseURLs))
+		if err != nil {
+			return 0, 0, fmt.Errorf("store: delete replaced users_settings: %w", err)
+		}
+		n, _ := res.RowsAffected()
+		deleted = int(n)
+	}
+
+	if hasDefault {
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE users_settings SET is_default = FALSE, updated_at = now() WHERE user_id = $1 AND is_default`,
+			userID); err != nil {
+			return 0, 0, fmt.Errorf("store: clear default users_settings: %w", err)
+		}
+	}
+
+	for _, site := range sites {
+		apiToken, err := s.seal(site.AtlassianAPIKey)
+		if err != nil {
+			return 0, 0, err
+		}
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO users_settings (user_id, jira_base_url, jira_email, jira_api_token, jira_cloud_id, is_default)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			ON CONFLICT (user_id, jira_base_url) DO UPDATE
+			SET jira_email = EXCLUDED.jira_email,
+			    jira_api_token = EXCLUDED.jira_api_token,
+			    auth_method = 'api_token',
+			    jira_cloud_id = COALESCE(EXCLUDED.jira_cloud_id, users_settings.jira_cloud_id),
+			    is_default = EXCLUDED.is_default OR (users_settings.is_default AND users_settings.deleted_at IS NULL),
+			    revision = users_settings.revision + 1,
+			    deleted_at = NULL,
+			    updated_at = now()
+		`, userID, site.JiraBaseURL, site.JiraEmail, apiToken, site.JiraCloudID, site.IsDefault); err != nil {
+			return 0, 0, fmt.Errorf("store: import users_settings %s: %w", site.JiraBaseURL, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, 0, fmt.Errorf("store: commit settings import: %w", err)
+	}
+
+	return len(sites), deleted, nil
+}
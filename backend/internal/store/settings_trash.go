@@ -0,0 +1,122 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+)
+
+// SoftDeleteRetention is how long deleted accounts, Jira settings and jobs
+// can be restored before the retention job purges them.
+const SoftDeleteRetention = 30 * 24 * time.Hour
+
+// ErrUserSettingsNotFound is returned when a user has no Jira settings, live
+// or restorable, for the requested site.
+var ErrUserSettingsNotFound = errors.New("jira settings not found")
+
+// DeleteUserSettings deletes the Jira settings of the user identified by
+// email for baseURL. The row stays restorable for SoftDeleteRetention.
+func (s *Store) DeleteUserSettings(ctx context.Context, email, baseURL string) error {
+	if s == nil || s.db == nil {
+		return errors.New("store: db cannot be nil")
+	}
+
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE users_settings us SET deleted_at = now(), updated_at = now()
+		FROM users u
+		WHERE us.user_id = u.id AND LOWER(u.email) = LOWER($1) AND u.deleted_at IS NULL
+		  AND us.jira_base_url = $2 AND us.deleted_at IS NULL
+	`, email, baseURL)
+	if err != nil {
+		return fmt.Errorf("store: delete users_settings: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrUserSettingsNotFound
+	}
+	return nil
+}
+
+// RestoreUserSettings undoes DeleteUserSettings within the retention window.
+// The restored site stays the default only while no other site has become
+// the default since.
+func (s *Store) RestoreUserSettings(ctx context.Context, email, baseURL string) error {
+	if s == nil || s.db == nil {
+		return errors.New("store: db cannot be nil")
+	}
+
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE users_settings us SET
+			deleted_at = NULL,
+			is_default = us.is_default AND NOT EXISTS (
+				SELECT 1 FROM users_settings o
+				WHERE o.user_id = us.user_id AND o.is_default AND o.deleted_at IS NULL),
+			revision = us.revision + 1,
+			updated_at = now()
+		FROM users u
+		WHERE us.user_id = u.id AND LOWER(u.email) = LOWER($1) AND u.deleted_at IS NULL
+		  AND us.jira_base_url = $2
+		  AND us.deleted_at > now() - make_interval(secs => $3)
+	`, email, baseURL, SoftDeleteRetention.Seconds())
+	if err != nil {
+		return fmt.Errorf("store: restore users_settings: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrUserSettingsNotFound
+	}
+	return nil
+}
+
+// ListDeletedUserSettings returns the user's deleted Jira sites that can
+// still be restored, most recently deleted first.
+func (s *Store) ListDeletedUserSettings(ctx context.Context, email string) ([]models.DeletedJiraUserSettings, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT us.jira_base_url, us.jira_email, us.deleted_at
+		FROM users_settings us
+		JOIN users u ON us.user_id = u.id
+		WHERE LOWER(u.email) = LOWER($1) AND u.deleted_at IS NULL
+		  AND us.deleted_at > now() - make_interval(secs => $2)
+		ORDER BY us.deleted_at DESC
+	`, email, SoftDeleteRetention.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("store: list deleted users_settings: %w", err)
+	}
+	defer rows.Close()
+
+	var deleted []models.DeletedJiraUserSettings
+	for rows.Next() {
+		var d models.DeletedJiraUserSettings
+		if err := rows.Scan(&d.JiraBaseURL, &d.JiraEmail, &d.DeletedAt); err != nil {
+			return nil, fmt.Errorf("store: scan deleted users_settings: %w", err)
+		}
+		d.PurgeAfter = d.DeletedAt.Add(SoftDeleteRetention)
+		deleted = append(deleted, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("store: iterate deleted users_settings: %w", err)
+	}
+	return deleted, nil
+}
+
+// PurgeDeletedUserSettings removes Jira settings deleted more than retention
+// ago and returns how many it removed.
+func (s *Store) PurgeDeletedUserSettings(ctx context.Context, retention time.Duration) (int64, error) {
+	if s == nil || s.db == nil {
+		return 0, errors.New("store: db cannot be nil")
+	}
+
+	result, err := s.db.ExecContext(ctx,
+		`DELETE FROM users_settings WHERE deleted_at < now() - make_interval(secs => $1)`,
+		retention.Seconds())
+	if err != nil {
+		return 0, fmt.Errorf("store: purge deleted users_settings: %w", err)
+	}
+	n, _ := result.RowsAffected()
+	return n, nil
+}
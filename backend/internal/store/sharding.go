@@ -0,0 +1,225 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+)
+
+// ErrUnknownShard is returned for a shard without a configured database.
+var ErrUnknownShard = errors.New("unknown shard")
+
+// ErrTenantResident is returned when placing a tenant whose organization is
+// resident in a data region on a shard; residency decides where their data
+// lives.
+var ErrTenantResident = errors.New("tenant is resident in a data region")
+
+// SetShardDatabases configures the shard databases by shard name. Tenants
+// placed on a shard keep their request logs there; like regional databases,
+// shards use the regional schema.
+func (s *Store) SetShardDatabases(dbs map[string]*sql.DB) {
+	s.shards = dbs
+}
+
+// ShardDatabases returns the shard databases by shard name.
+func (s *Store) ShardDatabases() map[string]*sql.DB {
+	return s.shards
+}
+
+// Shards returns the names of the configured shards, sorted.
+func (s *Store) Shards() []string {
+	names := make([]string, 0, len(s.shards))
+	for name := range s.shards {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// requestDatabase is a database holding request logs.
+type requestDatabase struct {
+	label string
+	db    *sql.DB
+}
+
+// requestDatabases returns every database holding request logs: the
+// primary database first, then the regional and shard databases by name.
+func (s *Store) requestDatabases() []requestDatabase {
+	dbs := []requestDatabase{{label: "primary", db: s.db}}
+	for _, region := range s.DataRegions() {
+		dbs = append(dbs, requestDatabase{label: "region " + region, db: s.regions[region]})
+	}
+	for _, shard := range s.Shards() {
+		dbs = append(dbs, requestDatabase{label: "shard " + shard, db: s.shards[shard]})
+	}
+	return dbs
+}
+
+// shardDB returns the database of shard; the empty shard is the primary
+// database.
+func (s *Store) shardDB(shard string) (*sql.DB, error) {
+	if shard == "" {
+		return s.db, nil
+	}
+	db := s.shards[shard]
+	if db == nil {
+		return nil, fmt.Errorf("store: shard %q: %w", shard, ErrUnknownShard)
+	}
+	return db, nil
+}
+
+// userShard returns the shard the user is placed on, or "" for the primary
+// database.
+func (s *Store) userShard(ctx context.Context, userID int64) (string, error) {
+	if len(s.shards) == 0 {
+		return "", nil
+	}
+	var shard string
+	err := s.db.QueryRowContext(ctx, `SELECT shard FROM tenant_shards WHERE user_id = $1`, userID).Scan(&shard)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return "", fmt.Errorf("store: get tenant shard: %w", err)
+	}
+	return shard, nil
+}
+
+// CheckShards returns an error naming the shards tenants are placed on that
+// have no configured database. Their request logs would otherwise be
+// written to and read from the wrong database.
+func (s *Store) CheckShards(ctx context.Context) error {
+	if s == nil || s.db == nil {
+		return errors.New("store: db cannot be nil")
+	}
+
+	rows, err := s.db.QueryContext(ctx, `SELECT DISTINCT shard FROM tenant_shards`)
+	if err != nil {
+		return fmt.Errorf("store: list tenant shards: %w", err)
+	}
+	defer rows.Close()
+
+	var missing []string
+	for rows.Next() {
+		var shard string
+		if err := rows.Scan(&shard); err != nil {
+			return fmt.Errorf("store: scan tenant shard: %w", err)
+		}
+		if s.shards[shard] == nil {
+			missing = append(missing, shard)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("store: iterate tenant shards: %w", err)
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("store: tenants are placed on %s: %w", strings.Join(missing, ", "), ErrUnknownShard)
+	}
+	return nil
+}
+
+// GetTenantShard returns where the user's request logs are kept.
+func (s *Store) GetTenantShard(ctx context.Context, userID int64) (*models.TenantShard, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	placement := &models.TenantShard{UserID: userID, Status: models.TenantShardActive}
+	var updatedAt sql.NullTime
+	err := s.db.QueryRowContext(ctx, `
+		SELECT shard, status, updated_at FROM tenant_shards WHERE user_id = $1
+	`, userID).Scan(&placement.Shard, &placement.Status, &updatedAt)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("store: get tenant shard: %w", err)
+	}
+	if updatedAt.Valid {
+		placement.UpdatedAt = &updatedAt.Time
+	}
+
+	region, err := s.userRegion(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	placement.DataRegion = region
+	return placement, nil
+}
+
+// MoveTenantToShard places the user on shard ("" for the primary database)
+// and moves their existing request logs there. New logs go to the shard as
+// soon as the placement is recorded, while the move is still copying; the
+// placement reads "moving" until the copy completes. Moving to the current
+// shard again finishes a move that failed part way.
+func (s *Store) MoveTenantToShard(ctx context.Context, userID int64, shard string) error {
+	if s == nil || s.db == nil {
+		return errors.New("store: db cannot be nil")
+	}
+	target, err := s.shardDB(shard)
+	if err != nil {
+		return err
+	}
+
+	var exists bool
+	if err := s.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM users WHERE id = $1 AND deleted_at IS NULL)`, userID).Scan(&exists); err != nil {
+		return fmt.Errorf("store: check tenant: %w", err)
+	}
+	if !exists {
+		return ErrUserNotFound
+	}
+	region, err := s.userRegion(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if region != "" {
+		return fmt.Errorf("store: user %d is resident in %s: %w", userID, region, ErrTenantResident)
+	}
+
+	if shard == "" {
+		if _, err := s.db.ExecContext(ctx, `DELETE FROM tenant_shards WHERE user_id = $1`, userID); err != nil {
+			return fmt.Errorf("store: clear tenant shard: %w", err)
+		}
+	} else if _, err := s.db.ExecContext(ctx, `
+		INSERT INTO tenant_shards (user_id, shard, status)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id) DO UPDATE
+		SET shard = EXCLUDED.shard, status = EXCLUDED.status, updated_at = now()
+	`, userID, shard, models.TenantShardMoving); err != nil {
+		return fmt.Errorf("store: set tenant shard: %w", err)
+	}
+
+	if err := s.gatherUserRequests(ctx, userID, target); err != nil {
+		return err
+	}
+
+	if shard != "" {
+		if _, err := s.db.ExecContext(ctx, `
+			UPDATE tenant_shards SET status = $3, updated_at = now() WHERE user_id = $1 AND shard = $2
+		`, userID, shard, models.TenantShardActive); err != nil {
+			return fmt.Errorf("store: finish tenant shard move: %w", err)
+		}
+	}
+	return nil
+}
+
+// ListShardUsage reports the request logs held by the primary database and
+// each shard, for deciding which tenants to rebalance.
+func (s *Store) ListShardUsage(ctx context.Context) ([]models.ShardUsage, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	usage := make([]models.ShardUsage, 0, len(s.shards)+1)
+	for _, shard := range append([]string{""}, s.Shards()...) {
+		db, err := s.shardDB(shard)
+		if err != nil {
+			return nil, err
+		}
+		row := models.ShardUsage{Shard: shard}
+		if err := db.QueryRowContext(ctx, `SELECT COUNT(DISTINCT user_id), COUNT(*) FROM requests`).Scan(&row.Tenants, &row.Requests); err != nil {
+			return nil, fmt.Errorf("store: count requests in shard %q: %w", shard, err)
+		}
+		usage = append(usage, row)
+	}
+	return usage, nil
+}
@@ -0,0 +1,146 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+)
+
+// ExplainQuery returns the planner's EXPLAIN (FORMAT JSON) output for query
+// without executing it. Only single SELECT, INSERT, UPDATE, DELETE and WITH
+// statements are explained.
+func (s *Store) ExplainQuery(ctx context.Context, query string, args []any) ([]byte, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	trimmed := strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(query), ";"))
+	verb, _, _ := strings.Cut(trimmed, " ")
+	switch strings.ToUpper(verb) {
+	case "SELECT", "INSERT", "UPDATE", "DELETE", "WITH":
+	default:
+		return nil, fmt.Errorf("store: cannot explain %s statements", verb)
+	}
+	if strings.Contains(trimmed, ";") {
+		return nil, errors.New("store: cannot explain multiple statements")
+	}
+
+	var plan []byte
+	if err := s.db.QueryRowContext(ctx, `EXPLAIN (FORMAT JSON) `+trimmed, args...).Scan(&plan); err != nil {
+		return nil, fmt.Errorf("store: explain query: %w", err)
+	}
+
+	return plan, nil
+}
+
+// RecordSlowQuery adds one slow execution to the statement's aggregate. A
+// nil plan keeps the previously captured plan.
+func (s *Store) RecordSlowQuery(ctx context.Context, fingerprint, query string, duration time.Duration, plan []byte) error {
+	if s == nil || s.db == nil {
+		return errors.New("store: db cannot be nil")
+	}
+
+	ms := float64(duration) / float64(time.Millisecond)
+	var planArg any
+	if len(plan) > 0 {
+		planArg = string(plan)
+	}
+	if _, err := s.db.ExecContext(ctx, `
+		INSERT INTO slow_queries (fingerprint, query, calls, total_ms, max_ms, plan, plan_captured_at)
+		VALUES ($1, $2, 1, $3, $3, $4::jsonb, CASE WHEN $4::jsonb IS NULL THEN NULL ELSE now() END)
+		ON CONFLICT (fingerprint) DO UPDATE
+		SET calls = slow_queries.calls + 1,
+		    total_ms = slow_queries.total_ms + EXCLUDED.total_ms,
+		    max_ms = GREATEST(slow_queries.max_ms, EXCLUDED.max_ms),
+		    plan = COALESCE(EXCLUDED.plan, slow_queries.plan),
+		    plan_captured_at = COALESCE(EXCLUDED.plan_captured_at, slow_queries.plan_captured_at),
+		    last_seen_at = now()
+	`, fingerprint, query, ms, planArg); err != nil {
+		return fmt.Errorf("store: record slow query: %w", err)
+	}
+
+	return nil
+}
+
+// ListSlowQueries returns recorded slow statements, most total time first.
+func (s *Store) ListSlowQueries(ctx context.Context, limit int) ([]models.SlowQuery, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+	if limit <= 0 {
+		limit = 100
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT fingerprint, query, calls, total_ms, max_ms, plan, plan_captured_at, first_seen_at, last_seen_at
+		FROM slow_queries
+		ORDER BY total_ms DESC
+		LIMIT $1
+	`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("store: list slow queries: %w", err)
+	}
+	defer rows.Close()
+
+	var queries []models.SlowQuery
+	for rows.Next() {
+		var q models.SlowQuery
+		var plan []byte
+		if err := rows.Scan(&q.Fingerprint, &q.Query, &q.Calls, &q.TotalMs, &q.MaxMs, &plan,
+			&q.PlanCapturedAt, &q.FirstSeenAt, &q.LastSeenAt); err != nil {
+			return nil, fmt.Errorf("store: scan slow query: %w", err)
+		}
+		q.Plan = plan
+		queries = append(queries, q)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("store: iterate slow queries: %w", err)
+	}
+
+	return queries, nil
+}
+
+// ListIndexColumns returns the column lists of every index in the current
+// schema, keyed by table. Expression columns are reported as "(expr)".
+func (s *Store) ListIndexColumns(ctx context.Context) (map[string][][]string, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT t.relname,
+		       array_agg(COALESCE(a.attname::text, '(expr)') ORDER BY k.ord)
+		FROM pg_index x
+		JOIN pg_class t ON t.oid = x.indrelid
+		JOIN pg_namespace n ON n.oid = t.relnamespace
+		CROSS JOIN LATERAL unnest(x.indkey::int2[]) WITH ORDINALITY AS k(attnum, ord)
+		LEFT JOIN pg_attribute a ON a.attrelid = t.oid AND a.attnum = k.attnum
+		WHERE n.nspname = current_schema()
+		GROUP BY t.relname, x.indexrelid
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("store: list index columns: %w", err)
+	}
+	defer rows.Close()
+
+	indexes := map[string][][]string{}
+	for rows.Next() {
+		var table string
+		var columns []string
+		if err := rows.Scan(&table, pq.Array(&columns)); err != nil {
+			return nil, fmt.Errorf("store: scan index columns: %w", err)
+		}
+		indexes[table] = append(indexes[table], columns)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("store: iterate index columns: %w", err)
+	}
+
+	return indexes, nil
+}
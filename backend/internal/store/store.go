@@ -4,22 +4,66 @@ import (
 	"context"
 	"crypto/rand"
 	"database/sql"
+	"encoding/base64"
 	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
+	"sort"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/events"
 	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/secretbox"
 )
 
 const (
 	defaultPageSize = 200
 )
 
+// ErrRevisionMismatch is returned by conditional writes when the stored
+// revision no longer matches the one the caller read.
+var ErrRevisionMismatch = errors.New("revision mismatch")
+
+// ErrMCPSecretNotFound is returned when an mcp_secret matches no user.
+var ErrMCPSecretNotFound = errors.New("store: no user found for MCP secret")
+
 // Store provides database-backed accessors for application data.
 type Store struct {
-	db *sql.DB
+	db     *sql.DB
+	outbox *OutboxStore
+
+	// regions holds the regional databases by data region name.
+	regions map[string]*sql.DB
+	// shards holds the shard databases by shard name.
+	shards map[string]*sql.DB
+	// secrets encrypts stored credentials; nil stores them in plaintext.
+	secrets *secretbox.Box
+}
+
+// SetOutbox routes user lifecycle events through the transactional outbox so
+// they are committed together with the change that produced them. Without an
+// outbox, the store emits no events.
+func (s *Store) SetOutbox(outbox *OutboxStore) {
+	s.outbox = outbox
+}
+
+// enqueueEvent writes ev to the outbox as part of tx when an outbox is set.
+func (s *Store) enqueueEvent(ctx context.Context, tx *sql.Tx, ev events.Event) error {
+	if s.outbox == nil {
+		return nil
+	}
+	return s.outbox.EnqueueTx(ctx, tx, ev)
+}
+
+// wakeOutbox nudges the dispatcher after a transaction with events commits.
+func (s *Store) wakeOutbox() {
+	if s.outbox != nil {
+		s.outbox.Wake()
+	}
 }
 
 // New creates a Store using the provided sql.DB connection.
@@ -30,55 +74,162 @@ func New(db *sql.DB) (*Store, error) {
 	return &Store{db: db}, nil
 }
 
-// ListUsers returns up to `limit` users ordered by creation time descending.
-func (s *Store) ListUsers(ctx context.Context, limit int) ([]models.PublicUser, error) {
+// ErrInvalidUserSort is returned by ListUsers for an unknown sort order.
+var ErrInvalidUserSort = errors.New("store: invalid user sort")
+
+// ErrInvalidUserCursor is returned by ListUsers for a cursor it did not
+// issue or that was issued for another sort order.
+var ErrInvalidUserCursor = errors.New("store: invalid user cursor")
+
+// userSortColumns maps each user sort order to the column it sorts by.
+// Missing emails and names sort as empty strings.
+var userSortColumns = map[string]string{
+	models.UserSortCreatedDesc: "created_at",
+	models.UserSortCreatedAsc:  "created_at",
+	models.UserSortEmailAsc:    "COALESCE(email, '')",
+	models.UserSortEmailDesc:   "COALESCE(email, '')",
+	models.UserSortNameAsc:     "COALESCE(name, '')",
+	models.UserSortNameDesc:    "COALESCE(name, '')",
+}
+
+// userCursor is the position after the last user of a page: its sort key
+// and ID. Cursors are opaque to callers.
+type userCursor struct {
+	Sort  string `json:"s"`
+	Value string `json:"v"`
+	ID    int64  `json:"id"`
+}
+
+func (c userCursor) encode() string {
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeUserCursor(cursor, sort string) (*userCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidUserCursor
+	}
+	var c userCursor
+	if err := json.Unmarshal(raw, &c); err != nil || c.Sort != sort || c.ID <= 0 {
+		return nil, ErrInvalidUserCursor
+	}
+	return &c, nil
+}
+
+// ListUsers returns a page of up to opts.Limit users (defaultPageSize at
+// most) in opts.Sort order, newest first by default, after opts.Cursor.
+// The page's NextCursor continues the listing; Total counts all users.
+func (s *Store) ListUsers(ctx context.Context, opts models.UserListOptions) (*models.UserPage, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+	limit := opts.Limit
 	if limit <= 0 || limit > defaultPageSize {
 		limit = defaultPageSize
 	}
+	sortOrder := opts.Sort
+	if sortOrder == "" {
+		sortOrder = models.UserSortCreatedDesc
+	}
+	column, ok := userSortColumns[sortOrder]
+	if !ok {
+		return nil, ErrInvalidUserSort
+	}
+	direction, op := "ASC", ">"
+	if strings.HasPrefix(sortOrder, "-") {
+		direction, op = "DESC", "<"
+	}
+
+	// One extra row tells whether another page follows.
+	args := []any{limit + 1}
+	where := "WHERE deleted_at IS NULL"
+	if opts.Cursor != "" {
+		cursor, err := decodeUserCursor(opts.Cursor, sortOrder)
+		if err != nil {
+			return nil, err
+		}
+		// column is the same expression the rows are ordered by, so users
+		// without a name or email compare as "" on both sides of the cursor.
+		where += fmt.Sprintf(" AND (%s, id) %s ($2, $3)", column, op)
+		if column == "created_at" {
+			at, err := time.Parse(time.RFC3339Nano, cursor.Value)
+			if err != nil {
+				return nil, ErrInvalidUserCursor
+			}
+			args = append(args, at, cursor.ID)
+		} else {
+			args = append(args, cursor.Value, cursor.ID)
+		}
+	}
 
 	query := fmt.Sprintf(`
 SELECT
   id::text AS id,
   email,
   name,
-  avatar_url AS image
+  avatar_url AS image,
+  created_at
 FROM users
-ORDER BY created_at DESC
+%s
+ORDER BY %s %s, id %s
 LIMIT $1
-`)
+`, where, column, direction, direction)
 
-	rows, err := s.db.QueryContext(ctx, query, limit)
+	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("query users: %w", err)
 	}
 	defer rows.Close()
 
-	var users []models.PublicUser
+	page := &models.UserPage{Users: []models.PublicUser{}}
+	var last userCursor
 	for rows.Next() {
 		var (
-			id    string
-			email sql.NullString
-			name  sql.NullString
-			image sql.NullString
+			id        string
+			email     sql.NullString
+			name      sql.NullString
+			image     sql.NullString
+			createdAt time.Time
 		)
 
-		if err := rows.Scan(&id, &email, &name, &image); err != nil {
+		if err := rows.Scan(&id, &email, &name, &image, &createdAt); err != nil {
 			return nil, fmt.Errorf("scan users: %w", err)
 		}
 
-		users = append(users, models.PublicUser{
+		if len(page.Users) == limit {
+			page.NextCursor = last.encode()
+			break
+		}
+		page.Users = append(page.Users, models.PublicUser{
 			ID:    id,
 			Email: nullStringPtr(email),
 			Name:  nullStringPtr(name),
 			Image: nullStringPtr(image),
 		})
+
+		last = userCursor{Sort: sortOrder}
+		last.ID, _ = strconv.ParseInt(id, 10, 64)
+		switch strings.TrimPrefix(sortOrder, "-") {
+		case "created_at":
+			last.Value = createdAt.UTC().Format(time.RFC3339Nano)
+		case "email":
+			last.Value = email.String
+		default:
+			last.Value = name.String
+		}
 	}
 
 	if err := rows.Err(); err != nil {
 		return nil, fmt.Errorf("iterate users: %w", err)
 	}
+	rows.Close()
+
+	if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM users WHERE deleted_at IS NULL`).Scan(&page.Total); err != nil {
+		return nil, fmt.Errorf("count users: %w", err)
+	}
 
-	return users, nil
+	return page, nil
 }
 
 // UpsertGitHubUser ensures that the given GitHub-authenticated user exists in
@@ -89,6 +240,11 @@ func (s *Store) UpsertGitHubUser(ctx context.Context, user models.GitHubAuthUser
 		return errors.New("store: db cannot be nil")
 	}
 
+	accessToken, err := s.seal(user.AccessToken)
+	if err != nil {
+		return err
+	}
+
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("store: begin upsert github user tx: %w", err)
@@ -102,22 +258,44 @@ func (s *Store) UpsertGitHubUser(ctx context.Context, user models.GitHubAuthUser
 	var userID int64
 	var existingEmail sql.NullString
 	var existingAvatar sql.NullString
+	var existingVerified bool
+	var deleted bool
 	var foundByEmail bool
 
 	if user.Email != nil && *user.Email != "" {
 		if err := tx.QueryRowContext(
 			ctx,
-			`SELECT id, email, avatar_url FROM users WHERE LOWER(email) = LOWER($1) LIMIT 1`,
+			`SELECT id, email, avatar_url, email_verified_at IS NOT NULL, deleted_at IS NOT NULL FROM users WHERE LOWER(email) = LOWER($1) LIMIT 1`,
 			*user.Email,
-		).Scan(&userID, &existingEmail, &existingAvatar); err == nil {
+		).Scan(&userID, &existingEmail, &existingAvatar, &existingVerified, &deleted); err == nil {
 			foundByEmail = true
 		} else if !errors.Is(err, sql.ErrNoRows) {
 			return fmt.Errorf("store: lookup user by email: %w", err)
 		}
 	}
+	if deleted {
+		return ErrAccountDeleted
+	}
 
 	accountID := strconv.FormatInt(user.GitHubID, 10)
 
+	// An unverified email must not merge this login into someone else's
+	// verified account, unless this GitHub identity is already linked to it.
+	if foundByEmail && existingVerified && !user.EmailVerified {
+		var linked bool
+		if err := tx.QueryRowContext(
+			ctx,
+			`SELECT EXISTS (SELECT 1 FROM users_oauths WHERE provider = 'github' AND provider_account_id = $1 AND user_id = $2)`,
+			accountID,
+			userID,
+		).Scan(&linked); err != nil {
+			return fmt.Errorf("store: lookup linked github identity: %w", err)
+		}
+		if !linked {
+			return ErrEmailUnverified
+		}
+	}
+
 	if !foundByEmail {
 		// Create or update a user row keyed by (provider, provider_account_id).
 		if err := tx.QueryRowContext(
@@ -130,6 +308,7 @@ func (s *Store) UpsertGitHubUser(ctx context.Context, user models.GitHubAuthUser
 			     email = EXCLUDED.email,
 			     avatar_url = EXCLUDED.avatar_url,
 			     updated_at = now()
+			 WHERE users.deleted_at IS NULL
 			 RETURNING id`,
 			user.Login,
 			user.Name,
@@ -137,7 +316,10 @@ func (s *Store) UpsertGitHubUser(ctx context.Context, user models.GitHubAuthUser
 			user.AvatarURL,
 			"github",
 			accountID,
-		).Scan(&userID); err != nil {
+		).Scan(&userID); errors.Is(err, sql.ErrNoRows) {
+			// The conflicting row belongs to a deleted account.
+			return ErrAccountDeleted
+		} else if err != nil {
 			return fmt.Errorf("store: upsert users by provider/account: %w", err)
 		}
 	} else {
@@ -183,17 +365,29 @@ func (s *Store) UpsertGitHubUser(ctx context.Context, user models.GitHubAuthUser
 		userID,
 		"github",
 		accountID,
-		user.AccessToken,
+		accessToken,
 		scope,
 		user.AvatarURL,
 	); err != nil {
 		return fmt.Errorf("store: upsert users_oauths: %w", err)
 	}
 
+	if user.EmailVerified {
+		if err := markEmailVerified(ctx, tx, userID); err != nil {
+			return err
+		}
+	}
+
+	if err := s.enqueueEvent(ctx, tx, events.UserUpserted{UserID: userID, Email: stringValue(user.Email), Provider: "github"}); err != nil {
+		return err
+	}
+
 	if err := tx.Commit(); err != nil {
 		return fmt.Errorf("store: commit upsert github user tx: %w", err)
 	}
 
+	s.wakeOutbox()
+
 	return nil
 }
 
@@ -205,6 +399,11 @@ func (s *Store) UpsertGoogleUser(ctx context.Context, user models.GoogleAuthUser
 		return errors.New("store: db cannot be nil")
 	}
 
+	accessToken, err := s.seal(user.AccessToken)
+	if err != nil {
+		return err
+	}
+
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("store: begin upsert google user tx: %w", err)
@@ -216,19 +415,23 @@ func (s *Store) UpsertGoogleUser(ctx context.Context, user models.GoogleAuthUser
 	var userID int64
 	var existingEmail sql.NullString
 	var existingAvatar sql.NullString
+	var deleted bool
 	var foundByEmail bool
 
 	if user.Email != nil && *user.Email != "" {
 		if err := tx.QueryRowContext(
 			ctx,
-			`SELECT id, email, avatar_url FROM users WHERE LOWER(email) = LOWER($1) LIMIT 1`,
+			`SELECT id, email, avatar_url, deleted_at IS NOT NULL FROM users WHERE LOWER(email) = LOWER($1) LIMIT 1`,
 			*user.Email,
-		).Scan(&userID, &existingEmail, &existingAvatar); err == nil {
+		).Scan(&userID, &existingEmail, &existingAvatar, &deleted); err == nil {
 			foundByEmail = true
 		} else if !errors.Is(err, sql.ErrNoRows) {
 			return fmt.Errorf("store: lookup user by email: %w", err)
 		}
 	}
+	if deleted {
+		return ErrAccountDeleted
+	}
 
 	accountID := user.Sub
 	login := accountID
@@ -248,6 +451,7 @@ func (s *Store) UpsertGoogleUser(ctx context.Context, user models.GoogleAuthUser
 			     email = EXCLUDED.email,
 			     avatar_url = EXCLUDED.avatar_url,
 			     updated_at = now()
+			 WHERE users.deleted_at IS NULL
 			 RETURNING id`,
 			login,
 			user.Name,
@@ -255,7 +459,9 @@ func (s *Store) UpsertGoogleUser(ctx context.Context, user models.GoogleAuthUser
 			user.AvatarURL,
 			"google",
 			accountID,
-		).Scan(&userID); err != nil {
+		).Scan(&userID); errors.Is(err, sql.ErrNoRows) {
+			return ErrAccountDeleted
+		} else if err != nil {
 			return fmt.Errorf("store: upsert users by provider/account (google): %w", err)
 		}
 	} else {
@@ -296,17 +502,28 @@ func (s *Store) UpsertGoogleUser(ctx context.Context, user models.GoogleAuthUser
 		userID,
 		"google",
 		accountID,
-		user.AccessToken,
+		accessToken,
 		"",
 		user.AvatarURL,
 	); err != nil {
 		return fmt.Errorf("store: upsert users_oauths (google): %w", err)
 	}
 
+	// Google only hands out addresses it has verified.
+	if err := markEmailVerified(ctx, tx, userID); err != nil {
+		return err
+	}
+
+	if err := s.enqueueEvent(ctx, tx, events.UserUpserted{UserID: userID, Email: stringValue(user.Email), Provider: "google"}); err != nil {
+		return err
+	}
+
 	if err := tx.Commit(); err != nil {
 		return fmt.Errorf("store: commit upsert google user tx: %w", err)
 	}
 
+	s.wakeOutbox()
+
 	return nil
 }
 
@@ -315,39 +532,91 @@ func (s *Store) UpsertGoogleUser(ctx context.Context, user models.GoogleAuthUser
 // and is stored as-is in users_settings. It will create or update the record
 // in the users_settings table identified by (user_id, jira_base_url).
 func (s *Store) UpsertUserSettings(ctx context.Context, userEmail, baseURL, jiraEmail, apiKey string) error {
+	_, err := s.upsertUserSettings(ctx, userEmail, baseURL, jiraEmail, apiKey, nil)
+	return err
+}
+
+// UpsertUserSettingsIfRevision behaves like UpsertUserSettings but only
+// writes when the row's current revision equals ifRevision (0 meaning the
+// row must not exist yet). It returns the new revision, or an error wrapping
+// ErrRevisionMismatch when another writer got there first.
+func (s *Store) UpsertUserSettingsIfRevision(ctx context.Context, userEmail, baseURL, jiraEmail, apiKey string, ifRevision int64) (int64, error) {
+	return s.upsertUserSettings(ctx, userEmail, baseURL, jiraEmail, apiKey, &ifRevision)
+}
+
+func (s *Store) upsertUserSettings(ctx context.Context, userEmail, baseURL, jiraEmail, apiKey string, ifRevision *int64) (int64, error) {
 	if s == nil || s.db == nil {
-		return errors.New("store: db cannot be nil")
+		return 0, errors.New("store: db cannot be nil")
+	}
+
+	apiKey, err := s.seal(apiKey)
+	if err != nil {
+		return 0, err
 	}
 
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("store: begin users_settings tx: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	// Locking the user serialises writers even when the site has no
+	// settings row yet for the revision check to lock.
 	var userID int64
-	if err := s.db.QueryRowContext(
+	if err := tx.QueryRowContext(
 		ctx,
-		`SELECT id FROM users WHERE LOWER(email) = LOWER($1)`,
+		`SELECT id FROM users WHERE LOWER(email) = LOWER($1) AND deleted_at IS NULL FOR UPDATE`,
 		userEmail,
 	).Scan(&userID); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return fmt.Errorf("store: no local user found for email=%s", userEmail)
+			return 0, fmt.Errorf("store: no local user found for email=%s", userEmail)
 		}
-		return fmt.Errorf("store: lookup user by email: %w", err)
+		return 0, fmt.Errorf("store: lookup user by email: %w", err)
 	}
 
-	if _, err := s.db.ExecContext(
+	if ifRevision != nil {
+		var current int64
+		err := tx.QueryRowContext(ctx,
+			`SELECT revision FROM users_settings WHERE user_id = $1 AND jira_base_url = $2 AND deleted_at IS NULL FOR UPDATE`,
+			userID, baseURL,
+		).Scan(&current)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return 0, fmt.Errorf("store: lock users_settings: %w", err)
+		}
+		if current != *ifRevision {
+			return 0, fmt.Errorf("store: users_settings revision is %d, expected %d: %w", current, *ifRevision, ErrRevisionMismatch)
+		}
+	}
+
+	var revision int64
+	if err := tx.QueryRowContext(
 		ctx,
 		`INSERT INTO users_settings (user_id, jira_base_url, jira_email, jira_api_token)
 		 VALUES ($1, $2, $3, $4)
 		 ON CONFLICT (user_id, jira_base_url) DO UPDATE
 		 SET jira_email = EXCLUDED.jira_email,
 		     jira_api_token = EXCLUDED.jira_api_token,
-		     updated_at = now()`,
+		     auth_method = 'api_token',
+		     is_default = users_settings.is_default AND users_settings.deleted_at IS NULL,
+		     revision = users_settings.revision + 1,
+		     deleted_at = NULL,
+		     updated_at = now()
+		 RETURNING revision`,
 		userID,
 		baseURL,
 		jiraEmail,
 		apiKey,
-	); err != nil {
-		return fmt.Errorf("store: upsert users_settings: %w", err)
+	).Scan(&revision); err != nil {
+		return 0, fmt.Errorf("store: upsert users_settings: %w", err)
 	}
 
-	return nil
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("store: commit users_settings: %w", err)
+	}
+
+	return revision, nil
 }
 
 // ListUserSettings returns all Jira settings records associated with the given
@@ -363,10 +632,12 @@ SELECT
   us.jira_base_url,
   us.jira_email,
   us.jira_cloud_id,
-  us.is_default
+  us.is_default,
+  us.auth_method,
+  us.revision
 FROM users_settings us
 JOIN users u ON us.user_id = u.id
-WHERE LOWER(u.email) = LOWER($1)
+WHERE LOWER(u.email) = LOWER($1) AND u.deleted_at IS NULL AND us.deleted_at IS NULL
 ORDER BY us.is_default DESC, us.jira_base_url ASC
 `, email)
 	if err != nil {
@@ -377,13 +648,15 @@ ORDER BY us.is_default DESC, us.jira_base_url ASC
 	var settings []models.JiraUserSettings
 	for rows.Next() {
 		var (
-			baseURL   string
-			jiraEmail string
-			cloudID   sql.NullString
-			isDefault bool
+			baseURL    string
+			jiraEmail  string
+			cloudID    sql.NullString
+			isDefault  bool
+			authMethod string
+			revision   int64
 		)
 
-		if err := rows.Scan(&baseURL, &jiraEmail, &cloudID, &isDefault); err != nil {
+		if err := rows.Scan(&baseURL, &jiraEmail, &cloudID, &isDefault, &authMethod, &revision); err != nil {
 			return nil, fmt.Errorf("store: scan users_settings: %w", err)
 		}
 
@@ -392,6 +665,8 @@ ORDER BY us.is_default DESC, us.jira_base_url ASC
 			JiraEmail:   jiraEmail,
 			JiraCloudID: nullStringPtr(cloudID),
 			IsDefault:   isDefault,
+			AuthMethod:  authMethod,
+			Revision:    revision,
 		})
 	}
 
@@ -405,7 +680,10 @@ ORDER BY us.is_default DESC, us.jira_base_url ASC
 // GetUserSettingsByMCPSecret looks up the most appropriate Jira settings row
 // for the user identified by the given mcp_secret. It prefers the row marked
 // as is_default, but will fall back to any available settings if none are
-// marked as default.
+// marked as default. Users without settings of their own get the shared Jira
+// account of an organization they belong to, if any. For a site connected
+// through Atlassian OAuth the current access token takes the place of the
+// API token.
 func (s *Store) GetUserSettingsByMCPSecret(ctx context.Context, secret string) (*models.JiraUserSettingsWithSecret, error) {
 	if s == nil || s.db == nil {
 		return nil, errors.New("store: db cannot be nil")
@@ -413,39 +691,59 @@ func (s *Store) GetUserSettingsByMCPSecret(ctx context.Context, secret string) (
 
 	row := s.db.QueryRowContext(ctx, `
 SELECT
+  us.user_id,
   us.jira_base_url,
   us.jira_email,
   us.jira_cloud_id,
   us.is_default,
-  us.jira_api_token
+  us.auth_method,
+  CASE WHEN us.auth_method = 'oauth' THEN COALESCE(it.access_token, '') ELSE us.jira_api_token END,
+  COALESCE(js.service_management, FALSE),
+  `+issueDefaultsColumns+`
 FROM users_settings us
 JOIN users u ON us.user_id = u.id
-WHERE u.mcp_secret = $1
+LEFT JOIN integration_tokens it ON it.user_id = us.user_id AND it.provider = 'atlassian'
+LEFT JOIN jira_issue_defaults d ON d.user_id = us.user_id
+`+jiraSiteJoin("us")+`
+WHERE u.mcp_secret = $1 AND u.deleted_at IS NULL AND us.deleted_at IS NULL
 ORDER BY us.is_default DESC, us.jira_base_url ASC
 LIMIT 1
 `, secret)
 
 	var (
-		baseURL   string
-		jiraEmail string
-		cloudID   sql.NullString
-		isDefault bool
-		apiToken  string
+		userID     int64
+		baseURL    string
+		jiraEmail  string
+		cloudID    sql.NullString
+		isDefault  bool
+		authMethod string
+		apiToken   string
+		jsm        bool
+		defaults   joinedIssueDefaults
 	)
 
-	if err := row.Scan(&baseURL, &jiraEmail, &cloudID, &isDefault, &apiToken); err != nil {
+	dest := append([]any{&userID, &baseURL, &jiraEmail, &cloudID, &isDefault, &authMethod, &apiToken, &jsm}, defaults.dest()...)
+	if err := row.Scan(dest...); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return nil, fmt.Errorf("store: no Jira settings found for provided mcp_secret")
+			return s.getSharedJiraSettingsByMCPSecret(ctx, secret)
 		}
 		return nil, fmt.Errorf("store: lookup users_settings by mcp_secret: %w", err)
 	}
+	apiToken, err := s.open(apiToken)
+	if err != nil {
+		return nil, err
+	}
 
 	return &models.JiraUserSettingsWithSecret{
 		JiraBaseURL:       baseURL,
 		JiraEmail:         jiraEmail,
 		JiraCloudID:       nullStringPtr(cloudID),
 		IsDefault:         isDefault,
+		AuthMethod:        authMethod,
 		AtlassianAPIToken: apiToken,
+		ServiceManagement: jsm,
+		UserID:            userID,
+		IssueDefaults:     defaults.value(),
 	}, nil
 }
 
@@ -464,6 +762,13 @@ func randomHex(nBytes int) (string, error) {
 	return hex.EncodeToString(buf), nil
 }
 
+func stringValue(v *string) string {
+	if v == nil {
+		return ""
+	}
+	return *v
+}
+
 // GenerateMCPSecret creates and stores a new random mcp_secret for the user
 // identified by email. The newly generated secret is returned.
 func (s *Store) GenerateMCPSecret(ctx context.Context, email string) (string, error) {
@@ -471,10 +776,18 @@ func (s *Store) GenerateMCPSecret(ctx context.Context, email string) (string, er
 		return "", errors.New("store: db cannot be nil")
 	}
 
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", fmt.Errorf("store: begin mcp_secret tx: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
 	var userID int64
-	if err := s.db.QueryRowContext(
+	if err := tx.QueryRowContext(
 		ctx,
-		`SELECT id FROM users WHERE LOWER(email) = LOWER($1)`,
+		`SELECT id FROM users WHERE LOWER(email) = LOWER($1) AND deleted_at IS NULL`,
 		email,
 	).Scan(&userID); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -488,7 +801,7 @@ func (s *Store) GenerateMCPSecret(ctx context.Context, email string) (string, er
 		return "", fmt.Errorf("store: generate mcp_secret: %w", err)
 	}
 
-	if _, err := s.db.ExecContext(
+	if _, err := tx.ExecContext(
 		ctx,
 		`UPDATE users SET mcp_secret = $1, updated_at = now() WHERE id = $2`,
 		secret,
@@ -497,6 +810,16 @@ func (s *Store) GenerateMCPSecret(ctx context.Context, email string) (string, er
 		return "", fmt.Errorf("store: update mcp_secret: %w", err)
 	}
 
+	if err := s.enqueueEvent(ctx, tx, events.MCPSecretRotated{UserID: userID}); err != nil {
+		return "", err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", fmt.Errorf("store: commit mcp_secret tx: %w", err)
+	}
+
+	s.wakeOutbox()
+
 	return secret, nil
 }
 
@@ -510,7 +833,7 @@ func (s *Store) GetMCPSecret(ctx context.Context, email string) (*string, error)
 	var secret sql.NullString
 	if err := s.db.QueryRowContext(
 		ctx,
-		`SELECT mcp_secret FROM users WHERE LOWER(email) = LOWER($1)`,
+		`SELECT mcp_secret FROM users WHERE LOWER(email) = LOWER($1) AND deleted_at IS NULL`,
 		email,
 	).Scan(&secret); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -526,33 +849,50 @@ func (s *Store) GetMCPSecret(ctx context.Context, email string) (*string, error)
 	return &secret.String, nil
 }
 
-// GetUserIDByMCPSecret retrieves the user ID for a given MCP secret
+// GetUserIDByMCPSecret retrieves the user ID for a given MCP secret.
+// Suspended accounts return their ID together with ErrUserSuspended.
 func (s *Store) GetUserIDByMCPSecret(ctx context.Context, secret string) (int64, error) {
 	if s == nil || s.db == nil {
 		return 0, errors.New("store: db cannot be nil")
 	}
 
-	var userID int64
-	err := s.db.QueryRowContext(ctx, "SELECT id FROM users WHERE mcp_secret = $1", secret).Scan(&userID)
+	var (
+		userID    int64
+		suspended bool
+	)
+	err := s.db.QueryRowContext(ctx, "SELECT id, suspended_at IS NOT NULL FROM users WHERE mcp_secret = $1 AND deleted_at IS NULL", secret).Scan(&userID, &suspended)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return 0, fmt.Errorf("store: no user found for MCP secret")
+			return 0, ErrMCPSecretNotFound
 		}
 		return 0, fmt.Errorf("store: query user by MCP secret: %w", err)
 	}
+	if suspended {
+		return userID, ErrUserSuspended
+	}
 
 	return userID, nil
 }
 
 // CreateRequest records a new API request for usage tracking
 func (s *Store) CreateRequest(ctx context.Context, userID int64, method, endpoint string, statusCode int, responseTimeMs, requestSizeBytes, responseSizeBytes *int, errorMessage *string) error {
+	return s.CreateSampledRequest(ctx, userID, 1, method, endpoint, statusCode, responseTimeMs, requestSizeBytes, responseSizeBytes, errorMessage)
+}
+
+// CreateSampledRequest records an API request that stands for sampleWeight
+// requests of the user: itself and the ones skipped by sampling since the
+// user's previous recorded request.
+func (s *Store) CreateSampledRequest(ctx context.Context, userID int64, sampleWeight int, method, endpoint string, statusCode int, responseTimeMs, requestSizeBytes, responseSizeBytes *int, errorMessage *string) error {
 	if s == nil || s.db == nil {
 		return errors.New("store: db cannot be nil")
 	}
+	if sampleWeight < 1 {
+		sampleWeight = 1
+	}
 
 	query := `
-	INSERT INTO requests (user_id, method, endpoint, status_code, response_time_ms, request_size_bytes, response_size_bytes, error_message)
-	VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	INSERT INTO requests (user_id, method, endpoint, status_code, response_time_ms, request_size_bytes, response_size_bytes, error_message, sample_weight)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 	`
 
 	var errMessage sql.NullString
@@ -560,8 +900,13 @@ func (s *Store) CreateRequest(ctx context.Context, userID int64, method, endpoin
 		errMessage = sql.NullString{String: *errorMessage, Valid: true}
 	}
 
+	db, err := s.userDB(ctx, userID)
+	if err != nil {
+		return err
+	}
+
 	log.Printf("[store] Attempting to create request: method=%s, endpoint=%s, userID=%d", method, endpoint, userID)
-	_, err := s.db.ExecContext(ctx, query, userID, method, endpoint, statusCode, responseTimeMs, requestSizeBytes, responseSizeBytes, errMessage)
+	_, err = db.ExecContext(ctx, query, userID, method, endpoint, statusCode, responseTimeMs, requestSizeBytes, responseSizeBytes, errMessage, sampleWeight)
 	if err != nil {
 		log.Printf("[store] Error creating request: %v", err)
 		return fmt.Errorf("store: create request: %w", err)
@@ -573,6 +918,18 @@ func (s *Store) CreateRequest(ctx context.Context, userID int64, method, endpoin
 
 // GetUserRequests returns requests for a specific user with pagination
 func (s *Store) GetUserRequests(ctx context.Context, userID int64, limit, offset int) ([]models.Request, error) {
+	return s.listUserRequests(ctx, userID, "", limit, offset)
+}
+
+// ListUserRequestErrors returns the user's most recent failed requests: those
+// answered with an error status or that recorded an error message.
+func (s *Store) ListUserRequestErrors(ctx context.Context, userID int64, limit int) ([]models.Request, error) {
+	return s.listUserRequests(ctx, userID, "AND (status_code >= 400 OR error_message IS NOT NULL)", limit, 0)
+}
+
+// listUserRequests pages the user's requests, newest first, narrowed by the
+// extra filter condition.
+func (s *Store) listUserRequests(ctx context.Context, userID int64, filter string, limit, offset int) ([]models.Request, error) {
 	if s == nil || s.db == nil {
 		return nil, errors.New("store: db cannot be nil")
 	}
@@ -592,14 +949,20 @@ func (s *Store) GetUserRequests(ctx context.Context, userID int64, limit, offset
 		request_size_bytes,
 		response_size_bytes,
 		error_message,
-		created_at
+		created_at,
+		sample_weight
 	FROM requests 
-	WHERE user_id = $1
+	WHERE user_id = $1 ` + filter + `
 	ORDER BY created_at DESC
 	LIMIT $2 OFFSET $3
 	`
 
-	rows, err := s.db.QueryContext(ctx, query, userID, limit, offset)
+	db, err := s.userDB(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.QueryContext(ctx, query, userID, limit, offset)
 	if err != nil {
 		return nil, fmt.Errorf("store: get user requests: %w", err)
 	}
@@ -621,6 +984,7 @@ func (s *Store) GetUserRequests(ctx context.Context, userID int64, limit, offset
 			&req.ResponseSizeBytes,
 			&errMessage,
 			&req.CreatedAt,
+			&req.SampleWeight,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("store: scan request: %w", err)
@@ -649,19 +1013,24 @@ func (s *Store) GetUserMetrics(ctx context.Context, userID int64) (*models.Reque
 	query := `
 	SELECT 
 		user_id::text,
-		COUNT(*) as total_requests,
-		COUNT(CASE WHEN status_code < 400 THEN 1 END) as success_requests,
-		COUNT(CASE WHEN status_code >= 400 THEN 1 END) as error_requests,
-		COALESCE(AVG(response_time_ms), 0) as avg_response_time_ms,
-		COALESCE(SUM(COALESCE(request_size_bytes, 0) + COALESCE(response_size_bytes, 0)), 0) as total_bytes,
+		SUM(sample_weight) as total_requests,
+		SUM(CASE WHEN status_code < 400 THEN sample_weight ELSE 0 END) as success_requests,
+		SUM(CASE WHEN status_code >= 400 THEN sample_weight ELSE 0 END) as error_requests,
+		COALESCE(SUM(response_time_ms::bigint * sample_weight) / NULLIF(SUM(CASE WHEN response_time_ms IS NOT NULL THEN sample_weight END), 0), 0) as avg_response_time_ms,
+		COALESCE(SUM((COALESCE(request_size_bytes, 0) + COALESCE(response_size_bytes, 0))::bigint * sample_weight), 0) as total_bytes,
 		MAX(created_at) as last_request_at
 	FROM requests 
 	WHERE user_id = $1
 	GROUP BY user_id
 	`
 
+	db, err := s.userDB(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
 	var metrics models.RequestMetrics
-	err := s.db.QueryRowContext(ctx, query, userID).Scan(
+	err = db.QueryRowContext(ctx, query, userID).Scan(
 		&metrics.UserID,
 		&metrics.TotalRequests,
 		&metrics.SuccessRequests,
@@ -697,18 +1066,35 @@ func (s *Store) GetAllMetrics(ctx context.Context) ([]models.RequestMetrics, err
 	query := `
 	SELECT 
 		user_id::text,
-		COUNT(*) as total_requests,
-		COUNT(CASE WHEN status_code < 400 THEN 1 END) as success_requests,
-		COUNT(CASE WHEN status_code >= 400 THEN 1 END) as error_requests,
-		COALESCE(AVG(response_time_ms), 0) as avg_response_time_ms,
-		COALESCE(SUM(COALESCE(request_size_bytes, 0) + COALESCE(response_size_bytes, 0)), 0) as total_bytes,
+		SUM(sample_weight) as total_requests,
+		SUM(CASE WHEN status_code < 400 THEN sample_weight ELSE 0 END) as success_requests,
+		SUM(CASE WHEN status_code >= 400 THEN sample_weight ELSE 0 END) as error_requests,
+		COALESCE(SUM(response_time_ms::bigint * sample_weight) / NULLIF(SUM(CASE WHEN response_time_ms IS NOT NULL THEN sample_weight END), 0), 0) as avg_response_time_ms,
+		COALESCE(SUM((COALESCE(request_size_bytes, 0) + COALESCE(response_size_bytes, 0))::bigint * sample_weight), 0) as total_bytes,
 		MAX(created_at) as last_request_at
 	FROM requests 
 	GROUP BY user_id
 	ORDER BY total_requests DESC
 	`
 
-	rows, err := s.db.QueryContext(ctx, query)
+	var metrics []models.RequestMetrics
+	dbs := s.requestDatabases()
+	for _, rdb := range dbs {
+		regional, err := queryRequestMetrics(ctx, rdb.db, query)
+		if err != nil {
+			return nil, err
+		}
+		metrics = append(metrics, regional...)
+	}
+	if len(dbs) > 1 {
+		sort.SliceStable(metrics, func(i, j int) bool { return metrics[i].TotalRequests > metrics[j].TotalRequests })
+	}
+
+	return metrics, nil
+}
+
+func queryRequestMetrics(ctx context.Context, db *sql.DB, query string) ([]models.RequestMetrics, error) {
+	rows, err := db.QueryContext(ctx, query)
 	if err != nil {
 		return nil, fmt.Errorf("store: get all metrics: %w", err)
 	}
@@ -779,10 +1165,11 @@ func (s *Store) GetSubscription(ctx context.Context, userEmail string) (*models.
 SELECT
 	s.id, s.user_id, s.stripe_customer_id, s.stripe_subscription_id,
 	s.stripe_price_id, s.status, s.current_period_start, s.current_period_end,
-	s.cancel_at_period_end, s.canceled_at, s.created_at, s.updated_at
+	s.cancel_at_period_end, s.canceled_at, s.pause_behavior, s.pause_resumes_at, s.paused_at,
+	s.created_at, s.updated_at
 FROM subscriptions s
 JOIN users u ON s.user_id = u.id
-WHERE u.email = $1 AND s.status IN ('active', 'trialing', 'past_due')
+WHERE u.email = $1 AND u.deleted_at IS NULL AND s.status IN ('active', 'trialing', 'past_due')
 ORDER BY s.created_at DESC
 LIMIT 1
 	`
@@ -799,6 +1186,9 @@ LIMIT 1
 		&sub.CurrentPeriodEnd,
 		&sub.CancelAtPeriodEnd,
 		&sub.CanceledAt,
+		&sub.PauseBehavior,
+		&sub.PauseResumesAt,
+		&sub.PausedAt,
 		&sub.CreatedAt,
 		&sub.UpdatedAt,
 	)
@@ -821,8 +1211,14 @@ SET status = $1,
 	current_period_end = $3,
 	cancel_at_period_end = $4,
 	canceled_at = $5,
+	pause_behavior = $6,
+	pause_resumes_at = $7,
+	paused_at = CASE
+		WHEN $6::text IS NULL THEN NULL
+		ELSE COALESCE(paused_at, now())
+	END,
 	updated_at = now()
-WHERE id = $6
+WHERE id = $8
 	`
 
 	_, err := s.db.ExecContext(ctx, query,
@@ -831,6 +1227,8 @@ WHERE id = $6
 		sub.CurrentPeriodEnd,
 		sub.CancelAtPeriodEnd,
 		sub.CanceledAt,
+		sub.PauseBehavior,
+		sub.PauseResumesAt,
 		sub.ID,
 	)
 	if err != nil {
@@ -877,7 +1275,7 @@ SELECT
 	p.currency, p.status, p.description, p.receipt_url, p.created_at
 FROM payment_history p
 JOIN users u ON p.user_id = u.id
-WHERE u.email = $1
+WHERE u.email = $1 AND u.deleted_at IS NULL
 ORDER BY p.created_at DESC
 LIMIT 100
 	`
@@ -922,7 +1320,8 @@ func (s *Store) GetSubscriptionByStripeID(ctx context.Context, stripeSubID strin
 	query := `
 SELECT id, user_id, stripe_customer_id, stripe_subscription_id,
 	stripe_price_id, status, current_period_start, current_period_end,
-	cancel_at_period_end, canceled_at, created_at, updated_at
+	cancel_at_period_end, canceled_at, pause_behavior, pause_resumes_at, paused_at,
+	created_at, updated_at
 FROM subscriptions
 WHERE stripe_subscription_id = $1
 LIMIT 1
@@ -932,7 +1331,8 @@ LIMIT 1
 	err := s.db.QueryRowContext(ctx, query, stripeSubID).Scan(
 		&sub.ID, &sub.UserID, &sub.StripeCustomerID, &sub.StripeSubscriptionID,
 		&sub.StripePriceID, &sub.Status, &sub.CurrentPeriodStart, &sub.CurrentPeriodEnd,
-		&sub.CancelAtPeriodEnd, &sub.CanceledAt, &sub.CreatedAt, &sub.UpdatedAt,
+		&sub.CancelAtPeriodEnd, &sub.CanceledAt, &sub.PauseBehavior, &sub.PauseResumesAt, &sub.PausedAt,
+		&sub.CreatedAt, &sub.UpdatedAt,
 	)
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -948,7 +1348,8 @@ func (s *Store) GetSubscriptionByCustomerID(ctx context.Context, customerID stri
 	query := `
 SELECT id, user_id, stripe_customer_id, stripe_subscription_id,
 	stripe_price_id, status, current_period_start, current_period_end,
-	cancel_at_period_end, canceled_at, created_at, updated_at
+	cancel_at_period_end, canceled_at, pause_behavior, pause_resumes_at, paused_at,
+	created_at, updated_at
 FROM subscriptions
 WHERE stripe_customer_id = $1
 ORDER BY created_at DESC
@@ -959,7 +1360,8 @@ LIMIT 1
 	err := s.db.QueryRowContext(ctx, query, customerID).Scan(
 		&sub.ID, &sub.UserID, &sub.StripeCustomerID, &sub.StripeSubscriptionID,
 		&sub.StripePriceID, &sub.Status, &sub.CurrentPeriodStart, &sub.CurrentPeriodEnd,
-		&sub.CancelAtPeriodEnd, &sub.CanceledAt, &sub.CreatedAt, &sub.UpdatedAt,
+		&sub.CancelAtPeriodEnd, &sub.CanceledAt, &sub.PauseBehavior, &sub.PauseResumesAt, &sub.PausedAt,
+		&sub.CreatedAt, &sub.UpdatedAt,
 	)
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -973,9 +1375,9 @@ LIMIT 1
 // GetUserByEmail retrieves a user by their email address.
 func (s *Store) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
 	query := `
-SELECT id, login, name, email, avatar_url, created_at, updated_at
+SELECT id, login, name, email, avatar_url, stripe_customer_id, created_at, updated_at
 FROM users
-WHERE email = $1
+WHERE email = $1 AND deleted_at IS NULL
 LIMIT 1
 	`
 
@@ -986,6 +1388,7 @@ LIMIT 1
 		&user.Name,
 		&user.Email,
 		&user.AvatarURL,
+		&user.StripeCustomerID,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
@@ -999,66 +1402,23 @@ LIMIT 1
 	return &user, nil
 }
 
-// DeleteUser deletes a user and all associated data by email address.
-func (s *Store) DeleteUser(ctx context.Context, email string) error {
+// SetStripeCustomerID records the Stripe customer ID for a user. Existing
+// values are only replaced when they differ so repeated webhook deliveries
+// are cheap no-ops.
+func (s *Store) SetStripeCustomerID(ctx context.Context, userID int64, customerID string) error {
 	if s == nil || s.db == nil {
 		return errors.New("store: db cannot be nil")
 	}
-
-	tx, err := s.db.BeginTx(ctx, nil)
-	if err != nil {
-		return fmt.Errorf("store: begin delete user tx: %w", err)
+	if customerID == "" {
+		return errors.New("store: stripe customer id cannot be empty")
 	}
-	defer func() {
-		_ = tx.Rollback()
-	}()
 
-	// Get user ID first
-	var userID int64
-	err = tx.QueryRowContext(ctx, `SELECT id FROM users WHERE LOWER(email) = LOWER($1)`, email).Scan(&userID)
-	if err == sql.ErrNoRows {
-		return fmt.Errorf("store: user not found")
-	}
-	if err != nil {
-		return fmt.Errorf("store: get user id: %w", err)
-	}
-
-	// Delete associated records in order (foreign key constraints)
-	// Note: payment_history, subscriptions, users_settings, and users_oauths have ON DELETE CASCADE,
-	// but we delete them explicitly for better control and logging
-
-	// Delete payment history
-	if _, err := tx.ExecContext(ctx, `DELETE FROM payment_history WHERE user_id = $1`, userID); err != nil {
-		return fmt.Errorf("store: delete payment history: %w", err)
-	}
-
-	// Delete subscriptions
-	if _, err := tx.ExecContext(ctx, `DELETE FROM subscriptions WHERE user_id = $1`, userID); err != nil {
-		return fmt.Errorf("store: delete subscriptions: %w", err)
-	}
-
-	// Delete Jira settings (table is named users_settings, not jira_user_settings)
-	if _, err := tx.ExecContext(ctx, `DELETE FROM users_settings WHERE user_id = $1`, userID); err != nil {
-		return fmt.Errorf("store: delete jira settings: %w", err)
-	}
-
-	// Delete OAuth associations
-	if _, err := tx.ExecContext(ctx, `DELETE FROM users_oauths WHERE user_id = $1`, userID); err != nil {
-		return fmt.Errorf("store: delete oauth associations: %w", err)
-	}
-
-	// Delete requests
-	if _, err := tx.ExecContext(ctx, `DELETE FROM requests WHERE user_id = $1`, userID); err != nil {
-		return fmt.Errorf("store: delete requests: %w", err)
-	}
-
-	// Finally, delete the user
-	if _, err := tx.ExecContext(ctx, `DELETE FROM users WHERE id = $1`, userID); err != nil {
-		return fmt.Errorf("store: delete user: %w", err)
-	}
-
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("store: commit delete user tx: %w", err)
+	if _, err := s.db.ExecContext(ctx, `
+UPDATE users
+SET stripe_customer_id = $1, updated_at = now()
+WHERE id = $2 AND stripe_customer_id IS DISTINCT FROM $1
+`, customerID, userID); err != nil {
+		return fmt.Errorf("store: set stripe customer id: %w", err)
 	}
 
 	return nil
@@ -1070,7 +1430,7 @@ func (s *Store) GetConnectedAccounts(ctx context.Context, email string) ([]model
 SELECT uo.provider, uo.provider_account_id, uo.avatar_url, uo.created_at
 FROM users_oauths uo
 JOIN users u ON uo.user_id = u.id
-WHERE LOWER(u.email) = LOWER($1)
+WHERE LOWER(u.email) = LOWER($1) AND u.deleted_at IS NULL
 ORDER BY uo.created_at ASC
 	`
 
@@ -1118,7 +1478,7 @@ func (s *Store) UpsertIntegrationToken(ctx context.Context, userEmail, provider,
 	var userID int64
 	if err := s.db.QueryRowContext(
 		ctx,
-		`SELECT id FROM users WHERE LOWER(email) = LOWER($1)`,
+		`SELECT id FROM users WHERE LOWER(email) = LOWER($1) AND deleted_at IS NULL`,
 		userEmail,
 	).Scan(&userID); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -1127,9 +1487,17 @@ func (s *Store) UpsertIntegrationToken(ctx context.Context, userEmail, provider,
 		return fmt.Errorf("store: lookup user by email: %w", err)
 	}
 
+	accessToken, err := s.seal(accessToken)
+	if err != nil {
+		return err
+	}
 	var refreshTok sql.NullString
 	if refreshToken != nil {
-		refreshTok = sql.NullString{String: *refreshToken, Valid: true}
+		sealed, err := s.seal(*refreshToken)
+		if err != nil {
+			return err
+		}
+		refreshTok = sql.NullString{String: sealed, Valid: true}
 	}
 	var scopesVal sql.NullString
 	if scopes != nil {
@@ -1156,7 +1524,7 @@ SET access_token  = EXCLUDED.access_token,
     metadata      = EXCLUDED.metadata,
     updated_at    = now()
 `
-	_, err := s.db.ExecContext(ctx, query, userID, provider, accessToken, refreshTok, tokenType, expiresAtVal, scopesVal, metadataVal)
+	_, err = s.db.ExecContext(ctx, query, userID, provider, accessToken, refreshTok, tokenType, expiresAtVal, scopesVal, metadataVal)
 	if err != nil {
 		return fmt.Errorf("store: upsert integration token: %w", err)
 	}
@@ -1174,7 +1542,7 @@ func (s *Store) ListIntegrationTokens(ctx context.Context, email string) ([]mode
 SELECT it.provider, it.token_type, it.expires_at, it.scopes, it.created_at, it.updated_at
 FROM integration_tokens it
 JOIN users u ON it.user_id = u.id
-WHERE LOWER(u.email) = LOWER($1)
+WHERE LOWER(u.email) = LOWER($1) AND u.deleted_at IS NULL
 ORDER BY it.provider ASC
 `, email)
 	if err != nil {
@@ -1226,7 +1594,7 @@ SELECT it.id, it.user_id, it.provider, it.access_token, it.refresh_token,
        it.token_type, it.expires_at, it.scopes, it.metadata, it.created_at, it.updated_at
 FROM integration_tokens it
 JOIN users u ON it.user_id = u.id
-WHERE LOWER(u.email) = LOWER($1) AND it.provider = $2
+WHERE LOWER(u.email) = LOWER($1) AND u.deleted_at IS NULL AND it.provider = $2
 `, email, provider).Scan(
 		&t.ID, &t.UserID, &t.Provider, &t.AccessToken, &refreshToken,
 		&t.TokenType, &expiresAt, &scopes, &metadata, &t.CreatedAt, &t.UpdatedAt,
@@ -1241,6 +1609,12 @@ WHERE LOWER(u.email) = LOWER($1) AND it.provider = $2
 	if refreshToken.Valid {
 		t.RefreshToken = &refreshToken.String
 	}
+	if t.AccessToken, err = s.open(t.AccessToken); err != nil {
+		return nil, err
+	}
+	if err := s.openPtr(t.RefreshToken); err != nil {
+		return nil, err
+	}
 	if expiresAt.Valid {
 		t.ExpiresAt = &expiresAt.Time
 	}
@@ -1272,7 +1646,7 @@ SELECT it.id, it.user_id, it.provider, it.access_token, it.refresh_token,
        it.token_type, it.expires_at, it.scopes, it.metadata, it.created_at, it.updated_at
 FROM integration_tokens it
 JOIN users u ON it.user_id = u.id
-WHERE u.mcp_secret = $1 AND it.provider = $2
+WHERE u.mcp_secret = $1 AND u.deleted_at IS NULL AND it.provider = $2
 `, secret, provider).Scan(
 		&t.ID, &t.UserID, &t.Provider, &t.AccessToken, &refreshToken,
 		&t.TokenType, &expiresAt, &scopes, &metadata, &t.CreatedAt, &t.UpdatedAt,
@@ -1287,6 +1661,12 @@ WHERE u.mcp_secret = $1 AND it.provider = $2
 	if refreshToken.Valid {
 		t.RefreshToken = &refreshToken.String
 	}
+	if t.AccessToken, err = s.open(t.AccessToken); err != nil {
+		return nil, err
+	}
+	if err := s.openPtr(t.RefreshToken); err != nil {
+		return nil, err
+	}
 	if expiresAt.Valid {
 		t.ExpiresAt = &expiresAt.Time
 	}
@@ -1308,7 +1688,7 @@ func (s *Store) DeleteIntegrationToken(ctx context.Context, email, provider stri
 
 	result, err := s.db.ExecContext(ctx, `
 DELETE FROM integration_tokens
-WHERE user_id = (SELECT id FROM users WHERE LOWER(email) = LOWER($1))
+WHERE user_id = (SELECT id FROM users WHERE LOWER(email) = LOWER($1) AND deleted_at IS NULL)
   AND provider = $2
 `, email, provider)
 	if err != nil {
@@ -2,11 +2,19 @@ package store
 
 import (
 	"context"
+	"database/sql"
+	"database/sql/driver"
 	"errors"
 	"regexp"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/events"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/keyring"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/secretbox"
 )
 
 func TestNewStoreValidation(t *testing.T) {
@@ -26,21 +34,129 @@ func TestListUsersSuccess(t *testing.T) {
 	})
 
 	query := regexp.MustCompile(`SELECT\s+id::text\s+AS id`)
-	rows := sqlmock.NewRows([]string{"id", "email", "name", "image"}).
-		AddRow("1", "user@example.com", "User", "https://avatar")
+	rows := sqlmock.NewRows([]string{"id", "email", "name", "image", "created_at"}).
+		AddRow("1", "user@example.com", "User", "https://avatar", time.Now())
+
+	mock.ExpectQuery(query.String()).WithArgs(6).WillReturnRows(rows)
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM users`).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+	page, err := s.ListUsers(context.Background(), models.UserListOptions{Limit: 5})
+	if err != nil {
+		t.Fatalf("ListUsers returned error: %v", err)
+	}
+
+	if len(page.Users) != 1 {
+		t.Fatalf("expected 1 user, got %d", len(page.Users))
+	}
+	if page.Users[0].ID != "1" {
+		t.Fatalf("unexpected id: %s", page.Users[0].ID)
+	}
+	if page.NextCursor != "" || page.Total != 1 {
+		t.Fatalf("expected last page of 1 user, got cursor %q total %d", page.NextCursor, page.Total)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestListUsersPagesWithCursor(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	s := &Store{db: db}
+	t.Cleanup(func() {
+		db.Close()
+	})
+
+	now := time.Now()
+	mock.ExpectQuery(`ORDER BY COALESCE\(email, ''\) ASC, id ASC`).WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email", "name", "image", "created_at"}).
+			AddRow("4", "a@example.com", nil, nil, now).
+			AddRow("2", "b@example.com", nil, nil, now).
+			AddRow("9", "c@example.com", nil, nil, now))
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM users`).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+
+	page, err := s.ListUsers(context.Background(), models.UserListOptions{Limit: 2, Sort: models.UserSortEmailAsc})
+	if err != nil {
+		t.Fatalf("ListUsers returned error: %v", err)
+	}
+	if len(page.Users) != 2 || page.NextCursor == "" || page.Total != 3 {
+		t.Fatalf("expected first page of 2 with a cursor, got %+v", page)
+	}
+
+	mock.ExpectQuery(`WHERE deleted_at IS NULL AND \(COALESCE\(email, ''\), id\) > \(\$2, \$3\)`).WithArgs(3, "b@example.com", int64(2)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email", "name", "image", "created_at"}).
+			AddRow("9", "c@example.com", nil, nil, now))
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM users`).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+
+	next, err := s.ListUsers(context.Background(), models.UserListOptions{Limit: 2, Sort: models.UserSortEmailAsc, Cursor: page.NextCursor})
+	if err != nil {
+		t.Fatalf("ListUsers returned error: %v", err)
+	}
+	if len(next.Users) != 1 || next.Users[0].ID != "9" || next.NextCursor != "" {
+		t.Fatalf("expected last page with user 9, got %+v", next)
+	}
+
+	if _, err := s.ListUsers(context.Background(), models.UserListOptions{Sort: models.UserSortNameAsc, Cursor: page.NextCursor}); !errors.Is(err, ErrInvalidUserCursor) {
+		t.Fatalf("expected ErrInvalidUserCursor for a cursor of another sort, got %v", err)
+	}
+	if _, err := s.ListUsers(context.Background(), models.UserListOptions{Sort: "age"}); !errors.Is(err, ErrInvalidUserSort) {
+		t.Fatalf("expected ErrInvalidUserSort, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestListUsersPagesPastMissingNames(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	s := &Store{db: db}
+	t.Cleanup(func() {
+		db.Close()
+	})
 
-	mock.ExpectQuery(query.String()).WithArgs(5).WillReturnRows(rows)
+	now := time.Now()
+	mock.ExpectQuery(`ORDER BY COALESCE\(name, ''\) ASC, id ASC`).WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email", "name", "image", "created_at"}).
+			AddRow("3", "c@example.com", nil, nil, now).
+			AddRow("5", "e@example.com", nil, nil, now).
+			AddRow("7", "g@example.com", nil, nil, now))
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM users`).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(4))
 
-	users, err := s.ListUsers(context.Background(), 5)
+	page, err := s.ListUsers(context.Background(), models.UserListOptions{Limit: 2, Sort: models.UserSortNameAsc})
 	if err != nil {
 		t.Fatalf("ListUsers returned error: %v", err)
 	}
+	if len(page.Users) != 2 || page.NextCursor == "" {
+		t.Fatalf("expected first page of 2 with a cursor, got %+v", page)
+	}
 
-	if len(users) != 1 {
-		t.Fatalf("expected 1 user, got %d", len(users))
+	// The next page starts after the last unnamed user instead of at the
+	// first named one.
+	mock.ExpectQuery(`WHERE deleted_at IS NULL AND \(COALESCE\(name, ''\), id\) > \(\$2, \$3\)\s+ORDER BY COALESCE\(name, ''\) ASC, id ASC`).
+		WithArgs(3, "", int64(5)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email", "name", "image", "created_at"}).
+			AddRow("7", "g@example.com", nil, nil, now).
+			AddRow("1", "a@example.com", "Ada", nil, now))
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM users`).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(4))
+
+	next, err := s.ListUsers(context.Background(), models.UserListOptions{Limit: 2, Sort: models.UserSortNameAsc, Cursor: page.NextCursor})
+	if err != nil {
+		t.Fatalf("ListUsers returned error: %v", err)
+	}
+	if len(next.Users) != 2 || next.Users[0].ID != "7" || next.Users[1].ID != "1" || next.NextCursor != "" {
+		t.Fatalf("expected last page with users 7 and 1, got %+v", next)
 	}
-	if users[0].ID != "1" {
-		t.Fatalf("unexpected id: %s", users[0].ID)
+
+	malformed := userCursor{Sort: models.UserSortCreatedDesc, Value: "yesterday", ID: 5}.encode()
+	if _, err := s.ListUsers(context.Background(), models.UserListOptions{Cursor: malformed}); !errors.Is(err, ErrInvalidUserCursor) {
+		t.Fatalf("expected ErrInvalidUserCursor for a malformed created_at cursor, got %v", err)
 	}
 
 	if err := mock.ExpectationsWereMet(); err != nil {
@@ -59,9 +175,898 @@ func TestListUsersQueryError(t *testing.T) {
 	})
 
 	query := regexp.MustCompile(`SELECT\s+id::text\s+AS id`)
-	mock.ExpectQuery(query.String()).WithArgs(defaultPageSize).WillReturnError(errors.New("boom"))
+	mock.ExpectQuery(query.String()).WithArgs(defaultPageSize + 1).WillReturnError(errors.New("boom"))
 
-	if _, err := s.ListUsers(context.Background(), 0); err == nil {
+	if _, err := s.ListUsers(context.Background(), models.UserListOptions{}); err == nil {
 		t.Fatal("expected error when query fails")
 	}
 }
+
+func TestSetStripeCustomerID(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	s := &Store{db: db}
+	t.Cleanup(func() {
+		db.Close()
+	})
+
+	mock.ExpectExec(`UPDATE users\s+SET stripe_customer_id = \$1`).
+		WithArgs("cus_123", int64(42)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	if err := s.SetStripeCustomerID(context.Background(), 42, "cus_123"); err != nil {
+		t.Fatalf("SetStripeCustomerID returned error: %v", err)
+	}
+
+	if err := s.SetStripeCustomerID(context.Background(), 42, ""); err == nil {
+		t.Fatal("expected error for empty customer id")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestMarkNotificationsReadRejectsUnknownSource(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	s := &Store{db: db}
+	t.Cleanup(func() {
+		db.Close()
+	})
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT id FROM users WHERE LOWER\(email\) = LOWER\(\$1\)`).
+		WithArgs("user@example.com").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(int64(7)))
+	mock.ExpectExec(`UPDATE user_notifications SET read_at = now\(\)`).
+		WithArgs(int64(3), int64(7)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectRollback()
+
+	refs := []models.NotificationRef{
+		{Source: models.NotificationSourceUser, ID: 3},
+		{Source: "bogus", ID: 4},
+	}
+	if err := s.MarkNotificationsRead(context.Background(), "user@example.com", refs); err == nil {
+		t.Fatal("expected error for unknown notification source")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestOutboxEnqueueWakesDispatcher(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	t.Cleanup(func() {
+		db.Close()
+	})
+
+	outbox, err := NewOutboxStore(db)
+	if err != nil {
+		t.Fatalf("NewOutboxStore returned error: %v", err)
+	}
+
+	mock.ExpectExec(`INSERT INTO event_outbox \(topic, payload\) VALUES \(\$1, \$2\)`).
+		WithArgs("user.mcp_secret_rotated", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	if err := outbox.Enqueue(context.Background(), events.MCPSecretRotated{UserID: 5}); err != nil {
+		t.Fatalf("Enqueue returned error: %v", err)
+	}
+
+	select {
+	case <-outbox.Notifications():
+	default:
+		t.Fatal("expected Enqueue to wake the dispatcher")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestGetUserPreferencesDefaultsWhenUnset(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	s := &Store{db: db}
+	t.Cleanup(func() {
+		db.Close()
+	})
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT timezone, locale, date_format, mcp_log_level, usage_digest, revision, updated_at FROM user_preferences WHERE user_id = $1`)).
+		WithArgs(int64(7)).
+		WillReturnRows(sqlmock.NewRows([]string{"timezone", "locale", "date_format", "mcp_log_level", "usage_digest", "revision", "updated_at"}))
+
+	prefs, err := s.GetUserPreferencesByUserID(context.Background(), 7)
+	if err != nil {
+		t.Fatalf("GetUserPreferencesByUserID returned error: %v", err)
+	}
+	if *prefs != models.DefaultUserPreferences() {
+		t.Fatalf("expected defaults, got %+v", prefs)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestSetToolResponseLimitUnknownUser(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	s := &Store{db: db}
+	t.Cleanup(func() {
+		db.Close()
+	})
+
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO tool_response_limits`)).
+		WithArgs("missing@example.com", models.ToolResponseLimitDefault, 4096).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	if err := s.SetToolResponseLimit(context.Background(), "missing@example.com", models.ToolResponseLimitDefault, 4096); err == nil {
+		t.Fatal("expected error when no user matches the email")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestUpsertUserSettingsIfRevisionConflict(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	s := &Store{db: db}
+	t.Cleanup(func() {
+		db.Close()
+	})
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id FROM users WHERE LOWER(email) = LOWER($1) AND deleted_at IS NULL FOR UPDATE`)).
+		WithArgs("user@example.com").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(int64(3)))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT revision FROM users_settings WHERE user_id = $1 AND jira_base_url = $2 AND deleted_at IS NULL FOR UPDATE`)).
+		WithArgs(int64(3), "https://example.atlassian.net").
+		WillReturnRows(sqlmock.NewRows([]string{"revision"}).AddRow(int64(5)))
+	mock.ExpectRollback()
+
+	_, err = s.UpsertUserSettingsIfRevision(context.Background(), "user@example.com", "https://example.atlassian.net", "jira@example.com", "token", 4)
+	if !errors.Is(err, ErrRevisionMismatch) {
+		t.Fatalf("expected ErrRevisionMismatch, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestDeleteAndRestoreUserSettings(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	s := &Store{db: db}
+	t.Cleanup(func() {
+		db.Close()
+	})
+
+	mock.ExpectExec(`UPDATE users_settings us SET deleted_at = now\(\).*us.deleted_at IS NULL`).
+		WithArgs("user@example.com", "https://example.atlassian.net").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE users_settings us SET\s+deleted_at = NULL.*us.deleted_at > now\(\) - make_interval\(secs => \$3\)`).
+		WithArgs("user@example.com", "https://example.atlassian.net", SoftDeleteRetention.Seconds()).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	if err := s.DeleteUserSettings(context.Background(), "user@example.com", "https://example.atlassian.net"); err != nil {
+		t.Fatalf("DeleteUserSettings: %v", err)
+	}
+	// Nothing left to restore once the row was purged.
+	err = s.RestoreUserSettings(context.Background(), "user@example.com", "https://example.atlassian.net")
+	if !errors.Is(err, ErrUserSettingsNotFound) {
+		t.Fatalf("expected ErrUserSettingsNotFound, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestDeleteUserIsRestorable(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	s := &Store{db: db}
+	t.Cleanup(func() {
+		db.Close()
+	})
+
+	deletedAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	mock.ExpectBegin()
+	mock.ExpectQuery(`UPDATE users SET deleted_at = now\(\).*deleted_at IS NULL\s+RETURNING id, deleted_at`).
+		WithArgs("user@example.com").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "deleted_at"}).AddRow(int64(4), deletedAt))
+	for i, table := range accountTables {
+		mock.ExpectQuery(`SELECT COUNT\(\*\) FROM ` + table + ` WHERE user_id = \$1`).
+			WithArgs(int64(4)).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(int64(i)))
+	}
+	mock.ExpectCommit()
+	mock.ExpectExec(`UPDATE users SET deleted_at = NULL.*deleted_at > now\(\) - make_interval\(secs => \$2\)`).
+		WithArgs("user@example.com", SoftDeleteRetention.Seconds()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	deletion, err := s.DeleteUser(context.Background(), "user@example.com")
+	if err != nil {
+		t.Fatalf("DeleteUser: %v", err)
+	}
+	if deletion.UserID != 4 || deletion.Retained["users_oauths"] != 3 || deletion.Retained["requests"] != 4 {
+		t.Fatalf("unexpected deletion summary: %+v", deletion)
+	}
+	if deletion.PurgeAfter == nil || !deletion.PurgeAfter.Equal(deletedAt.Add(SoftDeleteRetention)) {
+		t.Fatalf("unexpected purge_after: %v", deletion.PurgeAfter)
+	}
+	if err := s.RestoreUser(context.Background(), "user@example.com"); err != nil {
+		t.Fatalf("RestoreUser: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestRestoreUserOutsideRetentionIsNotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	s := &Store{db: db}
+	t.Cleanup(func() {
+		db.Close()
+	})
+
+	// Accounts deleted before the window, purged or never deleted match no
+	// row.
+	mock.ExpectExec(`UPDATE users SET deleted_at = NULL.*deleted_at > now\(\) - make_interval\(secs => \$2\)`).
+		WithArgs("purged@example.com", SoftDeleteRetention.Seconds()).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectBegin()
+	mock.ExpectQuery(`UPDATE users SET deleted_at = now\(\)`).
+		WithArgs("purged@example.com").
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectRollback()
+
+	if err := s.RestoreUser(context.Background(), "purged@example.com"); !errors.Is(err, ErrUserNotFound) {
+		t.Fatalf("expected ErrUserNotFound restoring, got %v", err)
+	}
+	if _, err := s.DeleteUser(context.Background(), "purged@example.com"); !errors.Is(err, ErrUserNotFound) {
+		t.Fatalf("expected ErrUserNotFound deleting, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestPurgeUserRemovesAccountData(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	shardDB, shardMock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create shard sqlmock: %v", err)
+	}
+	s := &Store{db: db, shards: map[string]*sql.DB{"a": shardDB}}
+	t.Cleanup(func() {
+		db.Close()
+		shardDB.Close()
+	})
+
+	mock.ExpectQuery(`SELECT id FROM users WHERE LOWER\(email\) = LOWER\(\$1\) AND deleted_at IS NOT NULL`).
+		WithArgs("gone@example.com").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(int64(4)))
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT id FROM users WHERE id = \$1 AND deleted_at IS NOT NULL FOR UPDATE`).
+		WithArgs(int64(4)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(int64(4)))
+	for i, table := range accountTables {
+		mock.ExpectExec(`DELETE FROM ` + table + ` WHERE user_id = \$1`).
+			WithArgs(int64(4)).
+			WillReturnResult(sqlmock.NewResult(0, int64(i+1)))
+	}
+	mock.ExpectExec(`DELETE FROM users WHERE id = \$1 AND deleted_at IS NOT NULL`).
+		WithArgs(int64(4)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	shardMock.ExpectBegin()
+	shardMock.ExpectExec(`DELETE FROM requests WHERE user_id = \$1`).
+		WithArgs(int64(4)).
+		WillReturnResult(sqlmock.NewResult(0, 10))
+	shardMock.ExpectCommit()
+	mock.ExpectCommit()
+
+	deletion, err := s.PurgeUser(context.Background(), "gone@example.com")
+	if err != nil {
+		t.Fatalf("PurgeUser: %v", err)
+	}
+	if deletion.UserID != 4 || deletion.Removed["payment_history"] != 1 || deletion.Removed["users_oauths"] != 4 {
+		t.Fatalf("unexpected purge summary: %+v", deletion)
+	}
+	// Five request rows on the primary database and ten on the shard.
+	if deletion.Removed["requests"] != 15 {
+		t.Fatalf("expected 15 requests removed, got %d", deletion.Removed["requests"])
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+	if err := shardMock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet shard expectations: %v", err)
+	}
+}
+
+func TestPurgeUserRollsBackOnError(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	shardDB, shardMock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create shard sqlmock: %v", err)
+	}
+	s := &Store{db: db, shards: map[string]*sql.DB{"a": shardDB}}
+	t.Cleanup(func() {
+		db.Close()
+		shardDB.Close()
+	})
+
+	mock.ExpectQuery(`SELECT id FROM users WHERE LOWER\(email\) = LOWER\(\$1\) AND deleted_at IS NOT NULL`).
+		WithArgs("gone@example.com").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(int64(4)))
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT id FROM users WHERE id = \$1 AND deleted_at IS NOT NULL FOR UPDATE`).
+		WithArgs(int64(4)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(int64(4)))
+	for _, table := range accountTables {
+		mock.ExpectExec(`DELETE FROM ` + table + ` WHERE user_id = \$1`).
+			WithArgs(int64(4)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	mock.ExpectExec(`DELETE FROM users WHERE id = \$1 AND deleted_at IS NOT NULL`).
+		WithArgs(int64(4)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	shardMock.ExpectBegin()
+	shardMock.ExpectExec(`DELETE FROM requests WHERE user_id = \$1`).
+		WithArgs(int64(4)).
+		WillReturnError(errors.New("shard unavailable"))
+	shardMock.ExpectRollback()
+	mock.ExpectRollback()
+
+	if _, err := s.PurgeUser(context.Background(), "gone@example.com"); err == nil {
+		t.Fatal("expected the shard failure to fail the purge")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+	if err := shardMock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet shard expectations: %v", err)
+	}
+}
+
+func TestPurgeUserKeepsUserRestoredInBetween(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	shardDB, shardMock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create shard sqlmock: %v", err)
+	}
+	s := &Store{db: db, shards: map[string]*sql.DB{"a": shardDB}}
+	t.Cleanup(func() {
+		db.Close()
+		shardDB.Close()
+	})
+
+	mock.ExpectQuery(`SELECT id FROM users WHERE LOWER\(email\) = LOWER\(\$1\) AND deleted_at IS NOT NULL`).
+		WithArgs("back@example.com").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(int64(4)))
+	// RestoreUser committed after the lookup, so the locked row is live.
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT id FROM users WHERE id = \$1 AND deleted_at IS NOT NULL FOR UPDATE`).
+		WithArgs(int64(4)).
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectRollback()
+
+	if _, err := s.PurgeUser(context.Background(), "back@example.com"); !errors.Is(err, ErrUserNotFound) {
+		t.Fatalf("expected ErrUserNotFound, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+	if err := shardMock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet shard expectations: %v", err)
+	}
+}
+
+func TestRecordAuditEventWithoutUserStoresNull(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	s := &Store{db: db}
+	t.Cleanup(func() {
+		db.Close()
+	})
+
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO audit_log (user_id, action, metadata)`)).
+		WithArgs(nil, "abuse.auth_failure_burst", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	if err := s.RecordAuditEvent(context.Background(), 0, "abuse.auth_failure_burst", models.JSONB{"client_ip": "203.0.113.7"}); err != nil {
+		t.Fatalf("RecordAuditEvent: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestProvisionSSOUserRejectsExistingNonMember(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	s := &Store{db: db}
+	t.Cleanup(func() {
+		db.Close()
+	})
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT EXISTS (SELECT 1 FROM organization_domains`)).
+		WithArgs(int64(3), "acme.com").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT u.id, EXISTS`)).
+		WithArgs("jane@acme.com", int64(3)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "exists", "deleted"}).AddRow(int64(9), false, false))
+	mock.ExpectRollback()
+
+	_, err = s.ProvisionSSOUser(context.Background(), 3, models.SSOIdentity{Subject: "abc", Email: "Jane@Acme.com"})
+	if !errors.Is(err, ErrSSOAccountConflict) {
+		t.Fatalf("expected ErrSSOAccountConflict, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestApproveOrganizationJoinRequestRequiresPendingRequest(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	s := &Store{db: db}
+	t.Cleanup(func() {
+		db.Close()
+	})
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE organization_join_requests SET status = 'approved'`)).
+		WithArgs(int64(3), int64(9)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	if err := s.ApproveOrganizationJoinRequest(context.Background(), 3, 9); !errors.Is(err, ErrJoinRequestNotFound) {
+		t.Fatalf("expected ErrJoinRequestNotFound, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestGetUserSettingsByMCPSecretFallsBackToSharedJiraAccount(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	s := &Store{db: db}
+	t.Cleanup(func() {
+		db.Close()
+	})
+
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM users_settings us`)).
+		WithArgs("secret").
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectQuery(regexp.QuoteMeta(`JOIN organization_jira_accounts a`)).
+		WithArgs("secret").
+		WillReturnRows(sqlmock.NewRows([]string{"org_id", "data_region", "jira_base_url", "jira_email", "jira_cloud_id", "jira_api_token", "service_management", "project_key", "issue_type", "labels", "components"}).
+			AddRow(int64(3), nil, "https://acme.atlassian.net", "bot@acme.com", nil, "token", false, "ENG", "Task", "{mcp}", "{}"))
+
+	settings, err := s.GetUserSettingsByMCPSecret(context.Background(), "secret")
+	if err != nil {
+		t.Fatalf("GetUserSettingsByMCPSecret: %v", err)
+	}
+	if settings.OrgID == nil || *settings.OrgID != 3 || settings.JiraEmail != "bot@acme.com" || settings.AtlassianAPIToken != "token" {
+		t.Fatalf("unexpected settings: %+v", settings)
+	}
+	if d := settings.IssueDefaults; d == nil || d.ProjectKey != "ENG" || d.IssueType != "Task" || len(d.Labels) != 1 || d.Labels[0] != "mcp" {
+		t.Fatalf("expected the member's issue defaults, got %+v", settings.IssueDefaults)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestGetUserSettingsByMCPSecretIgnoresDeletedOrganizationMember(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	s := &Store{db: db}
+	t.Cleanup(func() {
+		db.Close()
+	})
+
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM users_settings us`)).
+		WithArgs("secret").
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectQuery(regexp.QuoteMeta(`WHERE u.mcp_secret = $1 AND u.deleted_at IS NULL`)).
+		WithArgs("secret").
+		WillReturnError(sql.ErrNoRows)
+
+	if _, err := s.GetUserSettingsByMCPSecret(context.Background(), "secret"); err == nil {
+		t.Fatal("expected no settings for a deleted organization member")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestGetUserSettingsByMCPSecretReadsResidentAccountFromRegion(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	regional, regionalMock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create regional sqlmock: %v", err)
+	}
+	s := &Store{db: db}
+	s.SetRegionalDatabases(map[string]*sql.DB{"eu": regional})
+	t.Cleanup(func() {
+		db.Close()
+		regional.Close()
+	})
+
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM users_settings us`)).
+		WithArgs("secret").
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectQuery(regexp.QuoteMeta(`JOIN organization_jira_accounts a`)).
+		WithArgs("secret").
+		WillReturnRows(sqlmock.NewRows([]string{"org_id", "data_region", "jira_base_url", "jira_email", "jira_cloud_id", "jira_api_token", "service_management", "project_key", "issue_type", "labels", "components"}).
+			AddRow(int64(3), "eu", "", "", nil, "", false, nil, nil, nil, nil))
+	regionalMock.ExpectQuery(regexp.QuoteMeta(`FROM organization_jira_accounts`)).
+		WithArgs(int64(3)).
+		WillReturnRows(sqlmock.NewRows([]string{"jira_base_url", "jira_email", "jira_cloud_id", "jira_api_token"}).
+			AddRow("https://acme.atlassian.net", "bot@acme.com", nil, "token"))
+
+	settings, err := s.GetUserSettingsByMCPSecret(context.Background(), "secret")
+	if err != nil {
+		t.Fatalf("GetUserSettingsByMCPSecret: %v", err)
+	}
+	if settings.JiraBaseURL != "https://acme.atlassian.net" || settings.AtlassianAPIToken != "token" {
+		t.Fatalf("expected credentials from the regional database, got %+v", settings)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+	if err := regionalMock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet regional expectations: %v", err)
+	}
+}
+
+func TestGetUsageDigestSummarizesWeekAndMonthToDate(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	s := &Store{db: db}
+	t.Cleanup(func() {
+		db.Close()
+	})
+
+	weekStart := time.Date(2026, 10, 5, 0, 0, 0, 0, time.UTC)
+	dailyColumns := []string{"day", "request_count", "error_count", "total_response_ms", "cost_units"}
+	quota := 1000
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT email FROM users WHERE id = $1 AND deleted_at IS NULL`)).
+		WithArgs(int64(7)).
+		WillReturnRows(sqlmock.NewRows([]string{"email"}).AddRow("user@example.com"))
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM request_daily_rollups`)).
+		WithArgs(int64(7), "2026-10-05", "2026-10-11").
+		WillReturnRows(sqlmock.NewRows(dailyColumns).
+			AddRow(weekStart, 40, 2, int64(4000), int64(60)).
+			AddRow(weekStart.AddDate(0, 0, 1), 10, 1, int64(900), int64(15)))
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM tool_invocations`)).
+		WithArgs(int64(7), weekStart, weekStart.AddDate(0, 0, 7)).
+		WillReturnRows(sqlmock.NewRows([]string{"tool_name", "count", "errors", "cost_units"}).
+			AddRow("searchIssues", 30, 1, int64(45)).
+			AddRow("getIssue", 12, 0, int64(12)))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT project_key`)).
+		WithArgs(int64(7), weekStart, weekStart.AddDate(0, 0, 7), usageDigestTopN).
+		WillReturnRows(sqlmock.NewRows([]string{"project_key", "count", "errors"}).AddRow("ENG", 25, 1))
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM request_daily_rollups`)).
+		WithArgs(int64(7), "2026-10-01", "2026-10-11").
+		WillReturnRows(sqlmock.NewRows(dailyColumns).
+			AddRow(time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC), 100, 0, int64(9000), int64(150)).
+			AddRow(weekStart, 40, 2, int64(4000), int64(60)))
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM membership_plans`)).
+		WithArgs(int64(7)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "slug", "name", "description", "tier", "is_active", "monthly_request_quota", "monthly_cost_unit_quota", "max_streaming_connections", "requests_per_minute", "revision", "created_at", "updated_at"}).
+			AddRow(int64(1), "free", "Free", "", 0, true, quota, nil, nil, nil, 1, time.Now(), time.Now()))
+
+	digest, err := s.GetUsageDigest(context.Background(), 7, weekStart)
+	if err != nil {
+		t.Fatalf("GetUsageDigest: %v", err)
+	}
+	if digest.Requests != 50 || digest.Errors != 3 || digest.CostUnits != 75 {
+		t.Fatalf("unexpected week totals: %+v", digest)
+	}
+	if digest.ToolCalls != 42 || digest.ToolErrors != 1 || len(digest.TopProjects) != 1 || digest.TopProjects[0].ProjectKey != "ENG" {
+		t.Fatalf("unexpected tool usage: %+v", digest)
+	}
+	if digest.RequestsMonthToDate != 140 || digest.CostUnitsMonthToDate != 210 || digest.RequestQuota == nil || *digest.RequestQuota != quota {
+		t.Fatalf("unexpected quota consumption: %+v", digest)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestMoveTenantToShardCopiesRequestLogs(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	shard, shardMock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create shard sqlmock: %v", err)
+	}
+	s := &Store{db: db}
+	s.SetShardDatabases(map[string]*sql.DB{"s1": shard})
+	t.Cleanup(func() {
+		db.Close()
+		shard.Close()
+	})
+
+	requestColumns := []string{"id", "method", "endpoint", "status_code", "response_time_ms", "request_size_bytes",
+		"response_size_bytes", "error_message", "created_at", "sample_weight"}
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT EXISTS (SELECT 1 FROM users WHERE id = $1 AND deleted_at IS NULL)`)).
+		WithArgs(int64(7)).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO tenant_shards`)).
+		WithArgs(int64(7), "s1", "moving").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM requests WHERE user_id = $1`)).
+		WithArgs(int64(7), requestMoveBatch).
+		WillReturnRows(sqlmock.NewRows(requestColumns).
+			AddRow(int64(11), "GET", "/api/x", 200, nil, nil, nil, nil, time.Now(), 1))
+	shardMock.ExpectBegin()
+	shardMock.ExpectExec(regexp.QuoteMeta(`INSERT INTO requests`)).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	shardMock.ExpectCommit()
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM requests WHERE id = ANY($1)`)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM requests WHERE user_id = $1`)).
+		WithArgs(int64(7), requestMoveBatch).
+		WillReturnRows(sqlmock.NewRows(requestColumns))
+	shardMock.ExpectBegin()
+	shardMock.ExpectRollback()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE tenant_shards SET status = $3`)).
+		WithArgs(int64(7), "s1", "active").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	if err := s.MoveTenantToShard(context.Background(), 7, "s1"); err != nil {
+		t.Fatalf("MoveTenantToShard: %v", err)
+	}
+	if err := s.MoveTenantToShard(context.Background(), 7, "s2"); !errors.Is(err, ErrUnknownShard) {
+		t.Fatalf("expected ErrUnknownShard for an unconfigured shard, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+	if err := shardMock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet shard expectations: %v", err)
+	}
+}
+
+func TestSaveMCPSessionPassesJSONAsText(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+	s := &Store{db: db}
+
+	seen := time.Now()
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO mcp_sessions`)).
+		WithArgs("abc", int64(7), "2025-06-18", nil, `{"roots":{}}`, seen, seen.Add(time.Hour)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM mcp_sessions WHERE user_id = $1 AND expires_at < now()`)).
+		WithArgs(int64(7)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM mcp_sessions`)).
+		WithArgs("gone").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	err = s.SaveMCPSession(context.Background(), &models.MCPSession{
+		ID:                 "abc",
+		UserID:             7,
+		ProtocolVersion:    "2025-06-18",
+		ClientCapabilities: []byte(`{"roots":{}}`),
+		LastSeenAt:         seen,
+		ExpiresAt:          seen.Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatalf("SaveMCPSession: %v", err)
+	}
+	if _, err := s.GetMCPSession(context.Background(), "gone"); !errors.Is(err, ErrMCPSessionNotFound) {
+		t.Fatalf("expected ErrMCPSessionNotFound for an expired session, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+// sealedArg matches a credential encrypted by secretbox and remembers it.
+type sealedArg struct{ value *string }
+
+func (a sealedArg) Match(v driver.Value) bool {
+	s, ok := v.(string)
+	*a.value = s
+	return ok && secretbox.Sealed(s)
+}
+
+func TestUserSettingsTokensAreEncrypted(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	s, err := New(db)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	keys, _ := keyring.Parse("k1:secret")
+	box, _ := secretbox.New(keys)
+	s.SetSecretBox(box)
+
+	var stored string
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT id FROM users WHERE LOWER\(email\) = LOWER\(\$1\)`).
+		WithArgs("owner@example.com").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(int64(7)))
+	mock.ExpectQuery(`INSERT INTO users_settings`).
+		WithArgs(int64(7), "https://acme.atlassian.net", "bot@example.com", sealedArg{&stored}).
+		WillReturnRows(sqlmock.NewRows([]string{"revision"}).AddRow(int64(1)))
+	mock.ExpectCommit()
+
+	if err := s.UpsertUserSettings(context.Background(), "owner@example.com", "https://acme.atlassian.net", "bot@example.com", "plain-token"); err != nil {
+		t.Fatalf("UpsertUserSettings: %v", err)
+	}
+
+	mock.ExpectQuery(`FROM users_settings us`).
+		WithArgs("secret").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "jira_base_url", "jira_email", "jira_cloud_id", "is_default", "auth_method", "token", "service_management", "project_key", "issue_type", "labels", "components"}).
+			AddRow(int64(7), "https://acme.atlassian.net", "bot@example.com", nil, true, "api_token", stored, true, nil, nil, nil, nil))
+
+	settings, err := s.GetUserSettingsByMCPSecret(context.Background(), "secret")
+	if err != nil {
+		t.Fatalf("GetUserSettingsByMCPSecret: %v", err)
+	}
+	if settings.AtlassianAPIToken != "plain-token" {
+		t.Fatalf("expected the decrypted token, got %q", settings.AtlassianAPIToken)
+	}
+	if !settings.ServiceManagement {
+		t.Fatal("expected the site's service management flag")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestSetUserRoleAuditsChanges(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	s := &Store{db: db}
+	t.Cleanup(func() {
+		db.Close()
+	})
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT id, role\s+FROM users`).
+		WithArgs("ops@example.com").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "role"}).AddRow(int64(5), models.RoleUser))
+	mock.ExpectExec(`UPDATE users SET role = \$2`).
+		WithArgs(int64(5), models.RoleSupport).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO audit_log`).
+		WithArgs(int64(5), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	if err := s.SetUserRole(context.Background(), "ops@example.com", models.RoleSupport, 2); err != nil {
+		t.Fatalf("SetUserRole: %v", err)
+	}
+	if err := s.SetUserRole(context.Background(), "ops@example.com", "root", 2); err == nil {
+		t.Fatal("expected an unknown role to be refused")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestAuthenticateAPIKey(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	s := &Store{db: db}
+	t.Cleanup(func() {
+		db.Close()
+	})
+
+	const key = "mjt_0a1b2c3d_c2VjcmV0"
+	columns := []string{"id", "name", "prefix", "key_hash", "scopes", "last_used_at"}
+	mock.ExpectQuery(`SELECT id, name, prefix, key_hash, scopes, last_used_at\s+FROM api_keys`).
+		WithArgs("mjt_0a1b2c3d").
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(int64(3), "mcp worker", "mjt_0a1b2c3d", hashAPIKey(key), "{tenant}", nil))
+	mock.ExpectExec(`UPDATE api_keys SET last_used_at = NOW\(\)`).
+		WithArgs(int64(3)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`FROM api_keys`).
+		WithArgs("mjt_0a1b2c3d").
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(int64(3), "mcp worker", "mjt_0a1b2c3d", hashAPIKey(key), "{tenant}", time.Now()))
+
+	found, err := s.AuthenticateAPIKey(context.Background(), key)
+	if err != nil {
+		t.Fatalf("AuthenticateAPIKey: %v", err)
+	}
+	if found.ID != 3 || !found.HasScope(models.APIKeyScopeTenant) {
+		t.Fatalf("unexpected key %+v", found)
+	}
+	if _, err := s.AuthenticateAPIKey(context.Background(), "mjt_0a1b2c3d_guessed"); !errors.Is(err, ErrAPIKeyInvalid) {
+		t.Fatalf("expected a wrong secret to be refused, got %v", err)
+	}
+	if _, err := s.AuthenticateAPIKey(context.Background(), "not-a-key"); !errors.Is(err, ErrAPIKeyInvalid) {
+		t.Fatalf("expected a malformed key to be refused, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
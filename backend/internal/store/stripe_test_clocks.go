@@ -0,0 +1,78 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// ErrStripeTestClockNotFound is returned when the user has no Stripe test
+// clock.
+var ErrStripeTestClockNotFound = errors.New("stripe test clock not found")
+
+// RecordStripeTestClock stores the test clock and the test customer attached
+// to it, and makes that customer the user's Stripe customer.
+func (s *Store) RecordStripeTestClock(ctx context.Context, userID int64, testClockID, customerID string) error {
+	if s == nil || s.db == nil {
+		return errors.New("store: db cannot be nil")
+	}
+	if testClockID == "" || customerID == "" {
+		return errors.New("store: test clock and customer ids are required")
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("store: begin record test clock tx: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO stripe_test_clocks (user_id, test_clock_id, stripe_customer_id)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id) DO UPDATE
+		SET test_clock_id = EXCLUDED.test_clock_id,
+		    stripe_customer_id = EXCLUDED.stripe_customer_id,
+		    created_at = now()
+	`, userID, testClockID, customerID); err != nil {
+		return fmt.Errorf("store: record test clock: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE users SET stripe_customer_id = $1, updated_at = now() WHERE id = $2
+	`, customerID, userID); err != nil {
+		return fmt.Errorf("store: set test clock customer: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("store: commit record test clock tx: %w", err)
+	}
+
+	return nil
+}
+
+// GetStripeTestClockByEmail returns the id of the test clock attached to the
+// user's Stripe customer.
+func (s *Store) GetStripeTestClockByEmail(ctx context.Context, email string) (string, error) {
+	if s == nil || s.db == nil {
+		return "", errors.New("store: db cannot be nil")
+	}
+
+	var testClockID string
+	err := s.db.QueryRowContext(ctx, `
+		SELECT c.test_clock_id
+		FROM stripe_test_clocks c
+		JOIN users u ON u.id = c.user_id
+		WHERE LOWER(u.email) = LOWER($1) AND u.deleted_at IS NULL
+	`, email).Scan(&testClockID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrStripeTestClockNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("store: get test clock: %w", err)
+	}
+
+	return testClockID, nil
+}
@@ -0,0 +1,81 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+)
+
+// GetTenantState reads the account of the user with email for support: the
+// user row, connected OAuth providers, Jira settings without tokens, the
+// latest subscription whatever its status and the user's limit most recent
+// requests. Jobs live in the JobStore and are left for the caller to add.
+// It returns ErrUserNotFound when no user has the email.
+func (s *Store) GetTenantState(ctx context.Context, email string, limit int) (*models.TenantState, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	state := &models.TenantState{}
+	u := &state.User
+	var secret sql.NullString
+	err := s.db.QueryRowContext(ctx, `
+		SELECT id, login, name, email, avatar_url, stripe_customer_id, mcp_secret,
+		       email_verified_at, suspended_at, suspended_reason, debug_mode_until,
+		       created_at, updated_at
+		FROM users
+		WHERE LOWER(email) = LOWER($1) AND deleted_at IS NULL
+		ORDER BY id
+		LIMIT 1
+	`, email).Scan(
+		&u.ID, &u.Login, &u.Name, &u.Email, &u.AvatarURL, &u.StripeCustomerID, &secret,
+		&u.EmailVerifiedAt, &u.SuspendedAt, &u.SuspendedReason, &u.DebugModeUntil,
+		&u.CreatedAt, &u.UpdatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("store: get tenant user: %w", err)
+	}
+	u.HasMCPSecret = secret.Valid && secret.String != ""
+
+	if state.OAuthAccounts, err = s.GetConnectedAccounts(ctx, email); err != nil {
+		return nil, err
+	}
+	if state.JiraSettings, err = s.ListUserSettings(ctx, email); err != nil {
+		return nil, err
+	}
+
+	var sub models.Subscription
+	err = s.db.QueryRowContext(ctx, `
+		SELECT id, user_id, stripe_customer_id, stripe_subscription_id,
+		       stripe_price_id, status, current_period_start, current_period_end,
+		       cancel_at_period_end, canceled_at, pause_behavior, pause_resumes_at, paused_at,
+		       created_at, updated_at
+		FROM subscriptions
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+		LIMIT 1
+	`, u.ID).Scan(
+		&sub.ID, &sub.UserID, &sub.StripeCustomerID, &sub.StripeSubscriptionID,
+		&sub.StripePriceID, &sub.Status, &sub.CurrentPeriodStart, &sub.CurrentPeriodEnd,
+		&sub.CancelAtPeriodEnd, &sub.CanceledAt, &sub.PauseBehavior, &sub.PauseResumesAt, &sub.PausedAt,
+		&sub.CreatedAt, &sub.UpdatedAt,
+	)
+	switch {
+	case err == nil:
+		state.Subscription = &sub
+	case !errors.Is(err, sql.ErrNoRows):
+		return nil, fmt.Errorf("store: get tenant subscription: %w", err)
+	}
+
+	if state.RecentRequests, err = s.GetUserRequests(ctx, u.ID, limit, 0); err != nil {
+		return nil, err
+	}
+
+	return state, nil
+}
@@ -0,0 +1,283 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+)
+
+// ErrToolApprovalNotFound is returned when a held tool call does not exist,
+// belongs to another user or, when deciding, is no longer pending.
+var ErrToolApprovalNotFound = errors.New("tool approval not found")
+
+// ErrToolApprovalNotApproved is returned when the worker tries to execute a
+// held tool call that has not been approved, was already executed or has
+// expired.
+var ErrToolApprovalNotApproved = errors.New("tool approval is not approved")
+
+// toolApprovalStatus reports held calls whose window passed as expired.
+const toolApprovalStatus = `CASE WHEN status IN ('pending', 'approved') AND expires_at <= now() THEN 'expired' ELSE status END`
+
+const toolApprovalColumns = `
+	id, token, tool_name, arguments, ` + toolApprovalStatus + `,
+	created_at, expires_at, decided_at, executed_at`
+
+// ListToolApprovalPolicies returns the stored approval settings for the user
+// identified by email.
+func (s *Store) ListToolApprovalPolicies(ctx context.Context, email string) ([]models.ToolApprovalPolicy, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	return s.queryToolApprovalPolicies(ctx, `
+		SELECT p.tool_name, p.require_approval, p.updated_at
+		FROM tool_approval_policies p
+		JOIN users u ON u.id = p.user_id
+		WHERE LOWER(u.email) = LOWER($1) AND u.deleted_at IS NULL
+		ORDER BY p.tool_name
+	`, email)
+}
+
+// ListToolApprovalPoliciesByUserID returns the stored approval settings for
+// a user ID.
+func (s *Store) ListToolApprovalPoliciesByUserID(ctx context.Context, userID int64) ([]models.ToolApprovalPolicy, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	return s.queryToolApprovalPolicies(ctx, `
+		SELECT tool_name, require_approval, updated_at
+		FROM tool_approval_policies
+		WHERE user_id = $1
+		ORDER BY tool_name
+	`, userID)
+}
+
+func (s *Store) queryToolApprovalPolicies(ctx context.Context, query string, arg any) ([]models.ToolApprovalPolicy, error) {
+	rows, err := s.db.QueryContext(ctx, query, arg)
+	if err != nil {
+		return nil, fmt.Errorf("store: list tool approval policies: %w", err)
+	}
+	defer rows.Close()
+
+	var policies []models.ToolApprovalPolicy
+	for rows.Next() {
+		var p models.ToolApprovalPolicy
+		if err := rows.Scan(&p.Tool, &p.RequireApproval, &p.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("store: scan tool approval policy: %w", err)
+		}
+		policies = append(policies, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("store: iterate tool approval policies: %w", err)
+	}
+
+	return policies, nil
+}
+
+// SetToolApprovalPolicy stores whether calls to tool need approval, or the
+// default for destructive tools when tool is models.ToolApprovalDefault.
+func (s *Store) SetToolApprovalPolicy(ctx context.Context, email, tool string, requireApproval bool) error {
+	if s == nil || s.db == nil {
+		return errors.New("store: db cannot be nil")
+	}
+
+	res, err := s.db.ExecContext(ctx, `
+		INSERT INTO tool_approval_policies (user_id, tool_name, require_approval)
+		SELECT id, $2, $3 FROM users WHERE LOWER(email) = LOWER($1) AND deleted_at IS NULL
+		ON CONFLICT (user_id, tool_name) DO UPDATE
+		SET require_approval = EXCLUDED.require_approval,
+		    updated_at = now()
+	`, email, tool, requireApproval)
+	if err != nil {
+		return fmt.Errorf("store: set tool approval policy: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("store: no local user found for email=%s", email)
+	}
+
+	return nil
+}
+
+// DeleteToolApprovalPolicy removes a stored setting so the tool falls back
+// to the tenant default.
+func (s *Store) DeleteToolApprovalPolicy(ctx context.Context, email, tool string) error {
+	if s == nil || s.db == nil {
+		return errors.New("store: db cannot be nil")
+	}
+
+	if _, err := s.db.ExecContext(ctx, `
+		DELETE FROM tool_approval_policies
+		WHERE tool_name = $2
+		  AND user_id = (SELECT id FROM users WHERE LOWER(email) = LOWER($1) AND deleted_at IS NULL)
+	`, email, tool); err != nil {
+		return fmt.Errorf("store: delete tool approval policy: %w", err)
+	}
+
+	return nil
+}
+
+// CreateToolApproval holds a tool call for the user's approval and returns
+// it with the token the worker hands to the client.
+func (s *Store) CreateToolApproval(ctx context.Context, userID int64, tool string, arguments json.RawMessage, ttl time.Duration) (*models.ToolApproval, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+	if len(arguments) == 0 {
+		arguments = json.RawMessage(`{}`)
+	}
+
+	token, err := randomHex(24)
+	if err != nil {
+		return nil, fmt.Errorf("store: generate tool approval token: %w", err)
+	}
+
+	row := s.db.QueryRowContext(ctx, `
+		INSERT INTO tool_approvals (token, user_id, tool_name, arguments, expires_at)
+		VALUES ($1, $2, $3, $4, now() + $5 * interval '1 second')
+		RETURNING `+toolApprovalColumns,
+		token, userID, tool, []byte(arguments), int64(ttl/time.Second))
+	approval, err := scanToolApproval(row)
+	if err != nil {
+		return nil, fmt.Errorf("store: create tool approval: %w", err)
+	}
+	return approval, nil
+}
+
+// ListToolApprovals returns the most recent held tool calls of the user
+// identified by email, optionally only those in status.
+func (s *Store) ListToolApprovals(ctx context.Context, email, status string) ([]models.ToolApproval, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+toolApprovalColumns+`
+		FROM tool_approvals
+		WHERE user_id IN (SELECT id FROM users WHERE LOWER(email) = LOWER($1) AND deleted_at IS NULL)
+		  AND ($2 = '' OR `+toolApprovalStatus+` = $2)
+		ORDER BY created_at DESC
+		LIMIT 100
+	`, email, status)
+	if err != nil {
+		return nil, fmt.Errorf("store: list tool approvals: %w", err)
+	}
+	defer rows.Close()
+
+	var approvals []models.ToolApproval
+	for rows.Next() {
+		approval, err := scanToolApproval(rows)
+		if err != nil {
+			return nil, fmt.Errorf("store: scan tool approval: %w", err)
+		}
+		approval.Token = ""
+		approvals = append(approvals, *approval)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("store: iterate tool approvals: %w", err)
+	}
+
+	return approvals, nil
+}
+
+// DecideToolApproval approves or rejects a pending held call of the user
+// identified by email.
+func (s *Store) DecideToolApproval(ctx context.Context, email string, id int64, approve bool) (*models.ToolApproval, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	status := models.ToolApprovalRejected
+	if approve {
+		status = models.ToolApprovalApproved
+	}
+
+	row := s.db.QueryRowContext(ctx, `
+		UPDATE tool_approvals SET status = $3, decided_at = now()
+		WHERE id = $2 AND status = 'pending' AND expires_at > now()
+		  AND user_id IN (SELECT id FROM users WHERE LOWER(email) = LOWER($1) AND deleted_at IS NULL)
+		RETURNING `+toolApprovalColumns,
+		email, id, status)
+	approval, err := scanToolApproval(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrToolApprovalNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("store: decide tool approval: %w", err)
+	}
+	approval.Token = ""
+	return approval, nil
+}
+
+// GetToolApprovalByToken returns a held call of userID by its token.
+func (s *Store) GetToolApprovalByToken(ctx context.Context, userID int64, token string) (*models.ToolApproval, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	row := s.db.QueryRowContext(ctx, `
+		SELECT `+toolApprovalColumns+`
+		FROM tool_approvals
+		WHERE user_id = $1 AND token = $2
+	`, userID, token)
+	approval, err := scanToolApproval(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrToolApprovalNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("store: get tool approval: %w", err)
+	}
+	return approval, nil
+}
+
+// ConsumeToolApproval marks an approved call of userID as executed and
+// returns it, so each approval runs at most once. Calls that are not (or no
+// longer) approved are returned with ErrToolApprovalNotApproved.
+func (s *Store) ConsumeToolApproval(ctx context.Context, userID int64, token string) (*models.ToolApproval, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	row := s.db.QueryRowContext(ctx, `
+		UPDATE tool_approvals SET status = 'executed', executed_at = now()
+		WHERE user_id = $1 AND token = $2 AND status = 'approved' AND expires_at > now()
+		RETURNING `+toolApprovalColumns,
+		userID, token)
+	approval, err := scanToolApproval(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		current, err := s.GetToolApprovalByToken(ctx, userID, token)
+		if err != nil {
+			return nil, err
+		}
+		return current, ErrToolApprovalNotApproved
+	}
+	if err != nil {
+		return nil, fmt.Errorf("store: consume tool approval: %w", err)
+	}
+	return approval, nil
+}
+
+func scanToolApproval(row interface{ Scan(...any) error }) (*models.ToolApproval, error) {
+	var (
+		a         models.ToolApproval
+		arguments []byte
+		decided   sql.NullTime
+		executed  sql.NullTime
+	)
+	if err := row.Scan(&a.ID, &a.Token, &a.Tool, &arguments, &a.Status, &a.CreatedAt, &a.ExpiresAt, &decided, &executed); err != nil {
+		return nil, err
+	}
+	a.Arguments = json.RawMessage(arguments)
+	if decided.Valid {
+		a.DecidedAt = &decided.Time
+	}
+	if executed.Valid {
+		a.ExecutedAt = &executed.Time
+	}
+	return &a, nil
+}
@@ -0,0 +1,136 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+)
+
+// RecordToolInvocation appends an entry to the tool invocation log, charging
+// the tool's current cost weight and counting it in the tool's category, and
+// returns the cost units charged. Calls made with an organization's shared
+// Jira account are attributed to it.
+func (s *Store) RecordToolInvocation(ctx context.Context, inv *models.ToolInvocation) (int, error) {
+	if s == nil || s.db == nil {
+		return 0, errors.New("store: db cannot be nil")
+	}
+	if inv == nil {
+		return 0, errors.New("store: tool invocation cannot be nil")
+	}
+
+	err := s.db.QueryRowContext(ctx, `
+		INSERT INTO tool_invocations (user_id, tool_name, cost_units, is_error, duration_ms, org_id, project_key, category)
+		VALUES ($1, $2, COALESCE((SELECT cost_units FROM tool_cost_weights WHERE tool_name = $2), $3), $4, $5,
+		        (`+sharedJiraOrgForUser+`), $6,
+		        COALESCE((SELECT category FROM tool_categories WHERE tool_name = $2), $7))
+		RETURNING id, cost_units, org_id, category, created_at
+	`, inv.UserID, inv.Tool, models.DefaultToolCostUnits, inv.IsError, inv.DurationMs, inv.ProjectKey, models.DefaultToolCategory).Scan(&inv.ID, &inv.CostUnits, &inv.OrgID, &inv.Category, &inv.CreatedAt)
+	if err != nil {
+		return 0, fmt.Errorf("store: record tool invocation: %w", err)
+	}
+
+	return inv.CostUnits, nil
+}
+
+// ListToolCostWeights returns the configured per-tool cost weights. Tools
+// not listed cost models.DefaultToolCostUnits.
+func (s *Store) ListToolCostWeights(ctx context.Context) ([]models.ToolCostWeight, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT tool_name, cost_units, updated_at
+		FROM tool_cost_weights
+		ORDER BY tool_name
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("store: list tool cost weights: %w", err)
+	}
+	defer rows.Close()
+
+	var weights []models.ToolCostWeight
+	for rows.Next() {
+		var w models.ToolCostWeight
+		if err := rows.Scan(&w.Tool, &w.CostUnits, &w.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("store: scan tool cost weight: %w", err)
+		}
+		weights = append(weights, w)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("store: iterate tool cost weights: %w", err)
+	}
+
+	return weights, nil
+}
+
+// SetToolCostWeight creates or replaces a tool's cost weight. It applies to
+// invocations recorded from now on; past entries keep what they were charged.
+func (s *Store) SetToolCostWeight(ctx context.Context, tool string, costUnits int) error {
+	if s == nil || s.db == nil {
+		return errors.New("store: db cannot be nil")
+	}
+
+	if _, err := s.db.ExecContext(ctx, `
+		INSERT INTO tool_cost_weights (tool_name, cost_units, updated_at)
+		VALUES ($1, $2, now())
+		ON CONFLICT (tool_name) DO UPDATE
+		SET cost_units = EXCLUDED.cost_units,
+		    updated_at = now()
+	`, tool, costUnits); err != nil {
+		return fmt.Errorf("store: set tool cost weight: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteToolCostWeight removes a tool's weight so it falls back to the
+// default cost.
+func (s *Store) DeleteToolCostWeight(ctx context.Context, tool string) error {
+	if s == nil || s.db == nil {
+		return errors.New("store: db cannot be nil")
+	}
+
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM tool_cost_weights WHERE tool_name = $1`, tool); err != nil {
+		return fmt.Errorf("store: delete tool cost weight: %w", err)
+	}
+
+	return nil
+}
+
+// ListToolCosts aggregates the user's tool invocations in [from, to) by tool,
+// most expensive first.
+func (s *Store) ListToolCosts(ctx context.Context, userID int64, from, to time.Time) ([]models.ToolCost, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT tool_name, COUNT(*), COUNT(*) FILTER (WHERE is_error), COALESCE(SUM(cost_units), 0)
+		FROM tool_invocations
+		WHERE user_id = $1 AND created_at >= $2 AND created_at < $3
+		GROUP BY tool_name
+		ORDER BY 4 DESC, tool_name
+	`, userID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("store: list tool costs: %w", err)
+	}
+	defer rows.Close()
+
+	var costs []models.ToolCost
+	for rows.Next() {
+		var c models.ToolCost
+		if err := rows.Scan(&c.Tool, &c.Invocations, &c.Errors, &c.CostUnits); err != nil {
+			return nil, fmt.Errorf("store: scan tool cost: %w", err)
+		}
+		costs = append(costs, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("store: iterate tool costs: %w", err)
+	}
+
+	return costs, nil
+}
@@ -0,0 +1,104 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+)
+
+// ListToolResponseLimits returns the stored tool response limits for the user
+// identified by email.
+func (s *Store) ListToolResponseLimits(ctx context.Context, email string) ([]models.ToolResponseLimit, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	return s.queryToolResponseLimits(ctx, `
+		SELECT l.tool_name, l.max_bytes, l.updated_at
+		FROM tool_response_limits l
+		JOIN users u ON u.id = l.user_id
+		WHERE LOWER(u.email) = LOWER($1) AND u.deleted_at IS NULL
+		ORDER BY l.tool_name
+	`, email)
+}
+
+// ListToolResponseLimitsByUserID returns the stored tool response limits for
+// a user ID.
+func (s *Store) ListToolResponseLimitsByUserID(ctx context.Context, userID int64) ([]models.ToolResponseLimit, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	return s.queryToolResponseLimits(ctx, `
+		SELECT tool_name, max_bytes, updated_at
+		FROM tool_response_limits
+		WHERE user_id = $1
+		ORDER BY tool_name
+	`, userID)
+}
+
+func (s *Store) queryToolResponseLimits(ctx context.Context, query string, arg any) ([]models.ToolResponseLimit, error) {
+	rows, err := s.db.QueryContext(ctx, query, arg)
+	if err != nil {
+		return nil, fmt.Errorf("store: list tool response limits: %w", err)
+	}
+	defer rows.Close()
+
+	var limits []models.ToolResponseLimit
+	for rows.Next() {
+		var l models.ToolResponseLimit
+		if err := rows.Scan(&l.Tool, &l.MaxBytes, &l.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("store: scan tool response limit: %w", err)
+		}
+		limits = append(limits, l)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("store: iterate tool response limits: %w", err)
+	}
+
+	return limits, nil
+}
+
+// SetToolResponseLimit stores the response size limit for a tool, or the
+// tenant default when tool is models.ToolResponseLimitDefault.
+func (s *Store) SetToolResponseLimit(ctx context.Context, email, tool string, maxBytes int) error {
+	if s == nil || s.db == nil {
+		return errors.New("store: db cannot be nil")
+	}
+
+	res, err := s.db.ExecContext(ctx, `
+		INSERT INTO tool_response_limits (user_id, tool_name, max_bytes)
+		SELECT id, $2, $3 FROM users WHERE LOWER(email) = LOWER($1) AND deleted_at IS NULL
+		ON CONFLICT (user_id, tool_name) DO UPDATE
+		SET max_bytes = EXCLUDED.max_bytes,
+		    updated_at = now()
+	`, email, tool, maxBytes)
+	if err != nil {
+		return fmt.Errorf("store: set tool response limit: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("store: no local user found for email=%s", email)
+	}
+
+	return nil
+}
+
+// DeleteToolResponseLimit removes a stored limit so the tool falls back to
+// the tenant (or system) default.
+func (s *Store) DeleteToolResponseLimit(ctx context.Context, email, tool string) error {
+	if s == nil || s.db == nil {
+		return errors.New("store: db cannot be nil")
+	}
+
+	if _, err := s.db.ExecContext(ctx, `
+		DELETE FROM tool_response_limits
+		WHERE tool_name = $2
+		  AND user_id = (SELECT id FROM users WHERE LOWER(email) = LOWER($1) AND deleted_at IS NULL)
+	`, email, tool); err != nil {
+		return fmt.Errorf("store: delete tool response limit: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,109 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+)
+
+// ToolCategory returns the category a tool's calls are counted in,
+// models.DefaultToolCategory when it has none.
+func (s *Store) ToolCategory(ctx context.Context, tool string) (string, error) {
+	if s == nil || s.db == nil {
+		return "", errors.New("store: db cannot be nil")
+	}
+
+	var category string
+	err := s.db.QueryRowContext(ctx, `SELECT category FROM tool_categories WHERE tool_name = $1`, tool).Scan(&category)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.DefaultToolCategory, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("store: lookup tool category: %w", err)
+	}
+	return category, nil
+}
+
+// ListToolCategories returns the configured tool categories by tool name.
+// Tools not listed are models.DefaultToolCategory.
+func (s *Store) ListToolCategories(ctx context.Context) (map[string]string, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	rows, err := s.db.QueryContext(ctx, `SELECT tool_name, category FROM tool_categories ORDER BY tool_name`)
+	if err != nil {
+		return nil, fmt.Errorf("store: list tool categories: %w", err)
+	}
+	defer rows.Close()
+
+	categories := map[string]string{}
+	for rows.Next() {
+		var tool, category string
+		if err := rows.Scan(&tool, &category); err != nil {
+			return nil, fmt.Errorf("store: scan tool category: %w", err)
+		}
+		categories[tool] = category
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("store: iterate tool categories: %w", err)
+	}
+
+	return categories, nil
+}
+
+// ListToolCategoryUsage returns the user's successful tool calls since since
+// per category, with the daily limits of the plan with planID. Categories
+// that are neither limited nor used are left out.
+func (s *Store) ListToolCategoryUsage(ctx context.Context, userID, planID int64, since time.Time) ([]models.ToolCategoryUsage, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		WITH used AS (
+			SELECT category, COUNT(*) AS calls
+			FROM tool_invocations
+			WHERE user_id = $1 AND created_at >= $3 AND NOT is_error
+			GROUP BY category
+		), limits AS (
+			SELECT category, daily_limit FROM plan_tool_quotas WHERE plan_id = $2
+		)
+		SELECT COALESCE(u.category, l.category), COALESCE(u.calls, 0), l.daily_limit
+		FROM used u
+		FULL JOIN limits l ON l.category = u.category
+		ORDER BY 1
+	`, userID, planID, since)
+	if err != nil {
+		return nil, fmt.Errorf("store: list tool category usage: %w", err)
+	}
+	defer rows.Close()
+
+	var usage []models.ToolCategoryUsage
+	for rows.Next() {
+		var (
+			u     models.ToolCategoryUsage
+			limit sql.NullInt64
+		)
+		if err := rows.Scan(&u.Category, &u.Used, &limit); err != nil {
+			return nil, fmt.Errorf("store: scan tool category usage: %w", err)
+		}
+		if limit.Valid {
+			dailyLimit := int(limit.Int64)
+			remaining := max(limit.Int64-u.Used, 0)
+			u.DailyLimit = &dailyLimit
+			u.Remaining = &remaining
+			u.Exceeded = u.Used >= limit.Int64
+		}
+		usage = append(usage, u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("store: iterate tool category usage: %w", err)
+	}
+
+	return usage, nil
+}
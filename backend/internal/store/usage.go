@@ -0,0 +1,215 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+)
+
+// RefreshDailyRollups recomputes request_daily_rollups for every UTC day
+// starting at since from the raw requests table and the tool invocation log.
+// Sampled request rows count for their sample_weight.
+// It is idempotent, so overlapping refreshes are safe. It returns the number
+// of rollup rows written.
+func (s *Store) RefreshDailyRollups(ctx context.Context, since time.Time) (int64, error) {
+	if s == nil || s.db == nil {
+		return 0, errors.New("store: db cannot be nil")
+	}
+
+	res, err := s.db.ExecContext(ctx, `
+		WITH bounds AS (
+			SELECT date_trunc('day', $1::timestamptz AT TIME ZONE 'UTC') AT TIME ZONE 'UTC' AS since
+		), req AS (
+			SELECT
+				user_id,
+				(created_at AT TIME ZONE 'UTC')::date AS day,
+				SUM(sample_weight) AS request_count,
+				COALESCE(SUM(sample_weight) FILTER (WHERE status_code >= 400), 0) AS error_count,
+				COALESCE(SUM(response_time_ms::bigint * sample_weight), 0) AS total_response_ms
+			FROM requests, bounds
+			WHERE created_at >= bounds.since
+			GROUP BY user_id, day
+		), cost AS (
+			SELECT
+				user_id,
+				(created_at AT TIME ZONE 'UTC')::date AS day,
+				SUM(cost_units) AS cost_units
+			FROM tool_invocations, bounds
+			WHERE created_at >= bounds.since
+			GROUP BY user_id, day
+		)
+		INSERT INTO request_daily_rollups (user_id, day, request_count, error_count, total_response_ms, cost_units, updated_at)
+		SELECT
+			COALESCE(req.user_id, cost.user_id),
+			COALESCE(req.day, cost.day),
+			COALESCE(req.request_count, 0),
+			COALESCE(req.error_count, 0),
+			COALESCE(req.total_response_ms, 0),
+			COALESCE(cost.cost_units, 0),
+			now()
+		FROM req FULL OUTER JOIN cost ON cost.user_id = req.user_id AND cost.day = req.day
+		ON CONFLICT (user_id, day) DO UPDATE
+		SET request_count = EXCLUDED.request_count,
+		    error_count = EXCLUDED.error_count,
+		    total_response_ms = EXCLUDED.total_response_ms,
+		    cost_units = EXCLUDED.cost_units,
+		    updated_at = now()
+	`, since)
+	if err != nil {
+		return 0, fmt.Errorf("store: refresh daily rollups: %w", err)
+	}
+
+	n, _ := res.RowsAffected()
+	for _, rdb := range s.requestDatabases()[1:] {
+		written, err := s.refreshRegionalRollups(ctx, rdb.db, since)
+		if err != nil {
+			return n, fmt.Errorf("store: refresh %s rollups: %w", rdb.label, err)
+		}
+		n += written
+	}
+	return n, nil
+}
+
+// refreshRegionalRollups folds the request counts of a regional database
+// into request_daily_rollups, which only holds aggregates and stays in the
+// primary database. It runs after the primary refresh, which counts no
+// requests for users whose logs are kept in a region.
+func (s *Store) refreshRegionalRollups(ctx context.Context, db *sql.DB, since time.Time) (int64, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT
+			user_id,
+			(created_at AT TIME ZONE 'UTC')::date AS day,
+			SUM(sample_weight),
+			COALESCE(SUM(sample_weight) FILTER (WHERE status_code >= 400), 0),
+			COALESCE(SUM(response_time_ms::bigint * sample_weight), 0)
+		FROM requests
+		WHERE created_at >= date_trunc('day', $1::timestamptz AT TIME ZONE 'UTC') AT TIME ZONE 'UTC'
+		GROUP BY user_id, day
+	`, since)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	var n int64
+	for rows.Next() {
+		var (
+			userID                  int64
+			day                     time.Time
+			requests, errs, totalMs int64
+		)
+		if err := rows.Scan(&userID, &day, &requests, &errs, &totalMs); err != nil {
+			return n, err
+		}
+		if _, err := s.db.ExecContext(ctx, `
+			INSERT INTO request_daily_rollups (user_id, day, request_count, error_count, total_response_ms, cost_units, updated_at)
+			VALUES ($1, $2, $3, $4, $5, 0, now())
+			ON CONFLICT (user_id, day) DO UPDATE
+			SET request_count = EXCLUDED.request_count,
+			    error_count = EXCLUDED.error_count,
+			    total_response_ms = EXCLUDED.total_response_ms,
+			    updated_at = now()
+		`, userID, day, requests, errs, totalMs); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, rows.Err()
+}
+
+// ListDailyUsage returns the user's rollups for UTC days in [from, to],
+// oldest first. Days without requests are omitted.
+func (s *Store) ListDailyUsage(ctx context.Context, userID int64, from, to time.Time) ([]models.DailyUsage, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT day, request_count, error_count, total_response_ms, cost_units
+		FROM request_daily_rollups
+		WHERE user_id = $1 AND day BETWEEN $2::date AND $3::date
+		ORDER BY day ASC
+	`, userID, from.UTC().Format("2006-01-02"), to.UTC().Format("2006-01-02"))
+	if err != nil {
+		return nil, fmt.Errorf("store: list daily usage: %w", err)
+	}
+	defer rows.Close()
+
+	var usage []models.DailyUsage
+	for rows.Next() {
+		var u models.DailyUsage
+		if err := rows.Scan(&u.Day, &u.RequestCount, &u.ErrorCount, &u.TotalResponseMs, &u.CostUnits); err != nil {
+			return nil, fmt.Errorf("store: scan daily usage: %w", err)
+		}
+		usage = append(usage, u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("store: iterate daily usage: %w", err)
+	}
+
+	return usage, nil
+}
+
+// GetEffectivePlan returns the plan whose entitlements currently apply to the
+// user: the plan of their active (or trialing) subscription, or the free plan
+// when they have none or collection is paused.
+func (s *Store) GetEffectivePlan(ctx context.Context, userID int64) (*models.MembershipPlan, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	var p models.MembershipPlan
+	err := s.db.QueryRowContext(ctx, `
+		SELECT id, slug, name, description, tier, is_active, monthly_request_quota, monthly_cost_unit_quota, max_streaming_connections, requests_per_minute, revision, created_at, updated_at
+		FROM membership_plans
+		WHERE id = COALESCE(
+			(SELECT pv.plan_id
+			 FROM subscriptions sub
+			 JOIN plan_versions pv ON pv.stripe_price_id = sub.stripe_price_id
+			 WHERE sub.user_id = $1
+			   AND sub.status IN ('active', 'trialing')
+			   AND sub.pause_behavior IS NULL
+			 ORDER BY sub.updated_at DESC
+			 LIMIT 1),
+			(SELECT id FROM membership_plans WHERE slug = 'free')
+		)
+	`, userID).Scan(
+		&p.ID, &p.Slug, &p.Name, &p.Description,
+		&p.Tier, &p.IsActive, &p.MonthlyRequestQuota, &p.MonthlyCostUnitQuota, &p.MaxStreamingConnections, &p.RequestsPerMinute, &p.Revision, &p.CreatedAt, &p.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("store: no effective plan for user_id=%d", userID)
+		}
+		return nil, fmt.Errorf("store: get effective plan: %w", err)
+	}
+
+	return &p, nil
+}
+
+// CountPeriodRequests returns how many requests the user made since
+// periodStart, counting each logged request by its sample weight. It reads
+// the database the user's request logs are written to.
+func (s *Store) CountPeriodRequests(ctx context.Context, userID int64, periodStart time.Time) (int64, error) {
+	if s == nil || s.db == nil {
+		return 0, errors.New("store: db cannot be nil")
+	}
+
+	db, err := s.userDB(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	var count int64
+	if err := db.QueryRowContext(ctx, `
+		SELECT COALESCE(SUM(sample_weight), 0)
+		FROM requests
+		WHERE user_id = $1 AND created_at >= $2
+	`, userID, periodStart).Scan(&count); err != nil {
+		return 0, fmt.Errorf("store: count period requests: %w", err)
+	}
+	return count, nil
+}
@@ -0,0 +1,164 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+)
+
+// usageDigestTopN caps the tools and projects listed in a digest.
+const usageDigestTopN = 5
+
+// ListUsageDigestRecipients returns the IDs of users who made requests in
+// the week starting weekStart, have a verified email, have not opted out of
+// the digest and have not been sent that week's digest yet.
+func (s *Store) ListUsageDigestRecipients(ctx context.Context, weekStart time.Time) ([]int64, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT u.id
+		FROM users u
+		LEFT JOIN user_preferences p ON p.user_id = u.id
+		WHERE u.email IS NOT NULL
+		  AND u.email_verified_at IS NOT NULL
+		  AND u.deleted_at IS NULL
+		  AND COALESCE(p.usage_digest, TRUE)
+		  AND EXISTS (
+			SELECT 1 FROM request_daily_rollups r
+			WHERE r.user_id = u.id AND r.day >= $1::date AND r.day < $1::date + 7
+		  )
+		  AND NOT EXISTS (
+			SELECT 1 FROM usage_digests d WHERE d.user_id = u.id AND d.week_start = $1::date
+		  )
+		ORDER BY u.id
+	`, weekStart.UTC().Format("2006-01-02"))
+	if err != nil {
+		return nil, fmt.Errorf("store: list usage digest recipients: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("store: scan usage digest recipient: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("store: iterate usage digest recipients: %w", err)
+	}
+
+	return ids, nil
+}
+
+// GetUsageDigest builds the digest for the user's week starting weekStart
+// from the daily rollups and recorded tool invocations.
+func (s *Store) GetUsageDigest(ctx context.Context, userID int64, weekStart time.Time) (*models.UsageDigest, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	start := time.Date(weekStart.Year(), weekStart.Month(), weekStart.Day(), 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 0, 7)
+	digest := &models.UsageDigest{UserID: userID, WeekStart: start, WeekEnd: end}
+
+	if err := s.db.QueryRowContext(ctx, `SELECT email FROM users WHERE id = $1 AND deleted_at IS NULL`, userID).Scan(&digest.Email); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("store: get usage digest user: %w", err)
+	}
+
+	week, err := s.ListDailyUsage(ctx, userID, start, end.AddDate(0, 0, -1))
+	if err != nil {
+		return nil, err
+	}
+	for _, day := range week {
+		digest.Requests += day.RequestCount
+		digest.Errors += day.ErrorCount
+		digest.CostUnits += day.CostUnits
+	}
+
+	tools, err := s.ListToolCosts(ctx, userID, start, end)
+	if err != nil {
+		return nil, err
+	}
+	for _, tool := range tools {
+		digest.ToolCalls += tool.Invocations
+		digest.ToolErrors += tool.Errors
+	}
+	if len(tools) > usageDigestTopN {
+		tools = tools[:usageDigestTopN]
+	}
+	digest.TopTools = tools
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT project_key, COUNT(*), COUNT(*) FILTER (WHERE is_error)
+		FROM tool_invocations
+		WHERE user_id = $1 AND created_at >= $2 AND created_at < $3 AND project_key IS NOT NULL
+		GROUP BY project_key
+		ORDER BY 2 DESC, project_key
+		LIMIT $4
+	`, userID, start, end, usageDigestTopN)
+	if err != nil {
+		return nil, fmt.Errorf("store: list usage digest projects: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var p models.ProjectUsage
+		if err := rows.Scan(&p.ProjectKey, &p.ToolCalls, &p.Errors); err != nil {
+			return nil, fmt.Errorf("store: scan usage digest project: %w", err)
+		}
+		digest.TopProjects = append(digest.TopProjects, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("store: iterate usage digest projects: %w", err)
+	}
+
+	// Quotas are monthly, so report consumption for the month the week
+	// ended in, up to the end of the week.
+	lastDay := end.AddDate(0, 0, -1)
+	month, err := s.ListDailyUsage(ctx, userID, time.Date(lastDay.Year(), lastDay.Month(), 1, 0, 0, 0, 0, time.UTC), lastDay)
+	if err != nil {
+		return nil, err
+	}
+	for _, day := range month {
+		digest.RequestsMonthToDate += day.RequestCount
+		digest.CostUnitsMonthToDate += day.CostUnits
+	}
+
+	plan, err := s.GetEffectivePlan(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	digest.PlanName = plan.Name
+	digest.RequestQuota = plan.MonthlyRequestQuota
+	digest.CostUnitQuota = plan.MonthlyCostUnitQuota
+
+	return digest, nil
+}
+
+// MarkUsageDigestSent records that the user's digest for the week starting
+// weekStart went out.
+func (s *Store) MarkUsageDigestSent(ctx context.Context, userID int64, weekStart time.Time) error {
+	if s == nil || s.db == nil {
+		return errors.New("store: db cannot be nil")
+	}
+
+	if _, err := s.db.ExecContext(ctx, `
+		INSERT INTO usage_digests (user_id, week_start)
+		VALUES ($1, $2::date)
+		ON CONFLICT (user_id, week_start) DO NOTHING
+	`, userID, weekStart.UTC().Format("2006-01-02")); err != nil {
+		return fmt.Errorf("store: mark usage digest sent: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,283 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/events"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+)
+
+// ErrMergeSameUser is returned when asked to merge a user into itself.
+var ErrMergeSameUser = errors.New("cannot merge a user into itself")
+
+// mergeTable describes a table whose user_id column is reassigned by
+// MergeUsers. Rows whose key columns collide with one of the target's rows
+// are dropped so the target's copy wins.
+type mergeTable struct {
+	name   string
+	column string
+	key    []string
+}
+
+var mergeTables = []mergeTable{
+	{name: "users_oauths", column: "user_id"},
+	{name: "users_settings", column: "user_id", key: []string{"jira_base_url"}},
+	{name: "subscriptions", column: "user_id"},
+	{name: "payment_history", column: "user_id"},
+	{name: "integration_tokens", column: "user_id", key: []string{"provider"}},
+	{name: "announcement_reads", column: "user_id", key: []string{"announcement_id"}},
+	{name: "user_notifications", column: "user_id"},
+	{name: "debug_traces", column: "user_id"},
+	{name: "user_preferences", column: "user_id", key: []string{}},
+	{name: "jira_issue_defaults", column: "user_id", key: []string{}},
+	{name: "tool_response_limits", column: "user_id", key: []string{"tool_name"}},
+	{name: "tool_invocations", column: "user_id"},
+	{name: "requests", column: "user_id"},
+	{name: "organization_members", column: "user_id", key: []string{"org_id"}},
+	{name: "organization_join_requests", column: "user_id", key: []string{"org_id"}},
+	{name: "organization_jira_accounts", column: "updated_by"},
+	{name: "stripe_test_clocks", column: "user_id", key: []string{}},
+	{name: "login_events", column: "user_id"},
+	{name: "audit_log", column: "user_id"},
+	{name: "plan_version_assignments", column: "user_id", key: []string{"plan_id"}},
+	{name: "jira_webhook_deliveries", column: "user_id", key: []string{"delivery_id"}},
+	{name: "jira_issue_mirror", column: "user_id", key: []string{"issue_id"}},
+	{name: "tenant_shards", column: "user_id", key: []string{}},
+	{name: "mcp_sessions", column: "user_id"},
+	{name: "jira_automations", column: "user_id"},
+	{name: "report_schedules", column: "user_id"},
+	{name: "report_artifacts", column: "user_id"},
+	{name: "jira_field_options", column: "user_id", key: []string{"site_url", "project_key", "field_id", "option_id"}},
+	{name: "issue_comment_channels", column: "user_id", key: []string{"issue_key", "kind"}},
+	{name: "jira_issue_backfills", column: "user_id"},
+	{name: "api_keys", column: "created_by"},
+}
+
+// MergeUsers folds the duplicate user sourceID into targetID and deletes the
+// duplicate. Every row owned by the source is reassigned to the target;
+// where both own an equivalent row (same Jira site, token provider,
+// organization, ...) the target's is kept. Merge conflicts resolve in the
+// target's favour except that the stronger organization role survives, a
+// default Jira site is only carried over when the target has none, and the
+// source's Stripe customer, MCP secret and email verification fill gaps on
+// the target. Daily usage rollups are summed and jobs owned by the source
+// are re-pointed at the target.
+func (s *Store) MergeUsers(ctx context.Context, sourceID, targetID int64) (*models.UserMerge, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+	if sourceID == targetID {
+		return nil, ErrMergeSameUser
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("store: begin merge users tx: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	merge := &models.UserMerge{
+		SourceID: sourceID,
+		TargetID: targetID,
+		Moved:    map[string]int64{},
+		Dropped:  map[string]int64{},
+	}
+
+	rows, err := tx.QueryContext(ctx, `SELECT id, email FROM users WHERE id IN ($1, $2) AND deleted_at IS NULL ORDER BY id FOR UPDATE`, sourceID, targetID)
+	if err != nil {
+		return nil, fmt.Errorf("store: lock merged users: %w", err)
+	}
+	found := 0
+	for rows.Next() {
+		var id int64
+		var email sql.NullString
+		if err := rows.Scan(&id, &email); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("store: scan merged user: %w", err)
+		}
+		found++
+		if id == sourceID {
+			merge.SourceEmail = email.String
+		} else {
+			merge.TargetEmail = email.String
+		}
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return nil, fmt.Errorf("store: iterate merged users: %w", err)
+	}
+	rows.Close()
+	if found != 2 {
+		return nil, ErrUserNotFound
+	}
+
+	// Keep the target's default Jira site; the source's sites still move.
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE users_settings SET is_default = FALSE
+		WHERE user_id = $1 AND is_default
+		  AND EXISTS (SELECT 1 FROM users_settings WHERE user_id = $2 AND is_default AND deleted_at IS NULL)
+	`, sourceID, targetID); err != nil {
+		return nil, fmt.Errorf("store: merge default jira settings: %w", err)
+	}
+
+	// A site the target deleted must not shadow the source's live copy.
+	if _, err := tx.ExecContext(ctx, `
+		DELETE FROM users_settings dst
+		WHERE dst.user_id = $2 AND dst.deleted_at IS NOT NULL
+		  AND EXISTS (SELECT 1 FROM users_settings src
+		              WHERE src.user_id = $1 AND src.jira_base_url = dst.jira_base_url AND src.deleted_at IS NULL)
+	`, sourceID, targetID); err != nil {
+		return nil, fmt.Errorf("store: drop deleted jira settings: %w", err)
+	}
+
+	// Promote the target to the stronger of the two roles in shared
+	// organizations before the source's membership is dropped.
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE organization_members t SET role = src.role
+		FROM organization_members src
+		WHERE t.user_id = $2 AND src.user_id = $1 AND src.org_id = t.org_id
+		  AND array_position(ARRAY['member', 'admin', 'owner'], src.role) >
+		      array_position(ARRAY['member', 'admin', 'owner'], t.role)
+	`, sourceID, targetID); err != nil {
+		return nil, fmt.Errorf("store: merge organization roles: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO request_daily_rollups (user_id, day, request_count, error_count, total_response_ms, updated_at)
+		SELECT $2::bigint, day, request_count, error_count, total_response_ms, now()
+		FROM request_daily_rollups WHERE user_id = $1
+		ON CONFLICT (user_id, day) DO UPDATE SET
+			request_count = request_daily_rollups.request_count + EXCLUDED.request_count,
+			error_count = request_daily_rollups.error_count + EXCLUDED.error_count,
+			total_response_ms = request_daily_rollups.total_response_ms + EXCLUDED.total_response_ms,
+			updated_at = now()
+	`, sourceID, targetID); err != nil {
+		return nil, fmt.Errorf("store: merge usage rollups: %w", err)
+	}
+	result, err := tx.ExecContext(ctx, `DELETE FROM request_daily_rollups WHERE user_id = $1`, sourceID)
+	if err != nil {
+		return nil, fmt.Errorf("store: clear merged usage rollups: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n > 0 {
+		merge.Moved["request_daily_rollups"] = n
+	}
+
+	// Unconsumed links were issued for the source's address and are useless
+	// once it is gone.
+	if _, err := tx.ExecContext(ctx, `DELETE FROM email_verification_tokens WHERE user_id = $1`, sourceID); err != nil {
+		return nil, fmt.Errorf("store: clear merged verification tokens: %w", err)
+	}
+
+	for _, t := range mergeTables {
+		if t.key != nil {
+			result, err := tx.ExecContext(ctx, mergeConflictQuery(t), sourceID, targetID)
+			if err != nil {
+				return nil, fmt.Errorf("store: drop conflicting %s: %w", t.name, err)
+			}
+			if n, _ := result.RowsAffected(); n > 0 {
+				merge.Dropped[t.name] = n
+			}
+		}
+		result, err := tx.ExecContext(ctx, fmt.Sprintf(`UPDATE %s SET %s = $2 WHERE %s = $1`, t.name, t.column, t.column), sourceID, targetID)
+		if err != nil {
+			return nil, fmt.Errorf("store: reassign %s: %w", t.name, err)
+		}
+		if n, _ := result.RowsAffected(); n > 0 {
+			merge.Moved[t.name] = n
+		}
+	}
+
+	result, err = tx.ExecContext(ctx, `
+		UPDATE jobs SET
+			metadata = CASE WHEN metadata->>'user_id' = $1::text
+				THEN jsonb_set(metadata, '{user_id}', to_jsonb($2::bigint)) ELSE metadata END,
+			payload = CASE WHEN payload->>'user_id' = $1::text
+				THEN jsonb_set(payload, '{user_id}', to_jsonb($2::bigint)) ELSE payload END,
+			updated_at = now()
+		WHERE metadata->>'user_id' = $1::text OR payload->>'user_id' = $1::text
+	`, sourceID, targetID)
+	if err != nil {
+		return nil, fmt.Errorf("store: reassign jobs: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n > 0 {
+		merge.Moved["jobs"] = n
+	}
+
+	// The MCP secret is unique, so clear it on the source before the target
+	// can adopt it.
+	var secret, customerID sql.NullString
+	var verifiedAt sql.NullTime
+	if err := tx.QueryRowContext(ctx, `
+		UPDATE users u SET mcp_secret = NULL, stripe_customer_id = NULL
+		FROM (SELECT id, mcp_secret, stripe_customer_id, email_verified_at FROM users WHERE id = $1) old
+		WHERE u.id = old.id
+		RETURNING old.mcp_secret, old.stripe_customer_id, old.email_verified_at
+	`, sourceID).Scan(&secret, &customerID, &verifiedAt); err != nil {
+		return nil, fmt.Errorf("store: detach merged user identifiers: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE users SET
+			mcp_secret = COALESCE(mcp_secret, $2),
+			stripe_customer_id = COALESCE(stripe_customer_id, $3),
+			email_verified_at = CASE WHEN LOWER(email) = LOWER($5)
+				THEN COALESCE(email_verified_at, $4) ELSE email_verified_at END,
+			updated_at = now()
+		WHERE id = $1
+	`, targetID, secret, customerID, verifiedAt, merge.SourceEmail); err != nil {
+		return nil, fmt.Errorf("store: merge user identifiers: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM users WHERE id = $1`, sourceID); err != nil {
+		return nil, fmt.Errorf("store: delete merged user: %w", err)
+	}
+
+	if err := s.enqueueEvent(ctx, tx, events.UsersMerged{
+		SourceUserID: sourceID,
+		SourceEmail:  merge.SourceEmail,
+		TargetUserID: targetID,
+		Moved:        merge.Moved,
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("store: commit merge users tx: %w", err)
+	}
+
+	// Request logs kept in regional and shard databases are outside the
+	// transaction.
+	for _, rdb := range s.requestDatabases()[1:] {
+		result, err := rdb.db.ExecContext(ctx, `UPDATE requests SET user_id = $2 WHERE user_id = $1`, sourceID, targetID)
+		if err != nil {
+			return nil, fmt.Errorf("store: reassign requests in %s: %w", rdb.label, err)
+		}
+		if n, _ := result.RowsAffected(); n > 0 {
+			merge.Moved["requests"] += n
+		}
+	}
+	if err := s.ReconcileUserDataRegion(ctx, targetID); err != nil {
+		return nil, err
+	}
+
+	s.wakeOutbox()
+
+	return merge, nil
+}
+
+// mergeConflictQuery deletes the source's rows in t that collide with one of
+// the target's rows on t's key columns. An empty key means the table holds
+// at most one row per user.
+func mergeConflictQuery(t mergeTable) string {
+	match := []string{"dst." + t.column + " = $2"}
+	for _, col := range t.key {
+		match = append(match, fmt.Sprintf("dst.%s = src.%s", col, col))
+	}
+	return fmt.Sprintf(`DELETE FROM %s src WHERE src.%s = $1 AND EXISTS (SELECT 1 FROM %s dst WHERE %s)`,
+		t.name, t.column, t.name, strings.Join(match, " AND "))
+}
@@ -0,0 +1,282 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/events"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+)
+
+// ErrAccountDeleted is returned when signing in to an account that was
+// deleted and not yet purged.
+var ErrAccountDeleted = errors.New("account is deleted")
+
+// accountTables hold a user's data by user_id and are emptied when the
+// account is purged, in an order foreign keys allow. Requests are also
+// removed from regional and shard databases.
+var accountTables = []string{"payment_history", "subscriptions", "users_settings", "users_oauths", "requests"}
+
+// DeleteUser deletes the user with the given email address and counts the
+// data retained with it. The account and its data are kept for
+// SoftDeleteRetention, during which it can be restored; every lookup treats
+// it as gone in the meantime. Its sessions are left alone so a deletion
+// undone straight away does not sign the user out; revoke them with
+// RevokeAllUserSessions once the deletion stands.
+func (s *Store) DeleteUser(ctx context.Context, email string) (*models.AccountDeletion, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("store: begin delete user tx: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	var (
+		userID    int64
+		deletedAt time.Time
+	)
+	err = tx.QueryRowContext(ctx, `
+		UPDATE users SET deleted_at = now(), updated_at = now()
+		WHERE LOWER(email) = LOWER($1) AND deleted_at IS NULL
+		RETURNING id, deleted_at
+	`, email).Scan(&userID, &deletedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("store: delete user: %w", err)
+	}
+
+	purgeAfter := deletedAt.Add(SoftDeleteRetention)
+	deletion := &models.AccountDeletion{UserID: userID, Email: email, Retained: map[string]int64{}, PurgeAfter: &purgeAfter}
+	for _, table := range accountTables {
+		var n int64
+		if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM `+table+` WHERE user_id = $1`, userID).Scan(&n); err != nil {
+			return nil, fmt.Errorf("store: count %s: %w", table, err)
+		}
+		deletion.Retained[table] = n
+	}
+	for _, rdb := range s.requestDatabases()[1:] {
+		var n int64
+		if err := rdb.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM requests WHERE user_id = $1`, userID).Scan(&n); err != nil {
+			return nil, fmt.Errorf("store: count requests in %s: %w", rdb.label, err)
+		}
+		deletion.Retained["requests"] += n
+	}
+
+	if err := s.enqueueEvent(ctx, tx, events.UserDeleted{UserID: userID, Email: email}); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("store: commit delete user tx: %w", err)
+	}
+
+	s.wakeOutbox()
+
+	return deletion, nil
+}
+
+// RestoreUser undoes DeleteUser within the retention window.
+func (s *Store) RestoreUser(ctx context.Context, email string) error {
+	if s == nil || s.db == nil {
+		return errors.New("store: db cannot be nil")
+	}
+
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE users SET deleted_at = NULL, updated_at = now()
+		WHERE LOWER(email) = LOWER($1)
+		  AND deleted_at > now() - make_interval(secs => $2)
+	`, email, SoftDeleteRetention.Seconds())
+	if err != nil {
+		return fmt.Errorf("store: restore user: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// ListDeletedUsers returns the deleted accounts that can still be restored,
+// most recently deleted first.
+func (s *Store) ListDeletedUsers(ctx context.Context) ([]models.DeletedUser, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, email, name, deleted_at
+		FROM users
+		WHERE deleted_at > now() - make_interval(secs => $1)
+		ORDER BY deleted_at DESC
+		LIMIT $2
+	`, SoftDeleteRetention.Seconds(), defaultPageSize)
+	if err != nil {
+		return nil, fmt.Errorf("store: list deleted users: %w", err)
+	}
+	defer rows.Close()
+
+	deleted := []models.DeletedUser{}
+	for rows.Next() {
+		var (
+			d           models.DeletedUser
+			email, name sql.NullString
+		)
+		if err := rows.Scan(&d.ID, &email, &name, &d.DeletedAt); err != nil {
+			return nil, fmt.Errorf("store: scan deleted users: %w", err)
+		}
+		d.Email, d.Name = nullStringPtr(email), nullStringPtr(name)
+		d.PurgeAfter = d.DeletedAt.Add(SoftDeleteRetention)
+		deleted = append(deleted, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("store: iterate deleted users: %w", err)
+	}
+	return deleted, nil
+}
+
+// PurgeUser removes the deleted account with the given email address and
+// all its data now, without waiting for the retention window to pass, and
+// summarises what it removed.
+func (s *Store) PurgeUser(ctx context.Context, email string) (*models.AccountDeletion, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store: db cannot be nil")
+	}
+
+	var userID int64
+	err := s.db.QueryRowContext(ctx,
+		`SELECT id FROM users WHERE LOWER(email) = LOWER($1) AND deleted_at IS NOT NULL`,
+		email).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("store: get deleted user id: %w", err)
+	}
+	removed, err := s.purgeUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &models.AccountDeletion{UserID: userID, Email: email, Removed: removed}, nil
+}
+
+// PurgeDeletedUsers removes accounts deleted more than retention ago and
+// their data, and returns how many it removed.
+func (s *Store) PurgeDeletedUsers(ctx context.Context, retention time.Duration) (int64, error) {
+	if s == nil || s.db == nil {
+		return 0, errors.New("store: db cannot be nil")
+	}
+
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT id FROM users WHERE deleted_at < now() - make_interval(secs => $1)`,
+		retention.Seconds())
+	if err != nil {
+		return 0, fmt.Errorf("store: list purgeable users: %w", err)
+	}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("store: scan purgeable users: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("store: iterate purgeable users: %w", err)
+	}
+
+	var purged int64
+	for _, id := range ids {
+		if _, err := s.purgeUser(ctx, id); errors.Is(err, ErrUserNotFound) {
+			continue
+		} else if err != nil {
+			return purged, err
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+// purgeUser deletes a deleted user and all associated data, and returns
+// the rows removed per table, or ErrUserNotFound if the user was restored
+// since it was listed. Request logs in regional and shard databases
+// are deleted in a transaction on each, committed just before the one on
+// the primary database: a failure before then leaves everything in place,
+// and a failed primary commit leaves the user to be purged again.
+func (s *Store) purgeUser(ctx context.Context, userID int64) (map[string]int64, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("store: begin purge user tx: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	// The user row stays locked until the purge commits, so a concurrent
+	// RestoreUser either lands first and stops the purge, or waits for it
+	// and finds nothing to restore.
+	if err := tx.QueryRowContext(ctx,
+		`SELECT id FROM users WHERE id = $1 AND deleted_at IS NOT NULL FOR UPDATE`, userID,
+	).Scan(&userID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("store: lock deleted user: %w", err)
+	}
+
+	removed := map[string]int64{}
+	// Most of these tables cascade from users, but deleting them explicitly
+	// tells how much was removed.
+	for _, table := range accountTables {
+		result, err := tx.ExecContext(ctx, `DELETE FROM `+table+` WHERE user_id = $1`, userID)
+		if err != nil {
+			return nil, fmt.Errorf("store: delete %s: %w", table, err)
+		}
+		n, _ := result.RowsAffected()
+		removed[table] += n
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM users WHERE id = $1 AND deleted_at IS NOT NULL`, userID); err != nil {
+		return nil, fmt.Errorf("store: delete user: %w", err)
+	}
+
+	var requestTxs []*sql.Tx
+	defer func() {
+		for _, rtx := range requestTxs {
+			_ = rtx.Rollback()
+		}
+	}()
+	for _, rdb := range s.requestDatabases()[1:] {
+		rtx, err := rdb.db.BeginTx(ctx, nil)
+		if err != nil {
+			return nil, fmt.Errorf("store: begin purge requests tx in %s: %w", rdb.label, err)
+		}
+		requestTxs = append(requestTxs, rtx)
+		result, err := rtx.ExecContext(ctx, `DELETE FROM requests WHERE user_id = $1`, userID)
+		if err != nil {
+			return nil, fmt.Errorf("store: delete requests in %s: %w", rdb.label, err)
+		}
+		n, _ := result.RowsAffected()
+		removed["requests"] += n
+	}
+	for _, rtx := range requestTxs {
+		if err := rtx.Commit(); err != nil {
+			return nil, fmt.Errorf("store: commit purge requests tx: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("store: commit purge user tx: %w", err)
+	}
+	return removed, nil
+}
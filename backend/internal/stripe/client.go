@@ -3,12 +3,15 @@ package stripe
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // Client wraps Stripe API calls using the REST API directly (no SDK dependency)
@@ -27,15 +30,34 @@ func NewClient(secretKey string) *Client {
 	}
 }
 
+// CheckoutSessionParams describes a subscription Checkout session. When
+// CustomerID is set the session is attached to that existing customer;
+// otherwise CustomerEmail is used and Stripe creates a new customer.
+type CheckoutSessionParams struct {
+	CustomerID        string
+	CustomerEmail     string
+	ClientReferenceID string
+	PriceID           string
+	SuccessURL        string
+	CancelURL         string
+}
+
 // CreateCheckoutSession creates a Stripe Checkout session for a subscription
-func (c *Client) CreateCheckoutSession(customerEmail, priceID, successURL, cancelURL string) (sessionID, sessionURL string, err error) {
+func (c *Client) CreateCheckoutSession(params CheckoutSessionParams) (sessionID, sessionURL string, err error) {
 	data := url.Values{}
 	data.Set("mode", "subscription")
-	data.Set("customer_email", customerEmail)
-	data.Set("line_items[0][price]", priceID)
+	if params.CustomerID != "" {
+		data.Set("customer", params.CustomerID)
+	} else {
+		data.Set("customer_email", params.CustomerEmail)
+	}
+	if params.ClientReferenceID != "" {
+		data.Set("client_reference_id", params.ClientReferenceID)
+	}
+	data.Set("line_items[0][price]", params.PriceID)
 	data.Set("line_items[0][quantity]", "1")
-	data.Set("success_url", successURL)
-	data.Set("cancel_url", cancelURL)
+	data.Set("success_url", params.SuccessURL)
+	data.Set("cancel_url", params.CancelURL)
 
 	resp, err := c.post("/checkout/sessions", data)
 	if err != nil {
@@ -53,56 +75,253 @@ func (c *Client) CreateCheckoutSession(customerEmail, priceID, successURL, cance
 
 // UpdateSubscriptionPrice migrates a subscription to a new price (for plan version migration)
 func (c *Client) UpdateSubscriptionPrice(subscriptionID, newPriceID string) error {
-	// First, get the subscription to find the current item ID
-	sub, err := c.get("/subscriptions/" + subscriptionID)
+	return c.updateSubscriptionPrice(subscriptionID, newPriceID, "create_prorations")
+}
+
+// UpdateSubscriptionPriceAtRenewal migrates a subscription to a new price
+// without prorating, so the current period stays billed at the old price
+// and the new price is charged from the next renewal.
+func (c *Client) UpdateSubscriptionPriceAtRenewal(subscriptionID, newPriceID string) error {
+	return c.updateSubscriptionPrice(subscriptionID, newPriceID, "none")
+}
+
+func (c *Client) updateSubscriptionPrice(subscriptionID, newPriceID, prorationBehavior string) error {
+	itemID, err := c.firstSubscriptionItemID(subscriptionID)
 	if err != nil {
 		return fmt.Errorf("get subscription for migration: %w", err)
 	}
 
-	// Extract the first subscription item ID
+	// Update the subscription with the new price
+	data := url.Values{}
+	data.Set("items[0][id]", itemID)
+	data.Set("items[0][price]", newPriceID)
+	data.Set("proration_behavior", prorationBehavior)
+
+	_, err = c.post("/subscriptions/"+subscriptionID, data)
+	if err != nil {
+		return fmt.Errorf("update subscription price: %w", err)
+	}
+
+	log.Printf("[stripe] Migrated subscription %s to price %s", subscriptionID, newPriceID)
+	return nil
+}
+
+// UpdateSubscriptionQuantity sets the seat count billed on a subscription,
+// prorating the change.
+func (c *Client) UpdateSubscriptionQuantity(subscriptionID string, quantity int) error {
+	itemID, err := c.firstSubscriptionItemID(subscriptionID)
+	if err != nil {
+		return fmt.Errorf("get subscription for quantity update: %w", err)
+	}
+
+	data := url.Values{}
+	data.Set("items[0][id]", itemID)
+	data.Set("items[0][quantity]", strconv.Itoa(quantity))
+	data.Set("proration_behavior", "create_prorations")
+
+	_, err = c.post("/subscriptions/"+subscriptionID, data)
+	if err != nil {
+		return fmt.Errorf("update subscription quantity: %w", err)
+	}
+
+	log.Printf("[stripe] Updated subscription %s to %d seats", subscriptionID, quantity)
+	return nil
+}
+
+// firstSubscriptionItemID returns the ID of the subscription's first item.
+func (c *Client) firstSubscriptionItemID(subscriptionID string) (string, error) {
+	sub, err := c.get("/subscriptions/" + subscriptionID)
+	if err != nil {
+		return "", err
+	}
+
 	items, ok := sub["items"].(map[string]interface{})
 	if !ok {
-		return fmt.Errorf("unexpected subscription items format")
+		return "", fmt.Errorf("unexpected subscription items format")
 	}
 	dataArr, ok := items["data"].([]interface{})
 	if !ok || len(dataArr) == 0 {
-		return fmt.Errorf("no subscription items found")
+		return "", fmt.Errorf("no subscription items found")
 	}
 	firstItem, ok := dataArr[0].(map[string]interface{})
 	if !ok {
-		return fmt.Errorf("unexpected subscription item format")
+		return "", fmt.Errorf("unexpected subscription item format")
 	}
 	itemID, ok := firstItem["id"].(string)
 	if !ok {
-		return fmt.Errorf("missing subscription item ID")
+		return "", fmt.Errorf("missing subscription item ID")
 	}
+	return itemID, nil
+}
 
-	// Update the subscription with the new price
-	data := url.Values{}
-	data.Set("items[0][id]", itemID)
-	data.Set("items[0][price]", newPriceID)
-	data.Set("proration_behavior", "create_prorations")
+// CancelOptions controls how CancelSubscription ends a subscription.
+type CancelOptions struct {
+	// AtPeriodEnd keeps the subscription until the paid period ends.
+	AtPeriodEnd bool
+	// RefundUnused refunds the unused part of the paid period, prorated to
+	// the second, when the subscription is cancelled immediately.
+	RefundUnused bool
+}
 
-	_, err = c.post("/subscriptions/"+subscriptionID, data)
+// Cancellation is the outcome of CancelSubscription. RefundID is empty when
+// nothing was refunded.
+type Cancellation struct {
+	SubscriptionID string
+	RefundID       string
+	RefundCents    int64
+	Currency       string
+}
+
+// CancelSubscription cancels a Stripe subscription. When the refund of an
+// immediate cancellation fails, the subscription stays cancelled and the
+// Cancellation is returned along with the error.
+func (c *Client) CancelSubscription(subscriptionID string, opts CancelOptions) (*Cancellation, error) {
+	cancellation := &Cancellation{SubscriptionID: subscriptionID}
+	if opts.AtPeriodEnd {
+		data := url.Values{}
+		data.Set("cancel_at_period_end", "true")
+		if _, err := c.post("/subscriptions/"+subscriptionID, data); err != nil {
+			return nil, fmt.Errorf("cancel subscription: %w", err)
+		}
+		return cancellation, nil
+	}
+
+	// The paid period has to be read before cancelling ends it.
+	var refund unusedTime
+	if opts.RefundUnused {
+		sub, err := c.get("/subscriptions/" + subscriptionID + "?expand[]=latest_invoice")
+		if err != nil {
+			return nil, fmt.Errorf("get subscription for refund: %w", err)
+		}
+		refund = parseUnusedTime(sub)
+	}
+
+	if _, err := c.delete("/subscriptions/" + subscriptionID); err != nil {
+		return nil, fmt.Errorf("cancel subscription: %w", err)
+	}
+	log.Printf("[stripe] Cancelled subscription %s", subscriptionID)
+
+	cents := refund.cents(time.Now())
+	if cents <= 0 || (refund.paymentIntent == "" && refund.charge == "") {
+		return cancellation, nil
+	}
+	data := url.Values{}
+	if refund.paymentIntent != "" {
+		data.Set("payment_intent", refund.paymentIntent)
+	} else {
+		data.Set("charge", refund.charge)
+	}
+	data.Set("amount", strconv.FormatInt(cents, 10))
+	data.Set("reason", "requested_by_customer")
+	data.Set("metadata[subscription]", subscriptionID)
+	resp, err := c.post("/refunds", data)
 	if err != nil {
-		return fmt.Errorf("update subscription price: %w", err)
+		return cancellation, fmt.Errorf("refund unused subscription time: %w", err)
 	}
+	cancellation.RefundID, _ = resp["id"].(string)
+	cancellation.RefundCents = cents
+	cancellation.Currency = refund.currency
+	log.Printf("[stripe] Refunded %d %s of unused time on subscription %s", cents, refund.currency, subscriptionID)
+	return cancellation, nil
+}
 
-	log.Printf("[stripe] Migrated subscription %s to price %s", subscriptionID, newPriceID)
+// unusedTime is what was paid for a subscription's current period and the
+// payment to refund it from.
+type unusedTime struct {
+	paidCents     int64
+	currency      string
+	paymentIntent string
+	charge        string
+	start, end    time.Time
+}
+
+// parseUnusedTime reads the current period and its latest paid invoice from
+// a subscription fetched with its latest_invoice expanded. Newer API
+// versions keep the period on the subscription items.
+func parseUnusedTime(sub map[string]interface{}) unusedTime {
+	var u unusedTime
+	periodOf := func(obj map[string]interface{}) bool {
+		start, okStart := obj["current_period_start"].(float64)
+		end, okEnd := obj["current_period_end"].(float64)
+		if okStart && okEnd {
+			u.start, u.end = time.Unix(int64(start), 0), time.Unix(int64(end), 0)
+		}
+		return okStart && okEnd
+	}
+	if !periodOf(sub) {
+		items, _ := sub["items"].(map[string]interface{})
+		data, _ := items["data"].([]interface{})
+		if len(data) > 0 {
+			if item, ok := data[0].(map[string]interface{}); ok {
+				periodOf(item)
+			}
+		}
+	}
+
+	invoice, _ := sub["latest_invoice"].(map[string]interface{})
+	if paid, ok := invoice["amount_paid"].(float64); ok {
+		u.paidCents = int64(paid)
+	}
+	u.currency, _ = invoice["currency"].(string)
+	u.paymentIntent = expandableID(invoice["payment_intent"])
+	u.charge = expandableID(invoice["charge"])
+	return u
+}
+
+// cents is the share of the paid amount for the part of the period left at
+// now, rounded down.
+func (u unusedTime) cents(now time.Time) int64 {
+	period := u.end.Sub(u.start)
+	left := u.end.Sub(now)
+	if u.paidCents <= 0 || period <= 0 || left <= 0 {
+		return 0
+	}
+	left = min(left, period)
+	return u.paidCents * int64(left/time.Second) / int64(period/time.Second)
+}
+
+// expandableID is the ID of a Stripe field that is either an ID or, when
+// expanded, the object itself.
+func expandableID(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case map[string]interface{}:
+		id, _ := v["id"].(string)
+		return id
+	}
+	return ""
+}
+
+// PauseSubscription pauses payment collection on a subscription. behavior must
+// be one of keep_as_draft, mark_uncollectible or void. When resumesAt is set,
+// Stripe automatically resumes collection at that time.
+func (c *Client) PauseSubscription(subscriptionID, behavior string, resumesAt *time.Time) error {
+	data := url.Values{}
+	data.Set("pause_collection[behavior]", behavior)
+	if resumesAt != nil {
+		data.Set("pause_collection[resumes_at]", strconv.FormatInt(resumesAt.Unix(), 10))
+	}
+
+	if _, err := c.post("/subscriptions/"+subscriptionID, data); err != nil {
+		return fmt.Errorf("pause subscription: %w", err)
+	}
+
+	log.Printf("[stripe] Paused collection for subscription %s (behavior: %s)", subscriptionID, behavior)
 	return nil
 }
 
-// CancelSubscription cancels a Stripe subscription
-func (c *Client) CancelSubscription(subscriptionID string, atPeriodEnd bool) error {
-	if atPeriodEnd {
-		data := url.Values{}
-		data.Set("cancel_at_period_end", "true")
-		_, err := c.post("/subscriptions/"+subscriptionID, data)
-		return err
+// ResumeSubscription clears pause_collection so Stripe resumes invoicing.
+func (c *Client) ResumeSubscription(subscriptionID string) error {
+	data := url.Values{}
+	data.Set("pause_collection", "")
+
+	if _, err := c.post("/subscriptions/"+subscriptionID, data); err != nil {
+		return fmt.Errorf("resume subscription: %w", err)
 	}
 
-	_, err := c.delete("/subscriptions/" + subscriptionID)
-	return err
+	log.Printf("[stripe] Resumed collection for subscription %s", subscriptionID)
+	return nil
 }
 
 // ArchiveProduct archives a Stripe product (marks it inactive)
@@ -133,6 +352,51 @@ func (c *Client) ArchivePrice(priceID string) error {
 	return nil
 }
 
+// CreateCustomer creates a Stripe customer. When testClockID is set the
+// customer is attached to that test clock.
+func (c *Client) CreateCustomer(email, name, testClockID string) (string, error) {
+	data := url.Values{}
+	data.Set("email", email)
+	if name != "" {
+		data.Set("name", name)
+	}
+	if testClockID != "" {
+		data.Set("test_clock", testClockID)
+	}
+
+	resp, err := c.post("/customers", data)
+	if err != nil {
+		return "", fmt.Errorf("create customer: %w", err)
+	}
+
+	customerID, _ := resp["id"].(string)
+	if customerID == "" {
+		return "", fmt.Errorf("create customer: missing customer ID in response")
+	}
+	return customerID, nil
+}
+
+// IsTestMode reports whether the client uses a test mode API key. Test
+// clocks are only available in test mode.
+func (c *Client) IsTestMode() bool {
+	return strings.HasPrefix(c.secretKey, "sk_test_") || strings.HasPrefix(c.secretKey, "rk_test_")
+}
+
+// ErrInvalidKey is returned by VerifyKey when Stripe rejects the secret key.
+var ErrInvalidKey = errors.New("stripe: secret key rejected")
+
+// VerifyKey makes a read-only call to check that Stripe accepts the secret
+// key. A rejected key returns ErrInvalidKey; other failures, such as Stripe
+// being unreachable, are returned as they are.
+func (c *Client) VerifyKey() error {
+	_, err := c.get("/balance")
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && (apiErr.StatusCode == http.StatusUnauthorized || apiErr.StatusCode == http.StatusForbidden) {
+		return fmt.Errorf("%w: %s", ErrInvalidKey, apiErr.Message)
+	}
+	return err
+}
+
 // CreateProduct creates a new Stripe product
 func (c *Client) CreateProduct(name, description string) (string, error) {
 	data := url.Values{}
@@ -167,8 +431,8 @@ func (c *Client) CreatePrice(productID string, unitAmountCents int, currency, in
 	return priceID, nil
 }
 
-// ConstructWebhookEvent parses and returns the raw event body
-// In production, you should verify the webhook signature using the signing secret
+// ConstructWebhookEvent parses and returns the raw event body. Verify the
+// signature with VerifyWebhookSignature first.
 func ConstructWebhookEvent(body []byte) (map[string]interface{}, error) {
 	var event map[string]interface{}
 	if err := json.Unmarshal(body, &event); err != nil {
@@ -235,8 +499,36 @@ func (c *Client) doRequest(req *http.Request) (map[string]interface{}, error) {
 				msg = m
 			}
 		}
-		return nil, fmt.Errorf("stripe API error (%d): %s", resp.StatusCode, msg)
+		apiErr := &APIError{StatusCode: resp.StatusCode, Message: msg}
+		if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && secs > 0 {
+			apiErr.RetryAfterDelay = time.Duration(secs) * time.Second
+		}
+		return nil, apiErr
 	}
 
 	return result, nil
 }
+
+// APIError is an error response from the Stripe API
+type APIError struct {
+	StatusCode int
+	Message    string
+	// RetryAfterDelay is the response's Retry-After hint, zero when absent
+	RetryAfterDelay time.Duration
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("stripe API error (%d): %s", e.StatusCode, e.Message)
+}
+
+// RateLimited reports whether Stripe rejected the request for exceeding
+// its rate limit
+func (e *APIError) RateLimited() bool {
+	return e.StatusCode == http.StatusTooManyRequests
+}
+
+// RetryAfter returns how long to wait before retrying a rate-limited
+// request, for job backoff
+func (e *APIError) RetryAfter() time.Duration {
+	return e.RetryAfterDelay
+}
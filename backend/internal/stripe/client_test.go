@@ -0,0 +1,117 @@
+package stripe
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestUpdateSubscriptionPriceProration(t *testing.T) {
+	var prorations []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/subscriptions/sub_1":
+			w.Write([]byte(`{"id":"sub_1","items":{"data":[{"id":"si_1"}]}}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/subscriptions/sub_1":
+			r.ParseForm()
+			if r.PostForm.Get("items[0][id]") != "si_1" || r.PostForm.Get("items[0][price]") != "price_new" {
+				t.Errorf("unexpected update: %v", r.PostForm)
+			}
+			prorations = append(prorations, r.PostForm.Get("proration_behavior"))
+			w.Write([]byte(`{"id":"sub_1"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	c := NewClient("sk_test")
+	c.baseURL = server.URL
+
+	if err := c.UpdateSubscriptionPrice("sub_1", "price_new"); err != nil {
+		t.Fatalf("UpdateSubscriptionPrice: %v", err)
+	}
+	if err := c.UpdateSubscriptionPriceAtRenewal("sub_1", "price_new"); err != nil {
+		t.Fatalf("UpdateSubscriptionPriceAtRenewal: %v", err)
+	}
+	if len(prorations) != 2 || prorations[0] != "create_prorations" || prorations[1] != "none" {
+		t.Fatalf("unexpected proration behaviors: %v", prorations)
+	}
+}
+
+func TestCancelSubscriptionRefundsUnusedTime(t *testing.T) {
+	now := time.Now()
+	start, end := now.Add(-10*24*time.Hour), now.Add(20*24*time.Hour)
+	var cancelled bool
+	var refundAmount int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/subscriptions/sub_1":
+			if r.URL.Query().Get("expand[]") != "latest_invoice" {
+				t.Errorf("expected the latest invoice expanded, got %s", r.URL.RawQuery)
+			}
+			fmt.Fprintf(w, `{"id":"sub_1","items":{"data":[{"current_period_start":%d,"current_period_end":%d}]},
+				"latest_invoice":{"amount_paid":3000,"currency":"usd","payment_intent":"pi_1"}}`, start.Unix(), end.Unix())
+		case r.Method == http.MethodDelete && r.URL.Path == "/subscriptions/sub_1":
+			cancelled = true
+			w.Write([]byte(`{"id":"sub_1","status":"canceled"}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/refunds":
+			r.ParseForm()
+			if r.PostForm.Get("payment_intent") != "pi_1" {
+				t.Errorf("unexpected refund: %v", r.PostForm)
+			}
+			refundAmount, _ = strconv.ParseInt(r.PostForm.Get("amount"), 10, 64)
+			w.Write([]byte(`{"id":"re_1"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	c := NewClient("sk_test")
+	c.baseURL = server.URL
+
+	cancellation, err := c.CancelSubscription("sub_1", CancelOptions{RefundUnused: true})
+	if err != nil {
+		t.Fatalf("CancelSubscription: %v", err)
+	}
+	if !cancelled {
+		t.Fatal("expected the subscription to be cancelled")
+	}
+	// Two thirds of the period were left.
+	if refundAmount < 1999 || refundAmount > 2000 || cancellation.RefundCents != refundAmount ||
+		cancellation.RefundID != "re_1" || cancellation.Currency != "usd" {
+		t.Fatalf("unexpected refund of %d: %+v", refundAmount, cancellation)
+	}
+}
+
+func TestVerifyKey(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if user, _, _ := r.BasicAuth(); user != "sk_test_good" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error":{"message":"Invalid API Key provided"}}`))
+			return
+		}
+		w.Write([]byte(`{"object":"balance"}`))
+	}))
+	defer server.Close()
+
+	good := NewClient("sk_test_good")
+	good.baseURL = server.URL
+	if err := good.VerifyKey(); err != nil {
+		t.Fatalf("VerifyKey: %v", err)
+	}
+
+	bad := NewClient("sk_test_bad")
+	bad.baseURL = server.URL
+	if err := bad.VerifyKey(); !errors.Is(err, ErrInvalidKey) {
+		t.Fatalf("expected ErrInvalidKey, got %v", err)
+	}
+}
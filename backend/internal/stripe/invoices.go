@@ -0,0 +1,87 @@
+package stripe
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// ChangePreview is Stripe's upcoming invoice for a subscription as if its
+// price were changed at ProrationDate. ProrationCents is what the change
+// itself costs: the charge for the remainder of the period on the new price
+// minus the credit for the unused time on the old one, negative when the
+// change leaves a credit. Prorations are invoiced with the next invoice,
+// whose total is NextInvoiceCents.
+type ChangePreview struct {
+	Currency         string    `json:"currency"`
+	ProrationCents   int64     `json:"proration_cents"`
+	NextInvoiceCents int64     `json:"next_invoice_cents"`
+	AmountDueCents   int64     `json:"amount_due_cents"`
+	ProrationDate    time.Time `json:"proration_date"`
+	PeriodEnd        time.Time `json:"period_end"`
+}
+
+// PreviewSubscriptionChange asks Stripe's upcoming invoice API what moving
+// the subscription to newPriceID at prorationDate would be billed, without
+// changing the subscription. The proration date is echoed back so the same
+// instant can be used when the change is applied.
+func (c *Client) PreviewSubscriptionChange(subscriptionID, newPriceID string, prorationDate time.Time) (*ChangePreview, error) {
+	itemID, err := c.firstSubscriptionItemID(subscriptionID)
+	if err != nil {
+		return nil, fmt.Errorf("get subscription for change preview: %w", err)
+	}
+
+	query := url.Values{}
+	query.Set("subscription", subscriptionID)
+	query.Set("subscription_items[0][id]", itemID)
+	query.Set("subscription_items[0][price]", newPriceID)
+	query.Set("subscription_proration_behavior", "create_prorations")
+	query.Set("subscription_proration_date", strconv.FormatInt(prorationDate.Unix(), 10))
+
+	resp, err := c.get("/invoices/upcoming?" + query.Encode())
+	if err != nil {
+		return nil, fmt.Errorf("preview subscription change: %w", err)
+	}
+
+	return parseChangePreview(resp, prorationDate), nil
+}
+
+func parseChangePreview(invoice map[string]interface{}, prorationDate time.Time) *ChangePreview {
+	preview := &ChangePreview{ProrationDate: prorationDate.UTC()}
+	preview.Currency, _ = invoice["currency"].(string)
+	if total, ok := invoice["total"].(float64); ok {
+		preview.NextInvoiceCents = int64(total)
+	}
+	if due, ok := invoice["amount_due"].(float64); ok {
+		preview.AmountDueCents = int64(due)
+	}
+	if end, ok := invoice["period_end"].(float64); ok {
+		preview.PeriodEnd = time.Unix(int64(end), 0).UTC()
+	}
+
+	lines, _ := invoice["lines"].(map[string]interface{})
+	data, _ := lines["data"].([]interface{})
+	for _, raw := range data {
+		line, ok := raw.(map[string]interface{})
+		if !ok || line["proration"] != true {
+			continue
+		}
+		if amount, ok := line["amount"].(float64); ok {
+			preview.ProrationCents += int64(amount)
+		}
+	}
+
+	return preview
+}
+
+// GetChargeCustomer returns the ID of the customer a charge was made to,
+// empty for charges without a customer.
+func (c *Client) GetChargeCustomer(chargeID string) (string, error) {
+	charge, err := c.get("/charges/" + url.PathEscape(chargeID))
+	if err != nil {
+		return "", fmt.Errorf("get charge: %w", err)
+	}
+	customerID, _ := charge["customer"].(string)
+	return customerID, nil
+}
@@ -0,0 +1,48 @@
+package stripe
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestPreviewSubscriptionChangeSumsProrations(t *testing.T) {
+	prorationDate := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/subscriptions/sub_1":
+			w.Write([]byte(`{"id":"sub_1","items":{"data":[{"id":"si_1"}]}}`))
+		case "/invoices/upcoming":
+			q := r.URL.Query()
+			if q.Get("subscription_items[0][id]") != "si_1" || q.Get("subscription_items[0][price]") != "price_pro" ||
+				q.Get("subscription_proration_date") != "1792238400" {
+				t.Errorf("unexpected preview query: %v", q)
+			}
+			w.Write([]byte(`{"currency":"usd","total":3450,"amount_due":3450,"period_end":1794873600,"lines":{"data":[
+				{"amount":-450,"proration":true},
+				{"amount":1900,"proration":true},
+				{"amount":2000,"proration":false}
+			]}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	c := NewClient("sk_test")
+	c.baseURL = server.URL
+
+	preview, err := c.PreviewSubscriptionChange("sub_1", "price_pro", prorationDate)
+	if err != nil {
+		t.Fatalf("PreviewSubscriptionChange: %v", err)
+	}
+	if preview.ProrationCents != 1450 || preview.NextInvoiceCents != 3450 || preview.Currency != "usd" {
+		t.Fatalf("unexpected preview: %+v", preview)
+	}
+	if !preview.ProrationDate.Equal(prorationDate) {
+		t.Fatalf("expected proration date %v, got %v", prorationDate, preview.ProrationDate)
+	}
+}
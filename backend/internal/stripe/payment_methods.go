@@ -0,0 +1,133 @@
+package stripe
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+)
+
+// ErrPaymentMethodNotAttached is returned when a payment method does not
+// belong to the customer it is being made the default of.
+var ErrPaymentMethodNotAttached = errors.New("payment method is not attached to the customer")
+
+// PaymentMethod is a card saved on a Stripe customer.
+type PaymentMethod struct {
+	ID        string `json:"id"`
+	Brand     string `json:"brand"`
+	Last4     string `json:"last4"`
+	ExpMonth  int    `json:"exp_month"`
+	ExpYear   int    `json:"exp_year"`
+	IsDefault bool   `json:"is_default"`
+}
+
+// SetupIntent is a Stripe SetupIntent. The client confirms it with
+// Stripe.js using ClientSecret to save a new card without charging it.
+type SetupIntent struct {
+	ID           string `json:"id"`
+	ClientSecret string `json:"client_secret"`
+	Status       string `json:"status"`
+}
+
+// ListPaymentMethods returns the customer's saved cards, marking the one
+// invoices are charged to by default.
+func (c *Client) ListPaymentMethods(customerID string) ([]PaymentMethod, error) {
+	customer, err := c.get("/customers/" + url.PathEscape(customerID))
+	if err != nil {
+		return nil, fmt.Errorf("get customer: %w", err)
+	}
+	defaultID := ""
+	if settings, ok := customer["invoice_settings"].(map[string]interface{}); ok {
+		defaultID, _ = settings["default_payment_method"].(string)
+	}
+
+	query := url.Values{}
+	query.Set("customer", customerID)
+	query.Set("type", "card")
+	query.Set("limit", "100")
+	resp, err := c.get("/payment_methods?" + query.Encode())
+	if err != nil {
+		return nil, fmt.Errorf("list payment methods: %w", err)
+	}
+
+	data, _ := resp["data"].([]interface{})
+	methods := make([]PaymentMethod, 0, len(data))
+	for _, raw := range data {
+		pm, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		method := parsePaymentMethod(pm)
+		method.IsDefault = method.ID == defaultID
+		methods = append(methods, method)
+	}
+	return methods, nil
+}
+
+// CreateSetupIntent starts saving a new card for off-session use on the
+// customer's invoices.
+func (c *Client) CreateSetupIntent(customerID string) (*SetupIntent, error) {
+	data := url.Values{}
+	data.Set("customer", customerID)
+	data.Set("usage", "off_session")
+	data.Set("payment_method_types[]", "card")
+
+	resp, err := c.post("/setup_intents", data)
+	if err != nil {
+		return nil, fmt.Errorf("create setup intent: %w", err)
+	}
+
+	intent := &SetupIntent{}
+	intent.ID, _ = resp["id"].(string)
+	intent.ClientSecret, _ = resp["client_secret"].(string)
+	intent.Status, _ = resp["status"].(string)
+	if intent.ClientSecret == "" {
+		return nil, fmt.Errorf("create setup intent: missing client secret in response")
+	}
+	return intent, nil
+}
+
+// SetDefaultPaymentMethod makes a card already attached to the customer the
+// default for their invoices. When subscriptionID is set the subscription's
+// own default is replaced too, since it takes precedence over the
+// customer's.
+func (c *Client) SetDefaultPaymentMethod(customerID, paymentMethodID, subscriptionID string) error {
+	pm, err := c.get("/payment_methods/" + url.PathEscape(paymentMethodID))
+	if err != nil {
+		return fmt.Errorf("get payment method: %w", err)
+	}
+	if owner, _ := pm["customer"].(string); owner != customerID {
+		return ErrPaymentMethodNotAttached
+	}
+
+	data := url.Values{}
+	data.Set("invoice_settings[default_payment_method]", paymentMethodID)
+	if _, err := c.post("/customers/"+url.PathEscape(customerID), data); err != nil {
+		return fmt.Errorf("set customer default payment method: %w", err)
+	}
+
+	if subscriptionID != "" {
+		data := url.Values{}
+		data.Set("default_payment_method", paymentMethodID)
+		if _, err := c.post("/subscriptions/"+subscriptionID, data); err != nil {
+			return fmt.Errorf("set subscription default payment method: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func parsePaymentMethod(pm map[string]interface{}) PaymentMethod {
+	method := PaymentMethod{}
+	method.ID, _ = pm["id"].(string)
+	if card, ok := pm["card"].(map[string]interface{}); ok {
+		method.Brand, _ = card["brand"].(string)
+		method.Last4, _ = card["last4"].(string)
+		if month, ok := card["exp_month"].(float64); ok {
+			method.ExpMonth = int(month)
+		}
+		if year, ok := card["exp_year"].(float64); ok {
+			method.ExpYear = int(year)
+		}
+	}
+	return method
+}
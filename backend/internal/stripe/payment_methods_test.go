@@ -0,0 +1,51 @@
+package stripe
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPaymentMethodsDefaultHandling(t *testing.T) {
+	var updatedCustomer bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/customers/cus_1":
+			w.Write([]byte(`{"id":"cus_1","invoice_settings":{"default_payment_method":"pm_2"}}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/payment_methods":
+			w.Write([]byte(`{"data":[
+				{"id":"pm_1","card":{"brand":"visa","last4":"4242","exp_month":4,"exp_year":2030}},
+				{"id":"pm_2","card":{"brand":"mastercard","last4":"4444","exp_month":9,"exp_year":2031}}
+			]}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/payment_methods/pm_other":
+			w.Write([]byte(`{"id":"pm_other","customer":"cus_2"}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/customers/cus_1":
+			updatedCustomer = true
+			w.Write([]byte(`{"id":"cus_1"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	c := NewClient("sk_test")
+	c.baseURL = server.URL
+
+	methods, err := c.ListPaymentMethods("cus_1")
+	if err != nil {
+		t.Fatalf("ListPaymentMethods: %v", err)
+	}
+	if len(methods) != 2 || methods[0].IsDefault || !methods[1].IsDefault || methods[1].Last4 != "4444" || methods[1].ExpYear != 2031 {
+		t.Fatalf("unexpected payment methods: %+v", methods)
+	}
+
+	err = c.SetDefaultPaymentMethod("cus_1", "pm_other", "")
+	if !errors.Is(err, ErrPaymentMethodNotAttached) {
+		t.Fatalf("expected ErrPaymentMethodNotAttached, got %v", err)
+	}
+	if updatedCustomer {
+		t.Fatal("customer default must not change for another customer's card")
+	}
+}
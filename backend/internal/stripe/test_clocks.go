@@ -0,0 +1,73 @@
+package stripe
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// TestClock is a Stripe test clock. Customers attached to a clock see time
+// as the clock's frozen time, so billing cycles can be simulated by
+// advancing it. Test clocks only exist in Stripe test mode.
+type TestClock struct {
+	ID         string    `json:"id"`
+	Name       string    `json:"name"`
+	Status     string    `json:"status"`
+	FrozenTime time.Time `json:"frozen_time"`
+}
+
+// CreateTestClock creates a test clock frozen at frozenTime
+func (c *Client) CreateTestClock(name string, frozenTime time.Time) (*TestClock, error) {
+	data := url.Values{}
+	data.Set("frozen_time", strconv.FormatInt(frozenTime.Unix(), 10))
+	if name != "" {
+		data.Set("name", name)
+	}
+
+	resp, err := c.post("/test_helpers/test_clocks", data)
+	if err != nil {
+		return nil, fmt.Errorf("create test clock: %w", err)
+	}
+
+	return parseTestClock(resp)
+}
+
+// GetTestClock returns the current state of a test clock
+func (c *Client) GetTestClock(testClockID string) (*TestClock, error) {
+	resp, err := c.get("/test_helpers/test_clocks/" + testClockID)
+	if err != nil {
+		return nil, fmt.Errorf("get test clock: %w", err)
+	}
+
+	return parseTestClock(resp)
+}
+
+// AdvanceTestClock moves a test clock forward to frozenTime. Stripe advances
+// clocks asynchronously; the returned clock reports status "advancing" until
+// the resulting invoices and webhooks have been generated.
+func (c *Client) AdvanceTestClock(testClockID string, frozenTime time.Time) (*TestClock, error) {
+	data := url.Values{}
+	data.Set("frozen_time", strconv.FormatInt(frozenTime.Unix(), 10))
+
+	resp, err := c.post("/test_helpers/test_clocks/"+testClockID+"/advance", data)
+	if err != nil {
+		return nil, fmt.Errorf("advance test clock: %w", err)
+	}
+
+	return parseTestClock(resp)
+}
+
+func parseTestClock(resp map[string]interface{}) (*TestClock, error) {
+	clock := &TestClock{}
+	clock.ID, _ = resp["id"].(string)
+	if clock.ID == "" {
+		return nil, fmt.Errorf("missing test clock ID in response")
+	}
+	clock.Name, _ = resp["name"].(string)
+	clock.Status, _ = resp["status"].(string)
+	if frozen, ok := resp["frozen_time"].(float64); ok {
+		clock.FrozenTime = time.Unix(int64(frozen), 0).UTC()
+	}
+	return clock, nil
+}
@@ -0,0 +1,36 @@
+package stripe
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestVerifyWebhookSignature(t *testing.T) {
+	payload := []byte(`{"id":"evt_1","type":"invoice.payment_succeeded"}`)
+	header := SignWebhookPayload("whsec_test", payload, time.Now())
+
+	if err := VerifyWebhookSignature(payload, header, "whsec_test", DefaultWebhookTolerance); err != nil {
+		t.Fatalf("expected a valid signature, got %v", err)
+	}
+	// A rolled secret still verifies while the old signature is listed too.
+	rolled := SignWebhookPayload("whsec_new", payload, time.Now()) + ",v1=deadbeef"
+	if err := VerifyWebhookSignature(payload, rolled, "whsec_new", DefaultWebhookTolerance); err != nil {
+		t.Fatalf("expected any v1 signature to match, got %v", err)
+	}
+
+	cases := map[string]struct {
+		payload []byte
+		header  string
+	}{
+		"wrong secret":     {payload, SignWebhookPayload("whsec_other", payload, time.Now())},
+		"tampered payload": {[]byte(`{"id":"evt_2"}`), header},
+		"too old":          {payload, SignWebhookPayload("whsec_test", payload, time.Now().Add(-time.Hour))},
+		"missing header":   {payload, ""},
+	}
+	for name, tc := range cases {
+		if err := VerifyWebhookSignature(tc.payload, tc.header, "whsec_test", DefaultWebhookTolerance); !errors.Is(err, ErrInvalidWebhookSignature) {
+			t.Errorf("%s: expected ErrInvalidWebhookSignature, got %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package testutil
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/store"
+)
+
+var factorySeq atomic.Int64
+
+// NewStore returns a Store backed by db.
+func NewStore(t testing.TB, db *sql.DB) *store.Store {
+	t.Helper()
+
+	s, err := store.New(db)
+	if err != nil {
+		t.Fatalf("testutil: new store: %v", err)
+	}
+	return s
+}
+
+// CreateUser inserts a Google-authenticated user and returns it. An empty
+// email gets a unique generated address.
+func CreateUser(t testing.TB, s *store.Store, email string) *models.User {
+	t.Helper()
+
+	n := factorySeq.Add(1)
+	if email == "" {
+		email = fmt.Sprintf("user%d@example.com", n)
+	}
+	name := fmt.Sprintf("Test User %d", n)
+
+	ctx := context.Background()
+	if err := s.UpsertGoogleUser(ctx, models.GoogleAuthUser{
+		Sub:         fmt.Sprintf("google-%d", n),
+		Name:        &name,
+		Email:       &email,
+		AccessToken: fmt.Sprintf("token-%d", n),
+	}); err != nil {
+		t.Fatalf("testutil: create user %s: %v", email, err)
+	}
+
+	user, err := s.GetUserByEmail(ctx, email)
+	if err != nil {
+		t.Fatalf("testutil: load user %s: %v", email, err)
+	}
+	return user
+}
+
+// CreateJiraSettings stores Jira settings for the user and returns the
+// user's MCP secret.
+func CreateJiraSettings(t testing.TB, s *store.Store, user *models.User) string {
+	t.Helper()
+
+	email := *user.Email
+	ctx := context.Background()
+	if err := s.UpsertUserSettings(ctx, email, "https://example.atlassian.net", email, "jira-api-token"); err != nil {
+		t.Fatalf("testutil: create jira settings for %s: %v", email, err)
+	}
+
+	secret, err := s.GenerateMCPSecret(ctx, email)
+	if err != nil {
+		t.Fatalf("testutil: generate mcp secret for %s: %v", email, err)
+	}
+	return secret
+}
+
+// EnqueueJob enqueues a pending job of the given type and priority.
+func EnqueueJob(t testing.TB, jobs *store.JobStore, jobType string, priority models.JobPriority) *models.Job {
+	t.Helper()
+
+	job := &models.Job{
+		JobType:     jobType,
+		Payload:     models.JSONB{"seq": factorySeq.Add(1)},
+		Priority:    priority,
+		MaxAttempts: 3,
+		Metadata:    models.JSONB{},
+	}
+	if err := jobs.Enqueue(context.Background(), job); err != nil {
+		t.Fatalf("testutil: enqueue %s job: %v", jobType, err)
+	}
+	return job
+}
+
+// NewJobStore returns a JobStore backed by db.
+func NewJobStore(t testing.TB, db *sql.DB) *store.JobStore {
+	t.Helper()
+
+	jobs, err := store.NewJobStore(db)
+	if err != nil {
+		t.Fatalf("testutil: new job store: %v", err)
+	}
+	return jobs
+}
@@ -0,0 +1,183 @@
+// Package testutil provides a real Postgres harness for integration tests.
+//
+// Tests call NewDB to get a fresh, fully migrated database. The server comes
+// from TEST_DATABASE_URL when set; otherwise a throwaway postgres container
+// is started with the docker CLI on first use and removed by Main. Tests are
+// skipped when neither is available, so integration suites stay opt-in via
+// the "integration" build tag:
+//
+//	go test -tags integration ./...
+package testutil
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	_ "github.com/lib/pq"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/migrations"
+)
+
+const (
+	envTestDatabaseURL = "TEST_DATABASE_URL"
+	postgresImage      = "postgres:16-alpine"
+	postgresPassword   = "testutil"
+	startupTimeout     = 60 * time.Second
+)
+
+var (
+	serverOnce  sync.Once
+	serverURL   string
+	serverErr   error
+	containerID string
+	dbCounter   atomic.Int64
+)
+
+// Main runs the package's tests and removes the postgres container, if one
+// was started. Use it from TestMain:
+//
+//	func TestMain(m *testing.M) { os.Exit(testutil.Main(m)) }
+func Main(m *testing.M) int {
+	code := m.Run()
+	if containerID != "" {
+		if out, err := exec.Command("docker", "rm", "-f", containerID).CombinedOutput(); err != nil {
+			log.Printf("testutil: remove postgres container %s: %v: %s", containerID, err, out)
+		}
+	}
+	return code
+}
+
+// NewDB creates an empty database on the test server, applies all
+// migrations and returns a connection to it. The database is dropped when
+// the test finishes.
+func NewDB(t testing.TB) *sql.DB {
+	t.Helper()
+
+	serverOnce.Do(func() {
+		serverURL, serverErr = startServer()
+	})
+	if serverErr != nil {
+		t.Skipf("testutil: postgres unavailable: %v", serverErr)
+	}
+
+	admin, err := sql.Open("postgres", serverURL)
+	if err != nil {
+		t.Fatalf("testutil: open server: %v", err)
+	}
+	defer admin.Close()
+
+	name := fmt.Sprintf("testutil_%d_%d", os.Getpid(), dbCounter.Add(1))
+	if _, err := admin.Exec("CREATE DATABASE " + name); err != nil {
+		t.Fatalf("testutil: create database %s: %v", name, err)
+	}
+
+	dsn, err := withDatabase(serverURL, name)
+	if err != nil {
+		t.Fatalf("testutil: %v", err)
+	}
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("testutil: open database %s: %v", name, err)
+	}
+
+	t.Cleanup(func() {
+		_ = db.Close()
+		admin, err := sql.Open("postgres", serverURL)
+		if err != nil {
+			return
+		}
+		defer admin.Close()
+		if _, err := admin.Exec("DROP DATABASE IF EXISTS " + name + " WITH (FORCE)"); err != nil {
+			t.Logf("testutil: drop database %s: %v", name, err)
+		}
+	})
+
+	if err := migrations.Up(db); err != nil {
+		t.Fatalf("testutil: migrate %s: %v", name, err)
+	}
+
+	return db
+}
+
+// startServer returns the DSN of a postgres server's maintenance database,
+// starting a container when TEST_DATABASE_URL is not set.
+func startServer() (string, error) {
+	if dsn := os.Getenv(envTestDatabaseURL); dsn != "" {
+		return dsn, waitForServer(dsn)
+	}
+
+	if _, err := exec.LookPath("docker"); err != nil {
+		return "", fmt.Errorf("%s not set and docker not found", envTestDatabaseURL)
+	}
+
+	out, err := exec.Command("docker", "run", "-d", "--rm",
+		"-e", "POSTGRES_PASSWORD="+postgresPassword,
+		"-p", "127.0.0.1::5432",
+		postgresImage,
+	).Output()
+	if err != nil {
+		return "", fmt.Errorf("start postgres container: %w", commandError(err))
+	}
+	containerID = strings.TrimSpace(string(out))
+
+	out, err = exec.Command("docker", "port", containerID, "5432/tcp").Output()
+	if err != nil {
+		return "", fmt.Errorf("inspect postgres container: %w", commandError(err))
+	}
+	// docker port may list an IPv4 and an IPv6 binding; use the first.
+	hostPort := strings.TrimSpace(strings.SplitN(string(out), "\n", 2)[0])
+
+	dsn := fmt.Sprintf("postgres://postgres:%s@%s/postgres?sslmode=disable", postgresPassword, hostPort)
+	return dsn, waitForServer(dsn)
+}
+
+// waitForServer pings dsn until the server accepts connections.
+func waitForServer(dsn string) error {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return fmt.Errorf("open %s: %w", envTestDatabaseURL, err)
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), startupTimeout)
+	defer cancel()
+	for {
+		err := db.PingContext(ctx)
+		if err == nil {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("postgres not ready after %s: %w", startupTimeout, err)
+		case <-time.After(250 * time.Millisecond):
+		}
+	}
+}
+
+// withDatabase returns dsn pointed at a different database name.
+func withDatabase(dsn, name string) (string, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return "", fmt.Errorf("parse %s: %w", envTestDatabaseURL, err)
+	}
+	u.Path = "/" + name
+	return u.String(), nil
+}
+
+func commandError(err error) error {
+	if exitErr, ok := err.(*exec.ExitError); ok && len(exitErr.Stderr) > 0 {
+		return fmt.Errorf("%w: %s", err, bytes.TrimSpace(exitErr.Stderr))
+	}
+	return err
+}
@@ -0,0 +1,86 @@
+// Package warmup runs the startup checks that prepare the backend before it
+// accepts traffic: opening database connections, reading hot tables once
+// and verifying credentials of external services, so the first requests do
+// not pay for them and broken dependencies are caught at deploy time.
+package warmup
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// DefaultTimeout bounds the whole warm-up.
+const DefaultTimeout = 15 * time.Second
+
+// Check is one warm-up step.
+type Check struct {
+	Name string
+	// Required checks stop startup when they fail; the others only log.
+	Required bool
+	Run      func(ctx context.Context) error
+}
+
+// Run runs the checks concurrently and returns the failures of required
+// checks joined together.
+func Run(ctx context.Context, checks ...Check) error {
+	started := time.Now()
+	errs := make([]error, len(checks))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			checkStarted := time.Now()
+			err := check.Run(ctx)
+			switch {
+			case err == nil:
+				log.Printf("[warmup] %s ready in %v", check.Name, time.Since(checkStarted).Round(time.Millisecond))
+			case check.Required:
+				errs[i] = fmt.Errorf("%s: %w", check.Name, err)
+			default:
+				log.Printf("[warmup] %s skipped: %v", check.Name, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+	log.Printf("[warmup] completed in %v", time.Since(started).Round(time.Millisecond))
+	return nil
+}
+
+// Connections returns a required check that opens n connections to db at
+// once and returns them to the pool as idle connections. n should not
+// exceed the pool's idle limit, or the extra connections are closed again.
+func Connections(name string, db *sql.DB, n int) Check {
+	return Check{
+		Name:     name,
+		Required: true,
+		Run: func(ctx context.Context) error {
+			conns := make([]*sql.Conn, 0, n)
+			defer func() {
+				for _, conn := range conns {
+					conn.Close()
+				}
+			}()
+			for range n {
+				conn, err := db.Conn(ctx)
+				if err != nil {
+					return err
+				}
+				conns = append(conns, conn)
+				if err := conn.PingContext(ctx); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+}
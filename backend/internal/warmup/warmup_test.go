@@ -0,0 +1,46 @@
+package warmup
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestRunFailsOnlyOnRequiredChecks(t *testing.T) {
+	ok := Check{Name: "ok", Required: true, Run: func(ctx context.Context) error { return nil }}
+	optional := Check{Name: "optional", Run: func(ctx context.Context) error { return errors.New("cold") }}
+	if err := Run(context.Background(), ok, optional); err != nil {
+		t.Fatalf("expected optional failures to be ignored, got %v", err)
+	}
+
+	broken := Check{Name: "database", Required: true, Run: func(ctx context.Context) error { return errors.New("refused") }}
+	err := Run(context.Background(), ok, optional, broken)
+	if err == nil || !strings.Contains(err.Error(), "database: refused") {
+		t.Fatalf("expected the required failure, got %v", err)
+	}
+}
+
+func TestConnectionsFillsThePool(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+	db.SetMaxIdleConns(3)
+	for range 3 {
+		mock.ExpectPing()
+	}
+
+	if err := Run(context.Background(), Connections("primary database", db, 3)); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if idle := db.Stats().Idle; idle != 3 {
+		t.Fatalf("expected 3 idle connections, got %d", idle)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
@@ -0,0 +1,240 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/events"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/store"
+)
+
+// AbuseConfig holds abuse detector configuration
+type AbuseConfig struct {
+	// Interval is the time between scans
+	Interval time.Duration
+	// Window is the trailing period each scan inspects
+	Window time.Duration
+	// MaxAuthFailures is how many invalid mcp_secret attempts one IP may
+	// make within Window before it is flagged
+	MaxAuthFailures int
+	// SpikeFactor flags users whose requests in Window exceed their baseline
+	// for the same length of time by this multiple
+	SpikeFactor float64
+	// SpikeMinRequests ignores spikes smaller than this many requests
+	SpikeMinRequests int
+	// Baseline is the history the spike baseline is averaged over
+	Baseline time.Duration
+	// AutoLimit applies a temporary rate limit to flagged IPs
+	AutoLimit bool
+	// LimitRequestsPerMinute is the rate applied to flagged IPs
+	LimitRequestsPerMinute int
+	// LimitDuration is how long an applied limit lasts
+	LimitDuration time.Duration
+	// Retention is how long auth failure records are kept
+	Retention time.Duration
+}
+
+// DefaultAbuseConfig returns sensible default configuration
+func DefaultAbuseConfig() AbuseConfig {
+	return AbuseConfig{
+		Interval:               time.Minute,
+		Window:                 10 * time.Minute,
+		MaxAuthFailures:        20,
+		SpikeFactor:            100,
+		SpikeMinRequests:       500,
+		Baseline:               7 * 24 * time.Hour,
+		AutoLimit:              true,
+		LimitRequestsPerMinute: 10,
+		LimitDuration:          time.Hour,
+		Retention:              7 * 24 * time.Hour,
+	}
+}
+
+// AbuseDetector periodically scans recent traffic for credential stuffing
+// (many invalid mcp_secret attempts from one IP) and sudden request spikes,
+// publishes an AbuseDetected event for each finding, and optionally rate
+// limits offending IPs for a while.
+type AbuseDetector struct {
+	config AbuseConfig
+	store  *store.Store
+	bus    *events.Bus
+
+	// reported suppresses repeat events for the same IP or user until the
+	// finding has had a full window to subside.
+	reported map[string]time.Time
+
+	wg      sync.WaitGroup
+	stopCh  chan struct{}
+	stopped bool
+	mu      sync.Mutex
+}
+
+// NewAbuseDetector creates a new AbuseDetector instance
+func NewAbuseDetector(config AbuseConfig, s *store.Store, bus *events.Bus) *AbuseDetector {
+	defaults := DefaultAbuseConfig()
+	if config.Interval <= 0 {
+		config.Interval = defaults.Interval
+	}
+	if config.Window <= 0 {
+		config.Window = defaults.Window
+	}
+	if config.MaxAuthFailures <= 0 {
+		config.MaxAuthFailures = defaults.MaxAuthFailures
+	}
+	if config.SpikeFactor <= 0 {
+		config.SpikeFactor = defaults.SpikeFactor
+	}
+	if config.SpikeMinRequests <= 0 {
+		config.SpikeMinRequests = defaults.SpikeMinRequests
+	}
+	if config.Baseline <= 0 {
+		config.Baseline = defaults.Baseline
+	}
+	if config.LimitRequestsPerMinute <= 0 {
+		config.LimitRequestsPerMinute = defaults.LimitRequestsPerMinute
+	}
+	if config.LimitDuration <= 0 {
+		config.LimitDuration = defaults.LimitDuration
+	}
+	if config.Retention <= 0 {
+		config.Retention = defaults.Retention
+	}
+
+	return &AbuseDetector{
+		config:   config,
+		store:    s,
+		bus:      bus,
+		reported: map[string]time.Time{},
+		stopCh:   make(chan struct{}),
+	}
+}
+
+// Start begins the scan loop
+func (d *AbuseDetector) Start(ctx context.Context) {
+	d.wg.Add(1)
+	go d.loop(ctx)
+	log.Printf("[abuse] Detector started (interval %v, window %v)", d.config.Interval, d.config.Window)
+}
+
+// Stop waits for an in-flight scan to finish and stops the loop
+func (d *AbuseDetector) Stop(ctx context.Context) error {
+	d.mu.Lock()
+	if d.stopped {
+		d.mu.Unlock()
+		return nil
+	}
+	d.stopped = true
+	close(d.stopCh)
+	d.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		log.Printf("[abuse] Detector stopped")
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("abuse detector shutdown: %w", ctx.Err())
+	}
+}
+
+func (d *AbuseDetector) loop(ctx context.Context) {
+	defer d.wg.Done()
+
+	ticker := time.NewTicker(d.config.Interval)
+	defer ticker.Stop()
+	purge := time.NewTicker(time.Hour)
+	defer purge.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-d.stopCh:
+			return
+		case <-ticker.C:
+			if err := d.scan(ctx); err != nil {
+				log.Printf("[abuse] Scan error: %v", err)
+			}
+		case <-purge.C:
+			if err := d.store.PurgeAbuseRecords(ctx, d.config.Retention); err != nil {
+				log.Printf("[abuse] Purge error: %v", err)
+			}
+		}
+	}
+}
+
+// scan runs both detectors once.
+func (d *AbuseDetector) scan(ctx context.Context) error {
+	now := time.Now()
+
+	for key, at := range d.reported {
+		if now.Sub(at) >= d.config.Window {
+			delete(d.reported, key)
+		}
+	}
+	seen := func(key string) bool {
+		if _, ok := d.reported[key]; ok {
+			return true
+		}
+		d.reported[key] = now
+		return false
+	}
+
+	bursts, err := d.store.ListAuthFailureBursts(ctx, now.Add(-d.config.Window), d.config.MaxAuthFailures)
+	if err != nil {
+		return err
+	}
+	for _, b := range bursts {
+		if seen("ip:" + b.ClientIP) {
+			continue
+		}
+		ev := events.AbuseDetected{
+			Kind:     events.AbuseAuthFailureBurst,
+			ClientIP: b.ClientIP,
+			Count:    b.Failures,
+		}
+		if d.config.AutoLimit {
+			until := now.Add(d.config.LimitDuration)
+			if err := d.store.SetIPRateLimit(ctx, &models.IPRateLimit{
+				ClientIP:          b.ClientIP,
+				RequestsPerMinute: d.config.LimitRequestsPerMinute,
+				Reason:            events.AbuseAuthFailureBurst,
+				ExpiresAt:         until,
+			}); err != nil {
+				log.Printf("[abuse] Failed to rate limit %s: %v", b.ClientIP, err)
+			} else {
+				ev.RateLimitedUntil = &until
+			}
+		}
+		log.Printf("[abuse] %d invalid mcp_secret attempts from %s in %v", b.Failures, b.ClientIP, d.config.Window)
+		d.bus.Publish(ctx, ev)
+	}
+
+	spikes, err := d.store.ListRequestSpikes(ctx, d.config.Window, d.config.Baseline, d.config.SpikeFactor, d.config.SpikeMinRequests)
+	if err != nil {
+		return err
+	}
+	for _, sp := range spikes {
+		if seen(fmt.Sprintf("user:%d", sp.UserID)) {
+			continue
+		}
+		log.Printf("[abuse] Request spike for user %d: %d requests in %v (baseline %.1f)", sp.UserID, sp.Recent, d.config.Window, sp.Baseline)
+		d.bus.Publish(ctx, events.AbuseDetected{
+			Kind:     events.AbuseRequestSpike,
+			UserID:   sp.UserID,
+			Count:    sp.Recent,
+			Baseline: sp.Baseline,
+		})
+	}
+
+	return nil
+}
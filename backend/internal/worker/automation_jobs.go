@@ -0,0 +1,306 @@
+package worker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/jira"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/mail"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/store"
+)
+
+// jiraAutomationJobType runs one scheduled Jira automation.
+const jiraAutomationJobType = "jira_automation"
+
+// jiraAutomationScheduleJobType queues the automations that are due.
+const jiraAutomationScheduleJobType = "jira_automation_schedule"
+
+// automationScheduleBatchSize caps the automations queued per check.
+const automationScheduleBatchSize = 500
+
+// automationDeliveryTimeout bounds posting the results to the destination.
+const automationDeliveryTimeout = 15 * time.Second
+
+// automationFields are the issue fields posted with an automation's results.
+var automationFields = []string{"summary", "status", "assignee", "priority", "updated"}
+
+// RegisterAutomationJobs registers the scheduled Jira automation handlers.
+// refresh renews rejected Atlassian OAuth tokens and may be nil.
+func RegisterAutomationJobs(w *Worker, s *store.Store, mailer mail.Mailer, refresh jira.TokenRefresher) {
+	r := &automationRunner{
+		store:   s,
+		worker:  w,
+		mailer:  mailer,
+		refresh: refresh,
+		client:  &http.Client{Timeout: automationDeliveryTimeout},
+	}
+	w.RegisterHandler(jiraAutomationJobType, r.handle)
+	w.RegisterHandler(jiraAutomationScheduleJobType, r.schedule)
+
+	log.Println("[worker] Registered automation job handlers: " + jiraAutomationJobType + ", " + jiraAutomationScheduleJobType)
+}
+
+// JiraAutomationJob returns a job that runs the automation once. Runs are
+// not retried: a failure is recorded and the next scheduled run tries again.
+func JiraAutomationJob(automationID int64, due time.Time) *models.Job {
+	return &models.Job{
+		JobType: jiraAutomationJobType,
+		Payload: models.JSONB{
+			"automation_id": automationID,
+			"due":           due.UTC().Format(time.RFC3339),
+		},
+		Priority:    models.JobPriorityNormal,
+		MaxAttempts: 1,
+		DedupWindow: time.Minute,
+	}
+}
+
+type automationRunner struct {
+	store   *store.Store
+	worker  *Worker
+	mailer  mail.Mailer
+	refresh jira.TokenRefresher
+	client  *http.Client
+}
+
+// handle runs the automation, records the run and alerts the owner when it
+// starts failing or is disabled after failing too often.
+func (r *automationRunner) handle(ctx context.Context, job *models.Job) error {
+	idRaw, ok := job.Payload["automation_id"].(float64)
+	if !ok {
+		return fmt.Errorf("missing automation_id in payload")
+	}
+	target, err := r.store.GetAutomationForRun(ctx, int64(idRaw))
+	if errors.Is(err, store.ErrAutomationNotFound) {
+		// Deleted after the run was queued.
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("load automation %d: %w", int64(idRaw), err)
+	}
+	a := &target.Automation
+	if !a.Enabled {
+		return nil
+	}
+
+	run := &models.JiraAutomationRun{AutomationID: a.ID, StartedAt: time.Now()}
+	issues, runErr := r.run(ctx, target)
+	run.FinishedAt = time.Now()
+	run.IssueCount = issues
+	run.Status = models.AutomationRunSucceeded
+	if runErr != nil {
+		run.Status = models.AutomationRunFailed
+		message := runErr.Error()
+		run.Error = &message
+	}
+
+	failures, disabled, err := r.store.RecordAutomationRun(ctx, run)
+	if err != nil {
+		return fmt.Errorf("record run of automation %d: %w", a.ID, err)
+	}
+	if runErr == nil {
+		return nil
+	}
+	log.Printf("[automation] Automation %d of user %d failed (%d in a row): %v", a.ID, a.UserID, failures, runErr)
+
+	switch {
+	case disabled:
+		r.alert(ctx, target, "error", fmt.Sprintf("Automation %q was disabled", a.Name),
+			fmt.Sprintf("It failed %d times in a row and will not run again until you re-enable it. Last error: %v", failures, runErr), true)
+	case failures == 1:
+		r.alert(ctx, target, "warning", fmt.Sprintf("Automation %q failed", a.Name),
+			fmt.Sprintf("Its run at %s failed: %v. It will be disabled after %d failures in a row.", run.StartedAt.UTC().Format(time.RFC3339), runErr, models.MaxAutomationFailures), false)
+	}
+	return nil
+}
+
+// run searches Jira and posts the results, returning how many issues were
+// posted.
+func (r *automationRunner) run(ctx context.Context, target *models.AutomationRunTarget) (int, error) {
+	a := &target.Automation
+	if target.MCPSecret == "" {
+		return 0, errors.New("account has no MCP secret to resolve Jira credentials")
+	}
+	client, err := jira.ForMCPSecret(ctx, r.store, target.MCPSecret, r.refresh)
+	if err != nil {
+		return 0, err
+	}
+	result, err := client.Search(ctx, jira.SearchRequest{JQL: a.JQL, Fields: automationFields, MaxResults: a.MaxResults})
+	if err != nil {
+		return 0, fmt.Errorf("jira search: %w", err)
+	}
+
+	issues := make([]automationIssue, len(result.Issues))
+	for i, issue := range result.Issues {
+		issues[i] = newAutomationIssue(client.BaseURL(), issue)
+	}
+
+	var body any
+	switch a.Destination {
+	case models.AutomationDestinationSlack:
+		body = map[string]string{"text": renderAutomationSlack(a, issues)}
+	default:
+		body = map[string]any{
+			"automation": map[string]any{"id": a.ID, "name": a.Name, "jql": a.JQL},
+			"ran_at":     time.Now().UTC(),
+			"issues":     issues,
+		}
+	}
+	if err := r.post(ctx, a.DestinationURL, body); err != nil {
+		return 0, err
+	}
+	return len(issues), nil
+}
+
+// post sends body as JSON to the destination.
+func (r *automationRunner) post(ctx context.Context, destination string, body any) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("encode results: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, destination, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("build delivery request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		// The URL is a secret, so only report that delivery failed.
+		return errors.New("deliver results: destination unreachable")
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("deliver results: destination answered %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// alert adds a notification to the owner's feed and, when email is set,
+// mails them as well.
+func (r *automationRunner) alert(ctx context.Context, target *models.AutomationRunTarget, severity, title, body string, email bool) {
+	a := &target.Automation
+	metadata := models.JSONB{"automation_id": a.ID}
+	if err := r.store.CreateUserNotification(ctx, a.UserID, models.NotificationKindAutomationFailure, severity, title, body, metadata); err != nil {
+		log.Printf("[automation] Failed to notify user %d about automation %d: %v", a.UserID, a.ID, err)
+	}
+	if !email || r.mailer == nil || target.Email == "" {
+		return
+	}
+	if err := r.mailer.Send(ctx, mail.Message{To: target.Email, Subject: title, Body: body + "\n"}); err != nil {
+		log.Printf("[automation] Failed to mail user %d about automation %d: %v", a.UserID, a.ID, err)
+	}
+}
+
+// automationIssue is an issue as posted to a webhook.
+type automationIssue struct {
+	Key      string `json:"key"`
+	URL      string `json:"url"`
+	Summary  string `json:"summary"`
+	Status   string `json:"status,omitempty"`
+	Assignee string `json:"assignee,omitempty"`
+	Priority string `json:"priority,omitempty"`
+	Updated  string `json:"updated,omitempty"`
+}
+
+func newAutomationIssue(baseURL string, issue jira.Issue) automationIssue {
+	name := func(field, key string) string {
+		if v, ok := issue.Fields[field].(map[string]any); ok {
+			s, _ := v[key].(string)
+			return s
+		}
+		return ""
+	}
+	summary, _ := issue.Fields["summary"].(string)
+	updated, _ := issue.Fields["updated"].(string)
+	return automationIssue{
+		Key:      issue.Key,
+		URL:      strings.TrimRight(baseURL, "/") + "/browse/" + issue.Key,
+		Summary:  summary,
+		Status:   name("status", "name"),
+		Assignee: name("assignee", "displayName"),
+		Priority: name("priority", "name"),
+		Updated:  updated,
+	}
+}
+
+// renderAutomationSlack formats the results as Slack mrkdwn.
+func renderAutomationSlack(a *models.JiraAutomation, issues []automationIssue) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%s*: %d issue", a.Name, len(issues))
+	if len(issues) != 1 {
+		b.WriteString("s")
+	}
+	b.WriteString(" matching `" + a.JQL + "`")
+	for _, issue := range issues {
+		fmt.Fprintf(&b, "\n• <%s|%s> %s", issue.URL, issue.Key, issue.Summary)
+		if issue.Status != "" {
+			b.WriteString(" (" + issue.Status + ")")
+		}
+		if issue.Assignee != "" {
+			b.WriteString(", " + issue.Assignee)
+		}
+	}
+	return b.String()
+}
+
+// AutomationScheduleJob is the builtin recurring job that queues a run of
+// every enabled automation as it comes due, checked every minute.
+func AutomationScheduleJob() models.RecurringJob {
+	return models.RecurringJob{
+		Slug:            "jira-automation-schedule",
+		JobType:         jiraAutomationScheduleJobType,
+		Payload:         models.JSONB{},
+		Priority:        models.JobPriorityHigh,
+		MaxAttempts:     1,
+		IntervalSeconds: int(time.Minute / time.Second),
+		Enabled:         true,
+	}
+}
+
+// schedule queues a run of every automation due now and moves each to its
+// next scheduled time. A run missed while nothing was scheduling is run
+// once, not once per missed time.
+func (r *automationRunner) schedule(ctx context.Context, job *models.Job) error {
+	now := time.Now()
+	due, err := r.store.ListDueAutomations(ctx, now, automationScheduleBatchSize)
+	if err != nil {
+		return err
+	}
+
+	queued := 0
+	for _, automation := range due {
+		next, err := automation.NextRun(now)
+		if err != nil {
+			log.Printf("[automation] Failed to schedule automation %d: %v", automation.ID, err)
+			continue
+		}
+		moved, err := r.store.ScheduleAutomation(ctx, automation.ID, automation.NextRunAt, next)
+		if err != nil {
+			log.Printf("[automation] Failed to reschedule automation %d: %v", automation.ID, err)
+			continue
+		}
+		if !moved {
+			continue
+		}
+		if err := r.worker.Enqueue(ctx, JiraAutomationJob(automation.ID, automation.NextRunAt)); err != nil {
+			log.Printf("[automation] Failed to queue automation %d: %v", automation.ID, err)
+			continue
+		}
+		queued++
+	}
+	if queued > 0 {
+		log.Printf("[automation] Queued %d automation runs", queued)
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+)
+
+// autoscaling reports whether the processor count follows the queue.
+func (w *Worker) autoscaling() bool {
+	return w.config.MinConcurrent > 0 && w.config.MinConcurrent < w.config.MaxConcurrent
+}
+
+// scaleTo starts or retires processors until n are running. Retired
+// processors finish their current job first.
+func (w *Worker) scaleTo(ctx context.Context, n int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.stopped {
+		return
+	}
+
+	for len(w.processors) < n {
+		quit := make(chan struct{})
+		w.processors = append(w.processors, quit)
+		w.wg.Add(1)
+		go w.processor(ctx, w.nextProcessorID, quit)
+		w.nextProcessorID++
+	}
+	for len(w.processors) > n {
+		last := len(w.processors) - 1
+		close(w.processors[last])
+		w.processors = w.processors[:last]
+	}
+}
+
+// autoscale samples the queue every ScaleInterval and resizes the pool
+// between MinConcurrent and MaxConcurrent.
+func (w *Worker) autoscale(ctx context.Context) {
+	defer w.wg.Done()
+
+	ticker := time.NewTicker(w.config.ScaleInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-w.stopCh:
+			return
+		case <-ticker.C:
+		}
+
+		depth, err := w.store.CountReadyJobs(ctx)
+		if err != nil {
+			log.Printf("[worker] Autoscaler could not read queue depth: %v", err)
+			continue
+		}
+		w.rescale(ctx, depth)
+	}
+}
+
+// rescale picks the processor count for the sampled queue depth: double
+// when jobs wait longer than TargetQueueWait, add one while every processor
+// is busy and jobs are ready, and retire one when the queue is empty and a
+// processor is idle.
+func (w *Worker) rescale(ctx context.Context, depth int) {
+	w.mu.Lock()
+	w.queueDepth = depth
+	if depth == 0 {
+		// Nothing is waiting, so the last observed wait no longer applies.
+		w.queueWait = 0
+	}
+	current := len(w.processors)
+	busy := len(w.activeJobs)
+	wait := w.queueWait
+	w.mu.Unlock()
+
+	target := current
+	switch {
+	case depth > 0 && wait > w.config.TargetQueueWait:
+		target = current * 2
+	case depth > 0 && busy >= current:
+		target = current + 1
+	case depth == 0 && busy < current:
+		target = current - 1
+	}
+	if target > w.config.MaxConcurrent {
+		target = w.config.MaxConcurrent
+	}
+	if target < w.config.MinConcurrent {
+		target = w.config.MinConcurrent
+	}
+	if target == current {
+		return
+	}
+
+	log.Printf("[worker] Scaling processors %d -> %d (ready jobs: %d, queue wait: %v)", current, target, depth, wait.Round(time.Millisecond))
+	w.scaleTo(ctx, target)
+}
+
+// observeQueueWait folds the time a claimed job spent ready but unclaimed
+// into a moving average.
+func (w *Worker) observeQueueWait(job *models.Job) {
+	if job.ProcessedAt == nil {
+		return
+	}
+	due := job.CreatedAt
+	if job.ScheduledFor != nil && job.ScheduledFor.After(due) {
+		due = *job.ScheduledFor
+	}
+	if job.RetryAfter != nil && job.RetryAfter.After(due) {
+		due = *job.RetryAfter
+	}
+	wait := job.ProcessedAt.Sub(due)
+	if wait < 0 {
+		wait = 0
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.queueWait == 0 {
+		w.queueWait = wait
+		return
+	}
+	w.queueWait = (w.queueWait*4 + wait) / 5
+}
@@ -0,0 +1,168 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/backfill"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/jira"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/store"
+)
+
+// issueBackfillJobType loads the issues matching a backfill's JQL into the
+// issue mirror.
+const issueBackfillJobType = "jira_issue_backfill"
+
+// issueBackfillEvent is the webhook event recorded on mirrored issues a
+// backfill stored.
+const issueBackfillEvent = "backfill"
+
+// backfillFields are the issue fields the mirror keeps.
+var backfillFields = []string{"summary", "status", "updated"}
+
+// jiraTimeLayout is how Jira formats timestamps such as updated.
+const jiraTimeLayout = "2006-01-02T15:04:05.000-0700"
+
+// RegisterBackfillJobs registers the issue backfill handler. refresh renews
+// rejected Atlassian OAuth tokens and may be nil.
+func RegisterBackfillJobs(w *Worker, s *store.Store, refresh jira.TokenRefresher) {
+	w.RegisterHandler(issueBackfillJobType, issueBackfillHandler(w, s, refresh))
+
+	log.Println("[worker] Registered issue backfill job handlers: " + issueBackfillJobType)
+}
+
+// IssueBackfillJob returns a job that runs the user's backfill. A retried
+// job continues from the backfill's checkpoint.
+func IssueBackfillJob(userID, backfillID int64) *models.Job {
+	return &models.Job{
+		JobType:     issueBackfillJobType,
+		Payload:     models.JSONB{"user_id": userID, "backfill_id": backfillID},
+		Priority:    models.JobPriorityLow,
+		MaxAttempts: 5,
+	}
+}
+
+// issueBackfillHandler fetches the backfill's issues into the mirror,
+// checkpointing and reporting progress after every page. The last
+// attempt's failure marks the backfill failed; it can then be resumed.
+func issueBackfillHandler(w *Worker, s *store.Store, refresh jira.TokenRefresher) Handler {
+	return func(ctx context.Context, job *models.Job) error {
+		idRaw, ok := job.Payload["backfill_id"].(float64)
+		if !ok {
+			return fmt.Errorf("missing backfill_id in payload")
+		}
+		target, err := s.GetIssueBackfillTarget(ctx, int64(idRaw))
+		if errors.Is(err, store.ErrIssueBackfillNotFound) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("load issue backfill %d: %w", int64(idRaw), err)
+		}
+		b := &target.Backfill
+		if b.Status == models.IssueBackfillCompleted || b.Status == models.IssueBackfillFailed {
+			return nil
+		}
+
+		runErr := runIssueBackfill(ctx, w, s, refresh, job, target)
+		if runErr == nil {
+			return nil
+		}
+		final := job.Attempts >= job.MaxAttempts
+		if err := s.RecordIssueBackfillError(ctx, b.ID, runErr.Error(), final); err != nil {
+			log.Printf("[backfill] Failed to record error of issue backfill %d: %v", b.ID, err)
+		}
+		return fmt.Errorf("issue backfill %d: %w", b.ID, runErr)
+	}
+}
+
+func runIssueBackfill(ctx context.Context, w *Worker, s *store.Store, refresh jira.TokenRefresher, job *models.Job, target *models.IssueBackfillTarget) error {
+	b := &target.Backfill
+	if target.MCPSecret == "" {
+		return errors.New("account has no MCP secret to resolve Jira credentials")
+	}
+	client, err := jira.ForMCPSecret(ctx, s, target.MCPSecret, refresh)
+	if err != nil {
+		return err
+	}
+	engine := backfill.New(backfill.Config{Fields: backfillFields})
+	client.WithRateObserver(engine.Observe)
+
+	// The estimate only feeds progress, so a failure is not fatal.
+	total := 0
+	if b.Total != nil {
+		total = *b.Total
+	} else if count, err := client.ApproximateCount(ctx, b.JQL); err != nil {
+		log.Printf("[backfill] Failed to estimate issues of backfill %d: %v", b.ID, err)
+	} else {
+		total = count
+		b.Total = &count
+	}
+	if err := s.StartIssueBackfill(ctx, b.ID, b.Total); err != nil {
+		return err
+	}
+
+	sink := &mirrorSink{store: s, worker: w, job: job, backfill: b, total: total}
+	cp := backfill.Checkpoint{PageToken: b.NextPageToken, Fetched: b.Fetched}
+	_, err = engine.Run(ctx, client, b.JQL, cp, sink)
+	return err
+}
+
+// mirrorSink stores a backfill's issues in the issue mirror and its
+// checkpoints on the backfill.
+type mirrorSink struct {
+	store    *store.Store
+	worker   *Worker
+	job      *models.Job
+	backfill *models.IssueBackfill
+	total    int
+}
+
+func (m *mirrorSink) StoreIssues(ctx context.Context, issues []jira.Issue) error {
+	for _, issue := range issues {
+		if _, err := m.store.ApplyJiraIssueState(ctx, mirrorState(m.backfill.UserID, issue)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *mirrorSink) SaveCheckpoint(ctx context.Context, cp backfill.Checkpoint) error {
+	if err := m.store.SaveIssueBackfillCheckpoint(ctx, m.backfill.ID, cp.PageToken, cp.Fetched, cp.Done); err != nil {
+		return err
+	}
+	total := m.total
+	if total > 0 || cp.Done {
+		// Jira's estimate can fall short of the issues actually fetched.
+		total = max(total, cp.Fetched)
+	}
+	if err := m.worker.ReportProgress(ctx, m.job, cp.Fetched, total); err != nil {
+		log.Printf("[backfill] Failed to report progress of issue backfill %d: %v", m.backfill.ID, err)
+	}
+	return nil
+}
+
+// mirrorState is the mirrored state of an issue fetched by a backfill. Its
+// event timestamp is the issue's last update, so a webhook delivered for a
+// later change is never overwritten.
+func mirrorState(userID int64, issue jira.Issue) *models.JiraIssueState {
+	state := &models.JiraIssueState{
+		UserID:       userID,
+		IssueID:      issue.ID,
+		IssueKey:     issue.Key,
+		WebhookEvent: issueBackfillEvent,
+	}
+	state.Summary, _ = issue.Fields["summary"].(string)
+	if status, ok := issue.Fields["status"].(map[string]any); ok {
+		state.Status, _ = status["name"].(string)
+	}
+	if updated, ok := issue.Fields["updated"].(string); ok {
+		if at, err := time.Parse(jiraTimeLayout, updated); err == nil {
+			state.EventTimestamp = at.UnixMilli()
+		}
+	}
+	return state
+}
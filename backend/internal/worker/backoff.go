@@ -0,0 +1,109 @@
+package worker
+
+import (
+	"errors"
+	"math/rand"
+	"time"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+)
+
+// BackoffStrategy names how the delay grows between retries of a job
+type BackoffStrategy string
+
+const (
+	// BackoffExponential multiplies the delay by Multiplier each attempt
+	BackoffExponential BackoffStrategy = "exponential"
+	// BackoffFixed waits BaseDelay between every attempt
+	BackoffFixed BackoffStrategy = "fixed"
+	// BackoffFibonacci grows the delay along the Fibonacci sequence, gentler
+	// than doubling for jobs that retry many times
+	BackoffFibonacci BackoffStrategy = "fibonacci"
+	// BackoffRetryAfter waits as long as a rate-limited upstream (Stripe,
+	// Jira) asked in its Retry-After header, falling back to exponential
+	// when the error carries no hint
+	BackoffRetryAfter BackoffStrategy = "retry_after"
+)
+
+// Backoff is a job type's retry profile. Zero fields take the worker's
+// RetryBaseDelay, RetryMaxDelay and RetryBackoffMultiplier; an empty
+// Strategy is exponential.
+type Backoff struct {
+	Strategy   BackoffStrategy
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+	Multiplier float64
+}
+
+// retryAfterHint is implemented by errors that carry an upstream's
+// Retry-After hint, such as *stripe.APIError
+type retryAfterHint interface {
+	RetryAfter() time.Duration
+}
+
+type retryAfterError struct {
+	err   error
+	after time.Duration
+}
+
+func (e *retryAfterError) Error() string             { return e.err.Error() }
+func (e *retryAfterError) Unwrap() error             { return e.err }
+func (e *retryAfterError) RetryAfter() time.Duration { return e.after }
+
+// RetryAfter wraps a handler error with the delay an upstream asked for,
+// for job types registered with BackoffRetryAfter
+func RetryAfter(err error, after time.Duration) error {
+	return &retryAfterError{err: err, after: after}
+}
+
+// RegisterHandlerWithBackoff registers a handler whose retries follow
+// backoff instead of the worker's default exponential profile
+func (w *Worker) RegisterHandlerWithBackoff(jobType string, handler Handler, backoff Backoff) {
+	w.RegisterHandler(jobType, handler)
+	w.backoffs[jobType] = backoff
+}
+
+// retryDelay returns how long to wait before retrying job after err, with
+// ±20% jitter to prevent a thundering herd. Upstream hints are never
+// shortened, only spread out.
+func (w *Worker) retryDelay(job *models.Job, err error) time.Duration {
+	b := w.backoffs[job.JobType]
+	if b.BaseDelay <= 0 {
+		b.BaseDelay = w.config.RetryBaseDelay
+	}
+	if b.MaxDelay <= 0 {
+		b.MaxDelay = w.config.RetryMaxDelay
+	}
+	if b.Multiplier <= 1 {
+		b.Multiplier = w.config.RetryBackoffMultiplier
+	}
+
+	if b.Strategy == BackoffRetryAfter {
+		var hint retryAfterHint
+		if errors.As(err, &hint) && hint.RetryAfter() > 0 {
+			return time.Duration(float64(hint.RetryAfter()) * (1 + 0.2*rand.Float64()))
+		}
+	}
+
+	var delay float64
+	switch b.Strategy {
+	case BackoffFixed:
+		delay = float64(b.BaseDelay)
+	case BackoffFibonacci:
+		delay = float64(b.BaseDelay) * fibonacci(job.Attempts)
+	default:
+		delay = float64(b.BaseDelay) * pow(b.Multiplier, float64(job.Attempts-1))
+	}
+	delay = min(delay, float64(b.MaxDelay))
+
+	return time.Duration(delay * (0.8 + 0.4*rand.Float64()))
+}
+
+// fibonacci returns the nth Fibonacci number, counting from fibonacci(1) = 1
+func fibonacci(n int) float64 {
+	a, b := 0.0, 1.0
+	for i := 0; i < n; i++ {
+		a, b = b, a+b
+	}
+	return a
+}
@@ -3,8 +3,10 @@ package worker
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"time"
 
 	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
 	"github.com/PortNumber53/mcp-jira-thing/backend/internal/store"
@@ -13,7 +15,13 @@ import (
 
 // RegisterBillingJobs registers the plan migration and archival job handlers
 func RegisterBillingJobs(w *Worker, planStore *store.PlanStore, stripe *stripeClient.Client) {
-	w.RegisterHandler("plan_migration", planMigrationHandler(planStore, stripe))
+	// Migrated subscriptions leave the deprecated version, so a migration
+	// that hits Stripe's rate limit can stop and resume where it left off.
+	w.RegisterHandlerWithBackoff("plan_migration", planMigrationHandler(planStore, stripe), Backoff{
+		Strategy:  BackoffRetryAfter,
+		BaseDelay: 5 * time.Second,
+		MaxDelay:  5 * time.Minute,
+	})
 	w.RegisterHandler("plan_archival", planArchivalHandler(planStore, stripe))
 	w.RegisterHandler("plan_migration_check", planMigrationCheckHandler(planStore, w))
 
@@ -59,13 +67,25 @@ func planMigrationHandler(planStore *store.PlanStore, stripe *stripeClient.Clien
 			return nil
 		}
 
+		// Subscribers of versions that migrate at renewal keep the old price
+		// until their current period ends.
+		updatePrice := stripe.UpdateSubscriptionPrice
+		policy, _ := job.Payload["migration_policy"].(string)
+		if models.PlanMigrationPolicy(policy) == models.PlanMigrateAtRenewal {
+			updatePrice = stripe.UpdateSubscriptionPriceAtRenewal
+		}
+
 		log.Printf("[migration] Migrating %d subscriptions from version %d to version %d",
 			len(subs), deprecatedVersionID, newVersionID)
 
 		var migrated, failed int
 		for _, sub := range subs {
 			// Update in Stripe
-			if err := stripe.UpdateSubscriptionPrice(sub.StripeSubscriptionID, newStripePriceID); err != nil {
+			if err := updatePrice(sub.StripeSubscriptionID, newStripePriceID); err != nil {
+				var apiErr *stripeClient.APIError
+				if errors.As(err, &apiErr) && apiErr.RateLimited() {
+					return fmt.Errorf("stripe rate limited after migrating %d subscriptions: %w", migrated, err)
+				}
 				log.Printf("[migration] Failed to migrate subscription %s in Stripe: %v",
 					sub.StripeSubscriptionID, err)
 				failed++
@@ -142,7 +162,8 @@ func planArchivalHandler(planStore *store.PlanStore, stripe *stripeClient.Client
 }
 
 // planMigrationCheckHandler checks for deprecated versions past their grace period
-// and enqueues migration + archival jobs
+// and enqueues migration + archival jobs according to each version's migration
+// policy; grandfathered versions are never returned past their deadline
 func planMigrationCheckHandler(planStore *store.PlanStore, w *Worker) Handler {
 	return func(ctx context.Context, job *models.Job) error {
 		versions, err := planStore.GetDeprecatedVersionsPastDeadline(ctx)
@@ -173,6 +194,7 @@ func planMigrationCheckHandler(planStore *store.PlanStore, w *Worker) Handler {
 				"deprecated_version_id": v.ID,
 				"new_version_id":        activeVersion.ID,
 				"new_stripe_price_id":   newStripePriceID,
+				"migration_policy":      v.MigrationPolicy,
 			})
 			var migrationPayload models.JSONB
 			json.Unmarshal(payload, &migrationPayload)
@@ -216,7 +238,7 @@ func planMigrationCheckHandler(planStore *store.PlanStore, w *Worker) Handler {
 				log.Printf("[migration-check] Failed to enqueue archival for version %d: %v", v.ID, err)
 			}
 
-			log.Printf("[migration-check] Enqueued migration and archival jobs for version %d", v.ID)
+			log.Printf("[migration-check] Enqueued migration (%s) and archival jobs for version %d", v.MigrationPolicy, v.ID)
 		}
 
 		return nil
@@ -0,0 +1,357 @@
+package worker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/events"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/jira"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/mail"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/store"
+)
+
+const (
+	// commentOutboundJobType posts a new Jira comment to the channels
+	// linked to its issue.
+	commentOutboundJobType = "comment_sync_outbound"
+	// commentInboundJobType adds a reply from a channel to the issue as a
+	// Jira comment.
+	commentInboundJobType = "comment_sync_inbound"
+)
+
+// slackPostMessageURL is Slack's chat.postMessage Web API method.
+const slackPostMessageURL = "https://slack.com/api/chat.postMessage"
+
+// slackProvider is the integration token provider holding a tenant's Slack
+// bot token.
+const slackProvider = "slack"
+
+// commentDeliveryTimeout bounds posting a comment to Slack.
+const commentDeliveryTimeout = 15 * time.Second
+
+// slackEscaper escapes the characters Slack reads as control sequences, so
+// comment text cannot mention @channel or forge links.
+var slackEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// RegisterCommentSyncJobs registers the comment sync handlers and queues an
+// outbound sync for every comment_created Jira webhook on bus. replyAddress
+// is the base address replies to emailed comments go to; refresh renews
+// rejected Atlassian OAuth tokens and may be nil.
+func RegisterCommentSyncJobs(w *Worker, s *store.Store, bus *events.Bus, mailer mail.Mailer, replyAddress string, refresh jira.TokenRefresher) {
+	c := &commentSyncer{
+		store:        s,
+		mailer:       mailer,
+		replyAddress: replyAddress,
+		refresh:      refresh,
+		client:       &http.Client{Timeout: commentDeliveryTimeout},
+	}
+	w.RegisterHandler(commentOutboundJobType, c.outbound)
+	w.RegisterHandler(commentInboundJobType, c.inbound)
+
+	if bus != nil {
+		events.OnAsync(bus, func(ctx context.Context, ev events.JiraWebhookReceived) error {
+			if ev.WebhookEvent != "comment_created" {
+				return nil
+			}
+			issueKey, _ := ev.Data["issue_key"].(string)
+			commentID, _ := ev.Data["comment_id"].(string)
+			body, _ := ev.Data["comment_body"].(string)
+			author, _ := ev.Data["comment_author"].(string)
+			if issueKey == "" || commentID == "" || strings.Contains(body, models.CommentSyncMarker) {
+				return nil
+			}
+			return w.Enqueue(ctx, CommentOutboundJob(ev.UserID, issueKey, commentID, author, body))
+		})
+	}
+
+	log.Println("[worker] Registered comment sync job handlers: " + commentOutboundJobType + ", " + commentInboundJobType)
+}
+
+// CommentOutboundJob returns a job that posts a Jira comment to the
+// channels linked to its issue.
+func CommentOutboundJob(userID int64, issueKey, commentID, author, body string) *models.Job {
+	return &models.Job{
+		JobType: commentOutboundJobType,
+		Payload: models.JSONB{
+			"user_id":    userID,
+			"issue_key":  issueKey,
+			"comment_id": commentID,
+			"author":     author,
+			"body":       body,
+		},
+		Priority:    models.JobPriorityNormal,
+		MaxAttempts: 3,
+		DedupWindow: time.Hour,
+	}
+}
+
+// CommentInboundJob returns a job that adds a reply received in a channel
+// to its issue. externalID identifies the reply in the channel: the Slack
+// message ts or the email's Message-ID.
+func CommentInboundJob(channelID int64, externalID, author, text string) *models.Job {
+	return &models.Job{
+		JobType: commentInboundJobType,
+		Payload: models.JSONB{
+			"channel_id":  channelID,
+			"external_id": externalID,
+			"author":      author,
+			"text":        text,
+		},
+		Priority:    models.JobPriorityNormal,
+		MaxAttempts: 3,
+		DedupWindow: time.Hour,
+	}
+}
+
+type commentSyncer struct {
+	store        *store.Store
+	mailer       mail.Mailer
+	replyAddress string
+	refresh      jira.TokenRefresher
+	client       *http.Client
+}
+
+// outbound posts the comment to every enabled channel of its issue that has
+// not received it yet. Comments that came in from a channel are not posted
+// back to it. Channels that fail are retried with the job; the others are
+// not posted to twice.
+func (c *commentSyncer) outbound(ctx context.Context, job *models.Job) error {
+	userIDRaw, ok := job.Payload["user_id"].(float64)
+	if !ok {
+		return fmt.Errorf("missing user_id in payload")
+	}
+	userID := int64(userIDRaw)
+	issueKey, _ := job.Payload["issue_key"].(string)
+	commentID, _ := job.Payload["comment_id"].(string)
+	author, _ := job.Payload["author"].(string)
+	body, _ := job.Payload["body"].(string)
+	if issueKey == "" || commentID == "" {
+		return fmt.Errorf("missing issue_key or comment_id in payload")
+	}
+
+	targets, err := c.store.ListIssueCommentSyncTargets(ctx, userID, issueKey)
+	if err != nil {
+		return fmt.Errorf("load comment channels of %s: %w", issueKey, err)
+	}
+
+	var errs []error
+	for i := range targets {
+		target := &targets[i]
+		ch := &target.Channel
+		echo, err := c.store.IsInboundJiraComment(ctx, ch.ID, commentID)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if echo {
+			continue
+		}
+		claimed, err := c.store.ClaimCommentSync(ctx, ch.ID, models.CommentSyncOutbound, commentID)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+
+		postErr := c.post(ctx, target, author, body)
+		if postErr != nil {
+			if err := c.store.ReleaseCommentSync(ctx, ch.ID, models.CommentSyncOutbound, commentID); err != nil {
+				log.Printf("[comments] Failed to release comment %s on channel %d: %v", commentID, ch.ID, err)
+			}
+			errs = append(errs, fmt.Errorf("%s channel %d: %w", ch.Kind, ch.ID, postErr))
+		}
+		c.record(ctx, job, ch, postErr)
+	}
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("sync comment %s of %s: %w", commentID, issueKey, err)
+	}
+	return nil
+}
+
+// post sends the comment to the channel.
+func (c *commentSyncer) post(ctx context.Context, target *models.CommentSyncTarget, author, body string) error {
+	ch := &target.Channel
+	if author == "" {
+		author = "Someone"
+	}
+
+	switch ch.Kind {
+	case models.CommentChannelSlack:
+		token, err := c.store.GetIntegrationToken(ctx, target.Email, slackProvider)
+		if err != nil {
+			return err
+		}
+		if token == nil {
+			return errors.New("slack is not connected")
+		}
+		text := fmt.Sprintf("*%s* commented on %s:\n%s\n_%s_", slackEscaper.Replace(author), ch.IssueKey, slackEscaper.Replace(body), models.CommentSyncMarker)
+		ts, err := c.postSlack(ctx, token.AccessToken, ch.SlackChannel, ch.SlackThreadTS, text)
+		if err != nil {
+			return err
+		}
+		if ch.SlackThreadTS == "" {
+			if err := c.store.SetCommentChannelThread(ctx, ch.ID, ts); err != nil {
+				log.Printf("[comments] Failed to record the Slack thread of channel %d: %v", ch.ID, err)
+			}
+		}
+		return nil
+
+	default:
+		if c.mailer == nil {
+			return errors.New("no mailer configured")
+		}
+		if c.replyAddress == "" {
+			return errors.New("no reply address configured")
+		}
+		return c.mailer.Send(ctx, mail.Message{
+			To:      ch.EmailAddress,
+			Subject: fmt.Sprintf("[%s] New comment from %s", ch.IssueKey, author),
+			Body: fmt.Sprintf("%s commented on %s:\n\n%s\n\n-- \nReply to this email to comment on %s.\n%s\n",
+				author, ch.IssueKey, body, ch.IssueKey, models.CommentSyncMarker),
+			ReplyTo: ch.ReplyAddress(c.replyAddress),
+		})
+	}
+}
+
+// postSlack posts text to a Slack channel, in the thread when threadTS is
+// set, and returns the ts of the new message.
+func (c *commentSyncer) postSlack(ctx context.Context, token, channel, threadTS, text string) (string, error) {
+	body := map[string]any{"channel": channel, "text": text, "unfurl_links": false}
+	if threadTS != "" {
+		body["thread_ts"] = threadTS
+	}
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return "", fmt.Errorf("encode slack message: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, slackPostMessageURL, bytes.NewReader(payload))
+	if err != nil {
+		return "", fmt.Errorf("build slack request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("post to slack: %w", err)
+	}
+	defer resp.Body.Close()
+	var out struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+		TS    string `json:"ts"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return "", fmt.Errorf("post to slack: status %d", resp.StatusCode)
+	}
+	if !out.OK {
+		return "", fmt.Errorf("post to slack: %s", out.Error)
+	}
+	return out.TS, nil
+}
+
+// inbound adds a reply from a channel to the channel's issue as a Jira
+// comment ending with the sync marker, and records the comment so the
+// outbound sync does not post it back.
+func (c *commentSyncer) inbound(ctx context.Context, job *models.Job) error {
+	idRaw, ok := job.Payload["channel_id"].(float64)
+	if !ok {
+		return fmt.Errorf("missing channel_id in payload")
+	}
+	externalID, _ := job.Payload["external_id"].(string)
+	author, _ := job.Payload["author"].(string)
+	text, _ := job.Payload["text"].(string)
+	if externalID == "" || strings.TrimSpace(text) == "" {
+		return nil
+	}
+
+	target, err := c.store.GetCommentSyncTarget(ctx, int64(idRaw))
+	if errors.Is(err, store.ErrCommentChannelNotFound) {
+		// Deleted after the reply was queued.
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("load comment channel %d: %w", int64(idRaw), err)
+	}
+	ch := &target.Channel
+	if !ch.Enabled {
+		return nil
+	}
+	claimed, err := c.store.ClaimCommentSync(ctx, ch.ID, models.CommentSyncInbound, externalID)
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		return nil
+	}
+
+	commentID, addErr := c.addComment(ctx, target, author, text)
+	if addErr != nil {
+		if err := c.store.ReleaseCommentSync(ctx, ch.ID, models.CommentSyncInbound, externalID); err != nil {
+			log.Printf("[comments] Failed to release reply %s on channel %d: %v", externalID, ch.ID, err)
+		}
+		c.record(ctx, job, ch, addErr)
+		return fmt.Errorf("add reply to %s: %w", ch.IssueKey, addErr)
+	}
+	if err := c.store.SetCommentSyncJiraComment(ctx, ch.ID, externalID, commentID); err != nil {
+		log.Printf("[comments] Failed to record comment %s for reply %s: %v", commentID, externalID, err)
+	}
+	c.record(ctx, job, ch, nil)
+	return nil
+}
+
+// addComment adds the reply to the issue and returns the new comment's ID.
+func (c *commentSyncer) addComment(ctx context.Context, target *models.CommentSyncTarget, author, text string) (string, error) {
+	if target.MCPSecret == "" {
+		return "", errors.New("account has no MCP secret to resolve Jira credentials")
+	}
+	client, err := jira.ForMCPSecret(ctx, c.store, target.MCPSecret, c.refresh)
+	if err != nil {
+		return "", err
+	}
+	via := "Slack"
+	if target.Channel.Kind == models.CommentChannelEmail {
+		via = "email"
+	}
+	if author == "" {
+		author = "Someone"
+	}
+	comment, err := client.AddComment(ctx, target.Channel.IssueKey,
+		fmt.Sprintf("%s\n\n%s via %s %s", strings.TrimSpace(text), author, via, models.CommentSyncMarker))
+	if err != nil {
+		return "", err
+	}
+	return comment.ID, nil
+}
+
+// record stores the outcome of syncing through the channel and notifies
+// the owner when the job's last attempt fails.
+func (c *commentSyncer) record(ctx context.Context, job *models.Job, ch *models.CommentChannel, syncErr error) {
+	var message *string
+	if syncErr != nil {
+		text := syncErr.Error()
+		message = &text
+	}
+	if err := c.store.RecordCommentChannelSync(ctx, ch.ID, time.Now(), message); err != nil {
+		log.Printf("[comments] Failed to record sync of channel %d: %v", ch.ID, err)
+	}
+	if syncErr == nil || job.Attempts < job.MaxAttempts {
+		return
+	}
+
+	title := fmt.Sprintf("Comments on %s could not be synced", ch.IssueKey)
+	body := fmt.Sprintf("A comment could not be synced with the issue's %s channel: %v.", ch.Kind, syncErr)
+	if err := c.store.CreateUserNotification(ctx, ch.UserID, models.NotificationKindCommentSyncFailure, "warning", title, body, models.JSONB{"comment_channel_id": ch.ID}); err != nil {
+		log.Printf("[comments] Failed to notify user %d about channel %d: %v", ch.UserID, ch.ID, err)
+	}
+}
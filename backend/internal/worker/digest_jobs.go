@@ -0,0 +1,252 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/mail"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/store"
+)
+
+// usageDigestJobType mails a user the summary of one week of their usage.
+const usageDigestJobType = "usage_digest"
+
+// RegisterDigestJobs registers the weekly usage digest handler.
+func RegisterDigestJobs(w *Worker, s *store.Store, mailer mail.Mailer) {
+	w.RegisterHandler(usageDigestJobType, usageDigestHandler(s, mailer))
+
+	log.Println("[worker] Registered digest job handlers: " + usageDigestJobType)
+}
+
+// UsageDigestJob returns a job that mails the user's digest for the week
+// starting weekStart.
+func UsageDigestJob(userID int64, weekStart time.Time) *models.Job {
+	return &models.Job{
+		JobType: usageDigestJobType,
+		Payload: models.JSONB{
+			"user_id":    userID,
+			"week_start": weekStart.UTC().Format("2006-01-02"),
+		},
+		Priority:    models.JobPriorityLow,
+		MaxAttempts: 3,
+		DedupWindow: 24 * time.Hour,
+	}
+}
+
+// usageDigestHandler renders the week's digest and mails it, then records
+// the send so the scheduler does not queue it again.
+func usageDigestHandler(s *store.Store, mailer mail.Mailer) Handler {
+	return func(ctx context.Context, job *models.Job) error {
+		userIDRaw, ok := job.Payload["user_id"].(float64)
+		if !ok {
+			return fmt.Errorf("missing user_id in payload")
+		}
+		userID := int64(userIDRaw)
+		weekStartRaw, _ := job.Payload["week_start"].(string)
+		weekStart, err := time.Parse("2006-01-02", weekStartRaw)
+		if err != nil {
+			return fmt.Errorf("invalid week_start in payload: %w", err)
+		}
+		if mailer == nil {
+			return fmt.Errorf("no mailer configured")
+		}
+
+		digest, err := s.GetUsageDigest(ctx, userID, weekStart)
+		if errors.Is(err, store.ErrUserNotFound) {
+			// The account was deleted after the digest was queued.
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("build usage digest for user %d: %w", userID, err)
+		}
+
+		subject, body := renderUsageDigest(digest)
+		if err := mailer.Send(ctx, mail.Message{To: digest.Email, Subject: subject, Body: body}); err != nil {
+			return fmt.Errorf("send usage digest to user %d: %w", userID, err)
+		}
+
+		return s.MarkUsageDigestSent(ctx, userID, weekStart)
+	}
+}
+
+// renderUsageDigest formats a digest as a plain text email.
+func renderUsageDigest(d *models.UsageDigest) (subject, body string) {
+	lastDay := d.WeekEnd.AddDate(0, 0, -1)
+	subject = fmt.Sprintf("Your MCP Jira usage for %s - %s", d.WeekStart.Format("Jan 2"), lastDay.Format("Jan 2"))
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Here is your usage for the week of %s to %s (UTC).\n\n", d.WeekStart.Format("Jan 2"), lastDay.Format("Jan 2, 2006"))
+	fmt.Fprintf(&b, "Requests:    %d (%d errors)\n", d.Requests, d.Errors)
+	fmt.Fprintf(&b, "Tool calls:  %d (%d errors)\n", d.ToolCalls, d.ToolErrors)
+	fmt.Fprintf(&b, "Cost units:  %d\n", d.CostUnits)
+
+	if len(d.TopTools) > 0 {
+		b.WriteString("\nMost used tools:\n")
+		for _, t := range d.TopTools {
+			fmt.Fprintf(&b, "  %-28s %d calls, %d cost units", t.Tool, t.Invocations, t.CostUnits)
+			if t.Errors > 0 {
+				fmt.Fprintf(&b, ", %d errors", t.Errors)
+			}
+			b.WriteString("\n")
+		}
+	}
+
+	if len(d.TopProjects) > 0 {
+		b.WriteString("\nTop projects touched:\n")
+		for _, p := range d.TopProjects {
+			fmt.Fprintf(&b, "  %-28s %d calls", p.ProjectKey, p.ToolCalls)
+			if p.Errors > 0 {
+				fmt.Fprintf(&b, ", %d errors", p.Errors)
+			}
+			b.WriteString("\n")
+		}
+	}
+
+	fmt.Fprintf(&b, "\nQuota (%s plan, %s to date):\n", d.PlanName, lastDay.Format("January"))
+	b.WriteString("  Requests:   " + quotaLine(int64(d.RequestsMonthToDate), d.RequestQuota) + "\n")
+	b.WriteString("  Cost units: " + quotaLine(d.CostUnitsMonthToDate, d.CostUnitQuota) + "\n")
+
+	b.WriteString("\nYou can turn off these emails in your preferences (usage_digest).\n")
+	return subject, b.String()
+}
+
+func quotaLine(used int64, quota *int) string {
+	if quota == nil {
+		return fmt.Sprintf("%d (unlimited)", used)
+	}
+	if *quota <= 0 {
+		return fmt.Sprintf("%d of %d", used, *quota)
+	}
+	return fmt.Sprintf("%d of %d (%.0f%%)", used, *quota, float64(used)*100/float64(*quota))
+}
+
+// DigestConfig holds usage digest scheduler configuration
+type DigestConfig struct {
+	// Interval is the time between checks for digests that are due
+	Interval time.Duration
+}
+
+// DefaultDigestConfig returns sensible default configuration
+func DefaultDigestConfig() DigestConfig {
+	return DigestConfig{
+		Interval: time.Hour,
+	}
+}
+
+// DigestScheduler queues a usage digest job for every eligible user once
+// the UTC week (Monday to Sunday) has ended. Users already sent the week's
+// digest are skipped, so checking every interval is safe.
+type DigestScheduler struct {
+	config DigestConfig
+	store  *store.Store
+	worker *Worker
+
+	wg      sync.WaitGroup
+	stopCh  chan struct{}
+	stopped bool
+	mu      sync.Mutex
+}
+
+// NewDigestScheduler creates a new DigestScheduler instance
+func NewDigestScheduler(config DigestConfig, s *store.Store, w *Worker) *DigestScheduler {
+	if config.Interval <= 0 {
+		config.Interval = DefaultDigestConfig().Interval
+	}
+
+	return &DigestScheduler{
+		config: config,
+		store:  s,
+		worker: w,
+		stopCh: make(chan struct{}),
+	}
+}
+
+// Start queues due digests immediately and then on every interval
+func (d *DigestScheduler) Start(ctx context.Context) {
+	d.wg.Add(1)
+	go d.loop(ctx)
+	log.Printf("[digest] Usage digests started (interval %v)", d.config.Interval)
+}
+
+// Stop waits for an in-flight check to finish and stops the loop
+func (d *DigestScheduler) Stop(ctx context.Context) error {
+	d.mu.Lock()
+	if d.stopped {
+		d.mu.Unlock()
+		return nil
+	}
+	d.stopped = true
+	close(d.stopCh)
+	d.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		log.Printf("[digest] Usage digests stopped")
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("digest scheduler shutdown: %w", ctx.Err())
+	}
+}
+
+func (d *DigestScheduler) loop(ctx context.Context) {
+	defer d.wg.Done()
+
+	ticker := time.NewTicker(d.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		if n, err := d.enqueueDue(ctx, time.Now()); err != nil {
+			log.Printf("[digest] Schedule error: %v", err)
+		} else if n > 0 {
+			log.Printf("[digest] Queued %d usage digests", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-d.stopCh:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// enqueueDue queues digests for the last complete week before now.
+func (d *DigestScheduler) enqueueDue(ctx context.Context, now time.Time) (int, error) {
+	weekStart := lastCompleteWeek(now)
+	userIDs, err := d.store.ListUsageDigestRecipients(ctx, weekStart)
+	if err != nil {
+		return 0, err
+	}
+
+	queued := 0
+	for _, userID := range userIDs {
+		if err := d.worker.Enqueue(ctx, UsageDigestJob(userID, weekStart)); err != nil {
+			log.Printf("[digest] Failed to queue digest for user %d: %v", userID, err)
+			continue
+		}
+		queued++
+	}
+	return queued, nil
+}
+
+// lastCompleteWeek returns the Monday (UTC) starting the most recent week
+// that has fully ended.
+func lastCompleteWeek(now time.Time) time.Time {
+	now = now.UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	sinceMonday := (int(today.Weekday()) + 6) % 7
+	return today.AddDate(0, 0, -sinceMonday-7)
+}
@@ -0,0 +1,95 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/store"
+)
+
+// domainVerificationJobType checks an organization domain's DNS TXT
+// challenge. A missing record fails the job so it is retried with backoff
+// while DNS propagates.
+const domainVerificationJobType = "domain_verification"
+
+// RegisterDomainJobs registers the organization domain verification handler
+func RegisterDomainJobs(w *Worker, s *store.Store) {
+	w.RegisterHandler(domainVerificationJobType, domainVerificationHandler(s))
+
+	log.Println("[worker] Registered domain job handlers: " + domainVerificationJobType)
+}
+
+// DomainVerificationJob returns a job that verifies the organization's
+// claimed domain.
+func DomainVerificationJob(orgID int64, domain string) *models.Job {
+	return &models.Job{
+		JobType: domainVerificationJobType,
+		Payload: models.JSONB{
+			"org_id": orgID,
+			"domain": domain,
+		},
+		Priority:    models.JobPriorityNormal,
+		MaxAttempts: 20,
+	}
+}
+
+// domainVerificationHandler looks up the domain's challenge record and marks
+// the domain verified when it carries the expected token
+func domainVerificationHandler(s *store.Store) Handler {
+	return func(ctx context.Context, job *models.Job) error {
+		orgIDRaw, ok := job.Payload["org_id"].(float64)
+		if !ok {
+			return fmt.Errorf("missing org_id in payload")
+		}
+		orgID := int64(orgIDRaw)
+		domainName, _ := job.Payload["domain"].(string)
+		if domainName == "" {
+			return fmt.Errorf("missing domain in payload")
+		}
+
+		domain, err := s.GetOrganizationDomain(ctx, orgID, domainName)
+		if err != nil {
+			return fmt.Errorf("load domain %s for org %d: %w", domainName, orgID, err)
+		}
+		if domain.VerifiedAt != nil {
+			return nil
+		}
+
+		name, value := domain.VerificationRecord()
+		checkErr := checkTXTRecord(ctx, name, value)
+		if err := s.RecordDomainVerification(ctx, orgID, domain.Domain, checkErr == nil, errorString(checkErr)); err != nil {
+			return fmt.Errorf("record verification of %s: %w", domain.Domain, err)
+		}
+		if checkErr != nil {
+			return fmt.Errorf("verify %s: %w", domain.Domain, checkErr)
+		}
+
+		log.Printf("[domain-verification] Verified %s for org %d", domain.Domain, orgID)
+		return nil
+	}
+}
+
+// checkTXTRecord returns nil when name has a TXT record equal to value.
+func checkTXTRecord(ctx context.Context, name, value string) error {
+	records, err := net.DefaultResolver.LookupTXT(ctx, name)
+	if err != nil {
+		return fmt.Errorf("TXT lookup for %s failed: %w", name, err)
+	}
+	for _, record := range records {
+		if strings.TrimSpace(record) == value {
+			return nil
+		}
+	}
+	return fmt.Errorf("no TXT record for %s matches the verification token", name)
+}
+
+func errorString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
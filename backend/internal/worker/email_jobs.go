@@ -0,0 +1,84 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/mail"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/store"
+)
+
+// emailVerificationJobType mails a link that confirms the user owns their
+// email address. The token is created when the job runs so it never sits in
+// the job payload.
+const emailVerificationJobType = "email_verification"
+
+// emailVerificationTTL is how long a verification link stays valid.
+const emailVerificationTTL = 24 * time.Hour
+
+// RegisterEmailJobs registers the email verification handler. verifyURL is
+// the public endpoint that consumes the token.
+func RegisterEmailJobs(w *Worker, s *store.Store, mailer mail.Mailer, verifyURL string) {
+	w.RegisterHandler(emailVerificationJobType, emailVerificationHandler(s, mailer, verifyURL))
+
+	log.Println("[worker] Registered email job handlers: " + emailVerificationJobType)
+}
+
+// EmailVerificationJob returns a job that mails a verification link for the
+// user's email.
+func EmailVerificationJob(userID int64, email string) *models.Job {
+	return &models.Job{
+		JobType: emailVerificationJobType,
+		Payload: models.JSONB{
+			"user_id": userID,
+			"email":   email,
+		},
+		Priority:    models.JobPriorityHigh,
+		MaxAttempts: 5,
+	}
+}
+
+// emailVerificationHandler issues a token and mails the link. Emails that
+// were verified in the meantime are skipped.
+func emailVerificationHandler(s *store.Store, mailer mail.Mailer, verifyURL string) Handler {
+	return func(ctx context.Context, job *models.Job) error {
+		userIDRaw, ok := job.Payload["user_id"].(float64)
+		if !ok {
+			return fmt.Errorf("missing user_id in payload")
+		}
+		userID := int64(userIDRaw)
+		email, _ := job.Payload["email"].(string)
+		if email == "" {
+			return fmt.Errorf("missing email in payload")
+		}
+		if mailer == nil {
+			return fmt.Errorf("no mailer configured")
+		}
+
+		token, err := s.CreateEmailVerification(ctx, userID, email, emailVerificationTTL)
+		if errors.Is(err, store.ErrEmailAlreadyVerified) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("create verification for user %d: %w", userID, err)
+		}
+
+		link := verifyURL + "?token=" + url.QueryEscape(token)
+		if err := mailer.Send(ctx, mail.Message{
+			To:      email,
+			Subject: "Verify your email address",
+			Body: "Confirm that this is your email address to finish activating your account:\n\n" +
+				link + "\n\nThe link expires in 24 hours. If you did not sign up, you can ignore this email.\n",
+		}); err != nil {
+			return fmt.Errorf("send verification email to user %d: %w", userID, err)
+		}
+
+		log.Printf("[email-verification] Sent verification link to user %d", userID)
+		return nil
+	}
+}
@@ -0,0 +1,146 @@
+package worker
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"log"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+)
+
+// FaultConfig holds fault injection rates for exercising retry and backoff
+// paths outside production. Rates are probabilities between 0 and 1.
+type FaultConfig struct {
+	// DelayRate is the chance a handler is held back before it runs
+	DelayRate float64
+	// MaxDelay is the longest injected handler delay
+	MaxDelay time.Duration
+	// FailRate is the chance a handler fails without running
+	FailRate float64
+	// DBDropRate is the chance a job store call fails as if its database
+	// connection dropped. A dropped completion leaves the job in processing.
+	DBDropRate float64
+}
+
+// Enabled reports whether any fault is configured
+func (c FaultConfig) Enabled() bool {
+	return c.DelayRate > 0 || c.FailRate > 0 || c.DBDropRate > 0
+}
+
+// String formats the config in the form accepted by ParseFaultConfig
+func (c FaultConfig) String() string {
+	return fmt.Sprintf("delay=%g:%s,fail=%g,db=%g", c.DelayRate, c.MaxDelay, c.FailRate, c.DBDropRate)
+}
+
+// ParseFaultConfig parses a comma separated fault spec such as
+// "delay=0.2:2s,fail=0.1,db=0.05". The delay rate defaults MaxDelay to one
+// second when no duration is given.
+func ParseFaultConfig(spec string) (FaultConfig, error) {
+	var cfg FaultConfig
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return FaultConfig{}, fmt.Errorf("fault %q: expected name=rate", part)
+		}
+
+		rateStr := value
+		if name == "delay" {
+			var maxDelay string
+			rateStr, maxDelay, _ = strings.Cut(value, ":")
+			cfg.MaxDelay = time.Second
+			if maxDelay != "" {
+				d, err := time.ParseDuration(maxDelay)
+				if err != nil || d <= 0 {
+					return FaultConfig{}, fmt.Errorf("fault %q: invalid max delay", part)
+				}
+				cfg.MaxDelay = d
+			}
+		}
+		rate, err := strconv.ParseFloat(rateStr, 64)
+		if err != nil || rate < 0 || rate > 1 {
+			return FaultConfig{}, fmt.Errorf("fault %q: rate must be between 0 and 1", part)
+		}
+
+		switch name {
+		case "delay":
+			cfg.DelayRate = rate
+		case "fail":
+			cfg.FailRate = rate
+		case "db":
+			cfg.DBDropRate = rate
+		default:
+			return FaultConfig{}, fmt.Errorf("fault %q: unknown fault %q", part, name)
+		}
+	}
+	return cfg, nil
+}
+
+// faultInjector rolls the configured faults. A nil injector injects nothing.
+type faultInjector struct {
+	config FaultConfig
+
+	mu  sync.Mutex
+	rnd *rand.Rand
+}
+
+func newFaultInjector(config FaultConfig) *faultInjector {
+	return &faultInjector{config: config, rnd: rand.New(rand.NewSource(time.Now().UnixNano()))}
+}
+
+// roll reports whether an event with the given probability happens
+func (f *faultInjector) roll(rate float64) bool {
+	if rate <= 0 {
+		return false
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.rnd.Float64() < rate
+}
+
+func (f *faultInjector) randomDelay() time.Duration {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return time.Duration(f.rnd.Int63n(int64(f.config.MaxDelay) + 1))
+}
+
+// beforeHandler delays and/or fails a job before its handler runs
+func (f *faultInjector) beforeHandler(ctx context.Context, job *models.Job) error {
+	if f == nil {
+		return nil
+	}
+
+	if f.roll(f.config.DelayRate) {
+		delay := f.randomDelay()
+		log.Printf("[worker] fault: delaying job %d by %v", job.ID, delay)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+
+	if f.roll(f.config.FailRate) {
+		log.Printf("[worker] fault: failing job %d", job.ID)
+		return fmt.Errorf("fault: injected failure for job type %s", job.JobType)
+	}
+	return nil
+}
+
+// storeCall fails a job store operation as if the connection had dropped
+func (f *faultInjector) storeCall(op string) error {
+	if f == nil || !f.roll(f.config.DBDropRate) {
+		return nil
+	}
+	log.Printf("[worker] fault: dropping database connection during %s", op)
+	return fmt.Errorf("fault: %s: %w", op, driver.ErrBadConn)
+}
@@ -0,0 +1,139 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/jira"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/store"
+)
+
+// fieldOptionSyncJobType syncs the select field options of one tenant's
+// projects from Jira.
+const fieldOptionSyncJobType = "jira_field_options_sync"
+
+// fieldOptionSyncAllJobType queues a field option sync for every tenant.
+const fieldOptionSyncAllJobType = "jira_field_options_sync_all"
+
+// fieldOptionSyncBatchSize caps the tenants queued per sync.
+const fieldOptionSyncBatchSize = 5000
+
+// fieldOptionProjectWindow is how far back tool calls make a project worth
+// syncing.
+const fieldOptionProjectWindow = 30 * 24 * time.Hour
+
+// staleFieldOptionAge is how long options of projects that are no longer
+// synced are kept.
+const staleFieldOptionAge = 7 * 24 * time.Hour
+
+// RegisterFieldOptionJobs registers the field option sync handlers. refresh
+// renews rejected Atlassian OAuth tokens and may be nil.
+func RegisterFieldOptionJobs(w *Worker, s *store.Store, refresh jira.TokenRefresher) {
+	w.RegisterHandler(fieldOptionSyncJobType, fieldOptionSyncHandler(s, refresh))
+	w.RegisterHandler(fieldOptionSyncAllJobType, fieldOptionSyncAllHandler(s, w))
+
+	log.Println("[worker] Registered field option job handlers: " + fieldOptionSyncJobType + ", " + fieldOptionSyncAllJobType)
+}
+
+// FieldOptionSyncJob returns a job that syncs the user's field options.
+func FieldOptionSyncJob(userID int64) *models.Job {
+	return &models.Job{
+		JobType:     fieldOptionSyncJobType,
+		Payload:     models.JSONB{"user_id": userID},
+		Priority:    models.JobPriorityLow,
+		MaxAttempts: 3,
+		DedupWindow: time.Hour,
+	}
+}
+
+// fieldOptionSyncHandler replaces the stored options of each of the user's
+// recent projects with those Jira reports. Projects that cannot be read are
+// skipped; their options expire after staleFieldOptionAge.
+func fieldOptionSyncHandler(s *store.Store, refresh jira.TokenRefresher) Handler {
+	return func(ctx context.Context, job *models.Job) error {
+		userIDRaw, ok := job.Payload["user_id"].(float64)
+		if !ok {
+			return fmt.Errorf("missing user_id in payload")
+		}
+		userID := int64(userIDRaw)
+
+		now := time.Now()
+		target, err := s.GetFieldOptionSyncTarget(ctx, userID, now.Add(-fieldOptionProjectWindow))
+		if errors.Is(err, store.ErrUserNotFound) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("load field option sync target for user %d: %w", userID, err)
+		}
+		if target.MCPSecret == "" || len(target.ProjectKeys) == 0 {
+			return nil
+		}
+
+		client, err := jira.ForMCPSecret(ctx, s, target.MCPSecret, refresh)
+		if err != nil {
+			return fmt.Errorf("jira client for user %d: %w", userID, err)
+		}
+		synced := 0
+		for _, projectKey := range target.ProjectKeys {
+			options, err := client.ProjectFieldOptions(ctx, projectKey)
+			if err != nil {
+				log.Printf("[field-options] Failed to read options of project %s for user %d: %v", projectKey, userID, err)
+				continue
+			}
+			if err := s.ReplaceJiraFieldOptions(ctx, userID, client.BaseURL(), projectKey, options); err != nil {
+				return fmt.Errorf("store options of project %s for user %d: %w", projectKey, userID, err)
+			}
+			synced++
+		}
+		if synced == 0 {
+			return fmt.Errorf("no projects of user %d could be read", userID)
+		}
+
+		if err := s.DeleteStaleJiraFieldOptions(ctx, userID, now.Add(-staleFieldOptionAge)); err != nil {
+			log.Printf("[field-options] Failed to delete stale options for user %d: %v", userID, err)
+		}
+		return nil
+	}
+}
+
+// FieldOptionSyncAllJob is the builtin recurring job that queues a field
+// option sync for every tenant that uses Jira projects, every six hours.
+func FieldOptionSyncAllJob() models.RecurringJob {
+	return models.RecurringJob{
+		Slug:            "jira-field-options-sync",
+		JobType:         fieldOptionSyncAllJobType,
+		Payload:         models.JSONB{},
+		Priority:        models.JobPriorityLow,
+		MaxAttempts:     1,
+		IntervalSeconds: int(6 * time.Hour / time.Second),
+		Enabled:         true,
+	}
+}
+
+// fieldOptionSyncAllHandler queues a sync for every tenant with projects to
+// sync.
+func fieldOptionSyncAllHandler(s *store.Store, w *Worker) Handler {
+	return func(ctx context.Context, job *models.Job) error {
+		users, err := s.ListFieldOptionSyncUsers(ctx, time.Now().Add(-fieldOptionProjectWindow), fieldOptionSyncBatchSize)
+		if err != nil {
+			return err
+		}
+
+		queued := 0
+		for _, userID := range users {
+			if err := w.Enqueue(ctx, FieldOptionSyncJob(userID)); err != nil {
+				log.Printf("[field-options] Failed to queue field option sync for user %d: %v", userID, err)
+				continue
+			}
+			queued++
+		}
+		if queued > 0 {
+			log.Printf("[field-options] Queued %d field option syncs", queued)
+		}
+		return nil
+	}
+}
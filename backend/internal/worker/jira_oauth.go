@@ -0,0 +1,104 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/jira"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/store"
+)
+
+// jiraTokenRefreshJobType refreshes the Atlassian OAuth access tokens that
+// are about to expire.
+const jiraTokenRefreshJobType = "jira_oauth_refresh"
+
+// jiraTokenRefreshInterval is the time between scans for expiring tokens.
+const jiraTokenRefreshInterval = 5 * time.Minute
+
+// jiraTokenRefreshLead is how long before expiry a token is refreshed; it
+// exceeds jiraTokenRefreshInterval so no token expires between scans.
+const jiraTokenRefreshLead = 15 * time.Minute
+
+// RegisterJiraOAuthJobs registers the Atlassian OAuth token refresh handler.
+// oauth is nil when Atlassian OAuth is not configured; refreshes then do
+// nothing.
+func RegisterJiraOAuthJobs(w *Worker, s *store.Store, oauth *jira.OAuth) {
+	r := &JiraTokenRefresher{store: s, oauth: oauth}
+	w.RegisterHandler(jiraTokenRefreshJobType, func(ctx context.Context, job *models.Job) error {
+		if r.oauth == nil {
+			return nil
+		}
+		r.RefreshExpiring(ctx)
+		return nil
+	})
+
+	log.Println("[worker] Registered Jira OAuth job handlers: " + jiraTokenRefreshJobType)
+}
+
+// JiraTokenRefreshJob is the builtin recurring job that refreshes Atlassian
+// OAuth access tokens before they expire, so Jira calls made with them never
+// see an expired token.
+func JiraTokenRefreshJob() models.RecurringJob {
+	return models.RecurringJob{
+		Slug:            "jira-oauth-refresh",
+		JobType:         jiraTokenRefreshJobType,
+		Payload:         models.JSONB{},
+		Priority:        models.JobPriorityHigh,
+		MaxAttempts:     1,
+		IntervalSeconds: int(jiraTokenRefreshInterval / time.Second),
+		Enabled:         true,
+	}
+}
+
+// JiraTokenRefresher refreshes Atlassian OAuth access tokens.
+type JiraTokenRefresher struct {
+	store *store.Store
+	oauth *jira.OAuth
+}
+
+// RefreshExpiring refreshes every token expiring within the lead time and
+// returns how many were refreshed. A token whose grant Atlassian revoked
+// loses its refresh token; its user has to connect Jira again.
+func (r *JiraTokenRefresher) RefreshExpiring(ctx context.Context) int {
+	tokens, err := r.store.ListExpiringJiraOAuthTokens(ctx, time.Now().Add(jiraTokenRefreshLead))
+	if err != nil {
+		log.Printf("[jira-oauth] List expiring tokens error: %v", err)
+		return 0
+	}
+
+	refreshed := 0
+	for _, t := range tokens {
+		if ctx.Err() != nil {
+			break
+		}
+		token, err := r.oauth.Refresh(ctx, *t.RefreshToken)
+		if errors.Is(err, jira.ErrInvalidGrant) {
+			log.Printf("[jira-oauth] Grant revoked for user %d, dropping refresh token", t.UserID)
+			if err := r.store.UpdateJiraOAuthToken(ctx, t.ID, "", "", *t.ExpiresAt); err != nil {
+				log.Printf("[jira-oauth] Clear refresh token error for user %d: %v", t.UserID, err)
+			}
+			continue
+		}
+		if err != nil {
+			log.Printf("[jira-oauth] Refresh error for user %d: %v", t.UserID, err)
+			continue
+		}
+
+		refreshToken := token.RefreshToken
+		if refreshToken == "" {
+			refreshToken = *t.RefreshToken
+		}
+		if err := r.store.UpdateJiraOAuthToken(ctx, t.ID, token.AccessToken, refreshToken, token.ExpiresAt); err != nil {
+			log.Printf("[jira-oauth] Save refreshed token error for user %d: %v", t.UserID, err)
+			continue
+		}
+		refreshed++
+	}
+	if refreshed > 0 {
+		log.Printf("[jira-oauth] Refreshed %d access tokens", refreshed)
+	}
+	return refreshed
+}
@@ -0,0 +1,213 @@
+package worker
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// DefaultLeaderLockKey is the advisory lock all replicas contend for.
+const DefaultLeaderLockKey int64 = 0x6d6a745f6c656164 // "mjt_lead"
+
+// LeaderConfig holds leader election configuration
+type LeaderConfig struct {
+	// LockKey identifies the session advisory lock; every replica must use
+	// the same key
+	LockKey int64
+	// RetryInterval is how often followers try to take the lock, bounding
+	// how long failover takes after the leader dies
+	RetryInterval time.Duration
+	// CheckInterval is how often the leader checks that the connection
+	// holding the lock is still alive
+	CheckInterval time.Duration
+}
+
+// DefaultLeaderConfig returns sensible default configuration
+func DefaultLeaderConfig() LeaderConfig {
+	return LeaderConfig{
+		LockKey:       DefaultLeaderLockKey,
+		RetryInterval: 15 * time.Second,
+		CheckInterval: 5 * time.Second,
+	}
+}
+
+// LeaderTask is a background loop that must only run on one replica. Start
+// returns immediately and the loop exits when ctx is cancelled, so it can
+// be started again when leadership returns. The owner still calls the
+// task's Stop on shutdown.
+type LeaderTask interface {
+	Start(ctx context.Context)
+}
+
+// LeaderElector runs its tasks only while this instance holds a Postgres
+// session advisory lock. The lock lives on a dedicated connection: if the
+// leader dies or loses that connection Postgres releases the lock and
+// another replica takes over on its next attempt.
+type LeaderElector struct {
+	config LeaderConfig
+	db     *sql.DB
+	tasks  []LeaderTask
+
+	leading bool
+
+	wg      sync.WaitGroup
+	stopCh  chan struct{}
+	stopped bool
+	mu      sync.Mutex
+}
+
+// NewLeaderElector creates a new LeaderElector instance
+func NewLeaderElector(config LeaderConfig, db *sql.DB, tasks ...LeaderTask) *LeaderElector {
+	defaults := DefaultLeaderConfig()
+	if config.LockKey == 0 {
+		config.LockKey = defaults.LockKey
+	}
+	if config.RetryInterval <= 0 {
+		config.RetryInterval = defaults.RetryInterval
+	}
+	if config.CheckInterval <= 0 {
+		config.CheckInterval = defaults.CheckInterval
+	}
+
+	return &LeaderElector{
+		config: config,
+		db:     db,
+		tasks:  tasks,
+		stopCh: make(chan struct{}),
+	}
+}
+
+// Start begins contending for leadership
+func (e *LeaderElector) Start(ctx context.Context) {
+	e.wg.Add(1)
+	go e.loop(ctx)
+	log.Printf("[leader] Election started (lock %d, %d tasks)", e.config.LockKey, len(e.tasks))
+}
+
+// IsLeader reports whether this instance currently runs the leader tasks
+func (e *LeaderElector) IsLeader() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.leading
+}
+
+// Stop stops the leader tasks and releases the lock so another replica can
+// take over without waiting for the connection to time out
+func (e *LeaderElector) Stop(ctx context.Context) error {
+	e.mu.Lock()
+	if e.stopped {
+		e.mu.Unlock()
+		return nil
+	}
+	e.stopped = true
+	close(e.stopCh)
+	e.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		e.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		log.Printf("[leader] Election stopped")
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("leader election shutdown: %w", ctx.Err())
+	}
+}
+
+func (e *LeaderElector) loop(ctx context.Context) {
+	defer e.wg.Done()
+
+	ticker := time.NewTicker(e.config.RetryInterval)
+	defer ticker.Stop()
+
+	for {
+		if conn, ok := e.acquire(ctx); ok {
+			e.lead(ctx, conn)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-e.stopCh:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// acquire tries to take the lock on a dedicated connection, which is
+// returned only when the lock was granted.
+func (e *LeaderElector) acquire(ctx context.Context) (*sql.Conn, bool) {
+	conn, err := e.db.Conn(ctx)
+	if err != nil {
+		log.Printf("[leader] Connection error: %v", err)
+		return nil, false
+	}
+
+	var locked bool
+	if err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1)`, e.config.LockKey).Scan(&locked); err != nil {
+		log.Printf("[leader] Lock attempt error: %v", err)
+		_ = conn.Close()
+		return nil, false
+	}
+	if !locked {
+		_ = conn.Close()
+		return nil, false
+	}
+	return conn, true
+}
+
+// lead runs the tasks until the lock connection fails or the elector stops.
+func (e *LeaderElector) lead(ctx context.Context, conn *sql.Conn) {
+	leaderCtx, cancel := context.WithCancel(ctx)
+	e.setLeading(true)
+	log.Printf("[leader] Acquired leadership, starting %d tasks", len(e.tasks))
+	for _, task := range e.tasks {
+		task.Start(leaderCtx)
+	}
+
+	defer func() {
+		cancel()
+		e.setLeading(false)
+		// Unlock explicitly on a healthy connection; otherwise closing it
+		// ends the session, which releases the lock anyway.
+		unlockCtx, unlockCancel := context.WithTimeout(context.Background(), 5*time.Second)
+		_, _ = conn.ExecContext(unlockCtx, `SELECT pg_advisory_unlock($1)`, e.config.LockKey)
+		unlockCancel()
+		_ = conn.Close()
+	}()
+
+	ticker := time.NewTicker(e.config.CheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-e.stopCh:
+			return
+		case <-ticker.C:
+		}
+
+		checkCtx, checkCancel := context.WithTimeout(ctx, e.config.CheckInterval)
+		err := conn.PingContext(checkCtx)
+		checkCancel()
+		if err != nil {
+			log.Printf("[leader] Lost lock connection, stepping down: %v", err)
+			return
+		}
+	}
+}
+
+func (e *LeaderElector) setLeading(leading bool) {
+	e.mu.Lock()
+	e.leading = leading
+	e.mu.Unlock()
+}
@@ -0,0 +1,210 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/events"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/store"
+)
+
+// OutboxConfig holds outbox dispatcher configuration
+type OutboxConfig struct {
+	// PollInterval is the time between polls when the dispatcher is not woken
+	PollInterval time.Duration
+	// BatchSize is the maximum number of events claimed per poll
+	BatchSize int
+	// Lease is how long a claimed event stays hidden from other dispatchers
+	Lease time.Duration
+	// MaxAttempts is the number of deliveries before an event is parked
+	MaxAttempts int
+	// RetryBaseDelay is the base delay for exponential backoff
+	RetryBaseDelay time.Duration
+	// Retention is how long delivered events are kept before being purged
+	Retention time.Duration
+}
+
+// DefaultOutboxConfig returns sensible default configuration
+func DefaultOutboxConfig() OutboxConfig {
+	return OutboxConfig{
+		PollInterval:   2 * time.Second,
+		BatchSize:      50,
+		Lease:          30 * time.Second,
+		MaxAttempts:    10,
+		RetryBaseDelay: time.Second,
+		Retention:      7 * 24 * time.Hour,
+	}
+}
+
+// OutboxDispatcher delivers events from the transactional outbox to the
+// in-process event bus. Delivery is at-least-once: an event is marked
+// dispatched only after every subscriber, including asynchronous ones, has
+// handled it without error. When any subscriber fails the whole event is
+// redelivered later, so subscribers must tolerate duplicates.
+type OutboxDispatcher struct {
+	config OutboxConfig
+	store  *store.OutboxStore
+	bus    *events.Bus
+
+	wg      sync.WaitGroup
+	stopCh  chan struct{}
+	stopped bool
+	mu      sync.Mutex
+}
+
+// NewOutboxDispatcher creates a new OutboxDispatcher instance
+func NewOutboxDispatcher(config OutboxConfig, outbox *store.OutboxStore, bus *events.Bus) *OutboxDispatcher {
+	defaults := DefaultOutboxConfig()
+	if config.PollInterval <= 0 {
+		config.PollInterval = defaults.PollInterval
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = defaults.BatchSize
+	}
+	if config.Lease <= 0 {
+		config.Lease = defaults.Lease
+	}
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = defaults.MaxAttempts
+	}
+	if config.RetryBaseDelay <= 0 {
+		config.RetryBaseDelay = defaults.RetryBaseDelay
+	}
+	if config.Retention <= 0 {
+		config.Retention = defaults.Retention
+	}
+
+	return &OutboxDispatcher{
+		config: config,
+		store:  outbox,
+		bus:    bus,
+		stopCh: make(chan struct{}),
+	}
+}
+
+// Start begins the dispatch loop
+func (d *OutboxDispatcher) Start(ctx context.Context) {
+	d.wg.Add(1)
+	go d.loop(ctx)
+	log.Printf("[outbox] Dispatcher started (poll interval %v)", d.config.PollInterval)
+}
+
+// Stop waits for the in-flight batch to finish and stops the loop
+func (d *OutboxDispatcher) Stop(ctx context.Context) error {
+	d.mu.Lock()
+	if d.stopped {
+		d.mu.Unlock()
+		return nil
+	}
+	d.stopped = true
+	close(d.stopCh)
+	d.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		log.Printf("[outbox] Dispatcher stopped")
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("outbox dispatcher shutdown: %w", ctx.Err())
+	}
+}
+
+func (d *OutboxDispatcher) loop(ctx context.Context) {
+	defer d.wg.Done()
+
+	purge := time.NewTicker(time.Hour)
+	defer purge.Stop()
+
+	for {
+		// Drain everything available before waiting again.
+		for {
+			n, err := d.dispatchBatch(ctx)
+			if err != nil {
+				log.Printf("[outbox] Dispatch error: %v", err)
+				break
+			}
+			if n < d.config.BatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-d.stopCh:
+			return
+		case <-d.store.Notifications():
+		case <-time.After(d.config.PollInterval):
+		case <-purge.C:
+			if n, err := d.store.PurgeDispatched(ctx, d.config.Retention); err != nil {
+				log.Printf("[outbox] Purge error: %v", err)
+			} else if n > 0 {
+				log.Printf("[outbox] Purged %d delivered events", n)
+			}
+		}
+	}
+}
+
+// dispatchBatch claims and delivers one batch, returning how many events
+// were claimed.
+func (d *OutboxDispatcher) dispatchBatch(ctx context.Context) (int, error) {
+	batch, err := d.store.ClaimBatch(ctx, d.config.BatchSize, d.config.Lease)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, item := range batch {
+		d.deliver(ctx, item)
+	}
+
+	return len(batch), nil
+}
+
+func (d *OutboxDispatcher) deliver(ctx context.Context, item *models.OutboxEvent) {
+	ev, err := events.Decode(events.Topic(item.Topic), item.Payload)
+	if err != nil {
+		// Undecodable payloads will never succeed; park them immediately.
+		log.Printf("[outbox] Event %d (%s) cannot be decoded: %v", item.ID, item.Topic, err)
+		if markErr := d.store.MarkFailed(ctx, item.ID, err.Error(), nil); markErr != nil {
+			log.Printf("[outbox] Failed to park event %d: %v", item.ID, markErr)
+		}
+		return
+	}
+
+	if err := d.bus.PublishAndWait(ctx, ev); err != nil {
+		var retryAt *time.Time
+		if item.Attempts < d.config.MaxAttempts {
+			t := time.Now().Add(d.backoff(item.Attempts))
+			retryAt = &t
+		}
+		log.Printf("[outbox] Event %d (%s) delivery failed on attempt %d: %v", item.ID, item.Topic, item.Attempts, err)
+		if markErr := d.store.MarkFailed(ctx, item.ID, err.Error(), retryAt); markErr != nil {
+			log.Printf("[outbox] Failed to record failure for event %d: %v", item.ID, markErr)
+		}
+		return
+	}
+
+	if err := d.store.MarkDispatched(ctx, item.ID); err != nil {
+		// The lease will expire and the event will be redelivered.
+		log.Printf("[outbox] Failed to mark event %d dispatched: %v", item.ID, err)
+	}
+}
+
+func (d *OutboxDispatcher) backoff(attempt int) time.Duration {
+	delay := float64(d.config.RetryBaseDelay) * math.Pow(2, float64(attempt-1))
+	if max := float64(time.Hour); delay > max {
+		delay = max
+	}
+	return time.Duration(delay)
+}
@@ -0,0 +1,168 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/store"
+)
+
+// RecurringConfig holds recurring job scheduler configuration
+type RecurringConfig struct {
+	// Interval is the time between checks for recurring jobs that are due;
+	// recurring jobs cannot run more often than this
+	Interval time.Duration
+}
+
+// DefaultRecurringConfig returns sensible default configuration
+func DefaultRecurringConfig() RecurringConfig {
+	return RecurringConfig{
+		Interval: time.Minute,
+	}
+}
+
+// RecurringJobScheduler enqueues the provisioned recurring jobs as they
+// come due and moves each to its next run.
+type RecurringJobScheduler struct {
+	config   RecurringConfig
+	store    *store.Store
+	worker   *Worker
+	builtins []models.RecurringJob
+
+	wg      sync.WaitGroup
+	stopCh  chan struct{}
+	stopped bool
+	mu      sync.Mutex
+}
+
+// NewRecurringJobScheduler creates a new RecurringJobScheduler instance
+func NewRecurringJobScheduler(config RecurringConfig, s *store.Store, w *Worker) *RecurringJobScheduler {
+	if config.Interval <= 0 {
+		config.Interval = DefaultRecurringConfig().Interval
+	}
+
+	return &RecurringJobScheduler{
+		config: config,
+		store:  s,
+		worker: w,
+		stopCh: make(chan struct{}),
+	}
+}
+
+// Builtin adds a recurring job the server itself relies on. It is
+// provisioned when the scheduler starts unless a recurring job with its slug
+// already exists, so operators can still retune or disable it through the
+// admin API.
+func (r *RecurringJobScheduler) Builtin(job models.RecurringJob) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.builtins = append(r.builtins, job)
+}
+
+// Start enqueues due recurring jobs immediately and then on every interval
+func (r *RecurringJobScheduler) Start(ctx context.Context) {
+	r.wg.Add(1)
+	go r.loop(ctx)
+	log.Printf("[recurring] Recurring jobs started (interval %v)", r.config.Interval)
+}
+
+// Stop waits for an in-flight check to finish and stops the loop
+func (r *RecurringJobScheduler) Stop(ctx context.Context) error {
+	r.mu.Lock()
+	if r.stopped {
+		r.mu.Unlock()
+		return nil
+	}
+	r.stopped = true
+	close(r.stopCh)
+	r.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		r.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		log.Printf("[recurring] Recurring jobs stopped")
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("recurring job scheduler shutdown: %w", ctx.Err())
+	}
+}
+
+func (r *RecurringJobScheduler) loop(ctx context.Context) {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(r.config.Interval)
+	defer ticker.Stop()
+
+	r.provisionBuiltins(ctx)
+	for {
+		if n, err := r.enqueueDue(ctx, time.Now()); err != nil {
+			log.Printf("[recurring] Schedule error: %v", err)
+		} else if n > 0 {
+			log.Printf("[recurring] Queued %d recurring jobs", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-r.stopCh:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// enqueueDue queues one run of every recurring job due at now.
+func (r *RecurringJobScheduler) enqueueDue(ctx context.Context, now time.Time) (int, error) {
+	due, err := r.store.ListDueRecurringJobs(ctx, now)
+	if err != nil {
+		return 0, err
+	}
+
+	queued := 0
+	for _, recurring := range due {
+		job := recurring.Job()
+		if err := r.worker.Enqueue(ctx, job); err != nil {
+			log.Printf("[recurring] Failed to queue %s: %v", recurring.Slug, err)
+			continue
+		}
+		if err := r.store.MarkRecurringJobEnqueued(ctx, recurring.Slug, recurring.NextRunAt, job.ID); err != nil {
+			log.Printf("[recurring] Failed to reschedule %s: %v", recurring.Slug, err)
+			continue
+		}
+		queued++
+	}
+	return queued, nil
+}
+
+// provisionBuiltins creates the builtin recurring jobs that do not exist yet.
+func (r *RecurringJobScheduler) provisionBuiltins(ctx context.Context) {
+	r.mu.Lock()
+	builtins := append([]models.RecurringJob(nil), r.builtins...)
+	r.mu.Unlock()
+
+	for _, job := range builtins {
+		_, err := r.store.GetRecurringJob(ctx, job.Slug)
+		if err == nil {
+			continue
+		}
+		if !errors.Is(err, store.ErrRecurringJobNotFound) {
+			log.Printf("[recurring] Failed to check %s: %v", job.Slug, err)
+			continue
+		}
+		if _, err := r.store.PutRecurringJob(ctx, &job); err != nil {
+			log.Printf("[recurring] Failed to provision %s: %v", job.Slug, err)
+			continue
+		}
+		log.Printf("[recurring] Provisioned %s every %v", job.Slug, job.Interval())
+	}
+}
@@ -0,0 +1,193 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/jira"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/mail"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/reports"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/store"
+)
+
+// reportJobType renders one scheduled report and delivers it.
+const reportJobType = "report_generation"
+
+// reportScheduleJobType queues the reports that are due.
+const reportScheduleJobType = "report_schedule"
+
+// reportScheduleBatchSize caps the reports queued per check.
+const reportScheduleBatchSize = 500
+
+// RegisterReportJobs registers the scheduled report handlers. refresh renews
+// rejected Atlassian OAuth tokens and may be nil.
+func RegisterReportJobs(w *Worker, s *store.Store, mailer mail.Mailer, refresh jira.TokenRefresher) {
+	w.RegisterHandler(reportJobType, reportHandler(s, mailer, refresh))
+	w.RegisterHandler(reportScheduleJobType, reportScheduleHandler(s, w))
+
+	log.Println("[worker] Registered report job handlers: " + reportJobType + ", " + reportScheduleJobType)
+}
+
+// ReportJob returns a job that renders and delivers the report schedule's
+// report once.
+func ReportJob(scheduleID int64, due time.Time) *models.Job {
+	return &models.Job{
+		JobType: reportJobType,
+		Payload: models.JSONB{
+			"schedule_id": scheduleID,
+			"due":         due.UTC().Format(time.RFC3339),
+		},
+		Priority:    models.JobPriorityLow,
+		MaxAttempts: 3,
+		DedupWindow: time.Hour,
+	}
+}
+
+// reportHandler renders the schedule's template and mails the report or
+// stores it as an artifact. The outcome of every attempt is recorded on the
+// schedule; the owner is notified when the last attempt fails.
+func reportHandler(s *store.Store, mailer mail.Mailer, refresh jira.TokenRefresher) Handler {
+	return func(ctx context.Context, job *models.Job) error {
+		idRaw, ok := job.Payload["schedule_id"].(float64)
+		if !ok {
+			return fmt.Errorf("missing schedule_id in payload")
+		}
+		target, err := s.GetReportScheduleForRun(ctx, int64(idRaw))
+		if errors.Is(err, store.ErrReportScheduleNotFound) {
+			// Deleted after the run was queued.
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("load report schedule %d: %w", int64(idRaw), err)
+		}
+		schedule := &target.Schedule
+		if !schedule.Enabled {
+			return nil
+		}
+
+		ranAt := time.Now()
+		runErr := deliverReport(ctx, s, mailer, refresh, target, ranAt)
+		var message *string
+		if runErr != nil {
+			text := runErr.Error()
+			message = &text
+		}
+		if err := s.RecordReportRun(ctx, schedule.ID, ranAt, message); err != nil {
+			log.Printf("[reports] Failed to record run of report schedule %d: %v", schedule.ID, err)
+		}
+		if runErr == nil {
+			return nil
+		}
+
+		if job.Attempts >= job.MaxAttempts {
+			title := fmt.Sprintf("Report %q failed", schedule.Name)
+			body := fmt.Sprintf("The report could not be generated: %v. It will be tried again at its next scheduled time.", runErr)
+			if err := s.CreateUserNotification(ctx, schedule.UserID, models.NotificationKindReportFailure, "warning", title, body, models.JSONB{"report_schedule_id": schedule.ID}); err != nil {
+				log.Printf("[reports] Failed to notify user %d about report schedule %d: %v", schedule.UserID, schedule.ID, err)
+			}
+		}
+		return fmt.Errorf("report schedule %d: %w", schedule.ID, runErr)
+	}
+}
+
+// deliverReport generates the schedule's report and delivers it.
+func deliverReport(ctx context.Context, s *store.Store, mailer mail.Mailer, refresh jira.TokenRefresher, target *models.ReportRunTarget, now time.Time) error {
+	schedule := &target.Schedule
+	if target.MCPSecret == "" {
+		return errors.New("account has no MCP secret to resolve Jira credentials")
+	}
+	client, err := jira.ForMCPSecret(ctx, s, target.MCPSecret, refresh)
+	if err != nil {
+		return err
+	}
+	report, err := reports.Generate(ctx, client, schedule, now)
+	if err != nil {
+		return err
+	}
+	content, err := report.Render(schedule.Format)
+	if err != nil {
+		return err
+	}
+
+	switch schedule.Delivery {
+	case models.ReportDeliveryArtifact:
+		artifact := &models.ReportArtifact{
+			ScheduleID: &schedule.ID,
+			Template:   schedule.Template,
+			Format:     schedule.Format,
+			Title:      report.Title,
+			Content:    content,
+		}
+		return s.CreateReportArtifact(ctx, schedule.UserID, artifact)
+
+	default:
+		if mailer == nil {
+			return errors.New("no mailer configured")
+		}
+		if target.Email == "" {
+			return errors.New("account has no email address")
+		}
+		msg := mail.Message{To: target.Email, Subject: report.Title, Body: report.Markdown()}
+		if schedule.Format == models.ReportFormatHTML {
+			msg.HTML = content
+		}
+		return mailer.Send(ctx, msg)
+	}
+}
+
+// ReportScheduleJob is the builtin recurring job that queues a report for
+// every enabled report schedule as it comes due, checked every minute.
+func ReportScheduleJob() models.RecurringJob {
+	return models.RecurringJob{
+		Slug:            "report-schedule",
+		JobType:         reportScheduleJobType,
+		Payload:         models.JSONB{},
+		Priority:        models.JobPriorityHigh,
+		MaxAttempts:     1,
+		IntervalSeconds: int(time.Minute / time.Second),
+		Enabled:         true,
+	}
+}
+
+// reportScheduleHandler queues a report for every schedule due now and
+// moves each to its next run. Runs missed while nothing was scheduling
+// produce one report, not one per missed time.
+func reportScheduleHandler(s *store.Store, w *Worker) Handler {
+	return func(ctx context.Context, job *models.Job) error {
+		now := time.Now()
+		due, err := s.ListDueReportSchedules(ctx, now, reportScheduleBatchSize)
+		if err != nil {
+			return err
+		}
+
+		queued := 0
+		for _, schedule := range due {
+			next, err := schedule.NextRun(now)
+			if err != nil {
+				log.Printf("[reports] Failed to schedule report %d: %v", schedule.ID, err)
+				continue
+			}
+			moved, err := s.ScheduleReport(ctx, schedule.ID, schedule.NextRunAt, next)
+			if err != nil {
+				log.Printf("[reports] Failed to reschedule report %d: %v", schedule.ID, err)
+				continue
+			}
+			if !moved {
+				continue
+			}
+			if err := w.Enqueue(ctx, ReportJob(schedule.ID, schedule.NextRunAt)); err != nil {
+				log.Printf("[reports] Failed to queue report %d: %v", schedule.ID, err)
+				continue
+			}
+			queued++
+		}
+		if queued > 0 {
+			log.Printf("[reports] Queued %d reports", queued)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,113 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/store"
+)
+
+// RollupConfig holds usage rollup refresher configuration
+type RollupConfig struct {
+	// Interval is the time between rollup refreshes
+	Interval time.Duration
+	// Lookback is how far back each refresh recomputes; it must cover any
+	// requests that were still being recorded at the previous refresh
+	Lookback time.Duration
+}
+
+// DefaultRollupConfig returns sensible default configuration
+func DefaultRollupConfig() RollupConfig {
+	return RollupConfig{
+		Interval: 15 * time.Minute,
+		Lookback: 48 * time.Hour,
+	}
+}
+
+// UsageRollup periodically folds the raw requests table into per-user daily
+// rollups used for usage forecasting.
+type UsageRollup struct {
+	config RollupConfig
+	store  *store.Store
+
+	wg      sync.WaitGroup
+	stopCh  chan struct{}
+	stopped bool
+	mu      sync.Mutex
+}
+
+// NewUsageRollup creates a new UsageRollup instance
+func NewUsageRollup(config RollupConfig, s *store.Store) *UsageRollup {
+	defaults := DefaultRollupConfig()
+	if config.Interval <= 0 {
+		config.Interval = defaults.Interval
+	}
+	if config.Lookback <= 0 {
+		config.Lookback = defaults.Lookback
+	}
+
+	return &UsageRollup{
+		config: config,
+		store:  s,
+		stopCh: make(chan struct{}),
+	}
+}
+
+// Start refreshes the rollups immediately and then on every interval
+func (u *UsageRollup) Start(ctx context.Context) {
+	u.wg.Add(1)
+	go u.loop(ctx)
+	log.Printf("[rollup] Usage rollups started (interval %v)", u.config.Interval)
+}
+
+// Stop waits for an in-flight refresh to finish and stops the loop
+func (u *UsageRollup) Stop(ctx context.Context) error {
+	u.mu.Lock()
+	if u.stopped {
+		u.mu.Unlock()
+		return nil
+	}
+	u.stopped = true
+	close(u.stopCh)
+	u.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		u.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		log.Printf("[rollup] Usage rollups stopped")
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("usage rollup shutdown: %w", ctx.Err())
+	}
+}
+
+func (u *UsageRollup) loop(ctx context.Context) {
+	defer u.wg.Done()
+
+	ticker := time.NewTicker(u.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		if n, err := u.store.RefreshDailyRollups(ctx, time.Now().Add(-u.config.Lookback)); err != nil {
+			log.Printf("[rollup] Refresh error: %v", err)
+		} else {
+			log.Printf("[rollup] Refreshed %d daily rollups", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-u.stopCh:
+			return
+		case <-ticker.C:
+		}
+	}
+}
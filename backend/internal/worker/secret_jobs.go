@@ -0,0 +1,60 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/store"
+)
+
+// encryptSecretsJobType encrypts stored credentials that are still in
+// plaintext or sealed with a retired key.
+const encryptSecretsJobType = "encrypt_stored_secrets"
+
+// encryptSecretsBatch is how many values per column each pass seals.
+const encryptSecretsBatch = 200
+
+// RegisterSecretJobs registers the stored credential encryption handler
+func RegisterSecretJobs(w *Worker, s *store.Store) {
+	w.RegisterHandler(encryptSecretsJobType, encryptSecretsHandler(s))
+
+	log.Println("[worker] Registered secret job handlers: " + encryptSecretsJobType)
+}
+
+// EncryptStoredSecretsJob returns a job that encrypts every stored
+// credential under the primary key. Instances starting together queue it
+// once.
+func EncryptStoredSecretsJob() *models.Job {
+	return &models.Job{
+		JobType:     encryptSecretsJobType,
+		Payload:     models.JSONB{},
+		Priority:    models.JobPriorityLow,
+		MaxAttempts: 5,
+		DedupWindow: time.Hour,
+	}
+}
+
+// encryptSecretsHandler seals batches until nothing is left. Each pass only
+// picks up values not yet under the primary key, so a retried job resumes
+// where the failed one stopped.
+func encryptSecretsHandler(s *store.Store) Handler {
+	return func(ctx context.Context, job *models.Job) error {
+		total := 0
+		for {
+			n, err := s.EncryptStoredSecrets(ctx, encryptSecretsBatch)
+			total += n
+			if err != nil {
+				return fmt.Errorf("encrypt stored secrets after %d: %w", total, err)
+			}
+			if n == 0 {
+				break
+			}
+		}
+
+		log.Printf("[secrets] Encrypted %d stored credentials", total)
+		return nil
+	}
+}
@@ -0,0 +1,67 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/models"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/store"
+)
+
+// tenantShardMoveJobType places a tenant on a shard and copies their request
+// logs there. Moves are idempotent, so a failed job is simply retried.
+const tenantShardMoveJobType = "tenant_shard_move"
+
+// RegisterShardJobs registers the tenant shard move handler
+func RegisterShardJobs(w *Worker, s *store.Store) {
+	w.RegisterHandler(tenantShardMoveJobType, tenantShardMoveHandler(s))
+
+	log.Println("[worker] Registered shard job handlers: " + tenantShardMoveJobType)
+}
+
+// TenantShardMoveJob returns a job that moves the user's request logs to
+// shard, or back to the primary database when shard is "".
+func TenantShardMoveJob(userID int64, shard string) *models.Job {
+	return &models.Job{
+		JobType: tenantShardMoveJobType,
+		Payload: models.JSONB{
+			"user_id": userID,
+			"shard":   shard,
+		},
+		Priority:    models.JobPriorityLow,
+		MaxAttempts: 5,
+	}
+}
+
+// tenantShardMoveHandler moves the tenant named in the payload
+func tenantShardMoveHandler(s *store.Store) Handler {
+	return func(ctx context.Context, job *models.Job) error {
+		userIDRaw, ok := job.Payload["user_id"].(float64)
+		if !ok {
+			return fmt.Errorf("missing user_id in payload")
+		}
+		userID := int64(userIDRaw)
+		shard, _ := job.Payload["shard"].(string)
+
+		err := s.MoveTenantToShard(ctx, userID, shard)
+		if errors.Is(err, store.ErrUserNotFound) {
+			// The tenant was deleted after the move was queued.
+			log.Printf("[shard-move] User %d no longer exists; nothing to move", userID)
+			return nil
+		}
+		if errors.Is(err, store.ErrTenantResident) {
+			// Residency decides where the tenant's data lives; retrying
+			// cannot succeed until the organization leaves its region.
+			log.Printf("[shard-move] Not moving user %d: %v", userID, err)
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("move user %d to shard %q: %w", userID, shard, err)
+		}
+
+		log.Printf("[shard-move] Moved user %d to shard %q", userID, shard)
+		return nil
+	}
+}
@@ -0,0 +1,133 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/slowquery"
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/store"
+)
+
+// SlowQueryConfig holds slow query recorder configuration
+type SlowQueryConfig struct {
+	// PlanInterval is the minimum time between plan captures for the same
+	// statement
+	PlanInterval time.Duration
+	// ExplainTimeout bounds each EXPLAIN call
+	ExplainTimeout time.Duration
+}
+
+// DefaultSlowQueryConfig returns sensible default configuration
+func DefaultSlowQueryConfig() SlowQueryConfig {
+	return SlowQueryConfig{
+		PlanInterval:   10 * time.Minute,
+		ExplainTimeout: 5 * time.Second,
+	}
+}
+
+// SlowQueryRecorder drains the collector's slow statements into the
+// slow_queries table, capturing each statement's plan at most once per
+// PlanInterval.
+type SlowQueryRecorder struct {
+	config    SlowQueryConfig
+	collector *slowquery.Collector
+	store     *store.Store
+
+	lastPlan map[string]time.Time
+
+	wg      sync.WaitGroup
+	stopCh  chan struct{}
+	stopped bool
+	mu      sync.Mutex
+}
+
+// NewSlowQueryRecorder creates a new SlowQueryRecorder instance
+func NewSlowQueryRecorder(config SlowQueryConfig, collector *slowquery.Collector, s *store.Store) *SlowQueryRecorder {
+	defaults := DefaultSlowQueryConfig()
+	if config.PlanInterval <= 0 {
+		config.PlanInterval = defaults.PlanInterval
+	}
+	if config.ExplainTimeout <= 0 {
+		config.ExplainTimeout = defaults.ExplainTimeout
+	}
+
+	return &SlowQueryRecorder{
+		config:    config,
+		collector: collector,
+		store:     s,
+		lastPlan:  map[string]time.Time{},
+		stopCh:    make(chan struct{}),
+	}
+}
+
+// Start begins recording slow statements
+func (r *SlowQueryRecorder) Start(ctx context.Context) {
+	r.wg.Add(1)
+	go r.loop(ctx)
+	log.Printf("[slow-queries] Recording statements slower than %v", r.collector.Threshold())
+}
+
+// Stop waits for the sample being recorded and stops the loop
+func (r *SlowQueryRecorder) Stop(ctx context.Context) error {
+	r.mu.Lock()
+	if r.stopped {
+		r.mu.Unlock()
+		return nil
+	}
+	r.stopped = true
+	close(r.stopCh)
+	r.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		r.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		if n := r.collector.Dropped(); n > 0 {
+			log.Printf("[slow-queries] Stopped; %d samples dropped while the queue was full", n)
+		}
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("slow query recorder shutdown: %w", ctx.Err())
+	}
+}
+
+func (r *SlowQueryRecorder) loop(ctx context.Context) {
+	defer r.wg.Done()
+
+	ctx = slowquery.WithoutRecording(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-r.stopCh:
+			return
+		case sample := <-r.collector.Samples():
+			r.record(ctx, sample)
+		}
+	}
+}
+
+func (r *SlowQueryRecorder) record(ctx context.Context, sample slowquery.Sample) {
+	var plan []byte
+	if last, ok := r.lastPlan[sample.Fingerprint]; !ok || time.Since(last) >= r.config.PlanInterval {
+		r.lastPlan[sample.Fingerprint] = time.Now()
+		explainCtx, cancel := context.WithTimeout(ctx, r.config.ExplainTimeout)
+		var err error
+		plan, err = r.store.ExplainQuery(explainCtx, sample.Query, sample.Args)
+		cancel()
+		if err != nil {
+			log.Printf("[slow-queries] No plan for %s: %v", sample.Fingerprint, err)
+		}
+	}
+
+	if err := r.store.RecordSlowQuery(ctx, sample.Fingerprint, sample.Query, sample.Duration, plan); err != nil {
+		log.Printf("[slow-queries] Record error: %v", err)
+	}
+}
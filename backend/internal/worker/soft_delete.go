@@ -0,0 +1,133 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/PortNumber53/mcp-jira-thing/backend/internal/store"
+)
+
+// SoftDeleteConfig holds soft delete purger configuration
+type SoftDeleteConfig struct {
+	// Interval is the time between purges
+	Interval time.Duration
+	// Retention is how long deleted accounts, Jira settings and jobs stay restorable
+	Retention time.Duration
+}
+
+// DefaultSoftDeleteConfig returns sensible default configuration
+func DefaultSoftDeleteConfig() SoftDeleteConfig {
+	return SoftDeleteConfig{
+		Interval:  time.Hour,
+		Retention: store.SoftDeleteRetention,
+	}
+}
+
+// SoftDeletePurger periodically removes accounts, Jira settings and jobs
+// whose grace window for restoring them has passed.
+type SoftDeletePurger struct {
+	config SoftDeleteConfig
+	store  *store.Store
+	jobs   *store.JobStore
+
+	wg      sync.WaitGroup
+	stopCh  chan struct{}
+	stopped bool
+	mu      sync.Mutex
+}
+
+// NewSoftDeletePurger creates a new SoftDeletePurger instance
+func NewSoftDeletePurger(config SoftDeleteConfig, s *store.Store, jobs *store.JobStore) *SoftDeletePurger {
+	defaults := DefaultSoftDeleteConfig()
+	if config.Interval <= 0 {
+		config.Interval = defaults.Interval
+	}
+	if config.Retention <= 0 {
+		config.Retention = defaults.Retention
+	}
+
+	return &SoftDeletePurger{
+		config: config,
+		store:  s,
+		jobs:   jobs,
+		stopCh: make(chan struct{}),
+	}
+}
+
+// Start purges immediately and then on every interval
+func (p *SoftDeletePurger) Start(ctx context.Context) {
+	p.wg.Add(1)
+	go p.loop(ctx)
+	log.Printf("[soft-delete] Purger started (interval %v, retention %v)", p.config.Interval, p.config.Retention)
+}
+
+// Stop waits for an in-flight purge to finish and stops the loop
+func (p *SoftDeletePurger) Stop(ctx context.Context) error {
+	p.mu.Lock()
+	if p.stopped {
+		p.mu.Unlock()
+		return nil
+	}
+	p.stopped = true
+	close(p.stopCh)
+	p.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		log.Printf("[soft-delete] Purger stopped")
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("soft delete purger shutdown: %w", ctx.Err())
+	}
+}
+
+func (p *SoftDeletePurger) loop(ctx context.Context) {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(p.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		p.purge(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-p.stopCh:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *SoftDeletePurger) purge(ctx context.Context) {
+	if n, err := p.store.PurgeDeletedUsers(ctx, p.config.Retention); err != nil {
+		log.Printf("[soft-delete] Account purge error: %v", err)
+	} else if n > 0 {
+		log.Printf("[soft-delete] Purged %d deleted accounts", n)
+	}
+
+	if n, err := p.store.PurgeDeletedUserSettings(ctx, p.config.Retention); err != nil {
+		log.Printf("[soft-delete] Jira settings purge error: %v", err)
+	} else if n > 0 {
+		log.Printf("[soft-delete] Purged %d deleted Jira settings", n)
+	}
+
+	if p.jobs == nil {
+		return
+	}
+	if n, err := p.jobs.PurgeDeletedJobs(ctx, p.config.Retention); err != nil {
+		log.Printf("[soft-delete] Job purge error: %v", err)
+	} else if n > 0 {
+		log.Printf("[soft-delete] Purged %d deleted jobs", n)
+	}
+}
@@ -29,6 +29,8 @@ type Instrumentation struct {
 	OnRetry     func(job *models.Job, retryAfter time.Duration)
 	OnCancel    func(job *models.Job)
 	OnHeartbeat func(workerID string, stats Stats)
+	// OnProgress is called when a handler reports how far a job got
+	OnProgress func(job *models.Job, done, total int)
 }
 
 // Stats holds worker statistics
@@ -40,15 +42,31 @@ type Stats struct {
 	ActiveWorkers   int
 	QueueDepth      int
 	LastProcessedAt time.Time
+
+	// Concurrency is the number of running processors
+	Concurrency int
+	// QueueWait is the smoothed time jobs wait before being claimed;
+	// it and QueueDepth are sampled while autoscaling
+	QueueWait time.Duration
 }
 
 // Config holds worker configuration
 type Config struct {
 	// MaxConcurrent is the maximum number of concurrent job processors
 	MaxConcurrent int
+	// MinConcurrent is the fewest processors the autoscaler keeps running;
+	// zero, or a value not below MaxConcurrent, runs MaxConcurrent
+	// processors at all times
+	MinConcurrent int
+	// ScaleInterval is the time between autoscaler samples
+	ScaleInterval time.Duration
+	// TargetQueueWait is the queue wait above which the autoscaler doubles
+	// the processors instead of adding one
+	TargetQueueWait time.Duration
 	// PollInterval is the time between polling for new jobs
 	PollInterval time.Duration
-	// RetryBaseDelay is the base delay for exponential backoff
+	// RetryBaseDelay is the base delay for exponential backoff; job types
+	// registered with their own Backoff may override it
 	RetryBaseDelay time.Duration
 	// RetryMaxDelay is the maximum delay between retries
 	RetryMaxDelay time.Duration
@@ -66,6 +84,8 @@ type Config struct {
 func DefaultConfig() Config {
 	return Config{
 		MaxConcurrent:          5,
+		ScaleInterval:          10 * time.Second,
+		TargetQueueWait:        5 * time.Second,
 		PollInterval:           time.Second,
 		RetryBaseDelay:         time.Second,
 		RetryMaxDelay:          time.Minute,
@@ -81,7 +101,9 @@ type Worker struct {
 	config          Config
 	store           *store.JobStore
 	handlers        Handlers
+	backoffs        map[string]Backoff
 	instrumentation *Instrumentation
+	faults          *faultInjector
 
 	workerID string
 	wg       sync.WaitGroup
@@ -92,6 +114,13 @@ type Worker struct {
 	// activeJobs tracks currently processing job IDs for graceful shutdown
 	activeJobs map[int64]context.CancelFunc
 
+	// processors holds the quit channel of each running processor, newest
+	// last; the autoscaler closes them to scale down
+	processors      []chan struct{}
+	nextProcessorID int
+	queueDepth      int
+	queueWait       time.Duration
+
 	// stats tracking
 	statsMu         sync.RWMutex
 	jobsProcessed   int64
@@ -106,6 +135,12 @@ func (w *Worker) RegisterHandler(jobType string, handler Handler) {
 	w.handlers[jobType] = handler
 }
 
+// HasHandler reports whether a handler is registered for jobType
+func (w *Worker) HasHandler(jobType string) bool {
+	_, ok := w.handlers[jobType]
+	return ok
+}
+
 // New creates a new Worker instance
 func New(config Config, store *store.JobStore, handlers Handlers) *Worker {
 	if config.MaxConcurrent <= 0 {
@@ -129,11 +164,21 @@ func New(config Config, store *store.JobStore, handlers Handlers) *Worker {
 	if config.ShutdownTimeout <= 0 {
 		config.ShutdownTimeout = DefaultConfig().ShutdownTimeout
 	}
+	if config.MinConcurrent < 0 || config.MinConcurrent > config.MaxConcurrent {
+		config.MinConcurrent = 0
+	}
+	if config.ScaleInterval <= 0 {
+		config.ScaleInterval = DefaultConfig().ScaleInterval
+	}
+	if config.TargetQueueWait <= 0 {
+		config.TargetQueueWait = DefaultConfig().TargetQueueWait
+	}
 
 	return &Worker{
 		config:          config,
 		store:           store,
 		handlers:        handlers,
+		backoffs:        make(map[string]Backoff),
 		workerID:        generateWorkerID(),
 		stopCh:          make(chan struct{}),
 		activeJobs:      make(map[int64]context.CancelFunc),
@@ -148,6 +193,17 @@ func (w *Worker) SetInstrumentation(inst *Instrumentation) {
 	w.instrumentation = inst
 }
 
+// SetFaults enables fault injection. It must be called before Start and is
+// meant for staging and test environments only.
+func (w *Worker) SetFaults(config FaultConfig) {
+	if !config.Enabled() {
+		w.faults = nil
+		return
+	}
+	log.Printf("[worker] Fault injection enabled: %s", config)
+	w.faults = newFaultInjector(config)
+}
+
 // Start begins the worker loop
 func (w *Worker) Start(ctx context.Context) {
 	log.Printf("[worker] Starting with ID: %s, max concurrent: %d", w.workerID, w.config.MaxConcurrent)
@@ -159,12 +215,15 @@ func (w *Worker) Start(ctx context.Context) {
 	}
 
 	// Start worker pool
-	for i := 0; i < w.config.MaxConcurrent; i++ {
+	initial := w.config.MaxConcurrent
+	if w.autoscaling() {
+		initial = w.config.MinConcurrent
 		w.wg.Add(1)
-		go w.processor(ctx, i)
+		go w.autoscale(ctx)
 	}
+	w.scaleTo(ctx, initial)
 
-	log.Printf("[worker] Started %d processors", w.config.MaxConcurrent)
+	log.Printf("[worker] Started %d processors", initial)
 }
 
 // Stop gracefully shuts down the worker
@@ -206,8 +265,9 @@ func (w *Worker) Stop(ctx context.Context) error {
 	}
 }
 
-// processor is the main loop for a single worker goroutine
-func (w *Worker) processor(ctx context.Context, id int) {
+// processor is the main loop for a single worker goroutine; closing quit
+// retires it once its current job is done
+func (w *Worker) processor(ctx context.Context, id int, quit <-chan struct{}) {
 	defer w.wg.Done()
 
 	processorID := fmt.Sprintf("%s-processor-%d", w.workerID, id)
@@ -221,6 +281,9 @@ func (w *Worker) processor(ctx context.Context, id int) {
 		case <-w.stopCh:
 			log.Printf("[worker] Processor %s shutting down (stop signal)", processorID)
 			return
+		case <-quit:
+			log.Printf("[worker] Processor %s retired (scaled down)", processorID)
+			return
 		default:
 			if err := w.processNextJob(ctx); err != nil {
 				if err != context.Canceled && err != context.DeadlineExceeded {
@@ -234,6 +297,9 @@ func (w *Worker) processor(ctx context.Context, id int) {
 // processNextJob attempts to claim and process the next available job
 func (w *Worker) processNextJob(ctx context.Context) error {
 	// Try to claim a job
+	if err := w.faults.storeCall("claim"); err != nil {
+		return err
+	}
 	job, err := w.store.ClaimNextJob(ctx, w.workerID)
 	if err != nil {
 		return err
@@ -251,6 +317,7 @@ func (w *Worker) processNextJob(ctx context.Context) error {
 	}
 
 	// Process the job
+	w.observeQueueWait(job)
 	w.processJob(ctx, job)
 	return nil
 }
@@ -283,7 +350,10 @@ func (w *Worker) processJob(ctx context.Context, job *models.Job) {
 	}
 
 	// Execute the handler
-	err := handler(jobCtx, job)
+	err := w.faults.beforeHandler(jobCtx, job)
+	if err == nil {
+		err = handler(jobCtx, job)
+	}
 
 	if err != nil {
 		w.handleError(jobCtx, job, err, start)
@@ -311,13 +381,8 @@ func (w *Worker) handleError(ctx context.Context, job *models.Job, err error, st
 
 	// Check if we should retry
 	if job.Attempts < job.MaxAttempts {
-		// Calculate retry delay with exponential backoff and jitter
-		baseDelay := float64(w.config.RetryBaseDelay) * pow(w.config.RetryBackoffMultiplier, float64(job.Attempts-1))
-		maxDelay := float64(w.config.RetryMaxDelay)
-		delay := time.Duration(min(baseDelay, maxDelay))
-
-		// Add jitter (±20%) to prevent thundering herd
-		jitter := time.Duration(float64(delay) * (0.8 + 0.4*rand.Float64()))
+		// Calculate retry delay from the job type's backoff profile
+		jitter := w.retryDelay(job, err)
 		retryAfter := time.Now().Add(jitter)
 
 		w.statsMu.Lock()
@@ -332,14 +397,18 @@ func (w *Worker) handleError(ctx context.Context, job *models.Job, err error, st
 		log.Printf("[worker] Scheduling retry for job %d after %v (attempt %d/%d)",
 			job.ID, jitter, job.Attempts, job.MaxAttempts)
 
-		if err := w.store.ScheduleRetry(ctx, job.ID, err.Error(), retryAfter); err != nil {
+		if err := w.markJob("schedule retry", func() error {
+			return w.store.ScheduleRetry(ctx, job.ID, err.Error(), retryAfter)
+		}); err != nil {
 			log.Printf("[worker] Failed to schedule retry for job %d: %v", job.ID, err)
 		}
 	} else {
 		// Max attempts reached, mark as failed
 		log.Printf("[worker] Job %d exhausted all %d attempts, marking as failed", job.ID, job.MaxAttempts)
 
-		if err := w.store.MarkFailed(ctx, job.ID, err.Error()); err != nil {
+		if err := w.markJob("mark failed", func() error {
+			return w.store.MarkFailed(ctx, job.ID, err.Error())
+		}); err != nil {
 			log.Printf("[worker] Failed to mark job %d as failed: %v", job.ID, err)
 		}
 	}
@@ -362,11 +431,21 @@ func (w *Worker) handleSuccess(ctx context.Context, job *models.Job, start time.
 		w.instrumentation.OnComplete(job, duration)
 	}
 
-	if err := w.store.MarkCompleted(ctx, job.ID); err != nil {
+	if err := w.markJob("mark completed", func() error {
+		return w.store.MarkCompleted(ctx, job.ID)
+	}); err != nil {
 		log.Printf("[worker] Failed to mark job %d as completed: %v", job.ID, err)
 	}
 }
 
+// markJob runs a job status update unless an injected fault drops it
+func (w *Worker) markJob(op string, update func() error) error {
+	if err := w.faults.storeCall(op); err != nil {
+		return err
+	}
+	return update()
+}
+
 // trackActiveJob adds a job to the active jobs map
 func (w *Worker) trackActiveJob(jobID int64, cancel context.CancelFunc) {
 	w.mu.Lock()
@@ -438,6 +517,8 @@ func (w *Worker) getStats() Stats {
 
 	w.mu.RLock()
 	activeWorkers := len(w.activeJobs)
+	concurrency := len(w.processors)
+	queueDepth, queueWait := w.queueDepth, w.queueWait
 	w.mu.RUnlock()
 
 	return Stats{
@@ -446,6 +527,9 @@ func (w *Worker) getStats() Stats {
 		JobsFailed:      w.jobsFailed,
 		JobsRetried:     w.jobsRetried,
 		ActiveWorkers:   activeWorkers,
+		Concurrency:     concurrency,
+		QueueDepth:      queueDepth,
+		QueueWait:       queueWait,
 		LastProcessedAt: w.lastProcessedAt,
 	}
 }
@@ -474,6 +558,21 @@ func (w *Worker) Enqueue(ctx context.Context, job *models.Job) error {
 	return nil
 }
 
+// ReportProgress records that a running job has done done of total units
+// of work (total is 0 when unknown), so GET /api/jobs/{id} and the job's
+// owner can follow it
+func (w *Worker) ReportProgress(ctx context.Context, job *models.Job, done, total int) error {
+	if err := w.store.SetProgress(ctx, job.ID, done, total); err != nil {
+		return err
+	}
+
+	// Instrumentation: job progressed
+	if w.instrumentation.OnProgress != nil {
+		w.instrumentation.OnProgress(job, done, total)
+	}
+	return nil
+}
+
 // CancelJob cancels a pending or failed job
 func (w *Worker) CancelJob(ctx context.Context, jobID int64) error {
 	if err := w.store.CancelJob(ctx, jobID); err != nil {
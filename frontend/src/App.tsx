@@ -87,6 +87,9 @@ const AppContent = () => {
   const location = useLocation();
   const navigate = useNavigate();
   const route = location.pathname;
+  const loginError = new URLSearchParams(location.search).get("error");
+  const ssoRequired = loginError === "sso_required";
+  const emailUnverified = loginError === "email_unverified";
   const [isAccountMenuOpen, setAccountMenuOpen] = useState(false);
   const [jiraSettings, setJiraSettings] = useState<JiraSettingsFormState>({
     baseUrl: "",
@@ -228,6 +231,22 @@ const AppContent = () => {
     window.location.href = loginUrl.toString();
   };
 
+  const beginLoginWithSSO = () => {
+    const loginUrl = new URL("/api/auth/sso/login", window.location.origin);
+    const org = new URLSearchParams(window.location.search).get("org");
+    if (org) {
+      loginUrl.searchParams.set("org", org);
+    } else {
+      const email = window.prompt("Enter your work email to sign in with your organization's SSO");
+      if (!email) {
+        return;
+      }
+      loginUrl.searchParams.set("email", email.trim());
+    }
+    loginUrl.searchParams.set("redirect", "/dashboard");
+    window.location.href = loginUrl.toString();
+  };
+
   const beginLogout = () => {
     const logout = async () => {
       try {
@@ -306,6 +325,9 @@ const AppContent = () => {
               <button type="button" className="button" onClick={beginLoginWithGoogle}>
                 Sign in with Google
               </button>
+              <button type="button" className="button" onClick={beginLoginWithSSO}>
+                Sign in with SSO
+              </button>
             </div>
             <p style={{ fontSize: '0.875rem', color: '#666', marginTop: '1rem', maxWidth: '400px' }}>
               Note: To switch GitHub accounts, please log out of GitHub.com first. Google login allows account selection.
@@ -343,7 +365,21 @@ const AppContent = () => {
             <button type="button" className="button" onClick={beginLoginWithGoogle}>
               Sign in with Google
             </button>
+            <button type="button" className="button" onClick={beginLoginWithSSO}>
+              Sign in with SSO
+            </button>
           </div>
+          {ssoRequired && (
+            <p role="alert" style={{ marginTop: '1rem', maxWidth: '400px' }}>
+              Your organization requires single sign-on. Use "Sign in with SSO" to continue.
+            </p>
+          )}
+          {emailUnverified && (
+            <p role="alert" style={{ marginTop: '1rem', maxWidth: '400px' }}>
+              Your GitHub email is not verified and belongs to an existing account. Sign in with that account's
+              provider, or verify the email on GitHub and try again.
+            </p>
+          )}
           <p style={{ fontSize: '0.875rem', color: '#666', marginTop: '1rem', maxWidth: '400px' }}>
             Note: To switch GitHub accounts, please log out of GitHub.com first. Google login allows account selection.
           </p>
@@ -10,8 +10,22 @@ const stripePromise = stripeKey ? loadStripe(stripeKey).catch(err => {
   return null;
 }) : Promise.resolve(null);
 
+type UsageForecast = {
+  month_to_date: number;
+  projected: number;
+  quota?: number;
+  projected_percent?: number;
+  quota_exhausted_on?: string;
+  cost_units_to_date: number;
+  cost_units_projected: number;
+  cost_unit_quota?: number;
+  overage_risk: 'none' | 'low' | 'medium' | 'high';
+  upgrade_suggested: boolean;
+};
+
 const Billing = () => {
   const [subscriptionId, setSubscriptionId] = useState<string | null>(null);
+  const [forecast, setForecast] = useState<UsageForecast | null>(null);
   const [isCanceling, setIsCanceling] = useState(false);
   const [cancelMessage, setCancelMessage] = useState<string | null>(null);
 
@@ -21,6 +35,11 @@ const Billing = () => {
     if (storedSubId) {
       setSubscriptionId(storedSubId);
     }
+
+    fetch('/api/metrics/forecast', { credentials: 'include' })
+      .then(response => (response.ok ? response.json() : null))
+      .then(data => setForecast(data))
+      .catch(err => console.error('Failed to load usage forecast:', err));
   }, []);
 
   const handleCancelSubscription = async () => {
@@ -98,6 +117,37 @@ const Billing = () => {
         Manage your subscription and payment methods. All transactions are securely processed through Stripe.
       </p>
 
+      {forecast && (forecast.overage_risk === 'medium' || forecast.overage_risk === 'high') && (
+        <div style={{
+          padding: '16px',
+          backgroundColor: forecast.upgrade_suggested ? '#fee2e2' : '#fff3cd',
+          border: `1px solid ${forecast.upgrade_suggested ? '#fecaca' : '#ffc107'}`,
+          borderRadius: '8px',
+          marginBottom: '24px',
+          color: forecast.upgrade_suggested ? '#991b1b' : '#856404'
+        }}>
+          <p style={{ margin: '0 0 8px 0', fontWeight: 'bold' }}>
+            {forecast.upgrade_suggested ? 'You are on track to exceed your plan this month' : 'You are approaching your monthly plan limits'}
+          </p>
+          {forecast.quota && (
+            <p style={{ margin: '0 0 4px 0', fontSize: '14px' }}>
+              Requests: {forecast.month_to_date.toLocaleString()} of {forecast.quota.toLocaleString()} used, about{' '}
+              {forecast.projected.toLocaleString()} projected by the end of the month.
+            </p>
+          )}
+          {forecast.cost_unit_quota && (
+            <p style={{ margin: '0 0 4px 0', fontSize: '14px' }}>
+              Tool cost units: {forecast.cost_units_to_date.toLocaleString()} of {forecast.cost_unit_quota.toLocaleString()} used, about{' '}
+              {forecast.cost_units_projected.toLocaleString()} projected by the end of the month.
+            </p>
+          )}
+          <p style={{ margin: 0, fontSize: '14px' }}>
+            {forecast.quota_exhausted_on && `Your allowance is expected to run out around ${new Date(forecast.quota_exhausted_on).toLocaleDateString(undefined, { timeZone: 'UTC' })}. `}
+            {forecast.upgrade_suggested && 'Upgrade your plan to avoid interruptions.'}
+          </p>
+        </div>
+      )}
+
       {subscriptionId ? (
         // Show subscription management
         <div>
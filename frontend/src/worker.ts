@@ -31,6 +31,8 @@ type SessionPayload = {
   email?: string | null;
   provider?: "github" | "google";
   exp: number;
+  // Server-side session id; cookies without one cannot be revoked early.
+  sid?: string;
 };
 
 type StatePayload = {
@@ -56,6 +58,12 @@ export interface Env {
   STRIPE_PRICE_ID?: string;
   STRIPE_WEBHOOK_SECRET?: string;
   DATABASE_URL?: string;
+  // Auth-scoped API key the backend may require on forwarded sign-ins.
+  BACKEND_API_KEY?: string;
+}
+
+function backendAPIKeyHeader(env: Env): Record<string, string> {
+  return env.BACKEND_API_KEY ? { "X-API-Key": env.BACKEND_API_KEY } : {};
 }
 
 const keyCache = new Map<string, Promise<CryptoKey>>();
@@ -301,6 +309,10 @@ async function readSession(request: Request, env: Env): Promise<SessionPayload |
       return null;
     }
 
+    if (payload.sid && !(await sessionStillActive(request, env, payload.sid))) {
+      return null;
+    }
+
     return payload;
   } catch (error) {
     console.error("Failed to read session", error);
@@ -308,6 +320,104 @@ async function readSession(request: Request, env: Env): Promise<SessionPayload |
   }
 }
 
+// attachSessionId puts the server-side session id returned by the backend
+// auth sync into a new session cookie, so the session can be revoked. The
+// auth token issued with it is cached so the first API call needs no
+// /api/auth/token round trip.
+async function attachSessionId(env: Env, session: SessionPayload, backendResponse: Response): Promise<string> {
+  const synced = (await backendResponse.json().catch(() => null)) as {
+    session_id?: string;
+    token?: string;
+    expires_at?: string;
+  } | null;
+  if (synced?.session_id) {
+    session.sid = synced.session_id;
+  }
+  if (synced?.token && synced.expires_at) {
+    const cacheKey = session.sid ?? session.email ?? session.login;
+    backendAuthTokens.set(cacheKey, { token: synced.token, expiresAt: Date.parse(synced.expires_at) });
+  }
+  return encodeSignedPayload(getCookieSecret(env), session);
+}
+
+// Revocation checks are cached briefly so every request does not call the
+// backend; a revoked session is signed out within SESSION_CHECK_TTL_MS.
+const SESSION_CHECK_TTL_MS = 30_000;
+const activeSessionChecks = new Map<string, number>();
+
+// sessionStillActive asks the backend whether the session behind the request
+// cookie has been revoked. Backend failures keep the session usable.
+async function sessionStillActive(request: Request, env: Env, sid: string): Promise<boolean> {
+  if (!env.BACKEND_BASE_URL) {
+    return true;
+  }
+  const checkedUntil = activeSessionChecks.get(sid);
+  if (checkedUntil !== undefined && checkedUntil > Date.now()) {
+    return true;
+  }
+
+  try {
+    const resp = await fetch(new URL("/api/auth/session", env.BACKEND_BASE_URL).toString(), {
+      method: "GET",
+      headers: { Cookie: request.headers.get("Cookie") ?? "" },
+    });
+    if (!resp.ok) {
+      return true;
+    }
+    const body = (await resp.json()) as { authenticated?: boolean };
+    if (!body.authenticated) {
+      activeSessionChecks.delete(sid);
+      return false;
+    }
+    activeSessionChecks.set(sid, Date.now() + SESSION_CHECK_TTL_MS);
+    return true;
+  } catch (error) {
+    console.error("Failed to check session with backend", error);
+    return true;
+  }
+}
+
+// Backend auth tokens are reused until shortly before they expire.
+const AUTH_TOKEN_REFRESH_MARGIN_MS = 30_000;
+const backendAuthTokens = new Map<string, { token: string; expiresAt: number }>();
+
+// backendAuthHeaders exchanges the request's session cookie for a short-lived
+// backend auth token, which identifies the user to the backend in place of
+// an email in the query string or body. Returns no headers when a token
+// cannot be obtained.
+async function backendAuthHeaders(request: Request, env: Env, session: SessionPayload): Promise<Record<string, string>> {
+  if (!env.BACKEND_BASE_URL) {
+    return {};
+  }
+  const cacheKey = session.sid ?? session.email ?? session.login;
+  const cached = backendAuthTokens.get(cacheKey);
+  if (cached && cached.expiresAt - AUTH_TOKEN_REFRESH_MARGIN_MS > Date.now()) {
+    return { Authorization: `Bearer ${cached.token}` };
+  }
+
+  try {
+    const resp = await fetch(new URL("/api/auth/token", env.BACKEND_BASE_URL).toString(), {
+      method: "POST",
+      headers: { Cookie: request.headers.get("Cookie") ?? "" },
+    });
+    if (!resp.ok) {
+      console.error("Backend auth token request failed", { status: resp.status });
+      backendAuthTokens.delete(cacheKey);
+      return {};
+    }
+    const body = (await resp.json()) as { token?: string; expires_at?: string };
+    if (!body.token) {
+      return {};
+    }
+    const expiresAt = body.expires_at ? Date.parse(body.expires_at) : Date.now();
+    backendAuthTokens.set(cacheKey, { token: body.token, expiresAt });
+    return { Authorization: `Bearer ${body.token}` };
+  } catch (error) {
+    console.error("Failed to obtain backend auth token", error);
+    return {};
+  }
+}
+
 function isSecureRequest(request: Request, url: URL): boolean {
   const forwardedProto = request.headers.get("x-forwarded-proto");
   if (forwardedProto) {
@@ -327,6 +437,71 @@ function getEffectiveOrigin(request: Request, url: URL): string {
   return url.origin;
 }
 
+// parseSSORequired returns the organization slug when the backend rejected a
+// social login because the user's organization mandates SSO.
+function parseSSORequired(status: number, body: string): string | null {
+  if (status !== 403) {
+    return null;
+  }
+  try {
+    const parsed = JSON.parse(body) as { error?: string; org?: string };
+    return parsed.error === "sso_required" ? parsed.org ?? "" : null;
+  } catch {
+    return null;
+  }
+}
+
+// parseEmailUnverified reports whether the backend rejected a GitHub login
+// whose unverified email belongs to another, verified account.
+function parseEmailUnverified(status: number, body: string): boolean {
+  if (status !== 403) {
+    return false;
+  }
+  try {
+    return (JSON.parse(body) as { error?: string }).error === "email_unverified";
+  } catch {
+    return false;
+  }
+}
+
+function ssoRequiredRedirect(request: Request, url: URL, env: Env, org: string): Response {
+  return loginErrorRedirect(request, url, env, "sso_required", org ? { org } : {});
+}
+
+// loginErrorRedirect sends the browser back to the login page with an error
+// code and clears the OAuth state cookie without starting a session.
+function loginErrorRedirect(
+  request: Request,
+  url: URL,
+  env: Env,
+  error: string,
+  params: Record<string, string> = {},
+): Response {
+  const location = new URL("/login", url.origin);
+  location.searchParams.set("error", error);
+  for (const [key, value] of Object.entries(params)) {
+    location.searchParams.set(key, value);
+  }
+  const response = new Response(null, {
+    status: 303,
+    headers: {
+      Location: location.pathname + location.search,
+    },
+  });
+  response.headers.append(
+    "Set-Cookie",
+    serializeCookie(STATE_COOKIE, "", {
+      httpOnly: true,
+      secure: isSecureRequest(request, url),
+      sameSite: "Lax",
+      path: "/",
+      domain: getCookieDomain(env),
+      maxAge: 0,
+    }),
+  );
+  return response;
+}
+
 function acceptsHtml(request: Request): boolean {
   return (request.headers.get("Accept") ?? "").includes("text/html");
 }
@@ -409,6 +584,17 @@ export async function handleFrontendFetch(
       return response;
     }
 
+    // Organization SSO runs on the backend, which sets the shared session
+    // cookie once the identity provider has signed the user in.
+    if (url.pathname === "/api/auth/sso/login" && request.method === "GET") {
+      if (!env.BACKEND_BASE_URL) {
+        return jsonResponse({ error: "Backend not configured" }, { status: 500 });
+      }
+      const target = new URL(buildBackendUrl(env.BACKEND_BASE_URL, "/api/auth/sso/login"));
+      target.search = url.search;
+      return Response.redirect(target.toString(), 302);
+    }
+
     if (url.pathname === "/api/auth/google/login" && request.method === "GET") {
       try {
         if (!env.GOOGLE_CLIENT_ID) {
@@ -482,11 +668,10 @@ export async function handleFrontendFetch(
       }
 
       const backendUrl = new URL("/api/auth/connected-accounts", env.BACKEND_BASE_URL);
-      if (session.email) {
-        backendUrl.searchParams.set("email", session.email);
-      }
-
-      const upstreamResp = await fetch(backendUrl.toString(), { method: "GET" });
+      const upstreamResp = await fetch(backendUrl.toString(), {
+        method: "GET",
+        headers: await backendAuthHeaders(request, env, session),
+      });
       const text = await upstreamResp.text();
 
       if (!upstreamResp.ok) {
@@ -510,6 +695,78 @@ export async function handleFrontendFetch(
       });
     }
 
+    if (
+      (url.pathname === "/api/auth/sessions" && (request.method === "GET" || request.method === "DELETE")) ||
+      (url.pathname.startsWith("/api/auth/sessions/") && request.method === "DELETE")
+    ) {
+      const session = await readSession(request, env);
+      if (!session || !session.email) {
+        return jsonResponse({ error: "Not authenticated" }, { status: 401 });
+      }
+
+      if (!env.BACKEND_BASE_URL) {
+        console.error("[sessions] BACKEND_BASE_URL missing");
+        return jsonResponse({ error: "Backend is not configured" }, { status: 500 });
+      }
+
+      const backendUrl = new URL(url.pathname, env.BACKEND_BASE_URL);
+      const upstreamResp = await fetch(backendUrl.toString(), {
+        method: request.method,
+        headers: await backendAuthHeaders(request, env, session),
+      });
+      const text = await upstreamResp.text();
+      if (!upstreamResp.ok) {
+        console.error("Backend sessions request failed", {
+          status: upstreamResp.status,
+          body: text,
+        });
+      }
+
+      return new Response(text, {
+        status: upstreamResp.status,
+        headers: {
+          "Content-Type": upstreamResp.headers.get("Content-Type") || "application/json; charset=utf-8",
+        },
+      });
+    }
+
+    if (url.pathname === "/api/auth/logins" && request.method === "GET") {
+      const session = await readSession(request, env);
+      if (!session) {
+        return jsonResponse({ error: "Not authenticated" }, { status: 401 });
+      }
+
+      if (!env.BACKEND_BASE_URL) {
+        console.error("[login-history] BACKEND_BASE_URL missing");
+        return jsonResponse({ error: "Backend is not configured" }, { status: 500 });
+      }
+
+      const backendUrl = new URL("/api/auth/logins", env.BACKEND_BASE_URL);
+      const limit = url.searchParams.get("limit");
+      if (limit) {
+        backendUrl.searchParams.set("limit", limit);
+      }
+
+      const upstreamResp = await fetch(backendUrl.toString(), {
+        method: "GET",
+        headers: await backendAuthHeaders(request, env, session),
+      });
+      const text = await upstreamResp.text();
+      if (!upstreamResp.ok) {
+        console.error("Backend login history fetch failed", {
+          status: upstreamResp.status,
+          body: text,
+        });
+      }
+
+      return new Response(text, {
+        status: upstreamResp.status,
+        headers: {
+          "Content-Type": upstreamResp.headers.get("Content-Type") || "application/json; charset=utf-8",
+        },
+      });
+    }
+
     if (url.pathname === "/api/settings/jira/test") {
       const session = await readSession(request, env);
       if (!session) {
@@ -620,14 +877,11 @@ export async function handleFrontendFetch(
           atlassian_api_key: body.atlassian_api_key,
         };
 
-        if (session.email) {
-          payload.user_email = session.email;
-        }
-
         const upstreamResp = await fetch(backendUrl.toString(), {
           method: "POST",
           headers: {
             "Content-Type": "application/json",
+            ...(await backendAuthHeaders(request, env, session)),
           },
           body: JSON.stringify(payload),
         });
@@ -655,13 +909,9 @@ export async function handleFrontendFetch(
       }
 
       if (request.method === "GET") {
-        const urlWithEmail = new URL(backendUrl.toString());
-        if (session.email) {
-          urlWithEmail.searchParams.set("email", session.email);
-        }
-
-        const upstreamResp = await fetch(urlWithEmail.toString(), {
+        const upstreamResp = await fetch(backendUrl.toString(), {
           method: "GET",
+          headers: await backendAuthHeaders(request, env, session),
         });
 
         const text = await upstreamResp.text();
@@ -703,12 +953,10 @@ export async function handleFrontendFetch(
       const backendUrl = new URL("/api/mcp/secret", env.BACKEND_BASE_URL);
 
       if (request.method === "GET") {
-        const urlWithEmail = new URL(backendUrl.toString());
-        if (session.email) {
-          urlWithEmail.searchParams.set("email", session.email);
-        }
-
-        const upstreamResp = await fetch(urlWithEmail.toString(), { method: "GET" });
+        const upstreamResp = await fetch(backendUrl.toString(), {
+          method: "GET",
+          headers: await backendAuthHeaders(request, env, session),
+        });
         const text = await upstreamResp.text();
         if (!upstreamResp.ok) {
           console.error("Backend MCP secret load failed", {
@@ -732,17 +980,13 @@ export async function handleFrontendFetch(
       }
 
       if (request.method === "POST") {
-        const payload: { user_email?: string } = {};
-        if (session.email) {
-          payload.user_email = session.email;
-        }
-
         const upstreamResp = await fetch(backendUrl.toString(), {
           method: "POST",
           headers: {
             "Content-Type": "application/json",
+            ...(await backendAuthHeaders(request, env, session)),
           },
-          body: JSON.stringify(payload),
+          body: JSON.stringify({}),
         });
         const text = await upstreamResp.text();
         if (!upstreamResp.ok) {
@@ -783,10 +1027,10 @@ export async function handleFrontendFetch(
       const backendUrl = new URL("/api/integrations/tokens", env.BACKEND_BASE_URL);
 
       if (request.method === "GET") {
-        if (session.email) {
-          backendUrl.searchParams.set("email", session.email);
-        }
-        const upstreamResp = await fetch(backendUrl.toString(), { method: "GET" });
+        const upstreamResp = await fetch(backendUrl.toString(), {
+          method: "GET",
+          headers: await backendAuthHeaders(request, env, session),
+        });
         const text = await upstreamResp.text();
         return new Response(text, {
           status: upstreamResp.status,
@@ -801,10 +1045,10 @@ export async function handleFrontendFetch(
         } catch {
           return jsonResponse({ error: "Invalid JSON" }, { status: 400 });
         }
-        body.user_email = session.email;
+        delete body.user_email;
         const upstreamResp = await fetch(backendUrl.toString(), {
           method: "POST",
-          headers: { "Content-Type": "application/json" },
+          headers: { "Content-Type": "application/json", ...(await backendAuthHeaders(request, env, session)) },
           body: JSON.stringify(body),
         });
         const text = await upstreamResp.text();
@@ -819,9 +1063,11 @@ export async function handleFrontendFetch(
         if (!provider) {
           return jsonResponse({ error: "provider query parameter is required" }, { status: 400 });
         }
-        backendUrl.searchParams.set("email", session.email || "");
         backendUrl.searchParams.set("provider", provider);
-        const upstreamResp = await fetch(backendUrl.toString(), { method: "DELETE" });
+        const upstreamResp = await fetch(backendUrl.toString(), {
+          method: "DELETE",
+          headers: await backendAuthHeaders(request, env, session),
+        });
         const text = await upstreamResp.text();
         return new Response(text, {
           status: upstreamResp.status,
@@ -974,9 +1220,8 @@ export async function handleFrontendFetch(
 
         await fetch(new URL("/api/integrations/tokens", env.BACKEND_BASE_URL).toString(), {
           method: "POST",
-          headers: { "Content-Type": "application/json" },
+          headers: { "Content-Type": "application/json", ...(await backendAuthHeaders(request, env, session)) },
           body: JSON.stringify({
-            user_email: session.email,
             provider: "google_docs",
             access_token: tokenData.access_token,
             refresh_token: tokenData.refresh_token || null,
@@ -1006,6 +1251,78 @@ export async function handleFrontendFetch(
       return response;
     }
 
+    if (url.pathname === "/api/metrics/forecast" && request.method === "GET") {
+      const session = await readSession(request, env);
+      if (!session) {
+        return jsonResponse({ error: "Not authenticated" }, { status: 401 });
+      }
+
+      if (!env.BACKEND_BASE_URL) {
+        console.error("[metrics/forecast] BACKEND_BASE_URL missing");
+        return jsonResponse({ error: "Backend is not configured" }, { status: 500 });
+      }
+
+      const backendUrl = new URL("/api/metrics/forecast", env.BACKEND_BASE_URL);
+      const upstreamResp = await fetch(backendUrl.toString(), {
+        method: "GET",
+        headers: await backendAuthHeaders(request, env, session),
+      });
+      const text = await upstreamResp.text();
+      if (!upstreamResp.ok) {
+        console.error("Backend usage forecast load failed", {
+          status: upstreamResp.status,
+          body: text,
+        });
+      }
+
+      return new Response(text, {
+        status: upstreamResp.status,
+        headers: {
+          "Content-Type": upstreamResp.headers.get("Content-Type") || "application/json; charset=utf-8",
+        },
+      });
+    }
+
+    // Organization management: members, claimed domains and SSO settings.
+    if (url.pathname === "/api/orgs" || url.pathname.startsWith("/api/orgs/")) {
+      const session = await readSession(request, env);
+      if (!session) {
+        return jsonResponse({ error: "Not authenticated" }, { status: 401 });
+      }
+
+      if (!env.BACKEND_BASE_URL) {
+        console.error("[orgs] BACKEND_BASE_URL missing");
+        return jsonResponse({ error: "Backend is not configured" }, { status: 500 });
+      }
+
+      const backendUrl = new URL(buildBackendUrl(env.BACKEND_BASE_URL, url.pathname));
+      backendUrl.search = url.search;
+      backendUrl.searchParams.delete("email");
+
+      const hasBody = request.method !== "GET" && request.method !== "HEAD";
+      const authHeaders = await backendAuthHeaders(request, env, session);
+      const upstreamResp = await fetch(backendUrl.toString(), {
+        method: request.method,
+        headers: hasBody ? { "Content-Type": "application/json", ...authHeaders } : authHeaders,
+        body: hasBody ? await request.text() : undefined,
+      });
+      const text = await upstreamResp.text();
+      if (!upstreamResp.ok) {
+        console.error("Backend organization request failed", {
+          path: url.pathname,
+          status: upstreamResp.status,
+          body: text,
+        });
+      }
+
+      return new Response(text, {
+        status: upstreamResp.status,
+        headers: {
+          "Content-Type": upstreamResp.headers.get("Content-Type") || "application/json; charset=utf-8",
+        },
+      });
+    }
+
     if (url.pathname === "/api/billing/create-subscription" && request.method === "POST") {
       const session = await readSession(request, env);
       if (!session) {
@@ -1108,9 +1425,9 @@ export async function handleFrontendFetch(
               method: "POST",
               headers: {
                 "Content-Type": "application/json",
+                ...(await backendAuthHeaders(request, env, session)),
               },
               body: JSON.stringify({
-                user_email: session.email,
                 stripe_customer_id: customer.id,
                 stripe_subscription_id: subscription.id,
                 stripe_price_id: env.STRIPE_PRICE_ID,
@@ -1514,6 +1831,21 @@ export async function handleFrontendFetch(
     }
 
     if (url.pathname === "/api/auth/logout" && request.method === "POST") {
+      const session = await readSession(request, env);
+      if (session?.sid && env.BACKEND_BASE_URL) {
+        const revokeUrl = new URL(`/api/auth/sessions/${encodeURIComponent(session.sid)}`, env.BACKEND_BASE_URL);
+        try {
+          await fetch(revokeUrl.toString(), {
+            method: "DELETE",
+            headers: await backendAuthHeaders(request, env, session),
+          });
+        } catch (error) {
+          console.error("Failed to revoke session on logout", error);
+        }
+        activeSessionChecks.delete(session.sid);
+        backendAuthTokens.delete(session.sid);
+      }
+
       const response = jsonResponse({ ok: true });
       response.headers.append(
         "Set-Cookie",
@@ -1680,6 +2012,7 @@ export async function handleFrontendFetch(
 
       // If this is an account linking operation, preserve the existing session
       let sessionCookieValue: string;
+      let newSession: SessionPayload | null = null;
       const redirectTarget = normalizeRedirectTarget(parsedState.redirect) || "/";
 
       if (parsedState.linkAccount) {
@@ -1720,6 +2053,7 @@ export async function handleFrontendFetch(
             exp: Math.floor(Date.now() / 1000) + SESSION_TTL_SECONDS,
           };
           sessionCookieValue = await encodeSignedPayload(getCookieSecret(env), sessionPayload);
+          newSession = sessionPayload;
         }
       } else {
         // Normal login - create a new session
@@ -1733,10 +2067,12 @@ export async function handleFrontendFetch(
           exp: Math.floor(Date.now() / 1000) + SESSION_TTL_SECONDS,
         };
         sessionCookieValue = await encodeSignedPayload(getCookieSecret(env), sessionPayload);
+        newSession = sessionPayload;
       }
 
       // Best-effort: synchronise the authenticated Google user into the backend
       // multi-tenant database. Failures here should not block login.
+      let ssoRequiredOrg: string | null = null;
       if (env.BACKEND_BASE_URL && tokenPayload.access_token) {
         const backendUrl = new URL("/api/auth/google", env.BACKEND_BASE_URL);
         const body = JSON.stringify({
@@ -1745,6 +2081,9 @@ export async function handleFrontendFetch(
           email,
           avatar_url: userData.picture ?? null,
           access_token: tokenPayload.access_token,
+          client_ip: request.headers.get("CF-Connecting-IP") ?? undefined,
+          user_agent: request.headers.get("User-Agent") ?? undefined,
+          start_session: newSession !== null,
         });
 
         try {
@@ -1752,21 +2091,31 @@ export async function handleFrontendFetch(
             method: "POST",
             headers: {
               "Content-Type": "application/json",
+              ...backendAPIKeyHeader(env),
             },
             body,
           });
           if (!backendResponse.ok) {
             const text = await backendResponse.text();
+            ssoRequiredOrg = parseSSORequired(backendResponse.status, text);
             console.error("Backend Google auth sync failed", {
               status: backendResponse.status,
               body: text,
             });
+          } else if (newSession) {
+            sessionCookieValue = await attachSessionId(env, newSession, backendResponse);
           }
         } catch (error) {
           console.error("Failed to sync Google user to backend", error);
         }
       }
 
+      // Members of organizations that mandate SSO must not get a session
+      // from a social login.
+      if (ssoRequiredOrg !== null) {
+        return ssoRequiredRedirect(request, url, env, ssoRequiredOrg);
+      }
+
       const response = new Response(null, {
         status: 303,
         headers: {
@@ -1882,7 +2231,10 @@ export async function handleFrontendFetch(
         email?: string | null;
       };
 
+      // GitHub only lets users publish a verified address; hidden ones are
+      // looked up and may be unverified, which the backend confirms by email.
       let primaryEmail: string | null | undefined = userData.email ?? null;
+      let primaryEmailVerified = Boolean(primaryEmail);
       if (!primaryEmail) {
         const emailResponse = await fetch("https://api.github.com/user/emails", {
           headers: {
@@ -1900,11 +2252,13 @@ export async function handleFrontendFetch(
           }>;
           const preferred = emails.find((item) => item.primary && item.verified) ?? emails.find((item) => item.primary) ?? emails[0];
           primaryEmail = preferred?.email ?? null;
+          primaryEmailVerified = preferred?.verified === true;
         }
       }
 
       // If this is an account linking operation, preserve the existing session
       let sessionCookieValue: string;
+      let newSession: SessionPayload | null = null;
       const redirectTarget = normalizeRedirectTarget(parsedState.redirect) || "/";
 
       if (parsedState.linkAccount) {
@@ -1945,6 +2299,7 @@ export async function handleFrontendFetch(
             exp: Math.floor(Date.now() / 1000) + SESSION_TTL_SECONDS,
           };
           sessionCookieValue = await encodeSignedPayload(getCookieSecret(env), sessionPayload);
+          newSession = sessionPayload;
         }
       } else {
         // Normal login - create a new session
@@ -1958,10 +2313,13 @@ export async function handleFrontendFetch(
           exp: Math.floor(Date.now() / 1000) + SESSION_TTL_SECONDS,
         };
         sessionCookieValue = await encodeSignedPayload(getCookieSecret(env), sessionPayload);
+        newSession = sessionPayload;
       }
 
       // Best-effort: synchronise the authenticated GitHub user into the backend
       // multi-tenant database. Failures here should not block login.
+      let ssoRequiredOrg: string | null = null;
+      let emailUnverified = false;
       if (env.BACKEND_BASE_URL && tokenPayload.access_token) {
         const backendUrl = new URL("/api/auth/github", env.BACKEND_BASE_URL);
         const scope = tokenPayload.scope ?? "";
@@ -1970,9 +2328,13 @@ export async function handleFrontendFetch(
           login: userData.login,
           name: userData.name ?? null,
           email: primaryEmail ?? null,
+          email_verified: primaryEmailVerified,
           avatar_url: userData.avatar_url ?? null,
           access_token: tokenPayload.access_token,
           scope: scope.length > 0 ? scope : undefined,
+          client_ip: request.headers.get("CF-Connecting-IP") ?? undefined,
+          user_agent: request.headers.get("User-Agent") ?? undefined,
+          start_session: newSession !== null,
         });
 
         try {
@@ -1980,21 +2342,38 @@ export async function handleFrontendFetch(
             method: "POST",
             headers: {
               "Content-Type": "application/json",
+              ...backendAPIKeyHeader(env),
             },
             body,
           });
           if (!backendResponse.ok) {
             const text = await backendResponse.text();
+            ssoRequiredOrg = parseSSORequired(backendResponse.status, text);
+            emailUnverified = parseEmailUnverified(backendResponse.status, text);
             console.error("Backend GitHub auth sync failed", {
               status: backendResponse.status,
               body: text,
             });
+          } else if (newSession) {
+            sessionCookieValue = await attachSessionId(env, newSession, backendResponse);
           }
         } catch (error) {
           console.error("Failed to sync GitHub user to backend", error);
         }
       }
 
+      // Members of organizations that mandate SSO must not get a session
+      // from a social login.
+      if (ssoRequiredOrg !== null) {
+        return ssoRequiredRedirect(request, url, env, ssoRequiredOrg);
+      }
+
+      // An unverified GitHub email must not sign in as the verified account
+      // that owns the address.
+      if (emailUnverified) {
+        return loginErrorRedirect(request, url, env, "email_unverified");
+      }
+
       const response = new Response(null, {
         status: 303,
         headers: {
@@ -0,0 +1,38 @@
+import { BackpressureError, backpressureFromResponse, backpressureToolResult, parseRetryAfter } from "./backpressure";
+
+describe("backpressure", () => {
+  it("parses Retry-After seconds and dates", () => {
+    expect(parseRetryAfter("30")).toBe(30_000);
+    expect(parseRetryAfter(null)).toBeNull();
+    expect(parseRetryAfter("soon")).toBeNull();
+    const inAMinute = new Date(Date.now() + 60_000).toUTCString();
+    expect(parseRetryAfter(inAMinute)).toBeGreaterThan(50_000);
+  });
+
+  it("reads the backend backoff hint", async () => {
+    const response = new Response(
+      JSON.stringify({ error: "too many requests", reason: "rate_limited", retry_after_seconds: 42 }),
+      { status: 429, headers: { "Retry-After": "42", "Content-Type": "application/json" } },
+    );
+    const err = await backpressureFromResponse(response, "Backend");
+    expect(err).toBeInstanceOf(BackpressureError);
+    expect(err?.reason).toBe("rate_limited");
+    expect(err?.retryAfterMs).toBe(42_000);
+
+    const result = backpressureToolResult(err!);
+    expect(result.isError).toBe(true);
+    expect(result.data.backoff.retry_after_seconds).toBe(42);
+    expect(result.content[0].text).toContain("Retry in 42 seconds");
+  });
+
+  it("ignores other statuses", async () => {
+    expect(await backpressureFromResponse(new Response("nope", { status: 404 }), "Jira")).toBeNull();
+  });
+
+  it("falls back to the status when the body is not a hint", async () => {
+    const err = await backpressureFromResponse(new Response("down", { status: 503 }), "Jira");
+    expect(err?.reason).toBe("unavailable");
+    expect(err?.retryAfterMs).toBeNull();
+    expect(backpressureToolResult(err!).content[0].text).toContain("Retry later");
+  });
+});
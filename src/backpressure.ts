@@ -0,0 +1,112 @@
+/**
+ * Backpressure signaling. When the backend or Jira rejects a request because
+ * of a rate limit, quota or maintenance window, the rejection is turned into
+ * a BackpressureError carrying the Retry-After delay, and tool calls that
+ * fail with one return a structured backoff hint instead of a bare error.
+ */
+
+// Statuses that mean "try again later" rather than "this request is wrong".
+const BACKPRESSURE_STATUSES = new Set([429, 503]);
+
+// Machine-readable body the backend sends with 429/503 rejections.
+type BackoffHintBody = {
+  error?: string;
+  reason?: string;
+  retry_after_seconds?: number;
+};
+
+export class BackpressureError extends Error {
+  readonly status: number;
+  readonly reason: string;
+  // Null when the upstream gave no Retry-After.
+  readonly retryAfterMs: number | null;
+  // Who rejected the request, e.g. "Jira" or "Backend".
+  readonly source: string;
+
+  constructor(message: string, status: number, reason: string, retryAfterMs: number | null, source = "") {
+    super(message);
+    this.name = "BackpressureError";
+    this.status = status;
+    this.reason = reason;
+    this.retryAfterMs = retryAfterMs;
+    this.source = source;
+  }
+}
+
+export function isBackpressureStatus(status: number): boolean {
+  return BACKPRESSURE_STATUSES.has(status);
+}
+
+/**
+ * Parses a Retry-After header, either delay-seconds or an HTTP date, into
+ * milliseconds from now.
+ */
+export function parseRetryAfter(header: string | null): number | null {
+  if (!header) {
+    return null;
+  }
+
+  const seconds = Number(header);
+  if (!Number.isNaN(seconds)) {
+    return Math.max(0, seconds * 1000);
+  }
+
+  const date = Date.parse(header);
+  if (!Number.isNaN(date)) {
+    return Math.max(0, date - Date.now());
+  }
+
+  return null;
+}
+
+/**
+ * Builds a BackpressureError from a 429/503 response, reading the backend's
+ * backoff hint from the body when present. Returns null for other statuses.
+ * Consumes the response body.
+ */
+export async function backpressureFromResponse(response: Response, source: string): Promise<BackpressureError | null> {
+  if (!isBackpressureStatus(response.status)) {
+    return null;
+  }
+
+  let hint: BackoffHintBody = {};
+  try {
+    const text = await response.text();
+    const parsed = JSON.parse(text);
+    if (parsed && typeof parsed === "object") hint = parsed as BackoffHintBody;
+  } catch {
+    // Not every upstream sends JSON; the header alone is enough.
+  }
+
+  let retryAfterMs = parseRetryAfter(response.headers.get("Retry-After"));
+  if (retryAfterMs === null && typeof hint.retry_after_seconds === "number") {
+    retryAfterMs = Math.max(0, hint.retry_after_seconds * 1000);
+  }
+  const reason = hint.reason || (response.status === 429 ? "rate_limited" : "unavailable");
+  const detail = hint.error || response.statusText || String(response.status);
+
+  return new BackpressureError(`${source} is rejecting requests (${reason}): ${detail}`, response.status, reason, retryAfterMs, source);
+}
+
+/**
+ * Converts a BackpressureError into a tool result the client can act on:
+ * the text tells a model when to retry and data carries the same hint for
+ * programmatic callers.
+ */
+export function backpressureToolResult(err: BackpressureError) {
+  const retryAfterSeconds = err.retryAfterMs === null ? null : Math.ceil(err.retryAfterMs / 1000);
+  const when = retryAfterSeconds === null ? "later" : `in ${retryAfterSeconds} second${retryAfterSeconds === 1 ? "" : "s"}`;
+  return {
+    content: [{ text: `${err.message}. Retry ${when}.`, type: "text" }],
+    isError: true,
+    data: {
+      success: false,
+      backoff: {
+        reason: err.reason,
+        status: err.status,
+        retry_after_seconds: retryAfterSeconds,
+        retry_at: retryAfterSeconds === null ? null : new Date(Date.now() + retryAfterSeconds * 1000).toISOString(),
+      },
+    },
+  };
+}
@@ -1,4 +1,6 @@
 import { z } from "zod";
+import { formatJiraDate, resolveNaturalDate } from "../utils";
+import { toolSchemas } from "../tool-schemas";
 
 /**
  * Register workflow-oriented Jira tools on the MCP server.
@@ -10,15 +12,28 @@ import { z } from "zod";
  *
  * @param {object} server - McpServer instance
  * @param {function} getJiraClient - async function returning a JiraClient
- * @param {object} helpers - { stripAvatarUrls, normalizeUser, normalizeResponse }
+ * @param {object} helpers - { stripAvatarUrls, normalizeUser, normalizeResponse, getPreferences }
  * @returns {string[]} names of registered tools
  */
 export async function registerJiraWorkflowTools(server, getJiraClient, helpers) {
   const { stripAvatarUrls, normalizeUser, normalizeResponse } = helpers;
+  const getPreferences = helpers.getPreferences || (async () => undefined);
   const registeredTools = [];
 
   // ── Internal helpers ──────────────────────────────────────
 
+  // Interpret a due date ("next Friday", "in 2 weeks", "2025-07-01") in the
+  // tenant's timezone and return a Jira date.
+  const resolveDueDate = (input, prefs) => {
+    if (!input) return undefined;
+    const date = resolveNaturalDate(input, prefs);
+    if (!date)
+      throw new Error(
+        `Could not understand due date "${input}". Use YYYY-MM-DD or phrases like "tomorrow", "next Friday", "in 2 weeks", "end of month".`,
+      );
+    return date;
+  };
+
   // Resolve an assignee name/email to accountId
   const resolveAssignee = async (jiraClient, assigneeInput) => {
     if (!assigneeInput) return undefined;
@@ -109,11 +124,12 @@ export async function registerJiraWorkflowTools(server, getJiraClient, helpers)
     "getProjectOverview",
     "Get a comprehensive overview of a Jira project: details, active sprint, backlog count, issue types, boards, recent activity, and stale issues. Set listProjects=true to list all projects instead.",
     {
-      projectKey: z.string().optional().describe("Project key (e.g. 'ENG'). Required unless listProjects is true."),
+      projectKey: toolSchemas.projectKey().optional().describe("Project key (e.g. 'ENG'). Required unless listProjects is true."),
       listProjects: z.boolean().optional().describe("If true, returns a simple list of all projects."),
     },
     async (input) => {
       const jiraClient = await getJiraClient();
+      const prefs = await getPreferences();
 
       if (input.listProjects) {
         const projects = await jiraClient.getProjects();
@@ -203,13 +219,13 @@ export async function registerJiraWorkflowTools(server, getJiraClient, helpers)
         key: i.key,
         summary: i.fields?.summary,
         status: i.fields?.status?.name,
-        updated: i.fields?.updated,
+        updated: formatJiraDate(i.fields?.updated, prefs),
       }));
       const stale = (staleResult.issues || []).map((i) => ({
         key: i.key,
         summary: i.fields?.summary,
         status: i.fields?.status?.name,
-        updated: i.fields?.updated,
+        updated: formatJiraDate(i.fields?.updated, prefs),
         assignee: i.fields?.assignee?.displayName || "Unassigned",
       }));
 
@@ -269,8 +285,8 @@ export async function registerJiraWorkflowTools(server, getJiraClient, helpers)
     "getSprintBoard",
     "View a sprint board: issues grouped by status (To Do / In Progress / Done), assignee workload, and progress percentages. Provide sprintId directly or projectKey to auto-find the active sprint.",
     {
-      sprintId: z.number().optional().describe("Sprint ID to view. If omitted, projectKey is used to find the active sprint."),
-      projectKey: z.string().optional().describe("Project key — used to find the active sprint when sprintId is not provided."),
+      sprintId: toolSchemas.jiraId().optional().describe("Sprint ID to view. If omitted, projectKey is used to find the active sprint."),
+      projectKey: toolSchemas.projectKey().optional().describe("Project key — used to find the active sprint when sprintId is not provided."),
     },
     async (input) => {
       const jiraClient = await getJiraClient();
@@ -357,11 +373,11 @@ export async function registerJiraWorkflowTools(server, getJiraClient, helpers)
     "Manage the project backlog: list issues, move issues to a sprint, or move issues back to the backlog.",
     {
       command: z.enum(["list", "moveToSprint", "moveToBacklog", "/help"]).describe("The operation to perform."),
-      projectKey: z.string().optional().describe("Project key (required for 'list')."),
-      sprintId: z.number().optional().describe("Target sprint ID for moveToSprint."),
-      boardId: z.number().optional().describe("Board ID for moveToBacklog (defaults to first board of the project)."),
-      issueKeys: z.array(z.string()).optional().describe("Issue keys to move (required for moveToSprint/moveToBacklog)."),
-      maxResults: z.number().optional().describe("Max issues to return for 'list' (default 50)."),
+      projectKey: toolSchemas.projectKey().optional().describe("Project key (required for 'list')."),
+      sprintId: toolSchemas.jiraId().optional().describe("Target sprint ID for moveToSprint."),
+      boardId: toolSchemas.jiraId().optional().describe("Board ID for moveToBacklog (defaults to first board of the project)."),
+      issueKeys: toolSchemas.issueKeys().optional().describe("Issue keys to move (required for moveToSprint/moveToBacklog)."),
+      maxResults: toolSchemas.maxResults().optional().describe("Max issues to return for 'list' (default 50)."),
     },
     async (input) => {
       if (input.command === "/help") {
@@ -442,17 +458,19 @@ export async function registerJiraWorkflowTools(server, getJiraClient, helpers)
     "createWorkItem",
     "Create a Jira issue with smart resolution: issue type by name, assignee by name/email, priority by name. Validates against the project's available issue types. Optionally link to a parent epic.",
     {
-      projectKey: z.string().describe("Project key (e.g. 'ENG')."),
+      projectKey: toolSchemas.projectKey().describe("Project key (e.g. 'ENG')."),
       summary: z.string().describe("Issue title/summary."),
       issueType: z.string().describe("Issue type name (e.g. 'Bug', 'Task', 'Story', 'Epic'). Resolved automatically."),
       description: z.string().optional().describe("Plain text description."),
       assignee: z.string().optional().describe("Assignee name, email, or accountId. Resolved automatically."),
       priority: z.string().optional().describe("Priority name (e.g. 'High', 'Low')."),
       labels: z.array(z.string()).optional().describe("Labels to apply."),
-      parentKey: z.string().optional().describe("Parent issue key (for subtasks or linking to an epic)."),
+      parentKey: toolSchemas.issueKey().optional().describe("Parent issue key (for subtasks or linking to an epic)."),
+      dueDate: z.string().optional().describe("Due date as YYYY-MM-DD or a phrase like 'next Friday' or 'in 2 weeks' (interpreted in the tenant's timezone)."),
     },
     async (input) => {
       const jiraClient = await getJiraClient();
+      const prefs = await getPreferences();
 
       if (!input.projectKey) throw new Error("projectKey is required.");
       if (!input.summary) throw new Error("summary is required.");
@@ -471,6 +489,7 @@ export async function registerJiraWorkflowTools(server, getJiraClient, helpers)
       if (input.priority) fields.priority = { name: input.priority };
       if (input.labels && input.labels.length > 0) fields.labels = input.labels;
       if (input.parentKey) fields.parent = { key: input.parentKey };
+      if (input.dueDate) fields.duedate = resolveDueDate(input.dueDate, prefs);
 
       // Resolve assignee
       if (input.assignee) {
@@ -492,7 +511,7 @@ export async function registerJiraWorkflowTools(server, getJiraClient, helpers)
         issueType: issue.fields?.issuetype?.name,
         priority: issue.fields?.priority?.name,
         assignee: issue.fields?.assignee?.displayName || "Unassigned",
-        created: issue.fields?.created,
+        created: formatJiraDate(issue.fields?.created, prefs),
       };
 
       return {
@@ -509,7 +528,7 @@ export async function registerJiraWorkflowTools(server, getJiraClient, helpers)
     "updateWorkItem",
     "Update a Jira issue in one call: transition status (by name), assign/unassign, add comment, update labels, priority, summary, or description. Returns the updated issue state and available next transitions.",
     {
-      issueKey: z.string().describe("Issue key (e.g. 'ENG-123')."),
+      issueKey: toolSchemas.issueKey().describe("Issue key (e.g. 'ENG-123')."),
       status: z.string().optional().describe("Target status name (e.g. 'In Progress', 'Done'). Resolved to transition ID automatically."),
       assignee: z.string().optional().describe("Assignee name, email, or accountId. Use empty string to unassign."),
       comment: z.string().optional().describe("Comment text to add to the issue."),
@@ -518,9 +537,11 @@ export async function registerJiraWorkflowTools(server, getJiraClient, helpers)
       priority: z.string().optional().describe("Priority name (e.g. 'High', 'Low')."),
       summary: z.string().optional().describe("New summary/title."),
       description: z.string().optional().describe("New description (plain text)."),
+      dueDate: z.string().optional().describe("New due date as YYYY-MM-DD or a phrase like 'next Friday' (tenant's timezone). Use empty string to clear."),
     },
     async (input) => {
       const jiraClient = await getJiraClient();
+      const prefs = await getPreferences();
       const { issueKey } = input;
 
       // Perform updates in sequence (some depend on issue state)
@@ -538,6 +559,7 @@ export async function registerJiraWorkflowTools(server, getJiraClient, helpers)
       if (input.summary !== undefined) fields.summary = input.summary;
       if (input.description !== undefined) fields.description = input.description;
       if (input.priority) fields.priority = { name: input.priority };
+      if (input.dueDate !== undefined) fields.duedate = input.dueDate === "" ? null : resolveDueDate(input.dueDate, prefs);
 
       // Assignee
       if (input.assignee !== undefined) {
@@ -586,7 +608,7 @@ export async function registerJiraWorkflowTools(server, getJiraClient, helpers)
         priority: issue.fields?.priority?.name,
         assignee: issue.fields?.assignee?.displayName || "Unassigned",
         labels: issue.fields?.labels || [],
-        updated: issue.fields?.updated,
+        updated: formatJiraDate(issue.fields?.updated, prefs),
         availableTransitions: transitionsResp.transitions.map((t) => ({
           id: t.id, name: t.name, targetStatus: t.to?.name,
         })),
@@ -616,22 +638,23 @@ export async function registerJiraWorkflowTools(server, getJiraClient, helpers)
     "searchWorkItems",
     "Search for Jira issues using structured filters — no JQL needed. Filter by project, status, assignee, issue type, priority, labels, sprint, or text. Advanced users can pass a raw JQL override.",
     {
-      projectKey: z.string().optional().describe("Project key to search within."),
+      projectKey: toolSchemas.projectKey().optional().describe("Project key to search within."),
       status: z.union([z.string(), z.array(z.string())]).optional().describe("Status name(s) to filter by."),
-      statusCategory: z.string().optional().describe("Status category: 'To Do', 'In Progress', or 'Done'."),
+      statusCategory: toolSchemas.statusCategory().optional().describe("Status category: 'To Do', 'In Progress', or 'Done'."),
       assignee: z.string().optional().describe("Assignee name or accountId. Use 'currentUser' for self."),
       issueType: z.string().optional().describe("Issue type name (e.g. 'Bug', 'Task')."),
       priority: z.string().optional().describe("Priority name."),
       labels: z.array(z.string()).optional().describe("Labels to filter by (OR match)."),
-      sprintId: z.number().optional().describe("Sprint ID to search within."),
+      sprintId: toolSchemas.jiraId().optional().describe("Sprint ID to search within."),
       text: z.string().optional().describe("Text to search in summary/description."),
-      updatedWithin: z.number().optional().describe("Only issues updated within last N days."),
-      createdWithin: z.number().optional().describe("Only issues created within last N days."),
+      updatedWithin: toolSchemas.days().optional().describe("Only issues updated within last N days."),
+      createdWithin: toolSchemas.days().optional().describe("Only issues created within last N days."),
       jql: z.string().optional().describe("Raw JQL override (ignores other filters)."),
-      maxResults: z.number().optional().describe("Max results (default 25)."),
+      maxResults: toolSchemas.maxResults().optional().describe("Max results (default 25)."),
     },
     async (input) => {
       const jiraClient = await getJiraClient();
+      const prefs = await getPreferences();
 
       let jql = input.jql;
       if (!jql) {
@@ -653,7 +676,7 @@ export async function registerJiraWorkflowTools(server, getJiraClient, helpers)
         priority: i.fields?.priority?.name,
         assignee: i.fields?.assignee?.displayName || "Unassigned",
         labels: i.fields?.labels || [],
-        updated: i.fields?.updated,
+        updated: formatJiraDate(i.fields?.updated, prefs),
       }));
 
       const lines = [
@@ -675,11 +698,12 @@ export async function registerJiraWorkflowTools(server, getJiraClient, helpers)
     "getWorkItemDetails",
     "Get full details of a Jira issue: description (as plain text), status, priority, assignee, reporter, subtasks with their statuses, recent comments, attachments, and available transitions (as status names).",
     {
-      issueKey: z.string().describe("Issue key (e.g. 'ENG-123')."),
-      commentLimit: z.number().optional().describe("Max comments to return (default 5)."),
+      issueKey: toolSchemas.issueKey().describe("Issue key (e.g. 'ENG-123')."),
+      commentLimit: toolSchemas.maxResults().optional().describe("Max comments to return (default 5)."),
     },
     async (input) => {
       const jiraClient = await getJiraClient();
+      const prefs = await getPreferences();
       const { issueKey } = input;
 
       // Fetch issue, comments, and transitions in parallel
@@ -708,7 +732,7 @@ export async function registerJiraWorkflowTools(server, getJiraClient, helpers)
         id: c.id,
         author: c.author?.displayName || "Unknown",
         body: jiraClient.documentToPlainText(c.body) || "",
-        created: c.created,
+        created: formatJiraDate(c.created, prefs),
       }));
       const attachments = (f.attachment || []).map((a) => ({
         id: a.id,
@@ -738,8 +762,8 @@ export async function registerJiraWorkflowTools(server, getJiraClient, helpers)
         comments,
         attachments,
         availableTransitions: transitions,
-        created: f.created,
-        updated: f.updated,
+        created: formatJiraDate(f.created, prefs),
+        updated: formatJiraDate(f.updated, prefs),
       };
 
       const lines = [
@@ -780,7 +804,7 @@ export async function registerJiraWorkflowTools(server, getJiraClient, helpers)
     "deleteComment",
     "Delete a comment from a Jira issue by comment ID. Use getWorkItemDetails to find comment IDs. Returns confirmation of deletion.",
     {
-      issueKey: z.string().describe("Issue key (e.g. 'ENG-123')."),
+      issueKey: toolSchemas.issueKey().describe("Issue key (e.g. 'ENG-123')."),
       commentId: z.string().describe("ID of the comment to delete."),
     },
     async (input) => {
@@ -806,12 +830,12 @@ export async function registerJiraWorkflowTools(server, getJiraClient, helpers)
     "planSprint",
     "Create a new sprint and optionally move issues into it in one call. Returns the sprint summary with all issues.",
     {
-      boardId: z.number().describe("Board ID to create the sprint on. Use getProjectOverview to find board IDs."),
+      boardId: toolSchemas.jiraId().describe("Board ID to create the sprint on. Use getProjectOverview to find board IDs."),
       name: z.string().describe("Sprint name."),
       goal: z.string().optional().describe("Sprint goal."),
       startDate: z.string().optional().describe("Start date in ISO format (e.g. '2025-07-01T08:00:00.000Z'). Defaults to now."),
       endDate: z.string().optional().describe("End date in ISO format. Defaults to 2 weeks after start."),
-      issueKeys: z.array(z.string()).optional().describe("Issue keys to move into the sprint."),
+      issueKeys: toolSchemas.issueKeys().optional().describe("Issue keys to move into the sprint."),
     },
     async (input) => {
       const jiraClient = await getJiraClient();
@@ -866,7 +890,7 @@ export async function registerJiraWorkflowTools(server, getJiraClient, helpers)
     "completeSprintReport",
     "Complete an active sprint and get a report: completed vs incomplete issues, and suggestions for incomplete items.",
     {
-      sprintId: z.number().describe("Sprint ID to complete. Must be in 'active' state."),
+      sprintId: toolSchemas.jiraId().describe("Sprint ID to complete. Must be in 'active' state."),
     },
     async (input) => {
       const jiraClient = await getJiraClient();
@@ -942,8 +966,8 @@ export async function registerJiraWorkflowTools(server, getJiraClient, helpers)
     {
       command: z.enum(["search", "projectMembers", "/help"]).describe("The operation to perform."),
       query: z.string().optional().describe("Search query for 'search' (name, email, or username)."),
-      projectKey: z.string().optional().describe("Project key for 'projectMembers'."),
-      maxResults: z.number().optional().describe("Max results (default 10 for search, 50 for projectMembers)."),
+      projectKey: toolSchemas.projectKey().optional().describe("Project key for 'projectMembers'."),
+      maxResults: toolSchemas.maxResults().optional().describe("Max results (default 10 for search, 50 for projectMembers)."),
     },
     async (input) => {
       if (input.command === "/help") {
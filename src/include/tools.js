@@ -1,6 +1,7 @@
 import { Octokit } from "octokit";
 import { z } from "zod";
 import { registerJiraWorkflowTools } from "./jira-workflow-tools";
+import { backendAuthHeaders } from "../utils";
 
 /**
  * Lightweight copy of the stack-location helper from src/index.ts to keep this
@@ -105,6 +106,7 @@ export async function registerTools() {
     stripAvatarUrls,
     normalizeUser,
     normalizeResponse,
+    getPreferences: () => this.getPreferences(),
   });
   registeredTools.push(...jiraTools);
 
@@ -182,11 +184,17 @@ export async function registerTools() {
       if (!backendBase) {
         return { content: [{ text: "Error: BACKEND_BASE_URL is not configured.", type: "text" }], isError: true };
       }
+      // The backend only lets tenants whose role allows it manage jobs.
+      const mcpSecret = (this.props ?? {}).mcpSecret;
+      if (!mcpSecret) {
+        return { content: [{ text: "Error: no MCP secret available to identify the tenant.", type: "text" }], isError: true };
+      }
 
       switch (input.command) {
         case "enqueue": {
           if (!input.jobType) throw new Error("enqueue requires jobType.");
           const url = new URL("/api/jobs", backendBase);
+          url.searchParams.set("mcp_secret", mcpSecret);
           const response = await fetch(url.toString(), {
             method: "POST",
             headers: { "Content-Type": "application/json" },
@@ -209,6 +217,7 @@ export async function registerTools() {
         case "getStatus": {
           if (!input.jobId) throw new Error("getStatus requires jobId.");
           const url = new URL("/api/jobs", backendBase);
+          url.searchParams.set("mcp_secret", mcpSecret);
           url.searchParams.set("id", String(input.jobId));
           const response = await fetch(url.toString(), { method: "GET", headers: { Accept: "application/json" } });
           if (!response.ok) {
@@ -222,6 +231,7 @@ export async function registerTools() {
         }
         case "getStats": {
           const url = new URL("/api/jobs/stats", backendBase);
+          url.searchParams.set("mcp_secret", mcpSecret);
           const response = await fetch(url.toString(), { method: "GET", headers: { Accept: "application/json" } });
           if (!response.ok) {
             const errorText = await response.text();
@@ -252,7 +262,11 @@ export async function registerTools() {
     url.searchParams.set("mcp_secret", mcpSecret);
     url.searchParams.set("provider", provider);
 
-    const resp = await fetch(url.toString(), { method: "GET", headers: { Accept: "application/json" } });
+    const signatureHeaders = await backendAuthHeaders(this.env, "GET", url);
+    const resp = await fetch(url.toString(), {
+      method: "GET",
+      headers: { Accept: "application/json", ...signatureHeaders },
+    });
     if (resp.status === 404) return null;
     if (!resp.ok) {
       const text = await resp.text();
@@ -0,0 +1,43 @@
+import { createToolReporter, isMcpLogLevel, shouldLog } from "./mcp-logging";
+
+describe("mcp logging", () => {
+  it("orders levels by severity", () => {
+    expect(shouldLog("error", "warning")).toBe(true);
+    expect(shouldLog("debug", "info")).toBe(false);
+    expect(shouldLog("info", "info")).toBe(true);
+    expect(isMcpLogLevel("notice")).toBe(true);
+    expect(isMcpLogLevel("verbose")).toBe(false);
+  });
+
+  it("streams logs at or above the threshold and progress for calls that asked for it", async () => {
+    const sent: { method: string; params?: Record<string, unknown> }[] = [];
+    const extra = {
+      _meta: { progressToken: "tok-1" },
+      sendNotification: async (n: { method: string; params?: Record<string, unknown> }) => {
+        sent.push(n);
+      },
+    };
+
+    const reporter = createToolReporter(extra, "searchJiraIssues", "info");
+    reporter.log("debug", "dropped");
+    reporter.log("info", "kept", { duration_ms: 5 });
+    reporter.progress(1, 2, "halfway");
+
+    expect(sent).toEqual([
+      {
+        method: "notifications/message",
+        params: { level: "info", logger: "searchJiraIssues", data: { message: "kept", duration_ms: 5 } },
+      },
+      { method: "notifications/progress", params: { progressToken: "tok-1", progress: 1, total: 2, message: "halfway" } },
+    ]);
+  });
+
+  it("skips progress without a token and tolerates calls without a notifier", () => {
+    const sent: unknown[] = [];
+    const reporter = createToolReporter({ sendNotification: async (n: unknown) => void sent.push(n) }, "add", "debug");
+    reporter.progress(1, 1);
+    expect(sent).toEqual([]);
+
+    expect(() => createToolReporter(undefined, "add", "debug").log("error", "nowhere to go")).not.toThrow();
+  });
+});
@@ -0,0 +1,70 @@
+/**
+ * MCP logging and progress pass-through. Tool calls stream log messages
+ * (notifications/message) and, when the client asked for them with a
+ * progressToken, progress notifications back to the client session that made
+ * the call. Messages below the session's level are dropped; the level comes
+ * from the client's logging/setLevel request, else the tenant's
+ * mcp_log_level preference.
+ */
+
+export type McpLogLevel = "debug" | "info" | "notice" | "warning" | "error" | "critical" | "alert" | "emergency";
+
+// Least severe first, matching the MCP specification (RFC 5424 severities).
+const MCP_LOG_LEVELS: McpLogLevel[] = ["debug", "info", "notice", "warning", "error", "critical", "alert", "emergency"];
+
+export const DEFAULT_MCP_LOG_LEVEL: McpLogLevel = "info";
+
+export function isMcpLogLevel(value: unknown): value is McpLogLevel {
+  return typeof value === "string" && (MCP_LOG_LEVELS as string[]).includes(value);
+}
+
+/** Reports whether a message at level passes the threshold. */
+export function shouldLog(level: McpLogLevel, threshold: McpLogLevel): boolean {
+  return MCP_LOG_LEVELS.indexOf(level) >= MCP_LOG_LEVELS.indexOf(threshold);
+}
+
+// The subset of the SDK's RequestHandlerExtra used here.
+type ToolCallExtra = {
+  _meta?: { progressToken?: string | number };
+  sendNotification?: (notification: { method: string; params?: Record<string, unknown> }) => Promise<void>;
+};
+
+export type ToolReporter = {
+  log(level: McpLogLevel, message: string, data?: Record<string, unknown>): void;
+  progress(progress: number, total?: number, message?: string): void;
+};
+
+/**
+ * Returns a reporter for one tool call. Notifications go out on the call's
+ * own stream via extra.sendNotification; delivery failures are ignored so a
+ * disconnected client never fails the tool.
+ */
+export function createToolReporter(extra: unknown, logger: string, threshold: McpLogLevel): ToolReporter {
+  const call = (extra && typeof extra === "object" ? extra : {}) as ToolCallExtra;
+  const send = (method: string, params: Record<string, unknown>) => {
+    if (typeof call.sendNotification !== "function") return;
+    call.sendNotification({ method, params }).catch(() => {});
+  };
+  const progressToken = call._meta?.progressToken;
+
+  return {
+    log(level, message, data) {
+      if (!shouldLog(level, threshold)) return;
+      send("notifications/message", { level, logger, data: data ? { message, ...data } : message });
+    },
+    progress(progress, total, message) {
+      if (progressToken === undefined) return;
+      send("notifications/progress", {
+        progressToken,
+        progress,
+        ...(total !== undefined ? { total } : {}),
+        ...(message ? { message } : {}),
+      });
+    },
+  };
+}
+
+/** Returns the call's extra argument: tool handlers receive it last. */
+export function toolCallExtra(handlerArgs: unknown[]): unknown {
+  return handlerArgs[handlerArgs.length - 1];
+}
@@ -1,11 +1,55 @@
 import OAuthProvider from "@cloudflare/workers-oauth-provider";
 import { McpServer } from "@modelcontextprotocol/sdk/server/mcp.js";
+import { CallToolRequestSchema, InitializeRequestSchema, SetLevelRequestSchema } from "@modelcontextprotocol/sdk/types.js";
 import { McpAgent } from "agents/mcp";
+import { z, type ZodRawShape } from "zod";
 import { JiraClient } from "./tools/jira";
 import { GitHubHandler } from "./github-handler";
 import { registerTools } from "./include/tools";
-import type { Props } from "./utils";
+import { DEFAULT_PREFERENCES, projectKeyFromArgs, backendAuthHeaders, type Props, type UserPreferences } from "./utils";
 import { integrationRegistry } from "./integrations";
+import { BackpressureError, backpressureFromResponse, backpressureToolResult } from "./backpressure";
+import {
+  DEFAULT_MCP_LOG_LEVEL,
+  createToolReporter,
+  isMcpLogLevel,
+  toolCallExtra,
+  type McpLogLevel,
+  type ToolReporter,
+} from "./mcp-logging";
+import {
+  ContinuationStore,
+  DEFAULT_TOOL_RESPONSE_LIMITS,
+  limitToolResult,
+  maxBytesForTool,
+  nextContinuationPage,
+  type ToolResponseLimits,
+} from "./response-limits";
+import {
+  CURRENT_TOOL_SCHEMA_VERSION,
+  TOOL_SCHEMA_CAPABILITY,
+  downgradeResult,
+  extractToolSchemaVersionFromRequest,
+  negotiateToolSchemaVersion,
+  preferencesForVersion,
+  shapeForVersion,
+  toolSchemaCapability,
+  upgradeArgs,
+} from "./tool-versions";
+import { validateToolArguments } from "./tool-schemas";
+import { classifyToolError, toolErrorResult } from "./tool-errors";
+import { ToolQuotaTracker, type ToolQuotaSnapshot } from "./tool-quotas";
+import { CACHEABLE_TOOLS, ToolResultCache } from "./tool-cache";
+import { ToolCallLimiter } from "./tool-concurrency";
+import {
+  DEFAULT_TOOL_APPROVAL_POLICIES,
+  EXECUTE_APPROVED_TOOL,
+  approvalNotReadyResult,
+  approvalPendingResult,
+  requiresApproval,
+  type ToolApproval,
+  type ToolApprovalPolicies,
+} from "./tool-approvals";
 
 export type McpEnv = Cloudflare.Env & {
   SESSION_SECRET?: string;
@@ -13,6 +57,9 @@ export type McpEnv = Cloudflare.Env & {
   MCP_OBJECT: DurableObjectNamespace<MyMCP>;
   MCP_SECRET?: string;
   BACKEND_BASE_URL?: string;
+  WORKER_SHARED_KEY?: string;
+  // Tenant-scoped API key sent in X-API-Key on backend-only routes.
+  BACKEND_API_KEY?: string;
   LOG_LEVEL?: string;
 };
 
@@ -85,8 +132,34 @@ export function extractMcpSecretFromRequest(request: Request): string | undefine
   return undefined;
 }
 
+// How long to stop reporting debug traces after the backend says the
+// tenant's debug mode is off.
+const DEBUG_TRACE_BACKOFF_MS = 5 * 60_000;
+
+// How long tenant preferences and tool response limits are cached per session.
+const TENANT_CONFIG_TTL_MS = 5 * 60_000;
+
 export class MyMCP extends McpAgent<McpEnv, Props> {
   private jiraClient: JiraClient | null = null;
+  private debugTracesPausedUntil = 0;
+  private preferences: UserPreferences | null = null;
+  private preferencesFetchedAt = 0;
+  private toolResponseLimits: ToolResponseLimits | null = null;
+  private toolResponseLimitsFetchedAt = 0;
+  private toolApprovalPolicies: ToolApprovalPolicies | null = null;
+  private toolApprovalPoliciesFetchedAt = 0;
+  // Handlers behind the approval gate, used to run approved calls.
+  private gatedHandlers = new Map<string, { handler: (...args: any[]) => any; takesArgs: boolean }>();
+  private continuations = new ContinuationStore();
+  private resultCache = new ToolResultCache();
+  private toolCalls = new ToolCallLimiter();
+  private toolQuotas = new ToolQuotaTracker();
+  // Level requested by this session's client with logging/setLevel; it
+  // overrides the tenant's mcp_log_level preference.
+  private clientLogLevel: McpLogLevel | null = null;
+  // Tool schema version negotiated when this session initialized.
+  private toolSchemaVersion = CURRENT_TOOL_SCHEMA_VERSION;
+  private versionedTools = new Map<string, { registered: any; shape: ZodRawShape }>();
 
   constructor(state: DurableObjectState, env: McpEnv) {
     super(state, env);
@@ -99,13 +172,51 @@ export class MyMCP extends McpAgent<McpEnv, Props> {
     return this.jiraClient;
   }
 
-  server = new McpServer({
-    name: "Github OAuth Proxy Demo",
-    version: "1.0.0",
-  });
+  server = new McpServer(
+    {
+      name: "Github OAuth Proxy Demo",
+      version: "1.0.0",
+    },
+    { capabilities: { logging: {}, experimental: toolSchemaCapability() } },
+  );
 
   async init() {
+    this.server.server.setRequestHandler(SetLevelRequestSchema, async (request) => {
+      this.clientLogLevel = request.params.level;
+      return {};
+    });
+    await this.negotiateToolSchemas();
+
+    this.applyResultCache();
+    this.applyApprovalGate();
+    this.applyConcurrencyLimit();
+    this.instrumentTools();
+    this.applyToolQuotas();
+    this.applyResponseLimits();
+    this.applyToolSchemaVersions();
     await registerTools.call(this);
+    this.validateToolCalls();
+
+    this.server.tool(
+      EXECUTE_APPROVED_TOOL,
+      "Run a tool call that was held for approval, after the user approved it. Pass the approvalToken from the held call's response.",
+      { approvalToken: z.string().min(1).describe("Token from the response of the held tool call.") },
+      async ({ approvalToken }, extra) => this.executeApprovedCall(approvalToken, extra),
+    );
+
+    this.server.tool(
+      "fetchMoreResults",
+      "Fetch the next page of a tool response that was truncated to fit the response size limit. Pass the continuationToken from the truncated response.",
+      { continuationToken: z.string().describe("Token from the truncation notice of a previous response.") },
+      async ({ continuationToken }) => {
+        const limits = await this.getToolResponseLimits();
+        return nextContinuationPage(
+          continuationToken,
+          maxBytesForTool(limits, "fetchMoreResults"),
+          this.continuations,
+        ) as any;
+      },
+    );
 
     // Activate feature-flag gated integration modules
     const props = (this.props as Props | undefined) ?? undefined;
@@ -138,6 +249,494 @@ export class MyMCP extends McpAgent<McpEnv, Props> {
     );
   }
 
+  /**
+   * Returns the tenant's timezone, locale and date format, used to format
+   * Jira dates and interpret natural dates in tool arguments. Falls back to
+   * the defaults when the backend is unreachable. Sessions on an older tool
+   * schema version see the preferences as that version presented them.
+   */
+  async getPreferences(): Promise<UserPreferences> {
+    if (!this.preferences || Date.now() - this.preferencesFetchedAt >= TENANT_CONFIG_TTL_MS) {
+      const prefs = await this.fetchTenantResource<Partial<UserPreferences>>("/api/preferences/tenant");
+      if (prefs) {
+        this.preferences = { ...DEFAULT_PREFERENCES, ...prefs };
+        this.preferencesFetchedAt = Date.now();
+      }
+    }
+    return preferencesForVersion(this.preferences ?? DEFAULT_PREFERENCES, this.toolSchemaVersion);
+  }
+
+  /**
+   * Returns the minimum level of log messages streamed to this session: the
+   * client's logging/setLevel choice, else the tenant's preference.
+   */
+  async getLogLevel(): Promise<McpLogLevel> {
+    if (this.clientLogLevel) return this.clientLogLevel;
+    const level = (await this.getPreferences()).mcp_log_level;
+    return isMcpLogLevel(level) ? level : DEFAULT_MCP_LOG_LEVEL;
+  }
+
+  /**
+   * Returns a reporter that streams log messages and progress for a tool
+   * call back to the client. Pass the extra argument the tool handler
+   * received.
+   */
+  async toolReporter(extra: unknown, tool: string): Promise<ToolReporter> {
+    return createToolReporter(extra, tool, await this.getLogLevel());
+  }
+
+  /**
+   * Returns the tenant's tool response size limits, falling back to the
+   * defaults when the backend is unreachable.
+   */
+  async getToolResponseLimits(): Promise<ToolResponseLimits> {
+    if (this.toolResponseLimits && Date.now() - this.toolResponseLimitsFetchedAt < TENANT_CONFIG_TTL_MS) {
+      return this.toolResponseLimits;
+    }
+    const limits = await this.fetchTenantResource<Partial<ToolResponseLimits>>("/api/settings/tool-limits/tenant");
+    if (limits) {
+      this.toolResponseLimits = { ...DEFAULT_TOOL_RESPONSE_LIMITS, ...limits };
+      this.toolResponseLimitsFetchedAt = Date.now();
+    }
+    return this.toolResponseLimits ?? DEFAULT_TOOL_RESPONSE_LIMITS;
+  }
+
+  /**
+   * Returns which tools need approval for the tenant, falling back to the
+   * gate being off when the backend is unreachable.
+   */
+  async getToolApprovalPolicies(): Promise<ToolApprovalPolicies> {
+    if (this.toolApprovalPolicies && Date.now() - this.toolApprovalPoliciesFetchedAt < TENANT_CONFIG_TTL_MS) {
+      return this.toolApprovalPolicies;
+    }
+    const policies = await this.fetchTenantResource<Partial<ToolApprovalPolicies>>("/api/settings/tool-approvals/tenant");
+    if (policies) {
+      this.toolApprovalPolicies = { ...DEFAULT_TOOL_APPROVAL_POLICIES, ...policies };
+      this.toolApprovalPoliciesFetchedAt = Date.now();
+    }
+    return this.toolApprovalPolicies ?? DEFAULT_TOOL_APPROVAL_POLICIES;
+  }
+
+  /**
+   * Wraps server.tool so calls to tools the tenant gated are held for
+   * approval instead of running. The wrapped handler is kept so
+   * executeApprovedCall can run the call once it is approved.
+   */
+  private applyApprovalGate() {
+    const server = this.server as any;
+    const registerTool = server.tool.bind(server);
+    server.tool = (...args: any[]) => {
+      const name = args[0];
+      const handler = args[args.length - 1];
+      if (typeof handler === "function") {
+        const shapeIndex = typeof args[1] === "string" ? 2 : 1;
+        const takesArgs = args.length > shapeIndex + 1 && Boolean(args[shapeIndex]) && typeof args[shapeIndex] === "object";
+        this.gatedHandlers.set(name, { handler, takesArgs });
+        args[args.length - 1] = async (...handlerArgs: any[]) => {
+          if (!requiresApproval(await this.getToolApprovalPolicies(), name)) return handler(...handlerArgs);
+
+          const resp = await this.tenantRequest("POST", "/api/tool-approvals/tenant", {
+            tool: name,
+            arguments: takesArgs ? (handlerArgs[0] ?? {}) : {},
+          });
+          if (!resp?.ok) {
+            return {
+              isError: true,
+              content: [
+                { type: "text", text: `${name} needs approval, but the call could not be held for approval. It was not executed.` },
+              ],
+            };
+          }
+          const { approval } = (await resp.json()) as { approval: ToolApproval };
+          return approvalPendingResult(approval);
+        };
+      }
+      return registerTool(...args);
+    };
+  }
+
+  // Runs a held call the tenant approved. The backend hands each approval
+  // out once, so retries cannot run it twice.
+  private async executeApprovedCall(approvalToken: string, extra: unknown) {
+    const resp = await this.tenantRequest("POST", `/api/tool-approvals/tenant/${encodeURIComponent(approvalToken)}/consume`);
+    if (resp?.status === 409) {
+      const { status } = (await resp.json()) as { status: string };
+      return approvalNotReadyResult(status);
+    }
+    if (!resp?.ok) {
+      const reason = resp?.status === 404 ? "Unknown approval token." : "The approval could not be checked; try again.";
+      return { isError: true, content: [{ type: "text" as const, text: reason }] };
+    }
+
+    const { approval } = (await resp.json()) as { approval: ToolApproval };
+    const gated = this.gatedHandlers.get(approval.tool);
+    if (!gated) {
+      return { isError: true, content: [{ type: "text" as const, text: `${approval.tool} is not available in this session.` }] };
+    }
+    return gated.takesArgs ? gated.handler(approval.arguments ?? {}, extra) : gated.handler(extra);
+  }
+
+  // Sends a signed, tenant-scoped request to the backend. Returns undefined
+  // when the tenant cannot be resolved or the backend is unreachable.
+  private async tenantRequest(method: string, path: string, payload?: unknown): Promise<Response | undefined> {
+    const env = this.env as McpEnv;
+    const mcpSecret = (this.props as Props | undefined)?.mcpSecret;
+    if (!env.BACKEND_BASE_URL || !mcpSecret) return undefined;
+
+    try {
+      const url = new URL(path, env.BACKEND_BASE_URL);
+      url.searchParams.set("mcp_secret", mcpSecret);
+      const body = payload === undefined ? undefined : JSON.stringify(payload);
+      const signatureHeaders = await backendAuthHeaders(env, method, url, body);
+      return await fetch(url.toString(), {
+        method,
+        headers: { Accept: "application/json", ...(body ? { "Content-Type": "application/json" } : {}), ...signatureHeaders },
+        body,
+        signal: AbortSignal.timeout(5_000),
+      });
+    } catch (err) {
+      logMessage(this.env, "warn", `Backend request ${method} ${path} failed`, { error: String(err) });
+      return undefined;
+    }
+  }
+
+  // Fetches a signed, tenant-scoped backend resource. Returns undefined when
+  // the tenant cannot be resolved or the request fails.
+  private async fetchTenantResource<T>(path: string): Promise<T | undefined> {
+    const env = this.env as McpEnv;
+    const mcpSecret = (this.props as Props | undefined)?.mcpSecret;
+    if (!env.BACKEND_BASE_URL || !mcpSecret) return undefined;
+
+    try {
+      const url = new URL(path, env.BACKEND_BASE_URL);
+      url.searchParams.set("mcp_secret", mcpSecret);
+      const signatureHeaders = await backendAuthHeaders(env, "GET", url);
+      const resp = await fetch(url.toString(), {
+        method: "GET",
+        headers: { Accept: "application/json", ...signatureHeaders },
+        signal: AbortSignal.timeout(5_000),
+      });
+      if (!resp.ok) throw new Error(`${resp.status} ${resp.statusText}`);
+      return (await resp.json()) as T;
+    } catch (err) {
+      logMessage(this.env, "warn", `Failed to load ${path}, using defaults`, { error: String(err) });
+      return undefined;
+    }
+  }
+
+  /**
+   * Restores the tool schema version this session negotiated before the
+   * Durable Object was evicted, and hooks initialize so new sessions pick
+   * one. The initialize result reports the negotiated version next to the
+   * supported ones.
+   */
+  private async negotiateToolSchemas() {
+    const stored = await this.ctx.storage.get<number>("toolSchemaVersion");
+    if (stored) this.toolSchemaVersion = Math.min(stored, CURRENT_TOOL_SCHEMA_VERSION);
+
+    const protocol = this.server.server as any;
+    // The SDK's own initialize handler, wrapped rather than reimplemented so
+    // protocol revision negotiation stays with the SDK.
+    const initialize = protocol._oninitialize?.bind(protocol);
+    if (typeof initialize !== "function") {
+      logMessage(this.env, "warn", "MCP SDK initialize handler not found, tool schema negotiation disabled");
+      return;
+    }
+    protocol.setRequestHandler(InitializeRequestSchema, async (request: any) => {
+      const requested = (this.props as Props | undefined)?.toolSchemaVersion;
+      this.toolSchemaVersion = negotiateToolSchemaVersion(request.params, requested);
+      await this.ctx.storage.put("toolSchemaVersion", this.toolSchemaVersion);
+      this.advertiseToolShapes();
+
+      const result = await initialize(request);
+      const experimental = result.capabilities?.experimental ?? {};
+      return {
+        ...result,
+        capabilities: {
+          ...result.capabilities,
+          experimental: {
+            ...experimental,
+            [TOOL_SCHEMA_CAPABILITY]: { ...experimental[TOOL_SCHEMA_CAPABILITY], negotiated: this.toolSchemaVersion },
+          },
+        },
+      };
+    });
+  }
+
+  /**
+   * Checks tools/call arguments against the tool's input schema before the
+   * SDK dispatches the call. Invalid calls get a tool error listing each
+   * offending argument, which the model sees, rather than the SDK's protocol
+   * error, which most clients do not pass on. Must run after the first tool
+   * is registered, when the SDK installs its tools/call handler.
+   */
+  private validateToolCalls() {
+    const protocol = this.server.server as any;
+    const dispatch = protocol._requestHandlers?.get("tools/call");
+    if (typeof dispatch !== "function") {
+      logMessage(this.env, "warn", "MCP SDK tools/call handler not found, argument validation disabled");
+      return;
+    }
+    protocol.setRequestHandler(CallToolRequestSchema, async (request: any, extra: any) => {
+      const { name, arguments: args } = request.params;
+      const tool = (this.server as any)._registeredTools?.[name];
+      if (tool?.enabled !== false && tool?.inputSchema) {
+        const invalid = validateToolArguments(name, tool.inputSchema, args);
+        if (invalid) return invalid;
+      }
+      return dispatch(request, extra);
+    });
+  }
+
+  /**
+   * Wraps server.tool so sessions on an older tool schema version have
+   * their arguments upgraded to, and results downgraded from, the current
+   * shape. Tools with an input shape are remembered so the shape they
+   * advertise can follow the negotiated version.
+   */
+  private applyToolSchemaVersions() {
+    const server = this.server as any;
+    const registerTool = server.tool.bind(server);
+    server.tool = (...args: any[]) => {
+      const name = args[0];
+      const handler = args[args.length - 1];
+      const shapeIndex = typeof args[1] === "string" ? 2 : 1;
+      const shape: ZodRawShape | undefined =
+        args.length > shapeIndex + 1 && args[shapeIndex] && typeof args[shapeIndex] === "object" ? args[shapeIndex] : undefined;
+      if (typeof handler === "function") {
+        args[args.length - 1] = async (...handlerArgs: any[]) => {
+          const version = this.toolSchemaVersion;
+          if (shape) handlerArgs[0] = upgradeArgs(name, handlerArgs[0] ?? {}, version);
+          return downgradeResult(name, await handler(...handlerArgs), version);
+        };
+      }
+      if (shape) args[shapeIndex] = shapeForVersion(name, shape, this.toolSchemaVersion);
+      const registered = registerTool(...args);
+      if (shape && registered) this.versionedTools.set(name, { registered, shape });
+      return registered;
+    };
+  }
+
+  // Re-advertises each tool's input shape for the negotiated version.
+  private advertiseToolShapes() {
+    for (const [name, { registered, shape }] of this.versionedTools) {
+      registered.update({ paramsSchema: shapeForVersion(name, shape, this.toolSchemaVersion) });
+    }
+  }
+
+  /**
+   * Wraps server.tool so repeated calls to idempotent tools with the same
+   * arguments are answered from the session's result cache for a short
+   * window. Hits skip the invocation log: nothing was sent to Jira.
+   */
+  private applyResultCache() {
+    const server = this.server as any;
+    const registerTool = server.tool.bind(server);
+    server.tool = (...args: any[]) => {
+      const name = args[0];
+      const handler = args[args.length - 1];
+      if (typeof handler === "function") {
+        args[args.length - 1] = async (...handlerArgs: any[]) => {
+          this.resultCache.noteCall(name);
+          if (!CACHEABLE_TOOLS.has(name)) return handler(...handlerArgs);
+
+          const toolArgs = handlerArgs.length > 1 ? handlerArgs[0] : {};
+          const cached = this.resultCache.get(name, toolArgs);
+          if (cached !== undefined) {
+            const reporter = await this.toolReporter(toolCallExtra(handlerArgs), name);
+            reporter.log("debug", `${name} answered from the session cache`);
+            return cached;
+          }
+          const result = await handler(...handlerArgs);
+          if (!result?.isError) this.resultCache.put(name, toolArgs, result);
+          return result;
+        };
+      }
+      return registerTool(...args);
+    };
+  }
+
+  /**
+   * Wraps server.tool so at most MAX_CONCURRENT_TOOL_CALLS calls of this
+   * session run at once, each under its MCP request's abort signal so a
+   * cancelled call gives up its slot and aborts its Jira requests.
+   */
+  private applyConcurrencyLimit() {
+    const server = this.server as any;
+    const registerTool = server.tool.bind(server);
+    server.tool = (...args: any[]) => {
+      const handler = args[args.length - 1];
+      if (typeof handler === "function") {
+        args[args.length - 1] = (...handlerArgs: any[]) => {
+          const signal = (toolCallExtra(handlerArgs) as { signal?: AbortSignal } | undefined)?.signal;
+          return this.toolCalls.run(signal, () => handler(...handlerArgs));
+        };
+      }
+      return registerTool(...args);
+    };
+  }
+
+  /**
+   * Wraps server.tool so results larger than the tenant's response size
+   * limit are trimmed, with the overflow available via fetchMoreResults.
+   */
+  private applyResponseLimits() {
+    const server = this.server as any;
+    const registerTool = server.tool.bind(server);
+    server.tool = (...args: any[]) => {
+      const name = args[0];
+      const handler = args[args.length - 1];
+      if (typeof handler === "function" && name !== "fetchMoreResults") {
+        args[args.length - 1] = async (...handlerArgs: any[]) => {
+          const result = await handler(...handlerArgs);
+          const limits = await this.getToolResponseLimits();
+          return limitToolResult(name, result, maxBytesForTool(limits, name), this.continuations);
+        };
+      }
+      return registerTool(...args);
+    };
+  }
+
+  /**
+   * Wraps server.tool so every tool call is recorded in the backend's
+   * invocation log, where it is charged the tool's cost units, and reported
+   * to the debug trace endpoint. The backend redacts trace payloads and drops
+   * them unless the tenant has debug mode enabled. The call's start, outcome
+   * and progress are also streamed to the client as MCP notifications.
+   */
+  private instrumentTools() {
+    const server = this.server as any;
+    const registerTool = server.tool.bind(server);
+    server.tool = (...args: any[]) => {
+      const name = args[0];
+      const handler = args[args.length - 1];
+      if (typeof handler === "function") {
+        args[args.length - 1] = async (...handlerArgs: any[]) => {
+          const started = Date.now();
+          const reporter = await this.toolReporter(toolCallExtra(handlerArgs), name);
+          reporter.log("debug", `Calling ${name}`);
+          reporter.progress(0, 1, `Running ${name}`);
+          try {
+            const result = await handler(...handlerArgs);
+            const durationMs = Date.now() - started;
+            const isError = Boolean(result?.isError);
+            this.reportToolInvocation(name, handlerArgs[0], durationMs, isError);
+            this.reportDebugTrace(name, handlerArgs[0], result, durationMs, isError);
+            reporter.log(isError ? "warning" : "info", `${name} ${isError ? "returned an error" : "finished"} in ${durationMs}ms`, {
+              duration_ms: durationMs,
+            });
+            reporter.progress(1, 1, `${name} finished`);
+            return result;
+          } catch (err: any) {
+            const durationMs = Date.now() - started;
+            this.reportToolInvocation(name, handlerArgs[0], durationMs, true);
+            this.reportDebugTrace(name, handlerArgs[0], { error: String(err?.message ?? err) }, durationMs, true);
+            // Rate limits, quotas and maintenance are relayed with a retry
+            // hint so clients back off instead of retrying immediately.
+            if (err instanceof BackpressureError) {
+              reporter.log("warning", `${name} was throttled: ${err.message}`, {
+                duration_ms: durationMs,
+                reason: err.reason,
+                retry_after_ms: err.retryAfterMs,
+              });
+              const { structuredContent } = toolErrorResult(classifyToolError(err));
+              return { ...backpressureToolResult(err), structuredContent };
+            }
+            // Other failures are returned with their error code and a
+            // remediation hint rather than as a bare message.
+            const detail = classifyToolError(err);
+            reporter.log("error", `${name} failed: ${detail.message}`, { duration_ms: durationMs, code: detail.code });
+            return toolErrorResult(detail);
+          }
+        };
+      }
+      return registerTool(...args);
+    };
+  }
+
+  /**
+   * Wraps server.tool so calls in a tool category the tenant used up for
+   * the day are refused before they run. The refusal is a quota_exceeded
+   * backoff, which instrumentTools returns with the time the quotas reset.
+   */
+  private applyToolQuotas() {
+    const server = this.server as any;
+    const registerTool = server.tool.bind(server);
+    server.tool = (...args: any[]) => {
+      const name = args[0];
+      const handler = args[args.length - 1];
+      if (typeof handler === "function") {
+        args[args.length - 1] = async (...handlerArgs: any[]) => {
+          await this.refreshToolQuotas();
+          this.toolQuotas.check(name);
+          const result = await handler(...handlerArgs);
+          if (!result?.isError) this.toolQuotas.record(name);
+          return result;
+        };
+      }
+      return registerTool(...args);
+    };
+  }
+
+  /**
+   * Reloads the tenant's tool quotas when they are stale. Calls keep being
+   * checked against the last known quotas when the backend is unreachable.
+   */
+  private async refreshToolQuotas() {
+    if (!this.toolQuotas.isStale()) return;
+    const snapshot = await this.fetchTenantResource<ToolQuotaSnapshot>("/api/metrics/tool-quotas/tenant");
+    if (snapshot) this.toolQuotas.update(snapshot);
+  }
+
+  private reportToolInvocation(tool: string, args: unknown, durationMs: number, isError: boolean) {
+    const env = this.env as McpEnv;
+    const mcpSecret = (this.props as Props | undefined)?.mcpSecret;
+    if (!env.BACKEND_BASE_URL || !mcpSecret) return;
+
+    const send = async () => {
+      const url = new URL("/api/metrics/tool-invocations/tenant", env.BACKEND_BASE_URL);
+      url.searchParams.set("mcp_secret", mcpSecret);
+      const body = JSON.stringify({ tool, duration_ms: durationMs, is_error: isError, project_key: projectKeyFromArgs(args) });
+      const signatureHeaders = await backendAuthHeaders(env, "POST", url, body);
+
+      await fetch(url.toString(), {
+        method: "POST",
+        headers: { "Content-Type": "application/json", ...signatureHeaders },
+        body,
+        signal: AbortSignal.timeout(5_000),
+      });
+    };
+
+    send().catch((err) => logMessage(this.env, "debug", "Failed to record tool invocation", { tool, error: String(err) }));
+  }
+
+  private reportDebugTrace(tool: string, request: unknown, response: unknown, durationMs: number, isError: boolean) {
+    const env = this.env as McpEnv;
+    const mcpSecret = (this.props as Props | undefined)?.mcpSecret;
+    if (!env.BACKEND_BASE_URL || !mcpSecret || Date.now() < this.debugTracesPausedUntil) return;
+
+    const send = async () => {
+      const url = new URL("/api/debug/traces/tenant", env.BACKEND_BASE_URL);
+      url.searchParams.set("mcp_secret", mcpSecret);
+      const body = JSON.stringify({ tool, request: request ?? {}, response: response ?? {}, duration_ms: durationMs, is_error: isError });
+      const signatureHeaders = await backendAuthHeaders(env, "POST", url, body);
+
+      const resp = await fetch(url.toString(), {
+        method: "POST",
+        headers: { "Content-Type": "application/json", ...signatureHeaders },
+        body,
+        signal: AbortSignal.timeout(5_000),
+      });
+      if (!resp.ok) return;
+      const data = (await resp.json()) as { debug_enabled?: boolean };
+      if (!data.debug_enabled) {
+        this.debugTracesPausedUntil = Date.now() + DEBUG_TRACE_BACKOFF_MS;
+      }
+    };
+
+    send().catch((err) => logMessage(this.env, "debug", "Failed to report debug trace", { tool, error: String(err) }));
+  }
+
   private async buildTenantJiraEnv(): Promise<McpEnv> {
     const baseEnv = this.env as McpEnv;
 
@@ -181,6 +780,8 @@ export class MyMCP extends McpAgent<McpEnv, Props> {
         throw err;
       }
       if (!secretResponse.ok) {
+        const backpressure = await backpressureFromResponse(secretResponse, "Backend");
+        if (backpressure) throw backpressure;
         throw new Error(
           `[mcp] Failed to resolve MCP secret by email: ${secretResponse.status} ${secretResponse.statusText}`,
         );
@@ -198,12 +799,13 @@ export class MyMCP extends McpAgent<McpEnv, Props> {
     logMessage(this.env, "debug", "Sending request to /api/settings/jira/tenant to resolve Jira settings");
     const url = new URL("/api/settings/jira/tenant", backendBase);
     url.searchParams.set("mcp_secret", mcpSecret);
+    const signatureHeaders = await backendAuthHeaders(baseEnv, "GET", url);
 
     let response: Response;
     try {
       response = await fetch(url.toString(), {
         method: "GET",
-        headers: { Accept: "application/json" },
+        headers: { Accept: "application/json", ...signatureHeaders },
         signal: AbortSignal.timeout(BACKEND_TIMEOUT_MS),
       });
     } catch (err: any) {
@@ -213,6 +815,8 @@ export class MyMCP extends McpAgent<McpEnv, Props> {
       throw err;
     }
     if (!response.ok) {
+      const backpressure = await backpressureFromResponse(response, "Backend");
+      if (backpressure) throw backpressure;
       throw new Error(
         `[mcp] Failed to resolve Jira settings by MCP secret: ${response.status} ${response.statusText}`,
       );
@@ -239,14 +843,25 @@ export class MyMCP extends McpAgent<McpEnv, Props> {
 const sseHandler = MyMCP.serveSSE("/sse") as any;
 const mcpHandler = MyMCP.serve("/mcp") as any;
 
+// Copies the tenant's MCP secret and any requested tool schema version from
+// the connection request onto the session props.
+function attachConnectionProps(request: Request, ctx: ExecutionContext) {
+  const mcpSecret = extractMcpSecretFromRequest(request);
+  const toolSchemaVersion = extractToolSchemaVersionFromRequest(request);
+  if (!mcpSecret && toolSchemaVersion === undefined) return;
+
+  const existingProps = (ctx as any).props ?? {};
+  (ctx as any).props = {
+    ...existingProps,
+    ...(mcpSecret ? { mcpSecret } : {}),
+    ...(toolSchemaVersion !== undefined ? { toolSchemaVersion } : {}),
+  } as Props;
+}
+
 function withMcpSecret(handler: any): any {
   return {
     async fetch(request: Request, env: McpEnv, ctx: ExecutionContext) {
-      const mcpSecret = extractMcpSecretFromRequest(request);
-      if (mcpSecret) {
-        const existingProps = (ctx as any).props ?? {};
-        (ctx as any).props = { ...existingProps, mcpSecret } as Props;
-      }
+      attachConnectionProps(request, ctx);
 
       if (typeof handler === "function") {
         return handler(request, env, ctx);
@@ -280,11 +895,7 @@ export async function handleMcpWithoutOAuth(
   ctx: ExecutionContext,
 ): Promise<Response> {
   const url = new URL(request.url);
-  const mcpSecret = extractMcpSecretFromRequest(request);
-  if (mcpSecret) {
-    const existingProps = (ctx as any).props ?? {};
-    (ctx as any).props = { ...existingProps, mcpSecret } as Props;
-  }
+  attachConnectionProps(request, ctx);
 
   if (url.pathname.startsWith("/sse")) {
     return (sseHandler.fetch ? sseHandler.fetch(request, env, ctx) : sseHandler(request, env, ctx)) as Response;
@@ -0,0 +1,208 @@
+/**
+ * Tool response size limits. Large Jira results can overflow an LLM's
+ * context window, so results above the tenant's limit are trimmed: heavy
+ * fields are dropped from list items (keys and summaries are kept), and items
+ * that still don't fit are parked behind a continuation token that the
+ * fetchMoreResults tool pages through.
+ */
+
+// Resolved limits served by the backend's /api/settings/tool-limits/tenant.
+export type ToolResponseLimits = {
+  default_max_bytes: number;
+  tools: Record<string, number>;
+};
+
+export const DEFAULT_TOOL_RESPONSE_LIMITS: ToolResponseLimits = {
+  default_max_bytes: 50_000,
+  tools: {},
+};
+
+export function maxBytesForTool(limits: ToolResponseLimits, tool: string): number {
+  return limits.tools?.[tool] ?? limits.default_max_bytes ?? DEFAULT_TOOL_RESPONSE_LIMITS.default_max_bytes;
+}
+
+// Fields always kept on list items, whatever their size.
+const ESSENTIAL_FIELDS = new Set(["id", "key", "name", "summary", "title", "status", "issueType", "assignee"]);
+// Non-essential fields larger than this are dropped from list items.
+const HEAVY_FIELD_BYTES = 512;
+const CONTINUATION_TTL_MS = 15 * 60_000;
+const MAX_CONTINUATIONS = 20;
+
+type ToolResult = {
+  content?: Array<{ type: string; text?: string }>;
+  data?: Record<string, unknown>;
+  [key: string]: unknown;
+};
+
+type Continuation = {
+  tool: string;
+  dataKey: string;
+  items: unknown[];
+  expiresAt: number;
+};
+
+const byteLength = (value: unknown) => new TextEncoder().encode(JSON.stringify(value) ?? "").length;
+
+/**
+ * Holds the items that did not fit in a response, per MCP session.
+ */
+export class ContinuationStore {
+  private entries = new Map<string, Continuation>();
+
+  put(tool: string, dataKey: string, items: unknown[]): string {
+    this.prune();
+    if (this.entries.size >= MAX_CONTINUATIONS) {
+      const oldest = this.entries.keys().next().value;
+      if (oldest) this.entries.delete(oldest);
+    }
+    const token = crypto.randomUUID();
+    this.entries.set(token, { tool, dataKey, items, expiresAt: Date.now() + CONTINUATION_TTL_MS });
+    return token;
+  }
+
+  take(token: string): Continuation | undefined {
+    this.prune();
+    const entry = this.entries.get(token);
+    this.entries.delete(token);
+    return entry;
+  }
+
+  private prune() {
+    const now = Date.now();
+    for (const [token, entry] of this.entries) {
+      if (entry.expiresAt <= now) this.entries.delete(token);
+    }
+  }
+}
+
+/**
+ * Trims a tool result to maxBytes. Returns the result unchanged when it
+ * already fits.
+ */
+export function limitToolResult(
+  tool: string,
+  result: ToolResult,
+  maxBytes: number,
+  continuations: ContinuationStore,
+): ToolResult {
+  if (!result || typeof result !== "object" || byteLength(result) <= maxBytes) return result;
+
+  const data = result.data && typeof result.data === "object" ? { ...result.data } : undefined;
+  const dataKey = data ? largestArrayKey(data) : undefined;
+  const notes: string[] = [];
+
+  let items = dataKey ? (data![dataKey] as unknown[]) : [];
+  if (dataKey) {
+    const dropped = new Set<string>();
+    items = items.map((item) => slimItem(item, dropped));
+    if (dropped.size > 0) notes.push(`dropped large fields (${[...dropped].join(", ")}); fetch single items for full details`);
+  }
+
+  const content = (result.content ?? []).map((c) => ({ ...c }));
+  const build = (count: number, extra?: Record<string, unknown>): ToolResult => ({
+    ...result,
+    content,
+    ...(data ? { data: { ...data, ...(dataKey ? { [dataKey]: items.slice(0, count) } : {}), ...extra } } : {}),
+  });
+
+  // Text content gets at most half the budget; list data gets the rest.
+  const textBudget = Math.floor(maxBytes / 2);
+  for (const c of content) {
+    if (typeof c.text === "string" && byteLength(c.text) > textBudget) {
+      c.text = truncateText(c.text, textBudget);
+      notes.push("text output shortened");
+    }
+  }
+
+  let count = items.length;
+  if (byteLength(build(count)) > maxBytes) {
+    // Largest prefix of items that fits, leaving room for the truncation note.
+    let lo = 0;
+    let hi = items.length;
+    while (lo < hi) {
+      const mid = Math.ceil((lo + hi) / 2);
+      if (byteLength(build(mid)) + 512 <= maxBytes) lo = mid;
+      else hi = mid - 1;
+    }
+    count = lo;
+  }
+
+  const truncation: Record<string, unknown> = { truncated: true, maxBytes };
+  if (dataKey && count < items.length) {
+    const token = continuations.put(tool, dataKey, items.slice(count));
+    truncation.returned = count;
+    truncation.remaining = items.length - count;
+    truncation.continuationToken = token;
+    notes.push(`showing ${count} of ${items.length} ${dataKey}; call fetchMoreResults with continuationToken "${token}" for the rest`);
+  }
+
+  const limited = build(count, { truncation });
+  if (notes.length > 0) {
+    limited.content = [...content, { type: "text", text: `[Response truncated to fit ${maxBytes} bytes: ${notes.join("; ")}.]` }];
+  }
+  return limited;
+}
+
+/**
+ * Returns the next page of a truncated result, itself limited to maxBytes.
+ */
+export function nextContinuationPage(
+  token: string,
+  maxBytes: number,
+  continuations: ContinuationStore,
+): ToolResult {
+  const entry = continuations.take(token);
+  if (!entry) {
+    return {
+      content: [{ type: "text", text: "Continuation token is unknown or expired. Re-run the original tool call." }],
+      isError: true,
+    };
+  }
+
+  const result: ToolResult = {
+    content: [{ type: "text", text: `${entry.items.length} more ${entry.dataKey} from ${entry.tool}.` }],
+    data: { success: true, tool: entry.tool, [entry.dataKey]: entry.items },
+  };
+  return limitToolResult(entry.tool, result, maxBytes, continuations);
+}
+
+function largestArrayKey(data: Record<string, unknown>): string | undefined {
+  let best: string | undefined;
+  let bestSize = 0;
+  for (const [key, value] of Object.entries(data)) {
+    if (!Array.isArray(value) || value.length === 0) continue;
+    const size = byteLength(value);
+    if (size > bestSize) {
+      best = key;
+      bestSize = size;
+    }
+  }
+  return best;
+}
+
+function slimItem(item: unknown, dropped: Set<string>): unknown {
+  if (!item || typeof item !== "object" || Array.isArray(item)) return item;
+  const slim: Record<string, unknown> = {};
+  for (const [field, value] of Object.entries(item as Record<string, unknown>)) {
+    if (!ESSENTIAL_FIELDS.has(field) && byteLength(value) > HEAVY_FIELD_BYTES) {
+      dropped.add(field);
+      continue;
+    }
+    slim[field] = value;
+  }
+  return slim;
+}
+
+function truncateText(text: string, maxBytes: number): string {
+  const lines = text.split("\n");
+  const kept: string[] = [];
+  let size = 0;
+  for (const line of lines) {
+    const lineBytes = byteLength(line) + 1;
+    if (size + lineBytes > maxBytes) break;
+    kept.push(line);
+    size += lineBytes;
+  }
+  if (kept.length === 0) return text.slice(0, Math.floor(maxBytes / 4));
+  return `${kept.join("\n")}\n… (${lines.length - kept.length} more lines)`;
+}
@@ -0,0 +1,28 @@
+import { DEFAULT_TOOL_APPROVAL_POLICIES, EXECUTE_APPROVED_TOOL, approvalPendingResult, requiresApproval } from "./tool-approvals";
+
+describe("tool approvals", () => {
+  it("gates destructive tools only once the tenant turns the gate on", () => {
+    expect(requiresApproval(DEFAULT_TOOL_APPROVAL_POLICIES, "deleteComment")).toBe(false);
+
+    const policies = { default: true, tools: { completeSprintReport: false, createWorkItem: true } };
+    expect(requiresApproval(policies, "deleteComment")).toBe(true);
+    expect(requiresApproval(policies, "completeSprintReport")).toBe(false);
+    expect(requiresApproval(policies, "createWorkItem")).toBe(true);
+    expect(requiresApproval(policies, "searchWorkItems")).toBe(false);
+    expect(requiresApproval({ default: true, tools: { [EXECUTE_APPROVED_TOOL]: true } }, EXECUTE_APPROVED_TOOL)).toBe(false);
+  });
+
+  it("tells the client how to run the held call", () => {
+    const result = approvalPendingResult({
+      id: 4,
+      token: "abc",
+      tool: "deleteComment",
+      arguments: { issueKey: "ENG-1", commentId: "10" },
+      status: "pending",
+      expires_at: "2026-01-02T00:00:00Z",
+    });
+    expect(result.content[0].text).toContain("approve request #4");
+    expect(result.content[0].text).toContain(`call ${EXECUTE_APPROVED_TOOL} with approvalToken "abc"`);
+    expect(result._meta.approval.status).toBe("pending");
+  });
+});
@@ -0,0 +1,72 @@
+/**
+ * Human approval gate for destructive tools. When a tenant turns it on, a
+ * call to a gated tool is not executed: its arguments are held by the
+ * backend and the client gets an approval token. Once the tenant approves
+ * the call from the dashboard (or the approval endpoint), the client passes
+ * the token to executeApprovedTool and the worker runs the held call, once.
+ */
+
+// Resolved policies served by the backend's /api/settings/tool-approvals/tenant.
+export type ToolApprovalPolicies = {
+  // Whether destructive tools need approval unless overridden per tool.
+  default: boolean;
+  tools: Record<string, boolean>;
+};
+
+export const DEFAULT_TOOL_APPROVAL_POLICIES: ToolApprovalPolicies = {
+  default: false,
+  tools: {},
+};
+
+// Tools that delete data or make changes that are hard to undo.
+export const DESTRUCTIVE_TOOLS = new Set(["deleteComment", "deleteJiraIssueType", "completeSprintReport", "replaceInGoogleDoc"]);
+
+export const EXECUTE_APPROVED_TOOL = "executeApprovedTool";
+
+export type ToolApproval = {
+  id: number;
+  token?: string;
+  tool: string;
+  arguments: Record<string, unknown>;
+  status: "pending" | "approved" | "rejected" | "executed" | "expired";
+  expires_at: string;
+};
+
+/** Reports whether calls to tool must be approved before they run. */
+export function requiresApproval(policies: ToolApprovalPolicies, tool: string): boolean {
+  if (tool === EXECUTE_APPROVED_TOOL) return false;
+  const override = policies.tools?.[tool];
+  if (override !== undefined) return override;
+  return policies.default && DESTRUCTIVE_TOOLS.has(tool);
+}
+
+/** Result returned in place of a held call. */
+export function approvalPendingResult(approval: ToolApproval) {
+  return {
+    content: [
+      {
+        type: "text" as const,
+        text: [
+          `${approval.tool} needs approval before it runs and was not executed.`,
+          `Ask the user to approve request #${approval.id} in the dashboard before ${approval.expires_at}.`,
+          `Once approved, call ${EXECUTE_APPROVED_TOOL} with approvalToken "${approval.token}" to run it.`,
+        ].join("\n"),
+      },
+    ],
+    _meta: { approval: { id: approval.id, token: approval.token, status: approval.status, expiresAt: approval.expires_at } },
+  };
+}
+
+/** Result for an approval token that cannot be executed (yet). */
+export function approvalNotReadyResult(status: string) {
+  const reason: Record<string, string> = {
+    pending: "is still waiting for the user's approval; try again after they approve it",
+    rejected: "was rejected by the user and will not run",
+    executed: "has already been executed",
+    expired: "expired before it was approved and executed",
+  };
+  return {
+    isError: true,
+    content: [{ type: "text" as const, text: `The held call ${reason[status] ?? `cannot run (status: ${status})`}.` }],
+  };
+}
@@ -0,0 +1,35 @@
+import { ToolResultCache, toolCacheKey } from "./tool-cache";
+
+describe("tool result cache", () => {
+  it("keys on arguments regardless of order and ignores undefined ones", () => {
+    expect(toolCacheKey("searchWorkItems", { projectKey: "ENG", maxResults: 5 })).toBe(
+      toolCacheKey("searchWorkItems", { maxResults: 5, projectKey: "ENG", jql: undefined }),
+    );
+    expect(toolCacheKey("searchWorkItems", { projectKey: "ENG" })).not.toBe(toolCacheKey("searchWorkItems", { projectKey: "OPS" }));
+  });
+
+  it("serves results within the window and clears on writes", () => {
+    const cache = new ToolResultCache(1_000);
+    const result = { content: [{ type: "text", text: "ENG-1" }] };
+    cache.put("getWorkItemDetails", { issueKey: "ENG-1" }, result);
+
+    cache.noteCall("getWorkItemDetails");
+    cache.noteCall("fetchMoreResults");
+    expect(cache.get("getWorkItemDetails", { issueKey: "ENG-1" })).toBe(result);
+
+    cache.noteCall("updateWorkItem");
+    expect(cache.get("getWorkItemDetails", { issueKey: "ENG-1" })).toBeUndefined();
+  });
+
+  it("expires entries after the window", () => {
+    vi.useFakeTimers();
+    try {
+      const cache = new ToolResultCache(1_000);
+      cache.put("findPeople", { command: "search", query: "ana" }, { content: [] });
+      vi.advanceTimersByTime(1_001);
+      expect(cache.get("findPeople", { command: "search", query: "ana" })).toBeUndefined();
+    } finally {
+      vi.useRealTimers();
+    }
+  });
+});
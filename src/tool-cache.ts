@@ -0,0 +1,86 @@
+/**
+ * Per-session cache of idempotent tool results. LLMs often retry the same
+ * read a few times in a row; within a short window those calls are answered
+ * from the session's cache instead of going back to Jira. Any call to a tool
+ * that is not cacheable may have changed what the reads return, so it clears
+ * the cache.
+ */
+
+export const TOOL_CACHE_TTL_MS = 30_000;
+const MAX_CACHED_RESULTS = 100;
+
+// Read-only tools whose results depend only on their arguments.
+export const CACHEABLE_TOOLS = new Set([
+  "getProjectOverview",
+  "getSprintBoard",
+  "searchWorkItems",
+  "getWorkItemDetails",
+  "findPeople",
+  "listGoogleDocs",
+  "getGoogleDoc",
+  "listSlackChannels",
+]);
+
+// Tools that neither read Jira nor change it, so calling them leaves the
+// cache alone.
+const CACHE_NEUTRAL_TOOLS = new Set(["fetchMoreResults", "listIntegrations", "userInfoOctokit"]);
+
+type CachedResult = { result: unknown; expiresAt: number };
+
+/** Serializes arguments with sorted keys so equal arguments share a key. */
+export function toolCacheKey(tool: string, args: unknown): string {
+  const stable = (value: unknown): unknown => {
+    if (Array.isArray(value)) return value.map(stable);
+    if (value && typeof value === "object") {
+      return Object.fromEntries(
+        Object.keys(value)
+          .sort()
+          .filter((k) => (value as Record<string, unknown>)[k] !== undefined)
+          .map((k) => [k, stable((value as Record<string, unknown>)[k])]),
+      );
+    }
+    return value;
+  };
+  return `${tool}:${JSON.stringify(stable(args ?? {}))}`;
+}
+
+export class ToolResultCache {
+  private entries = new Map<string, CachedResult>();
+
+  constructor(private ttlMs = TOOL_CACHE_TTL_MS) {}
+
+  get(tool: string, args: unknown): unknown | undefined {
+    const key = toolCacheKey(tool, args);
+    const entry = this.entries.get(key);
+    if (!entry) return undefined;
+    if (entry.expiresAt <= Date.now()) {
+      this.entries.delete(key);
+      return undefined;
+    }
+    return entry.result;
+  }
+
+  put(tool: string, args: unknown, result: unknown) {
+    this.prune();
+    if (this.entries.size >= MAX_CACHED_RESULTS) {
+      const oldest = this.entries.keys().next().value;
+      if (oldest) this.entries.delete(oldest);
+    }
+    this.entries.set(toolCacheKey(tool, args), { result, expiresAt: Date.now() + this.ttlMs });
+  }
+
+  /**
+   * Records a call to tool: cacheable and neutral tools leave the cache
+   * alone, any other tool may have written and empties it.
+   */
+  noteCall(tool: string) {
+    if (!CACHEABLE_TOOLS.has(tool) && !CACHE_NEUTRAL_TOOLS.has(tool)) this.entries.clear();
+  }
+
+  private prune() {
+    const now = Date.now();
+    for (const [key, entry] of this.entries) {
+      if (entry.expiresAt <= now) this.entries.delete(key);
+    }
+  }
+}
@@ -0,0 +1,51 @@
+import { ToolCallLimiter, currentToolSignal } from "./tool-concurrency";
+
+const flush = () => new Promise((resolve) => setTimeout(resolve, 0));
+
+describe("tool call limiter", () => {
+  it("caps concurrent calls and starts queued ones as slots free up", async () => {
+    const limiter = new ToolCallLimiter(2);
+    const gates: (() => void)[] = [];
+    let peak = 0;
+    const call = () =>
+      limiter.run(undefined, async () => {
+        peak = Math.max(peak, limiter.inFlight);
+        await new Promise<void>((resolve) => gates.push(resolve));
+      });
+
+    const calls = [call(), call(), call()];
+    await flush();
+    expect(gates).toHaveLength(2);
+
+    gates[0]();
+    await calls[0];
+    await flush();
+    expect(gates).toHaveLength(3);
+
+    gates[1]();
+    gates[2]();
+    await Promise.all(calls);
+    expect(peak).toBe(2);
+    expect(limiter.inFlight).toBe(0);
+  });
+
+  it("exposes the call's signal and drops cancelled calls from the queue", async () => {
+    const limiter = new ToolCallLimiter(1);
+    let release!: () => void;
+    const first = new AbortController();
+    const running = limiter.run(first.signal, async () => {
+      expect(currentToolSignal()).toBe(first.signal);
+      await new Promise<void>((resolve) => (release = resolve));
+    });
+
+    const queued = new AbortController();
+    const waiting = limiter.run(queued.signal, async () => "ran");
+    queued.abort(new Error("cancelled"));
+    await expect(waiting).rejects.toThrow("cancelled");
+
+    release();
+    await running;
+    expect(limiter.inFlight).toBe(0);
+    expect(currentToolSignal()).toBeUndefined();
+  });
+});
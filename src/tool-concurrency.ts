@@ -0,0 +1,67 @@
+/**
+ * Concurrent tool execution. An MCP session may have several tool calls in
+ * flight at once; at most MAX_CONCURRENT_TOOL_CALLS run at a time and the
+ * rest wait for a slot. Each call runs with the abort signal of its MCP
+ * request, which the SDK fires on notifications/cancelled, so a cancelled
+ * call stops waiting for a slot and its in-flight Jira requests are aborted.
+ */
+import { AsyncLocalStorage } from "node:async_hooks";
+
+export const MAX_CONCURRENT_TOOL_CALLS = 4;
+
+const toolCallSignal = new AsyncLocalStorage<AbortSignal>();
+
+/** Returns the abort signal of the tool call running in this async context. */
+export function currentToolSignal(): AbortSignal | undefined {
+  return toolCallSignal.getStore();
+}
+
+export class ToolCallLimiter {
+  private running = 0;
+  private waiting: (() => void)[] = [];
+
+  constructor(private max = MAX_CONCURRENT_TOOL_CALLS) {}
+
+  /**
+   * Runs fn once a slot is free, with signal as the current tool signal.
+   * Rejects with the signal's reason if it aborts while waiting.
+   */
+  async run<T>(signal: AbortSignal | undefined, fn: () => Promise<T>): Promise<T> {
+    await this.acquire(signal);
+    try {
+      return await (signal ? toolCallSignal.run(signal, fn) : fn());
+    } finally {
+      this.release();
+    }
+  }
+
+  get inFlight(): number {
+    return this.running;
+  }
+
+  private acquire(signal?: AbortSignal): Promise<void> {
+    signal?.throwIfAborted();
+    if (this.running < this.max) {
+      this.running++;
+      return Promise.resolve();
+    }
+    return new Promise((resolve, reject) => {
+      const grant = () => {
+        signal?.removeEventListener("abort", cancel);
+        this.running++;
+        resolve();
+      };
+      const cancel = () => {
+        this.waiting = this.waiting.filter((w) => w !== grant);
+        reject(signal?.reason);
+      };
+      signal?.addEventListener("abort", cancel, { once: true });
+      this.waiting.push(grant);
+    });
+  }
+
+  private release() {
+    this.running--;
+    this.waiting.shift()?.();
+  }
+}
@@ -0,0 +1,33 @@
+import { BackpressureError } from "./backpressure";
+import { JiraApiError, classifyToolError, toolErrorResult } from "./tool-errors";
+
+describe("tool errors", () => {
+  it("classifies Jira API failures", () => {
+    expect(classifyToolError(new JiraApiError("Jira API error: 401", 401)).code).toBe("auth_expired");
+    expect(classifyToolError(new JiraApiError("Jira API error: 404", 404)).code).toBe("not_found");
+    expect(classifyToolError(new JiraApiError("Jira API error: 400", 400, { priority: "invalid" }))).toEqual({
+      code: "field_validation",
+      message: "Jira API error: 400",
+      fields: { priority: "invalid" },
+    });
+    expect(classifyToolError(new JiraApiError("Jira API error: 500", 500)).code).toBe("tool_failed");
+    expect(classifyToolError(new Error("boom")).code).toBe("tool_failed");
+  });
+
+  it("separates Jira rate limits from backend quotas", () => {
+    const jira = classifyToolError(new BackpressureError("Jira is rejecting requests", 429, "rate_limited", 1_500, "Jira"));
+    expect(jira).toMatchObject({ code: "jira_rate_limited", retryAfterSeconds: 2 });
+
+    const quota = classifyToolError(new BackpressureError("Backend is rejecting requests", 429, "quota_exceeded", null, "Backend"));
+    expect(quota.code).toBe("quota_exceeded");
+  });
+
+  it("puts the code and hint in the result", () => {
+    const result = toolErrorResult({ code: "not_found", message: "Issue ENG-9 does not exist" });
+    expect(result.isError).toBe(true);
+    expect(result.structuredContent.error.code).toBe("not_found");
+    expect(result.structuredContent.error.hint).toContain("Check the key");
+    expect(result.content[0].text.startsWith("Issue ENG-9 does not exist")).toBe(true);
+    expect(result.content[0].text).toContain("Error code: not_found.");
+  });
+});
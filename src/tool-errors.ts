@@ -0,0 +1,98 @@
+/**
+ * Tool error taxonomy. Failed tool calls carry a machine-readable code and a
+ * remediation hint in structuredContent, so LLM clients can branch on the
+ * kind of failure instead of parsing messages. The codes match the backend's
+ * built-in MCP server (internal/mcp).
+ */
+import { BackpressureError } from "./backpressure";
+
+export const TOOL_ERROR_CODES = [
+  "auth_expired",
+  "jira_rate_limited",
+  "field_validation",
+  "not_found",
+  "quota_exceeded",
+  "permission_denied",
+  "tool_failed",
+] as const;
+
+export type ToolErrorCode = (typeof TOOL_ERROR_CODES)[number];
+
+const DEFAULT_HINTS: Partial<Record<ToolErrorCode, string>> = {
+  auth_expired:
+    "The Jira credentials were rejected. Ask the user to reconnect Jira or update the API token in their settings; retrying will not help until then.",
+  jira_rate_limited: "Jira is rate limiting requests. Wait before retrying and avoid issuing calls in parallel.",
+  field_validation: "Fix the listed arguments or fields and call the tool again; the tool's input schema is in tools/list.",
+  not_found:
+    "Check the key or ID. It may not exist, or the connected Jira account may not have permission to see it; search to find the right one.",
+  quota_exceeded:
+    "The account has used up its plan quota. Tell the user; calls will keep failing until the quota resets or the plan is upgraded.",
+  permission_denied:
+    "The connected Jira account lacks the named project permission. Ask the user or a Jira admin to grant it; retrying will not help until then.",
+};
+
+/** An error response from the Jira REST API. */
+export class JiraApiError extends Error {
+  readonly status: number;
+  // Jira's errors object: field name to message.
+  readonly fieldErrors: Record<string, string>;
+
+  constructor(message: string, status: number, fieldErrors: Record<string, string> = {}) {
+    super(message);
+    this.name = "JiraApiError";
+    this.status = status;
+    this.fieldErrors = fieldErrors;
+  }
+}
+
+export type ToolErrorDetail = {
+  code: ToolErrorCode;
+  message: string;
+  hint?: string;
+  fields?: Record<string, string>;
+  retryAfterSeconds?: number;
+};
+
+/** Classifies an error thrown by a tool. */
+export function classifyToolError(err: unknown): ToolErrorDetail {
+  const message = String((err as Error)?.message ?? err);
+
+  if (err instanceof BackpressureError) {
+    let code: ToolErrorCode = "tool_failed";
+    if (err.reason === "quota_exceeded") code = "quota_exceeded";
+    else if (err.source === "Jira" && err.status === 429) code = "jira_rate_limited";
+    const retryAfterSeconds = err.retryAfterMs === null ? undefined : Math.ceil(err.retryAfterMs / 1000);
+    return { code, message, retryAfterSeconds };
+  }
+
+  if (err instanceof JiraApiError) {
+    if (err.status === 401 || err.status === 403) return { code: "auth_expired", message };
+    if (err.status === 404) return { code: "not_found", message };
+    if (err.status === 400 && Object.keys(err.fieldErrors).length > 0) {
+      return { code: "field_validation", message, fields: err.fieldErrors };
+    }
+  }
+
+  return { code: "tool_failed", message };
+}
+
+/**
+ * Builds the tools/call result for a classified failure: the text leads with
+ * the message and ends with the code and hint, and structuredContent carries
+ * the same details.
+ */
+export function toolErrorResult(detail: ToolErrorDetail) {
+  const hint = detail.hint ?? DEFAULT_HINTS[detail.code];
+  const lines = [detail.message];
+  for (const [field, problem] of Object.entries(detail.fields ?? {})) {
+    lines.push(`- ${field}: ${problem}`);
+  }
+  if (hint) lines.push("", `Error code: ${detail.code}. ${hint}`);
+  if (detail.retryAfterSeconds !== undefined) lines.push(`Retry after ${detail.retryAfterSeconds} seconds.`);
+
+  return {
+    isError: true,
+    content: [{ type: "text" as const, text: lines.join("\n") }],
+    structuredContent: { error: { ...detail, ...(hint ? { hint } : {}) } },
+  };
+}
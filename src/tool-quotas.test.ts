@@ -0,0 +1,46 @@
+import { BackpressureError } from "./backpressure";
+import { classifyToolError } from "./tool-errors";
+import { ToolQuotaTracker, type ToolQuotaSnapshot } from "./tool-quotas";
+
+const now = Date.parse("2026-04-10T12:00:00Z");
+
+const snapshot = (used: number): ToolQuotaSnapshot => ({
+  plan_slug: "free",
+  resets_at: "2026-04-11T00:00:00Z",
+  categories: [{ category: "write", used, daily_limit: 3, remaining: Math.max(0, 3 - used), exceeded: used >= 3 }],
+  tools: { createTask: "write", searchJiraIssues: "search" },
+  default_category: "read",
+});
+
+describe("tool quotas", () => {
+  it("refuses calls in a used up category until the quotas reset", () => {
+    const quotas = new ToolQuotaTracker();
+    quotas.update(snapshot(2), now);
+
+    expect(() => quotas.check("createTask", now)).not.toThrow();
+    quotas.record("createTask");
+
+    let refused: unknown;
+    try {
+      quotas.check("createTask", now);
+    } catch (err) {
+      refused = err;
+    }
+    expect(refused).toBeInstanceOf(BackpressureError);
+    expect(classifyToolError(refused)).toMatchObject({ code: "quota_exceeded", retryAfterSeconds: 12 * 3600 });
+
+    expect(() => quotas.check("getProjects", now)).not.toThrow();
+    expect(() => quotas.check("searchJiraIssues", now)).not.toThrow();
+    expect(() => quotas.check("createTask", Date.parse("2026-04-11T00:00:01Z"))).not.toThrow();
+  });
+
+  it("lets calls through until a snapshot is loaded and refreshes stale ones", () => {
+    const quotas = new ToolQuotaTracker(60_000);
+    expect(quotas.isStale(now)).toBe(true);
+    expect(() => quotas.check("createTask", now)).not.toThrow();
+
+    quotas.update(snapshot(3), now);
+    expect(quotas.isStale(now + 30_000)).toBe(false);
+    expect(quotas.isStale(now + 60_000)).toBe(true);
+  });
+});
@@ -0,0 +1,84 @@
+/**
+ * Per-category daily tool quotas. The backend reports how many successful
+ * calls the tenant made today in each tool category (read, search, write)
+ * against their plan's daily limits, together with each configured tool's
+ * category. Calls in a category that is used up are refused before they run
+ * with a quota_exceeded backoff lasting until the quotas reset; successful
+ * calls are counted locally between refreshes.
+ */
+import { BackpressureError } from "./backpressure";
+
+// How long a quota snapshot is trusted before it is fetched again.
+export const TOOL_QUOTA_TTL_MS = 60_000;
+
+export type ToolCategoryUsage = {
+  category: string;
+  used: number;
+  daily_limit: number | null;
+  remaining: number | null;
+  exceeded: boolean;
+};
+
+export type ToolQuotaSnapshot = {
+  plan_slug?: string;
+  resets_at: string;
+  categories: ToolCategoryUsage[];
+  tools: Record<string, string>;
+  default_category: string;
+};
+
+export class ToolQuotaTracker {
+  private snapshot: ToolQuotaSnapshot | null = null;
+  private fetchedAt = 0;
+  // Successful calls per category since the snapshot was fetched.
+  private counted = new Map<string, number>();
+
+  constructor(private ttlMs = TOOL_QUOTA_TTL_MS) {}
+
+  /** Whether the snapshot is missing, too old or from a previous day. */
+  isStale(now = Date.now()): boolean {
+    if (!this.snapshot || now - this.fetchedAt >= this.ttlMs) return true;
+    return now >= Date.parse(this.snapshot.resets_at);
+  }
+
+  update(snapshot: ToolQuotaSnapshot, now = Date.now()) {
+    this.snapshot = snapshot;
+    this.fetchedAt = now;
+    this.counted.clear();
+  }
+
+  categoryOf(tool: string): string {
+    return this.snapshot?.tools?.[tool] ?? this.snapshot?.default_category ?? "read";
+  }
+
+  /**
+   * Throws a BackpressureError with reason quota_exceeded when the tool's
+   * category has no calls left today. Without a snapshot every call passes.
+   */
+  check(tool: string, now = Date.now()) {
+    const snapshot = this.snapshot;
+    if (!snapshot) return;
+    const resetsAt = Date.parse(snapshot.resets_at);
+    if (now >= resetsAt) return;
+
+    const category = this.categoryOf(tool);
+    const usage = snapshot.categories.find((c) => c.category === category);
+    if (!usage || usage.daily_limit === null) return;
+    const used = usage.used + (this.counted.get(category) ?? 0);
+    if (used < usage.daily_limit) return;
+
+    throw new BackpressureError(
+      `Daily ${category} tool quota of ${usage.daily_limit} calls exceeded for the ${snapshot.plan_slug ?? "current"} plan`,
+      429,
+      "quota_exceeded",
+      Math.max(0, resetsAt - now),
+      "Backend",
+    );
+  }
+
+  /** Counts a successful call of tool against its category. */
+  record(tool: string) {
+    const category = this.categoryOf(tool);
+    this.counted.set(category, (this.counted.get(category) ?? 0) + 1);
+  }
+}
@@ -0,0 +1,25 @@
+import { z } from "zod";
+import { toolSchemas, validateToolArguments } from "./tool-schemas";
+
+describe("tool schemas", () => {
+  it("accepts Jira keys and rejects malformed ones", () => {
+    expect(toolSchemas.issueKey().safeParse("ENG-123").success).toBe(true);
+    expect(toolSchemas.issueKey().safeParse("ENG 123").success).toBe(false);
+    expect(toolSchemas.projectKey().safeParse("ENG").success).toBe(true);
+    expect(toolSchemas.projectKey().safeParse("ENG OR 1=1").success).toBe(false);
+    expect(toolSchemas.maxResults().safeParse(500).success).toBe(false);
+    expect(toolSchemas.statusCategory().safeParse("Done").success).toBe(true);
+  });
+
+  it("returns a tool error naming each invalid argument", () => {
+    const schema = z.object({ issueKey: toolSchemas.issueKey(), maxResults: toolSchemas.maxResults().optional() });
+
+    expect(validateToolArguments("getWorkItemDetails", schema, { issueKey: "ENG-1" })).toBeUndefined();
+
+    const result = validateToolArguments("getWorkItemDetails", schema, { maxResults: 1.5 });
+    expect(result?.isError).toBe(true);
+    expect(result?._meta.validationErrors.map((i) => i.path)).toEqual(["issueKey", "maxResults"]);
+    expect(result?.content[0].text).toContain("Invalid arguments for getWorkItemDetails:");
+    expect(result?.content[0].text).toContain("- issueKey: Required");
+  });
+});
@@ -0,0 +1,60 @@
+/**
+ * Shared argument schemas for MCP tools and the validation that runs before
+ * a tool executes. Tools build their input shapes from these fragments so the
+ * JSON Schema served in tools/list carries patterns, bounds and enums, and
+ * LLMs produce correctly typed arguments. Invalid arguments are answered with
+ * a tool error listing each offending field, which the model can act on,
+ * instead of a protocol error it never sees.
+ */
+import { z, type ZodError, type ZodTypeAny } from "zod";
+
+const JIRA_KEY_PATTERN = /^[A-Za-z][A-Za-z0-9_]*$/;
+const JIRA_ISSUE_KEY_PATTERN = /^[A-Za-z][A-Za-z0-9_]*-\d+$/;
+
+export const STATUS_CATEGORIES = ["To Do", "In Progress", "Done"] as const;
+
+export const toolSchemas = {
+  projectKey: () => z.string().regex(JIRA_KEY_PATTERN, "Expected a Jira project key such as 'ENG'"),
+  issueKey: () => z.string().regex(JIRA_ISSUE_KEY_PATTERN, "Expected a Jira issue key such as 'ENG-123'"),
+  issueKeys: () => z.array(toolSchemas.issueKey()).min(1).max(100),
+  // Numeric Jira ids (sprints, boards).
+  jiraId: () => z.number().int().positive(),
+  statusCategory: () => z.enum(STATUS_CATEGORIES),
+  // Page sizes; Jira caps most searches at 100.
+  maxResults: () => z.number().int().min(1).max(100),
+  days: () => z.number().int().min(1).max(3650),
+};
+
+export type ToolArgumentIssue = { path: string; message: string };
+
+/** Flattens a zod error into one entry per offending argument. */
+export function toolArgumentIssues(error: ZodError): ToolArgumentIssue[] {
+  return error.issues.map((issue) => ({
+    path: issue.path.length ? issue.path.join(".") : "(arguments)",
+    message: issue.message,
+  }));
+}
+
+/**
+ * Validates arguments against a tool's input schema. Returns undefined when
+ * they are valid, else the tool error result to send back.
+ */
+export function validateToolArguments(tool: string, schema: ZodTypeAny, args: unknown) {
+  const parsed = schema.safeParse(args ?? {});
+  if (parsed.success) return undefined;
+
+  const issues = toolArgumentIssues(parsed.error);
+  const lines = [
+    `Invalid arguments for ${tool}:`,
+    ...issues.map((i) => `- ${i.path}: ${i.message}`),
+    `Fix these arguments and call ${tool} again; the tool's input schema is in tools/list.`,
+  ];
+  return {
+    isError: true,
+    content: [{ type: "text" as const, text: lines.join("\n") }],
+    structuredContent: {
+      error: { code: "field_validation" as const, message: lines[0], fields: Object.fromEntries(issues.map((i) => [i.path, i.message])) },
+    },
+    _meta: { validationErrors: issues },
+  };
+}
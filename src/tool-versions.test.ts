@@ -0,0 +1,39 @@
+import { DEFAULT_PREFERENCES } from "./utils";
+import {
+  CURRENT_TOOL_SCHEMA_VERSION,
+  TOOL_SCHEMA_CAPABILITY,
+  extractToolSchemaVersionFromRequest,
+  negotiateToolSchemaVersion,
+  parseToolSchemaVersion,
+  preferencesForVersion,
+} from "./tool-versions";
+
+describe("tool schema versions", () => {
+  it("prefers the initialize capability, then the connection, then the protocol default", () => {
+    const withCapability = {
+      protocolVersion: "2025-03-26",
+      capabilities: { experimental: { [TOOL_SCHEMA_CAPABILITY]: { version: 1 } } },
+    };
+    expect(negotiateToolSchemaVersion(withCapability, CURRENT_TOOL_SCHEMA_VERSION)).toBe(1);
+    expect(negotiateToolSchemaVersion({ protocolVersion: "2025-03-26" }, 1)).toBe(1);
+    expect(negotiateToolSchemaVersion({ protocolVersion: "2024-11-05" })).toBe(1);
+    expect(negotiateToolSchemaVersion({ protocolVersion: "2025-03-26" })).toBe(CURRENT_TOOL_SCHEMA_VERSION);
+  });
+
+  it("clamps requested versions and ignores unusable ones", () => {
+    expect(parseToolSchemaVersion("99")).toBe(CURRENT_TOOL_SCHEMA_VERSION);
+    expect(parseToolSchemaVersion("0")).toBeUndefined();
+    expect(parseToolSchemaVersion("two")).toBeUndefined();
+
+    const header = new Request("https://mcp.example.com/mcp?tool_schema_version=2", {
+      headers: { "X-MCP-Tool-Schema-Version": "1" },
+    });
+    expect(extractToolSchemaVersionFromRequest(header)).toBe(1);
+    expect(extractToolSchemaVersionFromRequest(new Request("https://mcp.example.com/mcp?tool_schema_version=1"))).toBe(1);
+  });
+
+  it("keeps raw Jira dates for version 1 sessions", () => {
+    expect(preferencesForVersion(DEFAULT_PREFERENCES, 1).date_format).toBe("raw");
+    expect(preferencesForVersion(DEFAULT_PREFERENCES, CURRENT_TOOL_SCHEMA_VERSION)).toEqual(DEFAULT_PREFERENCES);
+  });
+});
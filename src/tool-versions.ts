@@ -0,0 +1,124 @@
+/**
+ * Tool schema versioning. Whenever a tool changes shape in a way existing
+ * clients could trip over (renamed or retyped arguments, reshaped results),
+ * the change is recorded here under a new schema version together with the
+ * adapters that present the previous shape. Each MCP session negotiates a
+ * version when it initializes, and sessions on an older version keep seeing
+ * the tools as they were.
+ *
+ * A client picks a version by sending, in order of precedence:
+ *   - capabilities.experimental["mcp-jira-thing/toolSchema"].version in initialize
+ *   - the tool_schema_version query parameter or X-MCP-Tool-Schema-Version header
+ * Clients that ask for nothing get the newest version, except clients on a
+ * protocol revision listed in PROTOCOL_TOOL_SCHEMA_DEFAULTS.
+ */
+import type { ZodRawShape } from "zod";
+import type { UserPreferences } from "./utils";
+
+export const TOOL_SCHEMA_CAPABILITY = "mcp-jira-thing/toolSchema";
+
+export type ToolSchemaChange = {
+  // Schema version that introduced the change; sessions on an older
+  // version get the adapters below.
+  version: number;
+  description: string;
+  // Tools the change applies to; all tools when omitted.
+  tools?: string[];
+  // Rewrites the input shape advertised to older sessions.
+  downgradeShape?: (shape: ZodRawShape) => ZodRawShape;
+  // Converts arguments sent in the older shape to the current one.
+  upgradeArgs?: (args: Record<string, unknown>) => Record<string, unknown>;
+  // Converts a current tool result to the older shape.
+  downgradeResult?: (result: any) => any;
+  // Adjusts the tenant preferences tools see in older sessions.
+  downgradePreferences?: (prefs: UserPreferences) => UserPreferences;
+};
+
+export const TOOL_SCHEMA_CHANGES: ToolSchemaChange[] = [
+  {
+    version: 2,
+    description: "Jira dates in tool results are formatted in the tenant's timezone, locale and date format.",
+    downgradePreferences: (prefs) => ({ ...prefs, date_format: "raw" }),
+  },
+];
+
+export const CURRENT_TOOL_SCHEMA_VERSION = Math.max(1, ...TOOL_SCHEMA_CHANGES.map((c) => c.version));
+
+// Default tool schema version for clients that negotiated an older MCP
+// protocol revision and did not ask for a version.
+const PROTOCOL_TOOL_SCHEMA_DEFAULTS: Record<string, number> = {
+  "2024-11-05": 1,
+};
+
+/** Parses a requested version, returning undefined for anything unusable. */
+export function parseToolSchemaVersion(value: unknown): number | undefined {
+  const version = typeof value === "string" ? Number(value.trim()) : value;
+  if (typeof version !== "number" || !Number.isInteger(version) || version < 1) return undefined;
+  return Math.min(version, CURRENT_TOOL_SCHEMA_VERSION);
+}
+
+/** Reads a version requested on the connection URL or headers. */
+export function extractToolSchemaVersionFromRequest(request: Request): number | undefined {
+  const header = request.headers.get("x-mcp-tool-schema-version");
+  if (header) return parseToolSchemaVersion(header);
+  try {
+    return parseToolSchemaVersion(new URL(request.url).searchParams.get("tool_schema_version") ?? undefined);
+  } catch {
+    return undefined;
+  }
+}
+
+/**
+ * Picks the tool schema version for a session from its initialize request
+ * parameters and any version requested on the connection.
+ */
+export function negotiateToolSchemaVersion(
+  initialize: { protocolVersion?: string; capabilities?: { experimental?: Record<string, any> } },
+  requested?: number,
+): number {
+  const fromCapabilities = parseToolSchemaVersion(initialize.capabilities?.experimental?.[TOOL_SCHEMA_CAPABILITY]?.version);
+  if (fromCapabilities !== undefined) return fromCapabilities;
+  if (requested !== undefined) return requested;
+  return PROTOCOL_TOOL_SCHEMA_DEFAULTS[initialize.protocolVersion ?? ""] ?? CURRENT_TOOL_SCHEMA_VERSION;
+}
+
+/** Returns the changes a session on version must be shielded from, newest first. */
+export function changesSince(version: number, tool?: string): ToolSchemaChange[] {
+  return TOOL_SCHEMA_CHANGES.filter((c) => c.version > version && (!tool || !c.tools || c.tools.includes(tool))).sort(
+    (a, b) => b.version - a.version,
+  );
+}
+
+/** Returns the input shape advertised to a session on version. */
+export function shapeForVersion(tool: string, shape: ZodRawShape, version: number): ZodRawShape {
+  return changesSince(version, tool).reduce((s, c) => (c.downgradeShape ? c.downgradeShape(s) : s), shape);
+}
+
+/** Converts arguments from a session on version to the current shape. */
+export function upgradeArgs(tool: string, args: Record<string, unknown>, version: number): Record<string, unknown> {
+  // Oldest change first, so each adapter sees the shape it was written for.
+  return changesSince(version, tool)
+    .reverse()
+    .reduce((a, c) => (c.upgradeArgs ? c.upgradeArgs(a) : a), args);
+}
+
+/** Converts a current tool result to the shape a session on version expects. */
+export function downgradeResult(tool: string, result: any, version: number): any {
+  return changesSince(version, tool).reduce((r, c) => (c.downgradeResult ? c.downgradeResult(r) : r), result);
+}
+
+/** Returns the tenant preferences as tools should see them on version. */
+export function preferencesForVersion(prefs: UserPreferences, version: number): UserPreferences {
+  return changesSince(version).reduce((p, c) => (c.downgradePreferences ? c.downgradePreferences(p) : p), prefs);
+}
+
+/** Server capability advertising the supported tool schema versions. */
+export function toolSchemaCapability(): Record<string, unknown> {
+  return {
+    [TOOL_SCHEMA_CAPABILITY]: {
+      current: CURRENT_TOOL_SCHEMA_VERSION,
+      supported: Array.from({ length: CURRENT_TOOL_SCHEMA_VERSION }, (_, i) => i + 1),
+      changes: TOOL_SCHEMA_CHANGES.map(({ version, description, tools }) => ({ version, description, ...(tools ? { tools } : {}) })),
+    },
+  };
+}
@@ -1,3 +1,7 @@
+import { backpressureFromResponse, parseRetryAfter } from "../../../backpressure";
+import { currentToolSignal } from "../../../tool-concurrency";
+import { JiraApiError } from "../../../tool-errors";
+
 interface RetryOptions {
   maxAttempts?: number;
   initialDelayMs?: number;
@@ -55,8 +59,12 @@ export class JiraClientCore {
       headers.set("Content-Type", "application/json");
     }
 
+    // Requests made for a tool call are aborted when the client cancels it.
+    const cancelSignal = currentToolSignal();
+
     let lastError: unknown;
     for (let attempt = 1; attempt <= Math.max(1, retryOptions.maxAttempts); attempt += 1) {
+      cancelSignal?.throwIfAborted();
       const requestOptions: RequestInit = {
         method,
         headers,
@@ -71,19 +79,31 @@ export class JiraClientCore {
       try {
         const response = await fetch(`${this.baseUrl}${endpoint}`, {
           ...requestOptions,
-          signal: AbortSignal.timeout(30_000),
+          signal: cancelSignal ? AbortSignal.any([cancelSignal, AbortSignal.timeout(30_000)]) : AbortSignal.timeout(30_000),
         });
 
         if (!response.ok) {
           const retryAfter = response.headers.get("Retry-After");
-          if (shouldRetry(response.status) && attempt < retryOptions.maxAttempts) {
+          // Waits beyond the retry budget are left for the caller to schedule.
+          const retryAfterMs = parseRetryAfter(retryAfter);
+          const waitTooLong = retryAfterMs !== null && retryAfterMs > retryOptions.maxDelayMs;
+          if (shouldRetry(response.status) && attempt < retryOptions.maxAttempts && !waitTooLong) {
             const delay = calculateBackoffDelay(attempt, retryOptions, retryAfter);
             await sleep(delay);
             continue;
           }
 
+          const backpressure = await backpressureFromResponse(response, "Jira");
+          if (backpressure) {
+            throw backpressure;
+          }
+
           const errorText = await safeReadResponse(response);
-          throw new Error(`Jira API error: ${response.status} ${response.statusText} - ${errorText}`);
+          throw new JiraApiError(
+            `Jira API error: ${response.status} ${response.statusText} - ${errorText}`,
+            response.status,
+            parseFieldErrors(errorText),
+          );
         }
 
         if (response.status === 204) {
@@ -136,24 +156,6 @@ function calculateBackoffDelay(attempt: number, options: Required<RetryOptions>,
   return Math.min(delay, options.maxDelayMs);
 }
 
-function parseRetryAfter(header: string | null): number | null {
-  if (!header) {
-    return null;
-  }
-
-  const seconds = Number(header);
-  if (!Number.isNaN(seconds)) {
-    return Math.max(0, seconds * 1000);
-  }
-
-  const date = Date.parse(header);
-  if (!Number.isNaN(date)) {
-    return Math.max(0, date - Date.now());
-  }
-
-  return null;
-}
-
 async function safeReadResponse(response: Response): Promise<string> {
   try {
     return await response.text();
@@ -163,6 +165,17 @@ async function safeReadResponse(response: Response): Promise<string> {
   }
 }
 
+// Jira reports rejected fields as {"errors": {"field": "message"}}.
+function parseFieldErrors(body: string): Record<string, string> {
+  try {
+    const parsed = JSON.parse(body);
+    if (parsed?.errors && typeof parsed.errors === "object") return parsed.errors as Record<string, string>;
+  } catch {
+    // Not JSON; the message carries the body.
+  }
+  return {};
+}
+
 function sleep(durationMs: number): Promise<void> {
   return new Promise((resolve) => setTimeout(resolve, durationMs));
 }
@@ -110,4 +110,192 @@ export type Props = {
   email: string;
   accessToken: string;
   mcpSecret?: string;
+  // Tool schema version requested on the connection URL or headers.
+  toolSchemaVersion?: number;
 };
+
+const encoder = new TextEncoder();
+
+function toHex(buf: ArrayBuffer): string {
+  return [...new Uint8Array(buf)].map((b) => b.toString(16).padStart(2, "0")).join("");
+}
+
+/**
+ * Builds the X-MCP-Timestamp / X-MCP-Signature headers required by the Go
+ * backend's backend-only routes. The signature is an HMAC-SHA256, keyed with
+ * WORKER_SHARED_KEY, over:
+ *
+ *   METHOD \n PATH \n RAW_QUERY \n TIMESTAMP \n hex(sha256(BODY))
+ *
+ * Returns no headers when no key is configured.
+ */
+export async function signBackendRequest(
+  sharedKey: string | undefined,
+  method: string,
+  url: URL,
+  body = "",
+): Promise<Record<string, string>> {
+  if (!sharedKey) return {};
+
+  const timestamp = Math.floor(Date.now() / 1000).toString();
+  const bodyHash = toHex(await crypto.subtle.digest("SHA-256", encoder.encode(body)));
+  const canonical = [method.toUpperCase(), url.pathname, url.search.replace(/^\?/, ""), timestamp, bodyHash].join("\n");
+
+  const key = await crypto.subtle.importKey("raw", encoder.encode(sharedKey), { name: "HMAC", hash: "SHA-256" }, false, [
+    "sign",
+  ]);
+  const signature = toHex(await crypto.subtle.sign("HMAC", key, encoder.encode(canonical)));
+
+  return {
+    "X-MCP-Timestamp": timestamp,
+    "X-MCP-Signature": signature,
+  };
+}
+
+/**
+ * Headers that authenticate the worker to the backend's backend-only
+ * routes: the request signature plus the BACKEND_API_KEY API key, when
+ * either is configured. The backend accepts a tenant-scoped API key in place
+ * of the signature and requires one when API_KEYS_REQUIRED is set.
+ */
+export async function backendAuthHeaders(
+  env: { WORKER_SHARED_KEY?: string; BACKEND_API_KEY?: string },
+  method: string,
+  url: URL,
+  body = "",
+): Promise<Record<string, string>> {
+  const headers = await signBackendRequest(env.WORKER_SHARED_KEY, method, url, body);
+  if (env.BACKEND_API_KEY) headers["X-API-Key"] = env.BACKEND_API_KEY;
+  return headers;
+}
+
+// Per-tenant date preferences served by the backend's /api/preferences/tenant.
+export type UserPreferences = {
+  timezone: string;
+  locale: string;
+  // "raw" keeps Jira's own timestamps; tool-versions.ts uses it for
+  // sessions on tool schema version 1.
+  date_format: "iso" | "short" | "medium" | "long" | "raw";
+  // Minimum level of MCP log messages streamed to the tenant's sessions.
+  mcp_log_level?: string;
+};
+
+export const DEFAULT_PREFERENCES: UserPreferences = {
+  timezone: "UTC",
+  locale: "en-US",
+  date_format: "iso",
+};
+
+/**
+ * Formats a Jira timestamp (e.g. "2025-06-30T08:00:00.000+0000") or date
+ * ("2025-06-30") for display in the tenant's timezone and locale. Unparseable
+ * values are returned unchanged.
+ */
+export function formatJiraDate(value: string | null | undefined, prefs: UserPreferences = DEFAULT_PREFERENCES) {
+  if (!value) return value ?? null;
+  if (prefs.date_format === "raw") return value;
+
+  const dateOnly = /^\d{4}-\d{2}-\d{2}$/.test(value);
+  // Jira emits offsets without a colon (+0000), which Date.parse rejects.
+  const parsed = new Date(dateOnly ? `${value}T00:00:00Z` : value.replace(/([+-]\d{2})(\d{2})$/, "$1:$2"));
+  if (Number.isNaN(parsed.getTime())) return value;
+
+  // Date-only fields (due dates) have no time component to shift.
+  const timeZone = dateOnly ? "UTC" : prefs.timezone;
+
+  try {
+    if (prefs.date_format === "iso") {
+      const parts = zonedParts(parsed, timeZone);
+      const date = `${parts.year}-${parts.month}-${parts.day}`;
+      return dateOnly ? date : `${date} ${parts.hour}:${parts.minute} (${prefs.timezone})`;
+    }
+    const formatted = new Intl.DateTimeFormat(prefs.locale, {
+      dateStyle: prefs.date_format,
+      ...(dateOnly ? {} : { timeStyle: "short" }),
+      timeZone,
+    }).format(parsed);
+    return dateOnly ? formatted : `${formatted} (${prefs.timezone})`;
+  } catch {
+    return value;
+  }
+}
+
+const WEEKDAYS = ["sunday", "monday", "tuesday", "wednesday", "thursday", "friday", "saturday"];
+
+/**
+ * Interprets a natural date phrase relative to "now" in the tenant's timezone
+ * and returns a Jira date (YYYY-MM-DD). Supports ISO dates, today, tomorrow,
+ * yesterday, [this|next] <weekday>, in N days/weeks, N days/weeks ago, and
+ * end of week/month. Returns undefined for phrases it does not understand.
+ */
+export function resolveNaturalDate(
+  input: string | null | undefined,
+  prefs: UserPreferences = DEFAULT_PREFERENCES,
+  now: Date = new Date(),
+): string | undefined {
+  const phrase = (input ?? "").trim().toLowerCase().replace(/\s+/g, " ");
+  if (!phrase) return undefined;
+  if (/^\d{4}-\d{2}-\d{2}$/.test(phrase)) return phrase;
+
+  const parts = zonedParts(now, prefs.timezone);
+  // Work on a UTC date holding the tenant's local calendar day.
+  const today = new Date(Date.UTC(Number(parts.year), Number(parts.month) - 1, Number(parts.day)));
+  const addDays = (n: number) => new Date(today.getTime() + n * 86_400_000);
+  const format = (d: Date) => d.toISOString().slice(0, 10);
+
+  if (phrase === "today") return format(today);
+  if (phrase === "tomorrow") return format(addDays(1));
+  if (phrase === "yesterday") return format(addDays(-1));
+
+  let match = phrase.match(/^in (\d+) (day|week)s?$/);
+  if (match) return format(addDays(Number(match[1]) * (match[2] === "week" ? 7 : 1)));
+
+  match = phrase.match(/^(\d+) (day|week)s? ago$/);
+  if (match) return format(addDays(-Number(match[1]) * (match[2] === "week" ? 7 : 1)));
+
+  if (phrase === "end of week") return format(addDays((5 - today.getUTCDay() + 7) % 7));
+  if (phrase === "end of month") {
+    return format(new Date(Date.UTC(today.getUTCFullYear(), today.getUTCMonth() + 1, 0)));
+  }
+
+  match = phrase.match(/^(this |next )?(sunday|monday|tuesday|wednesday|thursday|friday|saturday)$/);
+  if (match) {
+    const target = WEEKDAYS.indexOf(match[2]);
+    let delta = (target - today.getUTCDay() + 7) % 7;
+    // "next friday" on a Friday means a week out; a bare weekday or
+    // "this friday" on that day means today.
+    if (match[1] === "next " && delta === 0) delta = 7;
+    return format(addDays(delta));
+  }
+
+  return undefined;
+}
+
+function zonedParts(date: Date, timeZone: string) {
+  const parts: Record<string, string> = {};
+  for (const p of new Intl.DateTimeFormat("en-US", {
+    timeZone,
+    year: "numeric",
+    month: "2-digit",
+    day: "2-digit",
+    hour: "2-digit",
+    minute: "2-digit",
+    hourCycle: "h23",
+  }).formatToParts(date)) {
+    parts[p.type] = p.value;
+  }
+  return parts;
+}
+
+/**
+ * Jira project a tool call touched, for per-project usage reporting: an
+ * explicit projectKey, else the prefix of the (first) issue key.
+ */
+export function projectKeyFromArgs(args: unknown): string | undefined {
+  if (!args || typeof args !== "object") return undefined;
+  const a = args as Record<string, unknown>;
+  if (typeof a.projectKey === "string" && a.projectKey) return a.projectKey.toUpperCase();
+  const issueKey = typeof a.issueKey === "string" ? a.issueKey : Array.isArray(a.issueKeys) ? a.issueKeys[0] : undefined;
+  const match = typeof issueKey === "string" ? /^([A-Za-z][A-Za-z0-9_]*)-\d+$/.exec(issueKey) : null;
+  return match ? match[1].toUpperCase() : undefined;
+}